@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	_ "net/http/pprof" // Enable pprof endpoints
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,10 +20,12 @@ import (
 	"github.com/koopa0/assistant-go/internal/assistant"
 	"github.com/koopa0/assistant-go/internal/cli"
 	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain/indexer"
 	"github.com/koopa0/assistant-go/internal/platform/observability"
 	"github.com/koopa0/assistant-go/internal/platform/server"
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+	"github.com/koopa0/assistant-go/internal/tool"
 	"github.com/koopa0/assistant-go/internal/user"
 )
 
@@ -165,6 +172,14 @@ func main() {
 				os.Exit(1)
 			}
 			runDirectQuery(ctx, assistantCore, os.Args[2], logger)
+		case "workflow":
+			runWorkflow(ctx, assistantCore, os.Args[2:], logger)
+		case "artifacts":
+			runArtifacts(ctx, assistantCore, os.Args[2:], logger)
+		case "tools":
+			runTools(ctx, assistantCore, os.Args[2:], logger)
+		case "index":
+			runIndex(ctx, assistantCore, os.Args[2:], logger)
 
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", os.Args[1])
@@ -300,6 +315,307 @@ func runDirectQuery(ctx context.Context, assistant *assistant.Assistant, query s
 	}
 }
 
+// runWorkflow lists workflows or runs one with key=value inputs; values
+// that parse as JSON are passed as JSON, anything else as a string. Runs
+// are recorded for the user given with --user.
+func runWorkflow(ctx context.Context, assistant *assistant.Assistant, args []string, logger *slog.Logger) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s workflow <list|run [--user <user_id>] <name> [key=value ...]>\n", os.Args[0])
+	}
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		workflows, err := assistant.ListWorkflows()
+		if err != nil {
+			logger.Error("Failed to list workflows", slog.Any("error", err))
+			os.Exit(1)
+		}
+		for _, wf := range workflows {
+			fmt.Printf("%-24s %s\n", wf.Name, wf.Description)
+		}
+	case "run":
+		flags := flag.NewFlagSet("workflow run", flag.ExitOnError)
+		userID := flags.String("user", "", "user id the run is recorded for")
+		flags.Usage = func() {
+			usage()
+			flags.PrintDefaults()
+		}
+		_ = flags.Parse(args[1:])
+		if flags.NArg() < 1 {
+			flags.Usage()
+			os.Exit(1)
+		}
+		if *userID == "" {
+			logger.Warn("No --user given, the workflow run will not be recorded")
+		}
+
+		inputs := make(map[string]interface{})
+		for _, arg := range flags.Args()[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Invalid input %q, expected key=value\n", arg)
+				os.Exit(1)
+			}
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+				decoded = value
+			}
+			inputs[key] = decoded
+		}
+
+		run, err := assistant.RunWorkflow(ctx, flags.Arg(0), inputs, *userID)
+		if err != nil {
+			logger.Error("Workflow run failed", slog.Any("error", err))
+			os.Exit(1)
+		}
+		out, err := json.MarshalIndent(run, "", "  ")
+		if err != nil {
+			logger.Error("Failed to encode workflow run", slog.Any("error", err))
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+		if !run.Success {
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown workflow command: %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Available commands: list, run\n")
+		os.Exit(1)
+	}
+}
+
+// runArtifacts lists the artifacts of a conversation or saves one to a
+// file; a path of "-" writes it to stdout
+func runArtifacts(ctx context.Context, assistant *assistant.Assistant, args []string, logger *slog.Logger) {
+	if len(args) < 2 || (args[0] != "list" && args[0] != "open") {
+		fmt.Fprintf(os.Stderr, "Usage: %s artifacts <list <conversation_id>|open <id> [path]>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	if args[0] == "list" {
+		artifacts, err := assistant.ListArtifacts(ctx, args[1])
+		if err != nil {
+			logger.Error("Failed to list artifacts", slog.Any("error", err))
+			os.Exit(1)
+		}
+		for _, a := range artifacts {
+			fmt.Printf("%s  %-12s %10d  %-24s %s\n", a.ID, a.ToolName, a.Size, a.ContentType, a.Name)
+		}
+		return
+	}
+
+	a, content, err := assistant.OpenArtifact(ctx, args[1])
+	if err != nil {
+		logger.Error("Failed to open artifact", slog.Any("error", err))
+		os.Exit(1)
+	}
+	defer content.Close()
+
+	path := a.Name
+	if len(args) > 2 {
+		path = args[2]
+	}
+	out := os.Stdout
+	if path != "-" {
+		if out, err = os.Create(path); err != nil {
+			logger.Error("Failed to create file", slog.Any("error", err))
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+	if _, err := io.Copy(out, content); err != nil {
+		logger.Error("Failed to write artifact", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if path != "-" {
+		fmt.Printf("Saved %s (%d bytes) to %s\n", a.Name, a.Size, path)
+	}
+}
+
+// runTools shows and edits the tool settings of a user, or of one of
+// their conversations when a conversation ID is given
+func runTools(ctx context.Context, assistant *assistant.Assistant, args []string, logger *slog.Logger) {
+	// Arguments without "=" are positional; the rest are settings to change
+	var positional, assignments []string
+	for _, arg := range args {
+		if strings.Contains(arg, "=") {
+			assignments = append(assignments, arg)
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	minArgs := 3
+	if len(positional) > 0 && positional[0] == "settings" {
+		minArgs = 2
+	}
+	if len(positional) < minArgs {
+		fmt.Fprintf(os.Stderr, "Usage: %s tools <settings <user_id>|enable|disable|reset <user_id> <tool>|set <user_id> <tool> key=value ...> [conversation_id]\n", os.Args[0])
+		os.Exit(1)
+	}
+	command, userID := positional[0], positional[1]
+
+	if command == "settings" {
+		var conversationID string
+		if len(positional) > 2 {
+			conversationID = positional[2]
+		}
+		settings, err := assistant.GetToolSettings(ctx, userID, conversationID)
+		if err != nil {
+			logger.Error("Failed to get tool settings", slog.Any("error", err))
+			os.Exit(1)
+		}
+		output, _ := json.MarshalIndent(settings, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	toolName := positional[2]
+	var conversationID string
+	if len(positional) > 3 {
+		conversationID = positional[3]
+	}
+
+	if command == "reset" {
+		if err := assistant.DeleteToolSetting(ctx, userID, conversationID, toolName); err != nil {
+			logger.Error("Failed to reset tool setting", slog.Any("error", err))
+			os.Exit(1)
+		}
+		fmt.Printf("Reset settings of %s\n", toolName)
+		return
+	}
+
+	// Change the stored setting of the scope rather than replacing it
+	current, err := assistant.GetToolSettings(ctx, userID, conversationID)
+	if err != nil {
+		logger.Error("Failed to get tool settings", slog.Any("error", err))
+		os.Exit(1)
+	}
+	setting := current.User[toolName]
+	if conversationID != "" {
+		setting = current.Conversation[toolName]
+	}
+
+	switch command {
+	case "enable", "disable":
+		enabled := command == "enable"
+		setting.Enabled = &enabled
+	case "set":
+		if len(assignments) == 0 {
+			fmt.Fprintf(os.Stderr, "Usage: %s tools set <user_id> <tool> [conversation_id] timeout=30s max_retries=2 retry_delay=1s <param>=<value> ...\n", os.Args[0])
+			os.Exit(1)
+		}
+		if err := applyToolSetting(&setting, assignments); err != nil {
+			logger.Error("Invalid tool setting", slog.Any("error", err))
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown tools command: %s\n", command)
+		os.Exit(1)
+	}
+
+	if err := assistant.SetToolSetting(ctx, userID, conversationID, toolName, setting); err != nil {
+		logger.Error("Failed to store tool setting", slog.Any("error", err))
+		os.Exit(1)
+	}
+	output, _ := json.MarshalIndent(setting, "", "  ")
+	fmt.Printf("Settings of %s:\n%s\n", toolName, output)
+}
+
+// applyToolSetting applies key=value assignments to a tool setting.
+// timeout, max_retries and retry_delay set the execution config; other
+// keys pin tool parameters.
+func applyToolSetting(setting *tool.ToolSetting, assignments []string) error {
+	for _, assignment := range assignments {
+		key, value, _ := strings.Cut(assignment, "=")
+		var err error
+		switch key {
+		case "timeout":
+			setting.Timeout, err = time.ParseDuration(value)
+		case "retry_delay":
+			setting.RetryDelay, err = time.ParseDuration(value)
+		case "max_retries":
+			setting.MaxRetries, err = strconv.Atoi(value)
+		default:
+			if setting.Custom == nil {
+				setting.Custom = make(map[string]interface{})
+			}
+			setting.Custom[key] = value
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// runIndex indexes a code base for retrieval, once or, with --watch, again
+// whenever its files change
+func runIndex(ctx context.Context, assistant *assistant.Assistant, args []string, logger *slog.Logger) {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	collection := flags.String("collection", indexer.DefaultCollection, "collection to index into")
+	watch := flags.Bool("watch", false, "keep indexing as files change")
+	interval := flags.Duration("interval", indexer.DefaultWatchInterval, "how often --watch polls for changes")
+	extensions := flags.String("ext", "", "comma-separated file extensions to index (default: common source files)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s index [--collection name] [--ext .go,.md] [--watch [--interval 2s]] <dir>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	root := flags.Arg(0)
+
+	options := indexer.Options{Collection: *collection}
+	if *extensions != "" {
+		for _, ext := range strings.Split(*extensions, ",") {
+			ext = strings.TrimSpace(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			options.Extensions = append(options.Extensions, strings.ToLower(ext))
+		}
+	}
+	ix, err := assistant.NewCodeIndexer(options)
+	if err != nil {
+		logger.Error("Failed to create code indexer", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	report := func(result *indexer.Result, err error) {
+		if err != nil {
+			logger.Error("Failed to index code", slog.Any("error", err))
+			return
+		}
+		fmt.Printf("Indexed %s into %s: %d files, %d changed, %d unchanged, %d removed, %d failed; %d chunks embedded, %d kept, %d deleted (%s)\n",
+			result.Root, result.Collection, result.Files, result.Indexed, result.Unchanged, result.Removed, result.Failed,
+			result.ChunksEmbedded, result.ChunksKept, result.ChunksDeleted, result.Duration.Round(time.Millisecond))
+	}
+
+	if !*watch {
+		result, err := ix.Index(ctx, root)
+		report(result, err)
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Watching %s, press Ctrl+C to stop\n", root)
+	if err := ix.Watch(ctx, root, *interval, report); err != nil {
+		logger.Error("Failed to watch code", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
 func runMigrate(ctx context.Context, cfg *config.Config, logger *slog.Logger, command string) {
 	// Initialize database connection for migration
 	client, err := postgres.NewClient(ctx, cfg.Database)
@@ -364,6 +680,15 @@ Commands:
   cli, interactive      Start interactive CLI mode
   ask <question>        Ask a direct question
   migrate <up|down|status>  Database migration commands
+  workflow list         List workflows
+  workflow run [--user <user_id>] <name> [key=value ...]  Run a workflow, recorded for the user
+  artifacts list <conversation_id>     List the artifacts of a conversation
+  artifacts open <id> [path]           Save an artifact to a file ("-" for stdout)
+  tools settings <user_id> [conversation_id]           Show tool settings
+  tools enable|disable <user_id> <tool> [conversation_id]  Enable or disable a tool ("*" for all)
+  tools set <user_id> <tool> [conversation_id] key=value ...  Set timeout, max_retries, retry_delay or pin parameters
+  tools reset <user_id> <tool> [conversation_id]       Remove a tool's settings
+  index [--collection name] [--ext .go,.md] [--watch] <dir>  Index a code base for retrieval
   version              Show version information
   help                 Show this help message
 
@@ -371,7 +696,8 @@ Examples:
   %s serve                           # Start API server
   %s cli                             # Start interactive CLI
   %s ask "Explain Go's memory model" # Ask direct question
+  %s workflow run dockerfile_review dockerfile_path=./Dockerfile
 
 For more information, visit: https://github.com/koopa0/assistant
-`, appName, cli.GetVersion(), os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+`, appName, cli.GetVersion(), os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
@@ -0,0 +1,55 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+)
+
+// Generator generates the embedding of a text with an AI provider
+type Generator interface {
+	GenerateEmbedding(ctx context.Context, text string, providerName string) (*ai.EmbeddingResponse, error)
+}
+
+// Embedder adapts an AI provider's embeddings to langchaingo's
+// embeddings.Embedder, so vector stores and indexers can use them
+type Embedder struct {
+	generator Generator
+	provider  string
+}
+
+// NewEmbedder creates an embedder that generates embeddings with the named
+// provider, or the generator's default provider when provider is empty
+func NewEmbedder(generator Generator, provider string) *Embedder {
+	return &Embedder{
+		generator: generator,
+		provider:  provider,
+	}
+}
+
+// EmbedDocuments embeds each text in turn
+func (e *Embedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for i, text := range texts {
+		vector, err := e.EmbedQuery(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed text %d: %w", i, err)
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+// EmbedQuery embeds a single text
+func (e *Embedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	resp, err := e.generator.GenerateEmbedding(ctx, text, e.provider)
+	if err != nil {
+		return nil, err
+	}
+	vector := make([]float32, len(resp.Embedding))
+	for i, v := range resp.Embedding {
+		vector[i] = float32(v)
+	}
+	return vector, nil
+}
@@ -92,8 +92,8 @@ func (s *Service) GenerateEmbedding(ctx context.Context, text string) (*ai.Embed
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
-	// Validate embedding dimensions
-	if len(response.Embedding) != s.config.Dimensions {
+	// Validate embedding dimensions, when configured
+	if s.config.Dimensions > 0 && len(response.Embedding) != s.config.Dimensions {
 		s.logger.Warn("Embedding dimension mismatch",
 			slog.Int("expected", s.config.Dimensions),
 			slog.Int("actual", len(response.Embedding)))
@@ -0,0 +1,180 @@
+// Package local provides an embedding provider that runs in process,
+// without an API key or network access, for development and tests.
+//
+// Texts are embedded with the hashing trick: their words, word bigrams and
+// character trigrams are hashed into a fixed number of dimensions with
+// sublinear term frequencies, and the vector is normalized to unit length.
+// Embeddings are deterministic, and texts sharing words and word parts are
+// similar, which is good enough to exercise retrieval end to end but no
+// substitute for a trained model.
+package local
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// Provider is the name of the local embedding provider
+	Provider = "local"
+
+	// Model names the local embedding model
+	Model = "hashed-ngrams"
+
+	// DefaultDimensions is the dimension count of embedders created
+	// without one
+	DefaultDimensions = 384
+)
+
+// Feature weights; words count more than the bigrams and trigrams that
+// make related phrasings and word forms similar
+const (
+	wordWeight    = 1.0
+	bigramWeight  = 0.5
+	trigramWeight = 0.3
+)
+
+// EmbeddingResponse represents an embedding response
+type EmbeddingResponse struct {
+	Embedding    []float64     `json:"embedding"`
+	Model        string        `json:"model"`
+	Provider     string        `json:"provider"`
+	TokensUsed   int           `json:"tokens_used"`
+	ResponseTime time.Duration `json:"response_time"`
+}
+
+// Embedder embeds texts into vectors of a fixed dimension count. It
+// implements langchaingo's embeddings.Embedder and is safe for concurrent
+// use.
+type Embedder struct {
+	dimensions int
+}
+
+// NewEmbedder creates an embedder of the given dimension count, or of
+// DefaultDimensions when dimensions is not positive
+func NewEmbedder(dimensions int) *Embedder {
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	return &Embedder{dimensions: dimensions}
+}
+
+// Dimensions returns the dimension count of the embeddings
+func (e *Embedder) Dimensions() int {
+	return e.dimensions
+}
+
+// GenerateEmbedding embeds a text
+func (e *Embedder) GenerateEmbedding(ctx context.Context, text string) (*EmbeddingResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	vector, words := e.embed(text)
+	return &EmbeddingResponse{
+		Embedding:    vector,
+		Model:        Model,
+		Provider:     Provider,
+		TokensUsed:   words,
+		ResponseTime: time.Since(startTime),
+	}, nil
+}
+
+// Embed embeds a text. A text without words embeds to the zero vector.
+func (e *Embedder) Embed(text string) []float64 {
+	vector, _ := e.embed(text)
+	return vector
+}
+
+// EmbedDocuments embeds each text
+func (e *Embedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector, err := e.EmbedQuery(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+// EmbedQuery embeds a single text
+func (e *Embedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	embedding := e.Embed(text)
+	vector := make([]float32, len(embedding))
+	for i, v := range embedding {
+		vector[i] = float32(v)
+	}
+	return vector, nil
+}
+
+// embed returns the embedding of a text and its word count
+func (e *Embedder) embed(text string) ([]float64, int) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	// Term frequencies of the features, keyed by kind and text
+	type key struct {
+		weight float64
+		text   string
+	}
+	counts := make(map[key]int)
+	var order []key // sums in a fixed order for identical vectors
+	add := func(feature key) {
+		if counts[feature] == 0 {
+			order = append(order, feature)
+		}
+		counts[feature]++
+	}
+	for i, word := range words {
+		add(key{wordWeight, "w:" + word})
+		if i > 0 {
+			add(key{bigramWeight, "b:" + words[i-1] + " " + word})
+		}
+		runes := []rune("^" + word + "$")
+		for j := 0; j+3 <= len(runes); j++ {
+			add(key{trigramWeight, "c:" + string(runes[j:j+3])})
+		}
+	}
+
+	vector := make([]float64, e.dimensions)
+	for _, feature := range order {
+		index, sign := e.bucket(feature.text)
+		// Sublinear frequency keeps repeated terms from dominating
+		vector[index] += sign * feature.weight * (1 + math.Log(float64(counts[feature])))
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+	return vector, len(words)
+}
+
+// bucket returns the dimension a feature hashes to and its sign, which
+// keeps colliding features from adding up on average
+func (e *Embedder) bucket(feature string) (int, float64) {
+	hash := fnv.New64a()
+	hash.Write([]byte(feature))
+	sum := hash.Sum64()
+	sign := 1.0
+	if sum>>63 == 1 {
+		sign = -1
+	}
+	return int(sum % uint64(e.dimensions)), sign
+}
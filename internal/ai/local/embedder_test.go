@@ -0,0 +1,76 @@
+package local
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+var _ embeddings.Embedder = (*Embedder)(nil)
+
+func cosine(a, b []float64) float64 {
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+func TestEmbedder_Embed(t *testing.T) {
+	embedder := NewEmbedder(256)
+
+	first := embedder.Embed("Goroutines communicate over channels")
+	if len(first) != 256 {
+		t.Fatalf("len(Embed()) = %d, want 256", len(first))
+	}
+	if again := NewEmbedder(256).Embed("Goroutines communicate over channels"); !slices.Equal(first, again) {
+		t.Error("Embed() is not deterministic")
+	}
+	if norm := math.Sqrt(cosine(first, first)); math.Abs(norm-1) > 1e-9 {
+		t.Errorf("norm = %f, want 1", norm)
+	}
+
+	related := embedder.Embed("goroutine channel communication")
+	unrelated := embedder.Embed("the recipe needs flour and sugar")
+	if cosine(first, related) <= cosine(first, unrelated) {
+		t.Errorf("similarity to related text %f <= to unrelated text %f", cosine(first, related), cosine(first, unrelated))
+	}
+
+	for _, v := range embedder.Embed(" ,.; ") {
+		if v != 0 {
+			t.Fatalf("Embed() of text without words = %v, want zero vector", v)
+		}
+	}
+}
+
+func TestEmbedder_Dimensions(t *testing.T) {
+	if got := NewEmbedder(0).Dimensions(); got != DefaultDimensions {
+		t.Errorf("NewEmbedder(0).Dimensions() = %d, want %d", got, DefaultDimensions)
+	}
+
+	embedder := NewEmbedder(8)
+	vectors, err := embedder.EmbedDocuments(context.Background(), []string{"one", "two words"})
+	if err != nil {
+		t.Fatalf("EmbedDocuments() error = %v", err)
+	}
+	if len(vectors) != 2 || len(vectors[0]) != 8 || len(vectors[1]) != 8 {
+		t.Errorf("EmbedDocuments() returned %d vectors, want 2 of 8 dimensions", len(vectors))
+	}
+
+	resp, err := embedder.GenerateEmbedding(context.Background(), "two words")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding() error = %v", err)
+	}
+	if resp.Provider != Provider || resp.Model != Model || resp.TokensUsed != 2 || len(resp.Embedding) != 8 {
+		t.Errorf("GenerateEmbedding() = %+v", resp)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := embedder.EmbedQuery(ctx, "cancelled"); err == nil {
+		t.Error("EmbedQuery() with a cancelled context succeeded")
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/koopa0/assistant-go/internal/ai/claude"
 	"github.com/koopa0/assistant-go/internal/ai/gemini"
+	"github.com/koopa0/assistant-go/internal/ai/local"
 	"github.com/koopa0/assistant-go/internal/ai/prompt"
 	"github.com/koopa0/assistant-go/internal/config"
 )
@@ -20,6 +21,7 @@ import (
 type Service struct {
 	claudeClient    *claude.Client
 	geminiClient    *gemini.Client
+	localEmbedder   *local.Embedder
 	promptService   *prompt.PromptService
 	defaultProvider string
 	logger          *slog.Logger
@@ -37,6 +39,7 @@ func NewService(cfg *config.Config, logger *slog.Logger) (*Service, error) {
 
 	svc := &Service{
 		defaultProvider: cfg.AI.DefaultProvider,
+		localEmbedder:   local.NewEmbedder(cfg.AI.Embeddings.Dimensions),
 		logger:          logger,
 		promptService:   prompt.NewPromptService(logger),
 	}
@@ -174,6 +177,12 @@ func (s *Service) GenerateEmbedding(ctx context.Context, text string, providerNa
 			return nil, err
 		}
 		return convertGeminiEmbeddingResponse(resp), nil
+	case local.Provider:
+		resp, err := s.localEmbedder.GenerateEmbedding(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		return convertLocalEmbeddingResponse(resp), nil
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", provider)
 	}
@@ -191,6 +200,12 @@ func (s *Service) GetAvailableProviders() []string {
 	return providers
 }
 
+// GetEmbeddingProviders returns the providers that generate embeddings:
+// the available providers and the local embedder, which needs no API key
+func (s *Service) GetEmbeddingProviders() []string {
+	return append(s.GetAvailableProviders(), local.Provider)
+}
+
 // GetDefaultProvider returns the default provider name
 func (s *Service) GetDefaultProvider() string {
 	return s.defaultProvider
@@ -422,6 +437,16 @@ func convertGeminiEmbeddingResponse(resp *gemini.EmbeddingResponse) *EmbeddingRe
 	}
 }
 
+func convertLocalEmbeddingResponse(resp *local.EmbeddingResponse) *EmbeddingResponse {
+	return &EmbeddingResponse{
+		Embedding:    resp.Embedding,
+		Model:        resp.Model,
+		Provider:     resp.Provider,
+		TokensUsed:   resp.TokensUsed,
+		ResponseTime: resp.ResponseTime,
+	}
+}
+
 func convertClaudeUsageStats(stats *claude.UsageStats) *UsageStats {
 	return &UsageStats{
 		TotalRequests:   stats.TotalRequests,
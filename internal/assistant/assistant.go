@@ -6,16 +6,33 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"slices"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/embeddings"
+
+	"github.com/koopa0/assistant-go/internal/ai/embedding"
 	"github.com/koopa0/assistant-go/internal/config"
 	"github.com/koopa0/assistant-go/internal/conversation"
 	"github.com/koopa0/assistant-go/internal/langchain"
+	"github.com/koopa0/assistant-go/internal/langchain/collection"
+	"github.com/koopa0/assistant-go/internal/langchain/indexer"
+	"github.com/koopa0/assistant-go/internal/langchain/router"
+	"github.com/koopa0/assistant-go/internal/langchain/vectorstore"
+	"github.com/koopa0/assistant-go/internal/platform/event"
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
 	"github.com/koopa0/assistant-go/internal/tool"
+	"github.com/koopa0/assistant-go/internal/tool/artifact"
 	"github.com/koopa0/assistant-go/internal/tool/docker"
 	"github.com/koopa0/assistant-go/internal/tool/godev"
+	"github.com/koopa0/assistant-go/internal/tool/k8s"
+	"github.com/koopa0/assistant-go/internal/tool/openapi"
+	"github.com/koopa0/assistant-go/internal/tool/plugin"
 	postgrestool "github.com/koopa0/assistant-go/internal/tool/postgres"
+	"github.com/koopa0/assistant-go/internal/tool/search"
+	"github.com/koopa0/assistant-go/internal/tool/workflow"
 )
 
 // Assistant is the core orchestrator of the intelligent development companion.
@@ -39,6 +56,15 @@ type Assistant struct {
 	processor        *Processor                       // Request processing pipeline
 	conversationMgr  conversation.ConversationService // Conversation service
 	langchainService *langchain.Service               // LangChain integration service
+	openapi          *openapi.Manager                 // Tools generated from OpenAPI specs, nil without specs
+	plugins          *plugin.Manager                  // External plugin tools, nil without a plugins directory
+	workflows        *workflow.Manager                // YAML workflows, nil without a workflows directory
+	events           *event.EventBus                  // System events such as tool health changes
+	supervisor       *tool.Supervisor                 // Background tool health checks, nil when disabled
+	artifacts        *artifact.Store                  // Stored tool artifacts, nil without an artifacts directory
+	toolSettings     *tool.SettingsResolver           // Tool enablement and config by role, user and conversation
+	router           *router.Router                   // Routes queries to agents, chains and tools
+	collections      *collection.Manager              // Knowledge-base collections, nil without a database
 }
 
 // QueryRequest represents a comprehensive query request to the Assistant.
@@ -105,6 +131,9 @@ type QueryResponse struct {
 	// ToolsUsed lists any tools that were executed during processing
 	ToolsUsed []string `json:"tools_used,omitempty"`
 
+	// Citations lists the sources tools drew their results from
+	Citations []tool.Citation `json:"citations,omitempty"`
+
 	// Context contains additional metadata about the processing
 	// TODO: Replace with typed ResponseContext struct
 	Context map[string]any `json:"context,omitempty"`
@@ -174,6 +203,7 @@ func New(ctx context.Context, cfg *config.Config, db postgres.DB, logger *slog.L
 
 		if langchainClient != nil {
 			langchainService = langchain.NewService(langchainClient, logger, db.GetQueries())
+			langchainService.UseTools(registry)
 			logger.Info("LangChain service initialized successfully")
 		} else {
 			logger.Warn("LangChain service not initialized - no AI provider configured")
@@ -195,6 +225,56 @@ func New(ctx context.Context, cfg *config.Config, db postgres.DB, logger *slog.L
 		return nil, fmt.Errorf("failed to register builtin tools: %w", err)
 	}
 
+	// Start the event bus and the tool health supervisor
+	if err := assistant.startSupervision(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start tool supervision: %w", err)
+	}
+
+	// Open the artifact store tools write their files into
+	if err := assistant.openArtifacts(); err != nil {
+		return nil, fmt.Errorf("failed to open artifact store: %w", err)
+	}
+
+	// Resolve tool availability per role, user and conversation
+	var settingsStore tool.SettingsStore
+	if queries := db.GetQueries(); queries != nil {
+		settingsStore = tool.NewQueriesSettingsStore(queries)
+	}
+	assistant.toolSettings = tool.NewSettingsResolver(cfg.Tools.Roles, settingsStore, logger)
+	assistant.processor.toolSettings = assistant.toolSettings
+	if langchainService != nil {
+		langchainService.UseToolSettings(assistant.toolSettings)
+		if assistant.artifacts != nil {
+			langchainService.UseToolResults(assistant.persistAgentArtifacts)
+		}
+	}
+
+	// Route queries to agents, chains and tools with a cheap model call
+	assistant.router = assistant.newRouter()
+	assistant.processor.router = assistant.router
+	if langchainService != nil {
+		langchainService.UseRouter(assistant.router)
+		if service := processor.aiService; service != nil {
+			langchainService.UseEmbedder(embedding.NewEmbedder(service, cfg.AI.Embeddings.Provider))
+		}
+	}
+
+	// Scope RAG retrieval to the knowledge-base collections callers may read
+	assistant.collections = assistant.newCollections()
+	if langchainService != nil && assistant.collections != nil {
+		langchainService.UseCollections(assistant.collections)
+	}
+	if assistant.collections != nil {
+		// Code indexes are stored in the default collection with the
+		// configured model, which it adopts unless it has another
+		if err := assistant.collections.AdoptModel(ctx, collection.DefaultID, cfg.AI.Embeddings.Model); err != nil {
+			logger.Warn("Failed to adopt the embedding model", slog.Any("error", err))
+		}
+		if err := assistant.collections.Resume(ctx); err != nil {
+			logger.Warn("Failed to resume re-embedding jobs", slog.Any("error", err))
+		}
+	}
+
 	logger.Info("Assistant initialized successfully",
 		slog.String("mode", cfg.Mode),
 		slog.String("default_provider", cfg.AI.DefaultProvider))
@@ -305,7 +385,21 @@ func (a *Assistant) DeleteConversation(ctx context.Context, conversationID strin
 
 // GetAvailableTools returns a list of available tools
 func (a *Assistant) GetAvailableTools() []Tool {
-	toolInfos := a.registry.ListTools()
+	return toolsFromInfo(a.registry.ListTools())
+}
+
+// ListToolsFor returns the tools available to a user, and within a
+// conversation when conversationID is set, favorites first
+func (a *Assistant) ListToolsFor(ctx context.Context, userID, conversationID string) ([]Tool, error) {
+	policy, err := a.processor.resolveToolPolicy(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return toolsFromInfo(policy.Filter(a.registry.ListTools())), nil
+}
+
+// toolsFromInfo converts registry tool info for the API
+func toolsFromInfo(toolInfos []tool.ToolInfo) []Tool {
 	tools := make([]Tool, 0, len(toolInfos))
 	for _, info := range toolInfos {
 		// Convert tool info to Tool struct
@@ -345,6 +439,92 @@ func (a *Assistant) GetAvailableTools() []Tool {
 	return tools
 }
 
+// ToolSettingsView shows the stored tool settings of a user and
+// conversation and the settings in effect for each registered tool
+type ToolSettingsView struct {
+	User         tool.ToolSettings           `json:"user"`
+	Conversation tool.ToolSettings           `json:"conversation,omitempty"`
+	Effective    map[string]tool.ToolSetting `json:"effective"`
+}
+
+// GetToolSettings returns the stored tool settings of a user, or of one
+// of their conversations, with the settings in effect for every tool
+func (a *Assistant) GetToolSettings(ctx context.Context, userID, conversationID string) (*ToolSettingsView, error) {
+	store, err := a.toolSettingsStore(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &ToolSettingsView{
+		Effective: make(map[string]tool.ToolSetting),
+	}
+	if view.User, err = store.Settings(ctx, userID, ""); err != nil {
+		return nil, err
+	}
+	if conversationID != "" {
+		if view.Conversation, err = store.Settings(ctx, userID, conversationID); err != nil {
+			return nil, err
+		}
+	}
+
+	policy, err := a.processor.resolveToolPolicy(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range a.registry.ListTools() {
+		setting := policy.Setting(info.Name)
+		enabled := policy.Enabled(info.Name)
+		setting.Enabled = &enabled
+		view.Effective[info.Name] = setting
+	}
+	return view, nil
+}
+
+// SetToolSetting stores the setting of a tool, or of every tool when name
+// is tool.AllTools, for a user or one of their conversations
+func (a *Assistant) SetToolSetting(ctx context.Context, userID, conversationID, name string, setting tool.ToolSetting) error {
+	store, err := a.toolSettingsStore(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	if name != tool.AllTools && !a.registry.IsRegistered(name) {
+		return tool.NewToolNotRegisteredError(name)
+	}
+	return store.SetSetting(ctx, userID, conversationID, name, setting)
+}
+
+// DeleteToolSetting removes the setting of a tool for a user or one of
+// their conversations
+func (a *Assistant) DeleteToolSetting(ctx context.Context, userID, conversationID, name string) error {
+	store, err := a.toolSettingsStore(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	return store.DeleteSetting(ctx, userID, conversationID, name)
+}
+
+// toolSettingsStore returns the settings store after checking that the
+// conversation, if any, belongs to the user
+func (a *Assistant) toolSettingsStore(ctx context.Context, userID, conversationID string) (tool.SettingsStore, error) {
+	if userID == "" {
+		return nil, NewAssistantInvalidInputError("user_id is required", userID)
+	}
+	if a.toolSettings == nil || a.toolSettings.Store() == nil {
+		return nil, NewAssistantInvalidInputError("tool settings require a database", userID)
+	}
+	store := a.toolSettings.Store()
+	if conversationID != "" {
+		conv, err := a.conversationMgr.GetConversation(ctx, conversationID)
+		if err != nil {
+			return nil, err
+		}
+		if conv.UserID != userID {
+			return nil, NewAssistantInvalidInputError("conversation belongs to another user", conversationID)
+		}
+	}
+	return store, nil
+}
+
 // GetToolInfo returns information about a specific tool
 func (a *Assistant) GetToolInfo(toolName string) (*tool.ToolInfo, error) {
 	return a.registry.GetToolInfo(toolName)
@@ -412,11 +592,38 @@ func (a *Assistant) Close(ctx context.Context) error {
 
 	// Note: conversation manager doesn't require explicit close
 
+	// Stop watching for plugin changes before the registry stops the plugins
+	if a.plugins != nil {
+		if err := a.plugins.Close(); err != nil {
+			a.logger.Error("Failed to close plugin manager", slog.Any("error", err))
+		}
+	}
+
+	// Stop health checks before the tools they probe are closed
+	if a.supervisor != nil {
+		a.supervisor.Close()
+	}
+
 	// Close tool registry
 	if err := a.registry.Close(ctx); err != nil {
 		a.logger.Error("Failed to close tool registry", slog.Any("error", err))
 	}
 
+	if a.artifacts != nil {
+		a.artifacts.Close()
+	}
+
+	// Stop running ingestion jobs
+	if a.collections != nil {
+		a.collections.Close()
+	}
+
+	if a.events != nil {
+		if err := a.events.Stop(ctx); err != nil {
+			a.logger.Error("Failed to stop event bus", slog.Any("error", err))
+		}
+	}
+
 	a.logger.Info("Assistant shutdown complete")
 	return nil
 }
@@ -438,25 +645,344 @@ func (a *Assistant) registerBuiltinTools(ctx context.Context) error {
 
 	// Register Docker tool factory
 	dockerFactory := func(cfg *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
-		return docker.NewDockerTool(logger), nil
+		return docker.NewDockerTool(a.config.Tools.Docker, logger), nil
 	}
 	if err := a.registry.Register("docker", dockerFactory); err != nil {
 		return fmt.Errorf("failed to register docker tool: %w", err)
 	}
 
+	// Register Kubernetes tool factory
+	kubernetesFactory := func(cfg *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
+		return k8s.NewKubernetesTool(a.config.Tools.Kubernetes, logger), nil
+	}
+	if err := a.registry.Register("kubernetes", kubernetesFactory); err != nil {
+		return fmt.Errorf("failed to register kubernetes tool: %w", err)
+	}
+
 	// Register PostgreSQL tool factory
 	postgresFactory := func(cfg *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
-		return postgrestool.NewPostgresTool(logger), nil
+		// Named connections come from the database_connections table when available
+		var store postgrestool.ConnectionStore
+		if queries := a.db.GetQueries(); queries != nil {
+			store = postgrestool.NewQueriesConnectionStore(queries)
+		}
+		return postgrestool.NewPostgresTool(a.config.Tools.Postgres, store, logger), nil
 	}
 	if err := a.registry.Register("postgres", postgresFactory); err != nil {
 		return fmt.Errorf("failed to register postgres tool: %w", err)
 	}
 
+	// Register web search tool factory
+	searchFactory := func(cfg *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
+		// Responses are cached in the search_cache table when available
+		var cache search.Cache
+		if queries := a.db.GetQueries(); queries != nil {
+			cache = search.NewQueriesCache(queries)
+		}
+		return search.NewWebSearchTool(a.config.Tools.Search, cache, logger), nil
+	}
+	if err := a.registry.Register("web_search", searchFactory); err != nil {
+		return fmt.Errorf("failed to register web_search tool: %w", err)
+	}
+
 	a.logger.Debug("Built-in tools registered successfully",
-		slog.Int("count", 3))
+		slog.Int("count", 5))
+
+	// Register tools generated from OpenAPI specs; a spec that cannot be
+	// loaded is reported and can be reloaded later
+	if specs := a.config.Tools.OpenAPI.Specs; len(specs) > 0 {
+		a.openapi = openapi.NewManager(specs, a.registry, a.logger)
+		if err := a.openapi.Reload(ctx, ""); err != nil {
+			a.logger.Warn("Some OpenAPI specs failed to load", slog.Any("error", err))
+		}
+	}
+
+	// Register external plugin tools and watch the directory for changes
+	if cfg := a.config.Tools.Plugins; cfg.Dir != "" {
+		a.plugins = plugin.NewManager(cfg, a.registry, a.logger)
+		if err := a.plugins.Scan(ctx); err != nil {
+			a.logger.Warn("Some plugins failed to load", slog.Any("error", err))
+		}
+		a.plugins.Start()
+	}
+
+	// Register workflows last so their steps can use every other tool
+	if cfg := a.config.Tools.Workflows; cfg.Dir != "" {
+		var store workflow.RunStore
+		if queries := a.db.GetQueries(); queries != nil {
+			store = workflow.NewQueriesStore(queries)
+		}
+		engine := workflow.NewEngine(a.registry, a.processor.aiService, store, cfg, a.logger)
+		a.workflows = workflow.NewManager(cfg.Dir, engine, a.registry, a.logger)
+		if err := a.workflows.Reload(); err != nil {
+			a.logger.Warn("Some workflows failed to load", slog.Any("error", err))
+		}
+	}
 	return nil
 }
 
+// startSupervision starts the event bus and, unless disabled, the tool
+// health supervisor that publishes to it
+func (a *Assistant) startSupervision(ctx context.Context) error {
+	events, err := event.NewEventBus(event.EventBusConfig{
+		BufferSize:      100,
+		MaxConcurrency:  10,
+		EnableMetrics:   true,
+		AsyncProcessing: true,
+	}, a.logger)
+	if err != nil {
+		return err
+	}
+	if err := events.Start(ctx); err != nil {
+		return err
+	}
+	a.events = events
+
+	if cfg := a.config.Tools.Health; cfg.Interval >= 0 {
+		supervisor, err := tool.NewSupervisor(a.registry, cfg, events, a.logger)
+		if err != nil {
+			return err
+		}
+		supervisor.Start()
+		a.supervisor = supervisor
+	}
+	return nil
+}
+
+// Events returns the event bus for subscribing to system events
+func (a *Assistant) Events() event.EventBusService {
+	return a.events
+}
+
+// GetToolHealth returns the supervised health of the instantiated tools
+func (a *Assistant) GetToolHealth() ([]tool.ToolHealth, error) {
+	if a.supervisor == nil {
+		return nil, NewAssistantInvalidInputError("tool health supervision is disabled", nil)
+	}
+	return a.supervisor.Status(), nil
+}
+
+// openArtifacts opens the artifact store when an artifacts directory is
+// configured and starts removing expired artifacts
+func (a *Assistant) openArtifacts() error {
+	cfg := a.config.Tools.Artifacts
+	if cfg.Dir == "" {
+		return nil
+	}
+	queries := a.db.GetQueries()
+	if queries == nil {
+		a.logger.Warn("Artifact store disabled: no database queries available")
+		return nil
+	}
+
+	store, err := artifact.NewStore(cfg, queries, a.logger)
+	if err != nil {
+		return err
+	}
+	store.Start()
+	a.artifacts = store
+	a.processor.artifacts = store
+	return nil
+}
+
+// persistAgentArtifacts stores the artifacts of an agent's tool call for
+// the user and conversation the agent ran for
+func (a *Assistant) persistAgentArtifacts(ctx context.Context, name string, toolContext *tool.ToolContext, result *tool.ToolResult) {
+	links := artifact.Links{ToolName: name}
+	if toolContext != nil {
+		links.UserID = toolContext.UserID
+		links.ConversationID = toolContext.ConversationID
+	}
+	if err := a.artifacts.Persist(ctx, result, links); err != nil {
+		a.logger.Warn("Failed to store tool artifacts",
+			slog.String("tool", name),
+			slog.Any("error", err))
+	}
+}
+
+// ListArtifacts returns the stored artifacts of a conversation
+func (a *Assistant) ListArtifacts(ctx context.Context, conversationID string) ([]*artifact.Artifact, error) {
+	if a.artifacts == nil {
+		return nil, NewAssistantInvalidInputError("no artifacts directory is configured", conversationID)
+	}
+	return a.artifacts.ListByConversation(ctx, conversationID)
+}
+
+// ListMessageArtifacts returns the stored artifacts linked to a message
+func (a *Assistant) ListMessageArtifacts(ctx context.Context, messageID string) ([]*artifact.Artifact, error) {
+	if a.artifacts == nil {
+		return nil, NewAssistantInvalidInputError("no artifacts directory is configured", messageID)
+	}
+	return a.artifacts.ListByMessage(ctx, messageID)
+}
+
+// OpenArtifact returns a stored artifact and its content. The caller
+// closes the file.
+func (a *Assistant) OpenArtifact(ctx context.Context, id string) (*artifact.Artifact, *os.File, error) {
+	if a.artifacts == nil {
+		return nil, nil, NewAssistantInvalidInputError("no artifacts directory is configured", id)
+	}
+	return a.artifacts.Open(ctx, id)
+}
+
+// newRouter creates the query router. The AI service classifies queries
+// when a provider is available and embeds them for the decision cache;
+// decisions are recorded as learning events.
+func (a *Assistant) newRouter() *router.Router {
+	var classifier router.Classifier
+	var embedder router.Embedder
+	if service := a.processor.aiService; service != nil && len(service.GetAvailableProviders()) > 0 {
+		classifier, embedder = service, service
+	}
+	var store router.Store
+	if queries := a.db.GetQueries(); queries != nil {
+		store = router.NewQueriesStore(queries)
+	}
+	return router.New(a.config.AI, classifier, embedder, store, a.logger)
+}
+
+// newCollections creates the knowledge-base collection manager. Chunks are
+// embedded with each collection's provider, or the configured embedding
+// provider for collections without one.
+func (a *Assistant) newCollections() *collection.Manager {
+	queries := a.db.GetQueries()
+	if queries == nil {
+		return nil
+	}
+
+	service := a.processor.aiService
+	embedderFor := func(provider string) embeddings.Embedder {
+		if service == nil || provider != "" && !slices.Contains(service.GetEmbeddingProviders(), provider) {
+			return nil
+		}
+		if provider == "" {
+			provider = a.config.AI.Embeddings.Provider
+		}
+		return embedding.NewEmbedder(service, provider)
+	}
+
+	retrieval := a.config.Tools.LangChain.Retrieval
+	options := vectorstore.SearchOptionsFromConfig(retrieval)
+	options.Reranker = vectorstore.NewReranker(retrieval.Reranker, retrieval.RerankerURL, nil)
+	manager := collection.NewManager(collection.NewQueriesStore(queries), queries, embedderFor, options,
+		a.config.Tools.LangChain.Collections, a.logger)
+
+	// Re-embedding jobs batch their requests through the embedding service
+	if client, ok := a.db.(*postgres.Client); ok && service != nil {
+		manager.UseBatchEmbedders(func(provider, model string) collection.BatchEmbedder {
+			if embedderFor(provider) == nil {
+				return nil
+			}
+			if provider == "" {
+				provider = a.config.AI.Embeddings.Provider
+			}
+			batcher, err := embedding.NewService(service, client, config.Embedding{Provider: provider, Model: model}, a.logger)
+			if err != nil {
+				return nil
+			}
+			return batcher
+		})
+	}
+	return manager
+}
+
+// Collections returns the knowledge-base collection manager, or nil
+// without a database
+func (a *Assistant) Collections() *collection.Manager {
+	return a.collections
+}
+
+// RouteQuery decides the agent, chain and tools for a user's query among
+// the tools enabled for them
+func (a *Assistant) RouteQuery(ctx context.Context, userID, conversationID, query string) (*router.Decision, error) {
+	if query == "" {
+		return nil, NewAssistantEmptyInputError()
+	}
+	request := &QueryRequest{Query: query, UserID: &userID}
+	if conversationID != "" {
+		request.ConversationID = &conversationID
+	}
+	return a.processor.routeQuery(ctx, request), nil
+}
+
+// RecordRoutingOutcome stores a user's feedback on a routing decision
+func (a *Assistant) RecordRoutingOutcome(ctx context.Context, userID, decisionID string, outcome router.Outcome) error {
+	if err := outcome.Validate(); err != nil {
+		return NewAssistantInvalidInputError(err.Error(), outcome)
+	}
+	if a.router == nil {
+		return router.ErrDecisionNotFound
+	}
+	return a.router.RecordOutcome(ctx, userID, decisionID, outcome)
+}
+
+// RoutingAccuracy reports routing accuracy by source and agent type since
+// a time, for one user or, with an empty user id, for everyone
+func (a *Assistant) RoutingAccuracy(ctx context.Context, userID string, since time.Time) ([]router.Accuracy, error) {
+	if a.router == nil {
+		return []router.Accuracy{}, nil
+	}
+	return a.router.Accuracy(ctx, userID, since)
+}
+
+// NewCodeIndexer creates an indexer that embeds code bases with the
+// configured embedding provider into a collection
+func (a *Assistant) NewCodeIndexer(options indexer.Options) (*indexer.Indexer, error) {
+	service := a.processor.aiService
+	if service == nil {
+		return nil, NewAssistantInvalidInputError("no AI provider is configured for embeddings", options.Collection)
+	}
+	queries := a.db.GetQueries()
+	if queries == nil {
+		return nil, NewAssistantInvalidInputError("no database queries available for the code index", options.Collection)
+	}
+	embedder := embedding.NewEmbedder(service, a.config.AI.Embeddings.Provider)
+	store := indexer.NewQueriesStore(queries, uuid.MustParse(collection.DefaultID), a.config.AI.Embeddings.Model)
+	return indexer.New(store, embedder, options, a.logger), nil
+}
+
+// ReloadOpenAPITools reloads the named OpenAPI spec, or all of them when
+// name is empty, replacing its tools in the registry. It returns the tool
+// names by spec after the reload.
+func (a *Assistant) ReloadOpenAPITools(ctx context.Context, name string) (map[string][]string, error) {
+	if a.openapi == nil {
+		return nil, NewAssistantInvalidInputError("no OpenAPI specs are configured", name)
+	}
+	err := a.openapi.Reload(ctx, name)
+	return a.openapi.Tools(), err
+}
+
+// ListWorkflows returns the loaded workflows
+func (a *Assistant) ListWorkflows() ([]*workflow.Definition, error) {
+	if a.workflows == nil {
+		return nil, NewAssistantInvalidInputError("no workflows directory is configured", nil)
+	}
+	return a.workflows.List(), nil
+}
+
+// RunWorkflow runs the named workflow for a user. A failed step does not
+// return an error; it is reported in the run.
+func (a *Assistant) RunWorkflow(ctx context.Context, name string, inputs map[string]interface{}, userID string) (*workflow.Run, error) {
+	if a.workflows == nil {
+		return nil, NewAssistantInvalidInputError("no workflows directory is configured", name)
+	}
+	def, err := a.workflows.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return a.workflows.Engine().Run(ctx, def, inputs, &tool.ToolContext{UserID: userID})
+}
+
+// ReloadWorkflows reloads the workflows directory and returns the loaded
+// workflows
+func (a *Assistant) ReloadWorkflows() ([]*workflow.Definition, error) {
+	if a.workflows == nil {
+		return nil, NewAssistantInvalidInputError("no workflows directory is configured", nil)
+	}
+	err := a.workflows.Reload()
+	return a.workflows.List(), err
+}
+
 // AssistantStats represents comprehensive statistics for the assistant
 type AssistantStats struct {
 	// Database contains database connection pool statistics
@@ -642,12 +1168,37 @@ func (a *Assistant) ExecuteTool(ctx context.Context, req *ToolExecutionRequest)
 		Parameters: req.Input,
 	}
 
-	toolConfig := &tool.ToolConfig{}
+	var toolConfig *tool.ToolConfig
 	if req.Config != nil {
 		// Convert config map to ToolConfig
 		toolConfig = tool.ConvertLegacyConfig(req.Config)
 	}
 
+	// Apply the caller's tool settings; their pinned parameters take
+	// precedence over the request
+	userID, _ := ctx.Value("user_id").(string)
+	var conversationID string
+	if req.Context != nil {
+		if userID == "" {
+			userID = req.Context.UserID
+		}
+		conversationID = req.Context.ConversationID
+	}
+	toolInput.Context = &tool.ToolContext{UserID: userID, ConversationID: conversationID}
+	if req.Context != nil {
+		toolInput.Context.SessionID = req.Context.SessionID
+		toolInput.Context.RequestID = req.Context.RequestID
+		toolInput.Context.Metadata = req.Context.Metadata
+	}
+	policy, err := a.processor.resolveToolPolicy(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled(req.ToolName) {
+		return nil, tool.NewToolPermissionDeniedError(req.ToolName, "execute", "tool settings")
+	}
+	toolConfig = tool.MergeConfig(toolConfig, policy.Config(req.ToolName))
+
 	result, err := a.registry.Execute(ctx, req.ToolName, toolInput, toolConfig)
 	if err != nil {
 		a.logger.Error("Tool execution failed",
@@ -661,6 +1212,17 @@ func (a *Assistant) ExecuteTool(ctx context.Context, req *ToolExecutionRequest)
 		slog.Bool("success", result.Success),
 		slog.Duration("execution_time", result.ExecutionTime))
 
+	// Store artifacts for the authenticated user rather than returning them inline
+	if a.artifacts != nil {
+		links := artifact.Links{ToolName: req.ToolName}
+		links.UserID, _ = ctx.Value("user_id").(string)
+		if err := a.artifacts.Persist(ctx, result, links); err != nil {
+			a.logger.Warn("Failed to store tool artifacts",
+				slog.String("tool", req.ToolName),
+				slog.Any("error", err))
+		}
+	}
+
 	// Convert tool.ToolResult to ToolExecutionResponse
 	executionResp := &ToolExecutionResponse{
 		Success:       result.Success,
@@ -680,6 +1242,7 @@ func (a *Assistant) ExecuteTool(ctx context.Context, req *ToolExecutionRequest)
 			executionResp.Data.Artifacts = make([]ToolArtifact, 0, len(result.Data.Artifacts))
 			for _, artifact := range result.Data.Artifacts {
 				executionResp.Data.Artifacts = append(executionResp.Data.Artifacts, ToolArtifact{
+					ID:          artifact.ID,
 					Name:        artifact.Name,
 					Type:        artifact.Type,
 					Content:     artifact.Content,
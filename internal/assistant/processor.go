@@ -4,18 +4,24 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
+
 	"github.com/koopa0/assistant-go/internal/ai"
 	aierrors "github.com/koopa0/assistant-go/internal/ai"
 	"github.com/koopa0/assistant-go/internal/config"
 	"github.com/koopa0/assistant-go/internal/conversation"
 	converrors "github.com/koopa0/assistant-go/internal/conversation"
+	"github.com/koopa0/assistant-go/internal/langchain/router"
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
 	"github.com/koopa0/assistant-go/internal/tool"
+	"github.com/koopa0/assistant-go/internal/tool/artifact"
 	userserrors "github.com/koopa0/assistant-go/internal/user"
 )
 
@@ -37,6 +43,9 @@ type Processor struct {
 	conversationMgr conversation.ConversationService
 	aiService       *ai.Service
 	envDetector     *EnvironmentDetector
+	artifacts       *artifact.Store // Stores tool artifacts, nil without an artifacts directory
+	toolSettings    *tool.SettingsResolver
+	router          *router.Router // Routes queries to agents, chains and tools
 }
 
 // NewProcessor creates a new processor with enhanced error handling
@@ -272,7 +281,11 @@ func (p *Processor) Process(ctx context.Context, request *QueryRequest) (*QueryR
 
 	// Step 9: Execute tools if any are requested
 	if len(request.Tools) > 0 {
-		toolResults, err := p.executeTools(ctx, request.Tools, request.Context)
+		toolResults, err := p.executeTools(ctx, request.Tools, request.Context, artifact.Links{
+			UserID:         conversation.UserID,
+			ConversationID: conversation.ID,
+			MessageID:      assistantMessage.ID,
+		})
 		if err != nil {
 			p.logger.Warn("Tool execution failed",
 				slog.Any("tools", request.Tools),
@@ -283,6 +296,7 @@ func (p *Processor) Process(ctx context.Context, request *QueryRequest) (*QueryR
 			queryResponse.ToolsUsed = request.Tools
 			queryResponse.Context["tools_requested"] = request.Tools
 			queryResponse.Context["tool_results"] = toolResults
+			queryResponse.Citations = collectCitations(request.Tools, toolResults)
 
 			p.logger.Debug("Tools executed successfully",
 				slog.Any("tools", request.Tools),
@@ -635,8 +649,9 @@ func (p *Processor) Stats(ctx context.Context) (map[string]interface{}, error) {
 	return stats, nil
 }
 
-// executeTools executes the requested tools
-func (p *Processor) executeTools(ctx context.Context, toolNames []string, toolParams map[string]interface{}) (map[string]interface{}, error) {
+// executeTools executes the requested tools. Artifacts the tools produce
+// are stored against links when an artifact store is configured.
+func (p *Processor) executeTools(ctx context.Context, toolNames []string, toolParams map[string]interface{}, links artifact.Links) (map[string]interface{}, error) {
 	if len(toolNames) == 0 {
 		return nil, nil
 	}
@@ -644,6 +659,11 @@ func (p *Processor) executeTools(ctx context.Context, toolNames []string, toolPa
 	results := make(map[string]interface{})
 	var lastError error
 
+	policy, err := p.resolveToolPolicy(ctx, links.UserID, links.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, toolName := range toolNames {
 		p.logger.Debug("Executing tool",
 			slog.String("tool", toolName),
@@ -663,23 +683,24 @@ func (p *Processor) executeTools(ctx context.Context, toolNames []string, toolPa
 			continue
 		}
 
-		// Get tool instance
-		toolInstance, err := p.registry.GetTool(toolName, nil) // Use nil config for now
-		if err != nil {
-			p.logger.Error("Failed to get tool instance",
-				slog.String("tool", toolName),
-				slog.Any("error", err))
+		if !policy.Enabled(toolName) {
+			err := tool.NewToolPermissionDeniedError(toolName, "execute", "tool settings")
 			results[toolName] = map[string]interface{}{
 				"error":  err.Error(),
-				"status": "get_failed",
+				"status": "disabled",
 			}
 			lastError = err
 			continue
 		}
 
-		// Prepare tool input with typed structure
+		// Prepare tool input with typed structure. Tools such as postgres
+		// resolve per-user resources from the context.
 		toolInput := &tool.ToolInput{
 			Parameters: make(map[string]interface{}),
+			Context: &tool.ToolContext{
+				UserID:         links.UserID,
+				ConversationID: links.ConversationID,
+			},
 		}
 
 		if toolParams != nil {
@@ -699,8 +720,10 @@ func (p *Processor) executeTools(ctx context.Context, toolNames []string, toolPa
 			toolCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 			defer cancel()
 
+			// Execute through the registry so executions count towards the
+			// tool's health and quarantined tools are refused
 			startTime := time.Now()
-			result, toolErr = toolInstance.Execute(toolCtx, toolInput)
+			result, toolErr = p.registry.Execute(toolCtx, toolName, toolInput, policy.Config(toolName))
 			executionTime = time.Since(startTime)
 		}()
 
@@ -723,6 +746,8 @@ func (p *Processor) executeTools(ctx context.Context, toolNames []string, toolPa
 			slog.String("tool", toolName),
 			slog.Duration("execution_time", executionTime))
 
+		p.storeArtifacts(ctx, toolName, toolInput, result, executionTime, links)
+
 		results[toolName] = map[string]interface{}{
 			"result":         result,
 			"status":         "success",
@@ -750,6 +775,125 @@ func (p *Processor) executeTools(ctx context.Context, toolNames []string, toolPa
 	return results, lastError
 }
 
+// resolveToolPolicy resolves the tool settings of a user and
+// conversation; without settings every tool is enabled
+func (p *Processor) resolveToolPolicy(ctx context.Context, userID, conversationID string) (*tool.Policy, error) {
+	if p.toolSettings == nil {
+		return nil, nil
+	}
+	policy, err := p.toolSettings.Resolve(ctx, tool.Subject{
+		UserID:         userID,
+		Roles:          contextRoles(ctx),
+		ConversationID: conversationID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tool settings: %w", err)
+	}
+	return policy, nil
+}
+
+// contextRoles returns the roles of the authenticated user, if any
+func contextRoles(ctx context.Context) []string {
+	info, err := userserrors.FromContext(ctx)
+	if err != nil {
+		return nil
+	}
+	return info.Roles
+}
+
+// storeArtifacts moves the inline artifacts of a tool result into the
+// artifact store, linked to a tool execution recorded against the message
+// the tool ran for. Failures are logged; the artifacts then stay inline.
+func (p *Processor) storeArtifacts(ctx context.Context, toolName string, input *tool.ToolInput, result *tool.ToolResult, executionTime time.Duration, links artifact.Links) {
+	if p.artifacts == nil || result.Data == nil || len(result.Data.Artifacts) == 0 {
+		return
+	}
+	links.ToolName = toolName
+
+	if queries := p.db.GetQueries(); queries != nil && links.MessageID != "" {
+		executionID, err := recordToolExecution(ctx, queries, toolName, links.MessageID, input, result, executionTime)
+		if err != nil {
+			p.logger.Warn("Failed to record tool execution",
+				slog.String("tool", toolName),
+				slog.Any("error", err))
+		}
+		links.ToolExecutionID = executionID
+	}
+
+	if err := p.artifacts.Persist(ctx, result, links); err != nil {
+		p.logger.Warn("Failed to store tool artifacts",
+			slog.String("tool", toolName),
+			slog.Any("error", err))
+	}
+}
+
+// recordToolExecution records a finished tool execution against a message
+// and returns its ID. Artifact content is left out of the recorded output.
+func recordToolExecution(ctx context.Context, queries *sqlc.Queries, toolName, messageID string, input *tool.ToolInput, result *tool.ToolResult, executionTime time.Duration) (string, error) {
+	msgID, err := postgres.ParseUUID(messageID)
+	if err != nil {
+		return "", fmt.Errorf("invalid message id %q: %w", messageID, err)
+	}
+	inputData, err := json.Marshal(input.Parameters)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal input: %w", err)
+	}
+	output := *result.Data
+	output.Artifacts = nil
+	outputData, err := json.Marshal(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	status := "completed"
+	var errorMessage pgtype.Text
+	if !result.Success {
+		status = "failed"
+		errorMessage = pgtype.Text{String: result.Error, Valid: true}
+	}
+
+	now := time.Now()
+	execution, err := queries.CreateToolExecution(ctx, sqlc.CreateToolExecutionParams{
+		ToolName:        toolName,
+		MessageID:       msgID,
+		Status:          status,
+		InputData:       inputData,
+		OutputData:      outputData,
+		ErrorMessage:    errorMessage,
+		ExecutionTimeMs: pgtype.Int4{Int32: int32(executionTime.Milliseconds()), Valid: true},
+		StartedAt:       pgtype.Timestamptz{Time: now.Add(-executionTime), Valid: true},
+		CompletedAt:     pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	return postgres.UUIDToString(execution.ID), nil
+}
+
+// collectCitations gathers the citations of successful tool results in
+// the order the tools were requested, dropping repeated URLs
+func collectCitations(toolNames []string, results map[string]interface{}) []tool.Citation {
+	var citations []tool.Citation
+	seen := make(map[string]bool)
+	for _, name := range toolNames {
+		resultMap, ok := results[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		result, ok := resultMap["result"].(*tool.ToolResult)
+		if !ok || result == nil || !result.Success || result.Data == nil {
+			continue
+		}
+		for _, c := range result.Data.Citations {
+			if !seen[c.URL] {
+				seen[c.URL] = true
+				citations = append(citations, c)
+			}
+		}
+	}
+	return citations
+}
+
 // Close closes the processor
 func (p *Processor) Close(ctx context.Context) error {
 	// Close AI manager
@@ -1077,7 +1221,7 @@ func (p *Processor) analyzeQueryContext(ctx context.Context, request *QueryReque
 	}
 
 	complexity := classifyComplexity(query)
-	requiredTools := suggestTools(query)
+	decision := p.routeQuery(ctx, request)
 	estimatedTokens := len(query) * 2 // Rough estimation
 
 	// Extract keywords (simplified)
@@ -1087,18 +1231,59 @@ func (p *Processor) analyzeQueryContext(ctx context.Context, request *QueryReque
 		Intent:          intent,
 		Category:        category,
 		Complexity:      complexity,
-		RequiredTools:   requiredTools,
+		RequiredTools:   decision.Tools,
 		EstimatedTokens: estimatedTokens,
 		Keywords:        keywords,
 		Metadata: map[string]string{
-			"analyzed_at":  time.Now().Format(time.RFC3339),
-			"query_length": fmt.Sprintf("%d", len(query)),
+			"analyzed_at":        time.Now().Format(time.RFC3339),
+			"query_length":       fmt.Sprintf("%d", len(query)),
+			"agent_type":         string(decision.AgentType),
+			"chain_type":         string(decision.ChainType),
+			"routing_source":     string(decision.Source),
+			"routing_confidence": fmt.Sprintf("%.2f", decision.Confidence),
 		},
 	}
+	if decision.ID != "" {
+		queryContext.Metadata["routing_id"] = decision.ID
+	}
 
 	return queryContext, nil
 }
 
+// routeQuery routes a query among the tools enabled for the user; without
+// a router, or when routing fails, the keyword heuristic decides
+func (p *Processor) routeQuery(ctx context.Context, request *QueryRequest) *router.Decision {
+	routeRequest := router.Request{
+		Query: request.Query,
+		Tools: request.Tools,
+	}
+	if userID, err := p.extractUserIDFromContext(ctx, request); err == nil {
+		routeRequest.UserID = userID
+	}
+	if request.ConversationID != nil {
+		routeRequest.ConversationID = *request.ConversationID
+	}
+	if len(routeRequest.Tools) == 0 {
+		tools := p.registry.ListTools()
+		if policy, err := p.resolveToolPolicy(ctx, routeRequest.UserID, routeRequest.ConversationID); err == nil {
+			tools = policy.Filter(tools)
+		}
+		for _, info := range tools {
+			routeRequest.Tools = append(routeRequest.Tools, info.Name)
+		}
+	}
+
+	if p.router == nil {
+		return router.Heuristic(routeRequest)
+	}
+	decision, err := p.router.Route(ctx, routeRequest)
+	if err != nil {
+		p.logger.Warn("Failed to route query", slog.Any("error", err))
+		return router.Heuristic(routeRequest)
+	}
+	return decision
+}
+
 // Helper functions
 func getMapKeys(m map[string]interface{}) []string {
 	keys := make([]string, 0, len(m))
@@ -1129,25 +1314,6 @@ func classifyComplexity(query string) string {
 	return "complex"
 }
 
-func suggestTools(query string) []string {
-	tools := make([]string, 0)
-
-	if containsAny(query, []string{"analyze", "go", "code", "function", "struct"}) {
-		tools = append(tools, "go_analyzer")
-	}
-	if containsAny(query, []string{"docker", "container", "image"}) {
-		tools = append(tools, "docker")
-	}
-	if containsAny(query, []string{"kubernetes", "k8s", "pod", "deployment"}) {
-		tools = append(tools, "kubernetes")
-	}
-	if containsAny(query, []string{"database", "postgres", "sql", "query"}) {
-		tools = append(tools, "postgres")
-	}
-
-	return tools
-}
-
 func extractKeywords(query string) []string {
 	// Simple keyword extraction - split by spaces and filter common words
 	words := strings.Fields(strings.ToLower(query))
@@ -2,6 +2,8 @@ package assistant
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"strings"
 	"testing"
 	"time"
@@ -10,6 +12,8 @@ import (
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
 	"github.com/koopa0/assistant-go/internal/testutil"
 	"github.com/koopa0/assistant-go/internal/tool"
+	"github.com/koopa0/assistant-go/internal/tool/artifact"
+	postgrestool "github.com/koopa0/assistant-go/internal/tool/postgres"
 )
 
 // TestProcessorCreation tests processor creation and initialization
@@ -303,3 +307,70 @@ func TestProcessorStats(t *testing.T) {
 		t.Errorf("Expected stats but got nil")
 	}
 }
+
+// recordingConnectionStore records which connection was asked for
+type recordingConnectionStore struct {
+	userID, name string
+}
+
+var errStoreReached = errors.New("connection store reached")
+
+func (s *recordingConnectionStore) ConnectionString(ctx context.Context, userID, name string) (string, error) {
+	s.userID, s.name = userID, name
+	return "", errStoreReached
+}
+
+func (s *recordingConnectionStore) ListConnections(ctx context.Context, userID string) ([]postgrestool.ConnectionInfo, error) {
+	return nil, nil
+}
+
+// TestProcessorExecuteToolsNamedConnection tests that chat tool calls
+// resolve the user's named database connections
+func TestProcessorExecuteToolsNamedConnection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cfg := &config.Config{
+		Mode: "test",
+		AI: config.AIConfig{
+			DefaultProvider: "claude",
+			Claude: config.Claude{
+				APIKey: "test-key",
+				Model:  "claude-3-sonnet-20240229",
+			},
+		},
+	}
+	store := &recordingConnectionStore{}
+	registry := tool.NewRegistry(testutil.NewTestLogger())
+	err := registry.Register("postgres", func(_ *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
+		return postgrestool.NewPostgresTool(config.Postgres{}, store, logger), nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register tool: %v", err)
+	}
+
+	processor, err := NewProcessor(cfg, postgres.NewMockClient(testutil.NewSilentLogger()), registry, testutil.NewTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	defer processor.Close(ctx)
+
+	const userID = "6f1c2f5e-8a4b-4c1d-9e2f-3a4b5c6d7e8f"
+	params := map[string]interface{}{
+		"postgres": map[string]interface{}{
+			"action":     "execute_query",
+			"query":      "SELECT 1",
+			"connection": "analytics",
+		},
+	}
+	results, _ := processor.executeTools(ctx, []string{"postgres"}, params, artifact.Links{UserID: userID, ConversationID: "conv-1"})
+
+	if store.userID != userID || store.name != "analytics" {
+		t.Errorf("Expected connection %q of user %s to be resolved, got %q of user %q", "analytics", userID, store.name, store.userID)
+	}
+	entry, _ := results["postgres"].(map[string]interface{})
+	result, _ := entry["result"].(*tool.ToolResult)
+	if result == nil || result.Success || !strings.Contains(result.Error, errStoreReached.Error()) {
+		t.Errorf("Expected the store error in the tool result, got %+v", result)
+	}
+}
@@ -323,6 +323,7 @@ type ToolResultData struct {
 
 // ToolArtifact represents a file or artifact produced by a tool
 type ToolArtifact struct {
+	ID          string `json:"id,omitempty"`
 	Name        string `json:"name"`
 	Type        string `json:"type"`
 	Content     []byte `json:"content,omitempty"`
@@ -15,7 +15,10 @@ import (
 	"github.com/koopa0/assistant-go/internal/assistant"
 	"github.com/koopa0/assistant-go/internal/cli/ui"
 	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain"
 	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/langchain/chain"
+	"github.com/koopa0/assistant-go/internal/langchain/router"
 	"github.com/koopa0/assistant-go/internal/user"
 )
 
@@ -29,6 +32,7 @@ type CLI struct {
 	authToken   string
 	currentUser *user.UserInfo
 	authService *user.AuthService
+	ragHistory  []chain.Turn // RAG questions and answers, for follow-up questions
 }
 
 // New creates a new enhanced CLI instance
@@ -248,6 +252,10 @@ func (c *CLI) handleCommand(ctx context.Context, input string) bool {
 	case "chains":
 		c.showLangChainChains(ctx)
 		return true
+
+	case "collections":
+		c.handleCollectionsCommand(ctx, args)
+		return true
 	}
 
 	return false
@@ -371,6 +379,7 @@ func (c *CLI) showHelp() {
 		{"langchain, lc", "LangChain operations"},
 		{"agents", "List available LangChain agents"},
 		{"chains", "List available LangChain chains"},
+		{"collections", "Manage knowledge-base collections"},
 	}
 
 	for _, cmd := range langchainCommands {
@@ -574,11 +583,13 @@ func (c *CLI) logout() {
 // showLangChainHelp displays help for LangChain commands
 func (c *CLI) showLangChainHelp() {
 	ui.Info.Println("\nLangChain Commands:")
-	ui.Muted.Println("  langchain agents [execute <type> <query>]  - List or execute agents")
-	ui.Muted.Println("  langchain chains [execute <type> <input>]  - List or execute chains")
+	ui.Muted.Println("  langchain agents [execute <type> <query>]  - List or execute agents (type auto routes)")
+	ui.Muted.Println("  langchain route <query>                    - Show the agent, chain and tools for a query")
+	ui.Muted.Println("  langchain chains [execute <type> <input>]  - List or execute chains (rag answers with citations)")
 	ui.Muted.Println("  langchain memory <command>                 - Memory operations")
 	ui.Muted.Println("  agents                                     - List available agents")
 	ui.Muted.Println("  chains                                     - List available chains")
+	ui.Muted.Println("  collections [command]                      - Manage knowledge-base collections")
 }
 
 // handleLangChainCommand handles LangChain subcommands
@@ -620,6 +631,13 @@ func (c *CLI) handleLangChainCommand(ctx context.Context, args []string) {
 			c.showLangChainChains(ctx)
 		}
 
+	case "route":
+		if len(subArgs) == 0 {
+			ui.Warning.Println("Usage: langchain route <query>")
+			return
+		}
+		c.routeLangChainQuery(ctx, strings.Join(subArgs, " "))
+
 	case "memory":
 		ui.Warning.Println("Memory commands not yet implemented")
 
@@ -657,6 +675,7 @@ func (c *CLI) showLangChainAgents(ctx context.Context) {
 			ui.Muted.Printf("  - %s: Custom agent\n", agentType)
 		}
 	}
+	ui.Muted.Printf("  - %s: Routes to the agent best suited to the query\n", langchain.AutoAgent)
 }
 
 // showLangChainChains displays available LangChain chains
@@ -672,7 +691,7 @@ func (c *CLI) showLangChainChains(ctx context.Context) {
 	ui.Muted.Println("  - sequential: Sequential processing chain")
 	ui.Muted.Println("  - conditional: Conditional branching chain")
 	ui.Muted.Println("  - parallel: Parallel processing chain")
-	ui.Muted.Println("  - rag: Retrieval-Augmented Generation chain")
+	ui.Muted.Println("  - rag: Retrieval-Augmented Generation with verified citations")
 }
 
 // executeLangChainAgent executes a specific agent
@@ -689,19 +708,29 @@ func (c *CLI) executeLangChainAgent(ctx context.Context, agentType, query string
 	// Create agent request
 	request := &agent.Request{
 		Query:       query,
-		MaxSteps:    5,
 		Temperature: 0.7,
 		Context:     make(map[string]interface{}),
 	}
 
-	// Execute agent through service
-	response, err := langchainService.ExecuteAgent(ctx, agent.AgentType(agentType), request)
+	// Execute agent through service; "auto" lets the router choose
+	var response *agent.Response
+	var decision *router.Decision
+	var err error
+	if agent.AgentType(agentType) == langchain.AutoAgent {
+		response, decision, err = langchainService.ExecuteRouted(ctx, request)
+	} else {
+		response, err = langchainService.ExecuteAgent(ctx, agent.AgentType(agentType), request)
+	}
 	stop()
 
 	if err != nil {
 		ui.Error.Printf("\nAgent execution failed: %v\n", err)
 		return
 	}
+	if decision != nil {
+		agentType = string(decision.AgentType)
+		showRoutingDecision(decision)
+	}
 
 	if response.Success {
 		ui.Success.Printf("\nAgent '%s' executed successfully\n", agentType)
@@ -712,14 +741,24 @@ func (c *CLI) executeLangChainAgent(ctx context.Context, agentType, query string
 	ui.Info.Printf("Query: %s\n", query)
 	ui.Info.Printf("Execution time: %v\n", response.ExecutionTime)
 	ui.Info.Printf("Confidence: %.2f\n", response.Confidence)
+	ui.Info.Printf("Tokens used: %d\n", response.TokensUsed)
 
 	// Show steps if available
 	if len(response.Steps) > 0 {
 		ui.Muted.Println("\nExecution steps:")
 		for i, step := range response.Steps {
 			ui.Muted.Printf("  %d. %s\n", i+1, step.Action)
-			if step.Result != "" {
-				ui.Muted.Printf("     %s\n", step.Result)
+			if step.Thought != "" {
+				ui.Muted.Printf("     Thought: %s\n", step.Thought)
+			}
+			if step.ActionInput != "" {
+				ui.Muted.Printf("     Input: %s\n", step.ActionInput)
+			}
+			if observation := step.Observation; observation != "" {
+				if len(observation) > 200 {
+					observation = observation[:200] + "..."
+				}
+				ui.Muted.Printf("     Observation: %s\n", observation)
 			}
 		}
 	}
@@ -728,6 +767,35 @@ func (c *CLI) executeLangChainAgent(ctx context.Context, agentType, query string
 	fmt.Println(response.Result)
 }
 
+// routeLangChainQuery shows the agent, chain and tools the router chooses
+// for a query
+func (c *CLI) routeLangChainQuery(ctx context.Context, query string) {
+	langchainService := c.assistant.GetLangChainService()
+	if langchainService == nil {
+		ui.Error.Println("LangChain service is not available")
+		return
+	}
+
+	decision, err := langchainService.Route(ctx, &agent.Request{Query: query})
+	if err != nil {
+		ui.Error.Printf("Routing failed: %v\n", err)
+		return
+	}
+	showRoutingDecision(decision)
+}
+
+// showRoutingDecision displays a routing decision
+func showRoutingDecision(decision *router.Decision) {
+	ui.Info.Printf("\nRouted to %s agent (%s chain) by %s, confidence %.2f\n",
+		decision.AgentType, decision.ChainType, decision.Source, decision.Confidence)
+	if len(decision.Tools) > 0 {
+		ui.Muted.Printf("  Tools: %s\n", strings.Join(decision.Tools, ", "))
+	}
+	if decision.Reason != "" {
+		ui.Muted.Printf("  Reason: %s\n", decision.Reason)
+	}
+}
+
 // executeLangChainChain executes a specific chain
 func (c *CLI) executeLangChainChain(ctx context.Context, chainType, input string) {
 	langchainService := c.assistant.GetLangChainService()
@@ -736,6 +804,11 @@ func (c *CLI) executeLangChainChain(ctx context.Context, chainType, input string
 		return
 	}
 
+	if chainType == "rag" {
+		c.queryLangChainRAG(ctx, langchainService, input)
+		return
+	}
+
 	// Show progress
 	stop := ui.ShowProgress(fmt.Sprintf("Executing %s chain...", chainType))
 	defer stop()
@@ -745,3 +818,56 @@ func (c *CLI) executeLangChainChain(ctx context.Context, chainType, input string
 	ui.Info.Printf("Input: %s\n", input)
 	ui.Muted.Println("\nOutput: [Chain execution not yet implemented]")
 }
+
+// maxRAGHistory bounds the RAG messages kept for follow-up questions
+const maxRAGHistory = 20
+
+// queryLangChainRAG answers a query from the knowledge base and shows the
+// answer's citations and the claims that failed verification. The
+// session's earlier questions and answers resolve follow-up questions.
+func (c *CLI) queryLangChainRAG(ctx context.Context, langchainService *langchain.Service, query string) {
+	if c.currentUser != nil {
+		ctx = user.WithUser(ctx, c.currentUser)
+	}
+	stop := ui.ShowProgress("Executing rag chain...")
+	result, err := langchainService.QueryRAG(ctx, query, nil, c.ragHistory)
+	stop()
+
+	if err != nil {
+		ui.Error.Printf("\nRAG query failed: %v\n", err)
+		return
+	}
+	c.ragHistory = append(c.ragHistory,
+		chain.Turn{Role: "user", Content: query},
+		chain.Turn{Role: "assistant", Content: result.Answer})
+	if len(c.ragHistory) > maxRAGHistory {
+		c.ragHistory = c.ragHistory[len(c.ragHistory)-maxRAGHistory:]
+	}
+
+	if queries, ok := result.Metadata["retrieval_queries"].([]string); ok && len(queries) > 0 && queries[0] != query {
+		ui.Muted.Printf("\nSearched for: %s\n", strings.Join(queries, " | "))
+	}
+
+	ui.Success.Println("\nAnswer:")
+	fmt.Println(result.Answer)
+
+	if len(result.Citations) > 0 {
+		ui.Info.Println("\nCitations:")
+		for _, citation := range result.Citations {
+			ui.Muted.Printf("  %s\n", citation)
+		}
+	}
+
+	var unsupported []chain.Claim
+	for _, claim := range result.Claims {
+		if claim.Checked != "" && !claim.Supported {
+			unsupported = append(unsupported, claim)
+		}
+	}
+	if len(unsupported) > 0 {
+		ui.Warning.Printf("\n%d claims are not supported by their citations:\n", len(unsupported))
+		for _, claim := range unsupported {
+			ui.Muted.Printf("  - %s (overlap %.2f, checked by %s)\n", claim.Text, claim.Overlap, claim.Checked)
+		}
+	}
+}
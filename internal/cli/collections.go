@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/cli/ui"
+	"github.com/koopa0/assistant-go/internal/langchain/collection"
+	"github.com/koopa0/assistant-go/internal/user"
+)
+
+// showCollectionsHelp displays help for collection commands
+func (c *CLI) showCollectionsHelp() {
+	ui.Info.Println("\nCollection Commands:")
+	ui.Muted.Println("  collections                                     - List the collections you may read")
+	ui.Muted.Println("  collections create <name> [provider]            - Create a collection you own")
+	ui.Muted.Println("  collections delete <name>                       - Delete a collection and its documents")
+	ui.Muted.Println("  collections share <name> <user|team> <id> <read|write>")
+	ui.Muted.Println("  collections unshare <name> <user|team> <id>     - Revoke a grant")
+	ui.Muted.Println("  collections docs <name>                         - List ingested documents")
+	ui.Muted.Println("  collections ingest <name> <file|dir|url>        - Ingest a source in the background")
+	ui.Muted.Println("  collections reingest <name> <document-id>       - Ingest a document again")
+	ui.Muted.Println("  collections rmdoc <name> <document-id>          - Delete a document")
+	ui.Muted.Println("  collections jobs <name>                         - Show ingestion jobs")
+	ui.Muted.Println("  collections search <name> <query>               - Search a collection")
+	ui.Muted.Println("  collections reembed <name> <model> [provider]   - Move a collection to a new embedding model")
+	ui.Muted.Println("  collections reembeds <name>                     - Show re-embedding jobs and their progress")
+	ui.Muted.Println("  collections cancel-reembed <name> <job-id>      - Cancel a re-embedding job")
+}
+
+// handleCollectionsCommand handles collection subcommands on behalf of
+// the logged-in user
+func (c *CLI) handleCollectionsCommand(ctx context.Context, args []string) {
+	manager := c.assistant.Collections()
+	if manager == nil {
+		ui.Error.Println("Collections are not available without a database")
+		return
+	}
+	if c.currentUser != nil {
+		ctx = user.WithUser(ctx, c.currentUser)
+	}
+	caller := collection.CallerFromContext(ctx)
+
+	if len(args) == 0 {
+		c.listCollections(ctx, manager, caller)
+		return
+	}
+
+	subcommand := strings.ToLower(args[0])
+	subArgs := args[1:]
+	usage := map[string]int{
+		"create": 1, "delete": 1, "share": 4, "unshare": 3, "docs": 1,
+		"ingest": 2, "reingest": 2, "rmdoc": 2, "jobs": 1, "search": 2,
+		"reembed": 2, "reembeds": 1, "cancel-reembed": 2,
+	}
+	required, ok := usage[subcommand]
+	if !ok {
+		ui.Error.Printf("Unknown collections command: %s\n", subcommand)
+		c.showCollectionsHelp()
+		return
+	}
+	if len(subArgs) < required {
+		c.showCollectionsHelp()
+		return
+	}
+	name := subArgs[0]
+
+	switch subcommand {
+	case "create":
+		request := collection.CreateRequest{Name: name}
+		if len(subArgs) > 1 {
+			request.EmbeddingProvider = subArgs[1]
+		}
+		created, err := manager.Create(ctx, caller, request)
+		if err != nil {
+			ui.Error.Printf("Failed to create collection: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Created collection %s (chunks of %d, overlap %d)\n", created.Name, created.ChunkSize, created.ChunkOverlap)
+
+	case "delete":
+		if err := manager.Delete(ctx, caller, name); err != nil {
+			ui.Error.Printf("Failed to delete collection: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Deleted collection %s\n", name)
+
+	case "share":
+		grant := collection.Grant{GranteeType: subArgs[1], GranteeID: subArgs[2], Permission: collection.Permission(subArgs[3])}
+		if _, err := manager.Share(ctx, caller, name, grant); err != nil {
+			ui.Error.Printf("Failed to share collection: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Granted %s %s %s access to %s\n", grant.GranteeType, grant.GranteeID, grant.Permission, name)
+
+	case "unshare":
+		if _, err := manager.Unshare(ctx, caller, name, subArgs[1], subArgs[2]); err != nil {
+			ui.Error.Printf("Failed to revoke grant: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Revoked the grant of %s %s on %s\n", subArgs[1], subArgs[2], name)
+
+	case "docs":
+		documents, err := manager.Documents(ctx, caller, name)
+		if err != nil {
+			ui.Error.Printf("Failed to list documents: %v\n", err)
+			return
+		}
+		ui.Info.Printf("\n%d documents in %s:\n", len(documents), name)
+		for _, document := range documents {
+			ui.Label.Printf("  %s  ", document.ID)
+			ui.Muted.Printf("%s (%d chunks, updated %s)\n", document.Source, document.Chunks, document.UpdatedAt.Format("2006-01-02 15:04"))
+		}
+
+	case "ingest", "reingest":
+		var job *collection.Job
+		var err error
+		if subcommand == "ingest" {
+			job, err = manager.Ingest(ctx, caller, name, subArgs[1])
+		} else {
+			job, err = manager.Reingest(ctx, caller, name, subArgs[1])
+		}
+		if err != nil {
+			ui.Error.Printf("Failed to start ingestion: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Started ingestion job %s for %s\n", job.ID, job.Source)
+		ui.Muted.Printf("  Check its status with: collections jobs %s\n", name)
+
+	case "rmdoc":
+		if err := manager.DeleteDocument(ctx, caller, name, subArgs[1]); err != nil {
+			ui.Error.Printf("Failed to delete document: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Deleted document %s\n", subArgs[1])
+
+	case "jobs":
+		jobs, err := manager.Jobs(ctx, caller, name, 0)
+		if err != nil {
+			ui.Error.Printf("Failed to list ingestion jobs: %v\n", err)
+			return
+		}
+		ui.Info.Printf("\nIngestion jobs of %s:\n", name)
+		for _, job := range jobs {
+			ui.Label.Printf("  %-10s", job.Status)
+			ui.Muted.Printf("%s  %d documents, %d chunks  %s\n", job.Source, job.Documents, job.Chunks, job.CreatedAt.Format("2006-01-02 15:04"))
+			if job.Error != "" {
+				ui.Error.Printf("    %s\n", job.Error)
+			}
+		}
+
+	case "search":
+		results, err := manager.Search(ctx, caller, []string{name}, strings.Join(subArgs[1:], " "), 5, 0)
+		if err != nil {
+			ui.Error.Printf("Search failed: %v\n", err)
+			return
+		}
+		ui.Info.Printf("\n%d results:\n", len(results))
+		for i, result := range results {
+			source, _ := result.Metadata["source"].(string)
+			ui.Label.Printf("  [%d] %s (score %.3f)\n", i+1, source, result.Score)
+			ui.Muted.Printf("      %s\n", preview(result.Content, 160))
+		}
+
+	case "reembed":
+		request := collection.ReembedRequest{Model: subArgs[1]}
+		if len(subArgs) > 2 {
+			request.Provider = subArgs[2]
+		}
+		job, err := manager.Reembed(ctx, caller, name, request)
+		if err != nil {
+			ui.Error.Printf("Failed to start re-embedding: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Started re-embedding job %s to %s\n", job.ID, job.Model)
+		ui.Muted.Printf("  Check its progress with: collections reembeds %s\n", name)
+
+	case "reembeds":
+		jobs, err := manager.ReembedJobs(ctx, caller, name, 0)
+		if err != nil {
+			ui.Error.Printf("Failed to list re-embedding jobs: %v\n", err)
+			return
+		}
+		ui.Info.Printf("\nRe-embedding jobs of %s:\n", name)
+		for _, job := range jobs {
+			ui.Label.Printf("  %-10s", job.Status)
+			ui.Muted.Printf("%s  %s  %d/%d chunks  %s\n", job.ID, job.Model, job.Done, job.Total, job.CreatedAt.Format("2006-01-02 15:04"))
+			if job.Error != "" {
+				ui.Error.Printf("    %s\n", job.Error)
+			}
+		}
+
+	case "cancel-reembed":
+		if _, err := manager.CancelReembed(ctx, caller, name, subArgs[1]); err != nil {
+			ui.Error.Printf("Failed to cancel re-embedding: %v\n", err)
+			return
+		}
+		ui.Success.Printf("Cancelled re-embedding job %s\n", subArgs[1])
+	}
+}
+
+// listCollections displays the collections the caller may read
+func (c *CLI) listCollections(ctx context.Context, manager *collection.Manager, caller collection.Caller) {
+	collections, err := manager.List(ctx, caller)
+	if err != nil {
+		ui.Error.Printf("Failed to list collections: %v\n", err)
+		return
+	}
+
+	ui.Info.Println("\nCollections:")
+	for _, col := range collections {
+		access := string(col.Access(caller))
+		if col.IsOwner(caller) {
+			access = "owner"
+		}
+		ui.Label.Printf("  %-20s", col.Name)
+		ui.Muted.Printf("%-6s %s\n", access, col.Description)
+	}
+}
+
+// preview shortens text to a single line of at most n runes
+func preview(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > n {
+		return fmt.Sprintf("%s...", string(runes[:n]))
+	}
+	return text
+}
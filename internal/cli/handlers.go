@@ -329,24 +329,29 @@ func (c *CLI) analyzeDockerfile(ctx context.Context) error {
 }
 
 func (c *CLI) checkK8sConfig(ctx context.Context) error {
-	ui.Warning.Println("Kubernetes 功能尚在開發中...")
+	manifestPath := ui.InputText("請輸入 Kubernetes manifest 路徑（檔案、Kustomize 目錄或 helm template 輸出）", "./k8s")
 
-	// Placeholder for K8s functionality
-	query := "檢查 Kubernetes 配置的最佳實踐"
+	query := fmt.Sprintf("分析 %s 的 Kubernetes manifests 並提供修正建議，重點關注：\n1. 資源 requests/limits\n2. 健康檢查探針\n3. runAsNonRoot 與映像版本固定\n4. Service 與 Deployment 的 selector/label\n5. PodDisruptionBudget 與已棄用的 apiVersion", manifestPath)
 
 	request := &assistant.QueryRequest{
 		Query: query,
+		Tools: []string{"kubernetes"},
 		Context: map[string]interface{}{
 			"task_type": "k8s_config_check",
+			"path":      manifestPath,
 		},
 	}
 
+	stop := ui.ShowProgress("正在分析 Kubernetes manifests...")
 	response, err := c.assistant.ProcessQueryRequest(ctx, request)
+	stop()
+
 	if err != nil {
 		ui.Error.Printf("檢查失敗: %v\n", err)
 		return err
 	}
 
+	ui.Success.Println("\n✅ Kubernetes 配置檢查完成")
 	fmt.Println(response.Response)
 	return nil
 }
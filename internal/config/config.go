@@ -75,6 +75,7 @@ type AIConfig struct {
 	Claude          Claude    `yaml:"claude"`
 	Gemini          Gemini    `yaml:"gemini"`
 	Embeddings      Embedding `yaml:"embeddings"`
+	Routing         Routing   `yaml:"routing"`
 }
 
 // Claude holds Claude-specific configuration
@@ -97,13 +98,27 @@ type Gemini struct {
 	Timeout     time.Duration `yaml:"timeout" env:"GEMINI_TIMEOUT" default:"30s"`
 }
 
-// Embedding holds embedding service configuration
+// Embedding holds embedding service configuration. The local provider
+// embeds in process without an API key, for development and tests.
 type Embedding struct {
 	Provider   string `yaml:"provider" env:"EMBEDDING_PROVIDER" default:"claude"`
 	Model      string `yaml:"model" env:"EMBEDDING_MODEL" default:"text-embedding-ada-002"`
 	Dimensions int    `yaml:"dimensions" env:"EMBEDDING_DIMENSIONS" default:"1536"`
 }
 
+// Routing holds request routing configuration. A cheap model classifies
+// requests into agent, chain and tools; decisions are cached for queries
+// whose embeddings are at least CacheSimilarity alike.
+type Routing struct {
+	Enabled         bool          `yaml:"enabled" env:"ROUTING_ENABLED" default:"true"`
+	Provider        string        `yaml:"provider" env:"ROUTING_PROVIDER"` // defaults to the default provider
+	Model           string        `yaml:"model" env:"ROUTING_MODEL"`       // defaults to the provider's cheapest model
+	Timeout         time.Duration `yaml:"timeout" env:"ROUTING_TIMEOUT" default:"5s"`
+	CacheSize       int           `yaml:"cache_size" env:"ROUTING_CACHE_SIZE" default:"500"`
+	CacheSimilarity float64       `yaml:"cache_similarity" env:"ROUTING_CACHE_SIMILARITY" default:"0.92"`
+	CacheTTL        time.Duration `yaml:"cache_ttl" env:"ROUTING_CACHE_TTL" default:"24h"`
+}
+
 // ToolsConfig holds tool-specific configuration
 type ToolsConfig struct {
 	Search     Search     `yaml:"search"`
@@ -112,6 +127,15 @@ type ToolsConfig struct {
 	Docker     Docker     `yaml:"docker"`
 	Cloudflare Cloudflare `yaml:"cloudflare"`
 	LangChain  LangChain  `yaml:"langchain"`
+	OpenAPI    OpenAPI    `yaml:"openapi"`
+	Plugins    Plugins    `yaml:"plugins"`
+	Workflows  Workflows  `yaml:"workflows"`
+	Health     ToolHealth `yaml:"health"`
+	Artifacts  Artifacts  `yaml:"artifacts"`
+	// Roles holds tool overrides by user role, keyed by tool name or "*"
+	// for every tool. They are a ceiling for user and conversation
+	// settings, which can only tighten them.
+	Roles map[string]map[string]ToolOverride `yaml:"roles"`
 }
 
 // Search holds search tool configuration
@@ -146,6 +170,7 @@ type Docker struct {
 	APIVersion string        `yaml:"api_version" env:"DOCKER_API_VERSION" default:"1.41"`
 	Timeout    time.Duration `yaml:"timeout" env:"DOCKER_TIMEOUT" default:"30s"`
 	TLSVerify  bool          `yaml:"tls_verify" env:"DOCKER_TLS_VERIFY" default:"false"`
+	CertPath   string        `yaml:"cert_path" env:"DOCKER_CERT_PATH"`
 }
 
 // Cloudflare holds Cloudflare tool configuration
@@ -163,6 +188,135 @@ type LangChain struct {
 	MemorySize    int           `yaml:"memory_size" env:"LANGCHAIN_MEMORY_SIZE" default:"10"`
 	MaxIterations int           `yaml:"max_iterations" env:"LANGCHAIN_MAX_ITERATIONS" default:"5"`
 	Timeout       time.Duration `yaml:"timeout" env:"LANGCHAIN_TIMEOUT" default:"60s"`
+	Retrieval     Retrieval     `yaml:"retrieval"`
+	Citations     Citations     `yaml:"citations"`
+	Rewriting     Rewriting     `yaml:"rewriting"`
+	Collections   Collections   `yaml:"collections"`
+}
+
+// Retrieval holds RAG retrieval configuration. Hybrid search fuses the
+// full-text and vector rankings of stored chunks by reciprocal rank; the
+// fused candidates can be reranked and diversified with MMR.
+type Retrieval struct {
+	Mode        string  `yaml:"mode" env:"RETRIEVAL_MODE" default:"hybrid"`        // vector, keyword or hybrid
+	Candidates  int     `yaml:"candidates" env:"RETRIEVAL_CANDIDATES" default:"4"` // candidates per result
+	RRFK        int     `yaml:"rrf_k" env:"RETRIEVAL_RRF_K" default:"60"`
+	Reranker    string  `yaml:"reranker" env:"RETRIEVAL_RERANKER"` // "", llm or cross_encoder
+	RerankerURL string  `yaml:"reranker_url" env:"RETRIEVAL_RERANKER_URL"`
+	MMRLambda   float64 `yaml:"mmr_lambda" env:"RETRIEVAL_MMR_LAMBDA"` // 0 disables MMR
+}
+
+// Citations holds the verification of inline citations in RAG answers.
+// Each cited claim is checked against its chunks by word overlap and,
+// with LLMCheck, by the model; unsupported claims are flagged or dropped.
+type Citations struct {
+	MinOverlap  float64 `yaml:"min_overlap" env:"CITATIONS_MIN_OVERLAP" default:"0.5"` // share of claim words found in the chunk
+	LLMCheck    bool    `yaml:"llm_check" env:"CITATIONS_LLM_CHECK"`
+	Unsupported string  `yaml:"unsupported" env:"CITATIONS_UNSUPPORTED" default:"flag"` // flag or drop
+}
+
+// Rewriting holds the rewriting of RAG queries before retrieval. A
+// follow-up question is rewritten into a standalone query with the last
+// HistoryTurns messages of its conversation. SubQueries more queries and,
+// with HyDE, a hypothetical answer can be searched too; their results are
+// merged.
+type Rewriting struct {
+	Enabled      bool `yaml:"enabled" env:"REWRITING_ENABLED" default:"true"`
+	HistoryTurns int  `yaml:"history_turns" env:"REWRITING_HISTORY_TURNS" default:"6"`
+	SubQueries   int  `yaml:"sub_queries" env:"REWRITING_SUB_QUERIES"` // 0 disables multi-query retrieval
+	HyDE         bool `yaml:"hyde" env:"REWRITING_HYDE"`
+}
+
+// Collections holds knowledge-base collection settings. New collections
+// chunk documents with the default sizes unless created with their own;
+// IngestRoots restricts the directories files may be ingested from.
+// Re-embedding jobs embed ReembedBatchSize chunks per request and at most
+// ReembedRate chunks a minute.
+type Collections struct {
+	ChunkSize        int      `yaml:"chunk_size" env:"COLLECTIONS_CHUNK_SIZE" default:"1000"`
+	ChunkOverlap     int      `yaml:"chunk_overlap" env:"COLLECTIONS_CHUNK_OVERLAP" default:"200"`
+	IngestRoots      []string `yaml:"ingest_roots" env:"COLLECTIONS_INGEST_ROOTS"` // empty allows any path
+	ReembedBatchSize int      `yaml:"reembed_batch_size" env:"COLLECTIONS_REEMBED_BATCH_SIZE" default:"32"`
+	ReembedRate      int      `yaml:"reembed_rate" env:"COLLECTIONS_REEMBED_RATE" default:"600"` // 0 is unlimited
+}
+
+// OpenAPI holds the specifications whose operations become tools
+type OpenAPI struct {
+	Specs []OpenAPISpec `yaml:"specs"`
+}
+
+// OpenAPISpec describes one HTTP service. Each selected operation becomes a
+// tool named <name>_<operation_id>.
+type OpenAPISpec struct {
+	Name    string        `yaml:"name"`
+	Source  string        `yaml:"source"`   // file path or http(s) URL of the spec
+	BaseURL string        `yaml:"base_url"` // overrides the servers in the spec
+	Timeout time.Duration `yaml:"timeout" default:"30s"`
+
+	// Operations and Tags select operations by operationId or tag; both
+	// empty selects every operation. Exclude removes operationIds again.
+	Operations []string `yaml:"operations"`
+	Tags       []string `yaml:"tags"`
+	Exclude    []string `yaml:"exclude"`
+
+	// Credentials by security scheme name as declared in the spec.
+	// Values may reference environment variables as ${VAR}.
+	Credentials map[string]OpenAPICredential `yaml:"credentials"`
+	Headers     map[string]string            `yaml:"headers"`
+}
+
+// OpenAPICredential holds the secret for one security scheme: Token for
+// apiKey, bearer and oauth2 schemes, Username and Password for basic auth
+type OpenAPICredential struct {
+	Token    string `yaml:"token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Plugins holds configuration for external plugin tools: executables in
+// Dir that speak JSON-RPC over stdin/stdout
+type Plugins struct {
+	Dir            string        `yaml:"dir" env:"PLUGINS_DIR"` // empty disables plugins
+	Timeout        time.Duration `yaml:"timeout" env:"PLUGINS_TIMEOUT" default:"30s"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"PLUGINS_RELOAD_INTERVAL" default:"5s"` // negative disables hot reload
+}
+
+// Workflows holds configuration for YAML workflow definitions
+type Workflows struct {
+	Dir         string        `yaml:"dir" env:"WORKFLOWS_DIR"` // empty disables workflows
+	MaxParallel int           `yaml:"max_parallel" env:"WORKFLOWS_MAX_PARALLEL" default:"4"`
+	StepTimeout time.Duration `yaml:"step_timeout" env:"WORKFLOWS_STEP_TIMEOUT" default:"5m"`
+}
+
+// ToolHealth holds configuration for background tool health supervision
+type ToolHealth struct {
+	Interval           time.Duration `yaml:"interval" env:"TOOL_HEALTH_INTERVAL" default:"1m"` // negative disables supervision
+	Timeout            time.Duration `yaml:"timeout" env:"TOOL_HEALTH_TIMEOUT" default:"10s"`
+	MaxBackoff         time.Duration `yaml:"max_backoff" env:"TOOL_HEALTH_MAX_BACKOFF" default:"15m"`
+	FailureThreshold   int           `yaml:"failure_threshold" env:"TOOL_HEALTH_FAILURE_THRESHOLD" default:"3"` // consecutive failed checks before quarantine
+	ErrorRateThreshold float64       `yaml:"error_rate_threshold" env:"TOOL_HEALTH_ERROR_RATE_THRESHOLD" default:"0.5"`
+	MinExecutions      int           `yaml:"min_executions" env:"TOOL_HEALTH_MIN_EXECUTIONS" default:"10"`  // per interval before the error rate counts
+	RecoveryProbes     int           `yaml:"recovery_probes" env:"TOOL_HEALTH_RECOVERY_PROBES" default:"2"` // passing checks before re-admission
+}
+
+// ToolOverride enables or disables a tool and overrides its configuration.
+// Custom values are pinned tool parameters that replace those in the input.
+type ToolOverride struct {
+	Enabled    *bool                  `yaml:"enabled"`
+	Timeout    time.Duration          `yaml:"timeout"`
+	MaxRetries int                    `yaml:"max_retries"`
+	RetryDelay time.Duration          `yaml:"retry_delay"`
+	Custom     map[string]interface{} `yaml:"custom"`
+}
+
+// Artifacts holds configuration for the store of files produced by tools.
+// Contents are kept on disk by hash, metadata in PostgreSQL.
+type Artifacts struct {
+	Dir             string        `yaml:"dir" env:"ARTIFACTS_DIR"`                                            // empty disables the store
+	MaxSize         int64         `yaml:"max_size" env:"ARTIFACTS_MAX_SIZE" default:"52428800"`               // 50MB per artifact
+	MaxUserBytes    int64         `yaml:"max_user_bytes" env:"ARTIFACTS_MAX_USER_BYTES" default:"1073741824"` // 1GB per user; 0 is unlimited
+	Retention       time.Duration `yaml:"retention" env:"ARTIFACTS_RETENTION" default:"720h"`                 // 0 keeps artifacts forever
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"ARTIFACTS_CLEANUP_INTERVAL" default:"1h"`
 }
 
 // SecurityConfig holds security-related configuration
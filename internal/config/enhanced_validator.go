@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strings"
@@ -405,6 +406,7 @@ func (v *Validator) validateAI(cfg AIConfig) {
 
 	// Validate embeddings configuration
 	v.validateEmbeddingsConfig(cfg.Embeddings)
+	v.validateRoutingConfig(cfg.Routing)
 }
 
 // validateClaudeConfig validates Claude-specific configuration
@@ -475,7 +477,7 @@ func (v *Validator) validateGeminiConfig(cfg Gemini) {
 
 // validateEmbeddingsConfig validates embeddings configuration
 func (v *Validator) validateEmbeddingsConfig(cfg Embedding) {
-	validEmbeddingProviders := []string{"claude", "openai", "gemini"}
+	validEmbeddingProviders := []string{"claude", "openai", "gemini", "local"}
 	if !contains(validEmbeddingProviders, cfg.Provider) {
 		v.addError("AI.Embeddings.Provider", cfg.Provider,
 			fmt.Sprintf("must be one of: %s", strings.Join(validEmbeddingProviders, ", ")), "INVALID_EMBEDDING_PROVIDER")
@@ -488,6 +490,21 @@ func (v *Validator) validateEmbeddingsConfig(cfg Embedding) {
 	}
 }
 
+// validateRoutingConfig validates request routing configuration
+func (v *Validator) validateRoutingConfig(cfg Routing) {
+	validRoutingProviders := []string{"", "claude", "gemini"}
+	if !contains(validRoutingProviders, cfg.Provider) {
+		v.addError("AI.Routing.Provider", cfg.Provider, "must be claude or gemini", "INVALID_ROUTING_PROVIDER")
+	}
+
+	if cfg.CacheSize < 0 {
+		v.addError("AI.Routing.CacheSize", cfg.CacheSize, "must not be negative", "INVALID_ROUTING_CACHE_SIZE")
+	}
+	if cfg.CacheSize > 0 && (cfg.CacheSimilarity <= 0 || cfg.CacheSimilarity > 1) {
+		v.addError("AI.Routing.CacheSimilarity", cfg.CacheSimilarity, "must be greater than 0 and at most 1", "INVALID_ROUTING_CACHE_SIMILARITY")
+	}
+}
+
 // validateSecurity validates security configuration
 func (v *Validator) validateSecurity(cfg SecurityConfig) {
 	// JWT secret validation
@@ -738,4 +755,83 @@ func (v *Validator) validateLangChainConfig(cfg LangChain) {
 	if cfg.Timeout <= 0 {
 		v.addError("Tools.LangChain.Timeout", cfg.Timeout, "must be greater than 0", "INVALID_LANGCHAIN_TIMEOUT")
 	}
+
+	v.validateRetrievalConfig(cfg.Retrieval)
+	v.validateCitationsConfig(cfg.Citations)
+	v.validateRewritingConfig(cfg.Rewriting)
+	v.validateCollectionsConfig(cfg.Collections)
+}
+
+// validateRetrievalConfig validates RAG retrieval configuration
+func (v *Validator) validateRetrievalConfig(cfg Retrieval) {
+	validModes := []string{"", "vector", "keyword", "hybrid"}
+	if !contains(validModes, cfg.Mode) {
+		v.addError("Tools.LangChain.Retrieval.Mode", cfg.Mode, "must be vector, keyword or hybrid", "INVALID_RETRIEVAL_MODE")
+	}
+
+	if cfg.Candidates < 0 {
+		v.addError("Tools.LangChain.Retrieval.Candidates", cfg.Candidates, "must not be negative", "INVALID_RETRIEVAL_CANDIDATES")
+	}
+	if cfg.RRFK < 0 {
+		v.addError("Tools.LangChain.Retrieval.RRFK", cfg.RRFK, "must not be negative", "INVALID_RRF_K")
+	}
+
+	validRerankers := []string{"", "llm", "cross_encoder"}
+	if !contains(validRerankers, cfg.Reranker) {
+		v.addError("Tools.LangChain.Retrieval.Reranker", cfg.Reranker, "must be llm or cross_encoder", "INVALID_RERANKER")
+	} else if cfg.Reranker == "cross_encoder" {
+		if cfg.RerankerURL == "" {
+			v.addError("Tools.LangChain.Retrieval.RerankerURL", "", "is required for the cross_encoder reranker", "MISSING_RERANKER_URL")
+		} else if _, err := url.Parse(cfg.RerankerURL); err != nil {
+			v.addError("Tools.LangChain.Retrieval.RerankerURL", cfg.RerankerURL, fmt.Sprintf("invalid URL format: %v", err), "INVALID_RERANKER_URL")
+		}
+	}
+
+	if cfg.MMRLambda < 0 || cfg.MMRLambda > 1 {
+		v.addError("Tools.LangChain.Retrieval.MMRLambda", cfg.MMRLambda, "must be between 0 and 1", "INVALID_MMR_LAMBDA")
+	}
+}
+
+// validateCitationsConfig validates RAG citation verification configuration
+func (v *Validator) validateCitationsConfig(cfg Citations) {
+	if cfg.MinOverlap < 0 || cfg.MinOverlap > 1 {
+		v.addError("Tools.LangChain.Citations.MinOverlap", cfg.MinOverlap, "must be between 0 and 1", "INVALID_CITATION_OVERLAP")
+	}
+
+	validPolicies := []string{"", "flag", "drop"}
+	if !contains(validPolicies, cfg.Unsupported) {
+		v.addError("Tools.LangChain.Citations.Unsupported", cfg.Unsupported, "must be flag or drop", "INVALID_CITATION_POLICY")
+	}
+}
+
+// validateRewritingConfig validates RAG query rewriting configuration
+func (v *Validator) validateRewritingConfig(cfg Rewriting) {
+	if cfg.HistoryTurns < 0 {
+		v.addError("Tools.LangChain.Rewriting.HistoryTurns", cfg.HistoryTurns, "must not be negative", "INVALID_REWRITING_HISTORY")
+	}
+	if cfg.SubQueries < 0 || cfg.SubQueries > 5 {
+		v.addError("Tools.LangChain.Rewriting.SubQueries", cfg.SubQueries, "must be between 0 and 5", "INVALID_REWRITING_SUB_QUERIES")
+	}
+}
+
+// validateCollectionsConfig validates knowledge-base collection configuration
+func (v *Validator) validateCollectionsConfig(cfg Collections) {
+	if cfg.ChunkSize < 0 {
+		v.addError("Tools.LangChain.Collections.ChunkSize", cfg.ChunkSize, "must not be negative", "INVALID_COLLECTION_CHUNK_SIZE")
+	}
+	if cfg.ChunkOverlap < 0 || (cfg.ChunkSize > 0 && cfg.ChunkOverlap >= cfg.ChunkSize) {
+		v.addError("Tools.LangChain.Collections.ChunkOverlap", cfg.ChunkOverlap, "must be between 0 and the chunk size", "INVALID_COLLECTION_CHUNK_OVERLAP")
+	}
+	if cfg.ReembedBatchSize < 0 {
+		v.addError("Tools.LangChain.Collections.ReembedBatchSize", cfg.ReembedBatchSize, "must not be negative", "INVALID_REEMBED_BATCH_SIZE")
+	}
+	if cfg.ReembedRate < 0 {
+		v.addError("Tools.LangChain.Collections.ReembedRate", cfg.ReembedRate, "must not be negative", "INVALID_REEMBED_RATE")
+	}
+
+	for i, root := range cfg.IngestRoots {
+		if !filepath.IsAbs(root) {
+			v.addError(fmt.Sprintf("Tools.LangChain.Collections.IngestRoots[%d]", i), root, "must be an absolute path", "INVALID_INGEST_ROOT")
+		}
+	}
 }
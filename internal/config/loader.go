@@ -180,6 +180,12 @@ func (cl *ConfigLoader) setDefaults(cfg *Config) error {
 	cfg.AI.Embeddings.Model = "text-embedding-ada-002"
 	cfg.AI.Embeddings.Dimensions = 1536
 
+	cfg.AI.Routing.Enabled = true
+	cfg.AI.Routing.Timeout = 5 * time.Second
+	cfg.AI.Routing.CacheSize = 500
+	cfg.AI.Routing.CacheSimilarity = 0.92
+	cfg.AI.Routing.CacheTTL = 24 * time.Hour
+
 	// Tools defaults
 	cfg.Tools.Search.SearXNGURL = "http://localhost:8888"
 	cfg.Tools.Search.Timeout = 30 * time.Second
@@ -200,10 +206,40 @@ func (cl *ConfigLoader) setDefaults(cfg *Config) error {
 	cfg.Tools.Docker.Timeout = 30 * time.Second
 	cfg.Tools.Docker.TLSVerify = false
 
+	cfg.Tools.Plugins.Timeout = 30 * time.Second
+	cfg.Tools.Plugins.ReloadInterval = 5 * time.Second
+
+	cfg.Tools.Workflows.MaxParallel = 4
+	cfg.Tools.Workflows.StepTimeout = 5 * time.Minute
+
+	cfg.Tools.Health.Interval = time.Minute
+	cfg.Tools.Health.Timeout = 10 * time.Second
+	cfg.Tools.Health.MaxBackoff = 15 * time.Minute
+	cfg.Tools.Health.FailureThreshold = 3
+	cfg.Tools.Health.ErrorRateThreshold = 0.5
+	cfg.Tools.Health.MinExecutions = 10
+	cfg.Tools.Health.RecoveryProbes = 2
+
+	cfg.Tools.Artifacts.MaxSize = 50 * 1024 * 1024
+	cfg.Tools.Artifacts.MaxUserBytes = 1024 * 1024 * 1024
+	cfg.Tools.Artifacts.Retention = 30 * 24 * time.Hour
+	cfg.Tools.Artifacts.CleanupInterval = time.Hour
+
 	cfg.Tools.LangChain.EnableMemory = true
 	cfg.Tools.LangChain.MemorySize = 10
 	cfg.Tools.LangChain.MaxIterations = 5
 	cfg.Tools.LangChain.Timeout = 60 * time.Second
+	cfg.Tools.LangChain.Retrieval.Mode = "hybrid"
+	cfg.Tools.LangChain.Retrieval.Candidates = 4
+	cfg.Tools.LangChain.Retrieval.RRFK = 60
+	cfg.Tools.LangChain.Citations.MinOverlap = 0.5
+	cfg.Tools.LangChain.Citations.Unsupported = "flag"
+	cfg.Tools.LangChain.Rewriting.Enabled = true
+	cfg.Tools.LangChain.Rewriting.HistoryTurns = 6
+	cfg.Tools.LangChain.Collections.ChunkSize = 1000
+	cfg.Tools.LangChain.Collections.ChunkOverlap = 200
+	cfg.Tools.LangChain.Collections.ReembedBatchSize = 32
+	cfg.Tools.LangChain.Collections.ReembedRate = 600
 
 	// Security defaults
 	cfg.Security.JWTExpiration = 24 * time.Hour
@@ -2,10 +2,14 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/koopa0/assistant-go/internal/testutil"
+	"github.com/koopa0/assistant-go/internal/tool"
 	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
 )
 
 // MockLLM implements a simple mock LLM for testing
@@ -170,7 +174,7 @@ func TestManager_SelectBestAgent(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			agentType, err := manager.SelectBestAgent(tt.request)
+			agentType, err := manager.SelectBestAgent(context.Background(), tt.request)
 			if err != nil {
 				t.Errorf("SelectBestAgent() error = %v", err)
 				return
@@ -182,6 +186,34 @@ func TestManager_SelectBestAgent(t *testing.T) {
 	}
 }
 
+// fixedSelector selects one agent type or fails
+type fixedSelector struct {
+	agentType AgentType
+	err       error
+}
+
+func (s fixedSelector) SelectAgent(ctx context.Context, request *Request) (AgentType, error) {
+	return s.agentType, s.err
+}
+
+func TestManager_SelectBestAgentSelector(t *testing.T) {
+	manager := NewManager(&MockLLM{}, testutil.NewTestLogger())
+	request := &Request{Query: "Write a function to parse JSON"}
+
+	manager.SetSelector(fixedSelector{agentType: TypeResearch})
+	if got, _ := manager.SelectBestAgent(context.Background(), request); got != TypeResearch {
+		t.Errorf("SelectBestAgent() = %v, want the selector's %v", got, TypeResearch)
+	}
+
+	// Failed selections and unregistered agents fall back to keywords
+	for _, selector := range []fixedSelector{{err: errors.New("model unavailable")}, {agentType: "finance"}} {
+		manager.SetSelector(selector)
+		if got, _ := manager.SelectBestAgent(context.Background(), request); got != TypeDevelopment {
+			t.Errorf("SelectBestAgent() with %+v = %v, want %v", selector, got, TypeDevelopment)
+		}
+	}
+}
+
 func TestDevelopmentAgent_Execute(t *testing.T) {
 	logger := testutil.NewTestLogger()
 	mockLLM := &MockLLM{response: "function parseJSON(data string) { return json.Unmarshal(data) }\nImplementation complete"}
@@ -212,3 +244,211 @@ func TestDevelopmentAgent_Execute(t *testing.T) {
 		t.Error("Execute() should have steps")
 	}
 }
+
+// scriptedLLM replies with the next of its replies on each call and
+// records the messages it was sent
+type scriptedLLM struct {
+	replies  []string
+	calls    int
+	messages [][]llms.MessageContent
+}
+
+func (m *scriptedLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *scriptedLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.messages = append(m.messages, messages)
+	reply := m.replies[min(m.calls, len(m.replies)-1)]
+	m.calls++
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			Content:        reply,
+			GenerationInfo: map[string]any{"InputTokens": 100, "OutputTokens": 20},
+		}},
+	}, nil
+}
+
+// recordingTool returns a fixed output and records its inputs
+type recordingTool struct {
+	name   string
+	output string
+	inputs []string
+}
+
+func (t *recordingTool) Name() string        { return t.name }
+func (t *recordingTool) Description() string { return "Test tool" }
+func (t *recordingTool) Call(ctx context.Context, input string) (string, error) {
+	t.inputs = append(t.inputs, input)
+	return t.output, nil
+}
+
+// staticToolProvider provides a fixed set of tools
+type staticToolProvider map[string]tools.Tool
+
+func (p staticToolProvider) GetLangChainTool(name string, toolContext *tool.ToolContext, policy *tool.Policy) (tools.Tool, error) {
+	if !policy.Enabled(name) {
+		return nil, errors.New("tool disabled")
+	}
+	t, ok := p[name]
+	if !ok {
+		return nil, errors.New("tool not registered")
+	}
+	return t, nil
+}
+
+func (p staticToolProvider) CreateToolsForAgent(agentType string, toolContext *tool.ToolContext, policy *tool.Policy) ([]tools.Tool, error) {
+	all := make([]tools.Tool, 0, len(p))
+	for name, t := range p {
+		if policy.Enabled(name) {
+			all = append(all, t)
+		}
+	}
+	return all, nil
+}
+
+// memoryRecorder keeps recorded executions
+type memoryRecorder struct {
+	responses []*Response
+}
+
+func (r *memoryRecorder) RecordExecution(ctx context.Context, agentType AgentType, request *Request, response *Response) error {
+	r.responses = append(r.responses, response)
+	return nil
+}
+
+func TestBaseAgent_ReActLoop(t *testing.T) {
+	logger := testutil.NewTestLogger()
+	llm := &scriptedLLM{replies: []string{
+		"Thought: I should look at the table.\nAction: postgres\nAction Input: ```json\n{\"query\": \"SELECT count(*) FROM users\"}\n```",
+		"Thought: The tool answered.\nFinal Answer: There are 42 users.",
+	}}
+	postgres := &recordingTool{name: "postgres", output: `{"count": 42}`}
+	recorder := &memoryRecorder{}
+
+	agent := NewDatabaseAgent(llm, logger)
+	agent.SetRuntime(Runtime{
+		Tools:         staticToolProvider{"postgres": postgres},
+		Recorder:      recorder,
+		MaxIterations: 5,
+	})
+
+	resp, err := agent.Execute(context.Background(), &Request{Query: "How many users are there?"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !resp.Success || resp.Result != "There are 42 users." {
+		t.Fatalf("Execute() = %+v, want the final answer", resp)
+	}
+
+	if len(postgres.inputs) != 1 || postgres.inputs[0] != `{"query": "SELECT count(*) FROM users"}` {
+		t.Errorf("tool inputs = %q", postgres.inputs)
+	}
+	if len(resp.Steps) != 2 {
+		t.Fatalf("steps = %d, want 2", len(resp.Steps))
+	}
+	step := resp.Steps[0]
+	if step.Thought != "I should look at the table." || step.Action != "postgres" || step.Observation != `{"count": 42}` {
+		t.Errorf("first step = %+v", step)
+	}
+	if resp.Steps[1].Action != FinalAnswer {
+		t.Errorf("last step action = %q, want %q", resp.Steps[1].Action, FinalAnswer)
+	}
+	if resp.TokensUsed != 240 {
+		t.Errorf("TokensUsed = %d, want 240", resp.TokensUsed)
+	}
+
+	// The observation is fed back before the second step
+	second := llm.messages[1]
+	last := second[len(second)-1].Parts[0].(llms.TextContent).Text
+	if last != `Observation: {"count": 42}` {
+		t.Errorf("last message of second step = %q", last)
+	}
+
+	if len(recorder.responses) != 1 || recorder.responses[0] != resp {
+		t.Error("execution was not recorded")
+	}
+}
+
+func TestBaseAgent_ToolPolicy(t *testing.T) {
+	logger := testutil.NewTestLogger()
+	llm := &scriptedLLM{replies: []string{
+		"Thought: I should query the table.\nAction: postgres\nAction Input: {\"query\": \"SELECT 1\"}",
+		"Final Answer: I cannot query the database.",
+	}}
+	postgres := &recordingTool{name: "postgres", output: "1"}
+
+	agent := NewDatabaseAgent(llm, logger)
+	agent.SetRuntime(Runtime{Tools: staticToolProvider{"postgres": postgres}})
+
+	disabled := false
+	resp, err := agent.Execute(context.Background(), &Request{
+		Query:  "Query the table",
+		Tools:  []string{"postgres"},
+		Policy: tool.NewPolicy(nil, tool.ToolSettings{"postgres": {Enabled: &disabled}}),
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(postgres.inputs) != 0 {
+		t.Errorf("tool inputs = %q, want the disabled tool not called", postgres.inputs)
+	}
+	if len(resp.Steps) == 0 || !strings.Contains(resp.Steps[0].Observation, `unknown tool "postgres"`) {
+		t.Errorf("steps = %+v, want the disabled tool unavailable", resp.Steps)
+	}
+}
+
+func TestBaseAgent_StepBudget(t *testing.T) {
+	logger := testutil.NewTestLogger()
+	llm := &scriptedLLM{replies: []string{"Thought: Try again.\nAction: unknown\nAction Input: {}"}}
+
+	agent := NewBaseAgent(TypeGeneral, llm, logger)
+	agent.SetRuntime(Runtime{MaxIterations: 3})
+
+	// Requests cannot exceed the configured budget
+	resp, err := agent.Execute(context.Background(), &Request{Query: "Loop", MaxSteps: 10})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Success {
+		t.Error("Execute() should fail without a final answer")
+	}
+	if len(resp.Steps) != 3 || llm.calls != 3 {
+		t.Errorf("steps = %d, calls = %d, want 3", len(resp.Steps), llm.calls)
+	}
+	if !strings.Contains(resp.Steps[0].Observation, `unknown tool "unknown"`) {
+		t.Errorf("observation = %q, want an unknown tool error", resp.Steps[0].Observation)
+	}
+}
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  Step
+	}{
+		{
+			name:  "action",
+			reply: "Thought: Search first.\nAction: web_search\nAction Input: {\"query\": \"go 1.24\"}",
+			want:  Step{Thought: "Search first.", Action: "web_search", ActionInput: `{"query": "go 1.24"}`},
+		},
+		{
+			name:  "final_answer",
+			reply: "Thought: Done.\nFinal Answer: Use errors.Join.",
+			want:  Step{Thought: "Done.", Action: FinalAnswer, Result: "Use errors.Join."},
+		},
+		{
+			name:  "plain_reply",
+			reply: "Use errors.Join.",
+			want:  Step{Action: FinalAnswer, Result: "Use errors.Join."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseStep(tt.reply); got != tt.want {
+				t.Errorf("parseStep() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
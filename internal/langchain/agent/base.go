@@ -7,14 +7,24 @@ import (
 	"time"
 
 	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/tools"
+
+	"github.com/koopa0/assistant-go/internal/tool"
 )
 
-// BaseAgent provides common functionality for all agents
+// defaultMaxSteps is the step budget without a configured one
+const defaultMaxSteps = 5
+
+// BaseAgent provides common functionality for all agents. It answers in
+// a reason-act-observe loop, calling tools until it has a final answer or
+// runs out of steps.
 type BaseAgent struct {
-	agentType AgentType
-	llm       llms.Model
-	logger    *slog.Logger
-	tools     []string
+	agentType    AgentType
+	llm          llms.Model
+	logger       *slog.Logger
+	tools        []string
+	instructions string // domain guidance added to the system prompt
+	runtime      Runtime
 }
 
 // NewBaseAgent creates a new base agent
@@ -27,7 +37,14 @@ func NewBaseAgent(agentType AgentType, llm llms.Model, logger *slog.Logger) *Bas
 	}
 }
 
-// Execute implements the Agent interface with basic execution logic
+// SetRuntime gives the agent its tools, recorder and step budget
+func (a *BaseAgent) SetRuntime(runtime Runtime) {
+	a.runtime = runtime
+}
+
+// Execute implements the Agent interface with a reason-act-observe loop.
+// Each step asks the LLM for a thought and either a tool call, whose
+// output is fed back as an observation, or a final answer.
 func (a *BaseAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
 	start := time.Now()
 
@@ -46,25 +63,41 @@ func (a *BaseAgent) Execute(ctx context.Context, request *Request) (*Response, e
 		slog.String("query", request.Query),
 		slog.Int("max_steps", request.MaxSteps))
 
-	// Execute steps
-	steps := []Step{}
-	result := ""
+	response, err := a.run(ctx, request)
+	response.ExecutionTime = time.Since(start)
+	for _, step := range response.Steps {
+		response.TokensUsed += step.InputTokens + step.OutputTokens
+	}
+	a.record(ctx, request, response)
+	return response, err
+}
 
+// run executes the steps of a request
+func (a *BaseAgent) run(ctx context.Context, request *Request) (*Response, error) {
+	if a.llm == nil {
+		return &Response{Result: "No LLM configured", Success: true}, nil
+	}
+
+	toolset := a.resolveTools(request)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, a.systemPrompt(toolset)),
+		llms.TextParts(llms.ChatMessageTypeHuman, a.taskPrompt(request)),
+	}
+
+	var steps []Step
 	for i := 0; i < request.MaxSteps; i++ {
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
 			return &Response{
-				Result:        "Execution cancelled",
-				Success:       false,
-				ExecutionTime: time.Since(start),
-				Steps:         steps,
+				Result:  "Execution cancelled",
+				Success: false,
+				Steps:   steps,
 			}, ctx.Err()
 		default:
 		}
 
-		// Execute a step
-		stepResult, done, err := a.executeStep(ctx, request, i, result)
+		step, reply, err := a.executeStep(ctx, request, messages, toolset)
 		if err != nil {
 			a.logger.Error("Step execution failed",
 				slog.String("agent_type", string(a.agentType)),
@@ -72,96 +105,168 @@ func (a *BaseAgent) Execute(ctx context.Context, request *Request) (*Response, e
 				slog.String("error", err.Error()))
 
 			return &Response{
-				Result:        fmt.Sprintf("Step %d failed: %v", i, err),
-				Success:       false,
-				ExecutionTime: time.Since(start),
-				Steps:         steps,
+				Result:  fmt.Sprintf("Step %d failed: %v", i, err),
+				Success: false,
+				Steps:   steps,
 			}, nil
 		}
+		steps = append(steps, step)
 
-		steps = append(steps, Step{
-			Action: fmt.Sprintf("Step %d", i),
-			Result: stepResult,
-		})
-
-		result = stepResult
-
-		if done {
-			break
+		if step.Action == FinalAnswer {
+			return &Response{
+				Result:     step.Result,
+				Success:    true,
+				Confidence: 0.8, // Default confidence
+				Steps:      steps,
+			}, nil
 		}
+
+		// Feed the action and its observation back for the next step
+		messages = append(messages,
+			llms.TextParts(llms.ChatMessageTypeAI, reply),
+			llms.TextParts(llms.ChatMessageTypeHuman, "Observation: "+step.Observation),
+		)
 	}
 
 	return &Response{
-		Result:        result,
-		Success:       true,
-		Confidence:    0.8, // Default confidence
-		ExecutionTime: time.Since(start),
-		Steps:         steps,
+		Result:  fmt.Sprintf("No final answer within %d steps", request.MaxSteps),
+		Success: false,
+		Steps:   steps,
 	}, nil
 }
 
-// validateRequest validates the agent request
-func (a *BaseAgent) validateRequest(request *Request) error {
-	if request.Query == "" {
-		return fmt.Errorf("query is required")
-	}
+// executeStep asks the LLM for the next step and runs its tool call. It
+// returns the step and the LLM's reply for the conversation history.
+func (a *BaseAgent) executeStep(ctx context.Context, request *Request, messages []llms.MessageContent, toolset map[string]tools.Tool) (Step, string, error) {
+	start := time.Now()
 
-	if request.MaxSteps <= 0 {
-		request.MaxSteps = 5 // Default
+	options := []llms.CallOption{
+		llms.WithTemperature(request.Temperature),
+		// Observations come from tools, not from the model
+		llms.WithStopWords([]string{"\nObservation:"}),
 	}
+	resp, err := a.llm.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return Step{}, "", fmt.Errorf("LLM generation failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return Step{}, "", fmt.Errorf("LLM returned no choices")
+	}
+	choice := resp.Choices[0]
 
-	if request.Temperature <= 0 {
-		request.Temperature = 0.7 // Default
+	step := parseStep(choice.Content)
+	step.InputTokens, step.OutputTokens = tokenUsage(choice.GenerationInfo)
+	if step.Action != FinalAnswer {
+		step.Observation = a.callTool(ctx, toolset, step.Action, step.ActionInput)
 	}
+	step.Duration = time.Since(start)
 
-	return nil
+	a.logger.Debug("Agent step completed",
+		slog.String("agent_type", string(a.agentType)),
+		slog.String("action", step.Action),
+		slog.Int("output_tokens", step.OutputTokens),
+		slog.Duration("duration", step.Duration))
+
+	return step, choice.Content, nil
 }
 
-// executeStep executes a single step - to be overridden by specific agents
-func (a *BaseAgent) executeStep(ctx context.Context, request *Request, stepNum int, previousResult string) (string, bool, error) {
-	// Base implementation - just use LLM directly
-	if a.llm == nil {
-		return "No LLM configured", true, nil
+// callTool runs a tool call and returns the observation; failures are
+// observations too, so the agent can recover from them
+func (a *BaseAgent) callTool(ctx context.Context, toolset map[string]tools.Tool, name, input string) string {
+	t, ok := toolset[name]
+	if !ok {
+		return fmt.Sprintf("Error: unknown tool %q. Available tools: %s", name, toolNames(toolset))
+	}
+	output, err := t.Call(ctx, input)
+	if err != nil {
+		a.logger.Warn("Agent tool call failed",
+			slog.String("agent_type", string(a.agentType)),
+			slog.String("tool", name),
+			slog.Any("error", err))
+		return fmt.Sprintf("Error: %v", err)
 	}
+	return truncateObservation(output)
+}
 
-	// Build prompt
-	prompt := a.buildPrompt(request, stepNum, previousResult)
+// resolveTools returns the tools of a request by name: those requested,
+// or the agent type's defaults, as far as the request's policy enables
+// them. They run for the request's user and conversation.
+func (a *BaseAgent) resolveTools(request *Request) map[string]tools.Tool {
+	toolset := make(map[string]tools.Tool)
+	provider := a.runtime.Tools
+	if provider == nil {
+		return toolset
+	}
 
-	// Generate response
-	response, err := llms.GenerateFromSinglePrompt(ctx, a.llm, prompt)
-	if err != nil {
-		return "", false, fmt.Errorf("LLM generation failed: %w", err)
+	names := request.Tools
+	if len(names) == 0 {
+		names = a.tools
 	}
 
-	// Simple completion check - if response contains "DONE" or "COMPLETE"
-	done := false
-	if stepNum >= request.MaxSteps-1 {
-		done = true
+	toolContext := &tool.ToolContext{
+		UserID:         request.UserID,
+		ConversationID: request.ConversationID,
+	}
+	var resolved []tools.Tool
+	if len(names) == 0 {
+		var err error
+		if resolved, err = provider.CreateToolsForAgent(string(a.agentType), toolContext, request.Policy); err != nil {
+			a.logger.Warn("Failed to create agent tools",
+				slog.String("agent_type", string(a.agentType)),
+				slog.Any("error", err))
+		}
+	}
+	for _, name := range names {
+		t, err := provider.GetLangChainTool(name, toolContext, request.Policy)
+		if err != nil {
+			a.logger.Warn("Agent tool unavailable",
+				slog.String("agent_type", string(a.agentType)),
+				slog.String("tool", name),
+				slog.Any("error", err))
+			continue
+		}
+		resolved = append(resolved, t)
 	}
 
-	return response, done, nil
+	for _, t := range resolved {
+		toolset[t.Name()] = t
+	}
+	return toolset
 }
 
-// buildPrompt builds the prompt for the LLM
-func (a *BaseAgent) buildPrompt(request *Request, stepNum int, previousResult string) string {
-	prompt := fmt.Sprintf("You are a %s agent. ", a.agentType)
+// record persists the execution when a recorder is configured
+func (a *BaseAgent) record(ctx context.Context, request *Request, response *Response) {
+	if a.runtime.Recorder == nil {
+		return
+	}
+	// Record cancelled executions too
+	if err := a.runtime.Recorder.RecordExecution(context.WithoutCancel(ctx), a.agentType, request, response); err != nil {
+		a.logger.Warn("Failed to record agent execution",
+			slog.String("agent_type", string(a.agentType)),
+			slog.Any("error", err))
+	}
+}
 
-	if stepNum == 0 {
-		prompt += fmt.Sprintf("User query: %s\n", request.Query)
+// validateRequest validates the agent request and applies defaults. A
+// configured step budget caps the steps a request may ask for.
+func (a *BaseAgent) validateRequest(request *Request) error {
+	if request.Query == "" {
+		return fmt.Errorf("query is required")
+	}
 
-		if len(request.Context) > 0 {
-			prompt += "Context:\n"
-			for k, v := range request.Context {
-				prompt += fmt.Sprintf("- %s: %v\n", k, v)
-			}
+	if request.MaxSteps <= 0 {
+		request.MaxSteps = defaultMaxSteps
+		if a.runtime.MaxIterations > 0 {
+			request.MaxSteps = a.runtime.MaxIterations
 		}
+	}
+	if budget := a.runtime.MaxIterations; budget > 0 && request.MaxSteps > budget {
+		request.MaxSteps = budget
+	}
 
-		prompt += "\nProvide a helpful response."
-	} else {
-		prompt += fmt.Sprintf("Continue working on: %s\n", request.Query)
-		prompt += fmt.Sprintf("Previous result: %s\n", previousResult)
-		prompt += "\nContinue or finalize your response."
+	if request.Temperature <= 0 {
+		request.Temperature = 0.7 // Default
 	}
 
-	return prompt
+	return nil
 }
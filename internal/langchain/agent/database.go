@@ -2,13 +2,23 @@ package agent
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
-	"strings"
 
 	"github.com/tmc/langchaingo/llms"
 )
 
+// databaseInstructions guide the database agent's reasoning
+const databaseInstructions = `You are an expert database agent specializing in PostgreSQL, query optimization, and database design.
+
+Guidelines:
+- Use PostgreSQL 17+ best practices
+- Optimize queries for performance
+- Consider indexes and query planning
+- Use proper constraints and data types
+- Follow normalization principles where appropriate
+- Include comments for complex queries
+- Inspect schemas and query plans with the database tools before advising`
+
 // DatabaseAgent specializes in database operations and SQL queries
 type DatabaseAgent struct {
 	*BaseAgent
@@ -16,72 +26,13 @@ type DatabaseAgent struct {
 
 // NewDatabaseAgent creates a new database agent
 func NewDatabaseAgent(llm llms.Model, logger *slog.Logger) *DatabaseAgent {
+	base := NewBaseAgent(TypeDatabase, llm, logger)
+	base.instructions = databaseInstructions
 	return &DatabaseAgent{
-		BaseAgent: NewBaseAgent(TypeDatabase, llm, logger),
+		BaseAgent: base,
 	}
 }
 
-// executeStep overrides base implementation for database-specific logic
-func (a *DatabaseAgent) executeStep(ctx context.Context, request *Request, stepNum int, previousResult string) (string, bool, error) {
-	// Build database-specific prompt
-	prompt := a.buildDatabasePrompt(request, stepNum, previousResult)
-
-	// Generate response
-	response, err := llms.GenerateFromSinglePrompt(ctx, a.llm, prompt)
-	if err != nil {
-		return "", false, fmt.Errorf("LLM generation failed: %w", err)
-	}
-
-	// Check for completion indicators
-	done := false
-	if stepNum >= request.MaxSteps-1 ||
-		strings.Contains(strings.ToLower(response), "query complete") ||
-		strings.Contains(strings.ToLower(response), "migration complete") {
-		done = true
-	}
-
-	return response, done, nil
-}
-
-// buildDatabasePrompt builds specialized prompts for database tasks
-func (a *DatabaseAgent) buildDatabasePrompt(request *Request, stepNum int, previousResult string) string {
-	prompt := "You are an expert database agent specializing in PostgreSQL, query optimization, and database design.\n\n"
-
-	if stepNum == 0 {
-		prompt += fmt.Sprintf("Task: %s\n\n", request.Query)
-
-		// Add database-specific guidelines
-		prompt += "Guidelines:\n"
-		prompt += "- Use PostgreSQL 17+ best practices\n"
-		prompt += "- Optimize queries for performance\n"
-		prompt += "- Consider indexes and query planning\n"
-		prompt += "- Use proper constraints and data types\n"
-		prompt += "- Follow normalization principles where appropriate\n"
-		prompt += "- Include comments for complex queries\n\n"
-
-		if len(request.Context) > 0 {
-			prompt += "Context:\n"
-			for k, v := range request.Context {
-				// Handle schema information specially
-				if k == "schema" || k == "tables" {
-					prompt += fmt.Sprintf("- %s:\n%v\n", k, v)
-				} else {
-					prompt += fmt.Sprintf("- %s: %v\n", k, v)
-				}
-			}
-			prompt += "\n"
-		}
-
-		prompt += "Provide SQL queries, schema designs, or optimization suggestions as appropriate."
-	} else {
-		prompt += fmt.Sprintf("Continue working on: %s\n\n", request.Query)
-		prompt += fmt.Sprintf("Previous work:\n%s\n\n", previousResult)
-		prompt += "Continue the query/migration or finalize with 'Query complete' or 'Migration complete'."
-	}
-
-	return prompt
-}
-
 // Execute implements the Agent interface with database-specific execution
 func (a *DatabaseAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
 	// Log database-specific request details
@@ -89,6 +40,6 @@ func (a *DatabaseAgent) Execute(ctx context.Context, request *Request) (*Respons
 		slog.String("query", request.Query),
 		slog.Any("has_schema_context", request.Context["schema"] != nil))
 
-	// Delegate to base implementation which will call our overridden executeStep
+	// Delegate to the base reason-act-observe loop
 	return a.BaseAgent.Execute(ctx, request)
 }
@@ -12,10 +12,23 @@ import (
 
 // Manager manages and coordinates multiple agents
 type Manager struct {
-	agents map[AgentType]Agent
-	llm    llms.Model
-	logger *slog.Logger
-	mu     sync.RWMutex
+	agents   map[AgentType]Agent
+	llm      llms.Model
+	logger   *slog.Logger
+	runtime  Runtime
+	selector Selector
+	mu       sync.RWMutex
+}
+
+// Selector picks the agent for a request; the router, which classifies
+// requests with a model, implements it
+type Selector interface {
+	SelectAgent(ctx context.Context, request *Request) (AgentType, error)
+}
+
+// runtimeAgent is implemented by agents that can act with a Runtime
+type runtimeAgent interface {
+	SetRuntime(runtime Runtime)
 }
 
 // NewManager creates a new agent manager
@@ -69,6 +82,9 @@ func (m *Manager) RegisterAgent(agentType AgentType, agent Agent) error {
 		return fmt.Errorf("agent cannot be nil")
 	}
 
+	if ra, ok := agent.(runtimeAgent); ok {
+		ra.SetRuntime(m.runtime)
+	}
 	m.agents[agentType] = agent
 	m.logger.Info("Agent registered",
 		slog.String("type", string(agentType)))
@@ -76,6 +92,28 @@ func (m *Manager) RegisterAgent(agentType AgentType, agent Agent) error {
 	return nil
 }
 
+// SetRuntime gives registered and future agents their tools, recorder
+// and step budget. Call it before executing requests.
+func (m *Manager) SetRuntime(runtime Runtime) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runtime = runtime
+	for _, agent := range m.agents {
+		if ra, ok := agent.(runtimeAgent); ok {
+			ra.SetRuntime(runtime)
+		}
+	}
+}
+
+// SetSelector makes SelectBestAgent ask selector first
+func (m *Manager) SetSelector(selector Selector) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.selector = selector
+}
+
 // GetAgent retrieves an agent by type
 func (m *Manager) GetAgent(agentType AgentType) (Agent, error) {
 	m.mu.RLock()
@@ -112,18 +150,35 @@ func (m *Manager) GetAvailableAgents() []AgentType {
 	return types
 }
 
-// SelectBestAgent analyzes a request and selects the most appropriate agent
-func (m *Manager) SelectBestAgent(request *Request) (AgentType, error) {
-	// Simple heuristic-based selection
-	// In a real implementation, this could use the LLM to analyze the query
+// SelectBestAgent selects the most appropriate agent for a request: the
+// selector's choice when one is set and its agent is registered, else the
+// keyword heuristic's
+func (m *Manager) SelectBestAgent(ctx context.Context, request *Request) (AgentType, error) {
+	m.mu.RLock()
+	selector := m.selector
+	m.mu.RUnlock()
+
+	if selector != nil {
+		agentType, err := selector.SelectAgent(ctx, request)
+		if err == nil {
+			if _, err = m.GetAgent(agentType); err == nil {
+				return agentType, nil
+			}
+		}
+		m.logger.Warn("Agent selector failed, using keyword heuristic",
+			slog.Any("error", err))
+	}
 
-	query := request.Query
+	return KeywordAgent(request.Query), nil
+}
 
+// KeywordAgent selects an agent type by the keywords of a query
+func KeywordAgent(query string) AgentType {
 	// Check for development-related keywords
 	developmentKeywords := []string{"code", "implement", "function", "class", "bug", "error", "test", "refactor", "go", "golang"}
 	for _, keyword := range developmentKeywords {
 		if containsIgnoreCase(query, keyword) {
-			return TypeDevelopment, nil
+			return TypeDevelopment
 		}
 	}
 
@@ -131,7 +186,7 @@ func (m *Manager) SelectBestAgent(request *Request) (AgentType, error) {
 	databaseKeywords := []string{"sql", "query", "database", "postgres", "table", "index", "migration", "schema"}
 	for _, keyword := range databaseKeywords {
 		if containsIgnoreCase(query, keyword) {
-			return TypeDatabase, nil
+			return TypeDatabase
 		}
 	}
 
@@ -139,7 +194,7 @@ func (m *Manager) SelectBestAgent(request *Request) (AgentType, error) {
 	infraKeywords := []string{"docker", "kubernetes", "k8s", "deploy", "container", "ci/cd", "cloud", "aws", "gcp"}
 	for _, keyword := range infraKeywords {
 		if containsIgnoreCase(query, keyword) {
-			return TypeInfrastructure, nil
+			return TypeInfrastructure
 		}
 	}
 
@@ -147,12 +202,12 @@ func (m *Manager) SelectBestAgent(request *Request) (AgentType, error) {
 	researchKeywords := []string{"research", "analyze", "compare", "explain", "documentation", "best practices", "learn"}
 	for _, keyword := range researchKeywords {
 		if containsIgnoreCase(query, keyword) {
-			return TypeResearch, nil
+			return TypeResearch
 		}
 	}
 
 	// Default to general agent
-	return TypeGeneral, nil
+	return TypeGeneral
 }
 
 // containsIgnoreCase checks if a string contains a substring (case-insensitive)
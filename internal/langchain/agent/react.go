@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/tools"
+
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// maxObservationLength bounds the tool output fed back to the LLM
+const maxObservationLength = 8000
+
+// systemPrompt describes the agent, its tools and the step format
+func (a *BaseAgent) systemPrompt(toolset map[string]tools.Tool) string {
+	var b strings.Builder
+	if a.instructions != "" {
+		b.WriteString(a.instructions)
+	} else {
+		fmt.Fprintf(&b, "You are a %s agent.", a.agentType)
+	}
+	b.WriteString("\n\n")
+
+	if len(toolset) == 0 {
+		b.WriteString("No tools are available. Answer directly in this format:\n\n")
+		b.WriteString("Thought: your reasoning\nFinal Answer: your answer\n")
+		return b.String()
+	}
+
+	b.WriteString("You can use these tools:\n\n")
+	for _, name := range sortedToolNames(toolset) {
+		t := toolset[name]
+		fmt.Fprintf(&b, "- %s: %s\n", name, t.Description())
+		if p, ok := t.(interface {
+			Parameters() *tool.ToolParametersSchema
+		}); ok && p.Parameters() != nil {
+			if schema, err := json.Marshal(p.Parameters()); err == nil {
+				fmt.Fprintf(&b, "  Parameters: %s\n", schema)
+			}
+		}
+	}
+
+	b.WriteString("\nWork in steps. To use a tool, respond with exactly:\n\n")
+	b.WriteString("Thought: your reasoning\nAction: the tool name\nAction Input: a JSON object of the tool's parameters\n\n")
+	b.WriteString("You will then receive an Observation with the tool's output. ")
+	b.WriteString("Use one tool per response. When you can answer, respond with:\n\n")
+	b.WriteString("Thought: your reasoning\nFinal Answer: your answer\n")
+	return b.String()
+}
+
+// taskPrompt states the query and its context
+func (a *BaseAgent) taskPrompt(request *Request) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Task: %s\n", request.Query)
+
+	if len(request.Context) > 0 {
+		b.WriteString("\nContext:\n")
+		keys := make([]string, 0, len(request.Context))
+		for k := range request.Context {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "- %s: %v\n", k, request.Context[k])
+		}
+	}
+	return b.String()
+}
+
+// parseStep reads the thought and the action or final answer of an LLM
+// reply. A reply in neither format is taken as the final answer.
+func parseStep(reply string) Step {
+	reply = strings.TrimSpace(reply)
+	var step Step
+
+	if i := strings.Index(reply, "Final Answer:"); i >= 0 {
+		step.Thought = thought(reply[:i])
+		step.Action = FinalAnswer
+		step.Result = strings.TrimSpace(reply[i+len("Final Answer:"):])
+		return step
+	}
+
+	action := strings.Index(reply, "Action:")
+	if action < 0 {
+		step.Action = FinalAnswer
+		step.Result = reply
+		return step
+	}
+	step.Thought = thought(reply[:action])
+
+	rest := reply[action+len("Action:"):]
+	name, input, _ := strings.Cut(rest, "Action Input:")
+	step.Action = strings.Trim(strings.TrimSpace(name), "`\"")
+	step.ActionInput = actionInput(input)
+	return step
+}
+
+// thought strips the "Thought:" label from the text before an action
+func thought(text string) string {
+	text = strings.TrimSpace(text)
+	return strings.TrimSpace(strings.TrimPrefix(text, "Thought:"))
+}
+
+// actionInput strips code fences around an action input
+func actionInput(input string) string {
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "```json")
+	input = strings.TrimPrefix(input, "```")
+	input = strings.TrimSuffix(input, "```")
+	return strings.TrimSpace(input)
+}
+
+// tokenUsage reads the token counts providers report in the generation
+// info: Anthropic uses InputTokens/OutputTokens, Google AI input_tokens/
+// output_tokens
+func tokenUsage(info map[string]any) (input, output int) {
+	count := func(keys ...string) int {
+		for _, key := range keys {
+			switch v := info[key].(type) {
+			case int:
+				return v
+			case int32:
+				return int(v)
+			case int64:
+				return int(v)
+			case float64:
+				return int(v)
+			}
+		}
+		return 0
+	}
+	return count("InputTokens", "input_tokens", "PromptTokens"),
+		count("OutputTokens", "output_tokens", "CompletionTokens")
+}
+
+// truncateObservation bounds tool output so long results do not exhaust
+// the context window
+func truncateObservation(output string) string {
+	if len(output) <= maxObservationLength {
+		return output
+	}
+	return output[:maxObservationLength] + "\n... (truncated)"
+}
+
+// sortedToolNames returns the names of a toolset in order
+func sortedToolNames(toolset map[string]tools.Tool) []string {
+	names := make([]string, 0, len(toolset))
+	for name := range toolset {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// toolNames lists the names of a toolset for error observations
+func toolNames(toolset map[string]tools.Tool) string {
+	if len(toolset) == 0 {
+		return "none"
+	}
+	return strings.Join(sortedToolNames(toolset), ", ")
+}
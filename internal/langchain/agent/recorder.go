@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// QueriesRecorder implements ExecutionRecorder on top of the
+// agent_executions table
+type QueriesRecorder struct {
+	queries *sqlc.Queries
+}
+
+// NewQueriesRecorder creates an execution recorder backed by sqlc queries
+func NewQueriesRecorder(queries *sqlc.Queries) *QueriesRecorder {
+	return &QueriesRecorder{
+		queries: queries,
+	}
+}
+
+// RecordExecution records an execution with its steps. agent_executions
+// rows belong to a user, so executions without a user id are not recorded.
+func (r *QueriesRecorder) RecordExecution(ctx context.Context, agentType AgentType, request *Request, response *Response) error {
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		return nil
+	}
+	var conversationID pgtype.UUID
+	if id, err := uuid.Parse(request.ConversationID); err == nil {
+		conversationID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+
+	steps, err := json.Marshal(response.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to encode steps: %w", err)
+	}
+	metadata, err := json.Marshal(map[string]interface{}{
+		"tools":       request.Tools,
+		"max_steps":   request.MaxSteps,
+		"tokens_used": response.TokensUsed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	var errorMessage string
+	if !response.Success {
+		errorMessage = response.Result
+	}
+	_, err = r.queries.CreateAgentExecution(ctx, sqlc.CreateAgentExecutionParams{
+		AgentType:       string(agentType),
+		Column2:         pgtype.UUID{Bytes: userID, Valid: true},
+		ConversationID:  conversationID,
+		Query:           request.Query,
+		Response:        pgtype.Text{String: response.Result, Valid: response.Success},
+		Steps:           steps,
+		ExecutionTimeMs: pgtype.Int4{Int32: int32(response.ExecutionTime.Milliseconds()), Valid: true},
+		Success:         pgtype.Bool{Bool: response.Success, Valid: true},
+		ErrorMessage:    pgtype.Text{String: errorMessage, Valid: errorMessage != ""},
+		Metadata:        metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record agent execution: %w", err)
+	}
+	return nil
+}
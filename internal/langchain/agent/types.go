@@ -3,6 +3,10 @@ package agent
 import (
 	"context"
 	"time"
+
+	"github.com/tmc/langchaingo/tools"
+
+	"github.com/koopa0/assistant-go/internal/tool"
 )
 
 // AgentType represents the type of agent
@@ -16,13 +20,19 @@ const (
 	TypeResearch       AgentType = "research"
 )
 
-// Request represents an agent request
+// Request represents an agent request. Tools names the registry tools the
+// agent may call; without them the agent type's default tools are used.
+// Tools run for the request's user and conversation with Policy, the
+// tool settings resolved for them; a nil Policy enables every tool.
 type Request struct {
-	Query       string                 `json:"query"`
-	Context     map[string]interface{} `json:"context"`
-	Tools       []string               `json:"tools"`
-	MaxSteps    int                    `json:"max_steps"`
-	Temperature float64                `json:"temperature"`
+	Query          string                 `json:"query"`
+	Context        map[string]interface{} `json:"context"`
+	Tools          []string               `json:"tools"`
+	MaxSteps       int                    `json:"max_steps"`
+	Temperature    float64                `json:"temperature"`
+	UserID         string                 `json:"user_id,omitempty"`
+	ConversationID string                 `json:"conversation_id,omitempty"`
+	Policy         *tool.Policy           `json:"-"`
 }
 
 // Response represents an agent response
@@ -32,12 +42,45 @@ type Response struct {
 	Confidence    float64       `json:"confidence"`
 	ExecutionTime time.Duration `json:"execution_time"`
 	Steps         []Step        `json:"steps"`
+	TokensUsed    int           `json:"tokens_used"`
 }
 
-// Step represents a single step in agent execution
+// FinalAnswer is the action of the step that answers the query
+const FinalAnswer = "final_answer"
+
+// Step represents a single reason-act-observe step in agent execution.
+// Action is the tool called, or FinalAnswer with the answer in Result.
 type Step struct {
-	Action string `json:"action"`
-	Result string `json:"result"`
+	Thought      string        `json:"thought,omitempty"`
+	Action       string        `json:"action"`
+	ActionInput  string        `json:"action_input,omitempty"`
+	Observation  string        `json:"observation,omitempty"`
+	Result       string        `json:"result,omitempty"`
+	InputTokens  int           `json:"input_tokens"`
+	OutputTokens int           `json:"output_tokens"`
+	Duration     time.Duration `json:"duration"`
+}
+
+// ToolProvider supplies the tools agents call for the caller in
+// toolContext, as far as policy enables them; *tool.ToolRegistry
+// implements it with LangChain tool adapters
+type ToolProvider interface {
+	GetLangChainTool(name string, toolContext *tool.ToolContext, policy *tool.Policy) (tools.Tool, error)
+	CreateToolsForAgent(agentType string, toolContext *tool.ToolContext, policy *tool.Policy) ([]tools.Tool, error)
+}
+
+// ExecutionRecorder persists finished agent executions
+type ExecutionRecorder interface {
+	RecordExecution(ctx context.Context, agentType AgentType, request *Request, response *Response) error
+}
+
+// Runtime is what agents act with. The zero value leaves agents without
+// tools, records nothing and uses the default step budget.
+type Runtime struct {
+	Tools    ToolProvider
+	Recorder ExecutionRecorder
+	// MaxIterations caps the steps of a request
+	MaxIterations int
 }
 
 // SimpleManager manages agents
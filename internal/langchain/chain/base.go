@@ -29,6 +29,7 @@ type ChainRequest struct {
 	MaxSteps    int                    `json:"max_steps,omitempty"`
 	Temperature float64                `json:"temperature,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	History     []Turn                 `json:"history,omitempty"` // conversation before the input, to resolve follow-up questions
 }
 
 // ChainResponse represents the response from chain execution
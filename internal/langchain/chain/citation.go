@@ -0,0 +1,416 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain/documentloader"
+)
+
+// Unsupported claim policies
+const (
+	UnsupportedFlag = "flag"
+	UnsupportedDrop = "drop"
+)
+
+// Claim verification methods
+const (
+	CheckedLexical = "lexical"
+	CheckedLLM     = "llm"
+)
+
+// unsupportedMarker is appended to flagged claims
+const unsupportedMarker = " [unsupported]"
+
+// citationMarker matches inline citations such as [2] or [1, 3]
+var citationMarker = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// sentenceEnd matches the end of a sentence, with the citations following
+// its punctuation, up to the whitespace before the next sentence
+var sentenceEnd = regexp.MustCompile(`[.!?]+((?:\s*\[\d+(?:\s*,\s*\d+)*\])*)\s+`)
+
+// Citation identifies a numbered chunk of a RAG prompt and where in its
+// source the chunk was found
+type Citation struct {
+	Number      int    `json:"number"`
+	ChunkID     string `json:"chunk_id"`
+	ContentType string `json:"content_type,omitempty"`
+	Source      string `json:"source,omitempty"`   // file path or URL
+	Location    string `json:"location,omitempty"` // e.g. "lines 10-24" or "page 3"
+	StartLine   int    `json:"start_line,omitempty"`
+	EndLine     int    `json:"end_line,omitempty"`
+	Page        int    `json:"page,omitempty"`
+}
+
+// Claim is a sentence of an answer with the chunks it cites. Sentences
+// without citations are not verified.
+type Claim struct {
+	Text      string  `json:"text"`
+	Citations []int   `json:"citations,omitempty"`
+	Overlap   float64 `json:"overlap"` // share of claim words found in the cited chunks
+	Supported bool    `json:"supported"`
+	Checked   string  `json:"checked,omitempty"` // lexical or llm
+}
+
+// newCitation describes chunk number n from its metadata
+func newCitation(n int, chunkID, contentType string, metadata map[string]any) Citation {
+	citation := Citation{
+		Number:      n,
+		ChunkID:     chunkID,
+		ContentType: contentType,
+		Location:    documentloader.Location(metadata),
+	}
+	for _, key := range []string{"path", "source", "file_path", "file_name"} {
+		if source, ok := metadata[key].(string); ok && source != "" {
+			citation.Source = source
+			break
+		}
+	}
+	citation.StartLine = metadataInt(metadata["start_line"])
+	citation.EndLine = metadataInt(metadata["end_line"])
+	citation.Page = metadataInt(metadata["page"])
+	return citation
+}
+
+// String formats the citation as a source line, such as
+// "[2] internal/x.go (lines 10-24)"
+func (c Citation) String() string {
+	source := c.Source
+	if source == "" {
+		source = c.ChunkID
+	}
+	if c.Location != "" {
+		return fmt.Sprintf("[%d] %s (%s)", c.Number, source, c.Location)
+	}
+	return fmt.Sprintf("[%d] %s", c.Number, source)
+}
+
+// metadataInt reads a number from metadata, which holds float64 after a
+// JSON round trip through the database
+func metadataInt(value any) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// citationPrompt is the instruction added to prompts with numbered chunks
+const citationPrompt = `Cite the numbered sources you use inline, directly after each statement they support, as [1] or [1, 3]. Only state what the sources support; say so when they do not answer the question.`
+
+// numberedContext formats chunks as the numbered sources of a prompt
+func numberedContext(citations []Citation, contents []string) string {
+	var b strings.Builder
+	for i, citation := range citations {
+		b.WriteString(citation.String())
+		b.WriteString("\n")
+		b.WriteString(strings.TrimSpace(contents[i]))
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// CitationVerifier checks the inline citations of an answer against the
+// chunks they cite
+type CitationVerifier struct {
+	llm    llms.Model
+	config config.Citations
+	logger *slog.Logger
+}
+
+// NewCitationVerifier creates a verifier. llm checks claims when the
+// configuration asks for it and may be nil otherwise.
+func NewCitationVerifier(llm llms.Model, cfg config.Citations, logger *slog.Logger) *CitationVerifier {
+	if cfg.MinOverlap == 0 {
+		cfg.MinOverlap = 0.5
+	}
+	if cfg.Unsupported == "" {
+		cfg.Unsupported = UnsupportedFlag
+	}
+	return &CitationVerifier{llm: llm, config: cfg, logger: logger}
+}
+
+// Verify splits answer into claims and checks each cited claim against
+// chunks, which are numbered from 1. A claim is supported when enough of
+// its words occur in one of its chunks or, with the LLM check, when the
+// model says a chunk supports it; citations of unknown chunks are never
+// supported. It returns the answer with unsupported claims flagged or
+// dropped, and the claims.
+func (v *CitationVerifier) Verify(ctx context.Context, answer string, chunks []string) (string, []Claim) {
+	type sentence struct {
+		line  int
+		claim int // index into claims, -1 for text that is not a claim
+		text  string
+	}
+
+	var (
+		claims    []Claim
+		sentences []sentence
+		fence     bool
+	)
+	lines := strings.Split(answer, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			fence = !fence
+		}
+		if fence || strings.HasPrefix(trimmed, "```") || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			sentences = append(sentences, sentence{line: i, claim: -1, text: line})
+			continue
+		}
+		for _, text := range splitSentences(line) {
+			claims = append(claims, Claim{Text: strings.TrimSpace(text), Citations: citedNumbers(text)})
+			sentences = append(sentences, sentence{line: i, claim: len(claims) - 1, text: text})
+		}
+	}
+
+	var checked []int
+	for i := range claims {
+		claim := &claims[i]
+		if len(claim.Citations) == 0 {
+			continue
+		}
+		claim.Checked = CheckedLexical
+		valid := true
+		words := contentWords(citationMarker.ReplaceAllString(claim.Text, ""))
+		for _, n := range claim.Citations {
+			if n < 1 || n > len(chunks) {
+				valid = false
+				continue
+			}
+			claim.Overlap = max(claim.Overlap, overlap(words, chunks[n-1]))
+		}
+		claim.Supported = valid && claim.Overlap >= v.config.MinOverlap
+		if valid {
+			checked = append(checked, i)
+		}
+	}
+
+	if v.config.LLMCheck && v.llm != nil && len(checked) > 0 {
+		verdicts, err := v.checkWithLLM(ctx, claims, checked, chunks)
+		if err != nil {
+			v.logger.Warn("LLM citation check failed, keeping lexical verification", slog.Any("error", err))
+		} else {
+			for j, i := range checked {
+				claims[i].Supported = verdicts[j]
+				claims[i].Checked = CheckedLLM
+			}
+		}
+	}
+
+	// Rebuild the answer line by line; lines whose claims were all dropped
+	// are left out
+	var (
+		out      []string
+		current  = -1
+		parts    []string
+		anyClaim bool
+		kept     bool
+	)
+	flush := func() {
+		if current >= 0 && (!anyClaim || kept) {
+			out = append(out, strings.TrimRight(strings.Join(parts, ""), " "))
+		}
+		parts, anyClaim, kept = nil, false, false
+	}
+	for _, s := range sentences {
+		if s.line != current {
+			flush()
+			current = s.line
+		}
+		if s.claim < 0 {
+			parts = append(parts, s.text)
+			continue
+		}
+		anyClaim = true
+		claim := claims[s.claim]
+		switch {
+		case claim.Supported || claim.Checked == "":
+			parts = append(parts, s.text)
+			kept = true
+		case v.config.Unsupported == UnsupportedDrop:
+		default:
+			text := strings.TrimRight(s.text, " \t")
+			parts = append(parts, text+unsupportedMarker+s.text[len(text):])
+			kept = true
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n"), claims
+}
+
+// checkWithLLM asks the model whether each checked claim is supported by
+// its chunks, in a single call
+func (v *CitationVerifier) checkWithLLM(ctx context.Context, claims []Claim, checked []int, chunks []string) ([]bool, error) {
+	var b strings.Builder
+	b.WriteString("For each numbered claim below, decide whether the quoted sources fully support it.\n")
+	b.WriteString("Reply with only a JSON array of booleans, one per claim, in order.\n\n")
+	for j, i := range checked {
+		claim := claims[i]
+		fmt.Fprintf(&b, "Claim %d: %s\n", j+1, citationMarker.ReplaceAllString(claim.Text, ""))
+		for _, n := range claim.Citations {
+			fmt.Fprintf(&b, "Source [%d]: %s\n", n, strings.TrimSpace(chunks[n-1]))
+		}
+		b.WriteString("\n")
+	}
+
+	response, err := v.llm.Call(ctx, b.String(), llms.WithTemperature(0))
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(response, "["), strings.LastIndex(response, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON array in response: %q", response)
+	}
+	var verdicts []bool
+	if err := json.Unmarshal([]byte(response[start:end+1]), &verdicts); err != nil {
+		return nil, fmt.Errorf("failed to parse verdicts: %w", err)
+	}
+	if len(verdicts) != len(checked) {
+		return nil, fmt.Errorf("got %d verdicts for %d claims", len(verdicts), len(checked))
+	}
+	return verdicts, nil
+}
+
+// splitSentences splits a line after each sentence's punctuation and the
+// citations that follow it. The parts keep their whitespace, so joining
+// them restores the line.
+func splitSentences(line string) []string {
+	var sentences []string
+	start := 0
+	for _, m := range sentenceEnd.FindAllStringIndex(line, -1) {
+		sentences = append(sentences, line[start:m[1]])
+		start = m[1]
+	}
+	if start < len(line) {
+		sentences = append(sentences, line[start:])
+	}
+	return sentences
+}
+
+// citedNumbers returns the chunk numbers cited in text, in order and
+// without repeats
+func citedNumbers(text string) []int {
+	var numbers []int
+	seen := make(map[int]bool)
+	for _, m := range citationMarker.FindAllStringSubmatch(text, -1) {
+		for _, field := range strings.Split(m[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || seen[n] {
+				continue
+			}
+			seen[n] = true
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
+
+// CitedNumbers returns the chunk numbers cited by claims, in order of
+// first citation
+func CitedNumbers(claims []Claim) []int {
+	var numbers []int
+	seen := make(map[int]bool)
+	for _, claim := range claims {
+		for _, n := range claim.Citations {
+			if !seen[n] {
+				seen[n] = true
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	return numbers
+}
+
+// citedCitations returns the citations claims refer to, in order of
+// first citation
+func citedCitations(citations []Citation, claims []Claim) []Citation {
+	cited := make([]Citation, 0)
+	for _, n := range CitedNumbers(claims) {
+		if n >= 1 && n <= len(citations) {
+			cited = append(cited, citations[n-1])
+		}
+	}
+	return cited
+}
+
+// overlap returns the share of words found in chunk. Claims without
+// content words, like "See below.", fully overlap.
+func overlap(words []string, chunk string) float64 {
+	if len(words) == 0 {
+		return 1
+	}
+	found := make(map[string]bool)
+	for _, word := range contentWords(chunk) {
+		found[word] = true
+	}
+	matched := 0
+	for _, word := range words {
+		if found[word] {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(words))
+}
+
+// contentWords returns the distinct lowercase, stemmed words of text,
+// leaving out stopwords and words shorter than three characters
+func contentWords(text string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(field) < 3 || stopwords[field] {
+			continue
+		}
+		word := stem(field)
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// stem strips common English suffixes, so "handles" matches "handled"
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(word) > len(suffix)+3 && strings.HasSuffix(word, suffix) {
+			return word[:len(word)-len(suffix)]
+		}
+	}
+	return word
+}
+
+// stopwords are words that say nothing about whether a chunk supports a
+// claim
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "has": true, "had": true,
+	"was": true, "were": true, "its": true, "this": true, "that": true, "these": true,
+	"those": true, "with": true, "from": true, "into": true, "have": true, "been": true,
+	"will": true, "would": true, "should": true, "could": true, "which": true, "when": true,
+	"what": true, "where": true, "who": true, "how": true, "than": true, "then": true,
+	"also": true, "such": true, "their": true, "there": true, "they": true, "them": true,
+	"does": true, "did": true, "use": true, "used": true, "uses": true, "using": true,
+	"each": true, "other": true, "some": true, "more": true, "most": true, "only": true,
+	"about": true, "over": true, "both": true, "very": true, "may": true, "must": true,
+	"source": true, "sources": true, "according": true, "document": true,
+}
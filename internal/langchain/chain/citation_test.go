@@ -0,0 +1,143 @@
+package chain
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/testutil"
+)
+
+var citationChunks = []string{
+	"The scheduler retries failed jobs three times with exponential backoff.",
+	"Jobs are stored in the jobs table and leased by workers for five minutes.",
+}
+
+func TestCitationVerifier_Verify(t *testing.T) {
+	answer := "Failed jobs are retried three times with backoff [1]. Workers lease jobs for five minutes.[2]\n" +
+		"Jobs are deleted after a week [2]. See the [3] docs.\n" +
+		"\n" +
+		"```go\nx := []int{1} // [1]\n```"
+
+	tests := []struct {
+		name        string
+		unsupported string
+		want        string
+	}{
+		{
+			"flag",
+			UnsupportedFlag,
+			"Failed jobs are retried three times with backoff [1]. Workers lease jobs for five minutes.[2]\n" +
+				"Jobs are deleted after a week [2]. [unsupported] See the [3] docs. [unsupported]\n" +
+				"\n" +
+				"```go\nx := []int{1} // [1]\n```",
+		},
+		{
+			"drop",
+			UnsupportedDrop,
+			"Failed jobs are retried three times with backoff [1]. Workers lease jobs for five minutes.[2]\n" +
+				"\n" +
+				"```go\nx := []int{1} // [1]\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewCitationVerifier(nil, config.Citations{Unsupported: tt.unsupported}, testutil.NewTestLogger())
+			got, claims := verifier.Verify(context.Background(), answer, citationChunks)
+			if got != tt.want {
+				t.Errorf("Verify() answer =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			supported := make([]bool, len(claims))
+			for i, claim := range claims {
+				supported[i] = claim.Supported
+			}
+			if want := []bool{true, true, false, false}; !reflect.DeepEqual(supported, want) {
+				t.Errorf("Verify() supported = %v, want %v", supported, want)
+			}
+			if !reflect.DeepEqual(claims[1].Citations, []int{2}) || claims[1].Checked != CheckedLexical {
+				t.Errorf("claim = %+v, want citation 2 checked lexically", claims[1])
+			}
+		})
+	}
+}
+
+func TestCitationVerifier_LLMCheck(t *testing.T) {
+	answer := "Failed jobs are retried three times [1]. Jobs are leased by the scheduler [1, 2]."
+
+	llm := NewMockLLM()
+	llm.SetResponse("default", "```json\n[false, true]\n```")
+	verifier := NewCitationVerifier(llm, config.Citations{LLMCheck: true}, testutil.NewTestLogger())
+	got, claims := verifier.Verify(context.Background(), answer, citationChunks)
+
+	if claims[0].Supported || !claims[1].Supported || claims[0].Checked != CheckedLLM {
+		t.Errorf("Verify() claims = %+v, want the model's verdicts", claims)
+	}
+	if want := "Failed jobs are retried three times [1]. [unsupported] Jobs are leased by the scheduler [1, 2]."; got != want {
+		t.Errorf("Verify() answer = %q, want %q", got, want)
+	}
+
+	// An unusable reply keeps the lexical verdicts
+	llm.SetResponse("default", "yes")
+	_, claims = verifier.Verify(context.Background(), answer, citationChunks)
+	if !claims[0].Supported || claims[0].Checked != CheckedLexical {
+		t.Errorf("Verify() claim = %+v, want lexical verification", claims[0])
+	}
+}
+
+func TestNewCitation(t *testing.T) {
+	citation := newCitation(2, "chunk-1", "code", map[string]any{
+		"path":       "internal/jobs/scheduler.go",
+		"start_line": float64(10),
+		"end_line":   float64(24),
+	})
+	want := Citation{
+		Number:      2,
+		ChunkID:     "chunk-1",
+		ContentType: "code",
+		Source:      "internal/jobs/scheduler.go",
+		Location:    "lines 10-24",
+		StartLine:   10,
+		EndLine:     24,
+	}
+	if citation != want {
+		t.Errorf("newCitation() = %+v, want %+v", citation, want)
+	}
+	if got := citation.String(); got != "[2] internal/jobs/scheduler.go (lines 10-24)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestRAGChain_Query(t *testing.T) {
+	llm := NewMockLLM()
+	llm.SetResponse("default", "A mock document is related to retries [1]. The moon is made of cheese [2].")
+	ragChain := NewRAGChain(llm, config.LangChain{}, testutil.NewTestLogger())
+
+	result, err := ragChain.Query(context.Background(), "retries", nil)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if len(result.Sources) != 2 || len(result.Citations) != 2 || len(result.Claims) != 2 {
+		t.Fatalf("Query() = %d sources, %d citations, %d claims; want 2 each",
+			len(result.Sources), len(result.Citations), len(result.Claims))
+	}
+	if !result.Claims[0].Supported || result.Claims[1].Supported {
+		t.Errorf("Query() claims = %+v, want only the first supported", result.Claims)
+	}
+	if !strings.Contains(result.Answer, "cheese [2]. [unsupported]") || !strings.Contains(result.Answer, "**Sources:**\n- [1] mock") {
+		t.Errorf("Query() answer = %q, want the unsupported claim flagged and cited sources listed", result.Answer)
+	}
+
+	// Execute runs the same steps, including verification
+	response, err := ragChain.Execute(context.Background(), &ChainRequest{Input: "retries"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := response.Steps[len(response.Steps)-1].StepType; got != "citation_verification" {
+		t.Errorf("last step = %s, want citation_verification", got)
+	}
+}
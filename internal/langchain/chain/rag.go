@@ -1,11 +1,11 @@
 package chain
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
-	"sort"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,7 +18,6 @@ import (
 	"github.com/koopa0/assistant-go/internal/config"
 	"github.com/koopa0/assistant-go/internal/langchain/documentloader"
 	"github.com/koopa0/assistant-go/internal/langchain/vectorstore"
-	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
 )
 
@@ -30,10 +29,19 @@ type RAGChain struct {
 	embedder          embeddings.Embedder
 	docProcessor      *documentloader.DocumentProcessor
 	queries           sqlc.Querier // Direct database access
+	documentSearcher  DocumentSearcher
 	retrievalConfig   RAGRetrievalConfig
+	rewriter          *QueryRewriter
 	langchainRAGChain chains.Chain // Native LangChain RAG chain
 }
 
+// DocumentSearcher retrieves chunks in place of the chain's own search,
+// such as a search of the collections a caller may read. It embeds
+// queries itself.
+type DocumentSearcher interface {
+	Search(ctx context.Context, query string, limit int, threshold float64) ([]vectorstore.SearchResult, error)
+}
+
 // RAGRetrievalConfig configures the retrieval behavior
 type RAGRetrievalConfig struct {
 	MaxDocuments        int      `json:"max_documents"`
@@ -41,6 +49,34 @@ type RAGRetrievalConfig struct {
 	ContentTypes        []string `json:"content_types"`
 	IncludeMetadata     bool     `json:"include_metadata"`
 	RetrievalStrategy   string   `json:"retrieval_strategy"` // "similarity", "hybrid", "keyword"
+	PathPrefix          string   `json:"path_prefix,omitempty"`
+	Tags                []string `json:"tags,omitempty"`
+	Candidates          int      `json:"candidates,omitempty"`   // candidates per document for fusion and reranking
+	Reranker            string   `json:"reranker,omitempty"`     // "", "llm" or "cross_encoder"
+	RerankerURL         string   `json:"reranker_url,omitempty"` // cross-encoder endpoint
+	MMRLambda           float64  `json:"mmr_lambda,omitempty"`   // 0 disables MMR diversification
+}
+
+// defaultRetrievalConfig returns the retrieval configuration of new RAG
+// chains, seeded from the retrieval settings
+func defaultRetrievalConfig(cfg config.LangChain) RAGRetrievalConfig {
+	strategy := cfg.Retrieval.Mode
+	switch strategy {
+	case "", vectorstore.ModeVector:
+		strategy = "similarity"
+	}
+
+	return RAGRetrievalConfig{
+		MaxDocuments:        5,
+		SimilarityThreshold: 0.7,
+		ContentTypes:        []string{"message", "document", "code"},
+		IncludeMetadata:     true,
+		RetrievalStrategy:   strategy,
+		Candidates:          cfg.Retrieval.Candidates,
+		Reranker:            cfg.Retrieval.Reranker,
+		RerankerURL:         cfg.Retrieval.RerankerURL,
+		MMRLambda:           cfg.Retrieval.MMRLambda,
+	}
 }
 
 // RetrievedDocument represents a document retrieved from the knowledge base
@@ -52,16 +88,27 @@ type RetrievedDocument struct {
 	Similarity  float64                `json:"similarity"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	RetrievedAt time.Time              `json:"retrieved_at"`
+	Queries     []string               `json:"queries,omitempty"` // the rewritten queries that found it
 }
 
 // RAGContext represents the context built from retrieved documents
 type RAGContext struct {
-	Query             string              `json:"query"`
+	Query             string              `json:"query"`             // standalone when rewritten
+	Queries           []string            `json:"queries,omitempty"` // queries documents were retrieved for
 	RetrievedDocs     []RetrievedDocument `json:"retrieved_docs"`
 	ContextSummary    string              `json:"context_summary"`
 	RetrievalStrategy string              `json:"retrieval_strategy"`
 	TotalDocuments    int                 `json:"total_documents"`
 	AvgSimilarity     float64             `json:"avg_similarity"`
+	Citations         []Citation          `json:"citations"` // numbered like the prompt's chunks
+}
+
+// ragAnswer is the verified answer of a RAG chain run
+type ragAnswer struct {
+	Text      string
+	Context   *RAGContext
+	Claims    []Claim
+	Citations []Citation // the chunks the answer cites
 }
 
 // NewRAGChain creates a new RAG chain
@@ -69,15 +116,10 @@ func NewRAGChain(llm llms.Model, config config.LangChain, logger *slog.Logger) *
 	base := NewBaseChain(ChainTypeRAG, llm, config, logger)
 
 	chain := &RAGChain{
-		BaseChain:    base,
-		docProcessor: documentloader.NewDocumentProcessor(logger),
-		retrievalConfig: RAGRetrievalConfig{
-			MaxDocuments:        5,
-			SimilarityThreshold: 0.7,
-			ContentTypes:        []string{"message", "document", "code"},
-			IncludeMetadata:     true,
-			RetrievalStrategy:   "similarity",
-		},
+		BaseChain:       base,
+		docProcessor:    documentloader.NewDocumentProcessor(logger),
+		retrievalConfig: defaultRetrievalConfig(config),
+		rewriter:        NewQueryRewriter(llm, config.Rewriting, logger),
 	}
 
 	return chain
@@ -88,17 +130,12 @@ func NewRAGChainWithComponents(llm llms.Model, vectorStore vectorstores.VectorSt
 	base := NewBaseChain(ChainTypeRAG, llm, config, logger)
 
 	chain := &RAGChain{
-		BaseChain:    base,
-		vectorStore:  vectorStore,
-		embedder:     embedder,
-		docProcessor: documentloader.NewDocumentProcessor(logger),
-		retrievalConfig: RAGRetrievalConfig{
-			MaxDocuments:        5,
-			SimilarityThreshold: 0.7,
-			ContentTypes:        []string{"message", "document", "code"},
-			IncludeMetadata:     true,
-			RetrievalStrategy:   "similarity",
-		},
+		BaseChain:       base,
+		vectorStore:     vectorStore,
+		embedder:        embedder,
+		docProcessor:    documentloader.NewDocumentProcessor(logger),
+		retrievalConfig: defaultRetrievalConfig(config),
+		rewriter:        NewQueryRewriter(llm, config.Rewriting, logger),
 	}
 
 	// Set up retriever if vector store is available
@@ -172,6 +209,12 @@ func (rc *RAGChain) SetQueries(queries sqlc.Querier) {
 	rc.logger.Debug("Database queries set for RAG chain")
 }
 
+// SetDocumentSearcher sets the searcher documents are retrieved with
+func (rc *RAGChain) SetDocumentSearcher(searcher DocumentSearcher) {
+	rc.documentSearcher = searcher
+	rc.logger.Debug("Document searcher set for RAG chain")
+}
+
 // SetRetrievalConfig sets the retrieval configuration
 func (rc *RAGChain) SetRetrievalConfig(config RAGRetrievalConfig) {
 	rc.retrievalConfig = config
@@ -181,46 +224,184 @@ func (rc *RAGChain) SetRetrievalConfig(config RAGRetrievalConfig) {
 		slog.String("strategy", config.RetrievalStrategy))
 }
 
+// Execute runs the RAG steps. BaseChain.Execute would only call the LLM,
+// as it cannot dispatch to executeSteps of embedding types.
+func (rc *RAGChain) Execute(ctx context.Context, request *ChainRequest) (*ChainResponse, error) {
+	startTime := time.Now()
+
+	if err := rc.validateRequest(request); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	answer, steps, err := rc.run(ctx, request)
+	response := &ChainResponse{
+		Steps:         steps,
+		ExecutionTime: time.Since(startTime),
+		Metadata: map[string]interface{}{
+			"chain_type":     string(rc.chainType),
+			"steps_executed": len(steps),
+		},
+	}
+	if err != nil {
+		response.Error = err.Error()
+		return response, err
+	}
+
+	response.Output = answer.Text
+	response.Success = true
+	response.Metadata["citations"] = answer.Citations
+	response.Metadata["claims"] = answer.Claims
+
+	return response, nil
+}
+
+// Query answers a question from the knowledge base with verified inline
+// citations. A follow-up question is rewritten with the conversation
+// before it, history, into a standalone query.
+func (rc *RAGChain) Query(ctx context.Context, query string, history []Turn) (*RAGQueryResult, error) {
+	request := &ChainRequest{Input: query, History: history}
+	if err := rc.validateRequest(request); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	answer, steps, err := rc.run(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]DocumentSource, 0, len(answer.Context.RetrievedDocs))
+	for i, doc := range answer.Context.RetrievedDocs {
+		citation := answer.Context.Citations[i]
+		sources = append(sources, DocumentSource{
+			ID:       doc.ID,
+			Source:   citation.Source,
+			Location: citation.Location,
+			Content:  doc.Content,
+			Score:    doc.Similarity,
+			Metadata: doc.Metadata,
+			Queries:  doc.Queries,
+		})
+	}
+
+	unsupported := 0
+	for _, claim := range answer.Claims {
+		if claim.Checked != "" && !claim.Supported {
+			unsupported++
+		}
+	}
+
+	return &RAGQueryResult{
+		Query:     query,
+		Answer:    answer.Text,
+		Sources:   sources,
+		Citations: answer.Citations,
+		Claims:    answer.Claims,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"method":             "rag_chain",
+			"documents_used":     len(answer.Context.RetrievedDocs),
+			"retrieval_strategy": answer.Context.RetrievalStrategy,
+			"retrieval_queries":  answer.Context.Queries,
+			"unsupported_claims": unsupported,
+			"steps":              steps,
+		},
+	}, nil
+}
+
 // executeSteps implements RAG chain execution logic
 func (rc *RAGChain) executeSteps(ctx context.Context, request *ChainRequest) (string, []ChainStep, error) {
+	answer, steps, err := rc.run(ctx, request)
+	if err != nil {
+		return "", steps, err
+	}
+	return answer.Text, steps, nil
+}
+
+// run retrieves documents, generates an answer citing them and verifies
+// the citations
+func (rc *RAGChain) run(ctx context.Context, request *ChainRequest) (*ragAnswer, []ChainStep, error) {
 	steps := make([]ChainStep, 0)
 
 	rc.logger.Debug("Starting RAG chain execution",
 		slog.String("query", request.Input),
 		slog.Int("max_documents", rc.retrievalConfig.MaxDocuments))
 
-	// Step 1: Query Analysis and Embedding Generation
+	// Rewrite a follow-up question into a standalone query, with more
+	// queries to search when configured
+	queries := []string{request.Input}
 	stepStart := time.Now()
-	queryEmbedding, err := rc.generateQueryEmbedding(ctx, request.Input)
-	if err != nil {
-		return "", steps, fmt.Errorf("query embedding generation failed: %w", err)
+	rewritten, err := rc.rewriter.Rewrite(ctx, request.Input, request.History)
+	if rewritten != nil {
+		queries = rewritten.Queries()
+		step := ChainStep{
+			StepNumber: len(steps) + 1,
+			StepType:   "query_rewriting",
+			Input:      request.Input,
+			Output:     strings.Join(queries, "\n"),
+			Duration:   time.Since(stepStart),
+			Success:    err == nil,
+			Metadata: map[string]interface{}{
+				"standalone_query":    rewritten.Standalone,
+				"sub_queries":         rewritten.SubQueries,
+				"hypothetical_answer": rewritten.Hypothetical,
+				"history_turns":       len(request.History),
+			},
+		}
+		if err != nil {
+			// Retrieval goes on with the queries rewritten so far
+			step.Error = err.Error()
+			rc.logger.Warn("Query rewriting failed", slog.Any("error", err))
+		}
+		steps = append(steps, step)
 	}
 
+	// Query Analysis and Embedding Generation
+	stepStart = time.Now()
+	queryEmbeddings := make([][]float64, len(queries))
+	for i, query := range queries {
+		queryEmbeddings[i], err = rc.generateQueryEmbedding(ctx, query)
+		if err != nil {
+			return nil, steps, fmt.Errorf("query embedding generation failed: %w", err)
+		}
+	}
+
+	dimension := len(queryEmbeddings[0])
 	step1 := ChainStep{
-		StepNumber: 1,
+		StepNumber: len(steps) + 1,
 		StepType:   "query_embedding",
-		Input:      request.Input,
-		Output:     fmt.Sprintf("Generated embedding vector (dimension: %d)", len(queryEmbedding)),
+		Input:      strings.Join(queries, "\n"),
+		Output:     fmt.Sprintf("Generated %d embedding vectors (dimension: %d)", len(queries), dimension),
 		Duration:   time.Since(stepStart),
 		Success:    true,
 		Metadata: map[string]interface{}{
-			"embedding_dimension": len(queryEmbedding),
+			"embedding_dimension": dimension,
 			"query_length":        len(request.Input),
+			"queries":             len(queries),
 		},
 	}
 	steps = append(steps, step1)
 
-	// Step 2: Document Retrieval
+	// Document Retrieval, merging the documents of each query
 	stepStart = time.Now()
-	retrievedDocs, err := rc.retrieveRelevantDocuments(ctx, queryEmbedding, request.Input)
-	if err != nil {
-		return "", steps, fmt.Errorf("document retrieval failed: %w", err)
+	retrieved := make([][]RetrievedDocument, len(queries))
+	perQuery := make([]map[string]interface{}, len(queries))
+	for i, query := range queries {
+		retrieved[i], err = rc.retrieveRelevantDocuments(ctx, queryEmbeddings[i], query)
+		if err != nil {
+			return nil, steps, fmt.Errorf("document retrieval failed: %w", err)
+		}
+		ids := make([]string, len(retrieved[i]))
+		for j, doc := range retrieved[i] {
+			ids[j] = doc.ID
+		}
+		perQuery[i] = map[string]interface{}{"query": query, "documents": ids}
 	}
+	retrievedDocs := rc.mergeRetrieved(queries, retrieved)
 
 	step2 := ChainStep{
-		StepNumber: 2,
+		StepNumber: len(steps) + 1,
 		StepType:   "document_retrieval",
-		Input:      fmt.Sprintf("Query embedding + similarity search"),
+		Input:      fmt.Sprintf("%d queries: embedding + similarity search", len(queries)),
 		Output:     fmt.Sprintf("Retrieved %d documents", len(retrievedDocs)),
 		Duration:   time.Since(stepStart),
 		Success:    true,
@@ -228,19 +409,21 @@ func (rc *RAGChain) executeSteps(ctx context.Context, request *ChainRequest) (st
 			"documents_retrieved": len(retrievedDocs),
 			"avg_similarity":      rc.calculateAverageSimilarity(retrievedDocs),
 			"retrieval_strategy":  rc.retrievalConfig.RetrievalStrategy,
+			"queries":             perQuery,
 		},
 	}
 	steps = append(steps, step2)
 
-	// Step 3: Context Building
+	// Context Building, for the standalone question
 	stepStart = time.Now()
-	ragContext, err := rc.buildRAGContext(ctx, request.Input, retrievedDocs)
+	ragContext, err := rc.buildRAGContext(ctx, queries[0], retrievedDocs)
 	if err != nil {
-		return "", steps, fmt.Errorf("context building failed: %w", err)
+		return nil, steps, fmt.Errorf("context building failed: %w", err)
 	}
+	ragContext.Queries = queries
 
 	step3 := ChainStep{
-		StepNumber: 3,
+		StepNumber: len(steps) + 1,
 		StepType:   "context_building",
 		Input:      fmt.Sprintf("%d retrieved documents", len(retrievedDocs)),
 		Output:     fmt.Sprintf("Built context summary (%d characters)", len(ragContext.ContextSummary)),
@@ -254,15 +437,15 @@ func (rc *RAGChain) executeSteps(ctx context.Context, request *ChainRequest) (st
 	}
 	steps = append(steps, step3)
 
-	// Step 4: Augmented Generation
+	// Augmented Generation
 	stepStart = time.Now()
 	augmentedResponse, err := rc.generateAugmentedResponse(ctx, request, ragContext)
 	if err != nil {
-		return "", steps, fmt.Errorf("augmented generation failed: %w", err)
+		return nil, steps, fmt.Errorf("augmented generation failed: %w", err)
 	}
 
 	step4 := ChainStep{
-		StepNumber: 4,
+		StepNumber: len(steps) + 1,
 		StepType:   "augmented_generation",
 		Input:      fmt.Sprintf("Query + Context (%d chars)", len(ragContext.ContextSummary)),
 		Output:     augmentedResponse,
@@ -276,18 +459,59 @@ func (rc *RAGChain) executeSteps(ctx context.Context, request *ChainRequest) (st
 	}
 	steps = append(steps, step4)
 
+	// Citation Verification
+	stepStart = time.Now()
+	answer := rc.verifyCitations(ctx, augmentedResponse, ragContext)
+
+	supported := 0
+	for _, claim := range answer.Claims {
+		if claim.Supported {
+			supported++
+		}
+	}
+	step5 := ChainStep{
+		StepNumber: len(steps) + 1,
+		StepType:   "citation_verification",
+		Input:      fmt.Sprintf("%d claims", len(answer.Claims)),
+		Output:     fmt.Sprintf("%d of %d cited sources, %d claims supported", len(answer.Citations), len(ragContext.Citations), supported),
+		Duration:   time.Since(stepStart),
+		Success:    true,
+		Metadata: map[string]interface{}{
+			"claims":           len(answer.Claims),
+			"supported_claims": supported,
+			"cited_sources":    len(answer.Citations),
+		},
+	}
+	steps = append(steps, step5)
+
 	rc.logger.Info("RAG chain execution completed",
 		slog.Int("steps_executed", len(steps)),
 		slog.Int("documents_used", len(retrievedDocs)),
-		slog.Int("response_length", len(augmentedResponse)))
+		slog.Int("response_length", len(answer.Text)))
 
-	return augmentedResponse, steps, nil
+	return answer, steps, nil
 }
 
 // generateQueryEmbedding generates an embedding for the query
 func (rc *RAGChain) generateQueryEmbedding(ctx context.Context, query string) ([]float64, error) {
-	// For now, return a mock embedding since we don't have direct embedding generation
-	// In a real implementation, this would call an embedding service
+	if rc.documentSearcher != nil {
+		// The document searcher embeds the query for each collection
+		return nil, nil
+	}
+	if rc.embedder != nil {
+		vector, err := rc.embedder.EmbedQuery(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		embedding := make([]float64, len(vector))
+		for i, v := range vector {
+			embedding[i] = float64(v)
+		}
+		return embedding, nil
+	}
+
+	// Without an embedder, return a mock embedding; hybrid retrieval
+	// still finds documents by full text
 	mockEmbedding := make([]float64, 1536) // OpenAI embedding dimension
 	for i := range mockEmbedding {
 		mockEmbedding[i] = 0.1 // Simple mock values
@@ -300,62 +524,37 @@ func (rc *RAGChain) generateQueryEmbedding(ctx context.Context, query string) ([
 	return mockEmbedding, nil
 }
 
-// retrieveRelevantDocuments retrieves documents similar to the query
+// retrieveRelevantDocuments retrieves documents relevant to the query by
+// vector similarity, full text or both, as the retrieval strategy says
 func (rc *RAGChain) retrieveRelevantDocuments(ctx context.Context, queryEmbedding []float64, query string) ([]RetrievedDocument, error) {
-	if rc.queries == nil {
+	var results []vectorstore.SearchResult
+	var err error
+	switch {
+	case rc.documentSearcher != nil:
+		results, err = rc.documentSearcher.Search(ctx, query, rc.retrievalConfig.MaxDocuments, rc.retrievalConfig.SimilarityThreshold)
+	case rc.queries != nil:
+		results, err = rc.searchDocuments(ctx, queryEmbedding, query)
+	default:
 		// Return mock documents if no database queries are available
 		return rc.getMockDocuments(query), nil
 	}
-
-	retrievedDocs := make([]RetrievedDocument, 0)
-
-	// Search for similar embeddings for each content type
-	for _, contentType := range rc.retrievalConfig.ContentTypes {
-		searchResults, err := rc.queries.SearchSimilarEmbeddings(ctx, sqlc.SearchSimilarEmbeddingsParams{
-			QueryEmbedding: postgres.VectorToPgVector(queryEmbedding),
-			ContentType:    contentType,
-			Threshold:      rc.retrievalConfig.SimilarityThreshold,
-			ResultLimit:    int32(rc.retrievalConfig.MaxDocuments),
+	if err != nil {
+		return nil, err
+	}
+
+	retrievedDocs := make([]RetrievedDocument, 0, len(results))
+	for _, result := range results {
+		retrievedDocs = append(retrievedDocs, RetrievedDocument{
+			ID:          result.ID,
+			ContentType: result.ContentType,
+			ContentID:   result.ContentID,
+			Content:     result.Content,
+			Similarity:  result.Similarity,
+			Metadata:    result.Metadata,
+			RetrievedAt: time.Now(),
 		})
-		if err != nil {
-			rc.logger.Warn("Failed to search embeddings",
-				slog.String("content_type", contentType),
-				slog.Any("error", err))
-			continue
-		}
-
-		// Convert search results to retrieved documents
-		for _, result := range searchResults {
-			// Convert similarity from int32 to float64 (percentage)
-			similarityFloat := float64(result.Similarity) / 100.0
-
-			doc := RetrievedDocument{
-				ID:          postgres.UUIDToString(result.ID),
-				ContentType: result.ContentType,
-				ContentID:   postgres.UUIDToString(result.ContentID),
-				Content:     result.ContentText,
-				Similarity:  similarityFloat,
-				Metadata:    nil, // Parse JSON metadata if needed
-				RetrievedAt: time.Now(),
-			}
-
-			// Parse metadata if available
-			if len(result.Metadata) > 0 {
-				var metadata map[string]interface{}
-				if err := json.Unmarshal(result.Metadata, &metadata); err == nil {
-					doc.Metadata = metadata
-				}
-			}
-
-			retrievedDocs = append(retrievedDocs, doc)
-		}
 	}
 
-	// Sort by similarity (highest first) using sort.Slice
-	sort.Slice(retrievedDocs, func(i, j int) bool {
-		return retrievedDocs[i].Similarity > retrievedDocs[j].Similarity
-	})
-
 	// Limit results
 	if len(retrievedDocs) > rc.retrievalConfig.MaxDocuments {
 		retrievedDocs = retrievedDocs[:rc.retrievalConfig.MaxDocuments]
@@ -368,6 +567,88 @@ func (rc *RAGChain) retrieveRelevantDocuments(ctx context.Context, queryEmbeddin
 	return retrievedDocs, nil
 }
 
+// searchDocuments searches stored chunks with the chain's retrieval
+// configuration
+func (rc *RAGChain) searchDocuments(ctx context.Context, queryEmbedding []float64, query string) ([]vectorstore.SearchResult, error) {
+	mode := rc.retrievalConfig.RetrievalStrategy
+	if mode == "similarity" {
+		mode = vectorstore.ModeVector
+	}
+	searcher := vectorstore.NewSearcher(rc.queries, vectorstore.SearchOptions{
+		Mode:       mode,
+		Candidates: rc.retrievalConfig.Candidates,
+		RRFK:       rc.config.Retrieval.RRFK,
+		MMRLambda:  rc.retrievalConfig.MMRLambda,
+		Reranker:   vectorstore.NewReranker(rc.retrievalConfig.Reranker, rc.retrievalConfig.RerankerURL, rc.llm),
+	}, rc.logger)
+
+	embedding := make([]float32, len(queryEmbedding))
+	for i, v := range queryEmbedding {
+		embedding[i] = float32(v)
+	}
+	return searcher.Search(ctx, query, embedding, rc.retrievalConfig.MaxDocuments, vectorstore.Filter{
+		ContentTypes: rc.retrievalConfig.ContentTypes,
+		PathPrefix:   rc.retrievalConfig.PathPrefix,
+		Tags:         rc.retrievalConfig.Tags,
+	}, rc.retrievalConfig.SimilarityThreshold)
+}
+
+// mergeRetrieved merges the documents retrieved for each query by
+// reciprocal rank, so documents found by several queries rise, and keeps
+// each document once, with the queries that found it. The documents of a
+// single query are returned as retrieved.
+func (rc *RAGChain) mergeRetrieved(queries []string, retrieved [][]RetrievedDocument) []RetrievedDocument {
+	if len(retrieved) == 1 {
+		return retrieved[0]
+	}
+	k := rc.config.Retrieval.RRFK
+	if k <= 0 {
+		k = 60
+	}
+
+	// Indexes into merged by document ID and by content, as the same text
+	// may be stored more than once
+	byID, byContent := make(map[string]int), make(map[string]int)
+	var merged []RetrievedDocument
+	var scores []float64
+	for i, docs := range retrieved {
+		for rank, doc := range docs {
+			index, ok := byID[doc.ID]
+			if !ok && doc.Content != "" {
+				index, ok = byContent[doc.Content]
+			}
+			if !ok {
+				index = len(merged)
+				byID[doc.ID], byContent[doc.Content] = index, index
+				doc.Queries = nil
+				merged = append(merged, doc)
+				scores = append(scores, 0)
+			}
+			merged[index].Similarity = max(merged[index].Similarity, doc.Similarity)
+			if !slices.Contains(merged[index].Queries, queries[i]) {
+				merged[index].Queries = append(merged[index].Queries, queries[i])
+			}
+			scores[index] += 1 / float64(k+rank+1)
+		}
+	}
+
+	order := make([]int, len(merged))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	docs := make([]RetrievedDocument, 0, len(merged))
+	for _, i := range order {
+		docs = append(docs, merged[i])
+	}
+	if len(docs) > rc.retrievalConfig.MaxDocuments {
+		docs = docs[:rc.retrievalConfig.MaxDocuments]
+	}
+	return docs
+}
+
 // getMockDocuments returns mock documents for testing
 func (rc *RAGChain) getMockDocuments(query string) []RetrievedDocument {
 	return []RetrievedDocument{
@@ -405,20 +686,19 @@ func (rc *RAGChain) buildRAGContext(ctx context.Context, query string, docs []Re
 		}, nil
 	}
 
-	// Build context summary from retrieved documents
-	contextBuilder := strings.Builder{}
-	contextBuilder.WriteString("Relevant context from knowledge base:\n\n")
-
+	// Number the retrieved documents for the answer to cite
+	citations := make([]Citation, len(docs))
+	contents := make([]string, len(docs))
 	for i, doc := range docs {
-		contextBuilder.WriteString(fmt.Sprintf("Document %d (Similarity: %.2f):\n", i+1, doc.Similarity))
-		contextBuilder.WriteString(fmt.Sprintf("Type: %s\n", doc.ContentType))
-		contextBuilder.WriteString(fmt.Sprintf("Content: %s\n\n", doc.Content))
+		citations[i] = newCitation(i+1, doc.ID, doc.ContentType, doc.Metadata)
+		contents[i] = doc.Content
 	}
 
 	ragContext := &RAGContext{
 		Query:             query,
 		RetrievedDocs:     docs,
-		ContextSummary:    contextBuilder.String(),
+		ContextSummary:    numberedContext(citations, contents),
+		Citations:         citations,
 		RetrievalStrategy: rc.retrievalConfig.RetrievalStrategy,
 		TotalDocuments:    len(docs),
 		AvgSimilarity:     rc.calculateAverageSimilarity(docs),
@@ -435,7 +715,7 @@ func (rc *RAGChain) buildRAGContext(ctx context.Context, query string, docs []Re
 // generateAugmentedResponse generates the final response using retrieved context
 func (rc *RAGChain) generateAugmentedResponse(ctx context.Context, request *ChainRequest, ragContext *RAGContext) (string, error) {
 	// Build the augmented prompt
-	prompt := rc.buildAugmentedPrompt(request.Input, ragContext)
+	prompt := rc.buildAugmentedPrompt(ragContext.Query, ragContext)
 
 	// Generate response using LLM
 	options := []llms.CallOption{
@@ -451,12 +731,27 @@ func (rc *RAGChain) generateAugmentedResponse(ctx context.Context, request *Chai
 		return "", fmt.Errorf("LLM generation failed: %w", err)
 	}
 
-	// Add source attribution if documents were used
-	if len(ragContext.RetrievedDocs) > 0 {
-		response += rc.buildSourceAttribution(ragContext.RetrievedDocs)
+	return response, nil
+}
+
+// verifyCitations checks the answer's inline citations against the
+// retrieved documents and lists the cited sources below it
+func (rc *RAGChain) verifyCitations(ctx context.Context, response string, ragContext *RAGContext) *ragAnswer {
+	answer := &ragAnswer{Text: response, Context: ragContext, Citations: []Citation{}}
+	if len(ragContext.RetrievedDocs) == 0 {
+		return answer
 	}
 
-	return response, nil
+	contents := make([]string, len(ragContext.RetrievedDocs))
+	for i, doc := range ragContext.RetrievedDocs {
+		contents[i] = doc.Content
+	}
+	verifier := NewCitationVerifier(rc.llm, rc.config.Citations, rc.logger)
+	answer.Text, answer.Claims = verifier.Verify(ctx, response, contents)
+	answer.Citations = citedCitations(ragContext.Citations, answer.Claims)
+	answer.Text += rc.buildSourceAttribution(answer.Citations)
+
+	return answer
 }
 
 // buildAugmentedPrompt builds the prompt with retrieved context
@@ -466,31 +761,29 @@ func (rc *RAGChain) buildAugmentedPrompt(query string, ragContext *RAGContext) s
 		return fmt.Sprintf("Please answer the following question:\n\nQuestion: %s\n\nAnswer:", query)
 	}
 
-	// Build prompt with context
-	prompt := fmt.Sprintf(`Answer the following question using the provided context from the knowledge base. If the context doesn't contain relevant information, please indicate that and provide the best answer you can.
+	// Build prompt with numbered sources
+	prompt := fmt.Sprintf(`Answer the following question using the numbered sources from the knowledge base.
 
-Context:
+Sources:
 %s
-
 Question: %s
 
-Please provide a comprehensive answer based on the context above. If you reference specific information from the context, please indicate which document it came from.
+%s
 
-Answer:`, ragContext.ContextSummary, query)
+Answer:`, ragContext.ContextSummary, query, citationPrompt)
 
 	return prompt
 }
 
-// buildSourceAttribution builds source attribution for the response
-func (rc *RAGChain) buildSourceAttribution(docs []RetrievedDocument) string {
-	if len(docs) == 0 {
+// buildSourceAttribution lists the cited sources below the response
+func (rc *RAGChain) buildSourceAttribution(citations []Citation) string {
+	if len(citations) == 0 {
 		return ""
 	}
 
 	attribution := "\n\n---\n**Sources:**\n"
-	for i, doc := range docs {
-		attribution += fmt.Sprintf("- Document %d: %s (Type: %s, Similarity: %.2f)\n",
-			i+1, doc.ContentID, doc.ContentType, doc.Similarity)
+	for _, citation := range citations {
+		attribution += "- " + citation.String() + "\n"
 	}
 
 	return attribution
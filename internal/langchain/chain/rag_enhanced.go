@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
 	"time"
 
 	"github.com/tmc/langchaingo/embeddings"
@@ -13,7 +12,7 @@ import (
 	"github.com/tmc/langchaingo/vectorstores"
 
 	"github.com/koopa0/assistant-go/internal/config"
-	//"github.com/koopa0/assistant-go/internal/langchain/documentloader" // Used via embedded RAGChain.docProcessor
+	"github.com/koopa0/assistant-go/internal/langchain/documentloader"
 )
 
 // EnhancedRAGChain provides advanced RAG capabilities with document processing
@@ -85,7 +84,9 @@ func (erc *EnhancedRAGChain) IngestDocument(ctx context.Context, filePath string
 	return nil
 }
 
-// IngestDirectory processes and ingests all documents in a directory
+// IngestDirectory processes and ingests all documents in a directory.
+// Every file is ingested again on each call; code bases are better kept
+// up to date with the indexer package, which re-embeds only what changed.
 func (erc *EnhancedRAGChain) IngestDirectory(ctx context.Context, dirPath string, recursive bool, extensions []string, metadata map[string]any) error {
 	erc.logger.Info("Starting directory ingestion",
 		slog.String("dir_path", dirPath),
@@ -308,6 +309,7 @@ func (erc *EnhancedRAGChain) queryWithLangChainRAG(ctx context.Context, query st
 		if fileName, ok := doc.Metadata["file_name"].(string); ok {
 			source.Title = fileName
 		}
+		source.Location = documentloader.Location(doc.Metadata)
 
 		sources = append(sources, source)
 	}
@@ -325,15 +327,15 @@ func (erc *EnhancedRAGChain) queryWithLangChainRAG(ctx context.Context, query st
 	}, nil
 }
 
-// queryWithCustomRAG uses the custom RAG implementation
+// queryWithCustomRAG uses the custom RAG implementation, with numbered
+// sources and verified inline citations
 func (erc *EnhancedRAGChain) queryWithCustomRAG(ctx context.Context, query string, docs []schema.Document, options map[string]interface{}) (*RAGQueryResult, error) {
-	// Build context from documents
-	contextBuilder := strings.Builder{}
-	contextBuilder.WriteString("Based on the following information:\n\n")
-
 	sources := make([]DocumentSource, 0, len(docs))
+	citations := make([]Citation, 0, len(docs))
+	contents := make([]string, 0, len(docs))
 	for i, doc := range docs {
-		contextBuilder.WriteString(fmt.Sprintf("Source %d:\n%s\n\n", i+1, doc.PageContent))
+		citations = append(citations, newCitation(i+1, fmt.Sprintf("doc_%d", i), "document", doc.Metadata))
+		contents = append(contents, doc.PageContent)
 
 		source := DocumentSource{
 			ID:       fmt.Sprintf("doc_%d", i),
@@ -349,18 +351,22 @@ func (erc *EnhancedRAGChain) queryWithCustomRAG(ctx context.Context, query strin
 		if fileName, ok := doc.Metadata["file_name"].(string); ok {
 			source.Title = fileName
 		}
+		source.Location = documentloader.Location(doc.Metadata)
 
 		sources = append(sources, source)
 	}
 
 	// Build the prompt
-	prompt := fmt.Sprintf(`%s
+	sourceContext := numberedContext(citations, contents)
+	prompt := fmt.Sprintf(`Answer the following question using the numbered sources below.
 
+Sources:
+%s
 Question: %s
 
-Please provide a comprehensive answer based on the information above. If the information doesn't contain relevant details, please indicate that and provide what you can.
+%s
 
-Answer:`, contextBuilder.String(), query)
+Answer:`, sourceContext, query, citationPrompt)
 
 	// Generate response using LLM
 	response, err := erc.llm.Call(ctx, prompt, llms.WithMaxTokens(2000))
@@ -368,16 +374,21 @@ Answer:`, contextBuilder.String(), query)
 		return nil, fmt.Errorf("LLM generation failed: %w", err)
 	}
 
+	verifier := NewCitationVerifier(erc.llm, erc.config.Citations, erc.logger)
+	answer, claims := verifier.Verify(ctx, response, contents)
+
 	return &RAGQueryResult{
 		Query:     query,
-		Answer:    response,
+		Answer:    answer,
 		Sources:   sources,
+		Citations: citedCitations(citations, claims),
+		Claims:    claims,
 		Timestamp: time.Now(),
 		Metadata: map[string]interface{}{
 			"method":         "custom_rag",
 			"documents_used": len(docs),
 			"total_sources":  len(sources),
-			"context_length": contextBuilder.Len(),
+			"context_length": len(sourceContext),
 		},
 	}, nil
 }
@@ -412,6 +423,8 @@ type RAGQueryResult struct {
 	Query     string                 `json:"query"`
 	Answer    string                 `json:"answer"`
 	Sources   []DocumentSource       `json:"sources"`
+	Citations []Citation             `json:"citations,omitempty"` // sources the answer cites
+	Claims    []Claim                `json:"claims,omitempty"`    // answer sentences and their verification
 	Timestamp time.Time              `json:"timestamp"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -421,7 +434,9 @@ type DocumentSource struct {
 	ID       string                 `json:"id"`
 	Title    string                 `json:"title,omitempty"`
 	Source   string                 `json:"source,omitempty"`
+	Location string                 `json:"location,omitempty"` // e.g. "page 3" or "sheet Budget"
 	Content  string                 `json:"content"`
 	Score    float64                `json:"score"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Queries  []string               `json:"queries,omitempty"` // the rewritten queries that found it
 }
@@ -0,0 +1,207 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/koopa0/assistant-go/internal/config"
+)
+
+// maxTurnLength bounds the runes of each conversation message in the
+// rewriting prompt
+const maxTurnLength = 1000
+
+// listMarker matches the number or bullet before an item of a list
+var listMarker = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*•])\s+`)
+
+// Turn is a message of the conversation a query follows
+type Turn struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// RewrittenQuery is a query rewritten for retrieval
+type RewrittenQuery struct {
+	Original     string   `json:"original"`
+	Standalone   string   `json:"standalone"` // the query with the conversation's references resolved
+	SubQueries   []string `json:"sub_queries,omitempty"`
+	Hypothetical string   `json:"hypothetical,omitempty"` // HyDE answer, searched like a query
+}
+
+// Queries returns the queries to retrieve documents for: the standalone
+// query, the sub-queries and the hypothetical answer, without duplicates
+func (q *RewrittenQuery) Queries() []string {
+	queries := []string{q.Standalone}
+	seen := map[string]bool{strings.ToLower(q.Standalone): true}
+	for _, query := range append(slices.Clip(q.SubQueries), q.Hypothetical) {
+		if key := strings.ToLower(query); query != "" && !seen[key] {
+			seen[key] = true
+			queries = append(queries, query)
+		}
+	}
+	return queries
+}
+
+// QueryRewriter rewrites RAG queries before retrieval. Follow-up questions
+// such as "what about its indexes?" retrieve nothing useful by themselves,
+// so they are rewritten into standalone queries with the conversation.
+type QueryRewriter struct {
+	llm    llms.Model
+	config config.Rewriting
+	logger *slog.Logger
+}
+
+// NewQueryRewriter creates a rewriter that asks llm to rewrite queries
+func NewQueryRewriter(llm llms.Model, cfg config.Rewriting, logger *slog.Logger) *QueryRewriter {
+	if cfg.HistoryTurns == 0 {
+		cfg.HistoryTurns = 6
+	}
+	return &QueryRewriter{llm: llm, config: cfg, logger: logger}
+}
+
+// Rewrite rewrites a query with the conversation it follows, the last
+// messages of which are considered. It returns nil when there is nothing
+// to rewrite: rewriting is disabled, or the query has no history and
+// neither sub-queries nor HyDE are configured. A step that fails keeps the
+// query as far as it was rewritten and its error is returned with it.
+func (r *QueryRewriter) Rewrite(ctx context.Context, query string, history []Turn) (*RewrittenQuery, error) {
+	history = r.recent(query, history)
+	if !r.config.Enabled || r.llm == nil || len(history) == 0 && r.config.SubQueries == 0 && !r.config.HyDE {
+		return nil, nil
+	}
+
+	rewritten := &RewrittenQuery{Original: query, Standalone: query}
+	var errs []error
+	if len(history) > 0 {
+		standalone, err := r.standalone(ctx, query, history)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("standalone query: %w", err))
+		} else if standalone != "" {
+			rewritten.Standalone = standalone
+		}
+	}
+	if r.config.SubQueries > 0 {
+		subQueries, err := r.subQueries(ctx, rewritten.Standalone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sub-queries: %w", err))
+		}
+		rewritten.SubQueries = subQueries
+	}
+	if r.config.HyDE {
+		hypothetical, err := r.hypothetical(ctx, rewritten.Standalone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hypothetical answer: %w", err))
+		}
+		rewritten.Hypothetical = hypothetical
+	}
+
+	r.logger.Debug("Rewrote query",
+		slog.String("query", query),
+		slog.String("standalone", rewritten.Standalone),
+		slog.Int("sub_queries", len(rewritten.SubQueries)),
+		slog.Bool("hyde", rewritten.Hypothetical != ""))
+	return rewritten, errors.Join(errs...)
+}
+
+// recent returns the last configured turns of history before the query,
+// dropping the query itself when the conversation already records it
+func (r *QueryRewriter) recent(query string, history []Turn) []Turn {
+	if n := len(history); n > 0 && history[n-1].Role == "user" && strings.TrimSpace(history[n-1].Content) == strings.TrimSpace(query) {
+		history = history[:n-1]
+	}
+	if len(history) > r.config.HistoryTurns {
+		history = history[len(history)-r.config.HistoryTurns:]
+	}
+	return history
+}
+
+// standalone asks the model to resolve the query's references to the
+// conversation
+func (r *QueryRewriter) standalone(ctx context.Context, query string, history []Turn) (string, error) {
+	var b strings.Builder
+	b.WriteString("Rewrite the last question of the conversation below as a standalone search query. ")
+	b.WriteString("Replace pronouns and references such as \"it\" or \"that one\" with what they refer to in the conversation, ")
+	b.WriteString("and keep the language of the question. If the question already stands on its own, repeat it. ")
+	b.WriteString("Reply with only the query.\n\nConversation:\n")
+	for _, turn := range history {
+		role := "User"
+		if turn.Role == "assistant" {
+			role = "Assistant"
+		}
+		content := strings.Join(strings.Fields(turn.Content), " ")
+		if runes := []rune(content); len(runes) > maxTurnLength {
+			content = string(runes[:maxTurnLength]) + "..."
+		}
+		fmt.Fprintf(&b, "%s: %s\n", role, content)
+	}
+	fmt.Fprintf(&b, "\nQuestion: %s\n\nStandalone query:", query)
+
+	response, err := r.llm.Call(ctx, b.String(), llms.WithTemperature(0))
+	if err != nil {
+		return "", err
+	}
+	return cleanQuery(response), nil
+}
+
+// subQueries asks the model for queries looking for different aspects of
+// the query
+func (r *QueryRewriter) subQueries(ctx context.Context, query string) ([]string, error) {
+	prompt := fmt.Sprintf(`Write %d search queries that each look for a different aspect of the question below, to find the documents that answer it. Reply with one query per line and nothing else.
+
+Question: %s`, r.config.SubQueries, query)
+
+	response, err := r.llm.Call(ctx, prompt, llms.WithTemperature(0))
+	if err != nil {
+		return nil, err
+	}
+	var subQueries []string
+	for _, line := range strings.Split(response, "\n") {
+		line = cleanQuery(listMarker.ReplaceAllString(line, ""))
+		if line == "" || strings.EqualFold(line, query) {
+			continue
+		}
+		subQueries = append(subQueries, line)
+		if len(subQueries) == r.config.SubQueries {
+			break
+		}
+	}
+	return subQueries, nil
+}
+
+// hypothetical asks the model for a passage answering the query, which
+// is often closer to the documents than the question is (HyDE)
+func (r *QueryRewriter) hypothetical(ctx context.Context, query string) (string, error) {
+	prompt := fmt.Sprintf(`Write a short passage answering the question below, as it could appear in documentation. Do not mention that the passage is hypothetical.
+
+Question: %s
+
+Passage:`, query)
+
+	response, err := r.llm.Call(ctx, prompt, llms.WithMaxTokens(300))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
+
+// cleanQuery returns the first line of a model's reply without a label or
+// quotes around it
+func cleanQuery(response string) string {
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, ":"); i >= 0 && strings.EqualFold(strings.TrimSpace(line[:i]), "standalone query") {
+			line = strings.TrimSpace(line[i+1:])
+		}
+		if line = strings.Trim(line, "\"'`"); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain/vectorstore"
+	"github.com/koopa0/assistant-go/internal/testutil"
+)
+
+// promptLLM replies by the start of the prompt and records the prompts
+type promptLLM struct {
+	replies map[string]string // by prompt prefix
+	failing string            // prompt prefix that fails
+	prompts []string
+}
+
+func (m *promptLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	if m.failing != "" && strings.HasPrefix(prompt, m.failing) {
+		return "", errors.New("model unavailable")
+	}
+	for prefix, reply := range m.replies {
+		if strings.HasPrefix(prompt, prefix) {
+			return reply, nil
+		}
+	}
+	return "An answer [1].", nil
+}
+
+func (m *promptLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	return nil, errors.New("not supported")
+}
+
+var rewriteHistory = []Turn{
+	{Role: "user", Content: "How does the jobs table store leases?"},
+	{Role: "assistant", Content: "Each job row has a leased_until column [1]."},
+}
+
+func TestQueryRewriter_Rewrite(t *testing.T) {
+	llm := &promptLLM{replies: map[string]string{
+		"Rewrite":   "Standalone query: \"indexes of the jobs table\"\n",
+		"Write 2":   "1. jobs table index definitions\n- indexes of the jobs table\n* lease index on leased_until\n3. ignored",
+		"Write a s": "The jobs table has an index on leased_until.",
+	}}
+	ctx := context.Background()
+	logger := testutil.NewTestLogger()
+
+	rewriter := NewQueryRewriter(llm, config.Rewriting{Enabled: true}, logger)
+	if rewritten, err := rewriter.Rewrite(ctx, "what about its indexes?", nil); rewritten != nil || err != nil {
+		t.Errorf("Rewrite() without history = %+v, %v; want nothing to rewrite", rewritten, err)
+	}
+
+	// The conversation's record of the query itself is not history
+	history := append(rewriteHistory, Turn{Role: "user", Content: "what about its indexes?"})
+	rewritten, err := rewriter.Rewrite(ctx, "what about its indexes?", history)
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if rewritten.Standalone != "indexes of the jobs table" {
+		t.Errorf("Standalone = %q", rewritten.Standalone)
+	}
+	prompt := llm.prompts[len(llm.prompts)-1]
+	if !strings.Contains(prompt, "Assistant: Each job row") || strings.Count(prompt, "what about its indexes?") != 1 {
+		t.Errorf("rewrite prompt = %q, want the history once and the question", prompt)
+	}
+
+	rewriter = NewQueryRewriter(llm, config.Rewriting{Enabled: true, HistoryTurns: 1, SubQueries: 2, HyDE: true}, logger)
+	rewritten, err = rewriter.Rewrite(ctx, "what about its indexes?", rewriteHistory)
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if prompt := llm.prompts[len(llm.prompts)-3]; strings.Contains(prompt, "How does the jobs table") {
+		t.Errorf("rewrite prompt = %q, want only the last turn", prompt)
+	}
+	want := []string{
+		"indexes of the jobs table",
+		"jobs table index definitions",
+		"lease index on leased_until",
+		"The jobs table has an index on leased_until.",
+	}
+	if got := rewritten.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries() = %q, want %q", got, want)
+	}
+
+	// A failing step keeps the rewrite so far
+	llm.failing = "Write 2"
+	rewritten, err = rewriter.Rewrite(ctx, "what about its indexes?", rewriteHistory)
+	if err == nil || !strings.Contains(err.Error(), "sub-queries") {
+		t.Errorf("Rewrite() error = %v, want the sub-queries error", err)
+	}
+	if rewritten == nil || len(rewritten.Queries()) != 2 {
+		t.Errorf("Rewrite() = %+v, want the standalone query and the hypothetical answer", rewritten)
+	}
+
+	disabled := NewQueryRewriter(llm, config.Rewriting{}, logger)
+	if rewritten, _ := disabled.Rewrite(ctx, "what about its indexes?", rewriteHistory); rewritten != nil {
+		t.Errorf("Rewrite() when disabled = %+v, want nil", rewritten)
+	}
+}
+
+// querySearcher returns canned results by query
+type querySearcher map[string][]vectorstore.SearchResult
+
+func (s querySearcher) Search(ctx context.Context, query string, limit int, threshold float64) ([]vectorstore.SearchResult, error) {
+	return s[query], nil
+}
+
+func TestRAGChain_QueryRewriting(t *testing.T) {
+	llm := &promptLLM{replies: map[string]string{
+		"Rewrite": "indexes of the jobs table",
+		"Write 1": "lease index",
+	}}
+	cfg := config.LangChain{Rewriting: config.Rewriting{Enabled: true, SubQueries: 1}}
+	ragChain := NewRAGChain(llm, cfg, testutil.NewTestLogger())
+	ragChain.SetDocumentSearcher(querySearcher{
+		"indexes of the jobs table": {
+			{ID: "a", Content: "CREATE INDEX idx_jobs_status ON jobs (status);", Similarity: 0.8},
+			{ID: "b", Content: "CREATE INDEX idx_jobs_lease ON jobs (leased_until);", Similarity: 0.7},
+		},
+		"lease index": {
+			{ID: "b", Content: "CREATE INDEX idx_jobs_lease ON jobs (leased_until);", Similarity: 0.9},
+			{ID: "c", Content: "CREATE INDEX idx_jobs_lease ON jobs (leased_until);", Similarity: 0.6},
+		},
+	})
+
+	result, err := ragChain.Query(context.Background(), "what about its indexes?", rewriteHistory)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	// b is found by both queries and c duplicates its content
+	if len(result.Sources) != 2 || result.Sources[0].ID != "b" || result.Sources[1].ID != "a" {
+		t.Fatalf("Query() sources = %+v, want b then a", result.Sources)
+	}
+	if got := result.Sources[0].Queries; !reflect.DeepEqual(got, []string{"indexes of the jobs table", "lease index"}) || result.Sources[0].Score != 0.9 {
+		t.Errorf("source b = %+v, want both queries and the best similarity", result.Sources[0])
+	}
+	if got := result.Metadata["retrieval_queries"]; !reflect.DeepEqual(got, []string{"indexes of the jobs table", "lease index"}) {
+		t.Errorf("retrieval_queries = %v", got)
+	}
+	if prompt := llm.prompts[len(llm.prompts)-1]; !strings.Contains(prompt, "Question: indexes of the jobs table") {
+		t.Errorf("answer prompt = %q, want the standalone question", prompt)
+	}
+
+	steps, _ := result.Metadata["steps"].([]ChainStep)
+	if len(steps) == 0 || steps[0].StepType != "query_rewriting" || steps[0].Metadata["standalone_query"] != "indexes of the jobs table" {
+		t.Fatalf("steps = %+v, want query rewriting first", steps)
+	}
+	for i, step := range steps {
+		if step.StepNumber != i+1 {
+			t.Errorf("step %s is number %d, want %d", step.StepType, step.StepNumber, i+1)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Package collection manages knowledge-base collections: named sets of
+// ingested documents with an owner, read or write grants to users and
+// teams, and their own embedding provider and chunking settings. Documents
+// are ingested by background jobs, and searches only see the collections
+// their caller may read.
+//
+// Stored vectors are tagged with the embedding model that produced them,
+// and searches only compare the vectors of each collection's active model.
+// Re-embedding jobs move a collection to a new model in the background and
+// switch it over once every chunk has a vector of the new model.
+package collection
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
+	"github.com/koopa0/assistant-go/internal/user"
+)
+
+const (
+	// DefaultID is the id of the default collection, which holds the
+	// embeddings stored before collections existed
+	DefaultID = postgres.DefaultCollectionID
+	// DefaultName is the name of the default collection
+	DefaultName = "default"
+	// ContentType is the embeddings content type of collection chunks
+	ContentType = "document"
+)
+
+// Permission is a level of access to a collection
+type Permission string
+
+const (
+	PermissionNone  Permission = ""
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+)
+
+// Allows reports whether p includes required; write includes read
+func (p Permission) Allows(required Permission) bool {
+	switch required {
+	case PermissionRead:
+		return p == PermissionRead || p == PermissionWrite
+	case PermissionWrite:
+		return p == PermissionWrite
+	}
+	return false
+}
+
+// Grantee types
+const (
+	GranteeUser = "user"
+	GranteeTeam = "team"
+)
+
+// Ingestion and re-embedding job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+var (
+	ErrNotFound           = errors.New("collection not found")
+	ErrExists             = errors.New("collection already exists")
+	ErrForbidden          = errors.New("collection access denied")
+	ErrInvalid            = errors.New("invalid collection request")
+	ErrDocumentNotFound   = errors.New("document not found")
+	ErrJobNotFound        = errors.New("ingestion job not found")
+	ErrReembedJobNotFound = errors.New("re-embedding job not found")
+	ErrReembedding        = errors.New("collection is already being re-embedded")
+)
+
+// Collection is a named set of ingested documents
+type Collection struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	Description       string    `json:"description"`
+	OwnerID           string    `json:"owner_id,omitempty"` // empty for the default collection
+	EmbeddingProvider string    `json:"embedding_provider,omitempty"`
+	EmbeddingModel    string    `json:"embedding_model,omitempty"` // empty for vectors stored before models were recorded
+	ChunkSize         int       `json:"chunk_size"`
+	ChunkOverlap      int       `json:"chunk_overlap"`
+	Grants            []Grant   `json:"grants"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Grant gives a user, or every member of a team, access to a collection
+type Grant struct {
+	GranteeType string     `json:"grantee_type"` // user or team
+	GranteeID   string     `json:"grantee_id"`   // user id or team name
+	Permission  Permission `json:"permission"`
+}
+
+// Document is a source ingested into a collection
+type Document struct {
+	ID           string      `json:"id"`
+	CollectionID string      `json:"collection_id"`
+	Source       string      `json:"source"`
+	ContentHash  string      `json:"content_hash"`
+	Chunks       int         `json:"chunks"`
+	ChunkIDs     []uuid.UUID `json:"-"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// Job is a background ingestion of a source into a collection
+type Job struct {
+	ID           string     `json:"id"`
+	CollectionID string     `json:"collection_id"`
+	Source       string     `json:"source"`
+	Status       string     `json:"status"`
+	Documents    int        `json:"documents"`
+	Chunks       int        `json:"chunks"`
+	Error        string     `json:"error,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// ReembedJob is a background re-embedding of a collection's chunks with a
+// new model. Done counts the chunks with a vector of the new model, out of
+// the Total chunks of the active model.
+type ReembedJob struct {
+	ID           string     `json:"id"`
+	CollectionID string     `json:"collection_id"`
+	Provider     string     `json:"provider,omitempty"`
+	Model        string     `json:"model"`
+	Status       string     `json:"status"`
+	Total        int        `json:"total"`
+	Done         int        `json:"done"`
+	Dimensions   int        `json:"dimensions,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// Chunk is a stored chunk of a document
+type Chunk struct {
+	ID       uuid.UUID
+	Content  string
+	Metadata map[string]any
+}
+
+// Caller is who accesses collections: a user and the teams they are in
+type Caller struct {
+	UserID string
+	Teams  []string
+}
+
+// CallerFromContext returns the authenticated caller of a request. Teams
+// are the user's roles.
+func CallerFromContext(ctx context.Context) Caller {
+	if info, err := user.FromContext(ctx); err == nil {
+		return Caller{UserID: info.ID, Teams: info.Roles}
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		return Caller{UserID: userID}
+	}
+	return Caller{}
+}
+
+// IsOwner reports whether the caller owns the collection
+func (c *Collection) IsOwner(caller Caller) bool {
+	return c.OwnerID != "" && c.OwnerID == caller.UserID
+}
+
+// Access returns the caller's permission on the collection. Owners may
+// write; collections without an owner are readable by everyone; others
+// get the highest permission granted to them or one of their teams.
+func (c *Collection) Access(caller Caller) Permission {
+	if c.IsOwner(caller) {
+		return PermissionWrite
+	}
+
+	access := PermissionNone
+	if c.OwnerID == "" {
+		access = PermissionRead
+	}
+	for _, grant := range c.Grants {
+		matches := grant.GranteeType == GranteeUser && caller.UserID != "" && grant.GranteeID == caller.UserID ||
+			grant.GranteeType == GranteeTeam && slices.Contains(caller.Teams, grant.GranteeID)
+		if matches && !access.Allows(grant.Permission) {
+			access = grant.Permission
+		}
+	}
+	return access
+}
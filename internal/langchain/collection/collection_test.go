@@ -0,0 +1,979 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tmc/langchaingo/embeddings"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain/vectorstore"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+	"github.com/koopa0/assistant-go/internal/testutil"
+)
+
+// memoryStore keeps collections, documents, chunks and jobs in maps
+type memoryStore struct {
+	mu          sync.Mutex
+	collections map[string]*Collection // by name
+	documents   map[string]*Document
+	chunks      map[chunkKey]*storedChunk
+	jobs        map[string]*Job
+	reembeds    map[string]*ReembedJob
+}
+
+// chunkKey identifies the vector of a chunk by a model
+type chunkKey struct {
+	id    uuid.UUID
+	model string
+}
+
+type storedChunk struct {
+	collectionID string
+	content      string
+	dimensions   int
+}
+
+func newMemoryStore() *memoryStore {
+	s := &memoryStore{
+		collections: make(map[string]*Collection),
+		documents:   make(map[string]*Document),
+		chunks:      make(map[chunkKey]*storedChunk),
+		jobs:        make(map[string]*Job),
+		reembeds:    make(map[string]*ReembedJob),
+	}
+	s.collections[DefaultName] = &Collection{ID: DefaultID, Name: DefaultName}
+	return s
+}
+
+func (s *memoryStore) CreateCollection(ctx context.Context, collection *Collection) (*Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.collections[collection.Name]; ok {
+		return nil, ErrExists
+	}
+	created := *collection
+	created.ID = uuid.NewString()
+	s.collections[created.Name] = &created
+	return &created, nil
+}
+
+func (s *memoryStore) Collection(ctx context.Context, name string) (*Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	collection, ok := s.collections[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *collection
+	copied.Grants = slices.Clone(collection.Grants)
+	return &copied, nil
+}
+
+func (s *memoryStore) CollectionByID(ctx context.Context, id string) (*Collection, error) {
+	s.mu.Lock()
+	name := ""
+	for _, collection := range s.collections {
+		if collection.ID == id {
+			name = collection.Name
+		}
+	}
+	s.mu.Unlock()
+	return s.Collection(ctx, name)
+}
+
+func (s *memoryStore) Collections(ctx context.Context) ([]*Collection, error) {
+	var collections []*Collection
+	for name := range s.collections {
+		collection, _ := s.Collection(ctx, name)
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+func (s *memoryStore) DeleteCollection(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, collection := range s.collections {
+		if collection.ID == id {
+			delete(s.collections, name)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveGrant(ctx context.Context, collectionID string, grant Grant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, collection := range s.collections {
+		if collection.ID == collectionID {
+			collection.Grants = append(collection.Grants, grant)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteGrant(ctx context.Context, collectionID, granteeType, granteeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, collection := range s.collections {
+		if collection.ID == collectionID {
+			collection.Grants = slices.DeleteFunc(collection.Grants, func(g Grant) bool {
+				return g.GranteeType == granteeType && g.GranteeID == granteeID
+			})
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Documents(ctx context.Context, collectionID string) ([]*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var documents []*Document
+	for _, document := range s.documents {
+		if document.CollectionID == collectionID {
+			copied := *document
+			documents = append(documents, &copied)
+		}
+	}
+	return documents, nil
+}
+
+func (s *memoryStore) Document(ctx context.Context, id string) (*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	document, ok := s.documents[id]
+	if !ok {
+		return nil, ErrDocumentNotFound
+	}
+	copied := *document
+	return &copied, nil
+}
+
+func (s *memoryStore) SaveDocument(ctx context.Context, document *Document) (*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *document
+	saved.ID = uuid.NewString()
+	for id, stored := range s.documents {
+		if stored.CollectionID == document.CollectionID && stored.Source == document.Source {
+			saved.ID = id
+		}
+	}
+	saved.Chunks = len(saved.ChunkIDs)
+	s.documents[saved.ID] = &saved
+	return &saved, nil
+}
+
+func (s *memoryStore) DeleteDocument(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.documents, id)
+	return nil
+}
+
+func (s *memoryStore) PutChunk(ctx context.Context, collectionID string, id uuid.UUID, content string, vector []float32, metadata map[string]any, model string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := chunkKey{id: id, model: model}
+	if _, ok := s.chunks[key]; ok {
+		return errors.New("duplicate chunk")
+	}
+	s.chunks[key] = &storedChunk{collectionID: collectionID, content: content, dimensions: len(vector)}
+	return nil
+}
+
+func (s *memoryStore) DeleteChunks(ctx context.Context, collectionID string, ids []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.chunks {
+		if slices.Contains(ids, key.id) {
+			delete(s.chunks, key)
+		}
+	}
+	return nil
+}
+
+// models counts the stored vectors by model
+func (s *memoryStore) models() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models := make(map[string]int)
+	for key := range s.chunks {
+		models[key.model]++
+	}
+	return models
+}
+
+func (s *memoryStore) CreateJob(ctx context.Context, collectionID, source, createdBy string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := &Job{ID: uuid.NewString(), CollectionID: collectionID, Source: source, Status: JobPending, CreatedBy: createdBy}
+	s.jobs[job.ID] = job
+	copied := *job
+	return &copied, nil
+}
+
+func (s *memoryStore) Job(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (s *memoryStore) Jobs(ctx context.Context, collectionID string, limit int) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []*Job
+	for _, job := range s.jobs {
+		if job.CollectionID == collectionID {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memoryStore) StartJob(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[id].Status = JobRunning
+	return nil
+}
+
+func (s *memoryStore) FinishJob(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := *job
+	now := time.Now()
+	finished.FinishedAt = &now
+	s.jobs[job.ID] = &finished
+	return nil
+}
+
+func (s *memoryStore) CreateReembedJob(ctx context.Context, collectionID, provider, model, createdBy string) (*ReembedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.reembeds {
+		if job.CollectionID == collectionID && (job.Status == JobPending || job.Status == JobRunning) {
+			return nil, ErrReembedding
+		}
+	}
+	job := &ReembedJob{ID: uuid.NewString(), CollectionID: collectionID, Provider: provider, Model: model, Status: JobPending, CreatedBy: createdBy}
+	s.reembeds[job.ID] = job
+	copied := *job
+	return &copied, nil
+}
+
+func (s *memoryStore) ReembedJob(ctx context.Context, id string) (*ReembedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.reembeds[id]
+	if !ok {
+		return nil, ErrReembedJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (s *memoryStore) ReembedJobs(ctx context.Context, collectionID string, limit int) ([]*ReembedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []*ReembedJob
+	for _, job := range s.reembeds {
+		if job.CollectionID == collectionID {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memoryStore) UnfinishedReembedJobs(ctx context.Context) ([]*ReembedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []*ReembedJob
+	for _, job := range s.reembeds {
+		if job.Status == JobPending || job.Status == JobRunning {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memoryStore) StartReembedJob(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reembeds[id].Status = JobRunning
+	return nil
+}
+
+func (s *memoryStore) UpdateReembedJob(ctx context.Context, job *ReembedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.reembeds[job.ID]
+	stored.Total, stored.Done, stored.Dimensions = job.Total, job.Done, job.Dimensions
+	return nil
+}
+
+func (s *memoryStore) FinishReembedJob(ctx context.Context, job *ReembedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := *job
+	now := time.Now()
+	finished.FinishedAt = &now
+	s.reembeds[job.ID] = &finished
+	return nil
+}
+
+// activeModel returns the active model of a collection; callers hold mu
+func (s *memoryStore) activeModel(collectionID string) string {
+	for _, collection := range s.collections {
+		if collection.ID == collectionID {
+			return collection.EmbeddingModel
+		}
+	}
+	return ""
+}
+
+// candidates returns the chunks of the active model without a vector of
+// model; callers hold mu
+func (s *memoryStore) candidates(collectionID, model string) []Chunk {
+	active := s.activeModel(collectionID)
+	var chunks []Chunk
+	for key, chunk := range s.chunks {
+		if chunk.collectionID != collectionID || key.model != active {
+			continue
+		}
+		if _, ok := s.chunks[chunkKey{id: key.id, model: model}]; !ok {
+			chunks = append(chunks, Chunk{ID: key.id, Content: chunk.content, Metadata: map[string]any{}})
+		}
+	}
+	slices.SortFunc(chunks, func(a, b Chunk) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+	return chunks
+}
+
+func (s *memoryStore) ReembedCandidates(ctx context.Context, collectionID, model string, limit int) ([]Chunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunks := s.candidates(collectionID, model)
+	return chunks[:min(limit, len(chunks))], nil
+}
+
+func (s *memoryStore) ReembedProgress(ctx context.Context, collectionID, model string) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active := s.activeModel(collectionID)
+	total, done := 0, 0
+	for key, chunk := range s.chunks {
+		if chunk.collectionID != collectionID {
+			continue
+		}
+		if key.model == active {
+			total++
+		}
+		if key.model == model {
+			done++
+		}
+	}
+	return total, done, nil
+}
+
+func (s *memoryStore) SwitchModel(ctx context.Context, collectionID, provider, model string, dimensions int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mismatched := false
+	for key, chunk := range s.chunks {
+		if chunk.collectionID == collectionID && key.model == model && chunk.dimensions != dimensions {
+			delete(s.chunks, key)
+			mismatched = true
+		}
+	}
+	if mismatched {
+		return false, nil
+	}
+	for _, job := range s.jobs {
+		if job.CollectionID == collectionID && (job.Status == JobPending || job.Status == JobRunning) {
+			return false, nil
+		}
+	}
+	if len(s.candidates(collectionID, model)) > 0 {
+		return false, nil
+	}
+	for _, collection := range s.collections {
+		if collection.ID == collectionID {
+			collection.EmbeddingProvider, collection.EmbeddingModel = provider, model
+		}
+	}
+	for key, chunk := range s.chunks {
+		if chunk.collectionID == collectionID && key.model != model {
+			delete(s.chunks, key)
+		}
+	}
+	return true, nil
+}
+
+func (s *memoryStore) AdoptModel(ctx context.Context, collectionID, model string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, collection := range s.collections {
+		if collection.ID != collectionID || collection.EmbeddingModel != "" {
+			continue
+		}
+		collection.EmbeddingModel = model
+		for key, chunk := range s.chunks {
+			adoptedKey := chunkKey{id: key.id, model: model}
+			if _, exists := s.chunks[adoptedKey]; chunk.collectionID == collectionID && key.model == "" && !exists {
+				delete(s.chunks, key)
+				s.chunks[adoptedKey] = chunk
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *memoryStore) DeleteModelChunks(ctx context.Context, collectionID, model string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if model == s.activeModel(collectionID) {
+		return nil
+	}
+	for key, chunk := range s.chunks {
+		if chunk.collectionID == collectionID && key.model == model {
+			delete(s.chunks, key)
+		}
+	}
+	return nil
+}
+
+// countingEmbedder returns unit vectors and counts embedded texts
+type countingEmbedder struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (e *countingEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+
+func (e *countingEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+func (e *countingEmbedder) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.texts)
+}
+
+// batchEmbedder returns vectors of three dimensions. It fails once it has
+// embedded failAfter texts, and blocks each call until release is closed
+// when set.
+type batchEmbedder struct {
+	mu        sync.Mutex
+	texts     int
+	failAfter int
+	started   chan struct{}
+	release   chan struct{}
+}
+
+func (e *batchEmbedder) BatchGenerateEmbeddings(ctx context.Context, texts []string) ([]*ai.EmbeddingResponse, error) {
+	if e.release != nil {
+		e.started <- struct{}{}
+		select {
+		case <-e.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failAfter > 0 && e.texts >= e.failAfter {
+		return nil, errors.New("quota exceeded")
+	}
+	e.texts += len(texts)
+	responses := make([]*ai.EmbeddingResponse, len(texts))
+	for i := range texts {
+		responses[i] = &ai.EmbeddingResponse{Embedding: []float64{0, 1, 0}}
+	}
+	return responses, nil
+}
+
+func (e *batchEmbedder) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.texts
+}
+
+// searchQueries returns one chunk per search and records the collections
+// searched
+type searchQueries struct {
+	collections [][]string
+}
+
+func (q *searchQueries) SearchEmbeddingsByVector(ctx context.Context, arg sqlc.SearchEmbeddingsByVectorParams) ([]*sqlc.SearchEmbeddingsByVectorRow, error) {
+	ids := make([]string, 0, len(arg.CollectionIds))
+	for _, id := range arg.CollectionIds {
+		ids = append(ids, uuid.UUID(id.Bytes).String())
+	}
+	q.collections = append(q.collections, ids)
+	return []*sqlc.SearchEmbeddingsByVectorRow{{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		ContentType: ContentType,
+		ContentText: ids[0],
+		Metadata:    json.RawMessage(`{}`),
+		Similarity:  0.9,
+	}}, nil
+}
+
+func (q *searchQueries) SearchEmbeddingsByText(ctx context.Context, arg sqlc.SearchEmbeddingsByTextParams) ([]*sqlc.SearchEmbeddingsByTextRow, error) {
+	return nil, nil
+}
+
+func newTestManager(store Store, search vectorstore.SearchQueries, embedder embeddings.Embedder) *Manager {
+	embedderFor := func(provider string) embeddings.Embedder {
+		if provider == "unknown" {
+			return nil
+		}
+		return embedder
+	}
+	return NewManager(store, search, embedderFor, vectorstore.SearchOptions{Mode: vectorstore.ModeVector},
+		config.Collections{ChunkSize: 200, ChunkOverlap: 20}, testutil.NewTestLogger())
+}
+
+func TestCollection_Access(t *testing.T) {
+	collection := &Collection{
+		OwnerID: "alice",
+		Grants: []Grant{
+			{GranteeType: GranteeUser, GranteeID: "bob", Permission: PermissionRead},
+			{GranteeType: GranteeTeam, GranteeID: "dev", Permission: PermissionWrite},
+			{GranteeType: GranteeTeam, GranteeID: "ops", Permission: PermissionRead},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		caller Caller
+		want   Permission
+	}{
+		{"owner", Caller{UserID: "alice"}, PermissionWrite},
+		{"user grant", Caller{UserID: "bob"}, PermissionRead},
+		{"highest grant wins", Caller{UserID: "bob", Teams: []string{"ops", "dev"}}, PermissionWrite},
+		{"team grant", Caller{UserID: "carol", Teams: []string{"ops"}}, PermissionRead},
+		{"no grant", Caller{UserID: "carol"}, PermissionNone},
+		{"anonymous", Caller{}, PermissionNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collection.Access(tt.caller); got != tt.want {
+				t.Errorf("Access() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	defaultCollection := &Collection{ID: DefaultID, Name: DefaultName}
+	if got := defaultCollection.Access(Caller{}); got != PermissionRead {
+		t.Errorf("default collection Access() = %q, want read", got)
+	}
+}
+
+func TestManager_Permissions(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(newMemoryStore(), &searchQueries{}, &countingEmbedder{})
+	alice := Caller{UserID: "alice"}
+	bob := Caller{UserID: "bob", Teams: []string{"dev"}}
+
+	created, err := manager.Create(ctx, alice, CreateRequest{Name: "handbook"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ChunkSize != 200 || created.ChunkOverlap != 20 {
+		t.Errorf("Create() chunking = %d/%d, want the configured 200/20", created.ChunkSize, created.ChunkOverlap)
+	}
+	if _, err := manager.Create(ctx, alice, CreateRequest{Name: "bad/name"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Create() with invalid name error = %v, want ErrInvalid", err)
+	}
+	if _, err := manager.Create(ctx, alice, CreateRequest{Name: "other", EmbeddingProvider: "unknown"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Create() with unknown provider error = %v, want ErrInvalid", err)
+	}
+
+	// Bob sees only the default collection until it is shared with his team
+	listed, _ := manager.List(ctx, bob)
+	if len(listed) != 1 || listed[0].Name != DefaultName {
+		t.Errorf("List() = %v, want only the default collection", listed)
+	}
+	if _, err := manager.Get(ctx, bob, "handbook"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	if _, err := manager.Share(ctx, alice, "handbook", Grant{GranteeType: GranteeTeam, GranteeID: "dev", Permission: PermissionRead}); err != nil {
+		t.Fatalf("Share() error = %v", err)
+	}
+	if _, err := manager.Get(ctx, bob, "handbook"); err != nil {
+		t.Errorf("Get() after share error = %v", err)
+	}
+	if _, err := manager.Ingest(ctx, bob, "handbook", t.TempDir()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Ingest() with read access error = %v, want ErrForbidden", err)
+	}
+	if err := manager.Delete(ctx, bob, "handbook"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Delete() by non-owner error = %v, want ErrForbidden", err)
+	}
+	if _, err := manager.Share(ctx, bob, DefaultName, Grant{GranteeType: GranteeUser, GranteeID: "bob", Permission: PermissionWrite}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Share() of the default collection error = %v, want ErrForbidden", err)
+	}
+
+	if _, err := manager.Unshare(ctx, alice, "handbook", GranteeTeam, "dev"); err != nil {
+		t.Fatalf("Unshare() error = %v", err)
+	}
+	if _, err := manager.Get(ctx, bob, "handbook"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after unshare error = %v, want ErrNotFound", err)
+	}
+	if err := manager.Delete(ctx, alice, "handbook"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+}
+
+func TestManager_Ingest(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	embedder := &countingEmbedder{}
+	manager := newTestManager(store, &searchQueries{}, embedder)
+	alice := Caller{UserID: "alice"}
+	if _, err := manager.Create(ctx, alice, CreateRequest{Name: "docs"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("setup.md", "Install the tool with go install. Configure it with a YAML file.")
+	write("usage.txt", "Run the tool with a query. It prints the answer and its sources.")
+
+	ingest := func() *Job {
+		t.Helper()
+		job, err := manager.Ingest(ctx, alice, "docs", dir)
+		if err != nil {
+			t.Fatalf("Ingest() error = %v", err)
+		}
+		manager.Wait()
+		finished, err := manager.Job(ctx, alice, "docs", job.ID)
+		if err != nil {
+			t.Fatalf("Job() error = %v", err)
+		}
+		if finished.Status != JobSucceeded {
+			t.Fatalf("job status = %s (%s), want succeeded", finished.Status, finished.Error)
+		}
+		return finished
+	}
+
+	job := ingest()
+	if job.Documents != 2 || job.Chunks != 2 || embedder.count() != 2 {
+		t.Errorf("first ingest = %d documents, %d chunks, %d embedded; want 2 each", job.Documents, job.Chunks, embedder.count())
+	}
+
+	// Unchanged documents are skipped; changed ones embed only new chunks
+	if job := ingest(); job.Documents != 0 || embedder.count() != 2 {
+		t.Errorf("unchanged ingest = %d documents, %d embedded; want 0 and 2", job.Documents, embedder.count())
+	}
+	write("usage.txt", "Run the tool with a question instead.")
+	if job := ingest(); job.Documents != 1 || job.Chunks != 1 {
+		t.Errorf("changed ingest = %d documents, %d chunks; want 1 each", job.Documents, job.Chunks)
+	}
+	if len(store.chunks) != 2 {
+		t.Errorf("stored %d chunks, want the stale chunk replaced", len(store.chunks))
+	}
+
+	documents, err := manager.Documents(ctx, alice, "docs")
+	if err != nil || len(documents) != 2 {
+		t.Fatalf("Documents() = %d, %v; want 2", len(documents), err)
+	}
+	slices.SortFunc(documents, func(a, b *Document) int { return len(a.Source) - len(b.Source) })
+
+	reingest, err := manager.Reingest(ctx, alice, "docs", documents[0].ID)
+	if err != nil {
+		t.Fatalf("Reingest() error = %v", err)
+	}
+	manager.Wait()
+	if job, _ := manager.Job(ctx, alice, "docs", reingest.ID); job.Status != JobSucceeded || job.Chunks != 1 {
+		t.Errorf("reingest job = %+v, want one chunk embedded again", job)
+	}
+
+	if err := manager.DeleteDocument(ctx, alice, "docs", documents[1].ID); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if documents, _ := manager.Documents(ctx, alice, "docs"); len(documents) != 1 || len(store.chunks) != 1 {
+		t.Errorf("after delete: %d documents, %d chunks; want 1 each", len(documents), len(store.chunks))
+	}
+}
+
+func TestManager_IngestRoots(t *testing.T) {
+	root := t.TempDir()
+	manager := NewManager(newMemoryStore(), &searchQueries{}, nil, vectorstore.SearchOptions{},
+		config.Collections{IngestRoots: []string{root}}, testutil.NewTestLogger())
+
+	tests := []struct {
+		source string
+		valid  bool
+	}{
+		{filepath.Join(root, "guide.md"), true},
+		{root, true},
+		{filepath.Join(root, "..", "secrets.txt"), false},
+		{"/etc/passwd", false},
+		{"https://example.com/guide.html", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, err := manager.checkSource(tt.source); (err == nil) != tt.valid {
+			t.Errorf("checkSource(%q) error = %v, want valid %v", tt.source, err, tt.valid)
+		}
+	}
+}
+
+func TestManager_Search(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	search := &searchQueries{}
+	manager := newTestManager(store, search, &countingEmbedder{})
+	alice := Caller{UserID: "alice"}
+	bob := Caller{UserID: "bob"}
+
+	first, _ := manager.Create(ctx, alice, CreateRequest{Name: "first"})
+	second, _ := manager.Create(ctx, alice, CreateRequest{Name: "second", EmbeddingProvider: "gemini"})
+	if _, err := manager.Create(ctx, bob, CreateRequest{Name: "private"}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := manager.Searcher(alice, nil).Search(ctx, "query", 5, 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Search() = %d results, want one per provider", len(results))
+	}
+
+	// Collections are searched per embedding provider; bob's is not readable
+	var searched [][]string
+	for _, ids := range search.collections {
+		slices.Sort(ids)
+		searched = append(searched, ids)
+	}
+	slices.SortFunc(searched, func(a, b []string) int { return len(b) - len(a) })
+	want := [][]string{{DefaultID, first.ID}, {second.ID}}
+	slices.Sort(want[0])
+	if !reflect.DeepEqual(searched, want) {
+		t.Errorf("searched collections = %v, want %v", searched, want)
+	}
+
+	if _, err := manager.Search(ctx, alice, []string{"private"}, "query", 5, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Search() of an unreadable collection error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestManager_Reembed(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manager := NewManager(store, &searchQueries{}, func(string) embeddings.Embedder { return &countingEmbedder{} },
+		vectorstore.SearchOptions{}, config.Collections{ChunkSize: 200, ChunkOverlap: 20, ReembedBatchSize: 1}, testutil.NewTestLogger())
+	alice := Caller{UserID: "alice"}
+	bob := Caller{UserID: "bob"}
+
+	if _, err := manager.Create(ctx, alice, CreateRequest{Name: "docs", EmbeddingModel: "v1"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := manager.Reembed(ctx, alice, "docs", ReembedRequest{Model: "v2"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Reembed() without batch embedders error = %v, want ErrInvalid", err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.md": "First document.", "b.md": "Second document.", "c.md": "Third document."} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := manager.Ingest(ctx, alice, "docs", dir); err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	manager.Wait()
+	if got := store.models(); got["v1"] != 3 {
+		t.Fatalf("stored vectors = %v, want 3 of v1", got)
+	}
+
+	embedder := &batchEmbedder{failAfter: 1}
+	manager.UseBatchEmbedders(func(provider, model string) BatchEmbedder { return embedder })
+
+	if _, err := manager.Reembed(ctx, bob, "docs", ReembedRequest{Model: "v2"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Reembed() by a stranger error = %v, want ErrNotFound", err)
+	}
+	if _, err := manager.Reembed(ctx, alice, "docs", ReembedRequest{Model: "v1"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Reembed() to the active model error = %v, want ErrInvalid", err)
+	}
+
+	// A failed job keeps the collection on its model and its progress
+	failed, err := manager.Reembed(ctx, alice, "docs", ReembedRequest{Model: "v2"})
+	if err != nil {
+		t.Fatalf("Reembed() error = %v", err)
+	}
+	manager.Wait()
+	failed, _ = manager.ReembedJob(ctx, alice, "docs", failed.ID)
+	if failed.Status != JobFailed || failed.Done != 1 || failed.Total != 3 || failed.Dimensions != 3 {
+		t.Errorf("failed job = %+v, want failed at 1 of 3 chunks", failed)
+	}
+	if collection, _ := manager.Get(ctx, alice, "docs"); collection.EmbeddingModel != "v1" {
+		t.Errorf("model after failure = %q, want v1", collection.EmbeddingModel)
+	}
+
+	// A new job resumes from the chunks already embedded and switches over
+	embedder.failAfter = 0
+	job, err := manager.Reembed(ctx, alice, "docs", ReembedRequest{Model: "v2"})
+	if err != nil {
+		t.Fatalf("Reembed() error = %v", err)
+	}
+	manager.Wait()
+	job, _ = manager.ReembedJob(ctx, alice, "docs", job.ID)
+	if job.Status != JobSucceeded || job.Done != 3 || job.Total != 3 {
+		t.Errorf("job = %+v, want succeeded with 3 of 3 chunks", job)
+	}
+	if embedder.count() != 3 {
+		t.Errorf("embedded %d texts, want each chunk once", embedder.count())
+	}
+	if collection, _ := manager.Get(ctx, alice, "docs"); collection.EmbeddingModel != "v2" {
+		t.Errorf("model after re-embedding = %q, want v2", collection.EmbeddingModel)
+	}
+	if got := store.models(); !reflect.DeepEqual(got, map[string]int{"v2": 3}) {
+		t.Errorf("stored vectors = %v, want only the 3 of v2", got)
+	}
+
+	// A cancelled job deletes its vectors and leaves the model unchanged
+	blocking := &batchEmbedder{started: make(chan struct{}), release: make(chan struct{})}
+	manager.UseBatchEmbedders(func(provider, model string) BatchEmbedder { return blocking })
+	cancelled, err := manager.Reembed(ctx, alice, "docs", ReembedRequest{Model: "v3"})
+	if err != nil {
+		t.Fatalf("Reembed() error = %v", err)
+	}
+	<-blocking.started
+	if _, err := manager.Reembed(ctx, alice, "docs", ReembedRequest{Model: "v4"}); !errors.Is(err, ErrReembedding) {
+		t.Errorf("second Reembed() error = %v, want ErrReembedding", err)
+	}
+	if _, err := manager.CancelReembed(ctx, alice, "docs", cancelled.ID); err != nil {
+		t.Fatalf("CancelReembed() error = %v", err)
+	}
+	manager.Wait()
+	cancelled, _ = manager.ReembedJob(ctx, alice, "docs", cancelled.ID)
+	if cancelled.Status != JobFailed || cancelled.Error != errReembedCancelled.Error() {
+		t.Errorf("cancelled job = %+v, want failed as cancelled", cancelled)
+	}
+	if got := store.models(); !reflect.DeepEqual(got, map[string]int{"v2": 3}) {
+		t.Errorf("stored vectors after cancel = %v, want only the 3 of v2", got)
+	}
+}
+
+func TestManager_ResumeReembed(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manager := newTestManager(store, &searchQueries{}, &countingEmbedder{})
+	alice := Caller{UserID: "alice"}
+	created, _ := manager.Create(ctx, alice, CreateRequest{Name: "docs"})
+	for range 2 {
+		if err := store.PutChunk(ctx, created.ID, uuid.New(), "chunk", []float32{1, 0}, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A vector of the model with other dimensions is embedded again
+	var stale uuid.UUID
+	for key := range store.chunks {
+		stale = key.id
+	}
+	if err := store.PutChunk(ctx, created.ID, stale, "chunk", []float32{1, 0}, nil, "v2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A job left running when the server stopped resumes on start
+	job, _ := store.CreateReembedJob(ctx, created.ID, "", "v2", alice.UserID)
+	_ = store.StartReembedJob(ctx, job.ID)
+	embedder := &batchEmbedder{}
+	manager.UseBatchEmbedders(func(provider, model string) BatchEmbedder { return embedder })
+	if err := manager.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	manager.Wait()
+
+	job, _ = manager.ReembedJob(ctx, alice, "docs", job.ID)
+	if job.Status != JobSucceeded || job.Done != 2 || job.Dimensions != 3 {
+		t.Errorf("resumed job = %+v, want succeeded with 2 chunks of 3 dimensions", job)
+	}
+	if embedder.texts != 2 {
+		t.Errorf("embedded %d texts, want both chunks", embedder.texts)
+	}
+	if got := store.models(); !reflect.DeepEqual(got, map[string]int{"v2": 2}) {
+		t.Errorf("stored vectors = %v, want only the 2 of v2", got)
+	}
+}
+
+func TestManager_AdoptModel(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manager := newTestManager(store, &searchQueries{}, &countingEmbedder{})
+	if err := store.PutChunk(ctx, DefaultID, uuid.New(), "chunk", []float32{1, 0}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default collection adopts the configured model with its vectors
+	if err := manager.AdoptModel(ctx, DefaultID, "v1"); err != nil {
+		t.Fatalf("AdoptModel() error = %v", err)
+	}
+	if got := store.models(); !reflect.DeepEqual(got, map[string]int{"v1": 1}) {
+		t.Errorf("stored vectors = %v, want the vector recorded as v1", got)
+	}
+
+	// Once it has a model it keeps it
+	if err := manager.AdoptModel(ctx, DefaultID, "v2"); err != nil {
+		t.Fatalf("AdoptModel() error = %v", err)
+	}
+	if collection, _ := store.CollectionByID(ctx, DefaultID); collection.EmbeddingModel != "v1" {
+		t.Errorf("model = %q, want v1 kept", collection.EmbeddingModel)
+	}
+}
+
+func TestPacer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newPacer(60) // a chunk a second
+	if err := p.wait(ctx, 10); err != nil {
+		t.Fatalf("first wait() error = %v", err)
+	}
+	cancel()
+	if err := p.wait(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() before the next batch is due error = %v, want context.Canceled", err)
+	}
+	if err := newPacer(0).wait(context.Background(), 1000); err != nil {
+		t.Errorf("unlimited wait() error = %v", err)
+	}
+}
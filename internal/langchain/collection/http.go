@@ -0,0 +1,324 @@
+package collection
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/koopa0/assistant-go/internal/platform/observability"
+	"github.com/koopa0/assistant-go/internal/platform/server/handlers"
+	"github.com/koopa0/assistant-go/internal/platform/server/middleware"
+)
+
+// HTTPHandler handles HTTP requests for collections
+type HTTPHandler struct {
+	*handlers.Handler
+	manager *Manager
+}
+
+// NewHTTPHandler creates a new HTTP handler for collections
+func NewHTTPHandler(manager *Manager, logger *slog.Logger) *HTTPHandler {
+	return &HTTPHandler{
+		Handler: handlers.NewHandler(observability.ServerLogger(logger, "collections_http")),
+		manager: manager,
+	}
+}
+
+// IngestRequest names the file, directory or URL to ingest
+type IngestRequest struct {
+	Source string `json:"source"`
+}
+
+// SearchRequest searches a collection
+type SearchRequest struct {
+	Query     string  `json:"query"`
+	Limit     int     `json:"limit,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// RegisterRoutes registers all collection API routes
+func (h *HTTPHandler) RegisterRoutes(mux *http.ServeMux) {
+	// Collections and their grants
+	mux.HandleFunc("GET /api/collections", h.ListCollections)
+	mux.HandleFunc("POST /api/collections", h.CreateCollection)
+	mux.HandleFunc("GET /api/collections/{name}", h.GetCollection)
+	mux.HandleFunc("DELETE /api/collections/{name}", h.DeleteCollection)
+	mux.HandleFunc("PUT /api/collections/{name}/grants", h.ShareCollection)
+	mux.HandleFunc("DELETE /api/collections/{name}/grants/{type}/{grantee}", h.UnshareCollection)
+
+	// Documents and ingestion jobs
+	mux.HandleFunc("GET /api/collections/{name}/documents", h.ListDocuments)
+	mux.HandleFunc("POST /api/collections/{name}/documents", h.IngestDocuments)
+	mux.HandleFunc("POST /api/collections/{name}/documents/{id}/reingest", h.ReingestDocument)
+	mux.HandleFunc("DELETE /api/collections/{name}/documents/{id}", h.DeleteDocument)
+	mux.HandleFunc("GET /api/collections/{name}/jobs", h.ListJobs)
+	mux.HandleFunc("GET /api/collections/{name}/jobs/{id}", h.GetJob)
+
+	// Re-embedding with a new model
+	mux.HandleFunc("POST /api/collections/{name}/reembed", h.Reembed)
+	mux.HandleFunc("GET /api/collections/{name}/reembed", h.ListReembedJobs)
+	mux.HandleFunc("GET /api/collections/{name}/reembed/{id}", h.GetReembedJob)
+	mux.HandleFunc("DELETE /api/collections/{name}/reembed/{id}", h.CancelReembed)
+
+	// Retrieval
+	mux.HandleFunc("POST /api/collections/{name}/search", h.Search)
+}
+
+// ListCollections returns the collections the caller may read
+func (h *HTTPHandler) ListCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := h.manager.List(r.Context(), CallerFromContext(r.Context()))
+	if err != nil {
+		h.writeError(w, r, "collections.list", err)
+		return
+	}
+	h.WriteSuccess(w, collections, "Collections retrieved successfully")
+}
+
+// CreateCollection creates a collection owned by the caller
+func (h *HTTPHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := h.DecodeJSON(r, &req); err != nil {
+		h.WriteBadRequest(w, "Invalid request body", err.Error())
+		return
+	}
+
+	collection, err := h.manager.Create(r.Context(), CallerFromContext(r.Context()), req)
+	if err != nil {
+		h.writeError(w, r, "collections.create", err)
+		return
+	}
+	h.WriteSuccess(w, collection, "Collection created successfully")
+}
+
+// GetCollection returns a collection
+func (h *HTTPHandler) GetCollection(w http.ResponseWriter, r *http.Request) {
+	collection, err := h.manager.Get(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"))
+	if err != nil {
+		h.writeError(w, r, "collections.get", err)
+		return
+	}
+	h.WriteSuccess(w, collection, "Collection retrieved successfully")
+}
+
+// DeleteCollection deletes a collection and everything ingested into it
+func (h *HTTPHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.Delete(r.Context(), CallerFromContext(r.Context()), r.PathValue("name")); err != nil {
+		h.writeError(w, r, "collections.delete", err)
+		return
+	}
+	h.WriteSuccess(w, nil, "Collection deleted successfully")
+}
+
+// ShareCollection grants a user or team access to a collection
+func (h *HTTPHandler) ShareCollection(w http.ResponseWriter, r *http.Request) {
+	var req Grant
+	if err := h.DecodeJSON(r, &req); err != nil {
+		h.WriteBadRequest(w, "Invalid request body", err.Error())
+		return
+	}
+
+	collection, err := h.manager.Share(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), req)
+	if err != nil {
+		h.writeError(w, r, "collections.share", err)
+		return
+	}
+	h.WriteSuccess(w, collection, "Collection shared successfully")
+}
+
+// UnshareCollection revokes a grant of a collection
+func (h *HTTPHandler) UnshareCollection(w http.ResponseWriter, r *http.Request) {
+	collection, err := h.manager.Unshare(r.Context(), CallerFromContext(r.Context()),
+		r.PathValue("name"), r.PathValue("type"), r.PathValue("grantee"))
+	if err != nil {
+		h.writeError(w, r, "collections.unshare", err)
+		return
+	}
+	h.WriteSuccess(w, collection, "Collection grant revoked successfully")
+}
+
+// ListDocuments returns the documents of a collection
+func (h *HTTPHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	documents, err := h.manager.Documents(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"))
+	if err != nil {
+		h.writeError(w, r, "collections.list_documents", err)
+		return
+	}
+	h.WriteSuccess(w, documents, "Documents retrieved successfully")
+}
+
+// IngestDocuments starts an ingestion job and returns it; poll the job for
+// its status
+func (h *HTTPHandler) IngestDocuments(w http.ResponseWriter, r *http.Request) {
+	var req IngestRequest
+	if err := h.DecodeJSON(r, &req); err != nil {
+		h.WriteBadRequest(w, "Invalid request body", err.Error())
+		return
+	}
+
+	job, err := h.manager.Ingest(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), req.Source)
+	if err != nil {
+		h.writeError(w, r, "collections.ingest", err)
+		return
+	}
+	h.WriteSuccess(w, job, "Ingestion job started")
+}
+
+// ReingestDocument starts a job ingesting a document again
+func (h *HTTPHandler) ReingestDocument(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.Reingest(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, "collections.reingest", err)
+		return
+	}
+	h.WriteSuccess(w, job, "Ingestion job started")
+}
+
+// DeleteDocument deletes a document and its chunks
+func (h *HTTPHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	err := h.manager.DeleteDocument(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, "collections.delete_document", err)
+		return
+	}
+	h.WriteSuccess(w, nil, "Document deleted successfully")
+}
+
+// ListJobs returns the latest ingestion jobs of a collection
+func (h *HTTPHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	limit, ok := h.limit(w, r)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.manager.Jobs(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), limit)
+	if err != nil {
+		h.writeError(w, r, "collections.list_jobs", err)
+		return
+	}
+	h.WriteSuccess(w, jobs, "Ingestion jobs retrieved successfully")
+}
+
+// GetJob returns an ingestion job
+func (h *HTTPHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.Job(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, "collections.get_job", err)
+		return
+	}
+	h.WriteSuccess(w, job, "Ingestion job retrieved successfully")
+}
+
+// Reembed starts a job moving a collection to a new embedding model
+func (h *HTTPHandler) Reembed(w http.ResponseWriter, r *http.Request) {
+	var req ReembedRequest
+	if err := h.DecodeJSON(r, &req); err != nil {
+		h.WriteBadRequest(w, "Invalid request body", err.Error())
+		return
+	}
+
+	job, err := h.manager.Reembed(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), req)
+	if err != nil {
+		h.writeError(w, r, "collections.reembed", err)
+		return
+	}
+	h.WriteSuccess(w, job, "Re-embedding job started")
+}
+
+// ListReembedJobs returns the latest re-embedding jobs of a collection
+func (h *HTTPHandler) ListReembedJobs(w http.ResponseWriter, r *http.Request) {
+	limit, ok := h.limit(w, r)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.manager.ReembedJobs(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), limit)
+	if err != nil {
+		h.writeError(w, r, "collections.list_reembed_jobs", err)
+		return
+	}
+	h.WriteSuccess(w, jobs, "Re-embedding jobs retrieved successfully")
+}
+
+// GetReembedJob returns a re-embedding job and its progress
+func (h *HTTPHandler) GetReembedJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.ReembedJob(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, "collections.get_reembed_job", err)
+		return
+	}
+	h.WriteSuccess(w, job, "Re-embedding job retrieved successfully")
+}
+
+// CancelReembed cancels an unfinished re-embedding job
+func (h *HTTPHandler) CancelReembed(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.CancelReembed(r.Context(), CallerFromContext(r.Context()), r.PathValue("name"), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, "collections.cancel_reembed", err)
+		return
+	}
+	h.WriteSuccess(w, job, "Re-embedding job cancelled")
+}
+
+// Search returns the chunks of a collection matching a query
+func (h *HTTPHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var req SearchRequest
+	if err := h.DecodeJSON(r, &req); err != nil {
+		h.WriteBadRequest(w, "Invalid request body", err.Error())
+		return
+	}
+	if req.Query == "" {
+		h.WriteBadRequest(w, "query is required")
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 5
+	}
+
+	results, err := h.manager.Search(r.Context(), CallerFromContext(r.Context()),
+		[]string{r.PathValue("name")}, req.Query, req.Limit, req.Threshold)
+	if err != nil {
+		h.writeError(w, r, "collections.search", err)
+		return
+	}
+	h.WriteSuccess(w, results, "Search completed successfully")
+}
+
+// writeError maps collection errors to responses
+func (h *HTTPHandler) writeError(w http.ResponseWriter, r *http.Request, operation string, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		h.WriteNotFound(w, "Collection")
+	case errors.Is(err, ErrDocumentNotFound):
+		h.WriteNotFound(w, "Document")
+	case errors.Is(err, ErrJobNotFound):
+		h.WriteNotFound(w, "Ingestion job")
+	case errors.Is(err, ErrReembedJobNotFound):
+		h.WriteNotFound(w, "Re-embedding job")
+	case errors.Is(err, ErrForbidden):
+		h.WriteError(w, middleware.CodeForbidden, "Collection access denied", http.StatusForbidden)
+	case errors.Is(err, ErrExists):
+		h.WriteError(w, middleware.CodeInvalidRequest, "Collection already exists", http.StatusConflict)
+	case errors.Is(err, ErrReembedding):
+		h.WriteError(w, middleware.CodeInvalidRequest, "Collection is already being re-embedded", http.StatusConflict)
+	case errors.Is(err, ErrInvalid):
+		h.WriteBadRequest(w, err.Error())
+	default:
+		h.LogError(r, operation, err)
+		h.WriteInternalError(w, err)
+	}
+}
+
+// limit parses the optional limit query parameter; zero takes the default
+func (h *HTTPHandler) limit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > 100 {
+		h.WriteBadRequest(w, "invalid limit parameter")
+		return 0, false
+	}
+	return limit, true
+}
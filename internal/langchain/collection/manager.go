@@ -0,0 +1,580 @@
+package collection
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain/documentloader"
+	"github.com/koopa0/assistant-go/internal/langchain/vectorstore"
+)
+
+// chunkNamespace derives chunk ids from their collection, source, position
+// and content, so re-ingesting unchanged text keeps its chunks
+var chunkNamespace = uuid.MustParse("3b8e5f0a-7c21-4d9e-a6f4-1e2d9c8b7a65")
+
+// validName matches collection names, which appear in URL paths
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$`)
+
+const (
+	embedBatchSize  = 32
+	defaultJobLimit = 20
+	maxModelLength  = 100
+)
+
+// EmbedderFactory returns the embedder of an embedding provider, the
+// default one for an empty provider, or nil for an unknown provider
+type EmbedderFactory func(provider string) embeddings.Embedder
+
+// CreateRequest describes a new collection. Zero chunk settings take the
+// configured defaults.
+type CreateRequest struct {
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	EmbeddingProvider string `json:"embedding_provider,omitempty"`
+	EmbeddingModel    string `json:"embedding_model,omitempty"` // names the provider's model
+	ChunkSize         int    `json:"chunk_size,omitempty"`
+	ChunkOverlap      int    `json:"chunk_overlap,omitempty"`
+}
+
+// Manager manages collections on behalf of callers, enforcing their
+// access: reading needs a read grant, ingesting and deleting documents a
+// write grant, and deleting and sharing a collection its ownership.
+// Collections a caller may not read are reported as not found.
+type Manager struct {
+	store       Store
+	search      vectorstore.SearchQueries
+	embedderFor EmbedderFactory
+	options     vectorstore.SearchOptions
+	config      config.Collections
+	logger      *slog.Logger
+
+	batchEmbedderFor BatchEmbedderFactory
+
+	ctx      context.Context // cancelled on Close, stopping running jobs
+	cancel   context.CancelFunc
+	jobs     sync.WaitGroup
+	mu       sync.Mutex
+	reembeds map[string]context.CancelFunc // running re-embedding jobs by id
+}
+
+// NewManager creates a collection manager. Searches run on search with
+// options; chunks are embedded with the embedder of each collection's
+// provider.
+func NewManager(store Store, search vectorstore.SearchQueries, embedderFor EmbedderFactory, options vectorstore.SearchOptions, cfg config.Collections, logger *slog.Logger) *Manager {
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 1000
+	}
+	if cfg.ChunkOverlap < 0 || cfg.ChunkOverlap >= cfg.ChunkSize {
+		cfg.ChunkOverlap = cfg.ChunkSize / 5
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		store:       store,
+		search:      search,
+		embedderFor: embedderFor,
+		options:     options,
+		config:      cfg,
+		logger:      logger,
+		ctx:         ctx,
+		cancel:      cancel,
+		reembeds:    make(map[string]context.CancelFunc),
+	}
+}
+
+// List returns the collections the caller may read
+func (m *Manager) List(ctx context.Context, caller Caller) ([]*Collection, error) {
+	collections, err := m.store.Collections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	readable := make([]*Collection, 0, len(collections))
+	for _, collection := range collections {
+		if collection.Access(caller).Allows(PermissionRead) {
+			readable = append(readable, collection)
+		}
+	}
+	return readable, nil
+}
+
+// Get returns a collection the caller may read
+func (m *Manager) Get(ctx context.Context, caller Caller, name string) (*Collection, error) {
+	return m.collection(ctx, caller, name, PermissionRead)
+}
+
+// collection returns a collection the caller has the required permission
+// on
+func (m *Manager) collection(ctx context.Context, caller Caller, name string, required Permission) (*Collection, error) {
+	collection, err := m.store.Collection(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	access := collection.Access(caller)
+	if !access.Allows(PermissionRead) {
+		return nil, ErrNotFound
+	}
+	if !access.Allows(required) {
+		return nil, ErrForbidden
+	}
+	return collection, nil
+}
+
+// owned returns a collection the caller owns
+func (m *Manager) owned(ctx context.Context, caller Caller, name string) (*Collection, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	if !collection.IsOwner(caller) {
+		return nil, ErrForbidden
+	}
+	return collection, nil
+}
+
+// Create creates a collection owned by the caller
+func (m *Manager) Create(ctx context.Context, caller Caller, request CreateRequest) (*Collection, error) {
+	if caller.UserID == "" {
+		return nil, ErrForbidden
+	}
+	if !validName.MatchString(request.Name) {
+		return nil, fmt.Errorf("%w: name must be 1-100 letters, digits, dots, dashes or underscores", ErrInvalid)
+	}
+	if m.embedderFor(request.EmbeddingProvider) == nil {
+		return nil, fmt.Errorf("%w: unknown embedding provider %q", ErrInvalid, request.EmbeddingProvider)
+	}
+	if len(request.EmbeddingModel) > maxModelLength {
+		return nil, fmt.Errorf("%w: embedding model must be at most %d characters", ErrInvalid, maxModelLength)
+	}
+
+	chunkSize := cmp.Or(request.ChunkSize, m.config.ChunkSize)
+	chunkOverlap := request.ChunkOverlap
+	if request.ChunkSize == 0 && request.ChunkOverlap == 0 {
+		chunkOverlap = m.config.ChunkOverlap
+	}
+	if chunkSize < 0 || chunkOverlap < 0 || chunkOverlap >= chunkSize {
+		return nil, fmt.Errorf("%w: chunk overlap must be between 0 and the chunk size", ErrInvalid)
+	}
+
+	collection, err := m.store.CreateCollection(ctx, &Collection{
+		Name:              request.Name,
+		Description:       request.Description,
+		OwnerID:           caller.UserID,
+		EmbeddingProvider: request.EmbeddingProvider,
+		EmbeddingModel:    request.EmbeddingModel,
+		ChunkSize:         chunkSize,
+		ChunkOverlap:      chunkOverlap,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Created collection",
+		slog.String("name", collection.Name),
+		slog.String("owner_id", caller.UserID))
+	return collection, nil
+}
+
+// Delete deletes a collection the caller owns, with its documents, chunks
+// and jobs
+func (m *Manager) Delete(ctx context.Context, caller Caller, name string) error {
+	collection, err := m.owned(ctx, caller, name)
+	if err != nil {
+		return err
+	}
+	if err := m.store.DeleteCollection(ctx, collection.ID); err != nil {
+		return err
+	}
+
+	m.logger.Info("Deleted collection", slog.String("name", name))
+	return nil
+}
+
+// Share grants a user or team access to a collection the caller owns
+func (m *Manager) Share(ctx context.Context, caller Caller, name string, grant Grant) (*Collection, error) {
+	if grant.GranteeType != GranteeUser && grant.GranteeType != GranteeTeam {
+		return nil, fmt.Errorf("%w: grantee type must be user or team", ErrInvalid)
+	}
+	if grant.GranteeID == "" {
+		return nil, fmt.Errorf("%w: grantee id is required", ErrInvalid)
+	}
+	if grant.Permission != PermissionRead && grant.Permission != PermissionWrite {
+		return nil, fmt.Errorf("%w: permission must be read or write", ErrInvalid)
+	}
+
+	collection, err := m.owned(ctx, caller, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.SaveGrant(ctx, collection.ID, grant); err != nil {
+		return nil, err
+	}
+	return m.store.Collection(ctx, name)
+}
+
+// Unshare revokes a grant of a collection the caller owns
+func (m *Manager) Unshare(ctx context.Context, caller Caller, name, granteeType, granteeID string) (*Collection, error) {
+	collection, err := m.owned(ctx, caller, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.DeleteGrant(ctx, collection.ID, granteeType, granteeID); err != nil {
+		return nil, err
+	}
+	return m.store.Collection(ctx, name)
+}
+
+// Documents returns the documents of a collection
+func (m *Manager) Documents(ctx context.Context, caller Caller, name string) ([]*Document, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.Documents(ctx, collection.ID)
+}
+
+// document returns a document of a collection
+func (m *Manager) document(ctx context.Context, collection *Collection, id string) (*Document, error) {
+	document, err := m.store.Document(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if document.CollectionID != collection.ID {
+		return nil, ErrDocumentNotFound
+	}
+	return document, nil
+}
+
+// DeleteDocument deletes a document and its chunks
+func (m *Manager) DeleteDocument(ctx context.Context, caller Caller, name, id string) error {
+	collection, err := m.collection(ctx, caller, name, PermissionWrite)
+	if err != nil {
+		return err
+	}
+	document, err := m.document(ctx, collection, id)
+	if err != nil {
+		return err
+	}
+	if err := m.store.DeleteChunks(ctx, collection.ID, document.ChunkIDs); err != nil {
+		return err
+	}
+	return m.store.DeleteDocument(ctx, document.ID)
+}
+
+// Ingest starts a job ingesting a file, a directory or an http(s) URL
+// into a collection. Sources whose content did not change are skipped.
+func (m *Manager) Ingest(ctx context.Context, caller Caller, name, source string) (*Job, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	source, err = m.checkSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return m.start(ctx, caller, collection, source, false)
+}
+
+// Reingest starts a job loading, chunking and embedding a document again
+func (m *Manager) Reingest(ctx context.Context, caller Caller, name, id string) (*Job, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	document, err := m.document(ctx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+	return m.start(ctx, caller, collection, document.Source, true)
+}
+
+// Jobs returns the latest ingestion jobs of a collection
+func (m *Manager) Jobs(ctx context.Context, caller Caller, name string, limit int) ([]*Job, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultJobLimit
+	}
+	return m.store.Jobs(ctx, collection.ID, limit)
+}
+
+// Job returns an ingestion job of a collection
+func (m *Manager) Job(ctx context.Context, caller Caller, name, id string) (*Job, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	job, err := m.store.Job(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.CollectionID != collection.ID {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Wait waits for running ingestion and re-embedding jobs to finish
+func (m *Manager) Wait() {
+	m.jobs.Wait()
+}
+
+// Close stops running jobs and waits for them to finish. Ingestion jobs
+// are recorded as failed; re-embedding jobs resume on the next start.
+func (m *Manager) Close() {
+	m.cancel()
+	m.jobs.Wait()
+}
+
+// checkSource validates a source and returns its absolute path. Files must
+// be under one of the ingest roots, when configured.
+func (m *Manager) checkSource(source string) (string, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return "", fmt.Errorf("%w: source is required", ErrInvalid)
+	}
+	if isURL(source) {
+		return source, nil
+	}
+
+	path, err := filepath.Abs(source)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid source path: %v", ErrInvalid, err)
+	}
+	if len(m.config.IngestRoots) == 0 {
+		return path, nil
+	}
+	for _, root := range m.config.IngestRoots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: source is outside the ingest roots", ErrInvalid)
+}
+
+// start records a pending job and runs it in the background. The job
+// outlives the request that started it.
+func (m *Manager) start(ctx context.Context, caller Caller, collection *Collection, source string, force bool) (*Job, error) {
+	job, err := m.store.CreateJob(ctx, collection.ID, source, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.jobs.Add(1)
+	go func() {
+		defer m.jobs.Done()
+		m.run(m.ctx, collection, *job, force)
+	}()
+	return job, nil
+}
+
+// run ingests the source of a job and records its outcome
+func (m *Manager) run(ctx context.Context, collection *Collection, job Job, force bool) {
+	if err := m.store.StartJob(ctx, job.ID); err != nil {
+		m.logger.Warn("Failed to start ingestion job", slog.String("job_id", job.ID), slog.Any("error", err))
+	}
+	// A re-embedding may have switched the collection's model since the
+	// job was requested; it does not switch while the job runs
+	if current, err := m.store.CollectionByID(ctx, collection.ID); err == nil {
+		collection = current
+	}
+
+	err := m.ingest(ctx, collection, &job, force)
+	job.Status = JobSucceeded
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+	if err := m.store.FinishJob(context.WithoutCancel(ctx), &job); err != nil {
+		m.logger.Warn("Failed to finish ingestion job", slog.String("job_id", job.ID), slog.Any("error", err))
+	}
+
+	m.logger.Info("Ingestion job finished",
+		slog.String("collection", collection.Name),
+		slog.String("source", job.Source),
+		slog.String("status", job.Status),
+		slog.Int("documents", job.Documents),
+		slog.Int("chunks", job.Chunks))
+}
+
+// ingest loads the source of a job and ingests each document it holds.
+// Documents that fail are skipped and reported together.
+func (m *Manager) ingest(ctx context.Context, collection *Collection, job *Job, force bool) error {
+	embedder := m.embedderFor(collection.EmbeddingProvider)
+	if embedder == nil {
+		return fmt.Errorf("no embedder for provider %q", collection.EmbeddingProvider)
+	}
+	processor := documentloader.NewDocumentProcessor(m.logger)
+	processor.SetTextSplitter(documentloader.CreateCustomSplitter(collection.ChunkSize, collection.ChunkOverlap, nil))
+
+	loaded, err := load(ctx, processor, job.Source)
+	if err != nil {
+		return err
+	}
+	stored, err := m.store.Documents(ctx, collection.ID)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]*Document, len(stored))
+	for _, document := range stored {
+		previous[document.Source] = document
+	}
+
+	// Loaders return a document per page or section; ingest them by source
+	bySource := make(map[string][]schema.Document)
+	for _, doc := range loaded {
+		source, _ := doc.Metadata["source"].(string)
+		source = cmp.Or(source, job.Source)
+		bySource[source] = append(bySource[source], doc)
+	}
+
+	var errs []error
+	for _, source := range slices.Sorted(maps.Keys(bySource)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunks, err := m.ingestDocument(ctx, collection, processor, embedder, source, bySource[source], previous[source], force)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		if chunks >= 0 {
+			job.Documents++
+			job.Chunks += chunks
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ingestDocument embeds the new chunks of a document and deletes the
+// chunks it lost. It returns the number of chunks embedded, or -1 when
+// the document did not change.
+func (m *Manager) ingestDocument(ctx context.Context, collection *Collection, processor *documentloader.DocumentProcessor, embedder embeddings.Embedder, source string, docs []schema.Document, before *Document, force bool) (int, error) {
+	hash := sha256.New()
+	for _, doc := range docs {
+		hash.Write([]byte(doc.PageContent))
+	}
+	contentHash := hex.EncodeToString(hash.Sum(nil))
+	if before != nil && before.ContentHash == contentHash && !force {
+		return -1, nil
+	}
+
+	chunks, err := processor.SplitDocuments(ctx, docs)
+	if err != nil {
+		return 0, err
+	}
+
+	// A forced re-ingest embeds every chunk again
+	existing := make(map[uuid.UUID]bool)
+	if before != nil && force {
+		if err := m.store.DeleteChunks(ctx, collection.ID, before.ChunkIDs); err != nil {
+			return 0, err
+		}
+	} else if before != nil {
+		for _, id := range before.ChunkIDs {
+			existing[id] = true
+		}
+	}
+	ids := make([]uuid.UUID, len(chunks))
+	var pending []int // chunks to embed
+	for i, chunk := range chunks {
+		sum := sha256.Sum256([]byte(chunk.PageContent))
+		ids[i] = uuid.NewSHA1(chunkNamespace, []byte(collection.ID+"\x00"+source+"\x00"+strconv.Itoa(i)+"\x00"+hex.EncodeToString(sum[:])))
+		if existing[ids[i]] {
+			delete(existing, ids[i])
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	var embedded []uuid.UUID
+	for batch := range slices.Chunk(pending, embedBatchSize) {
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = chunks[i].PageContent
+		}
+		vectors, err := embedder.EmbedDocuments(ctx, texts)
+		if err == nil && len(vectors) != len(batch) {
+			err = fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(batch))
+		}
+		if err != nil {
+			m.discard(ctx, collection, embedded)
+			return 0, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		for j, i := range batch {
+			metadata := chunks[i].Metadata
+			metadata["collection"] = collection.Name
+			if err := m.store.PutChunk(ctx, collection.ID, ids[i], chunks[i].PageContent, vectors[j], metadata, collection.EmbeddingModel); err != nil {
+				m.discard(ctx, collection, embedded)
+				return 0, err
+			}
+			embedded = append(embedded, ids[i])
+		}
+	}
+
+	_, err = m.store.SaveDocument(ctx, &Document{
+		CollectionID: collection.ID,
+		Source:       source,
+		ContentHash:  contentHash,
+		ChunkIDs:     ids,
+	})
+	if err != nil {
+		m.discard(ctx, collection, embedded)
+		return 0, err
+	}
+
+	// Chunks left over are no longer part of the document
+	stale := slices.Collect(maps.Keys(existing))
+	if err := m.store.DeleteChunks(ctx, collection.ID, stale); err != nil {
+		return 0, err
+	}
+	return len(embedded), nil
+}
+
+// discard deletes the chunks stored by a failed document ingestion
+func (m *Manager) discard(ctx context.Context, collection *Collection, ids []uuid.UUID) {
+	if err := m.store.DeleteChunks(ctx, collection.ID, ids); err != nil {
+		m.logger.Warn("Failed to delete chunks of failed document",
+			slog.String("collection", collection.Name),
+			slog.Any("error", err))
+	}
+}
+
+// load loads the documents of a URL, file or directory
+func load(ctx context.Context, processor *documentloader.DocumentProcessor, source string) ([]schema.Document, error) {
+	if isURL(source) {
+		return processor.LoadFromURL(ctx, source)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source: %w", err)
+	}
+	if info.IsDir() {
+		return processor.LoadDirectory(ctx, source, true, processor.GetSupportedExtensions())
+	}
+	return processor.LoadFile(ctx, source)
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
@@ -0,0 +1,385 @@
+package collection
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+)
+
+// switchRetryInterval is how long a re-embedding job waits to switch over
+// while ingestion jobs add chunks to its collection
+var switchRetryInterval = 5 * time.Second
+
+// errReembedCancelled records that a re-embedding job was cancelled
+var errReembedCancelled = errors.New("re-embedding cancelled")
+
+// BatchEmbedder embeds texts in batches with one provider's model
+type BatchEmbedder interface {
+	BatchGenerateEmbeddings(ctx context.Context, texts []string) ([]*ai.EmbeddingResponse, error)
+}
+
+// BatchEmbedderFactory returns the batch embedder of a provider's model,
+// the default provider for an empty provider, or nil for an unknown
+// provider
+type BatchEmbedderFactory func(provider, model string) BatchEmbedder
+
+// ReembedRequest names the model to move a collection to, and its
+// provider when that changes too
+type ReembedRequest struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model"`
+}
+
+// UseBatchEmbedders enables re-embedding jobs, which embed chunks with the
+// batch embedders of factory
+func (m *Manager) UseBatchEmbedders(factory BatchEmbedderFactory) {
+	m.batchEmbedderFor = factory
+}
+
+// Reembed starts a job moving a collection the caller owns to a new
+// embedding model. The chunks are embedded with the new model next to the
+// active one, which searches keep using until every chunk has a vector of
+// the new model; the collection then switches over and the vectors of the
+// previous model are deleted.
+func (m *Manager) Reembed(ctx context.Context, caller Caller, name string, request ReembedRequest) (*ReembedJob, error) {
+	collection, err := m.owned(ctx, caller, name)
+	if err != nil {
+		return nil, err
+	}
+	if request.Model == "" || len(request.Model) > maxModelLength {
+		return nil, fmt.Errorf("%w: model must be 1-%d characters", ErrInvalid, maxModelLength)
+	}
+	if request.Model == collection.EmbeddingModel {
+		return nil, fmt.Errorf("%w: %s is already the active model", ErrInvalid, request.Model)
+	}
+	provider := cmp.Or(request.Provider, collection.EmbeddingProvider)
+	if m.batchEmbedderFor == nil {
+		return nil, fmt.Errorf("%w: re-embedding is not available", ErrInvalid)
+	}
+	if m.batchEmbedderFor(provider, request.Model) == nil || m.embedderFor(provider) == nil {
+		return nil, fmt.Errorf("%w: unknown embedding provider %q", ErrInvalid, provider)
+	}
+
+	job, err := m.store.CreateReembedJob(ctx, collection.ID, provider, request.Model, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	m.startReembed(*job)
+
+	m.logger.Info("Started re-embedding",
+		slog.String("collection", collection.Name),
+		slog.String("from", collection.EmbeddingModel),
+		slog.String("to", job.Model))
+	return job, nil
+}
+
+// ReembedJobs returns the latest re-embedding jobs of a collection
+func (m *Manager) ReembedJobs(ctx context.Context, caller Caller, name string, limit int) ([]*ReembedJob, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultJobLimit
+	}
+	return m.store.ReembedJobs(ctx, collection.ID, limit)
+}
+
+// ReembedJob returns a re-embedding job of a collection
+func (m *Manager) ReembedJob(ctx context.Context, caller Caller, name, id string) (*ReembedJob, error) {
+	collection, err := m.collection(ctx, caller, name, PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	return m.reembedJob(ctx, collection, id)
+}
+
+// reembedJob returns a re-embedding job of a collection
+func (m *Manager) reembedJob(ctx context.Context, collection *Collection, id string) (*ReembedJob, error) {
+	job, err := m.store.ReembedJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.CollectionID != collection.ID {
+		return nil, ErrReembedJobNotFound
+	}
+	return job, nil
+}
+
+// CancelReembed cancels an unfinished re-embedding job of a collection the
+// caller owns and deletes the vectors it stored
+func (m *Manager) CancelReembed(ctx context.Context, caller Caller, name, id string) (*ReembedJob, error) {
+	collection, err := m.owned(ctx, caller, name)
+	if err != nil {
+		return nil, err
+	}
+	job, err := m.reembedJob(ctx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != JobPending && job.Status != JobRunning {
+		return nil, fmt.Errorf("%w: the re-embedding job has finished", ErrInvalid)
+	}
+
+	m.mu.Lock()
+	cancel, running := m.reembeds[job.ID]
+	m.mu.Unlock()
+	if running {
+		// The job records its cancellation when it stops
+		cancel()
+		return job, nil
+	}
+	if err := m.cancelled(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// AdoptModel makes model the active model of a collection without one,
+// such as the default collection before a model was recorded, so the
+// vectors stored there with model are searched. A collection with another
+// model keeps it until a re-embedding job switches it.
+func (m *Manager) AdoptModel(ctx context.Context, id, model string) error {
+	if model == "" {
+		return nil
+	}
+	adopted, err := m.store.AdoptModel(ctx, id, model)
+	if err != nil {
+		return err
+	}
+	if adopted {
+		m.logger.Info("Collection adopted embedding model",
+			slog.String("collection_id", id),
+			slog.String("model", model))
+		return nil
+	}
+	collection, err := m.store.CollectionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if collection.EmbeddingModel != model {
+		m.logger.Warn("Collection has another embedding model; vectors of the configured model are not searched until it is re-embedded",
+			slog.String("collection", collection.Name),
+			slog.String("active_model", collection.EmbeddingModel),
+			slog.String("model", model))
+	}
+	return nil
+}
+
+// Resume restarts the re-embedding jobs left unfinished when the server
+// stopped
+func (m *Manager) Resume(ctx context.Context) error {
+	if m.batchEmbedderFor == nil {
+		return nil
+	}
+	jobs, err := m.store.UnfinishedReembedJobs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		m.logger.Info("Resuming re-embedding job",
+			slog.String("job_id", job.ID),
+			slog.String("model", job.Model),
+			slog.Int("done", job.Done),
+			slog.Int("total", job.Total))
+		m.startReembed(*job)
+	}
+	return nil
+}
+
+// startReembed runs a re-embedding job in the background until it
+// finishes, is cancelled or the manager closes
+func (m *Manager) startReembed(job ReembedJob) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.mu.Lock()
+	m.reembeds[job.ID] = cancel
+	m.mu.Unlock()
+
+	m.jobs.Add(1)
+	go func() {
+		defer m.jobs.Done()
+		defer func() {
+			m.mu.Lock()
+			delete(m.reembeds, job.ID)
+			m.mu.Unlock()
+			cancel()
+		}()
+		m.runReembed(ctx, job)
+	}()
+}
+
+// runReembed re-embeds the chunks of a job's collection and records its
+// outcome. A job stopped by Close stays running and resumes on the next
+// start.
+func (m *Manager) runReembed(ctx context.Context, job ReembedJob) {
+	if err := m.store.StartReembedJob(ctx, job.ID); err != nil {
+		m.logger.Warn("Failed to start re-embedding job", slog.String("job_id", job.ID), slog.Any("error", err))
+	}
+
+	err := m.reembed(ctx, &job)
+	switch {
+	case err != nil && m.ctx.Err() != nil:
+		m.logger.Info("Re-embedding job stopped", slog.String("job_id", job.ID), slog.Int("done", job.Done))
+		return
+	case err != nil && ctx.Err() != nil:
+		if err := m.cancelled(context.WithoutCancel(ctx), &job); err != nil {
+			m.logger.Warn("Failed to cancel re-embedding job", slog.String("job_id", job.ID), slog.Any("error", err))
+		}
+		m.logger.Info("Re-embedding job cancelled", slog.String("job_id", job.ID), slog.Int("done", job.Done))
+		return
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = JobSucceeded
+	}
+	if err := m.store.FinishReembedJob(context.WithoutCancel(ctx), &job); err != nil {
+		m.logger.Warn("Failed to finish re-embedding job", slog.String("job_id", job.ID), slog.Any("error", err))
+	}
+
+	m.logger.Info("Re-embedding job finished",
+		slog.String("job_id", job.ID),
+		slog.String("model", job.Model),
+		slog.String("status", job.Status),
+		slog.Int("done", job.Done),
+		slog.Int("total", job.Total))
+}
+
+// cancelled records a job as cancelled and deletes the vectors it stored
+func (m *Manager) cancelled(ctx context.Context, job *ReembedJob) error {
+	if err := m.store.DeleteModelChunks(ctx, job.CollectionID, job.Model); err != nil {
+		return err
+	}
+	job.Status = JobFailed
+	job.Error = errReembedCancelled.Error()
+	return m.store.FinishReembedJob(ctx, job)
+}
+
+// reembed embeds the chunks of the active model that lack a vector of the
+// job's model, batch by batch, then switches the collection over. Chunks
+// ingested meanwhile are picked up by later batches. A resumed job only
+// embeds the chunks without a vector of the model.
+func (m *Manager) reembed(ctx context.Context, job *ReembedJob) error {
+	embedder := m.batchEmbedderFor(job.Provider, job.Model)
+	if embedder == nil {
+		return fmt.Errorf("no embedder for provider %q", job.Provider)
+	}
+	batchSize := cmp.Or(m.config.ReembedBatchSize, embedBatchSize)
+	pacer := newPacer(m.config.ReembedRate)
+
+	for {
+		if err := m.progress(ctx, job); err != nil {
+			return err
+		}
+		chunks, err := m.store.ReembedCandidates(ctx, job.CollectionID, job.Model, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			switched, err := m.store.SwitchModel(ctx, job.CollectionID, job.Provider, job.Model, job.Dimensions)
+			if err != nil {
+				return err
+			}
+			if switched {
+				return m.progress(ctx, job)
+			}
+			// Vectors of other dimensions were deleted to embed again, or
+			// ingestion jobs are adding chunks of the active model
+			if left, err := m.store.ReembedCandidates(ctx, job.CollectionID, job.Model, 1); err != nil || len(left) > 0 {
+				continue
+			}
+			if err := sleep(ctx, switchRetryInterval); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := pacer.wait(ctx, len(chunks)); err != nil {
+			return err
+		}
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Content
+		}
+		responses, err := embedder.BatchGenerateEmbeddings(ctx, texts)
+		if err == nil && len(responses) != len(chunks) {
+			err = fmt.Errorf("embedder returned %d vectors for %d chunks", len(responses), len(chunks))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to embed chunks: %w", err)
+		}
+
+		for i, chunk := range chunks {
+			vector := make([]float32, len(responses[i].Embedding))
+			for j, v := range responses[i].Embedding {
+				vector[j] = float32(v)
+			}
+			if job.Dimensions == 0 {
+				job.Dimensions = len(vector)
+			}
+			if len(vector) == 0 || len(vector) != job.Dimensions {
+				return fmt.Errorf("model %s returned %d dimensions, want %d", job.Model, len(vector), job.Dimensions)
+			}
+			if err := m.store.PutChunk(ctx, job.CollectionID, chunk.ID, chunk.Content, vector, chunk.Metadata, job.Model); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// progress records how many chunks a job has embedded
+func (m *Manager) progress(ctx context.Context, job *ReembedJob) error {
+	total, done, err := m.store.ReembedProgress(ctx, job.CollectionID, job.Model)
+	if err != nil {
+		return err
+	}
+	job.Total, job.Done = total, done
+	return m.store.UpdateReembedJob(ctx, job)
+}
+
+// pacer spaces embedding requests to embed at most rate chunks a minute
+type pacer struct {
+	perChunk time.Duration
+	next     time.Time
+}
+
+// newPacer creates a pacer; a rate of 0 is unlimited
+func newPacer(rate int) *pacer {
+	p := &pacer{}
+	if rate > 0 {
+		p.perChunk = time.Minute / time.Duration(rate)
+	}
+	return p
+}
+
+// wait waits until n chunks may be embedded
+func (p *pacer) wait(ctx context.Context, n int) error {
+	if p.perChunk == 0 {
+		return nil
+	}
+	if err := sleep(ctx, time.Until(p.next)); err != nil {
+		return err
+	}
+	p.next = time.Now().Add(time.Duration(n) * p.perChunk)
+	return nil
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,106 @@
+package collection
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/koopa0/assistant-go/internal/langchain/vectorstore"
+)
+
+// Search returns up to limit chunks for a query from the named
+// collections, or from every collection the caller may read when names is
+// empty. The query is embedded once per embedding provider, as collections
+// of different providers have incomparable vectors, and the results of
+// each provider's search are merged by score.
+func (m *Manager) Search(ctx context.Context, caller Caller, names []string, query string, limit int, threshold float64) ([]vectorstore.SearchResult, error) {
+	var collections []*Collection
+	if len(names) == 0 {
+		readable, err := m.List(ctx, caller)
+		if err != nil {
+			return nil, err
+		}
+		collections = readable
+	}
+	for _, name := range names {
+		collection, err := m.collection(ctx, caller, name, PermissionRead)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	if len(collections) == 0 || limit <= 0 {
+		return []vectorstore.SearchResult{}, nil
+	}
+
+	byProvider := make(map[string][]string)
+	var providers []string
+	for _, collection := range collections {
+		if _, ok := byProvider[collection.EmbeddingProvider]; !ok {
+			providers = append(providers, collection.EmbeddingProvider)
+		}
+		byProvider[collection.EmbeddingProvider] = append(byProvider[collection.EmbeddingProvider], collection.ID)
+	}
+
+	searcher := vectorstore.NewSearcher(m.search, m.options, m.logger)
+	var results []vectorstore.SearchResult
+	for _, provider := range providers {
+		found, err := searcher.Search(ctx, query, m.embedQuery(ctx, provider, query), limit, vectorstore.Filter{
+			Collections: byProvider[provider],
+		}, threshold)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+
+	if len(providers) > 1 {
+		slices.SortStableFunc(results, func(a, b vectorstore.SearchResult) int {
+			return cmp.Compare(b.Score, a.Score)
+		})
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// embedQuery embeds a query with a provider. Without an embedding a
+// hybrid search falls back to full text.
+func (m *Manager) embedQuery(ctx context.Context, provider, query string) []float32 {
+	embedder := m.embedderFor(provider)
+	if embedder == nil {
+		return nil
+	}
+	vector, err := embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		m.logger.Warn("Failed to embed query",
+			slog.String("provider", provider),
+			slog.Any("error", err))
+		return nil
+	}
+	return vector
+}
+
+// Searcher searches the collections a caller may read
+type Searcher struct {
+	manager *Manager
+	caller  Caller
+	names   []string
+}
+
+// Searcher returns a searcher of the named collections, or of every
+// collection the caller may read when names is empty
+func (m *Manager) Searcher(caller Caller, names []string) *Searcher {
+	return &Searcher{
+		manager: m,
+		caller:  caller,
+		names:   names,
+	}
+}
+
+// Search returns up to limit chunks for a query
+func (s *Searcher) Search(ctx context.Context, query string, limit int, threshold float64) ([]vectorstore.SearchResult, error) {
+	return s.manager.Search(ctx, s.caller, s.names, query, limit, threshold)
+}
@@ -0,0 +1,757 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// Store persists collections, their grants, documents, chunks, ingestion
+// and re-embedding jobs
+type Store interface {
+	CreateCollection(ctx context.Context, collection *Collection) (*Collection, error)
+	Collection(ctx context.Context, name string) (*Collection, error)
+	CollectionByID(ctx context.Context, id string) (*Collection, error)
+	Collections(ctx context.Context) ([]*Collection, error)
+	DeleteCollection(ctx context.Context, id string) error
+	SaveGrant(ctx context.Context, collectionID string, grant Grant) error
+	DeleteGrant(ctx context.Context, collectionID, granteeType, granteeID string) error
+
+	Documents(ctx context.Context, collectionID string) ([]*Document, error)
+	Document(ctx context.Context, id string) (*Document, error)
+	SaveDocument(ctx context.Context, document *Document) (*Document, error)
+	DeleteDocument(ctx context.Context, id string) error
+
+	PutChunk(ctx context.Context, collectionID string, id uuid.UUID, content string, vector []float32, metadata map[string]any, model string) error
+	DeleteChunks(ctx context.Context, collectionID string, ids []uuid.UUID) error
+
+	CreateJob(ctx context.Context, collectionID, source, createdBy string) (*Job, error)
+	Job(ctx context.Context, id string) (*Job, error)
+	Jobs(ctx context.Context, collectionID string, limit int) ([]*Job, error)
+	StartJob(ctx context.Context, id string) error
+	FinishJob(ctx context.Context, job *Job) error
+
+	CreateReembedJob(ctx context.Context, collectionID, provider, model, createdBy string) (*ReembedJob, error)
+	ReembedJob(ctx context.Context, id string) (*ReembedJob, error)
+	ReembedJobs(ctx context.Context, collectionID string, limit int) ([]*ReembedJob, error)
+	UnfinishedReembedJobs(ctx context.Context) ([]*ReembedJob, error)
+	StartReembedJob(ctx context.Context, id string) error
+	UpdateReembedJob(ctx context.Context, job *ReembedJob) error
+	FinishReembedJob(ctx context.Context, job *ReembedJob) error
+
+	// ReembedCandidates returns up to limit chunks of the active model
+	// without a vector of model
+	ReembedCandidates(ctx context.Context, collectionID, model string, limit int) ([]Chunk, error)
+	// ReembedProgress counts the chunks of the active model and those with
+	// a vector of model
+	ReembedProgress(ctx context.Context, collectionID, model string) (total, done int, err error)
+	// SwitchModel makes model active and deletes the vectors of other
+	// models at once, unless chunks lack a vector of model or an ingestion
+	// job is running. Vectors of model without the given dimensions keep
+	// it from switching and are deleted. It reports whether the collection
+	// switched.
+	SwitchModel(ctx context.Context, collectionID, provider, model string, dimensions int) (bool, error)
+	// DeleteModelChunks deletes the vectors of an inactive model
+	DeleteModelChunks(ctx context.Context, collectionID, model string) error
+	// AdoptModel makes model active in a collection without a model and
+	// records it on the vectors stored before models were. It reports
+	// whether the collection adopted the model.
+	AdoptModel(ctx context.Context, collectionID, model string) (bool, error)
+}
+
+// QueriesStore implements Store with sqlc queries
+type QueriesStore struct {
+	queries *sqlc.Queries
+}
+
+// NewQueriesStore creates a collection store backed by sqlc queries
+func NewQueriesStore(queries *sqlc.Queries) *QueriesStore {
+	return &QueriesStore{
+		queries: queries,
+	}
+}
+
+// CreateCollection stores a new collection
+func (s *QueriesStore) CreateCollection(ctx context.Context, collection *Collection) (*Collection, error) {
+	ownerID, err := optionalID(collection.OwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid owner id", ErrInvalid)
+	}
+	row, err := s.queries.CreateCollection(ctx, sqlc.CreateCollectionParams{
+		Name:              collection.Name,
+		Description:       collection.Description,
+		OwnerID:           ownerID,
+		EmbeddingProvider: collection.EmbeddingProvider,
+		EmbeddingModel:    collection.EmbeddingModel,
+		ChunkSize:         int32(collection.ChunkSize),
+		ChunkOverlap:      int32(collection.ChunkOverlap),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrExists
+		}
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	return toCollection(row, nil), nil
+}
+
+// Collection returns a collection and its grants by name
+func (s *QueriesStore) Collection(ctx context.Context, name string) (*Collection, error) {
+	row, err := s.queries.GetCollectionByName(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	grants, err := s.grants(ctx, row.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toCollection(row, grants[row.ID.Bytes]), nil
+}
+
+// CollectionByID returns a collection and its grants by id
+func (s *QueriesStore) CollectionByID(ctx context.Context, id string) (*Collection, error) {
+	collectionID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	row, err := s.queries.GetCollection(ctx, pgtype.UUID{Bytes: collectionID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	grants, err := s.grants(ctx, row.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toCollection(row, grants[row.ID.Bytes]), nil
+}
+
+// Collections returns every collection and its grants
+func (s *QueriesStore) Collections(ctx context.Context) ([]*Collection, error) {
+	rows, err := s.queries.ListCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	ids := make([]pgtype.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	grants, err := s.grants(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := make([]*Collection, 0, len(rows))
+	for _, row := range rows {
+		collections = append(collections, toCollection(row, grants[row.ID.Bytes]))
+	}
+	return collections, nil
+}
+
+// grants returns the grants of collections by collection id
+func (s *QueriesStore) grants(ctx context.Context, ids ...pgtype.UUID) (map[[16]byte][]Grant, error) {
+	rows, err := s.queries.ListCollectionGrants(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list grants: %w", err)
+	}
+	grants := make(map[[16]byte][]Grant)
+	for _, row := range rows {
+		grants[row.CollectionID.Bytes] = append(grants[row.CollectionID.Bytes], Grant{
+			GranteeType: row.GranteeType,
+			GranteeID:   row.GranteeID,
+			Permission:  Permission(row.Permission),
+		})
+	}
+	return grants, nil
+}
+
+// DeleteCollection deletes a collection with its documents, chunks and jobs
+func (s *QueriesStore) DeleteCollection(ctx context.Context, id string) error {
+	collectionID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.queries.DeleteCollection(ctx, collectionID); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// SaveGrant creates or updates a grant
+func (s *QueriesStore) SaveGrant(ctx context.Context, collectionID string, grant Grant) error {
+	id, err := parseID(collectionID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.UpsertCollectionGrant(ctx, sqlc.UpsertCollectionGrantParams{
+		CollectionID: id,
+		GranteeType:  grant.GranteeType,
+		GranteeID:    grant.GranteeID,
+		Permission:   string(grant.Permission),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save grant: %w", err)
+	}
+	return nil
+}
+
+// DeleteGrant revokes a grant
+func (s *QueriesStore) DeleteGrant(ctx context.Context, collectionID, granteeType, granteeID string) error {
+	id, err := parseID(collectionID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.DeleteCollectionGrant(ctx, sqlc.DeleteCollectionGrantParams{
+		CollectionID: id,
+		GranteeType:  granteeType,
+		GranteeID:    granteeID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete grant: %w", err)
+	}
+	return nil
+}
+
+// Documents returns the documents of a collection
+func (s *QueriesStore) Documents(ctx context.Context, collectionID string) ([]*Document, error) {
+	id, err := parseID(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListCollectionDocuments(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	documents := make([]*Document, 0, len(rows))
+	for _, row := range rows {
+		documents = append(documents, toDocument(row))
+	}
+	return documents, nil
+}
+
+// Document returns a document by id
+func (s *QueriesStore) Document(ctx context.Context, id string) (*Document, error) {
+	documentID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrDocumentNotFound
+	}
+	row, err := s.queries.GetCollectionDocument(ctx, pgtype.UUID{Bytes: documentID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	return toDocument(row), nil
+}
+
+// SaveDocument creates or updates the document of a source
+func (s *QueriesStore) SaveDocument(ctx context.Context, document *Document) (*Document, error) {
+	collectionID, err := parseID(document.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	row, err := s.queries.UpsertCollectionDocument(ctx, sqlc.UpsertCollectionDocumentParams{
+		CollectionID: collectionID,
+		Source:       document.Source,
+		ContentHash:  document.ContentHash,
+		ChunkIds:     toPgIDs(document.ChunkIDs),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save document: %w", err)
+	}
+	return toDocument(row), nil
+}
+
+// DeleteDocument forgets a document; its chunks are deleted separately
+func (s *QueriesStore) DeleteDocument(ctx context.Context, id string) error {
+	documentID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.queries.DeleteCollectionDocument(ctx, documentID); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	return nil
+}
+
+// PutChunk stores a chunk and its embedding by model
+func (s *QueriesStore) PutChunk(ctx context.Context, collectionID string, id uuid.UUID, content string, vector []float32, metadata map[string]any, model string) error {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	err = s.queries.CreateCollectionEmbedding(ctx, sqlc.CreateCollectionEmbeddingParams{
+		CollectionID: cid,
+		ContentType:  ContentType,
+		ContentID:    pgtype.UUID{Bytes: id, Valid: true},
+		ContentText:  content,
+		Embedding:    pgvector.NewVector(vector),
+		Metadata:     data,
+		Model:        model,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store chunk: %w", err)
+	}
+	return nil
+}
+
+// DeleteChunks removes stored chunks
+func (s *QueriesStore) DeleteChunks(ctx context.Context, collectionID string, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.DeleteCollectionEmbeddings(ctx, sqlc.DeleteCollectionEmbeddingsParams{
+		CollectionID: cid,
+		ContentIds:   toPgIDs(ids),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return nil
+}
+
+// CreateJob records a pending ingestion job
+func (s *QueriesStore) CreateJob(ctx context.Context, collectionID, source, createdBy string) (*Job, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	// Jobs of callers without a user row are recorded without a creator
+	creator, err := optionalID(createdBy)
+	if err != nil {
+		creator = pgtype.UUID{}
+	}
+	row, err := s.queries.CreateIngestionJob(ctx, sqlc.CreateIngestionJobParams{
+		CollectionID: cid,
+		Source:       source,
+		CreatedBy:    creator,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ingestion job: %w", err)
+	}
+	return toJob(row), nil
+}
+
+// Job returns an ingestion job by id
+func (s *QueriesStore) Job(ctx context.Context, id string) (*Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	row, err := s.queries.GetIngestionJob(ctx, pgtype.UUID{Bytes: jobID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingestion job: %w", err)
+	}
+	return toJob(row), nil
+}
+
+// Jobs returns the latest ingestion jobs of a collection
+func (s *QueriesStore) Jobs(ctx context.Context, collectionID string, limit int) ([]*Job, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListIngestionJobs(ctx, sqlc.ListIngestionJobsParams{
+		CollectionID: cid,
+		Limit:        int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingestion jobs: %w", err)
+	}
+	jobs := make([]*Job, 0, len(rows))
+	for _, row := range rows {
+		jobs = append(jobs, toJob(row))
+	}
+	return jobs, nil
+}
+
+// StartJob marks a job as running
+func (s *QueriesStore) StartJob(ctx context.Context, id string) error {
+	jobID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.queries.StartIngestionJob(ctx, jobID); err != nil {
+		return fmt.Errorf("failed to start ingestion job: %w", err)
+	}
+	return nil
+}
+
+// FinishJob records the status, counts and error of a finished job
+func (s *QueriesStore) FinishJob(ctx context.Context, job *Job) error {
+	jobID, err := parseID(job.ID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.FinishIngestionJob(ctx, sqlc.FinishIngestionJobParams{
+		ID:        jobID,
+		Status:    job.Status,
+		Documents: int32(job.Documents),
+		Chunks:    int32(job.Chunks),
+		Error:     job.Error,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish ingestion job: %w", err)
+	}
+	return nil
+}
+
+// CreateReembedJob records a pending re-embedding job. A collection has at
+// most one unfinished job.
+func (s *QueriesStore) CreateReembedJob(ctx context.Context, collectionID, provider, model, createdBy string) (*ReembedJob, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := optionalID(createdBy)
+	if err != nil {
+		creator = pgtype.UUID{}
+	}
+	row, err := s.queries.CreateReembeddingJob(ctx, sqlc.CreateReembeddingJobParams{
+		CollectionID: cid,
+		Provider:     provider,
+		Model:        model,
+		CreatedBy:    creator,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrReembedding
+		}
+		return nil, fmt.Errorf("failed to create re-embedding job: %w", err)
+	}
+	return toReembedJob(row), nil
+}
+
+// ReembedJob returns a re-embedding job by id
+func (s *QueriesStore) ReembedJob(ctx context.Context, id string) (*ReembedJob, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrReembedJobNotFound
+	}
+	row, err := s.queries.GetReembeddingJob(ctx, pgtype.UUID{Bytes: jobID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReembedJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get re-embedding job: %w", err)
+	}
+	return toReembedJob(row), nil
+}
+
+// ReembedJobs returns the latest re-embedding jobs of a collection
+func (s *QueriesStore) ReembedJobs(ctx context.Context, collectionID string, limit int) ([]*ReembedJob, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListReembeddingJobs(ctx, sqlc.ListReembeddingJobsParams{
+		CollectionID: cid,
+		Limit:        int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list re-embedding jobs: %w", err)
+	}
+	jobs := make([]*ReembedJob, 0, len(rows))
+	for _, row := range rows {
+		jobs = append(jobs, toReembedJob(row))
+	}
+	return jobs, nil
+}
+
+// UnfinishedReembedJobs returns the pending and running re-embedding jobs
+// of every collection
+func (s *QueriesStore) UnfinishedReembedJobs(ctx context.Context) ([]*ReembedJob, error) {
+	rows, err := s.queries.ListUnfinishedReembeddingJobs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unfinished re-embedding jobs: %w", err)
+	}
+	jobs := make([]*ReembedJob, 0, len(rows))
+	for _, row := range rows {
+		jobs = append(jobs, toReembedJob(row))
+	}
+	return jobs, nil
+}
+
+// StartReembedJob marks a job as running
+func (s *QueriesStore) StartReembedJob(ctx context.Context, id string) error {
+	jobID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.queries.StartReembeddingJob(ctx, jobID); err != nil {
+		return fmt.Errorf("failed to start re-embedding job: %w", err)
+	}
+	return nil
+}
+
+// UpdateReembedJob records the progress of a running job
+func (s *QueriesStore) UpdateReembedJob(ctx context.Context, job *ReembedJob) error {
+	jobID, err := parseID(job.ID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.UpdateReembeddingProgress(ctx, sqlc.UpdateReembeddingProgressParams{
+		ID:         jobID,
+		Total:      int32(job.Total),
+		Done:       int32(job.Done),
+		Dimensions: int32(job.Dimensions),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update re-embedding job: %w", err)
+	}
+	return nil
+}
+
+// FinishReembedJob records the status and error of a finished job
+func (s *QueriesStore) FinishReembedJob(ctx context.Context, job *ReembedJob) error {
+	jobID, err := parseID(job.ID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.FinishReembeddingJob(ctx, sqlc.FinishReembeddingJobParams{
+		ID:     jobID,
+		Status: job.Status,
+		Error:  job.Error,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish re-embedding job: %w", err)
+	}
+	return nil
+}
+
+// ReembedCandidates returns chunks of the active model still to be
+// embedded with model
+func (s *QueriesStore) ReembedCandidates(ctx context.Context, collectionID, model string, limit int) ([]Chunk, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListReembeddingCandidates(ctx, sqlc.ListReembeddingCandidatesParams{
+		CollectionID: cid,
+		Model:        model,
+		ResultLimit:  int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks to re-embed: %w", err)
+	}
+	chunks := make([]Chunk, 0, len(rows))
+	for _, row := range rows {
+		metadata := make(map[string]any)
+		if len(row.Metadata) > 0 {
+			if err := json.Unmarshal(row.Metadata, &metadata); err != nil {
+				return nil, fmt.Errorf("failed to decode chunk metadata: %w", err)
+			}
+		}
+		chunks = append(chunks, Chunk{
+			ID:       uuid.UUID(row.ContentID.Bytes),
+			Content:  row.ContentText,
+			Metadata: metadata,
+		})
+	}
+	return chunks, nil
+}
+
+// ReembedProgress counts the chunks of the active model and of model
+func (s *QueriesStore) ReembedProgress(ctx context.Context, collectionID, model string) (int, int, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return 0, 0, err
+	}
+	row, err := s.queries.CountReembeddingProgress(ctx, sqlc.CountReembeddingProgressParams{
+		Model:        model,
+		CollectionID: cid,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count re-embedded chunks: %w", err)
+	}
+	return int(row.Total), int(row.Done), nil
+}
+
+// SwitchModel makes model the active model of a collection
+func (s *QueriesStore) SwitchModel(ctx context.Context, collectionID, provider, model string, dimensions int) (bool, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return false, err
+	}
+	switched, err := s.queries.SwitchCollectionModel(ctx, sqlc.SwitchCollectionModelParams{
+		EmbeddingProvider: provider,
+		EmbeddingModel:    model,
+		ID:                cid,
+		Dimensions:        int32(dimensions),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to switch embedding model: %w", err)
+	}
+	return switched, nil
+}
+
+// AdoptModel makes model active in a collection without a model
+func (s *QueriesStore) AdoptModel(ctx context.Context, collectionID, model string) (bool, error) {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return false, err
+	}
+	adopted, err := s.queries.AdoptCollectionModel(ctx, sqlc.AdoptCollectionModelParams{
+		EmbeddingModel: model,
+		ID:             cid,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to adopt embedding model: %w", err)
+	}
+	return adopted, nil
+}
+
+// DeleteModelChunks deletes the vectors of an inactive model
+func (s *QueriesStore) DeleteModelChunks(ctx context.Context, collectionID, model string) error {
+	cid, err := parseID(collectionID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.DeleteInactiveModelEmbeddings(ctx, sqlc.DeleteInactiveModelEmbeddingsParams{
+		CollectionID: cid,
+		Model:        model,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete re-embedded chunks: %w", err)
+	}
+	return nil
+}
+
+// parseID parses a stored row id
+func parseID(id string) (pgtype.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("invalid id %q: %w", id, err)
+	}
+	return pgtype.UUID{Bytes: parsed, Valid: true}, nil
+}
+
+// optionalID parses an id that may be empty
+func optionalID(id string) (pgtype.UUID, error) {
+	if id == "" {
+		return pgtype.UUID{}, nil
+	}
+	return parseID(id)
+}
+
+func idString(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return uuid.UUID(id.Bytes).String()
+}
+
+func toPgIDs(ids []uuid.UUID) []pgtype.UUID {
+	pgIDs := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		pgIDs = append(pgIDs, pgtype.UUID{Bytes: id, Valid: true})
+	}
+	return pgIDs
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func toCollection(row *sqlc.Collection, grants []Grant) *Collection {
+	if grants == nil {
+		grants = []Grant{}
+	}
+	return &Collection{
+		ID:                idString(row.ID),
+		Name:              row.Name,
+		Description:       row.Description,
+		OwnerID:           idString(row.OwnerID),
+		EmbeddingProvider: row.EmbeddingProvider,
+		EmbeddingModel:    row.EmbeddingModel,
+		ChunkSize:         int(row.ChunkSize),
+		ChunkOverlap:      int(row.ChunkOverlap),
+		Grants:            grants,
+		CreatedAt:         row.CreatedAt,
+		UpdatedAt:         row.UpdatedAt,
+	}
+}
+
+func toDocument(row *sqlc.CollectionDocument) *Document {
+	ids := make([]uuid.UUID, 0, len(row.ChunkIds))
+	for _, id := range row.ChunkIds {
+		ids = append(ids, uuid.UUID(id.Bytes))
+	}
+	return &Document{
+		ID:           idString(row.ID),
+		CollectionID: idString(row.CollectionID),
+		Source:       row.Source,
+		ContentHash:  row.ContentHash,
+		Chunks:       len(ids),
+		ChunkIDs:     ids,
+		CreatedAt:    row.CreatedAt,
+		UpdatedAt:    row.UpdatedAt,
+	}
+}
+
+func toJob(row *sqlc.IngestionJob) *Job {
+	return &Job{
+		ID:           idString(row.ID),
+		CollectionID: idString(row.CollectionID),
+		Source:       row.Source,
+		Status:       row.Status,
+		Documents:    int(row.Documents),
+		Chunks:       int(row.Chunks),
+		Error:        row.Error,
+		CreatedBy:    idString(row.CreatedBy),
+		CreatedAt:    row.CreatedAt,
+		StartedAt:    timePtr(row.StartedAt),
+		FinishedAt:   timePtr(row.FinishedAt),
+	}
+}
+
+func toReembedJob(row *sqlc.ReembeddingJob) *ReembedJob {
+	return &ReembedJob{
+		ID:           idString(row.ID),
+		CollectionID: idString(row.CollectionID),
+		Provider:     row.Provider,
+		Model:        row.Model,
+		Status:       row.Status,
+		Total:        int(row.Total),
+		Done:         int(row.Done),
+		Dimensions:   int(row.Dimensions),
+		Error:        row.Error,
+		CreatedBy:    idString(row.CreatedBy),
+		CreatedAt:    row.CreatedAt,
+		StartedAt:    timePtr(row.StartedAt),
+		UpdatedAt:    row.UpdatedAt,
+		FinishedAt:   timePtr(row.FinishedAt),
+	}
+}
@@ -0,0 +1,437 @@
+package documentloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tmc/langchaingo/documentloaders"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/textsplitter"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// boilerplateElements hold navigation, scripts and other page chrome
+var boilerplateElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Form: true, atom.Button: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Head: true, atom.Select: true, atom.Dialog: true,
+}
+
+// boilerplateClasses are class names and ids of page chrome
+var boilerplateClasses = map[string]bool{
+	"nav": true, "navbar": true, "navigation": true, "menu": true, "sidebar": true,
+	"breadcrumb": true, "breadcrumbs": true, "cookie": true, "cookies": true,
+	"advert": true, "advertisement": true, "ads": true, "share": true, "social": true,
+	"footer": true, "skip-link": true,
+}
+
+// boilerplateRoles are ARIA roles of page chrome
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "search": true,
+}
+
+// HTML loads an HTML page as markdown, with a document per heading section.
+// Headings, lists, tables, code blocks and links are kept; navigation,
+// scripts and other boilerplate are removed.
+type HTML struct {
+	r io.Reader
+}
+
+var _ documentloaders.Loader = HTML{}
+
+// NewHTML creates an HTML loader reading from r
+func NewHTML(r io.Reader) HTML {
+	return HTML{r: r}
+}
+
+// Load converts the page and splits it at its headings. Each document has
+// the page title and its heading path as metadata.
+func (h HTML) Load(ctx context.Context) ([]schema.Document, error) {
+	doc, err := html.Parse(h.r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	metadata := map[string]any{}
+	if title := findElement(doc, atom.Title); title != nil {
+		if text := collapseSpace(textContent(title)); text != "" {
+			metadata["title"] = text
+		}
+	}
+	return sectionDocuments(HTMLToMarkdown(doc), metadata), nil
+}
+
+// LoadAndSplit loads the page and splits its sections with splitter
+func (h HTML) LoadAndSplit(ctx context.Context, splitter textsplitter.TextSplitter) ([]schema.Document, error) {
+	docs, err := h.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return textsplitter.SplitDocuments(splitter, docs)
+}
+
+// HTMLToMarkdown converts the main content of a page to markdown: the
+// first main or article element when there is one, otherwise the body
+func HTMLToMarkdown(doc *html.Node) string {
+	root := findElement(doc, atom.Main)
+	if root == nil {
+		root = findElement(doc, atom.Article)
+	}
+	if root == nil {
+		root = findElement(doc, atom.Body)
+	}
+	if root == nil {
+		root = doc
+	}
+	return strings.Join(markdownBlocks(root), "\n\n")
+}
+
+// markdownBlocks converts the children of n to markdown blocks. Runs of
+// inline content between block elements become paragraphs.
+func markdownBlocks(n *html.Node) []string {
+	var blocks []string
+	var inline strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(collapseInline(inline.String())); text != "" {
+			blocks = append(blocks, text)
+		}
+		inline.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || !isBlock(c) {
+			inline.WriteString(markdownInline(c))
+			continue
+		}
+		if isBoilerplate(c) {
+			continue
+		}
+		flush()
+		blocks = append(blocks, markdownBlock(c)...)
+	}
+	flush()
+	return blocks
+}
+
+// markdownBlock converts a block element
+func markdownBlock(n *html.Node) []string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.TrimSpace(collapseInline(inlineChildren(n)))
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if code == "" {
+			return nil
+		}
+		return []string{"```" + codeLanguage(n) + "\n" + code + "\n```"}
+	case atom.Ul, atom.Ol:
+		if list := markdownList(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case atom.Blockquote:
+		inner := strings.Join(markdownBlocks(n), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		lines := strings.Split(inner, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return []string{strings.Join(lines, "\n")}
+	case atom.Table:
+		if table := markdownTable(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case atom.Hr:
+		return []string{"---"}
+	}
+	return markdownBlocks(n)
+}
+
+// markdownList converts a list; nested lists are indented under their item
+func markdownList(n *html.Node) string {
+	var items []string
+	number := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		blocks := markdownBlocks(li)
+		if len(blocks) == 0 {
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(strings.Join(blocks, "\n"), "\n")
+		for i := range lines {
+			if i == 0 {
+				lines[i] = marker + lines[i]
+			} else if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// markdownTable converts a table, taking its first row as the header
+func markdownTable(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						text := strings.TrimSpace(collapseInline(inlineChildren(cell)))
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case atom.Table:
+				// Nested tables are flattened into their cell
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return markdownRows(rows)
+}
+
+// markdownRows renders rows as a markdown table with the first row as
+// its header
+func markdownRows(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		b.WriteString("|")
+		for j := range width {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// markdownInline converts inline content: text, links, emphasis, code and
+// images. Whitespace is collapsed later.
+func markdownInline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		// Newlines in source text are whitespace; line breaks come from <br>
+		return strings.NewReplacer("\n", " ", "\r", " ").Replace(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if isBoilerplate(n) {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.A:
+		text := strings.TrimSpace(collapseInline(inlineChildren(n)))
+		href := attr(n, "href")
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+	case atom.Strong, atom.B:
+		return wrapInline(inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(inlineChildren(n), "*")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return wrapInline(textContent(n), "`")
+	case atom.Img:
+		if alt := attr(n, "alt"); alt != "" {
+			return "![" + alt + "](" + attr(n, "src") + ")"
+		}
+		return ""
+	}
+	return inlineChildren(n)
+}
+
+// inlineChildren converts the children of n as inline content; block
+// children contribute their text
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && isBlock(c) {
+			if !isBoilerplate(c) {
+				b.WriteString(" " + strings.Join(markdownBlocks(c), " ") + " ")
+			}
+			continue
+		}
+		b.WriteString(markdownInline(c))
+	}
+	return b.String()
+}
+
+// wrapInline surrounds collapsed text with a marker, keeping the spaces
+// around it outside the marker
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(collapseInline(text))
+	if trimmed == "" {
+		return text
+	}
+	prefix, suffix := "", ""
+	if strings.TrimLeft(text, " \t\n") != text {
+		prefix = " "
+	}
+	if strings.TrimRight(text, " \t\n") != text {
+		suffix = " "
+	}
+	return prefix + marker + trimmed + marker + suffix
+}
+
+// isBlock reports whether an element starts a new markdown block
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Body,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Li, atom.Pre, atom.Blockquote, atom.Table, atom.Hr,
+		atom.Dl, atom.Dt, atom.Dd, atom.Figure, atom.Figcaption, atom.Details, atom.Summary:
+		return true
+	}
+	return boilerplateElements[n.DataAtom]
+}
+
+// isBoilerplate reports whether an element is page chrome or hidden
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateElements[n.DataAtom] || boilerplateRoles[attr(n, "role")] {
+		return true
+	}
+	if attr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return true
+	}
+	if boilerplateClasses[strings.ToLower(attr(n, "id"))] {
+		return true
+	}
+	for _, class := range strings.Fields(strings.ToLower(attr(n, "class"))) {
+		if boilerplateClasses[class] {
+			return true
+		}
+	}
+	return false
+}
+
+// codeLanguage returns the language of a pre block from a "language-" or
+// "lang-" class on it or its code element
+func codeLanguage(pre *html.Node) string {
+	nodes := []*html.Node{pre}
+	if code := findElement(pre, atom.Code); code != nil {
+		nodes = append(nodes, code)
+	}
+	for _, n := range nodes {
+		for _, class := range strings.Fields(attr(n, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if language, ok := strings.CutPrefix(class, prefix); ok {
+					return language
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// findElement returns the first element of the given type in document order
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent returns the raw text under n
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// attr returns the value of an attribute, or "" when it is not set
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether an attribute is set
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseInline collapses runs of whitespace within the lines created by
+// line breaks and drops empty lines
+func collapseInline(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = collapseSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collapseSpace replaces runs of whitespace with single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/tmc/langchaingo/documentloaders"
@@ -45,61 +46,60 @@ func (dp *DocumentProcessor) LoadFile(ctx context.Context, filePath string) ([]s
 		slog.String("file_path", filePath))
 
 	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
 		return nil, fmt.Errorf("file does not exist: %s", filePath)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
 
 	// Determine file type and create appropriate loader
 	ext := strings.ToLower(filepath.Ext(filePath))
 
 	var loader documentloaders.Loader
-	var err error
-
 	switch ext {
 	case ".txt", ".md", ".go", ".py", ".js", ".ts", ".yaml", ".yml", ".json":
-		file, err := os.Open(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open file: %w", err)
-		}
-		defer file.Close()
 		loader = documentloaders.NewText(file)
 	case ".pdf":
-		// For PDF files, we'll need a PDF loader
-		// For now, treat as text (this would need proper PDF library integration)
-		dp.logger.Warn("PDF loading not fully implemented, treating as text")
-		file, err := os.Open(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open file: %w", err)
-		}
-		defer file.Close()
-		loader = documentloaders.NewText(file)
+		loader = documentloaders.NewPDF(file, info.Size())
 	case ".html", ".htm":
-		// For HTML files, we could use an HTML loader
-		dp.logger.Warn("HTML loading not fully implemented, treating as text")
-		file, err := os.Open(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open file: %w", err)
-		}
-		defer file.Close()
-		loader = documentloaders.NewText(file)
+		loader = NewHTML(file)
+	case ".docx":
+		loader = NewDOCX(file, info.Size())
+	case ".pptx":
+		loader = NewPPTX(file, info.Size())
+	case ".xlsx":
+		loader = NewXLSX(file, info.Size())
+	case ".ipynb":
+		loader = NewNotebook(file)
 	default:
 		// Try to load as text for unknown extensions
 		dp.logger.Warn("Unknown file extension, attempting to load as text",
 			slog.String("extension", ext))
-		file, err := os.Open(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open file: %w", err)
-		}
-		defer file.Close()
 		loader = documentloaders.NewText(file)
 	}
 
 	// Load the document
-	docs, err := loader.Load(ctx)
+	loaded, err := loader.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load document: %w", err)
 	}
 
+	// Drop empty documents, such as PDF pages without text
+	docs := make([]schema.Document, 0, len(loaded))
+	for _, doc := range loaded {
+		if strings.TrimSpace(doc.PageContent) != "" {
+			docs = append(docs, doc)
+		}
+	}
+
 	// Add file metadata
 	for i := range docs {
 		if docs[i].Metadata == nil {
@@ -128,7 +128,7 @@ func (dp *DocumentProcessor) LoadDirectory(ctx context.Context, dirPath string,
 
 	// Set default extensions if none provided
 	if len(extensions) == 0 {
-		extensions = []string{".txt", ".md", ".go", ".py", ".js", ".ts", ".yaml", ".yml", ".json"}
+		extensions = dp.GetSupportedExtensions()
 	}
 
 	// Create extension map for faster lookup
@@ -358,7 +358,7 @@ func (dp *DocumentProcessor) GetSupportedExtensions() []string {
 	return []string{
 		".txt", ".md", ".go", ".py", ".js", ".ts",
 		".yaml", ".yml", ".json", ".html", ".htm",
-		".pdf", // Note: PDF support requires additional implementation
+		".pdf", ".docx", ".pptx", ".xlsx", ".ipynb",
 	}
 }
 
@@ -389,12 +389,67 @@ func CreateMarkdownSplitter(chunkSize, chunkOverlap int) textsplitter.TextSplitt
 	)
 }
 
-// CreateCodeSplitter creates a splitter optimized for code documents
+// codeSeparators split source code at declaration boundaries first, then
+// at blank lines and lines. Separators are kept at the start of chunks.
+var codeSeparators = map[string][]string{
+	"go":         {"\nfunc ", "\ntype ", "\nvar ", "\nconst "},
+	"python":     {"\nclass ", "\ndef ", "\n\tdef ", "\n    def "},
+	"javascript": {"\nexport ", "\nclass ", "\nfunction ", "\nconst ", "\nlet "},
+	"typescript": {"\nexport ", "\nclass ", "\ninterface ", "\ntype ", "\nfunction ", "\nconst "},
+	"java":       {"\nclass ", "\ninterface ", "\nenum ", "\n    public ", "\n    protected ", "\n    private "},
+	"rust":       {"\nfn ", "\npub fn ", "\nstruct ", "\npub struct ", "\nenum ", "\nimpl ", "\ntrait ", "\nmod "},
+	"ruby":       {"\nclass ", "\nmodule ", "\ndef ", "\n  def "},
+	"c":          {"\nstruct ", "\nstatic ", "\nvoid ", "\nint "},
+	"cpp":        {"\nclass ", "\nnamespace ", "\nstruct ", "\ntemplate ", "\nvoid "},
+	"sql":        {"\n-- name:", "\nCREATE ", "\nALTER ", "\nINSERT ", "\nSELECT "},
+	"shell":      {"\nfunction ", "\n\n"},
+	"markdown":   {"\n## ", "\n### ", "\n#### "},
+	"proto":      {"\nmessage ", "\nservice ", "\nenum "},
+}
+
+// codeLanguages maps file extensions to the languages of codeSeparators
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".rs":    "rust",
+	".rb":    "ruby",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".sql":   "sql",
+	".sh":    "shell",
+	".bash":  "shell",
+	".md":    "markdown",
+	".proto": "proto",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".json":  "json",
+	".toml":  "toml",
+}
+
+// CodeLanguage returns the language of a source file by its extension, or
+// an empty string when the extension is unknown
+func CodeLanguage(filePath string) string {
+	return codeLanguages[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// CreateCodeSplitter creates a splitter optimized for code documents. Known
+// languages are split at declaration boundaries before blank lines and
+// lines; chunks are substrings of the source apart from surrounding space.
 func CreateCodeSplitter(language string, chunkSize, chunkOverlap int) textsplitter.TextSplitter {
-	// Use recursive character splitter for code
-	// In a more advanced implementation, you could use language-specific splitters
+	separators := append(slices.Clone(codeSeparators[language]), "\n\n", "\n", " ", "")
 	return textsplitter.NewRecursiveCharacter(
 		textsplitter.WithChunkSize(chunkSize),
 		textsplitter.WithChunkOverlap(chunkOverlap),
+		textsplitter.WithSeparators(separators),
+		textsplitter.WithKeepSeparator(true),
 	)
 }
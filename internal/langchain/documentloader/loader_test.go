@@ -0,0 +1,131 @@
+package documentloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocumentProcessor_LoadFile(t *testing.T) {
+	tests := []struct {
+		file      string
+		locations []string
+		contains  []string // in the document at the same index
+		excludes  []string // in no document
+	}{
+		{
+			file:      "sample.pdf",
+			locations: []string{"page 1", "page 3"}, // page 2 has no text
+			contains:  []string{"Run the installer.", "ErrNotFound means the file is missing."},
+		},
+		{
+			file:      "sample.html",
+			locations: []string{"Retry Guide", "Retry Guide > Backoff", "Retry Guide > Errors"},
+			contains: []string{
+				"Clients **should** retry failed requests. See the [backoff docs](https://example.com/backoff).",
+				"1. Wait `100ms`\n2. Double the wait\n   - Cap it at 10s\n\n```go\nfor attempt := range 5 {",
+				"| Code | Retry |\n| --- | --- |\n| 503 | yes |\n\n> Never retry 400.",
+			},
+			excludes: []string{"Home", "Section A", "cookies", "trackPageView", "font-family", "Copyright"},
+		},
+		{
+			file:      "sample.docx",
+			locations: []string{"Deployment Guide", "Install", "Install > Configuration"},
+			contains: []string{
+				"# Deployment Guide\n\nThis guide covers deployments.",
+				"- Download the binary\n- Run setup",
+				"Read the [configuration docs](https://example.com/config).\n\n| Key | Default |\n| --- | --- |\n| port | 8080 |",
+			},
+		},
+		{
+			// The presentation lists slide2.xml first
+			file:      "sample.pptx",
+			locations: []string{"slide 1, Quarterly Review", "slide 2, Next Steps"},
+			contains:  []string{"# Quarterly Review\nRevenue grew 12%\nChurn fell", "Hire two engineers"},
+		},
+		{
+			// The empty sheet is skipped
+			file:      "sample.xlsx",
+			locations: []string{"sheet Budget", "sheet Team"},
+			contains: []string{
+				"| Item | Cost |  | Approved |\n| --- | --- | --- | --- |\n| Cloud hosting | 1200.5 |  | TRUE |",
+				"| Name |\n| --- |\n| Ada |",
+			},
+		},
+		{
+			file:      "sample.ipynb",
+			locations: []string{"cells 1-3, Analysis > Load data", "cells 4-5, Analysis > Plot"},
+			contains: []string{
+				"```python\nimport pandas as pd\n",
+				"```python\ndf.plot()\n```\n\nOutput:\n```\n<Axes>\n```",
+			},
+			excludes: []string{"iVBORw0KGgo="},
+		},
+	}
+
+	dp := NewDocumentProcessor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			docs, err := dp.LoadFile(context.Background(), "testdata/"+tt.file)
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+
+			var locations []string
+			for _, doc := range docs {
+				locations = append(locations, Location(doc.Metadata))
+				if doc.Metadata["source"] != "testdata/"+tt.file {
+					t.Errorf("source = %v, want the file path", doc.Metadata["source"])
+				}
+				for _, excluded := range tt.excludes {
+					if strings.Contains(doc.PageContent, excluded) {
+						t.Errorf("document contains boilerplate %q:\n%s", excluded, doc.PageContent)
+					}
+				}
+			}
+			if !reflect.DeepEqual(locations, tt.locations) {
+				t.Fatalf("locations = %q, want %q", locations, tt.locations)
+			}
+			for i, want := range tt.contains {
+				if !strings.Contains(docs[i].PageContent, want) {
+					t.Errorf("document %d = %q, want it to contain %q", i, docs[i].PageContent, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSections(t *testing.T) {
+	markdown := "Intro text.\n\n# Guide\n## Setup\n\nInstall it.\n\n```sh\n# not a heading\n```\n\n### Linux\n\nUse apt.\n\n## Usage\n\nRun it."
+	var got []string
+	for _, s := range splitSections(markdown) {
+		got = append(got, s.headingPath)
+	}
+	want := []string{"", "Guide > Setup", "Guide > Setup > Linux", "Guide > Usage"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSections() heading paths = %q, want %q", got, want)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		metadata map[string]any
+		want     string
+	}{
+		{map[string]any{"page": 3, "total_pages": 9}, "page 3"},
+		{map[string]any{"page": float64(3)}, "page 3"}, // after a JSON round trip
+		{map[string]any{"sheet": "Budget"}, "sheet Budget"},
+		{map[string]any{"cell_start": 2, "cell_end": 2}, "cell 2"},
+		{map[string]any{"start_line": 10, "end_line": 24, "path": "main.go"}, "lines 10-24"},
+		{map[string]any{"slide": 2, "heading_path": "Roadmap"}, "slide 2, Roadmap"},
+		{map[string]any{"source": "notes.txt"}, ""},
+	}
+	for _, tt := range tests {
+		if got := Location(tt.metadata); got != tt.want {
+			t.Errorf("Location(%v) = %q, want %q", tt.metadata, got, tt.want)
+		}
+	}
+}
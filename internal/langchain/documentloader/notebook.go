@@ -0,0 +1,198 @@
+package documentloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/documentloaders"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/textsplitter"
+)
+
+// maxCellOutput bounds the characters of a code cell's output kept
+const maxCellOutput = 2000
+
+// Notebook loads a Jupyter notebook as markdown, with a document per
+// heading section. Code cells are fenced in the notebook's language and
+// followed by their text output.
+type Notebook struct {
+	r io.Reader
+}
+
+var _ documentloaders.Loader = Notebook{}
+
+// NewNotebook creates a Jupyter notebook loader reading from r
+func NewNotebook(r io.Reader) Notebook {
+	return Notebook{r: r}
+}
+
+// notebookText is a multiline string, stored as a string or a list of lines
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = notebookText(s)
+	return nil
+}
+
+// notebookOutput is an output of a code cell. Data holds a representation
+// per MIME type.
+type notebookOutput struct {
+	OutputType string                     `json:"output_type"`
+	Text       notebookText               `json:"text"`
+	Data       map[string]json.RawMessage `json:"data"`
+	EName      string                     `json:"ename"`
+	EValue     string                     `json:"evalue"`
+}
+
+// notebook is the nbformat 4 document structure
+type notebook struct {
+	Cells []struct {
+		CellType string           `json:"cell_type"`
+		Source   notebookText     `json:"source"`
+		Outputs  []notebookOutput `json:"outputs"`
+	} `json:"cells"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// Load converts the cells and splits them at markdown headings. Documents
+// have the heading path and the range of cells they hold as metadata.
+func (n Notebook) Load(ctx context.Context) ([]schema.Document, error) {
+	var nb notebook
+	if err := json.NewDecoder(n.r).Decode(&nb); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+	language := nb.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = nb.Metadata.KernelSpec.Language
+	}
+
+	var (
+		docs    []schema.Document
+		path    headingPath
+		blocks  []string
+		first   int
+		hasBody bool
+	)
+	flush := func(last int) {
+		if content := strings.TrimSpace(strings.Join(blocks, "\n\n")); content != "" {
+			metadata := map[string]any{
+				"cell_start": first,
+				"cell_end":   last,
+				"language":   language,
+			}
+			if p := path.String(); p != "" {
+				metadata["heading_path"] = p
+			}
+			docs = append(docs, schema.Document{PageContent: content, Metadata: metadata})
+		}
+		blocks, hasBody = nil, false
+	}
+
+	for i, cell := range nb.Cells {
+		number := i + 1
+		source := strings.TrimSpace(string(cell.Source))
+		if source == "" {
+			continue
+		}
+
+		switch cell.CellType {
+		case "markdown":
+			// The cell's first heading starts a new section unless the
+			// section so far only holds headings
+			headed, body := false, false
+			for _, line := range strings.Split(source, "\n") {
+				if m := headingLine.FindStringSubmatch(line); m != nil {
+					if !headed && hasBody {
+						flush(number - 1)
+					}
+					headed = true
+					path.push(len(m[1]), m[2])
+				} else if strings.TrimSpace(line) != "" {
+					body = true
+				}
+			}
+			if len(blocks) == 0 {
+				first = number
+			}
+			blocks = append(blocks, source)
+			hasBody = hasBody || body
+		case "code":
+			if len(blocks) == 0 {
+				first = number
+			}
+			blocks = append(blocks, "```"+language+"\n"+source+"\n```")
+			if output := cellOutput(cell.Outputs); output != "" {
+				blocks = append(blocks, "Output:\n```\n"+output+"\n```")
+			}
+			hasBody = true
+		default:
+			if len(blocks) == 0 {
+				first = number
+			}
+			blocks = append(blocks, source)
+			hasBody = true
+		}
+	}
+	flush(len(nb.Cells))
+	return docs, nil
+}
+
+// LoadAndSplit loads the notebook and splits its sections with splitter
+func (n Notebook) LoadAndSplit(ctx context.Context, splitter textsplitter.TextSplitter) ([]schema.Document, error) {
+	docs, err := n.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return textsplitter.SplitDocuments(splitter, docs)
+}
+
+// cellOutput returns the text output of a code cell: streams, plain text
+// results and errors. Images and HTML are left out.
+func cellOutput(outputs []notebookOutput) string {
+	var parts []string
+	for _, output := range outputs {
+		switch output.OutputType {
+		case "stream":
+			parts = append(parts, string(output.Text))
+		case "execute_result", "display_data":
+			var text notebookText
+			if data, ok := output.Data["text/plain"]; ok && json.Unmarshal(data, &text) == nil {
+				parts = append(parts, string(text))
+			}
+		case "error":
+			parts = append(parts, output.EName+": "+output.EValue)
+		}
+	}
+	return truncate(strings.TrimSpace(strings.Join(parts, "\n")), maxCellOutput)
+}
+
+// truncate shortens s to at most n bytes on a rune boundary
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,644 @@
+package documentloader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tmc/langchaingo/documentloaders"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/textsplitter"
+)
+
+// maxZipEntry bounds the uncompressed size of a part read from an Office
+// file, guarding against zip bombs
+const maxZipEntry = 64 << 20
+
+// DOCX loads a Word document as markdown, with a document per heading
+// section. Heading styles, lists, tables and hyperlinks are kept.
+type DOCX struct {
+	r    io.ReaderAt
+	size int64
+}
+
+var _ documentloaders.Loader = DOCX{}
+
+// NewDOCX creates a Word document loader
+func NewDOCX(r io.ReaderAt, size int64) DOCX {
+	return DOCX{r: r, size: size}
+}
+
+// Load converts the document and splits it at its headings
+func (d DOCX) Load(ctx context.Context) ([]schema.Document, error) {
+	zr, err := zip.NewReader(d.r, d.size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+	data, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	links, err := relationships(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	markdown, err := docxMarkdown(data, links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DOCX: %w", err)
+	}
+	return sectionDocuments(markdown, map[string]any{}), nil
+}
+
+// LoadAndSplit loads the document and splits its sections with splitter
+func (d DOCX) LoadAndSplit(ctx context.Context, splitter textsplitter.TextSplitter) ([]schema.Document, error) {
+	docs, err := d.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return textsplitter.SplitDocuments(splitter, docs)
+}
+
+// docxMarkdown converts word/document.xml to markdown
+func docxMarkdown(data []byte, links map[string]string) (string, error) {
+	var (
+		blocks []string
+		para   strings.Builder // text of the current paragraph
+		style  string          // style of the current paragraph
+		listed bool            // the current paragraph is a list item
+		link   string          // target of the current hyperlink
+		linkAt int             // start of the hyperlink text in para
+		inText bool
+
+		table [][]string // rows of the current table
+		row   []string   // cells of the current row
+		cell  []string   // paragraphs of the current cell
+		depth int        // table nesting
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				style, listed = "", false
+			case "pStyle":
+				style = xmlAttr(t, "val")
+			case "numPr":
+				listed = true
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "hyperlink":
+				link, linkAt = links[xmlAttr(t, "id")], para.Len()
+			case "tbl":
+				depth++
+				if depth == 1 {
+					table = nil
+				}
+			case "tr":
+				if depth == 1 {
+					row = nil
+				}
+			case "tc":
+				if depth == 1 {
+					cell = nil
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "hyperlink":
+				if text := para.String()[linkAt:]; link != "" && strings.TrimSpace(text) != "" {
+					s := para.String()[:linkAt] + "[" + strings.TrimSpace(text) + "](" + link + ")"
+					para.Reset()
+					para.WriteString(s)
+				}
+				link = ""
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if depth > 0 {
+					cell = append(cell, collapseSpace(text))
+					continue
+				}
+				if level := headingLevel(style); level > 0 {
+					text = strings.Repeat("#", level) + " " + collapseSpace(text)
+				} else if listed {
+					text = "- " + text
+				}
+				blocks = append(blocks, text)
+			case "tc":
+				if depth == 1 {
+					row = append(row, strings.ReplaceAll(strings.Join(cell, " "), "|", `\|`))
+				}
+			case "tr":
+				if depth == 1 && len(row) > 0 {
+					table = append(table, row)
+				}
+			case "tbl":
+				depth--
+				if depth == 0 {
+					if rendered := markdownRows(table); rendered != "" {
+						blocks = append(blocks, rendered)
+					}
+				}
+			}
+		}
+	}
+
+	// Consecutive list items form one list
+	var merged []string
+	for _, block := range blocks {
+		if n := len(merged); n > 0 && strings.HasPrefix(block, "- ") && strings.HasPrefix(merged[n-1], "- ") {
+			merged[n-1] += "\n" + block
+			continue
+		}
+		merged = append(merged, block)
+	}
+	return strings.Join(merged, "\n\n"), nil
+}
+
+// headingLevel returns the heading level of a Word paragraph style, or 0
+// when it is not a heading
+func headingLevel(style string) int {
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if style == "title" {
+		return 1
+	}
+	if rest, ok := strings.CutPrefix(style, "heading"); ok {
+		if level, err := strconv.Atoi(rest); err == nil && level >= 1 {
+			return min(level, 6)
+		}
+	}
+	return 0
+}
+
+// PPTX loads a PowerPoint presentation with a document per slide
+type PPTX struct {
+	r    io.ReaderAt
+	size int64
+}
+
+var _ documentloaders.Loader = PPTX{}
+
+// NewPPTX creates a PowerPoint presentation loader
+func NewPPTX(r io.ReaderAt, size int64) PPTX {
+	return PPTX{r: r, size: size}
+}
+
+// slidePath matches slide parts and captures their number
+var slidePath = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// Load extracts the text of each slide in presentation order. Documents
+// have the slide number, the slide count and the slide title as metadata.
+func (p PPTX) Load(ctx context.Context) ([]schema.Document, error) {
+	zr, err := zip.NewReader(p.r, p.size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PPTX: %w", err)
+	}
+	slides, err := slideOrder(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]schema.Document, 0, len(slides))
+	for i, name := range slides {
+		data, err := readZipFile(zr, name)
+		if err != nil {
+			return nil, err
+		}
+		title, paragraphs, err := slideText(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		var content []string
+		if title != "" {
+			content = append(content, "# "+title)
+		}
+		content = append(content, paragraphs...)
+		if len(content) == 0 {
+			continue
+		}
+
+		metadata := map[string]any{
+			"slide":        i + 1,
+			"total_slides": len(slides),
+		}
+		if title != "" {
+			metadata["heading_path"] = title
+		}
+		docs = append(docs, schema.Document{
+			PageContent: strings.Join(content, "\n"),
+			Metadata:    metadata,
+		})
+	}
+	return docs, nil
+}
+
+// LoadAndSplit loads the slides and splits them with splitter
+func (p PPTX) LoadAndSplit(ctx context.Context, splitter textsplitter.TextSplitter) ([]schema.Document, error) {
+	docs, err := p.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return textsplitter.SplitDocuments(splitter, docs)
+}
+
+// slideOrder returns the slide parts in presentation order, falling back to
+// their numbers when the presentation does not list them
+func slideOrder(zr *zip.Reader) ([]string, error) {
+	rels, err := relationships(zr, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+	var slides []string
+	if data, err := readZipFile(zr, "ppt/presentation.xml"); err == nil {
+		var presentation struct {
+			Slides []struct {
+				ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+			} `xml:"sldIdLst>sldId"`
+		}
+		if err := xml.Unmarshal(data, &presentation); err == nil {
+			for _, slide := range presentation.Slides {
+				if target := rels[slide.ID]; target != "" {
+					slides = append(slides, target)
+				}
+			}
+		}
+	}
+	if len(slides) > 0 {
+		return slides, nil
+	}
+
+	for _, f := range zr.File {
+		if slidePath.MatchString(f.Name) {
+			slides = append(slides, f.Name)
+		}
+	}
+	slices.SortFunc(slides, func(a, b string) int {
+		na, _ := strconv.Atoi(slidePath.FindStringSubmatch(a)[1])
+		nb, _ := strconv.Atoi(slidePath.FindStringSubmatch(b)[1])
+		return na - nb
+	})
+	return slides, nil
+}
+
+// slideText returns the title and the other paragraphs of a slide
+func slideText(data []byte) (string, []string, error) {
+	var (
+		title      []string
+		paragraphs []string
+		para       strings.Builder
+		isTitle    bool
+		inText     bool
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				isTitle = false
+			case "ph":
+				kind := xmlAttr(t, "type")
+				isTitle = kind == "title" || kind == "ctrTitle"
+			case "p":
+				para.Reset()
+			case "t":
+				inText = true
+			case "br":
+				para.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if isTitle {
+					title = append(title, collapseSpace(text))
+				} else {
+					paragraphs = append(paragraphs, text)
+				}
+			}
+		}
+	}
+	return strings.Join(title, " "), paragraphs, nil
+}
+
+// XLSX loads an Excel workbook with a document per sheet, rendered as a
+// markdown table
+type XLSX struct {
+	r    io.ReaderAt
+	size int64
+}
+
+var _ documentloaders.Loader = XLSX{}
+
+// NewXLSX creates an Excel workbook loader
+func NewXLSX(r io.ReaderAt, size int64) XLSX {
+	return XLSX{r: r, size: size}
+}
+
+// Load extracts the cell values of each sheet. Documents have the sheet
+// name, its position and its row count as metadata.
+func (x XLSX) Load(ctx context.Context) ([]schema.Document, error) {
+	zr, err := zip.NewReader(x.r, x.size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX: %w", err)
+	}
+
+	data, err := readZipFile(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(data, &workbook); err != nil {
+		return nil, fmt.Errorf("failed to parse workbook: %w", err)
+	}
+	rels, err := relationships(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	shared, err := sharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]schema.Document, 0, len(workbook.Sheets))
+	for i, sheet := range workbook.Sheets {
+		target := rels[sheet.ID]
+		if target == "" {
+			continue
+		}
+		data, err := readZipFile(zr, target)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := sheetRows(data, shared)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sheet %s: %w", sheet.Name, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		docs = append(docs, schema.Document{
+			PageContent: "# " + sheet.Name + "\n\n" + markdownRows(rows),
+			Metadata: map[string]any{
+				"sheet":       sheet.Name,
+				"sheet_index": i + 1,
+				"rows":        len(rows),
+			},
+		})
+	}
+	return docs, nil
+}
+
+// LoadAndSplit loads the sheets and splits them with splitter
+func (x XLSX) LoadAndSplit(ctx context.Context, splitter textsplitter.TextSplitter) ([]schema.Document, error) {
+	docs, err := x.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return textsplitter.SplitDocuments(splitter, docs)
+}
+
+// xlsxText is text in a shared string or inline string cell, either plain
+// or as rich text runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// sharedStrings reads the workbook's shared string table
+func sharedStrings(zr *zip.Reader) ([]string, error) {
+	data, err := readZipFile(zr, "xl/sharedStrings.xml")
+	if errors.Is(err, errZipEntryNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var table struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := xml.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+	}
+	strs := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		strs[i] = item.String()
+	}
+	return strs, nil
+}
+
+// sheetRows returns the cell values of a sheet by row and column. Empty
+// rows are dropped and gaps between cells are kept.
+func sheetRows(data []byte, shared []string) ([][]string, error) {
+	var worksheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &worksheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, r := range worksheet.Rows {
+		var row []string
+		for i, c := range r.Cells {
+			column := columnIndex(c.Ref)
+			if column < 0 {
+				column = i
+			}
+			for len(row) < column {
+				row = append(row, "")
+			}
+
+			value := c.Value
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared) {
+					value = shared[n]
+				}
+			case "inlineStr":
+				value = c.Inline.String()
+			case "b":
+				value = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			}
+			value = strings.ReplaceAll(collapseSpace(value), "|", `\|`)
+			if column < len(row) {
+				row[column] = value
+			} else {
+				row = append(row, value)
+			}
+		}
+		for len(row) > 0 && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// columnIndex returns the zero-based column of a cell reference such as
+// "C12", or -1 when it has none
+func columnIndex(ref string) int {
+	column := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		column = column*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 {
+		return -1
+	}
+	return column - 1
+}
+
+// errZipEntryNotFound is returned for a part missing from an Office file
+var errZipEntryNotFound = errors.New("entry not found")
+
+// readZipFile reads a part of an Office file
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		if f.UncompressedSize64 > maxZipEntry {
+			return nil, fmt.Errorf("%s is too large", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxZipEntry))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s: %w", name, errZipEntryNotFound)
+}
+
+// relationships returns the targets of a part's relationships by id.
+// Internal targets are resolved to part names; external ones, such as
+// hyperlinks, are returned as is.
+func relationships(zr *zip.Reader, part string) (map[string]string, error) {
+	dir, file := path.Split(part)
+	data, err := readZipFile(zr, dir+"_rels/"+file+".rels")
+	if errors.Is(err, errZipEntryNotFound) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID         string `xml:"Id,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil, fmt.Errorf("failed to parse relationships of %s: %w", part, err)
+	}
+
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		switch {
+		case rel.TargetMode == "External":
+			targets[rel.ID] = rel.Target
+		case strings.HasPrefix(rel.Target, "/"):
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		default:
+			targets[rel.ID] = path.Join(dir, rel.Target)
+		}
+	}
+	return targets, nil
+}
+
+// xmlAttr returns the value of an attribute by local name
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package documentloader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// headingLine matches a markdown ATX heading
+var headingLine = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// headingPath tracks the headings enclosing a position in a document
+type headingPath struct {
+	levels []int
+	titles []string
+}
+
+// push enters a heading, leaving headings of the same or a deeper level
+func (p *headingPath) push(level int, title string) {
+	for len(p.levels) > 0 && p.levels[len(p.levels)-1] >= level {
+		p.levels = p.levels[:len(p.levels)-1]
+		p.titles = p.titles[:len(p.titles)-1]
+	}
+	p.levels = append(p.levels, level)
+	p.titles = append(p.titles, title)
+}
+
+// String joins the headings, outermost first
+func (p *headingPath) String() string {
+	return strings.Join(p.titles, " > ")
+}
+
+// section is the text under a heading
+type section struct {
+	headingPath string
+	content     string
+}
+
+// splitSections splits markdown at its headings. Headings directly
+// followed by another heading stay with the section below them, so every
+// section has body text.
+func splitSections(markdown string) []section {
+	var (
+		sections []section
+		path     headingPath
+		current  []string
+		hasBody  bool
+		fence    string
+	)
+	flush := func() {
+		if content := strings.TrimSpace(strings.Join(current, "\n")); content != "" {
+			sections = append(sections, section{headingPath: path.String(), content: content})
+		}
+		current, hasBody = nil, false
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		default:
+			if m := headingLine.FindStringSubmatch(line); m != nil {
+				if hasBody {
+					flush()
+				}
+				path.push(len(m[1]), m[2])
+				current = append(current, line)
+				continue
+			}
+		}
+		current = append(current, line)
+		if trimmed != "" {
+			hasBody = true
+		}
+	}
+	flush()
+	return sections
+}
+
+// sectionDocuments turns markdown into a document per section, with the
+// section's heading path and the given metadata
+func sectionDocuments(markdown string, metadata map[string]any) []schema.Document {
+	sections := splitSections(markdown)
+	docs := make([]schema.Document, 0, len(sections))
+	for _, s := range sections {
+		doc := schema.Document{
+			PageContent: s.content,
+			Metadata:    make(map[string]any, len(metadata)+1),
+		}
+		for key, value := range metadata {
+			doc.Metadata[key] = value
+		}
+		if s.headingPath != "" {
+			doc.Metadata["heading_path"] = s.headingPath
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// Location describes where in its source a document was found, from the
+// metadata the loaders attach: "page 3", "slide 2", "sheet Budget",
+// "cells 4-6" or "lines 10-24", followed by the heading path.
+func Location(metadata map[string]any) string {
+	var parts []string
+	if page, ok := intValue(metadata["page"]); ok {
+		parts = append(parts, fmt.Sprintf("page %d", page))
+	}
+	if slide, ok := intValue(metadata["slide"]); ok {
+		parts = append(parts, fmt.Sprintf("slide %d", slide))
+	}
+	if sheet, ok := metadata["sheet"].(string); ok && sheet != "" {
+		parts = append(parts, "sheet "+sheet)
+	}
+	if span := spanOf(metadata, "cell_start", "cell_end", "cell", "cells"); span != "" {
+		parts = append(parts, span)
+	}
+	if span := spanOf(metadata, "start_line", "end_line", "line", "lines"); span != "" {
+		parts = append(parts, span)
+	}
+	if headings, ok := metadata["heading_path"].(string); ok && headings != "" {
+		parts = append(parts, headings)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// spanOf formats a range of numbered items, such as "lines 10-24"
+func spanOf(metadata map[string]any, startKey, endKey, singular, plural string) string {
+	start, ok := intValue(metadata[startKey])
+	if !ok {
+		return ""
+	}
+	end, ok := intValue(metadata[endKey])
+	if !ok || end == start {
+		return fmt.Sprintf("%s %d", singular, start)
+	}
+	return fmt.Sprintf("%s %d-%d", plural, start, end)
+}
+
+// intValue reads a number from metadata, which holds float64 after a JSON
+// round trip through the database
+func intValue(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
@@ -2,12 +2,16 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"log/slog"
 
 	"github.com/koopa0/assistant-go/internal/langchain"
 	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/langchain/chain"
+	"github.com/koopa0/assistant-go/internal/langchain/collection"
+	"github.com/koopa0/assistant-go/internal/langchain/router"
 	"github.com/koopa0/assistant-go/internal/platform/observability"
 	"github.com/koopa0/assistant-go/internal/platform/server/handlers"
 )
@@ -52,15 +56,17 @@ func (h *Handler) getAvailableAgents() []string {
 
 // ExecuteAgentRequest represents a request to execute an agent
 type ExecuteAgentRequest struct {
-	UserID      string                 `json:"user_id"`
-	Query       string                 `json:"query"`
-	Context     map[string]interface{} `json:"context,omitempty"`
-	Tools       []string               `json:"tools,omitempty"`
-	MaxSteps    int                    `json:"max_steps,omitempty"`
-	Temperature float64                `json:"temperature,omitempty"`
+	UserID         string                 `json:"user_id"`
+	ConversationID string                 `json:"conversation_id,omitempty"`
+	Query          string                 `json:"query"`
+	Context        map[string]interface{} `json:"context,omitempty"`
+	Tools          []string               `json:"tools,omitempty"`
+	MaxSteps       int                    `json:"max_steps,omitempty"`
+	Temperature    float64                `json:"temperature,omitempty"`
 }
 
-// ExecuteAgent executes a specific agent
+// ExecuteAgent executes a specific agent, or the agent the router
+// chooses for the "auto" type
 func (h *Handler) ExecuteAgent(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -91,10 +97,7 @@ func (h *Handler) ExecuteAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set defaults
-	if req.MaxSteps <= 0 {
-		req.MaxSteps = 5
-	}
+	// Set defaults; agents default max_steps to the configured budget
 	if req.Context == nil {
 		req.Context = make(map[string]interface{})
 	}
@@ -104,31 +107,46 @@ func (h *Handler) ExecuteAgent(w http.ResponseWriter, r *http.Request) {
 
 	// Create agent execution request
 	agentRequest := &agent.Request{
-		Query:       req.Query,
-		MaxSteps:    req.MaxSteps,
-		Context:     req.Context,
-		Tools:       req.Tools,
-		Temperature: req.Temperature,
+		Query:          req.Query,
+		MaxSteps:       req.MaxSteps,
+		Context:        req.Context,
+		Tools:          req.Tools,
+		Temperature:    req.Temperature,
+		UserID:         req.UserID,
+		ConversationID: req.ConversationID,
 	}
 
-	// Execute agent
-	response, err := h.service.ExecuteAgent(ctx, agentType, agentRequest)
+	// Execute agent; the auto agent type lets the router choose
+	var response *agent.Response
+	var decision *router.Decision
+	var err error
+	if agentType == langchain.AutoAgent {
+		response, decision, err = h.service.ExecuteRouted(ctx, agentRequest)
+	} else {
+		response, err = h.service.ExecuteAgent(ctx, agentType, agentRequest)
+	}
 	if err != nil {
 		h.LogError(r, "langchain.execute_agent", err)
 		h.WriteInternalError(w, err)
 		return
 	}
 
-	// Write response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	result := map[string]interface{}{
 		"success":        response.Success,
 		"result":         response.Result,
 		"confidence":     response.Confidence,
 		"execution_time": response.ExecutionTime.Milliseconds(),
 		"steps":          response.Steps,
-	})
+		"tokens_used":    response.TokensUsed,
+	}
+	if decision != nil {
+		result["routing"] = decision
+	}
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
 }
 
 // ExecutePromptRequest represents a request to execute a prompt
@@ -169,3 +187,65 @@ func (h *Handler) ExecutePrompt(w http.ResponseWriter, r *http.Request) {
 		"result":  response,
 	})
 }
+
+// QueryRAGRequest represents a question for the knowledge base
+type QueryRAGRequest struct {
+	Query          string       `json:"query"`
+	Collections    []string     `json:"collections,omitempty"`     // names; empty searches every readable collection
+	ConversationID string       `json:"conversation_id,omitempty"` // the caller's conversation the query follows
+	History        []chain.Turn `json:"history,omitempty"`         // messages the query follows, without a conversation id
+}
+
+// QueryRAG answers a question from the knowledge base with verified
+// citations of the chunks the answer relies on. A follow-up question is
+// rewritten with the conversation it follows before retrieval.
+func (h *Handler) QueryRAG(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse request body
+	var req QueryRAGRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.WriteBadRequest(w, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if req.Query == "" {
+		h.WriteBadRequest(w, "query is required")
+		return
+	}
+
+	history := req.History
+	if req.ConversationID != "" {
+		var err error
+		history, err = h.service.ConversationHistory(ctx, req.ConversationID)
+		if errors.Is(err, langchain.ErrConversationNotFound) {
+			h.WriteNotFound(w, "Conversation")
+			return
+		}
+		if err != nil {
+			h.LogError(r, "langchain.query_rag", err)
+			h.WriteInternalError(w, err)
+			return
+		}
+	}
+
+	result, err := h.service.QueryRAG(ctx, req.Query, req.Collections, history)
+	if errors.Is(err, collection.ErrNotFound) {
+		h.WriteNotFound(w, "Collection")
+		return
+	}
+	if err != nil {
+		h.LogError(r, "langchain.query_rag", err)
+		h.WriteInternalError(w, err)
+		return
+	}
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"result":  result,
+	})
+}
@@ -0,0 +1,213 @@
+package indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/langchain/documentloader"
+)
+
+// Chunk kinds
+const (
+	KindHeader      = "header"      // package clause, doc and imports
+	KindFunction    = "function"    // function with its doc comment
+	KindMethod      = "method"      // method with its doc comment
+	KindType        = "type"        // type declaration with its doc comment
+	KindDeclaration = "declaration" // const or var declaration
+	KindText        = "text"        // splitter chunk of a non-Go file
+)
+
+// Chunk is a piece of a source file that is embedded on its own
+type Chunk struct {
+	Path      string `json:"path"` // slash-separated, relative to the indexed root
+	Language  string `json:"language"`
+	Symbol    string `json:"symbol,omitempty"` // e.g. "Indexer.Index"; empty for text chunks
+	Kind      string `json:"kind"`
+	StartLine int    `json:"start_line"` // 1-based, inclusive
+	EndLine   int    `json:"end_line"`
+	Content   string `json:"content"`
+}
+
+// Hash returns the SHA-256 of the chunk's content
+func (c Chunk) Hash() string {
+	sum := sha256.Sum256([]byte(c.Content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Chunker splits source files into chunks. Go files are split on AST
+// boundaries; other files with the code splitter of their language.
+// Declarations larger than the chunk size are split further and keep
+// their symbol.
+type Chunker struct {
+	chunkSize    int
+	chunkOverlap int
+}
+
+// NewChunker creates a chunker for chunks of up to chunkSize characters
+func NewChunker(chunkSize, chunkOverlap int) *Chunker {
+	return &Chunker{
+		chunkSize:    chunkSize,
+		chunkOverlap: chunkOverlap,
+	}
+}
+
+// Chunk splits a file's content. Go files that do not parse are split as
+// text.
+func (c *Chunker) Chunk(path string, content []byte) ([]Chunk, error) {
+	language := documentloader.CodeLanguage(path)
+	if language == "go" {
+		if chunks, err := c.goChunks(path, content); err == nil {
+			return chunks, nil
+		}
+	}
+	return c.textChunks(Chunk{Path: path, Language: language, Kind: KindText, StartLine: 1}, string(content))
+}
+
+// goChunks splits a Go file into its header and top-level declarations
+func (c *Chunker) goChunks(path string, content []byte) ([]Chunk, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []Chunk
+	add := func(symbol, kind string, start, end token.Pos) error {
+		from, to := fset.Position(start), fset.Position(end)
+		split, err := c.textChunks(Chunk{
+			Path:      path,
+			Language:  "go",
+			Symbol:    symbol,
+			Kind:      kind,
+			StartLine: from.Line,
+		}, string(content[from.Offset:to.Offset]))
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, split...)
+		return nil
+	}
+
+	// The header runs from the package doc to the last import
+	headerStart, headerEnd := file.Package, file.Name.End()
+	if file.Doc != nil {
+		headerStart = file.Doc.Pos()
+	}
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			headerEnd = gen.End()
+		}
+	}
+	if err := add(file.Name.Name, KindHeader, headerStart, headerEnd); err != nil {
+		return nil, err
+	}
+
+	for _, decl := range file.Decls {
+		var symbol, kind string
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			symbol, kind, doc = d.Name.Name, KindFunction, d.Doc
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				symbol, kind = receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, KindMethod
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			symbol, kind, doc = specNames(d.Specs), KindDeclaration, d.Doc
+			if d.Tok == token.TYPE {
+				kind = KindType
+			}
+		default:
+			continue
+		}
+
+		start := decl.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		if err := add(symbol, kind, start, decl.End()); err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// textChunks splits text with the code splitter of the chunk's language.
+// Each piece copies base and gets the line range it spans; base.StartLine
+// is the line of the text's first character.
+func (c *Chunker) textChunks(base Chunk, text string) ([]Chunk, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	pieces := []string{text}
+	if len(text) > c.chunkSize {
+		splitter := documentloader.CreateCodeSplitter(base.Language, c.chunkSize, c.chunkOverlap)
+		var err error
+		if pieces, err = splitter.SplitText(text); err != nil {
+			return nil, fmt.Errorf("failed to split %s: %w", base.Path, err)
+		}
+	}
+
+	chunks := make([]Chunk, 0, len(pieces))
+	offset := 0
+	for _, piece := range pieces {
+		piece = strings.TrimSpace(piece)
+		if piece == "" {
+			continue
+		}
+		// Pieces are substrings of the text in order, though overlapping
+		// pieces may start before the end of the previous one
+		if i := strings.Index(text[offset:], piece); i >= 0 {
+			offset += i
+		}
+		chunk := base
+		chunk.StartLine = base.StartLine + strings.Count(text[:offset], "\n")
+		chunk.EndLine = chunk.StartLine + strings.Count(piece, "\n")
+		chunk.Content = piece
+		chunks = append(chunks, chunk)
+		offset++
+		if offset > len(text) {
+			offset = len(text)
+		}
+	}
+	return chunks, nil
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr: // generic receiver T[P]
+		return receiverName(t.X)
+	case *ast.IndexListExpr: // generic receiver T[P, Q]
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// specNames joins the names declared by type, const or var specs
+func specNames(specs []ast.Spec) string {
+	var names []string
+	for _, spec := range specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
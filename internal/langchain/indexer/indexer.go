@@ -0,0 +1,360 @@
+// Package indexer indexes code bases for retrieval. Files are split into
+// code-aware chunks, Go files on AST boundaries, and each chunk is embedded
+// with its path, symbol and line range as metadata. File content hashes
+// make runs incremental: unchanged files are skipped, only new chunks of
+// changed files are embedded and chunks of removed files are deleted.
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+// DefaultCollection is the collection code is indexed into by default
+const DefaultCollection = "code"
+
+// chunkNamespace derives chunk ids from their collection, root, path and
+// content, so unchanged chunks keep their id across runs
+var chunkNamespace = uuid.MustParse("6f1d3c52-8a0e-4f7b-9c2d-5e4a3b1f0c97")
+
+// DefaultExtensions are the file extensions indexed by default
+var DefaultExtensions = []string{
+	".go", ".py", ".js", ".jsx", ".ts", ".tsx", ".java", ".rs", ".rb",
+	".c", ".h", ".cc", ".cpp", ".hpp", ".sql", ".sh", ".proto", ".md",
+	".yaml", ".yml", ".toml",
+}
+
+// DefaultExclude are the directory names skipped by default, besides
+// hidden directories
+var DefaultExclude = []string{"vendor", "node_modules", "testdata", "dist", "build"}
+
+// Options configure an indexer. Zero values take the defaults.
+type Options struct {
+	Collection   string   // embeddings content type; default "code"
+	Extensions   []string // file extensions to index; default DefaultExtensions
+	Exclude      []string // directory names to skip; default DefaultExclude
+	ChunkSize    int      // characters per chunk; default 1500
+	ChunkOverlap int      // characters shared by split chunks; default 150
+	MaxFileSize  int64    // larger files are skipped; default 1 MiB
+	BatchSize    int      // chunks embedded per call; default 32
+}
+
+// Result summarizes an index run
+type Result struct {
+	Collection     string        `json:"collection"`
+	Root           string        `json:"root"`
+	Files          int           `json:"files"`
+	Indexed        int           `json:"indexed"`
+	Unchanged      int           `json:"unchanged"`
+	Removed        int           `json:"removed"`
+	Failed         int           `json:"failed"`
+	ChunksEmbedded int           `json:"chunks_embedded"`
+	ChunksKept     int           `json:"chunks_kept"`
+	ChunksDeleted  int           `json:"chunks_deleted"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// Indexer indexes code bases into a collection
+type Indexer struct {
+	store    Store
+	embedder embeddings.Embedder
+	chunker  *Chunker
+	options  Options
+	logger   *slog.Logger
+
+	mu sync.Mutex // one run at a time
+}
+
+// New creates an indexer storing chunks embedded by embedder in store
+func New(store Store, embedder embeddings.Embedder, options Options, logger *slog.Logger) *Indexer {
+	if options.Collection == "" {
+		options.Collection = DefaultCollection
+	}
+	if len(options.Extensions) == 0 {
+		options.Extensions = DefaultExtensions
+	}
+	if options.Exclude == nil {
+		options.Exclude = DefaultExclude
+	}
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = 1500
+	}
+	if options.ChunkOverlap < 0 || options.ChunkOverlap >= options.ChunkSize {
+		options.ChunkOverlap = options.ChunkSize / 10
+	}
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = 1 << 20
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 32
+	}
+
+	return &Indexer{
+		store:    store,
+		embedder: embedder,
+		chunker:  NewChunker(options.ChunkSize, options.ChunkOverlap),
+		options:  options,
+		logger:   logger,
+	}
+}
+
+// Collection returns the collection the indexer writes to
+func (ix *Indexer) Collection() string {
+	return ix.options.Collection
+}
+
+// Index brings the index of a directory up to date. Files that fail to
+// index are logged, counted and retried on the next run.
+func (ix *Indexer) Index(ctx context.Context, root string) (*Result, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	start := time.Now()
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	paths, err := ix.scan(root)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := ix.store.Files(ctx, ix.options.Collection, root)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]FileState, len(stored))
+	for _, file := range stored {
+		previous[file.Path] = file
+	}
+
+	result := &Result{Collection: ix.options.Collection, Root: root, Files: len(paths)}
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		before, known := previous[path]
+		delete(previous, path)
+
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			ix.fail(result, path, err)
+			continue
+		}
+		hash := contentHash(content)
+		if known && before.ContentHash == hash {
+			result.Unchanged++
+			continue
+		}
+
+		if err := ix.indexFile(ctx, root, path, content, hash, before.ChunkIDs, result); err != nil {
+			ix.fail(result, path, err)
+			continue
+		}
+		result.Indexed++
+	}
+
+	// Files left over were removed or no longer match
+	for path, file := range previous {
+		if err := ix.removeFile(ctx, root, file, result); err != nil {
+			ix.fail(result, path, err)
+			continue
+		}
+		result.Removed++
+	}
+
+	result.Duration = time.Since(start)
+	ix.logger.Info("Indexed code",
+		slog.String("collection", result.Collection),
+		slog.String("root", root),
+		slog.Int("files", result.Files),
+		slog.Int("indexed", result.Indexed),
+		slog.Int("removed", result.Removed),
+		slog.Int("failed", result.Failed),
+		slog.Int("chunks_embedded", result.ChunksEmbedded),
+		slog.Duration("duration", result.Duration))
+	return result, nil
+}
+
+// indexFile embeds the new chunks of a file, updates the metadata of the
+// chunks it kept and deletes the chunks it lost
+func (ix *Indexer) indexFile(ctx context.Context, root, path string, content []byte, hash string, before []uuid.UUID, result *Result) error {
+	chunks, err := ix.chunker.Chunk(path, content)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[uuid.UUID]bool, len(before))
+	for _, id := range before {
+		existing[id] = true
+	}
+
+	ids := make([]uuid.UUID, len(chunks))
+	seen := make(map[string]int, len(chunks))
+	var pending []int // chunks to embed
+	for i, chunk := range chunks {
+		chunkHash := chunk.Hash()
+		ids[i] = ix.chunkID(root, path, chunkHash, seen[chunkHash])
+		seen[chunkHash]++
+		if existing[ids[i]] {
+			// Same content; only its lines may have moved
+			if err := ix.store.UpdateChunk(ctx, ix.options.Collection, ids[i], chunkMetadata(root, chunk, chunkHash, hash)); err != nil {
+				return err
+			}
+			delete(existing, ids[i])
+			result.ChunksKept++
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for batch := range slices.Chunk(pending, ix.options.BatchSize) {
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = embedText(chunks[i])
+		}
+		vectors, err := ix.embedder.EmbedDocuments(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(batch))
+		}
+		for j, i := range batch {
+			chunk := chunks[i]
+			if err := ix.store.PutChunk(ctx, ix.options.Collection, ids[i], chunk.Content, vectors[j], chunkMetadata(root, chunk, chunk.Hash(), hash)); err != nil {
+				return err
+			}
+			result.ChunksEmbedded++
+		}
+	}
+
+	for id := range existing {
+		if err := ix.store.DeleteChunk(ctx, ix.options.Collection, id); err != nil {
+			return err
+		}
+		result.ChunksDeleted++
+	}
+
+	return ix.store.SaveFile(ctx, ix.options.Collection, root, FileState{
+		Path:        path,
+		ContentHash: hash,
+		ChunkIDs:    ids,
+	})
+}
+
+// removeFile deletes the chunks and state of a file
+func (ix *Indexer) removeFile(ctx context.Context, root string, file FileState, result *Result) error {
+	for _, id := range file.ChunkIDs {
+		if err := ix.store.DeleteChunk(ctx, ix.options.Collection, id); err != nil {
+			return err
+		}
+		result.ChunksDeleted++
+	}
+	return ix.store.DeleteFile(ctx, ix.options.Collection, root, file.Path)
+}
+
+// fail logs and counts a file that failed to index
+func (ix *Indexer) fail(result *Result, path string, err error) {
+	result.Failed++
+	ix.logger.Warn("Failed to index file",
+		slog.String("path", path),
+		slog.Any("error", err))
+}
+
+// fileStamp identifies a version of a file without reading it
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// scan returns the slash-separated paths of the files to index under root
+func (ix *Indexer) scan(root string) (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			ix.logger.Warn("Failed to read path", slog.String("path", path), slog.Any("error", err))
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || slices.Contains(ix.options.Exclude, name)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !slices.Contains(ix.options.Extensions, strings.ToLower(filepath.Ext(name))) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > ix.options.MaxFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		files[filepath.ToSlash(rel)] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// chunkID derives the id of the n-th chunk of a file with the given hash
+func (ix *Indexer) chunkID(root, path, chunkHash string, n int) uuid.UUID {
+	name := strings.Join([]string{ix.options.Collection, root, path, chunkHash, strconv.Itoa(n)}, "\x00")
+	return uuid.NewSHA1(chunkNamespace, []byte(name))
+}
+
+// chunkMetadata describes a chunk for retrieval and citations
+func chunkMetadata(root string, chunk Chunk, chunkHash, fileHash string) map[string]any {
+	return map[string]any{
+		"source":     chunk.Path,
+		"root":       root,
+		"path":       chunk.Path,
+		"file_name":  filepath.Base(chunk.Path),
+		"language":   chunk.Language,
+		"symbol":     chunk.Symbol,
+		"kind":       chunk.Kind,
+		"start_line": chunk.StartLine,
+		"end_line":   chunk.EndLine,
+		"chunk_hash": chunkHash,
+		"file_hash":  fileHash,
+	}
+}
+
+// embedText prefixes a chunk with its location, so the embedding captures
+// where the code lives as well as what it does
+func embedText(chunk Chunk) string {
+	header := chunk.Path
+	if chunk.Symbol != "" {
+		header += " " + chunk.Symbol
+	}
+	return header + "\n" + chunk.Content
+}
+
+// contentHash returns the SHA-256 of a file's content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,220 @@
+package indexer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memoryStore keeps file states and chunks in maps
+type memoryStore struct {
+	files  map[string]FileState
+	chunks map[uuid.UUID]map[string]any
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		files:  make(map[string]FileState),
+		chunks: make(map[uuid.UUID]map[string]any),
+	}
+}
+
+func (s *memoryStore) Files(ctx context.Context, collection, root string) ([]FileState, error) {
+	files := make([]FileState, 0, len(s.files))
+	for _, file := range s.files {
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (s *memoryStore) SaveFile(ctx context.Context, collection, root string, file FileState) error {
+	s.files[file.Path] = file
+	return nil
+}
+
+func (s *memoryStore) DeleteFile(ctx context.Context, collection, root, path string) error {
+	delete(s.files, path)
+	return nil
+}
+
+func (s *memoryStore) PutChunk(ctx context.Context, collection string, id uuid.UUID, content string, vector []float32, metadata map[string]any) error {
+	s.chunks[id] = metadata
+	return nil
+}
+
+func (s *memoryStore) UpdateChunk(ctx context.Context, collection string, id uuid.UUID, metadata map[string]any) error {
+	s.chunks[id] = metadata
+	return nil
+}
+
+func (s *memoryStore) DeleteChunk(ctx context.Context, collection string, id uuid.UUID) error {
+	delete(s.chunks, id)
+	return nil
+}
+
+// countingEmbedder returns unit vectors and counts embedded texts
+type countingEmbedder struct {
+	texts []string
+}
+
+func (e *countingEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+
+func (e *countingEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+const goSource = `// Package sample is a sample.
+package sample
+
+import "fmt"
+
+// Greeter greets people
+type Greeter struct {
+	Name string
+}
+
+// Greet returns a greeting
+func (g *Greeter) Greet() string {
+	return fmt.Sprintf("hello %s", g.Name)
+}
+
+const (
+	A = 1
+	B = 2
+)
+
+// Add adds two numbers
+func Add(a, b int) int {
+	return a + b
+}
+`
+
+func TestChunker_Go(t *testing.T) {
+	chunks, err := NewChunker(1000, 100).Chunk("pkg/sample.go", []byte(goSource))
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	type span struct {
+		Symbol, Kind string
+		Start, End   int
+	}
+	var got []span
+	for _, c := range chunks {
+		got = append(got, span{c.Symbol, c.Kind, c.StartLine, c.EndLine})
+	}
+	want := []span{
+		{"sample", KindHeader, 1, 4},
+		{"Greeter", KindType, 6, 9},
+		{"Greeter.Greet", KindMethod, 11, 14},
+		{"A, B", KindDeclaration, 16, 19},
+		{"Add", KindFunction, 21, 24},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() spans = %v, want %v", got, want)
+	}
+	if !strings.HasPrefix(chunks[2].Content, "// Greet returns a greeting\nfunc (g *Greeter) Greet()") {
+		t.Errorf("method chunk = %q, want it to start with its doc comment", chunks[2].Content)
+	}
+}
+
+func TestChunker_Text(t *testing.T) {
+	source := "import os\n\n\ndef first():\n    return 1\n\n\ndef second():\n    return 2\n"
+	chunks, err := NewChunker(30, 0).Chunk("tool.py", []byte(source))
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("Chunk() = %d chunks, want 3: %+v", len(chunks), chunks)
+	}
+	if chunks[1].StartLine != 4 || chunks[1].EndLine != 5 || !strings.HasPrefix(chunks[1].Content, "def first") {
+		t.Errorf("second chunk = %+v, want def first on lines 4-5", chunks[1])
+	}
+	if chunks[2].StartLine != 8 || chunks[2].Language != "python" || chunks[2].Kind != KindText {
+		t.Errorf("third chunk = %+v, want a python text chunk from line 8", chunks[2])
+	}
+}
+
+func TestIndexer_Incremental(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("sample.go", goSource)
+	write("notes.md", "# Notes\n\nSome notes.\n")
+	write("vendor/dep.go", "package dep\n")
+	write("image.png", "not code")
+
+	store := newMemoryStore()
+	embedder := &countingEmbedder{}
+	ix := New(store, embedder, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	first, err := ix.Index(ctx, root)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if first.Files != 2 || first.Indexed != 2 || first.ChunksEmbedded != 6 || len(store.chunks) != 6 {
+		t.Fatalf("first Index() = %+v with %d chunks, want 2 files and 6 chunks", first, len(store.chunks))
+	}
+
+	second, err := ix.Index(ctx, root)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if second.Unchanged != 2 || second.ChunksEmbedded != 0 {
+		t.Errorf("unchanged Index() = %+v, want nothing embedded", second)
+	}
+
+	// Changing one function re-embeds only its chunk; the function after
+	// it moves down a line and keeps its embedding
+	embedder.texts = nil
+	write("sample.go", strings.Replace(goSource, "return fmt.Sprintf(\"hello %s\", g.Name)", "name := g.Name\n\treturn fmt.Sprintf(\"hello %s\", name)", 1))
+	third, err := ix.Index(ctx, root)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if third.Indexed != 1 || third.ChunksEmbedded != 1 || third.ChunksKept != 4 || third.ChunksDeleted != 1 {
+		t.Errorf("changed Index() = %+v, want 1 embedded, 4 kept and 1 deleted", third)
+	}
+	if len(embedder.texts) != 1 || !strings.HasPrefix(embedder.texts[0], "sample.go Greeter.Greet\n") {
+		t.Errorf("embedded %q, want only the Greet method", embedder.texts)
+	}
+	for _, metadata := range store.chunks {
+		if metadata["symbol"] == "Add" && metadata["start_line"] != 22 {
+			t.Errorf("Add start_line = %v, want 22 after the edit above it", metadata["start_line"])
+		}
+	}
+
+	if err := os.Remove(filepath.Join(root, "notes.md")); err != nil {
+		t.Fatal(err)
+	}
+	fourth, err := ix.Index(ctx, root)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if fourth.Removed != 1 || len(store.chunks) != 5 || len(store.files) != 1 {
+		t.Errorf("Index() after removal = %+v with %d chunks, want notes.md removed", fourth, len(store.chunks))
+	}
+}
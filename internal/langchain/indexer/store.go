@@ -0,0 +1,159 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// FileState is what the index remembers of a file
+type FileState struct {
+	Path        string
+	ContentHash string
+	ChunkIDs    []uuid.UUID
+}
+
+// Store persists file states and chunk embeddings of a collection. Roots
+// keep code bases indexed into the same collection apart.
+type Store interface {
+	Files(ctx context.Context, collection, root string) ([]FileState, error)
+	SaveFile(ctx context.Context, collection, root string, file FileState) error
+	DeleteFile(ctx context.Context, collection, root, path string) error
+
+	PutChunk(ctx context.Context, collection string, id uuid.UUID, content string, vector []float32, metadata map[string]any) error
+	UpdateChunk(ctx context.Context, collection string, id uuid.UUID, metadata map[string]any) error
+	DeleteChunk(ctx context.Context, collection string, id uuid.UUID) error
+}
+
+// QueriesStore implements Store with file states in code_index_files and
+// chunks in embeddings, with the collection as content type. Chunks belong
+// to a knowledge-base collection and record the model of their vectors,
+// which searches only compare with the collection's active model.
+type QueriesStore struct {
+	queries      *sqlc.Queries
+	collectionID pgtype.UUID
+	model        string
+}
+
+// NewQueriesStore creates an index store backed by sqlc queries that
+// stores chunks into a knowledge-base collection as vectors of model
+func NewQueriesStore(queries *sqlc.Queries, collectionID uuid.UUID, model string) *QueriesStore {
+	return &QueriesStore{
+		queries:      queries,
+		collectionID: pgtype.UUID{Bytes: collectionID, Valid: true},
+		model:        model,
+	}
+}
+
+// Files returns the indexed files of a root
+func (s *QueriesStore) Files(ctx context.Context, collection, root string) ([]FileState, error) {
+	rows, err := s.queries.ListCodeIndexFiles(ctx, sqlc.ListCodeIndexFilesParams{
+		Collection: collection,
+		Root:       root,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+
+	files := make([]FileState, 0, len(rows))
+	for _, row := range rows {
+		file := FileState{
+			Path:        row.Path,
+			ContentHash: row.ContentHash,
+			ChunkIDs:    make([]uuid.UUID, 0, len(row.ChunkIds)),
+		}
+		for _, id := range row.ChunkIds {
+			file.ChunkIDs = append(file.ChunkIDs, uuid.UUID(id.Bytes))
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// SaveFile stores the state of an indexed file
+func (s *QueriesStore) SaveFile(ctx context.Context, collection, root string, file FileState) error {
+	ids := make([]pgtype.UUID, 0, len(file.ChunkIDs))
+	for _, id := range file.ChunkIDs {
+		ids = append(ids, pgtype.UUID{Bytes: id, Valid: true})
+	}
+	err := s.queries.UpsertCodeIndexFile(ctx, sqlc.UpsertCodeIndexFileParams{
+		Collection:  collection,
+		Root:        root,
+		Path:        file.Path,
+		ContentHash: file.ContentHash,
+		ChunkIds:    ids,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save indexed file: %w", err)
+	}
+	return nil
+}
+
+// DeleteFile forgets an indexed file
+func (s *QueriesStore) DeleteFile(ctx context.Context, collection, root, path string) error {
+	err := s.queries.DeleteCodeIndexFile(ctx, sqlc.DeleteCodeIndexFileParams{
+		Collection: collection,
+		Root:       root,
+		Path:       path,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete indexed file: %w", err)
+	}
+	return nil
+}
+
+// PutChunk stores a chunk and its embedding
+func (s *QueriesStore) PutChunk(ctx context.Context, collection string, id uuid.UUID, content string, vector []float32, metadata map[string]any) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	_, err = s.queries.CreateEmbedding(ctx, sqlc.CreateEmbeddingParams{
+		CollectionID: s.collectionID,
+		ContentType:  collection,
+		ContentID:    pgtype.UUID{Bytes: id, Valid: true},
+		ContentText:  content,
+		Embedding:    pgvector.NewVector(vector),
+		Metadata:     data,
+		Model:        s.model,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store chunk: %w", err)
+	}
+	return nil
+}
+
+// UpdateChunk replaces the metadata of a stored chunk
+func (s *QueriesStore) UpdateChunk(ctx context.Context, collection string, id uuid.UUID, metadata map[string]any) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	err = s.queries.UpdateEmbeddingMetadataByContent(ctx, sqlc.UpdateEmbeddingMetadataByContentParams{
+		ContentType: collection,
+		ContentID:   pgtype.UUID{Bytes: id, Valid: true},
+		Metadata:    data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update chunk: %w", err)
+	}
+	return nil
+}
+
+// DeleteChunk removes a stored chunk
+func (s *QueriesStore) DeleteChunk(ctx context.Context, collection string, id uuid.UUID) error {
+	err := s.queries.DeleteEmbedding(ctx, sqlc.DeleteEmbeddingParams{
+		ContentType: collection,
+		ContentID:   pgtype.UUID{Bytes: id, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete chunk: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package indexer
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls for changes by default
+const DefaultWatchInterval = 2 * time.Second
+
+// Watch indexes root, then polls it every interval and indexes it again
+// when files were added, changed or removed. Polling sizes and modification
+// times needs no platform support and catches changes made while a run was
+// in progress. Each run's result or error goes to report, which may be nil.
+// Watch returns when ctx is done.
+func (ix *Indexer) Watch(ctx context.Context, root string, interval time.Duration, report func(*Result, error)) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	if report == nil {
+		report = func(*Result, error) {}
+	}
+
+	// Take the snapshot before indexing so changes during a run are seen
+	// on the next poll
+	last, err := ix.scan(root)
+	if err != nil {
+		return err
+	}
+	report(ix.Index(ctx, root))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := ix.scan(root)
+		if err != nil {
+			ix.logger.Warn("Failed to scan watched directory",
+				slog.String("root", root),
+				slog.Any("error", err))
+			continue
+		}
+		if maps.Equal(current, last) {
+			continue
+		}
+		last = current
+		result, err := ix.Index(ctx, root)
+		if ctx.Err() != nil {
+			return nil
+		}
+		report(result, err)
+	}
+}
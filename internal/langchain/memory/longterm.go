@@ -18,11 +18,19 @@ import (
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
 )
 
+// mockEmbeddingModel names the placeholder vectors memories store without
+// an embedder, which no collection searches
+const mockEmbeddingModel = "mock"
+
+// defaultCollectionID is the knowledge-base collection memories are stored in
+var defaultCollectionID, _ = postgres.ParseUUID(postgres.DefaultCollectionID)
+
 // LongTermMemory implements persistent long-term memory with semantic search using LangChain vectorstore
 type LongTermMemory struct {
 	queries     sqlc.Querier
 	vectorStore vectorstores.VectorStore
 	embedder    embeddings.Embedder
+	model       string // model of the embedder
 	config      config.LangChain
 	logger      *slog.Logger
 }
@@ -36,12 +44,14 @@ func NewLongTermMemory(queries sqlc.Querier, config config.LangChain, logger *sl
 	}
 }
 
-// NewLongTermMemoryWithVectorStore creates a new long-term memory instance with custom vectorstore and embedder
-func NewLongTermMemoryWithVectorStore(queries sqlc.Querier, vectorStore vectorstores.VectorStore, embedder embeddings.Embedder, config config.LangChain, logger *slog.Logger) *LongTermMemory {
+// NewLongTermMemoryWithVectorStore creates a new long-term memory instance with custom vectorstore and embedder,
+// whose model is named by model
+func NewLongTermMemoryWithVectorStore(queries sqlc.Querier, vectorStore vectorstores.VectorStore, embedder embeddings.Embedder, model string, config config.LangChain, logger *slog.Logger) *LongTermMemory {
 	return &LongTermMemory{
 		queries:     queries,
 		vectorStore: vectorStore,
 		embedder:    embedder,
+		model:       model,
 		config:      config,
 		logger:      logger,
 	}
@@ -52,9 +62,11 @@ func (ltm *LongTermMemory) SetVectorStore(vectorStore vectorstores.VectorStore)
 	ltm.vectorStore = vectorStore
 }
 
-// SetEmbedder sets the embedder for this memory instance
-func (ltm *LongTermMemory) SetEmbedder(embedder embeddings.Embedder) {
+// SetEmbedder sets the embedder for this memory instance and the model it
+// embeds with
+func (ltm *LongTermMemory) SetEmbedder(embedder embeddings.Embedder, model string) {
 	ltm.embedder = embedder
+	ltm.model = model
 }
 
 // Store stores a memory entry in long-term memory with semantic indexing
@@ -130,16 +142,19 @@ func (ltm *LongTermMemory) storeWithDatabase(ctx context.Context, entry *MemoryE
 
 	// Generate embedding if not provided
 	if len(entry.Embedding) == 0 {
-		embedding, err := ltm.generateEmbedding(ctx, entry.Content)
+		embedding, model, err := ltm.generateEmbedding(ctx, entry.Content)
 		if err != nil {
 			ltm.logger.Warn("Failed to generate embedding for long-term memory",
 				slog.String("entry_id", entry.ID),
 				slog.Any("error", err))
 			// Continue without embedding - will use text search fallback
 		} else {
-			entry.Embedding = embedding
+			entry.Embedding, entry.EmbeddingModel = embedding, model
 		}
 	}
+	if entry.EmbeddingModel == "" {
+		entry.EmbeddingModel = ltm.model
+	}
 
 	// Store in database using embedding service
 	metadata := map[string]interface{}{
@@ -176,11 +191,13 @@ func (ltm *LongTermMemory) storeWithDatabase(ctx context.Context, entry *MemoryE
 
 	// Create embedding with queries
 	_, err = ltm.queries.CreateEmbedding(ctx, sqlc.CreateEmbeddingParams{
-		ContentType: "memory",
-		ContentID:   entryUUID,
-		ContentText: entry.Content,
-		Embedding:   postgres.VectorToPgVector(entry.Embedding),
-		Metadata:    metadataJSON,
+		CollectionID: defaultCollectionID,
+		ContentType:  "memory",
+		ContentID:    entryUUID,
+		ContentText:  entry.Content,
+		Embedding:    postgres.VectorToPgVector(entry.Embedding),
+		Metadata:     metadataJSON,
+		Model:        entry.EmbeddingModel,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to store long-term memory: %w", err)
@@ -307,14 +324,17 @@ func (ltm *LongTermMemory) searchWithDatabase(ctx context.Context, query *Memory
 		return results, nil // Return empty results gracefully
 	}
 
-	// Generate query embedding if provided
+	// Generate query embedding if provided. Only vectors of the model that
+	// produced it are compared; an embedding passed in the query is taken
+	// to come from the embedder.
 	var queryEmbedding []float64
+	model := ltm.model
 	var err error
 
 	if len(query.Embedding) > 0 {
 		queryEmbedding = query.Embedding
 	} else if query.Content != "" {
-		queryEmbedding, err = ltm.generateEmbedding(ctx, query.Content)
+		queryEmbedding, model, err = ltm.generateEmbedding(ctx, query.Content)
 		if err != nil {
 			ltm.logger.Warn("Failed to generate query embedding",
 				slog.String("query", query.Content),
@@ -352,6 +372,7 @@ func (ltm *LongTermMemory) searchWithDatabase(ctx context.Context, query *Memory
 	searchResults, err := ltm.queries.SearchSimilarEmbeddings(ctx, sqlc.SearchSimilarEmbeddingsParams{
 		QueryEmbedding: postgres.VectorToPgVector(queryEmbedding),
 		ContentType:    "memory",
+		Model:          model,
 		Threshold:      similarity,
 		ResultLimit:    int32(limit),
 	})
@@ -389,15 +410,12 @@ func (ltm *LongTermMemory) searchWithDatabase(ctx context.Context, query *Memory
 			continue
 		}
 
-		// Convert similarity from int32 to float64
-		similarityFloat := float64(searchResult.Similarity) / 100.0
-
 		// Calculate relevance
-		relevance := ltm.calculateRelevance(entry, query, similarityFloat)
+		relevance := ltm.calculateRelevance(entry, query, searchResult.Similarity)
 
 		memoryResult := &MemorySearchResult{
 			Entry:      entry,
-			Similarity: similarityFloat,
+			Similarity: searchResult.Similarity,
 			Relevance:  relevance,
 		}
 
@@ -449,15 +467,18 @@ func (ltm *LongTermMemory) Update(ctx context.Context, entry *MemoryEntry) error
 
 	// Update embedding if content changed
 	if len(entry.Embedding) == 0 {
-		embedding, err := ltm.generateEmbedding(ctx, entry.Content)
+		embedding, model, err := ltm.generateEmbedding(ctx, entry.Content)
 		if err != nil {
 			ltm.logger.Warn("Failed to generate embedding for update",
 				slog.String("entry_id", entry.ID),
 				slog.Any("error", err))
 		} else {
-			entry.Embedding = embedding
+			entry.Embedding, entry.EmbeddingModel = embedding, model
 		}
 	}
+	if entry.EmbeddingModel == "" {
+		entry.EmbeddingModel = ltm.model
+	}
 
 	// Convert entry ID to UUID
 	entryUUID, err := postgres.ParseUUID(entry.ID)
@@ -473,11 +494,13 @@ func (ltm *LongTermMemory) Update(ctx context.Context, entry *MemoryEntry) error
 
 	// Update in database
 	_, err = ltm.queries.CreateEmbedding(ctx, sqlc.CreateEmbeddingParams{
-		ContentType: "memory",
-		ContentID:   entryUUID,
-		ContentText: entry.Content,
-		Embedding:   postgres.VectorToPgVector(entry.Embedding),
-		Metadata:    metadataJSON,
+		CollectionID: defaultCollectionID,
+		ContentType:  "memory",
+		ContentID:    entryUUID,
+		ContentText:  entry.Content,
+		Embedding:    postgres.VectorToPgVector(entry.Embedding),
+		Metadata:     metadataJSON,
+		Model:        entry.EmbeddingModel,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to update long-term memory: %w", err)
@@ -618,8 +641,9 @@ func (ltm *LongTermMemory) Cleanup(ctx context.Context) error {
 	return nil
 }
 
-// generateEmbedding generates an embedding for the given text
-func (ltm *LongTermMemory) generateEmbedding(ctx context.Context, text string) ([]float64, error) {
+// generateEmbedding generates an embedding for the given text and names
+// the model that produced it
+func (ltm *LongTermMemory) generateEmbedding(ctx context.Context, text string) ([]float64, string, error) {
 	// Use LangChain embedder if available
 	if ltm.embedder != nil {
 		embedding, err := ltm.embedder.EmbedQuery(ctx, text)
@@ -633,7 +657,7 @@ func (ltm *LongTermMemory) generateEmbedding(ctx context.Context, text string) (
 			for i, v := range embedding {
 				embeddingFloat64[i] = float64(v)
 			}
-			return embeddingFloat64, nil
+			return embeddingFloat64, ltm.model, nil
 		}
 	}
 
@@ -648,7 +672,7 @@ func (ltm *LongTermMemory) generateEmbedding(ctx context.Context, text string) (
 		slog.String("text", text[:min(50, len(text))]),
 		slog.Int("dimension", len(mockEmbedding)))
 
-	return mockEmbedding, nil
+	return mockEmbedding, mockEmbeddingModel, nil
 }
 
 // parseDocumentToMemoryEntry converts a LangChain document to a memory entry
@@ -23,19 +23,20 @@ const (
 
 // MemoryEntry represents a single memory entry
 type MemoryEntry struct {
-	ID          string                 `json:"id"`
-	Type        MemoryType             `json:"type"`
-	UserID      string                 `json:"user_id"`
-	SessionID   string                 `json:"session_id,omitempty"`
-	Content     string                 `json:"content"`
-	Context     map[string]interface{} `json:"context,omitempty"`
-	Embedding   []float64              `json:"embedding,omitempty"`
-	Importance  float64                `json:"importance"` // 0.0 to 1.0
-	AccessCount int                    `json:"access_count"`
-	LastAccess  time.Time              `json:"last_access"`
-	CreatedAt   time.Time              `json:"created_at"`
-	ExpiresAt   *time.Time             `json:"expires_at,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	ID             string                 `json:"id"`
+	Type           MemoryType             `json:"type"`
+	UserID         string                 `json:"user_id"`
+	SessionID      string                 `json:"session_id,omitempty"`
+	Content        string                 `json:"content"`
+	Context        map[string]interface{} `json:"context,omitempty"`
+	Embedding      []float64              `json:"embedding,omitempty"`
+	EmbeddingModel string                 `json:"embedding_model,omitempty"` // model of Embedding; the embedder's when empty
+	Importance     float64                `json:"importance"`                // 0.0 to 1.0
+	AccessCount    int                    `json:"access_count"`
+	LastAccess     time.Time              `json:"last_access"`
+	CreatedAt      time.Time              `json:"created_at"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // MemoryQuery represents a query for retrieving memories
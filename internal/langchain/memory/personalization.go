@@ -136,11 +136,13 @@ func (pm *PersonalizationMemory) storePreference(ctx context.Context, entry *Mem
 
 	// Create embedding with queries
 	_, err = pm.queries.CreateEmbedding(ctx, sqlc.CreateEmbeddingParams{
-		ContentType: "personalization",
-		ContentID:   prefUUID,
-		ContentText: content,
-		Embedding:   postgres.VectorToPgVector(embedding),
-		Metadata:    metadataJSON,
+		CollectionID: defaultCollectionID,
+		ContentType:  "personalization",
+		ContentID:    prefUUID,
+		ContentText:  content,
+		Embedding:    postgres.VectorToPgVector(embedding),
+		Metadata:     metadataJSON,
+		Model:        mockEmbeddingModel,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to store preference: %w", err)
@@ -224,11 +226,13 @@ func (pm *PersonalizationMemory) storeContext(ctx context.Context, entry *Memory
 
 	// Create embedding with queries
 	_, err = pm.queries.CreateEmbedding(ctx, sqlc.CreateEmbeddingParams{
-		ContentType: "personalization",
-		ContentID:   ctxUUID,
-		ContentText: content,
-		Embedding:   postgres.VectorToPgVector(embedding),
-		Metadata:    metadataJSON,
+		CollectionID: defaultCollectionID,
+		ContentType:  "personalization",
+		ContentID:    ctxUUID,
+		ContentText:  content,
+		Embedding:    postgres.VectorToPgVector(embedding),
+		Metadata:     metadataJSON,
+		Model:        mockEmbeddingModel,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to store context: %w", err)
@@ -285,6 +289,7 @@ func (pm *PersonalizationMemory) Search(ctx context.Context, query *MemoryQuery)
 		searchResults, err := pm.queries.SearchSimilarEmbeddings(ctx, sqlc.SearchSimilarEmbeddingsParams{
 			QueryEmbedding: postgres.VectorToPgVector(queryEmbedding),
 			ContentType:    "personalization",
+			Model:          mockEmbeddingModel,
 			Threshold:      similarity,
 			ResultLimit:    int32(limit),
 		})
@@ -322,15 +327,12 @@ func (pm *PersonalizationMemory) Search(ctx context.Context, query *MemoryQuery)
 				continue
 			}
 
-			// Convert similarity from int32 to float64
-			similarityFloat := float64(searchResult.Similarity) / 100.0
-
 			// Calculate relevance
-			relevance := pm.calculateRelevance(entry, query, similarityFloat)
+			relevance := pm.calculateRelevance(entry, query, searchResult.Similarity)
 
 			memoryResult := &MemorySearchResult{
 				Entry:      entry,
-				Similarity: similarityFloat,
+				Similarity: searchResult.Similarity,
 				Relevance:  relevance,
 			}
 
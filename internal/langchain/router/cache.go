@@ -0,0 +1,173 @@
+package router
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxServedIDs bounds the decision ids remembered per cache entry
+const maxServedIDs = 32
+
+// cache holds model decisions by query embedding. A lookup returns the
+// decision of the most similar query at or above the similarity
+// threshold; the least recently used entry is evicted when full.
+type cache struct {
+	mu         sync.Mutex
+	entries    []*cacheEntry
+	size       int
+	similarity float64
+	ttl        time.Duration
+}
+
+type cacheEntry struct {
+	embedding []float64
+	norm      float64
+	decision  Decision
+	served    []string // ids of the decisions the entry produced
+	expires   time.Time
+	used      time.Time
+}
+
+// newCache creates a cache of up to size decisions; a zero ttl keeps
+// decisions until they are evicted
+func newCache(size int, similarity float64, ttl time.Duration) *cache {
+	return &cache{
+		size:       size,
+		similarity: similarity,
+		ttl:        ttl,
+	}
+}
+
+// lookup returns the decision cached for the most similar query and its
+// similarity, or nil
+func (c *cache) lookup(embedding []float64) (*Decision, float64) {
+	norm := vectorNorm(embedding)
+	if norm == 0 {
+		return nil, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.entries = slices.DeleteFunc(c.entries, func(e *cacheEntry) bool {
+		return !e.expires.IsZero() && now.After(e.expires)
+	})
+
+	var best *cacheEntry
+	bestSimilarity := c.similarity
+	for _, e := range c.entries {
+		if s := cosine(embedding, norm, e.embedding, e.norm); s >= bestSimilarity {
+			best, bestSimilarity = e, s
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	best.used = now
+	decision := best.decision
+	decision.Tools = slices.Clone(decision.Tools)
+	return &decision, bestSimilarity
+}
+
+// add caches a decision for a query embedding
+func (c *cache) add(embedding []float64, decision *Decision) {
+	norm := vectorNorm(embedding)
+	if norm == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.size {
+		oldest := 0
+		for i, e := range c.entries {
+			if e.used.Before(c.entries[oldest].used) {
+				oldest = i
+			}
+		}
+		c.entries = slices.Delete(c.entries, oldest, oldest+1)
+	}
+
+	now := time.Now()
+	entry := &cacheEntry{
+		embedding: embedding,
+		norm:      norm,
+		decision:  *decision,
+		used:      now,
+	}
+	entry.decision.Tools = slices.Clone(decision.Tools)
+	if decision.ID != "" {
+		entry.served = []string{decision.ID}
+	}
+	if c.ttl > 0 {
+		entry.expires = now.Add(c.ttl)
+	}
+	c.entries = append(c.entries, entry)
+}
+
+// served remembers that the entry of decision origin produced decision id
+func (c *cache) served(origin, id string) {
+	if origin == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.entries {
+		if e.decision.ID == origin {
+			e.served = append(e.served, id)
+			if len(e.served) > maxServedIDs {
+				// Keep the origin and the latest ids
+				e.served = slices.Delete(e.served, 1, len(e.served)-maxServedIDs+1)
+			}
+			return
+		}
+	}
+}
+
+// remove evicts the entry that produced a decision
+func (c *cache) remove(id string) {
+	if id == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = slices.DeleteFunc(c.entries, func(e *cacheEntry) bool {
+		return slices.Contains(e.served, id)
+	})
+}
+
+// len returns the number of cached decisions
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// cosine returns the cosine similarity of two vectors given their norms
+func cosine(a []float64, normA float64, b []float64, normB float64) float64 {
+	if len(a) != len(b) || normB == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot / (normA * normB)
+}
+
+// vectorNorm returns the Euclidean norm of a vector
+func vectorNorm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
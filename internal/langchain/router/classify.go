@@ -0,0 +1,145 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/langchain/chain"
+)
+
+// maxClassificationTokens bounds the model's reply, a small JSON object
+const maxClassificationTokens = 256
+
+// agentDescriptions describe the agent types to the model
+var agentDescriptions = []struct {
+	agent       agent.AgentType
+	description string
+}{
+	{agent.TypeDevelopment, "writing, reviewing, debugging, testing and refactoring code"},
+	{agent.TypeDatabase, "SQL, schemas, migrations, indexes and query performance"},
+	{agent.TypeInfrastructure, "containers, Kubernetes, deployment, CI/CD and cloud resources"},
+	{agent.TypeResearch, "explaining concepts, comparing options, documentation and best practices"},
+	{agent.TypeGeneral, "anything else"},
+}
+
+// chainDescriptions describe the chain types to the model
+var chainDescriptions = []struct {
+	chain       chain.ChainType
+	description string
+}{
+	{chain.ChainTypeSequential, "steps that each build on the previous one"},
+	{chain.ChainTypeParallel, "independent subtasks that can run at the same time"},
+	{chain.ChainTypeConditional, "the next step depends on an earlier result"},
+	{chain.ChainTypeRAG, "answers grounded in stored documents and knowledge"},
+}
+
+// classification is the model's structured reply
+type classification struct {
+	AgentType  string   `json:"agent_type"`
+	ChainType  string   `json:"chain_type"`
+	Tools      []string `json:"tools"`
+	Confidence float64  `json:"confidence"`
+	Reason     string   `json:"reason"`
+}
+
+// classifyWithModel asks the routing model to classify a request
+func (r *Router) classifyWithModel(ctx context.Context, request Request) (*Decision, error) {
+	system := classificationPrompt(request.Tools)
+	resp, err := r.classifier.GenerateResponse(ctx, &ai.GenerateRequest{
+		Messages:     []ai.Message{{Role: "user", Content: request.Query}},
+		MaxTokens:    maxClassificationTokens,
+		Temperature:  0,
+		Model:        r.config.Model,
+		SystemPrompt: &system,
+	}, r.provider)
+	if err != nil {
+		return nil, err
+	}
+
+	decision, err := parseClassification(resp.Content, request.Tools)
+	if err != nil {
+		return nil, err
+	}
+	decision.Model = resp.Model
+	return decision, nil
+}
+
+// classificationPrompt describes the choices and the reply format
+func classificationPrompt(tools []string) string {
+	var b strings.Builder
+	b.WriteString("You route requests to an assistant's agents. Classify the user's request.\n\nAgent types:\n")
+	for _, a := range agentDescriptions {
+		fmt.Fprintf(&b, "- %s: %s\n", a.agent, a.description)
+	}
+	b.WriteString("\nChain types:\n")
+	for _, c := range chainDescriptions {
+		fmt.Fprintf(&b, "- %s: %s\n", c.chain, c.description)
+	}
+	if len(tools) > 0 {
+		fmt.Fprintf(&b, "\nTools: %s\n", strings.Join(tools, ", "))
+	} else {
+		b.WriteString("\nNo tools are available; return an empty tools list.\n")
+	}
+	b.WriteString("\nReply with only a JSON object, no other text:\n")
+	b.WriteString(`{"agent_type": "...", "chain_type": "...", "tools": ["..."], "confidence": 0.0, "reason": "..."}`)
+	b.WriteString("\n\nconfidence is between 0 and 1; tools lists only the tools the request needs.")
+	return b.String()
+}
+
+// parseClassification reads the model's reply into a decision. Unknown
+// agent types are an error; unknown chain types default to sequential and
+// tools that are not candidates are dropped.
+func parseClassification(reply string, candidates []string) (*Decision, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in routing reply")
+	}
+
+	var c classification
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &c); err != nil {
+		return nil, fmt.Errorf("invalid routing reply: %w", err)
+	}
+
+	agentType := agent.AgentType(strings.ToLower(strings.TrimSpace(c.AgentType)))
+	if !knownAgent(agentType) {
+		return nil, fmt.Errorf("unknown agent type %q in routing reply", c.AgentType)
+	}
+	chainType := chain.ChainType(strings.ToLower(strings.TrimSpace(c.ChainType)))
+	if !knownChain(chainType) {
+		chainType = chain.ChainTypeSequential
+	}
+
+	return &Decision{
+		AgentType:  agentType,
+		ChainType:  chainType,
+		Tools:      allowed(c.Tools, candidates),
+		Confidence: min(max(c.Confidence, 0), 1),
+		Reason:     c.Reason,
+		Source:     SourceModel,
+	}, nil
+}
+
+// knownAgent reports whether the router routes to an agent type
+func knownAgent(agentType agent.AgentType) bool {
+	for _, a := range agentDescriptions {
+		if a.agent == agentType {
+			return true
+		}
+	}
+	return false
+}
+
+// knownChain reports whether the router routes to a chain type
+func knownChain(chainType chain.ChainType) bool {
+	for _, c := range chainDescriptions {
+		if c.chain == chainType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/langchain/chain"
+)
+
+// Confidence of heuristic decisions, which only match keywords
+const (
+	heuristicConfidence        = 0.5
+	heuristicDefaultConfidence = 0.3 // no keyword matched
+)
+
+// toolKeywords suggest tools by the words of a query
+var toolKeywords = []struct {
+	tool     string
+	keywords []string
+}{
+	{"godev", []string{"analyze", "go", "code", "function", "struct"}},
+	{"docker", []string{"docker", "container", "image"}},
+	{"kubernetes", []string{"kubernetes", "k8s", "pod", "deployment"}},
+	{"postgres", []string{"database", "postgres", "sql", "query"}},
+	{"web_search", []string{"search", "latest", "look up", "news"}},
+}
+
+// chainKeywords suggest a chain type by the words of a query; the first
+// match wins and sequential is the default
+var chainKeywords = []struct {
+	chain    chain.ChainType
+	keywords []string
+}{
+	{chain.ChainTypeRAG, []string{"document", "docs", "knowledge base", "according to", "our notes"}},
+	{chain.ChainTypeParallel, []string{"compare", "each of", "all of", "versus", " vs "}},
+	{chain.ChainTypeConditional, []string{" if ", "depending on", "whether", "otherwise"}},
+}
+
+// Heuristic routes a request by keywords. It is the fallback when no
+// model is available, and costs nothing.
+func Heuristic(request Request) *Decision {
+	agentType := agent.KeywordAgent(request.Query)
+	confidence := heuristicConfidence
+	if agentType == agent.TypeGeneral {
+		confidence = heuristicDefaultConfidence
+	}
+
+	return &Decision{
+		AgentType:  agentType,
+		ChainType:  keywordChain(request.Query),
+		Tools:      allowed(keywordTools(request.Query), request.Tools),
+		Confidence: confidence,
+		Source:     SourceHeuristic,
+	}
+}
+
+// keywordChain picks the chain type by keywords
+func keywordChain(query string) chain.ChainType {
+	query = " " + strings.ToLower(query) + " "
+	for _, candidate := range chainKeywords {
+		if containsAny(query, candidate.keywords) {
+			return candidate.chain
+		}
+	}
+	return chain.ChainTypeSequential
+}
+
+// keywordTools suggests tools by keywords
+func keywordTools(query string) []string {
+	query = strings.ToLower(query)
+	tools := make([]string, 0)
+	for _, candidate := range toolKeywords {
+		if containsAny(query, candidate.keywords) {
+			tools = append(tools, candidate.tool)
+		}
+	}
+	return tools
+}
+
+// containsAny reports whether a lower-case text contains any keyword
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
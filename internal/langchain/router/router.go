@@ -0,0 +1,333 @@
+// Package router decides which agent, chain and tools should handle a
+// request. A cheap model call classifies the request; when the model is
+// unavailable the keyword heuristic decides instead. Model decisions are
+// cached for similar queries by embedding, and every decision is logged
+// as a learning event so its outcome can be fed back and routing accuracy
+// measured over time.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/langchain/chain"
+)
+
+// Source tells how a decision was made
+type Source string
+
+const (
+	SourceModel     Source = "model"
+	SourceHeuristic Source = "heuristic"
+	SourceCache     Source = "cache"
+)
+
+// Outcomes of routed requests, as stored in learning_events
+const (
+	OutcomeSuccess   = "success"
+	OutcomeFailure   = "failure"
+	OutcomePartial   = "partial"
+	OutcomeAbandoned = "abandoned"
+)
+
+// ErrDecisionNotFound is returned for feedback on an unknown decision
+var ErrDecisionNotFound = errors.New("routing decision not found")
+
+// Request is a request to route. Tools lists the tools the router may
+// choose from, typically those enabled for the user.
+type Request struct {
+	Query          string   `json:"query"`
+	UserID         string   `json:"user_id,omitempty"`
+	ConversationID string   `json:"conversation_id,omitempty"`
+	Tools          []string `json:"tools,omitempty"`
+}
+
+// Decision is the routing of a request. ID is the learning event that
+// records it, empty when the decision was not recorded.
+type Decision struct {
+	ID         string          `json:"id,omitempty"`
+	AgentType  agent.AgentType `json:"agent_type"`
+	ChainType  chain.ChainType `json:"chain_type"`
+	Tools      []string        `json:"tools"`
+	Confidence float64         `json:"confidence"`
+	Reason     string          `json:"reason,omitempty"`
+	Source     Source          `json:"source"`
+	Model      string          `json:"model,omitempty"`
+	CachedFrom string          `json:"cached_from,omitempty"` // decision a cached decision repeats
+	Similarity float64         `json:"similarity,omitempty"`  // of the cached query
+	Duration   time.Duration   `json:"duration"`
+}
+
+// Outcome is feedback on a decision: the result of running the request
+// as routed and an optional score of -1, 0 or 1
+type Outcome struct {
+	Result  string `json:"outcome"`
+	Score   *int   `json:"score,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Validate checks the outcome against the values learning_events accepts
+func (o Outcome) Validate() error {
+	switch o.Result {
+	case OutcomeSuccess, OutcomeFailure, OutcomePartial, OutcomeAbandoned:
+	default:
+		return fmt.Errorf("invalid outcome %q: must be success, failure, partial or abandoned", o.Result)
+	}
+	if o.Score != nil && (*o.Score < -1 || *o.Score > 1) {
+		return fmt.Errorf("invalid score %d: must be -1, 0 or 1", *o.Score)
+	}
+	return nil
+}
+
+// negative reports whether the outcome says the routing was wrong
+func (o Outcome) negative() bool {
+	return o.Result == OutcomeFailure || (o.Score != nil && *o.Score < 0)
+}
+
+// Accuracy summarizes the decisions of one source and agent type
+type Accuracy struct {
+	Source           Source          `json:"source"`
+	AgentType        agent.AgentType `json:"agent_type"`
+	Decisions        int64           `json:"decisions"`
+	Rated            int64           `json:"rated"`
+	Successes        int64           `json:"successes"`
+	Failures         int64           `json:"failures"`
+	Accuracy         float64         `json:"accuracy"` // successes among successes and failures
+	AvgConfidence    float64         `json:"avg_confidence"`
+	AvgFeedbackScore float64         `json:"avg_feedback_score"`
+}
+
+// Classifier generates the model's routing reply; *ai.Service implements it
+type Classifier interface {
+	GenerateResponse(ctx context.Context, request *ai.GenerateRequest, providerName string) (*ai.GenerateResponse, error)
+}
+
+// Embedder embeds queries for the decision cache; *ai.Service implements it
+type Embedder interface {
+	GenerateEmbedding(ctx context.Context, text string, providerName string) (*ai.EmbeddingResponse, error)
+}
+
+// Store records decisions and their outcomes
+type Store interface {
+	RecordDecision(ctx context.Context, request Request, decision *Decision) (string, error)
+	RecordOutcome(ctx context.Context, userID, decisionID string, outcome Outcome) error
+	Accuracy(ctx context.Context, userID string, since time.Time) ([]Accuracy, error)
+}
+
+// defaultTimeout bounds model and embedding calls without a configured
+// timeout
+const defaultTimeout = 5 * time.Second
+
+// cheapModels are the models used for classification when none is
+// configured, by provider
+var cheapModels = map[string]string{
+	"claude": "claude-3-haiku-20240307",
+	"gemini": "gemini-1.5-flash",
+}
+
+// Router routes requests to agents, chains and tools
+type Router struct {
+	config            config.Routing
+	provider          string // of the classification model
+	embeddingProvider string
+	classifier        Classifier
+	embedder          Embedder
+	store             Store
+	cache             *cache
+	logger            *slog.Logger
+}
+
+// New creates a router. Without a classifier, or with routing disabled,
+// requests are routed by the keyword heuristic; without an embedder
+// decisions are not cached; without a store they are not recorded.
+func New(cfg config.AIConfig, classifier Classifier, embedder Embedder, store Store, logger *slog.Logger) *Router {
+	routing := cfg.Routing
+	provider := routing.Provider
+	if provider == "" {
+		provider = cfg.DefaultProvider
+	}
+	if routing.Model == "" {
+		routing.Model = cheapModels[provider]
+	}
+	if routing.Timeout <= 0 {
+		routing.Timeout = defaultTimeout
+	}
+
+	r := &Router{
+		config:            routing,
+		provider:          provider,
+		embeddingProvider: cfg.Embeddings.Provider,
+		classifier:        classifier,
+		embedder:          embedder,
+		store:             store,
+		logger:            logger,
+	}
+	if embedder != nil && routing.CacheSize > 0 {
+		r.cache = newCache(routing.CacheSize, routing.CacheSimilarity, routing.CacheTTL)
+	}
+	return r
+}
+
+// Route decides how to handle a request: from the cache when a similar
+// query was classified before, else by the model, else by the keyword
+// heuristic. The decision is recorded when the request has a user.
+func (r *Router) Route(ctx context.Context, request Request) (*Decision, error) {
+	if strings.TrimSpace(request.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	start := time.Now()
+
+	decision, embedding := r.fromCache(ctx, request)
+	if decision == nil {
+		decision = r.classify(ctx, request)
+	}
+	decision.Duration = time.Since(start)
+
+	r.record(ctx, request, decision)
+	// Only model decisions are cached: the heuristic is cheap anyway
+	if decision.Source == SourceModel && embedding != nil {
+		r.cache.add(embedding, decision)
+	}
+
+	r.logger.Info("Request routed",
+		slog.String("decision_id", decision.ID),
+		slog.String("source", string(decision.Source)),
+		slog.String("agent_type", string(decision.AgentType)),
+		slog.String("chain_type", string(decision.ChainType)),
+		slog.Any("tools", decision.Tools),
+		slog.Float64("confidence", decision.Confidence),
+		slog.Duration("duration", decision.Duration))
+
+	return decision, nil
+}
+
+// SelectAgent routes an agent request and returns the agent type; it lets
+// the router act as the agent manager's selector
+func (r *Router) SelectAgent(ctx context.Context, request *agent.Request) (agent.AgentType, error) {
+	decision, err := r.Route(ctx, Request{
+		Query:          request.Query,
+		UserID:         request.UserID,
+		ConversationID: request.ConversationID,
+		Tools:          request.Tools,
+	})
+	if err != nil {
+		return "", err
+	}
+	return decision.AgentType, nil
+}
+
+// RecordOutcome stores feedback on a decision. Negative feedback evicts
+// the decision from the cache so similar queries are classified again.
+func (r *Router) RecordOutcome(ctx context.Context, userID, decisionID string, outcome Outcome) error {
+	if err := outcome.Validate(); err != nil {
+		return err
+	}
+	if outcome.negative() && r.cache != nil {
+		r.cache.remove(decisionID)
+	}
+	if r.store == nil {
+		return ErrDecisionNotFound
+	}
+	return r.store.RecordOutcome(ctx, userID, decisionID, outcome)
+}
+
+// Accuracy reports routing accuracy by source and agent type since a
+// time, for one user or, with an empty user id, for everyone
+func (r *Router) Accuracy(ctx context.Context, userID string, since time.Time) ([]Accuracy, error) {
+	if r.store == nil {
+		return []Accuracy{}, nil
+	}
+	return r.store.Accuracy(ctx, userID, since)
+}
+
+// fromCache returns the cached decision for a similar query, and the
+// query's embedding for caching a new decision
+func (r *Router) fromCache(ctx context.Context, request Request) (*Decision, []float64) {
+	if r.cache == nil || !r.modelEnabled() {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	resp, err := r.embedder.GenerateEmbedding(ctx, request.Query, r.embeddingProvider)
+	if err != nil || len(resp.Embedding) == 0 {
+		r.logger.Debug("Routing cache unavailable", slog.Any("error", err))
+		return nil, nil
+	}
+
+	cached, similarity := r.cache.lookup(resp.Embedding)
+	if cached == nil {
+		return nil, resp.Embedding
+	}
+	decision := &Decision{
+		AgentType:  cached.AgentType,
+		ChainType:  cached.ChainType,
+		Tools:      allowed(cached.Tools, request.Tools),
+		Confidence: cached.Confidence,
+		Reason:     cached.Reason,
+		Source:     SourceCache,
+		Model:      cached.Model,
+		CachedFrom: cached.ID,
+		Similarity: similarity,
+	}
+	return decision, nil
+}
+
+// classify asks the model for a decision, falling back to the heuristic
+func (r *Router) classify(ctx context.Context, request Request) *Decision {
+	if !r.modelEnabled() {
+		return Heuristic(request)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	decision, err := r.classifyWithModel(ctx, request)
+	if err != nil {
+		r.logger.Warn("Model routing failed, using keyword heuristic",
+			slog.String("provider", r.provider),
+			slog.String("model", r.config.Model),
+			slog.Any("error", err))
+		return Heuristic(request)
+	}
+	return decision
+}
+
+// modelEnabled reports whether requests are classified by the model
+func (r *Router) modelEnabled() bool {
+	return r.config.Enabled && r.classifier != nil
+}
+
+// record logs a decision as a learning event and sets its id
+func (r *Router) record(ctx context.Context, request Request, decision *Decision) {
+	if r.store == nil {
+		return
+	}
+	id, err := r.store.RecordDecision(context.WithoutCancel(ctx), request, decision)
+	if err != nil {
+		r.logger.Warn("Failed to record routing decision", slog.Any("error", err))
+		return
+	}
+	decision.ID = id
+	if decision.Source == SourceCache && r.cache != nil {
+		r.cache.served(decision.CachedFrom, id)
+	}
+}
+
+// allowed keeps the tools that are among the candidates
+func allowed(tools, candidates []string) []string {
+	result := make([]string, 0, len(tools))
+	for _, name := range tools {
+		if slices.Contains(candidates, name) && !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
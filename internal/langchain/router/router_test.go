@@ -0,0 +1,288 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/langchain/chain"
+)
+
+// scriptedClassifier replies with a fixed routing reply
+type scriptedClassifier struct {
+	reply string
+	err   error
+	calls int
+	model string
+}
+
+func (c *scriptedClassifier) GenerateResponse(ctx context.Context, request *ai.GenerateRequest, providerName string) (*ai.GenerateResponse, error) {
+	c.calls++
+	c.model = request.Model
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &ai.GenerateResponse{Content: c.reply, Model: request.Model}, nil
+}
+
+// vectorEmbedder embeds queries with fixed vectors
+type vectorEmbedder map[string][]float64
+
+func (e vectorEmbedder) GenerateEmbedding(ctx context.Context, text string, providerName string) (*ai.EmbeddingResponse, error) {
+	v, ok := e[text]
+	if !ok {
+		return nil, fmt.Errorf("no embedding for %q", text)
+	}
+	return &ai.EmbeddingResponse{Embedding: v}, nil
+}
+
+// memoryStore keeps decisions and outcomes by id
+type memoryStore struct {
+	decisions map[string]*Decision
+	outcomes  map[string]Outcome
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		decisions: make(map[string]*Decision),
+		outcomes:  make(map[string]Outcome),
+	}
+}
+
+func (s *memoryStore) RecordDecision(ctx context.Context, request Request, decision *Decision) (string, error) {
+	if request.UserID == "" {
+		return "", nil
+	}
+	id := fmt.Sprintf("d%d", len(s.decisions)+1)
+	s.decisions[id] = decision
+	return id, nil
+}
+
+func (s *memoryStore) RecordOutcome(ctx context.Context, userID, decisionID string, outcome Outcome) error {
+	if _, ok := s.decisions[decisionID]; !ok {
+		return ErrDecisionNotFound
+	}
+	s.outcomes[decisionID] = outcome
+	return nil
+}
+
+func (s *memoryStore) Accuracy(ctx context.Context, userID string, since time.Time) ([]Accuracy, error) {
+	return nil, nil
+}
+
+func testConfig() config.AIConfig {
+	return config.AIConfig{
+		DefaultProvider: "claude",
+		Embeddings:      config.Embedding{Provider: "gemini"},
+		Routing: config.Routing{
+			Enabled:         true,
+			Timeout:         time.Second,
+			CacheSize:       10,
+			CacheSimilarity: 0.9,
+			CacheTTL:        time.Hour,
+		},
+	}
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRouter_ModelDecision(t *testing.T) {
+	classifier := &scriptedClassifier{reply: "```json\n" +
+		`{"agent_type": "database", "chain_type": "sequential", "tools": ["postgres", "unknown"], "confidence": 0.9, "reason": "SQL tuning"}` +
+		"\n```"}
+	store := newMemoryStore()
+	router := New(testConfig(), classifier, nil, store, testLogger())
+
+	decision, err := router.Route(context.Background(), Request{
+		Query:  "Why is this query slow?",
+		UserID: "u1",
+		Tools:  []string{"postgres", "docker"},
+	})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	if decision.Source != SourceModel || decision.AgentType != agent.TypeDatabase {
+		t.Errorf("Route() = %s from %s, want database from model", decision.AgentType, decision.Source)
+	}
+	if !reflect.DeepEqual(decision.Tools, []string{"postgres"}) {
+		t.Errorf("Route() tools = %v, want only the candidate postgres", decision.Tools)
+	}
+	if decision.ID != "d1" {
+		t.Errorf("Route() id = %q, want the recorded d1", decision.ID)
+	}
+	if classifier.model != cheapModels["claude"] {
+		t.Errorf("classified with %q, want the cheap model %q", classifier.model, cheapModels["claude"])
+	}
+}
+
+func TestRouter_Fallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		classifier Classifier
+		enabled    bool
+	}{
+		{name: "model_error", classifier: &scriptedClassifier{err: errors.New("overloaded")}, enabled: true},
+		{name: "invalid_reply", classifier: &scriptedClassifier{reply: "I think database"}, enabled: true},
+		{name: "no_model", classifier: nil, enabled: true},
+		{name: "disabled", classifier: &scriptedClassifier{reply: `{"agent_type": "research"}`}, enabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Routing.Enabled = tt.enabled
+			router := New(cfg, tt.classifier, nil, nil, testLogger())
+
+			decision, err := router.Route(context.Background(), Request{
+				Query: "Deploy the api to kubernetes",
+				Tools: []string{"kubernetes", "docker"},
+			})
+			if err != nil {
+				t.Fatalf("Route() error = %v", err)
+			}
+			if decision.Source != SourceHeuristic || decision.AgentType != agent.TypeInfrastructure {
+				t.Errorf("Route() = %s from %s, want infrastructure from heuristic", decision.AgentType, decision.Source)
+			}
+			if !reflect.DeepEqual(decision.Tools, []string{"kubernetes"}) {
+				t.Errorf("Route() tools = %v, want [kubernetes]", decision.Tools)
+			}
+		})
+	}
+}
+
+func TestRouter_Cache(t *testing.T) {
+	classifier := &scriptedClassifier{reply: `{"agent_type": "development", "chain_type": "sequential", "tools": ["godev"], "confidence": 0.8}`}
+	embedder := vectorEmbedder{
+		"fix the failing test":     {1, 0, 0},
+		"fix the failing tests":    {0.99, 0.05, 0},
+		"what is the weather like": {0, 0, 1},
+	}
+	store := newMemoryStore()
+	router := New(testConfig(), classifier, embedder, store, testLogger())
+	ctx := context.Background()
+	route := func(query string) *Decision {
+		t.Helper()
+		decision, err := router.Route(ctx, Request{Query: query, UserID: "u1", Tools: []string{"godev"}})
+		if err != nil {
+			t.Fatalf("Route(%q) error = %v", query, err)
+		}
+		return decision
+	}
+
+	first := route("fix the failing test")
+	if first.Source != SourceModel {
+		t.Fatalf("first Route() source = %s, want model", first.Source)
+	}
+
+	similar := route("fix the failing tests")
+	if similar.Source != SourceCache || similar.CachedFrom != first.ID || similar.AgentType != agent.TypeDevelopment {
+		t.Errorf("similar Route() = %+v, want development cached from %s", similar, first.ID)
+	}
+	if classifier.calls != 1 {
+		t.Errorf("classifier called %d times, want 1", classifier.calls)
+	}
+
+	if other := route("what is the weather like"); other.Source != SourceModel {
+		t.Errorf("dissimilar Route() source = %s, want model", other.Source)
+	}
+
+	// Negative feedback on a cached decision evicts its cache entry
+	score := -1
+	if err := router.RecordOutcome(ctx, "u1", similar.ID, Outcome{Result: OutcomeFailure, Score: &score}); err != nil {
+		t.Fatalf("RecordOutcome() error = %v", err)
+	}
+	if store.outcomes[similar.ID].Result != OutcomeFailure {
+		t.Errorf("outcome = %+v, want failure recorded", store.outcomes[similar.ID])
+	}
+	if again := route("fix the failing tests"); again.Source != SourceModel {
+		t.Errorf("Route() after negative feedback source = %s, want model", again.Source)
+	}
+}
+
+func TestRouter_RecordOutcomeValidation(t *testing.T) {
+	router := New(testConfig(), nil, nil, newMemoryStore(), testLogger())
+	score := 2
+	for _, outcome := range []Outcome{{Result: "great"}, {Result: OutcomeSuccess, Score: &score}} {
+		if err := router.RecordOutcome(context.Background(), "u1", "d1", outcome); err == nil || errors.Is(err, ErrDecisionNotFound) {
+			t.Errorf("RecordOutcome(%+v) error = %v, want validation error", outcome, err)
+		}
+	}
+	if err := router.RecordOutcome(context.Background(), "u1", "missing", Outcome{Result: OutcomeSuccess}); !errors.Is(err, ErrDecisionNotFound) {
+		t.Errorf("RecordOutcome(missing) error = %v, want ErrDecisionNotFound", err)
+	}
+}
+
+func TestParseClassification(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    *Decision
+		wantErr bool
+	}{
+		{
+			name:  "plain_json",
+			reply: `{"agent_type": "Research", "chain_type": "rag", "tools": ["web_search"], "confidence": 0.7, "reason": "docs"}`,
+			want: &Decision{AgentType: agent.TypeResearch, ChainType: chain.ChainTypeRAG, Tools: []string{"web_search"},
+				Confidence: 0.7, Reason: "docs", Source: SourceModel},
+		},
+		{
+			name:  "unknown_chain_and_clamped_confidence",
+			reply: `Here you go: {"agent_type": "general", "chain_type": "magic", "tools": [], "confidence": 1.5}`,
+			want:  &Decision{AgentType: agent.TypeGeneral, ChainType: chain.ChainTypeSequential, Tools: []string{}, Confidence: 1, Source: SourceModel},
+		},
+		{name: "unknown_agent", reply: `{"agent_type": "finance"}`, wantErr: true},
+		{name: "no_json", reply: "development", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClassification(tt.reply, []string{"web_search", "postgres"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClassification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseClassification() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	candidates := []string{"godev", "docker", "kubernetes", "postgres", "web_search"}
+	tests := []struct {
+		query     string
+		wantAgent agent.AgentType
+		wantChain chain.ChainType
+		wantTools []string
+	}{
+		{"Write a function to parse JSON", agent.TypeDevelopment, chain.ChainTypeSequential, []string{"godev"}},
+		{"Create a SQL index for the users table", agent.TypeDatabase, chain.ChainTypeSequential, []string{"postgres"}},
+		{"Compare docker and kubernetes deployment", agent.TypeInfrastructure, chain.ChainTypeParallel, []string{"docker", "kubernetes"}},
+		{"Summarize the documentation in our knowledge base", agent.TypeResearch, chain.ChainTypeRAG, []string{}},
+		{"What is the weather today?", agent.TypeGeneral, chain.ChainTypeSequential, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := Heuristic(Request{Query: tt.query, Tools: candidates})
+			if got.AgentType != tt.wantAgent || got.ChainType != tt.wantChain || !reflect.DeepEqual(got.Tools, tt.wantTools) {
+				t.Errorf("Heuristic() = %s/%s/%v, want %s/%s/%v",
+					got.AgentType, got.ChainType, got.Tools, tt.wantAgent, tt.wantChain, tt.wantTools)
+			}
+			if got.Source != SourceHeuristic {
+				t.Errorf("Heuristic() source = %s, want heuristic", got.Source)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// EventType is the learning event type of routing decisions
+const EventType = "routing_decision"
+
+// QueriesStore implements Store on top of the learning_events table
+type QueriesStore struct {
+	queries *sqlc.Queries
+}
+
+// NewQueriesStore creates a decision store backed by sqlc queries
+func NewQueriesStore(queries *sqlc.Queries) *QueriesStore {
+	return &QueriesStore{
+		queries: queries,
+	}
+}
+
+// RecordDecision records a decision as a learning event and returns its
+// id. learning_events rows belong to a user, so decisions without a user
+// id are not recorded and get an empty id.
+func (s *QueriesStore) RecordDecision(ctx context.Context, request Request, decision *Decision) (string, error) {
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		return "", nil
+	}
+
+	eventContext, err := json.Marshal(map[string]interface{}{
+		"conversation_id": request.ConversationID,
+		"candidate_tools": request.Tools,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode context: %w", err)
+	}
+	input, err := json.Marshal(map[string]interface{}{
+		"query": request.Query,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode input: %w", err)
+	}
+	output, err := json.Marshal(map[string]interface{}{
+		"agent_type": decision.AgentType,
+		"chain_type": decision.ChainType,
+		"tools":      decision.Tools,
+		"reason":     decision.Reason,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode output: %w", err)
+	}
+	metadata, err := json.Marshal(map[string]interface{}{
+		"source":      decision.Source,
+		"model":       decision.Model,
+		"cached_from": decision.CachedFrom,
+		"similarity":  decision.Similarity,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	event, err := s.queries.CreateLearningEvent(ctx, sqlc.CreateLearningEventParams{
+		Column1:          pgtype.UUID{Bytes: userID, Valid: true},
+		EventType:        EventType,
+		Context:          eventContext,
+		InputData:        input,
+		OutputData:       output,
+		Confidence:       pgtype.Float8{Float64: decision.Confidence, Valid: true},
+		LearningMetadata: metadata,
+		DurationMs:       pgtype.Int4{Int32: int32(decision.Duration.Milliseconds()), Valid: true},
+		SessionID:        pgtype.Text{String: request.ConversationID, Valid: request.ConversationID != ""},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to record routing decision: %w", err)
+	}
+	return uuid.UUID(event.ID.Bytes).String(), nil
+}
+
+// RecordOutcome stores the outcome of a decision of the user
+func (s *QueriesStore) RecordOutcome(ctx context.Context, userID, decisionID string, outcome Outcome) error {
+	user, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrDecisionNotFound
+	}
+	id, err := uuid.Parse(decisionID)
+	if err != nil {
+		return ErrDecisionNotFound
+	}
+
+	var score pgtype.Int4
+	if outcome.Score != nil {
+		score = pgtype.Int4{Int32: int32(*outcome.Score), Valid: true}
+	}
+	metadata, err := json.Marshal(map[string]interface{}{
+		"feedback_comment": outcome.Comment,
+		"feedback_at":      time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	_, err = s.queries.UpdateLearningEventOutcome(ctx, sqlc.UpdateLearningEventOutcomeParams{
+		ID:               pgtype.UUID{Bytes: id, Valid: true},
+		Outcome:          pgtype.Text{String: outcome.Result, Valid: true},
+		FeedbackScore:    score,
+		LearningMetadata: metadata,
+		Column5:          pgtype.UUID{Bytes: user, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDecisionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record routing outcome: %w", err)
+	}
+	return nil
+}
+
+// Accuracy reports routing accuracy by source and agent type
+func (s *QueriesStore) Accuracy(ctx context.Context, userID string, since time.Time) ([]Accuracy, error) {
+	var user pgtype.UUID
+	if userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id: %w", err)
+		}
+		user = pgtype.UUID{Bytes: id, Valid: true}
+	}
+
+	rows, err := s.queries.GetRoutingAccuracy(ctx, sqlc.GetRoutingAccuracyParams{
+		CreatedAt: since,
+		Column2:   user,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routing accuracy: %w", err)
+	}
+
+	result := make([]Accuracy, 0, len(rows))
+	for _, row := range rows {
+		accuracy := Accuracy{
+			Source:           Source(row.Source),
+			AgentType:        agent.AgentType(row.AgentType),
+			Decisions:        row.DecisionCount,
+			Rated:            row.RatedCount,
+			Successes:        row.SuccessCount,
+			Failures:         row.FailureCount,
+			AvgConfidence:    row.AvgConfidence,
+			AvgFeedbackScore: row.AvgFeedbackScore,
+		}
+		if judged := row.SuccessCount + row.FailureCount; judged > 0 {
+			accuracy.Accuracy = float64(row.SuccessCount) / float64(judged)
+		}
+		result = append(result, accuracy)
+	}
+	return result, nil
+}
@@ -5,22 +5,45 @@ package langchain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/koopa0/assistant-go/internal/langchain/agent"
+	"github.com/koopa0/assistant-go/internal/langchain/chain"
+	"github.com/koopa0/assistant-go/internal/langchain/collection"
+	"github.com/koopa0/assistant-go/internal/langchain/router"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+	"github.com/koopa0/assistant-go/internal/tool"
+	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/llms"
 )
 
 // Service provides LangChain integration services
 type Service struct {
-	client  *Client
-	logger  *slog.Logger
-	queries *sqlc.Queries
-	manager *agent.Manager
+	client      *Client
+	logger      *slog.Logger
+	queries     *sqlc.Queries
+	manager     *agent.Manager
+	router      *router.Router
+	tools       *tool.Registry
+	adapters    *tool.ToolRegistry
+	settings    *tool.SettingsResolver
+	toolResults tool.ResultHook
+	embedder    embeddings.Embedder
+	collections *collection.Manager
 }
 
+// ErrConversationNotFound reports a conversation that does not exist or
+// belongs to someone else
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// AutoAgent is the agent type that lets the router choose the agent
+const AutoAgent agent.AgentType = "auto"
+
 // NewService creates a new LangChain service
 func NewService(client *Client, logger *slog.Logger, queries *sqlc.Queries) *Service {
 	var llm llms.Model
@@ -34,10 +57,67 @@ func NewService(client *Client, logger *slog.Logger, queries *sqlc.Queries) *Ser
 		queries: queries,
 		manager: agent.NewManager(llm, logger),
 	}
+	service.manager.SetRuntime(service.runtime(nil))
 
 	return service
 }
 
+// UseTools lets agents call the tools of registry
+func (s *Service) UseTools(registry *tool.Registry) {
+	s.tools = registry
+	s.adapters = tool.NewToolRegistry(registry, s.logger)
+	s.adapters.OnResult(s.toolResults)
+	s.manager.SetRuntime(s.runtime(s.adapters))
+}
+
+// UseToolSettings limits and configures the tools agents call with the
+// settings of the caller's roles, user and conversation
+func (s *Service) UseToolSettings(settings *tool.SettingsResolver) {
+	s.settings = settings
+}
+
+// UseToolResults calls hook with the result of every tool call of agents
+func (s *Service) UseToolResults(hook tool.ResultHook) {
+	s.toolResults = hook
+	if s.adapters != nil {
+		s.adapters.OnResult(hook)
+	}
+}
+
+// UseRouter routes requests for the auto agent with r, which also becomes
+// the agent manager's selector
+func (s *Service) UseRouter(r *router.Router) {
+	s.router = r
+	s.manager.SetSelector(r)
+}
+
+// UseEmbedder embeds RAG queries with embedder. Without one, RAG queries
+// find documents by full text only.
+func (s *Service) UseEmbedder(embedder embeddings.Embedder) {
+	s.embedder = embedder
+}
+
+// UseCollections retrieves RAG context from the collections the caller
+// may read, managed by manager
+func (s *Service) UseCollections(manager *collection.Manager) {
+	s.collections = manager
+}
+
+// runtime returns the agent runtime: the given tools, executions
+// recorded in agent_executions and the configured step budget
+func (s *Service) runtime(tools agent.ToolProvider) agent.Runtime {
+	runtime := agent.Runtime{
+		Tools: tools,
+	}
+	if s.queries != nil {
+		runtime.Recorder = agent.NewQueriesRecorder(s.queries)
+	}
+	if s.client != nil {
+		runtime.MaxIterations = s.client.config.MaxIterations
+	}
+	return runtime
+}
+
 // ExecutePrompt executes a simple prompt
 func (s *Service) ExecutePrompt(ctx context.Context, prompt string) (string, error) {
 	if s.client == nil || s.client.llm == nil {
@@ -58,9 +138,174 @@ func (s *Service) ExecuteAgent(ctx context.Context, agentType agent.AgentType, r
 		return nil, fmt.Errorf("agent manager not initialized")
 	}
 
+	if err := s.scopeTools(ctx, request); err != nil {
+		return nil, err
+	}
 	return s.manager.ExecuteWithAgent(ctx, agentType, request)
 }
 
+// scopeTools resolves the tool policy of the caller for an agent request
+// and drops the requested tools it disables. The authenticated user, when
+// there is one, is who the tools run for.
+func (s *Service) scopeTools(ctx context.Context, request *agent.Request) error {
+	caller := collection.CallerFromContext(ctx)
+	if caller.UserID != "" {
+		request.UserID = caller.UserID
+	}
+	if s.settings == nil {
+		return nil
+	}
+
+	policy, err := s.settings.Resolve(ctx, tool.Subject{
+		UserID:         request.UserID,
+		Roles:          caller.Teams,
+		ConversationID: request.ConversationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resolve tool settings: %w", err)
+	}
+	request.Policy = policy
+	request.Tools = enabledTools(policy, request.Tools)
+	return nil
+}
+
+// enabledTools returns the names of the tools policy enables
+func enabledTools(policy *tool.Policy, names []string) []string {
+	enabled := make([]string, 0, len(names))
+	for _, name := range names {
+		if policy.Enabled(name) {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled
+}
+
+// Route decides the agent, chain and tools for a request, choosing among
+// the tools the request's policy enables. Without a router the keyword
+// heuristic decides.
+func (s *Service) Route(ctx context.Context, request *agent.Request) (*router.Decision, error) {
+	routeRequest := router.Request{
+		Query:          request.Query,
+		UserID:         request.UserID,
+		ConversationID: request.ConversationID,
+		Tools:          request.Tools,
+	}
+	if len(routeRequest.Tools) == 0 && s.tools != nil {
+		for _, info := range request.Policy.Filter(s.tools.ListTools()) {
+			routeRequest.Tools = append(routeRequest.Tools, info.Name)
+		}
+	}
+
+	if s.router == nil {
+		return router.Heuristic(routeRequest), nil
+	}
+	return s.router.Route(ctx, routeRequest)
+}
+
+// ExecuteRouted routes a request and executes it with the chosen agent
+// and tools. The execution's success is recorded as the outcome of the
+// routing decision.
+func (s *Service) ExecuteRouted(ctx context.Context, request *agent.Request) (*agent.Response, *router.Decision, error) {
+	if s.manager == nil {
+		return nil, nil, fmt.Errorf("agent manager not initialized")
+	}
+
+	if err := s.scopeTools(ctx, request); err != nil {
+		return nil, nil, err
+	}
+	decision, err := s.Route(ctx, request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to route request: %w", err)
+	}
+	if len(request.Tools) == 0 {
+		request.Tools = enabledTools(request.Policy, decision.Tools)
+	}
+
+	response, err := s.manager.ExecuteWithAgent(ctx, decision.AgentType, request)
+
+	outcome := router.Outcome{Result: router.OutcomeFailure}
+	if err == nil && response.Success {
+		outcome.Result = router.OutcomeSuccess
+	}
+	if decision.ID != "" && s.router != nil {
+		if recordErr := s.router.RecordOutcome(context.WithoutCancel(ctx), request.UserID, decision.ID, outcome); recordErr != nil {
+			s.logger.Warn("Failed to record routing outcome",
+				slog.String("decision_id", decision.ID),
+				slog.Any("error", recordErr))
+		}
+	}
+
+	return response, decision, err
+}
+
+// QueryRAG answers a query from the knowledge base: the named collections,
+// or every collection the caller may read when none are named. A follow-up
+// question is rewritten with the conversation before it, history, into a
+// standalone query. The answer cites the retrieved chunks inline; the
+// citations are verified and returned with the chunks' sources and
+// locations.
+func (s *Service) QueryRAG(ctx context.Context, query string, collections []string, history []chain.Turn) (*chain.RAGQueryResult, error) {
+	if s.client == nil || s.client.llm == nil {
+		return nil, fmt.Errorf("LLM client not initialized")
+	}
+
+	ragChain := chain.NewRAGChain(s.client.llm, s.client.config, s.logger)
+	if s.queries != nil {
+		ragChain.SetQueries(s.queries)
+	}
+	if s.embedder != nil {
+		ragChain.SetEmbedder(s.embedder)
+	}
+	if s.collections != nil {
+		ragChain.SetDocumentSearcher(s.collections.Searcher(collection.CallerFromContext(ctx), collections))
+	} else if len(collections) > 0 {
+		return nil, fmt.Errorf("collections are not available")
+	}
+
+	return ragChain.Query(ctx, query, history)
+}
+
+// ConversationHistory returns the last messages of a conversation of the
+// caller, oldest first, for rewriting follow-up questions
+func (s *Service) ConversationHistory(ctx context.Context, conversationID string) ([]chain.Turn, error) {
+	if s.queries == nil {
+		return nil, fmt.Errorf("conversations are not available")
+	}
+	id, err := postgres.ParseUUID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid conversation id", ErrConversationNotFound)
+	}
+	conversation, err := s.queries.GetConversation(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if caller := collection.CallerFromContext(ctx); caller.UserID != postgres.UUIDToString(conversation.UserID) {
+		return nil, ErrConversationNotFound
+	}
+
+	turns := 6
+	if s.client != nil && s.client.config.Rewriting.HistoryTurns > 0 {
+		turns = s.client.config.Rewriting.HistoryTurns
+	}
+	messages, err := s.queries.GetRecentMessages(ctx, sqlc.GetRecentMessagesParams{
+		ConversationID: id,
+		Limit:          int32(turns),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	history := make([]chain.Turn, 0, len(messages))
+	for _, message := range slices.Backward(messages) {
+		if message.Role == "user" || message.Role == "assistant" {
+			history = append(history, chain.Turn{Role: message.Role, Content: message.Content})
+		}
+	}
+	return history, nil
+}
+
 // GetAgentTypes returns available agent types
 func (s *Service) GetAgentTypes() []agent.AgentType {
 	if s.manager == nil {
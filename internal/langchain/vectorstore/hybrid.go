@@ -0,0 +1,411 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// Search modes
+const (
+	ModeVector  = "vector"  // pgvector similarity only
+	ModeKeyword = "keyword" // full-text only
+	ModeHybrid  = "hybrid"  // both, fused by reciprocal rank
+)
+
+// Defaults of SearchOptions
+const (
+	defaultCandidates = 4
+	defaultRRFK       = 60
+)
+
+// Filter restricts a search by collection, content type and metadata. Pass it to
+// SimilaritySearch with vectorstores.WithFilters.
+type Filter struct {
+	ContentTypes []string `json:"content_types,omitempty"`
+	PathPrefix   string   `json:"path_prefix,omitempty"` // matches metadata.path
+	Tags         []string `json:"tags,omitempty"`        // any of metadata.tags
+	Collections  []string `json:"collections,omitempty"` // collection ids
+}
+
+// SearchOptions configure hybrid search
+type SearchOptions struct {
+	Mode       string  // vector, keyword or hybrid
+	Candidates int     // candidates fetched per result for fusion, reranking and MMR
+	RRFK       int     // reciprocal rank fusion constant
+	MMRLambda  float64 // relevance weight of MMR; 0 disables MMR
+	Reranker   Reranker
+}
+
+// SearchOptionsFromConfig creates search options from retrieval
+// configuration. The reranker is set separately, as the LLM reranker
+// needs a model.
+func SearchOptionsFromConfig(cfg config.Retrieval) SearchOptions {
+	return SearchOptions{
+		Mode:       cfg.Mode,
+		Candidates: cfg.Candidates,
+		RRFK:       cfg.RRFK,
+		MMRLambda:  cfg.MMRLambda,
+	}
+}
+
+// SearchResult is a chunk found by a search
+type SearchResult struct {
+	ID          string         `json:"id"`
+	ContentType string         `json:"content_type"`
+	ContentID   string         `json:"content_id"`
+	Content     string         `json:"content"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Similarity  float64        `json:"similarity"`             // cosine similarity to the query
+	KeywordRank float64        `json:"keyword_rank,omitempty"` // full-text rank, 0 when the text did not match
+	Score       float64        `json:"score"`                  // fused score, or the reranker's
+	Reranked    bool           `json:"reranked,omitempty"`
+
+	embedding []float32
+}
+
+// SearchQueries are the queries hybrid search runs
+type SearchQueries interface {
+	SearchEmbeddingsByVector(ctx context.Context, arg sqlc.SearchEmbeddingsByVectorParams) ([]*sqlc.SearchEmbeddingsByVectorRow, error)
+	SearchEmbeddingsByText(ctx context.Context, arg sqlc.SearchEmbeddingsByTextParams) ([]*sqlc.SearchEmbeddingsByTextRow, error)
+}
+
+// Searcher finds stored chunks by full-text and vector similarity. Exact
+// identifiers and error strings rank high in full-text search even when
+// their embeddings are not close to the query's.
+type Searcher struct {
+	queries SearchQueries
+	options SearchOptions
+	logger  *slog.Logger
+}
+
+// NewSearcher creates a searcher; zero options take the defaults
+func NewSearcher(queries SearchQueries, options SearchOptions, logger *slog.Logger) *Searcher {
+	if options.Mode == "" {
+		options.Mode = ModeHybrid
+	}
+	if options.Candidates <= 0 {
+		options.Candidates = defaultCandidates
+	}
+	if options.RRFK <= 0 {
+		options.RRFK = defaultRRFK
+	}
+
+	return &Searcher{
+		queries: queries,
+		options: options,
+		logger:  logger,
+	}
+}
+
+// Search returns up to limit chunks for a query. Vector search needs the
+// query's embedding and only returns chunks above threshold; without an
+// embedding a hybrid search falls back to full text.
+func (s *Searcher) Search(ctx context.Context, query string, queryEmbedding []float32, limit int, filter Filter, threshold float64) ([]SearchResult, error) {
+	if limit <= 0 {
+		return []SearchResult{}, nil
+	}
+	useVector := s.options.Mode != ModeKeyword && len(queryEmbedding) > 0
+	useKeyword := s.options.Mode != ModeVector
+	if !useVector && !useKeyword {
+		return nil, fmt.Errorf("vector search needs a query embedding")
+	}
+
+	candidates := limit
+	if useVector && useKeyword || s.options.Reranker != nil || s.options.MMRLambda > 0 {
+		candidates = limit * s.options.Candidates
+	}
+	pathPrefix := escapeLike(filter.PathPrefix)
+	contentTypes := nonNil(filter.ContentTypes)
+	tags := nonNil(filter.Tags)
+	collections := make([]pgtype.UUID, 0, len(filter.Collections))
+	for _, id := range filter.Collections {
+		collection, err := postgres.ParseUUID(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collection id %q: %w", id, err)
+		}
+		collections = append(collections, collection)
+	}
+
+	var rankings [][]*SearchResult
+	if useVector {
+		rows, err := s.queries.SearchEmbeddingsByVector(ctx, sqlc.SearchEmbeddingsByVectorParams{
+			QueryEmbedding: pgvector.NewVector(queryEmbedding),
+			ContentTypes:   contentTypes,
+			PathPrefix:     pathPrefix,
+			Tags:           tags,
+			CollectionIds:  collections,
+			Threshold:      threshold,
+			ResultLimit:    int32(candidates),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search by vector: %w", err)
+		}
+		ranking := make([]*SearchResult, 0, len(rows))
+		for _, row := range rows {
+			r := newSearchResult(row.ID, row.ContentID, row.ContentType, row.ContentText, row.Embedding, row.Metadata)
+			r.Similarity = row.Similarity
+			ranking = append(ranking, r)
+		}
+		rankings = append(rankings, ranking)
+	}
+	if useKeyword {
+		if text := keywordQuery(query); text != "" {
+			rows, err := s.queries.SearchEmbeddingsByText(ctx, sqlc.SearchEmbeddingsByTextParams{
+				Query:         text,
+				ContentTypes:  contentTypes,
+				PathPrefix:    pathPrefix,
+				Tags:          tags,
+				CollectionIds: collections,
+				ResultLimit:   int32(candidates),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to search by text: %w", err)
+			}
+			ranking := make([]*SearchResult, 0, len(rows))
+			for _, row := range rows {
+				r := newSearchResult(row.ID, row.ContentID, row.ContentType, row.ContentText, row.Embedding, row.Metadata)
+				r.KeywordRank = row.Rank
+				r.Similarity = cosine(queryEmbedding, r.embedding)
+				ranking = append(ranking, r)
+			}
+			rankings = append(rankings, ranking)
+		}
+	}
+
+	results := fuse(rankings, s.options.RRFK)
+	if s.options.Reranker != nil && len(results) > 1 {
+		s.rerank(ctx, query, results)
+	}
+	if s.options.MMRLambda > 0 {
+		results = mmr(results, limit, s.options.MMRLambda)
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	s.logger.Debug("Hybrid search completed",
+		slog.String("mode", s.options.Mode),
+		slog.Bool("vector", useVector),
+		slog.Int("results", len(results)))
+	return results, nil
+}
+
+// rerank orders results by the reranker's scores. Results keep their
+// fused order when the reranker fails.
+func (s *Searcher) rerank(ctx context.Context, query string, results []SearchResult) {
+	documents := make([]string, len(results))
+	for i, r := range results {
+		documents[i] = r.Content
+	}
+	scores, err := s.options.Reranker.Rerank(ctx, query, documents)
+	if err == nil && len(scores) != len(results) {
+		err = fmt.Errorf("reranker returned %d scores for %d documents", len(scores), len(results))
+	}
+	if err != nil {
+		s.logger.Warn("Reranking failed, keeping fused order", slog.Any("error", err))
+		return
+	}
+
+	for i := range results {
+		results[i].Score = scores[i]
+		results[i].Reranked = true
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		return compareDesc(a.Score, b.Score)
+	})
+}
+
+// fuse merges rankings by reciprocal rank: a result scores 1/(k+rank) for
+// each ranking it appears in, so results found by both searches rise
+func fuse(rankings [][]*SearchResult, k int) []SearchResult {
+	byID := make(map[string]*SearchResult)
+	var order []string
+	for _, ranking := range rankings {
+		for rank, r := range ranking {
+			merged, ok := byID[r.ID]
+			if !ok {
+				merged = r
+				byID[r.ID] = merged
+				order = append(order, r.ID)
+			} else {
+				// Vector search, ranked first, reports the similarity
+				if merged.Similarity == 0 {
+					merged.Similarity = r.Similarity
+				}
+				merged.KeywordRank = max(merged.KeywordRank, r.KeywordRank)
+			}
+			merged.Score += 1 / float64(k+rank+1)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *byID[id])
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		return compareDesc(a.Score, b.Score)
+	})
+	return results
+}
+
+// mmr picks up to limit results by maximal marginal relevance: each pick
+// maximizes lambda*relevance - (1-lambda)*similarity to the results picked
+// so far. Relevance is the score scaled to [0, 1] among the candidates.
+func mmr(results []SearchResult, limit int, lambda float64) []SearchResult {
+	if len(results) <= 1 {
+		return results
+	}
+	low, high := results[0].Score, results[0].Score
+	for _, r := range results {
+		low, high = min(low, r.Score), max(high, r.Score)
+	}
+	relevance := func(r SearchResult) float64 {
+		if high == low {
+			return 1
+		}
+		return (r.Score - low) / (high - low)
+	}
+
+	remaining := slices.Clone(results)
+	picked := make([]SearchResult, 0, min(limit, len(results)))
+	for len(picked) < limit && len(remaining) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for i, candidate := range remaining {
+			redundancy := 0.0
+			for _, p := range picked {
+				redundancy = max(redundancy, cosine(candidate.embedding, p.embedding))
+			}
+			if score := lambda*relevance(candidate) - (1-lambda)*redundancy; score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked = append(picked, remaining[best])
+		remaining = slices.Delete(remaining, best, best+1)
+	}
+	return picked
+}
+
+// FilterFromOptions reads a Filter from vectorstores options. Filters may
+// be a Filter or a map with content_type(s), path_prefix and tags keys.
+func FilterFromOptions(opts vectorstores.Options) Filter {
+	switch f := opts.Filters.(type) {
+	case Filter:
+		return f
+	case *Filter:
+		if f != nil {
+			return *f
+		}
+	case map[string]any:
+		var filter Filter
+		if contentType, ok := f["content_type"].(string); ok && contentType != "" {
+			filter.ContentTypes = []string{contentType}
+		}
+		filter.ContentTypes = append(filter.ContentTypes, stringList(f["content_types"])...)
+		filter.PathPrefix, _ = f["path_prefix"].(string)
+		filter.Tags = stringList(f["tags"])
+		filter.Collections = stringList(f["collections"])
+		return filter
+	}
+	return Filter{}
+}
+
+// newSearchResult converts a row's common columns
+func newSearchResult(id, contentID pgtype.UUID, contentType string, content string, embedding pgvector.Vector, metadata json.RawMessage) *SearchResult {
+	r := &SearchResult{
+		ID:          postgres.UUIDToString(id),
+		ContentType: contentType,
+		ContentID:   postgres.UUIDToString(contentID),
+		Content:     content,
+		embedding:   embedding.Slice(),
+	}
+	if len(metadata) > 0 {
+		_ = json.Unmarshal(metadata, &r.Metadata)
+	}
+	return r
+}
+
+// keywordQuery turns a query into websearch_to_tsquery syntax matching any
+// of its words; the full-text rank favors chunks matching more of them
+func keywordQuery(query string) string {
+	var words []string
+	for _, word := range strings.Fields(query) {
+		word = strings.Trim(word, `"'-,;:!?()[]{}`)
+		if word == "" || strings.EqualFold(word, "or") {
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " or ")
+}
+
+// escapeLike escapes LIKE wildcards in a path prefix
+func escapeLike(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+}
+
+// stringList reads a list of strings from a decoded JSON or Go value
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// nonNil returns an empty slice for nil, which encodes as an empty array
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 when either is
+// empty or their dimensions differ
+func cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// compareDesc orders float64 values from high to low
+func compareDesc(a, b float64) int {
+	switch {
+	case a > b:
+		return -1
+	case a < b:
+		return 1
+	}
+	return 0
+}
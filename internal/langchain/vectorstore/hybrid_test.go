@@ -0,0 +1,254 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// fakeQueries returns fixed rankings and records the parameters it got
+type fakeQueries struct {
+	vector []*sqlc.SearchEmbeddingsByVectorRow
+	text   []*sqlc.SearchEmbeddingsByTextRow
+
+	vectorParams *sqlc.SearchEmbeddingsByVectorParams
+	textParams   *sqlc.SearchEmbeddingsByTextParams
+}
+
+func (q *fakeQueries) SearchEmbeddingsByVector(ctx context.Context, arg sqlc.SearchEmbeddingsByVectorParams) ([]*sqlc.SearchEmbeddingsByVectorRow, error) {
+	q.vectorParams = &arg
+	return q.vector, nil
+}
+
+func (q *fakeQueries) SearchEmbeddingsByText(ctx context.Context, arg sqlc.SearchEmbeddingsByTextParams) ([]*sqlc.SearchEmbeddingsByTextRow, error) {
+	q.textParams = &arg
+	return q.text, nil
+}
+
+func testID(n byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{15: n}, Valid: true}
+}
+
+func vectorRow(n byte, similarity float64, embedding ...float32) *sqlc.SearchEmbeddingsByVectorRow {
+	return &sqlc.SearchEmbeddingsByVectorRow{
+		ID:          testID(n),
+		ContentType: "code",
+		ContentID:   testID(n),
+		ContentText: string('a' + rune(n)),
+		Embedding:   pgvector.NewVector(embedding),
+		Metadata:    json.RawMessage(`{"path":"internal/x.go"}`),
+		Similarity:  similarity,
+	}
+}
+
+func textRow(n byte, rank float64, embedding ...float32) *sqlc.SearchEmbeddingsByTextRow {
+	return &sqlc.SearchEmbeddingsByTextRow{
+		ID:          testID(n),
+		ContentType: "code",
+		ContentID:   testID(n),
+		ContentText: string('a' + rune(n)),
+		Embedding:   pgvector.NewVector(embedding),
+		Rank:        rank,
+	}
+}
+
+func contents(results []SearchResult) []string {
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Content)
+	}
+	return got
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestSearcher_HybridFusion(t *testing.T) {
+	// b is second in both rankings and beats the leaders of each
+	queries := &fakeQueries{
+		vector: []*sqlc.SearchEmbeddingsByVectorRow{
+			vectorRow(1, 0.9, 1, 0),
+			vectorRow(2, 0.8, 1, 0),
+			vectorRow(3, 0.75, 1, 0),
+		},
+		text: []*sqlc.SearchEmbeddingsByTextRow{
+			textRow(4, 0.5, 0, 1),
+			textRow(2, 0.3, 1, 0),
+		},
+	}
+	searcher := NewSearcher(queries, SearchOptions{}, discard)
+
+	results, err := searcher.Search(context.Background(), `"ErrNotFound" handling`, []float32{1, 0}, 3,
+		Filter{PathPrefix: "internal/my_pkg"}, 0.7)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got, want := contents(results), []string{"c", "b", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search() = %v, want %v", got, want)
+	}
+	if results[0].KeywordRank != 0.3 || results[0].Similarity != 0.8 {
+		t.Errorf("fused result = %+v, want the keyword rank and similarity of both searches", results[0])
+	}
+	// Keyword-only hits get their similarity from the stored embedding
+	if results[2].Similarity != 0 {
+		t.Errorf("keyword-only similarity = %v, want 0 for an orthogonal embedding", results[2].Similarity)
+	}
+
+	if queries.vectorParams.ResultLimit != 12 || queries.textParams.ResultLimit != 12 {
+		t.Errorf("candidates = %d/%d, want 12", queries.vectorParams.ResultLimit, queries.textParams.ResultLimit)
+	}
+	if queries.textParams.Query != "ErrNotFound or handling" {
+		t.Errorf("text query = %q, want the words joined by or", queries.textParams.Query)
+	}
+	if queries.vectorParams.PathPrefix != `internal/my\_pkg` {
+		t.Errorf("path prefix = %q, want LIKE wildcards escaped", queries.vectorParams.PathPrefix)
+	}
+	if queries.vectorParams.ContentTypes == nil || queries.textParams.Tags == nil || queries.textParams.CollectionIds == nil {
+		t.Error("unset filters were passed as nil, want empty arrays")
+	}
+}
+
+func TestSearcher_Modes(t *testing.T) {
+	queries := &fakeQueries{
+		vector: []*sqlc.SearchEmbeddingsByVectorRow{vectorRow(1, 0.9, 1, 0)},
+		text:   []*sqlc.SearchEmbeddingsByTextRow{textRow(2, 0.5, 1, 0)},
+	}
+
+	results, err := NewSearcher(queries, SearchOptions{Mode: ModeKeyword}, discard).
+		Search(context.Background(), "query", []float32{1, 0}, 5, Filter{}, 0.7)
+	if err != nil || queries.vectorParams != nil || !reflect.DeepEqual(contents(results), []string{"c"}) {
+		t.Errorf("keyword Search() = %v, %v; want only full-text results", contents(results), err)
+	}
+
+	queries.textParams = nil
+	results, err = NewSearcher(queries, SearchOptions{Mode: ModeVector}, discard).
+		Search(context.Background(), "query", []float32{1, 0}, 5, Filter{}, 0.7)
+	if err != nil || queries.textParams != nil || !reflect.DeepEqual(contents(results), []string{"b"}) {
+		t.Errorf("vector Search() = %v, %v; want only vector results", contents(results), err)
+	}
+
+	// Hybrid search without an embedding falls back to full text
+	queries.vectorParams = nil
+	results, err = NewSearcher(queries, SearchOptions{}, discard).
+		Search(context.Background(), "query", nil, 5, Filter{}, 0.7)
+	if err != nil || queries.vectorParams != nil || len(results) != 1 {
+		t.Errorf("hybrid Search() without embedding = %v, %v; want full-text results", contents(results), err)
+	}
+
+	if _, err := NewSearcher(queries, SearchOptions{Mode: ModeVector}, discard).
+		Search(context.Background(), "query", nil, 5, Filter{}, 0.7); err == nil {
+		t.Error("vector Search() without embedding succeeded, want an error")
+	}
+}
+
+// fixedReranker returns fixed scores or an error
+type fixedReranker struct {
+	scores []float64
+	err    error
+}
+
+func (r *fixedReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	return r.scores, r.err
+}
+
+func TestSearcher_Rerank(t *testing.T) {
+	queries := &fakeQueries{
+		vector: []*sqlc.SearchEmbeddingsByVectorRow{
+			vectorRow(1, 0.9, 1, 0),
+			vectorRow(2, 0.8, 1, 0),
+			vectorRow(3, 0.7, 1, 0),
+		},
+	}
+
+	reranker := &fixedReranker{scores: []float64{1, 9, 5}}
+	results, err := NewSearcher(queries, SearchOptions{Mode: ModeVector, Reranker: reranker}, discard).
+		Search(context.Background(), "query", []float32{1, 0}, 2, Filter{}, 0.7)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got, want := contents(results), []string{"c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reranked Search() = %v, want %v", got, want)
+	}
+	if !results[0].Reranked || results[0].Score != 9 {
+		t.Errorf("reranked result = %+v, want the reranker's score", results[0])
+	}
+
+	// A failing reranker keeps the fused order
+	reranker = &fixedReranker{err: errors.New("unavailable")}
+	results, err = NewSearcher(queries, SearchOptions{Mode: ModeVector, Reranker: reranker}, discard).
+		Search(context.Background(), "query", []float32{1, 0}, 2, Filter{}, 0.7)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got, want := contents(results), []string{"b", "c"}; !reflect.DeepEqual(got, want) || results[0].Reranked {
+		t.Errorf("Search() with failing reranker = %v, want fused order %v", got, want)
+	}
+}
+
+func TestSearcher_MMR(t *testing.T) {
+	// b and c are near duplicates; MMR picks d over c
+	queries := &fakeQueries{
+		vector: []*sqlc.SearchEmbeddingsByVectorRow{
+			vectorRow(1, 0.9, 1, 0),
+			vectorRow(2, 0.89, 1, 0.01),
+			vectorRow(3, 0.8, 0, 1),
+		},
+	}
+
+	results, err := NewSearcher(queries, SearchOptions{Mode: ModeVector, MMRLambda: 0.5}, discard).
+		Search(context.Background(), "query", []float32{1, 0}, 2, Filter{}, 0.7)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got, want := contents(results), []string{"b", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MMR Search() = %v, want %v", got, want)
+	}
+}
+
+func TestFilterFromOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters any
+		want    Filter
+	}{
+		{"none", nil, Filter{}},
+		{"filter", Filter{PathPrefix: "cmd/"}, Filter{PathPrefix: "cmd/"}},
+		{"pointer", &Filter{Tags: []string{"go"}}, Filter{Tags: []string{"go"}}},
+		{
+			"map",
+			map[string]any{
+				"content_type":         "code",
+				"content_types":        []any{"document"},
+				"path_prefix":          "internal/",
+				"tags":                 []string{"api"},
+				"collections":          []any{"00000000-0000-0000-0000-000000000001"},
+				"similarity_threshold": 0.5,
+			},
+			Filter{
+				ContentTypes: []string{"code", "document"},
+				PathPrefix:   "internal/",
+				Tags:         []string{"api"},
+				Collections:  []string{"00000000-0000-0000-0000-000000000001"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts vectorstores.Options
+			vectorstores.WithFilters(tt.filters)(&opts)
+			if got := FilterFromOptions(opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterFromOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
@@ -16,24 +17,40 @@ import (
 
 // PGVectorStore implements LangChain's VectorStore interface using PostgreSQL with pgvector
 type PGVectorStore struct {
-	queries    sqlc.Querier
-	embedder   embeddings.Embedder
-	logger     *slog.Logger
-	collection string
-	dimensions int
+	queries      sqlc.Querier
+	embedder     embeddings.Embedder
+	model        string // model of the embedder, recorded on stored vectors
+	logger       *slog.Logger
+	collection   string
+	collectionID pgtype.UUID // knowledge-base collection of stored vectors
+	dimensions   int
+
+	searchOptions SearchOptions
 }
 
-// NewPGVectorStore creates a new PGVector store
-func NewPGVectorStore(queries sqlc.Querier, embedder embeddings.Embedder, logger *slog.Logger) *PGVectorStore {
+// NewPGVectorStore creates a new PGVector store that stores the vectors of
+// embedder, whose model is named by model, into the default collection
+func NewPGVectorStore(queries sqlc.Querier, embedder embeddings.Embedder, model string, logger *slog.Logger) *PGVectorStore {
+	collectionID, _ := postgres.ParseUUID(postgres.DefaultCollectionID)
 	return &PGVectorStore{
-		queries:    queries,
-		embedder:   embedder,
-		logger:     logger,
-		collection: "langchain_documents",
-		dimensions: 1536, // Default OpenAI embedding dimension
+		queries:      queries,
+		embedder:     embedder,
+		model:        model,
+		logger:       logger,
+		collection:   "langchain_documents",
+		collectionID: collectionID,
+		dimensions:   1536, // Default OpenAI embedding dimension
+
+		searchOptions: SearchOptions{Mode: ModeHybrid},
 	}
 }
 
+// SetSearchOptions sets how SimilaritySearch combines full-text and vector
+// search, reranks and diversifies results
+func (vs *PGVectorStore) SetSearchOptions(options SearchOptions) {
+	vs.searchOptions = options
+}
+
 // SetCollection sets the collection name for storing documents
 func (vs *PGVectorStore) SetCollection(collection string) {
 	vs.collection = collection
@@ -99,11 +116,13 @@ func (vs *PGVectorStore) AddDocuments(ctx context.Context, docs []schema.Documen
 
 		// Store in database
 		_, err = vs.queries.CreateEmbedding(ctx, sqlc.CreateEmbeddingParams{
-			ContentType: vs.collection,
-			ContentID:   docUUID,
-			ContentText: doc.PageContent,
-			Embedding:   postgres.VectorToPgVector(embeddingFloat64),
-			Metadata:    metadataJSON,
+			CollectionID: vs.collectionID,
+			ContentType:  vs.collection,
+			ContentID:    docUUID,
+			ContentText:  doc.PageContent,
+			Embedding:    postgres.VectorToPgVector(embeddingFloat64),
+			Metadata:     metadataJSON,
+			Model:        vs.model,
 		})
 		if err != nil {
 			vs.logger.Error("Failed to store document embedding",
@@ -122,7 +141,10 @@ func (vs *PGVectorStore) AddDocuments(ctx context.Context, docs []schema.Documen
 	return documentIDs, nil
 }
 
-// SimilaritySearch performs similarity search and returns documents
+// SimilaritySearch performs hybrid search and returns documents. Filters
+// may be a Filter or a map with content_type(s), path_prefix, tags and
+// similarity_threshold keys; without a content type filter the store's
+// collection is searched.
 func (vs *PGVectorStore) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
 	vs.logger.Debug("Performing similarity search",
 		slog.String("query", query),
@@ -141,69 +163,52 @@ func (vs *PGVectorStore) SimilaritySearch(ctx context.Context, query string, num
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
-	// Convert query embedding to float64
-	queryEmbeddingFloat64 := make([]float64, len(queryEmbedding))
-	for i, v := range queryEmbedding {
-		queryEmbeddingFloat64[i] = float64(v)
-	}
-
-	// Set default similarity threshold
-	similarity := 0.7
-	if opts.Filters != nil {
-		if filters, ok := opts.Filters.(map[string]interface{}); ok {
-			if sim, ok := filters["similarity_threshold"].(float64); ok {
-				similarity = sim
-			}
+	// Score threshold from options, then filters, then the default
+	threshold := 0.7
+	if opts.ScoreThreshold > 0 {
+		threshold = float64(opts.ScoreThreshold)
+	} else if filters, ok := opts.Filters.(map[string]any); ok {
+		if sim, ok := filters["similarity_threshold"].(float64); ok {
+			threshold = sim
 		}
 	}
 
-	// Perform vector similarity search
-	threshold := float64(0.7) // Default threshold
-	if similarity > 0 {
-		threshold = float64(similarity)
+	filter := FilterFromOptions(*opts)
+	if len(filter.ContentTypes) == 0 {
+		filter.ContentTypes = []string{vs.collection}
 	}
 
-	searchResults, err := vs.queries.SearchSimilarEmbeddings(ctx, sqlc.SearchSimilarEmbeddingsParams{
-		QueryEmbedding: postgres.VectorToPgVector(queryEmbeddingFloat64),
-		ContentType:    vs.collection,
-		Threshold:      threshold,
-		ResultLimit:    int32(numDocuments),
-	})
+	results, err := NewSearcher(vs.queries, vs.searchOptions, vs.logger).
+		Search(ctx, query, queryEmbedding, numDocuments, filter, threshold)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search similar embeddings: %w", err)
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
 	}
 
 	// Convert results to schema.Document
-	documents := make([]schema.Document, 0, len(searchResults))
-	for _, result := range searchResults {
-		// Convert to standard Embedding type for processing
-		emb := &sqlc.Embedding{
-			ID:          result.ID,
-			ContentType: result.ContentType,
-			ContentID:   result.ContentID,
-			ContentText: result.ContentText,
-			Embedding:   result.Embedding,
-			Metadata:    result.Metadata,
-			CreatedAt:   result.CreatedAt,
-		}
-
-		// Convert the embedding to domain type
-		embRecord := postgres.ConvertSQLCEmbedding(emb)
-
+	documents := make([]schema.Document, 0, len(results))
+	for _, result := range results {
 		doc := schema.Document{
-			PageContent: embRecord.ContentText,
-			Metadata:    make(map[string]any),
+			PageContent: result.Content,
+			Metadata:    make(map[string]any, len(result.Metadata)+4),
+			Score:       float32(result.Similarity),
 		}
 
 		// Copy metadata, excluding internal fields
-		for key, value := range embRecord.Metadata {
+		for key, value := range result.Metadata {
 			if key != "page_content" && key != "collection" {
 				doc.Metadata[key] = value
 			}
 		}
 
-		// Add similarity score from the search result
-		doc.Metadata["similarity_score"] = float64(result.Similarity) / 100.0
+		doc.Metadata["similarity_score"] = result.Similarity
+		doc.Metadata["fusion_score"] = result.Score
+		doc.Metadata["content_type"] = result.ContentType
+		if result.KeywordRank > 0 {
+			doc.Metadata["keyword_rank"] = result.KeywordRank
+		}
+		if result.Reranked {
+			doc.Metadata["rerank_score"] = result.Score
+		}
 
 		documents = append(documents, doc)
 	}
@@ -0,0 +1,150 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Reranker scores documents by relevance to a query; higher is more
+// relevant. It returns one score per document, in document order.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// maxRerankPassage bounds the characters of a passage sent to an LLM
+// reranker, to keep the prompt small
+const maxRerankPassage = 1000
+
+// LLMReranker asks a language model to score passages
+type LLMReranker struct {
+	llm llms.Model
+}
+
+// NewLLMReranker creates a reranker backed by a language model
+func NewLLMReranker(llm llms.Model) *LLMReranker {
+	return &LLMReranker{llm: llm}
+}
+
+// Rerank scores all documents in one prompt, from 0 (irrelevant) to 10
+func (r *LLMReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Rate how well each passage answers the query, from 0 (irrelevant) to 10 (answers it fully).\n")
+	fmt.Fprintf(&prompt, "Reply with only a JSON array of %d numbers, one per passage, in order.\n\n", len(documents))
+	fmt.Fprintf(&prompt, "Query: %s\n", query)
+	for i, document := range documents {
+		fmt.Fprintf(&prompt, "\n[%d] %s\n", i+1, truncate(document, maxRerankPassage))
+	}
+
+	response, err := llms.GenerateFromSinglePrompt(ctx, r.llm, prompt.String(), llms.WithTemperature(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to rerank: %w", err)
+	}
+
+	// Models may wrap the array in prose or a code block
+	start, end := strings.Index(response, "["), strings.LastIndex(response, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("reranker reply has no scores: %q", truncate(response, 200))
+	}
+	var scores []float64
+	if err := json.Unmarshal([]byte(response[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse reranker scores: %w", err)
+	}
+	return scores, nil
+}
+
+// CrossEncoderReranker scores passages with a cross-encoder served over
+// HTTP, such as a text-embeddings-inference /rerank endpoint
+type CrossEncoderReranker struct {
+	url    string
+	client *http.Client
+}
+
+// NewCrossEncoderReranker creates a reranker posting to url
+func NewCrossEncoderReranker(url string) *CrossEncoderReranker {
+	return &CrossEncoderReranker{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Rerank posts {"query", "texts"} and reads [{"index", "score"}] back
+func (r *CrossEncoderReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	body, err := json.Marshal(map[string]any{
+		"query": query,
+		"texts": documents,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rerank request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rerank request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rerank: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("reranker returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var ranked []struct {
+		Index int     `json:"index"`
+		Score float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ranked); err != nil {
+		return nil, fmt.Errorf("failed to decode rerank response: %w", err)
+	}
+	if len(ranked) != len(documents) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d documents", len(ranked), len(documents))
+	}
+	scores := make([]float64, len(documents))
+	for _, item := range ranked {
+		if item.Index < 0 || item.Index >= len(documents) {
+			return nil, fmt.Errorf("reranker returned index %d out of range", item.Index)
+		}
+		scores[item.Index] = item.Score
+	}
+	return scores, nil
+}
+
+// NewReranker creates the reranker named by retrieval configuration, or nil
+// when reranking is off. The LLM reranker needs llm.
+func NewReranker(kind, url string, llm llms.Model) Reranker {
+	switch kind {
+	case "llm":
+		if llm != nil {
+			return NewLLMReranker(llm)
+		}
+	case "cross_encoder":
+		if url != "" {
+			return NewCrossEncoderReranker(url)
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes on a rune boundary
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -81,6 +81,7 @@ const (
 	EventToolExecution    EventType = "tool.execution"
 	EventToolRegistration EventType = "tool.registration"
 	EventToolError        EventType = "tool.error"
+	EventToolHealthChange EventType = "tool.health_change"
 
 	// Learning events
 	EventLearningSession   EventType = "learning.session"
@@ -781,7 +782,7 @@ func getAllEventTypes() []EventType {
 		EventAgentRegistered, EventAgentUnregistered, EventAgentStatusChange, EventAgentTaskStart,
 		EventAgentTaskComplete, EventAgentTaskFailed, EventAgentCollaboration, EventAgentLearning,
 		EventUserRequest, EventUserFeedback, EventUserPreference, EventUserSession,
-		EventToolExecution, EventToolRegistration, EventToolError,
+		EventToolExecution, EventToolRegistration, EventToolError, EventToolHealthChange,
 		EventLearningSession, EventKnowledgeUpdate, EventPatternDiscovered, EventInsightGenerated,
 		EventPerformanceMetric, EventResourceUsage, EventBottleneckDetected,
 		EventCustom,
@@ -323,15 +323,20 @@ func (s *Server) authMiddleware(authService user.JWTService) func(http.Handler)
 			token := strings.TrimPrefix(authHeader, bearerPrefix)
 
 			// Validate token
-			userID, err := authService.ValidateToken(token)
+			claims, err := authService.ValidateTokenClaims(token)
 			if err != nil {
 				s.logger.Warn("Invalid token", slog.Any("error", err))
 				s.writeErrorResponse(w, http.StatusUnauthorized, "Invalid token")
 				return
 			}
 
-			// Add user ID to request context
-			ctx := context.WithValue(r.Context(), "user_id", userID)
+			// Add user ID and roles to request context
+			ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+			ctx = user.WithUser(ctx, &user.UserInfo{
+				ID:    claims.UserID,
+				Email: claims.Email,
+				Roles: []string{claims.Role},
+			})
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
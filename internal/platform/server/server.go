@@ -4,8 +4,10 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
@@ -18,7 +20,9 @@ import (
 	"github.com/koopa0/assistant-go/internal/conversation"
 	assterrors "github.com/koopa0/assistant-go/internal/errors"
 	"github.com/koopa0/assistant-go/internal/knowledge"
+	"github.com/koopa0/assistant-go/internal/langchain/collection"
 	langchainhttp "github.com/koopa0/assistant-go/internal/langchain/http"
+	"github.com/koopa0/assistant-go/internal/langchain/router"
 	"github.com/koopa0/assistant-go/internal/learning"
 	"github.com/koopa0/assistant-go/internal/memory"
 	memoryhttp "github.com/koopa0/assistant-go/internal/memory/http"
@@ -26,7 +30,11 @@ import (
 	"github.com/koopa0/assistant-go/internal/platform/server/middleware"
 	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
 	"github.com/koopa0/assistant-go/internal/system"
+	"github.com/koopa0/assistant-go/internal/tool"
+	"github.com/koopa0/assistant-go/internal/tool/artifact"
 	toolhttp "github.com/koopa0/assistant-go/internal/tool/http"
+	"github.com/koopa0/assistant-go/internal/tool/openapi"
+	"github.com/koopa0/assistant-go/internal/tool/workflow"
 	"github.com/koopa0/assistant-go/internal/transport/sse"
 	"github.com/koopa0/assistant-go/internal/transport/websocket"
 	"github.com/koopa0/assistant-go/internal/user"
@@ -233,9 +241,16 @@ func (s *Server) setupRoutes() {
 		s.mux.HandleFunc("GET /api/langchain/agents", langchainHandler.GetAvailableAgents)
 		s.mux.HandleFunc("POST /api/langchain/agents/{type}/execute", langchainHandler.ExecuteAgent)
 		s.mux.HandleFunc("POST /api/langchain/execute", langchainHandler.ExecutePrompt)
+		s.mux.HandleFunc("POST /api/langchain/rag/query", langchainHandler.QueryRAG)
 		s.logger.Info("LangChain API routes registered")
 	}
 
+	// Knowledge-base collections
+	if collections := s.assistant.Collections(); collections != nil {
+		collectionHandler := collection.NewHTTPHandler(collections, s.logger)
+		collectionHandler.RegisterRoutes(s.mux)
+	}
+
 	// 保持向後相容的舊 API 路由
 	s.mux.HandleFunc("GET /api/health", s.handleHealth)
 	s.mux.HandleFunc("GET /api/status", s.handleStatus)
@@ -245,8 +260,21 @@ func (s *Server) setupRoutes() {
 	// s.mux.HandleFunc("GET /api/conversations/{id}", s.handleGetConversation)
 	s.mux.HandleFunc("DELETE /api/conversations/{id}", s.handleDeleteConversation)
 	s.mux.HandleFunc("GET /api/tools", s.handleListTools)
+	s.mux.HandleFunc("GET /api/tools/health", s.handleToolHealth)
+	s.mux.HandleFunc("GET /api/tools/settings", s.handleGetToolSettings)
 	s.mux.HandleFunc("GET /api/tools/{name}", s.handleGetTool)
+	s.mux.HandleFunc("PUT /api/tools/{name}/settings", s.handleSetToolSetting)
+	s.mux.HandleFunc("DELETE /api/tools/{name}/settings", s.handleDeleteToolSetting)
 	s.mux.HandleFunc("POST /api/tools/{name}/execute", s.handleExecuteTool)
+	s.mux.HandleFunc("POST /api/tools/openapi/reload", s.handleReloadOpenAPITools)
+	s.mux.HandleFunc("POST /api/routing", s.handleRouteQuery)
+	s.mux.HandleFunc("POST /api/routing/{id}/feedback", s.handleRoutingFeedback)
+	s.mux.HandleFunc("GET /api/routing/accuracy", s.handleRoutingAccuracy)
+	s.mux.HandleFunc("GET /api/workflows", s.handleListWorkflows)
+	s.mux.HandleFunc("POST /api/workflows/{name}/run", s.handleRunWorkflow)
+	s.mux.HandleFunc("POST /api/workflows/reload", s.handleReloadWorkflows)
+	s.mux.HandleFunc("GET /api/artifacts", s.handleListArtifacts)
+	s.mux.HandleFunc("GET /api/artifacts/{id}", s.handleDownloadArtifact)
 
 	// 根路由 - 提供 API 資訊
 	s.mux.HandleFunc("GET /", s.handleRoot)
@@ -421,9 +449,20 @@ func (s *Server) handleDeleteConversation(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// List tools endpoint
+// List tools endpoint; for an authenticated user only the tools their
+// settings enable are listed, within ?conversation_id= when given
 func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	tools := s.assistant.GetAvailableTools()
+	if userID, ok := ctx.Value("user_id").(string); ok && userID != "" {
+		var err error
+		tools, err = s.assistant.ListToolsFor(ctx, userID, r.URL.Query().Get("conversation_id"))
+		if err != nil {
+			s.logger.Error("Failed to resolve tool settings", slog.Any("error", err))
+			http.Error(w, "Failed to list tools", http.StatusInternalServerError)
+			return
+		}
+	}
 	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
 		"tools": tools,
 		"total": len(tools),
@@ -459,8 +498,9 @@ func (s *Server) handleExecuteTool(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var request struct {
-		Input  map[string]interface{} `json:"input"`
-		Config map[string]interface{} `json:"config,omitempty"`
+		Input          map[string]interface{} `json:"input"`
+		Config         map[string]interface{} `json:"config,omitempty"`
+		ConversationID string                 `json:"conversation_id,omitempty"`
 	}
 
 	if err := s.parseJSONRequest(r, &request); err != nil {
@@ -475,7 +515,15 @@ func (s *Server) handleExecuteTool(w http.ResponseWriter, r *http.Request) {
 		Input:    request.Input,
 		Config:   request.Config,
 	}
+	if request.ConversationID != "" {
+		toolReq.Context = &assistant.ToolExecutionContext{ConversationID: request.ConversationID}
+	}
 	result, err := s.assistant.ExecuteTool(ctx, toolReq)
+	var assistantErr *assterrors.AssistantError
+	if errors.As(err, &assistantErr) && assistantErr.Code == tool.CodeToolPermissionDenied {
+		http.Error(w, fmt.Sprintf("Tool %s is disabled", toolName), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		s.logger.Error("Tool execution failed",
 			slog.String("tool", toolName),
@@ -487,6 +535,350 @@ func (s *Server) handleExecuteTool(w http.ResponseWriter, r *http.Request) {
 	s.writeJSONResponse(w, http.StatusOK, result)
 }
 
+// Tool settings endpoint; returns the caller's stored settings, those of
+// ?conversation_id= when given, and the settings in effect per tool
+func (s *Server) handleGetToolSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := s.assistant.GetToolSettings(ctx, userID, r.URL.Query().Get("conversation_id"))
+	if err != nil {
+		s.writeToolSettingsError(w, err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, settings)
+}
+
+// Set tool setting endpoint; {name} may be "*" for every tool
+func (s *Server) handleSetToolSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var setting tool.ToolSetting
+	if err := s.parseJSONRequest(r, &setting); err != nil {
+		s.logger.Warn("Invalid tool setting", slog.Any("error", err))
+		http.Error(w, fmt.Sprintf("Invalid tool setting: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := s.assistant.SetToolSetting(ctx, userID, r.URL.Query().Get("conversation_id"), name, setting); err != nil {
+		s.writeToolSettingsError(w, err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"tool":    name,
+		"setting": setting,
+	})
+}
+
+// Delete tool setting endpoint
+func (s *Server) handleDeleteToolSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.assistant.DeleteToolSetting(ctx, userID, r.URL.Query().Get("conversation_id"), r.PathValue("name")); err != nil {
+		s.writeToolSettingsError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeToolSettingsError reports invalid settings requests, such as an
+// unknown tool or another user's conversation, as bad requests
+func (s *Server) writeToolSettingsError(w http.ResponseWriter, err error) {
+	if assterrors.IsAssistantError(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.logger.Error("Tool settings request failed", slog.Any("error", err))
+	http.Error(w, "Tool settings request failed", http.StatusInternalServerError)
+}
+
+// Route query endpoint; returns the agent, chain and tools the router
+// chooses for a query, recorded for feedback under the decision id
+func (s *Server) handleRouteQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Query          string `json:"query"`
+		ConversationID string `json:"conversation_id,omitempty"`
+	}
+	if err := s.parseJSONRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+
+	decision, err := s.assistant.RouteQuery(ctx, userID, req.ConversationID, req.Query)
+	if err != nil {
+		s.logger.Error("Failed to route query", slog.Any("error", err))
+		http.Error(w, "Failed to route query", http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, decision)
+}
+
+// Routing feedback endpoint; records the outcome of a routing decision
+func (s *Server) handleRoutingFeedback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var outcome router.Outcome
+	if err := s.parseJSONRequest(r, &outcome); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	err := s.assistant.RecordRoutingOutcome(ctx, userID, id, outcome)
+	switch {
+	case errors.Is(err, router.ErrDecisionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case assterrors.IsAssistantError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		s.logger.Error("Failed to record routing feedback", slog.Any("error", err))
+		http.Error(w, "Failed to record routing feedback", http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"outcome": outcome,
+	})
+}
+
+// Routing accuracy endpoint; ?since= is a duration such as 168h (default
+// 7 days) and ?scope=user limits the report to the caller's decisions
+func (s *Server) handleRoutingAccuracy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	period := 7 * 24 * time.Hour
+	if since := r.URL.Query().Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid since duration", http.StatusBadRequest)
+			return
+		}
+		period = d
+	}
+
+	var userID string
+	if r.URL.Query().Get("scope") == "user" {
+		id, ok := ctx.Value("user_id").(string)
+		if !ok || id == "" {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		userID = id
+	}
+
+	since := time.Now().Add(-period)
+	accuracy, err := s.assistant.RoutingAccuracy(ctx, userID, since)
+	if err != nil {
+		s.logger.Error("Failed to get routing accuracy", slog.Any("error", err))
+		http.Error(w, "Failed to get routing accuracy", http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"since":    since.Format(time.RFC3339),
+		"accuracy": accuracy,
+	})
+}
+
+// Tool health endpoint; lists the supervised state of instantiated tools
+func (s *Server) handleToolHealth(w http.ResponseWriter, r *http.Request) {
+	health, err := s.assistant.GetToolHealth()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"tools": health,
+	})
+}
+
+// Reload OpenAPI tools endpoint; ?spec=name reloads a single spec
+func (s *Server) handleReloadOpenAPITools(w http.ResponseWriter, r *http.Request) {
+	tools, err := s.assistant.ReloadOpenAPITools(r.Context(), r.URL.Query().Get("spec"))
+	if tools == nil || errors.Is(err, openapi.ErrUnknownSpec) {
+		s.logger.Warn("OpenAPI reload rejected", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"tools": tools,
+	}
+	if err != nil {
+		s.logger.Warn("OpenAPI reload failed", slog.Any("error", err))
+		response["error"] = err.Error()
+	}
+	s.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// List workflows endpoint
+func (s *Server) handleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	workflows, err := s.assistant.ListWorkflows()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"workflows": workflows,
+	})
+}
+
+// Run workflow endpoint; a run whose steps failed is still returned with 200
+func (s *Server) handleRunWorkflow(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var request struct {
+		Inputs map[string]interface{} `json:"inputs"`
+		UserID string                 `json:"user_id,omitempty"`
+	}
+	if err := s.parseJSONRequest(r, &request); err != nil {
+		s.logger.Warn("Invalid workflow run request", slog.Any("error", err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.assistant.RunWorkflow(r.Context(), name, request.Inputs, request.UserID)
+	switch {
+	case err == nil:
+		s.writeJSONResponse(w, http.StatusOK, run)
+	case errors.Is(err, workflow.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, workflow.ErrUnknownWorkflow), assterrors.IsAssistantError(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		s.logger.Error("Workflow run failed",
+			slog.String("workflow", name),
+			slog.Any("error", err))
+		http.Error(w, fmt.Sprintf("Workflow run failed: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// Reload workflows endpoint
+func (s *Server) handleReloadWorkflows(w http.ResponseWriter, r *http.Request) {
+	workflows, err := s.assistant.ReloadWorkflows()
+	if workflows == nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"workflows": workflows,
+	}
+	if err != nil {
+		s.logger.Warn("Workflow reload failed", slog.Any("error", err))
+		response["error"] = err.Error()
+	}
+	s.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// List artifacts endpoint; ?conversation_id= or ?message_id= selects the
+// artifacts, and only those of the authenticated user are returned
+func (s *Server) handleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var artifacts []*artifact.Artifact
+	var err error
+	switch query := r.URL.Query(); {
+	case query.Get("conversation_id") != "":
+		artifacts, err = s.assistant.ListArtifacts(ctx, query.Get("conversation_id"))
+	case query.Get("message_id") != "":
+		artifacts, err = s.assistant.ListMessageArtifacts(ctx, query.Get("message_id"))
+	default:
+		http.Error(w, "conversation_id or message_id is required", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case assterrors.IsAssistantError(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		s.logger.Error("Failed to list artifacts", slog.Any("error", err))
+		http.Error(w, "Failed to list artifacts", http.StatusInternalServerError)
+		return
+	}
+
+	owned := make([]*artifact.Artifact, 0, len(artifacts))
+	for _, a := range artifacts {
+		if a.OwnedBy(userID) {
+			owned = append(owned, a)
+		}
+	}
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"artifacts": owned,
+	})
+}
+
+// Download artifact endpoint; artifacts of other users are reported as
+// not found
+func (s *Server) handleDownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	a, content, err := s.assistant.OpenArtifact(ctx, r.PathValue("id"))
+	switch {
+	case errors.Is(err, artifact.ErrNotFound), assterrors.IsAssistantError(err):
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+		return
+	case err != nil:
+		s.logger.Error("Failed to open artifact", slog.Any("error", err))
+		http.Error(w, "Failed to open artifact", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	if !a.OwnedBy(userID) {
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("ETag", `"`+a.Hash+`"`)
+	http.ServeContent(w, r, a.Name, a.CreatedAt, content)
+}
+
 // handleRoot provides API information at the root endpoint
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 	apiInfo := map[string]interface{}{
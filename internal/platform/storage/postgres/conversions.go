@@ -11,6 +11,10 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+// DefaultCollectionID is the id of the default knowledge-base collection,
+// which holds the embeddings not stored into another collection
+const DefaultCollectionID = "00000000-0000-0000-0000-000000000001"
+
 // ConversionHelpers provides utility functions for converting between sqlc and domain types
 type ConversionHelpers struct{}
 
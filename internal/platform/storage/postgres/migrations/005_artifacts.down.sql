@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_artifacts_expires;
+DROP INDEX IF EXISTS idx_artifacts_tool_execution;
+DROP INDEX IF EXISTS idx_artifacts_message;
+DROP INDEX IF EXISTS idx_artifacts_conversation;
+DROP INDEX IF EXISTS idx_artifacts_user;
+DROP INDEX IF EXISTS idx_artifacts_hash;
+
+-- Drop artifacts table
+DROP TABLE IF EXISTS artifacts;
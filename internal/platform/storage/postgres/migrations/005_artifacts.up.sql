@@ -0,0 +1,25 @@
+-- Files produced by tools. Contents live on disk under their SHA-256 hash,
+-- so identical artifacts share one blob; rows hold metadata and links to
+-- the conversation, message and tool execution that produced them.
+CREATE TABLE IF NOT EXISTS artifacts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    hash CHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL DEFAULT 'file',
+    content_type VARCHAR(255) NOT NULL DEFAULT 'application/octet-stream',
+    size BIGINT NOT NULL CHECK (size >= 0),
+    tool_name VARCHAR(255) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id UUID REFERENCES conversations(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    tool_execution_id UUID REFERENCES tool_executions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifacts_hash ON artifacts(hash);
+CREATE INDEX IF NOT EXISTS idx_artifacts_user ON artifacts(user_id);
+CREATE INDEX IF NOT EXISTS idx_artifacts_conversation ON artifacts(conversation_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_artifacts_message ON artifacts(message_id);
+CREATE INDEX IF NOT EXISTS idx_artifacts_tool_execution ON artifacts(tool_execution_id);
+CREATE INDEX IF NOT EXISTS idx_artifacts_expires ON artifacts(expires_at) WHERE expires_at IS NOT NULL;
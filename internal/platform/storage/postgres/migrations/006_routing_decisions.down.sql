@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_learning_events_routing;
+
+-- Restore the event types without routing decisions
+DELETE FROM learning_events WHERE event_type = 'routing_decision';
+ALTER TABLE learning_events DROP CONSTRAINT IF EXISTS learning_events_event_type_check;
+ALTER TABLE learning_events ADD CONSTRAINT learning_events_event_type_check CHECK (event_type IN (
+    'code_completion', 'refactoring', 'debugging', 'query_response',
+    'tool_usage', 'error_recovery', 'preference_detected'
+));
@@ -0,0 +1,11 @@
+-- Routing decisions are learning events: the router records the agent,
+-- chain and tools it chose for a query, and the outcome of running them
+-- is written back so routing accuracy can be measured over time.
+ALTER TABLE learning_events DROP CONSTRAINT IF EXISTS learning_events_event_type_check;
+ALTER TABLE learning_events ADD CONSTRAINT learning_events_event_type_check CHECK (event_type IN (
+    'code_completion', 'refactoring', 'debugging', 'query_response',
+    'tool_usage', 'error_recovery', 'preference_detected', 'routing_decision'
+));
+
+CREATE INDEX IF NOT EXISTS idx_learning_events_routing ON learning_events(created_at DESC)
+    WHERE event_type = 'routing_decision';
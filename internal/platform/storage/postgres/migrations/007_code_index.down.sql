@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_code_index_files_root;
+DROP INDEX IF EXISTS idx_embeddings_content;
+
+-- Drop tables
+DROP TABLE IF EXISTS code_index_files;
@@ -0,0 +1,33 @@
+-- Files of indexed code bases. Chunks live in embeddings under the
+-- collection's content type; a file's row holds its content hash and the
+-- ids of its chunks so unchanged files are skipped and removed ones are
+-- cleaned up on the next run.
+CREATE TABLE IF NOT EXISTS code_index_files (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    collection VARCHAR(100) NOT NULL,
+    root TEXT NOT NULL,
+    path TEXT NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    chunk_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (collection, root, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_code_index_files_root ON code_index_files(collection, root);
+
+-- Chunks are upserted by content type and id, which needs a unique index.
+-- Rows stored twice before it existed keep their newest copy.
+DELETE FROM embeddings
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY content_type, content_id
+            ORDER BY created_at DESC NULLS LAST, id DESC
+        ) AS copy
+        FROM embeddings
+    ) copies
+    WHERE copy > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_embeddings_content ON embeddings(content_type, content_id);
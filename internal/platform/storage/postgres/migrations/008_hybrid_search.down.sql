@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_embeddings_metadata_path;
+DROP INDEX IF EXISTS idx_embeddings_content_tsv;
+
+-- Drop columns
+ALTER TABLE embeddings DROP COLUMN IF EXISTS content_tsv;
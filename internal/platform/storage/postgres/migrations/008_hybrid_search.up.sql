@@ -0,0 +1,9 @@
+-- Full-text search over embedded content. The 'simple' configuration keeps
+-- identifiers and error strings as they are, without stemming or stop
+-- words, so exact matches are found alongside vector similarity.
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS content_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', content_text)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_embeddings_content_tsv ON embeddings USING GIN(content_tsv);
+CREATE INDEX IF NOT EXISTS idx_embeddings_metadata_path ON embeddings((metadata->>'path') text_pattern_ops);
@@ -0,0 +1,13 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_embeddings_collection;
+DROP INDEX IF EXISTS idx_ingestion_jobs_collection;
+DROP INDEX IF EXISTS idx_collections_owner;
+
+-- Drop columns
+ALTER TABLE embeddings DROP COLUMN IF EXISTS collection_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS ingestion_jobs;
+DROP TABLE IF EXISTS collection_documents;
+DROP TABLE IF EXISTS collection_grants;
+DROP TABLE IF EXISTS collections;
@@ -0,0 +1,70 @@
+-- Knowledge-base collections. A collection has an owner, grants to users
+-- and teams, and its own embedding provider and chunking settings. Chunks
+-- in embeddings reference their collection; chunks stored before
+-- collections existed move into the default collection, which has no owner
+-- and is readable by everyone.
+CREATE TABLE IF NOT EXISTS collections (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    embedding_provider VARCHAR(50) NOT NULL DEFAULT '',
+    chunk_size INTEGER NOT NULL DEFAULT 1000 CHECK (chunk_size > 0),
+    chunk_overlap INTEGER NOT NULL DEFAULT 200 CHECK (chunk_overlap >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_collections_owner ON collections(owner_id);
+
+-- Grants of read or write access to a user id or a team (a user role)
+CREATE TABLE IF NOT EXISTS collection_grants (
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    grantee_type VARCHAR(10) NOT NULL CHECK (grantee_type IN ('user', 'team')),
+    grantee_id VARCHAR(255) NOT NULL,
+    permission VARCHAR(10) NOT NULL CHECK (permission IN ('read', 'write')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (collection_id, grantee_type, grantee_id)
+);
+
+-- Documents ingested into a collection, with the ids of their chunks so a
+-- re-ingest or delete removes the previous chunks
+CREATE TABLE IF NOT EXISTS collection_documents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    chunk_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (collection_id, source)
+);
+
+-- Ingestion jobs load, chunk and embed a source in the background
+CREATE TABLE IF NOT EXISTS ingestion_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    documents INTEGER NOT NULL DEFAULT 0,
+    chunks INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_collection ON ingestion_jobs(collection_id, created_at DESC);
+
+INSERT INTO collections (id, name, description)
+VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Embeddings stored before collections existed')
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS collection_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001'
+    REFERENCES collections(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_embeddings_collection ON embeddings(collection_id);
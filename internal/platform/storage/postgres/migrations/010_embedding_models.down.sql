@@ -0,0 +1,26 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_reembedding_jobs_unfinished;
+DROP INDEX IF EXISTS idx_reembedding_jobs_collection;
+DROP INDEX IF EXISTS idx_embeddings_content_model;
+DROP INDEX IF EXISTS idx_embeddings_collection_model;
+DROP INDEX IF EXISTS idx_embeddings_vector_1536;
+DROP INDEX IF EXISTS idx_embeddings_vector_768;
+
+-- Drop tables
+DROP TABLE IF EXISTS reembedding_jobs;
+
+-- Keep the vectors of each collection's active model, one per chunk
+DELETE FROM embeddings e
+USING collections c
+WHERE c.id = e.collection_id AND c.embedding_model <> e.model;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_embeddings_content ON embeddings(content_type, content_id);
+
+-- Drop columns
+ALTER TABLE collections DROP COLUMN IF EXISTS embedding_model;
+ALTER TABLE embeddings DROP COLUMN IF EXISTS dimensions;
+ALTER TABLE embeddings DROP COLUMN IF EXISTS model;
+
+-- Restore the fixed dimension; vectors of other dimensions cannot be kept
+DELETE FROM embeddings WHERE vector_dims(embedding) <> 1536;
+ALTER TABLE embeddings ALTER COLUMN embedding TYPE vector(1536);
+CREATE INDEX IF NOT EXISTS idx_embeddings_vector ON embeddings USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100);
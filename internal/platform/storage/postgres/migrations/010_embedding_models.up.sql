@@ -0,0 +1,60 @@
+-- Embedding model versioning. Each vector records the model that produced
+-- it and its dimensions, and each collection its active model; searches
+-- only compare the vectors of a collection's active model. Vectors and
+-- collections stored before versioning have an empty model.
+--
+-- Vectors of different dimensions share the column, so it loses its fixed
+-- dimension and the approximate index that needs one. A collection only
+-- switches to a model once its vectors of the model have the re-embedding
+-- job's dimensions, and searches skip vectors of other dimensions than the
+-- query.
+DROP INDEX IF EXISTS idx_embeddings_vector;
+ALTER TABLE embeddings ALTER COLUMN embedding TYPE vector;
+
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS model VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS dimensions INTEGER GENERATED ALWAYS AS (vector_dims(embedding)) STORED;
+
+-- Approximate indexes for the dimensions of the supported embedding
+-- models (Gemini's 768 and OpenAI's 1536). SearchEmbeddingsByVector casts
+-- to the same fixed dimension so the planner can use them; vectors of
+-- other dimensions are scanned.
+CREATE INDEX IF NOT EXISTS idx_embeddings_vector_768 ON embeddings
+    USING hnsw ((embedding::vector(768)) vector_cosine_ops) WHERE dimensions = 768;
+CREATE INDEX IF NOT EXISTS idx_embeddings_vector_1536 ON embeddings
+    USING hnsw ((embedding::vector(1536)) vector_cosine_ops) WHERE dimensions = 1536;
+
+CREATE INDEX IF NOT EXISTS idx_embeddings_collection_model ON embeddings(collection_id, model, content_id);
+
+-- A chunk has a vector of each model while it is re-embedded, so upserts
+-- replace the vector of the model they write
+DROP INDEX IF EXISTS idx_embeddings_content;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_embeddings_content_model ON embeddings(content_type, content_id, model);
+
+ALTER TABLE collections
+    ADD COLUMN IF NOT EXISTS embedding_model VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Re-embedding jobs embed a collection's chunks with a new model next to
+-- the active one, then switch the collection over. A collection has at
+-- most one unfinished job; unfinished jobs resume when the server starts.
+CREATE TABLE IF NOT EXISTS reembedding_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL DEFAULT '',
+    model VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    total INTEGER NOT NULL DEFAULT 0,
+    done INTEGER NOT NULL DEFAULT 0,
+    dimensions INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reembedding_jobs_collection ON reembedding_jobs(collection_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reembedding_jobs_unfinished ON reembedding_jobs(collection_id)
+    WHERE status IN ('pending', 'running');
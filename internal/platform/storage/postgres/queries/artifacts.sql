@@ -0,0 +1,42 @@
+-- Artifact metadata for files produced by tools
+
+-- name: CreateArtifact :one
+INSERT INTO artifacts (
+    hash, name, type, content_type, size, tool_name,
+    user_id, conversation_id, message_id, tool_execution_id, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: GetArtifact :one
+SELECT * FROM artifacts
+WHERE id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: ListArtifactsByConversation :many
+SELECT * FROM artifacts
+WHERE conversation_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at;
+
+-- name: ListArtifactsByMessage :many
+SELECT * FROM artifacts
+WHERE message_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at;
+
+-- name: ListArtifactsByToolExecution :many
+SELECT * FROM artifacts
+WHERE tool_execution_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at;
+
+-- name: GetUserArtifactBytes :one
+SELECT COALESCE(SUM(size), 0)::bigint AS total
+FROM artifacts
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: CountArtifactsByHash :one
+SELECT COUNT(*) FROM artifacts
+WHERE hash = $1;
+
+-- name: DeleteExpiredArtifacts :many
+DELETE FROM artifacts
+WHERE expires_at <= NOW()
+RETURNING hash;
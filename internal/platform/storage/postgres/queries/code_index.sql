@@ -0,0 +1,19 @@
+-- File state of indexed code bases
+
+-- name: ListCodeIndexFiles :many
+SELECT * FROM code_index_files
+WHERE collection = $1 AND root = $2
+ORDER BY path;
+
+-- name: UpsertCodeIndexFile :exec
+INSERT INTO code_index_files (collection, root, path, content_hash, chunk_ids)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (collection, root, path)
+DO UPDATE SET
+    content_hash = EXCLUDED.content_hash,
+    chunk_ids = EXCLUDED.chunk_ids,
+    updated_at = NOW();
+
+-- name: DeleteCodeIndexFile :exec
+DELETE FROM code_index_files
+WHERE collection = $1 AND root = $2 AND path = $3;
@@ -0,0 +1,212 @@
+-- Knowledge-base collections, their grants, documents, ingestion and re-embedding jobs
+
+-- name: CreateCollection :one
+INSERT INTO collections (name, description, owner_id, embedding_provider, embedding_model, chunk_size, chunk_overlap)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetCollection :one
+SELECT * FROM collections
+WHERE id = $1;
+
+-- name: GetCollectionByName :one
+SELECT * FROM collections
+WHERE name = $1;
+
+-- name: ListCollections :many
+SELECT * FROM collections
+ORDER BY name;
+
+-- name: DeleteCollection :exec
+DELETE FROM collections
+WHERE id = $1;
+
+-- name: ListCollectionGrants :many
+SELECT * FROM collection_grants
+WHERE collection_id = ANY(sqlc.arg(collection_ids)::uuid[])
+ORDER BY collection_id, grantee_type, grantee_id;
+
+-- name: UpsertCollectionGrant :exec
+INSERT INTO collection_grants (collection_id, grantee_type, grantee_id, permission)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (collection_id, grantee_type, grantee_id)
+DO UPDATE SET permission = EXCLUDED.permission;
+
+-- name: DeleteCollectionGrant :exec
+DELETE FROM collection_grants
+WHERE collection_id = $1 AND grantee_type = $2 AND grantee_id = $3;
+
+-- name: ListCollectionDocuments :many
+SELECT * FROM collection_documents
+WHERE collection_id = $1
+ORDER BY source;
+
+-- name: GetCollectionDocument :one
+SELECT * FROM collection_documents
+WHERE id = $1;
+
+-- name: UpsertCollectionDocument :one
+INSERT INTO collection_documents (collection_id, source, content_hash, chunk_ids)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (collection_id, source)
+DO UPDATE SET
+    content_hash = EXCLUDED.content_hash,
+    chunk_ids = EXCLUDED.chunk_ids,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteCollectionDocument :exec
+DELETE FROM collection_documents
+WHERE id = $1;
+
+-- name: CreateCollectionEmbedding :exec
+INSERT INTO embeddings (collection_id, content_type, content_id, content_text, embedding, metadata, model)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: DeleteCollectionEmbeddings :exec
+DELETE FROM embeddings
+WHERE collection_id = sqlc.arg(collection_id) AND content_id = ANY(sqlc.arg(content_ids)::uuid[]);
+
+-- name: CreateIngestionJob :one
+INSERT INTO ingestion_jobs (collection_id, source, created_by)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetIngestionJob :one
+SELECT * FROM ingestion_jobs
+WHERE id = $1;
+
+-- name: ListIngestionJobs :many
+SELECT * FROM ingestion_jobs
+WHERE collection_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: StartIngestionJob :exec
+UPDATE ingestion_jobs
+SET status = 'running', started_at = NOW()
+WHERE id = $1;
+
+-- name: FinishIngestionJob :exec
+UPDATE ingestion_jobs
+SET status = $2, documents = $3, chunks = $4, error = $5, finished_at = NOW()
+WHERE id = $1;
+
+-- name: CreateReembeddingJob :one
+INSERT INTO reembedding_jobs (collection_id, provider, model, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetReembeddingJob :one
+SELECT * FROM reembedding_jobs
+WHERE id = $1;
+
+-- name: ListReembeddingJobs :many
+SELECT * FROM reembedding_jobs
+WHERE collection_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ListUnfinishedReembeddingJobs :many
+SELECT * FROM reembedding_jobs
+WHERE status IN ('pending', 'running')
+ORDER BY created_at;
+
+-- name: StartReembeddingJob :exec
+UPDATE reembedding_jobs
+SET status = 'running', started_at = COALESCE(started_at, NOW()), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateReembeddingProgress :exec
+UPDATE reembedding_jobs
+SET total = $2, done = $3, dimensions = $4, updated_at = NOW()
+WHERE id = $1;
+
+-- name: FinishReembeddingJob :exec
+UPDATE reembedding_jobs
+SET status = $2, error = $3, updated_at = NOW(), finished_at = NOW()
+WHERE id = $1;
+
+-- name: ListReembeddingCandidates :many
+-- Chunks of the collection's active model without a vector of the new one
+SELECT e.id, e.content_type, e.content_id, e.content_text, e.metadata
+FROM embeddings e
+JOIN collections c ON c.id = e.collection_id AND c.embedding_model = e.model
+WHERE e.collection_id = sqlc.arg(collection_id)
+  AND NOT EXISTS (
+      SELECT 1 FROM embeddings n
+      WHERE n.collection_id = e.collection_id AND n.content_id = e.content_id AND n.model = sqlc.arg(model)::text
+  )
+ORDER BY e.id
+LIMIT sqlc.arg(result_limit);
+
+-- name: CountReembeddingProgress :one
+SELECT
+    (COUNT(*) FILTER (WHERE e.model = c.embedding_model))::int4 AS total,
+    (COUNT(*) FILTER (WHERE e.model = sqlc.arg(model)::text))::int4 AS done
+FROM embeddings e
+JOIN collections c ON c.id = e.collection_id
+WHERE e.collection_id = sqlc.arg(collection_id);
+
+-- name: SwitchCollectionModel :one
+-- Makes the new model active and deletes the vectors of other models in
+-- one statement, once every chunk has a vector of the new model with the
+-- job's dimensions and no ingestion job is adding chunks. Vectors of the
+-- new model with other dimensions are deleted instead, so they are
+-- embedded again. Reports whether the collection switched.
+WITH switched AS (
+    UPDATE collections c
+    SET embedding_provider = sqlc.arg(embedding_provider), embedding_model = sqlc.arg(embedding_model), updated_at = NOW()
+    WHERE c.id = sqlc.arg(id)
+      AND NOT EXISTS (
+          SELECT 1 FROM ingestion_jobs j
+          WHERE j.collection_id = c.id AND j.status IN ('pending', 'running')
+      )
+      AND NOT EXISTS (
+          SELECT 1 FROM embeddings e
+          WHERE e.collection_id = c.id AND e.model = c.embedding_model
+            AND NOT EXISTS (
+                SELECT 1 FROM embeddings n
+                WHERE n.collection_id = e.collection_id AND n.content_id = e.content_id AND n.model = sqlc.arg(embedding_model)
+            )
+      )
+      AND NOT EXISTS (
+          SELECT 1 FROM embeddings d
+          WHERE d.collection_id = c.id AND d.model = sqlc.arg(embedding_model) AND d.dimensions <> sqlc.arg(dimensions)::int
+      )
+    RETURNING c.id
+), deleted AS (
+    DELETE FROM embeddings
+    WHERE collection_id IN (SELECT id FROM switched) AND model <> sqlc.arg(embedding_model)
+), mismatched AS (
+    DELETE FROM embeddings
+    WHERE collection_id = sqlc.arg(id) AND model = sqlc.arg(embedding_model) AND dimensions <> sqlc.arg(dimensions)::int
+)
+SELECT EXISTS (SELECT 1 FROM switched);
+
+-- name: AdoptCollectionModel :one
+-- Makes a model the active model of a collection without one and records
+-- it on the collection's vectors stored before models were, unless the
+-- chunk already has a vector of the model. Reports whether the collection
+-- adopted the model.
+WITH adopted AS (
+    UPDATE collections
+    SET embedding_model = sqlc.arg(embedding_model), updated_at = NOW()
+    WHERE id = sqlc.arg(id) AND embedding_model = ''
+    RETURNING id
+), claimed AS (
+    UPDATE embeddings e
+    SET model = sqlc.arg(embedding_model)
+    WHERE e.collection_id IN (SELECT id FROM adopted) AND e.model = ''
+      AND NOT EXISTS (
+          SELECT 1 FROM embeddings n
+          WHERE n.content_type = e.content_type AND n.content_id = e.content_id AND n.model = sqlc.arg(embedding_model)
+      )
+)
+SELECT EXISTS (SELECT 1 FROM adopted);
+
+-- name: DeleteInactiveModelEmbeddings :exec
+-- Deletes the vectors a cancelled re-embedding stored
+DELETE FROM embeddings
+WHERE collection_id = sqlc.arg(collection_id) AND model = sqlc.arg(model)
+  AND model <> (SELECT embedding_model FROM collections WHERE id = sqlc.arg(collection_id));
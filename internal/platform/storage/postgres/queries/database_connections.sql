@@ -0,0 +1,16 @@
+-- Database connection queries for the PostgreSQL tool
+
+-- name: GetDatabaseConnectionByName :one
+SELECT * FROM database_connections
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetDefaultDatabaseConnection :one
+SELECT * FROM database_connections
+WHERE user_id = $1 AND is_default = true
+ORDER BY updated_at DESC
+LIMIT 1;
+
+-- name: ListDatabaseConnections :many
+SELECT * FROM database_connections
+WHERE user_id = $1
+ORDER BY is_default DESC, name ASC;
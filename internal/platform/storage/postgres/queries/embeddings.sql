@@ -1,8 +1,9 @@
 -- name: CreateEmbedding :one
-INSERT INTO embeddings (content_type, content_id, content_text, embedding, metadata)
-VALUES ($1, $2, $3, $4, $5)
-ON CONFLICT (content_type, content_id) 
-DO UPDATE SET 
+INSERT INTO embeddings (collection_id, content_type, content_id, content_text, embedding, metadata, model)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (content_type, content_id, model)
+DO UPDATE SET
+    collection_id = EXCLUDED.collection_id,
     content_text = EXCLUDED.content_text,
     embedding = EXCLUDED.embedding,
     metadata = EXCLUDED.metadata,
@@ -28,9 +29,11 @@ SELECT
     embedding, 
     metadata, 
     created_at,
-    1 - (embedding <=> sqlc.arg(query_embedding)::vector) AS similarity
+    (1 - (embedding <=> sqlc.arg(query_embedding)::vector))::float8 AS similarity
 FROM embeddings
 WHERE content_type = sqlc.arg(content_type)
+  AND model = sqlc.arg(model)
+  AND dimensions = vector_dims(sqlc.arg(query_embedding)::vector)
   AND 1 - (embedding <=> sqlc.arg(query_embedding)::vector) > sqlc.arg(threshold)::float8
 ORDER BY embedding <=> sqlc.arg(query_embedding)::vector
 LIMIT sqlc.arg(result_limit);
@@ -44,9 +47,11 @@ SELECT
     embedding, 
     metadata, 
     created_at,
-    1 - (embedding <=> $1::vector) AS similarity
+    (1 - (embedding <=> $1::vector))::float8 AS similarity
 FROM embeddings
 WHERE 1 - (embedding <=> $1::vector) > $2
+  AND model = $4
+  AND dimensions = vector_dims($1::vector)
 ORDER BY embedding <=> $1::vector
 LIMIT $3;
 
@@ -79,3 +84,77 @@ UPDATE embeddings
 SET content_text = $3, embedding = $4, metadata = $5
 WHERE content_type = $1 AND content_id = $2
 RETURNING id, content_type, content_id, content_text, embedding, metadata, created_at;
+
+-- name: UpdateEmbeddingMetadataByContent :exec
+UPDATE embeddings
+SET metadata = $3
+WHERE content_type = $1 AND content_id = $2;
+
+-- name: SearchEmbeddingsByVector :many
+-- Searches the vectors of the query's dimensions. Dimensions with an
+-- approximate index have their own branch, whose cast matches the index
+-- expression; the branches of other dimensions are skipped as a whole.
+SELECT id, content_type, content_id, content_text, embedding, metadata, created_at, similarity
+FROM (
+    (SELECT id, content_type, content_id, content_text, embedding, metadata, created_at,
+        (1 - (embedding::vector(768) <=> sqlc.arg(query_embedding)::vector))::float8 AS similarity
+    FROM embeddings
+    WHERE vector_dims(sqlc.arg(query_embedding)::vector) = 768 AND dimensions = 768
+      AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+      AND (cardinality(sqlc.arg(content_types)::text[]) = 0 OR content_type = ANY(sqlc.arg(content_types)::text[]))
+      AND (sqlc.arg(path_prefix)::text = '' OR metadata->>'path' LIKE sqlc.arg(path_prefix)::text || '%')
+      AND (cardinality(sqlc.arg(tags)::text[]) = 0 OR metadata->'tags' ?| sqlc.arg(tags)::text[])
+      AND (cardinality(sqlc.arg(collection_ids)::uuid[]) = 0 OR collection_id = ANY(sqlc.arg(collection_ids)::uuid[]))
+      AND 1 - (embedding <=> sqlc.arg(query_embedding)::vector) > sqlc.arg(threshold)::float8
+    ORDER BY embedding::vector(768) <=> sqlc.arg(query_embedding)::vector
+    LIMIT sqlc.arg(result_limit))
+    UNION ALL
+    (SELECT id, content_type, content_id, content_text, embedding, metadata, created_at,
+        (1 - (embedding::vector(1536) <=> sqlc.arg(query_embedding)::vector))::float8 AS similarity
+    FROM embeddings
+    WHERE vector_dims(sqlc.arg(query_embedding)::vector) = 1536 AND dimensions = 1536
+      AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+      AND (cardinality(sqlc.arg(content_types)::text[]) = 0 OR content_type = ANY(sqlc.arg(content_types)::text[]))
+      AND (sqlc.arg(path_prefix)::text = '' OR metadata->>'path' LIKE sqlc.arg(path_prefix)::text || '%')
+      AND (cardinality(sqlc.arg(tags)::text[]) = 0 OR metadata->'tags' ?| sqlc.arg(tags)::text[])
+      AND (cardinality(sqlc.arg(collection_ids)::uuid[]) = 0 OR collection_id = ANY(sqlc.arg(collection_ids)::uuid[]))
+      AND 1 - (embedding <=> sqlc.arg(query_embedding)::vector) > sqlc.arg(threshold)::float8
+    ORDER BY embedding::vector(1536) <=> sqlc.arg(query_embedding)::vector
+    LIMIT sqlc.arg(result_limit))
+    UNION ALL
+    (SELECT id, content_type, content_id, content_text, embedding, metadata, created_at,
+        (1 - (embedding <=> sqlc.arg(query_embedding)::vector))::float8 AS similarity
+    FROM embeddings
+    WHERE vector_dims(sqlc.arg(query_embedding)::vector) NOT IN (768, 1536)
+      AND dimensions = vector_dims(sqlc.arg(query_embedding)::vector)
+      AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+      AND (cardinality(sqlc.arg(content_types)::text[]) = 0 OR content_type = ANY(sqlc.arg(content_types)::text[]))
+      AND (sqlc.arg(path_prefix)::text = '' OR metadata->>'path' LIKE sqlc.arg(path_prefix)::text || '%')
+      AND (cardinality(sqlc.arg(tags)::text[]) = 0 OR metadata->'tags' ?| sqlc.arg(tags)::text[])
+      AND (cardinality(sqlc.arg(collection_ids)::uuid[]) = 0 OR collection_id = ANY(sqlc.arg(collection_ids)::uuid[]))
+      AND 1 - (embedding <=> sqlc.arg(query_embedding)::vector) > sqlc.arg(threshold)::float8
+    ORDER BY embedding <=> sqlc.arg(query_embedding)::vector
+    LIMIT sqlc.arg(result_limit))
+) AS matches
+ORDER BY similarity DESC
+LIMIT sqlc.arg(result_limit);
+
+-- name: SearchEmbeddingsByText :many
+SELECT
+    id,
+    content_type,
+    content_id,
+    content_text,
+    embedding,
+    metadata,
+    created_at,
+    ts_rank_cd(content_tsv, websearch_to_tsquery('simple', sqlc.arg(query)::text))::float8 AS rank
+FROM embeddings
+WHERE content_tsv @@ websearch_to_tsquery('simple', sqlc.arg(query)::text)
+  AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+  AND (cardinality(sqlc.arg(content_types)::text[]) = 0 OR content_type = ANY(sqlc.arg(content_types)::text[]))
+  AND (sqlc.arg(path_prefix)::text = '' OR metadata->>'path' LIKE sqlc.arg(path_prefix)::text || '%')
+  AND (cardinality(sqlc.arg(tags)::text[]) = 0 OR metadata->'tags' ?| sqlc.arg(tags)::text[])
+  AND (cardinality(sqlc.arg(collection_ids)::uuid[]) = 0 OR collection_id = ANY(sqlc.arg(collection_ids)::uuid[]))
+ORDER BY rank DESC
+LIMIT sqlc.arg(result_limit);
@@ -4,6 +4,7 @@
 INSERT INTO agent_executions (
     agent_type,
     user_id,
+    conversation_id,
     query,
     response,
     steps,
@@ -12,7 +13,7 @@ INSERT INTO agent_executions (
     error_message,
     metadata
 ) VALUES (
-    $1, $2::uuid, $3, $4, $5, $6, $7, $8, $9
+    $1, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10
 ) RETURNING *;
 
 -- name: GetAgentExecution :one
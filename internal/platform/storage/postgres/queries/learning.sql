@@ -56,6 +56,32 @@ SET feedback_score = $2,
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateLearningEventOutcome :one
+UPDATE learning_events
+SET outcome = $2,
+    feedback_score = COALESCE($3, feedback_score),
+    learning_metadata = learning_metadata || $4
+WHERE id = $1
+  AND user_id = $5::uuid
+RETURNING *;
+
+-- name: GetRoutingAccuracy :many
+SELECT
+    COALESCE(learning_metadata->>'source', 'unknown')::text as source,
+    COALESCE(output_data->>'agent_type', '')::text as agent_type,
+    COUNT(*) as decision_count,
+    COUNT(*) FILTER (WHERE outcome IS NOT NULL) as rated_count,
+    COUNT(*) FILTER (WHERE outcome = 'success') as success_count,
+    COUNT(*) FILTER (WHERE outcome = 'failure') as failure_count,
+    COALESCE(AVG(confidence), 0)::float8 as avg_confidence,
+    COALESCE(AVG(feedback_score), 0)::float8 as avg_feedback_score
+FROM learning_events
+WHERE event_type = 'routing_decision'
+  AND created_at >= $1
+  AND ($2::uuid IS NULL OR user_id = $2::uuid)
+GROUP BY 1, 2
+ORDER BY 1, 2;
+
 -- name: DeleteOldLearningEvents :exec
 DELETE FROM learning_events
 WHERE user_id = $1::uuid
@@ -0,0 +1,19 @@
+-- Search cache queries for the web search tool
+
+-- name: GetSearchCache :one
+SELECT * FROM search_cache
+WHERE query_hash = $1 AND expires_at > NOW();
+
+-- name: UpsertSearchCache :exec
+INSERT INTO search_cache (query_hash, query_text, results, source, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (query_hash) DO UPDATE SET
+    query_text = EXCLUDED.query_text,
+    results = EXCLUDED.results,
+    source = EXCLUDED.source,
+    expires_at = EXCLUDED.expires_at,
+    created_at = NOW();
+
+-- name: DeleteExpiredSearchCache :execrows
+DELETE FROM search_cache
+WHERE expires_at <= NOW();
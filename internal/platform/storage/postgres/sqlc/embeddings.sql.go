@@ -15,10 +15,11 @@ import (
 )
 
 const CreateEmbedding = `-- name: CreateEmbedding :one
-INSERT INTO embeddings (content_type, content_id, content_text, embedding, metadata)
-VALUES ($1, $2, $3, $4, $5)
-ON CONFLICT (content_type, content_id) 
-DO UPDATE SET 
+INSERT INTO embeddings (collection_id, content_type, content_id, content_text, embedding, metadata, model)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (content_type, content_id, model)
+DO UPDATE SET
+    collection_id = EXCLUDED.collection_id,
     content_text = EXCLUDED.content_text,
     embedding = EXCLUDED.embedding,
     metadata = EXCLUDED.metadata,
@@ -27,20 +28,24 @@ RETURNING id, content_type, content_id, content_text, embedding, metadata, creat
 `
 
 type CreateEmbeddingParams struct {
-	ContentType string          `json:"content_type"`
-	ContentID   pgtype.UUID     `json:"content_id"`
-	ContentText string          `json:"content_text"`
-	Embedding   pgvector.Vector `json:"embedding"`
-	Metadata    json.RawMessage `json:"metadata"`
+	CollectionID pgtype.UUID     `json:"collection_id"`
+	ContentType  string          `json:"content_type"`
+	ContentID    pgtype.UUID     `json:"content_id"`
+	ContentText  string          `json:"content_text"`
+	Embedding    pgvector.Vector `json:"embedding"`
+	Metadata     json.RawMessage `json:"metadata"`
+	Model        string          `json:"model"`
 }
 
 func (q *Queries) CreateEmbedding(ctx context.Context, arg CreateEmbeddingParams) (*Embedding, error) {
 	row := q.db.QueryRow(ctx, CreateEmbedding,
+		arg.CollectionID,
 		arg.ContentType,
 		arg.ContentID,
 		arg.ContentText,
 		arg.Embedding,
 		arg.Metadata,
+		arg.Model,
 	)
 	var i Embedding
 	err := row.Scan(
@@ -194,6 +199,191 @@ func (q *Queries) GetEmbeddingsByType(ctx context.Context, arg GetEmbeddingsByTy
 	return items, nil
 }
 
+const SearchEmbeddingsByText = `-- name: SearchEmbeddingsByText :many
+SELECT
+    id,
+    content_type,
+    content_id,
+    content_text,
+    embedding,
+    metadata,
+    created_at,
+    ts_rank_cd(content_tsv, websearch_to_tsquery('simple', $1::text))::float8 AS rank
+FROM embeddings
+WHERE content_tsv @@ websearch_to_tsquery('simple', $1::text)
+  AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+  AND (cardinality($2::text[]) = 0 OR content_type = ANY($2::text[]))
+  AND ($3::text = '' OR metadata->>'path' LIKE $3::text || '%')
+  AND (cardinality($4::text[]) = 0 OR metadata->'tags' ?| $4::text[])
+  AND (cardinality($5::uuid[]) = 0 OR collection_id = ANY($5::uuid[]))
+ORDER BY rank DESC
+LIMIT $6
+`
+
+type SearchEmbeddingsByTextParams struct {
+	Query         string        `json:"query"`
+	ContentTypes  []string      `json:"content_types"`
+	PathPrefix    string        `json:"path_prefix"`
+	Tags          []string      `json:"tags"`
+	CollectionIds []pgtype.UUID `json:"collection_ids"`
+	ResultLimit   int32         `json:"result_limit"`
+}
+
+type SearchEmbeddingsByTextRow struct {
+	ID          pgtype.UUID     `json:"id"`
+	ContentType string          `json:"content_type"`
+	ContentID   pgtype.UUID     `json:"content_id"`
+	ContentText string          `json:"content_text"`
+	Embedding   pgvector.Vector `json:"embedding"`
+	Metadata    json.RawMessage `json:"metadata"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Rank        float64         `json:"rank"`
+}
+
+func (q *Queries) SearchEmbeddingsByText(ctx context.Context, arg SearchEmbeddingsByTextParams) ([]*SearchEmbeddingsByTextRow, error) {
+	rows, err := q.db.Query(ctx, SearchEmbeddingsByText,
+		arg.Query,
+		arg.ContentTypes,
+		arg.PathPrefix,
+		arg.Tags,
+		arg.CollectionIds,
+		arg.ResultLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*SearchEmbeddingsByTextRow{}
+	for rows.Next() {
+		var i SearchEmbeddingsByTextRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContentType,
+			&i.ContentID,
+			&i.ContentText,
+			&i.Embedding,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const SearchEmbeddingsByVector = `-- name: SearchEmbeddingsByVector :many
+SELECT id, content_type, content_id, content_text, embedding, metadata, created_at, similarity
+FROM (
+    (SELECT id, content_type, content_id, content_text, embedding, metadata, created_at,
+        (1 - (embedding::vector(768) <=> $1::vector))::float8 AS similarity
+    FROM embeddings
+    WHERE vector_dims($1::vector) = 768 AND dimensions = 768
+      AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+      AND (cardinality($2::text[]) = 0 OR content_type = ANY($2::text[]))
+      AND ($3::text = '' OR metadata->>'path' LIKE $3::text || '%')
+      AND (cardinality($4::text[]) = 0 OR metadata->'tags' ?| $4::text[])
+      AND (cardinality($5::uuid[]) = 0 OR collection_id = ANY($5::uuid[]))
+      AND 1 - (embedding <=> $1::vector) > $6::float8
+    ORDER BY embedding::vector(768) <=> $1::vector
+    LIMIT $7)
+    UNION ALL
+    (SELECT id, content_type, content_id, content_text, embedding, metadata, created_at,
+        (1 - (embedding::vector(1536) <=> $1::vector))::float8 AS similarity
+    FROM embeddings
+    WHERE vector_dims($1::vector) = 1536 AND dimensions = 1536
+      AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+      AND (cardinality($2::text[]) = 0 OR content_type = ANY($2::text[]))
+      AND ($3::text = '' OR metadata->>'path' LIKE $3::text || '%')
+      AND (cardinality($4::text[]) = 0 OR metadata->'tags' ?| $4::text[])
+      AND (cardinality($5::uuid[]) = 0 OR collection_id = ANY($5::uuid[]))
+      AND 1 - (embedding <=> $1::vector) > $6::float8
+    ORDER BY embedding::vector(1536) <=> $1::vector
+    LIMIT $7)
+    UNION ALL
+    (SELECT id, content_type, content_id, content_text, embedding, metadata, created_at,
+        (1 - (embedding <=> $1::vector))::float8 AS similarity
+    FROM embeddings
+    WHERE vector_dims($1::vector) NOT IN (768, 1536)
+      AND dimensions = vector_dims($1::vector)
+      AND EXISTS (SELECT 1 FROM collections c WHERE c.id = embeddings.collection_id AND c.embedding_model = embeddings.model)
+      AND (cardinality($2::text[]) = 0 OR content_type = ANY($2::text[]))
+      AND ($3::text = '' OR metadata->>'path' LIKE $3::text || '%')
+      AND (cardinality($4::text[]) = 0 OR metadata->'tags' ?| $4::text[])
+      AND (cardinality($5::uuid[]) = 0 OR collection_id = ANY($5::uuid[]))
+      AND 1 - (embedding <=> $1::vector) > $6::float8
+    ORDER BY embedding <=> $1::vector
+    LIMIT $7)
+) AS matches
+ORDER BY similarity DESC
+LIMIT $7
+`
+
+type SearchEmbeddingsByVectorParams struct {
+	QueryEmbedding pgvector.Vector `json:"query_embedding"`
+	ContentTypes   []string        `json:"content_types"`
+	PathPrefix     string          `json:"path_prefix"`
+	Tags           []string        `json:"tags"`
+	CollectionIds  []pgtype.UUID   `json:"collection_ids"`
+	Threshold      float64         `json:"threshold"`
+	ResultLimit    int32           `json:"result_limit"`
+}
+
+type SearchEmbeddingsByVectorRow struct {
+	ID          pgtype.UUID     `json:"id"`
+	ContentType string          `json:"content_type"`
+	ContentID   pgtype.UUID     `json:"content_id"`
+	ContentText string          `json:"content_text"`
+	Embedding   pgvector.Vector `json:"embedding"`
+	Metadata    json.RawMessage `json:"metadata"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Similarity  float64         `json:"similarity"`
+}
+
+// Searches the vectors of the query's dimensions. Dimensions with an
+// approximate index have their own branch, whose cast matches the index
+// expression; the branches of other dimensions are skipped as a whole.
+func (q *Queries) SearchEmbeddingsByVector(ctx context.Context, arg SearchEmbeddingsByVectorParams) ([]*SearchEmbeddingsByVectorRow, error) {
+	rows, err := q.db.Query(ctx, SearchEmbeddingsByVector,
+		arg.QueryEmbedding,
+		arg.ContentTypes,
+		arg.PathPrefix,
+		arg.Tags,
+		arg.CollectionIds,
+		arg.Threshold,
+		arg.ResultLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*SearchEmbeddingsByVectorRow{}
+	for rows.Next() {
+		var i SearchEmbeddingsByVectorRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContentType,
+			&i.ContentID,
+			&i.ContentText,
+			&i.Embedding,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const SearchSimilarEmbeddings = `-- name: SearchSimilarEmbeddings :many
 SELECT 
     id, 
@@ -203,17 +393,20 @@ SELECT
     embedding, 
     metadata, 
     created_at,
-    1 - (embedding <=> $1::vector) AS similarity
+    (1 - (embedding <=> $1::vector))::float8 AS similarity
 FROM embeddings
 WHERE content_type = $2
-  AND 1 - (embedding <=> $1::vector) > $3::float8
+  AND model = $3
+  AND dimensions = vector_dims($1::vector)
+  AND 1 - (embedding <=> $1::vector) > $4::float8
 ORDER BY embedding <=> $1::vector
-LIMIT $4
+LIMIT $5
 `
 
 type SearchSimilarEmbeddingsParams struct {
 	QueryEmbedding pgvector.Vector `json:"query_embedding"`
 	ContentType    string          `json:"content_type"`
+	Model          string          `json:"model"`
 	Threshold      float64         `json:"threshold"`
 	ResultLimit    int32           `json:"result_limit"`
 }
@@ -226,13 +419,14 @@ type SearchSimilarEmbeddingsRow struct {
 	Embedding   pgvector.Vector `json:"embedding"`
 	Metadata    json.RawMessage `json:"metadata"`
 	CreatedAt   time.Time       `json:"created_at"`
-	Similarity  int32           `json:"similarity"`
+	Similarity  float64         `json:"similarity"`
 }
 
 func (q *Queries) SearchSimilarEmbeddings(ctx context.Context, arg SearchSimilarEmbeddingsParams) ([]*SearchSimilarEmbeddingsRow, error) {
 	rows, err := q.db.Query(ctx, SearchSimilarEmbeddings,
 		arg.QueryEmbedding,
 		arg.ContentType,
+		arg.Model,
 		arg.Threshold,
 		arg.ResultLimit,
 	)
@@ -272,9 +466,11 @@ SELECT
     embedding, 
     metadata, 
     created_at,
-    1 - (embedding <=> $1::vector) AS similarity
+    (1 - (embedding <=> $1::vector))::float8 AS similarity
 FROM embeddings
 WHERE 1 - (embedding <=> $1::vector) > $2
+  AND model = $4
+  AND dimensions = vector_dims($1::vector)
 ORDER BY embedding <=> $1::vector
 LIMIT $3
 `
@@ -283,6 +479,7 @@ type SearchSimilarEmbeddingsAllTypesParams struct {
 	Column1   pgvector.Vector `json:"column_1"`
 	Embedding pgvector.Vector `json:"embedding"`
 	Limit     int32           `json:"limit"`
+	Model     string          `json:"model"`
 }
 
 type SearchSimilarEmbeddingsAllTypesRow struct {
@@ -293,11 +490,16 @@ type SearchSimilarEmbeddingsAllTypesRow struct {
 	Embedding   pgvector.Vector `json:"embedding"`
 	Metadata    json.RawMessage `json:"metadata"`
 	CreatedAt   time.Time       `json:"created_at"`
-	Similarity  int32           `json:"similarity"`
+	Similarity  float64         `json:"similarity"`
 }
 
 func (q *Queries) SearchSimilarEmbeddingsAllTypes(ctx context.Context, arg SearchSimilarEmbeddingsAllTypesParams) ([]*SearchSimilarEmbeddingsAllTypesRow, error) {
-	rows, err := q.db.Query(ctx, SearchSimilarEmbeddingsAllTypes, arg.Column1, arg.Embedding, arg.Limit)
+	rows, err := q.db.Query(ctx, SearchSimilarEmbeddingsAllTypes,
+		arg.Column1,
+		arg.Embedding,
+		arg.Limit,
+		arg.Model,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -360,3 +562,20 @@ func (q *Queries) UpdateEmbedding(ctx context.Context, arg UpdateEmbeddingParams
 	)
 	return &i, err
 }
+
+const UpdateEmbeddingMetadataByContent = `-- name: UpdateEmbeddingMetadataByContent :exec
+UPDATE embeddings
+SET metadata = $3
+WHERE content_type = $1 AND content_id = $2
+`
+
+type UpdateEmbeddingMetadataByContentParams struct {
+	ContentType string          `json:"content_type"`
+	ContentID   pgtype.UUID     `json:"content_id"`
+	Metadata    json.RawMessage `json:"metadata"`
+}
+
+func (q *Queries) UpdateEmbeddingMetadataByContent(ctx context.Context, arg UpdateEmbeddingMetadataByContentParams) error {
+	_, err := q.db.Exec(ctx, UpdateEmbeddingMetadataByContent, arg.ContentType, arg.ContentID, arg.Metadata)
+	return err
+}
@@ -18,6 +18,7 @@ const CreateAgentExecution = `-- name: CreateAgentExecution :one
 INSERT INTO agent_executions (
     agent_type,
     user_id,
+    conversation_id,
     query,
     response,
     steps,
@@ -26,13 +27,14 @@ INSERT INTO agent_executions (
     error_message,
     metadata
 ) VALUES (
-    $1, $2::uuid, $3, $4, $5, $6, $7, $8, $9
+    $1, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10
 ) RETURNING id, agent_type, user_id, conversation_id, query, response, steps, execution_time_ms, success, error_message, created_at, metadata
 `
 
 type CreateAgentExecutionParams struct {
 	AgentType       string          `json:"agent_type"`
 	Column2         pgtype.UUID     `json:"column_2"`
+	ConversationID  pgtype.UUID     `json:"conversation_id"`
 	Query           string          `json:"query"`
 	Response        pgtype.Text     `json:"response"`
 	Steps           []byte          `json:"steps"`
@@ -47,6 +49,7 @@ func (q *Queries) CreateAgentExecution(ctx context.Context, arg CreateAgentExecu
 	row := q.db.QueryRow(ctx, CreateAgentExecution,
 		arg.AgentType,
 		arg.Column2,
+		arg.ConversationID,
 		arg.Query,
 		arg.Response,
 		arg.Steps,
@@ -513,6 +513,69 @@ func (q *Queries) GetLearningEventsByCorrelation(ctx context.Context, dollar_1 p
 	return items, nil
 }
 
+const GetRoutingAccuracy = `-- name: GetRoutingAccuracy :many
+SELECT
+    COALESCE(learning_metadata->>'source', 'unknown')::text as source,
+    COALESCE(output_data->>'agent_type', '')::text as agent_type,
+    COUNT(*) as decision_count,
+    COUNT(*) FILTER (WHERE outcome IS NOT NULL) as rated_count,
+    COUNT(*) FILTER (WHERE outcome = 'success') as success_count,
+    COUNT(*) FILTER (WHERE outcome = 'failure') as failure_count,
+    COALESCE(AVG(confidence), 0)::float8 as avg_confidence,
+    COALESCE(AVG(feedback_score), 0)::float8 as avg_feedback_score
+FROM learning_events
+WHERE event_type = 'routing_decision'
+  AND created_at >= $1
+  AND ($2::uuid IS NULL OR user_id = $2::uuid)
+GROUP BY 1, 2
+ORDER BY 1, 2
+`
+
+type GetRoutingAccuracyParams struct {
+	CreatedAt time.Time   `json:"created_at"`
+	Column2   pgtype.UUID `json:"column_2"`
+}
+
+type GetRoutingAccuracyRow struct {
+	Source           string  `json:"source"`
+	AgentType        string  `json:"agent_type"`
+	DecisionCount    int64   `json:"decision_count"`
+	RatedCount       int64   `json:"rated_count"`
+	SuccessCount     int64   `json:"success_count"`
+	FailureCount     int64   `json:"failure_count"`
+	AvgConfidence    float64 `json:"avg_confidence"`
+	AvgFeedbackScore float64 `json:"avg_feedback_score"`
+}
+
+func (q *Queries) GetRoutingAccuracy(ctx context.Context, arg GetRoutingAccuracyParams) ([]*GetRoutingAccuracyRow, error) {
+	rows, err := q.db.Query(ctx, GetRoutingAccuracy, arg.CreatedAt, arg.Column2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetRoutingAccuracyRow{}
+	for rows.Next() {
+		var i GetRoutingAccuracyRow
+		if err := rows.Scan(
+			&i.Source,
+			&i.AgentType,
+			&i.DecisionCount,
+			&i.RatedCount,
+			&i.SuccessCount,
+			&i.FailureCount,
+			&i.AvgConfidence,
+			&i.AvgFeedbackScore,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetSkillProgression = `-- name: GetSkillProgression :one
 SELECT 
     skill_name,
@@ -836,6 +899,52 @@ func (q *Queries) UpdateLearningEventFeedback(ctx context.Context, arg UpdateLea
 	return &i, err
 }
 
+const UpdateLearningEventOutcome = `-- name: UpdateLearningEventOutcome :one
+UPDATE learning_events
+SET outcome = $2,
+    feedback_score = COALESCE($3, feedback_score),
+    learning_metadata = learning_metadata || $4
+WHERE id = $1
+  AND user_id = $5::uuid
+RETURNING id, user_id, event_type, context, input_data, output_data, outcome, confidence, feedback_score, learning_metadata, duration_ms, created_at, session_id, correlation_id
+`
+
+type UpdateLearningEventOutcomeParams struct {
+	ID               pgtype.UUID `json:"id"`
+	Outcome          pgtype.Text `json:"outcome"`
+	FeedbackScore    pgtype.Int4 `json:"feedback_score"`
+	LearningMetadata []byte      `json:"learning_metadata"`
+	Column5          pgtype.UUID `json:"column_5"`
+}
+
+func (q *Queries) UpdateLearningEventOutcome(ctx context.Context, arg UpdateLearningEventOutcomeParams) (*LearningEvent, error) {
+	row := q.db.QueryRow(ctx, UpdateLearningEventOutcome,
+		arg.ID,
+		arg.Outcome,
+		arg.FeedbackScore,
+		arg.LearningMetadata,
+		arg.Column5,
+	)
+	var i LearningEvent
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.EventType,
+		&i.Context,
+		&i.InputData,
+		&i.OutputData,
+		&i.Outcome,
+		&i.Confidence,
+		&i.FeedbackScore,
+		&i.LearningMetadata,
+		&i.DurationMs,
+		&i.CreatedAt,
+		&i.SessionID,
+		&i.CorrelationID,
+	)
+	return &i, err
+}
+
 const UpdatePatternOutcome = `-- name: UpdatePatternOutcome :one
 UPDATE learned_patterns
 SET positive_outcomes = positive_outcomes + $2,
@@ -96,6 +96,22 @@ type AiProviderUsage struct {
 	CreatedAt     time.Time   `json:"created_at"`
 }
 
+type Artifact struct {
+	ID              pgtype.UUID        `json:"id"`
+	Hash            string             `json:"hash"`
+	Name            string             `json:"name"`
+	Type            string             `json:"type"`
+	ContentType     string             `json:"content_type"`
+	Size            int64              `json:"size"`
+	ToolName        string             `json:"tool_name"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	ConversationID  pgtype.UUID        `json:"conversation_id"`
+	MessageID       pgtype.UUID        `json:"message_id"`
+	ToolExecutionID pgtype.UUID        `json:"tool_execution_id"`
+	CreatedAt       time.Time          `json:"created_at"`
+	ExpiresAt       pgtype.Timestamptz `json:"expires_at"`
+}
+
 type ChainExecution struct {
 	ID              pgtype.UUID     `json:"id"`
 	ChainType       string          `json:"chain_type"`
@@ -127,6 +143,17 @@ type CloudflareAccount struct {
 	UpdatedAt time.Time       `json:"updated_at"`
 }
 
+type CodeIndexFile struct {
+	ID          pgtype.UUID   `json:"id"`
+	Collection  string        `json:"collection"`
+	Root        string        `json:"root"`
+	Path        string        `json:"path"`
+	ContentHash string        `json:"content_hash"`
+	ChunkIds    []pgtype.UUID `json:"chunk_ids"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
 type CodePattern struct {
 	ID               pgtype.UUID        `json:"id"`
 	UserID           pgtype.UUID        `json:"user_id"`
@@ -142,6 +169,37 @@ type CodePattern struct {
 	UpdatedAt        time.Time          `json:"updated_at"`
 }
 
+type Collection struct {
+	ID                pgtype.UUID `json:"id"`
+	Name              string      `json:"name"`
+	Description       string      `json:"description"`
+	OwnerID           pgtype.UUID `json:"owner_id"`
+	EmbeddingProvider string      `json:"embedding_provider"`
+	ChunkSize         int32       `json:"chunk_size"`
+	ChunkOverlap      int32       `json:"chunk_overlap"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+	EmbeddingModel    string      `json:"embedding_model"`
+}
+
+type CollectionDocument struct {
+	ID           pgtype.UUID   `json:"id"`
+	CollectionID pgtype.UUID   `json:"collection_id"`
+	Source       string        `json:"source"`
+	ContentHash  string        `json:"content_hash"`
+	ChunkIds     []pgtype.UUID `json:"chunk_ids"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+type CollectionGrant struct {
+	CollectionID pgtype.UUID `json:"collection_id"`
+	GranteeType  string      `json:"grantee_type"`
+	GranteeID    string      `json:"grantee_id"`
+	Permission   string      `json:"permission"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
 type Conversation struct {
 	ID         pgtype.UUID     `json:"id"`
 	UserID     pgtype.UUID     `json:"user_id"`
@@ -196,13 +254,16 @@ type DockerConnection struct {
 }
 
 type Embedding struct {
-	ID          pgtype.UUID     `json:"id"`
-	ContentType string          `json:"content_type"`
-	ContentID   pgtype.UUID     `json:"content_id"`
-	ContentText string          `json:"content_text"`
-	Embedding   pgvector.Vector `json:"embedding"`
-	Metadata    json.RawMessage `json:"metadata"`
-	CreatedAt   time.Time       `json:"created_at"`
+	ID           pgtype.UUID     `json:"id"`
+	ContentType  string          `json:"content_type"`
+	ContentID    pgtype.UUID     `json:"content_id"`
+	ContentText  string          `json:"content_text"`
+	Embedding    pgvector.Vector `json:"embedding"`
+	Metadata     json.RawMessage `json:"metadata"`
+	CreatedAt    time.Time       `json:"created_at"`
+	CollectionID pgtype.UUID     `json:"collection_id"`
+	Model        string          `json:"model"`
+	Dimensions   pgtype.Int4     `json:"dimensions"`
 }
 
 type EpisodicMemory struct {
@@ -237,6 +298,20 @@ type EventProjection struct {
 	UpdatedAt            time.Time          `json:"updated_at"`
 }
 
+type IngestionJob struct {
+	ID           pgtype.UUID        `json:"id"`
+	CollectionID pgtype.UUID        `json:"collection_id"`
+	Source       string             `json:"source"`
+	Status       string             `json:"status"`
+	Documents    int32              `json:"documents"`
+	Chunks       int32              `json:"chunks"`
+	Error        string             `json:"error"`
+	CreatedBy    pgtype.UUID        `json:"created_by"`
+	CreatedAt    time.Time          `json:"created_at"`
+	StartedAt    pgtype.Timestamptz `json:"started_at"`
+	FinishedAt   pgtype.Timestamptz `json:"finished_at"`
+}
+
 type KnowledgeEdge struct {
 	ID            pgtype.UUID        `json:"id"`
 	UserID        pgtype.UUID        `json:"user_id"`
@@ -385,6 +460,23 @@ type ProceduralMemory struct {
 	UpdatedAt            time.Time          `json:"updated_at"`
 }
 
+type ReembeddingJob struct {
+	ID           pgtype.UUID        `json:"id"`
+	CollectionID pgtype.UUID        `json:"collection_id"`
+	Provider     string             `json:"provider"`
+	Model        string             `json:"model"`
+	Status       string             `json:"status"`
+	Total        int32              `json:"total"`
+	Done         int32              `json:"done"`
+	Dimensions   int32              `json:"dimensions"`
+	Error        string             `json:"error"`
+	CreatedBy    pgtype.UUID        `json:"created_by"`
+	CreatedAt    time.Time          `json:"created_at"`
+	StartedAt    pgtype.Timestamptz `json:"started_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	FinishedAt   pgtype.Timestamptz `json:"finished_at"`
+}
+
 type SearchCache struct {
 	ID        pgtype.UUID        `json:"id"`
 	QueryHash string             `json:"query_hash"`
@@ -15,6 +15,11 @@ type Querier interface {
 	AddCausalLink(ctx context.Context, arg AddCausalLinkParams) (*EpisodicMemory, error)
 	AddFavoriteTool(ctx context.Context, arg AddFavoriteToolParams) (*AddFavoriteToolRow, error)
 	AddSkillLearningPoint(ctx context.Context, arg AddSkillLearningPointParams) (*UserSkill, error)
+	// Makes a model the active model of a collection without one and records
+	// it on the collection's vectors stored before models were, unless the
+	// chunk already has a vector of the model. Reports whether the collection
+	// adopted the model.
+	AdoptCollectionModel(ctx context.Context, arg AdoptCollectionModelParams) (bool, error)
 	ArchiveConversation(ctx context.Context, id pgtype.UUID) error
 	// =====================================================
 	// MEMORY LIFECYCLE OPERATIONS
@@ -39,8 +44,10 @@ type Querier interface {
 	ConsolidateMemoryEntries(ctx context.Context, arg ConsolidateMemoryEntriesParams) ([]*ConsolidateMemoryEntriesRow, error)
 	ConsolidateWorkingMemory(ctx context.Context, arg ConsolidateWorkingMemoryParams) ([]*ConsolidateWorkingMemoryRow, error)
 	CountActiveUsers(ctx context.Context) (int64, error)
+	CountArtifactsByHash(ctx context.Context, hash string) (int64, error)
 	// Count embeddings by content type
 	CountEmbeddingsByType(ctx context.Context, contentType string) (int64, error)
+	CountReembeddingProgress(ctx context.Context, arg CountReembeddingProgressParams) (*CountReembeddingProgressRow, error)
 	// =====================================================
 	// AGENT COLLABORATIONS QUERIES
 	// =====================================================
@@ -52,12 +59,16 @@ type Querier interface {
 	CreateAgentDefinition(ctx context.Context, arg CreateAgentDefinitionParams) (*AgentDefinition, error)
 	// Agent execution queries
 	CreateAgentExecution(ctx context.Context, arg CreateAgentExecutionParams) (*AgentExecution, error)
+	// Artifact metadata for files produced by tools
+	CreateArtifact(ctx context.Context, arg CreateArtifactParams) (*Artifact, error)
 	// Chain execution queries
 	CreateChainExecution(ctx context.Context, arg CreateChainExecutionParams) (*ChainExecution, error)
 	// =====================================================
 	// CODE PATTERNS QUERIES
 	// =====================================================
 	CreateCodePattern(ctx context.Context, arg CreateCodePatternParams) (*CodePattern, error)
+	CreateCollection(ctx context.Context, arg CreateCollectionParams) (*Collection, error)
+	CreateCollectionEmbedding(ctx context.Context, arg CreateCollectionEmbeddingParams) error
 	CreateConversation(ctx context.Context, arg CreateConversationParams) (*Conversation, error)
 	// Development workflow queries
 	// =====================================================
@@ -74,6 +85,7 @@ type Querier interface {
 	// EVENT PROJECTIONS QUERIES
 	// =====================================================
 	CreateEventProjection(ctx context.Context, arg CreateEventProjectionParams) (*EventProjection, error)
+	CreateIngestionJob(ctx context.Context, arg CreateIngestionJobParams) (*IngestionJob, error)
 	// =====================================================
 	// KNOWLEDGE EDGES QUERIES
 	// =====================================================
@@ -112,6 +124,7 @@ type Querier interface {
 	// PROCEDURAL MEMORIES QUERIES
 	// =====================================================
 	CreateProceduralMemory(ctx context.Context, arg CreateProceduralMemoryParams) (*ProceduralMemory, error)
+	CreateReembeddingJob(ctx context.Context, arg CreateReembeddingJobParams) (*ReembeddingJob, error)
 	// =====================================================
 	// SEMANTIC MEMORIES QUERIES
 	// =====================================================
@@ -146,6 +159,11 @@ type Querier interface {
 	DeactivateUser(ctx context.Context, id pgtype.UUID) (*DeactivateUserRow, error)
 	DeleteAgentExecution(ctx context.Context, id pgtype.UUID) error
 	DeleteChainExecution(ctx context.Context, id pgtype.UUID) error
+	DeleteCodeIndexFile(ctx context.Context, arg DeleteCodeIndexFileParams) error
+	DeleteCollection(ctx context.Context, id pgtype.UUID) error
+	DeleteCollectionDocument(ctx context.Context, id pgtype.UUID) error
+	DeleteCollectionEmbeddings(ctx context.Context, arg DeleteCollectionEmbeddingsParams) error
+	DeleteCollectionGrant(ctx context.Context, arg DeleteCollectionGrantParams) error
 	DeleteConversation(ctx context.Context, id pgtype.UUID) error
 	DeleteDecayedMemories(ctx context.Context, arg DeleteDecayedMemoriesParams) error
 	DeleteEmbedding(ctx context.Context, arg DeleteEmbeddingParams) error
@@ -154,12 +172,16 @@ type Querier interface {
 	DeleteEmbeddingsByContentType(ctx context.Context, contentType string) error
 	// Delete embeddings matching specific metadata criteria
 	DeleteEmbeddingsByMetadata(ctx context.Context, dollar_1 []byte) error
+	DeleteExpiredArtifacts(ctx context.Context) ([]string, error)
 	// Delete embeddings older than a specific date
 	DeleteExpiredEmbeddings(ctx context.Context, arg DeleteExpiredEmbeddingsParams) error
 	// Deletes expired memory entries and returns count
 	DeleteExpiredMemoryEntries(ctx context.Context) (int64, error)
+	DeleteExpiredSearchCache(ctx context.Context) (int64, error)
 	DeleteExpiredToolCache(ctx context.Context) error
 	DeleteExpiredUserContext(ctx context.Context) error
+	// Deletes the vectors a cancelled re-embedding stored
+	DeleteInactiveModelEmbeddings(ctx context.Context, arg DeleteInactiveModelEmbeddingsParams) error
 	// Deletes memory entries by user with optional filters
 	DeleteMemoryEntriesByUser(ctx context.Context, arg DeleteMemoryEntriesByUserParams) (int64, error)
 	// Deletes a memory entry by ID
@@ -180,6 +202,8 @@ type Querier interface {
 	EvolvePattern(ctx context.Context, arg EvolvePatternParams) (*CodePattern, error)
 	ExtendWorkingMemoryExpiry(ctx context.Context, arg ExtendWorkingMemoryExpiryParams) (*WorkingMemory, error)
 	FindDirectConnections(ctx context.Context, arg FindDirectConnectionsParams) ([]*FindDirectConnectionsRow, error)
+	FinishIngestionJob(ctx context.Context, arg FinishIngestionJobParams) error
+	FinishReembeddingJob(ctx context.Context, arg FinishReembeddingJobParams) error
 	GetActiveCollaborations(ctx context.Context, dollar_1 pgtype.UUID) ([]*GetActiveCollaborationsRow, error)
 	GetActiveConversations(ctx context.Context, arg GetActiveConversationsParams) ([]*Conversation, error)
 	GetActiveSessions(ctx context.Context, dollar_1 pgtype.UUID) ([]*DevelopmentSession, error)
@@ -201,6 +225,7 @@ type Querier interface {
 	GetAllUserContext(ctx context.Context, dollar_1 pgtype.UUID) ([]*UserContext, error)
 	GetAllUserPreferences(ctx context.Context, dollar_1 pgtype.UUID) ([]*UserPreference, error)
 	GetArchivedConversations(ctx context.Context, userID pgtype.UUID) ([]*Conversation, error)
+	GetArtifact(ctx context.Context, id pgtype.UUID) (*Artifact, error)
 	GetAutomatableProcedures(ctx context.Context, arg GetAutomatableProceduresParams) ([]*ProceduralMemory, error)
 	GetChainExecution(ctx context.Context, id pgtype.UUID) (*ChainExecution, error)
 	GetChainExecutionStats(ctx context.Context, arg GetChainExecutionStatsParams) (*GetChainExecutionStatsRow, error)
@@ -214,10 +239,16 @@ type Querier interface {
 	GetCollaborationStatistics(ctx context.Context, arg GetCollaborationStatisticsParams) (*GetCollaborationStatisticsRow, error)
 	GetCollaborationTrends(ctx context.Context, arg GetCollaborationTrendsParams) ([]*GetCollaborationTrendsRow, error)
 	GetCollaborationsByAgent(ctx context.Context, arg GetCollaborationsByAgentParams) ([]*GetCollaborationsByAgentRow, error)
+	GetCollection(ctx context.Context, id pgtype.UUID) (*Collection, error)
+	GetCollectionByName(ctx context.Context, name string) (*Collection, error)
+	GetCollectionDocument(ctx context.Context, id pgtype.UUID) (*CollectionDocument, error)
 	GetConnectedNodes(ctx context.Context, arg GetConnectedNodesParams) ([]*GetConnectedNodesRow, error)
 	GetConversation(ctx context.Context, id pgtype.UUID) (*Conversation, error)
 	GetConversationCount(ctx context.Context, userID pgtype.UUID) (int64, error)
 	GetConversationsByUser(ctx context.Context, userID pgtype.UUID) ([]*Conversation, error)
+	// Database connection queries for the PostgreSQL tool
+	GetDatabaseConnectionByName(ctx context.Context, arg GetDatabaseConnectionByNameParams) (*DatabaseConnection, error)
+	GetDefaultDatabaseConnection(ctx context.Context, userID pgtype.UUID) (*DatabaseConnection, error)
 	GetDevelopmentSession(ctx context.Context, id pgtype.UUID) (*DevelopmentSession, error)
 	GetDevelopmentSessions(ctx context.Context, arg GetDevelopmentSessionsParams) ([]*DevelopmentSession, error)
 	GetEdgesByType(ctx context.Context, arg GetEdgesByTypeParams) ([]*GetEdgesByTypeRow, error)
@@ -240,6 +271,7 @@ type Querier interface {
 	// =====================================================
 	GetGraphStatistics(ctx context.Context, dollar_1 pgtype.UUID) (*GetGraphStatisticsRow, error)
 	GetHighlyConnectedNodes(ctx context.Context, arg GetHighlyConnectedNodesParams) ([]*GetHighlyConnectedNodesRow, error)
+	GetIngestionJob(ctx context.Context, id pgtype.UUID) (*IngestionJob, error)
 	GetKnowledgeEdge(ctx context.Context, id pgtype.UUID) (*KnowledgeEdge, error)
 	GetKnowledgeEdges(ctx context.Context, arg GetKnowledgeEdgesParams) ([]*GetKnowledgeEdgesRow, error)
 	GetKnowledgeEvolution(ctx context.Context, arg GetKnowledgeEvolutionParams) ([]*KnowledgeEvolution, error)
@@ -303,8 +335,12 @@ type Querier interface {
 	GetRecentEvents(ctx context.Context, arg GetRecentEventsParams) ([]*SystemEvent, error)
 	GetRecentMessages(ctx context.Context, arg GetRecentMessagesParams) ([]*GetRecentMessagesRow, error)
 	GetRecentToolExecutions(ctx context.Context, arg GetRecentToolExecutionsParams) ([]*ToolExecution, error)
+	GetReembeddingJob(ctx context.Context, id pgtype.UUID) (*ReembeddingJob, error)
 	// Gets memory entries related to a given entry through relationships
 	GetRelatedMemories(ctx context.Context, arg GetRelatedMemoriesParams) ([]*GetRelatedMemoriesRow, error)
+	GetRoutingAccuracy(ctx context.Context, arg GetRoutingAccuracyParams) ([]*GetRoutingAccuracyRow, error)
+	// Search cache queries for the web search tool
+	GetSearchCache(ctx context.Context, queryHash string) (*SearchCache, error)
 	GetSemanticMemories(ctx context.Context, arg GetSemanticMemoriesParams) ([]*SemanticMemory, error)
 	GetSemanticMemory(ctx context.Context, id pgtype.UUID) (*SemanticMemory, error)
 	GetSemanticRelationships(ctx context.Context, arg GetSemanticRelationshipsParams) ([]*SemanticMemory, error)
@@ -328,6 +364,7 @@ type Querier interface {
 	GetTopSkills(ctx context.Context, arg GetTopSkillsParams) ([]*GetTopSkillsRow, error)
 	GetUnprocessedEvents(ctx context.Context, limit int32) ([]*SystemEvent, error)
 	GetUserActivitySummary(ctx context.Context, id pgtype.UUID) (*GetUserActivitySummaryRow, error)
+	GetUserArtifactBytes(ctx context.Context, userID pgtype.UUID) (int64, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	GetUserByID(ctx context.Context, id pgtype.UUID) (*GetUserByIDRow, error)
 	GetUserByUsername(ctx context.Context, username string) (*GetUserByUsernameRow, error)
@@ -349,6 +386,20 @@ type Querier interface {
 	GetWorkingMemorySlot(ctx context.Context, arg GetWorkingMemorySlotParams) (*WorkingMemory, error)
 	// Atomically increments access count and updates last access time
 	IncrementMemoryAccess(ctx context.Context, id pgtype.UUID) error
+	ListArtifactsByConversation(ctx context.Context, conversationID pgtype.UUID) ([]*Artifact, error)
+	ListArtifactsByMessage(ctx context.Context, messageID pgtype.UUID) ([]*Artifact, error)
+	ListArtifactsByToolExecution(ctx context.Context, toolExecutionID pgtype.UUID) ([]*Artifact, error)
+	// File state of indexed code bases
+	ListCodeIndexFiles(ctx context.Context, arg ListCodeIndexFilesParams) ([]*CodeIndexFile, error)
+	ListCollectionDocuments(ctx context.Context, collectionID pgtype.UUID) ([]*CollectionDocument, error)
+	ListCollectionGrants(ctx context.Context, collectionIds []pgtype.UUID) ([]*CollectionGrant, error)
+	ListCollections(ctx context.Context) ([]*Collection, error)
+	ListDatabaseConnections(ctx context.Context, userID pgtype.UUID) ([]*DatabaseConnection, error)
+	ListIngestionJobs(ctx context.Context, arg ListIngestionJobsParams) ([]*IngestionJob, error)
+	// Chunks of the collection's active model without a vector of the new one
+	ListReembeddingCandidates(ctx context.Context, arg ListReembeddingCandidatesParams) ([]*ListReembeddingCandidatesRow, error)
+	ListReembeddingJobs(ctx context.Context, arg ListReembeddingJobsParams) ([]*ReembeddingJob, error)
+	ListUnfinishedReembeddingJobs(ctx context.Context) ([]*ReembeddingJob, error)
 	MarkEventFailed(ctx context.Context, arg MarkEventFailedParams) (*SystemEvent, error)
 	MarkEventProcessed(ctx context.Context, id pgtype.UUID) (*SystemEvent, error)
 	// Maintenance query to update memory statistics
@@ -361,6 +412,11 @@ type Querier interface {
 	SearchCodePatterns(ctx context.Context, arg SearchCodePatternsParams) ([]*CodePattern, error)
 	SearchConversations(ctx context.Context, arg SearchConversationsParams) ([]*Conversation, error)
 	// Search embeddings with metadata filtering
+	SearchEmbeddingsByText(ctx context.Context, arg SearchEmbeddingsByTextParams) ([]*SearchEmbeddingsByTextRow, error)
+	// Searches the vectors of the query's dimensions. Dimensions with an
+	// approximate index have their own branch, whose cast matches the index
+	// expression; the branches of other dimensions are skipped as a whole.
+	SearchEmbeddingsByVector(ctx context.Context, arg SearchEmbeddingsByVectorParams) ([]*SearchEmbeddingsByVectorRow, error)
 	SearchEmbeddingsByTypeWithMetadata(ctx context.Context, arg SearchEmbeddingsByTypeWithMetadataParams) ([]*SearchEmbeddingsByTypeWithMetadataRow, error)
 	SearchEpisodicMemoriesBySimilarity(ctx context.Context, arg SearchEpisodicMemoriesBySimilarityParams) ([]*SearchEpisodicMemoriesBySimilarityRow, error)
 	SearchKnowledgeNodesByName(ctx context.Context, arg SearchKnowledgeNodesByNameParams) ([]*KnowledgeNode, error)
@@ -375,6 +431,14 @@ type Querier interface {
 	SearchSimilarEmbeddings(ctx context.Context, arg SearchSimilarEmbeddingsParams) ([]*SearchSimilarEmbeddingsRow, error)
 	SearchSimilarEmbeddingsAllTypes(ctx context.Context, arg SearchSimilarEmbeddingsAllTypesParams) ([]*SearchSimilarEmbeddingsAllTypesRow, error)
 	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]*SearchUsersRow, error)
+	StartIngestionJob(ctx context.Context, id pgtype.UUID) error
+	StartReembeddingJob(ctx context.Context, id pgtype.UUID) error
+	// Makes the new model active and deletes the vectors of other models in
+	// one statement, once every chunk has a vector of the new model with the
+	// job's dimensions and no ingestion job is adding chunks. Vectors of the
+	// new model with other dimensions are deleted instead, so they are
+	// embedded again. Reports whether the collection switched.
+	SwitchCollectionModel(ctx context.Context, arg SwitchCollectionModelParams) (bool, error)
 	// Additional conversation queries that were missing
 	UnarchiveConversation(ctx context.Context, id pgtype.UUID) error
 	UpdateAgentCapabilities(ctx context.Context, arg UpdateAgentCapabilitiesParams) (*AgentDefinition, error)
@@ -386,6 +450,7 @@ type Querier interface {
 	UpdateEmbedding(ctx context.Context, arg UpdateEmbeddingParams) (*Embedding, error)
 	// Update metadata for a specific embedding
 	UpdateEmbeddingMetadata(ctx context.Context, arg UpdateEmbeddingMetadataParams) error
+	UpdateEmbeddingMetadataByContent(ctx context.Context, arg UpdateEmbeddingMetadataByContentParams) error
 	UpdateEpisodicMemoryAccess(ctx context.Context, id pgtype.UUID) (*EpisodicMemory, error)
 	UpdateEpisodicMemoryImportance(ctx context.Context, arg UpdateEpisodicMemoryImportanceParams) (*EpisodicMemory, error)
 	UpdateKnowledgeEdgeStrength(ctx context.Context, arg UpdateKnowledgeEdgeStrengthParams) (*KnowledgeEdge, error)
@@ -393,6 +458,7 @@ type Querier interface {
 	UpdateKnowledgeNodeProperties(ctx context.Context, arg UpdateKnowledgeNodePropertiesParams) (*KnowledgeNode, error)
 	UpdateKnowledgeShareAcceptance(ctx context.Context, arg UpdateKnowledgeShareAcceptanceParams) (*AgentKnowledgeShare, error)
 	UpdateLearningEventFeedback(ctx context.Context, arg UpdateLearningEventFeedbackParams) (*LearningEvent, error)
+	UpdateLearningEventOutcome(ctx context.Context, arg UpdateLearningEventOutcomeParams) (*LearningEvent, error)
 	// Updates a memory entry with optimistic locking
 	UpdateMemoryEntry(ctx context.Context, arg UpdateMemoryEntryParams) (*UpdateMemoryEntryRow, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) (*UpdateMessageRow, error)
@@ -401,6 +467,7 @@ type Querier interface {
 	UpdatePatternUsage(ctx context.Context, arg UpdatePatternUsageParams) (*CodePattern, error)
 	UpdateProceduralExecution(ctx context.Context, arg UpdateProceduralExecutionParams) (*ProceduralMemory, error)
 	UpdateProjectionProgress(ctx context.Context, arg UpdateProjectionProgressParams) (*EventProjection, error)
+	UpdateReembeddingProgress(ctx context.Context, arg UpdateReembeddingProgressParams) error
 	UpdateSemanticMemoryConfidence(ctx context.Context, arg UpdateSemanticMemoryConfidenceParams) (*SemanticMemory, error)
 	UpdateSessionProgress(ctx context.Context, arg UpdateSessionProgressParams) (*DevelopmentSession, error)
 	UpdateSkillRelatedPatterns(ctx context.Context, arg UpdateSkillRelatedPatternsParams) (*UserSkill, error)
@@ -414,6 +481,10 @@ type Querier interface {
 	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (*UpdateUserProfileRow, error)
 	UpdateUserSettings(ctx context.Context, arg UpdateUserSettingsParams) (*UpdateUserSettingsRow, error)
 	UpdateWorkingMemoryActivation(ctx context.Context, arg UpdateWorkingMemoryActivationParams) (*WorkingMemory, error)
+	UpsertCodeIndexFile(ctx context.Context, arg UpsertCodeIndexFileParams) error
+	UpsertCollectionDocument(ctx context.Context, arg UpsertCollectionDocumentParams) (*CollectionDocument, error)
+	UpsertCollectionGrant(ctx context.Context, arg UpsertCollectionGrantParams) error
+	UpsertSearchCache(ctx context.Context, arg UpsertSearchCacheParams) error
 	WeakenKnowledgeEdge(ctx context.Context, arg WeakenKnowledgeEdgeParams) (*KnowledgeEdge, error)
 }
 
@@ -0,0 +1,476 @@
+// Package artifact stores the files tools produce so they outlive the
+// response that returned them. Contents are kept on local disk under their
+// SHA-256 hash, so identical artifacts share one blob, and metadata linking
+// each artifact to its user, conversation, message and tool execution is
+// kept in PostgreSQL.
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+var (
+	// ErrNotFound is returned for unknown and expired artifacts
+	ErrNotFound = errors.New("artifact not found")
+	// ErrTooLarge is returned for artifacts over the configured size limit
+	ErrTooLarge = errors.New("artifact exceeds the size limit")
+	// ErrQuotaExceeded is returned when an artifact would take a user over
+	// their storage quota
+	ErrQuotaExceeded = errors.New("artifact storage quota exceeded")
+)
+
+// Artifact is the metadata of a stored artifact
+type Artifact struct {
+	ID              string     `json:"id"`
+	Hash            string     `json:"hash"`
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	ContentType     string     `json:"content_type"`
+	Size            int64      `json:"size"`
+	ToolName        string     `json:"tool_name"`
+	UserID          string     `json:"user_id,omitempty"`
+	ConversationID  string     `json:"conversation_id,omitempty"`
+	MessageID       string     `json:"message_id,omitempty"`
+	ToolExecutionID string     `json:"tool_execution_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+}
+
+// OwnedBy reports whether userID may read the artifact. Artifacts stored
+// without a user belong to no one.
+func (a *Artifact) OwnedBy(userID string) bool {
+	return a.UserID != "" && a.UserID == userID
+}
+
+// Links are the records an artifact is stored against; empty IDs are left
+// unlinked
+type Links struct {
+	ToolName        string
+	UserID          string
+	ConversationID  string
+	MessageID       string
+	ToolExecutionID string
+}
+
+// Metadata is the part of the generated queries the store needs
+type Metadata interface {
+	CreateArtifact(ctx context.Context, arg sqlc.CreateArtifactParams) (*sqlc.Artifact, error)
+	GetArtifact(ctx context.Context, id pgtype.UUID) (*sqlc.Artifact, error)
+	ListArtifactsByConversation(ctx context.Context, conversationID pgtype.UUID) ([]*sqlc.Artifact, error)
+	ListArtifactsByMessage(ctx context.Context, messageID pgtype.UUID) ([]*sqlc.Artifact, error)
+	ListArtifactsByToolExecution(ctx context.Context, toolExecutionID pgtype.UUID) ([]*sqlc.Artifact, error)
+	GetUserArtifactBytes(ctx context.Context, userID pgtype.UUID) (int64, error)
+	CountArtifactsByHash(ctx context.Context, hash string) (int64, error)
+	DeleteExpiredArtifacts(ctx context.Context) ([]string, error)
+}
+
+// Store keeps artifact contents on disk by hash and their metadata in the
+// database. Expired artifacts are removed by Cleanup.
+type Store struct {
+	cfg    config.Artifacts
+	meta   Metadata
+	logger *slog.Logger
+
+	// mu orders moving blobs into place and recording them against
+	// removing blobs that are no longer referenced
+	mu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStore creates a store in cfg.Dir, creating the directory if needed
+func NewStore(cfg config.Artifacts, meta Metadata, logger *slog.Logger) (*Store, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("artifact directory is required")
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = time.Hour
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	return &Store{
+		cfg:    cfg,
+		meta:   meta,
+		logger: logger,
+	}, nil
+}
+
+// Save stores an artifact's content, read from Content or else from Path,
+// and records it against links
+func (s *Store) Save(ctx context.Context, a tool.ToolArtifact, links Links) (*Artifact, error) {
+	var r io.Reader
+	switch {
+	case a.Content != nil:
+		r = bytes.NewReader(a.Content)
+	case a.Path != "":
+		f, err := os.Open(a.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open artifact %s: %w", a.Path, err)
+		}
+		defer f.Close()
+		r = f
+	default:
+		return nil, fmt.Errorf("artifact %q has no content", a.Name)
+	}
+
+	tmp, hash, size, sniffed, err := s.write(r)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp) // no-op once moved into place
+
+	if links.UserID != "" && s.cfg.MaxUserBytes > 0 {
+		userID, err := postgres.ParseUUID(links.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id %q: %w", links.UserID, err)
+		}
+		used, err := s.meta.GetUserArtifactBytes(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact usage: %w", err)
+		}
+		if used+size > s.cfg.MaxUserBytes {
+			return nil, fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, used, s.cfg.MaxUserBytes)
+		}
+	}
+
+	params := sqlc.CreateArtifactParams{
+		Hash:        hash,
+		Name:        artifactName(a, hash),
+		Type:        a.Type,
+		ContentType: a.ContentType,
+		Size:        size,
+		ToolName:    links.ToolName,
+	}
+	if params.Type == "" {
+		params.Type = "file"
+	}
+	if params.ContentType == "" {
+		params.ContentType = mime.TypeByExtension(filepath.Ext(params.Name))
+	}
+	if params.ContentType == "" {
+		params.ContentType = sniffed
+	}
+	if s.cfg.Retention > 0 {
+		params.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(s.cfg.Retention), Valid: true}
+	}
+	for _, link := range []struct {
+		id  string
+		dst *pgtype.UUID
+	}{
+		{links.UserID, &params.UserID},
+		{links.ConversationID, &params.ConversationID},
+		{links.MessageID, &params.MessageID},
+		{links.ToolExecutionID, &params.ToolExecutionID},
+	} {
+		if link.id == "" {
+			continue
+		}
+		if *link.dst, err = postgres.ParseUUID(link.id); err != nil {
+			return nil, fmt.Errorf("invalid id %q: %w", link.id, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blob := s.blobPath(hash)
+	if _, err := os.Stat(blob); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+		}
+		if err := os.Rename(tmp, blob); err != nil {
+			return nil, fmt.Errorf("failed to store artifact: %w", err)
+		}
+	}
+
+	row, err := s.meta.CreateArtifact(ctx, params)
+	if err != nil {
+		s.releaseLocked(ctx, hash)
+		return nil, fmt.Errorf("failed to record artifact: %w", err)
+	}
+	return convertArtifact(row), nil
+}
+
+// write copies r into a temporary file in the store, hashing it on the
+// way. It returns the file, the hash, the size and the sniffed content type.
+func (s *Store) write(r io.Reader) (string, string, int64, string, error) {
+	f, err := os.CreateTemp(s.cfg.Dir, ".upload-*")
+	if err != nil {
+		return "", "", 0, "", fmt.Errorf("failed to create artifact file: %w", err)
+	}
+	defer f.Close()
+
+	if s.cfg.MaxSize > 0 {
+		r = io.LimitReader(r, s.cfg.MaxSize+1)
+	}
+	h := sha256.New()
+	head := &prefixWriter{max: 512}
+	size, err := io.Copy(io.MultiWriter(f, h, head), r)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", "", 0, "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if s.cfg.MaxSize > 0 && size > s.cfg.MaxSize {
+		os.Remove(f.Name())
+		return "", "", 0, "", fmt.Errorf("%w of %d bytes", ErrTooLarge, s.cfg.MaxSize)
+	}
+	return f.Name(), hex.EncodeToString(h.Sum(nil)), size, http.DetectContentType(head.buf), nil
+}
+
+// Persist saves the inline artifacts of a tool result and replaces each
+// with a reference carrying its ID. Artifacts that cannot be saved stay
+// inline, except oversized ones whose content is dropped.
+func (s *Store) Persist(ctx context.Context, result *tool.ToolResult, links Links) error {
+	if result == nil || result.Data == nil {
+		return nil
+	}
+
+	var errs []error
+	for i := range result.Data.Artifacts {
+		a := &result.Data.Artifacts[i]
+		if a.ID != "" {
+			continue
+		}
+		stored, err := s.Save(ctx, *a, links)
+		if err != nil {
+			if errors.Is(err, ErrTooLarge) {
+				a.Content = nil
+			}
+			errs = append(errs, fmt.Errorf("artifact %q: %w", a.Name, err))
+			continue
+		}
+		*a = tool.ToolArtifact{
+			ID:          stored.ID,
+			Name:        stored.Name,
+			Type:        stored.Type,
+			ContentType: stored.ContentType,
+			Size:        stored.Size,
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Get returns the metadata of an unexpired artifact
+func (s *Store) Get(ctx context.Context, id string) (*Artifact, error) {
+	uuid, err := postgres.ParseUUID(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	row, err := s.meta.GetArtifact(ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return convertArtifact(row), nil
+}
+
+// Open returns an artifact's metadata and its content. The caller closes
+// the file.
+func (s *Store) Open(ctx context.Context, id string) (*Artifact, *os.File, error) {
+	a, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(s.blobPath(a.Hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: content of %s is missing", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	return a, f, nil
+}
+
+// ListByConversation returns the unexpired artifacts of a conversation in
+// the order they were stored
+func (s *Store) ListByConversation(ctx context.Context, conversationID string) ([]*Artifact, error) {
+	return s.list(ctx, conversationID, s.meta.ListArtifactsByConversation)
+}
+
+// ListByMessage returns the unexpired artifacts linked to a message
+func (s *Store) ListByMessage(ctx context.Context, messageID string) ([]*Artifact, error) {
+	return s.list(ctx, messageID, s.meta.ListArtifactsByMessage)
+}
+
+// ListByToolExecution returns the unexpired artifacts of a tool execution
+func (s *Store) ListByToolExecution(ctx context.Context, executionID string) ([]*Artifact, error) {
+	return s.list(ctx, executionID, s.meta.ListArtifactsByToolExecution)
+}
+
+func (s *Store) list(ctx context.Context, id string, query func(context.Context, pgtype.UUID) ([]*sqlc.Artifact, error)) ([]*Artifact, error) {
+	uuid, err := postgres.ParseUUID(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q: %w", id, err)
+	}
+	rows, err := query(ctx, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	artifacts := make([]*Artifact, len(rows))
+	for i, row := range rows {
+		artifacts[i] = convertArtifact(row)
+	}
+	return artifacts, nil
+}
+
+// Cleanup deletes expired artifacts and the contents no other artifact
+// shares. It returns the number of artifacts deleted.
+func (s *Store) Cleanup(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hashes, err := s.meta.DeleteExpiredArtifacts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired artifacts: %w", err)
+	}
+
+	released := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		if !released[hash] {
+			released[hash] = true
+			s.releaseLocked(ctx, hash)
+		}
+	}
+	return len(hashes), nil
+}
+
+// releaseLocked removes a blob once no artifact refers to it. s.mu must
+// be held.
+func (s *Store) releaseLocked(ctx context.Context, hash string) {
+	count, err := s.meta.CountArtifactsByHash(ctx, hash)
+	if err != nil {
+		s.logger.Warn("Failed to count artifact references",
+			slog.String("hash", hash),
+			slog.Any("error", err))
+		return
+	}
+	if count > 0 {
+		return
+	}
+	if err := os.Remove(s.blobPath(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.logger.Warn("Failed to remove artifact content",
+			slog.String("hash", hash),
+			slog.Any("error", err))
+	}
+}
+
+// Start removes expired artifacts every cleanup interval until Close is
+// called
+func (s *Store) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.CleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				deleted, err := s.Cleanup(context.Background())
+				if err != nil {
+					s.logger.Warn("Artifact cleanup failed", slog.Any("error", err))
+				} else if deleted > 0 {
+					s.logger.Info("Expired artifacts deleted", slog.Int("count", deleted))
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the background cleanup
+func (s *Store) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// blobPath is where content with the given hash is kept, fanned out by
+// the first two hex digits
+func (s *Store) blobPath(hash string) string {
+	return filepath.Join(s.cfg.Dir, hash[:2], hash)
+}
+
+// artifactName names an artifact after the tool's name for it, its path,
+// or failing both its hash
+func artifactName(a tool.ToolArtifact, hash string) string {
+	switch {
+	case a.Name != "":
+		return filepath.Base(a.Name)
+	case a.Path != "":
+		return filepath.Base(a.Path)
+	default:
+		return hash[:12]
+	}
+}
+
+// convertArtifact converts an artifacts row
+func convertArtifact(row *sqlc.Artifact) *Artifact {
+	a := &Artifact{
+		ID:              postgres.UUIDToString(row.ID),
+		Hash:            row.Hash,
+		Name:            row.Name,
+		Type:            row.Type,
+		ContentType:     row.ContentType,
+		Size:            row.Size,
+		ToolName:        row.ToolName,
+		UserID:          postgres.UUIDToString(row.UserID),
+		ConversationID:  postgres.UUIDToString(row.ConversationID),
+		MessageID:       postgres.UUIDToString(row.MessageID),
+		ToolExecutionID: postgres.UUIDToString(row.ToolExecutionID),
+		CreatedAt:       row.CreatedAt,
+	}
+	if row.ExpiresAt.Valid {
+		expires := row.ExpiresAt.Time
+		a.ExpiresAt = &expires
+	}
+	return a
+}
+
+// prefixWriter keeps the first max bytes written to it
+type prefixWriter struct {
+	buf []byte
+	max int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if n := w.max - len(w.buf); n > 0 {
+		w.buf = append(w.buf, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,346 @@
+package artifact
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+const (
+	userID         = "11111111-1111-1111-1111-111111111111"
+	conversationID = "22222222-2222-2222-2222-222222222222"
+	messageID      = "33333333-3333-3333-3333-333333333333"
+)
+
+// memoryMetadata keeps artifact rows in memory
+type memoryMetadata struct {
+	mu   sync.Mutex
+	rows []*sqlc.Artifact
+}
+
+func (m *memoryMetadata) live(row *sqlc.Artifact) bool {
+	return !row.ExpiresAt.Valid || row.ExpiresAt.Time.After(time.Now())
+}
+
+func (m *memoryMetadata) CreateArtifact(ctx context.Context, arg sqlc.CreateArtifactParams) (*sqlc.Artifact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var id pgtype.UUID
+	if _, err := rand.Read(id.Bytes[:]); err != nil {
+		return nil, err
+	}
+	id.Valid = true
+	row := &sqlc.Artifact{
+		ID:              id,
+		Hash:            arg.Hash,
+		Name:            arg.Name,
+		Type:            arg.Type,
+		ContentType:     arg.ContentType,
+		Size:            arg.Size,
+		ToolName:        arg.ToolName,
+		UserID:          arg.UserID,
+		ConversationID:  arg.ConversationID,
+		MessageID:       arg.MessageID,
+		ToolExecutionID: arg.ToolExecutionID,
+		CreatedAt:       time.Now(),
+		ExpiresAt:       arg.ExpiresAt,
+	}
+	m.rows = append(m.rows, row)
+	return row, nil
+}
+
+func (m *memoryMetadata) GetArtifact(ctx context.Context, id pgtype.UUID) (*sqlc.Artifact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, row := range m.rows {
+		if row.ID == id && m.live(row) {
+			return row, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (m *memoryMetadata) list(match func(*sqlc.Artifact) bool) []*sqlc.Artifact {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rows := []*sqlc.Artifact{}
+	for _, row := range m.rows {
+		if match(row) && m.live(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func (m *memoryMetadata) ListArtifactsByConversation(ctx context.Context, id pgtype.UUID) ([]*sqlc.Artifact, error) {
+	return m.list(func(row *sqlc.Artifact) bool { return row.ConversationID == id }), nil
+}
+
+func (m *memoryMetadata) ListArtifactsByMessage(ctx context.Context, id pgtype.UUID) ([]*sqlc.Artifact, error) {
+	return m.list(func(row *sqlc.Artifact) bool { return row.MessageID == id }), nil
+}
+
+func (m *memoryMetadata) ListArtifactsByToolExecution(ctx context.Context, id pgtype.UUID) ([]*sqlc.Artifact, error) {
+	return m.list(func(row *sqlc.Artifact) bool { return row.ToolExecutionID == id }), nil
+}
+
+func (m *memoryMetadata) GetUserArtifactBytes(ctx context.Context, id pgtype.UUID) (int64, error) {
+	var total int64
+	for _, row := range m.list(func(row *sqlc.Artifact) bool { return row.UserID == id }) {
+		total += row.Size
+	}
+	return total, nil
+}
+
+func (m *memoryMetadata) CountArtifactsByHash(ctx context.Context, hash string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var count int64
+	for _, row := range m.rows {
+		if row.Hash == hash {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryMetadata) DeleteExpiredArtifacts(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hashes := []string{}
+	kept := m.rows[:0]
+	for _, row := range m.rows {
+		if m.live(row) {
+			kept = append(kept, row)
+		} else {
+			hashes = append(hashes, row.Hash)
+		}
+	}
+	m.rows = kept
+	return hashes, nil
+}
+
+// expire makes every stored artifact expired
+func (m *memoryMetadata) expire() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, row := range m.rows {
+		row.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Second), Valid: true}
+	}
+}
+
+func newTestStore(t *testing.T, cfg config.Artifacts) (*Store, *memoryMetadata) {
+	t.Helper()
+	cfg.Dir = t.TempDir()
+	meta := &memoryMetadata{}
+	store, err := NewStore(cfg, meta, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	return store, meta
+}
+
+// blobs returns the content files in the store
+func blobs(t *testing.T, s *Store) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(s.cfg.Dir, "??", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestStore_SaveAndOpen(t *testing.T) {
+	store, _ := newTestStore(t, config.Artifacts{Retention: time.Hour})
+	ctx := context.Background()
+	links := Links{ToolName: "docker", UserID: userID, ConversationID: conversationID, MessageID: messageID}
+
+	report, err := store.Save(ctx, tool.ToolArtifact{Name: "report.json", Content: []byte(`{"ok":true}`)}, links)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if report.Size != 11 || report.ContentType != "application/json" || report.Type != "file" || report.ExpiresAt == nil {
+		t.Errorf("Save() = %+v", report)
+	}
+	if report.UserID != userID || report.ConversationID != conversationID || report.MessageID != messageID || report.ToolName != "docker" {
+		t.Errorf("Save() links = %+v", report)
+	}
+
+	// Content is read from Path when there is none inline; identical
+	// content shares one blob
+	path := filepath.Join(t.TempDir(), "copy.json")
+	if err := os.WriteFile(path, []byte(`{"ok":true}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := store.Save(ctx, tool.ToolArtifact{Path: path}, links)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if copied.Name != "copy.json" || copied.Hash != report.Hash || copied.ID == report.ID {
+		t.Errorf("Save() = %+v, want a new artifact sharing %s", copied, report.Hash)
+	}
+	if n := len(blobs(t, store)); n != 1 {
+		t.Errorf("store holds %d blobs, want 1", n)
+	}
+
+	// Unknown extensions fall back to sniffing the content
+	log, err := store.Save(ctx, tool.ToolArtifact{Name: "build.out", Content: []byte("step 1/3\n")}, links)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !strings.HasPrefix(log.ContentType, "text/plain") {
+		t.Errorf("ContentType = %q, want text/plain", log.ContentType)
+	}
+
+	a, f, err := store.Open(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+	content, _ := io.ReadAll(f)
+	if string(content) != `{"ok":true}` || a.Name != "report.json" {
+		t.Errorf("Open() = %+v, %q", a, content)
+	}
+
+	listed, err := store.ListByConversation(ctx, conversationID)
+	if err != nil || len(listed) != 3 {
+		t.Errorf("ListByConversation() = %d artifacts, %v, want 3", len(listed), err)
+	}
+
+	for _, id := range []string{"not-a-uuid", "44444444-4444-4444-4444-444444444444"} {
+		if _, err := store.Get(ctx, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+
+	if _, err := store.Save(ctx, tool.ToolArtifact{Name: "empty"}, links); err == nil {
+		t.Error("Save() succeeded for an artifact without content")
+	}
+}
+
+func TestStore_Limits(t *testing.T) {
+	store, _ := newTestStore(t, config.Artifacts{MaxSize: 8, MaxUserBytes: 12})
+	ctx := context.Background()
+	links := Links{ToolName: "docker", UserID: userID}
+
+	if _, err := store.Save(ctx, tool.ToolArtifact{Name: "big", Content: []byte("123456789")}, links); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Save() error = %v, want ErrTooLarge", err)
+	}
+	if _, err := store.Save(ctx, tool.ToolArtifact{Name: "a", Content: []byte("12345678")}, links); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := store.Save(ctx, tool.ToolArtifact{Name: "b", Content: []byte("abcdefgh")}, links); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Save() error = %v, want ErrQuotaExceeded", err)
+	}
+
+	// Other users have their own quota, and nothing rejected is left behind
+	other := Links{ToolName: "docker", UserID: "55555555-5555-5555-5555-555555555555"}
+	if _, err := store.Save(ctx, tool.ToolArtifact{Name: "b", Content: []byte("abcdefgh")}, other); err != nil {
+		t.Errorf("Save() error = %v for another user", err)
+	}
+	if n := len(blobs(t, store)); n != 2 {
+		t.Errorf("store holds %d blobs, want 2", n)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(store.cfg.Dir, ".upload-*")); len(tmp) != 0 {
+		t.Errorf("temporary files left behind: %v", tmp)
+	}
+}
+
+func TestStore_Persist(t *testing.T) {
+	store, _ := newTestStore(t, config.Artifacts{MaxSize: 16})
+	ctx := context.Background()
+
+	result := &tool.ToolResult{
+		Success: true,
+		Data: &tool.ToolResultData{
+			Artifacts: []tool.ToolArtifact{
+				{Name: "plan.txt", Type: "code", Content: []byte("apply")},
+				{Name: "dump.bin", Content: []byte(strings.Repeat("x", 32))},
+				{ID: "already-stored", Name: "kept"},
+			},
+		},
+	}
+	err := store.Persist(ctx, result, Links{ToolName: "terraform", UserID: userID, MessageID: messageID})
+	if !errors.Is(err, ErrTooLarge) || !strings.Contains(err.Error(), "dump.bin") {
+		t.Errorf("Persist() error = %v, want dump.bin to be too large", err)
+	}
+
+	stored := result.Data.Artifacts[0]
+	if stored.ID == "" || stored.Content != nil || stored.Size != 5 || stored.Type != "code" || stored.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("stored artifact = %+v, want a reference", stored)
+	}
+	if dropped := result.Data.Artifacts[1]; dropped.ID != "" || dropped.Content != nil {
+		t.Errorf("oversized artifact = %+v, want its content dropped", dropped)
+	}
+	if kept := result.Data.Artifacts[2]; kept.ID != "already-stored" {
+		t.Errorf("reference = %+v, want it unchanged", kept)
+	}
+
+	listed, err := store.ListByMessage(ctx, messageID)
+	if err != nil || len(listed) != 1 || listed[0].ID != stored.ID {
+		t.Errorf("ListByMessage() = %v, %v", listed, err)
+	}
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	store, meta := newTestStore(t, config.Artifacts{Retention: time.Hour})
+	ctx := context.Background()
+	links := Links{ToolName: "docker"}
+
+	first, err := store.Save(ctx, tool.ToolArtifact{Name: "a", Content: []byte("shared")}, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save(ctx, tool.ToolArtifact{Name: "b", Content: []byte("shared")}, links); err != nil {
+		t.Fatal(err)
+	}
+	meta.expire()
+
+	// Unexpired artifacts sharing the content keep it on disk
+	if _, err := store.Save(ctx, tool.ToolArtifact{Name: "c", Content: []byte("shared")}, links); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := store.Cleanup(ctx)
+	if err != nil || deleted != 2 {
+		t.Fatalf("Cleanup() = %d, %v, want 2", deleted, err)
+	}
+	if n := len(blobs(t, store)); n != 1 {
+		t.Fatalf("store holds %d blobs, want the shared one", n)
+	}
+	if _, err := store.Get(ctx, first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v for an expired artifact, want ErrNotFound", err)
+	}
+
+	meta.expire()
+	if deleted, err := store.Cleanup(ctx); err != nil || deleted != 1 {
+		t.Fatalf("Cleanup() = %d, %v, want 1", deleted, err)
+	}
+	if n := len(blobs(t, store)); n != 0 {
+		t.Errorf("store holds %d blobs, want none", n)
+	}
+}
+
+func TestArtifact_OwnedBy(t *testing.T) {
+	a := &Artifact{UserID: userID}
+	if !a.OwnedBy(userID) || a.OwnedBy("someone-else") || a.OwnedBy("") {
+		t.Error("OwnedBy() does not match only the owner")
+	}
+	if (&Artifact{}).OwnedBy("") {
+		t.Error("OwnedBy(\"\") = true for an artifact without a user")
+	}
+}
@@ -1,11 +1,15 @@
 package docker
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/docker/dockerfile"
 )
 
 // DockerfileAnalyzer analyzes Dockerfiles for best practices and issues
@@ -24,16 +28,22 @@ func NewDockerfileAnalyzer(logger *slog.Logger) *DockerfileAnalyzer {
 type AnalysisResult struct {
 	Issues        []Issue           `json:"issues"`
 	Metrics       DockerfileMetrics `json:"metrics"`
+	Stages        []StageInfo       `json:"stages"`
 	Suggestions   []string          `json:"suggestions"`
 	BestPractices map[string]bool   `json:"best_practices"`
 }
 
-// Issue represents a problem found in the Dockerfile
+// Issue represents a problem found in a Dockerfile or compose file
 type Issue struct {
+	File     string `json:"file,omitempty"` // set when not the analyzed file
 	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line,omitempty"`
 	Severity string `json:"severity"` // "error", "warning", "info"
 	Message  string `json:"message"`
 	Rule     string `json:"rule"`
+	Stage    string `json:"stage,omitempty"`
+	Service  string `json:"service,omitempty"`
+	Fixable  bool   `json:"fixable,omitempty"` // optimize_dockerfile rewrites it
 }
 
 // DockerfileMetrics contains metrics about the Dockerfile
@@ -46,228 +56,368 @@ type DockerfileMetrics struct {
 	RunInstructions  int `json:"run_instructions"`
 }
 
+// StageInfo describes a build stage and its place in the stage graph
+type StageInfo struct {
+	Index     int      `json:"index"`
+	Name      string   `json:"name,omitempty"`
+	Image     string   `json:"image"`
+	Line      int      `json:"line"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	Target    bool     `json:"target"`
+	Used      bool     `json:"used"`
+}
+
 // AnalyzeFile analyzes a Dockerfile file
 func (a *DockerfileAnalyzer) AnalyzeFile(filepath string) (*AnalysisResult, error) {
-	file, err := os.Open(filepath)
+	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open Dockerfile: %w", err)
 	}
-	defer file.Close()
+	return a.Analyze(string(content))
+}
+
+// Analyze analyzes Dockerfile content. Syntax errors are reported as issues
+// with the "syntax" rule and the rest of the file is still analyzed.
+func (a *DockerfileAnalyzer) Analyze(src string) (*AnalysisResult, error) {
+	df, err := dockerfile.Parse(src)
 
 	result := &AnalysisResult{
 		Issues:        []Issue{},
+		Stages:        []StageInfo{},
 		Suggestions:   []string{},
 		BestPractices: make(map[string]bool),
 	}
 
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	hasHealthcheck := false
-	hasUser := false
-	hasWorkdir := false
-	runCommands := []string{}
+	var syntaxErrors dockerfile.ErrorList
+	if errors.As(err, &syntaxErrors) {
+		for _, e := range syntaxErrors {
+			result.Issues = append(result.Issues, Issue{
+				Line:     e.Pos.Line,
+				Severity: "error",
+				Message:  e.Msg,
+				Rule:     "syntax",
+			})
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
 
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+	a.collectMetrics(df, result)
+	a.describeStages(df, result)
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	reachable := df.Reachable(df.Target())
+	for _, stage := range df.Stages {
+		if !reachable[stage.Index] {
+			a.addIssue(result, stage, stage.From, "info", "unused-stage",
+				fmt.Sprintf("Stage %q is not needed to build the final stage and is skipped by BuildKit", stage.Ref()), false)
 		}
+		a.analyzeBaseImage(df, stage, result)
+		a.analyzeStage(src, df, stage, result)
+	}
+	for _, inst := range df.Args {
+		a.analyzeArg(nil, inst, result)
+	}
+	a.analyzeTarget(df, result)
 
-		// Parse instruction
-		parts := strings.Fields(line)
-		if len(parts) == 0 {
-			continue
-		}
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		return result.Issues[i].Line < result.Issues[j].Line
+	})
+
+	result.BestPractices["has_workdir"] = a.hasCommand(df, "WORKDIR")
+	result.BestPractices["minimal_layers"] = result.Metrics.Layers < 20
+	result.BestPractices["efficient_caching"] = !hasRule(result.Issues, "cache-order")
+	result.BestPractices["pinned_base_images"] = !hasRule(result.Issues, "no-latest-tag")
+	result.BestPractices["no_secrets"] = !hasRule(result.Issues, "no-secrets")
+	result.BestPractices["multi_stage"] = len(df.Stages) > 1
 
-		instruction := strings.ToUpper(parts[0])
-		result.Metrics.Instructions++
+	// Add suggestions based on analysis
+	a.generateSuggestions(df, result)
 
-		// Analyze each instruction
-		switch instruction {
+	return result, nil
+}
+
+// collectMetrics counts instructions and layers
+func (a *DockerfileAnalyzer) collectMetrics(df *dockerfile.Dockerfile, result *AnalysisResult) {
+	result.Metrics.TotalLines = df.Lines
+	result.Metrics.Instructions = len(df.Instructions)
+	result.Metrics.BaseImageCount = len(df.Stages)
+	for _, inst := range df.Instructions {
+		switch inst.Command {
 		case "FROM":
-			result.Metrics.BaseImageCount++
 			result.Metrics.Layers++
-			a.analyzeFrom(line, lineNum, result)
-
 		case "RUN":
 			result.Metrics.RunInstructions++
 			result.Metrics.Layers++
-			runCommands = append(runCommands, line)
-			a.analyzeRun(line, lineNum, result)
-
 		case "COPY", "ADD":
 			result.Metrics.CopyInstructions++
 			result.Metrics.Layers++
-			a.analyzeCopy(line, lineNum, instruction, result)
+		}
+	}
+}
 
-		case "USER":
-			hasUser = true
+// describeStages records the stage graph
+func (a *DockerfileAnalyzer) describeStages(df *dockerfile.Dockerfile, result *AnalysisResult) {
+	reachable := df.Reachable(df.Target())
+	for _, stage := range df.Stages {
+		image, missing := df.BaseImage(stage, nil)
+		if len(missing) > 0 {
+			image = stage.Image
+		}
+		info := StageInfo{
+			Index:  stage.Index,
+			Name:   stage.Name,
+			Image:  image,
+			Line:   stage.From.StartLine(),
+			Target: stage == df.Target(),
+			Used:   reachable[stage.Index],
+		}
+		for _, dep := range stage.Deps {
+			info.DependsOn = append(info.DependsOn, df.Stages[dep].Ref())
+		}
+		result.Stages = append(result.Stages, info)
+	}
+}
 
-		case "WORKDIR":
-			hasWorkdir = true
+// analyzeBaseImage checks that the base image of a stage is pinned
+func (a *DockerfileAnalyzer) analyzeBaseImage(df *dockerfile.Dockerfile, stage *dockerfile.Stage, result *AnalysisResult) {
+	if stage.BaseStage >= 0 || stage.Image == "" {
+		return
+	}
+	image, missing := df.BaseImage(stage, nil)
+	if len(missing) > 0 {
+		a.addIssue(result, stage, stage.From, "info", "unresolved-image",
+			fmt.Sprintf("Base image %q depends on build args without a default (%s); the image cannot be checked for pinning",
+				stage.Image, strings.Join(missing, ", ")), false)
+		return
+	}
+	a.checkImagePinned(stage, stage.From, image, result)
+}
 
-		case "HEALTHCHECK":
-			hasHealthcheck = true
+// checkImagePinned reports an image reference that uses or implies latest
+func (a *DockerfileAnalyzer) checkImagePinned(stage *dockerfile.Stage, inst *dockerfile.Instruction, image string, result *AnalysisResult) {
+	if strings.EqualFold(image, "scratch") {
+		return
+	}
+	ref := dockerfile.ParseImageRef(image)
+	switch {
+	case ref.Digest != "":
+		return
+	case ref.Tag == "":
+		a.addIssue(result, stage, inst, "warning", "no-latest-tag",
+			fmt.Sprintf("Image %q has no tag and resolves to 'latest'; pin a version for reproducible builds", image), false)
+	case ref.Tag == "latest":
+		a.addIssue(result, stage, inst, "warning", "no-latest-tag",
+			fmt.Sprintf("Image %q uses the 'latest' tag; pin a version for reproducible builds", image), false)
+	}
+}
 
-		case "ENV":
-			a.analyzeEnv(line, lineNum, result)
+// analyzeStage applies the per-instruction rules to a stage
+func (a *DockerfileAnalyzer) analyzeStage(src string, df *dockerfile.Dockerfile, stage *dockerfile.Stage, result *AnalysisResult) {
+	var contextCopy *dockerfile.Instruction
+	var previous *dockerfile.Instruction
+	last := make(map[string]*dockerfile.Instruction)
 
-		case "EXPOSE":
-			a.analyzeExpose(line, lineNum, result)
-		}
+	for _, inst := range stage.Instructions {
+		switch inst.Command {
+		case "RUN":
+			a.analyzeRun(stage, inst, result)
+			if previous != nil && previous.Command == "RUN" && combinableRun(previous) && combinableRun(inst) {
+				adjacent := strings.TrimSpace(src[previous.To.Offset:inst.From.Offset]) == ""
+				a.addIssue(result, stage, inst, "info", "combine-run",
+					fmt.Sprintf("RUN on line %d can be combined with this RUN to reduce layers", previous.StartLine()), adjacent)
+			}
+			if contextCopy != nil {
+				if install := dependencyInstall(inst); install != "" {
+					a.addIssue(result, stage, contextCopy, "warning", "cache-order",
+						fmt.Sprintf("The whole build context is copied before '%s' on line %d; copy only the dependency manifests first so the install layer stays cached when sources change",
+							install, inst.StartLine()), false)
+					contextCopy = nil
+				}
+			}
 
-	}
+		case "COPY", "ADD":
+			a.analyzeCopy(df, stage, inst, result)
+			if _, fromStage := inst.Flag("from"); !fromStage && copiesContext(inst) && contextCopy == nil {
+				contextCopy = inst
+			}
 
-	result.Metrics.TotalLines = lineNum
+		case "ENV":
+			a.analyzeEnv(stage, inst, result)
 
-	// Check best practices
-	result.BestPractices["has_user"] = hasUser
-	result.BestPractices["has_workdir"] = hasWorkdir
-	result.BestPractices["has_healthcheck"] = hasHealthcheck
-	result.BestPractices["minimal_layers"] = result.Metrics.Layers < 20
-	result.BestPractices["efficient_caching"] = a.checkCachingEfficiency(runCommands)
+		case "ARG":
+			a.analyzeArg(stage, inst, result)
 
-	// Add suggestions based on analysis
-	a.generateSuggestions(result)
+		case "EXPOSE":
+			a.analyzeExpose(stage, inst, result)
 
-	return result, scanner.Err()
+		case "WORKDIR":
+			if dir := inst.Value; dir != "" && !strings.HasPrefix(dir, "/") && !strings.HasPrefix(dir, "$") && !windowsPath(dir) {
+				a.addIssue(result, stage, inst, "info", "workdir-absolute",
+					fmt.Sprintf("WORKDIR %q is relative to the previous WORKDIR; use an absolute path", dir), false)
+			}
+
+		case "CMD", "ENTRYPOINT", "HEALTHCHECK":
+			if prev := last[inst.Command]; prev != nil {
+				a.addIssue(result, stage, prev, "warning", "multiple-"+strings.ToLower(inst.Command),
+					fmt.Sprintf("Only the last %s in a stage takes effect; this one is overridden on line %d", inst.Command, inst.StartLine()), false)
+			}
+			last[inst.Command] = inst
+			if inst.Command != "HEALTHCHECK" && !inst.JSON {
+				a.addIssue(result, stage, inst, "info", "exec-form",
+					fmt.Sprintf("%s uses the shell form, so the process runs under /bin/sh -c and does not receive signals; use the JSON exec form", inst.Command),
+					execFormArgs(inst) != nil)
+			}
+		}
+		previous = inst
+	}
 }
 
-// analyzeFrom analyzes FROM instructions
-func (a *DockerfileAnalyzer) analyzeFrom(line string, lineNum int, result *AnalysisResult) {
-	if strings.Contains(line, "latest") {
-		result.Issues = append(result.Issues, Issue{
-			Line:     lineNum,
-			Severity: "warning",
-			Message:  "Using 'latest' tag is not recommended for reproducible builds",
-			Rule:     "no-latest-tag",
-		})
-	}
+// analyzeRun checks package manager usage and privilege escalation
+func (a *DockerfileAnalyzer) analyzeRun(stage *dockerfile.Stage, inst *dockerfile.Instruction, result *AnalysisResult) {
+	use := inspectPackages(inst)
+	fixable := rewritableRun(inst)
 
-	// Check for official base images
-	if !strings.Contains(line, "/") && !strings.Contains(line, "AS") {
-		// Likely an official image, which is good
-		return
+	if use.aptInstall && !use.aptCleanup {
+		a.addIssue(result, stage, inst, "warning", "apt-cleanup",
+			"apt-get install should be followed by 'rm -rf /var/lib/apt/lists/*' in the same RUN to reduce image size", fixable)
 	}
-
-	// Check for Alpine Linux (good for size)
-	if strings.Contains(line, "alpine") {
-		result.Suggestions = append(result.Suggestions,
-			"Good choice using Alpine Linux for smaller image size")
+	if use.aptInstall && !use.aptNoRecommends {
+		a.addIssue(result, stage, inst, "info", "apt-no-recommends",
+			"Use 'apt-get install --no-install-recommends' to avoid pulling in unneeded packages", fixable)
+	}
+	if use.aptUpdate && !use.aptInstall {
+		a.addIssue(result, stage, inst, "warning", "apt-update-install",
+			"apt-get update without apt-get install in the same RUN leaves a stale package index in the layer cache", false)
+	}
+	if use.apkAdd && !use.apkNoCache {
+		a.addIssue(result, stage, inst, "warning", "apk-no-cache",
+			"Use 'apk add --no-cache' to avoid storing the package index in the image", fixable)
+	}
+	if use.yumInstall != "" && !use.yumClean {
+		a.addIssue(result, stage, inst, "warning", "yum-cleanup",
+			fmt.Sprintf("%s install should be followed by '%s clean all' in the same RUN to reduce image size", use.yumInstall, use.yumInstall), fixable)
+	}
+	for _, cmd := range inst.Commands() {
+		if containsWord(cmd[:commandIndex(cmd)], "sudo") {
+			a.addIssue(result, stage, inst, "warning", "no-sudo",
+				"Avoid using sudo in Dockerfiles; RUN already executes as the current USER", false)
+			break
+		}
 	}
 }
 
-// analyzeRun analyzes RUN instructions
-func (a *DockerfileAnalyzer) analyzeRun(line string, lineNum int, result *AnalysisResult) {
-	// Check for apt-get/yum without clean
-	if strings.Contains(line, "apt-get install") && !strings.Contains(line, "apt-get clean") &&
-		!strings.Contains(line, "rm -rf /var/lib/apt/lists/*") {
-		result.Issues = append(result.Issues, Issue{
-			Line:     lineNum,
-			Severity: "warning",
-			Message:  "apt-get install should be followed by cleanup to reduce image size",
-			Rule:     "apt-cleanup",
-		})
+// analyzeCopy analyzes COPY and ADD instructions
+func (a *DockerfileAnalyzer) analyzeCopy(df *dockerfile.Dockerfile, stage *dockerfile.Stage, inst *dockerfile.Instruction, result *AnalysisResult) {
+	if from, ok := inst.Flag("from"); ok && df.Stage(from) == nil {
+		// --from names an external image rather than a stage
+		image, _ := df.Expand(from, df.GlobalArgs(nil))
+		a.checkImagePinned(stage, inst, image, result)
 	}
 
-	// Check for multiple RUN commands that could be combined
-	if strings.Count(line, "&&") < 1 && (strings.Contains(line, "apt-get") ||
-		strings.Contains(line, "yum") || strings.Contains(line, "apk")) {
-		result.Issues = append(result.Issues, Issue{
-			Line:     lineNum,
-			Severity: "info",
-			Message:  "Consider combining multiple RUN commands to reduce layers",
-			Rule:     "combine-run",
-		})
+	if inst.Command == "ADD" && addIsPlainCopy(inst) {
+		a.addIssue(result, stage, inst, "info", "prefer-copy",
+			"Prefer COPY over ADD for simple file copying", true)
 	}
 
-	// Check for sudo usage
-	if strings.Contains(line, "sudo") {
-		result.Issues = append(result.Issues, Issue{
-			Line:     lineNum,
-			Severity: "warning",
-			Message:  "Avoid using sudo in Dockerfiles",
-			Rule:     "no-sudo",
-		})
+	if _, fromStage := inst.Flag("from"); !fromStage && copiesContext(inst) {
+		a.addIssue(result, stage, inst, "warning", "specific-copy",
+			"Copying entire context may include unnecessary files. Consider using .dockerignore", false)
 	}
 }
 
-// analyzeCopy analyzes COPY and ADD instructions
-func (a *DockerfileAnalyzer) analyzeCopy(line string, lineNum int, instruction string, result *AnalysisResult) {
-	if instruction == "ADD" && !strings.Contains(line, "http") && !strings.Contains(line, ".tar") {
-		result.Issues = append(result.Issues, Issue{
-			Line:     lineNum,
-			Severity: "info",
-			Message:  "Prefer COPY over ADD for simple file copying",
-			Rule:     "prefer-copy",
-		})
-	}
-
-	// Check for copying entire context
-	if strings.Contains(line, "COPY . .") || strings.Contains(line, "ADD . .") {
-		result.Issues = append(result.Issues, Issue{
-			Line:     lineNum,
-			Severity: "warning",
-			Message:  "Copying entire context may include unnecessary files. Consider using .dockerignore",
-			Rule:     "specific-copy",
-		})
-	}
-}
-
-// analyzeEnv analyzes ENV instructions
-func (a *DockerfileAnalyzer) analyzeEnv(line string, lineNum int, result *AnalysisResult) {
-	// Check for secrets in ENV
-	lowerLine := strings.ToLower(line)
-	if strings.Contains(lowerLine, "password") || strings.Contains(lowerLine, "secret") ||
-		strings.Contains(lowerLine, "key") || strings.Contains(lowerLine, "token") {
-		result.Issues = append(result.Issues, Issue{
-			Line:     lineNum,
-			Severity: "error",
-			Message:  "Avoid hardcoding secrets in ENV instructions",
-			Rule:     "no-secrets",
-		})
+// analyzeEnv checks ENV instructions for hardcoded secrets
+func (a *DockerfileAnalyzer) analyzeEnv(stage *dockerfile.Stage, inst *dockerfile.Instruction, result *AnalysisResult) {
+	for _, kv := range inst.KeyValues() {
+		if secretName(kv.Key) && kv.Value != "" {
+			a.addIssue(result, stage, inst, "error", "no-secrets",
+				fmt.Sprintf("ENV %s hardcodes a secret in the image; pass it at runtime or use RUN --mount=type=secret", kv.Key), false)
+		}
+	}
+}
+
+// analyzeArg checks ARG instructions for secrets, which remain visible in
+// the image history
+func (a *DockerfileAnalyzer) analyzeArg(stage *dockerfile.Stage, inst *dockerfile.Instruction, result *AnalysisResult) {
+	for _, kv := range inst.KeyValues() {
+		if secretName(kv.Key) {
+			a.addIssue(result, stage, inst, "warning", "secret-arg",
+				fmt.Sprintf("ARG %s looks like a secret; build args are recorded in the image history, use RUN --mount=type=secret instead", kv.Key), false)
+		}
 	}
 }
 
 // analyzeExpose analyzes EXPOSE instructions
-func (a *DockerfileAnalyzer) analyzeExpose(line string, lineNum int, result *AnalysisResult) {
-	// Check for privileged ports
-	parts := strings.Fields(line)
-	if len(parts) > 1 {
-		port := parts[1]
-		if strings.HasPrefix(port, "22") || port == "23" {
-			result.Issues = append(result.Issues, Issue{
-				Line:     lineNum,
-				Severity: "warning",
-				Message:  "Exposing SSH/Telnet ports may be a security risk",
-				Rule:     "secure-ports",
-			})
+func (a *DockerfileAnalyzer) analyzeExpose(stage *dockerfile.Stage, inst *dockerfile.Instruction, result *AnalysisResult) {
+	for _, port := range inst.Args {
+		number, _, _ := strings.Cut(port, "/")
+		if number == "22" || number == "23" {
+			a.addIssue(result, stage, inst, "warning", "secure-ports",
+				fmt.Sprintf("Exposing SSH/Telnet port %s may be a security risk", number), false)
 		}
 	}
 }
 
-// checkCachingEfficiency checks if the Dockerfile is structured for efficient caching
-func (a *DockerfileAnalyzer) checkCachingEfficiency(runCommands []string) bool {
-	// Simple heuristic: package installations should come before code copying
-	hasPackageInstall := false
-	for _, cmd := range runCommands {
-		if strings.Contains(cmd, "apt-get") || strings.Contains(cmd, "yum") ||
-			strings.Contains(cmd, "apk") || strings.Contains(cmd, "npm install") ||
-			strings.Contains(cmd, "pip install") {
-			hasPackageInstall = true
-			break
+// analyzeTarget checks the runtime configuration of the final stage,
+// following its base stages
+func (a *DockerfileAnalyzer) analyzeTarget(df *dockerfile.Dockerfile, result *AnalysisResult) {
+	target := df.Target()
+	if target == nil {
+		return
+	}
+
+	user, userInst := effectiveUser(df, target)
+	nonRoot := user != "" && !rootUser(user)
+	if user == "" {
+		image, _ := df.BaseImage(target, nil)
+		nonRoot = strings.Contains(image, "nonroot")
+	}
+	result.BestPractices["has_user"] = nonRoot
+	if !nonRoot {
+		inst, message := target.From, "The final stage runs as root; add a USER instruction with a non-root user"
+		if userInst != nil {
+			inst, message = userInst, fmt.Sprintf("The final stage runs as %q; switch to a non-root user", user)
 		}
+		a.addIssue(result, target, inst, "warning", "non-root-user", message, false)
+	}
+
+	healthcheck := lastInStages(df, target, "HEALTHCHECK")
+	result.BestPractices["has_healthcheck"] = healthcheck != nil
+	if healthcheck == nil {
+		a.addIssue(result, target, target.From, "info", "healthcheck",
+			"The final stage has no HEALTHCHECK instruction for container monitoring", false)
+	}
+}
+
+// addIssue appends an issue located at inst
+func (a *DockerfileAnalyzer) addIssue(result *AnalysisResult, stage *dockerfile.Stage, inst *dockerfile.Instruction, severity, rule, message string, fixable bool) {
+	issue := Issue{
+		Line:     inst.StartLine(),
+		Severity: severity,
+		Message:  message,
+		Rule:     rule,
+		Fixable:  fixable,
+	}
+	if end := inst.EndLine(); end != issue.Line {
+		issue.EndLine = end
 	}
+	if stage != nil && len(result.Stages) > 1 {
+		issue.Stage = stage.Ref()
+	}
+	result.Issues = append(result.Issues, issue)
+}
 
-	// This is a simplified check - in reality, we'd need to track instruction order
-	return !hasPackageInstall || len(runCommands) < 5
+// hasCommand reports whether any instruction uses command
+func (a *DockerfileAnalyzer) hasCommand(df *dockerfile.Dockerfile, command string) bool {
+	for _, inst := range df.Instructions {
+		if inst.Command == command {
+			return true
+		}
+	}
+	return false
 }
 
 // generateSuggestions generates suggestions based on the analysis
-func (a *DockerfileAnalyzer) generateSuggestions(result *AnalysisResult) {
+func (a *DockerfileAnalyzer) generateSuggestions(df *dockerfile.Dockerfile, result *AnalysisResult) {
 	if !result.BestPractices["has_user"] {
 		result.Suggestions = append(result.Suggestions,
 			"Consider using USER instruction to run as non-root for better security")
@@ -288,14 +438,330 @@ func (a *DockerfileAnalyzer) generateSuggestions(result *AnalysisResult) {
 			"Multi-stage build detected - good for reducing final image size")
 	}
 
-	// Add Go-specific suggestions if detected
-	for _, issue := range result.Issues {
-		if strings.Contains(issue.Message, "go build") {
-			result.Suggestions = append(result.Suggestions,
-				"For Go applications, consider using CGO_ENABLED=0 for static binaries")
+	if target := df.Target(); target != nil {
+		if image, _ := df.BaseImage(target, nil); strings.Contains(image, "alpine") {
 			result.Suggestions = append(result.Suggestions,
-				"Use -ldflags='-w -s' to reduce binary size")
+				"Good choice using Alpine Linux for smaller image size")
+		}
+	}
+
+	// Add Go-specific suggestions if a Go build is detected
+	for _, inst := range df.Instructions {
+		if inst.Command != "RUN" {
+			continue
+		}
+		for _, cmd := range inst.Commands() {
+			if i := commandIndex(cmd); commandName(cmd) == "go" && i+1 < len(cmd) && cmd[i+1] == "build" {
+				if !strings.Contains(inst.Value, "CGO_ENABLED=0") {
+					result.Suggestions = append(result.Suggestions,
+						"For Go applications, consider using CGO_ENABLED=0 for static binaries")
+				}
+				if !strings.Contains(inst.Value, "-ldflags") {
+					result.Suggestions = append(result.Suggestions,
+						"Use -ldflags='-w -s' to reduce binary size")
+				}
+				return
+			}
+		}
+	}
+}
+
+// packageUse describes the package manager commands of a RUN instruction
+type packageUse struct {
+	aptInstall      bool
+	aptUpdate       bool
+	aptCleanup      bool
+	aptNoRecommends bool
+	apkAdd          bool
+	apkNoCache      bool
+	yumInstall      string // "yum", "dnf" or "microdnf" when used
+	yumClean        bool
+}
+
+// inspectPackages reports how a RUN instruction uses package managers
+func inspectPackages(inst *dockerfile.Instruction) packageUse {
+	use := packageUse{aptNoRecommends: true, apkNoCache: true}
+	for _, mount := range inst.FlagValues("mount") {
+		target := dockerfile.MountOption(mount, "target")
+		if dockerfile.MountOption(mount, "type") != "cache" {
+			continue
+		}
+		if strings.HasPrefix(target, "/var/lib/apt") || strings.HasPrefix(target, "/var/cache/apt") {
+			use.aptCleanup = true
+		}
+		if strings.HasPrefix(target, "/var/cache/apk") {
+			use.apkNoCache = true
+		}
+	}
+
+	apkCleaned := false
+	apkMissingFlag := false
+	for _, cmd := range inst.Commands() {
+		name := commandName(cmd)
+		args := cmd[commandIndex(cmd)+1:]
+		sub := firstOperand(args)
+		switch {
+		case (name == "apt-get" || name == "apt") && sub == "install":
+			use.aptInstall = true
+			if !containsWord(args, "--no-install-recommends") && !strings.Contains(strings.Join(args, " "), "Install-Recommends=false") {
+				use.aptNoRecommends = false
+			}
+		case (name == "apt-get" || name == "apt") && sub == "update":
+			use.aptUpdate = true
+		case name == "rm" && anyHasPrefix(args, "/var/lib/apt/lists"):
+			use.aptCleanup = true
+		case name == "apk" && sub == "add":
+			use.apkAdd = true
+			if !containsWord(args, "--no-cache") {
+				apkMissingFlag = true
+			}
+		case name == "rm" && anyHasPrefix(args, "/var/cache/apk"):
+			apkCleaned = true
+		case (name == "yum" || name == "dnf" || name == "microdnf") && sub == "install":
+			use.yumInstall = name
+		case (name == "yum" || name == "dnf" || name == "microdnf") && sub == "clean":
+			use.yumClean = true
+		}
+	}
+	if apkMissingFlag && !apkCleaned {
+		use.apkNoCache = false
+	}
+	return use
+}
+
+// dependencyInstalls maps a tool to the subcommands that install project
+// dependencies from a manifest
+var dependencyInstalls = map[string][]string{
+	"npm":      {"ci", "install", "i"},
+	"yarn":     {"", "install"},
+	"pnpm":     {"install", "i"},
+	"pip":      {"install"},
+	"pip3":     {"install"},
+	"poetry":   {"install"},
+	"pipenv":   {"install", "sync"},
+	"uv":       {"sync"},
+	"go":       {"mod"},
+	"bundle":   {"install", ""},
+	"composer": {"install"},
+	"cargo":    {"fetch"},
+	"mvn":      {"dependency:go-offline", "dependency:resolve"},
+	"gradle":   {"dependencies"},
+}
+
+// dependencyInstall returns the dependency install command run by inst, or ""
+func dependencyInstall(inst *dockerfile.Instruction) string {
+	for _, cmd := range inst.Commands() {
+		name := commandName(cmd)
+		args := cmd[commandIndex(cmd)+1:]
+		sub := firstOperand(args)
+		for _, want := range dependencyInstalls[name] {
+			if sub != want {
+				continue
+			}
+			if name == "go" && firstOperand(args[1:]) != "download" {
+				continue
+			}
+			if (name == "pip" || name == "pip3") && !containsWord(args, "-r") && !anyHasPrefix(args, "--requirement") {
+				// Installing named packages does not depend on the sources
+				continue
+			}
+			return strings.Join(cmd[commandIndex(cmd):], " ")
+		}
+	}
+	return ""
+}
+
+// commandIndex returns the index of the command word, skipping variable
+// assignments and wrappers such as sudo and env
+func commandIndex(cmd []string) int {
+	for i, word := range cmd {
+		if strings.Contains(word, "=") && !strings.HasPrefix(word, "-") && !strings.HasPrefix(word, "=") {
+			continue
+		}
+		if word == "sudo" || word == "env" || word == "exec" {
+			continue
+		}
+		return i
+	}
+	return len(cmd) - 1
+}
+
+// commandName returns the base name of the command word
+func commandName(cmd []string) string {
+	if len(cmd) == 0 {
+		return ""
+	}
+	name := cmd[commandIndex(cmd)]
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// firstOperand returns the first argument that is not an option
+func firstOperand(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+func anyHasPrefix(words []string, prefix string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sources returns the source arguments of a COPY or ADD instruction
+func sources(inst *dockerfile.Instruction) []string {
+	if len(inst.Args) < 2 {
+		return nil
+	}
+	return inst.Args[:len(inst.Args)-1]
+}
+
+// copiesContext reports whether a COPY or ADD copies the whole build context
+func copiesContext(inst *dockerfile.Instruction) bool {
+	for _, src := range sources(inst) {
+		if src == "." || src == "./" || src == "*" || src == "./*" {
+			return true
+		}
+	}
+	return false
+}
+
+var archiveRe = regexp.MustCompile(`\.(tar|tar\.gz|tgz|tar\.bz2|tbz2?|tar\.xz|txz|tar\.zst|gz|bz2|xz)$`)
+
+// addIsPlainCopy reports whether an ADD only copies local files, which COPY
+// does without the remote fetch and archive extraction semantics
+func addIsPlainCopy(inst *dockerfile.Instruction) bool {
+	if len(inst.Heredocs) > 0 {
+		return false
+	}
+	for _, flag := range inst.Flags {
+		if flag.Name == "checksum" || flag.Name == "keep-git-dir" {
+			return false
+		}
+	}
+	srcs := sources(inst)
+	if len(srcs) == 0 {
+		return false
+	}
+	for _, src := range srcs {
+		if strings.Contains(src, "://") || strings.HasPrefix(src, "git@") || archiveRe.MatchString(src) {
+			return false
+		}
+	}
+	return true
+}
+
+// combinableRun reports whether a RUN can be merged with its neighbours
+// by joining the commands with &&
+func combinableRun(inst *dockerfile.Instruction) bool {
+	return rewritableRun(inst) && len(inst.Flags) == 0
+}
+
+// rewritableRun reports whether the command text of a RUN can be edited
+// safely: shell form, no heredocs and no trailing shell comment
+func rewritableRun(inst *dockerfile.Instruction) bool {
+	return inst.Command == "RUN" && !inst.JSON && len(inst.Heredocs) == 0 && !hasShellComment(inst.Value) &&
+		!strings.HasSuffix(strings.TrimSpace(inst.Value), "&")
+}
+
+// hasShellComment reports whether a shell command contains a # comment
+func hasShellComment(s string) bool {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return true
+		}
+	}
+	return false
+}
+
+// execFormArgs returns the words of a shell form CMD or ENTRYPOINT when it
+// can be converted to the exec form without changing its meaning, or nil
+func execFormArgs(inst *dockerfile.Instruction) []string {
+	if inst.JSON || strings.ContainsAny(inst.Value, "$&|;<>*?`()'\"\\~{}[]#\n") {
+		return nil
+	}
+	return strings.Fields(inst.Value)
+}
+
+var secretNameRe = regexp.MustCompile(`(^|[_.-])(password|passwd|secret|token|credentials?|api[_-]?key|private[_-]?key|access[_-]?key|secret[_-]?key|auth[_-]?key)($|[_.-])`)
+
+// secretName reports whether a variable name looks like it holds a secret
+func secretName(name string) bool {
+	return secretNameRe.MatchString(strings.ToLower(name))
+}
+
+// effectiveUser returns the user the stage runs as and the USER instruction
+// that set it, following base stages. It returns "" when no USER is set.
+func effectiveUser(df *dockerfile.Dockerfile, stage *dockerfile.Stage) (string, *dockerfile.Instruction) {
+	if inst := lastInStages(df, stage, "USER"); inst != nil {
+		if expanded, missing := df.Expand(inst.Value, nil); len(missing) == 0 {
+			return expanded, inst
+		}
+		return inst.Value, inst
+	}
+	return "", nil
+}
+
+// lastInStages returns the last instruction with command in stage or, when
+// it has none, in the stages it is based on
+func lastInStages(df *dockerfile.Dockerfile, stage *dockerfile.Stage, command string) *dockerfile.Instruction {
+	for s := stage; s != nil; {
+		for i := len(s.Instructions) - 1; i >= 0; i-- {
+			if s.Instructions[i].Command == command {
+				return s.Instructions[i]
+			}
+		}
+		if s.BaseStage < 0 {
 			break
 		}
+		s = df.Stages[s.BaseStage]
+	}
+	return nil
+}
+
+// rootUser reports whether a USER value selects the root user
+func rootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "root" || name == "0"
+}
+
+// windowsPath reports whether dir starts with a drive letter
+func windowsPath(dir string) bool {
+	return len(dir) >= 2 && dir[1] == ':'
+}
+
+func hasRule(issues []Issue, rule string) bool {
+	for _, issue := range issues {
+		if issue.Rule == rule {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/koopa0/assistant-go/internal/tool/docker/dockerfile"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// issueLines maps each reported rule to the lines it was reported on
+func issueLines(issues []Issue) map[string][]int {
+	lines := make(map[string][]int)
+	for _, issue := range issues {
+		lines[issue.Rule] = append(lines[issue.Rule], issue.Line)
+	}
+	return lines
+}
+
+func TestDockerfileAnalyzer_Analyze(t *testing.T) {
+	src := `# syntax=docker/dockerfile:1
+ARG GO_VERSION=1.24
+ARG GITHUB_TOKEN
+FROM golang:${GO_VERSION} AS build
+WORKDIR /src
+COPY . .
+RUN go mod download && \
+    go build -o /out/app .
+
+FROM build AS unused
+RUN echo never built
+
+FROM debian:latest
+RUN apt-get update && \
+    apt-get install -y \
+      ca-certificates
+RUN <<EOF
+apk add curl
+sudo rm -rf /tmp/*
+EOF
+ENV DB_PASSWORD=hunter2 MONKEY_BUSINESS=1
+ADD app.tar.gz /opt/
+ADD config.json /etc/app/
+COPY --from=build /out/app /usr/local/bin/app
+EXPOSE 8080 22/tcp
+CMD app --serve
+CMD ["app"]
+`
+	result, err := NewDockerfileAnalyzer(discardLogger()).Analyze(src)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	want := map[string][]int{
+		"secret-arg":        {3},
+		"specific-copy":     {6},
+		"cache-order":       {6},
+		"unused-stage":      {10},
+		"no-latest-tag":     {13},
+		"non-root-user":     {13},
+		"healthcheck":       {13},
+		"apt-cleanup":       {14},
+		"apt-no-recommends": {14},
+		"apk-no-cache":      {17},
+		"no-sudo":           {17},
+		"no-secrets":        {21},
+		"prefer-copy":       {23},
+		"secure-ports":      {25},
+		"multiple-cmd":      {26},
+		"exec-form":         {26},
+	}
+	got := issueLines(result.Issues)
+	for rule, lines := range want {
+		if !reflect.DeepEqual(got[rule], lines) {
+			t.Errorf("rule %s reported on lines %v, want %v", rule, got[rule], lines)
+		}
+	}
+	for rule := range got {
+		if _, ok := want[rule]; !ok {
+			t.Errorf("unexpected rule %s on lines %v", rule, got[rule])
+		}
+	}
+
+	for _, issue := range result.Issues {
+		if issue.Rule == "apt-cleanup" && issue.EndLine != 16 {
+			t.Errorf("apt-cleanup end line = %d, want 16", issue.EndLine)
+		}
+	}
+
+	if len(result.Stages) != 3 {
+		t.Fatalf("got %d stages, want 3", len(result.Stages))
+	}
+	if s := result.Stages[0]; s.Image != "golang:1.24" || !s.Used {
+		t.Errorf("build stage = %+v", s)
+	}
+	if s := result.Stages[2]; !s.Target || len(s.DependsOn) != 1 || s.DependsOn[0] != "build" {
+		t.Errorf("target stage = %+v", s)
+	}
+	if result.BestPractices["efficient_caching"] || result.BestPractices["has_user"] {
+		t.Errorf("best practices = %v", result.BestPractices)
+	}
+}
+
+func TestDockerfileAnalyzer_SyntaxErrors(t *testing.T) {
+	result, err := NewDockerfileAnalyzer(discardLogger()).Analyze("FROM alpine:3.20\nFROBNICATE now\nUSER app\n")
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	lines := issueLines(result.Issues)
+	if len(lines["syntax"]) != 1 || lines["syntax"][0] != 2 {
+		t.Errorf("syntax issues on lines %v, want [2]", lines["syntax"])
+	}
+	if !result.BestPractices["has_user"] {
+		t.Error("USER after the syntax error was not analyzed")
+	}
+}
+
+func TestDockerfileOptimizer_Optimize(t *testing.T) {
+	src := `FROM debian:bookworm-slim
+ENV APP_HOME=/app
+ENV PATH=$APP_HOME/bin:$PATH
+ENV LANG=C.UTF-8
+RUN apt-get update
+RUN apt-get install -y curl
+# keep this comment
+RUN echo done # trailing comment
+ADD https://example.com/tool /usr/local/bin/tool
+ADD app.conf /etc/
+USER app
+ENTRYPOINT app --serve
+`
+	want := `FROM debian:bookworm-slim
+ENV APP_HOME=/app
+ENV PATH=$APP_HOME/bin:$PATH \
+    LANG=C.UTF-8
+RUN apt-get update && \
+    apt-get install --no-install-recommends -y curl && rm -rf /var/lib/apt/lists/*
+# keep this comment
+RUN echo done # trailing comment
+ADD https://example.com/tool /usr/local/bin/tool
+COPY app.conf /etc/
+USER app
+ENTRYPOINT ["app", "--serve"]
+`
+	result, err := NewDockerfileOptimizer(discardLogger()).Optimize(src)
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if result.OptimizedContent != want {
+		t.Errorf("OptimizedContent =\n%s\nwant\n%s", result.OptimizedContent, want)
+	}
+	if result.LayersReduced != 1 {
+		t.Errorf("LayersReduced = %d, want 1", result.LayersReduced)
+	}
+	if _, err := dockerfile.Parse(result.OptimizedContent); err != nil {
+		t.Errorf("optimized Dockerfile does not parse: %v", err)
+	}
+
+	wantDiff := `--- a/Dockerfile
++++ b/Dockerfile
+@@ -1,12 +1,12 @@
+ FROM debian:bookworm-slim
+ ENV APP_HOME=/app
+-ENV PATH=$APP_HOME/bin:$PATH
+-ENV LANG=C.UTF-8
+-RUN apt-get update
+-RUN apt-get install -y curl
++ENV PATH=$APP_HOME/bin:$PATH \
++    LANG=C.UTF-8
++RUN apt-get update && \
++    apt-get install --no-install-recommends -y curl && rm -rf /var/lib/apt/lists/*
+ # keep this comment
+ RUN echo done # trailing comment
+ ADD https://example.com/tool /usr/local/bin/tool
+-ADD app.conf /etc/
++COPY app.conf /etc/
+ USER app
+-ENTRYPOINT app --serve
++ENTRYPOINT ["app", "--serve"]
+`
+	if result.Diff != wantDiff {
+		t.Errorf("Diff =\n%s\nwant\n%s", result.Diff, wantDiff)
+	}
+
+	applied := make(map[string]bool)
+	for _, o := range result.Optimizations {
+		applied[o.Type] = o.Applied
+	}
+	for _, kind := range []string{"combine-env", "combine-run", "apt-cleanup", "apt-no-recommends", "add-to-copy", "exec-form"} {
+		if !applied[kind] {
+			t.Errorf("optimization %s not applied", kind)
+		}
+	}
+	if _, ok := applied["non-root-user"]; ok {
+		t.Error("non-root-user reported for a stage running as app")
+	}
+}
+
+func TestDockerfileOptimizer_NoChanges(t *testing.T) {
+	src := "FROM alpine:3.20\nRUN apk add --no-cache curl\nUSER app\nHEALTHCHECK CMD [\"true\"]\nCMD [\"app\"]\n"
+	result, err := NewDockerfileOptimizer(discardLogger()).Optimize(src)
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if result.OptimizedContent != src || result.Diff != "" || len(result.Optimizations) != 0 {
+		t.Errorf("Optimize() changed a clean Dockerfile: %+v", result)
+	}
+}
+
+func TestUnifiedDiff_Hunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+	want := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ 1
+-2
++TWO
+ 3
+ 4
+ 5
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++16
+`
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
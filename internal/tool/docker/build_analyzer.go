@@ -2,23 +2,26 @@ package docker
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os/exec"
 	"strings"
 	"time"
 )
 
-// BuildAnalyzer analyzes Docker builds for performance and efficiency
+// BuildAnalyzer analyzes Docker builds for performance and efficiency.
+// Builds run through the Engine API, which is sent the build context as a
+// tar stream, and the resulting image is inspected through it as well.
 type BuildAnalyzer struct {
 	logger *slog.Logger
+	client *Client
 }
 
 // NewBuildAnalyzer creates a new build analyzer
-func NewBuildAnalyzer(logger *slog.Logger) *BuildAnalyzer {
+func NewBuildAnalyzer(logger *slog.Logger, client *Client) *BuildAnalyzer {
 	return &BuildAnalyzer{
 		logger: logger,
+		client: client,
 	}
 }
 
@@ -62,20 +65,31 @@ type LayerSize struct {
 	Command string `json:"command"`
 }
 
-// AnalyzeBuild performs a build and analyzes its performance
-func (a *BuildAnalyzer) AnalyzeBuild(dockerfilePath string) (*BuildAnalysisResult, error) {
-	ctx := context.Background()
-	startTime := time.Now()
+// AnalyzeBuild builds the Dockerfile at dockerfilePath, with the working
+// directory as build context, and analyzes the build's performance
+func (a *BuildAnalyzer) AnalyzeBuild(ctx context.Context, dockerfilePath string) (*BuildAnalysisResult, error) {
+	if a.client == nil {
+		return nil, errors.New("docker client is not available")
+	}
 
-	// Run build with BuildKit for better output
-	buildCmd := exec.CommandContext(ctx, "docker", "build",
-		"--progress=plain",
-		"--no-cache", // Force rebuild to get accurate timing
-		"-f", dockerfilePath,
-		"-t", fmt.Sprintf("analyze-build-%d", time.Now().Unix()),
-		".")
+	startTime := time.Now()
+	imageName := fmt.Sprintf("analyze-build-%d", startTime.Unix())
 
-	output, err := buildCmd.CombinedOutput()
+	buildCtx, dockerfile, err := buildContext(".", dockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer buildCtx.Close()
+
+	var output strings.Builder
+	err = a.client.BuildImage(ctx, buildCtx, BuildOptions{
+		Dockerfile: dockerfile,
+		Tag:        imageName,
+		NoCache:    true, // Force rebuild to get accurate timing
+	}, func(msg BuildMessage) error {
+		output.WriteString(msg.Stream)
+		return nil
+	})
 	if err != nil {
 		// Even if build fails, we can still analyze what we have
 		a.logger.Warn("Build failed, analyzing partial results",
@@ -87,18 +101,21 @@ func (a *BuildAnalyzer) AnalyzeBuild(dockerfilePath string) (*BuildAnalysisResul
 	// Parse build output
 	result := &BuildAnalysisResult{
 		TotalBuildTime:   buildTime,
-		Stages:           a.parseStages(string(output)),
-		CacheUtilization: a.analyzeCacheUsage(string(output)),
+		Stages:           a.parseStages(output.String()),
+		CacheUtilization: a.analyzeCacheUsage(output.String()),
 		Recommendations:  []string{},
 	}
 
 	// If build succeeded, analyze the image
 	if err == nil {
-		imageName := fmt.Sprintf("analyze-build-%d", startTime.Unix())
 		result.SizeAnalysis = a.analyzeImageSize(ctx, imageName)
 
 		// Clean up the test image
-		exec.CommandContext(ctx, "docker", "rmi", imageName).Run()
+		if err := a.client.RemoveImage(ctx, imageName, true); err != nil {
+			a.logger.Warn("Failed to remove analyzed image",
+				slog.String("image", imageName),
+				slog.String("error", err.Error()))
+		}
 	}
 
 	// Generate recommendations
@@ -127,8 +144,8 @@ func (a *BuildAnalyzer) parseStages(output string) []StageAnalysis {
 			}
 		}
 
-		// Look for timing information
-		if strings.Contains(line, "CACHED") {
+		// Look for cached steps
+		if strings.Contains(line, "Using cache") {
 			currentStage.CacheHit = true
 		}
 
@@ -156,85 +173,86 @@ func (a *BuildAnalyzer) analyzeCacheUsage(output string) CacheAnalysis {
 	analysis := CacheAnalysis{}
 	lines := strings.Split(output, "\n")
 
+	// Every step prints a "Step" line; cached ones follow it with
+	// "Using cache"
+	steps := 0
 	for _, line := range lines {
-		if strings.Contains(line, "CACHED") {
+		if strings.Contains(line, "Using cache") {
 			analysis.CacheHits++
-		} else if strings.Contains(line, "Step ") && !strings.Contains(line, "CACHED") {
-			analysis.CacheMisses++
+		} else if strings.HasPrefix(line, "Step ") {
+			steps++
 		}
 	}
+	analysis.CacheMisses = max(steps-analysis.CacheHits, 0)
 
-	total := analysis.CacheHits + analysis.CacheMisses
-	if total > 0 {
-		analysis.CacheRatio = float64(analysis.CacheHits) / float64(total)
+	if steps > 0 {
+		analysis.CacheRatio = float64(analysis.CacheHits) / float64(steps)
 	}
 
 	return analysis
 }
 
-// analyzeImageSize analyzes the size of the built image
+// analyzeImageSize analyzes the layer sizes of the built image
 func (a *BuildAnalyzer) analyzeImageSize(ctx context.Context, imageName string) ImageSizeAnalysis {
 	analysis := ImageSizeAnalysis{
 		LayerSizes: []LayerSize{},
 	}
 
-	// Get image history
-	historyCmd := exec.CommandContext(ctx, "docker", "history", "--no-trunc", "--format", "json", imageName)
-	output, err := historyCmd.Output()
+	history, err := a.client.ImageHistory(ctx, imageName)
 	if err != nil {
 		a.logger.Error("Failed to get image history",
 			slog.String("error", err.Error()))
 		return analysis
 	}
+	return sizeAnalysis(history)
+}
 
-	// Parse each line as JSON
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-
-		var entry map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
-		}
+// sizeAnalysis summarizes image history, newest layer first. The base image
+// starts at the first older layer carrying a tag of its own; without one
+// the oldest layer is taken as the base.
+func sizeAnalysis(history []ImageLayer) ImageSizeAnalysis {
+	analysis := ImageSizeAnalysis{
+		LayerSizes: []LayerSize{},
+	}
 
-		// Extract layer information
-		size := int64(0)
-		if sizeStr, ok := entry["Size"].(string); ok {
-			// Parse size (e.g., "1.2MB", "500KB")
-			size = parseSize(sizeStr)
+	base := len(history) - 1
+	for i := 1; i < len(history); i++ {
+		if len(history[i].Tags) > 0 {
+			base = i
+			break
 		}
+	}
 
-		command := ""
-		if cmd, ok := entry["CreatedBy"].(string); ok {
-			// Truncate long commands
-			if len(cmd) > 100 {
-				command = cmd[:100] + "..."
-			} else {
-				command = cmd
-			}
+	for i, layer := range history {
+		command := layer.CreatedBy
+		// Truncate long commands
+		if len(command) > 100 {
+			command = command[:100] + "..."
 		}
-
-		layer := LayerSize{
-			Size:    size,
+		analysis.LayerSizes = append(analysis.LayerSizes, LayerSize{
+			ID:      layer.ID,
+			Size:    layer.Size,
 			Command: command,
+		})
+		analysis.TotalSize += layer.Size
+		if i >= base {
+			analysis.BaseSize += layer.Size
 		}
-
-		analysis.LayerSizes = append(analysis.LayerSizes, layer)
-		analysis.TotalSize += size
-	}
-
-	// Calculate base vs added size
-	if len(analysis.LayerSizes) > 0 {
-		// Assume last layer is base image
-		analysis.BaseSize = analysis.LayerSizes[len(analysis.LayerSizes)-1].Size
-		analysis.AddedSize = analysis.TotalSize - analysis.BaseSize
 	}
+	analysis.AddedSize = analysis.TotalSize - analysis.BaseSize
 
 	return analysis
 }
 
+// sizeOfLayers returns the total size of an image from its history
+func sizeOfLayers(layers []ImageLayer) int64 {
+	var total int64
+	for _, layer := range layers {
+		total += layer.Size
+	}
+	return total
+}
+
 // generateRecommendations generates build recommendations
 func (a *BuildAnalyzer) generateRecommendations(result *BuildAnalysisResult) {
 	// Check build time
@@ -283,30 +301,3 @@ func (a *BuildAnalyzer) generateRecommendations(result *BuildAnalysisResult) {
 		}
 	}
 }
-
-// parseSize parses Docker size strings like "1.2MB", "500KB"
-func parseSize(sizeStr string) int64 {
-	sizeStr = strings.TrimSpace(sizeStr)
-	if sizeStr == "0B" || sizeStr == "" {
-		return 0
-	}
-
-	// Simple parsing - in production, use a proper parser
-	multipliers := map[string]int64{
-		"B":  1,
-		"KB": 1024,
-		"MB": 1024 * 1024,
-		"GB": 1024 * 1024 * 1024,
-	}
-
-	for suffix, multiplier := range multipliers {
-		if strings.HasSuffix(sizeStr, suffix) {
-			numStr := strings.TrimSuffix(sizeStr, suffix)
-			var num float64
-			fmt.Sscanf(numStr, "%f", &num)
-			return int64(num * float64(multiplier))
-		}
-	}
-
-	return 0
-}
@@ -0,0 +1,222 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// buildContext streams dir as the tar build context of a build of
+// dockerfile, leaving out the paths .dockerignore excludes. It returns the
+// path of the Dockerfile inside the context: a Dockerfile outside dir is
+// added under a name of its own, as the docker CLI does.
+func buildContext(dir, dockerfile string) (io.ReadCloser, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(dir, dockerfile)
+	}
+	if _, err := os.Stat(dockerfile); err != nil {
+		return nil, "", fmt.Errorf("failed to read Dockerfile: %w", err)
+	}
+	ignore, err := readDockerignore(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	name, err := filepath.Rel(dir, dockerfile)
+	outside := err != nil || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator))
+	if outside {
+		name = ".dockerfile." + filepath.Base(dockerfile)
+	}
+	name = filepath.ToSlash(name)
+
+	r, w := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(w)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil || rel == "." {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			// The Dockerfile and .dockerignore are always sent, the
+			// daemon reads them
+			if rel != name && rel != ".dockerignore" && ignore.excludes(rel) {
+				if d.IsDir() && !ignore.hasExceptions {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return addToTar(tw, path, rel)
+		})
+		if err == nil && outside {
+			err = addToTar(tw, dockerfile, name)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		w.CloseWithError(err)
+	}()
+	return r, name, nil
+}
+
+// addToTar writes the file at path to tw as name
+func addToTar(tw *tar.Writer, path, name string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// dockerignore holds the patterns of a .dockerignore file
+type dockerignore struct {
+	patterns      []ignorePattern
+	hasExceptions bool
+}
+
+// ignorePattern is a .dockerignore line; exceptions start with "!"
+type ignorePattern struct {
+	re        *regexp.Regexp
+	exception bool
+}
+
+// readDockerignore reads the patterns of a .dockerignore file; a missing
+// file excludes nothing
+func readDockerignore(path string) (*dockerignore, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &dockerignore{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .dockerignore: %w", err)
+	}
+	defer f.Close()
+
+	ignore := &dockerignore{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := ignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			p.exception, ignore.hasExceptions = true, true
+			line = strings.TrimSpace(line[1:])
+		}
+		line = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(line)), "/")
+		if p.re, err = ignoreRegexp(line); err != nil {
+			return nil, fmt.Errorf("invalid .dockerignore pattern %q: %w", line, err)
+		}
+		ignore.patterns = append(ignore.patterns, p)
+	}
+	return ignore, scanner.Err()
+}
+
+// excludes reports whether path, relative to the context and slash
+// separated, is left out. A pattern also matches the paths below a
+// directory it matches, and the last matching pattern decides.
+func (d *dockerignore) excludes(path string) bool {
+	excluded := false
+	for _, p := range d.patterns {
+		for prefix := path; ; {
+			if p.re.MatchString(prefix) {
+				excluded = !p.exception
+				break
+			}
+			i := strings.LastIndexByte(prefix, '/')
+			if i < 0 {
+				break
+			}
+			prefix = prefix[:i]
+		}
+	}
+	return excluded
+}
+
+// ignoreRegexp compiles a .dockerignore pattern: "**" matches any number of
+// directories, "*" and "?" do not cross a "/"
+func ignoreRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// "**/" also matches no directory at all
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		case '[':
+			// Character classes keep their syntax
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i : i+end+1]
+			if strings.HasPrefix(class, "[!") {
+				class = "[^" + class[2:]
+			}
+			b.WriteString(class)
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,617 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+)
+
+// Client talks to the Docker Engine API over the configured host
+type Client struct {
+	http       *http.Client
+	baseURL    string
+	apiVersion string
+	timeout    time.Duration
+}
+
+// APIError is an error response from the Engine API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("docker API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an Engine API 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// NewClient creates an Engine API client for cfg.Host. unix:// hosts are
+// dialed directly; tcp:// hosts use HTTPS when TLS verification is enabled
+// or a certificate directory is configured.
+func NewClient(cfg config.Docker) (*Client, error) {
+	host := cfg.Host
+	if host == "" {
+		host = "unix:///var/run/docker.sock"
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:    4,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	c := &Client{
+		http:       &http.Client{Transport: transport},
+		apiVersion: strings.TrimPrefix(cfg.APIVersion, "v"),
+		timeout:    cfg.Timeout,
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		if socket == "" {
+			socket = u.Opaque
+		}
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		// The host part is ignored when dialing the socket
+		c.baseURL = "http://docker"
+	case "tcp", "http", "https":
+		scheme := "http"
+		if u.Scheme == "https" || cfg.TLSVerify || cfg.CertPath != "" {
+			tlsConfig, err := loadTLSConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig = tlsConfig
+			scheme = "https"
+		}
+		c.baseURL = scheme + "://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme: %s", u.Scheme)
+	}
+
+	return c, nil
+}
+
+// loadTLSConfig builds the client TLS configuration from ca.pem, cert.pem
+// and key.pem in cfg.CertPath, falling back to ~/.docker
+func loadTLSConfig(cfg config.Docker) (*tls.Config, error) {
+	dir := cfg.CertPath
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate docker certificates: %w", err)
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: !cfg.TLSVerify, // same semantics as DOCKER_TLS_VERIFY
+	}
+
+	ca, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	switch {
+	case err == nil:
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", filepath.Join(dir, "ca.pem"))
+		}
+		tlsConfig.RootCAs = pool
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Close releases idle connections
+func (c *Client) Close() {
+	c.http.CloseIdleConnections()
+}
+
+// Ping checks that the daemon is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Version returns the daemon version information
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var v Version
+	if err := c.getJSON(ctx, "/version", nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Filters are Engine API list filters, e.g. {"status": ["running"]}
+type Filters map[string][]string
+
+// ListContainersOptions configures ListContainers
+type ListContainersOptions struct {
+	All     bool
+	Size    bool
+	Limit   int
+	Filters Filters
+}
+
+// ListContainers returns the containers, only running ones unless opts.All
+func (c *Client) ListContainers(ctx context.Context, opts ListContainersOptions) ([]Container, error) {
+	query := url.Values{}
+	if opts.All {
+		query.Set("all", "true")
+	}
+	if opts.Size {
+		query.Set("size", "true")
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if err := setFilters(query, opts.Filters); err != nil {
+		return nil, err
+	}
+
+	containers := []Container{}
+	if err := c.getJSON(ctx, "/containers/json", query, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// InspectContainer returns the details of a container by ID or name
+func (c *Client) InspectContainer(ctx context.Context, id string) (*ContainerDetails, error) {
+	var details ContainerDetails
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(id)+"/json", nil, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// LogsOptions configures ContainerLogs
+type LogsOptions struct {
+	Follow     bool
+	Since      time.Time
+	Until      time.Time
+	Tail       string // number of lines or "all"
+	Timestamps bool
+	Stdout     bool
+	Stderr     bool
+}
+
+// ContainerLogs streams the output of a container line by line to fn. With
+// Follow set it returns when ctx is done, the container stops or fn returns
+// an error; io.EOF from fn ends the stream without an error.
+func (c *Client) ContainerLogs(ctx context.Context, id string, opts LogsOptions, fn func(LogLine) error) error {
+	// Containers with a TTY produce a raw stream instead of a multiplexed one
+	details, err := c.InspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !opts.Stdout && !opts.Stderr {
+		opts.Stdout, opts.Stderr = true, true
+	}
+	query := url.Values{}
+	query.Set("stdout", strconv.FormatBool(opts.Stdout))
+	query.Set("stderr", strconv.FormatBool(opts.Stderr))
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+	if opts.Timestamps {
+		query.Set("timestamps", "true")
+	}
+	if opts.Tail != "" {
+		query.Set("tail", opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", unixTime(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", unixTime(opts.Until))
+	}
+
+	resp, err := c.stream(ctx, "/containers/"+url.PathEscape(id)+"/logs", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	emit := func(stream, text string) error {
+		line := LogLine{Stream: stream, Text: text}
+		if opts.Timestamps {
+			if ts, rest, ok := strings.Cut(text, " "); ok {
+				if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+					line.Timestamp, line.Text = t, rest
+				}
+			}
+		}
+		return fn(line)
+	}
+
+	if details.Config.Tty {
+		err = readLines(resp.Body, func(text string) error { return emit("stdout", text) })
+	} else {
+		err = demuxLines(resp.Body, emit)
+	}
+	if errors.Is(err, io.EOF) || (opts.Follow && ctx.Err() != nil) {
+		return nil
+	}
+	return err
+}
+
+// demuxLines splits a multiplexed log stream into lines. Each frame has an
+// 8-byte header: the stream type, three padding bytes and a big-endian
+// payload length.
+func demuxLines(r io.Reader, fn func(stream, text string) error) error {
+	pending := map[string]*bytes.Buffer{"stdout": {}, "stderr": {}}
+	header := make([]byte, 8)
+	var payload []byte
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return fmt.Errorf("failed to read log stream: %w", err)
+		}
+		stream := "stdout"
+		if header[0] == 2 || header[0] == 3 {
+			stream = "stderr"
+		}
+		size := binary.BigEndian.Uint32(header[4:])
+		if cap(payload) < int(size) {
+			payload = make([]byte, size)
+		}
+		payload = payload[:size]
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return fmt.Errorf("failed to read log stream: %w", err)
+		}
+
+		buf := pending[stream]
+		buf.Write(payload)
+		for {
+			i := bytes.IndexByte(buf.Bytes(), '\n')
+			if i < 0 {
+				break
+			}
+			text := strings.TrimSuffix(string(buf.Next(i + 1)[:i]), "\r")
+			if err := fn(stream, text); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Flush output that did not end with a newline
+	for _, stream := range []string{"stdout", "stderr"} {
+		if buf := pending[stream]; buf.Len() > 0 {
+			if err := fn(stream, buf.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readLines calls fn for every line of a raw stream
+func readLines(r io.Reader, fn func(text string) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if err := fn(strings.TrimRight(line, "\r\n")); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read log stream: %w", err)
+		}
+	}
+}
+
+// ListImages returns the images, skipping intermediate layers unless all
+func (c *Client) ListImages(ctx context.Context, all bool, filters Filters) ([]Image, error) {
+	query := url.Values{}
+	if all {
+		query.Set("all", "true")
+	}
+	if err := setFilters(query, filters); err != nil {
+		return nil, err
+	}
+
+	images := []Image{}
+	if err := c.getJSON(ctx, "/images/json", query, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// ImageHistory returns the layers of an image, newest first
+func (c *Client) ImageHistory(ctx context.Context, name string) ([]ImageLayer, error) {
+	layers := []ImageLayer{}
+	if err := c.getJSON(ctx, "/images/"+url.PathEscape(name)+"/history", nil, &layers); err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// RemoveImage removes an image
+func (c *Client) RemoveImage(ctx context.Context, name string, force bool) error {
+	query := url.Values{}
+	if force {
+		query.Set("force", "true")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, http.MethodDelete, "/images/"+url.PathEscape(name), query)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// BuildOptions configures BuildImage
+type BuildOptions struct {
+	Dockerfile string // path of the Dockerfile inside the build context
+	Tag        string
+	NoCache    bool
+}
+
+// BuildImage builds an image from a tar build context and calls fn for each
+// message of the build output. A failed build is returned as an error after
+// its output. Builds can be long, so the caller's context bounds it rather
+// than the client timeout.
+func (c *Client) BuildImage(ctx context.Context, buildContext io.Reader, opts BuildOptions, fn func(BuildMessage) error) error {
+	query := url.Values{}
+	if opts.Dockerfile != "" {
+		query.Set("dockerfile", opts.Dockerfile)
+	}
+	if opts.Tag != "" {
+		query.Set("t", opts.Tag)
+	}
+	if opts.NoCache {
+		query.Set("nocache", "true")
+	}
+	query.Set("rm", "true")
+
+	resp, err := c.send(ctx, http.MethodPost, "/build", query, buildContext, "application/x-tar")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg BuildMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode build output: %w", err)
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("build failed: %s", msg.Error)
+		}
+	}
+}
+
+// ListVolumes returns the volumes
+func (c *Client) ListVolumes(ctx context.Context, filters Filters) ([]Volume, error) {
+	query := url.Values{}
+	if err := setFilters(query, filters); err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Volumes  []Volume `json:"Volumes"`
+		Warnings []string `json:"Warnings"`
+	}
+	if err := c.getJSON(ctx, "/volumes", query, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Volumes == nil {
+		resp.Volumes = []Volume{}
+	}
+	return resp.Volumes, nil
+}
+
+// ListNetworks returns the networks
+func (c *Client) ListNetworks(ctx context.Context, filters Filters) ([]Network, error) {
+	query := url.Values{}
+	if err := setFilters(query, filters); err != nil {
+		return nil, err
+	}
+
+	networks := []Network{}
+	if err := c.getJSON(ctx, "/networks", query, &networks); err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
+
+// EventsOptions configures Events
+type EventsOptions struct {
+	Since   time.Time
+	Until   time.Time
+	Filters Filters
+}
+
+// Events subscribes to daemon events and calls fn for each one. Without
+// Until the subscription lasts until ctx is done or fn returns an error;
+// io.EOF from fn ends it without an error.
+func (c *Client) Events(ctx context.Context, opts EventsOptions, fn func(Event) error) error {
+	query := url.Values{}
+	if !opts.Since.IsZero() {
+		query.Set("since", unixTime(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", unixTime(opts.Until))
+	}
+	if err := setFilters(query, opts.Filters); err != nil {
+		return err
+	}
+
+	resp, err := c.stream(ctx, "/events", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+		if err := fn(event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// getJSON performs a GET bounded by the client timeout and decodes the body
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out interface{}) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, http.MethodGet, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+// stream performs a GET whose body is read incrementally; the client
+// timeout does not apply so the caller's context bounds it
+func (c *Client) stream(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, path, query)
+}
+
+// withTimeout applies the configured request timeout to ctx
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// do sends a request without a body to the versioned API path and turns
+// error statuses into an APIError
+func (c *Client) do(ctx context.Context, method, path string, query url.Values) (*http.Response, error) {
+	return c.send(ctx, method, path, query, nil, "")
+}
+
+// send is do with a request body of the given content type
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	endpoint := c.baseURL
+	if c.apiVersion != "" {
+		endpoint += "/v" + c.apiVersion
+	}
+	endpoint += path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach docker daemon: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &msg) == nil && msg.Message != "" {
+			apiErr.Message = msg.Message
+		} else {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+// setFilters encodes filters as the JSON filters query parameter
+func setFilters(query url.Values, filters Filters) error {
+	if len(filters) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return fmt.Errorf("failed to encode filters: %w", err)
+	}
+	query.Set("filters", string(encoded))
+	return nil
+}
+
+// unixTime formats t as the fractional unix timestamp the API accepts
+func unixTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
@@ -0,0 +1,582 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// fakeEngine is an in-process Engine API serving canned responses on a unix
+// socket
+type fakeEngine struct {
+	server *httptest.Server
+	cfg    config.Docker
+
+	mu sync.Mutex
+	// queries records the query string of each request by path
+	queries map[string]string
+	// buildContext records the entries of the last build context
+	buildContext []string
+}
+
+// query returns the query string of the last request to path
+func (e *fakeEngine) query(path string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.queries[path]
+}
+
+func newFakeEngine(t *testing.T) *fakeEngine {
+	t.Helper()
+
+	// Socket paths are limited to ~100 bytes, so avoid the long t.TempDir
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen on %s: %v", socket, err)
+	}
+
+	e := &fakeEngine{queries: make(map[string]string)}
+	mux := http.NewServeMux()
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			e.queries[r.URL.Path] = r.URL.RawQuery
+			e.mu.Unlock()
+			h(w, r)
+		})
+	}
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	handle("GET /v1.41/_ping", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "OK")
+	})
+	handle("GET /v1.41/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]interface{}{{
+			"Id":     "abc123",
+			"Names":  []string{"/web"},
+			"Image":  "nginx:1.27",
+			"State":  "running",
+			"Status": "Up 2 hours",
+			"Ports":  []map[string]interface{}{{"PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}},
+		}})
+	})
+	handle("GET /v1.41/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id != "web" && id != "tty" {
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(w, map[string]string{"message": "No such container: " + id})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"Id":     id + "-id",
+			"Name":   "/" + id,
+			"State":  map[string]interface{}{"Status": "running", "Running": true, "Pid": 42},
+			"Config": map[string]interface{}{"Image": "nginx:1.27", "Tty": id == "tty"},
+		})
+	})
+	handle("GET /v1.41/containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "tty" {
+			io.WriteString(w, "raw one\r\nraw two")
+			return
+		}
+		w.Write(logFrame(1, "2024-05-01T10:00:00.000000001Z hello\n2024-05-01T10:00:01Z wor"))
+		w.Write(logFrame(2, "2024-05-01T10:00:02Z oops\n"))
+		w.Write(logFrame(1, "ld\n"))
+		if r.URL.Query().Get("follow") == "true" {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	})
+	handle("GET /v1.41/images/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]interface{}{{"Id": "sha256:1", "RepoTags": []string{"app:1"}, "Size": 300}})
+	})
+	handle("GET /v1.41/images/{name}/history", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]interface{}{
+			{"Id": "sha256:top", "CreatedBy": "COPY app /app", "Size": 30, "Tags": []string{"app:1"}},
+			{"Id": "<missing>", "CreatedBy": "RUN apk add curl", "Size": 70},
+			{"Id": "sha256:base", "CreatedBy": "CMD [\"/bin/sh\"]", "Size": 0, "Tags": []string{"alpine:3.20"}},
+			{"Id": "<missing>", "CreatedBy": "ADD rootfs.tar /", "Size": 200},
+		})
+	})
+	handle("DELETE /v1.41/images/{name}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]string{{"Untagged": r.PathValue("name")}})
+	})
+	handle("POST /v1.41/build", func(w http.ResponseWriter, r *http.Request) {
+		var entries []string
+		tr := tar.NewReader(r.Body)
+		for {
+			header, err := tr.Next()
+			if err != nil {
+				break
+			}
+			entries = append(entries, header.Name)
+		}
+		e.mu.Lock()
+		e.buildContext = entries
+		e.mu.Unlock()
+
+		enc := json.NewEncoder(w)
+		enc.Encode(map[string]string{"stream": "Step 1/3 : FROM alpine:3.20\n"})
+		enc.Encode(map[string]string{"stream": " ---> Using cache\n"})
+		enc.Encode(map[string]string{"stream": "Step 2/3 : COPY app /app\n"})
+		enc.Encode(map[string]string{"stream": "Step 3/3 : RUN go build ./...\n"})
+		enc.Encode(map[string]string{"stream": "Successfully built 1234\n"})
+	})
+	handle("GET /v1.41/volumes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"Volumes": []map[string]interface{}{{"Name": "data", "Driver": "local", "Mountpoint": "/var/lib/docker/volumes/data/_data"}},
+		})
+	})
+	handle("GET /v1.41/networks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]interface{}{{"Id": "net1", "Name": "bridge", "Driver": "bridge", "Scope": "local"}})
+	})
+	handle("GET /v1.41/events", func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		enc.Encode(map[string]interface{}{"Type": "container", "Action": "start", "Actor": map[string]interface{}{"ID": "abc123"}, "time": 1714557600})
+		enc.Encode(map[string]interface{}{"Type": "container", "Action": "die", "Actor": map[string]interface{}{"ID": "abc123", "Attributes": map[string]string{"exitCode": "1"}}, "time": 1714557601})
+		if r.URL.Query().Get("until") == "" {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	})
+
+	e.server = httptest.NewUnstartedServer(mux)
+	e.server.Listener = listener
+	e.server.Start()
+	t.Cleanup(e.server.Close)
+
+	e.cfg = config.Docker{Host: "unix://" + socket, APIVersion: "1.41", Timeout: 5 * time.Second}
+	return e
+}
+
+// logFrame encodes payload as one frame of a multiplexed log stream
+func logFrame(stream byte, payload string) []byte {
+	frame := make([]byte, 8, 8+len(payload))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeEngine) {
+	t.Helper()
+	engine := newFakeEngine(t)
+	client, err := NewClient(engine.cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client, engine
+}
+
+func TestNewClient_Hosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Docker
+		want    string
+		wantErr bool
+	}{
+		{name: "unix socket", cfg: config.Docker{Host: "unix:///var/run/docker.sock"}, want: "http://docker"},
+		{name: "plain tcp", cfg: config.Docker{Host: "tcp://10.0.0.5:2375"}, want: "http://10.0.0.5:2375"},
+		{name: "missing certs", cfg: config.Docker{Host: "tcp://10.0.0.5:2376", TLSVerify: true, CertPath: "/nonexistent"}, want: "https://10.0.0.5:2376"},
+		{name: "unsupported scheme", cfg: config.Docker{Host: "ssh://user@host"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && client.baseURL != tt.want {
+				t.Errorf("baseURL = %q, want %q", client.baseURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Lists(t *testing.T) {
+	client, engine := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	containers, err := client.ListContainers(ctx, ListContainersOptions{All: true, Filters: Filters{"status": {"running"}}})
+	if err != nil {
+		t.Fatalf("ListContainers() error = %v", err)
+	}
+	if len(containers) != 1 || containers[0].ID != "abc123" || containers[0].Ports[0].PublicPort != 8080 {
+		t.Errorf("ListContainers() = %+v", containers)
+	}
+	if got, want := engine.query("/v1.41/containers/json"), "all=true&filters=%7B%22status%22%3A%5B%22running%22%5D%7D"; got != want {
+		t.Errorf("containers query = %q, want %q", got, want)
+	}
+
+	images, err := client.ListImages(ctx, false, nil)
+	if err != nil || len(images) != 1 || images[0].RepoTags[0] != "app:1" {
+		t.Errorf("ListImages() = %+v, %v", images, err)
+	}
+	volumes, err := client.ListVolumes(ctx, nil)
+	if err != nil || len(volumes) != 1 || volumes[0].Name != "data" {
+		t.Errorf("ListVolumes() = %+v, %v", volumes, err)
+	}
+	networks, err := client.ListNetworks(ctx, nil)
+	if err != nil || len(networks) != 1 || networks[0].Driver != "bridge" {
+		t.Errorf("ListNetworks() = %+v, %v", networks, err)
+	}
+}
+
+func TestClient_InspectContainer(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	details, err := client.InspectContainer(context.Background(), "web")
+	if err != nil {
+		t.Fatalf("InspectContainer() error = %v", err)
+	}
+	if details.ID != "web-id" || !details.State.Running || details.Config.Image != "nginx:1.27" {
+		t.Errorf("InspectContainer() = %+v", details)
+	}
+
+	_, err = client.InspectContainer(context.Background(), "missing")
+	if !IsNotFound(err) {
+		t.Fatalf("InspectContainer(missing) error = %v, want not found", err)
+	}
+	if !strings.Contains(err.Error(), "No such container: missing") {
+		t.Errorf("error message = %q", err.Error())
+	}
+}
+
+func TestClient_ContainerLogs(t *testing.T) {
+	client, engine := newTestClient(t)
+	since := time.Unix(1714557600, 5)
+
+	var lines []LogLine
+	err := client.ContainerLogs(context.Background(), "web", LogsOptions{Timestamps: true, Tail: "10", Since: since}, func(line LogLine) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ContainerLogs() error = %v", err)
+	}
+
+	want := []LogLine{
+		{Stream: "stdout", Timestamp: time.Date(2024, 5, 1, 10, 0, 0, 1, time.UTC), Text: "hello"},
+		{Stream: "stderr", Timestamp: time.Date(2024, 5, 1, 10, 0, 2, 0, time.UTC), Text: "oops"},
+		{Stream: "stdout", Timestamp: time.Date(2024, 5, 1, 10, 0, 1, 0, time.UTC), Text: "world"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("ContainerLogs() lines = %+v, want %+v", lines, want)
+	}
+	if got, want := engine.query("/v1.41/containers/web/logs"), "since=1714557600.000000005&stderr=true&stdout=true&tail=10&timestamps=true"; got != want {
+		t.Errorf("logs query = %q, want %q", got, want)
+	}
+}
+
+func TestClient_ContainerLogs_TTY(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	var texts []string
+	err := client.ContainerLogs(context.Background(), "tty", LogsOptions{}, func(line LogLine) error {
+		texts = append(texts, line.Text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ContainerLogs() error = %v", err)
+	}
+	if !reflect.DeepEqual(texts, []string{"raw one", "raw two"}) {
+		t.Errorf("ContainerLogs() = %q", texts)
+	}
+}
+
+func TestClient_ContainerLogs_Follow(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	// The stream stays open until the context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	count := 0
+	err := client.ContainerLogs(ctx, "web", LogsOptions{Follow: true}, func(LogLine) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ContainerLogs() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("got %d lines, want 3", count)
+	}
+
+	// Returning io.EOF stops reading early
+	count = 0
+	err = client.ContainerLogs(context.Background(), "web", LogsOptions{Follow: true}, func(LogLine) error {
+		count++
+		return io.EOF
+	})
+	if err != nil || count != 1 {
+		t.Errorf("ContainerLogs() = %v after %d lines, want nil after 1", err, count)
+	}
+}
+
+func TestClient_Events(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var events []Event
+	err := client.Events(ctx, EventsOptions{Filters: Filters{"type": {"container"}}}, func(event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if len(events) != 2 || events[1].Action != "die" || events[1].Actor.Attributes["exitCode"] != "1" || events[0].Time != 1714557600 {
+		t.Errorf("Events() = %+v", events)
+	}
+
+	// A bounded subscription ends when the daemon closes the stream
+	events = nil
+	err = client.Events(context.Background(), EventsOptions{Until: time.Now()}, func(event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil || len(events) != 2 {
+		t.Errorf("Events(until) = %d events, %v", len(events), err)
+	}
+}
+
+func TestSizeAnalysis(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	history, err := client.ImageHistory(context.Background(), "app:1")
+	if err != nil {
+		t.Fatalf("ImageHistory() error = %v", err)
+	}
+	analysis := sizeAnalysis(history)
+	if analysis.TotalSize != 300 || analysis.BaseSize != 200 || analysis.AddedSize != 100 {
+		t.Errorf("sizeAnalysis() = %+v", analysis)
+	}
+	if len(analysis.LayerSizes) != 4 || analysis.LayerSizes[1].Command != "RUN apk add curl" {
+		t.Errorf("layers = %+v", analysis.LayerSizes)
+	}
+}
+
+func TestDockerTool_Execute(t *testing.T) {
+	engine := newFakeEngine(t)
+	dockerTool := NewDockerTool(engine.cfg, discardLogger())
+	defer dockerTool.Close(context.Background())
+
+	if err := dockerTool.Health(context.Background()); err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+
+	execute := func(params map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		result, err := dockerTool.Execute(context.Background(), &tool.ToolInput{Parameters: params})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !result.Success {
+			t.Fatalf("Execute(%v) failed: %s", params["action"], result.Error)
+		}
+		return result.Data.Output
+	}
+
+	out := execute(map[string]interface{}{
+		"action":       "container_logs",
+		"container_id": "web",
+		"options":      map[string]interface{}{"follow": true, "duration": "100ms", "max_lines": float64(2)},
+	})
+	if out["count"] != float64(2) || out["truncated"] != true {
+		t.Errorf("container_logs output = %v", out)
+	}
+
+	out = execute(map[string]interface{}{"action": "list_volumes"})
+	if out["count"] != float64(1) {
+		t.Errorf("list_volumes output = %v", out)
+	}
+
+	out = execute(map[string]interface{}{"action": "image_history", "image_name": "app:1"})
+	if out["size"] != float64(300) {
+		t.Errorf("image_history output = %v", out)
+	}
+
+	result, err := dockerTool.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{
+		"action":       "inspect_container",
+		"container_id": "missing",
+	}})
+	if err != nil || result.Success || result.Error != "container not found: missing" {
+		t.Errorf("inspect_container(missing) = %+v, %v", result, err)
+	}
+}
+
+func TestBuildAnalyzer_AnalyzeBuild(t *testing.T) {
+	client, engine := newTestClient(t)
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"Dockerfile":      "FROM alpine:3.20\nCOPY app /app\n",
+		".dockerignore":   "*.log\n**/old.log\nnode_modules\ndocs/**\n!docs/keep.md\n",
+		"app/main.go":     "package main\n",
+		"app/debug.log":   "", // *.log only matches at the root
+		"build.log":       "",
+		"node_modules/x":  "",
+		"docs/notes.md":   "",
+		"docs/keep.md":    "",
+		"docs/a/b/c.md":   "",
+		"scripts/run.sh":  "",
+		"scripts/old.log": "",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	result, err := NewBuildAnalyzer(discardLogger(), client).AnalyzeBuild(context.Background(), "Dockerfile")
+	if err != nil {
+		t.Fatalf("AnalyzeBuild() error = %v", err)
+	}
+
+	query := engine.query("/v1.41/build")
+	for _, want := range []string{"dockerfile=Dockerfile", "nocache=true", "t=analyze-build-"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("build query = %q, want %q", query, want)
+		}
+	}
+	engine.mu.Lock()
+	entries := engine.buildContext
+	engine.mu.Unlock()
+	var files []string
+	for _, name := range entries {
+		if !strings.HasSuffix(name, "/") {
+			files = append(files, name)
+		}
+	}
+	wantFiles := []string{".dockerignore", "Dockerfile", "app/debug.log", "app/main.go", "docs/keep.md", "scripts/run.sh"}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("build context = %v, want %v", files, wantFiles)
+	}
+
+	want := CacheAnalysis{CacheHits: 1, CacheMisses: 2, CacheRatio: 1.0 / 3}
+	if result.CacheUtilization != want {
+		t.Errorf("CacheUtilization = %+v, want %+v", result.CacheUtilization, want)
+	}
+	if len(result.Stages) != 1 || len(result.Stages[0].Instructions) != 3 || !result.Stages[0].CacheHit {
+		t.Errorf("Stages = %+v, want one cached stage of 3 instructions", result.Stages)
+	}
+	if result.SizeAnalysis.TotalSize != 300 {
+		t.Errorf("SizeAnalysis.TotalSize = %d, want 300", result.SizeAnalysis.TotalSize)
+	}
+	values, _ := url.ParseQuery(query)
+	if got := engine.query("/v1.41/images/" + values.Get("t")); got != "force=true" {
+		t.Errorf("image removal query = %q, want force=true", got)
+	}
+}
+
+func TestBuildContext_DockerfileOutsideContext(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile.dev")
+	if err := os.WriteFile(dockerfile, []byte("FROM scratch\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, name, err := buildContext(dir, dockerfile)
+	if err != nil {
+		t.Fatalf("buildContext() error = %v", err)
+	}
+	defer r.Close()
+	if name != ".dockerfile.Dockerfile.dev" {
+		t.Errorf("dockerfile name = %q, want .dockerfile.Dockerfile.dev", name)
+	}
+
+	var entries []string
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, header.Name)
+	}
+	if want := []string{"main.go", name}; !reflect.DeepEqual(entries, want) {
+		t.Errorf("build context = %v, want %v", entries, want)
+	}
+}
+
+func TestOptionTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   interface{}
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-05-01T10:00:00Z", want: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "15m", want: now.Add(-15 * time.Minute)},
+		{value: float64(1714557600), want: time.Unix(1714557600, 0)},
+		{value: "1714557600.5", want: time.Unix(1714557600, 500_000_000)},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := optionTime(map[string]interface{}{"since": tt.value}, "since", now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("optionTime(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("optionTime(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDockerTool_ClientUnavailable(t *testing.T) {
+	dockerTool := NewDockerTool(config.Docker{Host: "ssh://user@host"}, discardLogger())
+	if err := dockerTool.Health(context.Background()); err == nil || !strings.Contains(err.Error(), "unsupported docker host scheme") {
+		t.Errorf("Health() error = %v", err)
+	}
+
+	// Dockerfile analysis does not need the daemon
+	result, err := dockerTool.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{
+		"action":  "analyze_dockerfile",
+		"content": "FROM alpine:3.20\nUSER app\n",
+	}})
+	if err != nil || !result.Success {
+		t.Errorf("analyze_dockerfile = %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,244 @@
+package docker
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The types below mirror the Docker Engine API responses. Field names and
+// JSON keys follow the API so results read like `docker inspect` output.
+
+// Container is an entry of GET /containers/json
+type Container struct {
+	ID         string            `json:"Id"`
+	Names      []string          `json:"Names"`
+	Image      string            `json:"Image"`
+	ImageID    string            `json:"ImageID"`
+	Command    string            `json:"Command"`
+	Created    int64             `json:"Created"`
+	State      string            `json:"State"`
+	Status     string            `json:"Status"`
+	Ports      []Port            `json:"Ports"`
+	Labels     map[string]string `json:"Labels"`
+	SizeRw     int64             `json:"SizeRw,omitempty"`
+	SizeRootFs int64             `json:"SizeRootFs,omitempty"`
+	Mounts     []MountPoint      `json:"Mounts"`
+}
+
+// Port is a published or exposed container port
+type Port struct {
+	IP          string `json:"IP,omitempty"`
+	PrivatePort uint16 `json:"PrivatePort"`
+	PublicPort  uint16 `json:"PublicPort,omitempty"`
+	Type        string `json:"Type"`
+}
+
+// MountPoint is a volume or bind mount of a container
+type MountPoint struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name,omitempty"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	Driver      string `json:"Driver,omitempty"`
+	Mode        string `json:"Mode"`
+	RW          bool   `json:"RW"`
+}
+
+// ContainerDetails is the response of GET /containers/{id}/json
+type ContainerDetails struct {
+	ID              string          `json:"Id"`
+	Created         time.Time       `json:"Created"`
+	Path            string          `json:"Path"`
+	Args            []string        `json:"Args"`
+	State           ContainerState  `json:"State"`
+	Image           string          `json:"Image"`
+	Name            string          `json:"Name"`
+	RestartCount    int             `json:"RestartCount"`
+	Platform        string          `json:"Platform"`
+	Config          ContainerConfig `json:"Config"`
+	HostConfig      HostConfig      `json:"HostConfig"`
+	Mounts          []MountPoint    `json:"Mounts"`
+	NetworkSettings NetworkSettings `json:"NetworkSettings"`
+}
+
+// ContainerState is the runtime state of a container
+type ContainerState struct {
+	Status     string  `json:"Status"`
+	Running    bool    `json:"Running"`
+	Paused     bool    `json:"Paused"`
+	Restarting bool    `json:"Restarting"`
+	OOMKilled  bool    `json:"OOMKilled"`
+	Dead       bool    `json:"Dead"`
+	Pid        int     `json:"Pid"`
+	ExitCode   int     `json:"ExitCode"`
+	Error      string  `json:"Error"`
+	StartedAt  string  `json:"StartedAt"`
+	FinishedAt string  `json:"FinishedAt"`
+	Health     *Health `json:"Health,omitempty"`
+}
+
+// Health is the HEALTHCHECK state of a container
+type Health struct {
+	Status        string `json:"Status"`
+	FailingStreak int    `json:"FailingStreak"`
+}
+
+// ContainerConfig is the configuration a container was created with
+type ContainerConfig struct {
+	Hostname     string              `json:"Hostname"`
+	User         string              `json:"User"`
+	Env          []string            `json:"Env"`
+	Cmd          []string            `json:"Cmd"`
+	Entrypoint   []string            `json:"Entrypoint"`
+	Image        string              `json:"Image"`
+	WorkingDir   string              `json:"WorkingDir"`
+	Labels       map[string]string   `json:"Labels"`
+	Tty          bool                `json:"Tty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+}
+
+// HostConfig holds the host-level settings of a container
+type HostConfig struct {
+	NetworkMode    string        `json:"NetworkMode"`
+	RestartPolicy  RestartPolicy `json:"RestartPolicy"`
+	Binds          []string      `json:"Binds"`
+	Memory         int64         `json:"Memory"`
+	NanoCpus       int64         `json:"NanoCpus"`
+	Privileged     bool          `json:"Privileged"`
+	ReadonlyRootfs bool          `json:"ReadonlyRootfs"`
+}
+
+// RestartPolicy is the restart policy of a container
+type RestartPolicy struct {
+	Name              string `json:"Name"`
+	MaximumRetryCount int    `json:"MaximumRetryCount"`
+}
+
+// NetworkSettings describes the networks a container is attached to
+type NetworkSettings struct {
+	Ports    map[string][]PortBinding    `json:"Ports"`
+	Networks map[string]EndpointSettings `json:"Networks"`
+}
+
+// PortBinding is a host binding of a container port
+type PortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// EndpointSettings describes a container endpoint on a network
+type EndpointSettings struct {
+	NetworkID  string   `json:"NetworkID"`
+	IPAddress  string   `json:"IPAddress"`
+	Gateway    string   `json:"Gateway"`
+	MacAddress string   `json:"MacAddress"`
+	Aliases    []string `json:"Aliases"`
+}
+
+// Image is an entry of GET /images/json
+type Image struct {
+	ID          string            `json:"Id"`
+	ParentID    string            `json:"ParentId"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Created     int64             `json:"Created"`
+	Size        int64             `json:"Size"`
+	Labels      map[string]string `json:"Labels"`
+	Containers  int64             `json:"Containers"`
+}
+
+// ImageLayer is an entry of GET /images/{name}/history, newest first
+type ImageLayer struct {
+	ID        string   `json:"Id"`
+	Created   int64    `json:"Created"`
+	CreatedBy string   `json:"CreatedBy"`
+	Tags      []string `json:"Tags"`
+	Size      int64    `json:"Size"`
+	Comment   string   `json:"Comment"`
+}
+
+// Volume is an entry of GET /volumes
+type Volume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	CreatedAt  string            `json:"CreatedAt"`
+	Labels     map[string]string `json:"Labels"`
+	Scope      string            `json:"Scope"`
+	Options    map[string]string `json:"Options"`
+}
+
+// Network is an entry of GET /networks
+type Network struct {
+	ID         string                      `json:"Id"`
+	Name       string                      `json:"Name"`
+	Created    time.Time                   `json:"Created"`
+	Scope      string                      `json:"Scope"`
+	Driver     string                      `json:"Driver"`
+	Internal   bool                        `json:"Internal"`
+	Attachable bool                        `json:"Attachable"`
+	IPAM       IPAM                        `json:"IPAM"`
+	Containers map[string]NetworkContainer `json:"Containers"`
+	Labels     map[string]string           `json:"Labels"`
+}
+
+// IPAM is the IP address management configuration of a network
+type IPAM struct {
+	Driver string       `json:"Driver"`
+	Config []IPAMConfig `json:"Config"`
+}
+
+// IPAMConfig is an address pool of a network
+type IPAMConfig struct {
+	Subnet  string `json:"Subnet"`
+	Gateway string `json:"Gateway,omitempty"`
+}
+
+// NetworkContainer is a container attached to a network
+type NetworkContainer struct {
+	Name        string `json:"Name"`
+	IPv4Address string `json:"IPv4Address"`
+	MacAddress  string `json:"MacAddress"`
+}
+
+// Event is a message of GET /events
+type Event struct {
+	Type     string     `json:"Type"`
+	Action   string     `json:"Action"`
+	Actor    EventActor `json:"Actor"`
+	Scope    string     `json:"scope"`
+	Time     int64      `json:"time"`
+	TimeNano int64      `json:"timeNano"`
+}
+
+// EventActor is the object an event is about
+type EventActor struct {
+	ID         string            `json:"ID"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// Version is the response of GET /version
+type Version struct {
+	Version       string `json:"Version"`
+	APIVersion    string `json:"ApiVersion"`
+	MinAPIVersion string `json:"MinAPIVersion"`
+	GitCommit     string `json:"GitCommit"`
+	GoVersion     string `json:"GoVersion"`
+	Os            string `json:"Os"`
+	Arch          string `json:"Arch"`
+	KernelVersion string `json:"KernelVersion"`
+}
+
+// BuildMessage is a message of the POST /build output stream
+type BuildMessage struct {
+	Stream string          `json:"stream"`
+	Error  string          `json:"error"`
+	Aux    json.RawMessage `json:"aux"`
+}
+
+// LogLine is a line of container output
+type LogLine struct {
+	Stream    string    `json:"stream"` // "stdout" or "stderr"
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	Text      string    `json:"text"`
+}
@@ -0,0 +1,149 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseEnvFile parses an env file in the format Compose uses for .env and
+// env_file: KEY=VALUE lines with an optional export prefix, # comments,
+// single-quoted literal values, double-quoted values with escapes, and
+// quoted values spanning lines. Unquoted and double-quoted values are
+// interpolated with variables defined earlier in the file, then lookup. A
+// bare KEY has no value. name is recorded in the returned positions.
+func ParseEnvFile(name, src string, lookup LookupFunc) ([]*EnvVar, error) {
+	var vars []*EnvVar
+	var errs ErrorList
+	defined := make(map[string]string)
+	scope := func(key string) (string, bool) {
+		if v, ok := defined[key]; ok {
+			return v, true
+		}
+		if lookup != nil {
+			return lookup(key)
+		}
+		return "", false
+	}
+
+	line := 1
+	for len(src) > 0 {
+		// Take the next physical line; quoted values may extend it
+		end := strings.IndexByte(src, '\n')
+		if end < 0 {
+			end = len(src)
+		}
+		text := strings.TrimSpace(strings.TrimSuffix(src[:end], "\r"))
+		start := line
+		consumed := end
+
+		if text != "" && !strings.HasPrefix(text, "#") {
+			text = strings.TrimPrefix(text, "export ")
+			key, value, hasValue := strings.Cut(text, "=")
+			key = strings.TrimSpace(key)
+			pos := Pos{File: name, Line: start}
+
+			switch {
+			case key == "" || strings.ContainsAny(key, " \t"):
+				errs = append(errs, &Error{Pos: pos, Msg: fmt.Sprintf("invalid variable name %q", key)})
+			case !hasValue:
+				vars = append(vars, &EnvVar{Key: key, Pos: pos})
+			default:
+				value = strings.TrimLeft(value, " \t")
+				v := &EnvVar{Key: key, HasValue: true, Pos: pos}
+				if value != "" && (value[0] == '\'' || value[0] == '"') {
+					// Find the closing quote, possibly on a later line. The
+					// key contains no "=", so the first one ends it.
+					rest := strings.TrimLeft(src[strings.IndexByte(src, '=')+1:], " \t")
+					quoted, n, ok := readQuoted(rest)
+					if !ok {
+						errs = append(errs, &Error{Pos: pos, Msg: "unterminated quoted value for " + key})
+						quoted = value[1:]
+					} else {
+						consumed = len(src) - len(rest) + n
+						if nl := strings.IndexByte(src[consumed:], '\n'); nl >= 0 {
+							consumed += nl
+						} else {
+							consumed = len(src)
+						}
+					}
+					if rest[0] == '"' {
+						value = unescapeDouble(quoted)
+						v.Value = expandEnvValue(value, scope, v, &errs)
+					} else {
+						v.Value = quoted
+					}
+				} else {
+					if i := strings.Index(value, " #"); i >= 0 {
+						value = value[:i]
+					}
+					v.Value = expandEnvValue(strings.TrimSpace(value), scope, v, &errs)
+				}
+				defined[key] = v.Value
+				vars = append(vars, v)
+			}
+		}
+
+		line += strings.Count(src[:consumed], "\n")
+		if consumed < len(src) {
+			consumed++ // the newline
+			line++
+		}
+		src = src[consumed:]
+	}
+	return vars, errs.Err()
+}
+
+// readQuoted reads a value quoted with s[0] and returns its contents and
+// the length including both quotes
+func readQuoted(s string) (string, int, bool) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"' && i+1 < len(s):
+			i++
+		case s[i] == quote:
+			return s[1:i], i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// unescapeDouble resolves the escapes of a double-quoted value
+func unescapeDouble(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// expandEnvValue interpolates an env file value, recording errors at v
+func expandEnvValue(value string, lookup LookupFunc, v *EnvVar, errs *ErrorList) string {
+	res, err := interpolate(value, lookup)
+	if err != nil {
+		*errs = append(*errs, &Error{Pos: v.Pos, Msg: err.Error()})
+		return value
+	}
+	v.Interpolated = res.refs
+	return res.value
+}
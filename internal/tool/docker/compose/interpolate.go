@@ -0,0 +1,189 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LookupFunc returns the value of an environment variable and whether it
+// is set
+type LookupFunc func(name string) (string, bool)
+
+// MapLookup returns a LookupFunc over env
+func MapLookup(env map[string]string) LookupFunc {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+// Interpolate substitutes $VAR and ${VAR} references in s the way Compose
+// does. ${VAR:-default}, ${VAR-default}, ${VAR:+alt}, ${VAR+alt},
+// ${VAR:?message} and ${VAR?message} are supported, defaults may contain
+// further references, and $$ is a literal dollar sign. Unset variables
+// without a default expand to an empty string.
+func Interpolate(s string, lookup LookupFunc) (string, error) {
+	res, err := interpolate(s, lookup)
+	return res.value, err
+}
+
+// interpolation is the result of interpolating a string
+type interpolation struct {
+	value string
+	refs  bool     // s references at least one variable
+	unset []string // referenced variables that were unset without a default
+}
+
+func interpolate(s string, lookup LookupFunc) (interpolation, error) {
+	var res interpolation
+	if !strings.Contains(s, "$") {
+		res.value = s
+		return res, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i += 2
+		case next == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return res, fmt.Errorf("invalid interpolation format for %q: missing closing brace", s)
+			}
+			value, err := expandBraced(s[i+2:end], lookup, &res)
+			if err != nil {
+				return res, err
+			}
+			b.WriteString(value)
+			res.refs = true
+			i = end + 1
+		case isNameStart(next):
+			n := nameLen(s[i+1:])
+			name := s[i+1 : i+1+n]
+			value, ok := lookup(name)
+			if !ok {
+				res.unset = appendName(res.unset, name)
+			}
+			b.WriteString(value)
+			res.refs = true
+			i += 1 + n
+		default:
+			b.WriteByte('$')
+			i++
+		}
+	}
+	res.value = b.String()
+	return res, nil
+}
+
+// expandBraced expands the body of a ${...} reference
+func expandBraced(body string, lookup LookupFunc, res *interpolation) (string, error) {
+	n := nameLen(body)
+	if n == 0 {
+		return "", fmt.Errorf("invalid interpolation format for ${%s}", body)
+	}
+	name, rest := body[:n], body[n:]
+	value, set := lookup(name)
+
+	if rest == "" {
+		if !set {
+			res.unset = appendName(res.unset, name)
+		}
+		return value, nil
+	}
+
+	colon := strings.HasPrefix(rest, ":")
+	op := strings.TrimPrefix(rest, ":")
+	if op == "" {
+		return "", fmt.Errorf("invalid interpolation format for ${%s}", body)
+	}
+	word := op[1:]
+	// With a colon an empty value counts as unset
+	present := set && (!colon || value != "")
+
+	// word is only expanded when it is used
+	expandWord := func() (string, error) {
+		inner, err := interpolate(word, lookup)
+		if err != nil {
+			return "", err
+		}
+		for _, u := range inner.unset {
+			res.unset = appendName(res.unset, u)
+		}
+		return inner.value, nil
+	}
+
+	switch op[0] {
+	case '-':
+		if present {
+			return value, nil
+		}
+		return expandWord()
+	case '+':
+		if present {
+			return expandWord()
+		}
+		return "", nil
+	case '?':
+		if present {
+			return value, nil
+		}
+		msg, err := expandWord()
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("required variable %s is missing a value: %s", name, msg)
+	}
+	return "", fmt.Errorf("invalid interpolation format for ${%s}", body)
+}
+
+// closingBrace returns the index of the brace closing a ${ whose body starts
+// at start, accounting for nested references
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// nameLen returns the length of the variable name at the start of s
+func nameLen(s string) int {
+	if s == "" || !isNameStart(s[0]) {
+		return 0
+	}
+	n := 1
+	for n < len(s) && (isNameStart(s[n]) || ('0' <= s[n] && s[n] <= '9')) {
+		n++
+	}
+	return n
+}
+
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
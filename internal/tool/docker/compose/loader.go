@@ -0,0 +1,847 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options configures Load
+type Options struct {
+	// Environment is used for interpolation and takes precedence over the
+	// .env file. The process environment is not consulted.
+	Environment map[string]string
+	// EnvFile replaces the project .env file, which is otherwise read from
+	// the working directory when present
+	EnvFile string
+	// Profiles are the active profiles; COMPOSE_PROFILES from the
+	// environment is used when empty
+	Profiles []string
+	// WorkingDir defaults to the directory of the compose file
+	WorkingDir string
+}
+
+// Load reads and loads the compose file at path. Problems that leave the
+// project usable are returned as an ErrorList together with the project.
+func Load(path string, opts Options) (*Project, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %w", err)
+	}
+	return LoadBytes(path, src, opts)
+}
+
+// LoadBytes loads compose file content; path names the file in positions
+// and anchors relative paths
+func LoadBytes(path string, src []byte, opts Options) (*Project, error) {
+	workingDir := opts.WorkingDir
+	if workingDir == "" {
+		workingDir = filepath.Dir(path)
+	}
+	l := &loader{
+		project: &Project{
+			File:        path,
+			WorkingDir:  workingDir,
+			Environment: make(map[string]string),
+		},
+		origin:       make(map[*yaml.Node]string),
+		interpolated: make(map[*yaml.Node]bool),
+		documents:    make(map[string]*yaml.Node),
+		resolved:     make(map[string]*yaml.Node),
+		warned:       make(map[string]bool),
+	}
+
+	l.loadEnvironment(opts)
+
+	root, err := l.parse(path, src)
+	if err != nil {
+		return nil, err
+	}
+	l.documents[path] = root
+
+	p := l.project
+	p.Profiles = opts.Profiles
+	if len(p.Profiles) == 0 {
+		p.Profiles = splitList(p.Environment["COMPOSE_PROFILES"])
+	}
+
+	var services *yaml.Node
+	for _, kv := range pairs(root) {
+		key, value := kv[0].Value, kv[1]
+		switch key {
+		case "version":
+			p.Version, p.VersionPos = value.Value, l.pos(kv[0])
+		case "name":
+			p.Name = value.Value
+		case "services":
+			services = value
+		case "volumes":
+			p.Volumes = l.resources(value)
+		case "networks":
+			p.Networks = l.resources(value)
+		case "secrets":
+			p.Secrets = l.resources(value)
+		case "configs", "include":
+		default:
+			if !strings.HasPrefix(key, "x-") {
+				if value.Kind == yaml.MappingNode && (lookupKey(value, "image") != nil || lookupKey(value, "build") != nil) {
+					l.errorf(kv[0], "service %q is at the top level; the version 1 format is not supported", key)
+				} else {
+					l.errorf(kv[0], "unknown top-level key %q", key)
+				}
+			}
+		}
+	}
+	if p.Name == "" {
+		p.Name = p.Environment["COMPOSE_PROJECT_NAME"]
+	}
+	if p.Name == "" {
+		if abs, err := filepath.Abs(workingDir); err == nil {
+			p.Name = filepath.Base(abs)
+		}
+	}
+	p.Name = normalizeProjectName(p.Name)
+
+	if services == nil {
+		l.errorf(root, "no services defined")
+	}
+	for _, kv := range pairs(services) {
+		node := l.resolveService(path, kv[0].Value, kv[1], nil)
+		if node == nil {
+			continue
+		}
+		svc := l.service(kv[0], node)
+		l.applyEnvFiles(svc)
+		svc.Enabled = profileEnabled(svc.Profiles, p.Profiles)
+		p.Services = append(p.Services, svc)
+	}
+
+	return p, l.errs.Err()
+}
+
+// loader holds the state of a Load call
+type loader struct {
+	project *Project
+	lookup  LookupFunc
+	errs    ErrorList
+	// origin records the file every node was parsed from
+	origin map[*yaml.Node]string
+	// interpolated records the scalars whose value references a variable
+	interpolated map[*yaml.Node]bool
+	// documents holds the parsed files by path
+	documents map[string]*yaml.Node
+	// resolved caches services with extends applied by file and name
+	resolved map[string]*yaml.Node
+	warned   map[string]bool
+}
+
+func (l *loader) pos(n *yaml.Node) Pos {
+	return Pos{File: l.origin[n], Line: n.Line}
+}
+
+func (l *loader) errorf(n *yaml.Node, format string, args ...interface{}) {
+	l.errs = append(l.errs, &Error{Pos: l.pos(n), Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l *loader) warnf(n *yaml.Node, format string, args ...interface{}) {
+	l.project.Warnings = append(l.project.Warnings, &Error{Pos: l.pos(n), Msg: fmt.Sprintf(format, args...)})
+}
+
+// loadEnvironment reads the .env file and overlays opts.Environment
+func (l *loader) loadEnvironment(opts Options) {
+	env := l.project.Environment
+	l.lookup = MapLookup(env)
+
+	path, required := opts.EnvFile, true
+	if path == "" {
+		path, required = filepath.Join(l.project.WorkingDir, ".env"), false
+	}
+	src, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		vars, err := ParseEnvFile(path, string(src), MapLookup(opts.Environment))
+		var list ErrorList
+		if errors.As(err, &list) {
+			l.errs = append(l.errs, list...)
+		}
+		for _, v := range vars {
+			if v.HasValue {
+				env[v.Key] = v.Value
+			}
+		}
+	case required || !errors.Is(err, os.ErrNotExist):
+		l.errs = append(l.errs, &Error{Pos: Pos{File: path}, Msg: fmt.Sprintf("failed to read env file: %v", err)})
+	}
+
+	for k, v := range opts.Environment {
+		env[k] = v
+	}
+}
+
+// parse parses a compose document, expands anchors and merge keys and
+// interpolates its values
+func (l *loader) parse(path string, src []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("failed to parse %s: empty document", path)
+	}
+	root := expandAliases(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse %s: top level must be a mapping", path)
+	}
+	walk(root, func(n *yaml.Node) { l.origin[n] = path })
+	l.interpolate(root)
+	return root, nil
+}
+
+// interpolate substitutes variables in every value below n; mapping keys
+// are left alone
+func (l *loader) interpolate(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			l.interpolate(n.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			l.interpolate(c)
+		}
+	case yaml.ScalarNode:
+		res, err := interpolate(n.Value, l.lookup)
+		if err != nil {
+			l.errorf(n, "%v", err)
+			return
+		}
+		for _, name := range res.unset {
+			if !l.warned[name] {
+				l.warned[name] = true
+				l.warnf(n, "the %q variable is not set, defaulting to a blank string", name)
+			}
+		}
+		if res.refs {
+			l.interpolated[n] = true
+		}
+		if res.value != n.Value {
+			n.Value = res.value
+			n.Tag = "!!str"
+		}
+	}
+}
+
+// resolveService returns the service mapping with extends applied, or nil
+// when it cannot be resolved. stack holds the services being resolved to
+// detect cycles.
+func (l *loader) resolveService(file, name string, node *yaml.Node, stack []string) *yaml.Node {
+	id := file + "#" + name
+	if resolved, ok := l.resolved[id]; ok {
+		return resolved
+	}
+	if slices.Contains(stack, id) {
+		l.errorf(node, "circular extends: %s", strings.Join(append(stack, id), " -> "))
+		return nil
+	}
+	// Failures are cached too so each is reported once
+	resolved := l.extend(file, name, node, append(stack, id))
+	l.resolved[id] = resolved
+	return resolved
+}
+
+// extend applies the extends section of a service
+func (l *loader) extend(file, name string, node *yaml.Node, stack []string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		if node.Tag != "!!null" {
+			l.errorf(node, "service %q must be a mapping", name)
+			return nil
+		}
+		node = &yaml.Node{Kind: yaml.MappingNode, Line: node.Line}
+		l.origin[node] = file
+	}
+
+	extends := lookupKey(node, "extends")
+	if extends == nil {
+		return node
+	}
+
+	baseName, baseFile := extends.Value, file
+	if extends.Kind == yaml.MappingNode {
+		baseName = scalar(lookupKey(extends, "service"))
+		if f := scalar(lookupKey(extends, "file")); f != "" {
+			baseFile = filepath.Join(filepath.Dir(file), f)
+		}
+	}
+	if baseName == "" {
+		l.errorf(extends, "extends of service %q must name a service", name)
+		return nil
+	}
+
+	doc, err := l.document(baseFile)
+	if err != nil {
+		l.errorf(extends, "%v", err)
+		return nil
+	}
+	baseNode := lookupKey(lookupKey(doc, "services"), baseName)
+	if baseNode == nil {
+		l.errorf(extends, "service %q extends undefined service %q in %s", name, baseName, baseFile)
+		return nil
+	}
+	base := l.resolveService(baseFile, baseName, baseNode, stack)
+	if base == nil {
+		return nil
+	}
+
+	merged := l.merge(l.copyNode(base), node, "")
+	removeKey(merged, "extends")
+	return merged
+}
+
+// document returns the parsed compose file at path
+func (l *loader) document(path string) (*yaml.Node, error) {
+	if doc, ok := l.documents[path]; ok {
+		return doc, nil
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extended file: %w", err)
+	}
+	doc, err := l.parse(path, src)
+	if err != nil {
+		return nil, err
+	}
+	l.documents[path] = doc
+	return doc, nil
+}
+
+// copyNode deep-copies n, keeping origin and interpolation marks
+func (l *loader) copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = l.copyNode(child)
+	}
+	l.origin[&c] = l.origin[n]
+	if l.interpolated[n] {
+		l.interpolated[&c] = true
+	}
+	return &c
+}
+
+// replacedKeys are sequences an extending service replaces instead of
+// merging
+var replacedKeys = map[string]bool{"command": true, "entrypoint": true, "test": true, "profiles": true}
+
+// merge merges over into base following the Compose merge rules: mappings
+// merge recursively, volumes merge by target, most sequences are unioned
+// and scalars are overridden
+func (l *loader) merge(base, over *yaml.Node, key string) *yaml.Node {
+	base, over = l.normalize(key, base), l.normalize(key, over)
+
+	switch {
+	case base.Kind == yaml.MappingNode && over.Kind == yaml.MappingNode:
+		for _, kv := range pairs(over) {
+			k := kv[0].Value
+			if existing := lookupKey(base, k); existing != nil {
+				setKey(base, kv[0], l.merge(existing, kv[1], k))
+			} else {
+				base.Content = append(base.Content, kv[0], kv[1])
+			}
+		}
+		return base
+	case base.Kind == yaml.SequenceNode && over.Kind == yaml.SequenceNode && !replacedKeys[key]:
+		if key == "volumes" {
+			return mergeVolumes(base, over)
+		}
+		for _, item := range over.Content {
+			if item.Kind != yaml.ScalarNode || !slices.ContainsFunc(base.Content, func(b *yaml.Node) bool {
+				return b.Kind == yaml.ScalarNode && b.Value == item.Value
+			}) {
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+	}
+	return over
+}
+
+// listToMapKeys are keys whose list form is converted to a mapping before
+// merging
+var listToMapKeys = map[string]string{
+	"environment": "=", "labels": "=", "annotations": "=", "sysctls": "=", "extra_hosts": ":",
+}
+
+// normalize converts the short forms of n under key to the long mapping
+// form so both sides of a merge have the same shape. New nodes inherit the
+// origin and interpolation marks of the nodes they replace.
+func (l *loader) normalize(key string, n *yaml.Node) *yaml.Node {
+	newNode := func(kind yaml.Kind, tag, value string, from *yaml.Node) *yaml.Node {
+		c := &yaml.Node{Kind: kind, Tag: tag, Value: value, Line: from.Line, Column: from.Column}
+		l.origin[c] = l.origin[from]
+		if l.interpolated[from] {
+			l.interpolated[c] = true
+		}
+		return c
+	}
+
+	switch {
+	case n.Kind == yaml.SequenceNode && listToMapKeys[key] != "":
+		m := newNode(yaml.MappingNode, "!!map", "", n)
+		for _, item := range n.Content {
+			k, v, ok := strings.Cut(item.Value, listToMapKeys[key])
+			if !ok && key == "extra_hosts" {
+				k, v, ok = strings.Cut(item.Value, "=")
+			}
+			value := newNode(yaml.ScalarNode, "!!str", v, item)
+			if !ok {
+				value.Tag = "!!null"
+			}
+			m.Content = append(m.Content, newNode(yaml.ScalarNode, "!!str", k, item), value)
+		}
+		return m
+	case n.Kind == yaml.SequenceNode && (key == "depends_on" || key == "networks"):
+		m := newNode(yaml.MappingNode, "!!map", "", n)
+		for _, item := range n.Content {
+			m.Content = append(m.Content, item, newNode(yaml.ScalarNode, "!!null", "", item))
+		}
+		return m
+	case n.Kind == yaml.ScalarNode && key == "build":
+		m := newNode(yaml.MappingNode, "!!map", "", n)
+		m.Content = []*yaml.Node{newNode(yaml.ScalarNode, "!!str", "context", n), n}
+		return m
+	}
+	return n
+}
+
+// mergeVolumes merges volume lists; a mount of over replaces the mount of
+// base with the same target
+func mergeVolumes(base, over *yaml.Node) *yaml.Node {
+	for _, item := range over.Content {
+		target := volumeTarget(item)
+		i := slices.IndexFunc(base.Content, func(b *yaml.Node) bool { return volumeTarget(b) == target })
+		if i >= 0 && target != "" {
+			base.Content[i] = item
+		} else {
+			base.Content = append(base.Content, item)
+		}
+	}
+	return base
+}
+
+func volumeTarget(n *yaml.Node) string {
+	if n.Kind == yaml.MappingNode {
+		return scalar(lookupKey(n, "target"))
+	}
+	parts := strings.Split(n.Value, ":")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// service decodes a resolved service mapping
+func (l *loader) service(name, node *yaml.Node) *Service {
+	s := &Service{
+		Name: name.Value,
+		Pos:  l.pos(name),
+		Keys: make(map[string]Pos),
+	}
+
+	for _, kv := range pairs(node) {
+		key, value := kv[0].Value, kv[1]
+		s.Keys[key] = l.pos(kv[0])
+
+		switch key {
+		case "image":
+			s.Image = value.Value
+		case "build":
+			s.Build = l.build(l.normalize("build", value))
+		case "container_name":
+			s.ContainerName = value.Value
+		case "environment":
+			s.Environment = l.environment(value)
+		case "env_file":
+			s.EnvFiles = l.envFiles(value)
+		case "ports":
+			for _, item := range l.sequence(value, key) {
+				if port := l.port(item); port != nil {
+					s.Ports = append(s.Ports, port)
+				}
+			}
+		case "expose":
+			s.Expose = stringList(value)
+		case "volumes":
+			for _, item := range l.sequence(value, key) {
+				s.Volumes = append(s.Volumes, l.volume(item))
+			}
+		case "networks":
+			for _, kv := range pairs(l.normalize(key, value)) {
+				n := &ServiceNetwork{Name: kv[0].Value, Pos: l.pos(kv[0])}
+				if kv[1].Kind == yaml.MappingNode {
+					n.Aliases = stringList(lookupKey(kv[1], "aliases"))
+				}
+				s.Networks = append(s.Networks, n)
+			}
+		case "network_mode":
+			s.NetworkMode = value.Value
+		case "depends_on":
+			for _, kv := range pairs(l.normalize(key, value)) {
+				dep := &Dependency{Service: kv[0].Value, Condition: "service_started", Required: true, Pos: l.pos(kv[0])}
+				if kv[1].Kind == yaml.MappingNode {
+					if c := scalar(lookupKey(kv[1], "condition")); c != "" {
+						dep.Condition = c
+					}
+					if r := lookupKey(kv[1], "required"); r != nil {
+						dep.Required = boolValue(r)
+					}
+				}
+				switch dep.Condition {
+				case "service_started", "service_healthy", "service_completed_successfully":
+				default:
+					l.errorf(kv[1], "invalid depends_on condition %q", dep.Condition)
+				}
+				s.DependsOn = append(s.DependsOn, dep)
+			}
+		case "healthcheck":
+			s.Healthcheck = l.healthcheck(kv[0], value)
+		case "profiles":
+			s.Profiles = stringList(value)
+		case "privileged":
+			s.Privileged = boolValue(value)
+		case "cap_add":
+			s.CapAdd = stringList(value)
+		case "restart":
+			s.Restart = value.Value
+		case "secrets":
+			for _, item := range l.sequence(value, key) {
+				if item.Kind == yaml.MappingNode {
+					s.Secrets = append(s.Secrets, scalar(lookupKey(item, "source")))
+				} else {
+					s.Secrets = append(s.Secrets, item.Value)
+				}
+			}
+		case "mem_limit":
+			s.Limits.Memory, s.Limits.Pos = value.Value, l.pos(kv[0])
+		case "cpus":
+			s.Limits.CPUs, s.Limits.Pos = value.Value, l.pos(kv[0])
+		case "deploy":
+			if limits := lookupPath(value, "resources", "limits"); limits != nil {
+				if v := scalar(lookupKey(limits, "memory")); v != "" {
+					s.Limits.Memory = v
+				}
+				if v := scalar(lookupKey(limits, "cpus")); v != "" {
+					s.Limits.CPUs = v
+				}
+				s.Limits.Pos = l.pos(limits)
+			}
+		case "extends":
+			// Only present on services that failed to resolve
+		}
+	}
+
+	if s.Image == "" && s.Build == nil {
+		l.errorf(name, "service %q has neither an image nor a build context", s.Name)
+	}
+	return s
+}
+
+// sequence returns the items of a sequence, reporting other kinds
+func (l *loader) sequence(n *yaml.Node, key string) []*yaml.Node {
+	if n.Kind != yaml.SequenceNode {
+		if n.Tag != "!!null" {
+			l.errorf(n, "%s must be a list", key)
+		}
+		return nil
+	}
+	return n.Content
+}
+
+func (l *loader) build(n *yaml.Node) *Build {
+	b := &Build{Context: ".", Dockerfile: "Dockerfile", Args: make(map[string]string)}
+	for _, kv := range pairs(n) {
+		switch kv[0].Value {
+		case "context":
+			b.Context = kv[1].Value
+		case "dockerfile":
+			b.Dockerfile = kv[1].Value
+		case "target":
+			b.Target = kv[1].Value
+		case "args":
+			for _, arg := range pairs(l.normalize("environment", kv[1])) {
+				b.Args[arg[0].Value] = arg[1].Value
+			}
+		}
+	}
+	return b
+}
+
+func (l *loader) environment(n *yaml.Node) []*EnvVar {
+	var vars []*EnvVar
+	for _, kv := range pairs(l.normalize("environment", n)) {
+		vars = append(vars, &EnvVar{
+			Key:          kv[0].Value,
+			Value:        kv[1].Value,
+			HasValue:     kv[1].Tag != "!!null",
+			Interpolated: l.interpolated[kv[1]],
+			Pos:          l.pos(kv[0]),
+		})
+	}
+	return vars
+}
+
+// envFiles decodes env_file entries and checks that the files exist. Paths
+// are relative to the file that declared them.
+func (l *loader) envFiles(n *yaml.Node) []*EnvFile {
+	items := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		items = n.Content
+	}
+	var files []*EnvFile
+	for _, item := range items {
+		f := &EnvFile{Path: item.Value, Required: true, Pos: l.pos(item)}
+		if item.Kind == yaml.MappingNode {
+			f.Path = scalar(lookupKey(item, "path"))
+			if r := lookupKey(item, "required"); r != nil {
+				f.Required = boolValue(r)
+			}
+		}
+		if !filepath.IsAbs(f.Path) {
+			f.Path = filepath.Join(filepath.Dir(l.origin[item]), f.Path)
+		}
+		if _, err := os.Stat(f.Path); err != nil {
+			f.Missing = true
+			if f.Required {
+				l.errorf(item, "env file %s not found", f.Path)
+			}
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// applyEnvFiles adds variables from the service env files that are not set
+// in environment
+func (l *loader) applyEnvFiles(s *Service) {
+	for _, f := range s.EnvFiles {
+		if f.Missing {
+			continue
+		}
+		src, err := os.ReadFile(f.Path)
+		if err != nil {
+			l.errs = append(l.errs, &Error{Pos: f.Pos, Msg: fmt.Sprintf("failed to read env file: %v", err)})
+			continue
+		}
+		vars, err := ParseEnvFile(f.Path, string(src), l.lookup)
+		var list ErrorList
+		if errors.As(err, &list) {
+			l.errs = append(l.errs, list...)
+		}
+		for _, v := range vars {
+			if !slices.ContainsFunc(s.Environment, func(e *EnvVar) bool { return e.Key == v.Key }) {
+				v.FromEnvFile = true
+				s.Environment = append(s.Environment, v)
+			}
+		}
+	}
+}
+
+var portProtocolRe = regexp.MustCompile(`/(tcp|udp|sctp)$`)
+
+// port decodes a port mapping in the short "[ip:][published:]target[/proto]"
+// form or the long form
+func (l *loader) port(n *yaml.Node) *Port {
+	p := &Port{Protocol: "tcp", Raw: n.Value, Pos: l.pos(n)}
+	var err error
+
+	if n.Kind == yaml.MappingNode {
+		var raw []string
+		for _, kv := range pairs(n) {
+			raw = append(raw, kv[0].Value+"="+kv[1].Value)
+		}
+		p.Raw = strings.Join(raw, ",")
+		p.HostIP = scalar(lookupKey(n, "host_ip"))
+		if proto := scalar(lookupKey(n, "protocol")); proto != "" {
+			p.Protocol = proto
+		}
+		if p.TargetStart, p.TargetEnd, err = parsePortRange(scalar(lookupKey(n, "target"))); err == nil {
+			if published := scalar(lookupKey(n, "published")); published != "" {
+				p.PublishedStart, p.PublishedEnd, err = parsePortRange(published)
+			}
+		}
+		if err != nil {
+			l.errorf(n, "invalid port %s: %v", p.Raw, err)
+			return nil
+		}
+		return p
+	}
+
+	spec := n.Value
+	if m := portProtocolRe.FindStringSubmatch(spec); m != nil {
+		p.Protocol = m[1]
+		spec = strings.TrimSuffix(spec, m[0])
+	}
+	if strings.HasPrefix(spec, "[") {
+		end := strings.Index(spec, "]:")
+		if end < 0 {
+			l.errorf(n, "invalid port %s: unterminated IPv6 address", p.Raw)
+			return nil
+		}
+		p.HostIP, spec = spec[1:end], spec[end+2:]
+	}
+
+	parts := strings.Split(spec, ":")
+	var published string
+	switch len(parts) {
+	case 1:
+	case 2:
+		published = parts[0]
+	case 3:
+		if p.HostIP != "" {
+			l.errorf(n, "invalid port %s", p.Raw)
+			return nil
+		}
+		p.HostIP, published = parts[0], parts[1]
+	default:
+		l.errorf(n, "invalid port %s", p.Raw)
+		return nil
+	}
+	if p.TargetStart, p.TargetEnd, err = parsePortRange(parts[len(parts)-1]); err == nil && published != "" {
+		p.PublishedStart, p.PublishedEnd, err = parsePortRange(published)
+	}
+	if err != nil {
+		l.errorf(n, "invalid port %s: %v", p.Raw, err)
+		return nil
+	}
+	return p
+}
+
+// parsePortRange parses "80" or "8000-8010"
+func parsePortRange(s string) (int, int, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	start, err := strconv.Atoi(from)
+	if err != nil || start < 1 || start > 65535 {
+		return 0, 0, fmt.Errorf("%q is not a port number", from)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil || end < start || end > 65535 {
+		return 0, 0, fmt.Errorf("%q is not a port range", s)
+	}
+	return start, end, nil
+}
+
+// volume decodes a mount in the short "[source:]target[:mode]" form or the
+// long form
+func (l *loader) volume(n *yaml.Node) *ServiceVolume {
+	v := &ServiceVolume{Pos: l.pos(n)}
+	if n.Kind == yaml.MappingNode {
+		v.Type = scalar(lookupKey(n, "type"))
+		v.Source = scalar(lookupKey(n, "source"))
+		v.Target = scalar(lookupKey(n, "target"))
+		v.ReadOnly = boolValue(lookupKey(n, "read_only"))
+		if v.Type == "" {
+			v.Type = "volume"
+		}
+		return v
+	}
+
+	parts := strings.Split(n.Value, ":")
+	switch len(parts) {
+	case 1:
+		v.Type, v.Target = "volume", parts[0]
+		return v
+	case 3:
+		v.ReadOnly = slices.Contains(strings.Split(parts[2], ","), "ro")
+	}
+	v.Source, v.Target = parts[0], parts[1]
+	v.Type = "volume"
+	if strings.HasPrefix(v.Source, ".") || strings.HasPrefix(v.Source, "/") || strings.HasPrefix(v.Source, "~") {
+		v.Type = "bind"
+	}
+	return v
+}
+
+func (l *loader) healthcheck(key, n *yaml.Node) *Healthcheck {
+	h := &Healthcheck{Pos: l.pos(key)}
+	for _, kv := range pairs(n) {
+		switch kv[0].Value {
+		case "test":
+			if kv[1].Kind == yaml.ScalarNode {
+				h.Test = []string{"CMD-SHELL", kv[1].Value}
+			} else {
+				h.Test = stringList(kv[1])
+			}
+		case "disable":
+			h.Disable = boolValue(kv[1])
+		case "interval":
+			h.Interval = kv[1].Value
+		case "timeout":
+			h.Timeout = kv[1].Value
+		case "retries":
+			h.Retries, _ = strconv.Atoi(kv[1].Value)
+		}
+	}
+	if len(h.Test) > 0 && h.Test[0] == "NONE" {
+		h.Disable = true
+	}
+	return h
+}
+
+// resources decodes a top-level volumes, networks or secrets mapping
+func (l *loader) resources(n *yaml.Node) []*Resource {
+	var list []*Resource
+	for _, kv := range pairs(n) {
+		r := &Resource{Name: kv[0].Value, Pos: l.pos(kv[0])}
+		if kv[1].Kind == yaml.MappingNode {
+			r.Driver = scalar(lookupKey(kv[1], "driver"))
+			if ext := lookupKey(kv[1], "external"); ext != nil {
+				r.External = ext.Kind == yaml.MappingNode || boolValue(ext)
+			}
+		}
+		list = append(list, r)
+	}
+	return list
+}
+
+// profileEnabled reports whether a service with profiles is enabled; a
+// service without profiles always is
+func profileEnabled(profiles, active []string) bool {
+	if len(profiles) == 0 || slices.Contains(active, "*") {
+		return true
+	}
+	for _, p := range profiles {
+		if slices.Contains(active, p) {
+			return true
+		}
+	}
+	return false
+}
+
+var projectNameRe = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// normalizeProjectName lowercases name and drops characters Compose does
+// not allow in project names
+func normalizeProjectName(name string) string {
+	name = projectNameRe.ReplaceAllString(strings.ToLower(name), "")
+	return strings.TrimLeft(name, "_-")
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
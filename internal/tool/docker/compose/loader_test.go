@@ -0,0 +1,230 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoad_Project(t *testing.T) {
+	path := filepath.Join("testdata", "project", "compose.yaml")
+	p, err := Load(path, Options{Profiles: []string{"db"}})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if p.Name != "demo_app" {
+		t.Errorf("Name = %q, want demo_app", p.Name)
+	}
+	if p.Environment["API_PORT"] != "18080" {
+		t.Errorf(".env not loaded: %v", p.Environment)
+	}
+	if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0].Msg, `"API_TOKEN" variable is not set`) {
+		t.Errorf("Warnings = %v", p.Warnings)
+	}
+
+	api := p.Service("api")
+	if api == nil {
+		t.Fatal("service api not loaded")
+	}
+	if api.Image != "example/api:1.0" || api.Build == nil || api.Build.Context != "./api" || api.Restart != "unless-stopped" {
+		t.Errorf("api = image %q build %+v restart %q", api.Image, api.Build, api.Restart)
+	}
+
+	env := make(map[string]string)
+	for _, v := range api.Environment {
+		env[v.Key] = v.Value
+		switch v.Key {
+		case "API_TOKEN":
+			if !v.Interpolated || v.Pos.Line != 16 {
+				t.Errorf("API_TOKEN = %+v, want interpolated on line 16", v)
+			}
+		case "FEATURE_FLAGS":
+			if !v.FromEnvFile || v.Pos.Line != 2 {
+				t.Errorf("FEATURE_FLAGS = %+v, want line 2 of api.env", v)
+			}
+		}
+	}
+	wantEnv := map[string]string{"LOG_LEVEL": "info", "API_TOKEN": "", "PRICE": "$5", "FEATURE_FLAGS": "a,b"}
+	if !reflect.DeepEqual(env, wantEnv) {
+		t.Errorf("environment = %v, want %v", env, wantEnv)
+	}
+	if len(api.EnvFiles) != 2 || api.EnvFiles[0].Missing || !api.EnvFiles[1].Missing || api.EnvFiles[1].Required {
+		t.Errorf("env files = %+v %+v", api.EnvFiles[0], api.EnvFiles[1])
+	}
+
+	var ports []string
+	for _, port := range api.Ports {
+		ports = append(ports, port.HostIP+"|"+portRange(port.PublishedStart, port.PublishedEnd)+"|"+portRange(port.TargetStart, port.TargetEnd)+"|"+port.Protocol)
+	}
+	wantPorts := []string{"|8080|8080|tcp", "|18080|8080|tcp", "127.0.0.1|9000-9001|9000-9001|udp", "::1|7001|7000|tcp"}
+	if !reflect.DeepEqual(ports, wantPorts) {
+		t.Errorf("ports = %v, want %v", ports, wantPorts)
+	}
+
+	if len(api.Volumes) != 2 || api.Volumes[0].Source != "data" || api.Volumes[1].Type != "bind" || !api.Volumes[1].ReadOnly {
+		t.Errorf("volumes = %+v %+v", api.Volumes[0], api.Volumes[1])
+	}
+	if api.Limits.Memory != "256M" || filepath.Base(api.Limits.Pos.File) != "common.yaml" {
+		t.Errorf("limits = %+v", api.Limits)
+	}
+	if len(api.DependsOn) != 1 || api.DependsOn[0].Condition != "service_healthy" {
+		t.Errorf("depends_on = %+v", api.DependsOn)
+	}
+
+	db := p.Service("db")
+	if !db.Enabled || db.Healthcheck == nil || !reflect.DeepEqual(db.Healthcheck.Test, []string{"CMD-SHELL", "pg_isready"}) {
+		t.Errorf("db = %+v", db)
+	}
+	if p.Service("debug").Enabled {
+		t.Error("debug enabled without its profile")
+	}
+
+	order, cycle := p.StartOrder()
+	if !reflect.DeepEqual(order, []string{"db", "api"}) || cycle != nil {
+		t.Errorf("StartOrder() = %v, %v", order, cycle)
+	}
+}
+
+// portRange formats a port range the way the short syntax writes it
+func portRange(from, to int) string {
+	switch {
+	case from == 0:
+		return ""
+	case from == to:
+		return strconv.Itoa(from)
+	}
+	return fmt.Sprintf("%d-%d", from, to)
+}
+
+func TestLoadBytes_Errors(t *testing.T) {
+	src := `version: "2.4"
+web:
+  image: nginx
+services:
+  a:
+    extends: b
+  b:
+    extends: a
+  c:
+    environment:
+      - X=1
+  d:
+    image: alpine
+    ports:
+      - "80:http"
+    depends_on:
+      c:
+        condition: service_ready
+  e:
+    image: "${MISSING:?must be set}"
+`
+	p, err := LoadBytes("compose.yaml", []byte(src), Options{})
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("LoadBytes() error = %v, want ErrorList", err)
+	}
+
+	var got []string
+	for _, e := range list {
+		got = append(got, e.Error())
+	}
+	want := []string{
+		`compose.yaml:2: service "web" is at the top level; the version 1 format is not supported`,
+		`compose.yaml:6: circular extends: compose.yaml#a -> compose.yaml#b -> compose.yaml#a`,
+		`compose.yaml:9: service "c" has neither an image nor a build context`,
+		`compose.yaml:15: invalid port 80:http: "http" is not a port number`,
+		`compose.yaml:18: invalid depends_on condition "service_ready"`,
+		`compose.yaml:20: required variable MISSING is missing a value: must be set`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if p.Version != "2.4" || p.VersionPos.Line != 1 {
+		t.Errorf("version = %q at %v", p.Version, p.VersionPos)
+	}
+	if p.Service("d") == nil || p.Service("a") != nil {
+		t.Errorf("services = %v", p.Services)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	env := MapLookup(map[string]string{"SET": "value", "EMPTY": "", "INNER": "inner"})
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "plain", want: "plain"},
+		{in: "$SET/${SET}", want: "value/value"},
+		{in: "$$SET costs $$5", want: "$SET costs $5"},
+		{in: "${UNSET:-fallback} ${EMPTY:-fallback} ${EMPTY-kept}", want: "fallback fallback "},
+		{in: "${UNSET:-${INNER}-x}", want: "inner-x"},
+		{in: "${SET:+alt} ${EMPTY:+alt} ${EMPTY+alt} ${UNSET+alt}", want: "alt  alt "},
+		{in: "${SET:?boom}", want: "value"},
+		{in: "${EMPTY:?is empty}", wantErr: "required variable EMPTY is missing a value: is empty"},
+		{in: "${EMPTY?unset only}", want: ""},
+		{in: "${UNSET", wantErr: "missing closing brace"},
+		{in: "${1BAD}", wantErr: "invalid interpolation format"},
+		{in: "cost: $ 5", want: "cost: $ 5"},
+	}
+	for _, tt := range tests {
+		got, err := Interpolate(tt.in, env)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Interpolate(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Interpolate(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	src := `# comment
+export HOST=db.local
+PORT = 5432
+URL=postgres://${HOST}:${PORT}/app # trailing comment
+LITERAL='no $HOST expansion'
+QUOTED="line1\nline2 \"q\""
+MULTI="first
+second"
+BARE
+AFTER=ok
+bad key=1
+`
+	vars, err := ParseEnvFile("app.env", src, nil)
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != 1 || list[0].Pos.Line != 11 {
+		t.Fatalf("ParseEnvFile() error = %v, want one error on line 11", err)
+	}
+
+	got := make(map[string]string)
+	lines := make(map[string]int)
+	for _, v := range vars {
+		got[v.Key] = v.Value
+		lines[v.Key] = v.Pos.Line
+	}
+	want := map[string]string{
+		"HOST":    "db.local",
+		"PORT":    "5432",
+		"URL":     "postgres://db.local:5432/app",
+		"LITERAL": "no $HOST expansion",
+		"QUOTED":  "line1\nline2 \"q\"",
+		"MULTI":   "first\nsecond",
+		"BARE":    "",
+		"AFTER":   "ok",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseEnvFile() = %q, want %q", got, want)
+	}
+	if lines["BARE"] != 9 || lines["AFTER"] != 10 {
+		t.Errorf("lines = %v", lines)
+	}
+}
@@ -0,0 +1,167 @@
+package compose
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pairs returns the key and value nodes of a mapping; other kinds have none
+func pairs(n *yaml.Node) [][2]*yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	kvs := make([][2]*yaml.Node, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		kvs = append(kvs, [2]*yaml.Node{n.Content[i], n.Content[i+1]})
+	}
+	return kvs
+}
+
+// lookupKey returns the value of key in a mapping, or nil
+func lookupKey(n *yaml.Node, key string) *yaml.Node {
+	for _, kv := range pairs(n) {
+		if kv[0].Value == key {
+			return kv[1]
+		}
+	}
+	return nil
+}
+
+// lookupPath follows a chain of mapping keys
+func lookupPath(n *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if n = lookupKey(n, key); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// setKey replaces the mapping entry with key's name
+func setKey(n, key, value *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key.Value {
+			n.Content[i], n.Content[i+1] = key, value
+			return
+		}
+	}
+}
+
+// removeKey deletes key from a mapping
+func removeKey(n *yaml.Node, key string) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// scalar returns the value of a scalar node, or ""
+func scalar(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// stringList returns a scalar as a one-item list and the scalars of a
+// sequence
+func stringList(n *yaml.Node) []string {
+	switch {
+	case n == nil:
+		return nil
+	case n.Kind == yaml.ScalarNode && n.Tag != "!!null":
+		return []string{n.Value}
+	case n.Kind == yaml.SequenceNode:
+		list := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			list = append(list, item.Value)
+		}
+		return list
+	}
+	return nil
+}
+
+// boolValue reads a YAML boolean, accepting the quoted forms Compose allows
+func boolValue(n *yaml.Node) bool {
+	if n == nil {
+		return false
+	}
+	switch strings.ToLower(n.Value) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// walk calls fn for n and every node below it
+func walk(n *yaml.Node, fn func(*yaml.Node)) {
+	fn(n)
+	for _, c := range n.Content {
+		walk(c, fn)
+	}
+}
+
+// expandAliases replaces aliases with copies of their anchors and applies
+// "<<" merge keys, so later passes see plain trees
+func expandAliases(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		return expandAliases(copyTree(n.Alias))
+	}
+	for i, c := range n.Content {
+		n.Content[i] = expandAliases(c)
+	}
+	if n.Kind != yaml.MappingNode {
+		return n
+	}
+
+	var merged []*yaml.Node
+	var own []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Tag != "!!merge" && key.Value != "<<" {
+			own = append(own, key, value)
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, src := range sources {
+			for _, kv := range pairs(src) {
+				if !hasKey(own, kv[0].Value) && !hasKey(merged, kv[0].Value) {
+					merged = append(merged, kv[0], kv[1])
+				}
+			}
+		}
+	}
+	// Keys of the mapping itself win over merged ones
+	for i := 0; i+1 < len(merged); i += 2 {
+		if !hasKey(own, merged[i].Value) {
+			own = append(own, merged[i], merged[i+1])
+		}
+	}
+	n.Content = own
+	return n
+}
+
+// copyTree deep-copies a node tree
+func copyTree(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyTree(child)
+	}
+	return &c
+}
+
+func hasKey(content []*yaml.Node, key string) bool {
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
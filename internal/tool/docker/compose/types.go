@@ -0,0 +1,303 @@
+// Package compose loads Docker Compose files into a typed project.
+//
+// The loader follows the Compose specification for the parts that matter
+// to analysis: variable interpolation with the project .env file, service
+// extends across files, profiles, env_file, and the short and long forms of
+// ports, volumes, networks and depends_on. Both the legacy v2/v3 files with
+// a version key and version-less files are accepted. Loading continues past
+// errors so a partially broken file can still be analysed.
+package compose
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Pos is a position in a compose or env file
+type Pos struct {
+	File string // path of the file, as given to the loader
+	Line int    // line number, starting at 1; 0 when unknown
+}
+
+func (p Pos) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// Project is a loaded compose project
+type Project struct {
+	Name       string
+	File       string // main compose file
+	WorkingDir string
+	// Version is the obsolete top-level version key, if present
+	Version    string
+	VersionPos Pos
+	// Services holds every service in file order, including those disabled
+	// by profiles
+	Services []*Service
+	Volumes  []*Resource
+	Networks []*Resource
+	Secrets  []*Resource
+	// Profiles holds the active profiles
+	Profiles []string
+	// Environment is the environment used for interpolation: the .env file
+	// overlaid with the environment given to the loader
+	Environment map[string]string
+	// Warnings holds problems that do not make the project invalid, such as
+	// unset variables
+	Warnings []*Error
+}
+
+// Service returns the service with the given name, or nil
+func (p *Project) Service(name string) *Service {
+	for _, s := range p.Services {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// EnabledServices returns the services enabled by the active profiles
+func (p *Project) EnabledServices() []*Service {
+	var enabled []*Service
+	for _, s := range p.Services {
+		if s.Enabled {
+			enabled = append(enabled, s)
+		}
+	}
+	return enabled
+}
+
+// StartOrder returns the enabled services ordered so that every service
+// comes after its dependencies, and the services that are part of a
+// dependency cycle. Services without an order between them keep file order.
+func (p *Project) StartOrder() (order []string, cycle []string) {
+	enabled := make(map[string]*Service)
+	for _, s := range p.EnabledServices() {
+		enabled[s.Name] = s
+	}
+
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := make(map[string]int)
+	inCycle := make(map[string]bool)
+	var visit func(s *Service, path []string)
+	visit = func(s *Service, path []string) {
+		switch state[s.Name] {
+		case done:
+			return
+		case visiting:
+			for i := len(path) - 1; i >= 0; i-- {
+				inCycle[path[i]] = true
+				if path[i] == s.Name {
+					break
+				}
+			}
+			return
+		}
+		state[s.Name] = visiting
+		for _, dep := range s.DependsOn {
+			if d, ok := enabled[dep.Service]; ok {
+				visit(d, append(path, s.Name))
+			}
+		}
+		state[s.Name] = done
+		order = append(order, s.Name)
+	}
+	for _, s := range p.Services {
+		if s.Enabled {
+			visit(s, nil)
+		}
+	}
+
+	for name := range inCycle {
+		cycle = append(cycle, name)
+	}
+	sort.Strings(cycle)
+	return order, cycle
+}
+
+// Service is a service definition after extends and env_file are applied
+type Service struct {
+	Name string
+	Pos  Pos
+	// Keys records the position of each key of the service mapping
+	Keys map[string]Pos
+
+	Image         string
+	Build         *Build
+	ContainerName string
+	Environment   []*EnvVar
+	EnvFiles      []*EnvFile
+	Ports         []*Port
+	Expose        []string
+	Volumes       []*ServiceVolume
+	Networks      []*ServiceNetwork
+	NetworkMode   string
+	DependsOn     []*Dependency
+	Healthcheck   *Healthcheck
+	Profiles      []string
+	Privileged    bool
+	CapAdd        []string
+	Restart       string
+	Secrets       []string
+	Limits        Limits
+	Extends       *Extends
+
+	// Enabled reports whether the service is enabled by the active profiles
+	Enabled bool
+}
+
+// KeyPos returns the position of key in the service mapping, falling back
+// to the service name
+func (s *Service) KeyPos(key string) Pos {
+	if pos, ok := s.Keys[key]; ok {
+		return pos
+	}
+	return s.Pos
+}
+
+// Build is the build section of a service
+type Build struct {
+	Context    string
+	Dockerfile string
+	Target     string
+	Args       map[string]string
+}
+
+// EnvVar is a service environment variable
+type EnvVar struct {
+	Key      string
+	Value    string
+	HasValue bool // false for a bare KEY taken from the shell
+	// Interpolated reports whether the value references a variable
+	Interpolated bool
+	// FromEnvFile reports whether the variable came from an env_file
+	FromEnvFile bool
+	Pos         Pos
+}
+
+// EnvFile is an env_file entry of a service
+type EnvFile struct {
+	Path     string // resolved path
+	Required bool
+	Missing  bool
+	Pos      Pos
+}
+
+// Port is a port mapping of a service. Published and Target hold a single
+// port or a range; a mapping without a published port publishes an
+// ephemeral host port.
+type Port struct {
+	HostIP         string
+	PublishedStart int
+	PublishedEnd   int
+	TargetStart    int
+	TargetEnd      int
+	Protocol       string
+	Raw            string
+	Pos            Pos
+}
+
+// Published reports whether the mapping binds a fixed host port
+func (p *Port) Published() bool {
+	return p.PublishedStart > 0
+}
+
+// ServiceVolume is a mount of a service
+type ServiceVolume struct {
+	Type     string // "volume", "bind" or "tmpfs"
+	Source   string // volume name or host path; empty for anonymous volumes
+	Target   string
+	ReadOnly bool
+	Pos      Pos
+}
+
+// ServiceNetwork is a network a service is attached to
+type ServiceNetwork struct {
+	Name    string
+	Aliases []string
+	Pos     Pos
+}
+
+// Dependency is a depends_on entry
+type Dependency struct {
+	Service   string
+	Condition string // service_started, service_healthy or service_completed_successfully
+	Required  bool
+	Pos       Pos
+}
+
+// Healthcheck is the healthcheck of a service
+type Healthcheck struct {
+	Test     []string
+	Disable  bool
+	Interval string
+	Timeout  string
+	Retries  int
+	Pos      Pos
+}
+
+// Limits are the resource limits of a service from deploy.resources.limits
+// or the v2 mem_limit and cpus keys
+type Limits struct {
+	CPUs   string
+	Memory string
+	Pos    Pos
+}
+
+// Extends is the extends section of a service
+type Extends struct {
+	Service string
+	File    string
+}
+
+// Resource is a top-level volume, network or secret
+type Resource struct {
+	Name     string
+	Driver   string
+	External bool
+	Pos      Pos
+}
+
+// Error is a problem found while loading a project
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is a list of load errors
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns l sorted by position as an error, or nil when it is empty
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].Pos.File != l[j].Pos.File {
+			return l[i].Pos.File < l[j].Pos.File
+		}
+		return l[i].Pos.Line < l[j].Pos.Line
+	})
+	return l
+}
@@ -0,0 +1,384 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/docker/compose"
+	"github.com/koopa0/assistant-go/internal/tool/docker/dockerfile"
+)
+
+// ComposeAnalyzer analyzes Docker Compose projects
+type ComposeAnalyzer struct {
+	logger *slog.Logger
+}
+
+// NewComposeAnalyzer creates a new compose analyzer
+func NewComposeAnalyzer(logger *slog.Logger) *ComposeAnalyzer {
+	return &ComposeAnalyzer{
+		logger: logger,
+	}
+}
+
+// ComposeAnalysisResult represents the result of compose analysis
+type ComposeAnalysisResult struct {
+	Project        string               `json:"project"`
+	File           string               `json:"file"`
+	ActiveProfiles []string             `json:"active_profiles"`
+	Services       []ComposeServiceInfo `json:"services"`
+	StartOrder     []string             `json:"start_order"`
+	Issues         []Issue              `json:"issues"`
+}
+
+// ComposeServiceInfo describes a service and its place in the dependency
+// graph
+type ComposeServiceInfo struct {
+	Name        string              `json:"name"`
+	Line        int                 `json:"line"`
+	Image       string              `json:"image,omitempty"`
+	Build       string              `json:"build,omitempty"`
+	Enabled     bool                `json:"enabled"`
+	Profiles    []string            `json:"profiles,omitempty"`
+	DependsOn   []ComposeDependency `json:"depends_on,omitempty"`
+	Ports       []string            `json:"ports,omitempty"`
+	Healthcheck bool                `json:"healthcheck"`
+}
+
+// ComposeDependency is an edge of the service dependency graph
+type ComposeDependency struct {
+	Service   string `json:"service"`
+	Condition string `json:"condition"`
+}
+
+// composeFileNames are the default compose file names in lookup order
+var composeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// findComposeFile returns the first default compose file in dir
+func findComposeFile(dir string) (string, error) {
+	for _, name := range composeFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no compose file found in %s", dir)
+}
+
+// AnalyzeFile analyzes the compose file at path
+func (a *ComposeAnalyzer) AnalyzeFile(path string, opts compose.Options) (*ComposeAnalysisResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compose file: %w", err)
+	}
+	return a.Analyze(path, content, opts)
+}
+
+// Analyze analyzes compose file content; path names the file and anchors
+// relative paths. Load errors are reported as issues with the "syntax" rule
+// and the rest of the project is still analyzed.
+func (a *ComposeAnalyzer) Analyze(path string, content []byte, opts compose.Options) (*ComposeAnalysisResult, error) {
+	project, err := compose.LoadBytes(path, content, opts)
+	var loadErrors compose.ErrorList
+	if err != nil && !errors.As(err, &loadErrors) {
+		return nil, fmt.Errorf("failed to load compose file: %w", err)
+	}
+
+	result := &ComposeAnalysisResult{
+		Project:        project.Name,
+		File:           path,
+		ActiveProfiles: project.Profiles,
+		Services:       []ComposeServiceInfo{},
+		Issues:         []Issue{},
+	}
+	for _, e := range loadErrors {
+		a.addIssue(result, project, "", e.Pos, "error", "syntax", e.Msg)
+	}
+	for _, w := range project.Warnings {
+		a.addIssue(result, project, "", w.Pos, "warning", "unset-variable", w.Msg)
+	}
+	if project.Version != "" {
+		a.addIssue(result, project, "", project.VersionPos, "info", "obsolete-version",
+			"The top-level version key is obsolete and ignored by Compose v2")
+	}
+
+	a.describeServices(project, result)
+	a.analyzeDependencies(project, result)
+	a.analyzePorts(project, result)
+	for _, svc := range project.Services {
+		a.analyzeService(project, svc, result)
+	}
+	a.analyzeResources(project, result)
+
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		if result.Issues[i].File != result.Issues[j].File {
+			return result.Issues[i].File == ""
+		}
+		return result.Issues[i].Line < result.Issues[j].Line
+	})
+
+	a.logger.Debug("Compose analysis complete",
+		slog.String("file", path),
+		slog.Int("services", len(result.Services)),
+		slog.Int("issues", len(result.Issues)))
+
+	return result, nil
+}
+
+// describeServices fills in the service list and start order
+func (a *ComposeAnalyzer) describeServices(p *compose.Project, result *ComposeAnalysisResult) {
+	for _, svc := range p.Services {
+		info := ComposeServiceInfo{
+			Name:        svc.Name,
+			Line:        svc.Pos.Line,
+			Image:       svc.Image,
+			Enabled:     svc.Enabled,
+			Profiles:    svc.Profiles,
+			Healthcheck: svc.Healthcheck != nil && !svc.Healthcheck.Disable,
+		}
+		if svc.Build != nil {
+			info.Build = svc.Build.Context
+		}
+		for _, dep := range svc.DependsOn {
+			info.DependsOn = append(info.DependsOn, ComposeDependency{Service: dep.Service, Condition: dep.Condition})
+		}
+		for _, port := range svc.Ports {
+			info.Ports = append(info.Ports, port.Raw)
+		}
+		result.Services = append(result.Services, info)
+	}
+
+	order, cycle := p.StartOrder()
+	result.StartOrder = order
+	if len(cycle) > 0 {
+		svc := p.Service(cycle[0])
+		a.addIssue(result, p, svc.Name, svc.KeyPos("depends_on"), "error", "dependency-cycle",
+			fmt.Sprintf("Services %s depend on each other and cannot be started", strings.Join(cycle, ", ")))
+	}
+}
+
+// analyzeDependencies checks that dependencies exist, are enabled and can
+// report the condition that is waited for
+func (a *ComposeAnalyzer) analyzeDependencies(p *compose.Project, result *ComposeAnalysisResult) {
+	for _, svc := range p.EnabledServices() {
+		for _, dep := range svc.DependsOn {
+			target := p.Service(dep.Service)
+			switch {
+			case target == nil:
+				a.addIssue(result, p, svc.Name, dep.Pos, "error", "undefined-service",
+					fmt.Sprintf("Service %q depends on undefined service %q", svc.Name, dep.Service))
+				continue
+			case !target.Enabled && dep.Required:
+				a.addIssue(result, p, svc.Name, dep.Pos, "error", "disabled-dependency",
+					fmt.Sprintf("Service %q depends on %q, which is disabled unless profile %s is active",
+						svc.Name, dep.Service, strings.Join(target.Profiles, " or ")))
+			}
+
+			if dep.Condition != "service_healthy" {
+				continue
+			}
+			severity, reason := a.missingHealthcheck(p, target)
+			if reason != "" {
+				a.addIssue(result, p, svc.Name, dep.Pos, severity, "healthcheck-required",
+					fmt.Sprintf("Service %q waits for %q to be healthy, but %s", svc.Name, dep.Service, reason))
+			}
+		}
+	}
+}
+
+// missingHealthcheck explains why svc cannot become healthy, or returns ""
+// when it has a healthcheck. A healthcheck may come from the Dockerfile of
+// a service that is built; for other images it cannot be verified.
+func (a *ComposeAnalyzer) missingHealthcheck(p *compose.Project, svc *compose.Service) (string, string) {
+	if hc := svc.Healthcheck; hc != nil {
+		if hc.Disable {
+			return "error", "its healthcheck is disabled"
+		}
+		return "", ""
+	}
+	if svc.Build == nil {
+		return "warning", "it defines no healthcheck and image " + svc.Image + " may not provide one"
+	}
+
+	path := svc.Build.Dockerfile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.WorkingDir, svc.Build.Context, path)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "warning", "it defines no healthcheck and its Dockerfile could not be read"
+	}
+	df, _ := dockerfile.Parse(string(src))
+	stage := df.Target()
+	if svc.Build.Target != "" {
+		stage = df.Stage(svc.Build.Target)
+	}
+	if stage != nil && lastInStages(df, stage, "HEALTHCHECK") != nil {
+		return "", ""
+	}
+	return "error", "neither the service nor its Dockerfile defines a healthcheck"
+}
+
+// analyzePorts reports host ports published by more than one enabled
+// mapping
+func (a *ComposeAnalyzer) analyzePorts(p *compose.Project, result *ComposeAnalysisResult) {
+	type binding struct {
+		service string
+		port    *compose.Port
+	}
+	var seen []binding
+	for _, svc := range p.EnabledServices() {
+		for _, port := range svc.Ports {
+			if !port.Published() {
+				continue
+			}
+			for _, prev := range seen {
+				if portsCollide(prev.port, port) {
+					owner := fmt.Sprintf("service %q", prev.service)
+					if prev.service == svc.Name {
+						owner = "the same service"
+					}
+					a.addIssue(result, p, svc.Name, port.Pos, "error", "port-collision",
+						fmt.Sprintf("Host port %s of service %q is already published by %s (line %d)",
+							port.Raw, svc.Name, owner, prev.port.Pos.Line))
+					break
+				}
+			}
+			seen = append(seen, binding{svc.Name, port})
+		}
+	}
+}
+
+// portsCollide reports whether two published mappings bind an overlapping
+// host port range on an overlapping address
+func portsCollide(a, b *compose.Port) bool {
+	if a.Protocol != b.Protocol || a.PublishedEnd < b.PublishedStart || b.PublishedEnd < a.PublishedStart {
+		return false
+	}
+	return anyAddress(a.HostIP) || anyAddress(b.HostIP) || a.HostIP == b.HostIP
+}
+
+func anyAddress(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// dangerousCapabilities are capabilities that amount to root on the host
+var dangerousCapabilities = []string{"ALL", "SYS_ADMIN", "CAP_SYS_ADMIN"}
+
+// analyzeService checks the configuration of a single service
+func (a *ComposeAnalyzer) analyzeService(p *compose.Project, svc *compose.Service, result *ComposeAnalysisResult) {
+	// The image of a built service names the build result
+	if svc.Image != "" && svc.Build == nil {
+		ref := dockerfile.ParseImageRef(svc.Image)
+		if ref.Digest == "" && (ref.Tag == "" || ref.Tag == "latest") {
+			a.addIssue(result, p, svc.Name, svc.KeyPos("image"), "warning", "no-latest-tag",
+				fmt.Sprintf("Service %q uses image %s without a pinned version; use a specific tag or digest", svc.Name, svc.Image))
+		}
+	}
+
+	if svc.Privileged {
+		a.addIssue(result, p, svc.Name, svc.KeyPos("privileged"), "warning", "privileged",
+			fmt.Sprintf("Service %q runs privileged with full access to host devices", svc.Name))
+	}
+	for _, capability := range svc.CapAdd {
+		if slices.Contains(dangerousCapabilities, strings.ToUpper(capability)) {
+			a.addIssue(result, p, svc.Name, svc.KeyPos("cap_add"), "warning", "privileged",
+				fmt.Sprintf("Service %q adds capability %s, which is close to running privileged", svc.Name, capability))
+		}
+	}
+	for _, v := range svc.Volumes {
+		if v.Type == "bind" && strings.HasSuffix(v.Source, "/docker.sock") {
+			a.addIssue(result, p, svc.Name, v.Pos, "warning", "privileged",
+				fmt.Sprintf("Service %q mounts the Docker socket, which grants root access to the host", svc.Name))
+		}
+	}
+
+	for _, v := range svc.Environment {
+		if v.FromEnvFile || !v.HasValue || v.Value == "" || v.Interpolated {
+			continue
+		}
+		if secretName(v.Key) {
+			a.addIssue(result, p, svc.Name, v.Pos, "warning", "secret-in-environment",
+				fmt.Sprintf("Service %q sets %s inline; use secrets, an env_file or ${%s} interpolation", svc.Name, v.Key, v.Key))
+		} else if u, err := url.Parse(v.Value); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				a.addIssue(result, p, svc.Name, v.Pos, "warning", "secret-in-environment",
+					fmt.Sprintf("Service %q sets %s to a URL with an inline password", svc.Name, v.Key))
+			}
+		}
+	}
+
+	if svc.Enabled && svc.Limits.Memory == "" {
+		a.addIssue(result, p, svc.Name, svc.Pos, "warning", "resource-limits",
+			fmt.Sprintf("Service %q has no memory limit; set deploy.resources.limits", svc.Name))
+	}
+}
+
+// analyzeResources checks that the volumes and networks services use are
+// declared and that declared ones are used. Services disabled by profiles
+// count as users.
+func (a *ComposeAnalyzer) analyzeResources(p *compose.Project, result *ComposeAnalysisResult) {
+	usedVolumes := make(map[string]bool)
+	usedNetworks := make(map[string]bool)
+	for _, svc := range p.Services {
+		for _, v := range svc.Volumes {
+			if v.Type != "volume" || v.Source == "" {
+				continue
+			}
+			usedVolumes[v.Source] = true
+			if !slices.ContainsFunc(p.Volumes, func(r *compose.Resource) bool { return r.Name == v.Source }) {
+				a.addIssue(result, p, svc.Name, v.Pos, "error", "undefined-volume",
+					fmt.Sprintf("Service %q uses volume %q, which is not declared under volumes", svc.Name, v.Source))
+			}
+		}
+
+		if svc.NetworkMode != "" {
+			continue
+		}
+		if len(svc.Networks) == 0 {
+			usedNetworks["default"] = true
+		}
+		for _, n := range svc.Networks {
+			usedNetworks[n.Name] = true
+			if n.Name != "default" && !slices.ContainsFunc(p.Networks, func(r *compose.Resource) bool { return r.Name == n.Name }) {
+				a.addIssue(result, p, svc.Name, n.Pos, "error", "undefined-network",
+					fmt.Sprintf("Service %q uses network %q, which is not declared under networks", svc.Name, n.Name))
+			}
+		}
+	}
+
+	for _, v := range p.Volumes {
+		if !usedVolumes[v.Name] {
+			a.addIssue(result, p, "", v.Pos, "warning", "unused-volume",
+				fmt.Sprintf("Volume %q is declared but not used by any service", v.Name))
+		}
+	}
+	for _, n := range p.Networks {
+		if !usedNetworks[n.Name] {
+			a.addIssue(result, p, "", n.Pos, "warning", "unused-network",
+				fmt.Sprintf("Network %q is declared but not used by any service", n.Name))
+		}
+	}
+}
+
+// addIssue appends an issue at pos; the file is only recorded for positions
+// outside the main compose file
+func (a *ComposeAnalyzer) addIssue(result *ComposeAnalysisResult, p *compose.Project, service string, pos compose.Pos, severity, rule, message string) {
+	issue := Issue{
+		Line:     pos.Line,
+		Severity: severity,
+		Message:  message,
+		Rule:     rule,
+		Service:  service,
+	}
+	if pos.File != p.File {
+		issue.File = pos.File
+	}
+	result.Issues = append(result.Issues, issue)
+}
@@ -0,0 +1,205 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+	"github.com/koopa0/assistant-go/internal/tool/docker/compose"
+)
+
+func TestComposeAnalyzer_RepoCompose(t *testing.T) {
+	result, err := NewComposeAnalyzer(discardLogger()).AnalyzeFile(filepath.Join("..", "..", "..", "docker-compose.yml"), compose.Options{})
+	if err != nil {
+		t.Fatalf("AnalyzeFile() error = %v", err)
+	}
+
+	want := map[string][]int{
+		"obsolete-version":      {4},
+		"resource-limits":       {8, 30, 45, 55},
+		"secret-in-environment": {14},
+		"no-latest-tag":         {31},
+	}
+	if got := issueLines(result.Issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v, want %v", got, want)
+	}
+	if want := []string{"postgres", "redis", "searxng", "goassistant"}; !reflect.DeepEqual(result.StartOrder, want) {
+		t.Errorf("StartOrder = %v, want %v", result.StartOrder, want)
+	}
+	for _, issue := range result.Issues {
+		if strings.Contains(issue.Message, "assistant123") {
+			t.Errorf("issue leaks the secret value: %s", issue.Message)
+		}
+	}
+
+	// The dev profile enables devtools, which then needs a limit too
+	result, err = NewComposeAnalyzer(discardLogger()).AnalyzeFile(filepath.Join("..", "..", "..", "docker-compose.yml"), compose.Options{Profiles: []string{"dev"}})
+	if err != nil {
+		t.Fatalf("AnalyzeFile() error = %v", err)
+	}
+	if got := issueLines(result.Issues)["resource-limits"]; len(got) != 5 {
+		t.Errorf("resource-limits with dev profile = %v, want 5 services", got)
+	}
+}
+
+func TestComposeAnalyzer_Analyze(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("worker/Dockerfile", "FROM alpine:3.20\nCMD [\"worker\"]\n")
+	writeFile(".env", "WEB_PORT=8080\n")
+
+	src := `services:
+  web:
+    image: nginx
+    ports:
+      - "${WEB_PORT}:80"
+      - "127.0.0.1:9000-9002:9000-9002"
+    depends_on:
+      worker:
+        condition: service_healthy
+      cache:
+        condition: service_healthy
+    environment:
+      API_KEY: abc123
+      DATABASE_URL: postgres://app:hunter2@db/app
+      DB_PASSWORD: ${DB_PASSWORD:-}
+    volumes:
+      - data:/data
+      - /var/run/docker.sock:/var/run/docker.sock
+    deploy:
+      resources:
+        limits:
+          memory: 128M
+  worker:
+    build: ./worker
+    privileged: true
+    ports:
+      - "8080:8081"
+      - "127.0.0.2:9001:9001"
+    networks:
+      - backend
+      - missing
+    mem_limit: 64M
+  cache:
+    image: redis:7@sha256:0123
+    volumes:
+      - undeclared:/data
+    depends_on:
+      - debug
+    cap_add:
+      - SYS_ADMIN
+    mem_limit: 64M
+  debug:
+    image: busybox:1.36
+    profiles: [debug]
+  a:
+    image: alpine:3.20
+    depends_on: [b]
+    mem_limit: 8M
+  b:
+    image: alpine:latest
+    depends_on: [a]
+    mem_limit: 8M
+volumes:
+  data:
+  orphan:
+networks:
+  backend:
+  unused:
+`
+	result, err := NewComposeAnalyzer(discardLogger()).Analyze(filepath.Join(dir, "compose.yaml"), []byte(src), compose.Options{})
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	want := map[string][]int{
+		"no-latest-tag":         {3, 50},
+		"healthcheck-required":  {8, 10},
+		"secret-in-environment": {13, 14},
+		"privileged":            {18, 25, 39},
+		"port-collision":        {27},
+		"undefined-network":     {31},
+		"undefined-volume":      {36},
+		"disabled-dependency":   {38},
+		"dependency-cycle":      {47},
+		"unused-volume":         {55},
+		"unused-network":        {58},
+	}
+	if got := issueLines(result.Issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v, want %v", got, want)
+	}
+
+	for _, issue := range result.Issues {
+		switch {
+		case issue.Rule == "healthcheck-required" && issue.Line == 8 && issue.Severity != "error":
+			t.Errorf("built service without HEALTHCHECK severity = %s, want error", issue.Severity)
+		case issue.Rule == "healthcheck-required" && issue.Line == 10 && issue.Severity != "warning":
+			t.Errorf("image service without healthcheck severity = %s, want warning", issue.Severity)
+		case issue.Rule == "secret-in-environment" && strings.Contains(issue.Message, "hunter2"):
+			t.Errorf("issue leaks the secret value: %s", issue.Message)
+		}
+	}
+
+	// Services in a cycle still get a place after their other dependencies
+	if !reflect.DeepEqual(result.StartOrder, []string{"worker", "cache", "web", "b", "a"}) {
+		t.Errorf("StartOrder = %v", result.StartOrder)
+	}
+
+	// A HEALTHCHECK in the worker Dockerfile satisfies service_healthy
+	writeFile("worker/Dockerfile", "FROM alpine:3.20\nHEALTHCHECK CMD true\nCMD [\"worker\"]\n")
+	result, err = NewComposeAnalyzer(discardLogger()).Analyze(filepath.Join(dir, "compose.yaml"), []byte(src), compose.Options{})
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if got := issueLines(result.Issues)["healthcheck-required"]; !reflect.DeepEqual(got, []int{10}) {
+		t.Errorf("healthcheck-required = %v, want [10]", got)
+	}
+}
+
+func TestComposeAnalyzer_SyntaxErrors(t *testing.T) {
+	src := "services:\n  web:\n    ports:\n      - \"80:http\"\n  db:\n    image: postgres:16\n    mem_limit: 1G\n"
+	result, err := NewComposeAnalyzer(discardLogger()).Analyze("compose.yaml", []byte(src), compose.Options{})
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if got := issueLines(result.Issues)["syntax"]; !reflect.DeepEqual(got, []int{2, 4}) {
+		t.Errorf("syntax issues = %v, want [2 4]", got)
+	}
+	// Invalid services are still analyzed
+	if len(result.Services) != 2 || result.Services[1].Image != "postgres:16" {
+		t.Errorf("services = %+v", result.Services)
+	}
+}
+
+func TestDockerTool_AnalyzeCompose(t *testing.T) {
+	dockerTool := NewDockerTool(config.Docker{Host: "ssh://user@host"}, discardLogger())
+	result, err := dockerTool.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{
+		"action":       "analyze_compose",
+		"compose_path": filepath.Join("..", "..", ".."),
+		"options":      map[string]interface{}{"profiles": "dev"},
+	}})
+	if err != nil || !result.Success {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+	output := result.Data.Output
+	if profiles, _ := output["active_profiles"].([]interface{}); len(profiles) != 1 || profiles[0] != "dev" {
+		t.Errorf("active_profiles = %v", output["active_profiles"])
+	}
+	if services, _ := output["services"].([]interface{}); len(services) != 5 {
+		t.Errorf("services = %v", output["services"])
+	}
+}
@@ -0,0 +1,378 @@
+// Package dockerfile parses Dockerfiles into an instruction AST.
+//
+// The parser follows the BuildKit frontend: parser directives, line
+// continuations with the configured escape character, heredocs, exec and
+// shell forms, instruction flags and build stages. Parsing continues past
+// errors so a partially broken Dockerfile can still be analysed.
+package dockerfile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Pos is a position in the source
+type Pos struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // byte column, starting at 1
+}
+
+// Node is implemented by every AST node
+type Node interface {
+	Pos() Pos // position of the first byte of the node
+	End() Pos // position immediately after the node
+}
+
+// Span records the source range of a node and implements Pos and End
+type Span struct {
+	From Pos
+	To   Pos
+}
+
+// Pos returns the start of the span
+func (s Span) Pos() Pos { return s.From }
+
+// End returns the position immediately after the span
+func (s Span) End() Pos { return s.To }
+
+// Text returns the source text of a node
+func Text(src string, n Node) string {
+	from, to := n.Pos().Offset, n.End().Offset
+	if from < 0 || to > len(src) || from > to {
+		return ""
+	}
+	return src[from:to]
+}
+
+// Dockerfile is a parsed Dockerfile
+type Dockerfile struct {
+	// Directives holds the parser directives at the top of the file
+	Directives []*Directive
+	// Escape is the escape character, a backslash unless changed by the
+	// escape directive
+	Escape byte
+	// Instructions holds every instruction in source order
+	Instructions []*Instruction
+	// Args holds the global ARG instructions that precede the first FROM
+	Args []*Instruction
+	// Stages holds the build stages in source order
+	Stages []*Stage
+	// Comments holds every comment line that is not a directive
+	Comments []*Comment
+	// Lines is the number of lines in the source
+	Lines int
+}
+
+// Directive is a parser directive such as "# syntax=docker/dockerfile:1"
+type Directive struct {
+	Span
+	Name  string // lower-cased directive name
+	Value string
+}
+
+// Comment is a comment line
+type Comment struct {
+	Span
+	Text string // comment text without the leading #
+}
+
+// Instruction is a single Dockerfile instruction. Its span covers every
+// continuation line and any heredoc bodies.
+type Instruction struct {
+	Span
+	// Command is the upper-cased instruction keyword
+	Command string
+	// Flags holds the leading --name=value flags
+	Flags []Flag
+	// Value is the argument text after the flags, with line continuations
+	// joined
+	Value string
+	// JSON reports whether the arguments use the exec (JSON array) form
+	JSON bool
+	// Args holds the exec form elements, or the shell form words with
+	// quotes removed
+	Args []string
+	// Heredocs holds the here-documents introduced by the instruction
+	Heredocs []*Heredoc
+	// Trigger is the wrapped instruction of an ONBUILD
+	Trigger *Instruction
+	// Stage is the index of the enclosing stage, -1 before the first FROM
+	Stage int
+}
+
+// Flag is an instruction flag such as --from=builder
+type Flag struct {
+	Name  string // lower-cased name without the leading dashes
+	Value string
+}
+
+// Heredoc is a here-document such as <<EOF ... EOF
+type Heredoc struct {
+	Span
+	Name      string
+	Content   string
+	Expand    bool // false when the delimiter is quoted
+	StripTabs bool // true for the <<- form
+}
+
+// Flag returns the value of the named flag and whether it is present
+func (i *Instruction) Flag(name string) (string, bool) {
+	for _, f := range i.Flags {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// FlagValues returns the values of every occurrence of the named flag
+func (i *Instruction) FlagValues(name string) []string {
+	var values []string
+	for _, f := range i.Flags {
+		if f.Name == name {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// StartLine returns the first line of the instruction
+func (i *Instruction) StartLine() int { return i.From.Line }
+
+// EndLine returns the last line of the instruction
+func (i *Instruction) EndLine() int { return i.To.Line }
+
+// KeyValue is a key=value pair of an ENV, LABEL or ARG instruction
+type KeyValue struct {
+	Key      string
+	Value    string
+	HasValue bool // false for an ARG without a default
+}
+
+// KeyValues returns the pairs of an ENV, LABEL or ARG instruction. The
+// legacy "ENV key value" form yields a single pair.
+func (i *Instruction) KeyValues() []KeyValue {
+	if len(i.Args) == 0 {
+		return nil
+	}
+	if i.Command != "ARG" && !strings.Contains(i.Args[0], "=") {
+		key := i.Args[0]
+		value := strings.TrimSpace(strings.TrimPrefix(i.Value, rawFirstWord(i.Value)))
+		return []KeyValue{{Key: key, Value: unquote(value), HasValue: true}}
+	}
+	pairs := make([]KeyValue, 0, len(i.Args))
+	for _, arg := range i.Args {
+		key, value, ok := strings.Cut(arg, "=")
+		pairs = append(pairs, KeyValue{Key: key, Value: value, HasValue: ok})
+	}
+	return pairs
+}
+
+// Commands returns the shell commands run by a shell form RUN, split on
+// control operators, as unquoted words. When the RUN feeds a heredoc to the
+// shell, the heredoc lines are included.
+func (i *Instruction) Commands() [][]string {
+	if i.JSON {
+		if len(i.Args) == 0 {
+			return nil
+		}
+		return [][]string{i.Args}
+	}
+	commands := splitCommands(i.Value)
+	if len(i.Heredocs) > 0 && strings.HasPrefix(strings.TrimSpace(i.Value), "<<") {
+		// RUN <<EOF runs the heredoc itself as the script
+		commands = nil
+		for _, doc := range i.Heredocs {
+			commands = append(commands, splitCommands(doc.Content)...)
+		}
+	}
+	return commands
+}
+
+// Stage is a build stage introduced by FROM
+type Stage struct {
+	Index int
+	// Name is the lower-cased stage name given with AS, or ""
+	Name string
+	// Image is the base image as written, before ARG expansion
+	Image string
+	// Platform is the --platform flag value, or ""
+	Platform string
+	// From is the FROM instruction
+	From *Instruction
+	// Instructions holds the instructions that follow FROM
+	Instructions []*Instruction
+	// BaseStage is the index of the stage used as base image, or -1 for
+	// an external image
+	BaseStage int
+	// Deps holds the indexes of the stages this stage depends on, through
+	// its base image, COPY --from or RUN --mount from=
+	Deps []int
+}
+
+// Ref returns the name used to refer to the stage: its name or its index
+func (s *Stage) Ref() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strconv.Itoa(s.Index)
+}
+
+// Target returns the last stage, which is built by default
+func (f *Dockerfile) Target() *Stage {
+	if len(f.Stages) == 0 {
+		return nil
+	}
+	return f.Stages[len(f.Stages)-1]
+}
+
+// Stage returns the stage with the given name or index, or nil
+func (f *Dockerfile) Stage(ref string) *Stage {
+	return f.stageBefore(ref, len(f.Stages))
+}
+
+// stageBefore resolves ref among the stages that precede limit. BuildKit
+// only resolves --from and FROM against earlier stages; anything else is
+// an image reference.
+func (f *Dockerfile) stageBefore(ref string, limit int) *Stage {
+	ref = strings.ToLower(ref)
+	if ref == "" {
+		return nil
+	}
+	for _, s := range f.Stages[:limit] {
+		if s.Name != "" && s.Name == ref {
+			return s
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil && n >= 0 && n < limit {
+		return f.Stages[n]
+	}
+	return nil
+}
+
+// Reachable reports, for every stage, whether building target needs it
+func (f *Dockerfile) Reachable(target *Stage) []bool {
+	reachable := make([]bool, len(f.Stages))
+	if target == nil {
+		return reachable
+	}
+	var visit func(int)
+	visit = func(i int) {
+		if reachable[i] {
+			return
+		}
+		reachable[i] = true
+		for _, dep := range f.Stages[i].Deps {
+			visit(dep)
+		}
+	}
+	visit(target.Index)
+	return reachable
+}
+
+// GlobalArgs returns the defaults of the global ARGs, overridden by
+// buildArgs. ARGs without a default and without a build arg are omitted.
+func (f *Dockerfile) GlobalArgs(buildArgs map[string]string) map[string]string {
+	vars := make(map[string]string)
+	for _, inst := range f.Args {
+		for _, kv := range inst.KeyValues() {
+			if v, ok := buildArgs[kv.Key]; ok {
+				vars[kv.Key] = v
+				continue
+			}
+			if kv.HasValue {
+				value, _ := f.Expand(kv.Value, vars)
+				vars[kv.Key] = value
+			}
+		}
+	}
+	return vars
+}
+
+// BaseImage returns the stage base image with global ARGs expanded, and the
+// names of any variables that could not be resolved
+func (f *Dockerfile) BaseImage(s *Stage, buildArgs map[string]string) (string, []string) {
+	return f.Expand(s.Image, f.GlobalArgs(buildArgs))
+}
+
+// Expand substitutes $NAME, ${NAME}, ${NAME:-word}, ${NAME-word},
+// ${NAME:+word} and ${NAME+word} in word using vars. It returns the result
+// and the names of variables that were referenced but not set.
+func (f *Dockerfile) Expand(word string, vars map[string]string) (string, []string) {
+	escape := f.Escape
+	if escape == 0 {
+		escape = '\\'
+	}
+	return expand(word, escape, vars)
+}
+
+// ImageRef is a parsed image reference
+type ImageRef struct {
+	Name   string // registry and repository, e.g. docker.io/library/golang
+	Tag    string // "" when no tag is given
+	Digest string // "" when no digest is given
+}
+
+// ParseImageRef splits an image reference into name, tag and digest
+func ParseImageRef(ref string) ImageRef {
+	var r ImageRef
+	if name, digest, ok := strings.Cut(ref, "@"); ok {
+		ref, r.Digest = name, digest
+	}
+	slash := strings.LastIndex(ref, "/")
+	if colon := strings.LastIndex(ref, ":"); colon > slash {
+		ref, r.Tag = ref[:colon], ref[colon+1:]
+	}
+	r.Name = ref
+	return r
+}
+
+// String returns the reference in its canonical textual form
+func (r ImageRef) String() string {
+	s := r.Name
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Error is a syntax error at a position in the source
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Pos.Line, e.Msg)
+}
+
+// ErrorList is a list of syntax errors, in source order
+type ErrorList []*Error
+
+// Error implements the error interface
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+	}
+}
+
+// Err returns nil for an empty list and the list otherwise
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Pos.Offset < l[j].Pos.Offset })
+	return l
+}
@@ -0,0 +1,496 @@
+package dockerfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// commands lists the instructions the parser accepts
+var commands = map[string]bool{
+	"ADD": true, "ARG": true, "CMD": true, "COPY": true, "ENTRYPOINT": true,
+	"ENV": true, "EXPOSE": true, "FROM": true, "HEALTHCHECK": true,
+	"LABEL": true, "MAINTAINER": true, "ONBUILD": true, "RUN": true,
+	"SHELL": true, "STOPSIGNAL": true, "USER": true, "VOLUME": true,
+	"WORKDIR": true,
+}
+
+// flagCommands lists the instructions that take leading --flags
+var flagCommands = map[string]bool{
+	"ADD": true, "COPY": true, "FROM": true, "HEALTHCHECK": true, "RUN": true,
+}
+
+// jsonCommands lists the instructions that accept the exec (JSON) form
+var jsonCommands = map[string]bool{
+	"ADD": true, "CMD": true, "COPY": true, "ENTRYPOINT": true, "RUN": true,
+	"SHELL": true, "VOLUME": true,
+}
+
+// heredocCommands lists the instructions that accept heredocs
+var heredocCommands = map[string]bool{"ADD": true, "COPY": true, "RUN": true}
+
+var (
+	directiveRe = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9_-]*)\s*=\s*(.*?)\s*$`)
+	heredocRe   = regexp.MustCompile(`^<<(-?)(["']?)([A-Za-z_][A-Za-z0-9_.-]*)(["']?)`)
+	stageNameRe = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+)
+
+// knownDirectives lists the parser directives BuildKit recognises
+var knownDirectives = map[string]bool{"syntax": true, "escape": true, "check": true}
+
+// Parse parses a Dockerfile.
+//
+// Parsing continues past errors: an instruction that cannot be parsed is
+// skipped or kept in the best form available, and every error is reported
+// in the returned ErrorList, so the Dockerfile is always usable.
+func Parse(src string) (*Dockerfile, error) {
+	p := newParser(src)
+	p.parseDirectives()
+	for p.line < len(p.lines) {
+		p.parseLine()
+	}
+	p.resolveDeps()
+	return p.file, p.errs.Err()
+}
+
+// sourceLine is a physical line of the source
+type sourceLine struct {
+	text   string // without the line terminator
+	offset int
+}
+
+type parser struct {
+	src   string
+	lines []sourceLine
+	line  int // index of the next line to read
+	file  *Dockerfile
+	errs  ErrorList
+}
+
+func newParser(src string) *parser {
+	p := &parser{src: src, file: &Dockerfile{Escape: '\\'}}
+	offset := 0
+	for offset < len(src) {
+		end := strings.IndexByte(src[offset:], '\n')
+		next := offset + end + 1
+		if end < 0 {
+			end = len(src) - offset
+			next = len(src)
+		}
+		text := strings.TrimSuffix(src[offset:offset+end], "\r")
+		p.lines = append(p.lines, sourceLine{text: text, offset: offset})
+		offset = next
+	}
+	p.file.Lines = len(p.lines)
+	return p
+}
+
+// pos returns the position of byte col (0-based) of line index i
+func (p *parser) pos(i, col int) Pos {
+	if i >= len(p.lines) {
+		return Pos{Offset: len(p.src), Line: len(p.lines) + 1, Column: 1}
+	}
+	return Pos{Offset: p.lines[i].offset + col, Line: i + 1, Column: col + 1}
+}
+
+// lineSpan returns the span of line index i without its terminator
+func (p *parser) lineSpan(i int) Span {
+	return Span{From: p.pos(i, 0), To: p.pos(i, len(p.lines[i].text))}
+}
+
+func (p *parser) errorf(pos Pos, format string, args ...interface{}) {
+	p.errs = append(p.errs, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// parseDirectives reads the parser directives at the top of the file. Any
+// other line, including an unknown directive, ends the directive block.
+func (p *parser) parseDirectives() {
+	seen := make(map[string]bool)
+	for p.line < len(p.lines) {
+		m := directiveRe.FindStringSubmatch(p.lines[p.line].text)
+		if m == nil {
+			return
+		}
+		name := strings.ToLower(m[1])
+		if !knownDirectives[name] || seen[name] {
+			return
+		}
+		seen[name] = true
+
+		d := &Directive{Span: p.lineSpan(p.line), Name: name, Value: m[2]}
+		if name == "escape" {
+			if d.Value != "\\" && d.Value != "`" {
+				p.errorf(d.From, "invalid escape character %q: must be ` or \\", d.Value)
+			} else {
+				p.file.Escape = d.Value[0]
+			}
+		}
+		p.file.Directives = append(p.file.Directives, d)
+		p.line++
+	}
+}
+
+// parseLine reads a blank line, a comment or a whole instruction
+func (p *parser) parseLine() {
+	text := p.lines[p.line].text
+	trimmed := strings.TrimLeft(text, " \t")
+	switch {
+	case trimmed == "":
+		p.line++
+	case trimmed[0] == '#':
+		p.comment(p.line)
+		p.line++
+	default:
+		p.parseInstruction()
+	}
+}
+
+func (p *parser) comment(i int) {
+	text := p.lines[i].text
+	indent := len(text) - len(strings.TrimLeft(text, " \t"))
+	p.file.Comments = append(p.file.Comments, &Comment{
+		Span: Span{From: p.pos(i, indent), To: p.pos(i, len(text))},
+		Text: strings.TrimSpace(text[indent+1:]),
+	})
+}
+
+// parseInstruction reads an instruction with its continuation lines and
+// heredoc bodies
+func (p *parser) parseInstruction() {
+	start := p.line
+	first := p.lines[start].text
+	indent := len(first) - len(strings.TrimLeft(first, " \t"))
+	from := p.pos(start, indent)
+
+	var logical strings.Builder
+	end := p.pos(start, len(strings.TrimRight(first, " \t")))
+	for p.line < len(p.lines) {
+		i := p.line
+		text := p.lines[i].text
+		if i == start {
+			text = text[indent:]
+		} else {
+			trimmed := strings.TrimSpace(text)
+			if trimmed == "" {
+				// Empty continuation lines are skipped
+				p.line++
+				continue
+			}
+			if trimmed[0] == '#' {
+				// Comments inside a continuation are removed
+				p.comment(i)
+				p.line++
+				continue
+			}
+		}
+		body := strings.TrimRight(text, " \t")
+		end = p.pos(i, len(p.lines[i].text)-len(text)+len(body))
+		p.line++
+		if strings.HasSuffix(body, string(p.file.Escape)) {
+			logical.WriteString(body[:len(body)-1])
+			continue
+		}
+		logical.WriteString(body)
+		break
+	}
+
+	keyword := rawFirstWord(logical.String())
+	rest := strings.TrimLeft(strings.TrimPrefix(logical.String(), keyword), " \t")
+	command := strings.ToUpper(keyword)
+
+	var heredocs []*Heredoc
+	if heredocCommands[command] {
+		heredocs = p.readHeredocs(from, rest)
+		if len(heredocs) > 0 {
+			end = heredocs[len(heredocs)-1].To
+		}
+	}
+
+	inst := p.newInstruction(command, rest, Span{From: from, To: end})
+	if inst == nil {
+		return
+	}
+	inst.Heredocs = heredocs
+	p.addInstruction(inst)
+}
+
+// newInstruction parses the arguments of a command. It returns nil for an
+// unknown instruction.
+func (p *parser) newInstruction(command, rest string, span Span) *Instruction {
+	if !commands[command] {
+		p.errorf(span.From, "unknown instruction: %s", command)
+		return nil
+	}
+	inst := &Instruction{Span: span, Command: command}
+
+	if flagCommands[command] {
+		for strings.HasPrefix(rest, "--") {
+			word := rawFirstWord(rest)
+			name, value, _ := strings.Cut(word[2:], "=")
+			inst.Flags = append(inst.Flags, Flag{Name: strings.ToLower(name), Value: unquote(value)})
+			rest = strings.TrimLeft(rest[len(word):], " \t")
+		}
+	}
+	inst.Value = rest
+
+	if command == "ONBUILD" {
+		keyword := rawFirstWord(rest)
+		trigger := strings.ToUpper(keyword)
+		switch trigger {
+		case "":
+			p.errorf(span.From, "ONBUILD requires an instruction")
+		case "ONBUILD", "FROM", "MAINTAINER":
+			p.errorf(span.From, "%s is not allowed as an ONBUILD trigger", trigger)
+		default:
+			inst.Trigger = p.newInstruction(trigger, strings.TrimLeft(rest[len(keyword):], " \t"), span)
+		}
+		if inst.Trigger != nil {
+			inst.Args = append([]string{inst.Trigger.Command}, inst.Trigger.Args...)
+		}
+		return inst
+	}
+
+	if jsonCommands[command] && strings.HasPrefix(rest, "[") {
+		var args []string
+		if err := json.Unmarshal([]byte(rest), &args); err == nil {
+			inst.JSON = true
+			inst.Args = args
+		}
+	}
+	if command == "HEALTHCHECK" && strings.EqualFold(rawFirstWord(rest), "CMD") {
+		// HEALTHCHECK CMD takes a command in either form
+		cmd := strings.TrimLeft(rest[3:], " \t")
+		var args []string
+		if strings.HasPrefix(cmd, "[") && json.Unmarshal([]byte(cmd), &args) == nil {
+			inst.JSON = true
+			inst.Args = append([]string{rawFirstWord(rest)}, args...)
+		}
+	}
+	if !inst.JSON {
+		inst.Args = splitWords(rest, p.file.Escape)
+	}
+
+	p.validate(inst)
+	return inst
+}
+
+// validate reports argument errors for a parsed instruction
+func (p *parser) validate(inst *Instruction) {
+	if len(inst.Args) == 0 && !inst.JSON {
+		p.errorf(inst.From, "%s requires at least one argument", inst.Command)
+		return
+	}
+
+	switch inst.Command {
+	case "FROM":
+		if len(inst.Args) != 1 && (len(inst.Args) != 3 || !strings.EqualFold(inst.Args[1], "AS")) {
+			p.errorf(inst.From, "FROM requires either one argument, or three: FROM <image> AS <name>")
+		}
+	case "COPY", "ADD":
+		if len(inst.Args) < 2 {
+			p.errorf(inst.From, "%s requires at least two arguments: a source and a destination", inst.Command)
+		}
+	case "SHELL":
+		if !inst.JSON {
+			p.errorf(inst.From, "SHELL requires the arguments to be in JSON form")
+		}
+	case "ENV", "LABEL":
+		if strings.Contains(inst.Args[0], "=") {
+			for _, arg := range inst.Args {
+				if !strings.Contains(arg, "=") {
+					p.errorf(inst.From, "%s names can not be blank: %q has no value", inst.Command, arg)
+					break
+				}
+			}
+		} else if len(inst.Args) < 2 {
+			p.errorf(inst.From, "%s %s requires a value", inst.Command, inst.Args[0])
+		}
+	case "HEALTHCHECK":
+		kind := strings.ToUpper(inst.Args[0])
+		if kind != "CMD" && kind != "NONE" {
+			p.errorf(inst.From, "unknown HEALTHCHECK type %q: must be CMD or NONE", inst.Args[0])
+		}
+	}
+}
+
+// readHeredocs consumes the bodies of the heredocs introduced in rest
+func (p *parser) readHeredocs(from Pos, rest string) []*Heredoc {
+	var docs []*Heredoc
+	for _, m := range findHeredocs(rest) {
+		doc := &Heredoc{Name: m.name, Expand: m.expand, StripTabs: m.stripTabs}
+		var body []string
+		terminated := false
+		startLine := p.line
+		for p.line < len(p.lines) {
+			text := p.lines[p.line].text
+			check := text
+			if m.stripTabs {
+				text = strings.TrimLeft(text, "\t")
+				check = text
+			}
+			p.line++
+			if check == m.name {
+				terminated = true
+				break
+			}
+			body = append(body, text)
+		}
+		if !terminated {
+			p.errorf(from, "unterminated heredoc <<%s", m.name)
+		}
+		if len(body) > 0 {
+			doc.Content = strings.Join(body, "\n") + "\n"
+		}
+		doc.Span = Span{From: p.pos(startLine, 0), To: p.pos(p.line-1, len(p.lines[p.line-1].text))}
+		if startLine == p.line {
+			doc.Span = Span{From: p.pos(startLine, 0), To: p.pos(startLine, 0)}
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+type heredocMarker struct {
+	name      string
+	expand    bool
+	stripTabs bool
+}
+
+// findHeredocs returns the heredoc markers in the unquoted parts of s
+func findHeredocs(s string) []heredocMarker {
+	var markers []heredocMarker
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '\'' || c == '"' {
+			quote = c
+			continue
+		}
+		if c != '<' || !strings.HasPrefix(s[i:], "<<") || strings.HasPrefix(s[i:], "<<<") {
+			continue
+		}
+		if i > 0 && s[i-1] == '<' {
+			continue
+		}
+		m := heredocRe.FindStringSubmatch(s[i:])
+		if m == nil || m[2] != m[4] {
+			continue
+		}
+		markers = append(markers, heredocMarker{name: m[3], expand: m[2] == "", stripTabs: m[1] == "-"})
+		i += len(m[0]) - 1
+	}
+	return markers
+}
+
+// addInstruction appends inst to the file and to its stage
+func (p *parser) addInstruction(inst *Instruction) {
+	f := p.file
+	f.Instructions = append(f.Instructions, inst)
+
+	if inst.Command == "FROM" {
+		p.addStage(inst)
+		return
+	}
+	if len(f.Stages) == 0 {
+		inst.Stage = -1
+		if inst.Command == "ARG" {
+			f.Args = append(f.Args, inst)
+		} else {
+			p.errorf(inst.From, "%s instruction before the first FROM", inst.Command)
+		}
+		return
+	}
+	stage := f.Stages[len(f.Stages)-1]
+	inst.Stage = stage.Index
+	if inst.Trigger != nil {
+		inst.Trigger.Stage = stage.Index
+	}
+	stage.Instructions = append(stage.Instructions, inst)
+}
+
+// addStage starts a new stage at a FROM instruction
+func (p *parser) addStage(from *Instruction) {
+	f := p.file
+	stage := &Stage{Index: len(f.Stages), From: from, BaseStage: -1}
+	from.Stage = stage.Index
+	stage.Platform, _ = from.Flag("platform")
+
+	if len(from.Args) > 0 {
+		stage.Image = from.Args[0]
+	}
+	if len(from.Args) == 3 && strings.EqualFold(from.Args[1], "AS") {
+		name := strings.ToLower(from.Args[2])
+		switch {
+		case !stageNameRe.MatchString(name):
+			p.errorf(from.From, "invalid stage name %q", from.Args[2])
+		case f.stageBefore(name, len(f.Stages)) != nil:
+			p.errorf(from.From, "duplicate stage name %q", name)
+		default:
+			stage.Name = name
+		}
+	}
+
+	image, _ := f.BaseImage(stage, nil)
+	if base := f.stageBefore(image, len(f.Stages)); base != nil {
+		stage.BaseStage = base.Index
+	}
+	f.Stages = append(f.Stages, stage)
+}
+
+// resolveDeps records the stage graph edges of every stage
+func (p *parser) resolveDeps() {
+	f := p.file
+	for _, stage := range f.Stages {
+		var deps []int
+		add := func(ref string) {
+			if dep := f.stageBefore(ref, stage.Index); dep != nil {
+				deps = appendUniqueInt(deps, dep.Index)
+			}
+		}
+		if stage.BaseStage >= 0 {
+			deps = append(deps, stage.BaseStage)
+		}
+		for _, inst := range stage.Instructions {
+			if inst.Command == "COPY" || inst.Command == "ADD" {
+				if ref, ok := inst.Flag("from"); ok {
+					add(ref)
+				}
+			}
+			if inst.Command == "RUN" {
+				for _, mount := range inst.FlagValues("mount") {
+					if ref := MountOption(mount, "from"); ref != "" {
+						add(ref)
+					}
+				}
+			}
+		}
+		stage.Deps = deps
+	}
+}
+
+// MountOption returns an option of a RUN --mount flag value such as
+// "type=cache,target=/root/.cache,from=builder", or ""
+func MountOption(mount, name string) string {
+	for _, opt := range strings.Split(mount, ",") {
+		if key, value, ok := strings.Cut(opt, "="); ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func appendUniqueInt(list []int, n int) []int {
+	for _, v := range list {
+		if v == n {
+			return list
+		}
+	}
+	return append(list, n)
+}
@@ -0,0 +1,308 @@
+package dockerfile
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// TestParse_Golden parses every testdata/*.Dockerfile and compares a dump of
+// the resulting AST with the matching .golden file. Run with -update to
+// rewrite the golden files after an intended change.
+func TestParse_Golden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata/*.Dockerfile files found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			df, err := Parse(string(src))
+			got := dumpFile(df, err)
+
+			golden := strings.TrimSuffix(file, ".Dockerfile") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("AST mismatch for %s (run with -update to accept)\ngot:\n%s", file, got)
+			}
+		})
+	}
+}
+
+func TestParse_Spans(t *testing.T) {
+	src := "FROM alpine\n  RUN echo a \\\n    # note\n    b\nCOPY <<EOF /x\nhello\nEOF\n"
+	df, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(df.Instructions) != 3 {
+		t.Fatalf("got %d instructions, want 3", len(df.Instructions))
+	}
+
+	run := df.Instructions[1]
+	if got, want := Text(src, run), "RUN echo a \\\n    # note\n    b"; got != want {
+		t.Errorf("RUN text = %q, want %q", got, want)
+	}
+	if run.StartLine() != 2 || run.EndLine() != 4 || run.From.Column != 3 {
+		t.Errorf("RUN span = %+v, want lines 2-4 from column 3", run.Span)
+	}
+	if run.Value != "echo a     b" {
+		t.Errorf("RUN value = %q", run.Value)
+	}
+
+	cp := df.Instructions[2]
+	if cp.StartLine() != 5 || cp.EndLine() != 7 {
+		t.Errorf("COPY lines = %d-%d, want 5-7", cp.StartLine(), cp.EndLine())
+	}
+	if len(cp.Heredocs) != 1 || cp.Heredocs[0].Content != "hello\n" {
+		t.Errorf("COPY heredocs = %+v", cp.Heredocs)
+	}
+	if len(df.Comments) != 1 || df.Comments[0].Text != "note" {
+		t.Errorf("comments = %+v", df.Comments)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"VERSION": "1.24", "EMPTY": ""}
+	tests := []struct {
+		word    string
+		want    string
+		missing []string
+	}{
+		{"golang:$VERSION", "golang:1.24", nil},
+		{"golang:${VERSION}-alpine", "golang:1.24-alpine", nil},
+		{"alpine:${TAG:-3.20}", "alpine:3.20", nil},
+		{"alpine:${EMPTY:-3.20}", "alpine:3.20", nil},
+		{"alpine:${EMPTY-3.20}", "alpine:", nil},
+		{"${VERSION:+set}", "set", nil},
+		{"${TAG+set}", "", nil},
+		{"image:$TAG", "image:", []string{"TAG"}},
+		{`cost \$5`, "cost $5", nil},
+		{"trailing $", "trailing $", nil},
+	}
+	f := &Dockerfile{Escape: '\\'}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			got, missing := f.Expand(tt.word, vars)
+			if got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("Expand(%q) missing = %v, want %v", tt.word, missing, tt.missing)
+			}
+		})
+	}
+}
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want ImageRef
+	}{
+		{"alpine", ImageRef{Name: "alpine"}},
+		{"alpine:3.20", ImageRef{Name: "alpine", Tag: "3.20"}},
+		{"localhost:5000/team/app", ImageRef{Name: "localhost:5000/team/app"}},
+		{"localhost:5000/team/app:v2", ImageRef{Name: "localhost:5000/team/app", Tag: "v2"}},
+		{"golang:1.24@sha256:abc", ImageRef{Name: "golang", Tag: "1.24", Digest: "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got := ParseImageRef(tt.ref)
+			if got != tt.want {
+				t.Errorf("ParseImageRef(%q) = %+v, want %+v", tt.ref, got, tt.want)
+			}
+			if got.String() != tt.ref {
+				t.Errorf("String() = %q, want %q", got.String(), tt.ref)
+			}
+		})
+	}
+}
+
+func TestInstruction_Commands(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want [][]string
+	}{
+		{
+			name: "control operators",
+			src:  `RUN apt-get update && apt-get install -y curl || true; echo "a && b" | tee /log 2>&1`,
+			want: [][]string{
+				{"apt-get", "update"},
+				{"apt-get", "install", "-y", "curl"},
+				{"true"},
+				{"echo", "a && b"},
+				{"tee", "/log", "2>&1"},
+			},
+		},
+		{
+			name: "exec form",
+			src:  `RUN ["go", "build", "./..."]`,
+			want: [][]string{{"go", "build", "./..."}},
+		},
+		{
+			name: "heredoc script",
+			src:  "RUN <<EOF\nset -e\n# comment\napk add curl\nEOF",
+			want: [][]string{{"set", "-e"}, {"apk", "add", "curl"}},
+		},
+		{
+			name: "heredoc data",
+			src:  "RUN cat <<EOF > /etc/motd\nhello\nEOF",
+			want: [][]string{{"cat", "<<EOF", ">", "/etc/motd"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df, err := Parse("FROM alpine\n" + tt.src)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := df.Stages[0].Instructions[0].Commands()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Commands() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstruction_KeyValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []KeyValue
+	}{
+		{`ENV A=1 B="two words"`, []KeyValue{{"A", "1", true}, {"B", "two words", true}}},
+		{`ENV LEGACY some value`, []KeyValue{{"LEGACY", "some value", true}}},
+		{`ARG VERSION`, []KeyValue{{"VERSION", "", false}}},
+		{`ARG VERSION=1 EMPTY=`, []KeyValue{{"VERSION", "1", true}, {"EMPTY", "", true}}},
+		{`LABEL org.opencontainers.image.title="My App"`, []KeyValue{{"org.opencontainers.image.title", "My App", true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			df, err := Parse("FROM alpine\n" + tt.src)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := df.Stages[0].Instructions[0].KeyValues(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeyValues() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerfile_Reachable(t *testing.T) {
+	src := `FROM golang:1.24 AS deps
+FROM deps AS build
+FROM alpine AS unused
+FROM alpine AS assets
+FROM scratch
+COPY --from=build /app /app
+RUN --mount=type=bind,from=assets,target=/assets true
+COPY --from=nginx:1.27 /etc/nginx /etc/nginx
+`
+	df, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := df.Stages[1].BaseStage; got != 0 {
+		t.Errorf("build base stage = %d, want 0", got)
+	}
+	if got, want := df.Target().Deps, []int{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("target deps = %v, want %v", got, want)
+	}
+	if got, want := df.Reachable(df.Target()), []bool{true, true, false, true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Reachable() = %v, want %v", got, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, err := Parse("FROM alpine\nRUN\nFROM alpine AS 1bad\n")
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Parse() error = %T, want ErrorList", err)
+	}
+	if len(list) != 2 || list[0].Pos.Line != 2 || list[1].Pos.Line != 3 {
+		t.Errorf("errors = %v", list)
+	}
+}
+
+// dumpFile renders a parsed Dockerfile as text for golden files
+func dumpFile(df *Dockerfile, err error) string {
+	var b strings.Builder
+	if df != nil {
+		fmt.Fprintf(&b, "Lines: %d, escape %q\n", df.Lines, df.Escape)
+		for _, d := range df.Directives {
+			fmt.Fprintf(&b, "Directive %d: %s = %q\n", d.From.Line, d.Name, d.Value)
+		}
+		for _, c := range df.Comments {
+			fmt.Fprintf(&b, "Comment %d: %q\n", c.From.Line, c.Text)
+		}
+		for _, inst := range df.Instructions {
+			dumpInstruction(&b, inst, "")
+		}
+		for _, s := range df.Stages {
+			image, missing := df.BaseImage(s, nil)
+			fmt.Fprintf(&b, "Stage %d %q: image %q -> %q", s.Index, s.Name, s.Image, image)
+			if len(missing) > 0 {
+				fmt.Fprintf(&b, " (unresolved %v)", missing)
+			}
+			if s.Platform != "" {
+				fmt.Fprintf(&b, " platform %q", s.Platform)
+			}
+			fmt.Fprintf(&b, " base %d deps %v instructions %d\n", s.BaseStage, s.Deps, len(s.Instructions))
+		}
+	}
+	if err != nil {
+		var list ErrorList
+		if errors.As(err, &list) {
+			for _, e := range list {
+				fmt.Fprintf(&b, "Error: %s\n", e)
+			}
+		} else {
+			fmt.Fprintf(&b, "Error: %v\n", err)
+		}
+	}
+	return b.String()
+}
+
+func dumpInstruction(b *strings.Builder, inst *Instruction, indent string) {
+	fmt.Fprintf(b, "%s%d-%d %s stage %d", indent, inst.StartLine(), inst.EndLine(), inst.Command, inst.Stage)
+	for _, f := range inst.Flags {
+		fmt.Fprintf(b, " --%s=%q", f.Name, f.Value)
+	}
+	if inst.JSON {
+		b.WriteString(" json")
+	}
+	fmt.Fprintf(b, " %q\n", inst.Args)
+	for _, doc := range inst.Heredocs {
+		fmt.Fprintf(b, "%s  heredoc %s %d-%d expand=%t strip=%t %q\n", indent, doc.Name,
+			doc.From.Line, doc.To.Line, doc.Expand, doc.StripTabs, doc.Content)
+	}
+	if inst.Trigger != nil {
+		dumpInstruction(b, inst.Trigger, indent+"  trigger ")
+	}
+}
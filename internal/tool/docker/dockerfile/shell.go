@@ -0,0 +1,248 @@
+package dockerfile
+
+import (
+	"strings"
+)
+
+// splitWords splits shell form arguments on unquoted whitespace and removes
+// the quotes, the way BuildKit splits ENV, LABEL, ARG and COPY arguments
+func splitWords(s string, escape byte) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+				continue
+			}
+			word.WriteByte(c)
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+				continue
+			}
+			if c == escape && i+1 < len(s) && strings.IndexByte(`"$`+string(escape), s[i+1]) >= 0 {
+				i++
+				c = s[i]
+			}
+			word.WriteByte(c)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == escape && i+1 < len(s):
+			i++
+			word.WriteByte(s[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// splitCommands splits a shell script into simple commands on the control
+// operators &&, ||, ;, |, & and newlines. Each command is returned as its
+// unquoted words; empty commands are dropped.
+func splitCommands(script string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				continue
+			}
+			if quote == '"' && c == '\\' && i+1 < len(script) {
+				i++
+				c = script[i]
+			}
+			word.WriteByte(c)
+		case c == '\\' && i+1 < len(script):
+			i++
+			if script[i] != '\n' {
+				word.WriteByte(script[i])
+				inWord = true
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == '#' && !inWord:
+			// Comment to the end of the line
+			for i+1 < len(script) && script[i+1] != '\n' {
+				i++
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		case c == '&' && i > 0 && (script[i-1] == '>' || script[i-1] == '<'):
+			// Redirection such as 2>&1
+			word.WriteByte(c)
+		case c == '\n' || c == ';' || c == '|' || c == '&':
+			endCommand()
+			if i+1 < len(script) && (c == '|' || c == '&') && script[i+1] == c {
+				i++
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
+
+// rawFirstWord returns the first whitespace-separated word of s as written
+func rawFirstWord(s string) string {
+	s = strings.TrimLeft(s, " \t")
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// unquote removes one level of matching surrounding quotes
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// expand performs BuildKit variable substitution on word
+func expand(word string, escape byte, vars map[string]string) (string, []string) {
+	var b strings.Builder
+	var missing []string
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		if !ok {
+			missing = appendUnique(missing, name)
+		}
+		return v, ok
+	}
+
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		if c == escape && i+1 < len(word) && word[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		if c != '$' || i+1 >= len(word) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if word[i+1] == '{' {
+			end := strings.IndexByte(word[i+2:], '}')
+			if end < 0 {
+				b.WriteString(word[i:])
+				break
+			}
+			inner := word[i+2 : i+2+end]
+			i += 2 + end
+			b.WriteString(expandBraced(inner, vars, lookup))
+			continue
+		}
+
+		n := identLen(word[i+1:])
+		if n == 0 {
+			b.WriteByte(c)
+			continue
+		}
+		v, _ := lookup(word[i+1 : i+1+n])
+		b.WriteString(v)
+		i += n
+	}
+	return b.String(), missing
+}
+
+// expandBraced expands the inside of ${...}
+func expandBraced(inner string, vars map[string]string, lookup func(string) (string, bool)) string {
+	n := identLen(inner)
+	name, modifier := inner[:n], inner[n:]
+	value, set := vars[name]
+
+	switch {
+	case modifier == "":
+		v, _ := lookup(name)
+		return v
+	case strings.HasPrefix(modifier, ":-"):
+		if value == "" {
+			return modifier[2:]
+		}
+	case strings.HasPrefix(modifier, "-"):
+		if !set {
+			return modifier[1:]
+		}
+	case strings.HasPrefix(modifier, ":+"):
+		if value != "" {
+			return modifier[2:]
+		}
+		return ""
+	case strings.HasPrefix(modifier, "+"):
+		if set {
+			return modifier[1:]
+		}
+		return ""
+	default:
+		v, _ := lookup(name)
+		return v
+	}
+	return value
+}
+
+// identLen returns the length of the variable name at the start of s
+func identLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -1,10 +1,15 @@
 package docker
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/docker/dockerfile"
 )
 
 // DockerfileOptimizer optimizes Dockerfiles for better performance and smaller size
@@ -26,296 +31,394 @@ type OptimizationResult struct {
 	LayersReduced    int            `json:"layers_reduced"`
 	Optimizations    []Optimization `json:"optimizations"`
 	OptimizedContent string         `json:"optimized_content"`
+	Diff             string         `json:"diff"`
 	SizeReduction    string         `json:"size_reduction"`
 }
 
-// Optimization represents a single optimization made
+// Optimization represents a single optimization. Applied optimizations are
+// part of OptimizedContent; the others need a manual change.
 type Optimization struct {
 	Type        string `json:"type"`
 	Description string `json:"description"`
 	Impact      string `json:"impact"` // "high", "medium", "low"
+	Line        int    `json:"line"`
+	Applied     bool   `json:"applied"`
 	LinesBefore int    `json:"lines_before"`
 	LinesAfter  int    `json:"lines_after"`
 }
 
+// edit replaces src[from:to] with text
+type edit struct {
+	from, to int
+	text     string
+}
+
 // OptimizeFile optimizes a Dockerfile
 func (o *DockerfileOptimizer) OptimizeFile(filepath string) (*OptimizationResult, error) {
 	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read Dockerfile: %w", err)
 	}
+	return o.Optimize(string(content))
+}
+
+// Optimize rewrites Dockerfile content, applying the fixable analyzer rules,
+// and returns the rewritten Dockerfile with a unified diff against src
+func (o *DockerfileOptimizer) Optimize(src string) (*OptimizationResult, error) {
+	df, err := dockerfile.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
 
-	lines := strings.Split(string(content), "\n")
 	result := &OptimizationResult{
-		OriginalSize:  len(lines),
+		OriginalSize:  df.Lines,
 		Optimizations: []Optimization{},
 	}
 
-	// Apply optimizations
-	optimizedLines := o.optimizeLines(lines, result)
+	var edits []edit
+	for _, stage := range df.Stages {
+		edits = append(edits, o.optimizeStage(src, df, stage, result)...)
+	}
+	o.reviewTarget(df, result)
+
+	optimized := applyEdits(src, edits)
+	fixed, err := dockerfile.Parse(optimized)
+	if err != nil {
+		return nil, fmt.Errorf("auto-fix produced an invalid Dockerfile: %w", err)
+	}
 
-	// Generate optimized content
-	result.OptimizedContent = strings.Join(optimizedLines, "\n")
-	result.OptimizedSize = len(optimizedLines)
-	result.LayersReduced = result.OriginalSize - result.OptimizedSize
+	result.OptimizedContent = optimized
+	result.OptimizedSize = fixed.Lines
+	result.LayersReduced = countLayers(df) - countLayers(fixed)
+	if optimized != src {
+		result.Diff = unifiedDiff("a/Dockerfile", "b/Dockerfile", src, optimized)
+	}
 
 	// Calculate size reduction
 	if result.OriginalSize > 0 {
-		reduction := float64(result.LayersReduced) / float64(result.OriginalSize) * 100
+		reduction := float64(result.OriginalSize-result.OptimizedSize) / float64(result.OriginalSize) * 100
 		result.SizeReduction = fmt.Sprintf("%.1f%%", reduction)
 	}
 
+	sort.SliceStable(result.Optimizations, func(i, j int) bool {
+		return result.Optimizations[i].Line < result.Optimizations[j].Line
+	})
+
+	o.logger.Debug("Optimized Dockerfile",
+		slog.Int("edits", len(edits)),
+		slog.Int("layers_reduced", result.LayersReduced))
+
 	return result, nil
 }
 
-// optimizeLines applies various optimizations to Dockerfile lines
-func (o *DockerfileOptimizer) optimizeLines(lines []string, result *OptimizationResult) []string {
-	var optimized []string
-	var currentRunCommands []string
-	var lastInstruction string
-	inMultiStage := false
-	stageCount := 0
-
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Skip empty lines in sequences
-		if trimmed == "" && i > 0 && i < len(lines)-1 {
-			prev := strings.TrimSpace(lines[i-1])
-			next := strings.TrimSpace(lines[i+1])
-			if prev == "" || next == "" {
-				continue
-			}
-		}
+// optimizeStage plans the edits for the instructions of a stage
+func (o *DockerfileOptimizer) optimizeStage(src string, df *dockerfile.Dockerfile, stage *dockerfile.Stage, result *OptimizationResult) []edit {
+	var edits []edit
+	o.optimizeFrom(df, stage, result)
 
-		// Handle comments
-		if strings.HasPrefix(trimmed, "#") {
-			optimized = append(optimized, line)
-			continue
-		}
-
-		parts := strings.Fields(trimmed)
-		if len(parts) == 0 {
-			optimized = append(optimized, line)
+	insts := stage.Instructions
+	for i := 0; i < len(insts); {
+		inst := insts[i]
+		switch inst.Command {
+		case "RUN":
+			group := adjacentGroup(src, insts[i:], combinableRun)
+			edits = append(edits, o.optimizeRuns(src, df, group, result)...)
+			i += len(group)
 			continue
-		}
 
-		instruction := strings.ToUpper(parts[0])
-
-		switch instruction {
-		case "FROM":
-			// Handle multi-stage builds
-			if strings.Contains(trimmed, " AS ") || strings.Contains(trimmed, " as ") {
-				inMultiStage = true
-				stageCount++
+		case "ENV":
+			group := envGroup(src, insts[i:])
+			if len(group) > 1 {
+				edits = append(edits, o.combineEnv(src, df, group, result))
 			}
+			i += len(group)
+			continue
 
-			// Flush any pending RUN commands
-			if len(currentRunCommands) > 0 {
-				optimized = append(optimized, o.combineRunCommands(currentRunCommands, result))
-				currentRunCommands = nil
+		case "ADD":
+			if addIsPlainCopy(inst) {
+				keyword := "COPY"
+				if src[inst.From.Offset] == 'a' {
+					keyword = "copy"
+				}
+				edits = append(edits, edit{from: inst.From.Offset, to: inst.From.Offset + len("ADD"), text: keyword})
+				o.add(result, inst, "add-to-copy", "Replaced ADD with COPY for simple file operations", "low", true)
 			}
 
-			// Optimize FROM instruction
-			optimized = append(optimized, o.optimizeFrom(line, result))
-
-		case "RUN":
-			// Collect RUN commands for potential combination
-			currentRunCommands = append(currentRunCommands, trimmed)
-
-		case "COPY", "ADD":
-			// Flush RUN commands before COPY/ADD
-			if len(currentRunCommands) > 0 {
-				optimized = append(optimized, o.combineRunCommands(currentRunCommands, result))
-				currentRunCommands = nil
+		case "COPY":
+			// Add --chown flag suggestion for COPY
+			if _, ok := inst.Flag("chown"); !ok && i+1 < len(insts) && chownsAfterCopy(insts[i+1]) {
+				o.add(result, inst, "copy-chown",
+					fmt.Sprintf("Use COPY --chown instead of the chown in the RUN on line %d to avoid duplicating the files in a new layer",
+						insts[i+1].StartLine()), "medium", false)
 			}
 
-			// Optimize COPY/ADD
-			optimized = append(optimized, o.optimizeCopy(line, instruction, result))
-
-		case "ENV":
-			// Combine ENV instructions if possible
-			if lastInstruction == "ENV" && len(optimized) > 0 {
-				lastLine := optimized[len(optimized)-1]
-				combined := o.combineEnv(lastLine, line, result)
-				optimized[len(optimized)-1] = combined
-			} else {
-				optimized = append(optimized, line)
+		case "CMD", "ENTRYPOINT":
+			if args := execFormArgs(inst); args != nil {
+				edits = append(edits, edit{from: inst.From.Offset, to: inst.To.Offset, text: keywordText(src, inst) + " " + jsonArray(args)})
+				o.add(result, inst, "exec-form",
+					fmt.Sprintf("Converted %s to the exec form so the process receives signals directly", inst.Command), "medium", true)
 			}
 
 		case "WORKDIR":
-			// Optimize WORKDIR
-			optimized = append(optimized, o.optimizeWorkdir(line, result))
-
-		default:
-			// Flush RUN commands before other instructions
-			if len(currentRunCommands) > 0 {
-				optimized = append(optimized, o.combineRunCommands(currentRunCommands, result))
-				currentRunCommands = nil
+			if dir := inst.Value; dir != "" && !strings.HasPrefix(dir, "/") && !strings.HasPrefix(dir, "$") && !windowsPath(dir) {
+				o.add(result, inst, "workdir-absolute", "Use absolute paths with WORKDIR for clarity", "low", false)
 			}
-			optimized = append(optimized, line)
 		}
-
-		lastInstruction = instruction
+		i++
 	}
+	return edits
+}
 
-	// Flush any remaining RUN commands
-	if len(currentRunCommands) > 0 {
-		optimized = append(optimized, o.combineRunCommands(currentRunCommands, result))
+// optimizeFrom reports base image changes that need a human decision
+func (o *DockerfileOptimizer) optimizeFrom(df *dockerfile.Dockerfile, stage *dockerfile.Stage, result *OptimizationResult) {
+	if stage.BaseStage >= 0 {
+		return
 	}
-
-	// Add multi-stage build optimization suggestion
-	if inMultiStage && stageCount > 1 {
-		result.Optimizations = append(result.Optimizations, Optimization{
-			Type:        "multi-stage",
-			Description: "Multi-stage build detected - already optimized for size",
-			Impact:      "high",
-		})
+	image, missing := df.BaseImage(stage, nil)
+	if len(missing) > 0 || strings.EqualFold(image, "scratch") {
+		return
+	}
+	ref := dockerfile.ParseImageRef(image)
+	if ref.Digest == "" && (ref.Tag == "" || ref.Tag == "latest") {
+		o.add(result, stage.From, "pin-version",
+			fmt.Sprintf("Replace %q with a specific version tag or digest for reproducibility", image), "medium", false)
 	}
 
-	return optimized
+	// Suggest Alpine or slim variants for smaller size
+	if name := ref.Name; (strings.HasSuffix(name, "ubuntu") || strings.HasSuffix(name, "debian")) &&
+		!strings.Contains(ref.Tag, "slim") && stage == df.Target() {
+		o.add(result, stage.From, "base-image",
+			"Consider a slim or Alpine Linux variant of the base image for a smaller image", "high", false)
+	}
 }
 
-// optimizeFrom optimizes FROM instructions
-func (o *DockerfileOptimizer) optimizeFrom(line string, result *OptimizationResult) string {
-	// Replace latest tags with specific versions
-	if strings.Contains(line, ":latest") {
-		result.Optimizations = append(result.Optimizations, Optimization{
-			Type:        "pin-version",
-			Description: "Replace 'latest' tag with specific version for reproducibility",
-			Impact:      "medium",
-		})
-		// In a real implementation, we might look up the actual latest version
-		// For now, we'll just add a comment
-		return line + " # TODO: Replace 'latest' with specific version"
-	}
-
-	// Suggest Alpine variants for smaller size
-	if strings.Contains(line, "ubuntu") || strings.Contains(line, "debian") {
-		if !strings.Contains(line, "alpine") {
-			result.Optimizations = append(result.Optimizations, Optimization{
-				Type:        "base-image",
-				Description: "Consider using Alpine Linux variant for smaller image size",
-				Impact:      "high",
-			})
+// optimizeRuns fixes package manager usage in a group of adjacent RUN
+// instructions and combines the group into a single RUN
+func (o *DockerfileOptimizer) optimizeRuns(src string, df *dockerfile.Dockerfile, group []*dockerfile.Instruction, result *OptimizationResult) []edit {
+	escape := string(df.Escape)
+	var commands []string
+	changed := false
+	for _, inst := range group {
+		command, fixed := o.fixRun(src, df, inst, result)
+		commands = append(commands, command)
+		changed = changed || fixed
+	}
+
+	first, last := group[0], group[len(group)-1]
+	if len(group) == 1 {
+		if !changed {
+			return nil
 		}
+		return []edit{{from: first.From.Offset, to: first.To.Offset, text: keywordText(src, first) + " " + commands[0]}}
 	}
 
-	return line
+	combined := keywordText(src, first) + " " + strings.Join(commands, " && "+escape+"\n    ")
+	result.Optimizations = append(result.Optimizations, Optimization{
+		Type:        "combine-run",
+		Description: fmt.Sprintf("Combined %d RUN commands to reduce layers", len(group)),
+		Impact:      "high",
+		Line:        first.StartLine(),
+		Applied:     true,
+		LinesBefore: last.EndLine() - first.StartLine() + 1,
+		LinesAfter:  strings.Count(combined, "\n") + 1,
+	})
+	return []edit{{from: first.From.Offset, to: last.To.Offset, text: combined}}
 }
 
-// combineRunCommands combines multiple RUN commands into one
-func (o *DockerfileOptimizer) combineRunCommands(commands []string, result *OptimizationResult) string {
-	if len(commands) <= 1 {
-		return strings.Join(commands, "\n")
-	}
-
-	// Create a combined RUN command
-	combined := "RUN "
-	cmdParts := []string{}
+var (
+	aptInstallRe = regexp.MustCompile(`\b(apt-get|apt)((?:[ \t]+-[-\w=.:]+)*)[ \t]+install\b`)
+	apkAddRe     = regexp.MustCompile(`\bapk([ \t]+)add\b`)
+)
 
-	for _, cmd := range commands {
-		// Remove "RUN " prefix
-		cmdContent := strings.TrimPrefix(cmd, "RUN ")
-		cmdContent = strings.TrimSpace(cmdContent)
+// fixRun returns the command text of a RUN, after the keyword, with the
+// package manager fixes applied, and whether anything changed
+func (o *DockerfileOptimizer) fixRun(src string, df *dockerfile.Dockerfile, inst *dockerfile.Instruction, result *OptimizationResult) (string, bool) {
+	text := dockerfile.Text(src, inst)
+	command := strings.TrimLeft(text[len(keywordText(src, inst)):], " \t")
+	if !rewritableRun(inst) {
+		return command, false
+	}
 
-		// Add cleanup for package managers
-		if strings.Contains(cmdContent, "apt-get install") {
-			if !strings.Contains(cmdContent, "apt-get clean") {
-				cmdContent += " && apt-get clean && rm -rf /var/lib/apt/lists/*"
-			}
-		} else if strings.Contains(cmdContent, "yum install") {
-			if !strings.Contains(cmdContent, "yum clean") {
-				cmdContent += " && yum clean all"
-			}
-		} else if strings.Contains(cmdContent, "apk add") {
-			if !strings.Contains(cmdContent, "rm -rf /var/cache/apk/*") {
-				cmdContent += " && rm -rf /var/cache/apk/*"
-			}
+	use := inspectPackages(inst)
+	changed := false
+	multiline := strings.Contains(command, "\n")
+	appendCommand := func(cmd string) {
+		if multiline {
+			command += " " + string(df.Escape) + "\n    && " + cmd
+		} else {
+			command += " && " + cmd
 		}
+		changed = true
+	}
 
-		cmdParts = append(cmdParts, cmdContent)
+	if use.aptInstall && !use.aptNoRecommends {
+		if fixed := aptInstallRe.ReplaceAllString(command, "$0 --no-install-recommends"); fixed != command {
+			command, changed = fixed, true
+			o.add(result, inst, "apt-no-recommends", "Added --no-install-recommends to apt-get install", "medium", true)
+		}
 	}
+	if use.aptInstall && !use.aptCleanup {
+		appendCommand("rm -rf /var/lib/apt/lists/*")
+		o.add(result, inst, "apt-cleanup", "Removed the apt package lists in the same layer as the install", "high", true)
+	}
+	if use.apkAdd && !use.apkNoCache {
+		if fixed := apkAddRe.ReplaceAllString(command, "apk${1}add --no-cache"); fixed != command {
+			command, changed = fixed, true
+			o.add(result, inst, "apk-no-cache", "Added --no-cache to apk add", "medium", true)
+		}
+	}
+	if use.yumInstall != "" && !use.yumClean {
+		appendCommand(use.yumInstall + " clean all")
+		o.add(result, inst, "yum-cleanup",
+			fmt.Sprintf("Added '%s clean all' in the same layer as the install", use.yumInstall), "high", true)
+	}
+	return command, changed
+}
 
-	combined += strings.Join(cmdParts, " && \\\n    ")
+// combineEnv merges adjacent ENV instructions into one
+func (o *DockerfileOptimizer) combineEnv(src string, df *dockerfile.Dockerfile, group []*dockerfile.Instruction, result *OptimizationResult) edit {
+	var pairs []string
+	for _, inst := range group {
+		text := dockerfile.Text(src, inst)
+		pairs = append(pairs, strings.TrimLeft(text[len(keywordText(src, inst)):], " \t"))
+	}
+	first, last := group[0], group[len(group)-1]
+	combined := keywordText(src, first) + " " + strings.Join(pairs, " "+string(df.Escape)+"\n    ")
 
 	result.Optimizations = append(result.Optimizations, Optimization{
-		Type:        "combine-run",
-		Description: fmt.Sprintf("Combined %d RUN commands to reduce layers", len(commands)),
-		Impact:      "high",
-		LinesBefore: len(commands),
-		LinesAfter:  1,
+		Type:        "combine-env",
+		Description: fmt.Sprintf("Combined %d ENV instructions", len(group)),
+		Impact:      "low",
+		Line:        first.StartLine(),
+		Applied:     true,
+		LinesBefore: last.EndLine() - first.StartLine() + 1,
+		LinesAfter:  strings.Count(combined, "\n") + 1,
 	})
-
-	return combined
+	return edit{from: first.From.Offset, to: last.To.Offset, text: combined}
 }
 
-// optimizeCopy optimizes COPY and ADD instructions
-func (o *DockerfileOptimizer) optimizeCopy(line, instruction string, result *OptimizationResult) string {
-	// Replace ADD with COPY when appropriate
-	if instruction == "ADD" && !strings.Contains(line, "http") && !strings.Contains(line, ".tar") {
-		optimized := strings.Replace(line, "ADD", "COPY", 1)
-		result.Optimizations = append(result.Optimizations, Optimization{
-			Type:        "add-to-copy",
-			Description: "Replaced ADD with COPY for simple file operations",
-			Impact:      "low",
-		})
-		return optimized
-	}
-
-	// Add --chown flag suggestion for COPY
-	if instruction == "COPY" && !strings.Contains(line, "--chown") {
-		// Check if we're copying executable files
-		if strings.Contains(line, ".sh") || strings.Contains(line, "bin/") {
-			result.Optimizations = append(result.Optimizations, Optimization{
-				Type:        "copy-chown",
-				Description: "Consider using --chown flag with COPY to avoid separate RUN chown",
-				Impact:      "medium",
-			})
+// reviewTarget reports runtime settings of the final stage that cannot be
+// fixed automatically
+func (o *DockerfileOptimizer) reviewTarget(df *dockerfile.Dockerfile, result *OptimizationResult) {
+	target := df.Target()
+	if target == nil {
+		return
+	}
+	if user, inst := effectiveUser(df, target); user == "" || rootUser(user) {
+		if inst == nil {
+			inst = target.From
 		}
+		o.add(result, inst, "non-root-user", "Run the final stage as a non-root USER", "high", false)
+	}
+	if lastInStages(df, target, "HEALTHCHECK") == nil {
+		o.add(result, target.From, "healthcheck", "Add a HEALTHCHECK to the final stage", "low", false)
 	}
+}
 
-	return line
+// add records an optimization located at inst
+func (o *DockerfileOptimizer) add(result *OptimizationResult, inst *dockerfile.Instruction, kind, description, impact string, applied bool) {
+	lines := inst.EndLine() - inst.StartLine() + 1
+	result.Optimizations = append(result.Optimizations, Optimization{
+		Type:        kind,
+		Description: description,
+		Impact:      impact,
+		Line:        inst.StartLine(),
+		Applied:     applied,
+		LinesBefore: lines,
+		LinesAfter:  lines,
+	})
 }
 
-// combineEnv combines multiple ENV instructions
-func (o *DockerfileOptimizer) combineEnv(prev, current string, result *OptimizationResult) string {
-	// Extract ENV values
-	prevParts := strings.SplitN(prev, " ", 2)
-	currParts := strings.SplitN(current, " ", 2)
+// adjacentGroup returns the leading instructions of insts that have the
+// same command, satisfy ok and are separated only by blank lines. It always
+// returns at least the first instruction.
+func adjacentGroup(src string, insts []*dockerfile.Instruction, ok func(*dockerfile.Instruction) bool) []*dockerfile.Instruction {
+	group := insts[:1]
+	if !ok(insts[0]) {
+		return group
+	}
+	for _, inst := range insts[1:] {
+		prev := group[len(group)-1]
+		if inst.Command != prev.Command || !ok(inst) || strings.TrimSpace(src[prev.To.Offset:inst.From.Offset]) != "" {
+			break
+		}
+		group = append(group, inst)
+	}
+	return group
+}
 
-	if len(prevParts) < 2 || len(currParts) < 2 {
-		return current
+// envGroup returns the leading ENV instructions that can be merged. Within
+// one ENV, values cannot refer to keys set by the same instruction, so the
+// group ends at the first ENV that references an earlier key of the group.
+func envGroup(src string, insts []*dockerfile.Instruction) []*dockerfile.Instruction {
+	group := adjacentGroup(src, insts, func(inst *dockerfile.Instruction) bool {
+		return len(inst.Args) > 0 && strings.Contains(inst.Args[0], "=")
+	})
+	keys := make(map[string]bool)
+	for i, inst := range group {
+		for _, kv := range inst.KeyValues() {
+			for key := range keys {
+				if strings.Contains(kv.Value, "$"+key) || strings.Contains(kv.Value, "${"+key) {
+					return group[:i]
+				}
+			}
+		}
+		for _, kv := range inst.KeyValues() {
+			keys[kv.Key] = true
+		}
 	}
+	return group
+}
 
-	// Combine ENV instructions
-	combined := fmt.Sprintf("%s \\\n    %s", prev, currParts[1])
+// chownsAfterCopy reports whether a RUN only changes file ownership
+func chownsAfterCopy(inst *dockerfile.Instruction) bool {
+	if inst.Command != "RUN" {
+		return false
+	}
+	commands := inst.Commands()
+	return len(commands) == 1 && commandName(commands[0]) == "chown"
+}
 
-	result.Optimizations = append(result.Optimizations, Optimization{
-		Type:        "combine-env",
-		Description: "Combined ENV instructions",
-		Impact:      "low",
-		LinesBefore: 2,
-		LinesAfter:  1,
-	})
+// keywordText returns the instruction keyword as written
+func keywordText(src string, inst *dockerfile.Instruction) string {
+	return src[inst.From.Offset : inst.From.Offset+len(inst.Command)]
+}
 
-	return combined
+// jsonArray formats args as a JSON array with spaces after the commas
+func jsonArray(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		b, _ := json.Marshal(arg)
+		quoted[i] = string(b)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
 }
 
-// optimizeWorkdir optimizes WORKDIR instructions
-func (o *DockerfileOptimizer) optimizeWorkdir(line string, result *OptimizationResult) string {
-	// Suggest creating directory with WORKDIR instead of RUN mkdir
-	parts := strings.Fields(line)
-	if len(parts) > 1 {
-		dir := parts[1]
-		if !strings.HasPrefix(dir, "/") {
-			result.Optimizations = append(result.Optimizations, Optimization{
-				Type:        "workdir-absolute",
-				Description: "Use absolute paths with WORKDIR for clarity",
-				Impact:      "low",
-			})
+// applyEdits applies non-overlapping edits to src
+func applyEdits(src string, edits []edit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].from < edits[j].from })
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		if e.from < last {
+			continue
 		}
+		b.WriteString(src[last:e.from])
+		b.WriteString(e.text)
+		last = e.to
 	}
+	b.WriteString(src[last:])
+	return b.String()
+}
 
-	return line
+// countLayers counts the instructions that create filesystem layers
+func countLayers(df *dockerfile.Dockerfile) int {
+	layers := 0
+	for _, inst := range df.Instructions {
+		switch inst.Command {
+		case "RUN", "COPY", "ADD":
+			layers++
+		}
+	}
+	return layers
 }
@@ -7,23 +7,46 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
-	"os/exec"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/koopa0/assistant-go/internal/config"
 	"github.com/koopa0/assistant-go/internal/tool"
+	"github.com/koopa0/assistant-go/internal/tool/docker/compose"
+)
+
+// Bounds for streaming actions so a tool call always returns
+const (
+	defaultFollowDuration = 10 * time.Second
+	defaultEventsDuration = 5 * time.Second
+	maxStreamDuration     = 5 * time.Minute
+	defaultMaxLogLines    = 1000
+	defaultMaxEvents      = 500
 )
 
 // DockerTool implements the Tool interface for Docker operations
 type DockerTool struct {
-	logger *slog.Logger
+	logger    *slog.Logger
+	client    *Client
+	clientErr error
 }
 
-// NewDockerTool creates a new Docker tool instance
-func NewDockerTool(logger *slog.Logger) *DockerTool {
+// NewDockerTool creates a new Docker tool instance talking to the Engine API
+// at cfg.Host. An invalid host does not fail construction; the Dockerfile
+// actions keep working and the daemon actions report the error.
+func NewDockerTool(cfg config.Docker, logger *slog.Logger) *DockerTool {
+	client, err := NewClient(cfg)
+	if err != nil {
+		logger.Warn("Docker client unavailable", slog.String("error", err.Error()))
+	}
 	return &DockerTool{
-		logger: logger,
+		logger:    logger,
+		client:    client,
+		clientErr: err,
 	}
 }
 
@@ -34,7 +57,7 @@ func (t *DockerTool) Name() string {
 
 // Description returns the tool description
 func (t *DockerTool) Description() string {
-	return "Docker container management, Dockerfile optimization, and build analysis"
+	return "Docker container management, Dockerfile optimization, compose analysis, and build analysis"
 }
 
 // Parameters returns the tool parameter schema
@@ -48,10 +71,15 @@ func (t *DockerTool) Parameters() *tool.ToolParametersSchema {
 				Enum: []string{
 					"list_containers",
 					"list_images",
+					"list_volumes",
+					"list_networks",
 					"analyze_dockerfile",
 					"optimize_dockerfile",
+					"analyze_compose",
 					"inspect_container",
 					"container_logs",
+					"events",
+					"image_history",
 					"build_analyze",
 				},
 			},
@@ -59,6 +87,14 @@ func (t *DockerTool) Parameters() *tool.ToolParametersSchema {
 				Type:        tool.ParameterTypeString,
 				Description: "Path to the Dockerfile",
 			},
+			"compose_path": {
+				Type:        tool.ParameterTypeString,
+				Description: "Path to the compose file or its directory; defaults to compose.yaml or docker-compose.yml in the current directory",
+			},
+			"content": {
+				Type:        tool.ParameterTypeString,
+				Description: "File content for analyze_dockerfile, optimize_dockerfile and analyze_compose, used instead of reading the path",
+			},
 			"container_id": {
 				Type:        tool.ParameterTypeString,
 				Description: "Container ID or name",
@@ -69,7 +105,7 @@ func (t *DockerTool) Parameters() *tool.ToolParametersSchema {
 			},
 			"options": {
 				Type:        tool.ParameterTypeObject,
-				Description: "Additional options for the action",
+				Description: "Additional options: all, size and filters for listings; tail, timestamps, stream, follow, since, until, duration and max_lines for container_logs; since, until, duration, filters and max_events for events; profiles, environment and env_file for analyze_compose",
 			},
 		},
 		Required: []string{"action"},
@@ -105,14 +141,24 @@ func (t *DockerTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.
 		result, err = t.listContainers(ctx, params)
 	case "list_images":
 		result, err = t.listImages(ctx, params)
+	case "list_volumes":
+		result, err = t.listVolumes(ctx, params)
+	case "list_networks":
+		result, err = t.listNetworks(ctx, params)
 	case "analyze_dockerfile":
 		result, err = t.analyzeDockerfile(ctx, params)
 	case "optimize_dockerfile":
 		result, err = t.optimizeDockerfile(ctx, params)
+	case "analyze_compose":
+		result, err = t.analyzeCompose(ctx, params)
 	case "inspect_container":
 		result, err = t.inspectContainer(ctx, params)
 	case "container_logs":
 		result, err = t.getContainerLogs(ctx, params)
+	case "events":
+		result, err = t.getEvents(ctx, params)
+	case "image_history":
+		result, err = t.imageHistory(ctx, params)
 	case "build_analyze":
 		result, err = t.analyzeBuild(ctx, params)
 	default:
@@ -157,61 +203,41 @@ func (t *DockerTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.
 	}, nil
 }
 
-// listContainers lists all Docker containers
+// listContainers lists Docker containers, only running ones unless the all
+// option is set
 func (t *DockerTool) listContainers(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	options := extractOptions(params)
-
-	args := []string{"ps", "--format", "json"}
-	if options["all"] == "true" {
-		args = append(args, "-a")
+	client, err := t.dockerClient()
+	if err != nil {
+		return nil, err
 	}
+	options := extractOptions(params)
 
-	output, err := t.runDockerCommand(ctx, args...)
+	containers, err := client.ListContainers(ctx, ListContainersOptions{
+		All:     optionBool(options, "all"),
+		Size:    optionBool(options, "size"),
+		Filters: optionFilters(options),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list containers: %w", err)
 	}
 
-	// Parse JSON output
-	var containers []map[string]interface{}
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		var container map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &container); err != nil {
-			t.logger.Warn("Failed to parse container JSON", slog.String("line", line))
-			continue
-		}
-		containers = append(containers, container)
-	}
-
 	return map[string]interface{}{
 		"containers": containers,
 		"count":      len(containers),
 	}, nil
 }
 
-// listImages lists all Docker images
+// listImages lists Docker images
 func (t *DockerTool) listImages(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	output, err := t.runDockerCommand(ctx, "images", "--format", "json")
+	client, err := t.dockerClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list images: %w", err)
+		return nil, err
 	}
+	options := extractOptions(params)
 
-	// Parse JSON output
-	var images []map[string]interface{}
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		var image map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &image); err != nil {
-			t.logger.Warn("Failed to parse image JSON", slog.String("line", line))
-			continue
-		}
-		images = append(images, image)
+	images, err := client.ListImages(ctx, optionBool(options, "all"), optionFilters(options))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
 	}
 
 	return map[string]interface{}{
@@ -220,15 +246,57 @@ func (t *DockerTool) listImages(ctx context.Context, params map[string]interface
 	}, nil
 }
 
-// analyzeDockerfile analyzes a Dockerfile for best practices
-func (t *DockerTool) analyzeDockerfile(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	dockerfilePath, ok := params["dockerfile_path"].(string)
-	if !ok {
-		dockerfilePath = "Dockerfile"
+// listVolumes lists Docker volumes
+func (t *DockerTool) listVolumes(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	client, err := t.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := client.ListVolumes(ctx, optionFilters(extractOptions(params)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	return map[string]interface{}{
+		"volumes": volumes,
+		"count":   len(volumes),
+	}, nil
+}
+
+// listNetworks lists Docker networks
+func (t *DockerTool) listNetworks(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	client, err := t.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	networks, err := client.ListNetworks(ctx, optionFilters(extractOptions(params)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
 	}
 
+	return map[string]interface{}{
+		"networks": networks,
+		"count":    len(networks),
+	}, nil
+}
+
+// analyzeDockerfile analyzes a Dockerfile for best practices
+func (t *DockerTool) analyzeDockerfile(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	analyzer := NewDockerfileAnalyzer(t.logger)
-	result, err := analyzer.AnalyzeFile(dockerfilePath)
+
+	var result *AnalysisResult
+	var err error
+	if content, ok := params["content"].(string); ok && content != "" {
+		result, err = analyzer.Analyze(content)
+	} else {
+		dockerfilePath, ok := params["dockerfile_path"].(string)
+		if !ok {
+			dockerfilePath = "Dockerfile"
+		}
+		result, err = analyzer.AnalyzeFile(dockerfilePath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze Dockerfile: %w", err)
 	}
@@ -236,15 +304,22 @@ func (t *DockerTool) analyzeDockerfile(ctx context.Context, params map[string]in
 	return result, nil
 }
 
-// optimizeDockerfile suggests optimizations for a Dockerfile
+// optimizeDockerfile rewrites a Dockerfile with the automatic fixes applied
+// and returns the result with a diff
 func (t *DockerTool) optimizeDockerfile(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	dockerfilePath, ok := params["dockerfile_path"].(string)
-	if !ok {
-		dockerfilePath = "Dockerfile"
-	}
-
 	optimizer := NewDockerfileOptimizer(t.logger)
-	result, err := optimizer.OptimizeFile(dockerfilePath)
+
+	var result *OptimizationResult
+	var err error
+	if content, ok := params["content"].(string); ok && content != "" {
+		result, err = optimizer.Optimize(content)
+	} else {
+		dockerfilePath, ok := params["dockerfile_path"].(string)
+		if !ok {
+			dockerfilePath = "Dockerfile"
+		}
+		result, err = optimizer.OptimizeFile(dockerfilePath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to optimize Dockerfile: %w", err)
 	}
@@ -252,57 +327,203 @@ func (t *DockerTool) optimizeDockerfile(ctx context.Context, params map[string]i
 	return result, nil
 }
 
+// analyzeCompose loads a compose project and reports issues in it
+func (t *DockerTool) analyzeCompose(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	path, _ := params["compose_path"].(string)
+	if path == "" {
+		path = "."
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		found, err := findComposeFile(path)
+		if err != nil {
+			return nil, err
+		}
+		path = found
+	}
+
+	options := extractOptions(params)
+	opts := compose.Options{
+		Profiles: optionList(options, "profiles"),
+		EnvFile:  optionString(options, "env_file"),
+	}
+	if env, ok := options["environment"].(map[string]interface{}); ok {
+		opts.Environment = make(map[string]string, len(env))
+		for key := range env {
+			opts.Environment[key] = optionString(env, key)
+		}
+	}
+
+	analyzer := NewComposeAnalyzer(t.logger)
+	var result *ComposeAnalysisResult
+	var err error
+	if content, ok := params["content"].(string); ok && content != "" {
+		result, err = analyzer.Analyze(path, []byte(content), opts)
+	} else {
+		result, err = analyzer.AnalyzeFile(path, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze compose file: %w", err)
+	}
+
+	return result, nil
+}
+
 // inspectContainer inspects a Docker container
 func (t *DockerTool) inspectContainer(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	containerID, ok := params["container_id"].(string)
 	if !ok {
 		return nil, fmt.Errorf("container_id parameter is required")
 	}
-
-	output, err := t.runDockerCommand(ctx, "inspect", containerID)
+	client, err := t.dockerClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to inspect container: %w", err)
+		return nil, err
 	}
 
-	var result []map[string]interface{}
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
-	}
-
-	if len(result) == 0 {
-		return nil, fmt.Errorf("container not found: %s", containerID)
+	details, err := client.InspectContainer(ctx, containerID)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("container not found: %s", containerID)
+		}
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
 
-	return result[0], nil
+	return details, nil
 }
 
-// getContainerLogs retrieves logs from a Docker container
+// getContainerLogs retrieves logs from a Docker container. With follow set
+// the stream is read for at most the duration option (default 10s).
 func (t *DockerTool) getContainerLogs(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	containerID, ok := params["container_id"].(string)
 	if !ok {
 		return nil, fmt.Errorf("container_id parameter is required")
 	}
+	client, err := t.dockerClient()
+	if err != nil {
+		return nil, err
+	}
 
 	options := extractOptions(params)
-	args := []string{"logs"}
-
-	if tail, ok := options["tail"].(string); ok {
-		args = append(args, "--tail", tail)
+	now := time.Now()
+	opts := LogsOptions{
+		Follow:     optionBool(options, "follow"),
+		Tail:       optionString(options, "tail"),
+		Timestamps: optionBool(options, "timestamps"),
+	}
+	if stream := optionString(options, "stream"); stream != "" {
+		opts.Stdout = stream == "stdout"
+		opts.Stderr = stream == "stderr"
 	}
-	if options["timestamps"] == "true" {
-		args = append(args, "--timestamps")
+	if opts.Since, err = optionTime(options, "since", now); err != nil {
+		return nil, err
+	}
+	if opts.Until, err = optionTime(options, "until", now); err != nil {
+		return nil, err
 	}
 
-	args = append(args, containerID)
+	if opts.Follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, optionDuration(options, "duration", defaultFollowDuration))
+		defer cancel()
+	}
 
-	output, err := t.runDockerCommand(ctx, args...)
+	maxLines := optionInt(options, "max_lines", defaultMaxLogLines)
+	lines := []LogLine{}
+	var text strings.Builder
+	truncated := false
+	err = client.ContainerLogs(ctx, containerID, opts, func(line LogLine) error {
+		if len(lines) == maxLines {
+			truncated = true
+			return io.EOF
+		}
+		lines = append(lines, line)
+		text.WriteString(line.Text)
+		text.WriteByte('\n')
+		return nil
+	})
 	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("container not found: %s", containerID)
+		}
 		return nil, fmt.Errorf("failed to get container logs: %w", err)
 	}
 
 	return map[string]interface{}{
 		"container_id": containerID,
-		"logs":         output,
+		"lines":        lines,
+		"count":        len(lines),
+		"truncated":    truncated,
+		"logs":         text.String(),
+	}, nil
+}
+
+// getEvents collects daemon events. Without an until option the
+// subscription is read for the duration option (default 5s).
+func (t *DockerTool) getEvents(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	client, err := t.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	options := extractOptions(params)
+	now := time.Now()
+	opts := EventsOptions{Filters: optionFilters(options)}
+	if opts.Since, err = optionTime(options, "since", now); err != nil {
+		return nil, err
+	}
+	if opts.Until, err = optionTime(options, "until", now); err != nil {
+		return nil, err
+	}
+	if opts.Until.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, optionDuration(options, "duration", defaultEventsDuration))
+		defer cancel()
+	}
+
+	maxEvents := optionInt(options, "max_events", defaultMaxEvents)
+	events := []Event{}
+	truncated := false
+	err = client.Events(ctx, opts, func(event Event) error {
+		if len(events) == maxEvents {
+			truncated = true
+			return io.EOF
+		}
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	return map[string]interface{}{
+		"events":    events,
+		"count":     len(events),
+		"truncated": truncated,
+	}, nil
+}
+
+// imageHistory returns the layers of an image with their sizes
+func (t *DockerTool) imageHistory(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	imageName, ok := params["image_name"].(string)
+	if !ok || imageName == "" {
+		return nil, fmt.Errorf("image_name parameter is required")
+	}
+	client, err := t.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := client.ImageHistory(ctx, imageName)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("image not found: %s", imageName)
+		}
+		return nil, fmt.Errorf("failed to get image history: %w", err)
+	}
+
+	return map[string]interface{}{
+		"image":  imageName,
+		"layers": layers,
+		"size":   sizeOfLayers(layers),
 	}, nil
 }
 
@@ -313,8 +534,13 @@ func (t *DockerTool) analyzeBuild(ctx context.Context, params map[string]interfa
 		dockerfilePath = "Dockerfile"
 	}
 
-	analyzer := NewBuildAnalyzer(t.logger)
-	result, err := analyzer.AnalyzeBuild(dockerfilePath)
+	client, err := t.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer := NewBuildAnalyzer(t.logger, client)
+	result, err := analyzer.AnalyzeBuild(ctx, dockerfilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze build: %w", err)
 	}
@@ -322,19 +548,13 @@ func (t *DockerTool) analyzeBuild(ctx context.Context, params map[string]interfa
 	return result, nil
 }
 
-// runDockerCommand executes a Docker command and returns its output
-func (t *DockerTool) runDockerCommand(ctx context.Context, args ...string) (string, error) {
-	cmd := exec.CommandContext(ctx, "docker", args...)
-
-	t.logger.Debug("Running Docker command",
-		slog.String("command", fmt.Sprintf("docker %s", strings.Join(args, " "))))
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("docker command failed: %w\nOutput: %s", err, string(output))
+// dockerClient returns the Engine API client or the error that prevented
+// creating it
+func (t *DockerTool) dockerClient() (*Client, error) {
+	if t.clientErr != nil {
+		return nil, fmt.Errorf("docker client unavailable: %w", t.clientErr)
 	}
-
-	return string(output), nil
+	return t.client, nil
 }
 
 // extractOptions extracts options from parameters
@@ -345,30 +565,149 @@ func extractOptions(params map[string]interface{}) map[string]interface{} {
 	return make(map[string]interface{})
 }
 
-// Health checks if the Docker tool is healthy
+// optionBool reads a boolean option given as a bool or a string
+func optionBool(options map[string]interface{}, key string) bool {
+	switch v := options[key].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+// optionString reads a string option, formatting numbers without a fraction
+func optionString(options map[string]interface{}, key string) string {
+	switch v := options[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// optionInt reads a positive integer option
+func optionInt(options map[string]interface{}, key string, def int) int {
+	switch v := options[key].(type) {
+	case float64:
+		if v > 0 {
+			return int(v)
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// optionList reads a list option given as an array or a comma-separated
+// string
+func optionList(options map[string]interface{}, key string) []string {
+	var list []string
+	switch v := options[key].(type) {
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// optionDuration reads a duration option such as "30s", capped at
+// maxStreamDuration; numbers are seconds
+func optionDuration(options map[string]interface{}, key string, def time.Duration) time.Duration {
+	d := def
+	switch v := options[key].(type) {
+	case float64:
+		if v > 0 {
+			d = time.Duration(v * float64(time.Second))
+		}
+	case string:
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			d = parsed
+		}
+	}
+	return min(d, maxStreamDuration)
+}
+
+// optionTime reads a point in time given as RFC 3339, unix seconds or a
+// duration before now such as "15m"
+func optionTime(options map[string]interface{}, key string, now time.Time) (time.Time, error) {
+	switch v := options[key].(type) {
+	case nil:
+		return time.Time{}, nil
+	case float64:
+		return time.Unix(0, int64(v*float64(time.Second))), nil
+	case string:
+		if v == "" {
+			return time.Time{}, nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Unix(0, int64(secs*float64(time.Second))), nil
+		}
+		if d, err := time.ParseDuration(v); err == nil {
+			return now.Add(-d), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s option: %v", key, options[key])
+}
+
+// optionFilters reads the filters option, e.g. {"status": "running"} or
+// {"label": ["a=b", "c"]}
+func optionFilters(options map[string]interface{}) Filters {
+	raw, ok := options["filters"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	filters := make(Filters, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			filters[key] = append(filters[key], v)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					filters[key] = append(filters[key], s)
+				}
+			}
+		}
+	}
+	return filters
+}
+
+// Health checks that the Docker daemon is reachable
 func (t *DockerTool) Health(ctx context.Context) error {
-	// Check if Docker is available
-	cmd := exec.CommandContext(ctx, "docker", "version", "--format", "json")
-	output, err := cmd.CombinedOutput()
+	client, err := t.dockerClient()
 	if err != nil {
-		return fmt.Errorf("docker health check failed: %w\nOutput: %s", err, string(output))
+		return err
 	}
-
-	// Try to parse the version output
-	var versionInfo map[string]interface{}
-	if err := json.Unmarshal(output, &versionInfo); err != nil {
-		// Docker is running but output format might be different
-		t.logger.Warn("Failed to parse Docker version output", slog.String("output", string(output)))
+	if err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("docker health check failed: %w", err)
 	}
 
 	t.logger.Debug("Docker health check passed")
 	return nil
 }
 
-// Close closes the Docker tool and cleans up resources
+// Close closes the Docker tool and releases idle API connections
 func (t *DockerTool) Close(ctx context.Context) error {
-	// Docker tool doesn't maintain persistent connections
-	// Nothing to clean up
+	if t.client != nil {
+		t.client.Close()
+	}
 	t.logger.Debug("Docker tool closed")
 	return nil
 }
@@ -24,6 +24,7 @@ const (
 	CodeToolPermissionDenied  = "TOOL_PERMISSION_DENIED"
 	CodeToolDependencyMissing = "TOOL_DEPENDENCY_MISSING"
 	CodeToolResourceExhausted = "TOOL_RESOURCE_EXHAUSTED"
+	CodeToolQuarantined       = "TOOL_QUARANTINED"
 
 	// Tool validation errors
 	CodeInvalidToolConfig    = "INVALID_TOOL_CONFIG"
@@ -152,6 +153,19 @@ func NewToolResourceExhaustedError(toolName, resource string, limit interface{})
 		WithRetryAfter(time.Minute * 5)
 }
 
+// NewToolQuarantinedError creates an error for a tool quarantined after
+// repeated failures
+func NewToolQuarantinedError(toolName, reason string) *errors.AssistantError {
+	return errors.NewInfrastructureError(CodeToolQuarantined, "tool is quarantined", nil).
+		WithComponent("tools").
+		WithOperation("execute").
+		WithContext("tool_name", toolName).
+		WithContext("reason", reason).
+		WithUserMessage(fmt.Sprintf("Tool '%s' is temporarily unavailable after repeated failures.", toolName)).
+		WithActions("Wait for the tool to recover", "Check the tool's dependencies", "Use alternative tool").
+		WithRetryable(true)
+}
+
 // Tool Validation Error Constructors
 
 // NewInvalidToolConfigError creates an invalid tool configuration error
@@ -281,7 +295,7 @@ func IsToolExecutionError(err error) bool {
 		switch assistantErr.Code {
 		case CodeToolExecutionTimeout, CodeToolExecutionPanic,
 			CodeToolPermissionDenied, CodeToolDependencyMissing,
-			CodeToolResourceExhausted:
+			CodeToolResourceExhausted, CodeToolQuarantined:
 			return true
 		}
 	}
@@ -0,0 +1,579 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koopa0/assistant-go/internal/tool/docker/dockerfile"
+	"github.com/koopa0/assistant-go/internal/tool/k8s/manifest"
+)
+
+// ManifestAnalyzer checks Kubernetes manifests for common problems
+type ManifestAnalyzer struct {
+	logger *slog.Logger
+}
+
+// NewManifestAnalyzer creates a new manifest analyzer
+func NewManifestAnalyzer(logger *slog.Logger) *ManifestAnalyzer {
+	return &ManifestAnalyzer{
+		logger: logger,
+	}
+}
+
+// AnalyzeOptions controls manifest analysis
+type AnalyzeOptions struct {
+	// KubeVersion is the target cluster version, e.g. "1.29". APIs removed
+	// in it are errors and APIs deprecated in it are warnings; when empty
+	// every removed API is an error.
+	KubeVersion string
+	// Namespace is assumed for objects that do not set one
+	Namespace string
+}
+
+// AnalysisResult represents the result of manifest analysis
+type AnalysisResult struct {
+	Path      string         `json:"path"`
+	Resources []ResourceInfo `json:"resources"`
+	Issues    []Issue        `json:"issues"`
+}
+
+// ResourceInfo describes a loaded object
+type ResourceInfo struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"api_version"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Source     string `json:"source,omitempty"` // Helm template
+}
+
+// Issue represents a problem found in a manifest
+type Issue struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Severity  string `json:"severity"` // "error", "warning", "info"
+	Message   string `json:"message"`
+	Rule      string `json:"rule"`
+	Resource  string `json:"resource,omitempty"` // e.g. Deployment/shop/api
+	Container string `json:"container,omitempty"`
+	Source    string `json:"source,omitempty"` // Helm template
+}
+
+// Load loads the manifests at path and describes them without analysis
+func (a *ManifestAnalyzer) Load(path string) (*AnalysisResult, error) {
+	set, err := manifest.Load(path)
+	return a.describe(path, set, err)
+}
+
+// AnalyzeFile analyzes the manifests at path: a file, a Kustomize
+// directory or a directory tree
+func (a *ManifestAnalyzer) AnalyzeFile(path string, opts AnalyzeOptions) (*AnalysisResult, error) {
+	set, err := manifest.Load(path)
+	result, err := a.describe(path, set, err)
+	if err != nil {
+		return nil, err
+	}
+	return a.analyze(set, result, opts)
+}
+
+// Analyze analyzes manifest content such as helm template output; name
+// is used as the file name in issues
+func (a *ManifestAnalyzer) Analyze(name string, content []byte, opts AnalyzeOptions) (*AnalysisResult, error) {
+	set, err := manifest.Parse(name, content)
+	result, err := a.describe(name, set, err)
+	if err != nil {
+		return nil, err
+	}
+	return a.analyze(set, result, opts)
+}
+
+// describe lists the loaded objects and turns load errors into issues
+func (a *ManifestAnalyzer) describe(path string, set *manifest.Set, err error) (*AnalysisResult, error) {
+	var loadErrors manifest.ErrorList
+	if err != nil && !errors.As(err, &loadErrors) {
+		return nil, fmt.Errorf("failed to load manifests: %w", err)
+	}
+
+	result := &AnalysisResult{
+		Path:      path,
+		Resources: []ResourceInfo{},
+		Issues:    []Issue{},
+	}
+	for _, e := range loadErrors {
+		result.Issues = append(result.Issues, Issue{File: e.Pos.File, Line: e.Pos.Line, Severity: "error", Message: e.Msg, Rule: "syntax"})
+	}
+	for _, w := range set.Warnings {
+		result.Issues = append(result.Issues, Issue{File: w.Pos.File, Line: w.Pos.Line, Severity: "info", Message: w.Msg, Rule: "unsupported"})
+	}
+	for _, o := range set.Objects {
+		result.Resources = append(result.Resources, ResourceInfo{
+			Kind:       o.Kind,
+			APIVersion: o.APIVersion,
+			Name:       o.Name,
+			Namespace:  o.Namespace,
+			File:       o.Pos.File,
+			Line:       o.Pos.Line,
+			Source:     o.Source,
+		})
+	}
+	return result, nil
+}
+
+// analysis holds the state of one analysis run
+type analysis struct {
+	set    *manifest.Set
+	opts   AnalyzeOptions
+	target *version
+	result *AnalysisResult
+}
+
+func (a *ManifestAnalyzer) analyze(set *manifest.Set, result *AnalysisResult, opts AnalyzeOptions) (*AnalysisResult, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+	}
+	an := &analysis{set: set, opts: opts, result: result}
+	if opts.KubeVersion != "" {
+		v, err := parseVersion(opts.KubeVersion)
+		if err != nil {
+			return nil, err
+		}
+		an.target = &v
+	}
+
+	seen := make(map[string]*manifest.Object)
+	for _, o := range set.Objects {
+		key := o.Group() + "/" + o.Kind + "/" + an.namespace(o) + "/" + o.Name
+		if first, ok := seen[key]; ok && o.Name != "" {
+			an.add(o, "", o.Pos, "error", "duplicate-resource",
+				fmt.Sprintf("%s is defined twice; the first definition is at %s", o.ID(), first.Pos))
+		}
+		seen[key] = o
+
+		an.checkAPIVersion(o)
+		an.checkPods(o)
+		switch o.Kind {
+		case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet":
+			an.checkWorkloadSelector(o)
+		case "Service":
+			an.checkService(o)
+		case "PodDisruptionBudget":
+			an.checkPDB(o)
+		}
+	}
+	an.checkPDBCoverage()
+
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		if result.Issues[i].File != result.Issues[j].File {
+			return result.Issues[i].File < result.Issues[j].File
+		}
+		return result.Issues[i].Line < result.Issues[j].Line
+	})
+
+	a.logger.Debug("Manifest analysis complete",
+		slog.String("path", result.Path),
+		slog.Int("resources", len(result.Resources)),
+		slog.Int("issues", len(result.Issues)))
+
+	return result, nil
+}
+
+// add records an issue about o at pos
+func (an *analysis) add(o *manifest.Object, container string, pos manifest.Pos, severity, rule, message string) {
+	if pos.File == "" {
+		pos = o.Pos
+	}
+	an.result.Issues = append(an.result.Issues, Issue{
+		File:      pos.File,
+		Line:      pos.Line,
+		Severity:  severity,
+		Message:   message,
+		Rule:      rule,
+		Resource:  o.ID(),
+		Container: container,
+		Source:    o.Source,
+	})
+}
+
+// pos returns the position of n, falling back to the object
+func (an *analysis) pos(o *manifest.Object, n *yaml.Node) manifest.Pos {
+	if n == nil {
+		return o.Pos
+	}
+	return an.set.Pos(n)
+}
+
+// namespace returns the namespace the object is created in
+func (an *analysis) namespace(o *manifest.Object) string {
+	switch {
+	case manifest.ClusterScoped(o.Kind):
+		return ""
+	case o.Namespace != "":
+		return o.Namespace
+	}
+	return an.opts.Namespace
+}
+
+// checkAPIVersion reports deprecated and removed API versions
+func (an *analysis) checkAPIVersion(o *manifest.Object) {
+	d := findDeprecatedAPI(o.APIVersion, o.Kind)
+	if d == nil {
+		return
+	}
+	severity := "error"
+	if an.target != nil {
+		if an.target.less(d.deprecated) {
+			return
+		}
+		if an.target.less(d.removed) {
+			severity = "warning"
+		}
+	}
+	msg := fmt.Sprintf("%s %s is deprecated since Kubernetes %s and removed in %s", o.APIVersion, o.Kind, d.deprecated, d.removed)
+	if d.replacement != "" {
+		msg += "; use " + d.replacement
+	} else {
+		msg += " without a replacement"
+	}
+	an.add(o, "", an.pos(o, o.Get("apiVersion")), severity, "deprecated-api", msg)
+}
+
+// longRunning lists the kinds whose pods are expected to keep running
+var longRunning = map[string]bool{
+	"Pod": true, "Deployment": true, "ReplicaSet": true, "StatefulSet": true,
+	"DaemonSet": true, "ReplicationController": true,
+}
+
+// checkPods checks the containers of the pods an object runs
+func (an *analysis) checkPods(o *manifest.Object) {
+	_, spec := o.PodTemplate()
+	if spec == nil {
+		return
+	}
+	podSecurity := manifest.Lookup(spec, "securityContext")
+
+	for _, list := range []string{"initContainers", "containers"} {
+		for _, c := range manifest.Items(manifest.Lookup(spec, list)) {
+			name := manifest.Scalar(manifest.Lookup(c, "name"))
+			an.checkImage(o, c, name)
+			an.checkRunAsNonRoot(o, c, name, podSecurity)
+			if list == "containers" {
+				an.checkResources(o, c, name)
+				if longRunning[o.Kind] {
+					an.checkProbes(o, c, name)
+				}
+			}
+		}
+	}
+}
+
+// checkImage reports images that are not pinned to a version
+func (an *analysis) checkImage(o *manifest.Object, c *yaml.Node, name string) {
+	image := manifest.Lookup(c, "image")
+	if manifest.Scalar(image) == "" {
+		an.add(o, name, an.pos(o, c), "error", "image-not-pinned", fmt.Sprintf("Container %q has no image", name))
+		return
+	}
+	ref := dockerfile.ParseImageRef(image.Value)
+	if ref.Digest == "" && (ref.Tag == "" || ref.Tag == "latest") {
+		an.add(o, name, an.pos(o, image), "warning", "image-not-pinned",
+			fmt.Sprintf("Container %q uses image %s without a pinned version; use a specific tag or digest", name, image.Value))
+	}
+}
+
+// checkResources reports missing CPU and memory requests and a missing
+// memory limit. A CPU limit is not required, as it throttles rather than
+// protects the node.
+func (an *analysis) checkResources(o *manifest.Object, c *yaml.Node, name string) {
+	resources := manifest.Lookup(c, "resources")
+	pos := an.pos(o, c)
+	if resources != nil {
+		pos = an.pos(o, resources)
+	}
+
+	var missing []string
+	for _, r := range []string{"cpu", "memory"} {
+		if manifest.Lookup(resources, "requests", r) == nil {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) > 0 {
+		an.add(o, name, pos, "warning", "resources-requests",
+			fmt.Sprintf("Container %q sets no %s request; the scheduler cannot place it reliably", name, strings.Join(missing, " or ")))
+	}
+	if manifest.Lookup(resources, "limits", "memory") == nil {
+		an.add(o, name, pos, "warning", "resources-limits",
+			fmt.Sprintf("Container %q sets no memory limit and can exhaust the node", name))
+	}
+}
+
+// checkProbes reports missing readiness and liveness probes
+func (an *analysis) checkProbes(o *manifest.Object, c *yaml.Node, name string) {
+	if manifest.Lookup(c, "readinessProbe") == nil {
+		an.add(o, name, an.pos(o, c), "warning", "missing-probe",
+			fmt.Sprintf("Container %q has no readinessProbe; it receives traffic before it is ready", name))
+	}
+	if manifest.Lookup(c, "livenessProbe") == nil {
+		an.add(o, name, an.pos(o, c), "warning", "missing-probe",
+			fmt.Sprintf("Container %q has no livenessProbe; a hung process is never restarted", name))
+	}
+}
+
+// checkRunAsNonRoot reports containers that may run as root. Container
+// settings override the pod's.
+func (an *analysis) checkRunAsNonRoot(o *manifest.Object, c *yaml.Node, name string, podSecurity *yaml.Node) {
+	security := manifest.Lookup(c, "securityContext")
+	setting := func(key string) *yaml.Node {
+		if n := manifest.Lookup(security, key); n != nil {
+			return n
+		}
+		return manifest.Lookup(podSecurity, key)
+	}
+
+	runAsUser, runAsNonRoot := setting("runAsUser"), setting("runAsNonRoot")
+	switch {
+	case manifest.Scalar(runAsUser) == "0":
+		an.add(o, name, an.pos(o, runAsUser), "error", "run-as-non-root",
+			fmt.Sprintf("Container %q runs as root (runAsUser: 0)", name))
+	case manifest.Scalar(runAsNonRoot) == "false":
+		an.add(o, name, an.pos(o, runAsNonRoot), "warning", "run-as-non-root",
+			fmt.Sprintf("Container %q sets runAsNonRoot: false and may run as root", name))
+	case manifest.Scalar(runAsNonRoot) != "true" && runAsUser == nil:
+		an.add(o, name, an.pos(o, c), "warning", "run-as-non-root",
+			fmt.Sprintf("Container %q does not set runAsNonRoot; it runs as root if the image does", name))
+	}
+}
+
+// checkWorkloadSelector reports selectors that do not select the pod
+// template
+func (an *analysis) checkWorkloadSelector(o *manifest.Object) {
+	selector := o.Get("spec", "selector")
+	meta, _ := o.PodTemplate()
+	labels := manifest.StringMap(manifest.Lookup(meta, "labels"))
+	switch {
+	case selector == nil:
+		an.add(o, "", o.Pos, "error", "selector-mismatch", fmt.Sprintf("%s has no spec.selector", o.ID()))
+	case !matchesSelector(selector, labels):
+		an.add(o, "", an.pos(o, selector), "error", "selector-mismatch",
+			fmt.Sprintf("The selector of %s does not match its pod template labels %s", o.ID(), formatLabels(labels)))
+	}
+}
+
+// podTemplates returns the objects in a namespace that run pods
+func (an *analysis) podTemplates(namespace string) []*manifest.Object {
+	var objects []*manifest.Object
+	for _, o := range an.set.Objects {
+		if _, spec := o.PodTemplate(); spec != nil && an.namespace(o) == namespace {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+// templateLabels returns the labels of the pods an object runs
+func templateLabels(o *manifest.Object) map[string]string {
+	meta, _ := o.PodTemplate()
+	return manifest.StringMap(manifest.Lookup(meta, "labels"))
+}
+
+// checkService reports Services whose selector matches no pods and named
+// target ports that the selected pods do not declare
+func (an *analysis) checkService(o *manifest.Object) {
+	selector := o.Get("spec", "selector")
+	want := manifest.StringMap(selector)
+	if manifest.Scalar(o.Get("spec", "type")) == "ExternalName" || len(want) == 0 {
+		return
+	}
+
+	var matched []*manifest.Object
+	for _, w := range an.podTemplates(an.namespace(o)) {
+		if subset(want, templateLabels(w)) {
+			matched = append(matched, w)
+		}
+	}
+	if len(matched) == 0 {
+		an.add(o, "", an.pos(o, selector), "warning", "service-selector",
+			fmt.Sprintf("%s selects %s, but no workload in namespace %s has these pod labels", o.ID(), formatLabels(want), an.namespace(o)))
+		return
+	}
+
+	for _, port := range manifest.Items(o.Get("spec", "ports")) {
+		target := manifest.Lookup(port, "targetPort")
+		name := manifest.Scalar(target)
+		if _, err := strconv.Atoi(name); name == "" || err == nil {
+			continue
+		}
+		if !declaresPort(matched, name) {
+			an.add(o, "", an.pos(o, target), "error", "service-target-port",
+				fmt.Sprintf("%s targets port %q, which no selected container declares", o.ID(), name))
+		}
+	}
+}
+
+// declaresPort reports whether a container of the objects names a port
+func declaresPort(objects []*manifest.Object, name string) bool {
+	for _, w := range objects {
+		_, spec := w.PodTemplate()
+		for _, c := range manifest.Items(manifest.Lookup(spec, "containers")) {
+			for _, p := range manifest.Items(manifest.Lookup(c, "ports")) {
+				if manifest.Scalar(manifest.Lookup(p, "name")) == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// checkPDB reports PodDisruptionBudgets that select nothing or block every
+// eviction
+func (an *analysis) checkPDB(o *manifest.Object) {
+	selector := o.Get("spec", "selector")
+	covered := an.coveredBy(o)
+	if len(covered) == 0 {
+		an.add(o, "", an.pos(o, selector), "warning", "pdb-selector",
+			fmt.Sprintf("%s selects no workload in namespace %s", o.ID(), an.namespace(o)))
+		return
+	}
+
+	for _, w := range covered {
+		replicas := an.replicas(w)
+		minAvailable, maxUnavailable := o.Get("spec", "minAvailable"), o.Get("spec", "maxUnavailable")
+		blocks := false
+		switch {
+		case maxUnavailable != nil:
+			blocks = maxUnavailable.Value == "0" || maxUnavailable.Value == "0%"
+		case minAvailable != nil:
+			n, err := strconv.Atoi(minAvailable.Value)
+			blocks = minAvailable.Value == "100%" || (err == nil && n >= replicas)
+		}
+		if blocks {
+			an.add(o, "", o.Pos, "warning", "pdb-blocks-eviction",
+				fmt.Sprintf("%s allows no disruption of %s with %d replica(s); node drains will hang", o.ID(), w.ID(), replicas))
+		}
+	}
+}
+
+// coveredBy returns the workloads a PodDisruptionBudget selects
+func (an *analysis) coveredBy(pdb *manifest.Object) []*manifest.Object {
+	selector := pdb.Get("spec", "selector")
+	var covered []*manifest.Object
+	for _, w := range an.podTemplates(an.namespace(pdb)) {
+		if w.Kind != "Pod" && w.Kind != "Job" && w.Kind != "CronJob" && matchesSelector(selector, templateLabels(w)) {
+			covered = append(covered, w)
+		}
+	}
+	return covered
+}
+
+// checkPDBCoverage reports replicated workloads that no
+// PodDisruptionBudget protects
+func (an *analysis) checkPDBCoverage() {
+	covered := make(map[*manifest.Object]bool)
+	for _, o := range an.set.Objects {
+		if o.Kind == "PodDisruptionBudget" {
+			for _, w := range an.coveredBy(o) {
+				covered[w] = true
+			}
+		}
+	}
+	for _, o := range an.set.Objects {
+		if o.Kind != "Deployment" && o.Kind != "StatefulSet" {
+			continue
+		}
+		if replicas := an.replicas(o); replicas > 1 && !covered[o] {
+			an.add(o, "", o.Pos, "warning", "pdb-missing",
+				fmt.Sprintf("%s runs %d replicas but no PodDisruptionBudget protects it from voluntary disruptions", o.ID(), replicas))
+		}
+	}
+}
+
+// replicas returns the replica count of a workload, taking the minimum of
+// a HorizontalPodAutoscaler that scales it
+func (an *analysis) replicas(o *manifest.Object) int {
+	for _, hpa := range an.set.Objects {
+		ref := hpa.Get("spec", "scaleTargetRef")
+		if hpa.Kind == "HorizontalPodAutoscaler" && an.namespace(hpa) == an.namespace(o) &&
+			manifest.Scalar(manifest.Lookup(ref, "kind")) == o.Kind && manifest.Scalar(manifest.Lookup(ref, "name")) == o.Name {
+			if n, err := strconv.Atoi(manifest.Scalar(hpa.Get("spec", "minReplicas"))); err == nil {
+				return n
+			}
+			return 1
+		}
+	}
+	return o.Replicas(1)
+}
+
+// matchesSelector evaluates a label selector with matchLabels and
+// matchExpressions. An empty selector matches nothing, as Kubernetes
+// treats a missing selector on these objects.
+func matchesSelector(selector *yaml.Node, labels map[string]string) bool {
+	matchLabels := manifest.StringMap(manifest.Lookup(selector, "matchLabels"))
+	expressions := manifest.Items(manifest.Lookup(selector, "matchExpressions"))
+	if len(matchLabels) == 0 && len(expressions) == 0 {
+		return false
+	}
+	if !subset(matchLabels, labels) {
+		return false
+	}
+	for _, expr := range expressions {
+		key := manifest.Scalar(manifest.Lookup(expr, "key"))
+		value, ok := labels[key]
+		var values []string
+		for _, v := range manifest.Items(manifest.Lookup(expr, "values")) {
+			values = append(values, v.Value)
+		}
+		in := false
+		for _, v := range values {
+			in = in || (ok && v == value)
+		}
+		switch manifest.Scalar(manifest.Lookup(expr, "operator")) {
+		case "In":
+			if !in {
+				return false
+			}
+		case "NotIn":
+			if in {
+				return false
+			}
+		case "Exists":
+			if !ok {
+				return false
+			}
+		case "DoesNotExist":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// subset reports whether labels has every pair in want
+func subset(want, labels map[string]string) bool {
+	for k, v := range want {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels formats labels as sorted key=value pairs
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
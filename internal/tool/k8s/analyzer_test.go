@@ -0,0 +1,296 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// issueLines maps each reported rule to the lines it was reported on
+func issueLines(issues []Issue) map[string][]int {
+	lines := make(map[string][]int)
+	for _, issue := range issues {
+		lines[issue.Rule] = append(lines[issue.Rule], issue.Line)
+	}
+	return lines
+}
+
+const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: api
+  template:
+    metadata:
+      labels:
+        app: api
+    spec:
+      securityContext:
+        runAsNonRoot: true
+      containers:
+        - name: api
+          image: example/api:1.4.2
+          ports:
+            - name: http
+              containerPort: 8080
+          resources:
+            requests: {cpu: 100m, memory: 128Mi}
+            limits: {memory: 256Mi}
+          readinessProbe: {httpGet: {path: /ready, port: http}}
+          livenessProbe: {httpGet: {path: /live, port: http}}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  selector:
+    app: api
+  ports:
+    - port: 80
+      targetPort: grpc
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: orphan
+spec:
+  selector:
+    app: nothing
+  ports:
+    - port: 80
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: legacy
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: other
+  template:
+    metadata:
+      labels:
+        app: legacy
+    spec:
+      containers:
+        - name: legacy
+          image: nginx
+          securityContext:
+            runAsUser: 0
+---
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: legacy
+spec:
+  minAvailable: 1
+  selector:
+    matchExpressions:
+      - {key: app, operator: In, values: [worker]}
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          securityContext:
+            runAsNonRoot: false
+          containers:
+            - name: report
+              image: example/report@sha256:abc
+              resources:
+                requests: {cpu: 10m, memory: 16Mi}
+                limits: {memory: 32Mi}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  type: ExternalName
+  externalName: example.com
+`
+
+func TestManifestAnalyzer_Analyze(t *testing.T) {
+	result, err := NewManifestAnalyzer(discardLogger()).Analyze("all.yaml", []byte(manifests), AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	want := map[string][]int{
+		"pdb-missing":         {1, 50},
+		"service-target-port": {38},
+		"service-selector":    {46},
+		"deprecated-api":      {50, 70},
+		"selector-mismatch":   {57},
+		"missing-probe":       {65, 65},
+		"resources-requests":  {65},
+		"resources-limits":    {65},
+		"image-not-pinned":    {66},
+		"run-as-non-root":     {68, 91},
+		"pdb-selector":        {77},
+		"duplicate-resource":  {99},
+	}
+	if got := issueLines(result.Issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v\nwant %v", got, want)
+	}
+	for _, issue := range result.Issues {
+		if issue.File != "all.yaml" || issue.Resource == "" {
+			t.Errorf("issue without location: %+v", issue)
+		}
+		if issue.Rule == "run-as-non-root" && issue.Line == 68 && issue.Severity != "error" {
+			t.Errorf("runAsUser 0 severity = %s, want error", issue.Severity)
+		}
+	}
+	if len(result.Resources) != 7 || result.Resources[3].Name != "legacy" || result.Resources[3].Line != 50 {
+		t.Errorf("resources = %+v", result.Resources)
+	}
+}
+
+func TestManifestAnalyzer_KubeVersion(t *testing.T) {
+	analyze := func(version string) map[string]string {
+		t.Helper()
+		result, err := NewManifestAnalyzer(discardLogger()).Analyze("all.yaml", []byte(manifests), AnalyzeOptions{KubeVersion: version})
+		if err != nil {
+			t.Fatalf("Analyze(%s) error = %v", version, err)
+		}
+		severities := make(map[string]string)
+		for _, issue := range result.Issues {
+			if issue.Rule == "deprecated-api" {
+				severities[issue.Resource] = issue.Severity
+			}
+		}
+		return severities
+	}
+
+	if got := analyze("1.15"); !reflect.DeepEqual(got, map[string]string{"Deployment/legacy": "warning"}) {
+		t.Errorf("1.15: %v", got)
+	}
+	want := map[string]string{"Deployment/legacy": "error", "PodDisruptionBudget/legacy": "warning"}
+	if got := analyze("v1.22.3"); !reflect.DeepEqual(got, want) {
+		t.Errorf("1.22: %v, want %v", got, want)
+	}
+	if _, err := NewManifestAnalyzer(discardLogger()).Analyze("all.yaml", []byte(manifests), AnalyzeOptions{KubeVersion: "latest"}); err == nil {
+		t.Error("Analyze() accepted an invalid version")
+	}
+}
+
+func TestManifestAnalyzer_PDB(t *testing.T) {
+	src := `apiVersion: apps/v1
+kind: StatefulSet
+metadata: {name: db, namespace: data}
+spec:
+  selector: {matchLabels: {app: db}}
+  template:
+    metadata: {labels: {app: db, tier: storage}}
+    spec: {containers: [{name: db, image: "postgres:16"}]}
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata: {name: db, namespace: data}
+spec:
+  scaleTargetRef: {apiVersion: apps/v1, kind: StatefulSet, name: db}
+  minReplicas: 1
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata: {name: db, namespace: data}
+spec:
+  maxUnavailable: 0
+  selector: {matchLabels: {tier: storage}}
+`
+	result, err := NewManifestAnalyzer(discardLogger()).Analyze("db.yaml", []byte(src), AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	lines := issueLines(result.Issues)
+	if !reflect.DeepEqual(lines["pdb-blocks-eviction"], []int{17}) || lines["pdb-missing"] != nil || lines["pdb-selector"] != nil {
+		t.Errorf("pdb issues = %v", lines)
+	}
+}
+
+func TestManifestAnalyzer_Kustomize(t *testing.T) {
+	overlay := filepath.Join("manifest", "testdata", "kustomize", "overlays", "prod")
+	result, err := NewManifestAnalyzer(discardLogger()).AnalyzeFile(overlay, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeFile() error = %v", err)
+	}
+
+	// Issues point at the file that set or should set the field
+	got := make(map[string]string)
+	for _, issue := range result.Issues {
+		rel, _ := filepath.Rel(filepath.Join("manifest", "testdata", "kustomize"), issue.File)
+		got[issue.Rule] += fmt.Sprintf("%s:%d ", filepath.ToSlash(rel), issue.Line)
+	}
+	want := map[string]string{
+		"unsupported":        "overlays/prod/kustomization.yaml:22 ",
+		"resources-requests": "overlays/prod/resources.yaml:11 ",
+		"missing-probe":      "base/deployment.yaml:9 ",
+		"run-as-non-root":    "base/deployment.yaml:9 ",
+		"pdb-missing":        "base/deployment.yaml:1 ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v\nwant %v", got, want)
+	}
+}
+
+func TestKubernetesTool_Execute(t *testing.T) {
+	k8sTool := NewKubernetesTool(config.Kubernetes{Namespace: "default"}, discardLogger())
+	if err := k8sTool.Health(context.Background()); err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+
+	result, err := k8sTool.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{
+		"action":  "analyze_manifests",
+		"content": manifests,
+		"options": map[string]interface{}{"kube_version": "1.29"},
+	}})
+	if err != nil || !result.Success {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+	if issues, _ := result.Data.Output["issues"].([]interface{}); len(issues) == 0 {
+		t.Errorf("output = %v", result.Data.Output)
+	}
+
+	result, err = k8sTool.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{
+		"action": "list_resources",
+		"path":   filepath.Join("manifest", "testdata", "helm.yaml"),
+	}})
+	if err != nil || !result.Success {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+	resources, _ := result.Data.Output["resources"].([]interface{})
+	if len(resources) != 2 || resources[1].(map[string]interface{})["source"] != "shop/templates/deployment.yaml" {
+		t.Errorf("resources = %v", resources)
+	}
+
+	result, _ = k8sTool.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{
+		"action": "analyze_manifests",
+		"path":   "does-not-exist",
+	}})
+	if result.Success {
+		t.Error("Execute() succeeded for a missing path")
+	}
+}
@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// deprecatedAPI is an API version that was removed from Kubernetes
+type deprecatedAPI struct {
+	apiVersion  string
+	kinds       []string // nil for every kind served by apiVersion
+	deprecated  version
+	removed     version
+	replacement string // "" when there is none
+}
+
+// deprecatedAPIs follows the Kubernetes deprecated API migration guide
+var deprecatedAPIs = []deprecatedAPI{
+	{"extensions/v1beta1", []string{"Deployment", "DaemonSet", "ReplicaSet"}, version{1, 8}, version{1, 16}, "apps/v1"},
+	{"extensions/v1beta1", []string{"NetworkPolicy"}, version{1, 9}, version{1, 16}, "networking.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"PodSecurityPolicy"}, version{1, 10}, version{1, 16}, "policy/v1beta1"},
+	{"extensions/v1beta1", []string{"Ingress"}, version{1, 14}, version{1, 22}, "networking.k8s.io/v1"},
+	{"apps/v1beta1", nil, version{1, 9}, version{1, 16}, "apps/v1"},
+	{"apps/v1beta2", nil, version{1, 9}, version{1, 16}, "apps/v1"},
+	{"networking.k8s.io/v1beta1", []string{"Ingress", "IngressClass"}, version{1, 19}, version{1, 22}, "networking.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", nil, version{1, 17}, version{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1alpha1", nil, version{1, 17}, version{1, 22}, "rbac.authorization.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", nil, version{1, 16}, version{1, 22}, "apiextensions.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", nil, version{1, 19}, version{1, 22}, "apiregistration.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", nil, version{1, 16}, version{1, 22}, "admissionregistration.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", nil, version{1, 14}, version{1, 22}, "scheduling.k8s.io/v1"},
+	{"scheduling.k8s.io/v1alpha1", nil, version{1, 14}, version{1, 17}, "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", []string{"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"}, version{1, 19}, version{1, 22}, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", []string{"CSIStorageCapacity"}, version{1, 24}, version{1, 27}, "storage.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", nil, version{1, 19}, version{1, 22}, "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", nil, version{1, 19}, version{1, 22}, "coordination.k8s.io/v1"},
+	{"batch/v1beta1", []string{"CronJob"}, version{1, 21}, version{1, 25}, "batch/v1"},
+	{"batch/v2alpha1", []string{"CronJob"}, version{1, 8}, version{1, 21}, "batch/v1"},
+	{"policy/v1beta1", []string{"PodDisruptionBudget"}, version{1, 21}, version{1, 25}, "policy/v1"},
+	{"policy/v1beta1", []string{"PodSecurityPolicy"}, version{1, 21}, version{1, 25}, ""},
+	{"discovery.k8s.io/v1beta1", nil, version{1, 21}, version{1, 25}, "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", nil, version{1, 19}, version{1, 25}, "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", nil, version{1, 22}, version{1, 25}, "autoscaling/v2"},
+	{"autoscaling/v2beta2", nil, version{1, 23}, version{1, 26}, "autoscaling/v2"},
+	{"node.k8s.io/v1beta1", nil, version{1, 20}, version{1, 25}, "node.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", nil, version{1, 23}, version{1, 26}, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", nil, version{1, 26}, version{1, 29}, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", nil, version{1, 29}, version{1, 32}, "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// findDeprecatedAPI returns the entry for an apiVersion and kind, or nil
+func findDeprecatedAPI(apiVersion, kind string) *deprecatedAPI {
+	for i, d := range deprecatedAPIs {
+		if d.apiVersion != apiVersion {
+			continue
+		}
+		if d.kinds == nil {
+			return &deprecatedAPIs[i]
+		}
+		for _, k := range d.kinds {
+			if k == kind {
+				return &deprecatedAPIs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// version is a Kubernetes minor version
+type version struct {
+	major, minor int
+}
+
+// parseVersion parses "1.29", "v1.29" or "v1.29.3"
+func parseVersion(s string) (version, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) < 2 {
+		return version{}, fmt.Errorf("invalid Kubernetes version %q, want e.g. 1.29", s)
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return version{}, fmt.Errorf("invalid Kubernetes version %q, want e.g. 1.29", s)
+	}
+	return version{major, minor}, nil
+}
+
+func (v version) less(w version) bool {
+	return v.major < w.major || (v.major == w.major && v.minor < w.minor)
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
@@ -0,0 +1,386 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationNames are the file names Kustomize looks for, in order
+var kustomizationNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+func isKustomizationName(name string) bool {
+	return slices.Contains(kustomizationNames, name)
+}
+
+// kustomizationFile returns the kustomization file in dir, or ""
+func kustomizationFile(dir string) string {
+	for _, name := range kustomizationNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// isComponent reports whether a kustomization file declares a Component
+func isComponent(file string) bool {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	var k struct {
+		Kind string `yaml:"kind"`
+	}
+	return yaml.Unmarshal(src, &k) == nil && k.Kind == "Component"
+}
+
+// ignoredFields are kustomization fields that do not change the output
+var ignoredFields = map[string]bool{
+	"apiVersion": true, "kind": true, "metadata": true, "crds": true,
+	"openapi": true, "configurations": true, "sortOptions": true,
+	"buildMetadata": true, "generatorOptions": true,
+}
+
+// unsupportedFields are kustomization fields that are not built offline
+var unsupportedFields = map[string]bool{
+	"configMapGenerator": true, "secretGenerator": true, "generators": true,
+	"transformers": true, "validators": true, "helmCharts": true,
+	"helmGlobals": true, "replacements": true, "vars": true,
+}
+
+// kustomize builds the kustomization in dir. For a component, input holds
+// the resources of the including kustomization, which the component
+// transforms along with its own. stack holds the directories being built.
+func (l *loader) kustomize(dir string, input []*Object, stack []string) []*Object {
+	file := kustomizationFile(dir)
+	abs, _ := filepath.Abs(dir)
+	stack = append(stack, abs)
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		l.errorf(Pos{File: dir}, "failed to read kustomization: %v", err)
+		return input
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		l.errorf(Pos{File: file}, "invalid kustomization: %v", err)
+		return input
+	}
+	if len(doc.Content) == 0 {
+		return input
+	}
+	k := expandAliases(doc.Content[0])
+	l.mark(k, file)
+
+	for _, kv := range Pairs(k) {
+		key := kv[0].Value
+		switch {
+		case unsupportedFields[key]:
+			l.warnf(l.set.Pos(kv[0]), "%s is not supported offline and was skipped", key)
+		case !ignoredFields[key] && !kustomizeFields[key]:
+			l.warnf(l.set.Pos(kv[0]), "unknown kustomization field %q", key)
+		}
+	}
+
+	objects := input
+	var own []*Object
+	for _, key := range []string{"resources", "bases"} {
+		for _, entry := range Items(Lookup(k, key)) {
+			own = append(own, l.resource(dir, entry, stack)...)
+		}
+	}
+	objects = append(objects, own...)
+	for _, entry := range Items(Lookup(k, "components")) {
+		path, ok := l.localPath(dir, entry)
+		if !ok {
+			continue
+		}
+		if kustomizationFile(path) == "" {
+			l.errorf(l.set.Pos(entry), "component %s has no kustomization file", entry.Value)
+			continue
+		}
+		if l.circular(path, entry, stack) {
+			continue
+		}
+		objects = l.kustomize(path, objects, stack)
+	}
+
+	l.applyPatches(dir, k, objects)
+	l.transform(k, objects)
+	return objects
+}
+
+// kustomizeFields are the kustomization fields that are built
+var kustomizeFields = map[string]bool{
+	"resources": true, "bases": true, "components": true, "namespace": true,
+	"namePrefix": true, "nameSuffix": true, "commonLabels": true,
+	"labels": true, "commonAnnotations": true, "images": true,
+	"replicas": true, "patches": true, "patchesStrategicMerge": true,
+	"patchesJson6902": true,
+}
+
+// localPath resolves a resource entry relative to dir and records it as
+// used
+func (l *loader) localPath(dir string, entry *yaml.Node) (string, bool) {
+	ref := Scalar(entry)
+	if ref == "" {
+		l.errorf(l.set.Pos(entry), "empty resource entry")
+		return "", false
+	}
+	if strings.Contains(ref, "://") || strings.Contains(ref, "?ref=") || strings.HasPrefix(ref, "github.com/") || strings.HasPrefix(ref, "git@") {
+		l.errorf(l.set.Pos(entry), "remote resource %s cannot be loaded offline", ref)
+		return "", false
+	}
+	path := filepath.Join(dir, ref)
+	if _, err := os.Stat(path); err != nil {
+		l.errorf(l.set.Pos(entry), "resource %s does not exist", ref)
+		return "", false
+	}
+	l.used[filepath.Clean(path)] = true
+	return path, true
+}
+
+// resource loads a resources entry: a manifest file or a kustomization
+func (l *loader) resource(dir string, entry *yaml.Node, stack []string) []*Object {
+	path, ok := l.localPath(dir, entry)
+	if !ok {
+		return nil
+	}
+	if info, _ := os.Stat(path); info.IsDir() {
+		if kustomizationFile(path) == "" {
+			l.errorf(l.set.Pos(entry), "resource directory %s has no kustomization file", entry.Value)
+			return nil
+		}
+		if l.circular(path, entry, stack) {
+			return nil
+		}
+		return l.kustomize(path, nil, stack)
+	}
+	return l.readFile(path, true)
+}
+
+// circular reports a reference from entry to a kustomization that is
+// already being built
+func (l *loader) circular(dir string, entry *yaml.Node, stack []string) bool {
+	abs, _ := filepath.Abs(dir)
+	if !slices.Contains(stack, abs) {
+		return false
+	}
+	l.errorf(l.set.Pos(entry), "circular kustomization reference: %s", strings.Join(append(stack, abs), " -> "))
+	return true
+}
+
+// transform applies the builtin transformers in the order Kustomize runs
+// them
+func (l *loader) transform(k *yaml.Node, objects []*Object) {
+	if ns := Lookup(k, "namespace"); Scalar(ns) != "" {
+		for _, o := range objects {
+			if !ClusterScoped(o.Kind) {
+				l.setValue(l.ensureMap(o.Node, l.set.Pos(ns), "metadata"), "namespace", ns.Value, l.set.Pos(ns))
+				o.syncMeta()
+			}
+		}
+	}
+
+	prefix, suffix := Scalar(Lookup(k, "namePrefix")), Scalar(Lookup(k, "nameSuffix"))
+	if prefix != "" || suffix != "" {
+		l.rename(objects, prefix, suffix)
+	}
+
+	if labels := Lookup(k, "commonLabels"); labels != nil {
+		l.addLabels(objects, labels, true, true)
+	}
+	for _, entry := range Items(Lookup(k, "labels")) {
+		selectors := Scalar(Lookup(entry, "includeSelectors")) == "true"
+		templates := selectors || Scalar(Lookup(entry, "includeTemplates")) == "true"
+		l.addLabels(objects, Lookup(entry, "pairs"), selectors, templates)
+	}
+
+	if annotations := Lookup(k, "commonAnnotations"); annotations != nil {
+		for _, o := range objects {
+			l.addPairs(o.Node, annotations, "metadata", "annotations")
+			if template := o.template(); template != nil && template != o.Node {
+				l.addPairs(template, annotations, "metadata", "annotations")
+			}
+		}
+	}
+
+	for _, entry := range Items(Lookup(k, "images")) {
+		l.setImages(objects, entry)
+	}
+
+	for _, entry := range Items(Lookup(k, "replicas")) {
+		name, count := Scalar(Lookup(entry, "name")), Lookup(entry, "count")
+		for _, o := range objects {
+			switch o.Kind {
+			case "Deployment", "ReplicaSet", "StatefulSet", "ReplicationController":
+				if o.hasName(name) && count != nil {
+					l.setValue(l.ensureMap(o.Node, l.set.Pos(count), "spec"), "replicas", Scalar(count), l.set.Pos(count))
+					Lookup(o.Node, "spec", "replicas").Tag = "!!int"
+				}
+			}
+		}
+	}
+}
+
+// hasName reports whether the object is or was called name
+func (o *Object) hasName(name string) bool {
+	return o.Name == name || slices.Contains(o.names, name)
+}
+
+// rename applies namePrefix and nameSuffix and updates the references
+// of HorizontalPodAutoscalers to renamed objects
+func (l *loader) rename(objects []*Object, prefix, suffix string) {
+	renamed := make(map[string]string)
+	for _, o := range objects {
+		if o.Kind == "Namespace" || o.Kind == "CustomResourceDefinition" {
+			continue
+		}
+		name := o.Get("metadata", "name")
+		if name == nil {
+			continue
+		}
+		renamed[o.Kind+"/"+name.Value] = prefix + name.Value + suffix
+		o.names = append(o.names, name.Value)
+		name.Value = prefix + name.Value + suffix
+		o.syncMeta()
+	}
+	for _, o := range objects {
+		if o.Kind != "HorizontalPodAutoscaler" {
+			continue
+		}
+		ref := o.Get("spec", "scaleTargetRef")
+		if name := Lookup(ref, "name"); name != nil {
+			if newName, ok := renamed[Scalar(Lookup(ref, "kind"))+"/"+name.Value]; ok {
+				name.Value = newName
+			}
+		}
+	}
+}
+
+// addLabels adds labels to the objects and, when asked, to their
+// selectors and pod templates
+func (l *loader) addLabels(objects []*Object, labels *yaml.Node, selectors, templates bool) {
+	if labels == nil {
+		return
+	}
+	for _, o := range objects {
+		l.addPairs(o.Node, labels, "metadata", "labels")
+		if template := o.template(); templates && template != nil && template != o.Node {
+			l.addPairs(template, labels, "metadata", "labels")
+		}
+		if !selectors {
+			continue
+		}
+		switch o.Kind {
+		case "Service":
+			if o.Get("spec", "type") == nil || Scalar(o.Get("spec", "type")) != "ExternalName" {
+				l.addPairs(o.Node, labels, "spec", "selector")
+			}
+		case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "PodDisruptionBudget":
+			l.addPairs(o.Node, labels, "spec", "selector", "matchLabels")
+		case "ReplicationController":
+			l.addPairs(o.Node, labels, "spec", "selector")
+		}
+	}
+}
+
+// addPairs copies the entries of a mapping into the mapping at keys below
+// n, creating it where needed
+func (l *loader) addPairs(n, pairs *yaml.Node, keys ...string) {
+	kvs := Pairs(pairs)
+	if len(kvs) == 0 {
+		return
+	}
+	m := l.ensureMap(n, l.set.Pos(pairs), keys...)
+	for _, kv := range kvs {
+		l.setValue(m, kv[0].Value, Scalar(kv[1]), l.set.Pos(kv[1]))
+	}
+}
+
+// setImages applies an images entry to every container image it names
+func (l *loader) setImages(objects []*Object, entry *yaml.Node) {
+	name := Scalar(Lookup(entry, "name"))
+	newName := Scalar(Lookup(entry, "newName"))
+	newTag := Scalar(Lookup(entry, "newTag"))
+	digest := Scalar(Lookup(entry, "digest"))
+	pos := l.set.Pos(entry)
+
+	for _, o := range objects {
+		_, spec := o.PodTemplate()
+		for _, list := range []string{"initContainers", "containers"} {
+			for _, c := range Items(Lookup(spec, list)) {
+				image := Lookup(c, "image")
+				ref, tag, dgst := splitImage(Scalar(image))
+				if image == nil || ref != name {
+					continue
+				}
+				if newName != "" {
+					ref = newName
+				}
+				switch {
+				case digest != "":
+					tag, dgst = "", digest
+				case newTag != "":
+					tag, dgst = newTag, ""
+				}
+				value := ref
+				if tag != "" {
+					value += ":" + tag
+				}
+				if dgst != "" {
+					value += "@" + dgst
+				}
+				setKey(c, keyNode(c, "image"), l.scalarAt(value, pos))
+			}
+		}
+	}
+}
+
+// splitImage splits an image reference into name, tag and digest
+func splitImage(image string) (string, string, string) {
+	var digest, tag string
+	if i := strings.Index(image, "@"); i >= 0 {
+		image, digest = image[:i], image[i+1:]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image, tag = image[:colon], image[colon+1:]
+	}
+	return image, tag, digest
+}
+
+// PodTemplate returns the metadata and spec of the pods an object runs:
+// the object itself for a Pod, its pod template for workloads, and nil
+// for other kinds
+func (o *Object) PodTemplate() (meta, spec *yaml.Node) {
+	template := o.template()
+	return Lookup(template, "metadata"), Lookup(template, "spec")
+}
+
+// template returns the node holding the pod metadata and spec; the object
+// itself for a Pod
+func (o *Object) template() *yaml.Node {
+	switch o.Kind {
+	case "Pod":
+		return o.Node
+	case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "Job", "ReplicationController":
+		return o.Get("spec", "template")
+	case "CronJob":
+		return o.Get("spec", "jobTemplate", "spec", "template")
+	}
+	return nil
+}
+
+// Replicas returns spec.replicas, or def when it is not set
+func (o *Object) Replicas(def int) int {
+	if n, err := strconv.Atoi(Scalar(o.Get("spec", "replicas"))); err == nil {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,304 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load loads the manifests at path, which may be a manifest file, a
+// kustomization file or directory, or a directory tree. In a tree, every
+// Kustomize directory is built and files outside them are read as plain
+// manifests; kustomizations and files that another kustomization uses are
+// only loaded through it. Documents without a kind, such as Helm values
+// files, are skipped in trees.
+//
+// The returned set holds everything that could be loaded; problems are
+// reported as an ErrorList.
+func Load(path string) (*Set, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifests: %w", err)
+	}
+
+	l := newLoader()
+	switch {
+	case !info.IsDir() && isKustomizationName(filepath.Base(path)):
+		l.set.Objects = l.kustomize(filepath.Dir(path), nil, nil)
+	case !info.IsDir():
+		l.set.Objects = l.readFile(path, true)
+	case kustomizationFile(path) != "":
+		l.set.Objects = l.kustomize(path, nil, nil)
+	default:
+		l.set.Objects = l.loadTree(path)
+	}
+	return l.set, l.errs.Err()
+}
+
+// Parse parses manifest content such as the output of helm template or
+// kustomize build; name is recorded in positions
+func Parse(name string, src []byte) (*Set, error) {
+	l := newLoader()
+	l.set.Objects = l.parse(name, src, true)
+	return l.set, l.errs.Err()
+}
+
+// loader holds the state of a Load or Parse call
+type loader struct {
+	set  *Set
+	errs ErrorList
+	seen map[string]bool // reported errors, as errors repeat when a base is built twice
+	// used holds the cleaned paths of files and directories that a
+	// kustomization used
+	used map[string]bool
+}
+
+func newLoader() *loader {
+	return &loader{
+		set:  &Set{files: make(map[*yaml.Node]string)},
+		seen: make(map[string]bool),
+		used: make(map[string]bool),
+	}
+}
+
+func (l *loader) errorf(pos Pos, format string, args ...interface{}) {
+	e := &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	if !l.seen[e.Error()] {
+		l.seen[e.Error()] = true
+		l.errs = append(l.errs, e)
+	}
+}
+
+func (l *loader) warnf(pos Pos, format string, args ...interface{}) {
+	e := &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	if !l.seen[e.Error()] {
+		l.seen[e.Error()] = true
+		l.set.Warnings = append(l.set.Warnings, e)
+	}
+}
+
+// mark records file as the origin of n and the nodes below it
+func (l *loader) mark(n *yaml.Node, file string) {
+	walk(n, func(c *yaml.Node) { l.set.files[c] = file })
+}
+
+// copyNode deep-copies n keeping the origins
+func (l *loader) copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = l.copyNode(child)
+	}
+	l.set.files[&c] = l.set.files[n]
+	return &c
+}
+
+// scalarAt returns a new string scalar located at pos
+func (l *loader) scalarAt(value string, pos Pos) *yaml.Node {
+	n := newScalar(value, pos.Line)
+	l.set.files[n] = pos.File
+	return n
+}
+
+// ensureMap returns the mapping at keys below n, creating missing ones at
+// pos
+func (l *loader) ensureMap(n *yaml.Node, pos Pos, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		next := Lookup(n, key)
+		if next == nil || next.Kind != yaml.MappingNode {
+			next = newMapping(pos.Line)
+			l.set.files[next] = pos.File
+			setKey(n, l.scalarAt(key, pos), next)
+		}
+		n = next
+	}
+	return n
+}
+
+// setValue sets key in mapping m to a scalar located at pos
+func (l *loader) setValue(m *yaml.Node, key, value string, pos Pos) {
+	if k := keyNode(m, key); k != nil {
+		setKey(m, k, l.scalarAt(value, pos))
+		return
+	}
+	setKey(m, l.scalarAt(key, pos), l.scalarAt(value, pos))
+}
+
+// readFile loads the objects in a manifest file. In strict mode documents
+// that are not Kubernetes objects are errors; otherwise they are skipped.
+func (l *loader) readFile(path string, strict bool) []*Object {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		l.errorf(Pos{File: path}, "failed to read manifest: %v", err)
+		return nil
+	}
+	return l.parse(path, src, strict)
+}
+
+var (
+	sourceRe  = regexp.MustCompile(`^#\s*Source:\s*(\S+)`)
+	yamlErrRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+)
+
+// parse decodes the documents in src
+func (l *loader) parse(name string, src []byte, strict bool) []*Object {
+	// helm template writes "# Source: chart/templates/x.yaml" above each
+	// document
+	type source struct {
+		line int
+		path string
+	}
+	var sources []source
+	for i, line := range strings.Split(string(src), "\n") {
+		if m := sourceRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			sources = append(sources, source{i + 1, m[1]})
+		}
+	}
+
+	var objects []*Object
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	prevLine := 0
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			pos, msg := Pos{File: name}, err.Error()
+			if m := yamlErrRe.FindStringSubmatch(msg); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				pos.Line, msg = line, m[2]
+			}
+			l.errorf(pos, "invalid YAML: %s", msg)
+			break
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := expandAliases(doc.Content[0])
+		if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+			continue
+		}
+		l.mark(root, name)
+
+		src := ""
+		for _, s := range sources {
+			if s.line > prevLine && s.line < root.Line {
+				src = s.path
+			}
+		}
+		prevLine = root.Line
+
+		if root.Kind != yaml.MappingNode {
+			if strict {
+				l.errorf(Pos{File: name, Line: root.Line}, "document is not a mapping")
+			}
+			continue
+		}
+		if kind := Scalar(Lookup(root, "kind")); strings.HasSuffix(kind, "List") && Lookup(root, "items") != nil {
+			for _, item := range Items(Lookup(root, "items")) {
+				if o := l.object(name, item, src, strict); o != nil {
+					objects = append(objects, o)
+				}
+			}
+			continue
+		}
+		if o := l.object(name, root, src, strict); o != nil {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+// object validates a document and wraps it as an Object
+func (l *loader) object(file string, n *yaml.Node, source string, strict bool) *Object {
+	o := &Object{Node: n, Source: source, Pos: Pos{File: file, Line: n.Line}}
+	o.syncMeta()
+	switch {
+	case o.Kind == "" || o.APIVersion == "":
+		if strict {
+			l.errorf(o.Pos, "document is not a Kubernetes object: apiVersion and kind are required")
+		}
+		return nil
+	case o.Name == "" && Scalar(o.Get("metadata", "generateName")) == "":
+		l.errorf(o.Pos, "%s has no metadata.name", o.Kind)
+		return nil
+	}
+	return o
+}
+
+// loadTree loads every manifest and kustomization below root
+func (l *loader) loadTree(root string) []*Object {
+	var dirs, files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			l.errorf(Pos{File: path}, "failed to read: %v", err)
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+				l.warnf(Pos{File: path}, "Helm chart is not rendered; analyze the output of helm template instead")
+				return filepath.SkipDir
+			}
+			if file := kustomizationFile(path); file != "" {
+				// Components only make sense inside the kustomization
+				// that includes them
+				if !isComponent(file) {
+					dirs = append(dirs, path)
+				}
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); (ext == ".yaml" || ext == ".yml") && !isKustomizationName(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		l.errorf(Pos{File: root}, "failed to read: %v", err)
+	}
+
+	// Build every kustomization first so that used holds everything they
+	// reference
+	built := make([][]*Object, len(dirs))
+	for i, dir := range dirs {
+		built[i] = l.kustomize(dir, nil, nil)
+	}
+
+	var objects []*Object
+	for i, dir := range dirs {
+		if !l.used[filepath.Clean(dir)] {
+			objects = append(objects, built[i]...)
+		}
+	}
+	for _, file := range files {
+		if l.used[filepath.Clean(file)] || insideAny(file, dirs) {
+			continue
+		}
+		objects = append(objects, l.readFile(file, false)...)
+	}
+	return objects
+}
+
+// insideAny reports whether path is below one of dirs
+func insideAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ids returns the IDs of the objects in a set
+func ids(s *Set) []string {
+	var list []string
+	for _, o := range s.Objects {
+		list = append(list, o.ID())
+	}
+	return list
+}
+
+func TestLoad_File(t *testing.T) {
+	s, err := Load(filepath.Join("testdata", "app.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []string{"Deployment/web", "Service/web", "ConfigMap/settings", "Secret/creds"}
+	if !reflect.DeepEqual(ids(s), want) {
+		t.Errorf("objects = %v, want %v", ids(s), want)
+	}
+
+	lines := []int{2, 22, 35, 39}
+	for i, o := range s.Objects {
+		if o.Pos.Line != lines[i] {
+			t.Errorf("%s at line %d, want %d", o.ID(), o.Pos.Line, lines[i])
+		}
+	}
+	_, spec := s.Objects[0].PodTemplate()
+	image := Lookup(Items(Lookup(spec, "containers"))[0], "image")
+	if pos := s.Pos(image); pos.Line != 20 || pos.File != filepath.Join("testdata", "app.yaml") {
+		t.Errorf("image at %v, want line 20", pos)
+	}
+	if s.Objects[0].Replicas(1) != 2 || s.Objects[1].Replicas(1) != 1 {
+		t.Error("Replicas() did not read spec.replicas")
+	}
+}
+
+func TestParse_HelmOutput(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "helm.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Parse("rendered.yaml", src)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var sources []string
+	for _, o := range s.Objects {
+		sources = append(sources, o.Source)
+	}
+	want := []string{"shop/templates/serviceaccount.yaml", "shop/templates/deployment.yaml"}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+	if s.Objects[1].Pos.Line != 9 {
+		t.Errorf("Deployment at line %d, want 9", s.Objects[1].Pos.Line)
+	}
+}
+
+func TestLoad_Kustomize(t *testing.T) {
+	s, err := Load(filepath.Join("testdata", "kustomize", "overlays", "prod"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := []string{"Deployment/prod/prod-api", "Service/prod/prod-api"}; !reflect.DeepEqual(ids(s), want) {
+		t.Fatalf("objects = %v, want %v", ids(s), want)
+	}
+	if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0].Msg, "configMapGenerator") {
+		t.Errorf("warnings = %v", s.Warnings)
+	}
+
+	deploy, svc := s.Objects[0], s.Objects[1]
+	overlay := filepath.Join("testdata", "kustomize", "overlays", "prod")
+	if deploy.Replicas(1) != 3 || s.Pos(deploy.Get("spec", "replicas")) != (Pos{filepath.Join(overlay, "kustomization.yaml"), 12}) {
+		t.Errorf("replicas = %d at %v", deploy.Replicas(1), s.Pos(deploy.Get("spec", "replicas")))
+	}
+	if got := StringMap(deploy.Get("spec", "selector", "matchLabels")); !reflect.DeepEqual(got, map[string]string{"app": "api"}) {
+		t.Errorf("selector = %v, want commonLabels", got)
+	}
+	if got := StringMap(svc.Get("spec", "selector")); !reflect.DeepEqual(got, map[string]string{"app": "api"}) {
+		t.Errorf("service selector = %v, want commonLabels", got)
+	}
+	if port := Scalar(Lookup(Items(svc.Get("spec", "ports"))[0], "port")); port != "8080" {
+		t.Errorf("service port = %s, want JSON patch value 8080", port)
+	}
+
+	meta, spec := deploy.PodTemplate()
+	if StringMap(Lookup(meta, "labels"))["app"] != "api" {
+		t.Errorf("template labels = %v", StringMap(Lookup(meta, "labels")))
+	}
+	containers := Items(Lookup(spec, "containers"))
+	if len(containers) != 1 {
+		t.Fatalf("containers = %d, want the sidecar deleted", len(containers))
+	}
+	api := containers[0]
+	tests := []struct {
+		field string
+		value string
+		pos   Pos
+	}{
+		{"image", "example/api:2.1.0", Pos{filepath.Join(overlay, "kustomization.yaml"), 8}},
+		{"readinessProbe", "", Pos{filepath.Join("testdata", "kustomize", "components", "probes", "kustomization.yaml"), 10}},
+		{"resources", "", Pos{filepath.Join(overlay, "resources.yaml"), 11}},
+		{"ports", "", Pos{filepath.Join("testdata", "kustomize", "base", "deployment.yaml"), 12}},
+	}
+	for _, tt := range tests {
+		n := Lookup(api, tt.field)
+		if n == nil {
+			t.Errorf("%s missing", tt.field)
+			continue
+		}
+		if tt.value != "" && n.Value != tt.value {
+			t.Errorf("%s = %q, want %q", tt.field, n.Value, tt.value)
+		}
+		if s.Pos(n) != tt.pos {
+			t.Errorf("%s at %v, want %v", tt.field, s.Pos(n), tt.pos)
+		}
+	}
+}
+
+func TestLoad_Tree(t *testing.T) {
+	s, err := Load("testdata")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// The base is only built through the overlay and the component not at
+	// all on its own
+	want := []string{
+		"Deployment/prod/prod-api", "Service/prod/prod-api",
+		"Deployment/web", "Service/web", "ConfigMap/settings", "Secret/creds",
+		"ServiceAccount/shop", "Deployment/shop",
+	}
+	if !reflect.DeepEqual(ids(s), want) {
+		t.Errorf("objects = %v, want %v", ids(s), want)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/kustomization.yaml", "resources:\n  - ../b\n  - https://github.com/org/repo//deploy?ref=v1\n  - missing.yaml\n  - cm.yaml\npatches:\n  - patch: |\n      apiVersion: v1\n      kind: ConfigMap\n      metadata:\n        name: nope\n")
+	write("a/cm.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n---\nkind: Secret\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: s\n  labels: [a\n")
+	write("b/kustomization.yaml", "resources:\n  - ../a\n")
+
+	s, err := Load(filepath.Join(dir, "a"))
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Load() error = %v, want ErrorList", err)
+	}
+	var got []string
+	for _, e := range list {
+		rel, _ := filepath.Rel(dir, e.Pos.File)
+		got = append(got, Pos{File: rel, Line: e.Pos.Line}.String()+" "+e.Msg)
+	}
+	want := []string{
+		"a/cm.yaml:6 document is not a Kubernetes object: apiVersion and kind are required",
+		"a/cm.yaml:11 invalid YAML: did not find expected ',' or ']'",
+		"a/kustomization.yaml:3 remote resource https://github.com/org/repo//deploy?ref=v1 cannot be loaded offline",
+		"a/kustomization.yaml:4 resource missing.yaml does not exist",
+		"a/kustomization.yaml:8 patch target ConfigMap/nope not found",
+		"b/kustomization.yaml:2 circular kustomization reference: " + filepath.Join(dir, "a") + " -> " + filepath.Join(dir, "b") + " -> " + filepath.Join(dir, "a"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !reflect.DeepEqual(ids(s), []string{"ConfigMap/cm"}) {
+		t.Errorf("objects = %v", ids(s))
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	src := `apiVersion: v1
+kind: Pod
+metadata:
+  name: p
+spec:
+  containers:
+    - name: a
+      args: [x, y]
+`
+	s, err := Parse("pod.yaml", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &loader{set: s, seen: map[string]bool{}}
+	ops := l.parseDocuments("patch.yaml", []byte(`- {op: add, path: /spec/containers/0/args/1, value: inserted}
+- {op: add, path: /spec/containers/0/args/-, value: last}
+- {op: remove, path: /spec/containers/0/args/0}
+- {op: copy, from: /metadata/name, path: /metadata/labels}
+- {op: move, from: /metadata/labels, path: /metadata/annotations~1x}
+- {op: replace, path: /spec/missing, value: 1}
+- {op: test, path: /metadata/name, value: q}
+`), 0)[0]
+	l.jsonPatch(ops, &selector{kind: "Pod"}, s.Objects)
+
+	pod := s.Objects[0]
+	var args []string
+	for _, n := range Items(Lookup(Items(pod.Get("spec", "containers"))[0], "args")) {
+		args = append(args, n.Value)
+	}
+	if !reflect.DeepEqual(args, []string{"inserted", "y", "last"}) {
+		t.Errorf("args = %v", args)
+	}
+	if Scalar(pod.Get("metadata", "annotations/x")) != "p" || pod.Get("metadata", "labels") != nil {
+		t.Errorf("metadata = %v", StringMap(pod.Get("metadata")))
+	}
+	if len(l.errs) != 2 || !strings.Contains(l.errs[0].Msg, "/spec/missing not found") || l.errs[1].Pos.Line != 7 {
+		t.Errorf("errors = %v", l.errs)
+	}
+}
@@ -0,0 +1,163 @@
+package manifest
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Pairs returns the key and value nodes of a mapping; other kinds have none
+func Pairs(n *yaml.Node) [][2]*yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	kvs := make([][2]*yaml.Node, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		kvs = append(kvs, [2]*yaml.Node{n.Content[i], n.Content[i+1]})
+	}
+	return kvs
+}
+
+// Lookup follows a chain of mapping keys and returns the value, or nil
+func Lookup(n *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		var next *yaml.Node
+		for _, kv := range Pairs(n) {
+			if kv[0].Value == key {
+				next = kv[1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// Scalar returns the value of a scalar node, or ""
+func Scalar(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		return ""
+	}
+	return n.Value
+}
+
+// Items returns the items of a sequence node
+func Items(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// StringMap returns a mapping of scalars, such as labels, as a map
+func StringMap(n *yaml.Node) map[string]string {
+	kvs := Pairs(n)
+	if kvs == nil {
+		return nil
+	}
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		m[kv[0].Value] = Scalar(kv[1])
+	}
+	return m
+}
+
+// keyNode returns the key node of a mapping entry, or nil
+func keyNode(n *yaml.Node, key string) *yaml.Node {
+	for _, kv := range Pairs(n) {
+		if kv[0].Value == key {
+			return kv[0]
+		}
+	}
+	return nil
+}
+
+// setKey sets key in a mapping, replacing an existing entry
+func setKey(n *yaml.Node, key, value *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key.Value {
+			n.Content[i+1] = value
+			return
+		}
+	}
+	n.Content = append(n.Content, key, value)
+}
+
+// removeKey deletes key from a mapping and reports whether it was present
+func removeKey(n *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn for n and every node below it
+func walk(n *yaml.Node, fn func(*yaml.Node)) {
+	fn(n)
+	for _, c := range n.Content {
+		walk(c, fn)
+	}
+}
+
+// expandAliases replaces aliases with copies of their anchors and applies
+// "<<" merge keys, so later passes see plain trees
+func expandAliases(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		return expandAliases(copyTree(n.Alias))
+	}
+	for i, c := range n.Content {
+		n.Content[i] = expandAliases(c)
+	}
+	if n.Kind != yaml.MappingNode {
+		return n
+	}
+
+	var own, merged []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Tag != "!!merge" && key.Value != "<<" {
+			own = append(own, key, value)
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, src := range sources {
+			merged = append(merged, src.Content...)
+		}
+	}
+	// Keys of the mapping itself win over merged ones
+	result := &yaml.Node{Kind: yaml.MappingNode, Content: own}
+	for i := 0; i+1 < len(merged); i += 2 {
+		if keyNode(result, merged[i].Value) == nil {
+			result.Content = append(result.Content, merged[i], merged[i+1])
+		}
+	}
+	n.Content = result.Content
+	return n
+}
+
+// copyTree deep-copies a node tree
+func copyTree(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyTree(child)
+	}
+	return &c
+}
+
+// newScalar returns a string scalar placed at line
+func newScalar(value string, line int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: line}
+}
+
+// newMapping returns an empty mapping placed at line
+func newMapping(line int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line}
+}
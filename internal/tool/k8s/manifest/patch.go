@@ -0,0 +1,505 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// selector selects the objects a patch applies to
+type selector struct {
+	group, version, kind, name, namespace string
+	labelSelector                         string
+}
+
+// applyPatches applies patchesStrategicMerge, patchesJson6902 and patches
+func (l *loader) applyPatches(dir string, k *yaml.Node, objects []*Object) {
+	for _, entry := range Items(Lookup(k, "patchesStrategicMerge")) {
+		for _, patch := range l.patchContent(dir, entry, nil) {
+			l.strategicPatch(patch, nil, objects)
+		}
+	}
+
+	for _, entry := range Items(Lookup(k, "patchesJson6902")) {
+		sel := targetSelector(Lookup(entry, "target"))
+		for _, patch := range l.patchContent(dir, Lookup(entry, "path"), Lookup(entry, "patch")) {
+			l.jsonPatch(patch, sel, objects)
+		}
+	}
+
+	for _, entry := range Items(Lookup(k, "patches")) {
+		var sel *selector
+		if target := Lookup(entry, "target"); target != nil {
+			sel = targetSelector(target)
+		}
+		for _, patch := range l.patchContent(dir, Lookup(entry, "path"), Lookup(entry, "patch")) {
+			if patch.Kind == yaml.SequenceNode {
+				if sel == nil {
+					l.errorf(l.set.Pos(patch), "a JSON patch needs a target")
+					continue
+				}
+				l.jsonPatch(patch, sel, objects)
+				continue
+			}
+			l.strategicPatch(patch, sel, objects)
+		}
+	}
+}
+
+// patchContent returns the documents of a patch given as a file path or
+// inline. A patchesStrategicMerge entry is either, so it is passed as path
+// and treated as inline when it spans lines.
+func (l *loader) patchContent(dir string, path, inline *yaml.Node) []*yaml.Node {
+	if path != nil && inline == nil && strings.Contains(path.Value, "\n") {
+		path, inline = nil, path
+	}
+
+	var file string
+	var src []byte
+	offset := 0
+	switch {
+	case path != nil:
+		p, ok := l.localPath(dir, path)
+		if !ok {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			l.errorf(l.set.Pos(path), "failed to read patch: %v", err)
+			return nil
+		}
+		file, src = p, data
+	case inline != nil:
+		// Block scalars start on the line after the key
+		file, src, offset = l.set.files[inline], []byte(inline.Value), inline.Line-1
+		if inline.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			offset = inline.Line
+		}
+	default:
+		return nil
+	}
+
+	return l.parseDocuments(file, src, offset)
+}
+
+// parseDocuments decodes the documents of a patch, which need not be
+// complete objects
+func (l *loader) parseDocuments(file string, src []byte, offset int) []*yaml.Node {
+	var docs []*yaml.Node
+	for _, part := range splitDocuments(src) {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(part.src, &doc); err != nil {
+			l.errorf(Pos{File: file, Line: offset + part.line}, "invalid patch: %v", err)
+			continue
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := expandAliases(doc.Content[0])
+		walk(root, func(n *yaml.Node) {
+			n.Line += offset + part.line - 1
+			l.set.files[n] = file
+		})
+		docs = append(docs, root)
+	}
+	return docs
+}
+
+// document is a YAML document and the line it starts on
+type document struct {
+	src  []byte
+	line int
+}
+
+// splitDocuments splits a YAML stream at "---" lines
+func splitDocuments(src []byte) []document {
+	var docs []document
+	start, startLine := 0, 1
+	lines := strings.SplitAfter(string(src), "\n")
+	offset := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "---") && strings.TrimSpace(strings.TrimPrefix(line, "---")) == "" {
+			docs = append(docs, document{src[start:offset], startLine})
+			start, startLine = offset+len(line), i+2
+		}
+		offset += len(line)
+	}
+	return append(docs, document{src[start:], startLine})
+}
+
+// targetSelector reads a patch target
+func targetSelector(n *yaml.Node) *selector {
+	return &selector{
+		group:         Scalar(Lookup(n, "group")),
+		version:       Scalar(Lookup(n, "version")),
+		kind:          Scalar(Lookup(n, "kind")),
+		name:          Scalar(Lookup(n, "name")),
+		namespace:     Scalar(Lookup(n, "namespace")),
+		labelSelector: Scalar(Lookup(n, "labelSelector")),
+	}
+}
+
+// matches reports whether o is selected. Names are anchored regular
+// expressions, as in Kustomize.
+func (s *selector) matches(o *Object) bool {
+	group, version := splitAPIVersion(o.APIVersion)
+	switch {
+	case s.group != "" && s.group != group,
+		s.version != "" && s.version != version,
+		s.kind != "" && s.kind != o.Kind,
+		s.namespace != "" && s.namespace != o.Namespace:
+		return false
+	}
+	if s.name != "" && !o.hasName(s.name) {
+		re, err := regexp.Compile("^(?:" + s.name + ")$")
+		if err != nil || !slices.ContainsFunc(append([]string{o.Name}, o.names...), re.MatchString) {
+			return false
+		}
+	}
+	return s.labelSelector == "" || matchLabelSelector(s.labelSelector, o.Labels())
+}
+
+// matchLabelSelector evaluates a selector such as "app=web,tier!=db,canary"
+func matchLabelSelector(selector string, labels map[string]string) bool {
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		switch {
+		case term == "":
+		case strings.Contains(term, "!="):
+			key, value, _ := strings.Cut(term, "!=")
+			if labels[strings.TrimSpace(key)] == strings.TrimSpace(value) {
+				return false
+			}
+		case strings.Contains(term, "="):
+			key, value, _ := strings.Cut(strings.Replace(term, "==", "=", 1), "=")
+			if v, ok := labels[strings.TrimSpace(key)]; !ok || v != strings.TrimSpace(value) {
+				return false
+			}
+		case strings.HasPrefix(term, "!"):
+			if _, ok := labels[term[1:]]; ok {
+				return false
+			}
+		default:
+			if _, ok := labels[term]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// strategicPatch applies a strategic merge patch to the selected objects,
+// or to the object it names when there is no target
+func (l *loader) strategicPatch(patch *yaml.Node, sel *selector, objects []*Object) {
+	if sel == nil {
+		sel = &selector{
+			kind:      Scalar(Lookup(patch, "kind")),
+			name:      Scalar(Lookup(patch, "metadata", "name")),
+			namespace: Scalar(Lookup(patch, "metadata", "namespace")),
+		}
+		if sel.kind == "" || sel.name == "" {
+			l.errorf(l.set.Pos(patch), "patch has no kind and metadata.name to select its target")
+			return
+		}
+	}
+
+	matched := false
+	for _, o := range objects {
+		if !sel.matches(o) {
+			continue
+		}
+		matched = true
+		p := l.copyNode(patch)
+		// A patch with a target may name any object; keep the target's
+		// identity
+		for _, key := range []string{"apiVersion", "kind"} {
+			removeKey(p, key)
+		}
+		if meta := Lookup(p, "metadata"); meta != nil {
+			removeKey(meta, "name")
+			removeKey(meta, "namespace")
+		}
+		l.mergePatch(o.Node, p)
+		o.syncMeta()
+	}
+	if !matched {
+		l.errorf(l.set.Pos(patch), "patch target %s not found", sel)
+	}
+}
+
+func (s *selector) String() string {
+	var parts []string
+	for _, p := range []string{s.kind, s.namespace, s.name} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if s.labelSelector != "" {
+		parts = append(parts, "with labels "+s.labelSelector)
+	}
+	return strings.Join(parts, "/")
+}
+
+// mergeKeys are the keys that identify the items of lists that are merged
+// rather than replaced
+var mergeKeys = map[string]string{
+	"containers":          "name",
+	"initContainers":      "name",
+	"ephemeralContainers": "name",
+	"volumes":             "name",
+	"env":                 "name",
+	"imagePullSecrets":    "name",
+	"volumeMounts":        "mountPath",
+	"volumeDevices":       "devicePath",
+	"hostAliases":         "ip",
+	"ports":               "", // containerPort or port, depending on the items
+}
+
+// mergePatch merges a strategic merge patch into dst
+func (l *loader) mergePatch(dst, patch *yaml.Node) {
+	for _, kv := range Pairs(patch) {
+		key, value := kv[0], kv[1]
+		if strings.HasPrefix(key.Value, "$") {
+			continue
+		}
+		current := Lookup(dst, key.Value)
+		switch {
+		case value.Tag == "!!null":
+			removeKey(dst, key.Value)
+		case current == nil:
+			setKey(dst, key, value)
+		case value.Kind == yaml.MappingNode && current.Kind == yaml.MappingNode:
+			if Scalar(Lookup(value, "$patch")) == "replace" {
+				removeKey(value, "$patch")
+				setKey(dst, keyNode(dst, key.Value), value)
+				continue
+			}
+			l.mergePatch(current, value)
+		case value.Kind == yaml.SequenceNode && current.Kind == yaml.SequenceNode:
+			if mergeKey, ok := mergeKeys[key.Value]; ok {
+				l.mergeList(current, value, mergeKey)
+				continue
+			}
+			setKey(dst, keyNode(dst, key.Value), value)
+		default:
+			setKey(dst, keyNode(dst, key.Value), value)
+		}
+	}
+}
+
+// mergeList merges the items of a patch list into dst by mergeKey
+func (l *loader) mergeList(dst, patch *yaml.Node, mergeKey string) {
+	for _, item := range patch.Content {
+		key := mergeKey
+		if key == "" {
+			key = "port"
+			if Lookup(item, "containerPort") != nil {
+				key = "containerPort"
+			}
+		}
+		id := Scalar(Lookup(item, key))
+		index := -1
+		for i, existing := range dst.Content {
+			if id != "" && Scalar(Lookup(existing, key)) == id {
+				index = i
+				break
+			}
+		}
+
+		switch {
+		case Scalar(Lookup(item, "$patch")) == "delete":
+			if index >= 0 {
+				dst.Content = append(dst.Content[:index], dst.Content[index+1:]...)
+			}
+		case index >= 0:
+			l.mergePatch(dst.Content[index], item)
+		default:
+			dst.Content = append(dst.Content, item)
+		}
+	}
+}
+
+// jsonPatch applies RFC 6902 operations to the selected objects
+func (l *loader) jsonPatch(ops *yaml.Node, sel *selector, objects []*Object) {
+	if ops.Kind != yaml.SequenceNode {
+		l.errorf(l.set.Pos(ops), "a JSON patch must be a list of operations")
+		return
+	}
+	matched := false
+	for _, o := range objects {
+		if !sel.matches(o) {
+			continue
+		}
+		matched = true
+		for _, op := range ops.Content {
+			if err := l.applyOp(o.Node, op); err != nil {
+				l.errorf(l.set.Pos(op), "failed to apply patch to %s: %v", o.ID(), err)
+			}
+		}
+		o.syncMeta()
+	}
+	if !matched {
+		l.errorf(l.set.Pos(ops), "patch target %s not found", sel)
+	}
+}
+
+// applyOp applies a single JSON patch operation
+func (l *loader) applyOp(root, op *yaml.Node) error {
+	name := Scalar(Lookup(op, "op"))
+	path := Scalar(Lookup(op, "path"))
+	value := Lookup(op, "value")
+
+	switch name {
+	case "add", "replace":
+		if value == nil {
+			return fmt.Errorf("%s %s has no value", name, path)
+		}
+		return setPointer(root, path, l.copyNode(value), name == "replace")
+	case "remove":
+		_, err := removePointer(root, path)
+		return err
+	case "move", "copy":
+		from := Scalar(Lookup(op, "from"))
+		var n *yaml.Node
+		var err error
+		if name == "move" {
+			n, err = removePointer(root, from)
+		} else {
+			n, err = getPointer(root, from)
+			if n != nil {
+				n = l.copyNode(n)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return setPointer(root, path, n, false)
+	case "test":
+		n, err := getPointer(root, path)
+		if err != nil {
+			return err
+		}
+		if value != nil && Scalar(n) != Scalar(value) {
+			return fmt.Errorf("test %s failed: %q != %q", path, Scalar(n), Scalar(value))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown operation %q", name)
+}
+
+// splitPointer splits a JSON pointer into unescaped tokens
+func splitPointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// child returns the child of n named by token and its index in a sequence
+func child(n *yaml.Node, token string) (*yaml.Node, int) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return Lookup(n, token), -1
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(n.Content) {
+			return nil, -1
+		}
+		return n.Content[i], i
+	}
+	return nil, -1
+}
+
+// parentOf returns the node holding the last token of path
+func parentOf(root *yaml.Node, path string) (*yaml.Node, string, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tokens) == 0 {
+		return nil, "", fmt.Errorf("cannot patch the document root")
+	}
+	n := root
+	for _, t := range tokens[:len(tokens)-1] {
+		if n, _ = child(n, t); n == nil {
+			return nil, "", fmt.Errorf("path %s not found", path)
+		}
+	}
+	return n, tokens[len(tokens)-1], nil
+}
+
+func getPointer(root *yaml.Node, path string) (*yaml.Node, error) {
+	parent, last, err := parentOf(root, path)
+	if err != nil {
+		return nil, err
+	}
+	n, _ := child(parent, last)
+	if n == nil {
+		return nil, fmt.Errorf("path %s not found", path)
+	}
+	return n, nil
+}
+
+func setPointer(root *yaml.Node, path string, value *yaml.Node, replace bool) error {
+	parent, last, err := parentOf(root, path)
+	if err != nil {
+		return err
+	}
+	switch parent.Kind {
+	case yaml.MappingNode:
+		if replace && Lookup(parent, last) == nil {
+			return fmt.Errorf("path %s not found", path)
+		}
+		key := keyNode(parent, last)
+		if key == nil {
+			key = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last, Line: value.Line}
+		}
+		setKey(parent, key, value)
+		return nil
+	case yaml.SequenceNode:
+		if last == "-" && !replace {
+			parent.Content = append(parent.Content, value)
+			return nil
+		}
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i > len(parent.Content) || (replace && i == len(parent.Content)) {
+			return fmt.Errorf("path %s not found", path)
+		}
+		if replace {
+			parent.Content[i] = value
+			return nil
+		}
+		parent.Content = append(parent.Content[:i], append([]*yaml.Node{value}, parent.Content[i:]...)...)
+		return nil
+	}
+	return fmt.Errorf("path %s not found", path)
+}
+
+func removePointer(root *yaml.Node, path string) (*yaml.Node, error) {
+	parent, last, err := parentOf(root, path)
+	if err != nil {
+		return nil, err
+	}
+	n, i := child(parent, last)
+	if n == nil {
+		return nil, fmt.Errorf("path %s not found", path)
+	}
+	if parent.Kind == yaml.MappingNode {
+		removeKey(parent, last)
+	} else {
+		parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+	}
+	return n, nil
+}
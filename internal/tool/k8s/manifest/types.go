@@ -0,0 +1,172 @@
+// Package manifest loads Kubernetes manifests from disk without a cluster.
+//
+// It reads multi-document YAML files and List objects, rendered Helm output
+// (the "# Source:" comments helm template writes are kept), and Kustomize
+// directories, which are built offline: resources, bases, components,
+// patches, namespace, name prefixes and suffixes, labels, annotations,
+// images and replicas. Every node keeps the file and line it came from, so
+// a field set by an overlay patch is reported in the patch file. Loading
+// continues past errors so a partially broken tree can still be analysed.
+package manifest
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pos is a position in a manifest file
+type Pos struct {
+	File string // path of the file, as given to the loader
+	Line int    // line number, starting at 1; 0 when unknown
+}
+
+func (p Pos) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// Set is the result of loading manifests
+type Set struct {
+	// Objects holds the loaded objects in load order
+	Objects []*Object
+	// Warnings holds inputs that were skipped or only partly understood,
+	// such as unrendered Helm charts and Kustomize generators
+	Warnings []*Error
+
+	files map[*yaml.Node]string
+}
+
+// Pos returns the position of a node of one of the set's objects
+func (s *Set) Pos(n *yaml.Node) Pos {
+	if n == nil {
+		return Pos{}
+	}
+	return Pos{File: s.files[n], Line: n.Line}
+}
+
+// Object is a Kubernetes object
+type Object struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string // "" when the manifest does not set one
+	// Source is the Helm template that rendered the object, if known
+	Source string
+	// Node is the object's mapping node
+	Node *yaml.Node
+	Pos  Pos
+
+	// names holds earlier names of the object, before Kustomize prefixes
+	// and suffixes, so overlay patches can still target them
+	names []string
+}
+
+// ID identifies the object for messages, e.g. Deployment/shop/api
+func (o *Object) ID() string {
+	if o.Namespace == "" {
+		return o.Kind + "/" + o.Name
+	}
+	return o.Kind + "/" + o.Namespace + "/" + o.Name
+}
+
+// Group returns the API group of the object; "" for the core group
+func (o *Object) Group() string {
+	group, _ := splitAPIVersion(o.APIVersion)
+	return group
+}
+
+// Get returns the node at a path of mapping keys, or nil
+func (o *Object) Get(keys ...string) *yaml.Node {
+	return Lookup(o.Node, keys...)
+}
+
+// Labels returns the object's metadata labels
+func (o *Object) Labels() map[string]string {
+	return StringMap(o.Get("metadata", "labels"))
+}
+
+// syncMeta re-reads the identifying fields after the node changed
+func (o *Object) syncMeta() {
+	o.APIVersion = Scalar(Lookup(o.Node, "apiVersion"))
+	o.Kind = Scalar(Lookup(o.Node, "kind"))
+	o.Name = Scalar(Lookup(o.Node, "metadata", "name"))
+	o.Namespace = Scalar(Lookup(o.Node, "metadata", "namespace"))
+}
+
+// splitAPIVersion splits apps/v1 into its group and version
+func splitAPIVersion(apiVersion string) (string, string) {
+	for i := len(apiVersion) - 1; i >= 0; i-- {
+		if apiVersion[i] == '/' {
+			return apiVersion[:i], apiVersion[i+1:]
+		}
+	}
+	return "", apiVersion
+}
+
+// clusterScoped lists the built-in kinds without a namespace
+var clusterScoped = map[string]bool{
+	"APIService":                     true,
+	"CertificateSigningRequest":      true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CSIDriver":                      true,
+	"CSINode":                        true,
+	"CustomResourceDefinition":       true,
+	"IngressClass":                   true,
+	"MutatingWebhookConfiguration":   true,
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"PodSecurityPolicy":              true,
+	"PriorityClass":                  true,
+	"RuntimeClass":                   true,
+	"StorageClass":                   true,
+	"ValidatingWebhookConfiguration": true,
+	"VolumeAttachment":               true,
+}
+
+// ClusterScoped reports whether objects of a built-in kind have no namespace
+func ClusterScoped(kind string) bool {
+	return clusterScoped[kind]
+}
+
+// Error is a problem at a position in a manifest
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is a list of load errors
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns l sorted by position as an error, or nil when it is empty
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].Pos.File != l[j].Pos.File {
+			return l[i].Pos.File < l[j].Pos.File
+		}
+		return l[i].Pos.Line < l[j].Pos.Line
+	})
+	return l
+}
@@ -0,0 +1,209 @@
+// Package k8s provides Kubernetes tools for the Assistant.
+// It analyzes manifests, Kustomize overlays and rendered Helm output from
+// disk without cluster access.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// KubernetesTool implements the Tool interface for Kubernetes manifests
+type KubernetesTool struct {
+	config config.Kubernetes
+	logger *slog.Logger
+}
+
+// NewKubernetesTool creates a new Kubernetes tool instance. It works
+// offline; cfg.Namespace is assumed for objects that do not set one.
+func NewKubernetesTool(cfg config.Kubernetes, logger *slog.Logger) *KubernetesTool {
+	return &KubernetesTool{
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Name returns the tool name
+func (t *KubernetesTool) Name() string {
+	return "kubernetes"
+}
+
+// Description returns the tool description
+func (t *KubernetesTool) Description() string {
+	return "Offline Kubernetes manifest analysis for YAML files, Kustomize overlays and rendered Helm output"
+}
+
+// Parameters returns the tool parameter schema
+func (t *KubernetesTool) Parameters() *tool.ToolParametersSchema {
+	return &tool.ToolParametersSchema{
+		Type: "object",
+		Properties: map[string]tool.ParameterProperty{
+			"action": {
+				Type:        tool.ParameterTypeString,
+				Description: "The Kubernetes action to perform",
+				Enum: []string{
+					"analyze_manifests",
+					"list_resources",
+				},
+			},
+			"path": {
+				Type:        tool.ParameterTypeString,
+				Description: "Manifest file, kustomization directory or directory tree; defaults to the current directory",
+			},
+			"content": {
+				Type:        tool.ParameterTypeString,
+				Description: "Manifest content such as helm template output, used instead of path",
+			},
+			"options": {
+				Type:        tool.ParameterTypeObject,
+				Description: "Additional options: kube_version (target cluster version, e.g. 1.29) and namespace (assumed for objects without one)",
+			},
+		},
+		Required: []string{"action"},
+	}
+}
+
+// Execute runs the Kubernetes tool with the given parameters
+func (t *KubernetesTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.ToolResult, error) {
+	startTime := time.Now()
+
+	params := input.Parameters
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+
+	action, ok := params["action"].(string)
+	if !ok {
+		return &tool.ToolResult{
+			Success: false,
+			Error:   "action parameter is required",
+		}, nil
+	}
+
+	t.logger.Info("Executing Kubernetes action",
+		slog.String("action", action))
+
+	var result interface{}
+	var err error
+
+	switch action {
+	case "analyze_manifests":
+		result, err = t.analyzeManifests(ctx, params)
+	case "list_resources":
+		result, err = t.listResources(ctx, params)
+	default:
+		return &tool.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("unknown action: %s", action),
+		}, nil
+	}
+
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         err.Error(),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+
+	// Convert result to map[string]interface{} for output
+	var outputMap map[string]interface{}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         fmt.Sprintf("failed to marshal result: %v", err),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+	if err := json.Unmarshal(resultJSON, &outputMap); err != nil {
+		outputMap = map[string]interface{}{
+			"result": result,
+		}
+	}
+
+	return &tool.ToolResult{
+		Success: true,
+		Data: &tool.ToolResultData{
+			Output: outputMap,
+		},
+		ExecutionTime: time.Since(startTime),
+	}, nil
+}
+
+// analyzeManifests checks manifests for missing resources, probes and
+// security settings, selector mismatches, PDB coverage and removed APIs
+func (t *KubernetesTool) analyzeManifests(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	options := extractOptions(params)
+	opts := AnalyzeOptions{
+		KubeVersion: optionString(options, "kube_version"),
+		Namespace:   optionString(options, "namespace"),
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = t.config.Namespace
+	}
+
+	analyzer := NewManifestAnalyzer(t.logger)
+	var result *AnalysisResult
+	var err error
+	if content, ok := params["content"].(string); ok && content != "" {
+		result, err = analyzer.Analyze("manifest.yaml", []byte(content), opts)
+	} else {
+		result, err = analyzer.AnalyzeFile(manifestPath(params), opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze manifests: %w", err)
+	}
+
+	return result, nil
+}
+
+// listResources lists the objects the manifests define
+func (t *KubernetesTool) listResources(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	result, err := NewManifestAnalyzer(t.logger).Load(manifestPath(params))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+	return result, nil
+}
+
+// manifestPath returns the path parameter, defaulting to the current
+// directory
+func manifestPath(params map[string]interface{}) string {
+	if path, ok := params["path"].(string); ok && path != "" {
+		return path
+	}
+	return "."
+}
+
+// extractOptions extracts options from parameters
+func extractOptions(params map[string]interface{}) map[string]interface{} {
+	if options, ok := params["options"].(map[string]interface{}); ok {
+		return options
+	}
+	return make(map[string]interface{})
+}
+
+// optionString reads a string option. Versions must be strings, as the
+// number 1.30 would read as 1.3.
+func optionString(options map[string]interface{}, key string) string {
+	s, _ := options[key].(string)
+	return s
+}
+
+// Health reports the tool as healthy; it needs no cluster
+func (t *KubernetesTool) Health(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the Kubernetes tool
+func (t *KubernetesTool) Close(ctx context.Context) error {
+	t.logger.Debug("Kubernetes tool closed")
+	return nil
+}
@@ -9,16 +9,31 @@ import (
 	"github.com/tmc/langchaingo/tools"
 )
 
-// LangChainToolAdapter adapts internal tools to LangChain's tool interface
+// ResultHook is called with the result of every tool call an adapter
+// makes, e.g. to move the result's artifacts into the artifact store
+type ResultHook func(ctx context.Context, name string, toolContext *ToolContext, result *ToolResult)
+
+// LangChainToolAdapter adapts internal tools to LangChain's tool interface.
+// Calls execute through the registry for the caller in its tool context,
+// so they are configured, counted and refused like any other execution.
 type LangChainToolAdapter struct {
-	internalTool Tool
+	registry     *Registry
+	internalTool Tool // describes the tool; calls go through registry
+	toolContext  *ToolContext
+	config       *ToolConfig
+	onResult     ResultHook
 	logger       *slog.Logger
 }
 
-// NewLangChainToolAdapter creates a new adapter for an internal tool
-func NewLangChainToolAdapter(internalTool Tool, logger *slog.Logger) *LangChainToolAdapter {
+// NewLangChainToolAdapter creates a new adapter for an internal tool of
+// registry, calling it for toolContext with config. onResult may be nil.
+func NewLangChainToolAdapter(registry *Registry, internalTool Tool, toolContext *ToolContext, config *ToolConfig, onResult ResultHook, logger *slog.Logger) *LangChainToolAdapter {
 	return &LangChainToolAdapter{
+		registry:     registry,
 		internalTool: internalTool,
+		toolContext:  toolContext,
+		config:       config,
+		onResult:     onResult,
 		logger:       logger,
 	}
 }
@@ -33,6 +48,12 @@ func (a *LangChainToolAdapter) Description() string {
 	return a.internalTool.Description()
 }
 
+// Parameters returns the parameters schema of the internal tool, so
+// agents can tell the LLM how to form the input
+func (a *LangChainToolAdapter) Parameters() *ToolParametersSchema {
+	return a.internalTool.Parameters()
+}
+
 // Call executes the tool with string input (implements tools.Tool interface)
 func (a *LangChainToolAdapter) Call(ctx context.Context, input string) (string, error) {
 	a.logger.Debug("LangChain tool adapter call",
@@ -48,13 +69,17 @@ func (a *LangChainToolAdapter) Call(ctx context.Context, input string) (string,
 	// Convert map to ToolInput
 	toolInput := &ToolInput{
 		Parameters: inputMap,
+		Context:    a.toolContext,
 	}
 
-	// Execute internal tool
-	result, err := a.internalTool.Execute(ctx, toolInput)
+	// Execute through the registry
+	result, err := a.registry.Execute(ctx, a.Name(), toolInput, a.config)
 	if err != nil {
 		return "", fmt.Errorf("tool execution failed: %w", err)
 	}
+	if a.onResult != nil {
+		a.onResult(ctx, a.Name(), a.toolContext, result)
+	}
 
 	// Convert result to string
 	output, err := a.formatResult(result)
@@ -115,10 +140,12 @@ func (a *LangChainToolAdapter) formatResult(result *ToolResult) (string, error)
 	return "Operation completed successfully", nil
 }
 
-// ToolRegistry manages LangChain tool adapters
+// ToolRegistry creates LangChain tool adapters for the tools of a
+// registry. Adapters are created per caller, who the tool context and
+// policy given for them belong to, and are not shared.
 type ToolRegistry struct {
 	internalRegistry *Registry
-	adapters         map[string]*LangChainToolAdapter
+	onResult         ResultHook
 	logger           *slog.Logger
 }
 
@@ -126,44 +153,45 @@ type ToolRegistry struct {
 func NewToolRegistry(internalRegistry *Registry, logger *slog.Logger) *ToolRegistry {
 	return &ToolRegistry{
 		internalRegistry: internalRegistry,
-		adapters:         make(map[string]*LangChainToolAdapter),
 		logger:           logger,
 	}
 }
 
-// GetLangChainTool returns a LangChain-compatible tool adapter
-func (tr *ToolRegistry) GetLangChainTool(name string, config map[string]interface{}) (tools.Tool, error) {
-	// Check if adapter already exists
-	if adapter, exists := tr.adapters[name]; exists {
-		return adapter, nil
-	}
+// OnResult calls hook with the result of every call of the adapters
+// created afterwards
+func (tr *ToolRegistry) OnResult(hook ResultHook) {
+	tr.onResult = hook
+}
 
-	// Convert legacy config to ToolConfig
-	toolConfig := ConvertLegacyConfig(config)
+// GetLangChainTool returns a LangChain-compatible tool adapter calling the
+// tool for toolContext, configured by policy. Tools the policy disables
+// and quarantined tools are refused. A nil policy enables every tool.
+func (tr *ToolRegistry) GetLangChainTool(name string, toolContext *ToolContext, policy *Policy) (tools.Tool, error) {
+	if !policy.Enabled(name) {
+		return nil, NewToolPermissionDeniedError(name, "execute", "tool settings")
+	}
+	if reason, quarantined := tr.internalRegistry.Quarantined(name); quarantined {
+		return nil, NewToolQuarantinedError(name, reason)
+	}
 
-	// Get internal tool
-	internalTool, err := tr.internalRegistry.GetTool(name, toolConfig)
+	// Instances are shared by every caller; the caller's config is
+	// applied per call
+	internalTool, err := tr.internalRegistry.GetTool(name, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get internal tool: %w", err)
 	}
 
-	// Create adapter
-	adapter := NewLangChainToolAdapter(internalTool, tr.logger)
-	tr.adapters[name] = adapter
-
-	tr.logger.Debug("Created LangChain tool adapter",
-		slog.String("tool", name))
-
-	return adapter, nil
+	return NewLangChainToolAdapter(tr.internalRegistry, internalTool, toolContext, policy.Config(name), tr.onResult, tr.logger), nil
 }
 
-// GetAllLangChainTools returns all available tools as LangChain adapters
-func (tr *ToolRegistry) GetAllLangChainTools(config map[string]interface{}) ([]tools.Tool, error) {
-	toolInfos := tr.internalRegistry.ListTools()
+// GetAllLangChainTools returns the tools policy enables as LangChain
+// adapters
+func (tr *ToolRegistry) GetAllLangChainTools(toolContext *ToolContext, policy *Policy) ([]tools.Tool, error) {
+	toolInfos := policy.Filter(tr.internalRegistry.ListTools())
 	langchainTools := make([]tools.Tool, 0, len(toolInfos))
 
 	for _, toolInfo := range toolInfos {
-		adapter, err := tr.GetLangChainTool(toolInfo.Name, config)
+		adapter, err := tr.GetLangChainTool(toolInfo.Name, toolContext, policy)
 		if err != nil {
 			tr.logger.Warn("Failed to create adapter for tool",
 				slog.String("tool", toolInfo.Name),
@@ -176,29 +204,30 @@ func (tr *ToolRegistry) GetAllLangChainTools(config map[string]interface{}) ([]t
 	return langchainTools, nil
 }
 
-// CreateToolsForAgent creates LangChain tools for a specific agent
-func (tr *ToolRegistry) CreateToolsForAgent(agentType string, config map[string]interface{}) ([]tools.Tool, error) {
+// CreateToolsForAgent creates LangChain tools for a specific agent, of
+// those policy enables
+func (tr *ToolRegistry) CreateToolsForAgent(agentType string, toolContext *ToolContext, policy *Policy) ([]tools.Tool, error) {
 	var toolNames []string
 
 	// Define tools available for each agent type
 	switch agentType {
 	case "development":
-		toolNames = []string{"godev", "search"}
+		toolNames = []string{"godev", "web_search"}
 	case "database":
-		toolNames = []string{"postgres", "search"}
+		toolNames = []string{"postgres", "web_search"}
 	case "infrastructure":
-		toolNames = []string{"k8s", "docker", "search"}
+		toolNames = []string{"kubernetes", "docker", "web_search"}
 	case "research":
-		toolNames = []string{"search", "cloudflare"}
+		toolNames = []string{"web_search", "cloudflare"}
 	default:
 		// For unknown agent types, provide basic tools
-		toolNames = []string{"search"}
+		toolNames = []string{"web_search"}
 	}
 
 	var langchainTools []tools.Tool
 	for _, toolName := range toolNames {
-		if tr.internalRegistry.IsRegistered(toolName) {
-			adapter, err := tr.GetLangChainTool(toolName, config)
+		if tr.internalRegistry.IsRegistered(toolName) && policy.Enabled(toolName) {
+			adapter, err := tr.GetLangChainTool(toolName, toolContext, policy)
 			if err != nil {
 				tr.logger.Warn("Failed to create tool adapter for agent",
 					slog.String("agent_type", agentType),
@@ -217,32 +246,9 @@ func (tr *ToolRegistry) CreateToolsForAgent(agentType string, config map[string]
 	return langchainTools, nil
 }
 
-// Health checks the health of all tool adapters
+// Health checks the health of the registry's tools
 func (tr *ToolRegistry) Health(ctx context.Context) error {
-	for name, adapter := range tr.adapters {
-		// Check internal tool health
-		if err := adapter.internalTool.Health(ctx); err != nil {
-			return fmt.Errorf("tool adapter %s health check failed: %w", name, err)
-		}
-	}
-	return nil
-}
-
-// Close closes all tool adapters
-func (tr *ToolRegistry) Close(ctx context.Context) error {
-	var lastErr error
-	for name, adapter := range tr.adapters {
-		if err := adapter.internalTool.Close(ctx); err != nil {
-			tr.logger.Error("Failed to close tool adapter",
-				slog.String("tool", name),
-				slog.Any("error", err))
-			lastErr = err
-		}
-	}
-
-	// Clear adapters
-	tr.adapters = make(map[string]*LangChainToolAdapter)
-	return lastErr
+	return tr.internalRegistry.Health(ctx)
 }
 
 // StringInputTool is a helper interface for tools that can handle string inputs directly
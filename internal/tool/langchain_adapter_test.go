@@ -0,0 +1,80 @@
+package tool
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// TestToolRegistry_ScopedAdapters tests that adapters execute through the
+// registry for their caller, with the caller's settings
+func TestToolRegistry_ScopedAdapters(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	flaky := &flakyTool{testTool: testTool{name: "postgres"}, failures: 1}
+	registry := NewRegistry(logger)
+	_ = registry.Register("postgres", func(*ToolConfig, *slog.Logger) (Tool, error) { return flaky, nil })
+
+	var results []string
+	adapters := NewToolRegistry(registry, logger)
+	adapters.OnResult(func(ctx context.Context, name string, toolContext *ToolContext, result *ToolResult) {
+		results = append(results, name+":"+toolContext.UserID)
+	})
+	ctx := context.Background()
+
+	alice := NewPolicy(nil, ToolSettings{"postgres": {
+		MaxRetries: 1,
+		Custom:     map[string]interface{}{"connection": "analytics"},
+	}})
+	aliceTool, err := adapters.GetLangChainTool("postgres", &ToolContext{UserID: "alice", ConversationID: "c1"}, alice)
+	if err != nil {
+		t.Fatalf("GetLangChainTool() error = %v", err)
+	}
+	if _, err := aliceTool.Call(ctx, `{"query": "SELECT 1"}`); err != nil {
+		t.Fatalf("Call() error = %v, want the failure retried", err)
+	}
+	if flaky.attempts != 2 {
+		t.Errorf("attempts = %d, want a retry from the settings", flaky.attempts)
+	}
+	if got := flaky.lastInput.Parameters["connection"]; got != "analytics" {
+		t.Errorf("connection = %v, want the pinned one", got)
+	}
+	if c := flaky.lastInput.Context; c == nil || c.UserID != "alice" || c.ConversationID != "c1" {
+		t.Errorf("input context = %+v, want alice's conversation", c)
+	}
+
+	// Another caller gets their own settings, not the first caller's
+	bobTool, err := adapters.GetLangChainTool("postgres", &ToolContext{UserID: "bob"}, nil)
+	if err != nil {
+		t.Fatalf("GetLangChainTool() error = %v", err)
+	}
+	if _, err := bobTool.Call(ctx, `{"query": "SELECT 1"}`); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if _, pinned := flaky.lastInput.Parameters["connection"]; pinned || flaky.lastInput.Context.UserID != "bob" {
+		t.Errorf("input = %+v, want bob's call without alice's settings", flaky.lastInput)
+	}
+
+	if stats, _ := registry.Stats(ctx); stats.ExecutionStats["postgres"].TotalExecutions != 2 {
+		t.Errorf("executions = %d, want both calls counted", stats.ExecutionStats["postgres"].TotalExecutions)
+	}
+	if len(results) != 2 || results[0] != "postgres:alice" || results[1] != "postgres:bob" {
+		t.Errorf("results = %q, want one per call", results)
+	}
+
+	// Disabled and quarantined tools are refused
+	disabled := NewPolicy(nil, ToolSettings{"postgres": {Enabled: boolPtr(false)}})
+	if _, err := adapters.GetLangChainTool("postgres", &ToolContext{UserID: "carol"}, disabled); err == nil {
+		t.Error("GetLangChainTool() of a disabled tool succeeded")
+	}
+	if tools, _ := adapters.CreateToolsForAgent("database", &ToolContext{UserID: "carol"}, disabled); len(tools) != 0 {
+		t.Errorf("CreateToolsForAgent() = %d tools, want the disabled tool left out", len(tools))
+	}
+	_ = registry.Quarantine("postgres", "unhealthy")
+	if _, err := aliceTool.Call(ctx, `{"query": "SELECT 1"}`); err == nil {
+		t.Error("Call() of a quarantined tool succeeded")
+	}
+	if _, err := adapters.GetLangChainTool("postgres", &ToolContext{UserID: "alice"}, alice); err == nil {
+		t.Error("GetLangChainTool() of a quarantined tool succeeded")
+	}
+}
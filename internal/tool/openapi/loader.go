@@ -0,0 +1,95 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// maxSpecBytes caps the size of a specification
+const maxSpecBytes = 16 << 20
+
+// isURL reports whether source is an http(s) URL rather than a file path
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Load reads and parses a specification from a file path or URL
+func Load(ctx context.Context, client *http.Client, source string) (*Document, error) {
+	data, err := read(ctx, client, source)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return doc, nil
+}
+
+// read returns the raw specification
+func read(ctx context.Context, client *http.Client, source string) ([]byte, error) {
+	if !isURL(source) {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenAPI spec: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spec request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, application/yaml;q=0.9, */*;q=0.5")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI spec: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching OpenAPI spec %s returned %d", source, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI spec: %w", err)
+	}
+	return data, nil
+}
+
+// serverURL picks the base URL for requests: the configured override, or
+// the first server of the operation or document with its variables set
+// to their defaults. Relative server URLs resolve against the spec URL.
+func serverURL(override, source string, servers []Server) (string, error) {
+	if override != "" {
+		return strings.TrimRight(override, "/"), nil
+	}
+	if len(servers) == 0 {
+		return "", fmt.Errorf("the spec declares no servers; set base_url")
+	}
+
+	s := servers[0]
+	raw := s.URL
+	for name, v := range s.Variables {
+		raw = strings.ReplaceAll(raw, "{"+name+"}", v.Default)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q: %w", s.URL, err)
+	}
+	if !u.IsAbs() {
+		if !isURL(source) {
+			return "", fmt.Errorf("server URL %q is relative; set base_url", s.URL)
+		}
+		base, err := url.Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("invalid spec URL %q: %w", source, err)
+		}
+		u = base.ResolveReference(u)
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
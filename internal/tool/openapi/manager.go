@@ -0,0 +1,240 @@
+// Package openapi generates tools from OpenAPI 3 specifications.
+// Each selected operation of a spec becomes a tool whose parameters are
+// the operation's parameters and request body; specs can be reloaded at
+// runtime without restarting the assistant.
+package openapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// ErrUnknownSpec is returned when reloading a spec that is not configured
+var ErrUnknownSpec = errors.New("openapi spec is not configured")
+
+// Manager loads specifications and keeps their tools registered
+type Manager struct {
+	specs    []config.OpenAPISpec
+	registry *tool.Registry
+	client   *http.Client
+	logger   *slog.Logger
+
+	mu         sync.Mutex
+	registered map[string][]string // spec name -> tool names
+}
+
+// NewManager creates a manager for the configured specifications
+func NewManager(specs []config.OpenAPISpec, registry *tool.Registry, logger *slog.Logger) *Manager {
+	return &Manager{
+		specs:      specs,
+		registry:   registry,
+		client:     &http.Client{},
+		logger:     logger,
+		registered: make(map[string][]string),
+	}
+}
+
+// Reload loads the named spec, or every spec when name is empty, and swaps
+// its tools in the registry. A spec that fails to load keeps the tools of
+// its last successful load; failures are joined into the returned error.
+func (m *Manager) Reload(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	found := false
+	seen := make(map[string]bool)
+	for _, spec := range m.specs {
+		if name != "" && spec.Name != name {
+			continue
+		}
+		found = true
+		if seen[spec.Name] {
+			errs = append(errs, fmt.Errorf("openapi spec %q is configured twice", spec.Name))
+			continue
+		}
+		seen[spec.Name] = true
+
+		tools, err := m.build(ctx, spec)
+		if err != nil {
+			m.logger.Warn("Failed to load OpenAPI spec",
+				slog.String("spec", spec.Name),
+				slog.Any("error", err))
+			errs = append(errs, fmt.Errorf("openapi spec %q: %w", spec.Name, err))
+			continue
+		}
+		m.swap(spec.Name, tools)
+	}
+	if name != "" && !found {
+		return fmt.Errorf("%w: %s", ErrUnknownSpec, name)
+	}
+	return errors.Join(errs...)
+}
+
+// Tools returns the registered tool names by spec
+func (m *Manager) Tools() map[string][]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tools := make(map[string][]string, len(m.registered))
+	for spec, names := range m.registered {
+		tools[spec] = append([]string(nil), names...)
+	}
+	return tools
+}
+
+// build loads a spec and creates the tools for its selected operations
+func (m *Manager) build(ctx context.Context, spec config.OpenAPISpec) ([]*OperationTool, error) {
+	if spec.Name == "" || spec.Source == "" {
+		return nil, fmt.Errorf("name and source are required")
+	}
+
+	doc, err := Load(ctx, m.client, spec.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	spec = expandCredentials(spec)
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var tools []*OperationTool
+	names := make(map[string]int)
+	for _, path := range sortedKeys(doc.Paths) {
+		item := doc.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, entry := range item.operations() {
+			if !selected(spec, entry.op) {
+				continue
+			}
+
+			servers := doc.Servers
+			if len(entry.op.Servers) > 0 {
+				servers = entry.op.Servers
+			}
+			baseURL, err := serverURL(spec.BaseURL, spec.Source, servers)
+			if err != nil {
+				return nil, err
+			}
+
+			name := toolName(spec.Name, entry.method, path, entry.op.OperationID)
+			if n := names[name]; n > 0 {
+				name = fmt.Sprintf("%s_%d", name, n+1)
+			}
+			names[name]++
+
+			t, err := newOperationTool(name, operationSpec{
+				doc:     doc,
+				method:  entry.method,
+				path:    path,
+				item:    item,
+				op:      entry.op,
+				baseURL: baseURL,
+			}, spec, client, m.logger)
+			if err != nil {
+				// One unsupported operation should not hide the others
+				m.logger.Warn("Skipping OpenAPI operation",
+					slog.String("spec", spec.Name),
+					slog.String("operation", entry.method+" "+path),
+					slog.Any("error", err))
+				continue
+			}
+			tools = append(tools, t)
+		}
+	}
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("no operations selected")
+	}
+	return tools, nil
+}
+
+// swap replaces the registered tools of a spec
+func (m *Manager) swap(specName string, tools []*OperationTool) {
+	for _, name := range m.registered[specName] {
+		if err := m.registry.Unregister(name); err != nil {
+			m.logger.Warn("Failed to unregister OpenAPI tool",
+				slog.String("tool", name),
+				slog.Any("error", err))
+		}
+	}
+
+	var names []string
+	for _, t := range tools {
+		factory := func(cfg *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
+			return t, nil
+		}
+		if err := m.registry.Register(t.Name(), factory); err != nil {
+			m.logger.Warn("Failed to register OpenAPI tool",
+				slog.String("tool", t.Name()),
+				slog.Any("error", err))
+			continue
+		}
+		names = append(names, t.Name())
+	}
+	sort.Strings(names)
+	m.registered[specName] = names
+
+	m.logger.Info("OpenAPI tools registered",
+		slog.String("spec", specName),
+		slog.Int("count", len(names)))
+}
+
+// selected reports whether the spec's filters include an operation
+func selected(spec config.OpenAPISpec, op *Operation) bool {
+	for _, id := range spec.Exclude {
+		if id == op.OperationID {
+			return false
+		}
+	}
+	if len(spec.Operations) == 0 && len(spec.Tags) == 0 {
+		return true
+	}
+	for _, id := range spec.Operations {
+		if id == op.OperationID {
+			return true
+		}
+	}
+	for _, want := range spec.Tags {
+		for _, tag := range op.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandCredentials substitutes ${VAR} references in credentials and
+// headers so secrets can stay out of configuration files
+func expandCredentials(spec config.OpenAPISpec) config.OpenAPISpec {
+	creds := make(map[string]config.OpenAPICredential, len(spec.Credentials))
+	for name, c := range spec.Credentials {
+		creds[name] = config.OpenAPICredential{
+			Token:    os.ExpandEnv(c.Token),
+			Username: os.ExpandEnv(c.Username),
+			Password: os.ExpandEnv(c.Password),
+		}
+	}
+	headers := make(map[string]string, len(spec.Headers))
+	for k, v := range spec.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	spec.Credentials = creds
+	spec.Headers = headers
+	return spec
+}
@@ -0,0 +1,361 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// recorded is a request received by the API stand-in
+type recorded struct {
+	method, path, query, body string
+	header                    http.Header
+}
+
+// petstore starts an API stand-in that records requests
+func petstore(t *testing.T) (*httptest.Server, func() recorded) {
+	t.Helper()
+	var mu sync.Mutex
+	var last recorded
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		last = recorded{r.Method, r.URL.Path, r.URL.RawQuery, string(body), r.Header.Clone()}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/pets/404":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "pet not found"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/pets":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 7, "name": "Rex"}`))
+		default:
+			w.Write([]byte(`[{"id": 1, "name": "Tom"}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() recorded {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+func petstoreSpec(baseURL string) config.OpenAPISpec {
+	return config.OpenAPISpec{
+		Name:    "petstore",
+		Source:  filepath.Join("testdata", "petstore.yaml"),
+		BaseURL: baseURL,
+		Credentials: map[string]config.OpenAPICredential{
+			"bearerAuth": {Token: "${PETSTORE_TOKEN}"},
+		},
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	registry := tool.NewRegistry(discardLogger())
+	spec := petstoreSpec("http://localhost")
+	manager := NewManager([]config.OpenAPISpec{spec}, registry, discardLogger())
+	if err := manager.Reload(context.Background(), ""); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	// The multipart upload is skipped, the rest are registered
+	want := []string{
+		"petstore_create_pet", "petstore_delete_pets_pet_id", "petstore_get_pet_by_id",
+		"petstore_list_pets", "petstore_login", "petstore_set_notes",
+	}
+	if got := manager.Tools()["petstore"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("tools = %v, want %v", got, want)
+	}
+	for _, name := range want {
+		if !registry.IsRegistered(name) {
+			t.Errorf("%s is not registered", name)
+		}
+	}
+
+	// Filters narrow the tools on reload
+	spec.Tags = []string{"pets"}
+	spec.Exclude = []string{"setNotes"}
+	manager.specs = []config.OpenAPISpec{spec}
+	if err := manager.Reload(context.Background(), "petstore"); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	want = []string{"petstore_create_pet", "petstore_get_pet_by_id", "petstore_list_pets"}
+	if got := manager.Tools()["petstore"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("tools = %v, want %v", got, want)
+	}
+	if registry.IsRegistered("petstore_login") {
+		t.Error("filtered tool is still registered")
+	}
+
+	if err := manager.Reload(context.Background(), "billing"); !errors.Is(err, ErrUnknownSpec) {
+		t.Errorf("Reload(billing) error = %v, want ErrUnknownSpec", err)
+	}
+}
+
+func TestManager_ReloadFromURL(t *testing.T) {
+	var mu sync.Mutex
+	spec := `openapi: "3.1.0"
+info: {title: Status, version: "1"}
+servers: [{url: /api}]
+paths:
+  /status: {get: {operationId: getStatus, responses: {"200": {description: ok}}}}
+  /version: {get: {operationId: getVersion, responses: {"200": {description: ok}}}}
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/openapi.yaml":
+			if spec == "" {
+				http.Error(w, "down", http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(spec))
+		case "/api/status":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok": true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	setSpec := func(s string) {
+		mu.Lock()
+		spec = s
+		mu.Unlock()
+	}
+
+	registry := tool.NewRegistry(discardLogger())
+	manager := NewManager([]config.OpenAPISpec{{Name: "status", Source: server.URL + "/openapi.yaml"}}, registry, discardLogger())
+	if err := manager.Reload(context.Background(), ""); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	// The relative server URL resolves against the spec URL
+	result, err := registry.Execute(context.Background(), "status_get_status", &tool.ToolInput{}, nil)
+	if err != nil || !result.Success || !reflect.DeepEqual(result.Data.Result, map[string]interface{}{"ok": true}) {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+
+	setSpec(strings.Replace(spec, "  /version: {get: {operationId: getVersion, responses: {\"200\": {description: ok}}}}\n", "", 1))
+	if err := manager.Reload(context.Background(), "status"); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if registry.IsRegistered("status_get_version") || !registry.IsRegistered("status_get_status") {
+		t.Errorf("tools after reload = %v", manager.Tools())
+	}
+
+	// A failed reload keeps the tools of the last good load
+	setSpec("")
+	if err := manager.Reload(context.Background(), "status"); err == nil {
+		t.Error("Reload() succeeded for an unavailable spec")
+	}
+	if !registry.IsRegistered("status_get_status") {
+		t.Error("failed reload dropped the registered tools")
+	}
+}
+
+func TestOperationTool_Parameters(t *testing.T) {
+	doc, err := Load(context.Background(), nil, filepath.Join("testdata", "petstore.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	build := func(method, path string) *OperationTool {
+		t.Helper()
+		item := doc.Paths[path]
+		for _, entry := range item.operations() {
+			if entry.method == method {
+				op, err := newOperationTool("op", operationSpec{doc: doc, method: method, path: path, item: item, op: entry.op}, config.OpenAPISpec{}, nil, discardLogger())
+				if err != nil {
+					t.Fatalf("newOperationTool() error = %v", err)
+				}
+				return op
+			}
+		}
+		t.Fatalf("no %s %s", method, path)
+		return nil
+	}
+
+	list := build("GET", "/pets").Parameters()
+	limit := list.Properties["limit"]
+	if limit.Type != "integer" || limit.Default != float64(20) || *limit.Minimum != 1 || *limit.Maximum != 100 || limit.Description != "Maximum number of pets" {
+		t.Errorf("limit = %+v", limit)
+	}
+	if tag := list.Properties["tag"]; tag.Type != "array" || tag.Description != "(array of string)" {
+		t.Errorf("tag = %+v", tag)
+	}
+
+	create := build("POST", "/pets")
+	params := create.Parameters()
+	var names []string
+	for name := range params.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if want := []string{"X-Request-ID", "name", "owner", "status", "tags"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("parameters = %v, want %v", names, want)
+	}
+	if !reflect.DeepEqual(params.Required, []string{"name"}) {
+		t.Errorf("required = %v", params.Required)
+	}
+	if owner := params.Properties["owner"]; owner.Type != "object" || owner.Description != "(fields: email (string, required))" {
+		t.Errorf("owner = %+v", owner)
+	}
+	if status := params.Properties["status"]; !reflect.DeepEqual(status.Enum, []string{"available", "sold"}) {
+		t.Errorf("status = %+v", status)
+	}
+	if tags := params.Properties["tags"]; tags.Type != "array" {
+		t.Errorf("tags = %+v, want the non-null 3.1 type", tags)
+	}
+	if create.Description() != "Create a pet. [POST /pets]" {
+		t.Errorf("description = %q", create.Description())
+	}
+
+	get := build("GET", "/pets/{petId}").Parameters()
+	if get.Properties["petId"].Description != "The pet to operate on" || !reflect.DeepEqual(get.Required, []string{"petId"}) {
+		t.Errorf("petId = %+v, required %v", get.Properties["petId"], get.Required)
+	}
+	if notes := build("PUT", "/pets/{petId}/notes").Parameters(); notes.Properties["body"].Type != "string" {
+		t.Errorf("notes = %+v", notes.Properties)
+	}
+	if d := build("DELETE", "/pets/{petId}").Description(); d != "Delete a pet. Deprecated. [DELETE /pets/{petId}]" {
+		t.Errorf("description = %q", d)
+	}
+}
+
+func TestOperationTool_Execute(t *testing.T) {
+	t.Setenv("PETSTORE_TOKEN", "s3cret")
+	server, last := petstore(t)
+	registry := tool.NewRegistry(discardLogger())
+	spec := petstoreSpec(server.URL)
+	manager := NewManager([]config.OpenAPISpec{spec}, registry, discardLogger())
+	if err := manager.Reload(context.Background(), ""); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	execute := func(name string, params map[string]interface{}) *tool.ToolResult {
+		t.Helper()
+		result, err := registry.Execute(context.Background(), name, &tool.ToolInput{Parameters: params}, nil)
+		if err != nil {
+			t.Fatalf("Execute(%s) error = %v", name, err)
+		}
+		return result
+	}
+
+	result := execute("petstore_create_pet", map[string]interface{}{
+		"name":         "Rex",
+		"owner":        map[string]interface{}{"email": "a@example.com"},
+		"X-Request-ID": "req-1",
+	})
+	if !result.Success || result.Data.Output["status"] != http.StatusCreated {
+		t.Fatalf("create = %+v", result)
+	}
+	req := last()
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(req.body), &body); err != nil || body["name"] != "Rex" || body["owner"] == nil {
+		t.Errorf("body = %s", req.body)
+	}
+	if req.method != "POST" || req.header.Get("Authorization") != "Bearer s3cret" || req.header.Get("X-Request-ID") != "req-1" || req.header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %+v", req)
+	}
+
+	result = execute("petstore_list_pets", map[string]interface{}{"limit": float64(5), "tag": []interface{}{"cat", "dog"}})
+	if !result.Success || last().query != "limit=5&tag=cat&tag=dog" {
+		t.Errorf("list = %+v, query %q", result, last().query)
+	}
+	if items, _ := result.Data.Output["body"].([]interface{}); len(items) != 1 {
+		t.Errorf("body = %v", result.Data.Output["body"])
+	}
+
+	// An error response fails the call but keeps the body
+	result = execute("petstore_get_pet_by_id", map[string]interface{}{"petId": float64(404)})
+	if result.Success || result.Error != "GET /pets/{petId} returned 404: pet not found" || result.Data.Output["status"] != http.StatusNotFound {
+		t.Errorf("get = %+v", result)
+	}
+
+	result = execute("petstore_login", map[string]interface{}{"user": "ann", "password": "pw"})
+	if req := last(); !result.Success || req.body != "password=pw&user=ann" || req.header.Get("Authorization") != "" {
+		t.Errorf("login request = %+v", req)
+	}
+
+	execute("petstore_set_notes", map[string]interface{}{"petId": float64(3), "body": "friendly"})
+	if req := last(); req.path != "/pets/3/notes" || req.body != "friendly" || req.header.Get("Content-Type") != "text/plain" {
+		t.Errorf("notes request = %+v", req)
+	}
+
+	if result := execute("petstore_get_pet_by_id", map[string]interface{}{}); result.Success || result.Error != "petId parameter is required" {
+		t.Errorf("missing parameter = %+v", result)
+	}
+}
+
+func TestOperationTool_Credentials(t *testing.T) {
+	server, last := petstore(t)
+	registry := tool.NewRegistry(discardLogger())
+	spec := petstoreSpec(server.URL)
+	spec.Credentials = map[string]config.OpenAPICredential{"apiKey": {Token: "key-1"}}
+	manager := NewManager([]config.OpenAPISpec{spec}, registry, discardLogger())
+	if err := manager.Reload(context.Background(), ""); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	// getPetByID accepts either scheme and uses the configured one
+	result, _ := registry.Execute(context.Background(), "petstore_get_pet_by_id", &tool.ToolInput{Parameters: map[string]interface{}{"petId": float64(1)}}, nil)
+	if req := last(); !result.Success || req.header.Get("X-API-Key") != "key-1" || req.header.Get("Authorization") != "" {
+		t.Errorf("request = %+v", req)
+	}
+
+	result, _ = registry.Execute(context.Background(), "petstore_list_pets", &tool.ToolInput{}, nil)
+	if result.Success || result.Error != "no credentials configured for security scheme bearerAuth" {
+		t.Errorf("list = %+v", result)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"swagger: \"2.0\"\ninfo: {title: x}\n": "swagger 2.0 documents are not supported",
+		"openapi: 2.1\n":                       "unsupported OpenAPI version",
+		"openapi: [\n":                         "invalid OpenAPI document",
+	}
+	for src, want := range tests {
+		if _, err := Parse([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", src, err, want)
+		}
+	}
+}
+
+func TestToolName(t *testing.T) {
+	tests := []struct{ method, path, id, want string }{
+		{"GET", "/pets", "listPets", "svc_list_pets"},
+		{"GET", "/pets/{petId}", "getPetByID", "svc_get_pet_by_id"},
+		{"DELETE", "/pets/{petId}", "", "svc_delete_pets_pet_id"},
+		{"POST", "/v1/orders", "orders.create", "svc_orders_create"},
+	}
+	for _, tt := range tests {
+		if got := toolName("svc", tt.method, tt.path, tt.id); got != tt.want {
+			t.Errorf("toolName(%s %s %s) = %s, want %s", tt.method, tt.path, tt.id, got, tt.want)
+		}
+	}
+	if got := toolName("svc", "GET", "/", strings.Repeat("a", 100)); len(got) != maxNameLength {
+		t.Errorf("toolName() length = %d", len(got))
+	}
+}
@@ -0,0 +1,734 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+const (
+	// maxResponseBytes caps how much of a response is read
+	maxResponseBytes = 1 << 20
+	// maxNameLength is the longest tool name AI providers accept
+	maxNameLength = 64
+)
+
+// binding says where a tool parameter goes in the request
+type binding struct {
+	in   string // path, query, header, cookie, field (one body property) or body (the whole body)
+	name string // name in the request
+}
+
+// OperationTool implements the Tool interface for one API operation
+type OperationTool struct {
+	name        string
+	description string
+	method      string
+	path        string
+	baseURL     string
+	params      *tool.ToolParametersSchema
+	bindings    map[string]binding
+	contentType string // request body content type, "" without a body
+
+	security    []SecurityRequirement
+	schemes     map[string]*SecurityScheme
+	credentials map[string]config.OpenAPICredential
+	headers     map[string]string
+
+	client *http.Client
+	logger *slog.Logger
+}
+
+// operationSpec is what an operation tool is built from
+type operationSpec struct {
+	doc     *Document
+	method  string
+	path    string
+	item    *PathItem
+	op      *Operation
+	baseURL string
+}
+
+// newOperationTool converts an operation into a tool. Parameters and
+// object request body properties become top-level tool parameters; a body
+// that is not an object, or whose properties clash with parameters, is
+// passed as a single body parameter.
+func newOperationTool(name string, s operationSpec, spec config.OpenAPISpec, client *http.Client, logger *slog.Logger) (*OperationTool, error) {
+	t := &OperationTool{
+		name:        name,
+		description: describe(s),
+		method:      s.method,
+		path:        s.path,
+		baseURL:     s.baseURL,
+		params: &tool.ToolParametersSchema{
+			Type:       "object",
+			Properties: make(map[string]tool.ParameterProperty),
+		},
+		bindings:    make(map[string]binding),
+		schemes:     s.doc.Components.SecuritySchemes,
+		credentials: spec.Credentials,
+		headers:     spec.Headers,
+		client:      client,
+		logger:      logger,
+	}
+
+	t.security = s.doc.Security
+	if s.op.Security != nil {
+		t.security = *s.op.Security
+	}
+
+	params, err := mergeParameters(s.doc, s.item.Parameters, s.op.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range params {
+		if p.In == "header" && ignoredHeader(p.Name) {
+			continue
+		}
+		prop, err := property(s.doc, p.Schema, p.Description)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+		}
+		key := p.Name
+		if _, clash := t.bindings[key]; clash {
+			key = p.In + "_" + p.Name
+		}
+		t.add(key, prop, binding{in: p.In, name: p.Name}, p.Required || p.In == "path")
+	}
+
+	if s.op.RequestBody != nil {
+		if err := t.addBody(s.doc, s.op.RequestBody); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(t.params.Required)
+	return t, nil
+}
+
+// add declares a tool parameter and where it goes
+func (t *OperationTool) add(key string, prop tool.ParameterProperty, b binding, required bool) {
+	t.params.Properties[key] = prop
+	t.bindings[key] = b
+	if required {
+		t.params.Required = append(t.params.Required, key)
+	}
+}
+
+// addBody declares the parameters of a request body
+func (t *OperationTool) addBody(doc *Document, ref *RequestBody) error {
+	body, err := doc.requestBody(ref)
+	if err != nil {
+		return err
+	}
+	contentType, media := pickContentType(body.Content)
+	if contentType == "" {
+		return fmt.Errorf("unsupported request body content types: %s", strings.Join(sortedKeys(body.Content), ", "))
+	}
+	t.contentType = contentType
+
+	var schema *Schema
+	if media != nil {
+		if schema, err = doc.schema(media.Schema); err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+	}
+
+	flatten := schema != nil && schema.Type == "object" && len(schema.Properties) > 0 && contentType != "text/plain"
+	for name := range schemaProperties(schema) {
+		if _, clash := t.bindings[name]; clash {
+			flatten = false
+		}
+	}
+	if !flatten {
+		prop, err := property(doc, schema, body.Description)
+		if err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+		if prop.Description == "" {
+			prop.Description = "Request body"
+		}
+		t.add("body", prop, binding{in: "body"}, body.Required)
+		return nil
+	}
+
+	required := make(map[string]bool)
+	for _, name := range schema.Required {
+		required[name] = body.Required
+	}
+	for _, name := range sortedKeys(schema.Properties) {
+		propSchema, err := doc.schema(schema.Properties[name])
+		if err != nil {
+			return fmt.Errorf("request body property %s: %w", name, err)
+		}
+		if propSchema.ReadOnly {
+			continue
+		}
+		prop, err := property(doc, propSchema, "")
+		if err != nil {
+			return fmt.Errorf("request body property %s: %w", name, err)
+		}
+		t.add(name, prop, binding{in: "field", name: name}, required[name])
+	}
+	return nil
+}
+
+// schemaProperties returns the properties of an object schema
+func schemaProperties(s *Schema) map[string]*Schema {
+	if s == nil {
+		return nil
+	}
+	return s.Properties
+}
+
+// mergeParameters combines path-level and operation-level parameters; the
+// operation overrides a path parameter with the same name and location
+func mergeParameters(doc *Document, pathParams, opParams []*Parameter) ([]*Parameter, error) {
+	var list []*Parameter
+	index := make(map[string]int)
+	for _, group := range [][]*Parameter{pathParams, opParams} {
+		for _, ref := range group {
+			p, err := doc.parameter(ref)
+			if err != nil {
+				return nil, err
+			}
+			key := p.In + "\x00" + p.Name
+			if i, ok := index[key]; ok {
+				list[i] = p
+				continue
+			}
+			index[key] = len(list)
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+// ignoredHeader reports headers that OpenAPI says to describe elsewhere
+func ignoredHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "accept", "content-type", "authorization":
+		return true
+	}
+	return false
+}
+
+// pickContentType prefers JSON, then form and plain text bodies
+func pickContentType(content map[string]*MediaType) (string, *MediaType) {
+	keys := sortedKeys(content)
+	for _, want := range []func(string) bool{
+		func(ct string) bool { return ct == "application/json" },
+		func(ct string) bool { return strings.HasSuffix(ct, "+json") },
+		func(ct string) bool { return ct == "application/x-www-form-urlencoded" },
+		func(ct string) bool { return ct == "text/plain" },
+	} {
+		for _, ct := range keys {
+			if want(ct) {
+				return ct, content[ct]
+			}
+		}
+	}
+	return "", nil
+}
+
+// property converts a schema into a tool parameter property
+func property(doc *Document, ref *Schema, description string) (tool.ParameterProperty, error) {
+	s, err := doc.schema(ref)
+	if err != nil {
+		return tool.ParameterProperty{}, err
+	}
+	if s == nil {
+		return tool.ParameterProperty{Type: tool.ParameterTypeString, Description: description}, nil
+	}
+
+	prop := tool.ParameterProperty{
+		Type:        schemaType(doc, s),
+		Description: description,
+		Default:     s.Default,
+		Format:      s.Format,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		MinLength:   s.MinLength,
+		MaxLength:   s.MaxLength,
+	}
+	if prop.Description == "" {
+		prop.Description = s.Description
+	}
+	for _, v := range s.Enum {
+		if v != nil {
+			prop.Enum = append(prop.Enum, fmt.Sprint(v))
+		}
+	}
+
+	// Flat parameter schemas cannot nest, so describe the shape instead
+	var shape string
+	switch prop.Type {
+	case tool.ParameterTypeArray:
+		if items, err := doc.schema(s.Items); err == nil && items != nil {
+			shape = "array of " + schemaType(doc, items)
+			if fields := describeFields(doc, items); fields != "" {
+				shape += " with " + fields
+			}
+		}
+	case tool.ParameterTypeObject:
+		shape = describeFields(doc, s)
+	}
+	if shape != "" {
+		prop.Description = strings.TrimSpace(prop.Description + " (" + shape + ")")
+	}
+	return prop, nil
+}
+
+// schemaType maps a schema to a tool parameter type
+func schemaType(doc *Document, s *Schema) string {
+	switch s.Type {
+	case "string", "number", "integer", "boolean", "array", "object":
+		return string(s.Type)
+	}
+	if len(s.Properties) > 0 {
+		return tool.ParameterTypeObject
+	}
+	if s.Items != nil {
+		return tool.ParameterTypeArray
+	}
+	// A oneOf or anyOf of one kind is that kind
+	for _, alt := range append(append([]*Schema{}, s.OneOf...), s.AnyOf...) {
+		if r, err := doc.schema(alt); err == nil && r != nil && r.Type != "" && r.Type != "null" {
+			return schemaType(doc, r)
+		}
+	}
+	return tool.ParameterTypeString
+}
+
+// describeFields lists the properties of an object schema, e.g.
+// "fields: name (string, required), tags (array)"
+func describeFields(doc *Document, s *Schema) string {
+	if len(s.Properties) == 0 {
+		return ""
+	}
+	required := make(map[string]bool)
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	var fields []string
+	for _, name := range sortedKeys(s.Properties) {
+		field := name + " (" + tool.ParameterTypeString
+		if r, err := doc.schema(s.Properties[name]); err == nil && r != nil {
+			if r.ReadOnly {
+				continue
+			}
+			field = name + " (" + schemaType(doc, r)
+		}
+		if required[name] {
+			field += ", required"
+		}
+		fields = append(fields, field+")")
+	}
+	return "fields: " + strings.Join(fields, ", ")
+}
+
+// describe builds the tool description from the operation
+func describe(s operationSpec) string {
+	var parts []string
+	if s.op.Summary != "" {
+		parts = append(parts, strings.TrimSuffix(strings.TrimSpace(s.op.Summary), ".")+".")
+	}
+	if d := collapseSpace(s.op.Description); d != "" && d != s.op.Summary {
+		if len(d) > 300 {
+			cut := strings.LastIndex(d[:300], " ")
+			if cut <= 0 {
+				cut = 300
+			}
+			d = strings.ToValidUTF8(d[:cut], "") + "…"
+		}
+		parts = append(parts, d)
+	}
+	if s.op.Deprecated {
+		parts = append(parts, "Deprecated.")
+	}
+	parts = append(parts, fmt.Sprintf("[%s %s]", s.method, s.path))
+	return strings.Join(parts, " ")
+}
+
+// toolName derives a tool name from the operationId, or from the method
+// and path when there is none
+func toolName(prefix, method, path, operationID string) string {
+	base := operationID
+	if base == "" {
+		base = strings.ToLower(method) + "_" + path
+	}
+	name := snakeCase(prefix) + "_" + snakeCase(base)
+	if len(name) > maxNameLength {
+		name = strings.TrimRight(name[:maxNameLength], "_")
+	}
+	return name
+}
+
+// snakeCase converts camelCase and punctuation to lower snake_case
+func snakeCase(s string) string {
+	var b strings.Builder
+	prevLower := false
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			if prevLower {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			prevLower = false
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			prevLower = true
+		default:
+			b.WriteByte('_')
+			prevLower = false
+		}
+	}
+	parts := strings.FieldsFunc(b.String(), func(r rune) bool { return r == '_' })
+	return strings.Join(parts, "_")
+}
+
+// Name returns the tool name
+func (t *OperationTool) Name() string {
+	return t.name
+}
+
+// Description returns the tool description
+func (t *OperationTool) Description() string {
+	return t.description
+}
+
+// Parameters returns the tool parameter schema
+func (t *OperationTool) Parameters() *tool.ToolParametersSchema {
+	return t.params
+}
+
+// Execute calls the operation with the given parameters
+func (t *OperationTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.ToolResult, error) {
+	startTime := time.Now()
+
+	params := input.Parameters
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	for _, name := range t.params.Required {
+		if v, ok := params[name]; !ok || v == nil {
+			return &tool.ToolResult{
+				Success: false,
+				Error:   fmt.Sprintf("%s parameter is required", name),
+			}, nil
+		}
+	}
+
+	req, err := t.buildRequest(ctx, params)
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         err.Error(),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+
+	t.logger.Info("Calling API operation",
+		slog.String("tool", t.name),
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         fmt.Sprintf("failed to call %s %s: %v", t.method, t.path, err),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes+1))
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         fmt.Sprintf("failed to read response: %v", err),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+	truncated := len(data) > maxResponseBytes
+	if truncated {
+		data = data[:maxResponseBytes]
+	}
+
+	body := decodeBody(resp.Header.Get("Content-Type"), data, truncated)
+	output := map[string]interface{}{
+		"status": resp.StatusCode,
+		"body":   body,
+	}
+	if truncated {
+		output["truncated"] = true
+	}
+	result := &tool.ToolResult{
+		Success: resp.StatusCode < 400,
+		Data: &tool.ToolResultData{
+			Result: body,
+			Output: output,
+		},
+		ExecutionTime: time.Since(startTime),
+	}
+	if !result.Success {
+		result.Error = fmt.Sprintf("%s %s returned %d: %s", t.method, t.path, resp.StatusCode, errorMessage(body, resp.Status))
+	}
+	return result, nil
+}
+
+// buildRequest places the parameters in the path, query, headers, cookies
+// and body and applies credentials
+func (t *OperationTool) buildRequest(ctx context.Context, params map[string]interface{}) (*http.Request, error) {
+	path := t.path
+	query := url.Values{}
+	header := http.Header{}
+	var cookies []*http.Cookie
+	fields := make(map[string]interface{})
+	var body interface{}
+	hasBody := false
+
+	for _, key := range sortedKeys(t.bindings) {
+		v, ok := params[key]
+		if !ok || v == nil {
+			continue
+		}
+		b := t.bindings[key]
+		switch b.in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+b.name+"}", url.PathEscape(formatValue(v)))
+		case "query":
+			if list, ok := v.([]interface{}); ok {
+				for _, item := range list {
+					query.Add(b.name, formatValue(item))
+				}
+			} else {
+				query.Set(b.name, formatValue(v))
+			}
+		case "header":
+			header.Set(b.name, formatValue(v))
+		case "cookie":
+			cookies = append(cookies, &http.Cookie{Name: b.name, Value: formatValue(v)})
+		case "field":
+			fields[b.name] = v
+			hasBody = true
+		case "body":
+			body = v
+			hasBody = true
+		}
+	}
+	if body == nil && len(fields) > 0 {
+		body = fields
+	}
+
+	if err := t.authorize(query, header, &cookies); err != nil {
+		return nil, err
+	}
+
+	target := t.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if hasBody {
+		encoded, err := encodeBody(t.contentType, body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	if hasBody {
+		req.Header.Set("Content-Type", t.contentType)
+	}
+	req.Header.Set("Accept", "application/json, */*;q=0.5")
+	return req, nil
+}
+
+// authorize applies the credentials of the first security requirement
+// that can be met with the configured credentials
+func (t *OperationTool) authorize(query url.Values, header http.Header, cookies *[]*http.Cookie) error {
+	if len(t.security) == 0 {
+		return nil
+	}
+
+	var requirement SecurityRequirement
+	found := false
+	for _, r := range t.security {
+		ok := true
+		for scheme := range r {
+			if _, has := t.credentials[scheme]; !has || t.schemes[scheme] == nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			requirement, found = r, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("no credentials configured for security scheme %s", strings.Join(sortedKeys(t.security[0]), " + "))
+	}
+
+	for _, name := range sortedKeys(requirement) {
+		scheme, cred := t.schemes[name], t.credentials[name]
+		switch {
+		case scheme.Type == "apiKey":
+			switch scheme.In {
+			case "query":
+				query.Set(scheme.Name, cred.Token)
+			case "cookie":
+				*cookies = append(*cookies, &http.Cookie{Name: scheme.Name, Value: cred.Token})
+			default:
+				header.Set(scheme.Name, cred.Token)
+			}
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+			auth := base64.StdEncoding.EncodeToString([]byte(cred.Username + ":" + cred.Password))
+			header.Set("Authorization", "Basic "+auth)
+		case scheme.Type == "http" && !strings.EqualFold(scheme.Scheme, "bearer"):
+			header.Set("Authorization", scheme.Scheme+" "+cred.Token)
+		default:
+			// bearer, oauth2 and openIdConnect all send an access token
+			header.Set("Authorization", "Bearer "+cred.Token)
+		}
+	}
+	return nil
+}
+
+// formatValue renders a parameter value for a URL or header
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatValue(item)
+		}
+		return strings.Join(items, ",")
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// encodeBody encodes a request body for the content type
+func encodeBody(contentType string, body interface{}) ([]byte, error) {
+	switch {
+	case contentType == "application/x-www-form-urlencoded":
+		m, ok := body.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("body must be an object for %s", contentType)
+		}
+		form := url.Values{}
+		for _, k := range sortedKeys(m) {
+			form.Set(k, formatValue(m[k]))
+		}
+		return []byte(form.Encode()), nil
+	case contentType == "text/plain":
+		return []byte(formatValue(body)), nil
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		return data, nil
+	}
+}
+
+// decodeBody returns JSON responses as values and anything else as text
+func decodeBody(contentType string, data []byte, truncated bool) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !truncated && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err == nil {
+			return v
+		}
+	}
+	return string(data)
+}
+
+// errorMessage finds a human readable message in an error response
+func errorMessage(body interface{}, status string) string {
+	switch b := body.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"message", "error", "detail", "title"} {
+			if s, ok := b[key].(string); ok && s != "" {
+				return s
+			}
+		}
+	case string:
+		if s := collapseSpace(b); s != "" {
+			if len(s) > 200 {
+				s = s[:200] + "…"
+			}
+			return s
+		}
+	}
+	return status
+}
+
+// collapseSpace trims s and replaces runs of whitespace with one space
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Health reports the tool as healthy; the service is checked per call
+func (t *OperationTool) Health(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the operation tool
+func (t *OperationTool) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,329 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the subset of an OpenAPI 3 document that tools are built from
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Swagger    string                `json:"swagger"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL, possibly with {variables}
+type Server struct {
+	URL       string                    `json:"url"`
+	Variables map[string]ServerVariable `json:"variables"`
+}
+
+// ServerVariable is a substitution for a server URL template
+type ServerVariable struct {
+	Default string `json:"default"`
+}
+
+// PathItem holds the operations on one path
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Patch      *Operation   `json:"patch"`
+	Head       *Operation   `json:"head"`
+	Options    *Operation   `json:"options"`
+}
+
+// operations returns the operations of a path item by HTTP method
+func (p *PathItem) operations() []struct {
+	method string
+	op     *Operation
+} {
+	all := []struct {
+		method string
+		op     *Operation
+	}{
+		{"GET", p.Get}, {"PUT", p.Put}, {"POST", p.Post}, {"DELETE", p.Delete},
+		{"PATCH", p.Patch}, {"HEAD", p.Head}, {"OPTIONS", p.Options},
+	}
+	list := all[:0]
+	for _, m := range all {
+		if m.op != nil {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// Operation is a single API operation
+type Operation struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary"`
+	Description string                 `json:"description"`
+	Tags        []string               `json:"tags"`
+	Parameters  []*Parameter           `json:"parameters"`
+	RequestBody *RequestBody           `json:"requestBody"`
+	Security    *[]SecurityRequirement `json:"security"`
+	Servers     []Server               `json:"servers"`
+	Deprecated  bool                   `json:"deprecated"`
+}
+
+// Parameter is a path, query, header or cookie parameter
+type Parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is an operation's request body
+type RequestBody struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Required    bool                  `json:"required"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// MediaType is the schema of one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema
+type Schema struct {
+	Ref         string             `json:"$ref"`
+	Type        SchemaType         `json:"type"`
+	Format      string             `json:"format"`
+	Description string             `json:"description"`
+	Enum        []interface{}      `json:"enum"`
+	Default     interface{}        `json:"default"`
+	Properties  map[string]*Schema `json:"properties"`
+	Required    []string           `json:"required"`
+	Items       *Schema            `json:"items"`
+	AllOf       []*Schema          `json:"allOf"`
+	OneOf       []*Schema          `json:"oneOf"`
+	AnyOf       []*Schema          `json:"anyOf"`
+	Minimum     *float64           `json:"minimum"`
+	Maximum     *float64           `json:"maximum"`
+	MinLength   *int               `json:"minLength"`
+	MaxLength   *int               `json:"maxLength"`
+	ReadOnly    bool               `json:"readOnly"`
+}
+
+// SchemaType is a schema type. OpenAPI 3.1 allows a list such as
+// ["string", "null"], which is read as its first non-null entry.
+type SchemaType string
+
+// UnmarshalJSON accepts a type name or a list of them
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = SchemaType(name)
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("schema type must be a string or a list of strings")
+	}
+	for _, n := range names {
+		if n != "null" {
+			*t = SchemaType(n)
+			break
+		}
+	}
+	return nil
+}
+
+// Components holds the reusable definitions that $ref points to
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	Parameters      map[string]*Parameter      `json:"parameters"`
+	RequestBodies   map[string]*RequestBody    `json:"requestBodies"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is how an API authenticates requests
+type SecurityScheme struct {
+	Type   string `json:"type"`   // apiKey, http, oauth2 or openIdConnect
+	Scheme string `json:"scheme"` // basic or bearer for http
+	Name   string `json:"name"`   // header, query or cookie name for apiKey
+	In     string `json:"in"`
+}
+
+// SecurityRequirement lists the schemes that together authorize a request
+type SecurityRequirement map[string][]string
+
+// Parse parses a JSON or YAML OpenAPI 3 document
+func Parse(data []byte) (*Document, error) {
+	// YAML is a superset of JSON, so both go through the YAML decoder and
+	// are re-encoded for the typed decode
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	raw = stringKeys(raw)
+	if m, ok := raw.(map[string]interface{}); ok {
+		// An unquoted version such as openapi: 3.0 decodes as a number
+		for _, key := range []string{"openapi", "swagger"} {
+			if v, ok := m[key].(float64); ok {
+				m[key] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	switch {
+	case doc.Swagger != "":
+		return nil, fmt.Errorf("swagger %s documents are not supported; convert to OpenAPI 3 first", doc.Swagger)
+	case !strings.HasPrefix(doc.OpenAPI, "3."):
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, want 3.x", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// stringKeys converts the map[interface{}]interface{} values YAML produces
+// for keys such as response codes into JSON-encodable maps
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = stringKeys(val)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = stringKeys(val)
+		}
+		return v
+	}
+	return v
+}
+
+// refName returns the component name of a local reference of the given
+// kind, e.g. "Pet" for "#/components/schemas/Pet"
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %q; only local #/components/%s references are resolved", ref, kind)
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(strings.TrimPrefix(ref, prefix)), nil
+}
+
+// parameter resolves a parameter reference
+func (d *Document) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.Parameters[name]
+	if !ok || resolved.Ref != "" {
+		return nil, fmt.Errorf("unresolved reference %q", p.Ref)
+	}
+	return resolved, nil
+}
+
+// requestBody resolves a request body reference
+func (d *Document) requestBody(b *RequestBody) (*RequestBody, error) {
+	if b.Ref == "" {
+		return b, nil
+	}
+	name, err := refName(b.Ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.RequestBodies[name]
+	if !ok || resolved.Ref != "" {
+		return nil, fmt.Errorf("unresolved reference %q", b.Ref)
+	}
+	return resolved, nil
+}
+
+// maxRefDepth bounds chains of schema references and allOf merges
+const maxRefDepth = 32
+
+// schema resolves a schema reference and merges allOf into one object
+// schema
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	return d.resolveSchema(s, 0)
+}
+
+func (d *Document) resolveSchema(s *Schema, depth int) (*Schema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if depth > maxRefDepth {
+		return nil, fmt.Errorf("schema references are nested too deeply")
+	}
+	if s.Ref != "" {
+		name, err := refName(s.Ref, "schemas")
+		if err != nil {
+			return nil, err
+		}
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("unresolved reference %q", s.Ref)
+		}
+		return d.resolveSchema(resolved, depth+1)
+	}
+	if len(s.AllOf) == 0 {
+		return s, nil
+	}
+
+	merged := *s
+	merged.AllOf = nil
+	merged.Properties = make(map[string]*Schema)
+	for k, v := range s.Properties {
+		merged.Properties[k] = v
+	}
+	for _, part := range s.AllOf {
+		r, err := d.resolveSchema(part, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if merged.Type == "" {
+			merged.Type = r.Type
+		}
+		for k, v := range r.Properties {
+			merged.Properties[k] = v
+		}
+		merged.Required = append(merged.Required, r.Required...)
+	}
+	if merged.Type == "" && len(merged.Properties) > 0 {
+		merged.Type = "object"
+	}
+	return &merged, nil
+}
@@ -0,0 +1,234 @@
+// Package plugin runs external tools as subprocesses. A plugin is an
+// executable in the plugins directory that answers JSON-RPC requests on
+// stdin/stdout: describe, execute and health. Plugins are discovered at
+// startup and reloaded when the directory changes.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// validName matches the tool names a plugin may describe
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Manager discovers plugins and keeps their tools registered
+type Manager struct {
+	cfg      config.Plugins
+	registry *tool.Registry
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	plugins map[string]*installed // by executable path
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// installed is a plugin executable as of the last scan
+type installed struct {
+	modTime time.Time
+	size    int64
+	name    string // registered tool name, empty when it failed to load
+}
+
+// NewManager creates a manager for the plugins directory
+func NewManager(cfg config.Plugins, registry *tool.Registry, logger *slog.Logger) *Manager {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &Manager{
+		cfg:      cfg,
+		registry: registry,
+		logger:   logger,
+		plugins:  make(map[string]*installed),
+	}
+}
+
+// Scan registers new and changed plugins and unregisters removed ones.
+// A plugin that fails to describe itself is reported and retried once
+// its executable changes.
+func (m *Manager) Scan(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found, err := m.executables()
+	if err != nil {
+		return err
+	}
+
+	for path, p := range m.plugins {
+		if _, ok := found[path]; !ok {
+			m.remove(path, p)
+		}
+	}
+
+	var errs []error
+	for _, path := range sortedPaths(found) {
+		info := found[path]
+		if p, ok := m.plugins[path]; ok {
+			if p.modTime.Equal(info.ModTime()) && p.size == info.Size() {
+				continue
+			}
+			m.remove(path, p)
+		}
+
+		p := &installed{modTime: info.ModTime(), size: info.Size()}
+		m.plugins[path] = p
+		name, err := m.load(ctx, path)
+		if err != nil {
+			m.logger.Warn("Failed to load plugin",
+				slog.String("path", path),
+				slog.Any("error", err))
+			errs = append(errs, fmt.Errorf("plugin %s: %w", filepath.Base(path), err))
+			continue
+		}
+		p.name = name
+	}
+	return errors.Join(errs...)
+}
+
+// executables lists the executable regular files in the plugins directory
+func (m *Manager) executables() (map[string]os.FileInfo, error) {
+	dir, err := filepath.Abs(m.cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugins directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+
+	found := make(map[string]os.FileInfo)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// Stat follows symlinks so linked plugins work
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		found[path] = info
+	}
+	return found, nil
+}
+
+// load describes a plugin and registers its tool. Each tool instance
+// runs its own subprocess; describing uses a short-lived one.
+func (m *Manager) load(ctx context.Context, path string) (string, error) {
+	proc := newProcess(path, m.cfg.Timeout, m.logger)
+	defer proc.close()
+
+	var desc Description
+	if err := proc.call(ctx, MethodDescribe, DescribeParams{ProtocolVersion: ProtocolVersion}, &desc); err != nil {
+		return "", fmt.Errorf("describe failed: %w", err)
+	}
+	if !validName.MatchString(desc.Name) {
+		return "", fmt.Errorf("invalid tool name %q", desc.Name)
+	}
+
+	timeout := m.cfg.Timeout
+	factory := func(cfg *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
+		return NewPluginTool(desc, path, timeout, logger), nil
+	}
+	if err := m.registry.Register(desc.Name, factory); err != nil {
+		return "", err
+	}
+
+	m.logger.Info("Plugin registered",
+		slog.String("tool", desc.Name),
+		slog.String("path", path),
+		slog.String("version", desc.Version))
+	return desc.Name, nil
+}
+
+// remove unregisters a plugin's tool, which stops its subprocess
+func (m *Manager) remove(path string, p *installed) {
+	delete(m.plugins, path)
+	if p.name == "" {
+		return
+	}
+	if err := m.registry.Unregister(p.name); err != nil {
+		m.logger.Warn("Failed to unregister plugin",
+			slog.String("tool", p.name),
+			slog.Any("error", err))
+		return
+	}
+	m.logger.Info("Plugin unregistered", slog.String("tool", p.name))
+}
+
+// Tools returns the names of the registered plugin tools
+func (m *Manager) Tools() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for _, p := range m.plugins {
+		if p.name != "" {
+			names = append(names, p.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start rescans the plugins directory every ReloadInterval until Close
+func (m *Manager) Start() {
+	if m.cfg.ReloadInterval <= 0 || m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.cfg.ReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// Load failures are logged per plugin by Scan
+				if err := m.Scan(context.Background()); err != nil {
+					m.logger.Debug("Plugin rescan reported errors", slog.Any("error", err))
+				}
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops watching the plugins directory. Plugin subprocesses are
+// stopped when the registry closes their tools.
+func (m *Manager) Close() error {
+	if m.stop != nil {
+		close(m.stop)
+		<-m.done
+		m.stop = nil
+	}
+	return nil
+}
+
+// sortedPaths returns the keys of found in order
+func sortedPaths(found map[string]os.FileInfo) []string {
+	paths := make([]string, 0, len(found))
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,297 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// TestHelperPlugin is not a real test: plugin scripts written by
+// writePlugin run the test binary with PLUGIN_TEST_NAME set, and this
+// function then acts as the plugin
+func TestHelperPlugin(t *testing.T) {
+	name := os.Getenv("PLUGIN_TEST_NAME")
+	if name == "" {
+		return
+	}
+	defer os.Exit(0)
+	if name == "deaf" {
+		// Never reads stdin, so large requests fill the pipe
+		time.Sleep(time.Minute)
+		return
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	reply := func(id int64, result interface{}) {
+		out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result})
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64         `json:"id"`
+			Method string        `json:"method"`
+			Params ExecuteParams `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+		switch req.Method {
+		case MethodDescribe:
+			reply(req.ID, Description{
+				Name:        name,
+				Description: "Echoes its parameters",
+				Parameters: &tool.ToolParametersSchema{
+					Type:       "object",
+					Properties: map[string]tool.ParameterProperty{"mode": {Type: "string", Description: "Misbehaviour to test"}},
+				},
+			})
+		case MethodHealth:
+			reply(req.ID, HealthResult{Healthy: true})
+		case MethodExecute:
+			fmt.Fprintln(os.Stderr, "executing")
+			switch req.Params.Parameters["mode"] {
+			case "crash":
+				os.Exit(3)
+			case "hang":
+				time.Sleep(time.Minute)
+			case "garbage":
+				fmt.Println("panic: not json")
+			case "error":
+				out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": RPCError{Code: -32000, Message: "backend down"}})
+			default:
+				// An unsolicited notification is skipped by the caller
+				out.Encode(map[string]interface{}{"jsonrpc": "2.0", "method": "progress"})
+				reply(req.ID, ExecuteResult{
+					Success:   true,
+					Result:    req.Params.Parameters,
+					Output:    map[string]interface{}{"user": req.Params.Context.UserID},
+					Citations: []tool.Citation{{Title: "Docs", URL: "https://example.com"}},
+				})
+			}
+		}
+	}
+}
+
+// writePlugin writes an executable plugin script to dir that describes
+// itself as name
+func writePlugin(t *testing.T, dir, file, name string) string {
+	t.Helper()
+	script := fmt.Sprintf("#!/bin/sh\nPLUGIN_TEST_NAME='%s' exec %q -test.run='^TestHelperPlugin$'\n", name, os.Args[0])
+	path := filepath.Join(dir, file)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need /bin/sh")
+	}
+}
+
+func TestManager_Scan(t *testing.T) {
+	skipWithoutShell(t)
+	dir := t.TempDir()
+	writePlugin(t, dir, "echo", "echo")
+	writePlugin(t, dir, ".hidden", "hidden")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := tool.NewRegistry(discardLogger())
+	defer registry.Close(context.Background())
+	manager := NewManager(config.Plugins{Dir: dir, Timeout: 5 * time.Second}, registry, discardLogger())
+	if err := manager.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if got := manager.Tools(); !reflect.DeepEqual(got, []string{"echo"}) {
+		t.Fatalf("Tools() = %v, want [echo]", got)
+	}
+
+	input := &tool.ToolInput{
+		Parameters: map[string]interface{}{"q": "hi"},
+		Context:    &tool.ToolContext{UserID: "u1"},
+	}
+	result, err := registry.Execute(context.Background(), "echo", input, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+	if !reflect.DeepEqual(result.Data.Result, map[string]interface{}{"q": "hi"}) || result.Data.Output["user"] != "u1" || len(result.Data.Citations) != 1 {
+		t.Errorf("result = %+v", result.Data)
+	}
+	info, err := registry.GetToolInfo("echo")
+	if err != nil || info.Description != "Echoes its parameters" || info.Parameters.Properties["mode"].Type != "string" {
+		t.Errorf("GetToolInfo() = %+v, %v", info, err)
+	}
+	if err := registry.Health(context.Background()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+
+	// A changed executable is described again, a removed one unregistered
+	writePlugin(t, dir, "echo", "echo_v2")
+	writePlugin(t, dir, "other", "other")
+	if err := manager.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if got := manager.Tools(); !reflect.DeepEqual(got, []string{"echo_v2", "other"}) {
+		t.Errorf("Tools() = %v", got)
+	}
+	if registry.IsRegistered("echo") {
+		t.Error("replaced plugin is still registered")
+	}
+	os.Remove(filepath.Join(dir, "other"))
+	if err := manager.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if registry.IsRegistered("other") {
+		t.Error("removed plugin is still registered")
+	}
+}
+
+func TestManager_ScanErrors(t *testing.T) {
+	skipWithoutShell(t)
+	dir := t.TempDir()
+	writePlugin(t, dir, "bad", "bad name")
+	writePlugin(t, dir, "dup", "echo")
+	broken := filepath.Join(dir, "broken")
+	if err := os.WriteFile(broken, []byte("#!/bin/sh\nread request\necho hello\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := tool.NewRegistry(discardLogger())
+	defer registry.Close(context.Background())
+	registry.Register("echo", func(*tool.ToolConfig, *slog.Logger) (tool.Tool, error) { return nil, nil })
+	manager := NewManager(config.Plugins{Dir: dir, Timeout: 5 * time.Second}, registry, discardLogger())
+
+	err := manager.Scan(context.Background())
+	if err == nil {
+		t.Fatal("Scan() succeeded with broken plugins")
+	}
+	for _, want := range []string{`invalid tool name "bad name"`, "malformed plugin output", "tool echo is already registered"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Scan() error = %v, want %q", err, want)
+		}
+	}
+	if len(manager.Tools()) != 0 {
+		t.Errorf("Tools() = %v", manager.Tools())
+	}
+
+	// Failed plugins are only retried once they change
+	if err := manager.Scan(context.Background()); err != nil {
+		t.Errorf("second Scan() error = %v", err)
+	}
+
+	if err := NewManager(config.Plugins{Dir: filepath.Join(dir, "missing")}, registry, discardLogger()).Scan(context.Background()); err == nil {
+		t.Error("Scan() succeeded for a missing directory")
+	}
+}
+
+func TestPluginTool_Isolation(t *testing.T) {
+	skipWithoutShell(t)
+	path := writePlugin(t, t.TempDir(), "echo", "echo")
+	p := NewPluginTool(Description{Name: "echo"}, path, 500*time.Millisecond, discardLogger())
+	defer p.Close(context.Background())
+
+	execute := func(mode string) *tool.ToolResult {
+		t.Helper()
+		result, err := p.Execute(context.Background(), &tool.ToolInput{
+			Parameters: map[string]interface{}{"mode": mode},
+			Context:    &tool.ToolContext{},
+		})
+		if err != nil {
+			t.Fatalf("Execute(%s) error = %v", mode, err)
+		}
+		return result
+	}
+
+	tests := []struct{ mode, want string }{
+		{"crash", "plugin echo failed: plugin exited during execute: exit status 3"},
+		{"hang", "plugin echo failed: plugin did not answer execute within 500ms"},
+		{"garbage", `plugin echo failed: malformed plugin output: "panic: not json"`},
+		{"error", "plugin echo failed: plugin error -32000: backend down"},
+	}
+	for _, tt := range tests {
+		if result := execute(tt.mode); result.Success || result.Error != tt.want {
+			t.Errorf("Execute(%s) = %+v, want error %q", tt.mode, result, tt.want)
+		}
+		// Every failure leaves the tool usable
+		if result := execute("ok"); !result.Success {
+			t.Errorf("Execute after %s = %+v", tt.mode, result)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result, _ := p.Execute(ctx, &tool.ToolInput{Context: &tool.ToolContext{}}); result.Success {
+		t.Error("Execute() succeeded with a cancelled context")
+	}
+
+	p.Close(context.Background())
+	if result := execute("ok"); result.Success || !strings.Contains(result.Error, "plugin is closed") {
+		t.Errorf("Execute after Close = %+v", result)
+	}
+}
+
+func TestPluginTool_WriteTimeout(t *testing.T) {
+	skipWithoutShell(t)
+	path := writePlugin(t, t.TempDir(), "deaf", "deaf")
+	p := NewPluginTool(Description{Name: "deaf"}, path, 500*time.Millisecond, discardLogger())
+	defer p.Close(context.Background())
+
+	// Far larger than a pipe buffer, so the write blocks until the
+	// process is killed
+	large := strings.Repeat("x", 4<<20)
+	start := time.Now()
+	result, err := p.Execute(context.Background(), &tool.ToolInput{
+		Parameters: map[string]interface{}{"data": large},
+		Context:    &tool.ToolContext{},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "plugin deaf failed: plugin did not read execute within 500ms"
+	if result.Success || result.Error != want {
+		t.Errorf("Execute() = %+v, want error %q", result, want)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Execute() took %s, want the call timeout", elapsed)
+	}
+}
+
+func TestManager_Start(t *testing.T) {
+	skipWithoutShell(t)
+	dir := t.TempDir()
+	registry := tool.NewRegistry(discardLogger())
+	defer registry.Close(context.Background())
+	manager := NewManager(config.Plugins{Dir: dir, Timeout: 5 * time.Second, ReloadInterval: 20 * time.Millisecond}, registry, discardLogger())
+	manager.Start()
+	defer manager.Close()
+
+	writePlugin(t, dir, "late", "late")
+	deadline := time.Now().Add(5 * time.Second)
+	for !registry.IsRegistered("late") {
+		if time.Now().After(deadline) {
+			t.Fatal("plugin added after Start was not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
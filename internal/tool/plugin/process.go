@@ -0,0 +1,288 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxMessageBytes caps one protocol message
+const maxMessageBytes = 16 << 20
+
+// errClosed is returned for calls after Close
+var errClosed = errors.New("plugin is closed")
+
+// process runs a plugin executable. Calls are serialized. A call that
+// times out, is cancelled or reads malformed output kills the subprocess,
+// and the next call starts a fresh one, so a misbehaving plugin never
+// blocks or crashes the server.
+type process struct {
+	path    string
+	timeout time.Duration
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	conn   *conn
+	nextID int64
+	closed bool
+}
+
+// conn is one running subprocess
+type conn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *os.File
+	lines  chan []byte   // stdout lines, closed at EOF
+	done   chan struct{} // closed once the process has exited
+	err    error         // exit status, set before done is closed
+}
+
+func newProcess(path string, timeout time.Duration, logger *slog.Logger) *process {
+	return &process{
+		path:    path,
+		timeout: timeout,
+		logger:  logger.With(slog.String("plugin", filepath.Base(path))),
+	}
+}
+
+// start launches the subprocess
+func (p *process) start() (*conn, error) {
+	cmd := exec.Command(p.path)
+	cmd.Dir = filepath.Dir(p.path)
+	cmd.Stderr = &logWriter{logger: p.logger}
+	cmd.WaitDelay = time.Second
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugin stdin: %w", err)
+	}
+	// stdout is a plain pipe rather than StdoutPipe so that Wait never
+	// closes it while a response is still being read
+	stdout, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugin stdout: %w", err)
+	}
+	cmd.Stdout = w
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		w.Close()
+		return nil, fmt.Errorf("failed to start plugin: %w", err)
+	}
+	w.Close()
+
+	c := &conn{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		lines:  make(chan []byte),
+		done:   make(chan struct{}),
+	}
+	go c.read()
+	go func() {
+		c.err = cmd.Wait()
+		close(c.done)
+	}()
+
+	p.logger.Debug("Plugin process started", slog.Int("pid", cmd.Process.Pid))
+	return c, nil
+}
+
+// read forwards stdout lines until EOF or until the process is killed
+func (c *conn) read() {
+	defer close(c.lines)
+	scanner := bufio.NewScanner(c.stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		select {
+		case c.lines <- append([]byte(nil), line...):
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// kill stops the subprocess without waiting for it
+func (c *conn) kill() {
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.stdin.Close()
+	c.stdout.Close()
+}
+
+// exitStatus waits briefly for the process to exit and describes how
+func (c *conn) exitStatus() string {
+	select {
+	case <-c.done:
+		if c.err != nil {
+			return c.err.Error()
+		}
+		return "exit status 0"
+	case <-time.After(2 * time.Second):
+		c.kill()
+		return "stdout closed"
+	}
+}
+
+// call sends a request and decodes the result into out
+func (p *process) call(ctx context.Context, method string, params, out interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errClosed
+	}
+	if p.conn == nil {
+		c, err := p.start()
+		if err != nil {
+			return err
+		}
+		p.conn = c
+	}
+	c := p.conn
+
+	p.nextID++
+	id := p.nextID
+	data, err := json.Marshal(request{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	// The write is timed as well: a plugin that stops reading stdin
+	// blocks it once the pipe buffer is full. Killing the process on
+	// timeout unblocks the writer goroutine.
+	written := make(chan error, 1)
+	go func() {
+		_, err := c.stdin.Write(append(data, '\n'))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			// The plugin closed stdin, usually because it exited
+			p.conn = nil
+			return fmt.Errorf("plugin exited before %s: %s", method, c.exitStatus())
+		}
+	case <-timer.C:
+		p.reset()
+		return fmt.Errorf("plugin did not read %s within %s", method, p.timeout)
+	case <-ctx.Done():
+		p.reset()
+		return ctx.Err()
+	}
+
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				p.conn = nil
+				return fmt.Errorf("plugin exited during %s: %s", method, c.exitStatus())
+			}
+			var resp response
+			if err := json.Unmarshal(line, &resp); err != nil || resp.JSONRPC != "2.0" {
+				p.reset()
+				return fmt.Errorf("malformed plugin output: %q", truncate(line, 200))
+			}
+			if resp.ID == nil || *resp.ID != id {
+				p.logger.Debug("Ignoring unexpected plugin message", slog.String("message", truncate(line, 200)))
+				continue
+			}
+			if resp.Error != nil {
+				return resp.Error
+			}
+			if out != nil {
+				if err := json.Unmarshal(resp.Result, out); err != nil {
+					return fmt.Errorf("malformed %s result: %w", method, err)
+				}
+			}
+			return nil
+		case <-timer.C:
+			p.reset()
+			return fmt.Errorf("plugin did not answer %s within %s", method, p.timeout)
+		case <-ctx.Done():
+			p.reset()
+			return ctx.Err()
+		}
+	}
+}
+
+// reset kills the current subprocess; the next call starts a new one
+func (p *process) reset() {
+	if p.conn != nil {
+		p.logger.Warn("Killing plugin process")
+		p.conn.kill()
+		p.conn = nil
+	}
+}
+
+// close shuts the subprocess down: stdin is closed so the plugin can exit
+// on its own, and it is killed if it does not
+func (p *process) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	c := p.conn
+	p.conn = nil
+	if c == nil {
+		return
+	}
+	c.stdin.Close()
+	select {
+	case <-c.done:
+		c.stdout.Close()
+	case <-time.After(2 * time.Second):
+		c.kill()
+	}
+}
+
+// logWriter logs each line a plugin writes to stderr
+type logWriter struct {
+	logger *slog.Logger
+	buf    []byte
+}
+
+func (w *logWriter) Write(data []byte) (int, error) {
+	w.buf = append(w.buf, data...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(w.buf[:i]); len(line) > 0 {
+			w.logger.Info("Plugin stderr", slog.String("line", truncate(line, 1000)))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	// A plugin that never ends a line cannot grow the buffer unbounded
+	if len(w.buf) > 64*1024 {
+		w.logger.Info("Plugin stderr", slog.String("line", truncate(w.buf, 1000)))
+		w.buf = w.buf[:0]
+	}
+	return len(data), nil
+}
+
+// truncate shortens b to at most n bytes for logging and errors
+func truncate(b []byte, n int) string {
+	if len(b) <= n {
+		return string(b)
+	}
+	return string(b[:n]) + "..."
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// Protocol methods. Every message is one line of JSON-RPC 2.0 on the
+// plugin's stdin (requests) or stdout (responses); stderr is logged.
+const (
+	MethodDescribe = "describe" // -> Description
+	MethodExecute  = "execute"  // ExecuteParams -> ExecuteResult
+	MethodHealth   = "health"   // -> HealthResult
+)
+
+// ProtocolVersion is sent with describe so plugins can reject versions
+// they do not understand
+const ProtocolVersion = 1
+
+// request is a JSON-RPC request
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error"`
+}
+
+// RPCError is an error reported by a plugin
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// DescribeParams are the parameters of describe
+type DescribeParams struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// Description is a plugin's answer to describe
+type Description struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Version     string                     `json:"version,omitempty"`
+	Parameters  *tool.ToolParametersSchema `json:"parameters"`
+}
+
+// ExecuteParams are the parameters of execute
+type ExecuteParams struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Context    *tool.ToolContext      `json:"context,omitempty"`
+}
+
+// ExecuteResult is a plugin's answer to execute
+type ExecuteResult struct {
+	Success   bool                   `json:"success"`
+	Result    interface{}            `json:"result,omitempty"`
+	Output    map[string]interface{} `json:"output,omitempty"`
+	Artifacts []tool.ToolArtifact    `json:"artifacts,omitempty"`
+	Citations []tool.Citation        `json:"citations,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// HealthResult is a plugin's answer to health
+type HealthResult struct {
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// PluginTool is a tool implemented by a plugin executable. The subprocess
+// is started on first use and restarted after a crash or timeout.
+type PluginTool struct {
+	desc   Description
+	proc   *process
+	logger *slog.Logger
+}
+
+// NewPluginTool creates a tool for the plugin at path, described by desc
+func NewPluginTool(desc Description, path string, timeout time.Duration, logger *slog.Logger) *PluginTool {
+	return &PluginTool{
+		desc:   desc,
+		proc:   newProcess(path, timeout, logger),
+		logger: logger,
+	}
+}
+
+// Name returns the tool name
+func (t *PluginTool) Name() string {
+	return t.desc.Name
+}
+
+// Description returns the tool description
+func (t *PluginTool) Description() string {
+	return t.desc.Description
+}
+
+// Parameters returns the tool parameters schema
+func (t *PluginTool) Parameters() *tool.ToolParametersSchema {
+	if t.desc.Parameters == nil {
+		return &tool.ToolParametersSchema{
+			Type:       "object",
+			Properties: map[string]tool.ParameterProperty{},
+		}
+	}
+	return t.desc.Parameters
+}
+
+// Execute runs the plugin's execute method
+func (t *PluginTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.ToolResult, error) {
+	startTime := time.Now()
+
+	params := ExecuteParams{Parameters: input.Parameters, Context: input.Context}
+	if params.Parameters == nil {
+		params.Parameters = make(map[string]interface{})
+	}
+
+	var result ExecuteResult
+	if err := t.proc.call(ctx, MethodExecute, params, &result); err != nil {
+		t.logger.Warn("Plugin execution failed",
+			slog.String("tool", t.desc.Name),
+			slog.Any("error", err))
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         fmt.Sprintf("plugin %s failed: %v", t.desc.Name, err),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+
+	if !result.Success && result.Error == "" {
+		result.Error = "plugin reported failure"
+	}
+	return &tool.ToolResult{
+		Success: result.Success,
+		Data: &tool.ToolResultData{
+			Result:    result.Result,
+			Output:    result.Output,
+			Artifacts: result.Artifacts,
+			Citations: result.Citations,
+		},
+		Error:         result.Error,
+		ExecutionTime: time.Since(startTime),
+	}, nil
+}
+
+// Health runs the plugin's health method
+func (t *PluginTool) Health(ctx context.Context) error {
+	var result HealthResult
+	if err := t.proc.call(ctx, MethodHealth, nil, &result); err != nil {
+		return fmt.Errorf("plugin %s health check failed: %w", t.desc.Name, err)
+	}
+	if !result.Healthy {
+		return fmt.Errorf("plugin %s is unhealthy: %s", t.desc.Name, result.Message)
+	}
+	return nil
+}
+
+// Close stops the plugin subprocess
+func (t *PluginTool) Close(ctx context.Context) error {
+	t.proc.close()
+	return nil
+}
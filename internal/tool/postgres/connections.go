@@ -0,0 +1,334 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// ErrConnectionNotFound is returned when a named connection cannot be resolved
+var ErrConnectionNotFound = errors.New("database connection not found")
+
+// ConnectionStore resolves named database connections.
+// An empty name asks for the user's default connection.
+type ConnectionStore interface {
+	ConnectionString(ctx context.Context, userID, name string) (string, error)
+	ListConnections(ctx context.Context, userID string) ([]ConnectionInfo, error)
+}
+
+// ConnectionInfo describes a named connection without exposing its credentials
+type ConnectionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IsDefault   bool   `json:"is_default"`
+	Source      string `json:"source"` // "database_connections" or "config"
+}
+
+// QueriesConnectionStore implements ConnectionStore on top of the
+// database_connections table
+type QueriesConnectionStore struct {
+	queries *sqlc.Queries
+}
+
+// NewQueriesConnectionStore creates a connection store backed by sqlc queries
+func NewQueriesConnectionStore(queries *sqlc.Queries) *QueriesConnectionStore {
+	return &QueriesConnectionStore{
+		queries: queries,
+	}
+}
+
+// ConnectionString returns the connection string for a user's named connection
+func (s *QueriesConnectionStore) ConnectionString(ctx context.Context, userID, name string) (string, error) {
+	uid, err := parseUserID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	var conn *sqlc.DatabaseConnection
+	if name == "" {
+		conn, err = s.queries.GetDefaultDatabaseConnection(ctx, uid)
+	} else {
+		conn, err = s.queries.GetDatabaseConnectionByName(ctx, sqlc.GetDatabaseConnectionByNameParams{
+			UserID: uid,
+			Name:   name,
+		})
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrConnectionNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load database connection: %w", err)
+	}
+	if conn.ConnectionString == "" {
+		return "", ErrConnectionNotFound
+	}
+
+	return conn.ConnectionString, nil
+}
+
+// ListConnections lists the connections registered for a user
+func (s *QueriesConnectionStore) ListConnections(ctx context.Context, userID string) ([]ConnectionInfo, error) {
+	uid, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conns, err := s.queries.ListDatabaseConnections(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list database connections: %w", err)
+	}
+
+	infos := make([]ConnectionInfo, 0, len(conns))
+	for _, conn := range conns {
+		infos = append(infos, ConnectionInfo{
+			Name:        conn.Name,
+			Description: conn.Description.String,
+			IsDefault:   conn.IsDefault.Bool,
+			Source:      "database_connections",
+		})
+	}
+	return infos, nil
+}
+
+// parseUserID converts a user ID string to a pgtype.UUID
+func parseUserID(userID string) (pgtype.UUID, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("invalid user ID %q: %w", userID, err)
+	}
+	return pgtype.UUID{Bytes: id, Valid: true}, nil
+}
+
+// ConnectionRequest identifies which database an action should run against
+type ConnectionRequest struct {
+	UserID           string
+	Name             string // named connection, empty for the default
+	ConnectionString string // explicit connection string, takes precedence over Name
+}
+
+// ConnectionManager resolves connection requests to pooled, read-only connections.
+// Pools of named and default connections are created lazily and kept until
+// Close is called; pools of explicit connection strings last for one call.
+type ConnectionManager struct {
+	cfg    config.Postgres
+	store  ConnectionStore
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	pools map[string]*pgxpool.Pool // keyed by connection string
+}
+
+// NewConnectionManager creates a new connection manager.
+// store may be nil, in which case only config.DefaultConnection and explicit
+// connection strings can be used.
+func NewConnectionManager(cfg config.Postgres, store ConnectionStore, logger *slog.Logger) *ConnectionManager {
+	return &ConnectionManager{
+		cfg:    cfg,
+		store:  store,
+		logger: logger,
+		pools:  make(map[string]*pgxpool.Pool),
+	}
+}
+
+// Resolve returns a pool for the request and a function releasing it once
+// the caller is done, or a nil pool if no connection is configured. An error
+// is returned only when a connection was asked for and could not be opened.
+// Explicit connection strings come from callers such as the model, so
+// their pools are not cached: release closes them, unless the string is
+// that of a cached pool.
+func (m *ConnectionManager) Resolve(ctx context.Context, req ConnectionRequest) (*pgxpool.Pool, func(), error) {
+	connStr, err := m.connectionString(ctx, req)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	if connStr == "" {
+		return nil, func() {}, nil
+	}
+
+	if req.ConnectionString != "" {
+		m.mu.Lock()
+		pool, ok := m.pools[connStr]
+		m.mu.Unlock()
+		if ok {
+			return pool, func() {}, nil
+		}
+		pool, err := m.open(ctx, connStr)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return pool, pool.Close, nil
+	}
+
+	pool, err := m.pool(ctx, connStr)
+	return pool, func() {}, err
+}
+
+// List returns the named connections visible to the user
+func (m *ConnectionManager) List(ctx context.Context, userID string) ([]ConnectionInfo, error) {
+	var infos []ConnectionInfo
+	if m.store != nil && userID != "" {
+		stored, err := m.store.ListConnections(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, stored...)
+	}
+
+	if m.cfg.DefaultConnection != "" {
+		name := m.cfg.DefaultConnection
+		if isConnectionString(name) {
+			name = "default"
+		}
+		infos = append(infos, ConnectionInfo{
+			Name:      name,
+			IsDefault: len(infos) == 0,
+			Source:    "config",
+		})
+	}
+
+	return infos, nil
+}
+
+// connectionString resolves the request to a connection string.
+// Resolution order: explicit connection string, named connection,
+// the user's default connection, then config.Postgres.DefaultConnection.
+func (m *ConnectionManager) connectionString(ctx context.Context, req ConnectionRequest) (string, error) {
+	if req.ConnectionString != "" {
+		return req.ConnectionString, nil
+	}
+
+	if req.Name != "" {
+		if m.store != nil && req.UserID != "" {
+			connStr, err := m.store.ConnectionString(ctx, req.UserID, req.Name)
+			if err == nil {
+				return connStr, nil
+			}
+			if !errors.Is(err, ErrConnectionNotFound) {
+				return "", err
+			}
+		}
+		// The configured default may itself be a connection name
+		if m.cfg.DefaultConnection == req.Name || (req.Name == "default" && isConnectionString(m.cfg.DefaultConnection)) {
+			return m.defaultFromConfig(ctx, req.UserID)
+		}
+		return "", fmt.Errorf("%w: %s", ErrConnectionNotFound, req.Name)
+	}
+
+	if m.store != nil && req.UserID != "" {
+		connStr, err := m.store.ConnectionString(ctx, req.UserID, "")
+		if err == nil {
+			return connStr, nil
+		}
+		if !errors.Is(err, ErrConnectionNotFound) {
+			m.logger.Warn("Failed to resolve default database connection",
+				slog.String("error", err.Error()))
+		}
+	}
+
+	return m.defaultFromConfig(ctx, req.UserID)
+}
+
+// defaultFromConfig resolves config.Postgres.DefaultConnection, which may be
+// either a connection string or the name of a stored connection
+func (m *ConnectionManager) defaultFromConfig(ctx context.Context, userID string) (string, error) {
+	def := m.cfg.DefaultConnection
+	if def == "" || isConnectionString(def) {
+		return def, nil
+	}
+	if m.store == nil || userID == "" {
+		return "", fmt.Errorf("%w: %s", ErrConnectionNotFound, def)
+	}
+	return m.store.ConnectionString(ctx, userID, def)
+}
+
+// pool returns a cached pool for the connection string, creating it if needed
+func (m *ConnectionManager) pool(ctx context.Context, connStr string) (*pgxpool.Pool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if pool, ok := m.pools[connStr]; ok {
+		return pool, nil
+	}
+
+	pool, err := m.open(ctx, connStr)
+	if err != nil {
+		return nil, err
+	}
+	m.pools[connStr] = pool
+	return pool, nil
+}
+
+// open creates a read-only pool for the connection string and checks
+// that it connects
+func (m *ConnectionManager) open(ctx context.Context, connStr string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection string: %w", err)
+	}
+
+	// Every session defaults to read-only and is bounded by the query timeout,
+	// so analyzers that query the pool directly can't modify data either.
+	poolConfig.MaxConns = 4
+	poolConfig.MaxConnIdleTime = 5 * time.Minute
+	if poolConfig.ConnConfig.RuntimeParams == nil {
+		poolConfig.ConnConfig.RuntimeParams = make(map[string]string)
+	}
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "goassistant-postgres-tool"
+	poolConfig.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
+	if m.cfg.QueryTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(m.cfg.QueryTimeout.Milliseconds(), 10)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return pool, nil
+}
+
+// Health pings every open pool
+func (m *ConnectionManager) Health(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pool := range m.pools {
+		if err := pool.Ping(ctx); err != nil {
+			return fmt.Errorf("database connection unhealthy: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes every open pool
+func (m *ConnectionManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for connStr, pool := range m.pools {
+		pool.Close()
+		delete(m.pools, connStr)
+	}
+}
+
+// isConnectionString reports whether s looks like a URL or keyword/value DSN
+// rather than a connection name
+func isConnectionString(s string) bool {
+	return strings.Contains(s, "://") || strings.Contains(s, "=")
+}
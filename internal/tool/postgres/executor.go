@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrStatementNotAllowed is returned when a statement can't be run safely
+var ErrStatementNotAllowed = errors.New("statement not allowed")
+
+// ReadOnlyExecutor runs queries inside READ ONLY transactions that are always
+// rolled back, with a per-transaction statement_timeout
+type ReadOnlyExecutor struct {
+	logger       *slog.Logger
+	pool         *pgxpool.Pool
+	timeout      time.Duration
+	maxQuerySize int
+}
+
+// NewReadOnlyExecutor creates a new read-only executor
+func NewReadOnlyExecutor(logger *slog.Logger, pool *pgxpool.Pool, timeout time.Duration, maxQuerySize int) *ReadOnlyExecutor {
+	return &ReadOnlyExecutor{
+		logger:       logger,
+		pool:         pool,
+		timeout:      timeout,
+		maxQuerySize: maxQuerySize,
+	}
+}
+
+// QueryResult represents rows returned by a read-only query
+type QueryResult struct {
+	Columns       []string        `json:"columns"`
+	Rows          [][]interface{} `json:"rows"`
+	RowCount      int             `json:"row_count"`
+	Truncated     bool            `json:"truncated"`
+	ExecutionTime float64         `json:"execution_time_ms"`
+}
+
+// Query executes a single statement in a READ ONLY transaction and returns
+// at most maxRows rows
+func (e *ReadOnlyExecutor) Query(ctx context.Context, query string, maxRows int) (*QueryResult, error) {
+	if err := e.checkStatement(query); err != nil {
+		return nil, err
+	}
+	if isDataModifying(query) {
+		return nil, fmt.Errorf("%w: data-modifying statements can only be explained", ErrStatementNotAllowed)
+	}
+	if maxRows <= 0 {
+		maxRows = 100
+	}
+
+	result := &QueryResult{
+		Columns: []string{},
+		Rows:    [][]interface{}{},
+	}
+
+	err := e.inTx(ctx, pgx.ReadOnly, func(tx pgx.Tx) error {
+		start := time.Now()
+		rows, err := tx.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for _, fd := range rows.FieldDescriptions() {
+			result.Columns = append(result.Columns, fd.Name)
+		}
+
+		for rows.Next() {
+			if len(result.Rows) >= maxRows {
+				result.Truncated = true
+				break
+			}
+			values, err := rows.Values()
+			if err != nil {
+				return err
+			}
+			result.Rows = append(result.Rows, values)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		result.RowCount = len(result.Rows)
+		result.ExecutionTime = float64(time.Since(start).Microseconds()) / 1000
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// Explain runs EXPLAIN (FORMAT JSON) on the query and returns the raw plan.
+// With analyze set the statement is actually executed, so it runs in a
+// transaction that is always rolled back; data-modifying statements are only
+// accepted in that mode.
+func (e *ReadOnlyExecutor) Explain(ctx context.Context, query string, analyze bool) ([]byte, error) {
+	if err := e.checkStatement(query); err != nil {
+		return nil, err
+	}
+
+	mode := pgx.ReadOnly
+	if analyze && isDataModifying(query) {
+		mode = pgx.ReadWrite
+	}
+
+	options := "FORMAT JSON"
+	if analyze {
+		options = "ANALYZE, BUFFERS, FORMAT JSON"
+	}
+
+	var plan []byte
+	err := e.inTx(ctx, mode, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, fmt.Sprintf("EXPLAIN (%s) %s", options, query)).Scan(&plan)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	return plan, nil
+}
+
+// inTx runs fn in a transaction with the given access mode and always rolls back
+func (e *ReadOnlyExecutor) inTx(ctx context.Context, mode pgx.TxAccessMode, fn func(pgx.Tx) error) error {
+	tx, err := e.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: mode})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// Rollback guard: nothing executed here is ever committed
+	defer func() {
+		if err := tx.Rollback(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			e.logger.Warn("Failed to roll back transaction", slog.String("error", err.Error()))
+		}
+	}()
+
+	if e.timeout > 0 {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", e.timeout.Milliseconds())); err != nil {
+			return fmt.Errorf("set statement_timeout: %w", err)
+		}
+	}
+
+	return fn(tx)
+}
+
+// checkStatement rejects oversized input, multiple statements and statements
+// that can't be rolled back
+func (e *ReadOnlyExecutor) checkStatement(query string) error {
+	if e.maxQuerySize > 0 && len(query) > e.maxQuerySize {
+		return fmt.Errorf("%w: query exceeds %d bytes", ErrStatementNotAllowed, e.maxQuerySize)
+	}
+
+	trimmed := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(query), ";"))
+	if trimmed == "" {
+		return fmt.Errorf("%w: empty query", ErrStatementNotAllowed)
+	}
+	if strings.Contains(trimmed, ";") {
+		return fmt.Errorf("%w: multiple statements are not supported", ErrStatementNotAllowed)
+	}
+
+	switch firstKeyword(trimmed) {
+	case "SELECT", "WITH", "VALUES", "TABLE", "SHOW", "INSERT", "UPDATE", "DELETE":
+		return nil
+	default:
+		return fmt.Errorf("%w: only SELECT, WITH, VALUES, TABLE, SHOW, INSERT, UPDATE and DELETE can be run", ErrStatementNotAllowed)
+	}
+}
+
+// isDataModifying reports whether the statement writes data
+func isDataModifying(query string) bool {
+	switch firstKeyword(query) {
+	case "INSERT", "UPDATE", "DELETE":
+		return true
+	case "WITH":
+		upper := strings.ToUpper(query)
+		return strings.Contains(upper, "INSERT ") || strings.Contains(upper, "UPDATE ") || strings.Contains(upper, "DELETE ")
+	default:
+		return false
+	}
+}
+
+// firstKeyword returns the first SQL keyword of the statement, upper-cased
+func firstKeyword(query string) string {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	end := strings.IndexFunc(query, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\r' || r == '\n' || r == '('
+	})
+	if end < 0 {
+		end = len(query)
+	}
+	return strings.ToUpper(query[:end])
+}
@@ -0,0 +1,216 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// startPostgres starts a disposable PostgreSQL container seeded with a small table
+func startPostgres(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := tcpostgres.Run(ctx, "postgres:16-alpine",
+		tcpostgres.WithDatabase("tooltest"),
+		tcpostgres.WithUsername("tooltest"),
+		tcpostgres.WithPassword("tooltest"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		t.Skipf("PostgreSQL container unavailable: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate container: %v", err)
+		}
+	})
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to get connection string: %v", err)
+	}
+
+	pool, err := pgxpool.New(ctx, connStr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE accounts (id SERIAL PRIMARY KEY, email TEXT NOT NULL, balance INT NOT NULL);
+		INSERT INTO accounts (email, balance)
+		SELECT 'user' || g || '@example.com', g FROM generate_series(1, 500) g;
+		ANALYZE accounts;`)
+	if err != nil {
+		t.Fatalf("Failed to seed database: %v", err)
+	}
+
+	return connStr
+}
+
+func TestPostgresTool_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	connStr := startPostgres(t)
+	ctx := context.Background()
+	logger := slog.Default()
+
+	cfg := config.Postgres{
+		DefaultConnection: connStr,
+		QueryTimeout:      5 * time.Second,
+		MaxQuerySize:      1 << 20,
+		EnableExplain:     true,
+	}
+	pgTool := NewPostgresTool(cfg, nil, logger)
+	defer pgTool.Close(ctx)
+
+	execute := func(params map[string]interface{}) *tool.ToolResult {
+		t.Helper()
+		result, err := pgTool.Execute(ctx, &tool.ToolInput{Parameters: params})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return result
+	}
+
+	t.Run("ExecuteQuery", func(t *testing.T) {
+		result := execute(map[string]interface{}{
+			"action":   "execute_query",
+			"query":    "SELECT id, email FROM accounts ORDER BY id",
+			"max_rows": 10,
+		})
+		if !result.Success {
+			t.Fatalf("Expected success, got %s", result.Error)
+		}
+		rows, ok := result.Data.Result.(*QueryResult)
+		if !ok {
+			t.Fatalf("Expected *QueryResult, got %T", result.Data.Result)
+		}
+		if rows.RowCount != 10 || !rows.Truncated {
+			t.Errorf("Expected 10 truncated rows, got %d (truncated=%v)", rows.RowCount, rows.Truncated)
+		}
+	})
+
+	t.Run("ExecuteQueryRejectsWrites", func(t *testing.T) {
+		result := execute(map[string]interface{}{
+			"action": "execute_query",
+			"query":  "DELETE FROM accounts",
+		})
+		if result.Success {
+			t.Fatal("Expected DELETE to be rejected")
+		}
+	})
+
+	t.Run("ReadOnlyPool", func(t *testing.T) {
+		pool, release, err := pgTool.connections.Resolve(ctx, ConnectionRequest{})
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		defer release()
+		if _, err := pool.Exec(ctx, "DELETE FROM accounts"); err == nil {
+			t.Fatal("Expected pool sessions to be read-only")
+		}
+	})
+
+	t.Run("ExplainAnalyze", func(t *testing.T) {
+		result := execute(map[string]interface{}{
+			"action":  "explain_query",
+			"query":   "SELECT * FROM accounts WHERE balance > 100",
+			"analyze": true,
+		})
+		if !result.Success {
+			t.Fatalf("Expected success, got %s", result.Error)
+		}
+		explain, ok := result.Data.Result.(*ExplainAnalysis)
+		if !ok {
+			t.Fatalf("Expected *ExplainAnalysis, got %T", result.Data.Result)
+		}
+		if explain.Plan == nil || explain.Plan.NodeType != "Seq Scan" || explain.Plan.RelationName != "accounts" {
+			t.Errorf("Unexpected plan root: %+v", explain.Plan)
+		}
+		if !explain.Analyzed || explain.Plan.ActualLoops != 1 {
+			t.Errorf("Expected actual execution statistics, got %+v", explain.Plan)
+		}
+	})
+
+	t.Run("ExplainAnalyzeRollsBack", func(t *testing.T) {
+		result := execute(map[string]interface{}{
+			"action":  "explain_query",
+			"query":   "DELETE FROM accounts",
+			"analyze": true,
+		})
+		if !result.Success {
+			t.Fatalf("Expected success, got %s", result.Error)
+		}
+
+		count := execute(map[string]interface{}{
+			"action": "execute_query",
+			"query":  "SELECT count(*) FROM accounts",
+		})
+		rows := count.Data.Result.(*QueryResult)
+		if got := rows.Rows[0][0].(int64); got != 500 {
+			t.Errorf("Expected EXPLAIN ANALYZE to be rolled back, found %d rows", got)
+		}
+	})
+
+	t.Run("StatementTimeout", func(t *testing.T) {
+		pool, release, err := pgTool.connections.Resolve(ctx, ConnectionRequest{})
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		defer release()
+		executor := NewReadOnlyExecutor(logger, pool, 100*time.Millisecond, 0)
+		_, err = executor.Query(ctx, "SELECT pg_sleep(2)", 1)
+		if err == nil {
+			t.Fatal("Expected statement timeout")
+		}
+	})
+
+	t.Run("CheckPerformance", func(t *testing.T) {
+		result := execute(map[string]interface{}{"action": "check_performance"})
+		if !result.Success {
+			t.Fatalf("Expected success, got %s", result.Error)
+		}
+		if _, ok := result.Data.Result.(*PerformanceReport); !ok {
+			t.Errorf("Expected *PerformanceReport, got %T", result.Data.Result)
+		}
+	})
+
+	t.Run("ExplicitConnectionClosed", func(t *testing.T) {
+		adHoc := connStr + "&application_name=adhoc"
+		pool, release, err := pgTool.connections.Resolve(ctx, ConnectionRequest{ConnectionString: adHoc})
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if err := pool.Ping(ctx); err != nil {
+			t.Fatalf("Ping failed: %v", err)
+		}
+		release()
+		if _, cached := pgTool.connections.pools[adHoc]; cached {
+			t.Error("Expected the explicit connection's pool not to be cached")
+		}
+		if err := pool.Ping(ctx); err == nil {
+			t.Error("Expected the explicit connection's pool to be closed on release")
+		}
+	})
+
+	t.Run("UnknownConnection", func(t *testing.T) {
+		_, _, err := pgTool.connections.Resolve(ctx, ConnectionRequest{Name: "missing"})
+		if !errors.Is(err, ErrConnectionNotFound) {
+			t.Errorf("Expected ErrConnectionNotFound, got %v", err)
+		}
+	})
+}
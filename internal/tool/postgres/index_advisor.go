@@ -244,69 +244,41 @@ func (a *IndexAdvisor) analyzeQueryPatterns(ctx context.Context, tableName strin
 	return nil
 }
 
-// extractColumnPatterns extracts column usage patterns from queries
+// extractColumnPatterns counts how often columns of tableName appear in
+// WHERE conditions, join conditions and ORDER BY clauses of query. Aliases
+// are resolved through the FROM clause; queries that fail to parse are
+// skipped.
 func (a *IndexAdvisor) extractColumnPatterns(query, tableName string,
 	whereColumns, joinColumns, orderColumns map[string]int) {
 
-	queryUpper := strings.ToUpper(query)
-	tableUpper := strings.ToUpper(tableName)
-
-	// Extract WHERE clause columns (simplified)
-	if whereIdx := strings.Index(queryUpper, "WHERE"); whereIdx > 0 {
-		whereClause := queryUpper[whereIdx:]
-		if endIdx := strings.Index(whereClause, "GROUP BY"); endIdx > 0 {
-			whereClause = whereClause[:endIdx]
-		} else if endIdx := strings.Index(whereClause, "ORDER BY"); endIdx > 0 {
-			whereClause = whereClause[:endIdx]
-		}
-
-		// Look for table.column patterns
-		pattern := tableUpper + "."
-		for i := strings.Index(whereClause, pattern); i >= 0; i = strings.Index(whereClause[i+1:], pattern) {
-			if i+len(pattern) < len(whereClause) {
-				colEnd := i + len(pattern)
-				for colEnd < len(whereClause) && isColumnChar(whereClause[colEnd]) {
-					colEnd++
-				}
-				if colEnd > i+len(pattern) {
-					column := strings.ToLower(whereClause[i+len(pattern) : colEnd])
-					whereColumns[column]++
-				}
-			}
-		}
+	script, syntaxErrors := parseSQL(query)
+	if len(syntaxErrors) > 0 {
+		a.logger.Debug("Skipping unparseable query", slog.String("error", syntaxErrors.Error()))
+		return
 	}
 
-	// Extract ORDER BY columns
-	if orderIdx := strings.Index(queryUpper, "ORDER BY"); orderIdx > 0 {
-		orderClause := queryUpper[orderIdx+8:]
-		if limitIdx := strings.Index(orderClause, "LIMIT"); limitIdx > 0 {
-			orderClause = orderClause[:limitIdx]
-		}
+	// pg_stat_statements stores unqualified names as written in the query
+	if i := strings.LastIndex(tableName, "."); i >= 0 {
+		tableName = tableName[i+1:]
+	}
 
-		// Simple extraction
-		parts := strings.Split(orderClause, ",")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if strings.Contains(part, tableUpper+".") {
-				colStart := strings.Index(part, tableUpper+".") + len(tableUpper) + 1
-				colEnd := colStart
-				for colEnd < len(part) && isColumnChar(part[colEnd]) {
-					colEnd++
-				}
-				if colEnd > colStart {
-					column := strings.ToLower(part[colStart:colEnd])
-					orderColumns[column]++
-				}
+	for _, stmt := range script.Statements {
+		for _, use := range columnUses(stmt) {
+			if use.Table != tableName {
+				continue
+			}
+			switch use.Kind {
+			case useWhere:
+				whereColumns[use.Column]++
+			case useJoin:
+				joinColumns[use.Column]++
+			case useOrder:
+				orderColumns[use.Column]++
 			}
 		}
 	}
 }
 
-// isColumnChar checks if a character can be part of a column name
-func isColumnChar(ch byte) bool {
-	return (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_'
-}
-
 // suggestFromPatterns creates index suggestions based on query patterns
 func (a *IndexAdvisor) suggestFromPatterns(tableName string,
 	whereColumns, joinColumns, orderColumns map[string]int, advice *IndexAdvice) {
@@ -354,6 +326,33 @@ func (a *IndexAdvisor) suggestFromPatterns(tableName string,
 		}
 	}
 
+	// Suggest indexes for columns frequently used in join conditions
+	joinCols := make([]string, 0, len(joinColumns))
+	for col := range joinColumns {
+		joinCols = append(joinCols, col)
+	}
+	sort.Strings(joinCols)
+	for _, col := range joinCols {
+		freq := joinColumns[col]
+		if freq < 5 || whereColumns[col] >= 5 { // Skip infrequent or already suggested columns
+			continue
+		}
+		if a.hasCompositeIndex([]string{col}, advice.ExistingIndexes) {
+			continue
+		}
+		advice.SuggestedIndexes = append(advice.SuggestedIndexes, SuggestedIndex{
+			Columns:     []string{col},
+			Type:        "btree",
+			Reason:      fmt.Sprintf("Column frequently used in JOIN conditions (%d times)", freq),
+			Impact:      "medium",
+			CreateQuery: fmt.Sprintf("CREATE INDEX idx_%s_%s ON %s (%s);", tableName, col, tableName, col),
+			Considerations: []string{
+				"Enables nested loop and merge joins on this column",
+				"Less useful when the joined side is always scanned in full",
+			},
+		})
+	}
+
 	// Suggest composite indexes for common WHERE + ORDER BY patterns
 	if len(whereFreqs) > 0 && len(orderColumns) > 0 {
 		for orderCol := range orderColumns {
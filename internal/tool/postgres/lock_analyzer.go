@@ -0,0 +1,842 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/koopa0/assistant-go/internal/tool/postgres/sqlparser"
+)
+
+// LockLevel is a PostgreSQL table-level lock mode
+type LockLevel string
+
+// Table lock modes taken by DDL and DML, weakest first
+const (
+	LockNone                 LockLevel = ""
+	LockAccessShare          LockLevel = "ACCESS SHARE"
+	LockRowExclusive         LockLevel = "ROW EXCLUSIVE"
+	LockShareUpdateExclusive LockLevel = "SHARE UPDATE EXCLUSIVE"
+	LockShare                LockLevel = "SHARE"
+	LockShareRowExclusive    LockLevel = "SHARE ROW EXCLUSIVE"
+	LockAccessExclusive      LockLevel = "ACCESS EXCLUSIVE"
+)
+
+var lockStrength = map[LockLevel]int{
+	LockNone:                 0,
+	LockAccessShare:          1,
+	LockRowExclusive:         2,
+	LockShareUpdateExclusive: 3,
+	LockShare:                4,
+	LockShareRowExclusive:    5,
+	LockAccessExclusive:      6,
+}
+
+// Blocks describes the ordinary operations a lock mode blocks
+func (l LockLevel) Blocks() string {
+	switch l {
+	case LockAccessExclusive:
+		return "reads and writes"
+	case LockShare, LockShareRowExclusive:
+		return "writes"
+	case LockShareUpdateExclusive:
+		return "other schema changes and VACUUM"
+	}
+	return "nothing"
+}
+
+// blocksWrites reports whether the lock mode blocks INSERT, UPDATE and DELETE
+func (l LockLevel) blocksWrites() bool {
+	return lockStrength[l] >= lockStrength[LockShare]
+}
+
+// defaultServerVersion is assumed when the server version is unknown
+const defaultServerVersion = 17
+
+// LockAnalyzer classifies the locks taken by migration statements
+type LockAnalyzer struct {
+	logger  *slog.Logger
+	pool    *pgxpool.Pool
+	version int
+}
+
+// NewLockAnalyzer creates a lock analyzer. version is the server major
+// version the migration will run on; 0 reads it from pool, or assumes the
+// latest release without one. With a pool, table sizes are used to estimate
+// how long locks are held.
+func NewLockAnalyzer(logger *slog.Logger, pool *pgxpool.Pool, version int) *LockAnalyzer {
+	return &LockAnalyzer{
+		logger:  logger,
+		pool:    pool,
+		version: version,
+	}
+}
+
+// LockAnalysis is the lock impact of a migration
+type LockAnalysis struct {
+	ServerVersion  int             `json:"server_version"`
+	VersionAssumed bool            `json:"version_assumed"`
+	Statements     []StatementLock `json:"statements"`
+	StrongestLock  LockLevel       `json:"strongest_lock"`
+	ZeroDowntime   bool            `json:"zero_downtime"`
+	Notes          []string        `json:"notes"`
+}
+
+// StatementLock is the lock taken by one statement or ALTER TABLE action
+type StatementLock struct {
+	Line      int       `json:"line"`
+	Operation string    `json:"operation"`
+	Table     string    `json:"table,omitempty"` // for DROP INDEX, the index's table when it is known
+	Index     string    `json:"index,omitempty"` // index dropped by DROP INDEX
+	Lock      LockLevel `json:"lock"`
+	Blocks    string    `json:"blocks"`
+	Rewrite   bool      `json:"rewrites_table"`
+	Scan      bool      `json:"scans_table"` // reads every row, or builds an index, while the lock is held
+	Reason    string    `json:"reason,omitempty"`
+	// SafeRewrite lists statements that reach the same result without
+	// blocking traffic for the duration of a scan or rewrite
+	SafeRewrite []string `json:"safe_rewrite,omitempty"`
+	Risk        string   `json:"risk"` // "low", "medium", "high"
+
+	// Set when table sizes are known
+	EstimatedRows     int64  `json:"estimated_rows,omitempty"`
+	SizeBytes         int64  `json:"size_bytes,omitempty"`
+	EstimatedDuration string `json:"estimated_duration,omitempty"`
+
+	newTable  bool
+	sizeKnown bool
+	// column and newType describe ALTER COLUMN TYPE for the rewrite check
+	column, newType string
+}
+
+// Analyze classifies the lock taken by every statement of a parsed migration
+func (a *LockAnalyzer) Analyze(ctx context.Context, src string, script *sqlparser.Script) *LockAnalysis {
+	analysis := &LockAnalysis{
+		ServerVersion: a.version,
+		Statements:    []StatementLock{},
+		Notes:         []string{},
+	}
+	if analysis.ServerVersion == 0 && a.pool != nil {
+		analysis.ServerVersion = a.serverVersion(ctx)
+	}
+	if analysis.ServerVersion == 0 {
+		analysis.ServerVersion = defaultServerVersion
+		analysis.VersionAssumed = true
+	}
+
+	c := &lockClassifier{
+		src:           src,
+		version:       analysis.ServerVersion,
+		created:       make(map[string]bool),
+		indexes:       make(map[string]string),
+		notNullChecks: make(map[string]string),
+		validated:     make(map[string]bool),
+	}
+	forEachStatement(script, func(stmt sqlparser.Stmt) {
+		analysis.Statements = append(analysis.Statements, c.classify(stmt)...)
+	})
+
+	if a.pool != nil {
+		a.estimate(ctx, analysis)
+	}
+
+	analysis.ZeroDowntime = true
+	concurrent := false
+	for i := range analysis.Statements {
+		s := &analysis.Statements[i]
+		s.Blocks = s.Lock.Blocks()
+		s.Risk = lockRisk(s)
+		if lockStrength[s.Lock] > lockStrength[analysis.StrongestLock] {
+			analysis.StrongestLock = s.Lock
+		}
+		if s.Risk != "low" {
+			analysis.ZeroDowntime = false
+		}
+		concurrent = concurrent || strings.Contains(s.Operation, "CONCURRENTLY")
+		for _, stmt := range s.SafeRewrite {
+			concurrent = concurrent || strings.Contains(stmt, "CONCURRENTLY")
+		}
+	}
+
+	if analysis.StrongestLock == LockAccessExclusive {
+		analysis.Notes = append(analysis.Notes,
+			"Set lock_timeout (e.g. SET lock_timeout = '5s') so ACCESS EXCLUSIVE locks fail fast instead of queueing every query behind them")
+	}
+	if len(analysis.Statements) > 1 {
+		analysis.Notes = append(analysis.Notes,
+			"The migration runner applies each file in one transaction, so every lock is held until the whole file commits")
+	}
+	if concurrent {
+		analysis.Notes = append(analysis.Notes,
+			"CONCURRENTLY cannot run inside a transaction block; put those statements in their own migration run outside the runner's transaction")
+	}
+	if analysis.VersionAssumed {
+		analysis.Notes = append(analysis.Notes,
+			fmt.Sprintf("Server version unknown; lock behaviour of PostgreSQL %d assumed", defaultServerVersion))
+	}
+
+	return analysis
+}
+
+// lockRisk rates how much traffic a statement can block. Locks that block
+// writes only matter when they are held for a scan, rewrite or index build.
+func lockRisk(s *StatementLock) string {
+	if !s.Lock.blocksWrites() || (!s.Rewrite && !s.Scan) || s.newTable {
+		return "low"
+	}
+	switch {
+	case !s.sizeKnown && s.Lock == LockAccessExclusive:
+		return "high"
+	case !s.sizeKnown:
+		return "medium"
+	case s.SizeBytes < 10<<20:
+		return "low"
+	case s.SizeBytes < 1<<30:
+		return "medium"
+	}
+	return "high"
+}
+
+// serverVersion returns the major version of the connected server, or 0
+func (a *LockAnalyzer) serverVersion(ctx context.Context) int {
+	var num int
+	if err := a.pool.QueryRow(ctx, "SELECT current_setting('server_version_num')::int").Scan(&num); err != nil {
+		a.logger.Warn("Failed to read server version", slog.String("error", err.Error()))
+		return 0
+	}
+	return num / 10000
+}
+
+// Rough throughput used to turn table sizes into lock durations
+const (
+	scanBytesPerSecond    = 200 << 20
+	rewriteBytesPerSecond = 50 << 20
+)
+
+// estimate fills in table sizes and lock durations, and decides from the
+// current column type whether a type change rewrites the table
+func (a *LockAnalyzer) estimate(ctx context.Context, analysis *LockAnalysis) {
+	type tableSize struct{ rows, bytes int64 }
+	sizes := make(map[string]*tableSize)
+
+	for i := range analysis.Statements {
+		s := &analysis.Statements[i]
+		if s.Table == "" && s.Index != "" {
+			err := a.pool.QueryRow(ctx, `
+				SELECT i.indrelid::regclass::text
+				FROM pg_index i
+				WHERE i.indexrelid = to_regclass($1)`, s.Index).Scan(&s.Table)
+			if err != nil {
+				a.logger.Debug("Failed to resolve the table of an index",
+					slog.String("index", s.Index),
+					slog.String("error", err.Error()))
+			}
+		}
+		if s.Table == "" || s.newTable {
+			continue
+		}
+
+		if s.newType != "" {
+			var current string
+			err := a.pool.QueryRow(ctx, `
+				SELECT format_type(a.atttypid, a.atttypmod)
+				FROM pg_attribute a
+				WHERE a.attrelid = to_regclass($1) AND a.attname = $2 AND NOT a.attisdropped`,
+				s.Table, s.column).Scan(&current)
+			if err == nil && isBinaryCoercible(current, s.newType) {
+				s.Rewrite = false
+				s.Reason = fmt.Sprintf("%s to %s needs no rewrite; the lock is brief", current, s.newType)
+				s.SafeRewrite = nil
+			}
+		}
+
+		size, ok := sizes[s.Table]
+		if !ok {
+			size = &tableSize{}
+			err := a.pool.QueryRow(ctx, `
+				SELECT GREATEST(c.reltuples, 0)::bigint, pg_total_relation_size(c.oid)
+				FROM pg_class c
+				WHERE c.oid = to_regclass($1)`, s.Table).Scan(&size.rows, &size.bytes)
+			if err != nil {
+				a.logger.Debug("Failed to read table size",
+					slog.String("table", s.Table),
+					slog.String("error", err.Error()))
+				size = nil
+			}
+			sizes[s.Table] = size
+		}
+		if size == nil {
+			continue
+		}
+		s.EstimatedRows, s.SizeBytes, s.sizeKnown = size.rows, size.bytes, true
+
+		var seconds float64
+		switch {
+		case s.Rewrite:
+			seconds = float64(size.bytes) / rewriteBytesPerSecond
+		case s.Scan:
+			seconds = float64(size.bytes) / scanBytesPerSecond
+		default:
+			continue
+		}
+		if d := time.Duration(seconds * float64(time.Second)).Round(time.Second); d > 0 {
+			s.EstimatedDuration = d.String()
+		} else {
+			s.EstimatedDuration = "under 1s"
+		}
+	}
+}
+
+// lockClassifier carries what earlier statements of the migration did
+type lockClassifier struct {
+	src     string
+	version int
+	// created holds tables created by the migration; they are empty
+	created map[string]bool
+	// indexes maps indexes created by the migration to their table
+	indexes map[string]string
+	// notNullChecks maps CHECK (col IS NOT NULL) constraint names to table.column
+	notNullChecks map[string]string
+	// validated holds table.column pairs with a validated IS NOT NULL check
+	validated map[string]bool
+}
+
+func (c *lockClassifier) classify(stmt sqlparser.Stmt) []StatementLock {
+	line := stmt.Pos().Line
+	lock := func(op string, table sqlparser.QualifiedName, level LockLevel) StatementLock {
+		return StatementLock{
+			Line:      line,
+			Operation: op,
+			Table:     table.String(),
+			Lock:      level,
+			newTable:  c.created[table.String()],
+		}
+	}
+
+	switch s := stmt.(type) {
+	case *sqlparser.CreateTableStmt:
+		c.created[s.Name.String()] = true
+		var locks []StatementLock
+		sqlparser.Inspect(s, func(n sqlparser.Node) bool {
+			if ref, ok := n.(*sqlparser.Reference); ok && ref.Table.String() != s.Name.String() {
+				l := lock("CREATE TABLE with FOREIGN KEY", ref.Table, LockShareRowExclusive)
+				l.Reason = "the referenced table is locked against writes briefly while the constraint is created"
+				locks = append(locks, l)
+			}
+			return true
+		})
+		return locks
+
+	case *sqlparser.CreateIndexStmt:
+		if s.Name != "" {
+			// Indexes live in the schema of their table
+			c.indexes[sqlparser.QualifiedName{Schema: s.Table.Schema, Name: s.Name}.String()] = s.Table.String()
+		}
+		if s.Concurrently {
+			l := lock("CREATE INDEX CONCURRENTLY", s.Table, LockShareUpdateExclusive)
+			l.Scan = true
+			l.Reason = "builds the index without blocking writes"
+			return []StatementLock{l}
+		}
+		l := lock("CREATE INDEX", s.Table, LockShare)
+		l.Scan = true
+		l.Reason = "blocks writes to the table while the index is built"
+		l.SafeRewrite = []string{concurrentIndex(c.src, s)}
+		return []StatementLock{l}
+
+	case *sqlparser.DropStmt:
+		var locks []StatementLock
+		for _, name := range s.Names {
+			switch s.ObjectType {
+			case "TABLE":
+				locks = append(locks, lock("DROP TABLE", name, LockAccessExclusive))
+			case "INDEX":
+				// The table is resolved from the migration here, or from
+				// the catalog when sizes are estimated
+				table := sqlparser.QualifiedName{Name: c.indexes[name.String()]}
+				if s.Concurrently {
+					l := lock("DROP INDEX CONCURRENTLY", table, LockShareUpdateExclusive)
+					l.Index = name.String()
+					locks = append(locks, l)
+					continue
+				}
+				l := lock("DROP INDEX", table, LockAccessExclusive)
+				l.Index = name.String()
+				l.Reason = "locks the index's table briefly"
+				l.SafeRewrite = []string{fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", name)}
+				locks = append(locks, l)
+			}
+		}
+		return locks
+
+	case *sqlparser.TruncateStmt:
+		var locks []StatementLock
+		for _, name := range s.Tables {
+			locks = append(locks, lock("TRUNCATE", name, LockAccessExclusive))
+		}
+		return locks
+
+	case *sqlparser.InsertStmt:
+		return []StatementLock{lock("INSERT", s.Table.Name, LockRowExclusive)}
+
+	case *sqlparser.UpdateStmt:
+		l := lock("UPDATE", s.Table.Name, LockRowExclusive)
+		if s.Where == nil {
+			l.Reason = "updates every row in one transaction, holding row locks and bloating the table"
+			l.SafeRewrite = []string{"Backfill in batches, e.g. UPDATE ... WHERE id BETWEEN $1 AND $2, committing between batches"}
+		}
+		return []StatementLock{l}
+
+	case *sqlparser.DeleteStmt:
+		return []StatementLock{lock("DELETE", s.Table.Name, LockRowExclusive)}
+
+	case *sqlparser.AlterTableStmt:
+		var locks []StatementLock
+		for _, action := range s.Actions {
+			l := c.alterAction(s, action)
+			l.Line = action.Pos().Line
+			l.Table = s.Table.String()
+			l.newTable = c.created[l.Table]
+			locks = append(locks, l)
+		}
+		return locks
+
+	case *sqlparser.RawStmt:
+		return c.rawStatement(s, line)
+	}
+	return nil
+}
+
+// alterAction classifies one ALTER TABLE action
+func (c *lockClassifier) alterAction(alter *sqlparser.AlterTableStmt, action *sqlparser.AlterTableAction) StatementLock {
+	table := alter.Table.String()
+	l := StatementLock{Operation: string(action.Kind), Lock: LockAccessExclusive}
+
+	switch action.Kind {
+	case sqlparser.AlterAddColumn:
+		col := action.ColumnDef
+		if reason := c.addColumnRewrite(col); reason != "" {
+			l.Rewrite = true
+			l.Reason = reason
+			l.SafeRewrite = c.addColumnSafely(alter, col)
+		} else {
+			l.Reason = "only changes the catalog; the lock is brief"
+		}
+
+	case sqlparser.AlterColumnType:
+		l.Rewrite = true
+		l.column, l.newType = action.Column, action.Type.String()
+		l.Reason = "rewrites the table and its indexes unless the change is binary coercible, such as widening a varchar"
+		l.SafeRewrite = []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s_new %s", table, action.Column, action.Type),
+			"Write to both columns from the application (or a trigger) and backfill in batches",
+			fmt.Sprintf("Swap the columns in a later migration: DROP COLUMN %s, then RENAME COLUMN %s_new TO %s",
+				action.Column, action.Column, action.Column),
+		}
+
+	case sqlparser.AlterSetNotNull:
+		key := table + "." + action.Column
+		switch {
+		case c.version >= 12 && c.validated[key]:
+			l.Reason = "a validated CHECK (column IS NOT NULL) lets PostgreSQL skip the scan"
+		default:
+			l.Scan = true
+			l.Reason = "scans the whole table under ACCESS EXCLUSIVE to check for NULLs"
+			name := truncateIdent(alter.Table.Name + "_" + action.Column + "_not_null")
+			l.SafeRewrite = []string{
+				fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", table, name, quoteIdent(action.Column)),
+				fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, name),
+			}
+			if c.version >= 12 {
+				l.SafeRewrite = append(l.SafeRewrite,
+					fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, quoteIdent(action.Column)),
+					fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, name))
+			} else {
+				l.Reason += "; before PostgreSQL 12 a CHECK constraint can't stand in for the scan, so keep the CHECK instead of SET NOT NULL"
+			}
+		}
+
+	case sqlparser.AlterAddConstraint:
+		c.addConstraint(alter, action, &l)
+
+	case sqlparser.AlterValidateConstraint:
+		l.Lock = LockShareUpdateExclusive
+		l.Scan = true
+		l.Reason = "scans the table without blocking reads or writes"
+		if key, ok := c.notNullChecks[action.Name]; ok {
+			c.validated[key] = true
+		}
+
+	case sqlparser.AlterDropColumn:
+		l.Reason = "only marks the column dropped; the lock is brief but the application must stop using the column first"
+	case sqlparser.AlterSetDefault, sqlparser.AlterDropDefault, sqlparser.AlterDropNotNull,
+		sqlparser.AlterDropConstraint, sqlparser.AlterRenameColumn, sqlparser.AlterRenameTable,
+		sqlparser.AlterRenameConstraint, sqlparser.AlterSetSchema:
+		l.Reason = "only changes the catalog; the lock is brief"
+
+	case sqlparser.AlterOther:
+		text := strings.ToUpper(action.Text)
+		switch {
+		case strings.Contains(text, "SET STATISTICS"):
+			l.Lock = LockShareUpdateExclusive
+		case strings.HasPrefix(text, "ATTACH PARTITION") && c.version >= 12:
+			l.Lock = LockShareUpdateExclusive
+			l.Scan = true
+			l.Reason = "scans the partition unless a validated CHECK matches the partition bound"
+		case strings.HasPrefix(text, "DETACH PARTITION") && strings.Contains(text, "CONCURRENTLY"):
+			l.Lock = LockShareUpdateExclusive
+		case strings.Contains(text, "SET TABLESPACE") || strings.HasPrefix(text, "SET LOGGED") || strings.HasPrefix(text, "SET UNLOGGED"):
+			l.Rewrite = true
+			l.Reason = "rewrites the table"
+		}
+	}
+	return l
+}
+
+// addColumnRewrite returns why adding a column rewrites the table, or ""
+func (c *lockClassifier) addColumnRewrite(col *sqlparser.ColumnDef) string {
+	switch strings.ToLower(col.Type.Name) {
+	case "serial", "bigserial", "smallserial", "serial4", "serial8", "serial2":
+		return "a serial column fills every row from a sequence, rewriting the table"
+	}
+	for _, con := range col.Constraints {
+		switch con.Type {
+		case sqlparser.ConstraintIdentity:
+			return "an identity column fills every row, rewriting the table"
+		case sqlparser.ConstraintGenerated:
+			return "a stored generated column is computed for every row, rewriting the table"
+		}
+	}
+	def := col.Default()
+	if def == nil {
+		return ""
+	}
+	if c.version < 11 {
+		return "before PostgreSQL 11 any DEFAULT on a new column rewrites the table"
+	}
+	if fn := volatileCall(def); fn != "" {
+		return fmt.Sprintf("the default calls volatile %s(), so every row is rewritten with its own value", fn)
+	}
+	return ""
+}
+
+// addColumnSafely adds col as a plain nullable column of its base type and
+// fills existing rows in batches, so no statement rewrites the table
+func (c *lockClassifier) addColumnSafely(alter *sqlparser.AlterTableStmt, col *sqlparser.ColumnDef) []string {
+	table := alter.Table.String()
+	column := quoteIdent(col.Name)
+	typ := *col.Type
+	serial := false
+	if base, ok := serialTypes[strings.ToLower(typ.Name)]; ok {
+		typ.Name, serial = base, true
+	}
+	var generated sqlparser.Expr
+	for _, con := range col.Constraints {
+		switch con.Type {
+		case sqlparser.ConstraintIdentity:
+			serial = true
+		case sqlparser.ConstraintGenerated:
+			generated = con.Expr
+		}
+	}
+	steps := []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, typ.String())}
+
+	var value string
+	switch {
+	case serial:
+		seq := sqlparser.QualifiedName{Schema: alter.Table.Schema, Name: sequenceName(alter.Table.Name, col.Name)}
+		value = fmt.Sprintf("nextval(%s)", quoteLiteral(seq.String()))
+		steps = append(steps, fmt.Sprintf("CREATE SEQUENCE %s OWNED BY %s.%s", seq, table, column))
+	case generated != nil:
+		value = sqlparser.Text(c.src, generated)
+		if !strings.HasPrefix(value, "(") {
+			value = "(" + value + ")"
+		}
+	default:
+		value = sqlparser.Text(c.src, col.Default())
+	}
+	if generated == nil {
+		steps = append(steps, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", table, column, value))
+	}
+	steps = append(steps, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL AND id BETWEEN $1 AND $2", table, column, value, column))
+	if generated != nil {
+		return append(steps, fmt.Sprintf("Repeat the backfill for each id range and keep %s filled from the application or a trigger; turning it into a generated column rewrites the table", col.Name))
+	}
+	return append(steps, "Repeat the backfill for each id range, then add NOT NULL and other constraints in a later migration")
+}
+
+// addConstraint classifies ALTER TABLE ... ADD CONSTRAINT
+func (c *lockClassifier) addConstraint(alter *sqlparser.AlterTableStmt, action *sqlparser.AlterTableAction, l *StatementLock) {
+	con := action.Constraint
+	table := alter.Table.String()
+	name := action.Name
+	if name == "" {
+		name = con.Name
+	}
+	if name == "" {
+		name = defaultConstraintName(alter.Table.Name, &SnapshotConstraint{Type: string(con.Type), Columns: con.Columns}, con)
+	}
+
+	switch con.Type {
+	case sqlparser.ConstraintForeignKey:
+		l.Lock = LockShareRowExclusive
+		l.Operation = "ADD FOREIGN KEY"
+		if con.NotValid {
+			l.Reason = "NOT VALID skips checking existing rows; the lock is brief"
+			return
+		}
+		l.Scan = true
+		l.Reason = fmt.Sprintf("blocks writes to %s and %s while existing rows are checked", table, con.References.Table)
+		l.SafeRewrite = []string{
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s NOT VALID", table, quoteIdent(name), constraintBody(c.src, con)),
+			fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, quoteIdent(name)),
+		}
+
+	case sqlparser.ConstraintCheck:
+		l.Operation = "ADD CHECK"
+		if column := notNullCheckColumn(con.Expr); column != "" {
+			key := table + "." + column
+			c.notNullChecks[name] = key
+			if !con.NotValid {
+				c.validated[key] = true
+			}
+		}
+		if con.NotValid {
+			l.Reason = "NOT VALID skips checking existing rows; the lock is brief"
+			return
+		}
+		l.Scan = true
+		l.Reason = "scans the whole table under ACCESS EXCLUSIVE to check existing rows"
+		l.SafeRewrite = []string{
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s NOT VALID", table, quoteIdent(name), constraintBody(c.src, con)),
+			fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, quoteIdent(name)),
+		}
+
+	case sqlparser.ConstraintPrimaryKey, sqlparser.ConstraintUnique:
+		l.Operation = "ADD " + string(con.Type)
+		if con.UsingIndex != "" {
+			l.Reason = "uses an existing index; the lock is brief"
+			return
+		}
+		l.Scan = true
+		l.Reason = "builds a unique index under ACCESS EXCLUSIVE"
+		index := name
+		l.SafeRewrite = []string{
+			fmt.Sprintf("CREATE UNIQUE INDEX CONCURRENTLY %s ON %s (%s)", quoteIdent(index), table, quoteIdents(con.Columns)),
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s USING INDEX %s", table, quoteIdent(index), con.Type, quoteIdent(index)),
+		}
+
+	case sqlparser.ConstraintExclude:
+		l.Operation = "ADD EXCLUDE"
+		l.Scan = true
+		l.Reason = "builds an index under ACCESS EXCLUSIVE"
+	}
+}
+
+// rawStatement classifies statements the parser keeps as raw tokens
+func (c *lockClassifier) rawStatement(s *sqlparser.RawStmt, line int) []StatementLock {
+	l := StatementLock{Line: line, Operation: s.Command}
+
+	switch {
+	case s.Command == "VACUUM" && s.HasKeyword("FULL"):
+		l.Operation, l.Lock, l.Rewrite = "VACUUM FULL", LockAccessExclusive, true
+		l.Reason = "rewrites the table under ACCESS EXCLUSIVE"
+		l.SafeRewrite = []string{"Use pg_repack or plain VACUUM instead"}
+	case s.Command == "VACUUM", s.Command == "ANALYZE":
+		l.Lock = LockShareUpdateExclusive
+	case s.Command == "CLUSTER":
+		l.Lock, l.Rewrite = LockAccessExclusive, true
+		l.Reason = "rewrites the table under ACCESS EXCLUSIVE"
+		l.SafeRewrite = []string{"Use pg_repack to reorder the table online"}
+	case s.Command == "REINDEX" && s.HasKeyword("CONCURRENTLY"):
+		l.Lock, l.Scan = LockShareUpdateExclusive, true
+	case s.Command == "REINDEX":
+		l.Lock, l.Scan = LockAccessExclusive, true
+		l.Reason = "blocks the table while the index is rebuilt"
+		if c.version >= 12 {
+			if stmt := concurrentReindex(s.Tokens); stmt != "" {
+				l.SafeRewrite = []string{stmt}
+			}
+		}
+	case s.Command == "REFRESH MATERIALIZED VIEW" && !s.HasKeyword("CONCURRENTLY"):
+		l.Lock, l.Rewrite = LockAccessExclusive, true
+		l.Reason = "blocks reads of the view while it is refilled"
+		l.SafeRewrite = []string{"REFRESH MATERIALIZED VIEW CONCURRENTLY (requires a unique index on the view)"}
+	case s.Command == "ALTER TYPE" && s.HasKeyword("ADD") && s.HasKeyword("VALUE"):
+		if c.version < 12 {
+			l.Reason = "before PostgreSQL 12, ALTER TYPE ... ADD VALUE cannot run inside a transaction block"
+			l.SafeRewrite = []string{"Move the statement to its own migration run outside a transaction"}
+		}
+	case s.Command == "LOCK":
+		l.Lock = LockAccessExclusive
+		for _, level := range []LockLevel{LockShareRowExclusive, LockShareUpdateExclusive, LockRowExclusive, LockAccessShare, LockShare} {
+			if strings.Contains(strings.ToUpper(sqlparser.Text(c.src, s)), " "+string(level)+" MODE") {
+				l.Lock = level
+				break
+			}
+		}
+	default:
+		return nil
+	}
+
+	if l.Lock != LockNone || l.Reason != "" {
+		l.Table = rawTarget(s.Tokens).String()
+		return []StatementLock{l}
+	}
+	return nil
+}
+
+// commandWords are the keywords that precede the object name of VACUUM,
+// CLUSTER, REINDEX, REFRESH and LOCK
+var commandWords = map[string]bool{
+	"VACUUM": true, "FULL": true, "FREEZE": true, "VERBOSE": true, "ANALYZE": true, "CLUSTER": true,
+	"REINDEX": true, "TABLE": true, "INDEX": true, "CONCURRENTLY": true, "REFRESH": true,
+	"MATERIALIZED": true, "VIEW": true, "LOCK": true, "ONLY": true,
+}
+
+// rawTarget returns the object a maintenance statement applies to
+func rawTarget(tokens []sqlparser.Token) sqlparser.QualifiedName {
+	i := 0
+	for i < len(tokens) {
+		switch {
+		case commandWords[tokens[i].Keyword()]:
+			i++
+		case tokens[i].Kind == sqlparser.TokenLParen:
+			// VACUUM (FULL, ANALYZE) options
+			for i < len(tokens) && tokens[i].Kind != sqlparser.TokenRParen {
+				i++
+			}
+			i++
+		default:
+			name, _ := rawName(tokens, i)
+			return name
+		}
+	}
+	return sqlparser.QualifiedName{}
+}
+
+// concurrentIndex renders a CREATE INDEX statement with CONCURRENTLY
+func concurrentIndex(src string, s *sqlparser.CreateIndexStmt) string {
+	text := sqlparser.Text(src, s)
+	tokens, err := sqlparser.Tokenize(text)
+	if err != nil {
+		return "CREATE INDEX CONCURRENTLY ..."
+	}
+	for _, tok := range tokens {
+		if tok.Keyword() == "INDEX" {
+			return text[:tok.Pos.Offset+len(tok.Text)] + " CONCURRENTLY" + text[tok.Pos.Offset+len(tok.Text):]
+		}
+	}
+	return text
+}
+
+// concurrentReindex renders a REINDEX statement with CONCURRENTLY, or ""
+// for REINDEX SYSTEM, which can't run concurrently
+func concurrentReindex(tokens []sqlparser.Token) string {
+	for i, tok := range tokens {
+		switch kind := tok.Keyword(); kind {
+		case "INDEX", "TABLE", "SCHEMA", "DATABASE":
+			j := i + 1
+			if j < len(tokens) && tokens[j].Keyword() == "CONCURRENTLY" {
+				j++
+			}
+			name, _ := rawName(tokens, j)
+			if name.Name == "" {
+				return "REINDEX " + kind + " CONCURRENTLY"
+			}
+			return fmt.Sprintf("REINDEX %s CONCURRENTLY %s", kind, name)
+		}
+	}
+	return ""
+}
+
+// constraintBody renders a constraint without its CONSTRAINT name prefix
+func constraintBody(src string, con *sqlparser.Constraint) string {
+	text := sqlparser.Text(src, con)
+	tokens, err := sqlparser.Tokenize(text)
+	if err != nil || len(tokens) < 3 || tokens[0].Keyword() != "CONSTRAINT" {
+		return text
+	}
+	return text[tokens[2].Pos.Offset:]
+}
+
+// volatileFuncs are functions whose value differs per row
+var volatileFuncs = map[string]bool{
+	"random": true, "gen_random_uuid": true, "uuid_generate_v1": true, "uuid_generate_v1mc": true,
+	"uuid_generate_v4": true, "uuidv4": true, "uuidv7": true, "clock_timestamp": true,
+	"timeofday": true, "nextval": true, "txid_current": true,
+}
+
+// volatileCall returns the name of a volatile function called by expr, or ""
+func volatileCall(expr sqlparser.Expr) string {
+	var name string
+	sqlparser.Inspect(expr, func(n sqlparser.Node) bool {
+		if call, ok := n.(*sqlparser.FuncCall); ok && volatileFuncs[call.Name] {
+			name = call.Name
+		}
+		return name == ""
+	})
+	return name
+}
+
+// notNullCheckColumn returns col for a CHECK (col IS NOT NULL) condition
+func notNullCheckColumn(expr sqlparser.Expr) string {
+	is, ok := expr.(*sqlparser.IsExpr)
+	if !ok || !is.Not || is.Test != "NULL" {
+		return ""
+	}
+	if ref, ok := is.Expr.(*sqlparser.ColumnRef); ok && !ref.Star {
+		return ref.Column()
+	}
+	return ""
+}
+
+// isBinaryCoercible reports whether a column type change needs no table
+// rewrite: raising or removing a varchar or numeric limit, or varchar to text
+func isBinaryCoercible(from, to string) bool {
+	if from == to {
+		return true
+	}
+	base := func(t string) (string, []int) {
+		name, mods, ok := strings.Cut(t, "(")
+		if !ok {
+			return t, nil
+		}
+		var nums []int
+		for _, m := range strings.Split(strings.TrimSuffix(mods, ")"), ",") {
+			var n int
+			fmt.Sscanf(strings.TrimSpace(m), "%d", &n)
+			nums = append(nums, n)
+		}
+		return name, nums
+	}
+	fromBase, fromMods := base(from)
+	toBase, toMods := base(to)
+	if fromBase == "varchar" {
+		fromBase = "character varying"
+	}
+	if toBase == "varchar" {
+		toBase = "character varying"
+	}
+
+	switch {
+	case fromBase == "character varying" && toBase == "text":
+		return true
+	case fromBase != toBase:
+		return false
+	case toMods == nil:
+		return fromBase == "character varying" || fromBase == "numeric"
+	case fromMods == nil:
+		return false
+	case fromBase == "character varying":
+		return toMods[0] >= fromMods[0]
+	case fromBase == "numeric" && len(fromMods) == len(toMods):
+		// Only the precision may grow; a different scale changes stored values
+		return toMods[0] >= fromMods[0] && (len(toMods) < 2 || toMods[1] == fromMods[1])
+	}
+	return false
+}
@@ -55,12 +55,15 @@ type IndexDef struct {
 
 // MigrationResult contains generated migration
 type MigrationResult struct {
-	UpSQL       string    `json:"up_sql"`
-	DownSQL     string    `json:"down_sql"`
-	Filename    string    `json:"filename"`
-	Description string    `json:"description"`
-	Timestamp   time.Time `json:"timestamp"`
-	Notes       []string  `json:"notes"`
+	UpSQL        string         `json:"up_sql"`
+	DownSQL      string         `json:"down_sql"`
+	Filename     string         `json:"filename"`
+	DownFilename string         `json:"down_filename,omitempty"`
+	Description  string         `json:"description"`
+	Timestamp    time.Time      `json:"timestamp"`
+	Notes        []string       `json:"notes"`
+	Changes      []SchemaChange `json:"changes,omitempty"`
+	Destructive  []string       `json:"destructive,omitempty"`
 }
 
 // Generate creates a migration based on parameters
@@ -91,6 +94,94 @@ func (g *MigrationGenerator) Generate(params MigrationParams) (*MigrationResult,
 	}
 }
 
+// GenerateFromDiff creates a migration pair from the diffs that apply and
+// revert a schema change. Files follow the NNN_name.up.sql and
+// NNN_name.down.sql convention of the migration runner, which wraps each
+// file in a transaction.
+func (g *MigrationGenerator) GenerateFromDiff(up, down *SchemaDiff, version int, name string) (*MigrationResult, error) {
+	if version <= 0 {
+		return nil, fmt.Errorf("migration version must be positive, got %d", version)
+	}
+	name = migrationSlug(name)
+	if name == "" {
+		return nil, fmt.Errorf("migration name is required")
+	}
+
+	result := &MigrationResult{
+		Filename:     fmt.Sprintf("%03d_%s.up.sql", version, name),
+		DownFilename: fmt.Sprintf("%03d_%s.down.sql", version, name),
+		Timestamp:    time.Now(),
+		Notes:        []string{},
+		Changes:      up.Changes,
+	}
+
+	if len(up.Changes) == 0 {
+		result.Description = fmt.Sprintf("No schema changes between %s and %s", up.From, up.To)
+		return result, nil
+	}
+	result.Description = fmt.Sprintf("%d schema changes from %s to %s", len(up.Changes), up.From, up.To)
+
+	for _, c := range up.Destructive() {
+		result.Destructive = append(result.Destructive, c.Description)
+	}
+
+	result.UpSQL = g.migrationFile(up, result.Timestamp)
+	result.DownSQL = g.migrationFile(down, result.Timestamp)
+
+	if len(result.Destructive) > 0 {
+		result.Notes = append(result.Notes,
+			fmt.Sprintf("%d destructive changes can lose data; back up affected tables before applying", len(result.Destructive)))
+	}
+	if len(down.Destructive()) > 0 {
+		result.Notes = append(result.Notes, "Rolling back this migration is destructive")
+	}
+	if up.Approximate {
+		result.Notes = append(result.Notes,
+			"The sources define expressions differently; CHECK conditions and index predicates were compared by name only")
+	}
+	for _, c := range up.Changes {
+		if c.Warning != "" {
+			result.Notes = append(result.Notes, fmt.Sprintf("%s: %s", c.Object, c.Warning))
+		}
+	}
+
+	return result, nil
+}
+
+// migrationFile renders a diff as the body of a migration file
+func (g *MigrationGenerator) migrationFile(diff *SchemaDiff, timestamp time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema migration from %s to %s\n", diff.From, diff.To)
+	fmt.Fprintf(&b, "-- Generated %s\n", timestamp.Format(time.RFC3339))
+	if destructive := diff.Destructive(); len(destructive) > 0 {
+		b.WriteString("--\n-- WARNING: this migration contains destructive changes:\n")
+		for _, c := range destructive {
+			fmt.Fprintf(&b, "--   %s\n", c.Description)
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(diff.SQL())
+	return b.String()
+}
+
+// migrationSlug turns a migration name into the snake_case used in filenames
+func migrationSlug(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+	return b.String()
+}
+
 // generateCreateTable generates a CREATE TABLE migration
 func (g *MigrationGenerator) generateCreateTable(params MigrationParams, result *MigrationResult) (*MigrationResult, error) {
 	if params.TableName == "" {
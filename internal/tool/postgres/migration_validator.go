@@ -1,24 +1,37 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/postgres/sqlparser"
 )
 
 // MigrationValidator validates PostgreSQL migration files
 type MigrationValidator struct {
 	logger *slog.Logger
+	locks  *LockAnalyzer
 }
 
-// NewMigrationValidator creates a new migration validator
+// NewMigrationValidator creates a new migration validator. Locks are
+// classified for the latest PostgreSQL release without table sizes until
+// WithLockAnalyzer provides a server version or connection.
 func NewMigrationValidator(logger *slog.Logger) *MigrationValidator {
 	return &MigrationValidator{
 		logger: logger,
+		locks:  NewLockAnalyzer(logger, nil, 0),
 	}
 }
 
+// WithLockAnalyzer sets the analyzer used to classify the locks a migration takes
+func (v *MigrationValidator) WithLockAnalyzer(locks *LockAnalyzer) *MigrationValidator {
+	v.locks = locks
+	return v
+}
+
 // ValidationResult represents the result of migration validation
 type ValidationResult struct {
 	IsValid       bool              `json:"is_valid"`
@@ -26,6 +39,7 @@ type ValidationResult struct {
 	Safety        SafetyValidation  `json:"safety"`
 	BestPractices BestPractices     `json:"best_practices"`
 	Reversibility Reversibility     `json:"reversibility"`
+	Locks         *LockAnalysis     `json:"locks"`
 	Issues        []ValidationIssue `json:"issues"`
 	Warnings      []ValidationIssue `json:"warnings"`
 	Suggestions   []string          `json:"suggestions"`
@@ -76,8 +90,16 @@ type ValidationIssue struct {
 	Suggestion string `json:"suggestion,omitempty"`
 }
 
-// Validate performs comprehensive validation on a migration SQL
+// Validate performs comprehensive validation on a migration SQL. The
+// migration is parsed into statements so that every finding refers to the
+// statement, and line, it comes from.
 func (v *MigrationValidator) Validate(migration string) (*ValidationResult, error) {
+	return v.ValidateContext(context.Background(), migration)
+}
+
+// ValidateContext is Validate with a context for the database queries of
+// the lock analyzer
+func (v *MigrationValidator) ValidateContext(ctx context.Context, migration string) (*ValidationResult, error) {
 	result := &ValidationResult{
 		IsValid:     true,
 		Issues:      []ValidationIssue{},
@@ -85,20 +107,25 @@ func (v *MigrationValidator) Validate(migration string) (*ValidationResult, erro
 		Suggestions: []string{},
 	}
 
+	script, syntaxErrors := parseSQL(migration)
+
 	// Validate syntax
-	v.validateSyntax(migration, result)
+	v.validateSyntax(migration, script, syntaxErrors, result)
+
+	// Classify the locks each statement takes
+	result.Locks = v.locks.Analyze(ctx, migration, script)
 
 	// Check safety
-	v.checkSafety(migration, result)
+	v.checkSafety(script, result)
 
 	// Check best practices
-	v.checkBestPractices(migration, result)
+	v.checkBestPractices(script, result)
 
 	// Check reversibility
-	v.checkReversibility(migration, result)
+	v.checkReversibility(migration, script, result)
 
 	// Add general suggestions
-	v.addSuggestions(migration, result)
+	v.addSuggestions(script, result)
 
 	// Determine overall validity
 	result.IsValid = len(result.Issues) == 0 && !result.Syntax.HasSyntaxErrors
@@ -106,170 +133,236 @@ func (v *MigrationValidator) Validate(migration string) (*ValidationResult, erro
 	return result, nil
 }
 
-// validateSyntax checks for SQL syntax issues
-func (v *MigrationValidator) validateSyntax(migration string, result *ValidationResult) {
+// validateSyntax reports parse errors and classifies the statements
+func (v *MigrationValidator) validateSyntax(migration string, script *sqlparser.Script,
+	syntaxErrors sqlparser.ErrorList, result *ValidationResult) {
 	syntax := &SyntaxValidation{
-		HasSyntaxErrors: false,
+		HasSyntaxErrors: len(syntaxErrors) > 0,
 		Errors:          []string{},
+		SQLStatements:   len(script.Statements),
 		StatementTypes:  []string{},
 	}
 
-	// Split into statements (simple split by semicolon)
-	statements := v.splitStatements(migration)
-	syntax.SQLStatements = len(statements)
+	for _, e := range syntaxErrors {
+		syntax.Errors = append(syntax.Errors, e.Error())
+		result.Issues = append(result.Issues, ValidationIssue{
+			Type:     "syntax_error",
+			Severity: "error",
+			Message:  fmt.Sprintf("Syntax error: %s", e.Msg),
+			Line:     e.Pos.Line,
+			Column:   e.Pos.Column,
+		})
+	}
 
-	for i, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
+	for _, stmt := range script.Statements {
+		if bad, ok := stmt.(*sqlparser.BadStmt); ok {
+			// Common typos are the usual cause of statements that fail to parse
+			v.checkTypos(sqlparser.Text(migration, bad), bad.Pos().Line, result)
 			continue
 		}
+		syntax.StatementTypes = append(syntax.StatementTypes, v.identifyStatementType(stmt))
+	}
 
-		// Check for basic syntax patterns
-		if !v.isValidStatement(stmt) {
-			syntax.HasSyntaxErrors = true
-			syntax.Errors = append(syntax.Errors, fmt.Sprintf("Invalid SQL statement at position %d", i+1))
-			result.Issues = append(result.Issues, ValidationIssue{
-				Type:     "syntax_error",
-				Severity: "error",
-				Message:  fmt.Sprintf("Invalid SQL statement syntax at statement %d", i+1),
-				Line:     i + 1,
-			})
-		}
+	v.checkTrailingSemicolon(migration, script, result)
 
-		// Identify statement type
-		stmtType := v.identifyStatementType(stmt)
-		if stmtType != "" {
-			syntax.StatementTypes = append(syntax.StatementTypes, stmtType)
-		}
+	result.Syntax = *syntax
+}
 
-		// Check for common syntax issues
-		v.checkCommonSyntaxIssues(stmt, i+1, result)
+// forEachStatement calls fn for every statement that parsed successfully
+func forEachStatement(script *sqlparser.Script, fn func(stmt sqlparser.Stmt)) {
+	for _, stmt := range script.Statements {
+		if _, bad := stmt.(*sqlparser.BadStmt); !bad {
+			fn(stmt)
+		}
 	}
+}
 
-	result.Syntax = *syntax
+// forEachAlterAction calls fn for every ALTER TABLE action in the script
+func forEachAlterAction(script *sqlparser.Script, fn func(alter *sqlparser.AlterTableStmt, action *sqlparser.AlterTableAction)) {
+	forEachStatement(script, func(stmt sqlparser.Stmt) {
+		if alter, ok := stmt.(*sqlparser.AlterTableStmt); ok {
+			for _, action := range alter.Actions {
+				fn(alter, action)
+			}
+		}
+	})
+}
+
+// destructiveDrops are the DROP object types that lose data or schema
+var destructiveDrops = map[string]bool{
+	"TABLE":    true,
+	"INDEX":    true,
+	"DATABASE": true,
+	"SCHEMA":   true,
 }
 
 // checkSafety evaluates migration safety
-func (v *MigrationValidator) checkSafety(migration string, result *ValidationResult) {
+func (v *MigrationValidator) checkSafety(script *sqlparser.Script, result *ValidationResult) {
 	safety := &SafetyValidation{
 		IsSafe:       true,
 		SafetyIssues: []string{},
 	}
 
-	migrationUpper := strings.ToUpper(migration)
-
-	// Check for destructive operations
-	destructiveOps := []string{
-		"DROP TABLE",
-		"DROP COLUMN",
-		"DROP INDEX",
-		"DROP CONSTRAINT",
-		"TRUNCATE",
-		"DROP DATABASE",
-		"DROP SCHEMA",
-	}
-
-	for _, op := range destructiveOps {
-		if strings.Contains(migrationUpper, op) {
-			safety.HasDestructiveOps = true
-			safety.DataLossRisk = true
-			safety.SafetyIssues = append(safety.SafetyIssues, fmt.Sprintf("Contains destructive operation: %s", op))
-
-			if !strings.Contains(migrationUpper, "IF EXISTS") {
-				result.Issues = append(result.Issues, ValidationIssue{
-					Type:       "safety",
-					Severity:   "error",
-					Message:    fmt.Sprintf("%s without IF EXISTS can fail if object doesn't exist", op),
-					Suggestion: fmt.Sprintf("Use %s IF EXISTS for safer execution", op),
-				})
-			}
+	destructive := func(op string, line int, ifExists, canUseIfExists bool) {
+		safety.HasDestructiveOps = true
+		safety.DataLossRisk = true
+		issue := fmt.Sprintf("Contains destructive operation: %s", op)
+		if !slices.Contains(safety.SafetyIssues, issue) {
+			safety.SafetyIssues = append(safety.SafetyIssues, issue)
 		}
-	}
 
-	// Check for operations that require exclusive locks
-	lockingOps := []struct {
-		pattern string
-		message string
-	}{
-		{"ALTER TABLE.*ADD COLUMN.*NOT NULL", "Adding NOT NULL column without default requires table rewrite"},
-		{"ALTER TABLE.*ALTER COLUMN.*TYPE", "Changing column type may require table rewrite"},
-		{"CREATE INDEX", "CREATE INDEX without CONCURRENTLY locks table for writes"},
-		{"REINDEX", "REINDEX locks table"},
-		{"CLUSTER", "CLUSTER locks table exclusively"},
-		{"VACUUM FULL", "VACUUM FULL locks table exclusively"},
-	}
-
-	for _, op := range lockingOps {
-		if matched, _ := regexp.MatchString(op.pattern, migrationUpper); matched {
-			// Special case: CREATE INDEX CONCURRENTLY doesn't lock
-			if op.pattern == "CREATE INDEX" && strings.Contains(migrationUpper, "CONCURRENTLY") {
-				continue
-			}
-
-			safety.LocksTable = true
-			safety.RequiresDowntime = true
-			safety.SafetyIssues = append(safety.SafetyIssues, op.message)
-
-			result.Warnings = append(result.Warnings, ValidationIssue{
-				Type:     "locking",
-				Severity: "warning",
-				Message:  op.message,
+		if canUseIfExists && !ifExists {
+			result.Issues = append(result.Issues, ValidationIssue{
+				Type:       "safety",
+				Severity:   "error",
+				Message:    fmt.Sprintf("%s without IF EXISTS can fail if object doesn't exist", op),
+				Line:       line,
+				Suggestion: fmt.Sprintf("Use %s IF EXISTS for safer execution", op),
 			})
 		}
 	}
 
-	// Check for adding NOT NULL without default
-	if regexp.MustCompile(`(?i)ALTER\s+TABLE.*ADD\s+COLUMN.*NOT\s+NULL`).MatchString(migration) &&
-		!regexp.MustCompile(`(?i)ALTER\s+TABLE.*ADD\s+COLUMN.*NOT\s+NULL.*DEFAULT`).MatchString(migration) {
-		safety.RequiresDowntime = true
-		result.Issues = append(result.Issues, ValidationIssue{
-			Type:       "safety",
-			Severity:   "error",
-			Message:    "Adding NOT NULL column without DEFAULT will fail if table has data",
-			Suggestion: "Add a DEFAULT value or add column as nullable first, then add constraint",
-		})
-	}
-
-	// Check for renaming operations
-	if regexp.MustCompile(`(?i)ALTER\s+TABLE.*RENAME\s+(TO|COLUMN)`).MatchString(migration) {
+	// Statements that block traffic for a scan, rewrite or index build
+	for _, lock := range result.Locks.Statements {
+		if lock.Risk == "low" {
+			continue
+		}
+		safety.LocksTable = true
+		safety.RequiresDowntime = safety.RequiresDowntime || lock.Risk == "high"
+		message := fmt.Sprintf("%s on %s takes %s lock, blocking %s: %s",
+			lock.Operation, lock.Table, lock.Lock, lock.Blocks, lock.Reason)
+		if lock.EstimatedDuration != "" {
+			message += fmt.Sprintf(" (estimated %s)", lock.EstimatedDuration)
+		}
+		if !slices.Contains(safety.SafetyIssues, message) {
+			safety.SafetyIssues = append(safety.SafetyIssues, message)
+		}
 		result.Warnings = append(result.Warnings, ValidationIssue{
-			Type:       "compatibility",
+			Type:       "locking",
 			Severity:   "warning",
-			Message:    "Renaming tables or columns can break application code",
-			Suggestion: "Ensure all application code is updated before renaming",
+			Message:    message,
+			Line:       lock.Line,
+			Suggestion: strings.Join(lock.SafeRewrite, "; "),
 		})
 	}
 
+	forEachStatement(script, func(stmt sqlparser.Stmt) {
+		line := stmt.Pos().Line
+		switch s := stmt.(type) {
+		case *sqlparser.DropStmt:
+			if destructiveDrops[s.ObjectType] {
+				destructive("DROP "+s.ObjectType, line, s.IfExists, true)
+			}
+		case *sqlparser.TruncateStmt:
+			destructive("TRUNCATE", line, false, false)
+		}
+	})
+
+	forEachAlterAction(script, func(alter *sqlparser.AlterTableStmt, action *sqlparser.AlterTableAction) {
+		line := action.Pos().Line
+		switch action.Kind {
+		case sqlparser.AlterDropColumn:
+			destructive("DROP COLUMN", line, action.IfExists, true)
+		case sqlparser.AlterDropConstraint:
+			destructive("DROP CONSTRAINT", line, action.IfExists, true)
+		case sqlparser.AlterAddColumn:
+			col := action.ColumnDef
+			if !col.NotNull() || col.Default() != nil {
+				break
+			}
+			result.Issues = append(result.Issues, ValidationIssue{
+				Type:       "safety",
+				Severity:   "error",
+				Message:    fmt.Sprintf("Adding NOT NULL column %s.%s without DEFAULT will fail if table has data", alter.Table, col.Name),
+				Line:       line,
+				Suggestion: "Add a DEFAULT value or add column as nullable first, then add constraint",
+			})
+		case sqlparser.AlterRenameTable, sqlparser.AlterRenameColumn:
+			result.Warnings = append(result.Warnings, ValidationIssue{
+				Type:       "compatibility",
+				Severity:   "warning",
+				Message:    "Renaming tables or columns can break application code",
+				Line:       line,
+				Suggestion: "Ensure all application code is updated before renaming",
+			})
+		}
+	})
+
 	safety.IsSafe = !safety.DataLossRisk && len(result.Issues) == 0
 	result.Safety = *safety
 }
 
 // checkBestPractices evaluates adherence to best practices
-func (v *MigrationValidator) checkBestPractices(migration string, result *ValidationResult) {
+func (v *MigrationValidator) checkBestPractices(script *sqlparser.Script, result *ValidationResult) {
 	practices := &BestPractices{
 		ViolatedPractices: []string{},
+		HasComments:       len(script.Comments) > 0,
+		FollowsNaming:     true,
 	}
 
-	migrationUpper := strings.ToUpper(migration)
-
-	// Check for transaction usage
-	practices.HasTransaction = strings.Contains(migrationUpper, "BEGIN") ||
-		strings.Contains(migrationUpper, "START TRANSACTION")
-
-	// Check for comments
-	practices.HasComments = strings.Contains(migration, "--") ||
-		strings.Contains(migration, "/*") ||
-		strings.Contains(migrationUpper, "COMMENT ON")
-
-	// Check for IF EXISTS usage
-	practices.UsesIfExists = strings.Contains(migrationUpper, "IF EXISTS") ||
-		strings.Contains(migrationUpper, "IF NOT EXISTS")
-
-	// Check naming conventions
-	practices.FollowsNaming = v.checkNamingConventions(migration)
+	hasDML := false
+	forEachStatement(script, func(stmt sqlparser.Stmt) {
+		line := stmt.Pos().Line
+		switch s := stmt.(type) {
+		case *sqlparser.TransactionStmt:
+			if s.Kind == "BEGIN" {
+				practices.HasTransaction = true
+			}
+		case *sqlparser.RawStmt:
+			switch s.Command {
+			case "COMMENT ON":
+				practices.HasComments = true
+			case "MERGE", "COPY":
+				hasDML = true
+			}
+			if s.HasKeyword("IF") && s.HasKeyword("EXISTS") {
+				practices.UsesIfExists = true
+			}
+		case *sqlparser.InsertStmt:
+			hasDML = true
+			if v.hasHardcodedValues(s) {
+				result.Warnings = append(result.Warnings, ValidationIssue{
+					Type:       "best_practice",
+					Severity:   "info",
+					Message:    "Migration contains hardcoded values",
+					Line:       line,
+					Suggestion: "Consider if hardcoded values should be configuration-driven",
+				})
+			}
+		case *sqlparser.UpdateStmt, *sqlparser.DeleteStmt:
+			hasDML = true
+		case *sqlparser.CreateTableStmt:
+			practices.UsesIfExists = practices.UsesIfExists || s.IfNotExists
+			if !v.followsNaming(s) {
+				practices.FollowsNaming = false
+			}
+		case *sqlparser.CreateIndexStmt:
+			practices.UsesIfExists = practices.UsesIfExists || s.IfNotExists
+			if s.Name == "" {
+				practices.ViolatedPractices = append(practices.ViolatedPractices,
+					"Indexes should have explicit names")
+				result.Warnings = append(result.Warnings, ValidationIssue{
+					Type:       "naming",
+					Severity:   "warning",
+					Message:    "Index created without explicit name",
+					Line:       line,
+					Suggestion: "Name indexes explicitly for easier management",
+				})
+			}
+		case *sqlparser.CreateViewStmt:
+			practices.UsesIfExists = practices.UsesIfExists || s.IfNotExists
+		case *sqlparser.DropStmt:
+			practices.UsesIfExists = practices.UsesIfExists || s.IfExists
+		case *sqlparser.AlterTableStmt:
+			practices.UsesIfExists = practices.UsesIfExists || s.IfExists
+			for _, action := range s.Actions {
+				practices.UsesIfExists = practices.UsesIfExists || action.IfExists || action.IfNotExists
+			}
+		}
+	})
 
 	// Evaluate violations
-	if !practices.HasTransaction && !v.containsDDLOnly(migration) {
+	if !practices.HasTransaction && hasDML {
 		practices.ViolatedPractices = append(practices.ViolatedPractices,
 			"Migration should use transactions for atomicity")
 		result.Warnings = append(result.Warnings, ValidationIssue{
@@ -291,270 +384,226 @@ func (v *MigrationValidator) checkBestPractices(migration string, result *Valida
 		})
 	}
 
-	// Check for hardcoded values
-	if regexp.MustCompile(`(?i)VALUES\s*\([^)]*'[^']+`).MatchString(migration) {
-		result.Warnings = append(result.Warnings, ValidationIssue{
-			Type:       "best_practice",
-			Severity:   "info",
-			Message:    "Migration contains hardcoded values",
-			Suggestion: "Consider if hardcoded values should be configuration-driven",
-		})
-	}
-
-	// Check for proper index naming
-	if regexp.MustCompile(`(?i)CREATE\s+INDEX\s+ON`).MatchString(migration) &&
-		!regexp.MustCompile(`(?i)CREATE\s+INDEX\s+\w+\s+ON`).MatchString(migration) {
-		practices.ViolatedPractices = append(practices.ViolatedPractices,
-			"Indexes should have explicit names")
-		result.Warnings = append(result.Warnings, ValidationIssue{
-			Type:       "naming",
-			Severity:   "warning",
-			Message:    "Index created without explicit name",
-			Suggestion: "Name indexes explicitly for easier management",
-		})
-	}
-
 	result.BestPractices = *practices
 }
 
 // checkReversibility evaluates if migration can be reversed
-func (v *MigrationValidator) checkReversibility(migration string, result *ValidationResult) {
+func (v *MigrationValidator) checkReversibility(migration string, script *sqlparser.Script, result *ValidationResult) {
 	reversibility := &Reversibility{
 		IsReversible:       true,
 		IrreversibleOps:    []string{},
 		DownMigrationHints: []string{},
 	}
 
-	migrationUpper := strings.ToUpper(migration)
-
-	// Check for inherently irreversible operations
-	irreversiblePatterns := []struct {
-		pattern string
-		op      string
-		hint    string
-	}{
-		{
-			pattern: "DROP TABLE",
-			op:      "DROP TABLE",
-			hint:    "Save table structure and data before dropping",
-		},
-		{
-			pattern: "DROP COLUMN",
-			op:      "DROP COLUMN",
-			hint:    "Save column data before dropping",
-		},
-		{
-			pattern: "ALTER COLUMN.*TYPE",
-			op:      "Column type change",
-			hint:    "Some type conversions may lose data precision",
-		},
-		{
-			pattern: "TRUNCATE",
-			op:      "TRUNCATE",
-			hint:    "Data cannot be recovered after TRUNCATE",
-		},
-	}
-
-	for _, p := range irreversiblePatterns {
-		if strings.Contains(migrationUpper, p.pattern) {
-			reversibility.IsReversible = false
-			reversibility.IrreversibleOps = append(reversibility.IrreversibleOps, p.op)
-			reversibility.DownMigrationHints = append(reversibility.DownMigrationHints, p.hint)
+	// Inherently irreversible operations
+	irreversible := func(op, hint string) {
+		reversibility.IsReversible = false
+		if slices.Contains(reversibility.IrreversibleOps, op) {
+			return
 		}
+		reversibility.IrreversibleOps = append(reversibility.IrreversibleOps, op)
+		reversibility.DownMigrationHints = append(reversibility.DownMigrationHints, hint)
 	}
-
-	// Provide down migration hints for common operations
-	if strings.Contains(migrationUpper, "CREATE TABLE") {
+	hint := func(format string, args ...any) {
 		reversibility.DownMigrationHints = append(reversibility.DownMigrationHints,
-			"Down migration: DROP TABLE IF EXISTS table_name")
+			"Down migration: "+fmt.Sprintf(format, args...))
 	}
 
-	if strings.Contains(migrationUpper, "ADD COLUMN") {
-		reversibility.DownMigrationHints = append(reversibility.DownMigrationHints,
-			"Down migration: ALTER TABLE table_name DROP COLUMN IF EXISTS column_name")
-	}
-
-	if strings.Contains(migrationUpper, "CREATE INDEX") {
-		reversibility.DownMigrationHints = append(reversibility.DownMigrationHints,
-			"Down migration: DROP INDEX IF EXISTS index_name")
-	}
-
-	if strings.Contains(migrationUpper, "ADD CONSTRAINT") {
-		reversibility.DownMigrationHints = append(reversibility.DownMigrationHints,
-			"Down migration: ALTER TABLE table_name DROP CONSTRAINT IF EXISTS constraint_name")
-	}
+	forEachStatement(script, func(stmt sqlparser.Stmt) {
+		switch s := stmt.(type) {
+		case *sqlparser.DropStmt:
+			if s.ObjectType == "TABLE" {
+				irreversible("DROP TABLE", "Save table structure and data before dropping")
+			}
+		case *sqlparser.TruncateStmt:
+			irreversible("TRUNCATE", "Data cannot be recovered after TRUNCATE")
+		case *sqlparser.CreateTableStmt:
+			// Provide down migration hints for common operations
+			hint("DROP TABLE IF EXISTS %s", s.Name)
+		case *sqlparser.CreateIndexStmt:
+			name := s.Name
+			if name == "" {
+				name = "index_name"
+			}
+			hint("DROP INDEX IF EXISTS %s", name)
+		case *sqlparser.CreateViewStmt:
+			if s.Materialized {
+				hint("DROP MATERIALIZED VIEW IF EXISTS %s", s.Name)
+			} else {
+				hint("DROP VIEW IF EXISTS %s", s.Name)
+			}
+		}
+	})
+
+	forEachAlterAction(script, func(alter *sqlparser.AlterTableStmt, action *sqlparser.AlterTableAction) {
+		switch action.Kind {
+		case sqlparser.AlterDropColumn:
+			irreversible("DROP COLUMN", "Save column data before dropping")
+		case sqlparser.AlterColumnType:
+			irreversible("Column type change", "Some type conversions may lose data precision")
+		case sqlparser.AlterAddColumn:
+			hint("ALTER TABLE %s DROP COLUMN IF EXISTS %s", alter.Table, action.Column)
+		case sqlparser.AlterAddConstraint:
+			name := action.Name
+			if name == "" {
+				name = "constraint_name"
+			}
+			hint("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", alter.Table, name)
+		case sqlparser.AlterRenameColumn:
+			hint("ALTER TABLE %s RENAME COLUMN %s TO %s", alter.Table, action.NewName, action.Column)
+		case sqlparser.AlterRenameTable:
+			hint("ALTER TABLE %s RENAME TO %s", action.NewName, alter.Table.Name)
+		}
+	})
 
 	// Check if this looks like a down migration
-	if strings.Contains(migration, ".down.sql") || strings.Contains(migrationUpper, "-- DOWN") {
+	if strings.Contains(migration, ".down.sql") {
 		reversibility.HasDownMigration = true
 	}
+	for _, comment := range script.Comments {
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(comment.Text, "--"))), "DOWN") {
+			reversibility.HasDownMigration = true
+		}
+	}
 
 	result.Reversibility = *reversibility
 }
 
 // Helper methods
 
-func (v *MigrationValidator) splitStatements(migration string) []string {
-	// Simple statement splitter - in production, use a proper SQL parser
-	statements := strings.Split(migration, ";")
-	var result []string
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt != "" {
-			result = append(result, stmt)
-		}
-	}
-	return result
+// statementTypes maps statements the parser keeps as RawStmt to their type
+var statementTypes = map[string]string{
+	"CREATE FUNCTION": "CREATE_FUNCTION",
+	"CREATE TRIGGER":  "CREATE_TRIGGER",
+	"CREATE SEQUENCE": "CREATE_SEQUENCE",
+	"GRANT":           "GRANT",
+	"REVOKE":          "REVOKE",
 }
 
-func (v *MigrationValidator) isValidStatement(stmt string) bool {
-	// Basic validation - check if statement starts with known SQL keywords
-	validStarts := []string{
-		"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "DROP",
-		"BEGIN", "COMMIT", "ROLLBACK", "START", "END", "GRANT", "REVOKE",
-		"TRUNCATE", "COMMENT", "VACUUM", "ANALYZE", "REINDEX", "CLUSTER",
-		"COPY", "DO", "EXPLAIN", "LOCK", "NOTIFY", "PREPARE", "EXECUTE",
-		"DEALLOCATE", "DECLARE", "FETCH", "MOVE", "CLOSE", "SET", "RESET",
-		"SHOW", "--", "/*", "WITH",
-	}
-
-	stmtUpper := strings.ToUpper(strings.TrimSpace(stmt))
-	for _, start := range validStarts {
-		if strings.HasPrefix(stmtUpper, start) {
-			return true
+func (v *MigrationValidator) identifyStatementType(stmt sqlparser.Stmt) string {
+	switch s := stmt.(type) {
+	case *sqlparser.CreateTableStmt:
+		return "CREATE_TABLE"
+	case *sqlparser.AlterTableStmt:
+		return "ALTER_TABLE"
+	case *sqlparser.CreateIndexStmt:
+		return "CREATE_INDEX"
+	case *sqlparser.CreateViewStmt:
+		return "CREATE_VIEW"
+	case *sqlparser.DropStmt:
+		switch s.ObjectType {
+		case "TABLE":
+			return "DROP_TABLE"
+		case "INDEX":
+			return "DROP_INDEX"
 		}
-	}
-	return false
-}
-
-func (v *MigrationValidator) identifyStatementType(stmt string) string {
-	stmtUpper := strings.ToUpper(strings.TrimSpace(stmt))
-
-	typeMap := map[string]string{
-		"CREATE TABLE":    "CREATE_TABLE",
-		"ALTER TABLE":     "ALTER_TABLE",
-		"DROP TABLE":      "DROP_TABLE",
-		"CREATE INDEX":    "CREATE_INDEX",
-		"DROP INDEX":      "DROP_INDEX",
-		"INSERT":          "INSERT",
-		"UPDATE":          "UPDATE",
-		"DELETE":          "DELETE",
-		"CREATE VIEW":     "CREATE_VIEW",
-		"CREATE FUNCTION": "CREATE_FUNCTION",
-		"CREATE TRIGGER":  "CREATE_TRIGGER",
-		"CREATE SEQUENCE": "CREATE_SEQUENCE",
-		"GRANT":           "GRANT",
-		"REVOKE":          "REVOKE",
-	}
-
-	for pattern, stmtType := range typeMap {
-		if strings.HasPrefix(stmtUpper, pattern) {
-			return stmtType
+	case *sqlparser.InsertStmt:
+		return "INSERT"
+	case *sqlparser.UpdateStmt:
+		return "UPDATE"
+	case *sqlparser.DeleteStmt:
+		return "DELETE"
+	case *sqlparser.RawStmt:
+		if t, ok := statementTypes[s.Command]; ok {
+			return t
 		}
 	}
-
 	return "OTHER"
 }
 
-func (v *MigrationValidator) checkCommonSyntaxIssues(stmt string, lineNum int, result *ValidationResult) {
-	// Check for missing semicolons (heuristic)
-	if !strings.HasSuffix(strings.TrimSpace(stmt), ";") &&
-		!strings.HasPrefix(strings.TrimSpace(stmt), "--") &&
-		!strings.HasPrefix(strings.TrimSpace(stmt), "/*") {
-		// This might be OK for the last statement, so make it a warning
-		result.Warnings = append(result.Warnings, ValidationIssue{
-			Type:       "syntax",
-			Severity:   "warning",
-			Message:    "Statement may be missing semicolon",
-			Line:       lineNum,
-			Suggestion: "Ensure all SQL statements end with semicolons",
-		})
+// checkTrailingSemicolon warns when the last statement is not terminated.
+// Earlier statements are always separated by semicolons.
+func (v *MigrationValidator) checkTrailingSemicolon(migration string, script *sqlparser.Script, result *ValidationResult) {
+	if len(script.Statements) == 0 {
+		return
 	}
-
-	// Check for common typos
-	typos := map[string]string{
-		"CRAETE":  "CREATE",
-		"TABEL":   "TABLE",
-		"FORM":    "FROM",
-		"WEHRE":   "WHERE",
-		"UDPATE":  "UPDATE",
-		"DELEET":  "DELETE",
-		"DEFUALT": "DEFAULT",
+	tokens, err := sqlparser.Tokenize(migration)
+	if err != nil {
+		return
 	}
 
-	stmtUpper := strings.ToUpper(stmt)
-	for typo, correct := range typos {
-		if strings.Contains(stmtUpper, typo) {
-			result.Issues = append(result.Issues, ValidationIssue{
-				Type:       "typo",
-				Severity:   "error",
-				Message:    fmt.Sprintf("Possible typo: '%s' should be '%s'", typo, correct),
-				Line:       lineNum,
-				Suggestion: fmt.Sprintf("Replace '%s' with '%s'", typo, correct),
-			})
+	for i := len(tokens) - 1; i >= 0; i-- {
+		kind := tokens[i].Kind
+		if kind == sqlparser.TokenEOF || kind == sqlparser.TokenComment {
+			continue
 		}
+		if kind == sqlparser.TokenSemicolon {
+			return
+		}
+		break
 	}
 
-	// Check for unmatched quotes
-	singleQuotes := strings.Count(stmt, "'") - strings.Count(stmt, "''")
-	if singleQuotes%2 != 0 {
-		result.Issues = append(result.Issues, ValidationIssue{
-			Type:       "syntax",
-			Severity:   "error",
-			Message:    "Unmatched single quotes",
-			Line:       lineNum,
-			Suggestion: "Check for missing or extra single quotes",
-		})
-	}
+	last := script.Statements[len(script.Statements)-1]
+	result.Warnings = append(result.Warnings, ValidationIssue{
+		Type:       "syntax",
+		Severity:   "warning",
+		Message:    "Statement may be missing semicolon",
+		Line:       last.Pos().Line,
+		Suggestion: "Ensure all SQL statements end with semicolons",
+	})
+}
+
+// commonTypos maps frequent misspellings of SQL keywords to the keyword
+var commonTypos = map[string]string{
+	"CRAETE":  "CREATE",
+	"TABEL":   "TABLE",
+	"FORM":    "FROM",
+	"WEHRE":   "WHERE",
+	"UDPATE":  "UPDATE",
+	"DELEET":  "DELETE",
+	"DEFUALT": "DEFAULT",
+}
 
-	// Check for unmatched parentheses
-	openParens := strings.Count(stmt, "(")
-	closeParens := strings.Count(stmt, ")")
-	if openParens != closeParens {
+// checkTypos looks for misspelled keywords in a statement that failed to parse
+func (v *MigrationValidator) checkTypos(stmt string, line int, result *ValidationResult) {
+	tokens, _ := sqlparser.Tokenize(stmt)
+	for _, tok := range tokens {
+		correct, ok := commonTypos[tok.Keyword()]
+		if !ok {
+			continue
+		}
 		result.Issues = append(result.Issues, ValidationIssue{
-			Type:       "syntax",
+			Type:       "typo",
 			Severity:   "error",
-			Message:    fmt.Sprintf("Unmatched parentheses: %d opening, %d closing", openParens, closeParens),
-			Line:       lineNum,
-			Suggestion: "Ensure all parentheses are properly matched",
+			Message:    fmt.Sprintf("Possible typo: '%s' should be '%s'", tok.Text, correct),
+			Line:       line + tok.Pos.Line - 1,
+			Column:     tok.Pos.Column,
+			Suggestion: fmt.Sprintf("Replace '%s' with '%s'", tok.Text, correct),
 		})
 	}
 }
 
-func (v *MigrationValidator) checkNamingConventions(migration string) bool {
-	// Check for consistent naming patterns
-	hasIssues := false
-
-	// Check for mixed case in table/column names (PostgreSQL folds to lowercase)
-	if regexp.MustCompile(`(?i)CREATE\s+TABLE\s+"?[a-z]*[A-Z]+[a-zA-Z]*"?\s*\(`).MatchString(migration) {
-		hasIssues = true
+// followsNaming checks that table and column names are lower case and need
+// no quoting; PostgreSQL folds unquoted names to lower case
+func (v *MigrationValidator) followsNaming(stmt *sqlparser.CreateTableStmt) bool {
+	names := []string{stmt.Name.Name}
+	for _, col := range stmt.Columns {
+		names = append(names, col.Name)
 	}
-
-	// Check for spaces in names without quotes
-	if regexp.MustCompile(`(?i)CREATE\s+TABLE\s+[a-zA-Z]+\s+[a-zA-Z]+\s*\(`).MatchString(migration) {
-		hasIssues = true
+	for _, name := range names {
+		if name != strings.ToLower(name) || strings.ContainsAny(name, " -") {
+			return false
+		}
 	}
-
-	return !hasIssues
+	return true
 }
 
-func (v *MigrationValidator) containsDDLOnly(migration string) bool {
-	// Check if migration contains only DDL (no DML)
-	dmlPatterns := []string{
-		"INSERT", "UPDATE", "DELETE", "MERGE", "COPY",
+// hasHardcodedValues reports whether an INSERT ... VALUES contains string literals
+func (v *MigrationValidator) hasHardcodedValues(stmt *sqlparser.InsertStmt) bool {
+	if stmt.Query == nil {
+		return false
 	}
-
-	migrationUpper := strings.ToUpper(migration)
-	for _, pattern := range dmlPatterns {
-		if strings.Contains(migrationUpper, pattern) {
-			return false
+	for _, row := range stmt.Query.Values {
+		for _, value := range row {
+			if lit, ok := value.(*sqlparser.Literal); ok && lit.Kind == sqlparser.LiteralString {
+				return true
+			}
 		}
 	}
-
-	return true
+	return false
 }
 
-func (v *MigrationValidator) addSuggestions(migration string, result *ValidationResult) {
+func (v *MigrationValidator) addSuggestions(script *sqlparser.Script, result *ValidationResult) {
 	// Add general migration suggestions
 	result.Suggestions = append(result.Suggestions,
 		"Test migration on a copy of production data before applying",
@@ -563,16 +612,40 @@ func (v *MigrationValidator) addSuggestions(migration string, result *Validation
 		"Consider using migration tools like golang-migrate or goose",
 	)
 
+	var alterTable, blockingIndex, setsNotNull, foreignKeys bool
+	forEachStatement(script, func(stmt sqlparser.Stmt) {
+		switch s := stmt.(type) {
+		case *sqlparser.AlterTableStmt:
+			alterTable = true
+			for _, action := range s.Actions {
+				switch {
+				case action.Kind == sqlparser.AlterSetNotNull:
+					setsNotNull = true
+				case action.Kind == sqlparser.AlterAddConstraint && action.Constraint.References != nil:
+					foreignKeys = true
+				}
+			}
+		case *sqlparser.CreateIndexStmt:
+			blockingIndex = blockingIndex || !s.Concurrently
+		case *sqlparser.CreateTableStmt:
+			sqlparser.Inspect(s, func(n sqlparser.Node) bool {
+				if _, ok := n.(*sqlparser.Reference); ok {
+					foreignKeys = true
+				}
+				return !foreignKeys
+			})
+		}
+	})
+
 	// Add specific suggestions based on content
-	if strings.Contains(strings.ToUpper(migration), "ALTER TABLE") {
+	if alterTable {
 		result.Suggestions = append(result.Suggestions,
 			"For large tables, consider running ALTER TABLE operations during low-traffic periods",
 			"Use pg_stat_progress_create_index to monitor long-running index operations",
 		)
 	}
 
-	if strings.Contains(strings.ToUpper(migration), "CREATE INDEX") &&
-		!strings.Contains(strings.ToUpper(migration), "CONCURRENTLY") {
+	if blockingIndex {
 		result.Suggestions = append(result.Suggestions,
 			"Consider using CREATE INDEX CONCURRENTLY to avoid locking the table",
 		)
@@ -593,15 +666,15 @@ func (v *MigrationValidator) addSuggestions(migration string, result *Validation
 	}
 
 	// Performance suggestions
-	if strings.Contains(strings.ToUpper(migration), "NOT NULL") {
+	if setsNotNull {
 		result.Suggestions = append(result.Suggestions,
 			"Adding NOT NULL constraints to existing columns requires a full table scan",
 		)
 	}
 
-	if regexp.MustCompile(`(?i)CREATE\s+TABLE.*REFERENCES`).MatchString(migration) {
+	if foreignKeys {
 		result.Suggestions = append(result.Suggestions,
-			"Foreign keys are created with indexes on the referencing column by default",
+			"PostgreSQL does not index referencing columns of foreign keys automatically; add indexes where they are joined or deleted from",
 		)
 	}
 }
@@ -1,10 +1,14 @@
 package postgres
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
-	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/postgres/sqlparser"
 )
 
 // QueryAnalyzer analyzes SQL queries for potential issues and improvements
@@ -29,6 +33,7 @@ type QueryAnalysis struct {
 	EstimatedCost string        `json:"estimated_cost"`
 	IndexesUsed   []string      `json:"indexes_used,omitempty"`
 	JoinAnalysis  *JoinAnalysis `json:"join_analysis,omitempty"`
+	Plan          *PlanNode     `json:"plan,omitempty"` // planner output when a connection is available
 }
 
 // QueryIssue represents an issue found in the query
@@ -48,7 +53,8 @@ type JoinAnalysis struct {
 	Suggestions []string `json:"suggestions,omitempty"`
 }
 
-// Analyze performs static analysis on a SQL query
+// Analyze performs static analysis on a SQL query. The query is parsed into
+// an AST; statements with syntax errors are reported as issues and skipped.
 func (a *QueryAnalyzer) Analyze(query string) (*QueryAnalysis, error) {
 	analysis := &QueryAnalysis{
 		Tables:      []string{},
@@ -56,26 +62,43 @@ func (a *QueryAnalyzer) Analyze(query string) (*QueryAnalysis, error) {
 		Suggestions: []string{},
 	}
 
-	// Normalize query for analysis
-	normalizedQuery := strings.ToUpper(strings.TrimSpace(query))
+	script, syntaxErrors := parseSQL(query)
+	for _, e := range syntaxErrors {
+		analysis.Issues = append(analysis.Issues, QueryIssue{
+			Severity:   "error",
+			Type:       "syntax_error",
+			Message:    e.Msg,
+			Line:       e.Pos.Line,
+			Suggestion: "Fix the syntax error; the rest of the statement was not analyzed",
+		})
+	}
+
+	var stmts []sqlparser.Stmt
+	for _, stmt := range script.Statements {
+		if _, bad := stmt.(*sqlparser.BadStmt); !bad {
+			stmts = append(stmts, stmt)
+		}
+	}
 
 	// Determine query type
-	analysis.QueryType = a.determineQueryType(normalizedQuery)
+	analysis.QueryType = a.determineQueryType(query, script)
 
 	// Extract tables
-	analysis.Tables = a.extractTables(query)
+	analysis.Tables = a.extractTables(stmts)
 
 	// Analyze for common issues
-	a.checkSelectStar(query, analysis)
-	a.checkMissingWhere(normalizedQuery, analysis)
-	a.checkJoinConditions(query, analysis)
-	a.checkIndexUsage(query, analysis)
-	a.checkSubqueries(query, analysis)
-	a.checkFunctions(query, analysis)
-	a.checkPagination(query, analysis)
+	for _, stmt := range stmts {
+		a.checkSelectStar(stmt, analysis)
+		a.checkMissingWhere(stmt, analysis)
+		a.checkJoinConditions(stmt, analysis)
+		a.checkIndexUsage(stmt, analysis)
+		a.checkSubqueries(stmt, analysis)
+		a.checkFunctions(stmt, analysis)
+		a.checkPagination(stmt, analysis)
+	}
 
 	// Determine complexity
-	analysis.Complexity = a.determineComplexity(query, analysis)
+	analysis.Complexity = a.determineComplexity(stmts, analysis)
 
 	// Add general suggestions
 	a.addGeneralSuggestions(analysis)
@@ -129,202 +152,350 @@ func (a *QueryAnalyzer) AnalyzeExplainOutput(lines []string) (*ExplainAnalysis,
 	return analysis, nil
 }
 
-// determineQueryType identifies the type of SQL query
-func (a *QueryAnalyzer) determineQueryType(query string) string {
-	switch {
-	case strings.HasPrefix(query, "SELECT"):
-		return "SELECT"
-	case strings.HasPrefix(query, "INSERT"):
-		return "INSERT"
-	case strings.HasPrefix(query, "UPDATE"):
-		return "UPDATE"
-	case strings.HasPrefix(query, "DELETE"):
-		return "DELETE"
-	case strings.HasPrefix(query, "CREATE"):
-		return "CREATE"
-	case strings.HasPrefix(query, "ALTER"):
-		return "ALTER"
-	case strings.HasPrefix(query, "DROP"):
-		return "DROP"
-	default:
+// AnalyzeExplainJSON analyzes the output of EXPLAIN (FORMAT JSON), building
+// the full plan tree alongside the flattened node list
+func (a *QueryAnalyzer) AnalyzeExplainJSON(data []byte) (*ExplainAnalysis, error) {
+	var plans []explainJSON
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse EXPLAIN output: %w", err)
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("EXPLAIN output contains no plan")
+	}
+
+	root := plans[0].Plan.toPlanNode()
+	analysis := &ExplainAnalysis{
+		Plan:            &root,
+		PlanNodes:       []PlanNode{},
+		TotalCost:       root.Cost,
+		PlanningTime:    plans[0].PlanningTime,
+		ExecutionTime:   plans[0].ExecutionTime,
+		Recommendations: []string{},
+	}
+	flattenPlan(&root, &analysis.PlanNodes)
+
+	a.analyzePlanNodes(analysis)
+
+	return analysis, nil
+}
+
+// explainJSON mirrors one element of EXPLAIN (FORMAT JSON) output
+type explainJSON struct {
+	Plan          explainJSONNode `json:"Plan"`
+	PlanningTime  float64         `json:"Planning Time"`
+	ExecutionTime float64         `json:"Execution Time"`
+}
+
+// explainJSONNode mirrors a plan node in EXPLAIN (FORMAT JSON) output
+type explainJSONNode struct {
+	NodeType            string            `json:"Node Type"`
+	RelationName        string            `json:"Relation Name"`
+	IndexName           string            `json:"Index Name"`
+	StartupCost         float64           `json:"Startup Cost"`
+	TotalCost           float64           `json:"Total Cost"`
+	PlanRows            float64           `json:"Plan Rows"`
+	PlanWidth           int               `json:"Plan Width"`
+	ActualTotalTime     float64           `json:"Actual Total Time"`
+	ActualRows          float64           `json:"Actual Rows"`
+	ActualLoops         float64           `json:"Actual Loops"`
+	Filter              string            `json:"Filter"`
+	RowsRemovedByFilter float64           `json:"Rows Removed by Filter"`
+	SharedHitBlocks     int64             `json:"Shared Hit Blocks"`
+	SharedReadBlocks    int64             `json:"Shared Read Blocks"`
+	Plans               []explainJSONNode `json:"Plans"`
+}
+
+// toPlanNode converts a JSON plan node and its children to a PlanNode tree
+func (n explainJSONNode) toPlanNode() PlanNode {
+	node := PlanNode{
+		NodeType:            n.NodeType,
+		Cost:                n.TotalCost,
+		StartupCost:         n.StartupCost,
+		Rows:                int64(n.PlanRows),
+		Width:               n.PlanWidth,
+		ActualTime:          n.ActualTotalTime,
+		ActualRows:          int64(n.ActualRows),
+		ActualLoops:         int64(n.ActualLoops),
+		RelationName:        n.RelationName,
+		IndexName:           n.IndexName,
+		Filter:              n.Filter,
+		RowsRemovedByFilter: int64(n.RowsRemovedByFilter),
+		SharedHitBlocks:     n.SharedHitBlocks,
+		SharedReadBlocks:    n.SharedReadBlocks,
+	}
+
+	switch n.NodeType {
+	case "Seq Scan":
+		node.Warning = "Sequential scan may be slow on large tables"
+	case "Nested Loop":
+		node.Warning = "Nested loops can be expensive with large datasets"
+	}
+
+	for _, child := range n.Plans {
+		node.Children = append(node.Children, child.toPlanNode())
+	}
+	return node
+}
+
+// flattenPlan appends the node and its descendants, without children, in pre-order
+func flattenPlan(node *PlanNode, out *[]PlanNode) {
+	flat := *node
+	flat.Children = nil
+	*out = append(*out, flat)
+	for i := range node.Children {
+		flattenPlan(&node.Children[i], out)
+	}
+}
+
+// determineQueryType identifies the type of SQL query from its first statement
+func (a *QueryAnalyzer) determineQueryType(query string, script *sqlparser.Script) string {
+	if len(script.Statements) == 0 {
 		return "OTHER"
 	}
+	if _, bad := script.Statements[0].(*sqlparser.BadStmt); bad {
+		return queryTypeFromKeyword(leadingKeyword(query))
+	}
+	return queryTypeOf(script.Statements[0])
 }
 
-// extractTables extracts table names from the query
-func (a *QueryAnalyzer) extractTables(query string) []string {
+// extractTables extracts the table names referenced by the statements
+func (a *QueryAnalyzer) extractTables(stmts []sqlparser.Stmt) []string {
 	tables := []string{}
-
-	// Simple regex patterns for table extraction
-	patterns := []string{
-		`(?i)FROM\s+([a-zA-Z_][a-zA-Z0-9_]*)`,
-		`(?i)JOIN\s+([a-zA-Z_][a-zA-Z0-9_]*)`,
-		`(?i)INTO\s+([a-zA-Z_][a-zA-Z0-9_]*)`,
-		`(?i)UPDATE\s+([a-zA-Z_][a-zA-Z0-9_]*)`,
-	}
-
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindAllStringSubmatch(query, -1)
-		for _, match := range matches {
-			if len(match) > 1 {
-				table := strings.ToLower(match[1])
-				// Avoid duplicates
-				found := false
-				for _, t := range tables {
-					if t == table {
-						found = true
-						break
-					}
-				}
-				if !found {
-					tables = append(tables, table)
-				}
+	for _, stmt := range stmts {
+		for _, table := range referencedTables(stmt) {
+			if !slices.Contains(tables, table) {
+				tables = append(tables, table)
 			}
 		}
 	}
-
 	return tables
 }
 
-// checkSelectStar checks for SELECT * usage
-func (a *QueryAnalyzer) checkSelectStar(query string, analysis *QueryAnalysis) {
-	if regexp.MustCompile(`(?i)SELECT\s+\*`).MatchString(query) {
-		analysis.Issues = append(analysis.Issues, QueryIssue{
-			Severity:   "warning",
-			Type:       "select_star",
-			Message:    "Using SELECT * can transfer unnecessary data and break when schema changes",
-			Suggestion: "Specify exact columns needed",
-		})
-	}
+// checkSelectStar checks for SELECT * usage. A * inside EXISTS (...) is
+// harmless because the select list is never evaluated.
+func (a *QueryAnalyzer) checkSelectStar(stmt sqlparser.Stmt, analysis *QueryAnalysis) {
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		switch n := n.(type) {
+		case *sqlparser.SubqueryExpr:
+			return !n.Exists
+		case *sqlparser.SelectStmt:
+			for _, target := range n.Targets {
+				if ref, ok := target.Expr.(*sqlparser.ColumnRef); ok && ref.Star {
+					analysis.Issues = append(analysis.Issues, QueryIssue{
+						Severity:   "warning",
+						Type:       "select_star",
+						Message:    "Using SELECT * can transfer unnecessary data and break when schema changes",
+						Line:       ref.Pos().Line,
+						Suggestion: "Specify exact columns needed",
+					})
+					return true
+				}
+			}
+		}
+		return true
+	})
 }
 
 // checkMissingWhere checks for potentially dangerous queries without WHERE
-func (a *QueryAnalyzer) checkMissingWhere(query string, analysis *QueryAnalysis) {
-	if (strings.Contains(query, "UPDATE") || strings.Contains(query, "DELETE")) &&
-		!strings.Contains(query, "WHERE") {
-		analysis.Issues = append(analysis.Issues, QueryIssue{
-			Severity:   "error",
-			Type:       "missing_where",
-			Message:    "UPDATE/DELETE without WHERE clause affects all rows",
-			Suggestion: "Add WHERE clause to limit affected rows",
-		})
-	}
+func (a *QueryAnalyzer) checkMissingWhere(stmt sqlparser.Stmt, analysis *QueryAnalysis) {
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		var missing bool
+		switch n := n.(type) {
+		case *sqlparser.UpdateStmt:
+			missing = n.Where == nil
+		case *sqlparser.DeleteStmt:
+			missing = n.Where == nil
+		}
+		if missing {
+			analysis.Issues = append(analysis.Issues, QueryIssue{
+				Severity:   "error",
+				Type:       "missing_where",
+				Message:    "UPDATE/DELETE without WHERE clause affects all rows",
+				Line:       n.Pos().Line,
+				Suggestion: "Add WHERE clause to limit affected rows",
+			})
+		}
+		return true
+	})
 }
 
 // checkJoinConditions analyzes JOIN operations
-func (a *QueryAnalyzer) checkJoinConditions(query string, analysis *QueryAnalysis) {
-	joinPattern := regexp.MustCompile(`(?i)(INNER|LEFT|RIGHT|FULL|CROSS)\s+JOIN`)
-	joins := joinPattern.FindAllString(query, -1)
-
-	if len(joins) > 0 {
-		analysis.JoinAnalysis = &JoinAnalysis{
-			JoinCount: len(joins),
-			JoinTypes: joins,
+func (a *QueryAnalyzer) checkJoinConditions(stmt sqlparser.Stmt, analysis *QueryAnalysis) {
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		join, ok := n.(*sqlparser.JoinExpr)
+		if !ok {
+			return true
+		}
+
+		if analysis.JoinAnalysis == nil {
+			analysis.JoinAnalysis = &JoinAnalysis{}
+		}
+		joinType := join.Type + " JOIN"
+		if join.Natural {
+			joinType = "NATURAL " + joinType
 		}
+		analysis.JoinAnalysis.JoinCount++
+		analysis.JoinAnalysis.JoinTypes = append(analysis.JoinAnalysis.JoinTypes, joinType)
 
-		// Check for CROSS JOIN
-		if strings.Contains(strings.ToUpper(query), "CROSS JOIN") {
+		if join.Type == "CROSS" {
 			analysis.Issues = append(analysis.Issues, QueryIssue{
 				Severity:   "warning",
 				Type:       "cross_join",
 				Message:    "CROSS JOIN produces cartesian product, can be very expensive",
+				Line:       join.Right.Pos().Line,
 				Suggestion: "Ensure CROSS JOIN is intentional, consider adding join conditions",
 			})
 		}
-
-		// Check for multiple joins
-		if len(joins) > 3 {
-			analysis.JoinAnalysis.Suggestions = append(analysis.JoinAnalysis.Suggestions,
-				"Consider breaking complex queries with many JOINs into smaller queries or using CTEs")
-		}
+		return true
+	})
+
+	// Check for multiple joins
+	if analysis.JoinAnalysis != nil && analysis.JoinAnalysis.JoinCount > 3 &&
+		len(analysis.JoinAnalysis.Suggestions) == 0 {
+		analysis.JoinAnalysis.Suggestions = append(analysis.JoinAnalysis.Suggestions,
+			"Consider breaking complex queries with many JOINs into smaller queries or using CTEs")
 	}
 }
 
 // checkIndexUsage provides hints about index usage
-func (a *QueryAnalyzer) checkIndexUsage(query string, analysis *QueryAnalysis) {
-	// Check for functions on indexed columns
-	if regexp.MustCompile(`(?i)WHERE\s+[A-Z]+\([^)]+\)\s*=`).MatchString(query) {
-		analysis.Issues = append(analysis.Issues, QueryIssue{
-			Severity:   "warning",
-			Type:       "function_on_index",
-			Message:    "Using functions on columns in WHERE clause may prevent index usage",
-			Suggestion: "Consider functional indexes or rewriting the condition",
-		})
-	}
+func (a *QueryAnalyzer) checkIndexUsage(stmt sqlparser.Stmt, analysis *QueryAnalysis) {
+	var hasOr bool
+	whereClauses(stmt, func(where sqlparser.Expr) {
+		inspectShallow(where, func(n sqlparser.Node) bool {
+			switch n := n.(type) {
+			case *sqlparser.BinaryExpr:
+				if n.Op == "OR" {
+					hasOr = true
+				}
+				// Check for functions on indexed columns
+				if isComparisonOp(n.Op) && (isFuncOnColumn(n.Left) || isFuncOnColumn(n.Right)) {
+					analysis.Issues = append(analysis.Issues, QueryIssue{
+						Severity:   "warning",
+						Type:       "function_on_index",
+						Message:    "Using functions on columns in WHERE clause may prevent index usage",
+						Line:       n.Pos().Line,
+						Suggestion: "Consider functional indexes or rewriting the condition",
+					})
+				}
 
-	// Check for LIKE with leading wildcard
-	if regexp.MustCompile(`(?i)LIKE\s+'%[^']+`).MatchString(query) {
-		analysis.Issues = append(analysis.Issues, QueryIssue{
-			Severity:   "warning",
-			Type:       "leading_wildcard",
-			Message:    "LIKE with leading wildcard prevents index usage",
-			Suggestion: "Consider full-text search or trigram indexes for pattern matching",
+			case *sqlparser.LikeExpr:
+				// Check for LIKE with leading wildcard
+				if lit, ok := n.Pattern.(*sqlparser.Literal); ok && n.Op != "SIMILAR TO" &&
+					lit.Kind == sqlparser.LiteralString && strings.HasPrefix(lit.Value, "%") {
+					analysis.Issues = append(analysis.Issues, QueryIssue{
+						Severity:   "warning",
+						Type:       "leading_wildcard",
+						Message:    "LIKE with leading wildcard prevents index usage",
+						Line:       lit.Pos().Line,
+						Suggestion: "Consider full-text search or trigram indexes for pattern matching",
+					})
+				}
+			}
+			return true
 		})
-	}
+	})
 
 	// Check for OR conditions
-	if regexp.MustCompile(`(?i)WHERE.*\sOR\s`).MatchString(query) {
-		analysis.Suggestions = append(analysis.Suggestions,
+	if hasOr {
+		a.addSuggestion(analysis,
 			"OR conditions may prevent optimal index usage. Consider using UNION for better performance")
 	}
 }
 
-// checkSubqueries analyzes subquery usage
-func (a *QueryAnalyzer) checkSubqueries(query string, analysis *QueryAnalysis) {
-	// Check for correlated subqueries
-	if regexp.MustCompile(`(?i)WHERE.*IN\s*\([^)]+SELECT`).MatchString(query) {
-		analysis.Suggestions = append(analysis.Suggestions,
-			"Consider using JOIN instead of IN (SELECT ...) for better performance")
+// isFuncOnColumn reports whether expr applies a function or cast to a column
+func isFuncOnColumn(expr sqlparser.Expr) bool {
+	var column bool
+	switch e := expr.(type) {
+	case *sqlparser.FuncCall:
+		if e.Over != nil || aggregateFuncs[e.Name] {
+			return false
+		}
+		for _, arg := range e.Args {
+			inspectShallow(arg, func(n sqlparser.Node) bool {
+				if ref, ok := n.(*sqlparser.ColumnRef); ok && !ref.Star {
+					column = true
+				}
+				return !column
+			})
+		}
+	case *sqlparser.CastExpr:
+		_, column = e.Expr.(*sqlparser.ColumnRef)
 	}
+	return column
+}
 
-	// Check for NOT IN with subquery
-	if regexp.MustCompile(`(?i)NOT\s+IN\s*\([^)]+SELECT`).MatchString(query) {
+// checkSubqueries analyzes subquery usage
+func (a *QueryAnalyzer) checkSubqueries(stmt sqlparser.Stmt, analysis *QueryAnalysis) {
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		in, ok := n.(*sqlparser.InExpr)
+		if !ok || in.Query == nil {
+			return true
+		}
+
+		if !in.Not {
+			a.addSuggestion(analysis, "Consider using JOIN instead of IN (SELECT ...) for better performance")
+			return true
+		}
+
+		// NOT IN with subquery
 		analysis.Issues = append(analysis.Issues, QueryIssue{
 			Severity:   "warning",
 			Type:       "not_in_subquery",
 			Message:    "NOT IN with subquery can have unexpected behavior with NULLs",
+			Line:       in.Pos().Line,
 			Suggestion: "Consider using NOT EXISTS or LEFT JOIN ... WHERE ... IS NULL",
 		})
-	}
+		return true
+	})
 }
 
 // checkFunctions checks for expensive function usage
-func (a *QueryAnalyzer) checkFunctions(query string, analysis *QueryAnalysis) {
-	// Check for DISTINCT
-	if regexp.MustCompile(`(?i)SELECT\s+DISTINCT`).MatchString(query) {
-		analysis.Suggestions = append(analysis.Suggestions,
-			"DISTINCT can be expensive. Ensure it's necessary and consider if data model changes could eliminate duplicates")
-	}
-
-	// Check for COUNT(*)
-	if regexp.MustCompile(`(?i)COUNT\s*\(\s*\*\s*\)`).MatchString(query) &&
-		len(analysis.Tables) > 0 {
-		analysis.Suggestions = append(analysis.Suggestions,
-			"For approximate counts on large tables, consider using pg_stat_user_tables.n_live_tup")
-	}
+func (a *QueryAnalyzer) checkFunctions(stmt sqlparser.Stmt, analysis *QueryAnalysis) {
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		switch n := n.(type) {
+		case *sqlparser.SelectStmt:
+			if n.Distinct {
+				a.addSuggestion(analysis,
+					"DISTINCT can be expensive. Ensure it's necessary and consider if data model changes could eliminate duplicates")
+			}
+		case *sqlparser.FuncCall:
+			if n.Name == "count" && n.Star && len(analysis.Tables) > 0 {
+				a.addSuggestion(analysis,
+					"For approximate counts on large tables, consider using pg_stat_user_tables.n_live_tup")
+			}
+		}
+		return true
+	})
 }
 
 // checkPagination checks for pagination patterns
-func (a *QueryAnalyzer) checkPagination(query string, analysis *QueryAnalysis) {
-	// Check for OFFSET usage
-	offsetPattern := regexp.MustCompile(`(?i)OFFSET\s+(\d+)`)
-	if matches := offsetPattern.FindStringSubmatch(query); len(matches) > 1 {
-		var offset int
-		fmt.Sscanf(matches[1], "%d", &offset)
-		if offset > 1000 {
+func (a *QueryAnalyzer) checkPagination(stmt sqlparser.Stmt, analysis *QueryAnalysis) {
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		sel, ok := n.(*sqlparser.SelectStmt)
+		if !ok || sel.Offset == nil {
+			return true
+		}
+		lit, ok := sel.Offset.(*sqlparser.Literal)
+		if !ok || lit.Kind != sqlparser.LiteralNumber {
+			return true
+		}
+
+		offset, err := strconv.Atoi(lit.Value)
+		if err == nil && offset > 1000 {
 			analysis.Issues = append(analysis.Issues, QueryIssue{
 				Severity:   "warning",
 				Type:       "large_offset",
 				Message:    fmt.Sprintf("Large OFFSET value (%d) can be inefficient", offset),
+				Line:       lit.Pos().Line,
 				Suggestion: "Consider keyset pagination (WHERE id > last_id) for better performance",
 			})
 		}
-	}
+		return true
+	})
 }
 
 // determineComplexity estimates query complexity
-func (a *QueryAnalyzer) determineComplexity(query string, analysis *QueryAnalysis) string {
+func (a *QueryAnalyzer) determineComplexity(stmts []sqlparser.Stmt, analysis *QueryAnalysis) string {
 	score := 0
 
 	// Factor in number of tables
@@ -335,17 +506,46 @@ func (a *QueryAnalyzer) determineComplexity(query string, analysis *QueryAnalysi
 		score += analysis.JoinAnalysis.JoinCount * 3
 	}
 
+	var subqueries int
+	var aggregates, ctes bool
+	for _, stmt := range stmts {
+		sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+			switch n := n.(type) {
+			case *sqlparser.SubqueryExpr, *sqlparser.SubqueryTable:
+				subqueries++
+			case *sqlparser.InExpr:
+				if n.Query != nil {
+					subqueries++
+				}
+			case *sqlparser.QuantifiedExpr:
+				if n.Query != nil {
+					subqueries++
+				}
+			case *sqlparser.SelectStmt:
+				if len(n.GroupBy) > 0 || n.Having != nil {
+					aggregates = true
+				}
+			case *sqlparser.FuncCall:
+				if aggregateFuncs[n.Name] {
+					aggregates = true
+				}
+			case *sqlparser.WithClause:
+				ctes = true
+			}
+			return true
+		})
+	}
+
 	// Factor in subqueries
-	subqueryCount := strings.Count(strings.ToUpper(query), "(SELECT")
-	score += subqueryCount * 4
+	score += subqueries * 4
 
 	// Factor in aggregations
-	if regexp.MustCompile(`(?i)(GROUP BY|HAVING|COUNT|SUM|AVG|MAX|MIN)`).MatchString(query) {
+	if aggregates {
 		score += 3
 	}
 
 	// Factor in CTEs
-	if regexp.MustCompile(`(?i)WITH\s+\w+\s+AS`).MatchString(query) {
+	if ctes {
 		score += 2
 	}
 
@@ -359,6 +559,13 @@ func (a *QueryAnalyzer) determineComplexity(query string, analysis *QueryAnalysi
 	}
 }
 
+// addSuggestion adds a suggestion unless it is already present
+func (a *QueryAnalyzer) addSuggestion(analysis *QueryAnalysis, suggestion string) {
+	if !slices.Contains(analysis.Suggestions, suggestion) {
+		analysis.Suggestions = append(analysis.Suggestions, suggestion)
+	}
+}
+
 // addGeneralSuggestions adds general optimization suggestions
 func (a *QueryAnalyzer) addGeneralSuggestions(analysis *QueryAnalysis) {
 	if analysis.Complexity == "complex" {
@@ -405,13 +612,30 @@ func (a *QueryAnalyzer) analyzePlanNodes(analysis *ExplainAnalysis) {
 	seqScanCount := 0
 	nestedLoopCount := 0
 
+	var misestimated []string
+
 	for _, node := range analysis.PlanNodes {
-		if node.NodeType == "Sequential Scan" {
+		if node.NodeType == "Sequential Scan" || node.NodeType == "Seq Scan" {
 			seqScanCount++
 		}
 		if node.NodeType == "Nested Loop" {
 			nestedLoopCount++
 		}
+
+		// Row estimates off by more than 10x usually mean stale statistics
+		if node.ActualLoops > 0 && node.Rows > 0 {
+			actual := node.ActualRows
+			if actual == 0 {
+				actual = 1
+			}
+			if actual > node.Rows*10 || node.Rows > actual*10 {
+				target := node.NodeType
+				if node.RelationName != "" {
+					target = fmt.Sprintf("%s on %s", node.NodeType, node.RelationName)
+				}
+				misestimated = append(misestimated, target)
+			}
+		}
 	}
 
 	if seqScanCount > 0 {
@@ -424,6 +648,12 @@ func (a *QueryAnalyzer) analyzePlanNodes(analysis *ExplainAnalysis) {
 			"Multiple nested loops detected. Consider restructuring query or adding indexes")
 	}
 
+	if len(misestimated) > 0 {
+		analysis.Recommendations = append(analysis.Recommendations,
+			fmt.Sprintf("Row estimates are off by more than 10x for %s. Run ANALYZE to refresh planner statistics",
+				strings.Join(misestimated, ", ")))
+	}
+
 	if analysis.ExecutionTime > 1000 {
 		analysis.Recommendations = append(analysis.Recommendations,
 			fmt.Sprintf("Query took %.2f seconds. Consider optimization for queries over 1 second", analysis.ExecutionTime/1000))
@@ -433,9 +663,12 @@ func (a *QueryAnalyzer) analyzePlanNodes(analysis *ExplainAnalysis) {
 // ExplainAnalysis represents the analysis of EXPLAIN output
 type ExplainAnalysis struct {
 	QueryAnalysis   *QueryAnalysis `json:"query_analysis,omitempty"`
+	Plan            *PlanNode      `json:"plan,omitempty"`
 	PlanNodes       []PlanNode     `json:"plan_nodes"`
 	TotalCost       float64        `json:"total_cost"`
+	PlanningTime    float64        `json:"planning_time_ms,omitempty"`
 	ExecutionTime   float64        `json:"execution_time_ms"`
+	Analyzed        bool           `json:"analyzed"`
 	Recommendations []string       `json:"recommendations"`
 	Note            string         `json:"note,omitempty"`
 }
@@ -450,4 +683,14 @@ type PlanNode struct {
 	ActualRows int64   `json:"actual_rows,omitempty"`
 	IndexName  string  `json:"index_name,omitempty"`
 	Warning    string  `json:"warning,omitempty"`
+
+	// Populated from EXPLAIN (FORMAT JSON) output
+	RelationName        string     `json:"relation_name,omitempty"`
+	StartupCost         float64    `json:"startup_cost,omitempty"`
+	ActualLoops         int64      `json:"actual_loops,omitempty"`
+	Filter              string     `json:"filter,omitempty"`
+	RowsRemovedByFilter int64      `json:"rows_removed_by_filter,omitempty"`
+	SharedHitBlocks     int64      `json:"shared_hit_blocks,omitempty"`
+	SharedReadBlocks    int64      `json:"shared_read_blocks,omitempty"`
+	Children            []PlanNode `json:"children,omitempty"`
 }
@@ -3,8 +3,12 @@ package postgres
 import (
 	"fmt"
 	"log/slog"
-	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/postgres/sqlparser"
 )
 
 // QueryOptimizer suggests optimizations for SQL queries
@@ -46,8 +50,15 @@ type IndexSuggestion struct {
 	CreateQuery string   `json:"create_query"`
 }
 
-// Optimize analyzes and optimizes a SQL query
+// Optimize analyzes and optimizes a SQL query. The query must parse;
+// rewrites are applied to the source text at positions taken from the AST,
+// so comments and formatting outside the rewritten spans are preserved.
 func (o *QueryOptimizer) Optimize(query string) (*OptimizationResult, error) {
+	script, syntaxErrors := parseSQL(query)
+	if len(syntaxErrors) > 0 {
+		return nil, fmt.Errorf("failed to parse query: %w", syntaxErrors)
+	}
+
 	result := &OptimizationResult{
 		OriginalQuery:    query,
 		OptimizedQuery:   query,
@@ -55,19 +66,20 @@ func (o *QueryOptimizer) Optimize(query string) (*OptimizationResult, error) {
 		Warnings:         []string{},
 		IndexSuggestions: []IndexSuggestion{},
 	}
+	stmts := script.Statements
 
 	// Apply various optimizations
-	o.optimizeSelectStar(result)
-	o.optimizeJoins(result)
-	o.optimizeSubqueries(result)
-	o.optimizeInClauses(result)
-	o.optimizePagination(result)
-	o.optimizeDistinct(result)
-	o.optimizeFunctions(result)
-	o.optimizeExists(result)
+	o.optimizeSelectStar(stmts, result)
+	o.optimizeJoins(stmts, result)
+	o.optimizeSubqueries(stmts, result)
+	o.optimizeInClauses(stmts, result)
+	o.optimizePagination(stmts, result)
+	o.optimizeDistinct(stmts, result)
+	o.optimizeFunctions(stmts, result)
+	o.optimizeExists(stmts, result)
 
 	// Suggest indexes based on query patterns
-	o.suggestIndexes(result)
+	o.suggestIndexes(stmts, result)
 
 	// Calculate expected benefit
 	o.calculateBenefit(result)
@@ -81,9 +93,33 @@ func (o *QueryOptimizer) Optimize(query string) (*OptimizationResult, error) {
 	return result, nil
 }
 
+// inspectAll calls sqlparser.Inspect for each statement
+func inspectAll(stmts []sqlparser.Stmt, fn func(sqlparser.Node) bool) {
+	for _, stmt := range stmts {
+		sqlparser.Inspect(stmt, fn)
+	}
+}
+
+// hasSelectStar reports whether any select list uses *, ignoring EXISTS subqueries
+func hasSelectStar(stmts []sqlparser.Stmt) bool {
+	var found bool
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		switch n := n.(type) {
+		case *sqlparser.SubqueryExpr:
+			return !n.Exists
+		case *sqlparser.ResultTarget:
+			if ref, ok := n.Expr.(*sqlparser.ColumnRef); ok && ref.Star {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
 // optimizeSelectStar replaces SELECT * with specific columns
-func (o *QueryOptimizer) optimizeSelectStar(result *OptimizationResult) {
-	if !regexp.MustCompile(`(?i)SELECT\s+\*`).MatchString(result.OptimizedQuery) {
+func (o *QueryOptimizer) optimizeSelectStar(stmts []sqlparser.Stmt, result *OptimizationResult) {
+	if !hasSelectStar(stmts) {
 		return
 	}
 
@@ -99,27 +135,55 @@ func (o *QueryOptimizer) optimizeSelectStar(result *OptimizationResult) {
 		"Cannot automatically determine columns - please specify exact columns needed")
 }
 
+// textEdit replaces the source text between two offsets
+type textEdit struct {
+	from, to int
+	text     string
+}
+
+// applyEdits applies non-overlapping edits to src
+func applyEdits(src string, edits []textEdit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].from > edits[j].from })
+	for _, e := range edits {
+		src = src[:e.from] + e.text + src[e.to:]
+	}
+	return src
+}
+
 // optimizeJoins optimizes JOIN operations
-func (o *QueryOptimizer) optimizeJoins(result *OptimizationResult) {
-	query := result.OptimizedQuery
+func (o *QueryOptimizer) optimizeJoins(stmts []sqlparser.Stmt, result *OptimizationResult) {
+	// Convert implicit (comma) joins to explicit CROSS JOIN syntax. Only the
+	// separators are rewritten, so the FROM items keep their exact text.
+	var edits []textEdit
+	joinCount := 0
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		switch n := n.(type) {
+		case *sqlparser.SelectStmt:
+			for i := 1; i < len(n.From); i++ {
+				edits = append(edits, textEdit{
+					from: n.From[i-1].End().Offset,
+					to:   n.From[i].Pos().Offset,
+					text: " CROSS JOIN ",
+				})
+			}
+		case *sqlparser.JoinExpr:
+			joinCount++
+		}
+		return true
+	})
 
-	// Check for implicit cross joins
-	if regexp.MustCompile(`(?i)FROM\s+\w+\s*,\s*\w+`).MatchString(query) {
+	if len(edits) > 0 {
 		result.Optimizations = append(result.Optimizations, OptimizationDetail{
 			Type:        "explicit_join",
 			Description: "Convert implicit joins to explicit JOIN syntax",
 			Reasoning:   "Explicit JOINs are clearer and allow better optimization by the query planner",
 			Impact:      "medium",
 		})
-
-		// Convert implicit to explicit (simplified)
-		result.OptimizedQuery = regexp.MustCompile(`(?i)(FROM\s+)(\w+)\s*,\s*(\w+)`).
-			ReplaceAllString(result.OptimizedQuery, "$1$2 CROSS JOIN $3")
+		result.OptimizedQuery = applyEdits(result.OptimizedQuery, edits)
 	}
 
 	// Suggest join order optimization
-	joinCount := len(regexp.MustCompile(`(?i)JOIN`).FindAllString(query, -1))
-	if joinCount > 2 {
+	if joinCount+len(edits) > 2 {
 		result.Optimizations = append(result.Optimizations, OptimizationDetail{
 			Type:        "join_order",
 			Description: "Consider reordering JOINs to filter earlier",
@@ -130,12 +194,16 @@ func (o *QueryOptimizer) optimizeJoins(result *OptimizationResult) {
 }
 
 // optimizeSubqueries converts subqueries to JOINs where beneficial
-func (o *QueryOptimizer) optimizeSubqueries(result *OptimizationResult) {
-	query := result.OptimizedQuery
-
+func (o *QueryOptimizer) optimizeSubqueries(stmts []sqlparser.Stmt, result *OptimizationResult) {
 	// Check for IN (SELECT ...) pattern
-	inSubqueryPattern := regexp.MustCompile(`(?i)(\w+)\s+IN\s*\(\s*SELECT\s+(\w+)\s+FROM\s+(\w+)([^)]*)\)`)
-	if matches := inSubqueryPattern.FindStringSubmatch(query); len(matches) > 0 {
+	var inSubquery *sqlparser.InExpr
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		if in, ok := n.(*sqlparser.InExpr); ok && in.Query != nil && !in.Not && inSubquery == nil {
+			inSubquery = in
+		}
+		return true
+	})
+	if inSubquery != nil {
 		result.Optimizations = append(result.Optimizations, OptimizationDetail{
 			Type:        "subquery_to_join",
 			Description: "Convert IN (SELECT ...) to JOIN for better performance",
@@ -145,11 +213,12 @@ func (o *QueryOptimizer) optimizeSubqueries(result *OptimizationResult) {
 
 		// Provide example conversion
 		result.Warnings = append(result.Warnings,
-			fmt.Sprintf("Consider converting: %s IN (SELECT ...) to EXISTS or JOIN", matches[1]))
+			fmt.Sprintf("Consider converting: %s IN (SELECT ...) to EXISTS or JOIN",
+				sqlparser.Text(result.OriginalQuery, inSubquery.Expr)))
 	}
 
 	// Check for correlated subqueries
-	if strings.Contains(query, "SELECT") && strings.Count(query, "SELECT") > 1 {
+	if countCorrelatedSubqueries(stmts) > 0 {
 		result.Optimizations = append(result.Optimizations, OptimizationDetail{
 			Type:        "correlated_subquery",
 			Description: "Review correlated subqueries for optimization",
@@ -159,22 +228,95 @@ func (o *QueryOptimizer) optimizeSubqueries(result *OptimizationResult) {
 	}
 }
 
-// optimizeInClauses optimizes IN clauses
-func (o *QueryOptimizer) optimizeInClauses(result *OptimizationResult) {
-	query := result.OptimizedQuery
+// countCorrelatedSubqueries counts subqueries that reference a table of an
+// enclosing query through its name or alias
+func countCorrelatedSubqueries(stmts []sqlparser.Stmt) int {
+	count := 0
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		var outer map[string]string
+		var exprs []sqlparser.Expr
+		switch n := n.(type) {
+		case *sqlparser.SelectStmt:
+			outer = aliasTables(nil, n.From)
+			exprs = append(exprs, n.Where, n.Having)
+			for _, t := range n.Targets {
+				exprs = append(exprs, t.Expr)
+			}
+		case *sqlparser.UpdateStmt:
+			outer = aliasTables(n.Table, n.From)
+			exprs = append(exprs, n.Where)
+			for _, set := range n.Set {
+				exprs = append(exprs, set.Value)
+			}
+		case *sqlparser.DeleteStmt:
+			outer = aliasTables(n.Table, n.Using)
+			exprs = append(exprs, n.Where)
+		default:
+			return true
+		}
 
-	// Check for large IN clauses
-	inPattern := regexp.MustCompile(`(?i)IN\s*\([^)]+\)`)
-	inClauses := inPattern.FindAllString(query, -1)
+		for _, expr := range exprs {
+			if expr == nil {
+				continue
+			}
+			sqlparser.Inspect(expr, func(n sqlparser.Node) bool {
+				sub, ok := n.(*sqlparser.SelectStmt)
+				if !ok {
+					return true
+				}
+				if isCorrelated(sub, outer) {
+					count++
+				}
+				return false
+			})
+		}
+		return true
+	})
+	return count
+}
+
+// isCorrelated reports whether sub references a qualifier that is not
+// defined inside it but is defined by the enclosing query
+func isCorrelated(sub *sqlparser.SelectStmt, outer map[string]string) bool {
+	inner := make(map[string]bool)
+	sqlparser.Inspect(sub, func(n sqlparser.Node) bool {
+		if sel, ok := n.(*sqlparser.SelectStmt); ok {
+			for alias := range aliasTables(nil, sel.From) {
+				inner[alias] = true
+			}
+		}
+		return true
+	})
+
+	correlated := false
+	sqlparser.Inspect(sub, func(n sqlparser.Node) bool {
+		if ref, ok := n.(*sqlparser.ColumnRef); ok {
+			if q := ref.Qualifier(); q != "" && !inner[q] && outer[q] != "" {
+				correlated = true
+			}
+		}
+		return !correlated
+	})
+	return correlated
+}
 
-	for _, inClause := range inClauses {
-		// Count items in IN clause (simple approximation)
-		itemCount := strings.Count(inClause, ",") + 1
+// optimizeInClauses optimizes IN clauses
+func (o *QueryOptimizer) optimizeInClauses(stmts []sqlparser.Stmt, result *OptimizationResult) {
+	var hasNotIn bool
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		in, ok := n.(*sqlparser.InExpr)
+		if !ok {
+			return true
+		}
+		if in.Not {
+			hasNotIn = true
+		}
 
-		if itemCount > 10 {
+		// Check for large IN clauses
+		if len(in.List) > 10 {
 			result.Optimizations = append(result.Optimizations, OptimizationDetail{
 				Type:        "large_in_clause",
-				Description: fmt.Sprintf("Large IN clause with ~%d items detected", itemCount),
+				Description: fmt.Sprintf("Large IN clause with %d items detected", len(in.List)),
 				Reasoning:   "Large IN clauses can be inefficient. Consider using a temporary table or VALUES clause",
 				Impact:      "medium",
 			})
@@ -183,10 +325,11 @@ func (o *QueryOptimizer) optimizeInClauses(result *OptimizationResult) {
 			result.Warnings = append(result.Warnings,
 				"Consider using VALUES clause or temporary table for large IN clauses")
 		}
-	}
+		return true
+	})
 
 	// Check for NOT IN
-	if regexp.MustCompile(`(?i)NOT\s+IN`).MatchString(query) {
+	if hasNotIn {
 		result.Optimizations = append(result.Optimizations, OptimizationDetail{
 			Type:        "not_in_optimization",
 			Description: "Replace NOT IN with NOT EXISTS for NULL-safe behavior",
@@ -197,44 +340,65 @@ func (o *QueryOptimizer) optimizeInClauses(result *OptimizationResult) {
 }
 
 // optimizePagination optimizes LIMIT/OFFSET patterns
-func (o *QueryOptimizer) optimizePagination(result *OptimizationResult) {
-	query := result.OptimizedQuery
-
-	// Check for OFFSET usage
-	offsetPattern := regexp.MustCompile(`(?i)OFFSET\s+(\d+)`)
-	if matches := offsetPattern.FindStringSubmatch(query); len(matches) > 1 {
-		var offset int
-		fmt.Sscanf(matches[1], "%d", &offset)
+func (o *QueryOptimizer) optimizePagination(stmts []sqlparser.Stmt, result *OptimizationResult) {
+	var unordered bool
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		switch n := n.(type) {
+		case *sqlparser.SubqueryExpr:
+			// LIMIT inside EXISTS only decides whether a row exists
+			return !n.Exists
+		case *sqlparser.SelectStmt:
+			if n.Limit != nil && len(n.OrderBy) == 0 {
+				unordered = true
+			}
 
-		if offset > 100 {
-			result.Optimizations = append(result.Optimizations, OptimizationDetail{
-				Type:        "pagination",
-				Description: fmt.Sprintf("High OFFSET value (%d) detected", offset),
-				Reasoning:   "Large OFFSET values cause the database to scan and discard many rows",
-				Impact:      "high",
-			})
+			// Check for OFFSET usage
+			lit, ok := n.Offset.(*sqlparser.Literal)
+			if !ok || lit.Kind != sqlparser.LiteralNumber {
+				return true
+			}
+			if offset, err := strconv.Atoi(lit.Value); err == nil && offset > 100 {
+				result.Optimizations = append(result.Optimizations, OptimizationDetail{
+					Type:        "pagination",
+					Description: fmt.Sprintf("High OFFSET value (%d) detected", offset),
+					Reasoning:   "Large OFFSET values cause the database to scan and discard many rows",
+					Impact:      "high",
+				})
 
-			// Suggest keyset pagination
-			result.Warnings = append(result.Warnings,
-				"Consider keyset pagination: WHERE id > last_seen_id ORDER BY id LIMIT n")
+				// Suggest keyset pagination
+				result.Warnings = append(result.Warnings,
+					"Consider keyset pagination: WHERE id > last_seen_id ORDER BY id LIMIT n")
+			}
 		}
-	}
+		return true
+	})
 
 	// Check for missing ORDER BY with LIMIT
-	if strings.Contains(strings.ToUpper(query), "LIMIT") &&
-		!strings.Contains(strings.ToUpper(query), "ORDER BY") {
+	if unordered {
 		result.Warnings = append(result.Warnings,
 			"LIMIT without ORDER BY may return inconsistent results")
 	}
 }
 
 // optimizeDistinct optimizes DISTINCT usage
-func (o *QueryOptimizer) optimizeDistinct(result *OptimizationResult) {
-	query := result.OptimizedQuery
+func (o *QueryOptimizer) optimizeDistinct(stmts []sqlparser.Stmt, result *OptimizationResult) {
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		sel, ok := n.(*sqlparser.SelectStmt)
+		if !ok || !sel.Distinct {
+			return true
+		}
 
-	if regexp.MustCompile(`(?i)SELECT\s+DISTINCT`).MatchString(query) {
 		// Check if GROUP BY might be more appropriate
-		if regexp.MustCompile(`(?i)COUNT|SUM|AVG|MAX|MIN`).MatchString(query) {
+		var aggregate bool
+		for _, target := range sel.Targets {
+			inspectShallow(target.Expr, func(n sqlparser.Node) bool {
+				if fn, ok := n.(*sqlparser.FuncCall); ok && aggregateFuncs[fn.Name] {
+					aggregate = true
+				}
+				return !aggregate
+			})
+		}
+		if aggregate {
 			result.Optimizations = append(result.Optimizations, OptimizationDetail{
 				Type:        "distinct_with_aggregation",
 				Description: "DISTINCT with aggregation functions detected",
@@ -243,23 +407,91 @@ func (o *QueryOptimizer) optimizeDistinct(result *OptimizationResult) {
 			})
 		}
 
-		// Suggest index for DISTINCT columns
-		result.IndexSuggestions = append(result.IndexSuggestions, IndexSuggestion{
-			TableName: "affected_table",
-			Columns:   []string{"distinct_columns"},
-			IndexType: "btree",
-			Reasoning: "Index on DISTINCT columns can significantly improve performance",
-		})
-	}
+		// Suggest index for DISTINCT columns when they all come from one table
+		scope := columnScope{aliases: aliasTables(nil, sel.From)}
+		var table string
+		var columns []string
+		for _, target := range sel.Targets {
+			ref, ok := target.Expr.(*sqlparser.ColumnRef)
+			if !ok || ref.Star {
+				return true
+			}
+			t := scope.resolve(ref)
+			if t == "" || (table != "" && t != table) {
+				return true
+			}
+			table = t
+			columns = append(columns, ref.Column())
+		}
+		if table != "" {
+			result.IndexSuggestions = append(result.IndexSuggestions, IndexSuggestion{
+				TableName:   table,
+				Columns:     columns,
+				IndexType:   "btree",
+				Reasoning:   "Index on DISTINCT columns can significantly improve performance",
+				CreateQuery: createIndexQuery(table, columns),
+			})
+		}
+		return true
+	})
 }
 
 // optimizeFunctions optimizes function usage
-func (o *QueryOptimizer) optimizeFunctions(result *OptimizationResult) {
-	query := result.OptimizedQuery
+func (o *QueryOptimizer) optimizeFunctions(stmts []sqlparser.Stmt, result *OptimizationResult) {
+	var funcInWhere, wildcard bool
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		var (
+			where sqlparser.Expr
+			scope columnScope
+		)
+		switch n := n.(type) {
+		case *sqlparser.SelectStmt:
+			where, scope = n.Where, columnScope{aliases: aliasTables(nil, n.From)}
+		case *sqlparser.UpdateStmt:
+			where, scope = n.Where, columnScope{aliases: aliasTables(n.Table, n.From)}
+		case *sqlparser.DeleteStmt:
+			where, scope = n.Where, columnScope{aliases: aliasTables(n.Table, n.Using)}
+		default:
+			return true
+		}
+
+		inspectShallow(where, func(n sqlparser.Node) bool {
+			switch n := n.(type) {
+			case *sqlparser.BinaryExpr:
+				if !isComparisonOp(n.Op) {
+					return true
+				}
+				for _, side := range []sqlparser.Expr{n.Left, n.Right} {
+					if !isFuncOnColumn(side) {
+						continue
+					}
+					funcInWhere = true
+
+					// Suggest a functional index on the expression
+					if table := expressionTable(side, scope); table != "" {
+						expr := sqlparser.Text(result.OriginalQuery, side)
+						result.IndexSuggestions = append(result.IndexSuggestions, IndexSuggestion{
+							TableName:   table,
+							Columns:     []string{expr},
+							IndexType:   "btree",
+							Reasoning:   "Create functional index on the expression used in WHERE",
+							CreateQuery: fmt.Sprintf("CREATE INDEX %s ON %s ((%s))", indexName(table, expressionColumns(side)), table, expr),
+						})
+					}
+				}
+			case *sqlparser.LikeExpr:
+				if lit, ok := n.Pattern.(*sqlparser.Literal); ok && lit.Kind == sqlparser.LiteralString &&
+					strings.HasPrefix(lit.Value, "%") {
+					wildcard = true
+				}
+			}
+			return true
+		})
+		return true
+	})
 
 	// Check for functions in WHERE clause
-	whereFunctionPattern := regexp.MustCompile(`(?i)WHERE[^(]*\b(UPPER|LOWER|SUBSTR|DATE|EXTRACT)\s*\(`)
-	if whereFunctionPattern.MatchString(query) {
+	if funcInWhere {
 		result.Optimizations = append(result.Optimizations, OptimizationDetail{
 			Type:        "function_in_where",
 			Description: "Function calls in WHERE clause detected",
@@ -267,20 +499,12 @@ func (o *QueryOptimizer) optimizeFunctions(result *OptimizationResult) {
 			Impact:      "high",
 		})
 
-		// Suggest functional indexes
-		result.IndexSuggestions = append(result.IndexSuggestions, IndexSuggestion{
-			TableName: "affected_table",
-			Columns:   []string{"expression"},
-			IndexType: "btree",
-			Reasoning: "Create functional index on the expression used in WHERE",
-		})
-
 		result.Warnings = append(result.Warnings,
 			"Consider creating functional indexes or rewriting conditions to use bare columns")
 	}
 
 	// Check for expensive string operations
-	if regexp.MustCompile(`(?i)LIKE\s+'%[^']+%'`).MatchString(query) {
+	if wildcard {
 		result.Optimizations = append(result.Optimizations, OptimizationDetail{
 			Type:        "wildcard_search",
 			Description: "Wildcard search pattern detected",
@@ -294,65 +518,153 @@ func (o *QueryOptimizer) optimizeFunctions(result *OptimizationResult) {
 	}
 }
 
-// optimizeExists optimizes EXISTS/NOT EXISTS patterns
-func (o *QueryOptimizer) optimizeExists(result *OptimizationResult) {
-	query := result.OptimizedQuery
+// expressionColumns returns the columns referenced by an expression
+func expressionColumns(expr sqlparser.Expr) []string {
+	var columns []string
+	inspectShallow(expr, func(n sqlparser.Node) bool {
+		if ref, ok := n.(*sqlparser.ColumnRef); ok && !ref.Star {
+			columns = append(columns, ref.Column())
+		}
+		return true
+	})
+	return columns
+}
+
+// expressionTable returns the single table whose columns an expression uses, or ""
+func expressionTable(expr sqlparser.Expr, scope columnScope) string {
+	var table string
+	ambiguous := false
+	inspectShallow(expr, func(n sqlparser.Node) bool {
+		ref, ok := n.(*sqlparser.ColumnRef)
+		if !ok || ref.Star {
+			return true
+		}
+		t := scope.resolve(ref)
+		if t == "" || (table != "" && t != table) {
+			ambiguous = true
+		}
+		table = t
+		return !ambiguous
+	})
+	if ambiguous {
+		return ""
+	}
+	return table
+}
 
+// optimizeExists optimizes EXISTS/NOT EXISTS patterns
+func (o *QueryOptimizer) optimizeExists(stmts []sqlparser.Stmt, result *OptimizationResult) {
 	// Suggest EXISTS over COUNT(*) for existence checks
-	if regexp.MustCompile(`(?i)COUNT\s*\(\s*\*\s*\)[^><=]+>\s*0`).MatchString(query) {
-		result.Optimizations = append(result.Optimizations, OptimizationDetail{
-			Type:        "count_to_exists",
-			Description: "COUNT(*) > 0 pattern detected",
-			Reasoning:   "EXISTS stops at first match, COUNT(*) processes all rows",
-			Impact:      "high",
-		})
+	var found bool
+	inspectAll(stmts, func(n sqlparser.Node) bool {
+		if cmp, ok := n.(*sqlparser.BinaryExpr); ok && isCountExistenceCheck(cmp) {
+			found = true
+		}
+		return !found
+	})
+	if !found {
+		return
+	}
 
-		result.Warnings = append(result.Warnings,
-			"Replace COUNT(*) > 0 with EXISTS for better performance")
+	result.Optimizations = append(result.Optimizations, OptimizationDetail{
+		Type:        "count_to_exists",
+		Description: "COUNT(*) > 0 pattern detected",
+		Reasoning:   "EXISTS stops at first match, COUNT(*) processes all rows",
+		Impact:      "high",
+	})
+
+	result.Warnings = append(result.Warnings,
+		"Replace COUNT(*) > 0 with EXISTS for better performance")
+}
+
+// isCountExistenceCheck reports whether cmp compares a count with zero, as in
+// count(*) > 0, (SELECT count(*) ...) <> 0 or 0 < count(id)
+func isCountExistenceCheck(cmp *sqlparser.BinaryExpr) bool {
+	isZero := func(e sqlparser.Expr) bool {
+		lit, ok := e.(*sqlparser.Literal)
+		return ok && lit.Kind == sqlparser.LiteralNumber && lit.Value == "0"
+	}
+	isCount := func(e sqlparser.Expr) bool {
+		if sub, ok := e.(*sqlparser.SubqueryExpr); ok && !sub.Exists && len(sub.Query.Targets) == 1 {
+			e = sub.Query.Targets[0].Expr
+		}
+		fn, ok := e.(*sqlparser.FuncCall)
+		return ok && fn.Name == "count" && fn.Over == nil
+	}
+
+	switch cmp.Op {
+	case ">", "<>", "!=":
+		if isCount(cmp.Left) && isZero(cmp.Right) {
+			return true
+		}
+	}
+	switch cmp.Op {
+	case "<", "<>", "!=":
+		return isZero(cmp.Left) && isCount(cmp.Right)
 	}
+	return false
 }
 
 // suggestIndexes suggests indexes based on query patterns
-func (o *QueryOptimizer) suggestIndexes(result *OptimizationResult) {
-	query := result.OptimizedQuery
-
-	// Extract WHERE conditions
-	wherePattern := regexp.MustCompile(`(?i)WHERE\s+(.+?)(?:\s+GROUP|\s+ORDER|\s+LIMIT|$)`)
-	if matches := wherePattern.FindStringSubmatch(query); len(matches) > 1 {
-		conditions := matches[1]
-
-		// Extract column names from conditions
-		columnPattern := regexp.MustCompile(`(\w+)\s*[=<>]`)
-		columns := columnPattern.FindAllStringSubmatch(conditions, -1)
-
-		if len(columns) > 0 {
-			indexColumns := []string{}
-			for _, col := range columns {
-				if len(col) > 1 {
-					indexColumns = append(indexColumns, col[1])
-				}
+func (o *QueryOptimizer) suggestIndexes(stmts []sqlparser.Stmt, result *OptimizationResult) {
+	// Collect filter columns per table: equality columns lead, range columns follow
+	type tableColumns struct {
+		equality, rng []string
+	}
+	byTable := make(map[string]*tableColumns)
+	var tables []string
+	for _, stmt := range stmts {
+		for _, use := range columnUses(stmt) {
+			if use.Kind != useWhere || use.Table == "" {
+				continue
 			}
-
-			if len(indexColumns) > 0 {
-				result.IndexSuggestions = append(result.IndexSuggestions, IndexSuggestion{
-					TableName: "target_table",
-					Columns:   indexColumns,
-					IndexType: "btree",
-					Reasoning: "Index on WHERE clause columns for faster filtering",
-					CreateQuery: fmt.Sprintf("CREATE INDEX idx_table_columns ON target_table (%s)",
-						strings.Join(indexColumns, ", ")),
-				})
+			tc, ok := byTable[use.Table]
+			if !ok {
+				tc = &tableColumns{}
+				byTable[use.Table] = tc
+				tables = append(tables, use.Table)
+			}
+			if slices.Contains(tc.equality, use.Column) || slices.Contains(tc.rng, use.Column) {
+				continue
+			}
+			if use.Equality {
+				tc.equality = append(tc.equality, use.Column)
+			} else {
+				tc.rng = append(tc.rng, use.Column)
 			}
 		}
 	}
 
+	for _, table := range tables {
+		tc := byTable[table]
+		columns := append(slices.Clone(tc.equality), tc.rng...)
+		result.IndexSuggestions = append(result.IndexSuggestions, IndexSuggestion{
+			TableName:   table,
+			Columns:     columns,
+			IndexType:   "btree",
+			Reasoning:   "Index on WHERE clause columns for faster filtering",
+			CreateQuery: createIndexQuery(table, columns),
+		})
+	}
+
 	// Suggest covering index for SELECT columns
-	if !strings.Contains(strings.ToUpper(query), "SELECT *") {
+	if !hasSelectStar(stmts) {
 		result.Warnings = append(result.Warnings,
 			"Consider covering indexes that include all SELECT columns to enable index-only scans")
 	}
 }
 
+// indexName builds a conventional index name from a table and its columns
+func indexName(table string, columns []string) string {
+	parts := append([]string{"idx", table}, columns...)
+	return strings.ReplaceAll(strings.Join(parts, "_"), ".", "_")
+}
+
+// createIndexQuery builds a CREATE INDEX statement for plain columns
+func createIndexQuery(table string, columns []string) string {
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", indexName(table, columns), table, strings.Join(columns, ", "))
+}
+
 // calculateBenefit estimates the expected benefit of optimizations
 func (o *QueryOptimizer) calculateBenefit(result *OptimizationResult) {
 	highImpact := 0
@@ -621,3 +621,231 @@ func (a *SchemaAnalyzer) calculateStatistics(analysis *SchemaAnalysis) {
 		}
 	}
 }
+
+// Snapshot reads the tables, constraints, indexes, enums and functions of a
+// schema from the catalog. Objects that belong to extensions are skipped.
+func (a *SchemaAnalyzer) Snapshot(ctx context.Context, schemaName, source string) (*SchemaSnapshot, error) {
+	if a.pool == nil {
+		return nil, fmt.Errorf("database connection required for schema snapshot")
+	}
+
+	var ddl strings.Builder
+	steps := []struct {
+		name  string
+		fetch func(context.Context, string, *strings.Builder) error
+	}{
+		{"enums", a.snapshotEnums},
+		{"tables", a.snapshotTables},
+		{"constraints", a.snapshotConstraints},
+		{"indexes", a.snapshotIndexes},
+		{"functions", a.snapshotFunctions},
+	}
+	for _, step := range steps {
+		if err := step.fetch(ctx, schemaName, &ddl); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", step.name, err)
+		}
+	}
+
+	snapshot := NewSchemaSnapshot(schemaName, source, true)
+	if err := snapshot.ApplySQL(ddl.String()); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog definitions: %w", err)
+	}
+	return snapshot, nil
+}
+
+// notExtensionMember filters out catalog objects created by extensions
+const notExtensionMember = `NOT EXISTS (
+			SELECT 1 FROM pg_depend d WHERE d.objid = %s AND d.deptype = 'e'
+		)`
+
+// snapshotEnums writes a CREATE TYPE statement for every enum
+func (a *SchemaAnalyzer) snapshotEnums(ctx context.Context, schemaName string, ddl *strings.Builder) error {
+	query := `
+		SELECT t.typname, array_agg(e.enumlabel ORDER BY e.enumsortorder)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		WHERE n.nspname = $1
+		AND ` + fmt.Sprintf(notExtensionMember, "t.oid") + `
+		GROUP BY t.typname
+		ORDER BY t.typname`
+
+	rows, err := a.pool.Query(ctx, query, schemaName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var values []string
+		if err := rows.Scan(&name, &values); err != nil {
+			return err
+		}
+		fmt.Fprintf(ddl, "CREATE TYPE %s AS ENUM (%s);\n", quoteIdent(name), quoteLiterals(values))
+	}
+	return rows.Err()
+}
+
+// snapshotTables writes a CREATE TABLE statement with the columns of every
+// table. Partitions are part of their parent and are skipped.
+func (a *SchemaAnalyzer) snapshotTables(ctx context.Context, schemaName string, ddl *strings.Builder) error {
+	query := `
+		SELECT
+			c.relname,
+			a.attname,
+			format_type(a.atttypid, a.atttypmod),
+			a.attnotnull,
+			COALESCE(pg_get_expr(ad.adbin, ad.adrelid), ''),
+			a.attidentity::text,
+			a.attgenerated::text
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		LEFT JOIN pg_attrdef ad ON ad.adrelid = c.oid AND ad.adnum = a.attnum
+		WHERE n.nspname = $1
+		AND c.relkind IN ('r', 'p')
+		AND NOT c.relispartition
+		AND ` + fmt.Sprintf(notExtensionMember, "c.oid") + `
+		ORDER BY c.relname, a.attnum`
+
+	rows, err := a.pool.Query(ctx, query, schemaName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var table string
+	var columns []string
+	flush := func() {
+		if table != "" {
+			fmt.Fprintf(ddl, "CREATE TABLE %s (\n    %s\n);\n", quoteIdent(table), strings.Join(columns, ",\n    "))
+		}
+	}
+
+	for rows.Next() {
+		var name, column, dataType, expr, identity, generated string
+		var notNull bool
+		if err := rows.Scan(&name, &column, &dataType, &notNull, &expr, &identity, &generated); err != nil {
+			return err
+		}
+		if name != table {
+			flush()
+			table, columns = name, nil
+		}
+
+		def := quoteIdent(column) + " " + dataType
+		switch {
+		case generated == "s":
+			def += " GENERATED ALWAYS AS (" + expr + ") STORED"
+		case expr != "":
+			def += " DEFAULT " + expr
+		}
+		switch identity {
+		case "a":
+			def += " GENERATED ALWAYS AS IDENTITY"
+		case "d":
+			def += " GENERATED BY DEFAULT AS IDENTITY"
+		}
+		if notNull {
+			def += " NOT NULL"
+		}
+		columns = append(columns, def)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+// snapshotConstraints writes an ALTER TABLE statement for every primary key,
+// unique, foreign key, check and exclusion constraint
+func (a *SchemaAnalyzer) snapshotConstraints(ctx context.Context, schemaName string, ddl *strings.Builder) error {
+	query := `
+		SELECT c.relname, con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+		AND con.contype IN ('p', 'u', 'f', 'c', 'x')
+		AND con.conislocal
+		ORDER BY c.relname, con.conname`
+
+	rows, err := a.pool.Query(ctx, query, schemaName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, name, definition string
+		if err := rows.Scan(&table, &name, &definition); err != nil {
+			return err
+		}
+		fmt.Fprintf(ddl, "ALTER TABLE %s ADD CONSTRAINT %s %s;\n", quoteIdent(table), quoteIdent(name), definition)
+	}
+	return rows.Err()
+}
+
+// snapshotIndexes writes the definition of every index that does not back a
+// constraint; those are created by the constraint itself
+func (a *SchemaAnalyzer) snapshotIndexes(ctx context.Context, schemaName string, ddl *strings.Builder) error {
+	query := `
+		SELECT pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+		AND c.relkind IN ('r', 'p')
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_constraint con
+			WHERE con.conindid = i.indexrelid
+			AND con.conrelid = i.indrelid
+			AND con.contype IN ('p', 'u', 'x')
+		)
+		ORDER BY ic.relname`
+
+	rows, err := a.pool.Query(ctx, query, schemaName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return err
+		}
+		ddl.WriteString(definition + ";\n")
+	}
+	return rows.Err()
+}
+
+// snapshotFunctions writes the definition of every function and procedure
+func (a *SchemaAnalyzer) snapshotFunctions(ctx context.Context, schemaName string, ddl *strings.Builder) error {
+	query := `
+		SELECT pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1
+		AND p.prokind IN ('f', 'p')
+		AND ` + fmt.Sprintf(notExtensionMember, "p.oid") + `
+		ORDER BY p.proname, p.oid`
+
+	rows, err := a.pool.Query(ctx, query, schemaName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return err
+		}
+		ddl.WriteString(strings.TrimSpace(definition) + ";\n")
+	}
+	return rows.Err()
+}
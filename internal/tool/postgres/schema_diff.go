@@ -0,0 +1,903 @@
+package postgres
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/postgres/sqlparser"
+)
+
+// SchemaChange is one difference between two schema snapshots together with
+// the statements that apply it
+type SchemaChange struct {
+	Kind        string   `json:"kind"`   // e.g. "add_column", "drop_table"
+	Object      string   `json:"object"` // e.g. "users.email"
+	Description string   `json:"description"`
+	Destructive bool     `json:"destructive"` // the change can lose data
+	Warning     string   `json:"warning,omitempty"`
+	Statements  []string `json:"statements"`
+	phase       int
+}
+
+// SchemaDiff is the ordered list of changes that turn one snapshot into another
+type SchemaDiff struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Schema  string         `json:"schema"`
+	Changes []SchemaChange `json:"changes"`
+	// Approximate is set when the snapshots print expressions differently
+	// and CHECK conditions and index predicates were compared by name only
+	Approximate bool `json:"approximate"`
+}
+
+// Change phases. Statements run in phase order so that every object exists
+// before it is used and is no longer used when it is dropped.
+const (
+	phaseCreateType = iota
+	phaseAlterType
+	phaseDropForeignKey
+	phaseDropConstraint
+	phaseDropIndex
+	phaseCreateTable
+	phaseAddColumn
+	phaseAlterColumn
+	phaseFunction
+	phaseAddConstraint
+	phaseAddForeignKey
+	phaseCreateIndex
+	phaseDropColumn
+	phaseDropTable
+	phaseDropFunction
+	phaseDropType
+)
+
+// DiffSchemas computes the changes that turn the from snapshot into the to
+// snapshot. Swapping the arguments gives the changes that revert them.
+func DiffSchemas(from, to *SchemaSnapshot) *SchemaDiff {
+	d := &schemaDiffer{
+		from:   from,
+		to:     to,
+		schema: to.Schema,
+		exact:  from.Catalog == to.Catalog,
+	}
+
+	d.diffEnums()
+	d.diffTables()
+	d.diffFunctions()
+
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].phase < d.changes[j].phase
+	})
+
+	return &SchemaDiff{
+		From:        from.Source,
+		To:          to.Source,
+		Schema:      to.Schema,
+		Changes:     d.changes,
+		Approximate: !d.exact,
+	}
+}
+
+// Destructive returns the changes that can lose data
+func (d *SchemaDiff) Destructive() []SchemaChange {
+	var changes []SchemaChange
+	for _, c := range d.Changes {
+		if c.Destructive {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// SQL renders the changes as a migration script
+func (d *SchemaDiff) SQL() string {
+	var b strings.Builder
+	for i, c := range d.Changes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if c.Destructive {
+			fmt.Fprintf(&b, "-- %s (destructive)\n", c.Description)
+		} else {
+			fmt.Fprintf(&b, "-- %s\n", c.Description)
+		}
+		if c.Warning != "" {
+			fmt.Fprintf(&b, "-- WARNING: %s\n", c.Warning)
+		}
+		for _, stmt := range c.Statements {
+			b.WriteString(stmt)
+			if !strings.HasPrefix(stmt, "--") {
+				b.WriteString(";")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+type schemaDiffer struct {
+	from, to *SchemaSnapshot
+	schema   string
+	// exact is set when both snapshots print expressions the same way
+	exact   bool
+	changes []SchemaChange
+}
+
+func (d *schemaDiffer) add(phase int, change SchemaChange) {
+	change.phase = phase
+	d.changes = append(d.changes, change)
+}
+
+// qualify renders an object name, qualified unless it is in the public schema
+func (d *schemaDiffer) qualify(name string) string {
+	if d.schema == "public" {
+		return quoteIdent(name)
+	}
+	return quoteIdent(d.schema) + "." + quoteIdent(name)
+}
+
+func (d *schemaDiffer) diffEnums() {
+	for _, name := range sortedKeys(d.to.Enums) {
+		to := d.to.Enums[name]
+		from, ok := d.from.Enums[name]
+		if !ok {
+			d.add(phaseCreateType, SchemaChange{
+				Kind:        "create_enum",
+				Object:      name,
+				Description: fmt.Sprintf("Create enum %s", name),
+				Statements:  []string{fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", d.qualify(name), quoteLiterals(to.Values))},
+			})
+			continue
+		}
+
+		for i, value := range to.Values {
+			if slices.Contains(from.Values, value) {
+				continue
+			}
+			stmt := fmt.Sprintf("ALTER TYPE %s ADD VALUE %s", d.qualify(name), quoteLiteral(value))
+			if i > 0 {
+				stmt += " AFTER " + quoteLiteral(to.Values[i-1])
+			} else if len(to.Values) > 1 {
+				stmt += " BEFORE " + quoteLiteral(to.Values[1])
+			}
+			d.add(phaseAlterType, SchemaChange{
+				Kind:        "add_enum_value",
+				Object:      name,
+				Description: fmt.Sprintf("Add value %s to enum %s", quoteLiteral(value), name),
+				Statements:  []string{stmt},
+			})
+		}
+
+		for _, value := range from.Values {
+			if slices.Contains(to.Values, value) {
+				continue
+			}
+			d.add(phaseDropType, SchemaChange{
+				Kind:        "drop_enum_value",
+				Object:      name,
+				Description: fmt.Sprintf("Remove value %s from enum %s", quoteLiteral(value), name),
+				Destructive: true,
+				Warning:     "PostgreSQL cannot drop enum values; the type must be recreated and rows using the value converted",
+				Statements: []string{fmt.Sprintf("-- TODO: recreate %s without %s and convert the columns that use it",
+					name, quoteLiteral(value))},
+			})
+		}
+	}
+
+	for _, name := range sortedKeys(d.from.Enums) {
+		if _, ok := d.to.Enums[name]; !ok {
+			d.add(phaseDropType, SchemaChange{
+				Kind:        "drop_enum",
+				Object:      name,
+				Description: fmt.Sprintf("Drop enum %s", name),
+				Destructive: true,
+				Statements:  []string{fmt.Sprintf("DROP TYPE %s", d.qualify(name))},
+			})
+		}
+	}
+}
+
+func (d *schemaDiffer) diffTables() {
+	for _, name := range d.to.TableNames() {
+		to := d.to.Tables[name]
+		from, ok := d.from.Tables[name]
+		if !ok {
+			d.createTable(to)
+			continue
+		}
+		d.diffColumns(from, to)
+		d.diffConstraints(from, to)
+		d.diffIndexes(from, to)
+	}
+
+	for _, name := range d.from.TableNames() {
+		if _, ok := d.to.Tables[name]; !ok {
+			d.add(phaseDropTable, SchemaChange{
+				Kind:        "drop_table",
+				Object:      name,
+				Description: fmt.Sprintf("Drop table %s", name),
+				Destructive: true,
+				Statements:  []string{fmt.Sprintf("DROP TABLE %s", d.qualify(name))},
+			})
+		}
+	}
+}
+
+// createTable creates a table with its columns and constraints. Foreign keys
+// are added once every table exists, and indexes after that.
+func (d *schemaDiffer) createTable(table *SnapshotTable) {
+	var lines []string
+	for _, col := range table.Columns {
+		lines = append(lines, "    "+d.columnDefinition(table.Name, col))
+	}
+	for _, c := range table.Constraints {
+		if c.Type != string(sqlparser.ConstraintForeignKey) {
+			lines = append(lines, fmt.Sprintf("    CONSTRAINT %s %s", quoteIdent(c.Name), d.constraintDefinition(c)))
+		}
+	}
+	d.add(phaseCreateTable, SchemaChange{
+		Kind:        "create_table",
+		Object:      table.Name,
+		Description: fmt.Sprintf("Create table %s", table.Name),
+		Statements:  []string{fmt.Sprintf("CREATE TABLE %s (\n%s\n)", d.qualify(table.Name), strings.Join(lines, ",\n"))},
+	})
+
+	for _, c := range table.Constraints {
+		if c.Type == string(sqlparser.ConstraintForeignKey) {
+			d.addConstraint(table.Name, c, false)
+		}
+	}
+	for _, idx := range table.Indexes {
+		d.createIndex(idx)
+	}
+}
+
+func (d *schemaDiffer) diffColumns(from, to *SnapshotTable) {
+	table := d.qualify(to.Name)
+
+	for _, col := range to.Columns {
+		old := from.Column(col.Name)
+		object := to.Name + "." + col.Name
+
+		if old == nil {
+			change := SchemaChange{
+				Kind:        "add_column",
+				Object:      object,
+				Description: fmt.Sprintf("Add column %s", object),
+				Statements:  []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, d.columnDefinition(to.Name, col))},
+			}
+			if col.NotNull && col.Default == "" && col.Identity == "" && col.Generated == "" {
+				change.Warning = "adding a NOT NULL column without a default fails if the table has rows"
+			}
+			d.add(phaseAddColumn, change)
+			continue
+		}
+
+		// A changed generation expression can only be applied by recreating the column
+		if normalizeExpr(old.Generated, d.schema) != normalizeExpr(col.Generated, d.schema) {
+			d.add(phaseAlterColumn, SchemaChange{
+				Kind:        "recreate_column",
+				Object:      object,
+				Description: fmt.Sprintf("Recreate column %s", object),
+				Destructive: true,
+				Warning:     "the generation expression changed, so the column is dropped and added again",
+				Statements: []string{
+					fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, quoteIdent(col.Name)),
+					fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, d.columnDefinition(to.Name, col)),
+				},
+			})
+			continue
+		}
+
+		d.alterColumn(to.Name, old, col)
+	}
+
+	for _, col := range from.Columns {
+		if to.Column(col.Name) == nil {
+			object := from.Name + "." + col.Name
+			d.add(phaseDropColumn, SchemaChange{
+				Kind:        "drop_column",
+				Object:      object,
+				Description: fmt.Sprintf("Drop column %s", object),
+				Destructive: true,
+				Statements:  []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, quoteIdent(col.Name))},
+			})
+		}
+	}
+}
+
+// alterColumn changes the type, default, nullability and identity of a column
+func (d *schemaDiffer) alterColumn(tableName string, from, to *SnapshotColumn) {
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ", d.qualify(tableName), quoteIdent(to.Name))
+	object := tableName + "." + to.Name
+
+	if from.Type != to.Type {
+		change := SchemaChange{
+			Kind:        "alter_column_type",
+			Object:      object,
+			Description: fmt.Sprintf("Change type of %s from %s to %s", object, from.Type, to.Type),
+			Statements:  []string{alter + "TYPE " + to.Type},
+		}
+		if !isWideningConversion(from.Type, to.Type) {
+			change.Destructive = true
+			change.Warning = "the conversion rewrites the table and can fail or lose data; add a USING clause if there is no implicit cast"
+		}
+		d.add(phaseAlterColumn, change)
+	}
+
+	if normalizeExpr(from.Default, d.schema) != normalizeExpr(to.Default, d.schema) {
+		stmt := alter + "DROP DEFAULT"
+		if to.Default != "" {
+			stmt = alter + "SET DEFAULT " + to.Default
+		}
+		d.add(phaseAlterColumn, SchemaChange{
+			Kind:        "alter_column_default",
+			Object:      object,
+			Description: fmt.Sprintf("Change default of %s", object),
+			Statements:  []string{stmt},
+		})
+	}
+
+	if from.Identity != to.Identity {
+		var stmt string
+		switch {
+		case from.Identity == "":
+			stmt = alter + "ADD GENERATED " + to.Identity + " AS IDENTITY"
+		case to.Identity == "":
+			stmt = alter + "DROP IDENTITY"
+		default:
+			stmt = alter + "SET GENERATED " + to.Identity
+		}
+		d.add(phaseAlterColumn, SchemaChange{
+			Kind:        "alter_column_identity",
+			Object:      object,
+			Description: fmt.Sprintf("Change identity of %s", object),
+			Statements:  []string{stmt},
+		})
+	}
+
+	// Identity columns are NOT NULL implicitly
+	if from.NotNull != to.NotNull && from.Identity == to.Identity {
+		change := SchemaChange{
+			Kind:        "alter_column_nullability",
+			Object:      object,
+			Description: fmt.Sprintf("Allow NULL in %s", object),
+			Statements:  []string{alter + "DROP NOT NULL"},
+		}
+		if to.NotNull {
+			change.Description = fmt.Sprintf("Make %s NOT NULL", object)
+			change.Statements = []string{alter + "SET NOT NULL"}
+			change.Warning = "SET NOT NULL scans the table and fails if any row is NULL"
+		}
+		d.add(phaseAlterColumn, change)
+	}
+}
+
+func (d *schemaDiffer) diffConstraints(from, to *SnapshotTable) {
+	matched := make(map[string]bool)
+
+	for _, c := range to.Constraints {
+		old := from.Constraint(c.Name)
+		if old == nil {
+			// Constraints named differently by hand and by PostgreSQL are the same constraint
+			for _, candidate := range from.Constraints {
+				if !matched[candidate.Name] && to.Constraint(candidate.Name) == nil && d.sameConstraint(candidate, c) {
+					old = candidate
+					break
+				}
+			}
+			if old != nil {
+				matched[old.Name] = true
+				d.add(phaseAddConstraint, SchemaChange{
+					Kind:        "rename_constraint",
+					Object:      to.Name + "." + c.Name,
+					Description: fmt.Sprintf("Rename constraint %s on %s to %s", old.Name, to.Name, c.Name),
+					Statements: []string{fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s",
+						d.qualify(to.Name), quoteIdent(old.Name), quoteIdent(c.Name))},
+				})
+				continue
+			}
+			d.addConstraint(to.Name, c, true)
+			continue
+		}
+
+		matched[old.Name] = true
+		if !d.sameConstraint(old, c) {
+			d.dropConstraint(from.Name, old)
+			d.addConstraint(to.Name, c, true)
+		}
+	}
+
+	for _, c := range from.Constraints {
+		if !matched[c.Name] {
+			d.dropConstraint(from.Name, c)
+		}
+	}
+}
+
+// addConstraint adds a constraint to a table. Constraints on existing tables
+// are validated against their rows, which new tables don't have.
+func (d *schemaDiffer) addConstraint(table string, c *SnapshotConstraint, existing bool) {
+	phase := phaseAddConstraint
+	if c.Type == string(sqlparser.ConstraintForeignKey) {
+		phase = phaseAddForeignKey
+	}
+	change := SchemaChange{
+		Kind:        "add_constraint",
+		Object:      table + "." + c.Name,
+		Description: fmt.Sprintf("Add %s constraint %s on %s", c.Type, c.Name, table),
+		Statements: []string{fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s",
+			d.qualify(table), quoteIdent(c.Name), d.constraintDefinition(c))},
+	}
+	if existing && c.Type != string(sqlparser.ConstraintPrimaryKey) && c.Type != string(sqlparser.ConstraintUnique) {
+		change.Warning = "validating the constraint scans the table; add it NOT VALID and VALIDATE CONSTRAINT separately on large tables"
+	}
+	d.add(phase, change)
+}
+
+func (d *schemaDiffer) dropConstraint(table string, c *SnapshotConstraint) {
+	phase := phaseDropConstraint
+	if c.Type == string(sqlparser.ConstraintForeignKey) {
+		phase = phaseDropForeignKey
+	}
+	d.add(phase, SchemaChange{
+		Kind:        "drop_constraint",
+		Object:      table + "." + c.Name,
+		Description: fmt.Sprintf("Drop %s constraint %s on %s", c.Type, c.Name, table),
+		Statements: []string{fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s",
+			d.qualify(table), quoteIdent(c.Name))},
+	})
+}
+
+// sameConstraint compares constraints by definition, ignoring their names.
+// The columns of CHECK and EXCLUDE constraints are part of their expression.
+func (d *schemaDiffer) sameConstraint(a, b *SnapshotConstraint) bool {
+	keyed := a.Type != string(sqlparser.ConstraintCheck) && a.Type != string(sqlparser.ConstraintExclude)
+	if a.Type != b.Type || a.Deferrable != b.Deferrable || (keyed && !slices.Equal(a.Columns, b.Columns)) ||
+		a.RefTable != b.RefTable || !slices.Equal(a.RefColumns, b.RefColumns) ||
+		a.OnDelete != b.OnDelete || a.OnUpdate != b.OnUpdate {
+		return false
+	}
+	if !d.exact && a.Name == b.Name {
+		// Conditions can't be compared across forms; same name, same constraint
+		return true
+	}
+	return normalizeExpr(a.Expr, d.schema) == normalizeExpr(b.Expr, d.schema)
+}
+
+func (d *schemaDiffer) diffIndexes(from, to *SnapshotTable) {
+	matched := make(map[string]bool)
+	findIndex := func(indexes []*SnapshotIndex, name string) *SnapshotIndex {
+		for _, idx := range indexes {
+			if idx.Name == name {
+				return idx
+			}
+		}
+		return nil
+	}
+
+	for _, idx := range to.Indexes {
+		old := findIndex(from.Indexes, idx.Name)
+		if old == nil {
+			for _, candidate := range from.Indexes {
+				if !matched[candidate.Name] && findIndex(to.Indexes, candidate.Name) == nil && d.sameIndex(candidate, idx) {
+					old = candidate
+					break
+				}
+			}
+			if old != nil {
+				matched[old.Name] = true
+				d.add(phaseCreateIndex, SchemaChange{
+					Kind:        "rename_index",
+					Object:      idx.Name,
+					Description: fmt.Sprintf("Rename index %s to %s", old.Name, idx.Name),
+					Statements:  []string{fmt.Sprintf("ALTER INDEX %s RENAME TO %s", d.qualify(old.Name), quoteIdent(idx.Name))},
+				})
+				continue
+			}
+			d.createIndex(idx)
+			continue
+		}
+
+		matched[old.Name] = true
+		if !d.sameIndex(old, idx) {
+			d.dropIndex(old)
+			d.createIndex(idx)
+		}
+	}
+
+	for _, idx := range from.Indexes {
+		if !matched[idx.Name] {
+			d.dropIndex(idx)
+		}
+	}
+}
+
+func (d *schemaDiffer) createIndex(idx *SnapshotIndex) {
+	d.add(phaseCreateIndex, SchemaChange{
+		Kind:        "create_index",
+		Object:      idx.Name,
+		Description: fmt.Sprintf("Create index %s on %s", idx.Name, idx.Table),
+		Statements:  []string{d.indexDefinition(idx)},
+	})
+}
+
+func (d *schemaDiffer) dropIndex(idx *SnapshotIndex) {
+	d.add(phaseDropIndex, SchemaChange{
+		Kind:        "drop_index",
+		Object:      idx.Name,
+		Description: fmt.Sprintf("Drop index %s on %s", idx.Name, idx.Table),
+		Statements:  []string{fmt.Sprintf("DROP INDEX %s", d.qualify(idx.Name))},
+	})
+}
+
+// sameIndex compares index definitions, ignoring their names
+func (d *schemaDiffer) sameIndex(a, b *SnapshotIndex) bool {
+	if a.Table != b.Table || a.Unique != b.Unique || a.Method != b.Method ||
+		len(a.Columns) != len(b.Columns) || !slices.Equal(a.Include, b.Include) {
+		return false
+	}
+	for i := range a.Columns {
+		if normalizeExpr(a.Columns[i], d.schema) != normalizeExpr(b.Columns[i], d.schema) {
+			return false
+		}
+	}
+	if !d.exact {
+		return (a.Where == "") == (b.Where == "")
+	}
+	return normalizeExpr(a.Where, d.schema) == normalizeExpr(b.Where, d.schema)
+}
+
+func (d *schemaDiffer) diffFunctions() {
+	for _, signature := range sortedKeys(d.to.Functions) {
+		to := d.to.Functions[signature]
+		from, ok := d.from.Functions[signature]
+		switch {
+		case !ok:
+			d.add(phaseFunction, SchemaChange{
+				Kind:        "create_function",
+				Object:      signature,
+				Description: fmt.Sprintf("Create function %s", signature),
+				Statements:  []string{to.Definition},
+			})
+		case strings.Join(strings.Fields(from.Body), " ") != strings.Join(strings.Fields(to.Body), " "):
+			d.add(phaseFunction, SchemaChange{
+				Kind:        "replace_function",
+				Object:      signature,
+				Description: fmt.Sprintf("Replace function %s", signature),
+				Statements:  []string{to.Definition},
+			})
+		}
+	}
+
+	for _, signature := range sortedKeys(d.from.Functions) {
+		if _, ok := d.to.Functions[signature]; !ok {
+			d.add(phaseDropFunction, SchemaChange{
+				Kind:        "drop_function",
+				Object:      signature,
+				Description: fmt.Sprintf("Drop function %s", signature),
+				Warning:     "triggers and queries that call the function will fail",
+				Statements:  []string{"DROP FUNCTION " + d.qualifySignature(signature)},
+			})
+		}
+	}
+}
+
+// qualifySignature renders a function signature with a qualified name
+func (d *schemaDiffer) qualifySignature(signature string) string {
+	name, args, _ := strings.Cut(signature, "(")
+	return d.qualify(name) + "(" + args
+}
+
+// columnDefinition renders a column for CREATE TABLE and ADD COLUMN
+func (d *schemaDiffer) columnDefinition(table string, col *SnapshotColumn) string {
+	parts := []string{quoteIdent(col.Name)}
+
+	// Columns that default to their own sequence are written as serial
+	serial := map[string]string{"smallint": "smallserial", "integer": "serial", "bigint": "bigserial"}
+	ownSequence := normalizeExpr(fmt.Sprintf("nextval('%s')", sequenceName(table, col.Name)), d.schema)
+	if s, ok := serial[col.Type]; ok && col.NotNull && normalizeExpr(col.Default, d.schema) == ownSequence {
+		return strings.Join(append(parts, s), " ")
+	}
+
+	parts = append(parts, col.Type)
+	if col.Generated != "" {
+		parts = append(parts, "GENERATED ALWAYS AS ("+col.Generated+") STORED")
+	}
+	if col.Identity != "" {
+		parts = append(parts, "GENERATED "+col.Identity+" AS IDENTITY")
+	}
+	if col.Default != "" {
+		parts = append(parts, "DEFAULT "+col.Default)
+	}
+	if col.NotNull && col.Identity == "" {
+		parts = append(parts, "NOT NULL")
+	}
+	return strings.Join(parts, " ")
+}
+
+// constraintDefinition renders a constraint as it follows CONSTRAINT name
+func (d *schemaDiffer) constraintDefinition(c *SnapshotConstraint) string {
+	var def string
+	switch sqlparser.ConstraintType(c.Type) {
+	case sqlparser.ConstraintPrimaryKey, sqlparser.ConstraintUnique:
+		def = fmt.Sprintf("%s (%s)", c.Type, quoteIdents(c.Columns))
+	case sqlparser.ConstraintForeignKey:
+		ref := c.RefTable
+		if !strings.Contains(ref, ".") {
+			ref = d.qualify(ref)
+		}
+		def = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", quoteIdents(c.Columns), ref)
+		if len(c.RefColumns) > 0 {
+			def += fmt.Sprintf(" (%s)", quoteIdents(c.RefColumns))
+		}
+		if c.OnDelete != "" {
+			def += " ON DELETE " + c.OnDelete
+		}
+		if c.OnUpdate != "" {
+			def += " ON UPDATE " + c.OnUpdate
+		}
+	case sqlparser.ConstraintCheck:
+		def = fmt.Sprintf("CHECK (%s)", c.Expr)
+	default:
+		def = c.Expr
+	}
+	if c.Deferrable {
+		def += " DEFERRABLE"
+	}
+	return def
+}
+
+// indexDefinition renders a CREATE INDEX statement
+func (d *schemaDiffer) indexDefinition(idx *SnapshotIndex) string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if idx.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	fmt.Fprintf(&b, "INDEX %s ON %s", quoteIdent(idx.Name), d.qualify(idx.Table))
+	if idx.Method != "btree" {
+		b.WriteString(" USING " + idx.Method)
+	}
+	fmt.Fprintf(&b, " (%s)", strings.Join(idx.Columns, ", "))
+	if len(idx.Include) > 0 {
+		fmt.Fprintf(&b, " INCLUDE (%s)", quoteIdents(idx.Include))
+	}
+	if idx.Where != "" {
+		b.WriteString(" WHERE " + idx.Where)
+	}
+	return b.String()
+}
+
+// isWideningConversion reports whether a type change keeps every value:
+// a longer varchar, varchar to text, or a larger integer or float type
+func isWideningConversion(from, to string) bool {
+	base := func(t string) (string, int) {
+		name, mods, ok := strings.Cut(t, "(")
+		if !ok {
+			return t, -1
+		}
+		var n int
+		fmt.Sscanf(mods, "%d", &n)
+		return name, n
+	}
+	fromBase, fromLen := base(from)
+	toBase, toLen := base(to)
+
+	switch {
+	case fromBase == "character varying" && toBase == "text":
+		return true
+	case fromBase == toBase && (fromBase == "character varying" || fromBase == "numeric"):
+		return toLen < 0 || (fromLen >= 0 && toLen >= fromLen && fromBase == "character varying")
+	}
+
+	widening := map[string][]string{
+		"smallint": {"integer", "bigint", "numeric"},
+		"integer":  {"bigint", "numeric"},
+		"bigint":   {"numeric"},
+		"real":     {"double precision"},
+	}
+	return slices.Contains(widening[from], to)
+}
+
+// normalizeExpr returns a canonical form of an SQL expression for
+// comparison. Casts and redundant parentheses are dropped, so the catalog
+// form ('active'::character varying) compares equal to the hand-written
+// 'active', and sequence names in nextval() lose their schema.
+func normalizeExpr(text, schema string) string {
+	if text == "" {
+		return ""
+	}
+	tokens, err := sqlparser.Tokenize(text)
+	if err != nil {
+		return strings.Join(strings.Fields(text), " ")
+	}
+
+	var kept []sqlparser.Token
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.Kind == sqlparser.TokenEOF || tok.Kind == sqlparser.TokenComment:
+		case tok.Kind == sqlparser.TokenOperator && tok.Text == "::":
+			i = skipTypeTokens(tokens, i+1) - 1
+		default:
+			kept = append(kept, tok)
+		}
+	}
+	kept = stripParens(kept)
+
+	parts := make([]string, len(kept))
+	for i, tok := range kept {
+		switch tok.Kind {
+		case sqlparser.TokenIdent:
+			parts[i] = tok.Value
+		case sqlparser.TokenString:
+			value := tok.Value
+			if i >= 2 && kept[i-2].Value == "nextval" {
+				value = strings.TrimPrefix(value, schema+".")
+			}
+			parts[i] = quoteLiteral(value)
+		default:
+			parts[i] = tok.Text
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// skipTypeTokens returns the index following the type name at tokens[i]
+func skipTypeTokens(tokens []sqlparser.Token, i int) int {
+	isName := func(i int) bool {
+		return i < len(tokens) && (tokens[i].Kind == sqlparser.TokenIdent || tokens[i].Kind == sqlparser.TokenQuotedIdent)
+	}
+	if !isName(i) {
+		return i
+	}
+	i++
+	for i+1 < len(tokens) && tokens[i].Kind == sqlparser.TokenDot && isName(i+1) {
+		i += 2
+	}
+	for i < len(tokens) && typeContinuation[tokens[i].Keyword()] {
+		i++
+	}
+	if i < len(tokens) && tokens[i].Kind == sqlparser.TokenLParen {
+		for depth := 0; i < len(tokens); i++ {
+			if tokens[i].Kind == sqlparser.TokenLParen {
+				depth++
+			} else if tokens[i].Kind == sqlparser.TokenRParen {
+				depth--
+				if depth == 0 {
+					i++
+					break
+				}
+			}
+		}
+	}
+	for i+1 < len(tokens) && tokens[i].Kind == sqlparser.TokenLBracket {
+		j := i + 1
+		if tokens[j].Kind == sqlparser.TokenNumber {
+			j++
+		}
+		if j >= len(tokens) || tokens[j].Kind != sqlparser.TokenRBracket {
+			break
+		}
+		i = j + 1
+	}
+	return i
+}
+
+// stripParens removes parentheses that do not change the meaning of an
+// expression: around the whole of it, around a single token, and the outer
+// pair of doubled parentheses. Parentheses of calls and lists are kept.
+func stripParens(tokens []sqlparser.Token) []sqlparser.Token {
+	for {
+		closing := make(map[int]int)
+		var stack []int
+		for i, tok := range tokens {
+			switch tok.Kind {
+			case sqlparser.TokenLParen:
+				stack = append(stack, i)
+			case sqlparser.TokenRParen:
+				if len(stack) > 0 {
+					closing[stack[len(stack)-1]] = i
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+
+		removed := false
+		for open := 0; open < len(tokens) && !removed; open++ {
+			end, ok := closing[open]
+			if !ok {
+				continue
+			}
+			call := open > 0 && (tokens[open-1].Kind == sqlparser.TokenIdent || tokens[open-1].Kind == sqlparser.TokenQuotedIdent)
+			whole := open == 0 && end == len(tokens)-1
+			single := end == open+2 && !call
+			doubled := open+1 < end && tokens[open+1].Kind == sqlparser.TokenLParen && closing[open+1] == end-1
+			if whole || single || doubled {
+				tokens = append(tokens[:open:open], append(tokens[open+1:end:end], tokens[end+1:]...)...)
+				removed = true
+			}
+		}
+		if !removed {
+			return tokens
+		}
+	}
+}
+
+// isPlainIdent reports whether an identifier needs no quoting
+func isPlainIdent(name string) bool {
+	if name == "" || reservedWords[strings.ToUpper(name)] {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+		case (r >= '0' && r <= '9') || r == '$':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// reservedWords are keywords that can't be used as bare column or table names
+var reservedWords = map[string]bool{
+	"ALL": true, "ANALYSE": true, "ANALYZE": true, "AND": true, "ANY": true, "ARRAY": true, "AS": true,
+	"ASC": true, "ASYMMETRIC": true, "BOTH": true, "CASE": true, "CAST": true, "CHECK": true,
+	"COLLATE": true, "COLUMN": true, "CONSTRAINT": true, "CREATE": true, "CURRENT_DATE": true,
+	"CURRENT_ROLE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true, "CURRENT_USER": true,
+	"DEFAULT": true, "DEFERRABLE": true, "DESC": true, "DISTINCT": true, "DO": true, "ELSE": true,
+	"END": true, "EXCEPT": true, "FALSE": true, "FETCH": true, "FOR": true, "FOREIGN": true,
+	"FROM": true, "GRANT": true, "GROUP": true, "HAVING": true, "IN": true, "INITIALLY": true,
+	"INTERSECT": true, "INTO": true, "LATERAL": true, "LEADING": true, "LIMIT": true,
+	"LOCALTIME": true, "LOCALTIMESTAMP": true, "NOT": true, "NULL": true, "OFFSET": true, "ON": true,
+	"ONLY": true, "OR": true, "ORDER": true, "PLACING": true, "PRIMARY": true, "REFERENCES": true,
+	"RETURNING": true, "SELECT": true, "SESSION_USER": true, "SOME": true, "SYMMETRIC": true,
+	"TABLE": true, "THEN": true, "TO": true, "TRAILING": true, "TRUE": true, "UNION": true,
+	"UNIQUE": true, "USER": true, "USING": true, "VARIADIC": true, "WHEN": true, "WHERE": true,
+	"WINDOW": true, "WITH": true,
+}
+
+// quoteIdent quotes an identifier when it is not a plain lower case name
+func quoteIdent(name string) string {
+	if isPlainIdent(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quoteLiteral renders a string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteLiterals(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quoteLiteral(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,993 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/postgres/sqlparser"
+)
+
+// maxSchemaSourceSize bounds the size of a migration file or schema dump
+const maxSchemaSourceSize = 32 << 20 // 32MB
+
+// SchemaSnapshot is the structure of one schema as compared by a schema
+// diff: its tables, enum types and functions. Snapshots are built by applying
+// DDL in order, whether it comes from migration files, a schema dump or the
+// catalog of a live database.
+type SchemaSnapshot struct {
+	Schema string `json:"schema"`
+	Source string `json:"source"`
+	// Catalog is set when expressions are in the form PostgreSQL prints them,
+	// as in a live database or a pg_dump. Catalog expressions carry casts
+	// and parentheses that hand-written SQL does not, so CHECK conditions
+	// and index predicates are only compared between snapshots of one form.
+	Catalog   bool                         `json:"catalog"`
+	Tables    map[string]*SnapshotTable    `json:"tables"`
+	Enums     map[string]*SnapshotEnum     `json:"enums"`
+	Functions map[string]*SnapshotFunction `json:"functions"` // keyed by signature
+}
+
+// SnapshotTable is a table in a schema snapshot
+type SnapshotTable struct {
+	Name        string                `json:"name"`
+	Columns     []*SnapshotColumn     `json:"columns"`
+	Constraints []*SnapshotConstraint `json:"constraints"`
+	Indexes     []*SnapshotIndex      `json:"indexes"`
+}
+
+// SnapshotColumn is a column in a schema snapshot
+type SnapshotColumn struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"` // canonical type name, e.g. "character varying(255)"
+	NotNull   bool   `json:"not_null"`
+	Default   string `json:"default,omitempty"`   // expression as written
+	Identity  string `json:"identity,omitempty"`  // "ALWAYS" or "BY DEFAULT"
+	Generated string `json:"generated,omitempty"` // stored generation expression
+}
+
+// SnapshotConstraint is a table constraint in a schema snapshot. Column
+// constraints are recorded as table constraints with the name PostgreSQL
+// would give them.
+type SnapshotConstraint struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"` // PRIMARY KEY, UNIQUE, FOREIGN KEY, CHECK or EXCLUDE
+	Columns    []string `json:"columns,omitempty"`
+	RefTable   string   `json:"ref_table,omitempty"`
+	RefColumns []string `json:"ref_columns,omitempty"`
+	OnDelete   string   `json:"on_delete,omitempty"`
+	OnUpdate   string   `json:"on_update,omitempty"`
+	Expr       string   `json:"expr,omitempty"` // CHECK condition, or the EXCLUDE clause as written
+	Deferrable bool     `json:"deferrable,omitempty"`
+}
+
+// SnapshotIndex is an index in a schema snapshot. Indexes that implement
+// PRIMARY KEY and UNIQUE constraints are part of the constraint instead.
+type SnapshotIndex struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Unique  bool     `json:"unique"`
+	Method  string   `json:"method"`
+	Columns []string `json:"columns"` // index elements as written
+	Include []string `json:"include,omitempty"`
+	Where   string   `json:"where,omitempty"`
+}
+
+// SnapshotEnum is an enum type in a schema snapshot
+type SnapshotEnum struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// SnapshotFunction is a function or procedure in a schema snapshot
+type SnapshotFunction struct {
+	Name       string `json:"name"`
+	Signature  string `json:"signature"`  // name and argument types, e.g. "touch(uuid, integer)"
+	Definition string `json:"definition"` // CREATE OR REPLACE statement
+	Body       string `json:"body"`
+}
+
+// NewSchemaSnapshot creates an empty snapshot of schema
+func NewSchemaSnapshot(schema, source string, catalog bool) *SchemaSnapshot {
+	if schema == "" {
+		schema = "public"
+	}
+	return &SchemaSnapshot{
+		Schema:    schema,
+		Source:    source,
+		Catalog:   catalog,
+		Tables:    make(map[string]*SnapshotTable),
+		Enums:     make(map[string]*SnapshotEnum),
+		Functions: make(map[string]*SnapshotFunction),
+	}
+}
+
+// LoadSchemaDirectory builds a snapshot by applying the migrations in dir in
+// version order. Only *.up.sql files are applied when the directory has any;
+// otherwise every *.sql file is.
+func LoadSchemaDirectory(dir, schema string) (*SchemaSnapshot, error) {
+	files, err := migrationFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .sql files in %s", dir)
+	}
+
+	snapshot := NewSchemaSnapshot(schema, "directory:"+dir, false)
+	for _, file := range files {
+		src, err := readSchemaFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := snapshot.ApplySQL(src); err != nil {
+			return nil, fmt.Errorf("failed to apply %s: %w", filepath.Base(file), err)
+		}
+	}
+	return snapshot, nil
+}
+
+// LoadSchemaDump builds a snapshot from a schema-only pg_dump
+func LoadSchemaDump(path, schema string) (*SchemaSnapshot, error) {
+	src, err := readSchemaFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// pg_dump writes psql meta-commands such as \restrict, which are not SQL
+	var lines []string
+	for _, line := range strings.Split(src, "\n") {
+		if !strings.HasPrefix(line, "\\") {
+			lines = append(lines, line)
+		}
+	}
+
+	snapshot := NewSchemaSnapshot(schema, "dump:"+path, true)
+	if err := snapshot.ApplySQL(strings.Join(lines, "\n")); err != nil {
+		return nil, fmt.Errorf("failed to apply %s: %w", filepath.Base(path), err)
+	}
+	return snapshot, nil
+}
+
+// NextMigrationVersion returns the version following the highest NNN_ prefix
+// of the migration files in dir
+func NextMigrationVersion(dir string) (int, error) {
+	files, err := migrationFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+	next := 1
+	for _, file := range files {
+		if version, ok := migrationVersion(file); ok && version >= next {
+			next = version + 1
+		}
+	}
+	return next, nil
+}
+
+// migrationFiles lists the up migrations of dir sorted by version, then name
+func migrationFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var up, all []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			up = append(up, filepath.Join(dir, name))
+		case !strings.HasSuffix(name, ".down.sql"):
+			all = append(all, filepath.Join(dir, name))
+		}
+	}
+
+	files := all
+	if len(up) > 0 {
+		files = up
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		vi, _ := migrationVersion(files[i])
+		vj, _ := migrationVersion(files[j])
+		if vi != vj {
+			return vi < vj
+		}
+		return files[i] < files[j]
+	})
+	return files, nil
+}
+
+// migrationVersion parses the NNN prefix of a migration file name
+func migrationVersion(path string) (int, bool) {
+	prefix, _, ok := strings.Cut(filepath.Base(path), "_")
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(prefix)
+	return version, err == nil
+}
+
+func readSchemaFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if info.Size() > maxSchemaSourceSize {
+		return "", fmt.Errorf("%s is larger than %d bytes", path, maxSchemaSourceSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// ApplySQL applies the DDL statements of src to the snapshot. Statements
+// that do not change tables, enums or functions are ignored, as are objects
+// in other schemas.
+func (s *SchemaSnapshot) ApplySQL(src string) error {
+	script, syntaxErrors := parseSQL(src)
+	if len(syntaxErrors) > 0 {
+		return syntaxErrors
+	}
+
+	for _, stmt := range script.Statements {
+		switch stmt := stmt.(type) {
+		case *sqlparser.CreateTableStmt:
+			s.createTable(src, stmt)
+		case *sqlparser.AlterTableStmt:
+			s.alterTable(src, stmt)
+		case *sqlparser.CreateIndexStmt:
+			s.createIndex(src, stmt)
+		case *sqlparser.DropStmt:
+			s.drop(stmt)
+		case *sqlparser.RawStmt:
+			switch stmt.Command {
+			case "CREATE TYPE":
+				s.createEnum(stmt)
+			case "ALTER TYPE":
+				s.alterEnum(stmt)
+			case "ALTER INDEX":
+				s.alterIndex(stmt)
+			case "CREATE FUNCTION", "CREATE PROCEDURE":
+				s.createFunction(src, stmt)
+			}
+		}
+	}
+	s.resolveReferences()
+	return nil
+}
+
+// inSchema reports whether a name refers to an object of the snapshot's schema
+func (s *SchemaSnapshot) inSchema(name sqlparser.QualifiedName) bool {
+	return name.Schema == "" || name.Schema == s.Schema
+}
+
+// TableNames returns the names of the tables in the snapshot, sorted
+func (s *SchemaSnapshot) TableNames() []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Column returns the named column, or nil
+func (t *SnapshotTable) Column(name string) *SnapshotColumn {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+// Constraint returns the named constraint, or nil
+func (t *SnapshotTable) Constraint(name string) *SnapshotConstraint {
+	for _, c := range t.Constraints {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *SchemaSnapshot) createTable(src string, stmt *sqlparser.CreateTableStmt) {
+	if !s.inSchema(stmt.Name) || stmt.Temporary || stmt.PartitionOf != nil {
+		return
+	}
+	if _, exists := s.Tables[stmt.Name.Name]; exists {
+		return
+	}
+
+	table := &SnapshotTable{Name: stmt.Name.Name}
+	s.Tables[table.Name] = table
+	for _, col := range stmt.Columns {
+		s.addColumn(src, table, col)
+	}
+	for _, con := range stmt.Constraints {
+		s.addConstraint(src, table, con)
+	}
+}
+
+// serialTypes maps the serial pseudo-types to the integer type of the column
+var serialTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+}
+
+func (s *SchemaSnapshot) addColumn(src string, table *SnapshotTable, def *sqlparser.ColumnDef) {
+	if def.Type == nil || table.Column(def.Name) != nil {
+		return
+	}
+
+	col := &SnapshotColumn{Name: def.Name, Type: s.normalizeType(def.Type)}
+	if intType, ok := serialTypes[def.Type.Name]; ok && def.Type.ArrayDims == 0 {
+		// A serial column is an integer column that defaults to its own sequence
+		col.Type = intType
+		col.Default = fmt.Sprintf("nextval('%s'::regclass)", sequenceName(table.Name, col.Name))
+		col.NotNull = true
+	}
+	table.Columns = append(table.Columns, col)
+
+	for _, con := range def.Constraints {
+		switch con.Type {
+		case sqlparser.ConstraintNotNull:
+			col.NotNull = true
+		case sqlparser.ConstraintNull:
+			col.NotNull = false
+		case sqlparser.ConstraintDefault:
+			col.Default = sqlparser.Text(src, con.Expr)
+		case sqlparser.ConstraintIdentity:
+			col.Identity = con.Identity
+			col.NotNull = true
+		case sqlparser.ConstraintGenerated:
+			col.Generated = sqlparser.Text(src, con.Expr)
+		default:
+			tableCon := *con
+			tableCon.Columns = []string{def.Name}
+			s.addConstraint(src, table, &tableCon)
+		}
+	}
+}
+
+func (s *SchemaSnapshot) addConstraint(src string, table *SnapshotTable, con *sqlparser.Constraint) {
+	c := &SnapshotConstraint{
+		Name:       con.Name,
+		Type:       string(con.Type),
+		Columns:    con.Columns,
+		Deferrable: con.Deferrable,
+	}
+
+	switch con.Type {
+	case sqlparser.ConstraintPrimaryKey:
+		for _, name := range con.Columns {
+			if col := table.Column(name); col != nil {
+				col.NotNull = true
+			}
+		}
+	case sqlparser.ConstraintUnique:
+	case sqlparser.ConstraintForeignKey:
+		if con.References == nil {
+			return
+		}
+		ref := con.References
+		c.RefTable = ref.Table.Name
+		if !s.inSchema(ref.Table) {
+			c.RefTable = ref.Table.String()
+		}
+		c.RefColumns = ref.Columns
+		c.OnDelete = referentialAction(ref.OnDelete)
+		c.OnUpdate = referentialAction(ref.OnUpdate)
+	case sqlparser.ConstraintCheck:
+		c.Expr = sqlparser.Text(src, con.Expr)
+	case sqlparser.ConstraintExclude:
+		text := sqlparser.Text(src, con)
+		if i := strings.Index(strings.ToUpper(text), "EXCLUDE"); i >= 0 {
+			text = text[i:]
+		}
+		c.Expr = text
+	default:
+		return
+	}
+
+	if c.Name == "" {
+		c.Name = defaultConstraintName(table.Name, c, con)
+	}
+	if table.Constraint(c.Name) != nil {
+		return
+	}
+	table.Constraints = append(table.Constraints, c)
+}
+
+// referentialAction normalises an ON DELETE or ON UPDATE action; NO ACTION
+// is the default and is recorded as ""
+func referentialAction(action string) string {
+	action = strings.ToUpper(action)
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
+
+func (s *SchemaSnapshot) alterTable(src string, stmt *sqlparser.AlterTableStmt) {
+	if !s.inSchema(stmt.Table) {
+		return
+	}
+	table := s.Tables[stmt.Table.Name]
+	if table == nil {
+		return
+	}
+
+	for _, action := range stmt.Actions {
+		col := table.Column(action.Column)
+		switch action.Kind {
+		case sqlparser.AlterAddColumn:
+			s.addColumn(src, table, action.ColumnDef)
+		case sqlparser.AlterDropColumn:
+			table.dropColumn(action.Column)
+		case sqlparser.AlterColumnType:
+			if col != nil && action.Type != nil {
+				col.Type = s.normalizeType(action.Type)
+			}
+		case sqlparser.AlterSetDefault:
+			if col != nil {
+				col.Default = sqlparser.Text(src, action.Default)
+			}
+		case sqlparser.AlterDropDefault:
+			if col != nil {
+				col.Default = ""
+			}
+		case sqlparser.AlterSetNotNull:
+			if col != nil {
+				col.NotNull = true
+			}
+		case sqlparser.AlterDropNotNull:
+			if col != nil {
+				col.NotNull = false
+			}
+		case sqlparser.AlterAddConstraint:
+			s.addConstraint(src, table, action.Constraint)
+		case sqlparser.AlterDropConstraint:
+			table.Constraints = slices.DeleteFunc(table.Constraints, func(c *SnapshotConstraint) bool {
+				return c.Name == action.Name
+			})
+		case sqlparser.AlterRenameColumn:
+			s.renameColumn(table, action.Column, action.NewName)
+		case sqlparser.AlterRenameConstraint:
+			if c := table.Constraint(action.Name); c != nil {
+				c.Name = action.NewName
+			}
+		case sqlparser.AlterRenameTable:
+			s.renameTable(table, action.NewName)
+		case sqlparser.AlterSetSchema:
+			if action.NewName != s.Schema {
+				delete(s.Tables, table.Name)
+				return
+			}
+		}
+	}
+}
+
+// dropColumn removes a column with the constraints and indexes that use it
+func (t *SnapshotTable) dropColumn(name string) {
+	t.Columns = slices.DeleteFunc(t.Columns, func(col *SnapshotColumn) bool { return col.Name == name })
+	t.Constraints = slices.DeleteFunc(t.Constraints, func(c *SnapshotConstraint) bool {
+		return slices.Contains(c.Columns, name) || mentionsIdent(c.Expr, name)
+	})
+	t.Indexes = slices.DeleteFunc(t.Indexes, func(idx *SnapshotIndex) bool {
+		for _, elem := range idx.Columns {
+			if mentionsIdent(elem, name) {
+				return true
+			}
+		}
+		return slices.Contains(idx.Include, name) || mentionsIdent(idx.Where, name)
+	})
+}
+
+func (s *SchemaSnapshot) renameColumn(table *SnapshotTable, from, to string) {
+	col := table.Column(from)
+	if col == nil {
+		return
+	}
+	col.Name = to
+
+	for _, c := range table.Constraints {
+		replaceString(c.Columns, from, to)
+		c.Expr = renameIdent(c.Expr, from, to)
+	}
+	for _, idx := range table.Indexes {
+		for i, elem := range idx.Columns {
+			idx.Columns[i] = renameIdent(elem, from, to)
+		}
+		replaceString(idx.Include, from, to)
+		idx.Where = renameIdent(idx.Where, from, to)
+	}
+	for _, other := range s.Tables {
+		for _, c := range other.Constraints {
+			if c.Type == string(sqlparser.ConstraintForeignKey) && c.RefTable == table.Name {
+				replaceString(c.RefColumns, from, to)
+			}
+		}
+	}
+}
+
+func (s *SchemaSnapshot) renameTable(table *SnapshotTable, name string) {
+	delete(s.Tables, table.Name)
+	for _, other := range s.Tables {
+		for _, c := range other.Constraints {
+			if c.RefTable == table.Name {
+				c.RefTable = name
+			}
+		}
+	}
+	for _, c := range table.Constraints {
+		if c.RefTable == table.Name {
+			c.RefTable = name
+		}
+	}
+	for _, idx := range table.Indexes {
+		idx.Table = name
+	}
+	table.Name = name
+	s.Tables[name] = table
+}
+
+func (s *SchemaSnapshot) createIndex(src string, stmt *sqlparser.CreateIndexStmt) {
+	if !s.inSchema(stmt.Table) {
+		return
+	}
+	table := s.Tables[stmt.Table.Name]
+	if table == nil {
+		return
+	}
+
+	idx := &SnapshotIndex{
+		Name:    stmt.Name,
+		Table:   table.Name,
+		Unique:  stmt.Unique,
+		Method:  strings.ToLower(stmt.Method),
+		Include: stmt.Include,
+	}
+	if idx.Method == "" {
+		idx.Method = "btree"
+	}
+	var names []string
+	for _, elem := range stmt.Columns {
+		idx.Columns = append(idx.Columns, sqlparser.Text(src, elem))
+		// PostgreSQL names expression columns after the function they call
+		if call, ok := elem.Expr.(*sqlparser.FuncCall); ok {
+			names = append(names, call.Name)
+		} else if elem.Column != "" {
+			names = append(names, elem.Column)
+		} else {
+			names = append(names, "expr")
+		}
+	}
+	if stmt.Where != nil {
+		idx.Where = sqlparser.Text(src, stmt.Where)
+	}
+	if idx.Name == "" {
+		idx.Name = truncateIdent(table.Name + "_" + strings.Join(names, "_") + "_idx")
+	}
+
+	if s.findIndex(idx.Name) != nil {
+		return
+	}
+	table.Indexes = append(table.Indexes, idx)
+}
+
+// alterIndex handles ALTER INDEX name RENAME TO new_name
+func (s *SchemaSnapshot) alterIndex(stmt *sqlparser.RawStmt) {
+	tokens := stmt.Tokens
+	name, i := rawName(tokens, 2)
+	if !s.inSchema(name) || !rawKeywords(tokens, i, "RENAME", "TO") || i+2 >= len(tokens) {
+		return
+	}
+	if idx := s.findIndex(name.Name); idx != nil {
+		idx.Name = tokens[i+2].Value
+	}
+}
+
+// findIndex returns the named index of any table, or nil
+func (s *SchemaSnapshot) findIndex(name string) *SnapshotIndex {
+	for _, table := range s.Tables {
+		for _, idx := range table.Indexes {
+			if idx.Name == name {
+				return idx
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SchemaSnapshot) drop(stmt *sqlparser.DropStmt) {
+	for _, name := range stmt.Names {
+		if !s.inSchema(name) {
+			continue
+		}
+		switch stmt.ObjectType {
+		case "TABLE":
+			delete(s.Tables, name.Name)
+			// Foreign keys that reference the table go with it (CASCADE)
+			for _, table := range s.Tables {
+				table.Constraints = slices.DeleteFunc(table.Constraints, func(c *SnapshotConstraint) bool {
+					return c.RefTable == name.Name
+				})
+			}
+		case "INDEX":
+			for _, table := range s.Tables {
+				table.Indexes = slices.DeleteFunc(table.Indexes, func(idx *SnapshotIndex) bool {
+					return idx.Name == name.Name
+				})
+			}
+		case "TYPE":
+			delete(s.Enums, name.Name)
+		case "FUNCTION", "PROCEDURE":
+			// Argument lists are not modelled, so every overload is dropped
+			for signature, fn := range s.Functions {
+				if fn.Name == name.Name {
+					delete(s.Functions, signature)
+				}
+			}
+		}
+	}
+}
+
+// createEnum handles CREATE TYPE name AS ENUM ('a', 'b'); other kinds of
+// types are ignored
+func (s *SchemaSnapshot) createEnum(stmt *sqlparser.RawStmt) {
+	tokens := stmt.Tokens
+	name, i := rawName(tokens, 2)
+	if name.Name == "" || !s.inSchema(name) || !rawKeywords(tokens, i, "AS", "ENUM") {
+		return
+	}
+
+	enum := &SnapshotEnum{Name: name.Name, Values: []string{}}
+	for _, tok := range tokens[i+2:] {
+		if tok.Kind == sqlparser.TokenString {
+			enum.Values = append(enum.Values, tok.Value)
+		}
+	}
+	s.Enums[enum.Name] = enum
+}
+
+// alterEnum handles ADD VALUE, RENAME VALUE and RENAME TO of enum types
+func (s *SchemaSnapshot) alterEnum(stmt *sqlparser.RawStmt) {
+	tokens := stmt.Tokens
+	name, i := rawName(tokens, 2)
+	enum := s.Enums[name.Name]
+	if enum == nil || !s.inSchema(name) {
+		return
+	}
+
+	var literals []string
+	for _, tok := range tokens[i:] {
+		if tok.Kind == sqlparser.TokenString {
+			literals = append(literals, tok.Value)
+		}
+	}
+
+	switch {
+	case rawKeywords(tokens, i, "ADD", "VALUE") && len(literals) > 0:
+		value := literals[0]
+		if slices.Contains(enum.Values, value) {
+			return
+		}
+		position := len(enum.Values)
+		if len(literals) > 1 {
+			if at := slices.Index(enum.Values, literals[1]); at >= 0 {
+				position = at
+				if stmt.HasKeyword("AFTER") {
+					position++
+				}
+			}
+		}
+		enum.Values = slices.Insert(enum.Values, position, value)
+	case rawKeywords(tokens, i, "RENAME", "VALUE") && len(literals) == 2:
+		replaceString(enum.Values, literals[0], literals[1])
+	case rawKeywords(tokens, i, "RENAME", "TO") && i+2 < len(tokens):
+		delete(s.Enums, enum.Name)
+		enum.Name = tokens[i+2].Value
+		s.Enums[enum.Name] = enum
+	}
+}
+
+// createFunction records a CREATE [OR REPLACE] FUNCTION or PROCEDURE
+func (s *SchemaSnapshot) createFunction(src string, stmt *sqlparser.RawStmt) {
+	tokens := stmt.Tokens
+	start := 1
+	for start < len(tokens) && tokens[start].Keyword() != "FUNCTION" && tokens[start].Keyword() != "PROCEDURE" {
+		start++
+	}
+	name, i := rawName(tokens, start+1)
+	if name.Name == "" || !s.inSchema(name) || i >= len(tokens) || tokens[i].Kind != sqlparser.TokenLParen {
+		return
+	}
+
+	// Split the argument list on top-level commas
+	var args [][]sqlparser.Token
+	var arg []sqlparser.Token
+	depth := 0
+	for i++; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch tok.Kind {
+		case sqlparser.TokenLParen:
+			depth++
+		case sqlparser.TokenRParen:
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+		if depth == 0 && tok.Kind == sqlparser.TokenComma {
+			args = append(args, arg)
+			arg = nil
+			continue
+		}
+		arg = append(arg, tok)
+	}
+	if len(arg) > 0 {
+		args = append(args, arg)
+	}
+
+	var argTypes []string
+	for _, arg := range args {
+		if argType, ok := s.functionArgType(arg); ok {
+			argTypes = append(argTypes, argType)
+		}
+	}
+
+	fn := &SnapshotFunction{
+		Name:      name.Name,
+		Signature: fmt.Sprintf("%s(%s)", name.Name, strings.Join(argTypes, ", ")),
+	}
+
+	// The body is the string literal following AS
+	for j := i; j+1 < len(tokens); j++ {
+		if tokens[j].Keyword() == "AS" && tokens[j+1].Kind == sqlparser.TokenString {
+			fn.Body = tokens[j+1].Value
+			break
+		}
+	}
+
+	definition := sqlparser.Text(src, stmt)
+	if tokens[1].Keyword() != "OR" {
+		definition = "CREATE OR REPLACE" + definition[len(tokens[0].Text):]
+	}
+	fn.Definition = definition
+	if fn.Body == "" {
+		fn.Body = definition
+	}
+
+	s.Functions[fn.Signature] = fn
+}
+
+// argModes are the parameter modes of a function argument
+var argModes = map[string]bool{"IN": true, "OUT": true, "INOUT": true, "VARIADIC": true}
+
+// functionArgType returns the canonical type of a function argument, or
+// false for OUT arguments, which are not part of the signature
+func (s *SchemaSnapshot) functionArgType(arg []sqlparser.Token) (string, bool) {
+	if len(arg) == 0 {
+		return "", false
+	}
+	if mode := arg[0].Keyword(); argModes[mode] {
+		if mode == "OUT" {
+			return "", false
+		}
+		arg = arg[1:]
+	}
+	for i, tok := range arg {
+		if tok.Keyword() == "DEFAULT" || (tok.Kind == sqlparser.TokenOperator && tok.Text == "=") {
+			arg = arg[:i]
+			break
+		}
+	}
+	// Drop the argument name: a type never continues with a plain identifier
+	// unless it is one of the multi-word type names
+	if len(arg) > 1 && arg[1].Kind == sqlparser.TokenIdent && !typeContinuation[arg[1].Keyword()] {
+		arg = arg[1:]
+	}
+
+	var text []string
+	for _, tok := range arg {
+		text = append(text, tok.Text)
+	}
+	expr, err := sqlparser.ParseExpr("NULL::" + strings.Join(text, " "))
+	if err != nil {
+		return strings.ToLower(strings.Join(text, " ")), true
+	}
+	cast, ok := expr.(*sqlparser.CastExpr)
+	if !ok {
+		return strings.ToLower(strings.Join(text, " ")), true
+	}
+	return s.normalizeType(cast.Type), true
+}
+
+// typeContinuation are the words that continue multi-word type names such as
+// double precision and timestamp with time zone
+var typeContinuation = map[string]bool{
+	"PRECISION": true, "VARYING": true, "WITH": true, "WITHOUT": true, "TIME": true, "ZONE": true,
+}
+
+// resolveReferences fills in the referenced columns of foreign keys that
+// name only a table; they reference its primary key
+func (s *SchemaSnapshot) resolveReferences() {
+	for _, table := range s.Tables {
+		for _, c := range table.Constraints {
+			if c.Type != string(sqlparser.ConstraintForeignKey) || len(c.RefColumns) > 0 {
+				continue
+			}
+			if ref := s.Tables[c.RefTable]; ref != nil {
+				for _, rc := range ref.Constraints {
+					if rc.Type == string(sqlparser.ConstraintPrimaryKey) {
+						c.RefColumns = append([]string(nil), rc.Columns...)
+					}
+				}
+			}
+		}
+	}
+}
+
+// typeAliases maps alternative type names to the names PostgreSQL prints
+var typeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"int2":        "smallint",
+	"float":       "double precision",
+	"float8":      "double precision",
+	"float4":      "real",
+	"bool":        "boolean",
+	"decimal":     "numeric",
+	"varchar":     "character varying",
+	"char":        "character",
+	"varbit":      "bit varying",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+}
+
+// normalizeType returns the canonical spelling of a type
+func (s *SchemaSnapshot) normalizeType(t *sqlparser.TypeName) string {
+	canonical := *t
+	if alias, ok := typeAliases[canonical.Name]; ok {
+		canonical.Name = alias
+	}
+	if canonical.Schema == s.Schema || canonical.Schema == "pg_catalog" {
+		canonical.Schema = ""
+	}
+	return canonical.String()
+}
+
+// sequenceName returns the name PostgreSQL gives the sequence of a serial column
+func sequenceName(table, column string) string {
+	return truncateIdent(table + "_" + column + "_seq")
+}
+
+// defaultConstraintName returns the name PostgreSQL gives an unnamed constraint
+func defaultConstraintName(table string, c *SnapshotConstraint, con *sqlparser.Constraint) string {
+	switch sqlparser.ConstraintType(c.Type) {
+	case sqlparser.ConstraintPrimaryKey:
+		return truncateIdent(table + "_pkey")
+	case sqlparser.ConstraintUnique:
+		return truncateIdent(table + "_" + strings.Join(c.Columns, "_") + "_key")
+	case sqlparser.ConstraintForeignKey:
+		return truncateIdent(table + "_" + strings.Join(c.Columns, "_") + "_fkey")
+	case sqlparser.ConstraintExclude:
+		return truncateIdent(table + "_excl")
+	}
+
+	// CHECK constraints are named after their column when the condition
+	// references exactly one
+	columns := make(map[string]bool)
+	sqlparser.Inspect(con.Expr, func(n sqlparser.Node) bool {
+		if ref, ok := n.(*sqlparser.ColumnRef); ok && !ref.Star {
+			columns[ref.Column()] = true
+		}
+		return true
+	})
+	if len(columns) == 1 {
+		for column := range columns {
+			return truncateIdent(table + "_" + column + "_check")
+		}
+	}
+	return truncateIdent(table + "_check")
+}
+
+// truncateIdent truncates a generated name to PostgreSQL's 63 byte limit
+func truncateIdent(name string) string {
+	if len(name) > 63 {
+		return name[:63]
+	}
+	return name
+}
+
+// rawName reads a possibly schema-qualified name starting at tokens[i] and
+// returns it with the index of the token that follows it
+func rawName(tokens []sqlparser.Token, i int) (sqlparser.QualifiedName, int) {
+	var name sqlparser.QualifiedName
+	for i < len(tokens) && (tokens[i].Keyword() == "IF" || tokens[i].Keyword() == "NOT" || tokens[i].Keyword() == "EXISTS") {
+		i++
+	}
+	if i >= len(tokens) || (tokens[i].Kind != sqlparser.TokenIdent && tokens[i].Kind != sqlparser.TokenQuotedIdent) {
+		return name, i
+	}
+	name.Name = tokens[i].Value
+	i++
+	if i+1 < len(tokens) && tokens[i].Kind == sqlparser.TokenDot {
+		name.Schema, name.Name = name.Name, tokens[i+1].Value
+		i += 2
+	}
+	return name, i
+}
+
+// rawKeywords reports whether tokens[i:] starts with the given keywords
+func rawKeywords(tokens []sqlparser.Token, i int, keywords ...string) bool {
+	if i+len(keywords) > len(tokens) {
+		return false
+	}
+	for j, kw := range keywords {
+		if tokens[i+j].Keyword() != kw {
+			return false
+		}
+	}
+	return true
+}
+
+// mentionsIdent reports whether the SQL text contains the identifier name
+func mentionsIdent(text, name string) bool {
+	if text == "" {
+		return false
+	}
+	tokens, _ := sqlparser.Tokenize(text)
+	for _, tok := range tokens {
+		if (tok.Kind == sqlparser.TokenIdent || tok.Kind == sqlparser.TokenQuotedIdent) && tok.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+// renameIdent replaces the identifier from with to in SQL text
+func renameIdent(text, from, to string) string {
+	if !mentionsIdent(text, from) {
+		return text
+	}
+	tokens, _ := sqlparser.Tokenize(text)
+	var b strings.Builder
+	last := 0
+	for _, tok := range tokens {
+		if (tok.Kind == sqlparser.TokenIdent || tok.Kind == sqlparser.TokenQuotedIdent) && tok.Value == from {
+			b.WriteString(text[last:tok.Pos.Offset])
+			b.WriteString(quoteIdent(to))
+			last = tok.End.Offset
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// replaceString replaces every from in items with to, in place
+func replaceString(items []string, from, to string) {
+	for i, item := range items {
+		if item == from {
+			items[i] = to
+		}
+	}
+}
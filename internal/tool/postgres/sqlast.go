@@ -0,0 +1,327 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/koopa0/assistant-go/internal/tool/postgres/sqlparser"
+)
+
+// parseSQL parses src into a script. Statements that fail to parse are kept
+// as *sqlparser.BadStmt and their errors are returned alongside the script.
+func parseSQL(src string) (*sqlparser.Script, sqlparser.ErrorList) {
+	script, err := sqlparser.Parse(src)
+	var errs sqlparser.ErrorList
+	errors.As(err, &errs)
+	return script, errs
+}
+
+// queryTypeOf returns the query type reported by the analyzer for a statement
+func queryTypeOf(stmt sqlparser.Stmt) string {
+	switch s := stmt.(type) {
+	case *sqlparser.SelectStmt:
+		return "SELECT"
+	case *sqlparser.InsertStmt:
+		return "INSERT"
+	case *sqlparser.UpdateStmt:
+		return "UPDATE"
+	case *sqlparser.DeleteStmt:
+		return "DELETE"
+	case *sqlparser.ExplainStmt:
+		return queryTypeOf(s.Stmt)
+	case *sqlparser.CreateTableStmt, *sqlparser.CreateIndexStmt, *sqlparser.CreateViewStmt:
+		return "CREATE"
+	case *sqlparser.AlterTableStmt:
+		return "ALTER"
+	case *sqlparser.DropStmt:
+		return "DROP"
+	case *sqlparser.RawStmt:
+		keyword, _, _ := strings.Cut(s.Command, " ")
+		return queryTypeFromKeyword(keyword)
+	}
+	return "OTHER"
+}
+
+// queryTypeFromKeyword maps the leading keyword of a statement to a query type
+func queryTypeFromKeyword(keyword string) string {
+	switch keyword {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "DROP":
+		return keyword
+	}
+	return "OTHER"
+}
+
+// leadingKeyword returns the first keyword of src, skipping comments
+func leadingKeyword(src string) string {
+	tokens, _ := sqlparser.Tokenize(src)
+	for _, tok := range tokens {
+		if tok.Kind != sqlparser.TokenComment {
+			return tok.Keyword()
+		}
+	}
+	return ""
+}
+
+// cteNames returns the names of every common table expression in node
+func cteNames(node sqlparser.Node) map[string]bool {
+	names := make(map[string]bool)
+	sqlparser.Inspect(node, func(n sqlparser.Node) bool {
+		if cte, ok := n.(*sqlparser.CTE); ok {
+			names[cte.Name] = true
+		}
+		return true
+	})
+	return names
+}
+
+// referencedTables returns the tables a statement reads or writes, in order
+// of first appearance. References to CTEs are not tables and are skipped.
+func referencedTables(stmt sqlparser.Stmt) []string {
+	ctes := cteNames(stmt)
+	seen := make(map[string]bool)
+	tables := []string{}
+	add := func(name sqlparser.QualifiedName) {
+		if name.Name == "" || (name.Schema == "" && ctes[name.Name]) {
+			return
+		}
+		key := name.String()
+		if !seen[key] {
+			seen[key] = true
+			tables = append(tables, key)
+		}
+	}
+
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		switch n := n.(type) {
+		case *sqlparser.TableName:
+			add(n.Name)
+		case *sqlparser.CreateTableStmt:
+			add(n.Name)
+		case *sqlparser.CreateIndexStmt:
+			add(n.Table)
+		case *sqlparser.AlterTableStmt:
+			add(n.Table)
+		case *sqlparser.TruncateStmt:
+			for _, name := range n.Tables {
+				add(name)
+			}
+		case *sqlparser.DropStmt:
+			if n.ObjectType == "TABLE" {
+				for _, name := range n.Names {
+					add(name)
+				}
+			}
+		}
+		return true
+	})
+	return tables
+}
+
+// whereClauses calls fn with the WHERE condition of every SELECT, UPDATE and
+// DELETE in node, including those of subqueries
+func whereClauses(node sqlparser.Node, fn func(where sqlparser.Expr)) {
+	sqlparser.Inspect(node, func(n sqlparser.Node) bool {
+		var where sqlparser.Expr
+		switch n := n.(type) {
+		case *sqlparser.SelectStmt:
+			where = n.Where
+		case *sqlparser.UpdateStmt:
+			where = n.Where
+		case *sqlparser.DeleteStmt:
+			where = n.Where
+		}
+		if where != nil {
+			fn(where)
+		}
+		return true
+	})
+}
+
+// inspectShallow traverses an expression without descending into subqueries,
+// which have their own clauses
+func inspectShallow(expr sqlparser.Expr, fn func(sqlparser.Node) bool) {
+	if expr == nil {
+		return
+	}
+	sqlparser.Inspect(expr, func(n sqlparser.Node) bool {
+		if _, ok := n.(*sqlparser.SelectStmt); ok {
+			return false
+		}
+		return fn(n)
+	})
+}
+
+// aliasTables maps the aliases and names of the tables in a FROM list, and
+// the DML target if given, to table names
+func aliasTables(target *sqlparser.TableName, from []sqlparser.TableExpr) map[string]string {
+	aliases := make(map[string]string)
+	add := func(t *sqlparser.TableName) {
+		aliases[t.Name.Name] = t.Name.Name
+		if t.Alias != nil {
+			aliases[t.Alias.Name] = t.Name.Name
+		}
+	}
+	if target != nil {
+		add(target)
+	}
+	for _, item := range from {
+		sqlparser.Inspect(item, func(n sqlparser.Node) bool {
+			switch n := n.(type) {
+			case *sqlparser.TableName:
+				add(n)
+			case *sqlparser.SubqueryTable, *sqlparser.FuncTable:
+				return false
+			}
+			return true
+		})
+	}
+	return aliases
+}
+
+// isComparisonOp reports whether op compares two values
+func isComparisonOp(op string) bool {
+	switch op {
+	case "=", "<>", "!=", "<", ">", "<=", ">=":
+		return true
+	}
+	return false
+}
+
+// aggregateFuncs are the aggregate functions counted toward query complexity
+var aggregateFuncs = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+	"array_agg": true, "string_agg": true, "json_agg": true, "jsonb_agg": true,
+	"bool_and": true, "bool_or": true,
+}
+
+// Column usage kinds reported by columnUses
+const (
+	useWhere = "where"
+	useJoin  = "join"
+	useOrder = "order"
+)
+
+// columnUse is a column referenced by a filter, join condition or sort
+type columnUse struct {
+	Table    string // table the column belongs to; "" when it cannot be resolved
+	Column   string
+	Kind     string // useWhere, useJoin or useOrder
+	Equality bool   // compared with = or IN, which favours leading index positions
+}
+
+// columnUses returns the filter, join and sort columns of every SELECT,
+// UPDATE and DELETE in stmt, with table aliases resolved to table names
+func columnUses(stmt sqlparser.Stmt) []columnUse {
+	var uses []columnUse
+
+	sqlparser.Inspect(stmt, func(n sqlparser.Node) bool {
+		var (
+			aliases map[string]string
+			where   sqlparser.Expr
+			from    []sqlparser.TableExpr
+			orderBy []*sqlparser.OrderItem
+		)
+		switch n := n.(type) {
+		case *sqlparser.SelectStmt:
+			aliases, where, from, orderBy = aliasTables(nil, n.From), n.Where, n.From, n.OrderBy
+		case *sqlparser.UpdateStmt:
+			aliases, where, from = aliasTables(n.Table, n.From), n.Where, n.From
+		case *sqlparser.DeleteStmt:
+			aliases, where, from = aliasTables(n.Table, n.Using), n.Where, n.Using
+		default:
+			return true
+		}
+
+		scope := columnScope{aliases: aliases}
+		inspectShallow(where, func(n sqlparser.Node) bool {
+			uses = append(uses, scope.predicateUses(n, useWhere)...)
+			return true
+		})
+		for _, item := range from {
+			sqlparser.Inspect(item, func(n sqlparser.Node) bool {
+				join, ok := n.(*sqlparser.JoinExpr)
+				if !ok {
+					return true
+				}
+				inspectShallow(join.On, func(n sqlparser.Node) bool {
+					uses = append(uses, scope.predicateUses(n, useJoin)...)
+					return true
+				})
+				for _, column := range join.Using {
+					for _, side := range []sqlparser.TableExpr{join.Left, join.Right} {
+						if t, ok := side.(*sqlparser.TableName); ok {
+							uses = append(uses, columnUse{Table: t.Name.Name, Column: column, Kind: useJoin, Equality: true})
+						}
+					}
+				}
+				return true
+			})
+		}
+		for _, item := range orderBy {
+			if ref, ok := item.Expr.(*sqlparser.ColumnRef); ok && !ref.Star {
+				uses = append(uses, columnUse{Table: scope.resolve(ref), Column: ref.Column(), Kind: useOrder})
+			}
+		}
+		return true
+	})
+	return uses
+}
+
+// columnScope resolves column references against the tables of one query level
+type columnScope struct {
+	aliases map[string]string
+}
+
+// resolve returns the table a column reference belongs to, or "" if the
+// reference is unqualified and more than one table is in scope
+func (s columnScope) resolve(ref *sqlparser.ColumnRef) string {
+	if q := ref.Qualifier(); q != "" {
+		return s.aliases[q]
+	}
+	var table string
+	for _, t := range s.aliases {
+		if table != "" && t != table {
+			return ""
+		}
+		table = t
+	}
+	return table
+}
+
+// predicateUses returns the column uses of a single predicate node. Column to
+// column comparisons are join conditions wherever they appear.
+func (s columnScope) predicateUses(n sqlparser.Node, kind string) []columnUse {
+	use := func(e sqlparser.Expr, kind string, equality bool) []columnUse {
+		ref, ok := e.(*sqlparser.ColumnRef)
+		if !ok || ref.Star {
+			return nil
+		}
+		return []columnUse{{Table: s.resolve(ref), Column: ref.Column(), Kind: kind, Equality: equality}}
+	}
+
+	switch n := n.(type) {
+	case *sqlparser.BinaryExpr:
+		if !isComparisonOp(n.Op) {
+			return nil
+		}
+		_, leftCol := n.Left.(*sqlparser.ColumnRef)
+		_, rightCol := n.Right.(*sqlparser.ColumnRef)
+		if leftCol && rightCol {
+			return append(use(n.Left, useJoin, n.Op == "="), use(n.Right, useJoin, n.Op == "=")...)
+		}
+		return append(use(n.Left, kind, n.Op == "="), use(n.Right, kind, n.Op == "=")...)
+	case *sqlparser.InExpr:
+		if !n.Not {
+			return use(n.Expr, kind, true)
+		}
+	case *sqlparser.BetweenExpr:
+		return use(n.Expr, kind, false)
+	case *sqlparser.IsExpr:
+		return use(n.Expr, kind, n.Test == "NULL")
+	case *sqlparser.LikeExpr:
+		if lit, ok := n.Pattern.(*sqlparser.Literal); ok && !strings.HasPrefix(lit.Value, "%") {
+			return use(n.Expr, kind, false)
+		}
+	}
+	return nil
+}
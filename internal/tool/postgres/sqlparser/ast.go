@@ -0,0 +1,788 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node is implemented by every AST node
+type Node interface {
+	Pos() Pos // position of the first byte of the node
+	End() Pos // position immediately after the node
+}
+
+// Stmt is a top-level statement
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Expr is a value expression
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// TableExpr is an item in a FROM clause
+type TableExpr interface {
+	Node
+	tableExprNode()
+}
+
+// Span records the source range of a node and implements Pos and End
+type Span struct {
+	From Pos
+	To   Pos
+}
+
+// Pos returns the start of the span
+func (s Span) Pos() Pos { return s.From }
+
+// setSpan replaces the span. The parser uses it to widen a parenthesised
+// expression to cover its parentheses.
+func (s *Span) setSpan(span Span) { *s = span }
+
+// End returns the position immediately after the span
+func (s Span) End() Pos { return s.To }
+
+// Text returns the source text of a node
+func Text(src string, n Node) string {
+	from, to := n.Pos().Offset, n.End().Offset
+	if from < 0 || to > len(src) || from > to {
+		return ""
+	}
+	return src[from:to]
+}
+
+// Script is a parsed sequence of statements
+type Script struct {
+	Statements []Stmt
+	// Comments holds every comment in the source, in order
+	Comments []Token
+}
+
+// ----------------------------------------------------------------------------
+// Names
+
+// QualifiedName is an optionally schema-qualified object name.
+// Unquoted parts are folded to lower case.
+type QualifiedName struct {
+	Span
+	Schema string
+	Name   string
+}
+
+// String returns the name as schema.name, or just name when unqualified
+func (n QualifiedName) String() string {
+	if n.Schema == "" {
+		return n.Name
+	}
+	return n.Schema + "." + n.Name
+}
+
+// Alias is a table alias with optional column aliases
+type Alias struct {
+	Span
+	Name    string
+	Columns []string
+}
+
+// TypeName is a data type such as integer, varchar(255) or timestamp with time zone
+type TypeName struct {
+	Span
+	Schema    string
+	Name      string // lower case; multi-word names are joined by single spaces
+	Modifiers []Expr
+	ArrayDims int
+	SetOf     bool
+}
+
+// String renders the type with its modifiers and array dimensions
+func (t *TypeName) String() string {
+	var b strings.Builder
+	if t.SetOf {
+		b.WriteString("setof ")
+	}
+	if t.Schema != "" {
+		b.WriteString(t.Schema)
+		b.WriteByte('.')
+	}
+
+	name, suffix := t.Name, ""
+	for _, zone := range []string{" with time zone", " without time zone"} {
+		if strings.HasSuffix(name, zone) {
+			name, suffix = strings.TrimSuffix(name, zone), zone
+		}
+	}
+	b.WriteString(name)
+
+	if len(t.Modifiers) > 0 {
+		b.WriteByte('(')
+		for i, m := range t.Modifiers {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if lit, ok := m.(*Literal); ok {
+				b.WriteString(lit.Value)
+			} else if ref, ok := m.(*ColumnRef); ok {
+				b.WriteString(strings.Join(ref.Fields, "."))
+			}
+		}
+		b.WriteByte(')')
+	}
+	b.WriteString(suffix)
+	for i := 0; i < t.ArrayDims; i++ {
+		b.WriteString("[]")
+	}
+	return b.String()
+}
+
+// ----------------------------------------------------------------------------
+// Queries
+
+// WithClause is a WITH list of common table expressions
+type WithClause struct {
+	Span
+	Recursive bool
+	CTEs      []*CTE
+}
+
+// CTE is a single common table expression
+type CTE struct {
+	Span
+	Name         string
+	Columns      []string
+	Materialized string // "", "MATERIALIZED" or "NOT MATERIALIZED"
+	Query        Stmt   // *SelectStmt, or a data-modifying statement with RETURNING
+}
+
+// SelectStmt is a SELECT, VALUES or TABLE query, or a set operation combining two queries
+type SelectStmt struct {
+	Span
+	With *WithClause
+
+	Distinct   bool
+	DistinctOn []Expr
+	Targets    []*ResultTarget
+	From       []TableExpr
+	Where      Expr
+	GroupBy    []Expr
+	Having     Expr
+	Windows    []*WindowSpec
+
+	// Values holds the rows of a VALUES list
+	Values [][]Expr
+
+	// SetOp is "UNION", "INTERSECT" or "EXCEPT", optionally followed by " ALL";
+	// Left and Right are its operands
+	SetOp string
+	Left  *SelectStmt
+	Right *SelectStmt
+
+	OrderBy []*OrderItem
+	Limit   Expr
+	Offset  Expr
+	Locking []string // e.g. "FOR UPDATE SKIP LOCKED"
+}
+
+// ResultTarget is an item in a SELECT or RETURNING list
+type ResultTarget struct {
+	Span
+	Expr  Expr
+	Alias string
+}
+
+// OrderItem is an ORDER BY element
+type OrderItem struct {
+	Span
+	Expr  Expr
+	Desc  bool
+	Nulls string // "", "FIRST" or "LAST"
+	Using string // operator given with USING
+}
+
+// WindowSpec is a window definition used by OVER or WINDOW
+type WindowSpec struct {
+	Span
+	Name        string // WINDOW name, or the name of an existing window to refer to
+	Ref         string // existing window the definition builds on
+	PartitionBy []Expr
+	OrderBy     []*OrderItem
+	Frame       string // frame clause as written, e.g. "ROWS BETWEEN 1 PRECEDING AND CURRENT ROW"
+}
+
+// TableName is a reference to a table, view or CTE in a FROM clause or a DML target
+type TableName struct {
+	Span
+	Name  QualifiedName
+	Only  bool
+	Alias *Alias
+}
+
+// SubqueryTable is a subquery in a FROM clause
+type SubqueryTable struct {
+	Span
+	Lateral bool
+	Query   *SelectStmt
+	Alias   *Alias
+}
+
+// FuncTable is a set-returning function in a FROM clause
+type FuncTable struct {
+	Span
+	Lateral        bool
+	Func           *FuncCall
+	WithOrdinality bool
+	Alias          *Alias
+}
+
+// JoinExpr joins two FROM items
+type JoinExpr struct {
+	Span
+	Type    string // "INNER", "LEFT", "RIGHT", "FULL" or "CROSS"
+	Natural bool
+	Left    TableExpr
+	Right   TableExpr
+	On      Expr
+	Using   []string
+}
+
+// InsertStmt is an INSERT statement
+type InsertStmt struct {
+	Span
+	With          *WithClause
+	Table         *TableName
+	Columns       []string
+	Query         *SelectStmt // VALUES list or query; nil with DEFAULT VALUES
+	DefaultValues bool
+	OnConflict    *OnConflict
+	Returning     []*ResultTarget
+}
+
+// OnConflict is the ON CONFLICT clause of an INSERT
+type OnConflict struct {
+	Span
+	Target      []*IndexElem
+	TargetWhere Expr
+	Constraint  string
+	DoNothing   bool
+	Set         []*SetClause
+	Where       Expr
+}
+
+// SetClause is an assignment in UPDATE ... SET or ON CONFLICT DO UPDATE SET
+type SetClause struct {
+	Span
+	Columns []string // more than one for (a, b) = (...)
+	Value   Expr
+}
+
+// UpdateStmt is an UPDATE statement
+type UpdateStmt struct {
+	Span
+	With      *WithClause
+	Table     *TableName
+	Set       []*SetClause
+	From      []TableExpr
+	Where     Expr
+	Returning []*ResultTarget
+}
+
+// DeleteStmt is a DELETE statement
+type DeleteStmt struct {
+	Span
+	With      *WithClause
+	Table     *TableName
+	Using     []TableExpr
+	Where     Expr
+	Returning []*ResultTarget
+}
+
+// ExplainStmt is an EXPLAIN statement
+type ExplainStmt struct {
+	Span
+	Analyze bool
+	Options []string
+	Stmt    Stmt
+}
+
+// ----------------------------------------------------------------------------
+// DDL
+
+// CreateTableStmt is a CREATE TABLE statement
+type CreateTableStmt struct {
+	Span
+	Name        QualifiedName
+	IfNotExists bool
+	Temporary   bool
+	Unlogged    bool
+	Columns     []*ColumnDef
+	Constraints []*Constraint
+	Like        []QualifiedName
+	Inherits    []QualifiedName
+	PartitionOf *QualifiedName
+	PartitionBy string // partitioning clause as written
+	AsQuery     *SelectStmt
+}
+
+// ColumnDef is a column definition in CREATE TABLE or ALTER TABLE ADD COLUMN
+type ColumnDef struct {
+	Span
+	Name        string
+	Type        *TypeName
+	Collation   string
+	Constraints []*Constraint
+}
+
+// NotNull reports whether the column is declared NOT NULL or PRIMARY KEY
+func (c *ColumnDef) NotNull() bool {
+	for _, con := range c.Constraints {
+		if con.Type == ConstraintNotNull || con.Type == ConstraintPrimaryKey {
+			return true
+		}
+	}
+	return false
+}
+
+// Default returns the DEFAULT expression, or nil
+func (c *ColumnDef) Default() Expr {
+	for _, con := range c.Constraints {
+		if con.Type == ConstraintDefault {
+			return con.Expr
+		}
+	}
+	return nil
+}
+
+// ConstraintType identifies a column or table constraint
+type ConstraintType string
+
+// Constraint types
+const (
+	ConstraintNotNull    ConstraintType = "NOT NULL"
+	ConstraintNull       ConstraintType = "NULL"
+	ConstraintDefault    ConstraintType = "DEFAULT"
+	ConstraintCheck      ConstraintType = "CHECK"
+	ConstraintPrimaryKey ConstraintType = "PRIMARY KEY"
+	ConstraintUnique     ConstraintType = "UNIQUE"
+	ConstraintForeignKey ConstraintType = "FOREIGN KEY"
+	ConstraintExclude    ConstraintType = "EXCLUDE"
+	ConstraintGenerated  ConstraintType = "GENERATED"
+	ConstraintIdentity   ConstraintType = "IDENTITY"
+)
+
+// Constraint is a column constraint or a table constraint
+type Constraint struct {
+	Span
+	Name       string
+	Type       ConstraintType
+	Columns    []string   // table constraints: constrained columns
+	Expr       Expr       // CHECK condition, DEFAULT value or generation expression
+	References *Reference // FOREIGN KEY and column REFERENCES
+	Include    []string
+	UsingIndex string // UNIQUE / PRIMARY KEY ... USING INDEX name
+	Identity   string // "ALWAYS" or "BY DEFAULT" for identity columns
+	NotValid   bool
+	Deferrable bool
+}
+
+// Reference is the target of a foreign key
+type Reference struct {
+	Span
+	Table    QualifiedName
+	Columns  []string
+	Match    string
+	OnDelete string
+	OnUpdate string
+}
+
+// CreateIndexStmt is a CREATE INDEX statement
+type CreateIndexStmt struct {
+	Span
+	Unique       bool
+	Concurrently bool
+	IfNotExists  bool
+	Name         string
+	Table        QualifiedName
+	Only         bool
+	Method       string // access method, "" when not given (btree)
+	Columns      []*IndexElem
+	Include      []string
+	Where        Expr
+}
+
+// IndexElem is a column or expression in an index definition or ON CONFLICT target
+type IndexElem struct {
+	Span
+	Expr      Expr
+	Column    string // set when Expr is a plain column reference
+	Collation string
+	OpClass   string
+	Desc      bool
+	Nulls     string
+}
+
+// CreateViewStmt is a CREATE [MATERIALIZED] VIEW statement
+type CreateViewStmt struct {
+	Span
+	OrReplace    bool
+	Materialized bool
+	IfNotExists  bool
+	Name         QualifiedName
+	Columns      []string
+	Query        *SelectStmt
+}
+
+// AlterTableStmt is an ALTER TABLE statement
+type AlterTableStmt struct {
+	Span
+	IfExists bool
+	Only     bool
+	Table    QualifiedName
+	Actions  []*AlterTableAction
+}
+
+// AlterTableKind identifies an ALTER TABLE action
+type AlterTableKind string
+
+// ALTER TABLE actions
+const (
+	AlterAddColumn          AlterTableKind = "ADD COLUMN"
+	AlterDropColumn         AlterTableKind = "DROP COLUMN"
+	AlterColumnType         AlterTableKind = "ALTER COLUMN TYPE"
+	AlterSetDefault         AlterTableKind = "SET DEFAULT"
+	AlterDropDefault        AlterTableKind = "DROP DEFAULT"
+	AlterSetNotNull         AlterTableKind = "SET NOT NULL"
+	AlterDropNotNull        AlterTableKind = "DROP NOT NULL"
+	AlterAddConstraint      AlterTableKind = "ADD CONSTRAINT"
+	AlterDropConstraint     AlterTableKind = "DROP CONSTRAINT"
+	AlterValidateConstraint AlterTableKind = "VALIDATE CONSTRAINT"
+	AlterRenameTable        AlterTableKind = "RENAME TO"
+	AlterRenameColumn       AlterTableKind = "RENAME COLUMN"
+	AlterRenameConstraint   AlterTableKind = "RENAME CONSTRAINT"
+	AlterSetSchema          AlterTableKind = "SET SCHEMA"
+	AlterOther              AlterTableKind = "OTHER"
+)
+
+// AlterTableAction is a single action of an ALTER TABLE statement
+type AlterTableAction struct {
+	Span
+	Kind        AlterTableKind
+	Column      string      // column the action applies to
+	ColumnDef   *ColumnDef  // AlterAddColumn
+	Type        *TypeName   // AlterColumnType
+	Using       Expr        // AlterColumnType USING expression
+	Default     Expr        // AlterSetDefault
+	Constraint  *Constraint // AlterAddConstraint
+	Name        string      // constraint name for drop/validate/rename
+	NewName     string      // rename target or new schema
+	IfExists    bool
+	IfNotExists bool
+	Cascade     bool
+	Text        string // AlterOther: the action as written
+}
+
+// DropStmt is a DROP statement for any object type
+type DropStmt struct {
+	Span
+	ObjectType   string // e.g. "TABLE", "INDEX", "MATERIALIZED VIEW"
+	Concurrently bool
+	IfExists     bool
+	Names        []QualifiedName
+	On           *QualifiedName // table for DROP TRIGGER/POLICY/RULE ... ON
+	Cascade      bool
+}
+
+// TruncateStmt is a TRUNCATE statement
+type TruncateStmt struct {
+	Span
+	Tables          []QualifiedName
+	RestartIdentity bool
+	Cascade         bool
+}
+
+// TransactionStmt is BEGIN, START TRANSACTION, COMMIT, ROLLBACK, SAVEPOINT and friends
+type TransactionStmt struct {
+	Span
+	Kind string // "BEGIN", "COMMIT", "ROLLBACK", "SAVEPOINT", "RELEASE", "PREPARE"
+}
+
+// RawStmt is a statement the parser recognises but does not model, such as
+// CREATE FUNCTION, GRANT or VACUUM
+type RawStmt struct {
+	Span
+	Command string  // leading keywords, e.g. "CREATE FUNCTION", "VACUUM"
+	Tokens  []Token // every token of the statement except comments
+}
+
+// HasKeyword reports whether any unquoted token of the statement is kw
+func (s *RawStmt) HasKeyword(kw string) bool {
+	for _, tok := range s.Tokens {
+		if tok.Keyword() == kw {
+			return true
+		}
+	}
+	return false
+}
+
+// BadStmt is a statement that failed to parse
+type BadStmt struct {
+	Span
+	Err *Error
+}
+
+func (*SelectStmt) stmtNode()      {}
+func (*InsertStmt) stmtNode()      {}
+func (*UpdateStmt) stmtNode()      {}
+func (*DeleteStmt) stmtNode()      {}
+func (*ExplainStmt) stmtNode()     {}
+func (*CreateTableStmt) stmtNode() {}
+func (*CreateIndexStmt) stmtNode() {}
+func (*CreateViewStmt) stmtNode()  {}
+func (*AlterTableStmt) stmtNode()  {}
+func (*DropStmt) stmtNode()        {}
+func (*TruncateStmt) stmtNode()    {}
+func (*TransactionStmt) stmtNode() {}
+func (*RawStmt) stmtNode()         {}
+func (*BadStmt) stmtNode()         {}
+
+func (*TableName) tableExprNode()     {}
+func (*SubqueryTable) tableExprNode() {}
+func (*FuncTable) tableExprNode()     {}
+func (*JoinExpr) tableExprNode()      {}
+
+// ----------------------------------------------------------------------------
+// Expressions
+
+// ColumnRef is a possibly qualified column reference, or a * / table.* wildcard
+type ColumnRef struct {
+	Span
+	Fields []string // e.g. ["u", "email"]; empty for a bare *
+	Star   bool
+}
+
+// Column returns the column name, or "" for a wildcard
+func (c *ColumnRef) Column() string {
+	if c.Star || len(c.Fields) == 0 {
+		return ""
+	}
+	return c.Fields[len(c.Fields)-1]
+}
+
+// Qualifier returns the table or alias qualifying the column, or ""
+func (c *ColumnRef) Qualifier() string {
+	n := len(c.Fields)
+	if !c.Star {
+		n--
+	}
+	if n <= 0 {
+		return ""
+	}
+	return c.Fields[n-1]
+}
+
+// LiteralKind identifies the type of a literal
+type LiteralKind int
+
+// Literal kinds
+const (
+	LiteralString LiteralKind = iota
+	LiteralNumber
+	LiteralBool
+	LiteralNull
+)
+
+func (k LiteralKind) String() string {
+	switch k {
+	case LiteralString:
+		return "String"
+	case LiteralNumber:
+		return "Number"
+	case LiteralBool:
+		return "Bool"
+	case LiteralNull:
+		return "Null"
+	}
+	return fmt.Sprintf("LiteralKind(%d)", int(k))
+}
+
+// Literal is a string, numeric, boolean or NULL constant
+type Literal struct {
+	Span
+	Kind  LiteralKind
+	Value string // string content, number text, "true"/"false" or "null"
+}
+
+// Param is a positional parameter such as $1
+type Param struct {
+	Span
+	Number int
+}
+
+// BinaryExpr is an infix operator expression, including AND and OR
+type BinaryExpr struct {
+	Span
+	Op    string // operator symbol, or upper-case keyword such as "AND", "OR", "AT TIME ZONE"
+	Left  Expr
+	Right Expr
+}
+
+// UnaryExpr is a prefix operator expression, including NOT
+type UnaryExpr struct {
+	Span
+	Op   string
+	Expr Expr
+}
+
+// FuncCall is a function or aggregate call
+type FuncCall struct {
+	Span
+	Schema      string
+	Name        string // lower case
+	Args        []Expr
+	Star        bool // count(*)
+	Distinct    bool
+	OrderBy     []*OrderItem // aggregate ORDER BY or WITHIN GROUP
+	WithinGroup bool
+	Filter      Expr
+	Over        *WindowSpec
+	NoParens    bool // CURRENT_TIMESTAMP and friends
+}
+
+// SubqueryExpr is a scalar subquery or an EXISTS test
+type SubqueryExpr struct {
+	Span
+	Exists bool
+	Query  *SelectStmt
+}
+
+// InExpr is expr [NOT] IN (list) or expr [NOT] IN (subquery)
+type InExpr struct {
+	Span
+	Expr  Expr
+	Not   bool
+	List  []Expr
+	Query *SelectStmt
+}
+
+// QuantifiedExpr is ANY/SOME/ALL applied to a subquery or array, as in x = ANY($1)
+type QuantifiedExpr struct {
+	Span
+	Quantifier string // "ANY", "SOME" or "ALL"
+	Expr       Expr
+	Query      *SelectStmt
+}
+
+// BetweenExpr is expr [NOT] BETWEEN [SYMMETRIC] low AND high
+type BetweenExpr struct {
+	Span
+	Expr      Expr
+	Not       bool
+	Symmetric bool
+	Low       Expr
+	High      Expr
+}
+
+// LikeExpr is expr [NOT] LIKE / ILIKE / SIMILAR TO pattern [ESCAPE escape]
+type LikeExpr struct {
+	Span
+	Op      string // "LIKE", "ILIKE" or "SIMILAR TO"
+	Not     bool
+	Expr    Expr
+	Pattern Expr
+	Escape  Expr
+}
+
+// IsExpr is expr IS [NOT] NULL / TRUE / FALSE / UNKNOWN / DISTINCT FROM right
+type IsExpr struct {
+	Span
+	Expr  Expr
+	Not   bool
+	Test  string // "NULL", "TRUE", "FALSE", "UNKNOWN", "DISTINCT FROM", "DOCUMENT", "NORMALIZED"
+	Right Expr   // DISTINCT FROM operand
+}
+
+// CaseExpr is a CASE expression
+type CaseExpr struct {
+	Span
+	Operand Expr
+	Whens   []*CaseWhen
+	Else    Expr
+}
+
+// CaseWhen is a WHEN ... THEN ... arm of a CASE expression
+type CaseWhen struct {
+	Span
+	Cond   Expr
+	Result Expr
+}
+
+// CastExpr is expr::type, CAST(expr AS type) or a typed literal such as DATE '2024-01-01'
+type CastExpr struct {
+	Span
+	Expr   Expr
+	Type   *TypeName
+	Syntax string // "::", "CAST" or "PREFIX"
+}
+
+// ArrayExpr is ARRAY[...] or ARRAY(subquery)
+type ArrayExpr struct {
+	Span
+	Elems []Expr
+	Query *SelectStmt
+}
+
+// RowExpr is ROW(...) or a parenthesised list (a, b)
+type RowExpr struct {
+	Span
+	Elems []Expr
+}
+
+// SubscriptExpr is expr[index] or expr[lower:upper]
+type SubscriptExpr struct {
+	Span
+	Expr  Expr
+	Index Expr
+	Upper Expr
+	Slice bool
+}
+
+// FieldExpr selects a field from a composite value, as in (addr).city
+type FieldExpr struct {
+	Span
+	Expr  Expr
+	Field string
+}
+
+// CollateExpr is expr COLLATE collation
+type CollateExpr struct {
+	Span
+	Expr      Expr
+	Collation string
+}
+
+// DefaultExpr is the DEFAULT keyword in VALUES or SET
+type DefaultExpr struct {
+	Span
+}
+
+func (*ColumnRef) exprNode()      {}
+func (*Literal) exprNode()        {}
+func (*Param) exprNode()          {}
+func (*BinaryExpr) exprNode()     {}
+func (*UnaryExpr) exprNode()      {}
+func (*FuncCall) exprNode()       {}
+func (*SubqueryExpr) exprNode()   {}
+func (*InExpr) exprNode()         {}
+func (*QuantifiedExpr) exprNode() {}
+func (*BetweenExpr) exprNode()    {}
+func (*LikeExpr) exprNode()       {}
+func (*IsExpr) exprNode()         {}
+func (*CaseExpr) exprNode()       {}
+func (*CastExpr) exprNode()       {}
+func (*ArrayExpr) exprNode()      {}
+func (*RowExpr) exprNode()        {}
+func (*SubscriptExpr) exprNode()  {}
+func (*FieldExpr) exprNode()      {}
+func (*CollateExpr) exprNode()    {}
+func (*DefaultExpr) exprNode()    {}
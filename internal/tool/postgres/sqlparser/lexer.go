@@ -0,0 +1,390 @@
+package sqlparser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokenize splits src into tokens, including comments, followed by a
+// TokenEOF token. On an unterminated string, quoted identifier or comment it
+// returns the tokens read so far, an EOF token at the end of the input and
+// an *Error describing the problem.
+func Tokenize(src string) ([]Token, error) {
+	l := &lexer{src: src, line: 1, col: 1}
+	var tokens []Token
+	for {
+		tok, err := l.next(tokens)
+		if err != nil {
+			tokens = append(tokens, l.eof())
+			return tokens, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Kind == TokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+// lexer scans PostgreSQL source text
+type lexer struct {
+	src    string
+	offset int
+	line   int
+	col    int
+}
+
+// pos returns the current position
+func (l *lexer) pos() Pos {
+	return Pos{Offset: l.offset, Line: l.line, Column: l.col}
+}
+
+// eof returns an EOF token at the end of the input
+func (l *lexer) eof() Token {
+	for l.offset < len(l.src) {
+		l.advance(1)
+	}
+	p := l.pos()
+	return Token{Kind: TokenEOF, Pos: p, End: p}
+}
+
+// peek returns the byte at offset+n, or 0 past the end of the input
+func (l *lexer) peek(n int) byte {
+	if l.offset+n < len(l.src) {
+		return l.src[l.offset+n]
+	}
+	return 0
+}
+
+// advance moves forward n bytes, tracking line and column
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.offset < len(l.src); i++ {
+		if l.src[l.offset] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.offset++
+	}
+}
+
+// token builds a token spanning from start to the current position
+func (l *lexer) token(kind TokenKind, start Pos, value string) Token {
+	return Token{
+		Kind:  kind,
+		Text:  l.src[start.Offset:l.offset],
+		Value: value,
+		Pos:   start,
+		End:   l.pos(),
+	}
+}
+
+// next scans the next token. prev holds the tokens scanned so far, which
+// decides whether a leading '.' starts a number.
+func (l *lexer) next(prev []Token) (Token, error) {
+	for l.offset < len(l.src) && isSpace(l.src[l.offset]) {
+		l.advance(1)
+	}
+
+	start := l.pos()
+	if l.offset >= len(l.src) {
+		return Token{Kind: TokenEOF, Pos: start, End: start}, nil
+	}
+
+	c := l.src[l.offset]
+	switch {
+	case c == '-' && l.peek(1) == '-':
+		for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+			l.advance(1)
+		}
+		return l.token(TokenComment, start, ""), nil
+
+	case c == '/' && l.peek(1) == '*':
+		return l.blockComment(start)
+
+	case c == '\'':
+		return l.quoted(start, '\'', TokenString, false)
+
+	case c == '"':
+		return l.quoted(start, '"', TokenQuotedIdent, false)
+
+	case (c == 'e' || c == 'E') && l.peek(1) == '\'':
+		l.advance(1)
+		return l.quoted(start, '\'', TokenString, true)
+
+	case (c == 'b' || c == 'B' || c == 'x' || c == 'X' || c == 'n' || c == 'N') && l.peek(1) == '\'':
+		l.advance(1)
+		return l.quoted(start, '\'', TokenString, false)
+
+	case (c == 'u' || c == 'U') && l.peek(1) == '&' && (l.peek(2) == '\'' || l.peek(2) == '"'):
+		l.advance(2)
+		if l.src[l.offset] == '"' {
+			return l.quoted(start, '"', TokenQuotedIdent, false)
+		}
+		return l.quoted(start, '\'', TokenString, false)
+
+	case c == '$':
+		if isDigit(l.peek(1)) {
+			l.advance(1)
+			for l.offset < len(l.src) && isDigit(l.src[l.offset]) {
+				l.advance(1)
+			}
+			return l.token(TokenParam, start, l.src[start.Offset+1:l.offset]), nil
+		}
+		if tag, ok := l.dollarTag(); ok {
+			return l.dollarString(start, tag)
+		}
+		l.advance(1)
+		return l.token(TokenOperator, start, "$"), nil
+
+	case isIdentStart(c):
+		for l.offset < len(l.src) && isIdentChar(l.src[l.offset]) {
+			l.advance(1)
+		}
+		return l.token(TokenIdent, start, strings.ToLower(l.src[start.Offset:l.offset])), nil
+
+	case isDigit(c) || (c == '.' && isDigit(l.peek(1)) && !followsOperand(prev)):
+		return l.number(start), nil
+
+	case c == '(':
+		l.advance(1)
+		return l.token(TokenLParen, start, "("), nil
+	case c == ')':
+		l.advance(1)
+		return l.token(TokenRParen, start, ")"), nil
+	case c == '[':
+		l.advance(1)
+		return l.token(TokenLBracket, start, "["), nil
+	case c == ']':
+		l.advance(1)
+		return l.token(TokenRBracket, start, "]"), nil
+	case c == ',':
+		l.advance(1)
+		return l.token(TokenComma, start, ","), nil
+	case c == ';':
+		l.advance(1)
+		return l.token(TokenSemicolon, start, ";"), nil
+	case c == '.':
+		l.advance(1)
+		return l.token(TokenDot, start, "."), nil
+	case c == ':':
+		switch l.peek(1) {
+		case ':':
+			l.advance(2)
+			return l.token(TokenOperator, start, "::"), nil
+		case '=':
+			l.advance(2)
+			return l.token(TokenOperator, start, ":="), nil
+		}
+		l.advance(1)
+		return l.token(TokenColon, start, ":"), nil
+
+	case isOperatorChar(c):
+		return l.operator(start), nil
+	}
+
+	// Anything else is a stray character; keep it as a single-rune operator so
+	// the parser can report it in context
+	_, size := utf8.DecodeRuneInString(l.src[l.offset:])
+	l.advance(size)
+	text := l.src[start.Offset:l.offset]
+	return l.token(TokenOperator, start, text), nil
+}
+
+// blockComment scans a /* */ comment; PostgreSQL allows them to nest
+func (l *lexer) blockComment(start Pos) (Token, error) {
+	depth := 0
+	for l.offset < len(l.src) {
+		switch {
+		case l.src[l.offset] == '/' && l.peek(1) == '*':
+			depth++
+			l.advance(2)
+		case l.src[l.offset] == '*' && l.peek(1) == '/':
+			depth--
+			l.advance(2)
+			if depth == 0 {
+				return l.token(TokenComment, start, ""), nil
+			}
+		default:
+			l.advance(1)
+		}
+	}
+	return Token{}, &Error{Pos: start, Msg: "unterminated /* comment"}
+}
+
+// quoted scans a quoted string or identifier whose opening quote is at the
+// current position. A doubled quote stands for itself; with backslash set,
+// C-style escapes are recognised as in E'...' strings.
+func (l *lexer) quoted(start Pos, quote byte, kind TokenKind, backslash bool) (Token, error) {
+	l.advance(1)
+	var value strings.Builder
+	for l.offset < len(l.src) {
+		c := l.src[l.offset]
+		switch {
+		case c == quote && l.peek(1) == quote:
+			value.WriteByte(quote)
+			l.advance(2)
+		case c == quote:
+			l.advance(1)
+			return l.token(kind, start, value.String()), nil
+		case backslash && c == '\\' && l.offset+1 < len(l.src):
+			value.WriteByte(unescape(l.peek(1)))
+			l.advance(2)
+		default:
+			value.WriteByte(c)
+			l.advance(1)
+		}
+	}
+
+	what := "quoted string"
+	if kind == TokenQuotedIdent {
+		what = "quoted identifier"
+	}
+	return Token{}, &Error{Pos: start, Msg: "unterminated " + what}
+}
+
+// dollarTag reports whether a dollar-quote opening tag such as $$ or $body$
+// starts at the current position, and returns it
+func (l *lexer) dollarTag() (string, bool) {
+	end := l.offset + 1
+	for end < len(l.src) && l.src[end] != '$' {
+		if !isIdentChar(l.src[end]) || (end == l.offset+1 && isDigit(l.src[end])) {
+			return "", false
+		}
+		end++
+	}
+	if end >= len(l.src) {
+		return "", false
+	}
+	return l.src[l.offset : end+1], true
+}
+
+// dollarString scans a dollar-quoted string opened by tag
+func (l *lexer) dollarString(start Pos, tag string) (Token, error) {
+	l.advance(len(tag))
+	bodyStart := l.offset
+	idx := strings.Index(l.src[l.offset:], tag)
+	if idx < 0 {
+		return Token{}, &Error{Pos: start, Msg: "unterminated dollar-quoted string"}
+	}
+	l.advance(idx)
+	value := l.src[bodyStart:l.offset]
+	l.advance(len(tag))
+	return l.token(TokenString, start, value), nil
+}
+
+// number scans a numeric literal
+func (l *lexer) number(start Pos) Token {
+	if l.src[l.offset] == '0' && (l.peek(1) == 'x' || l.peek(1) == 'X' || l.peek(1) == 'o' ||
+		l.peek(1) == 'O' || l.peek(1) == 'b' || l.peek(1) == 'B') && isHexDigit(l.peek(2)) {
+		l.advance(2)
+		for l.offset < len(l.src) && (isHexDigit(l.src[l.offset]) || l.src[l.offset] == '_') {
+			l.advance(1)
+		}
+		return l.token(TokenNumber, start, l.src[start.Offset:l.offset])
+	}
+
+	digits := func() {
+		for l.offset < len(l.src) && (isDigit(l.src[l.offset]) || l.src[l.offset] == '_') {
+			l.advance(1)
+		}
+	}
+
+	digits()
+	// A '.' followed by another '.' is a slice bound, not a decimal point
+	if l.offset < len(l.src) && l.src[l.offset] == '.' && l.peek(1) != '.' {
+		l.advance(1)
+		digits()
+	}
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		n := 1
+		if s := l.peek(1); s == '+' || s == '-' {
+			n = 2
+		}
+		if isDigit(l.peek(n)) {
+			l.advance(n)
+			digits()
+		}
+	}
+	return l.token(TokenNumber, start, strings.ReplaceAll(l.src[start.Offset:l.offset], "_", ""))
+}
+
+// operator scans an operator following PostgreSQL's rules: an operator ends
+// before "--" or "/*", and a multi-character operator can only end in + or -
+// if it also contains one of ~ ! @ # % ^ & | ` ?
+func (l *lexer) operator(start Pos) Token {
+	end := l.offset
+	for end < len(l.src) && isOperatorChar(l.src[end]) {
+		if end > l.offset && (strings.HasPrefix(l.src[end:], "--") || strings.HasPrefix(l.src[end:], "/*")) {
+			break
+		}
+		end++
+	}
+
+	op := l.src[l.offset:end]
+	if len(op) > 1 && !strings.ContainsAny(op, "~!@#%^&|`?") {
+		for len(op) > 1 && (op[len(op)-1] == '+' || op[len(op)-1] == '-') {
+			op = op[:len(op)-1]
+		}
+	}
+
+	l.advance(len(op))
+	return l.token(TokenOperator, start, op)
+}
+
+// followsOperand reports whether the previous significant token ends an
+// operand, in which case a following '.' is a field selector, not a number
+func followsOperand(prev []Token) bool {
+	for i := len(prev) - 1; i >= 0; i-- {
+		switch prev[i].Kind {
+		case TokenComment:
+			continue
+		case TokenIdent, TokenQuotedIdent, TokenRParen, TokenRBracket:
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	default:
+		return c
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?", c) >= 0
+}
@@ -0,0 +1,1364 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parse parses a script of semicolon-separated statements.
+//
+// Parsing continues past errors: a statement that fails to parse is returned
+// as a *BadStmt and the error is reported in the returned ErrorList, so the
+// Script is always usable.
+func Parse(src string) (*Script, error) {
+	tokens, lexErr := Tokenize(src)
+
+	script := &Script{}
+	var significant []Token
+	for _, tok := range tokens {
+		if tok.Kind == TokenComment {
+			script.Comments = append(script.Comments, tok)
+			continue
+		}
+		significant = append(significant, tok)
+	}
+
+	var errs ErrorList
+	chunks := splitStatements(significant)
+	for i, chunk := range chunks {
+		if lexErr != nil && i == len(chunks)-1 && !chunk.terminated {
+			// The lexer stopped inside this statement
+			err := lexErr.(*Error)
+			script.Statements = append(script.Statements, &BadStmt{
+				Span: Span{From: chunk.tokens[0].Pos, To: significant[len(significant)-1].End},
+				Err:  err,
+			})
+			errs = append(errs, err)
+			lexErr = nil
+			continue
+		}
+
+		stmt, err := parseStatement(src, chunk.tokens)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		script.Statements = append(script.Statements, stmt)
+	}
+
+	if lexErr != nil {
+		err := lexErr.(*Error)
+		script.Statements = append(script.Statements, &BadStmt{
+			Span: Span{From: err.Pos, To: significant[len(significant)-1].End},
+			Err:  err,
+		})
+		errs = append(errs, err)
+	}
+
+	return script, errs.Err()
+}
+
+// ParseExpr parses a single value expression
+func ParseExpr(src string) (expr Expr, err error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var significant []Token
+	for _, tok := range tokens {
+		if tok.Kind != TokenComment {
+			significant = append(significant, tok)
+		}
+	}
+
+	p := &parser{src: src, toks: significant}
+	defer p.recover(&err)
+
+	expr = p.parseExpr()
+	if !p.is(TokenEOF) {
+		p.unexpected()
+	}
+	return expr, nil
+}
+
+// chunk holds the tokens of one statement
+type chunk struct {
+	tokens     []Token
+	terminated bool // followed by a semicolon
+}
+
+// splitStatements splits tokens into statements at semicolons, dropping
+// empty statements. The trailing EOF token is not included in any chunk.
+func splitStatements(tokens []Token) []chunk {
+	var chunks []chunk
+	var current []Token
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case TokenSemicolon:
+			if len(current) > 0 {
+				chunks = append(chunks, chunk{tokens: current, terminated: true})
+			}
+			current = nil
+		case TokenEOF:
+			if len(current) > 0 {
+				chunks = append(chunks, chunk{tokens: current})
+			}
+		default:
+			current = append(current, tok)
+		}
+	}
+	return chunks
+}
+
+// parseStatement parses the tokens of a single statement. On error it
+// returns a *BadStmt covering the tokens along with the error.
+func parseStatement(src string, tokens []Token) (stmt Stmt, perr *Error) {
+	last := tokens[len(tokens)-1]
+	eof := Token{Kind: TokenEOF, Pos: last.End, End: last.End}
+	p := &parser{
+		src:  src,
+		toks: append(tokens[:len(tokens):len(tokens)], eof),
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			b, ok := r.(bailout)
+			if !ok {
+				panic(r)
+			}
+			stmt = &BadStmt{Span: Span{From: tokens[0].Pos, To: last.End}, Err: b.err}
+			perr = b.err
+		}
+	}()
+
+	stmt = p.parseStmt()
+	if !p.is(TokenEOF) {
+		p.unexpected()
+	}
+	return stmt, nil
+}
+
+// parser is a recursive descent parser over the tokens of one statement.
+// Errors are raised by panicking with a bailout, which the entry points recover.
+type parser struct {
+	src  string
+	toks []Token // ends with a TokenEOF
+	pos  int
+}
+
+// bailout carries a syntax error up to the entry point
+type bailout struct {
+	err *Error
+}
+
+// recover turns a bailout into an error
+func (p *parser) recover(err *error) {
+	if r := recover(); r != nil {
+		b, ok := r.(bailout)
+		if !ok {
+			panic(r)
+		}
+		*err = b.err
+	}
+}
+
+func (p *parser) peek() Token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) peekAt(n int) Token {
+	if p.pos+n >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.pos+n]
+}
+
+func (p *parser) next() Token {
+	tok := p.toks[p.pos]
+	if tok.Kind != TokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// prevEnd returns the end of the last consumed token
+func (p *parser) prevEnd() Pos {
+	if p.pos == 0 {
+		return p.toks[0].Pos
+	}
+	return p.toks[p.pos-1].End
+}
+
+// span returns the span from start to the end of the last consumed token
+func (p *parser) span(start Pos) Span {
+	return Span{From: start, To: p.prevEnd()}
+}
+
+func (p *parser) is(kind TokenKind) bool {
+	return p.peek().Kind == kind
+}
+
+func (p *parser) accept(kind TokenKind) bool {
+	if p.is(kind) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind TokenKind) Token {
+	if !p.is(kind) {
+		p.errorf(p.peek(), "syntax error at or near %s, expected %s", p.peek(), kindText(kind))
+	}
+	return p.next()
+}
+
+// isOp reports whether the next token is the operator op
+func (p *parser) isOp(op string) bool {
+	tok := p.peek()
+	return tok.Kind == TokenOperator && tok.Text == op
+}
+
+func (p *parser) acceptOp(op string) bool {
+	if p.isOp(op) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// isKeyword reports whether the next token is one of the keywords
+func (p *parser) isKeyword(keywords ...string) bool {
+	kw := p.peek().Keyword()
+	if kw == "" {
+		return false
+	}
+	for _, k := range keywords {
+		if kw == k {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeywordAt reports whether the token n positions ahead is the keyword
+func (p *parser) isKeywordAt(n int, keyword string) bool {
+	return p.peekAt(n).Keyword() == keyword
+}
+
+// acceptKeyword consumes the next token if it is the keyword
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// acceptKeywords consumes the sequence of keywords if all of them are next
+func (p *parser) acceptKeywords(keywords ...string) bool {
+	for i, kw := range keywords {
+		if !p.isKeywordAt(i, kw) {
+			return false
+		}
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+func (p *parser) expectKeyword(keyword string) Token {
+	if !p.isKeyword(keyword) {
+		p.errorf(p.peek(), "syntax error at or near %s, expected %s", p.peek(), keyword)
+	}
+	return p.next()
+}
+
+func (p *parser) errorf(tok Token, format string, args ...interface{}) {
+	panic(bailout{&Error{Pos: tok.Pos, Msg: fmt.Sprintf(format, args...)}})
+}
+
+func (p *parser) unexpected() {
+	p.errorf(p.peek(), "syntax error at or near %s", p.peek())
+}
+
+// ident parses an identifier that may not be a reserved keyword
+func (p *parser) ident() string {
+	tok := p.peek()
+	switch {
+	case tok.Kind == TokenQuotedIdent:
+		p.next()
+		return tok.Value
+	case tok.Kind == TokenIdent && !reserved[tok.Keyword()]:
+		p.next()
+		return tok.Value
+	}
+	p.unexpected()
+	return ""
+}
+
+// anyIdent parses an identifier where reserved keywords are allowed, such as
+// after AS or a dot
+func (p *parser) anyIdent() string {
+	tok := p.peek()
+	if tok.Kind == TokenQuotedIdent || tok.Kind == TokenIdent {
+		p.next()
+		return tok.Value
+	}
+	p.unexpected()
+	return ""
+}
+
+// isIdent reports whether the next token can start an identifier
+func (p *parser) isIdent() bool {
+	tok := p.peek()
+	return tok.Kind == TokenQuotedIdent || (tok.Kind == TokenIdent && !reserved[tok.Keyword()])
+}
+
+// qualifiedName parses name, schema.name or catalog.schema.name
+func (p *parser) qualifiedName() QualifiedName {
+	start := p.peek().Pos
+	parts := []string{p.ident()}
+	for p.is(TokenDot) {
+		p.next()
+		parts = append(parts, p.anyIdent())
+	}
+	if len(parts) > 3 {
+		p.errorf(p.peek(), "improper qualified name (too many dotted names)")
+	}
+
+	name := QualifiedName{Name: parts[len(parts)-1]}
+	if len(parts) > 1 {
+		name.Schema = parts[len(parts)-2]
+	}
+	name.Span = p.span(start)
+	return name
+}
+
+// identList parses a parenthesised, comma-separated list of identifiers
+func (p *parser) identList() []string {
+	p.expect(TokenLParen)
+	var names []string
+	for {
+		names = append(names, p.ident())
+		if !p.accept(TokenComma) {
+			break
+		}
+	}
+	p.expect(TokenRParen)
+	return names
+}
+
+// skipParens skips a balanced parenthesised group starting at the next token
+func (p *parser) skipParens() {
+	p.expect(TokenLParen)
+	depth := 1
+	for depth > 0 {
+		switch p.next().Kind {
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			depth--
+		case TokenEOF:
+			p.errorf(p.peek(), "syntax error at end of input, unbalanced parentheses")
+		}
+	}
+}
+
+// textFrom returns the source text from start to the end of the last consumed token
+func (p *parser) textFrom(start Pos) string {
+	return p.src[start.Offset:p.prevEnd().Offset]
+}
+
+// parseStmt parses one statement
+func (p *parser) parseStmt() Stmt {
+	tok := p.peek()
+	if tok.Kind == TokenLParen {
+		return p.parseSelect()
+	}
+
+	switch tok.Keyword() {
+	case "SELECT", "VALUES", "TABLE":
+		return p.parseSelect()
+	case "WITH":
+		return p.parseWithStmt()
+	case "INSERT":
+		return p.parseInsert(nil)
+	case "UPDATE":
+		return p.parseUpdate(nil)
+	case "DELETE":
+		return p.parseDelete(nil)
+	case "EXPLAIN":
+		return p.parseExplain()
+	case "CREATE":
+		return p.parseCreate()
+	case "ALTER":
+		if p.isKeywordAt(1, "TABLE") {
+			return p.parseAlterTable()
+		}
+		return p.parseRaw()
+	case "DROP":
+		return p.parseDrop()
+	case "TRUNCATE":
+		return p.parseTruncate()
+	case "BEGIN", "START", "COMMIT", "END", "ROLLBACK", "ABORT", "SAVEPOINT", "RELEASE":
+		return p.parseTransaction()
+	}
+
+	if rawCommands[tok.Keyword()] {
+		return p.parseRaw()
+	}
+	p.unexpected()
+	return nil
+}
+
+// parseWithStmt parses a statement that starts with a WITH clause
+func (p *parser) parseWithStmt() Stmt {
+	start := p.peek().Pos
+	save := p.pos
+	with := p.parseWithClause()
+
+	switch p.peek().Keyword() {
+	case "INSERT":
+		return p.parseInsertAt(start, with)
+	case "UPDATE":
+		return p.parseUpdateAt(start, with)
+	case "DELETE":
+		return p.parseDeleteAt(start, with)
+	}
+
+	p.pos = save
+	return p.parseSelect()
+}
+
+// parseWithClause parses WITH [RECURSIVE] name [(cols)] AS [[NOT] MATERIALIZED] (query), ...
+func (p *parser) parseWithClause() *WithClause {
+	start := p.expectKeyword("WITH").Pos
+	with := &WithClause{Recursive: p.acceptKeyword("RECURSIVE")}
+
+	for {
+		cteStart := p.peek().Pos
+		cte := &CTE{Name: p.ident()}
+		if p.is(TokenLParen) {
+			cte.Columns = p.identList()
+		}
+		p.expectKeyword("AS")
+		switch {
+		case p.acceptKeyword("MATERIALIZED"):
+			cte.Materialized = "MATERIALIZED"
+		case p.acceptKeywords("NOT", "MATERIALIZED"):
+			cte.Materialized = "NOT MATERIALIZED"
+		}
+
+		p.expect(TokenLParen)
+		switch p.peek().Keyword() {
+		case "INSERT":
+			cte.Query = p.parseInsert(nil)
+		case "UPDATE":
+			cte.Query = p.parseUpdate(nil)
+		case "DELETE":
+			cte.Query = p.parseDelete(nil)
+		default:
+			cte.Query = p.parseSelect()
+		}
+		p.expect(TokenRParen)
+
+		// SEARCH and CYCLE clauses of recursive queries are accepted but not modelled
+		for p.isKeyword("SEARCH", "CYCLE") {
+			for !p.is(TokenComma) && !p.is(TokenEOF) && !p.isKeyword("SELECT", "INSERT", "UPDATE", "DELETE", "VALUES") {
+				if p.is(TokenLParen) {
+					p.skipParens()
+					continue
+				}
+				p.next()
+			}
+		}
+
+		cte.Span = p.span(cteStart)
+		with.CTEs = append(with.CTEs, cte)
+		if !p.accept(TokenComma) {
+			break
+		}
+	}
+
+	with.Span = p.span(start)
+	return with
+}
+
+// ----------------------------------------------------------------------------
+// SELECT
+
+// parseSelect parses a complete query: an optional WITH clause, set
+// operations, and the trailing ORDER BY, LIMIT, OFFSET, FETCH and locking clauses
+func (p *parser) parseSelect() *SelectStmt {
+	start := p.peek().Pos
+	var with *WithClause
+	if p.isKeyword("WITH") {
+		with = p.parseWithClause()
+	}
+
+	stmt := p.parseSetOperations()
+	p.parseSelectTail(stmt)
+
+	if with != nil {
+		stmt.With = with
+	}
+	if with != nil || len(stmt.OrderBy) > 0 || stmt.Limit != nil || stmt.Offset != nil || len(stmt.Locking) > 0 {
+		stmt.Span = p.span(start)
+	}
+	return stmt
+}
+
+// parseSetOperations parses select terms joined by UNION, INTERSECT and EXCEPT
+func (p *parser) parseSetOperations() *SelectStmt {
+	left := p.parseSelectTerm()
+	for p.isKeyword("UNION", "INTERSECT", "EXCEPT") {
+		op := p.next().Keyword()
+		if p.acceptKeyword("ALL") {
+			op += " ALL"
+		} else {
+			p.acceptKeyword("DISTINCT")
+		}
+		right := p.parseSelectTerm()
+		left = &SelectStmt{
+			Span:  Span{From: left.Pos(), To: right.End()},
+			SetOp: op,
+			Left:  left,
+			Right: right,
+		}
+	}
+	return left
+}
+
+// parseSelectTerm parses a SELECT core, VALUES list, TABLE name or parenthesised query
+func (p *parser) parseSelectTerm() *SelectStmt {
+	tok := p.peek()
+	if tok.Kind == TokenLParen {
+		p.next()
+		stmt := p.parseSelect()
+		p.expect(TokenRParen)
+		return stmt
+	}
+
+	switch tok.Keyword() {
+	case "SELECT":
+		return p.parseSelectCore()
+	case "VALUES":
+		return p.parseValues()
+	case "TABLE":
+		p.next()
+		nameStart := p.peek().Pos
+		table := &TableName{Only: p.acceptKeyword("ONLY"), Name: p.qualifiedName()}
+		p.acceptOp("*")
+		table.Span = p.span(nameStart)
+		return &SelectStmt{
+			Span:    p.span(tok.Pos),
+			Targets: []*ResultTarget{{Span: table.Span, Expr: &ColumnRef{Span: table.Span, Star: true}}},
+			From:    []TableExpr{table},
+		}
+	}
+
+	p.unexpected()
+	return nil
+}
+
+// parseSelectCore parses SELECT ... FROM ... WHERE ... GROUP BY ... HAVING ... WINDOW ...
+func (p *parser) parseSelectCore() *SelectStmt {
+	start := p.expectKeyword("SELECT").Pos
+	stmt := &SelectStmt{}
+
+	if p.acceptKeyword("DISTINCT") {
+		stmt.Distinct = true
+		if p.acceptKeyword("ON") {
+			p.expect(TokenLParen)
+			stmt.DistinctOn = p.parseExprList()
+			p.expect(TokenRParen)
+		}
+	} else {
+		p.acceptKeyword("ALL")
+	}
+
+	if !p.atTargetListEnd() {
+		stmt.Targets = p.parseTargetList()
+	}
+
+	if p.acceptKeyword("INTO") {
+		// SELECT INTO creates a table; the target is not modelled
+		for p.isKeyword("TEMPORARY", "TEMP", "UNLOGGED", "TABLE") {
+			p.next()
+		}
+		p.qualifiedName()
+	}
+
+	if p.acceptKeyword("FROM") {
+		stmt.From = p.parseFromList()
+	}
+	if p.acceptKeyword("WHERE") {
+		stmt.Where = p.parseExpr()
+	}
+	if p.acceptKeywords("GROUP", "BY") {
+		if !p.acceptKeyword("ALL") {
+			p.acceptKeyword("DISTINCT")
+		}
+		stmt.GroupBy = p.parseGroupingList()
+	}
+	if p.acceptKeyword("HAVING") {
+		stmt.Having = p.parseExpr()
+	}
+	if p.acceptKeyword("WINDOW") {
+		for {
+			nameTok := p.peek()
+			name := p.ident()
+			p.expectKeyword("AS")
+			spec := p.parseWindowSpec()
+			spec.Name = name
+			spec.Span = p.span(nameTok.Pos)
+			stmt.Windows = append(stmt.Windows, spec)
+			if !p.accept(TokenComma) {
+				break
+			}
+		}
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// atTargetListEnd reports whether a SELECT has an empty target list
+func (p *parser) atTargetListEnd() bool {
+	switch p.peek().Kind {
+	case TokenEOF, TokenSemicolon, TokenRParen:
+		return true
+	}
+	return p.isKeyword("FROM", "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET",
+		"UNION", "INTERSECT", "EXCEPT", "INTO", "WINDOW", "FETCH", "FOR")
+}
+
+// parseGroupingList parses GROUP BY elements, including GROUPING SETS, ROLLUP, CUBE and ()
+func (p *parser) parseGroupingList() []Expr {
+	var exprs []Expr
+	for {
+		exprs = append(exprs, p.parseGroupingElem())
+		if !p.accept(TokenComma) {
+			return exprs
+		}
+	}
+}
+
+func (p *parser) parseGroupingElem() Expr {
+	start := p.peek().Pos
+	if p.is(TokenLParen) && p.peekAt(1).Kind == TokenRParen {
+		p.next()
+		p.next()
+		return &RowExpr{Span: p.span(start)}
+	}
+	if p.acceptKeywords("GROUPING", "SETS") {
+		p.expect(TokenLParen)
+		fc := &FuncCall{Name: "grouping sets", Args: p.parseGroupingList()}
+		p.expect(TokenRParen)
+		fc.Span = p.span(start)
+		return fc
+	}
+	return p.parseExpr()
+}
+
+// parseTargetList parses a SELECT or RETURNING list
+func (p *parser) parseTargetList() []*ResultTarget {
+	var targets []*ResultTarget
+	for {
+		start := p.peek().Pos
+		target := &ResultTarget{Expr: p.parseExpr()}
+		switch {
+		case p.acceptKeyword("AS"):
+			target.Alias = p.anyIdent()
+		case p.isIdent():
+			target.Alias = p.ident()
+		}
+		target.Span = p.span(start)
+		targets = append(targets, target)
+
+		if !p.accept(TokenComma) {
+			return targets
+		}
+	}
+}
+
+// parseValues parses VALUES (...), (...)
+func (p *parser) parseValues() *SelectStmt {
+	start := p.expectKeyword("VALUES").Pos
+	stmt := &SelectStmt{}
+	for {
+		p.expect(TokenLParen)
+		stmt.Values = append(stmt.Values, p.parseExprList())
+		p.expect(TokenRParen)
+		if !p.accept(TokenComma) {
+			break
+		}
+	}
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseSelectTail parses ORDER BY, LIMIT, OFFSET, FETCH and FOR UPDATE/SHARE
+func (p *parser) parseSelectTail(stmt *SelectStmt) {
+	if p.acceptKeywords("ORDER", "BY") {
+		stmt.OrderBy = p.parseOrderList()
+	}
+
+	for {
+		switch {
+		case p.acceptKeyword("LIMIT"):
+			if p.isKeyword("ALL") {
+				p.next()
+			} else {
+				stmt.Limit = p.parseExpr()
+			}
+		case p.acceptKeyword("OFFSET"):
+			stmt.Offset = p.parseExpr()
+			if !p.acceptKeyword("ROWS") {
+				p.acceptKeyword("ROW")
+			}
+		case p.acceptKeyword("FETCH"):
+			if !p.acceptKeyword("FIRST") {
+				p.expectKeyword("NEXT")
+			}
+			if p.isKeyword("ROW", "ROWS") {
+				stmt.Limit = &Literal{Span: Span{From: p.peek().Pos, To: p.peek().Pos}, Kind: LiteralNumber, Value: "1"}
+			} else {
+				stmt.Limit = p.parseExprPrec(precAdd)
+			}
+			if !p.acceptKeyword("ROWS") {
+				p.expectKeyword("ROW")
+			}
+			if !p.acceptKeyword("ONLY") {
+				p.expectKeyword("WITH")
+				p.expectKeyword("TIES")
+			}
+		case p.isKeyword("FOR") && !p.isKeywordAt(1, "VALUES"):
+			start := p.next().Pos
+			for p.isKeyword("UPDATE", "NO", "KEY", "SHARE") {
+				p.next()
+			}
+			if p.acceptKeyword("OF") {
+				for {
+					p.qualifiedName()
+					if !p.accept(TokenComma) {
+						break
+					}
+				}
+			}
+			if !p.acceptKeyword("NOWAIT") {
+				p.acceptKeywords("SKIP", "LOCKED")
+			}
+			stmt.Locking = append(stmt.Locking, strings.ToUpper(p.textFrom(start)))
+		default:
+			return
+		}
+	}
+}
+
+// parseOrderList parses ORDER BY items
+func (p *parser) parseOrderList() []*OrderItem {
+	var items []*OrderItem
+	for {
+		start := p.peek().Pos
+		item := &OrderItem{Expr: p.parseExpr()}
+		switch {
+		case p.acceptKeyword("ASC"):
+		case p.acceptKeyword("DESC"):
+			item.Desc = true
+		case p.acceptKeyword("USING"):
+			item.Using = p.next().Text
+		}
+		if p.acceptKeyword("NULLS") {
+			if p.acceptKeyword("FIRST") {
+				item.Nulls = "FIRST"
+			} else {
+				p.expectKeyword("LAST")
+				item.Nulls = "LAST"
+			}
+		}
+		item.Span = p.span(start)
+		items = append(items, item)
+		if !p.accept(TokenComma) {
+			return items
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------
+// FROM
+
+// parseFromList parses a comma-separated list of FROM items
+func (p *parser) parseFromList() []TableExpr {
+	var items []TableExpr
+	for {
+		items = append(items, p.parseTableRef())
+		if !p.accept(TokenComma) {
+			return items
+		}
+	}
+}
+
+// parseTableRef parses a FROM item followed by any number of joins
+func (p *parser) parseTableRef() TableExpr {
+	left := p.parseTablePrimary()
+	for {
+		start := left.Pos()
+		natural := p.acceptKeyword("NATURAL")
+
+		var joinType string
+		switch {
+		case p.acceptKeyword("CROSS"):
+			joinType = "CROSS"
+		case p.acceptKeyword("INNER"):
+			joinType = "INNER"
+		case p.acceptKeyword("LEFT"):
+			p.acceptKeyword("OUTER")
+			joinType = "LEFT"
+		case p.acceptKeyword("RIGHT"):
+			p.acceptKeyword("OUTER")
+			joinType = "RIGHT"
+		case p.acceptKeyword("FULL"):
+			p.acceptKeyword("OUTER")
+			joinType = "FULL"
+		case p.isKeyword("JOIN"):
+			joinType = "INNER"
+		default:
+			if natural {
+				p.unexpected()
+			}
+			return left
+		}
+		p.expectKeyword("JOIN")
+
+		join := &JoinExpr{
+			Type:    joinType,
+			Natural: natural,
+			Left:    left,
+			Right:   p.parseTablePrimary(),
+		}
+		if joinType != "CROSS" && !natural {
+			switch {
+			case p.acceptKeyword("ON"):
+				join.On = p.parseExpr()
+			case p.acceptKeyword("USING"):
+				join.Using = p.identList()
+				if p.acceptKeyword("AS") {
+					p.ident()
+				}
+			default:
+				p.errorf(p.peek(), "syntax error at or near %s, JOIN requires an ON or USING clause", p.peek())
+			}
+		}
+		join.Span = p.span(start)
+		left = join
+	}
+}
+
+// parseTablePrimary parses a table name, subquery, function call or
+// parenthesised join, with an optional alias
+func (p *parser) parseTablePrimary() TableExpr {
+	start := p.peek().Pos
+	lateral := p.acceptKeyword("LATERAL")
+
+	if p.is(TokenLParen) {
+		if p.isQueryStartDeep(1) {
+			p.next()
+			query := p.parseSelect()
+			p.expect(TokenRParen)
+			sub := &SubqueryTable{Lateral: lateral, Query: query, Alias: p.parseAlias()}
+			sub.Span = p.span(start)
+			return sub
+		}
+		if lateral {
+			p.unexpected()
+		}
+		p.next()
+		inner := p.parseTableRef()
+		p.expect(TokenRParen)
+		if alias := p.parseAlias(); alias != nil {
+			if table, ok := inner.(*TableName); ok && table.Alias == nil {
+				table.Alias = alias
+			}
+		}
+		return inner
+	}
+
+	only := p.acceptKeyword("ONLY")
+	name := p.qualifiedName()
+
+	if p.is(TokenLParen) && !only {
+		fn := p.parseFuncCall(start, name.Schema, name.Name)
+		ft := &FuncTable{Lateral: lateral, Func: fn}
+		if p.acceptKeywords("WITH", "ORDINALITY") {
+			ft.WithOrdinality = true
+		}
+		ft.Alias = p.parseAlias()
+		ft.Span = p.span(start)
+		return ft
+	}
+	if lateral {
+		p.unexpected()
+	}
+
+	p.acceptOp("*")
+	table := &TableName{Name: name, Only: only, Alias: p.parseAlias()}
+	if p.acceptKeyword("TABLESAMPLE") {
+		p.ident()
+		p.skipParens()
+		if p.acceptKeyword("REPEATABLE") {
+			p.skipParens()
+		}
+	}
+	table.Span = p.span(start)
+	return table
+}
+
+// parseAlias parses an optional [AS] alias [(columns)]
+func (p *parser) parseAlias() *Alias {
+	start := p.peek().Pos
+	alias := &Alias{}
+	switch {
+	case p.acceptKeyword("AS"):
+		alias.Name = p.anyIdent()
+	case p.isIdent() && !p.isKeyword("SET", "VALUES", "OVERRIDING"):
+		alias.Name = p.ident()
+	default:
+		return nil
+	}
+	if p.is(TokenLParen) {
+		alias.Columns = p.identList()
+	}
+	alias.Span = p.span(start)
+	return alias
+}
+
+// isQueryStart reports whether the token n positions ahead starts a query
+func (p *parser) isQueryStart(n int) bool {
+	switch p.peekAt(n).Keyword() {
+	case "SELECT", "WITH", "VALUES":
+		return true
+	}
+	return false
+}
+
+// isQueryStartDeep is like isQueryStart but looks through nested parentheses
+func (p *parser) isQueryStartDeep(n int) bool {
+	for p.peekAt(n).Kind == TokenLParen {
+		n++
+	}
+	return p.isQueryStart(n) || p.isKeywordAt(n, "TABLE")
+}
+
+// ----------------------------------------------------------------------------
+// INSERT, UPDATE, DELETE
+
+func (p *parser) parseInsert(with *WithClause) *InsertStmt {
+	return p.parseInsertAt(p.peek().Pos, with)
+}
+
+// parseInsertAt parses INSERT INTO ... with the statement starting at start
+func (p *parser) parseInsertAt(start Pos, with *WithClause) *InsertStmt {
+	p.expectKeyword("INSERT")
+	p.expectKeyword("INTO")
+
+	stmt := &InsertStmt{With: with}
+	tableStart := p.peek().Pos
+	stmt.Table = &TableName{Name: p.qualifiedName()}
+	if p.isKeyword("AS") {
+		stmt.Table.Alias = p.parseAlias()
+	}
+	stmt.Table.Span = p.span(tableStart)
+
+	if p.is(TokenLParen) && !p.isQueryStartDeep(1) {
+		stmt.Columns = p.identList()
+	}
+
+	if p.acceptKeyword("OVERRIDING") {
+		if !p.acceptKeyword("SYSTEM") {
+			p.expectKeyword("USER")
+		}
+		p.expectKeyword("VALUE")
+	}
+
+	if p.acceptKeywords("DEFAULT", "VALUES") {
+		stmt.DefaultValues = true
+	} else {
+		stmt.Query = p.parseSelect()
+	}
+
+	if p.isKeyword("ON") && p.isKeywordAt(1, "CONFLICT") {
+		stmt.OnConflict = p.parseOnConflict()
+	}
+	if p.acceptKeyword("RETURNING") {
+		stmt.Returning = p.parseTargetList()
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseOnConflict parses ON CONFLICT [target] DO NOTHING | DO UPDATE SET ...
+func (p *parser) parseOnConflict() *OnConflict {
+	start := p.expectKeyword("ON").Pos
+	p.expectKeyword("CONFLICT")
+
+	oc := &OnConflict{}
+	switch {
+	case p.is(TokenLParen):
+		p.next()
+		oc.Target = p.parseIndexElems()
+		p.expect(TokenRParen)
+		if p.acceptKeyword("WHERE") {
+			oc.TargetWhere = p.parseExpr()
+		}
+	case p.acceptKeywords("ON", "CONSTRAINT"):
+		oc.Constraint = p.ident()
+	}
+
+	p.expectKeyword("DO")
+	if p.acceptKeyword("NOTHING") {
+		oc.DoNothing = true
+	} else {
+		p.expectKeyword("UPDATE")
+		p.expectKeyword("SET")
+		oc.Set = p.parseSetList()
+		if p.acceptKeyword("WHERE") {
+			oc.Where = p.parseExpr()
+		}
+	}
+
+	oc.Span = p.span(start)
+	return oc
+}
+
+func (p *parser) parseUpdate(with *WithClause) *UpdateStmt {
+	return p.parseUpdateAt(p.peek().Pos, with)
+}
+
+// parseUpdateAt parses UPDATE ... SET ... with the statement starting at start
+func (p *parser) parseUpdateAt(start Pos, with *WithClause) *UpdateStmt {
+	p.expectKeyword("UPDATE")
+	stmt := &UpdateStmt{With: with, Table: p.parseTargetTable()}
+
+	p.expectKeyword("SET")
+	stmt.Set = p.parseSetList()
+
+	if p.acceptKeyword("FROM") {
+		stmt.From = p.parseFromList()
+	}
+	if p.acceptKeyword("WHERE") {
+		stmt.Where = p.parseDMLWhere()
+	}
+	if p.acceptKeyword("RETURNING") {
+		stmt.Returning = p.parseTargetList()
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseSetList parses col = expr, (a, b) = (...), ...
+func (p *parser) parseSetList() []*SetClause {
+	var clauses []*SetClause
+	for {
+		start := p.peek().Pos
+		clause := &SetClause{}
+		if p.is(TokenLParen) {
+			clause.Columns = p.identList()
+		} else {
+			column := p.ident()
+			// Assignments to composite fields and array elements target the column
+			for p.is(TokenDot) || p.is(TokenLBracket) {
+				if p.accept(TokenDot) {
+					column += "." + p.anyIdent()
+					continue
+				}
+				p.next()
+				p.parseExpr()
+				p.expect(TokenRBracket)
+			}
+			clause.Columns = []string{column}
+		}
+		if !p.acceptOp("=") {
+			p.errorf(p.peek(), "syntax error at or near %s, expected =", p.peek())
+		}
+		clause.Value = p.parseExpr()
+		clause.Span = p.span(start)
+		clauses = append(clauses, clause)
+
+		if !p.accept(TokenComma) {
+			return clauses
+		}
+	}
+}
+
+func (p *parser) parseDelete(with *WithClause) *DeleteStmt {
+	return p.parseDeleteAt(p.peek().Pos, with)
+}
+
+// parseDeleteAt parses DELETE FROM ... with the statement starting at start
+func (p *parser) parseDeleteAt(start Pos, with *WithClause) *DeleteStmt {
+	p.expectKeyword("DELETE")
+	p.expectKeyword("FROM")
+	stmt := &DeleteStmt{With: with, Table: p.parseTargetTable()}
+
+	if p.acceptKeyword("USING") {
+		stmt.Using = p.parseFromList()
+	}
+	if p.acceptKeyword("WHERE") {
+		stmt.Where = p.parseDMLWhere()
+	}
+	if p.acceptKeyword("RETURNING") {
+		stmt.Returning = p.parseTargetList()
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseTargetTable parses the [ONLY] table [*] [[AS] alias] of UPDATE and DELETE
+func (p *parser) parseTargetTable() *TableName {
+	start := p.peek().Pos
+	table := &TableName{Only: p.acceptKeyword("ONLY"), Name: p.qualifiedName()}
+	p.acceptOp("*")
+	table.Alias = p.parseAlias()
+	table.Span = p.span(start)
+	return table
+}
+
+// ----------------------------------------------------------------------------
+// Utility statements
+
+// parseDMLWhere parses the condition of an UPDATE or DELETE WHERE clause.
+// WHERE CURRENT OF cursor is represented as a ColumnRef naming the cursor.
+func (p *parser) parseDMLWhere() Expr {
+	if !p.acceptKeywords("CURRENT", "OF") {
+		return p.parseExpr()
+	}
+	start := p.peek().Pos
+	name := p.ident()
+	return &ColumnRef{Span: p.span(start), Fields: []string{name}}
+}
+
+// parseExplain parses EXPLAIN [ANALYZE] [VERBOSE] stmt and EXPLAIN (options) stmt
+func (p *parser) parseExplain() *ExplainStmt {
+	start := p.expectKeyword("EXPLAIN").Pos
+	stmt := &ExplainStmt{}
+
+	if p.is(TokenLParen) && !p.isQueryStartDeep(1) {
+		p.next()
+		for {
+			optStart := p.peek().Pos
+			option := strings.ToUpper(p.anyIdent())
+			if !p.is(TokenComma) && !p.is(TokenRParen) {
+				p.next()
+			}
+			if option == "ANALYZE" && !strings.HasSuffix(strings.ToUpper(p.textFrom(optStart)), "FALSE") &&
+				!strings.HasSuffix(strings.ToUpper(p.textFrom(optStart)), "OFF") {
+				stmt.Analyze = true
+			}
+			stmt.Options = append(stmt.Options, strings.ToUpper(p.textFrom(optStart)))
+			if !p.accept(TokenComma) {
+				break
+			}
+		}
+		p.expect(TokenRParen)
+	} else {
+		for p.isKeyword("ANALYZE", "ANALYSE", "VERBOSE") {
+			option := p.next().Keyword()
+			if option != "VERBOSE" {
+				option = "ANALYZE"
+				stmt.Analyze = true
+			}
+			stmt.Options = append(stmt.Options, option)
+		}
+	}
+
+	stmt.Stmt = p.parseStmt()
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseTransaction parses transaction control statements
+func (p *parser) parseTransaction() *TransactionStmt {
+	start := p.peek().Pos
+	stmt := &TransactionStmt{}
+
+	switch p.next().Keyword() {
+	case "BEGIN", "START":
+		stmt.Kind = "BEGIN"
+	case "COMMIT", "END":
+		stmt.Kind = "COMMIT"
+		if p.isKeyword("PREPARED") {
+			stmt.Kind = "COMMIT PREPARED"
+		}
+	case "ROLLBACK", "ABORT":
+		stmt.Kind = "ROLLBACK"
+		switch {
+		case p.isKeyword("TO"):
+			stmt.Kind = "ROLLBACK TO SAVEPOINT"
+		case p.isKeyword("PREPARED"):
+			stmt.Kind = "ROLLBACK PREPARED"
+		}
+	case "SAVEPOINT":
+		stmt.Kind = "SAVEPOINT"
+	case "RELEASE":
+		stmt.Kind = "RELEASE"
+	}
+
+	// Modes, savepoint names and isolation levels are not modelled
+	for !p.is(TokenEOF) {
+		p.next()
+	}
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseRaw consumes a statement the parser does not model
+func (p *parser) parseRaw() *RawStmt {
+	start := p.peek().Pos
+	first := p.pos
+	stmt := &RawStmt{Command: p.command()}
+	p.pos = first
+	for !p.is(TokenEOF) {
+		stmt.Tokens = append(stmt.Tokens, p.next())
+	}
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// command returns the leading keywords naming the statement, such as
+// "CREATE FUNCTION" or "GRANT". It consumes tokens.
+func (p *parser) command() string {
+	first := p.next().Keyword()
+	switch first {
+	case "CREATE", "ALTER", "DROP":
+		p.acceptKeywords("OR", "REPLACE")
+		for p.isKeyword("TEMP", "TEMPORARY", "UNLOGGED", "GLOBAL", "LOCAL", "TRUSTED",
+			"PROCEDURAL", "CONSTRAINT", "DEFAULT", "UNIQUE", "RECURSIVE") {
+			p.next()
+		}
+		if object := p.objectType(); object != "" {
+			return first + " " + object
+		}
+	case "COMMENT", "SECURITY":
+		if p.isKeyword("ON") || p.isKeyword("LABEL") {
+			return first + " " + p.next().Keyword()
+		}
+	case "REFRESH":
+		if p.acceptKeywords("MATERIALIZED", "VIEW") {
+			return "REFRESH MATERIALIZED VIEW"
+		}
+	}
+	return first
+}
+
+// objectType parses the object type of CREATE, ALTER or DROP, returning ""
+// when the next token does not name one
+func (p *parser) objectType() string {
+	kw := p.peek().Keyword()
+	if !objectTypes[kw] {
+		return ""
+	}
+	p.next()
+
+	words := []string{kw}
+	switch kw {
+	case "MATERIALIZED", "FOREIGN", "EVENT", "ACCESS", "USER":
+		if next := p.peek().Keyword(); next != "" {
+			switch {
+			case kw == "MATERIALIZED" && next == "VIEW",
+				kw == "FOREIGN" && (next == "TABLE" || next == "DATA"),
+				kw == "EVENT" && next == "TRIGGER",
+				kw == "ACCESS" && next == "METHOD",
+				kw == "USER" && next == "MAPPING":
+				words = append(words, p.next().Keyword())
+			}
+		}
+		if kw == "FOREIGN" && words[len(words)-1] == "DATA" {
+			words = append(words, p.expectKeyword("WRAPPER").Keyword())
+		}
+	case "TEXT":
+		words = append(words, p.expectKeyword("SEARCH").Keyword(), p.next().Keyword())
+	case "OPERATOR":
+		if p.isKeyword("CLASS", "FAMILY") {
+			words = append(words, p.next().Keyword())
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// kindText describes a token kind for error messages
+func kindText(kind TokenKind) string {
+	switch kind {
+	case TokenLParen:
+		return `"("`
+	case TokenRParen:
+		return `")"`
+	case TokenLBracket:
+		return `"["`
+	case TokenRBracket:
+		return `"]"`
+	case TokenComma:
+		return `","`
+	case TokenSemicolon:
+		return `";"`
+	case TokenEOF:
+		return "end of input"
+	default:
+		return strings.ToLower(kind.String())
+	}
+}
+
+// reserved holds PostgreSQL's reserved keywords, including those that may
+// only be used as function or type names. They cannot be used as bare
+// identifiers or aliases.
+var reserved = makeSet(
+	"ALL", "ANALYSE", "ANALYZE", "AND", "ANY", "ARRAY", "AS", "ASC", "ASYMMETRIC",
+	"BOTH", "CASE", "CAST", "CHECK", "COLLATE", "COLUMN", "CONSTRAINT", "CREATE",
+	"CURRENT_CATALOG", "CURRENT_DATE", "CURRENT_ROLE", "CURRENT_TIME", "CURRENT_TIMESTAMP",
+	"CURRENT_USER", "DEFAULT", "DEFERRABLE", "DESC", "DISTINCT", "DO", "ELSE", "END",
+	"EXCEPT", "FALSE", "FETCH", "FOR", "FOREIGN", "FROM", "GRANT", "GROUP", "HAVING",
+	"IN", "INITIALLY", "INTERSECT", "INTO", "LATERAL", "LEADING", "LIMIT", "LOCALTIME",
+	"LOCALTIMESTAMP", "NOT", "NULL", "OFFSET", "ON", "ONLY", "OR", "ORDER", "PLACING",
+	"PRIMARY", "REFERENCES", "RETURNING", "SELECT", "SESSION_USER", "SOME", "SYMMETRIC",
+	"SYSTEM_USER", "TABLE", "THEN", "TO", "TRAILING", "TRUE", "UNION", "UNIQUE", "USER",
+	"USING", "VARIADIC", "WHEN", "WHERE", "WINDOW", "WITH",
+	// type_func_name_keyword
+	"AUTHORIZATION", "BINARY", "COLLATION", "CONCURRENTLY", "CROSS", "CURRENT_SCHEMA",
+	"FREEZE", "FULL", "ILIKE", "INNER", "IS", "ISNULL", "JOIN", "LEFT", "LIKE", "NATURAL",
+	"NOTNULL", "OUTER", "OVERLAPS", "RIGHT", "SIMILAR", "TABLESAMPLE", "VERBOSE",
+)
+
+// typeFuncNames are reserved keywords that may still be called as functions
+var typeFuncNames = makeSet(
+	"AUTHORIZATION", "BINARY", "COLLATION", "CONCURRENTLY", "CROSS", "CURRENT_SCHEMA",
+	"FREEZE", "FULL", "ILIKE", "INNER", "IS", "ISNULL", "JOIN", "LEFT", "LIKE", "NATURAL",
+	"NOTNULL", "OUTER", "OVERLAPS", "RIGHT", "SIMILAR", "TABLESAMPLE", "VERBOSE",
+)
+
+// rawCommands are statements that parse as RawStmt
+var rawCommands = makeSet(
+	"GRANT", "REVOKE", "COMMENT", "VACUUM", "ANALYZE", "ANALYSE", "REINDEX", "CLUSTER",
+	"COPY", "DO", "LOCK", "NOTIFY", "LISTEN", "UNLISTEN", "PREPARE", "EXECUTE", "DEALLOCATE",
+	"DECLARE", "FETCH", "MOVE", "CLOSE", "SET", "RESET", "SHOW", "REFRESH", "CALL", "MERGE",
+	"SECURITY", "DISCARD", "CHECKPOINT", "LOAD", "IMPORT", "REASSIGN",
+)
+
+// objectTypes are the first words of object types in CREATE, ALTER and DROP
+var objectTypes = makeSet(
+	"TABLE", "INDEX", "VIEW", "MATERIALIZED", "SEQUENCE", "SCHEMA", "DATABASE", "TYPE",
+	"DOMAIN", "FUNCTION", "PROCEDURE", "ROUTINE", "AGGREGATE", "EXTENSION", "TRIGGER",
+	"POLICY", "RULE", "ROLE", "USER", "GROUP", "COLLATION", "CONVERSION", "FOREIGN",
+	"SERVER", "EVENT", "PUBLICATION", "SUBSCRIPTION", "STATISTICS", "TABLESPACE",
+	"OPERATOR", "TEXT", "CAST", "LANGUAGE", "TRANSFORM", "ACCESS", "OWNED",
+)
+
+func makeSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
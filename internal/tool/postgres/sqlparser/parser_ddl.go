@@ -0,0 +1,763 @@
+package sqlparser
+
+import "strings"
+
+// parseCreate parses CREATE TABLE, CREATE INDEX and CREATE VIEW; other
+// CREATE statements become RawStmt
+func (p *parser) parseCreate() Stmt {
+	save := p.pos
+	start := p.expectKeyword("CREATE").Pos
+
+	orReplace := p.acceptKeywords("OR", "REPLACE")
+	var temporary, unlogged bool
+	for {
+		switch {
+		case p.acceptKeyword("GLOBAL"), p.acceptKeyword("LOCAL"):
+			continue
+		case p.acceptKeyword("TEMP"), p.acceptKeyword("TEMPORARY"):
+			temporary = true
+			continue
+		case p.acceptKeyword("UNLOGGED"):
+			unlogged = true
+			continue
+		}
+		break
+	}
+
+	switch {
+	case p.isKeyword("TABLE") && !orReplace:
+		return p.parseCreateTable(start, temporary, unlogged)
+	case p.isKeyword("INDEX"), p.isKeyword("UNIQUE") && p.isKeywordAt(1, "INDEX"):
+		return p.parseCreateIndex(start)
+	case p.isKeyword("VIEW"), p.isKeyword("MATERIALIZED") && p.isKeywordAt(1, "VIEW"),
+		p.isKeyword("RECURSIVE") && p.isKeywordAt(1, "VIEW"):
+		return p.parseCreateView(start, orReplace)
+	}
+
+	p.pos = save
+	return p.parseRaw()
+}
+
+// parseCreateTable parses the remainder of CREATE TABLE after its modifiers
+func (p *parser) parseCreateTable(start Pos, temporary, unlogged bool) *CreateTableStmt {
+	p.expectKeyword("TABLE")
+	stmt := &CreateTableStmt{
+		Temporary:   temporary,
+		Unlogged:    unlogged,
+		IfNotExists: p.acceptKeywords("IF", "NOT", "EXISTS"),
+		Name:        p.qualifiedName(),
+	}
+
+	if p.acceptKeywords("PARTITION", "OF") {
+		parent := p.qualifiedName()
+		stmt.PartitionOf = &parent
+	}
+
+	if p.is(TokenLParen) {
+		p.next()
+		if !p.is(TokenRParen) {
+			p.parseTableElements(stmt)
+		}
+		p.expect(TokenRParen)
+	}
+
+	if stmt.PartitionOf != nil {
+		// FOR VALUES ... / DEFAULT bounds are not modelled
+		for !p.is(TokenEOF) && !p.isKeyword("PARTITION") {
+			if p.is(TokenLParen) {
+				p.skipParens()
+				continue
+			}
+			p.next()
+		}
+	}
+
+	if p.acceptKeyword("INHERITS") {
+		p.expect(TokenLParen)
+		for {
+			stmt.Inherits = append(stmt.Inherits, p.qualifiedName())
+			if !p.accept(TokenComma) {
+				break
+			}
+		}
+		p.expect(TokenRParen)
+	}
+
+	if p.isKeyword("PARTITION") && p.isKeywordAt(1, "BY") {
+		byStart := p.peek().Pos
+		p.next()
+		p.next()
+		p.ident()
+		p.skipParens()
+		stmt.PartitionBy = p.textFrom(byStart)
+	}
+
+	p.parseTableOptions()
+
+	if p.acceptKeyword("AS") {
+		stmt.AsQuery = p.parseSelect()
+		if p.acceptKeyword("WITH") {
+			p.acceptKeyword("NO")
+			p.expectKeyword("DATA")
+		}
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseTableOptions skips USING, WITH (...), WITHOUT OIDS, ON COMMIT and TABLESPACE clauses
+func (p *parser) parseTableOptions() {
+	for {
+		switch {
+		case p.acceptKeyword("USING"):
+			p.ident()
+		case p.isKeyword("WITH") && p.peekAt(1).Kind == TokenLParen:
+			p.next()
+			p.skipParens()
+		case p.acceptKeywords("WITHOUT", "OIDS"):
+		case p.acceptKeywords("ON", "COMMIT"):
+			for p.isKeyword("PRESERVE", "DELETE", "DROP", "ROWS") {
+				p.next()
+			}
+		case p.acceptKeyword("TABLESPACE"):
+			p.ident()
+		default:
+			return
+		}
+	}
+}
+
+// parseTableElements parses the column definitions, table constraints and
+// LIKE clauses of CREATE TABLE
+func (p *parser) parseTableElements(stmt *CreateTableStmt) {
+	for {
+		switch {
+		case p.isTableConstraintStart():
+			stmt.Constraints = append(stmt.Constraints, p.parseTableConstraint())
+		case p.acceptKeyword("LIKE"):
+			stmt.Like = append(stmt.Like, p.qualifiedName())
+			for p.isKeyword("INCLUDING", "EXCLUDING") {
+				p.next()
+				p.anyIdent()
+			}
+		default:
+			stmt.Columns = append(stmt.Columns, p.parseColumnDef())
+		}
+		if !p.accept(TokenComma) {
+			return
+		}
+	}
+}
+
+// isTableConstraintStart reports whether a table constraint starts at the current position
+func (p *parser) isTableConstraintStart() bool {
+	switch p.peek().Keyword() {
+	case "CONSTRAINT", "CHECK", "UNIQUE", "PRIMARY", "FOREIGN", "EXCLUDE":
+		return true
+	}
+	return false
+}
+
+// parseColumnDef parses name type [COLLATE c] [constraints...]
+func (p *parser) parseColumnDef() *ColumnDef {
+	start := p.peek().Pos
+	col := &ColumnDef{Name: p.ident()}
+	col.Type = p.parseTypeName()
+
+	for {
+		switch {
+		case p.acceptKeyword("COLLATE"):
+			col.Collation = p.qualifiedName().String()
+		case p.isColumnConstraintStart():
+			col.Constraints = append(col.Constraints, p.parseColumnConstraint())
+		case p.acceptKeyword("STORAGE"), p.acceptKeyword("COMPRESSION"):
+			p.anyIdent()
+		default:
+			col.Span = p.span(start)
+			return col
+		}
+	}
+}
+
+// isColumnConstraintStart reports whether a column constraint starts at the current position
+func (p *parser) isColumnConstraintStart() bool {
+	switch p.peek().Keyword() {
+	case "CONSTRAINT", "NOT", "NULL", "CHECK", "DEFAULT", "GENERATED", "UNIQUE", "PRIMARY",
+		"REFERENCES", "DEFERRABLE", "INITIALLY":
+		return true
+	}
+	return false
+}
+
+// parseColumnConstraint parses a single column constraint
+func (p *parser) parseColumnConstraint() *Constraint {
+	start := p.peek().Pos
+	c := &Constraint{}
+	if p.acceptKeyword("CONSTRAINT") {
+		c.Name = p.ident()
+	}
+
+	switch {
+	case p.acceptKeywords("NOT", "NULL"):
+		c.Type = ConstraintNotNull
+	case p.acceptKeyword("NULL"):
+		c.Type = ConstraintNull
+	case p.acceptKeyword("CHECK"):
+		c.Type = ConstraintCheck
+		p.expect(TokenLParen)
+		c.Expr = p.parseExpr()
+		p.expect(TokenRParen)
+		p.acceptKeywords("NO", "INHERIT")
+	case p.acceptKeyword("DEFAULT"):
+		c.Type = ConstraintDefault
+		// DEFAULT takes a restricted expression so that following
+		// constraints such as NOT NULL are not swallowed
+		c.Expr = p.parseExprPrec(precIs)
+	case p.acceptKeyword("GENERATED"):
+		switch {
+		case p.acceptKeyword("ALWAYS"):
+			c.Identity = "ALWAYS"
+		default:
+			p.expectKeyword("BY")
+			p.expectKeyword("DEFAULT")
+			c.Identity = "BY DEFAULT"
+		}
+		p.expectKeyword("AS")
+		if p.acceptKeyword("IDENTITY") {
+			c.Type = ConstraintIdentity
+			if p.is(TokenLParen) {
+				p.skipParens()
+			}
+		} else {
+			c.Type = ConstraintGenerated
+			c.Identity = ""
+			p.expect(TokenLParen)
+			c.Expr = p.parseExpr()
+			p.expect(TokenRParen)
+			p.acceptKeyword("STORED")
+		}
+	case p.acceptKeyword("UNIQUE"):
+		c.Type = ConstraintUnique
+		p.parseNullsDistinct()
+		p.parseIndexParameters(c)
+	case p.acceptKeywords("PRIMARY", "KEY"):
+		c.Type = ConstraintPrimaryKey
+		p.parseIndexParameters(c)
+	case p.isKeyword("REFERENCES"):
+		c.Type = ConstraintForeignKey
+		c.References = p.parseReference()
+	case p.isKeyword("DEFERRABLE", "INITIALLY") || (p.isKeyword("NOT") && p.isKeywordAt(1, "DEFERRABLE")):
+		// Attributes of the preceding constraint; kept as a constraint without a type
+	default:
+		p.unexpected()
+	}
+
+	p.parseConstraintAttributes(c)
+	c.Span = p.span(start)
+	return c
+}
+
+// parseTableConstraint parses a table constraint
+func (p *parser) parseTableConstraint() *Constraint {
+	start := p.peek().Pos
+	c := &Constraint{}
+	if p.acceptKeyword("CONSTRAINT") {
+		c.Name = p.ident()
+	}
+
+	switch {
+	case p.acceptKeyword("CHECK"):
+		c.Type = ConstraintCheck
+		p.expect(TokenLParen)
+		c.Expr = p.parseExpr()
+		p.expect(TokenRParen)
+		p.acceptKeywords("NO", "INHERIT")
+	case p.acceptKeyword("UNIQUE"):
+		c.Type = ConstraintUnique
+		p.parseNullsDistinct()
+		p.parseKeyColumns(c)
+	case p.acceptKeywords("PRIMARY", "KEY"):
+		c.Type = ConstraintPrimaryKey
+		p.parseKeyColumns(c)
+	case p.acceptKeyword("EXCLUDE"):
+		c.Type = ConstraintExclude
+		if p.acceptKeyword("USING") {
+			p.ident()
+		}
+		p.skipParens()
+		p.parseIndexParameters(c)
+		if p.acceptKeyword("WHERE") {
+			p.skipParens()
+		}
+	case p.acceptKeywords("FOREIGN", "KEY"):
+		c.Type = ConstraintForeignKey
+		c.Columns = p.identList()
+		c.References = p.parseReference()
+	default:
+		p.unexpected()
+	}
+
+	p.parseConstraintAttributes(c)
+	c.Span = p.span(start)
+	return c
+}
+
+// parseKeyColumns parses the column list of a UNIQUE or PRIMARY KEY table
+// constraint, or USING INDEX name when adding one from an existing index
+func (p *parser) parseKeyColumns(c *Constraint) {
+	if p.acceptKeywords("USING", "INDEX") {
+		c.UsingIndex = p.ident()
+		return
+	}
+	c.Columns = p.identList()
+	p.parseIndexParameters(c)
+}
+
+// parseNullsDistinct skips NULLS [NOT] DISTINCT
+func (p *parser) parseNullsDistinct() {
+	if p.acceptKeyword("NULLS") {
+		p.acceptKeyword("NOT")
+		p.expectKeyword("DISTINCT")
+	}
+}
+
+// parseIndexParameters parses INCLUDE (...), WITH (...) and USING INDEX TABLESPACE
+func (p *parser) parseIndexParameters(c *Constraint) {
+	for {
+		switch {
+		case p.acceptKeyword("INCLUDE"):
+			c.Include = p.identList()
+		case p.isKeyword("WITH") && p.peekAt(1).Kind == TokenLParen:
+			p.next()
+			p.skipParens()
+		case p.acceptKeywords("USING", "INDEX", "TABLESPACE"):
+			p.ident()
+		default:
+			return
+		}
+	}
+}
+
+// parseConstraintAttributes parses [NOT] DEFERRABLE, INITIALLY ... and NOT VALID
+func (p *parser) parseConstraintAttributes(c *Constraint) {
+	for {
+		switch {
+		case p.acceptKeyword("DEFERRABLE"):
+			c.Deferrable = true
+		case p.acceptKeywords("NOT", "DEFERRABLE"):
+			c.Deferrable = false
+		case p.acceptKeyword("INITIALLY"):
+			if !p.acceptKeyword("DEFERRED") {
+				p.expectKeyword("IMMEDIATE")
+			}
+		case p.acceptKeywords("NOT", "VALID"):
+			c.NotValid = true
+		case p.acceptKeywords("NO", "INHERIT"):
+		default:
+			return
+		}
+	}
+}
+
+// parseReference parses REFERENCES table [(cols)] [MATCH ...] [ON DELETE ...] [ON UPDATE ...]
+func (p *parser) parseReference() *Reference {
+	start := p.expectKeyword("REFERENCES").Pos
+	ref := &Reference{Table: p.qualifiedName()}
+	if p.is(TokenLParen) {
+		ref.Columns = p.identList()
+	}
+	if p.acceptKeyword("MATCH") {
+		ref.Match = p.next().Keyword()
+	}
+	for p.isKeyword("ON") && (p.isKeywordAt(1, "DELETE") || p.isKeywordAt(1, "UPDATE")) {
+		p.next()
+		event := p.next().Keyword()
+		action := p.parseReferentialAction()
+		if event == "DELETE" {
+			ref.OnDelete = action
+		} else {
+			ref.OnUpdate = action
+		}
+	}
+	ref.Span = p.span(start)
+	return ref
+}
+
+// parseReferentialAction parses NO ACTION, RESTRICT, CASCADE, SET NULL or SET DEFAULT
+func (p *parser) parseReferentialAction() string {
+	switch {
+	case p.acceptKeywords("NO", "ACTION"):
+		return "NO ACTION"
+	case p.acceptKeyword("RESTRICT"):
+		return "RESTRICT"
+	case p.acceptKeyword("CASCADE"):
+		return "CASCADE"
+	case p.acceptKeywords("SET", "NULL"):
+		if p.is(TokenLParen) {
+			p.identList()
+		}
+		return "SET NULL"
+	case p.acceptKeywords("SET", "DEFAULT"):
+		if p.is(TokenLParen) {
+			p.identList()
+		}
+		return "SET DEFAULT"
+	}
+	p.unexpected()
+	return ""
+}
+
+// parseCreateIndex parses the remainder of CREATE [UNIQUE] INDEX
+func (p *parser) parseCreateIndex(start Pos) *CreateIndexStmt {
+	stmt := &CreateIndexStmt{Unique: p.acceptKeyword("UNIQUE")}
+	p.expectKeyword("INDEX")
+	stmt.Concurrently = p.acceptKeyword("CONCURRENTLY")
+
+	if p.acceptKeywords("IF", "NOT", "EXISTS") {
+		stmt.IfNotExists = true
+		stmt.Name = p.ident()
+	} else if !p.isKeyword("ON") {
+		stmt.Name = p.ident()
+	}
+
+	p.expectKeyword("ON")
+	stmt.Only = p.acceptKeyword("ONLY")
+	stmt.Table = p.qualifiedName()
+	if p.acceptKeyword("USING") {
+		stmt.Method = p.ident()
+	}
+
+	p.expect(TokenLParen)
+	stmt.Columns = p.parseIndexElems()
+	p.expect(TokenRParen)
+
+	if p.acceptKeyword("INCLUDE") {
+		stmt.Include = p.identList()
+	}
+	p.parseNullsDistinct()
+	if p.isKeyword("WITH") && p.peekAt(1).Kind == TokenLParen {
+		p.next()
+		p.skipParens()
+	}
+	if p.acceptKeyword("TABLESPACE") {
+		p.ident()
+	}
+	if p.acceptKeyword("WHERE") {
+		stmt.Where = p.parseExpr()
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseIndexElems parses the elements of an index definition
+func (p *parser) parseIndexElems() []*IndexElem {
+	var elems []*IndexElem
+	for {
+		start := p.peek().Pos
+		elem := &IndexElem{}
+		if p.is(TokenLParen) {
+			p.next()
+			elem.Expr = p.parseExpr()
+			p.expect(TokenRParen)
+		} else {
+			elem.Expr = p.parseExprPrec(precCollate - 1)
+		}
+		if c, ok := elem.Expr.(*CollateExpr); ok {
+			elem.Expr = c.Expr
+			elem.Collation = c.Collation
+		}
+		if p.acceptKeyword("COLLATE") {
+			elem.Collation = p.qualifiedName().String()
+		}
+		if ref, ok := elem.Expr.(*ColumnRef); ok && len(ref.Fields) == 1 {
+			elem.Column = ref.Fields[0]
+		}
+
+		if p.isIdent() && !p.isKeyword("NULLS") {
+			elem.OpClass = p.qualifiedName().String()
+			if p.is(TokenLParen) {
+				p.skipParens()
+			}
+		}
+		switch {
+		case p.acceptKeyword("ASC"):
+		case p.acceptKeyword("DESC"):
+			elem.Desc = true
+		}
+		if p.acceptKeyword("NULLS") {
+			elem.Nulls = p.next().Keyword()
+		}
+
+		elem.Span = p.span(start)
+		elems = append(elems, elem)
+		if !p.accept(TokenComma) {
+			return elems
+		}
+	}
+}
+
+// parseCreateView parses the remainder of CREATE [MATERIALIZED | RECURSIVE] VIEW
+func (p *parser) parseCreateView(start Pos, orReplace bool) *CreateViewStmt {
+	stmt := &CreateViewStmt{OrReplace: orReplace}
+	stmt.Materialized = p.acceptKeyword("MATERIALIZED")
+	p.acceptKeyword("RECURSIVE")
+	p.expectKeyword("VIEW")
+	stmt.IfNotExists = p.acceptKeywords("IF", "NOT", "EXISTS")
+	stmt.Name = p.qualifiedName()
+	if p.is(TokenLParen) {
+		stmt.Columns = p.identList()
+	}
+	p.parseTableOptions()
+
+	p.expectKeyword("AS")
+	stmt.Query = p.parseSelect()
+
+	if p.acceptKeyword("WITH") {
+		switch {
+		case p.acceptKeyword("NO"), p.isKeyword("DATA"):
+			p.expectKeyword("DATA")
+		default:
+			if !p.acceptKeyword("CASCADED") {
+				p.acceptKeyword("LOCAL")
+			}
+			p.expectKeyword("CHECK")
+			p.expectKeyword("OPTION")
+		}
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseAlterTable parses ALTER TABLE
+func (p *parser) parseAlterTable() *AlterTableStmt {
+	start := p.expectKeyword("ALTER").Pos
+	p.expectKeyword("TABLE")
+
+	stmt := &AlterTableStmt{
+		IfExists: p.acceptKeywords("IF", "EXISTS"),
+		Only:     p.acceptKeyword("ONLY"),
+	}
+	stmt.Table = p.qualifiedName()
+	p.acceptOp("*")
+
+	switch {
+	case p.isKeyword("RENAME"):
+		stmt.Actions = []*AlterTableAction{p.parseRename()}
+	case p.isKeyword("SET") && p.isKeywordAt(1, "SCHEMA"):
+		actionStart := p.next().Pos
+		p.next()
+		action := &AlterTableAction{Kind: AlterSetSchema, NewName: p.ident()}
+		action.Span = p.span(actionStart)
+		stmt.Actions = []*AlterTableAction{action}
+	default:
+		for {
+			stmt.Actions = append(stmt.Actions, p.parseAlterAction())
+			if !p.accept(TokenComma) {
+				break
+			}
+		}
+	}
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseRename parses RENAME TO, RENAME CONSTRAINT and RENAME [COLUMN]
+func (p *parser) parseRename() *AlterTableAction {
+	start := p.expectKeyword("RENAME").Pos
+	action := &AlterTableAction{}
+
+	switch {
+	case p.acceptKeyword("TO"):
+		action.Kind = AlterRenameTable
+		action.NewName = p.ident()
+	case p.acceptKeyword("CONSTRAINT"):
+		action.Kind = AlterRenameConstraint
+		action.Name = p.ident()
+		p.expectKeyword("TO")
+		action.NewName = p.ident()
+	default:
+		p.acceptKeyword("COLUMN")
+		action.Kind = AlterRenameColumn
+		action.Column = p.ident()
+		p.expectKeyword("TO")
+		action.NewName = p.ident()
+	}
+
+	action.Span = p.span(start)
+	return action
+}
+
+// parseAlterAction parses one comma-separated ALTER TABLE action
+func (p *parser) parseAlterAction() *AlterTableAction {
+	start := p.peek().Pos
+	action := &AlterTableAction{}
+
+	switch {
+	case p.acceptKeyword("ADD"):
+		if p.isTableConstraintStart() {
+			action.Kind = AlterAddConstraint
+			action.Constraint = p.parseTableConstraint()
+			action.Name = action.Constraint.Name
+			break
+		}
+		p.acceptKeyword("COLUMN")
+		action.Kind = AlterAddColumn
+		action.IfNotExists = p.acceptKeywords("IF", "NOT", "EXISTS")
+		action.ColumnDef = p.parseColumnDef()
+		action.Column = action.ColumnDef.Name
+
+	case p.isKeyword("DROP") && p.isKeywordAt(1, "CONSTRAINT"):
+		p.next()
+		p.next()
+		action.Kind = AlterDropConstraint
+		action.IfExists = p.acceptKeywords("IF", "EXISTS")
+		action.Name = p.ident()
+		action.Cascade = p.parseDropBehavior()
+
+	case p.isKeyword("DROP") && (p.isKeywordAt(1, "COLUMN") || p.peekAt(1).Kind == TokenQuotedIdent ||
+		(p.peekAt(1).Kind == TokenIdent && !alterDropOther[p.peekAt(1).Keyword()])):
+		p.next()
+		p.acceptKeyword("COLUMN")
+		action.Kind = AlterDropColumn
+		action.IfExists = p.acceptKeywords("IF", "EXISTS")
+		action.Column = p.ident()
+		action.Cascade = p.parseDropBehavior()
+
+	case p.isKeyword("ALTER"):
+		p.next()
+		p.acceptKeyword("COLUMN")
+		action.Column = p.ident()
+		p.parseAlterColumn(action)
+
+	case p.acceptKeywords("VALIDATE", "CONSTRAINT"):
+		action.Kind = AlterValidateConstraint
+		action.Name = p.ident()
+
+	default:
+		action.Kind = AlterOther
+		p.skipAlterAction()
+		action.Text = p.textFrom(start)
+	}
+
+	action.Span = p.span(start)
+	return action
+}
+
+// alterDropOther are words after DROP that do not start a column name
+var alterDropOther = makeSet("CONSTRAINT", "IDENTITY", "EXPRESSION", "DEFAULT", "NOT")
+
+// parseAlterColumn parses the action applied by ALTER [COLUMN] name
+func (p *parser) parseAlterColumn(action *AlterTableAction) {
+	start := p.peek().Pos
+	switch {
+	case p.acceptKeywords("SET", "DATA", "TYPE"), p.acceptKeyword("TYPE"):
+		action.Kind = AlterColumnType
+		action.Type = p.parseTypeName()
+		if p.acceptKeyword("COLLATE") {
+			p.qualifiedName()
+		}
+		if p.acceptKeyword("USING") {
+			action.Using = p.parseExpr()
+		}
+	case p.acceptKeywords("SET", "DEFAULT"):
+		action.Kind = AlterSetDefault
+		action.Default = p.parseExpr()
+	case p.acceptKeywords("DROP", "DEFAULT"):
+		action.Kind = AlterDropDefault
+	case p.acceptKeywords("SET", "NOT", "NULL"):
+		action.Kind = AlterSetNotNull
+	case p.acceptKeywords("DROP", "NOT", "NULL"):
+		action.Kind = AlterDropNotNull
+	default:
+		action.Kind = AlterOther
+		p.skipAlterAction()
+		action.Text = strings.TrimSpace(p.textFrom(start))
+	}
+}
+
+// skipAlterAction skips to the end of the current ALTER TABLE action
+func (p *parser) skipAlterAction() {
+	for !p.is(TokenComma) && !p.is(TokenEOF) {
+		if p.is(TokenLParen) {
+			p.skipParens()
+			continue
+		}
+		p.next()
+	}
+}
+
+// parseDropBehavior parses an optional CASCADE or RESTRICT, reporting CASCADE
+func (p *parser) parseDropBehavior() bool {
+	if p.acceptKeyword("CASCADE") {
+		return true
+	}
+	p.acceptKeyword("RESTRICT")
+	return false
+}
+
+// parseDrop parses DROP <object type> [CONCURRENTLY] [IF EXISTS] names [CASCADE | RESTRICT]
+func (p *parser) parseDrop() Stmt {
+	save := p.pos
+	start := p.expectKeyword("DROP").Pos
+
+	objectType := p.objectType()
+	switch objectType {
+	case "", "OWNED", "CAST", "OPERATOR", "OPERATOR CLASS", "OPERATOR FAMILY", "TRANSFORM", "USER MAPPING":
+		p.pos = save
+		return p.parseRaw()
+	}
+
+	stmt := &DropStmt{ObjectType: objectType}
+	stmt.Concurrently = p.acceptKeyword("CONCURRENTLY")
+	stmt.IfExists = p.acceptKeywords("IF", "EXISTS")
+
+	for {
+		stmt.Names = append(stmt.Names, p.qualifiedName())
+		if p.is(TokenLParen) {
+			// Function signatures are not modelled
+			p.skipParens()
+		}
+		if !p.accept(TokenComma) {
+			break
+		}
+	}
+
+	if p.acceptKeyword("ON") {
+		on := p.qualifiedName()
+		stmt.On = &on
+	}
+	stmt.Cascade = p.parseDropBehavior()
+
+	stmt.Span = p.span(start)
+	return stmt
+}
+
+// parseTruncate parses TRUNCATE [TABLE] [ONLY] names [RESTART | CONTINUE IDENTITY] [CASCADE | RESTRICT]
+func (p *parser) parseTruncate() *TruncateStmt {
+	start := p.expectKeyword("TRUNCATE").Pos
+	p.acceptKeyword("TABLE")
+
+	stmt := &TruncateStmt{}
+	for {
+		p.acceptKeyword("ONLY")
+		stmt.Tables = append(stmt.Tables, p.qualifiedName())
+		p.acceptOp("*")
+		if !p.accept(TokenComma) {
+			break
+		}
+	}
+
+	switch {
+	case p.acceptKeywords("RESTART", "IDENTITY"):
+		stmt.RestartIdentity = true
+	case p.acceptKeywords("CONTINUE", "IDENTITY"):
+	}
+	stmt.Cascade = p.parseDropBehavior()
+
+	stmt.Span = p.span(start)
+	return stmt
+}
@@ -0,0 +1,748 @@
+package sqlparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Operator precedence, lowest to highest, following the PostgreSQL manual
+const (
+	precLowest = iota
+	precOr
+	precAnd
+	precNot
+	precIs         // IS, ISNULL, NOTNULL
+	precComparison // < > = <= >= <>
+	precLike       // BETWEEN, IN, LIKE, ILIKE, SIMILAR
+	precOther      // all other operators
+	precAdd        // + -
+	precMul        // * / %
+	precExp        // ^
+	precAtTimeZone
+	precCollate
+	precUnary
+	precSubscript // [ ] and field selection
+	precCast      // ::
+)
+
+// parseExpr parses a complete expression
+func (p *parser) parseExpr() Expr {
+	return p.parseExprPrec(precLowest)
+}
+
+// parseExprPrec parses an expression, consuming only infix operators that
+// bind tighter than prec
+func (p *parser) parseExprPrec(prec int) Expr {
+	left := p.parsePrefix()
+	for {
+		opPrec, ok := p.infixPrec()
+		if !ok || opPrec <= prec {
+			return left
+		}
+		left = p.parseInfix(left, opPrec)
+	}
+}
+
+// parseExprList parses a comma-separated list of expressions
+func (p *parser) parseExprList() []Expr {
+	var exprs []Expr
+	for {
+		exprs = append(exprs, p.parseExpr())
+		if !p.accept(TokenComma) {
+			return exprs
+		}
+	}
+}
+
+// infixPrec returns the precedence of the infix or postfix operator at the
+// current position
+func (p *parser) infixPrec() (int, bool) {
+	tok := p.peek()
+	switch tok.Kind {
+	case TokenOperator:
+		switch tok.Text {
+		case "::":
+			return precCast, true
+		case "=", "<", ">", "<=", ">=", "<>", "!=":
+			return precComparison, true
+		case "+", "-":
+			return precAdd, true
+		case "*", "/", "%":
+			return precMul, true
+		case "^":
+			return precExp, true
+		case ":=":
+			return 0, false
+		default:
+			return precOther, true
+		}
+	case TokenLBracket, TokenDot:
+		return precSubscript, true
+	case TokenIdent:
+		switch tok.Keyword() {
+		case "OR":
+			return precOr, true
+		case "AND":
+			return precAnd, true
+		case "IS", "ISNULL", "NOTNULL":
+			return precIs, true
+		case "IN", "LIKE", "ILIKE", "SIMILAR", "BETWEEN":
+			return precLike, true
+		case "NOT":
+			switch p.peekAt(1).Keyword() {
+			case "IN", "LIKE", "ILIKE", "SIMILAR", "BETWEEN":
+				return precLike, true
+			}
+		case "AT":
+			if p.isKeywordAt(1, "TIME") {
+				return precAtTimeZone, true
+			}
+		case "COLLATE":
+			return precCollate, true
+		}
+	}
+	return 0, false
+}
+
+// parseInfix parses the operator at the current position applied to left
+func (p *parser) parseInfix(left Expr, prec int) Expr {
+	start := left.Pos()
+	tok := p.peek()
+
+	switch tok.Kind {
+	case TokenOperator:
+		p.next()
+		if tok.Text == "::" {
+			typ := p.parseTypeName()
+			return &CastExpr{Span: p.span(start), Expr: left, Type: typ, Syntax: "::"}
+		}
+		right := p.parseExprPrec(prec)
+		return &BinaryExpr{Span: p.span(start), Op: tok.Text, Left: left, Right: right}
+
+	case TokenLBracket:
+		p.next()
+		sub := &SubscriptExpr{Expr: left}
+		if !p.is(TokenColon) {
+			sub.Index = p.parseExpr()
+		}
+		if p.accept(TokenColon) {
+			sub.Slice = true
+			if !p.is(TokenRBracket) {
+				sub.Upper = p.parseExpr()
+			}
+		}
+		p.expect(TokenRBracket)
+		sub.Span = p.span(start)
+		return sub
+
+	case TokenDot:
+		p.next()
+		if p.acceptOp("*") {
+			return &FieldExpr{Span: p.span(start), Expr: left, Field: "*"}
+		}
+		field := p.anyIdent()
+		return &FieldExpr{Span: p.span(start), Expr: left, Field: field}
+	}
+
+	switch tok.Keyword() {
+	case "AND", "OR":
+		p.next()
+		right := p.parseExprPrec(prec)
+		return &BinaryExpr{Span: p.span(start), Op: tok.Keyword(), Left: left, Right: right}
+
+	case "IS":
+		p.next()
+		is := &IsExpr{Expr: left, Not: p.acceptKeyword("NOT")}
+		switch kw := p.peek().Keyword(); kw {
+		case "NULL", "TRUE", "FALSE", "UNKNOWN", "DOCUMENT":
+			p.next()
+			is.Test = kw
+		case "DISTINCT":
+			p.next()
+			p.expectKeyword("FROM")
+			is.Test = "DISTINCT FROM"
+			is.Right = p.parseExprPrec(precIs)
+		case "NFC", "NFD", "NFKC", "NFKD", "NORMALIZED":
+			p.acceptKeyword(kw)
+			p.acceptKeyword("NORMALIZED")
+			is.Test = "NORMALIZED"
+		default:
+			p.unexpected()
+		}
+		is.Span = p.span(start)
+		return is
+
+	case "ISNULL", "NOTNULL":
+		p.next()
+		return &IsExpr{Span: p.span(start), Expr: left, Not: tok.Keyword() == "NOTNULL", Test: "NULL"}
+
+	case "AT":
+		p.next()
+		p.expectKeyword("TIME")
+		p.expectKeyword("ZONE")
+		right := p.parseExprPrec(precAtTimeZone)
+		return &BinaryExpr{Span: p.span(start), Op: "AT TIME ZONE", Left: left, Right: right}
+
+	case "COLLATE":
+		p.next()
+		name := p.qualifiedName()
+		return &CollateExpr{Span: p.span(start), Expr: left, Collation: name.String()}
+	}
+
+	// [NOT] IN / LIKE / ILIKE / SIMILAR TO / BETWEEN
+	not := p.acceptKeyword("NOT")
+	switch kw := p.next().Keyword(); kw {
+	case "IN":
+		in := &InExpr{Expr: left, Not: not}
+		p.expect(TokenLParen)
+		if p.isQueryStart(0) {
+			in.Query = p.parseSelect()
+		} else {
+			in.List = p.parseExprList()
+		}
+		p.expect(TokenRParen)
+		in.Span = p.span(start)
+		return in
+
+	case "LIKE", "ILIKE", "SIMILAR":
+		op := kw
+		if kw == "SIMILAR" {
+			p.expectKeyword("TO")
+			op = "SIMILAR TO"
+		}
+		like := &LikeExpr{Op: op, Not: not, Expr: left, Pattern: p.parseExprPrec(precLike)}
+		if p.acceptKeyword("ESCAPE") {
+			like.Escape = p.parseExprPrec(precLike)
+		}
+		like.Span = p.span(start)
+		return like
+
+	case "BETWEEN":
+		between := &BetweenExpr{Expr: left, Not: not}
+		if p.acceptKeyword("SYMMETRIC") {
+			between.Symmetric = true
+		} else {
+			p.acceptKeyword("ASYMMETRIC")
+		}
+		between.Low = p.parseExprPrec(precLike)
+		p.expectKeyword("AND")
+		between.High = p.parseExprPrec(precLike)
+		between.Span = p.span(start)
+		return between
+	}
+
+	p.errorf(tok, "syntax error at or near %s", tok)
+	return nil
+}
+
+// parsePrefix parses prefix operators and primary expressions
+func (p *parser) parsePrefix() Expr {
+	tok := p.peek()
+
+	if tok.Keyword() == "NOT" {
+		p.next()
+		operand := p.parseExprPrec(precNot)
+		return &UnaryExpr{Span: p.span(tok.Pos), Op: "NOT", Expr: operand}
+	}
+
+	if tok.Kind == TokenOperator && tok.Text != "*" && tok.Text != "::" {
+		p.next()
+		prec := precOther
+		if tok.Text == "-" || tok.Text == "+" {
+			prec = precUnary
+		}
+		operand := p.parseExprPrec(prec)
+
+		// Fold signs into numeric literals so -1 is a constant
+		if lit, ok := operand.(*Literal); ok && lit.Kind == LiteralNumber && tok.Text == "-" {
+			lit.Value = "-" + lit.Value
+			lit.Span = p.span(tok.Pos)
+			return lit
+		}
+		return &UnaryExpr{Span: p.span(tok.Pos), Op: tok.Text, Expr: operand}
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary parses literals, column references, function calls,
+// subqueries and the special expression forms
+func (p *parser) parsePrimary() Expr {
+	tok := p.peek()
+	start := tok.Pos
+
+	switch tok.Kind {
+	case TokenNumber:
+		p.next()
+		return &Literal{Span: p.span(start), Kind: LiteralNumber, Value: tok.Value}
+
+	case TokenString:
+		p.next()
+		return &Literal{Span: p.span(start), Kind: LiteralString, Value: tok.Value}
+
+	case TokenParam:
+		p.next()
+		n, _ := strconv.Atoi(tok.Value)
+		return &Param{Span: p.span(start), Number: n}
+
+	case TokenLParen:
+		if p.isQueryStart(1) {
+			p.next()
+			query := p.parseSelect()
+			p.expect(TokenRParen)
+			return &SubqueryExpr{Span: p.span(start), Query: query}
+		}
+		p.next()
+		first := p.parseExpr()
+		if p.accept(TokenComma) {
+			row := &RowExpr{Elems: append([]Expr{first}, p.parseExprList()...)}
+			p.expect(TokenRParen)
+			row.Span = p.span(start)
+			return row
+		}
+		p.expect(TokenRParen)
+		if n, ok := first.(interface{ setSpan(Span) }); ok {
+			n.setSpan(p.span(start))
+		}
+		return first
+
+	case TokenOperator:
+		if tok.Text == "*" {
+			p.next()
+			return &ColumnRef{Span: p.span(start), Star: true}
+		}
+
+	case TokenQuotedIdent:
+		return p.parseNameExpr()
+
+	case TokenIdent:
+		return p.parseKeywordExpr()
+	}
+
+	p.unexpected()
+	return nil
+}
+
+// parseKeywordExpr parses an expression starting with an unquoted word:
+// constants, special forms, typed literals, column references and calls
+func (p *parser) parseKeywordExpr() Expr {
+	tok := p.peek()
+	start := tok.Pos
+	kw := tok.Keyword()
+	nextTok := p.peekAt(1)
+
+	switch kw {
+	case "NULL":
+		p.next()
+		return &Literal{Span: p.span(start), Kind: LiteralNull, Value: "null"}
+
+	case "TRUE", "FALSE":
+		p.next()
+		return &Literal{Span: p.span(start), Kind: LiteralBool, Value: strings.ToLower(kw)}
+
+	case "DEFAULT":
+		p.next()
+		return &DefaultExpr{Span: p.span(start)}
+
+	case "CASE":
+		return p.parseCase()
+
+	case "CAST":
+		p.next()
+		p.expect(TokenLParen)
+		expr := p.parseExpr()
+		p.expectKeyword("AS")
+		typ := p.parseTypeName()
+		p.expect(TokenRParen)
+		return &CastExpr{Span: p.span(start), Expr: expr, Type: typ, Syntax: "CAST"}
+
+	case "EXISTS":
+		if nextTok.Kind == TokenLParen {
+			p.next()
+			p.next()
+			query := p.parseSelect()
+			p.expect(TokenRParen)
+			return &SubqueryExpr{Span: p.span(start), Exists: true, Query: query}
+		}
+
+	case "ARRAY":
+		p.next()
+		if p.accept(TokenLParen) {
+			query := p.parseSelect()
+			p.expect(TokenRParen)
+			return &ArrayExpr{Span: p.span(start), Query: query}
+		}
+		return p.parseArrayElems(start)
+
+	case "ROW":
+		if nextTok.Kind == TokenLParen {
+			p.next()
+			p.next()
+			row := &RowExpr{}
+			if !p.is(TokenRParen) {
+				row.Elems = p.parseExprList()
+			}
+			p.expect(TokenRParen)
+			row.Span = p.span(start)
+			return row
+		}
+
+	case "ANY", "SOME", "ALL":
+		if nextTok.Kind == TokenLParen {
+			p.next()
+			p.next()
+			q := &QuantifiedExpr{Quantifier: kw}
+			if p.isQueryStart(0) {
+				q.Query = p.parseSelect()
+			} else {
+				q.Expr = p.parseExpr()
+			}
+			p.expect(TokenRParen)
+			q.Span = p.span(start)
+			return q
+		}
+
+	case "INTERVAL":
+		if nextTok.Kind == TokenString {
+			p.next()
+			value := p.next()
+			typ := &TypeName{Span: Span{From: start, To: tok.End}, Name: "interval"}
+			p.parseIntervalFields(typ)
+			lit := &Literal{Span: Span{From: value.Pos, To: value.End}, Kind: LiteralString, Value: value.Value}
+			return &CastExpr{Span: p.span(start), Expr: lit, Type: typ, Syntax: "PREFIX"}
+		}
+
+	case "CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP", "LOCALTIME", "LOCALTIMESTAMP",
+		"CURRENT_USER", "SESSION_USER", "USER", "CURRENT_ROLE", "CURRENT_CATALOG", "SYSTEM_USER":
+		p.next()
+		fc := &FuncCall{Name: strings.ToLower(kw), NoParens: true}
+		if p.is(TokenLParen) && (kw == "CURRENT_TIME" || kw == "CURRENT_TIMESTAMP" ||
+			kw == "LOCALTIME" || kw == "LOCALTIMESTAMP") {
+			p.next()
+			fc.NoParens = false
+			fc.Args = p.parseExprList()
+			p.expect(TokenRParen)
+		}
+		fc.Span = p.span(start)
+		return fc
+
+	case "CURRENT_SCHEMA":
+		if nextTok.Kind != TokenLParen {
+			p.next()
+			return &FuncCall{Span: p.span(start), Name: "current_schema", NoParens: true}
+		}
+
+	case "EXTRACT", "SUBSTRING", "POSITION", "TRIM", "OVERLAY":
+		if nextTok.Kind == TokenLParen {
+			return p.parseSpecialFunc()
+		}
+	}
+
+	// Typed literals such as DATE '2024-01-01' or TIMESTAMP '...'
+	if nextTok.Kind == TokenString && !reserved[kw] {
+		p.next()
+		value := p.next()
+		typ := &TypeName{Span: Span{From: start, To: tok.End}, Name: tok.Value}
+		lit := &Literal{Span: Span{From: value.Pos, To: value.End}, Kind: LiteralString, Value: value.Value}
+		return &CastExpr{Span: p.span(start), Expr: lit, Type: typ, Syntax: "PREFIX"}
+	}
+
+	if reserved[kw] && !(typeFuncNames[kw] && nextTok.Kind == TokenLParen) {
+		p.unexpected()
+	}
+	return p.parseNameExpr()
+}
+
+// parseNameExpr parses a column reference, wildcard or function call
+func (p *parser) parseNameExpr() Expr {
+	start := p.peek().Pos
+	parts := []string{p.next().Value}
+	for p.is(TokenDot) {
+		p.next()
+		if p.acceptOp("*") {
+			return &ColumnRef{Span: p.span(start), Fields: parts, Star: true}
+		}
+		parts = append(parts, p.anyIdent())
+	}
+
+	if p.is(TokenLParen) {
+		schema := ""
+		if len(parts) > 1 {
+			schema = parts[len(parts)-2]
+		}
+		return p.parseFuncCall(start, schema, parts[len(parts)-1])
+	}
+	return &ColumnRef{Span: p.span(start), Fields: parts}
+}
+
+// parseFuncCall parses the argument list and trailing clauses of a call
+// whose name has already been consumed
+func (p *parser) parseFuncCall(start Pos, schema, name string) *FuncCall {
+	fc := &FuncCall{Schema: schema, Name: name}
+	p.expect(TokenLParen)
+
+	switch {
+	case p.acceptOp("*"):
+		fc.Star = true
+	case !p.is(TokenRParen):
+		if p.acceptKeyword("DISTINCT") {
+			fc.Distinct = true
+		} else {
+			p.acceptKeyword("ALL")
+		}
+		p.acceptKeyword("VARIADIC")
+		fc.Args = p.parseExprList()
+		if p.acceptKeywords("ORDER", "BY") {
+			fc.OrderBy = p.parseOrderList()
+		}
+	}
+	p.expect(TokenRParen)
+
+	if p.acceptKeywords("WITHIN", "GROUP") {
+		p.expect(TokenLParen)
+		p.expectKeyword("ORDER")
+		p.expectKeyword("BY")
+		fc.OrderBy = p.parseOrderList()
+		fc.WithinGroup = true
+		p.expect(TokenRParen)
+	}
+	if p.isKeyword("FILTER") && p.peekAt(1).Kind == TokenLParen {
+		p.next()
+		p.next()
+		p.expectKeyword("WHERE")
+		fc.Filter = p.parseExpr()
+		p.expect(TokenRParen)
+	}
+	if p.acceptKeyword("OVER") {
+		if p.is(TokenLParen) {
+			fc.Over = p.parseWindowSpec()
+		} else {
+			nameStart := p.peek().Pos
+			fc.Over = &WindowSpec{Ref: p.ident()}
+			fc.Over.Span = p.span(nameStart)
+		}
+	}
+
+	fc.Span = p.span(start)
+	return fc
+}
+
+// parseWindowSpec parses ( [existing] [PARTITION BY ...] [ORDER BY ...] [frame] )
+func (p *parser) parseWindowSpec() *WindowSpec {
+	start := p.expect(TokenLParen).Pos
+	spec := &WindowSpec{}
+
+	if p.isIdent() && !p.isKeyword("PARTITION", "ORDER", "ROWS", "RANGE", "GROUPS") {
+		spec.Ref = p.ident()
+	}
+	if p.acceptKeywords("PARTITION", "BY") {
+		spec.PartitionBy = p.parseExprList()
+	}
+	if p.acceptKeywords("ORDER", "BY") {
+		spec.OrderBy = p.parseOrderList()
+	}
+	if p.isKeyword("ROWS", "RANGE", "GROUPS") {
+		frameStart := p.peek().Pos
+		for !p.is(TokenRParen) && !p.is(TokenEOF) {
+			if p.is(TokenLParen) {
+				p.skipParens()
+				continue
+			}
+			p.next()
+		}
+		spec.Frame = p.textFrom(frameStart)
+	}
+
+	p.expect(TokenRParen)
+	spec.Span = p.span(start)
+	return spec
+}
+
+// parseSpecialFunc parses EXTRACT, SUBSTRING, POSITION, TRIM and OVERLAY,
+// whose arguments may be separated by keywords instead of commas
+func (p *parser) parseSpecialFunc() Expr {
+	nameTok := p.next()
+	fc := &FuncCall{Name: nameTok.Value}
+	p.expect(TokenLParen)
+
+	if nameTok.Keyword() == "EXTRACT" {
+		field := p.next()
+		fc.Args = append(fc.Args, &Literal{Span: Span{From: field.Pos, To: field.End}, Kind: LiteralString, Value: field.Value})
+		p.expectKeyword("FROM")
+		fc.Args = append(fc.Args, p.parseExpr())
+		p.expect(TokenRParen)
+		fc.Span = p.span(nameTok.Pos)
+		return fc
+	}
+
+	if nameTok.Keyword() == "TRIM" {
+		for p.isKeyword("BOTH", "LEADING", "TRAILING") {
+			p.next()
+		}
+		p.acceptKeyword("FROM")
+	}
+
+	for !p.is(TokenRParen) {
+		// Stop below IN so POSITION(a IN b) splits at IN
+		fc.Args = append(fc.Args, p.parseExprPrec(precLike))
+		if !p.accept(TokenComma) && !p.acceptKeyword("FROM") && !p.acceptKeyword("FOR") &&
+			!p.acceptKeyword("IN") && !p.acceptKeyword("PLACING") {
+			break
+		}
+	}
+	p.expect(TokenRParen)
+
+	fc.Span = p.span(nameTok.Pos)
+	return fc
+}
+
+// parseCase parses CASE [operand] WHEN ... THEN ... [ELSE ...] END
+func (p *parser) parseCase() Expr {
+	start := p.expectKeyword("CASE").Pos
+	c := &CaseExpr{}
+	if !p.isKeyword("WHEN") {
+		c.Operand = p.parseExpr()
+	}
+	for p.isKeyword("WHEN") {
+		whenStart := p.next().Pos
+		when := &CaseWhen{Cond: p.parseExpr()}
+		p.expectKeyword("THEN")
+		when.Result = p.parseExpr()
+		when.Span = p.span(whenStart)
+		c.Whens = append(c.Whens, when)
+	}
+	if len(c.Whens) == 0 {
+		p.errorf(p.peek(), "syntax error at or near %s, expected WHEN", p.peek())
+	}
+	if p.acceptKeyword("ELSE") {
+		c.Else = p.parseExpr()
+	}
+	p.expectKeyword("END")
+	c.Span = p.span(start)
+	return c
+}
+
+// parseArrayElems parses [elem, ...] where elements may be nested brackets
+func (p *parser) parseArrayElems(start Pos) *ArrayExpr {
+	p.expect(TokenLBracket)
+	arr := &ArrayExpr{}
+	for !p.is(TokenRBracket) {
+		if p.is(TokenLBracket) {
+			arr.Elems = append(arr.Elems, p.parseArrayElems(p.peek().Pos))
+		} else {
+			arr.Elems = append(arr.Elems, p.parseExpr())
+		}
+		if !p.accept(TokenComma) {
+			break
+		}
+	}
+	p.expect(TokenRBracket)
+	arr.Span = p.span(start)
+	return arr
+}
+
+// parseTypeName parses a data type
+func (p *parser) parseTypeName() *TypeName {
+	start := p.peek().Pos
+	t := &TypeName{SetOf: p.acceptKeyword("SETOF")}
+
+	tok := p.peek()
+	if tok.Kind != TokenIdent && tok.Kind != TokenQuotedIdent {
+		p.unexpected()
+	}
+
+	switch tok.Keyword() {
+	case "DOUBLE":
+		p.next()
+		p.expectKeyword("PRECISION")
+		t.Name = "double precision"
+	case "CHARACTER", "CHAR", "NATIONAL", "NCHAR", "BIT":
+		words := []string{p.next().Value}
+		if tok.Keyword() == "NATIONAL" && p.isKeyword("CHARACTER", "CHAR") {
+			words = append(words, p.next().Value)
+		}
+		if p.isKeyword("VARYING") {
+			words = append(words, p.next().Value)
+		}
+		t.Name = strings.Join(words, " ")
+	case "TIMESTAMP", "TIME":
+		t.Name = p.next().Value
+		if p.is(TokenLParen) {
+			t.Modifiers = p.parseTypeModifiers()
+		}
+		if p.acceptKeywords("WITH", "TIME", "ZONE") {
+			t.Name += " with time zone"
+		} else if p.acceptKeywords("WITHOUT", "TIME", "ZONE") {
+			t.Name += " without time zone"
+		}
+	case "INTERVAL":
+		t.Name = p.next().Value
+		p.parseIntervalFields(t)
+	default:
+		name := p.next().Value
+		if p.is(TokenDot) {
+			p.next()
+			t.Schema = name
+			name = p.anyIdent()
+		}
+		t.Name = name
+	}
+
+	if p.is(TokenLParen) && t.Modifiers == nil {
+		t.Modifiers = p.parseTypeModifiers()
+	}
+
+	for {
+		if p.accept(TokenLBracket) {
+			if p.is(TokenNumber) {
+				p.next()
+			}
+			p.expect(TokenRBracket)
+			t.ArrayDims++
+			continue
+		}
+		if p.acceptKeyword("ARRAY") {
+			t.ArrayDims++
+			if p.accept(TokenLBracket) {
+				if p.is(TokenNumber) {
+					p.next()
+				}
+				p.expect(TokenRBracket)
+			}
+			continue
+		}
+		break
+	}
+
+	if p.isOp("%") && p.isKeywordAt(1, "TYPE") {
+		p.next()
+		p.next()
+		t.Name += "%type"
+	}
+
+	t.Span = p.span(start)
+	return t
+}
+
+// parseTypeModifiers parses (n[, m]) after a type name
+func (p *parser) parseTypeModifiers() []Expr {
+	p.expect(TokenLParen)
+	mods := p.parseExprList()
+	p.expect(TokenRParen)
+	return mods
+}
+
+// parseIntervalFields parses the optional field restriction of an interval
+// type, such as DAY TO SECOND, and its precision
+func (p *parser) parseIntervalFields(t *TypeName) {
+	fields := []string{}
+	for p.isKeyword("YEAR", "MONTH", "DAY", "HOUR", "MINUTE", "SECOND", "TO") {
+		fields = append(fields, p.next().Value)
+	}
+	if len(fields) > 0 {
+		t.Name += " " + strings.Join(fields, " ")
+	}
+	if p.is(TokenLParen) {
+		t.Modifiers = p.parseTypeModifiers()
+	}
+}
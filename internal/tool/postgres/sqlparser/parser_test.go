@@ -0,0 +1,414 @@
+package sqlparser
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// TestParse_Golden parses every testdata/*.sql file and compares a dump of
+// the resulting AST with the matching .golden file. Run with -update to
+// rewrite the golden files after an intended change.
+func TestParse_Golden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata/*.sql files found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			script, err := Parse(string(src))
+			got := dumpScript(script, err)
+
+			golden := strings.TrimSuffix(file, ".sql") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("AST mismatch for %s (run with -update to accept)\n%s", file, firstDiff(string(want), got))
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // "kind:value"
+	}{
+		{
+			name: "keywords and identifiers",
+			src:  `SELECT "Mixed" FROM t`,
+			want: []string{"Ident:select", "QuotedIdent:Mixed", "Ident:from", "Ident:t"},
+		},
+		{
+			name: "strings",
+			src:  `'it''s' E'a\nb' $$x;y$$ $tag$z$tag$`,
+			want: []string{"String:it's", "String:a\nb", "String:x;y", "String:z"},
+		},
+		{
+			name: "numbers and params",
+			src:  `1 1.5 .5 1e10 0xFF 1_000 $1`,
+			want: []string{"Number:1", "Number:1.5", "Number:.5", "Number:1e10", "Number:0xFF", "Number:1000", "Param:1"},
+		},
+		{
+			name: "operators",
+			src:  `a::int >= b || c @> d->>'k'`,
+			want: []string{"Ident:a", "Operator:::", "Ident:int", "Operator:>=", "Ident:b", "Operator:||",
+				"Ident:c", "Operator:@>", "Ident:d", "Operator:->>", "String:k"},
+		},
+		{
+			name: "operator stops before comment",
+			src:  "a=--x\nb",
+			want: []string{"Ident:a", "Operator:=", "Comment:", "Ident:b"},
+		},
+		{
+			name: "nested block comment",
+			src:  "/* a /* b */ c */ x",
+			want: []string{"Comment:", "Ident:x"},
+		},
+		{
+			name: "field selection is not a number",
+			src:  "t.5",
+			want: []string{"Ident:t", "Dot:.", "Number:5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.src)
+			if err != nil {
+				t.Fatalf("Tokenize() error = %v", err)
+			}
+			var got []string
+			for _, tok := range tokens {
+				if tok.Kind == TokenEOF {
+					break
+				}
+				got = append(got, tok.Kind.String()+":"+tok.Value)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenize_Positions(t *testing.T) {
+	tokens, err := Tokenize("SELECT 1\n  FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := tokens[2]
+	if from.Pos.Line != 2 || from.Pos.Column != 3 || from.Pos.Offset != 11 {
+		t.Errorf("FROM position = %+v, want line 2, column 3, offset 11", from.Pos)
+	}
+}
+
+func TestTokenize_Unterminated(t *testing.T) {
+	for _, src := range []string{"SELECT 'abc", `SELECT "abc`, "SELECT /* abc", "SELECT $$abc"} {
+		_, err := Tokenize(src)
+		var perr *Error
+		if !errors.As(err, &perr) {
+			t.Errorf("Tokenize(%q) error = %v, want *Error", src, err)
+			continue
+		}
+		if perr.Pos.Line != 1 || perr.Pos.Column != 8 {
+			t.Errorf("Tokenize(%q) error position = %+v, want 1:8", src, perr.Pos)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantLine int
+		wantCol  int
+		wantMsg  string
+	}{
+		{name: "unknown statement", src: "SELEC 1", wantLine: 1, wantCol: 1, wantMsg: "SELEC"},
+		{name: "missing table", src: "SELECT *\nFROM WHERE id = 1", wantLine: 2, wantCol: 6, wantMsg: "WHERE"},
+		{name: "unbalanced parens", src: "SELECT (1 + 2", wantLine: 1, wantCol: 14, wantMsg: "end of input"},
+		{name: "join without condition", src: "SELECT 1 FROM a JOIN b WHERE true", wantLine: 1, wantCol: 24, wantMsg: "ON or USING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := Parse(tt.src)
+			if err == nil {
+				t.Fatal("Parse() expected error")
+			}
+			var list ErrorList
+			if !errors.As(err, &list) || len(list) == 0 {
+				t.Fatalf("Parse() error = %T, want ErrorList", err)
+			}
+			first := list[0]
+			if first.Pos.Line != tt.wantLine || first.Pos.Column != tt.wantCol {
+				t.Errorf("error position = %d:%d, want %d:%d (%v)", first.Pos.Line, first.Pos.Column, tt.wantLine, tt.wantCol, first)
+			}
+			if !strings.Contains(first.Msg, tt.wantMsg) {
+				t.Errorf("error message = %q, want it to contain %q", first.Msg, tt.wantMsg)
+			}
+			if len(script.Statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(script.Statements))
+			}
+			if _, ok := script.Statements[0].(*BadStmt); !ok {
+				t.Errorf("statement = %T, want *BadStmt", script.Statements[0])
+			}
+		})
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "a + b * c", want: "(a + (b * c))"},
+		{src: "a OR b AND NOT c", want: "(a OR (b AND (NOT c)))"},
+		{src: "x BETWEEN 1 AND 2 AND y", want: "((x BETWEEN 1 AND 2) AND y)"},
+		{src: "-x::int", want: "(- x::int)"},
+		{src: "a = ANY($1)", want: "(a = ANY($1))"},
+		{src: "a IS NOT DISTINCT FROM b", want: "(a IS NOT DISTINCT FROM b)"},
+		{src: "name NOT ILIKE 'a%'", want: "(name NOT ILIKE 'a%')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			expr, err := ParseExpr(tt.src)
+			if err != nil {
+				t.Fatalf("ParseExpr() error = %v", err)
+			}
+			if got := formatExpr(expr); got != tt.want {
+				t.Errorf("ParseExpr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpr_ParenthesisedSpans(t *testing.T) {
+	src := "(a + b) * c"
+	expr, err := ParseExpr(src)
+	if err != nil {
+		t.Fatalf("ParseExpr() error = %v", err)
+	}
+	bin := expr.(*BinaryExpr)
+	if got := Text(src, bin); got != src {
+		t.Errorf("Text(expr) = %q, want %q", got, src)
+	}
+	if got := Text(src, bin.Left); got != "(a + b)" {
+		t.Errorf("Text(left) = %q, want %q", got, "(a + b)")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	script, err := Parse(`SELECT a FROM t1 JOIN t2 ON t1.id = t2.id WHERE b IN (SELECT c FROM t3)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tables []string
+	InspectScript(script, func(n Node) bool {
+		if tn, ok := n.(*TableName); ok {
+			tables = append(tables, tn.Name.Name)
+		}
+		return true
+	})
+	if want := []string{"t1", "t2", "t3"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("tables = %v, want %v", tables, want)
+	}
+
+	// Returning false prunes the subtree
+	tables = nil
+	InspectScript(script, func(n Node) bool {
+		if _, ok := n.(*InExpr); ok {
+			return false
+		}
+		if tn, ok := n.(*TableName); ok {
+			tables = append(tables, tn.Name.Name)
+		}
+		return true
+	})
+	if want := []string{"t1", "t2"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("tables with pruning = %v, want %v", tables, want)
+	}
+}
+
+// formatExpr renders an expression with explicit parentheses for precedence tests
+func formatExpr(e Expr) string {
+	switch n := e.(type) {
+	case *BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", formatExpr(n.Left), n.Op, formatExpr(n.Right))
+	case *UnaryExpr:
+		return fmt.Sprintf("(%s %s)", n.Op, formatExpr(n.Expr))
+	case *ColumnRef:
+		return strings.Join(n.Fields, ".")
+	case *Literal:
+		if n.Kind == LiteralString {
+			return "'" + n.Value + "'"
+		}
+		return n.Value
+	case *Param:
+		return fmt.Sprintf("$%d", n.Number)
+	case *CastExpr:
+		return fmt.Sprintf("%s::%s", formatExpr(n.Expr), n.Type)
+	case *BetweenExpr:
+		return fmt.Sprintf("(%s BETWEEN %s AND %s)", formatExpr(n.Expr), formatExpr(n.Low), formatExpr(n.High))
+	case *QuantifiedExpr:
+		return fmt.Sprintf("%s(%s)", n.Quantifier, formatExpr(n.Expr))
+	case *IsExpr:
+		not := ""
+		if n.Not {
+			not = "NOT "
+		}
+		if n.Right != nil {
+			return fmt.Sprintf("(%s IS %s%s %s)", formatExpr(n.Expr), not, n.Test, formatExpr(n.Right))
+		}
+		return fmt.Sprintf("(%s IS %s%s)", formatExpr(n.Expr), not, n.Test)
+	case *LikeExpr:
+		op := n.Op
+		if n.Not {
+			op = "NOT " + op
+		}
+		return fmt.Sprintf("(%s %s %s)", formatExpr(n.Expr), op, formatExpr(n.Pattern))
+	}
+	return fmt.Sprintf("<%T>", e)
+}
+
+// dumpScript renders a parsed script as an indented tree for golden files
+func dumpScript(script *Script, err error) string {
+	var b strings.Builder
+	if script != nil {
+		for _, stmt := range script.Statements {
+			dumpValue(&b, reflect.ValueOf(stmt), 0)
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Comments: %d\n", len(script.Comments))
+	}
+	if err != nil {
+		var list ErrorList
+		if errors.As(err, &list) {
+			for _, e := range list {
+				fmt.Fprintf(&b, "Error: %s\n", e)
+			}
+		} else {
+			fmt.Fprintf(&b, "Error: %v\n", err)
+		}
+	}
+	return b.String()
+}
+
+var (
+	spanType  = reflect.TypeOf(Span{})
+	posType   = reflect.TypeOf(Pos{})
+	tokenType = reflect.TypeOf(Token{})
+)
+
+// dumpValue writes v, which is a node, a value inside a node or a slice
+func dumpValue(b *strings.Builder, v reflect.Value, indent int) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		switch v.Type() {
+		case posType:
+			p := v.Interface().(Pos)
+			fmt.Fprintf(b, "%d:%d", p.Line, p.Column)
+			return
+		case tokenType:
+			fmt.Fprintf(b, "%q", v.Interface().(Token).Text)
+			return
+		}
+		b.WriteString(v.Type().Name())
+		if f := v.FieldByName("Span"); f.IsValid() && f.Type() == spanType {
+			s := f.Interface().(Span)
+			fmt.Fprintf(b, " @%d:%d-%d:%d", s.From.Line, s.From.Column, s.To.Line, s.To.Column)
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			fv := v.Field(i)
+			if !field.IsExported() || field.Type == spanType || fv.IsZero() {
+				continue
+			}
+			b.WriteString("\n")
+			b.WriteString(strings.Repeat("  ", indent+1))
+			b.WriteString(field.Name)
+			b.WriteString(":")
+			if fv.Kind() != reflect.Slice || fv.Type().Elem().Kind() == reflect.String {
+				b.WriteString(" ")
+			}
+			dumpValue(b, fv, indent+1)
+		}
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			fmt.Fprintf(b, "%q", v.Interface())
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			b.WriteString("\n")
+			b.WriteString(strings.Repeat("  ", indent+1))
+			b.WriteString("-")
+			if elem := v.Index(i); elem.Kind() != reflect.Slice {
+				b.WriteString(" ")
+			}
+			dumpValue(b, v.Index(i), indent+2)
+		}
+
+	case reflect.String:
+		fmt.Fprintf(b, "%q", v.String())
+
+	default:
+		fmt.Fprintf(b, "%v", v.Interface())
+	}
+}
+
+// firstDiff reports the first line that differs between want and got
+func firstDiff(want, got string) string {
+	wl := strings.Split(want, "\n")
+	gl := strings.Split(got, "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return ""
+}
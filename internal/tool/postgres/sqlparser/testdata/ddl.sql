@@ -0,0 +1,52 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status VARCHAR(32) NOT NULL DEFAULT 'new',
+    total NUMERIC(12, 2) CHECK (total >= 0),
+    tags TEXT[],
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now() NOT NULL,
+    CONSTRAINT orders_status_check CHECK (status IN ('new', 'paid')),
+    UNIQUE (user_id, created_at)
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_orders_user
+    ON orders USING btree (user_id, lower(status) DESC NULLS FIRST)
+    INCLUDE (total)
+    WHERE status <> 'cancelled';
+
+CREATE OR REPLACE VIEW active_orders AS
+    SELECT * FROM orders WHERE status = 'new';
+
+CREATE MATERIALIZED VIEW order_totals AS
+    SELECT user_id, sum(total) FROM orders GROUP BY user_id
+    WITH NO DATA;
+
+ALTER TABLE orders
+    ADD COLUMN note TEXT,
+    ALTER COLUMN status TYPE TEXT USING status::text,
+    ALTER COLUMN total SET DEFAULT 0,
+    DROP COLUMN IF EXISTS legacy CASCADE,
+    ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID;
+
+ALTER TABLE orders VALIDATE CONSTRAINT orders_user_fk;
+ALTER TABLE orders RENAME COLUMN note TO notes;
+ALTER TABLE orders RENAME TO purchases;
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_orders_user;
+DROP TABLE IF EXISTS old_orders, older_orders CASCADE;
+DROP TRIGGER set_updated_at ON purchases;
+TRUNCATE TABLE purchases RESTART IDENTITY;
+
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON TABLE purchases IS 'Customer purchases';
+VACUUM FULL purchases;
+
+COMMIT;
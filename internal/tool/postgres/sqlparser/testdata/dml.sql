@@ -0,0 +1,22 @@
+INSERT INTO users (email, name) VALUES ($1, $2)
+ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name
+RETURNING id;
+
+INSERT INTO audit SELECT * FROM events WHERE created_at < '2024-01-01'::date;
+
+INSERT INTO counters DEFAULT VALUES;
+
+UPDATE accounts a
+SET balance = a.balance - t.amount, updated_at = DEFAULT
+FROM transfers t
+WHERE t.account_id = a.id AND t.state = 'pending';
+
+UPDATE sessions SET expired = true;
+
+DELETE FROM sessions USING users
+WHERE sessions.user_id = users.id AND users.deleted_at IS NOT NULL
+RETURNING sessions.id;
+
+DELETE FROM logs;
+
+EXPLAIN (ANALYZE, BUFFERS) SELECT * FROM logs WHERE level = 'error';
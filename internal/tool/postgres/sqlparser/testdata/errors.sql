@@ -0,0 +1,5 @@
+SELECT id FROM users;
+SELEC name FROM users;
+SELECT * FROM WHERE id = 1;
+INSERT INTO t (a VALUES (1);
+SELECT 'unterminated
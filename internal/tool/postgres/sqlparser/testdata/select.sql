@@ -0,0 +1,38 @@
+-- Simple and qualified selects
+SELECT * FROM users WHERE email = 'user@example.com';
+
+SELECT u.id, u.name AS display_name, count(*) total
+FROM public.users u
+JOIN orders o ON o.user_id = u.id
+LEFT OUTER JOIN addresses a USING (user_id)
+WHERE u.created_at > now() - interval '30 days'
+  AND o.status IN ('paid', 'shipped')
+GROUP BY u.id, u.name
+HAVING count(*) > 5
+ORDER BY total DESC NULLS LAST
+LIMIT 10 OFFSET 20;
+
+WITH RECURSIVE tree (id, parent_id) AS (
+    SELECT id, parent_id FROM nodes WHERE parent_id IS NULL
+    UNION ALL
+    SELECT n.id, n.parent_id FROM nodes n JOIN tree t ON n.parent_id = t.id
+)
+SELECT * FROM tree;
+
+SELECT DISTINCT ON (customer_id) customer_id, amount
+FROM payments, customers
+WHERE payments.customer_id = customers.id
+ORDER BY customer_id, paid_at DESC
+FOR UPDATE SKIP LOCKED;
+
+SELECT name FROM products p
+WHERE EXISTS (SELECT 1 FROM stock s WHERE s.product_id = p.id)
+  AND p.category_id NOT IN (SELECT id FROM categories WHERE hidden)
+  AND lower(p.name) LIKE '%phone%';
+
+SELECT x.* FROM LATERAL generate_series(1, 3) WITH ORDINALITY AS x(n, ord);
+
+VALUES (1, 'one'), (2, 'two');
+
+SELECT sum(amount) FILTER (WHERE amount > 0) OVER (PARTITION BY account ORDER BY ts ROWS BETWEEN 1 PRECEDING AND CURRENT ROW)
+FROM ledger;
@@ -0,0 +1,142 @@
+// Package sqlparser implements a lexer and a recursive descent parser for the
+// PostgreSQL dialect of SQL.
+//
+// The parser produces an AST for SELECT, INSERT, UPDATE and DELETE statements
+// and for the DDL statements that matter to migrations (CREATE TABLE, CREATE
+// INDEX, CREATE VIEW, ALTER TABLE, DROP and TRUNCATE). Other statements are
+// recognised by their leading keywords and kept as RawStmt so that scripts
+// containing them still parse. Every node records its source span, so callers
+// can report line-accurate findings and slice the original text.
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a position in the source text.
+// Line and Column are 1-based; Column counts bytes.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// String returns the position as "line:column"
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// TokenKind identifies the lexical class of a token
+type TokenKind int
+
+// Token kinds
+const (
+	TokenEOF         TokenKind = iota
+	TokenIdent                 // unquoted identifier or keyword
+	TokenQuotedIdent           // "Quoted" identifier
+	TokenString                // 'string', E'string', $$string$$, B'0101', X'ff'
+	TokenNumber                // 42, 3.14, 1e10
+	TokenParam                 // $1
+	TokenOperator              // =, <>, ::, ||, ->>, ...
+	TokenLParen                // (
+	TokenRParen                // )
+	TokenLBracket              // [
+	TokenRBracket              // ]
+	TokenComma                 // ,
+	TokenSemicolon             // ;
+	TokenDot                   // .
+	TokenColon                 // :
+	TokenComment               // -- comment or /* comment */
+)
+
+var tokenKindNames = map[TokenKind]string{
+	TokenEOF:         "EOF",
+	TokenIdent:       "Ident",
+	TokenQuotedIdent: "QuotedIdent",
+	TokenString:      "String",
+	TokenNumber:      "Number",
+	TokenParam:       "Param",
+	TokenOperator:    "Operator",
+	TokenLParen:      "LParen",
+	TokenRParen:      "RParen",
+	TokenLBracket:    "LBracket",
+	TokenRBracket:    "RBracket",
+	TokenComma:       "Comma",
+	TokenSemicolon:   "Semicolon",
+	TokenDot:         "Dot",
+	TokenColon:       "Colon",
+	TokenComment:     "Comment",
+}
+
+// String returns the name of the token kind
+func (k TokenKind) String() string {
+	if name, ok := tokenKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// Token is a lexical token
+type Token struct {
+	Kind TokenKind
+	// Text is the token exactly as written in the source
+	Text string
+	// Value is the normalised value: unquoted identifiers are folded to lower
+	// case, quoted identifiers and string literals have quotes and escapes
+	// removed, and parameters hold their number.
+	Value string
+	Pos   Pos
+	End   Pos
+}
+
+// Keyword returns the upper-cased text of an unquoted identifier, or "" for
+// any other token. Quoted identifiers are never keywords.
+func (t Token) Keyword() string {
+	if t.Kind != TokenIdent {
+		return ""
+	}
+	return strings.ToUpper(t.Text)
+}
+
+// String describes the token for error messages
+func (t Token) String() string {
+	if t.Kind == TokenEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.Text)
+}
+
+// Error is a syntax error at a position in the source
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+// ErrorList is a list of syntax errors, in source order
+type ErrorList []*Error
+
+// Error implements the error interface
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+	}
+}
+
+// Err returns nil for an empty list and the list otherwise
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,258 @@
+package sqlparser
+
+// Inspect traverses the AST rooted at node in depth-first order, calling f
+// for each node. If f returns false, the children of that node are skipped.
+// Nil children are never passed to f.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *SelectStmt:
+		inspectWith(n.With, f)
+		inspectExprs(n.DistinctOn, f)
+		for _, t := range n.Targets {
+			Inspect(t, f)
+		}
+		for _, t := range n.From {
+			Inspect(t, f)
+		}
+		inspectExpr(n.Where, f)
+		inspectExprs(n.GroupBy, f)
+		inspectExpr(n.Having, f)
+		for _, w := range n.Windows {
+			Inspect(w, f)
+		}
+		for _, row := range n.Values {
+			inspectExprs(row, f)
+		}
+		inspectSelect(n.Left, f)
+		inspectSelect(n.Right, f)
+		inspectOrder(n.OrderBy, f)
+		inspectExpr(n.Limit, f)
+		inspectExpr(n.Offset, f)
+
+	case *WithClause:
+		for _, cte := range n.CTEs {
+			Inspect(cte, f)
+		}
+	case *CTE:
+		Inspect(n.Query, f)
+	case *ResultTarget:
+		inspectExpr(n.Expr, f)
+	case *OrderItem:
+		inspectExpr(n.Expr, f)
+	case *WindowSpec:
+		inspectExprs(n.PartitionBy, f)
+		inspectOrder(n.OrderBy, f)
+
+	case *TableName:
+	case *SubqueryTable:
+		inspectSelect(n.Query, f)
+	case *FuncTable:
+		if n.Func != nil {
+			Inspect(n.Func, f)
+		}
+	case *JoinExpr:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+		inspectExpr(n.On, f)
+
+	case *InsertStmt:
+		inspectWith(n.With, f)
+		if n.Table != nil {
+			Inspect(n.Table, f)
+		}
+		inspectSelect(n.Query, f)
+		if n.OnConflict != nil {
+			Inspect(n.OnConflict, f)
+		}
+		inspectTargets(n.Returning, f)
+	case *OnConflict:
+		inspectIndexElems(n.Target, f)
+		inspectExpr(n.TargetWhere, f)
+		inspectSet(n.Set, f)
+		inspectExpr(n.Where, f)
+	case *SetClause:
+		inspectExpr(n.Value, f)
+	case *UpdateStmt:
+		inspectWith(n.With, f)
+		if n.Table != nil {
+			Inspect(n.Table, f)
+		}
+		inspectSet(n.Set, f)
+		for _, t := range n.From {
+			Inspect(t, f)
+		}
+		inspectExpr(n.Where, f)
+		inspectTargets(n.Returning, f)
+	case *DeleteStmt:
+		inspectWith(n.With, f)
+		if n.Table != nil {
+			Inspect(n.Table, f)
+		}
+		for _, t := range n.Using {
+			Inspect(t, f)
+		}
+		inspectExpr(n.Where, f)
+		inspectTargets(n.Returning, f)
+	case *ExplainStmt:
+		Inspect(n.Stmt, f)
+
+	case *CreateTableStmt:
+		for _, col := range n.Columns {
+			Inspect(col, f)
+		}
+		inspectConstraints(n.Constraints, f)
+		inspectSelect(n.AsQuery, f)
+	case *ColumnDef:
+		inspectConstraints(n.Constraints, f)
+	case *Constraint:
+		inspectExpr(n.Expr, f)
+		if n.References != nil {
+			Inspect(n.References, f)
+		}
+	case *Reference:
+	case *CreateIndexStmt:
+		inspectIndexElems(n.Columns, f)
+		inspectExpr(n.Where, f)
+	case *IndexElem:
+		inspectExpr(n.Expr, f)
+	case *CreateViewStmt:
+		inspectSelect(n.Query, f)
+	case *AlterTableStmt:
+		for _, a := range n.Actions {
+			Inspect(a, f)
+		}
+	case *AlterTableAction:
+		if n.ColumnDef != nil {
+			Inspect(n.ColumnDef, f)
+		}
+		inspectExpr(n.Using, f)
+		inspectExpr(n.Default, f)
+		if n.Constraint != nil {
+			Inspect(n.Constraint, f)
+		}
+	case *DropStmt, *TruncateStmt, *TransactionStmt, *RawStmt, *BadStmt:
+
+	case *ColumnRef, *Literal, *Param, *DefaultExpr:
+	case *BinaryExpr:
+		inspectExpr(n.Left, f)
+		inspectExpr(n.Right, f)
+	case *UnaryExpr:
+		inspectExpr(n.Expr, f)
+	case *FuncCall:
+		inspectExprs(n.Args, f)
+		inspectOrder(n.OrderBy, f)
+		inspectExpr(n.Filter, f)
+		if n.Over != nil {
+			Inspect(n.Over, f)
+		}
+	case *SubqueryExpr:
+		inspectSelect(n.Query, f)
+	case *InExpr:
+		inspectExpr(n.Expr, f)
+		inspectExprs(n.List, f)
+		inspectSelect(n.Query, f)
+	case *QuantifiedExpr:
+		inspectExpr(n.Expr, f)
+		inspectSelect(n.Query, f)
+	case *BetweenExpr:
+		inspectExpr(n.Expr, f)
+		inspectExpr(n.Low, f)
+		inspectExpr(n.High, f)
+	case *LikeExpr:
+		inspectExpr(n.Expr, f)
+		inspectExpr(n.Pattern, f)
+		inspectExpr(n.Escape, f)
+	case *IsExpr:
+		inspectExpr(n.Expr, f)
+		inspectExpr(n.Right, f)
+	case *CaseExpr:
+		inspectExpr(n.Operand, f)
+		for _, w := range n.Whens {
+			Inspect(w, f)
+		}
+		inspectExpr(n.Else, f)
+	case *CaseWhen:
+		inspectExpr(n.Cond, f)
+		inspectExpr(n.Result, f)
+	case *CastExpr:
+		inspectExpr(n.Expr, f)
+	case *ArrayExpr:
+		inspectExprs(n.Elems, f)
+		inspectSelect(n.Query, f)
+	case *RowExpr:
+		inspectExprs(n.Elems, f)
+	case *SubscriptExpr:
+		inspectExpr(n.Expr, f)
+		inspectExpr(n.Index, f)
+		inspectExpr(n.Upper, f)
+	case *FieldExpr:
+		inspectExpr(n.Expr, f)
+	case *CollateExpr:
+		inspectExpr(n.Expr, f)
+	}
+}
+
+// InspectScript calls Inspect for every statement of s
+func InspectScript(s *Script, f func(Node) bool) {
+	for _, stmt := range s.Statements {
+		Inspect(stmt, f)
+	}
+}
+
+func inspectExpr(e Expr, f func(Node) bool) {
+	if e != nil {
+		Inspect(e, f)
+	}
+}
+
+func inspectExprs(list []Expr, f func(Node) bool) {
+	for _, e := range list {
+		inspectExpr(e, f)
+	}
+}
+
+func inspectSelect(s *SelectStmt, f func(Node) bool) {
+	if s != nil {
+		Inspect(s, f)
+	}
+}
+
+func inspectWith(w *WithClause, f func(Node) bool) {
+	if w != nil {
+		Inspect(w, f)
+	}
+}
+
+func inspectOrder(items []*OrderItem, f func(Node) bool) {
+	for _, item := range items {
+		Inspect(item, f)
+	}
+}
+
+func inspectTargets(targets []*ResultTarget, f func(Node) bool) {
+	for _, t := range targets {
+		Inspect(t, f)
+	}
+}
+
+func inspectSet(set []*SetClause, f func(Node) bool) {
+	for _, s := range set {
+		Inspect(s, f)
+	}
+}
+
+func inspectConstraints(list []*Constraint, f func(Node) bool) {
+	for _, c := range list {
+		Inspect(c, f)
+	}
+}
+
+func inspectIndexElems(elems []*IndexElem, f func(Node) bool) {
+	for _, e := range elems {
+		Inspect(e, f)
+	}
+}
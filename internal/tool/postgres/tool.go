@@ -6,6 +6,7 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -13,19 +14,26 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/koopa0/assistant-go/internal/config"
 	"github.com/koopa0/assistant-go/internal/tool"
 )
 
 // PostgresTool implements the Tool interface for PostgreSQL operations
 type PostgresTool struct {
-	logger *slog.Logger
-	pool   *pgxpool.Pool // Optional: can work without connection for some operations
+	logger      *slog.Logger
+	cfg         config.Postgres
+	connections *ConnectionManager
 }
 
-// NewPostgresTool creates a new PostgreSQL tool instance
-func NewPostgresTool(logger *slog.Logger) *PostgresTool {
+// NewPostgresTool creates a new PostgreSQL tool instance.
+// store resolves named connections and may be nil; the tool then only uses
+// cfg.DefaultConnection or explicit connection strings. Actions that don't
+// need a database keep working without any connection.
+func NewPostgresTool(cfg config.Postgres, store ConnectionStore, logger *slog.Logger) *PostgresTool {
 	return &PostgresTool{
-		logger: logger,
+		logger:      logger,
+		cfg:         cfg,
+		connections: NewConnectionManager(cfg, store, logger),
 	}
 }
 
@@ -56,6 +64,9 @@ func (t *PostgresTool) Parameters() *tool.ToolParametersSchema {
 					"suggest_indexes",
 					"check_performance",
 					"validate_migration",
+					"diff_schema",
+					"execute_query",
+					"list_connections",
 				},
 			},
 			"query": {
@@ -75,10 +86,49 @@ func (t *PostgresTool) Parameters() *tool.ToolParametersSchema {
 				Description: "Type of migration to generate",
 				Enum:        []string{"create_table", "add_column", "add_index", "alter_table"},
 			},
+			"migration": {
+				Type:        tool.ParameterTypeString,
+				Description: "Migration SQL to validate",
+			},
+			"pg_version": {
+				Type:        tool.ParameterTypeInteger,
+				Description: "PostgreSQL major version the migration runs on, for lock analysis (defaults to the connected server, or the latest release)",
+			},
+			"from": {
+				Type:        tool.ParameterTypeString,
+				Description: "Current schema for diff_schema: database[:connection], a connection string, directory:<path> of .sql migrations, dump:<path> or sql:<DDL>",
+			},
+			"to": {
+				Type:        tool.ParameterTypeString,
+				Description: "Desired schema for diff_schema, in the same form as from",
+			},
+			"name": {
+				Type:        tool.ParameterTypeString,
+				Description: "Name of the generated migration files",
+				Default:     "schema_diff",
+			},
+			"version": {
+				Type:        tool.ParameterTypeInteger,
+				Description: "Version number of the generated migration (defaults to the next free number of a directory source)",
+			},
+			"connection": {
+				Type:        tool.ParameterTypeString,
+				Description: "Name of a configured database connection (defaults to the user's default connection)",
+			},
 			"connection_string": {
 				Type:        tool.ParameterTypeString,
 				Description: "PostgreSQL connection string (optional for analysis)",
 			},
+			"analyze": {
+				Type:        tool.ParameterTypeBoolean,
+				Description: "Run EXPLAIN ANALYZE, executing the query in a transaction that is rolled back",
+				Default:     false,
+			},
+			"max_rows": {
+				Type:        tool.ParameterTypeInteger,
+				Description: "Maximum rows returned by execute_query",
+				Default:     100,
+			},
 		},
 		Required: []string{"action"},
 	}
@@ -86,13 +136,12 @@ func (t *PostgresTool) Parameters() *tool.ToolParametersSchema {
 
 // Health checks the health of the tool
 func (t *PostgresTool) Health(ctx context.Context) error {
-	// PostgresTool is always healthy as it doesn't maintain persistent connections
-	return nil
+	return t.connections.Health(ctx)
 }
 
 // Close closes any resources
 func (t *PostgresTool) Close(ctx context.Context) error {
-	// PostgresTool doesn't maintain persistent connections
+	t.connections.Close()
 	return nil
 }
 
@@ -117,16 +166,12 @@ func (t *PostgresTool) Execute(ctx context.Context, input *tool.ToolInput) (*too
 	t.logger.Info("Executing PostgreSQL action",
 		slog.String("action", action))
 
-	// Connect to database if connection string provided
-	if connStr, ok := params["connection_string"].(string); ok && connStr != "" {
-		pool, err := pgxpool.New(ctx, connStr)
-		if err != nil {
-			t.logger.Warn("Failed to connect to database, continuing with limited functionality",
-				slog.String("error", err.Error()))
-		} else {
-			t.pool = pool
-			defer pool.Close()
-		}
+	// Describe which database to use; pools are opened lazily by actions that need one
+	conn := ConnectionRequest{}
+	conn.Name, _ = params["connection"].(string)
+	conn.ConnectionString, _ = params["connection_string"].(string)
+	if input.Context != nil {
+		conn.UserID = input.Context.UserID
 	}
 
 	var result interface{}
@@ -134,21 +179,27 @@ func (t *PostgresTool) Execute(ctx context.Context, input *tool.ToolInput) (*too
 
 	switch action {
 	case "analyze_query":
-		result, err = t.analyzeQuery(ctx, params)
+		result, err = t.analyzeQuery(ctx, conn, params)
 	case "optimize_query":
 		result, err = t.optimizeQuery(ctx, params)
 	case "explain_query":
-		result, err = t.explainQuery(ctx, params)
+		result, err = t.explainQuery(ctx, conn, params)
+	case "execute_query":
+		result, err = t.executeQuery(ctx, conn, params)
 	case "generate_migration":
 		result, err = t.generateMigration(ctx, params)
 	case "analyze_schema":
-		result, err = t.analyzeSchema(ctx, params)
+		result, err = t.analyzeSchema(ctx, conn, params)
 	case "suggest_indexes":
-		result, err = t.suggestIndexes(ctx, params)
+		result, err = t.suggestIndexes(ctx, conn, params)
 	case "check_performance":
-		result, err = t.checkPerformance(ctx, params)
+		result, err = t.checkPerformance(ctx, conn, params)
 	case "validate_migration":
-		result, err = t.validateMigration(ctx, params)
+		result, err = t.validateMigration(ctx, conn, params)
+	case "diff_schema":
+		result, err = t.diffSchema(ctx, conn, params)
+	case "list_connections":
+		result, err = t.connections.List(ctx, conn.UserID)
 	default:
 		return &tool.ToolResult{
 			Success: false,
@@ -191,20 +242,45 @@ func (t *PostgresTool) Execute(ctx context.Context, input *tool.ToolInput) (*too
 }
 
 // analyzeQuery analyzes a SQL query
-func (t *PostgresTool) analyzeQuery(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+func (t *PostgresTool) analyzeQuery(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
 	query, ok := params["query"].(string)
 	if !ok || query == "" {
 		return nil, fmt.Errorf("query parameter is required")
 	}
 
-	// If no database connection, perform static analysis
-	if t.pool == nil {
-		return t.performStaticAnalysis(query), nil
+	analyzer := NewQueryAnalyzer(t.logger)
+	analysis, err := analyzer.Analyze(query)
+	if err != nil {
+		return nil, err
 	}
 
-	// With database connection, can do EXPLAIN ANALYZE
-	analyzer := NewQueryAnalyzer(t.logger)
-	return analyzer.Analyze(query)
+	// With a database connection, enrich the static analysis with the planner's view
+	pool, release, err := t.optionalPool(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if pool == nil || !t.cfg.EnableExplain {
+		return analysis, nil
+	}
+
+	explain, err := t.explain(ctx, pool, query, false)
+	if err != nil {
+		t.logger.Warn("Failed to explain query, returning static analysis",
+			slog.String("error", err.Error()))
+		return analysis, nil
+	}
+
+	analysis.Plan = explain.Plan
+	analysis.EstimatedCost = fmt.Sprintf("%.2f", explain.TotalCost)
+	for _, node := range explain.PlanNodes {
+		if node.IndexName != "" {
+			analysis.IndexesUsed = append(analysis.IndexesUsed, node.IndexName)
+		}
+	}
+	analysis.Suggestions = append(analysis.Suggestions, explain.Recommendations...)
+
+	return analysis, nil
 }
 
 // optimizeQuery optimizes a SQL query
@@ -227,40 +303,121 @@ func (t *PostgresTool) optimizeQuery(ctx context.Context, params map[string]inte
 }
 
 // explainQuery explains a SQL query execution plan
-func (t *PostgresTool) explainQuery(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+func (t *PostgresTool) explainQuery(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
 	query, ok := params["query"].(string)
 	if !ok || query == "" {
 		return nil, fmt.Errorf("query parameter is required")
 	}
+	analyze, _ := params["analyze"].(bool)
 
-	if t.pool == nil {
-		return map[string]interface{}{
-			"error": "Database connection required for EXPLAIN",
-		}, fmt.Errorf("database connection required")
+	analyzer := NewQueryAnalyzer(t.logger)
+
+	pool, release, err := t.optionalPool(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if pool == nil {
+		return analyzer.StaticExplain(query)
+	}
+	if !t.cfg.EnableExplain {
+		explain, err := analyzer.StaticExplain(query)
+		if err != nil {
+			return nil, err
+		}
+		explain.Note = "EXPLAIN is disabled by configuration - static analysis only"
+		return explain, nil
 	}
 
-	// Execute EXPLAIN
-	rows, err := t.pool.Query(ctx, fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS) %s", query))
+	explain, err := t.explain(ctx, pool, query, analyze)
 	if err != nil {
-		return nil, fmt.Errorf("failed to explain query: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	var plan []string
-	for rows.Next() {
-		var line string
-		if err := rows.Scan(&line); err != nil {
-			continue
-		}
-		plan = append(plan, line)
+	explain.QueryAnalysis, err = analyzer.Analyze(query)
+	if err != nil {
+		return nil, err
 	}
 
-	return map[string]interface{}{
-		"query": query,
-		"plan":  plan,
-	}, nil
+	return explain, nil
+}
+
+// executeQuery runs a read-only query and returns its rows
+func (t *PostgresTool) executeQuery(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
+	query, ok := params["query"].(string)
+	if !ok || query == "" {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+
+	maxRows := 100
+	switch v := params["max_rows"].(type) {
+	case float64:
+		maxRows = int(v)
+	case int:
+		maxRows = v
+	}
+
+	pool, release, err := t.requirePool(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	executor := NewReadOnlyExecutor(t.logger, pool, t.cfg.QueryTimeout, t.cfg.MaxQuerySize)
+	return executor.Query(ctx, query, maxRows)
 }
 
+// explain runs EXPLAIN against the database and parses the JSON plan
+func (t *PostgresTool) explain(ctx context.Context, pool *pgxpool.Pool, query string, analyze bool) (*ExplainAnalysis, error) {
+	executor := NewReadOnlyExecutor(t.logger, pool, t.cfg.QueryTimeout, t.cfg.MaxQuerySize)
+	plan, err := executor.Explain(ctx, query, analyze)
+	if err != nil {
+		return nil, err
+	}
+
+	explain, err := NewQueryAnalyzer(t.logger).AnalyzeExplainJSON(plan)
+	if err != nil {
+		return nil, err
+	}
+	explain.Analyzed = analyze
+
+	return explain, nil
+}
+
+// optionalPool resolves a connection for actions that can run without one,
+// with the function releasing it when the action is done.
+// A failure to open an explicitly requested connection is an error; a failing
+// default connection only degrades the action to static analysis.
+func (t *PostgresTool) optionalPool(ctx context.Context, conn ConnectionRequest) (*pgxpool.Pool, func(), error) {
+	pool, release, err := t.connections.Resolve(ctx, conn)
+	if err == nil {
+		return pool, release, nil
+	}
+	if conn.Name != "" || conn.ConnectionString != "" {
+		return nil, release, err
+	}
+
+	t.logger.Warn("Failed to connect to database, continuing with limited functionality",
+		slog.String("error", err.Error()))
+	return nil, release, nil
+}
+
+// requirePool resolves a connection for actions that can't run without one,
+// with the function releasing it when the action is done
+func (t *PostgresTool) requirePool(ctx context.Context, conn ConnectionRequest) (*pgxpool.Pool, func(), error) {
+	pool, release, err := t.connections.Resolve(ctx, conn)
+	if err != nil {
+		return nil, release, err
+	}
+	if pool == nil {
+		return nil, release, errConnectionRequired
+	}
+	return pool, release, nil
+}
+
+// errConnectionRequired is returned by actions that need a database
+var errConnectionRequired = errors.New("database connection required: pass connection or connection_string, or configure tools.postgres.default_connection")
+
 // generateMigration generates a database migration
 func (t *PostgresTool) generateMigration(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	migrationType, _ := params["migration_type"].(string)
@@ -335,102 +492,170 @@ func (t *PostgresTool) generateMigration(ctx context.Context, params map[string]
 }
 
 // analyzeSchema analyzes database schema
-func (t *PostgresTool) analyzeSchema(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+func (t *PostgresTool) analyzeSchema(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
 	schema, _ := params["schema"].(string)
 	if schema == "" {
 		schema = "public"
 	}
 
-	if t.pool == nil {
-		return map[string]interface{}{
-			"error": "Database connection required for schema analysis",
-		}, fmt.Errorf("database connection required")
+	pool, release, err := t.optionalPool(ctx, conn)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	analyzer := NewSchemaAnalyzer(t.logger, t.pool)
+	// Without a pool the analyzer falls back to static recommendations
+	analyzer := NewSchemaAnalyzer(t.logger, pool)
 	return analyzer.Analyze(ctx, schema)
 }
 
 // suggestIndexes suggests indexes for a table
-func (t *PostgresTool) suggestIndexes(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+func (t *PostgresTool) suggestIndexes(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
 	table, ok := params["table"].(string)
 	if !ok || table == "" {
 		return nil, fmt.Errorf("table parameter is required")
 	}
 
-	schema, _ := params["schema"].(string)
-	if schema == "" {
-		schema = "public"
-	}
-
-	if t.pool == nil {
-		return map[string]interface{}{
-			"error": "Database connection required for index suggestions",
-		}, fmt.Errorf("database connection required")
+	pool, release, err := t.optionalPool(ctx, conn)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	advisor := NewIndexAdvisor(t.logger, t.pool)
+	// Without a pool the advisor falls back to static suggestions
+	advisor := NewIndexAdvisor(t.logger, pool)
 	return advisor.SuggestIndexes(ctx, table)
 }
 
 // checkPerformance checks database performance
-func (t *PostgresTool) checkPerformance(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	if t.pool == nil {
+func (t *PostgresTool) checkPerformance(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
+	pool, release, err := t.optionalPool(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if pool == nil {
+		connections, err := t.connections.List(ctx, conn.UserID)
+		if err != nil {
+			t.logger.Warn("Failed to list database connections", slog.String("error", err.Error()))
+		}
 		return map[string]interface{}{
-			"error": "Database connection required for performance check",
-		}, fmt.Errorf("database connection required")
+			"connection_required":   true,
+			"error":                 errConnectionRequired.Error(),
+			"available_connections": connections,
+		}, nil
 	}
 
-	checker := NewPerformanceChecker(t.logger, t.pool)
+	checker := NewPerformanceChecker(t.logger, pool)
 	return checker.Check(ctx)
 }
 
-// validateMigration validates a migration
-func (t *PostgresTool) validateMigration(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+// validateMigration validates a migration. With a connection, lock impact
+// is estimated from the sizes of the affected tables.
+func (t *PostgresTool) validateMigration(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
 	migration, ok := params["migration"].(string)
 	if !ok || migration == "" {
 		return nil, fmt.Errorf("migration parameter is required")
 	}
 
-	validator := NewMigrationValidator(t.logger)
-	return validator.Validate(migration)
-}
-
-// performStaticAnalysis performs static SQL analysis without database connection
-func (t *PostgresTool) performStaticAnalysis(query string) map[string]interface{} {
-	// Basic static analysis
-	result := map[string]interface{}{
-		"query":       query,
-		"warnings":    []string{},
-		"suggestions": []string{},
+	pool, release, err := t.optionalPool(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	version := 0
+	if v, ok := params["pg_version"].(float64); ok {
+		version = int(v)
 	}
 
-	// Check for common issues
-	warnings := []string{}
-	suggestions := []string{}
+	validator := NewMigrationValidator(t.logger).WithLockAnalyzer(NewLockAnalyzer(t.logger, pool, version))
+	return validator.ValidateContext(ctx, migration)
+}
 
-	if len(query) > 1000 {
-		warnings = append(warnings, "Query is very long, consider breaking it down")
+// diffSchema compares two schema sources and generates the migrations that
+// turn the first into the second
+func (t *PostgresTool) diffSchema(ctx context.Context, conn ConnectionRequest, params map[string]interface{}) (interface{}, error) {
+	fromSpec, _ := params["from"].(string)
+	toSpec, _ := params["to"].(string)
+	if fromSpec == "" || toSpec == "" {
+		return nil, fmt.Errorf("from and to parameters are required")
 	}
-
-	// Check for SELECT *
-	if contains(query, "SELECT *") || contains(query, "select *") {
-		suggestions = append(suggestions, "Avoid SELECT *, specify columns explicitly")
+	schema, _ := params["schema"].(string)
+	if schema == "" {
+		schema = "public"
+	}
+	name, _ := params["name"].(string)
+	if name == "" {
+		name = "schema_diff"
 	}
 
-	// Check for missing WHERE in UPDATE/DELETE
-	upperQuery := strings.ToUpper(query)
-	if (strings.Contains(upperQuery, "UPDATE") || strings.Contains(upperQuery, "DELETE")) &&
-		!strings.Contains(upperQuery, "WHERE") {
-		warnings = append(warnings, "UPDATE/DELETE without WHERE clause affects all rows")
+	from, err := t.loadSchema(ctx, conn, fromSpec, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load from schema: %w", err)
+	}
+	to, err := t.loadSchema(ctx, conn, toSpec, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load to schema: %w", err)
 	}
 
-	result["warnings"] = warnings
-	result["suggestions"] = suggestions
+	// The new migration goes after the last one of a migrations directory
+	version := 1
+	if v, ok := params["version"].(float64); ok {
+		version = int(v)
+	} else {
+		for _, spec := range []string{toSpec, fromSpec} {
+			if dir, ok := strings.CutPrefix(spec, "directory:"); ok {
+				if version, err = NextMigrationVersion(dir); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
 
-	return result
+	generator := NewMigrationGenerator(t.logger)
+	return generator.GenerateFromDiff(DiffSchemas(from, to), DiffSchemas(to, from), version, name)
 }
 
-func contains(s, substr string) bool {
-	return strings.Contains(strings.ToUpper(s), strings.ToUpper(substr))
+// loadSchema loads a schema snapshot from a source:
+//
+//	database[:name]   the request's connection, or the named connection
+//	postgres://...    a connection string
+//	directory:<path>  a directory of .sql migration files
+//	dump:<path>       a schema dump, such as pg_dump --schema-only output
+//	sql:<ddl>         inline DDL
+func (t *PostgresTool) loadSchema(ctx context.Context, conn ConnectionRequest, spec, schema string) (*SchemaSnapshot, error) {
+	kind, value, _ := strings.Cut(spec, ":")
+	switch {
+	case kind == "directory":
+		return LoadSchemaDirectory(value, schema)
+	case kind == "dump":
+		return LoadSchemaDump(value, schema)
+	case kind == "sql":
+		snapshot := NewSchemaSnapshot(schema, "sql", false)
+		if err := snapshot.ApplySQL(value); err != nil {
+			return nil, err
+		}
+		return snapshot, nil
+	case kind == "database":
+		if value != "" {
+			conn.Name, conn.ConnectionString = value, ""
+		}
+	case isConnectionString(spec):
+		conn.Name, conn.ConnectionString = "", spec
+	default:
+		return nil, fmt.Errorf("unknown schema source %q: use database[:name], a connection string, directory:, dump: or sql:", spec)
+	}
+
+	pool, release, err := t.requirePool(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	source := "database"
+	if conn.Name != "" {
+		source = "database:" + conn.Name
+	}
+	return NewSchemaAnalyzer(t.logger, pool).Snapshot(ctx, schema, source)
 }
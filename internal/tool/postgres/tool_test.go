@@ -2,15 +2,21 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/koopa0/assistant-go/internal/config"
 	"github.com/koopa0/assistant-go/internal/tool"
 )
 
 func TestPostgresTool_Basic(t *testing.T) {
 	logger := slog.Default()
-	pgTool := NewPostgresTool(logger)
+	pgTool := NewPostgresTool(config.Postgres{}, nil, logger)
 
 	// Test tool metadata
 	if pgTool.Name() != "postgres" {
@@ -40,7 +46,7 @@ func TestPostgresTool_Basic(t *testing.T) {
 
 func TestPostgresTool_AnalyzeQuery(t *testing.T) {
 	logger := slog.Default()
-	pgTool := NewPostgresTool(logger)
+	pgTool := NewPostgresTool(config.Postgres{}, nil, logger)
 	ctx := context.Background()
 
 	tests := []struct {
@@ -91,7 +97,7 @@ func TestPostgresTool_AnalyzeQuery(t *testing.T) {
 
 func TestPostgresTool_ValidateMigration(t *testing.T) {
 	logger := slog.Default()
-	pgTool := NewPostgresTool(logger)
+	pgTool := NewPostgresTool(config.Postgres{}, nil, logger)
 	ctx := context.Background()
 
 	tests := []struct {
@@ -152,7 +158,7 @@ func TestPostgresTool_ValidateMigration(t *testing.T) {
 
 func TestPostgresTool_GenerateMigration(t *testing.T) {
 	logger := slog.Default()
-	pgTool := NewPostgresTool(logger)
+	pgTool := NewPostgresTool(config.Postgres{}, nil, logger)
 	ctx := context.Background()
 
 	input := &tool.ToolInput{
@@ -180,7 +186,7 @@ func TestPostgresTool_GenerateMigration(t *testing.T) {
 
 func TestPostgresTool_CheckPerformance_NoConnection(t *testing.T) {
 	logger := slog.Default()
-	pgTool := NewPostgresTool(logger)
+	pgTool := NewPostgresTool(config.Postgres{}, nil, logger)
 	ctx := context.Background()
 
 	input := &tool.ToolInput{
@@ -199,3 +205,802 @@ func TestPostgresTool_CheckPerformance_NoConnection(t *testing.T) {
 		t.Errorf("Expected success (with error message) but got failure: %s", result.Error)
 	}
 }
+
+func TestQueryAnalyzer_AnalyzeExplainJSON(t *testing.T) {
+	plan := `[{
+		"Plan": {
+			"Node Type": "Nested Loop",
+			"Startup Cost": 0.29,
+			"Total Cost": 120.5,
+			"Plan Rows": 10,
+			"Plan Width": 64,
+			"Actual Total Time": 4.2,
+			"Actual Rows": 5000,
+			"Actual Loops": 1,
+			"Plans": [
+				{
+					"Node Type": "Seq Scan",
+					"Relation Name": "orders",
+					"Total Cost": 80.0,
+					"Plan Rows": 10,
+					"Plan Width": 32,
+					"Actual Rows": 5000,
+					"Actual Loops": 1,
+					"Filter": "(status = 'open'::text)",
+					"Rows Removed by Filter": 120
+				},
+				{
+					"Node Type": "Index Scan",
+					"Relation Name": "users",
+					"Index Name": "users_pkey",
+					"Total Cost": 0.3,
+					"Plan Rows": 1,
+					"Plan Width": 32,
+					"Actual Rows": 1,
+					"Actual Loops": 5000
+				}
+			]
+		},
+		"Planning Time": 0.15,
+		"Execution Time": 4.5
+	}]`
+
+	analyzer := NewQueryAnalyzer(slog.Default())
+	analysis, err := analyzer.AnalyzeExplainJSON([]byte(plan))
+	if err != nil {
+		t.Fatalf("AnalyzeExplainJSON failed: %v", err)
+	}
+
+	if analysis.Plan == nil || len(analysis.Plan.Children) != 2 {
+		t.Fatalf("Expected plan tree with 2 children, got %+v", analysis.Plan)
+	}
+	if len(analysis.PlanNodes) != 3 {
+		t.Errorf("Expected 3 flattened plan nodes, got %d", len(analysis.PlanNodes))
+	}
+	if analysis.TotalCost != 120.5 {
+		t.Errorf("Expected total cost 120.5, got %v", analysis.TotalCost)
+	}
+	if analysis.ExecutionTime != 4.5 || analysis.PlanningTime != 0.15 {
+		t.Errorf("Unexpected timings: planning=%v execution=%v", analysis.PlanningTime, analysis.ExecutionTime)
+	}
+
+	scan := analysis.Plan.Children[0]
+	if scan.RelationName != "orders" || scan.RowsRemovedByFilter != 120 || scan.Warning == "" {
+		t.Errorf("Unexpected seq scan node: %+v", scan)
+	}
+	if analysis.Plan.Children[1].IndexName != "users_pkey" {
+		t.Errorf("Expected index name users_pkey, got %q", analysis.Plan.Children[1].IndexName)
+	}
+
+	var hasSeqScan, hasMisestimate bool
+	for _, rec := range analysis.Recommendations {
+		if strings.Contains(rec, "sequential scans") {
+			hasSeqScan = true
+		}
+		if strings.Contains(rec, "Seq Scan on orders") {
+			hasMisestimate = true
+		}
+	}
+	if !hasSeqScan || !hasMisestimate {
+		t.Errorf("Expected sequential scan and misestimate recommendations, got %v", analysis.Recommendations)
+	}
+
+	if _, err := analyzer.AnalyzeExplainJSON([]byte(`[]`)); err == nil {
+		t.Error("Expected error for empty plan")
+	}
+}
+
+func TestReadOnlyExecutor_CheckStatement(t *testing.T) {
+	executor := NewReadOnlyExecutor(slog.Default(), nil, 0, 64)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{name: "select", query: "SELECT 1", wantErr: false},
+		{name: "trailing semicolon", query: "SELECT 1;", wantErr: false},
+		{name: "cte", query: "WITH t AS (SELECT 1) SELECT * FROM t", wantErr: false},
+		{name: "update", query: "UPDATE users SET name = 'x' WHERE id = 1", wantErr: false},
+		{name: "multiple statements", query: "SELECT 1; DROP TABLE users", wantErr: true},
+		{name: "ddl", query: "DROP TABLE users", wantErr: true},
+		{name: "empty", query: " ; ", wantErr: true},
+		{name: "too large", query: "SELECT '" + strings.Repeat("x", 100) + "'", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executor.checkStatement(tt.query)
+			if tt.wantErr && !errors.Is(err, ErrStatementNotAllowed) {
+				t.Errorf("Expected ErrStatementNotAllowed, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+// stubConnectionStore is an in-memory ConnectionStore
+type stubConnectionStore struct {
+	conns    map[string]string
+	fallback string
+}
+
+func (s *stubConnectionStore) ConnectionString(ctx context.Context, userID, name string) (string, error) {
+	if name == "" {
+		name = s.fallback
+	}
+	if connStr, ok := s.conns[name]; ok {
+		return connStr, nil
+	}
+	return "", ErrConnectionNotFound
+}
+
+func (s *stubConnectionStore) ListConnections(ctx context.Context, userID string) ([]ConnectionInfo, error) {
+	var infos []ConnectionInfo
+	for name := range s.conns {
+		infos = append(infos, ConnectionInfo{Name: name, IsDefault: name == s.fallback, Source: "database_connections"})
+	}
+	return infos, nil
+}
+
+func TestConnectionManager_ConnectionString(t *testing.T) {
+	store := &stubConnectionStore{
+		conns: map[string]string{
+			"analytics": "postgres://analytics",
+			"primary":   "postgres://primary",
+		},
+		fallback: "primary",
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		cfg     config.Postgres
+		store   ConnectionStore
+		req     ConnectionRequest
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "explicit connection string wins",
+			store: store,
+			req:   ConnectionRequest{UserID: "u1", Name: "analytics", ConnectionString: "postgres://explicit"},
+			want:  "postgres://explicit",
+		},
+		{
+			name:  "named connection",
+			store: store,
+			req:   ConnectionRequest{UserID: "u1", Name: "analytics"},
+			want:  "postgres://analytics",
+		},
+		{
+			name:  "user default",
+			cfg:   config.Postgres{DefaultConnection: "postgres://config"},
+			store: store,
+			req:   ConnectionRequest{UserID: "u1"},
+			want:  "postgres://primary",
+		},
+		{
+			name: "config default without user",
+			cfg:  config.Postgres{DefaultConnection: "postgres://config"},
+			req:  ConnectionRequest{},
+			want: "postgres://config",
+		},
+		{
+			name:  "config default naming a stored connection",
+			cfg:   config.Postgres{DefaultConnection: "analytics"},
+			store: &stubConnectionStore{conns: store.conns},
+			req:   ConnectionRequest{UserID: "u1"},
+			want:  "postgres://analytics",
+		},
+		{
+			name: "nothing configured",
+			req:  ConnectionRequest{},
+			want: "",
+		},
+		{
+			name:    "unknown name",
+			store:   store,
+			req:     ConnectionRequest{UserID: "u1", Name: "missing"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewConnectionManager(tt.cfg, tt.store, slog.Default())
+			got, err := manager.connectionString(ctx, tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrConnectionNotFound) {
+					t.Errorf("Expected ErrConnectionNotFound, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQueryAnalyzer_Analyze(t *testing.T) {
+	analyzer := NewQueryAnalyzer(slog.Default())
+
+	t.Run("cte names are not tables", func(t *testing.T) {
+		analysis, err := analyzer.Analyze("WITH recent AS (SELECT id FROM orders) SELECT r.id FROM recent r JOIN users u ON u.id = r.id")
+		if err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		if strings.Join(analysis.Tables, ",") != "orders,users" {
+			t.Errorf("Expected tables [orders users], got %v", analysis.Tables)
+		}
+	})
+
+	t.Run("select star inside exists", func(t *testing.T) {
+		analysis, err := analyzer.Analyze("SELECT id FROM users u WHERE EXISTS (SELECT * FROM orders o WHERE o.user_id = u.id)")
+		if err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		for _, issue := range analysis.Issues {
+			if issue.Type == "select_star" {
+				t.Errorf("SELECT * inside EXISTS should not be reported: %+v", issue)
+			}
+		}
+	})
+
+	t.Run("syntax error line", func(t *testing.T) {
+		analysis, err := analyzer.Analyze("SELECT id FROM users;\nSELECT id FROM WHERE id = 1;")
+		if err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		var found bool
+		for _, issue := range analysis.Issues {
+			if issue.Type == "syntax_error" && issue.Line == 2 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected syntax error on line 2, got %+v", analysis.Issues)
+		}
+	})
+}
+
+func TestQueryOptimizer_Optimize(t *testing.T) {
+	optimizer := NewQueryOptimizer(slog.Default())
+
+	result, err := optimizer.Optimize("SELECT u.name, o.total FROM users u, orders o WHERE o.user_id = u.id AND o.note = 'a, b'")
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	want := "SELECT u.name, o.total FROM users u CROSS JOIN orders o WHERE o.user_id = u.id AND o.note = 'a, b'"
+	if result.OptimizedQuery != want {
+		t.Errorf("Expected optimized query %q, got %q", want, result.OptimizedQuery)
+	}
+
+	result, err = optimizer.Optimize("SELECT name, (SELECT count(*) FROM orders o WHERE o.user_id = u.id) FROM users u")
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	var correlated bool
+	for _, opt := range result.Optimizations {
+		if opt.Type == "correlated_subquery" {
+			correlated = true
+		}
+	}
+	if !correlated {
+		t.Errorf("Expected correlated subquery optimization, got %+v", result.Optimizations)
+	}
+
+	if _, err := optimizer.Optimize("SELEC name FROM users"); err == nil {
+		t.Error("Expected error for unparseable query")
+	}
+}
+
+func TestMigrationValidator_Validate(t *testing.T) {
+	validator := NewMigrationValidator(slog.Default())
+
+	tests := []struct {
+		name      string
+		migration string
+		wantValid bool
+		wantIssue string
+		wantLine  int
+	}{
+		{
+			name:      "safe migration",
+			migration: "-- add users\nCREATE TABLE IF NOT EXISTS users (id bigint PRIMARY KEY);\nCREATE INDEX CONCURRENTLY IF NOT EXISTS idx_users_id ON users (id);",
+			wantValid: true,
+		},
+		{
+			name:      "drop column without if exists",
+			migration: "-- cleanup\nALTER TABLE users\n  DROP COLUMN legacy;",
+			wantIssue: "safety",
+			wantLine:  3,
+		},
+		{
+			name:      "not null column without default",
+			migration: "ALTER TABLE users ADD COLUMN tenant_id bigint NOT NULL;",
+			wantIssue: "safety",
+			wantLine:  1,
+		},
+		{
+			name:      "keyword typo",
+			migration: "CREATE TABLE a (id int);\nCRAETE TABLE b (id int);",
+			wantIssue: "typo",
+			wantLine:  2,
+		},
+		{
+			name:      "drop in string literal",
+			migration: "-- seed\nINSERT INTO notes (body) VALUES ('DROP TABLE users');",
+			wantValid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.Validate(tt.migration)
+			if err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			if result.IsValid != tt.wantValid {
+				t.Errorf("Expected IsValid=%v, got %v (issues: %+v)", tt.wantValid, result.IsValid, result.Issues)
+			}
+			if tt.wantIssue == "" {
+				return
+			}
+			for _, issue := range result.Issues {
+				if issue.Type == tt.wantIssue && issue.Line == tt.wantLine {
+					return
+				}
+			}
+			t.Errorf("Expected %s issue on line %d, got %+v", tt.wantIssue, tt.wantLine, result.Issues)
+		})
+	}
+}
+
+func TestDiffSchemas(t *testing.T) {
+	tests := []struct {
+		name            string
+		from, to        string
+		fromCatalog     bool
+		wantKinds       []string
+		wantDestructive []string
+	}{
+		{
+			name:      "identical",
+			from:      "CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL UNIQUE);",
+			to:        "CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL UNIQUE);",
+			wantKinds: nil,
+		},
+		{
+			name: "new table references existing one",
+			from: "CREATE TABLE users (id serial PRIMARY KEY);",
+			to: `CREATE TABLE users (id serial PRIMARY KEY);
+				CREATE TABLE posts (id serial PRIMARY KEY, user_id int REFERENCES users ON DELETE CASCADE);
+				CREATE INDEX idx_posts_user_id ON posts (user_id);`,
+			wantKinds: []string{"create_table", "add_constraint", "create_index"},
+		},
+		{
+			name:            "drop and narrow columns",
+			from:            "CREATE TABLE users (id bigint PRIMARY KEY, email text, legacy text);",
+			to:              "CREATE TABLE users (id int PRIMARY KEY, email varchar(255), nickname text NOT NULL);",
+			wantKinds:       []string{"add_column", "alter_column_type", "alter_column_type", "drop_column"},
+			wantDestructive: []string{"users.id", "users.email", "users.legacy"},
+		},
+		{
+			name:      "widening type change",
+			from:      "CREATE TABLE users (id int PRIMARY KEY, email varchar(100));",
+			to:        "CREATE TABLE users (id bigint PRIMARY KEY, email text);",
+			wantKinds: []string{"alter_column_type", "alter_column_type"},
+		},
+		{
+			name:            "enums",
+			from:            "CREATE TYPE status AS ENUM ('draft', 'published'); CREATE TYPE mood AS ENUM ('ok');",
+			to:              "CREATE TYPE status AS ENUM ('draft', 'review', 'published');",
+			wantKinds:       []string{"add_enum_value", "drop_enum"},
+			wantDestructive: []string{"mood"},
+		},
+		{
+			name:      "renamed index and constraint",
+			from:      "CREATE TABLE t (a int, b int, UNIQUE (a)); CREATE INDEX ON t (b);",
+			to:        "CREATE TABLE t (a int, b int, CONSTRAINT t_a_unique UNIQUE (a)); CREATE INDEX idx_t_b ON t (b);",
+			wantKinds: []string{"rename_constraint", "rename_index"},
+		},
+		{
+			name:      "changed function",
+			from:      "CREATE FUNCTION f(x int) RETURNS int AS $$ SELECT x $$ LANGUAGE sql;",
+			to:        "CREATE OR REPLACE FUNCTION f(x integer) RETURNS int AS $$ SELECT x + 1 $$ LANGUAGE sql;",
+			wantKinds: []string{"replace_function"},
+		},
+		{
+			name: "catalog form matches hand-written form",
+			from: `CREATE TABLE users (
+					id integer DEFAULT nextval('users_id_seq'::regclass) NOT NULL,
+					status character varying(20) DEFAULT 'active'::character varying,
+					age integer
+				);
+				ALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+				ALTER TABLE users ADD CONSTRAINT users_age_check CHECK ((age > 0));
+				CREATE INDEX users_lower_idx ON public.users USING btree (lower((status)::text));`,
+			fromCatalog: true,
+			to: `CREATE TABLE users (id serial PRIMARY KEY, status varchar(20) DEFAULT 'active', age int CHECK (age > 0));
+				CREATE INDEX ON users (lower(status));`,
+			wantKinds: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := NewSchemaSnapshot("public", "from", tt.fromCatalog)
+			if err := from.ApplySQL(tt.from); err != nil {
+				t.Fatalf("ApplySQL(from) failed: %v", err)
+			}
+			to := NewSchemaSnapshot("public", "to", false)
+			if err := to.ApplySQL(tt.to); err != nil {
+				t.Fatalf("ApplySQL(to) failed: %v", err)
+			}
+
+			diff := DiffSchemas(from, to)
+			var kinds, destructive []string
+			for _, c := range diff.Changes {
+				kinds = append(kinds, c.Kind)
+				if c.Destructive {
+					destructive = append(destructive, c.Object)
+				}
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.wantKinds, ",") {
+				t.Errorf("Expected changes %v, got %v\n%s", tt.wantKinds, kinds, diff.SQL())
+			}
+			if strings.Join(destructive, ",") != strings.Join(tt.wantDestructive, ",") {
+				t.Errorf("Expected destructive %v, got %v", tt.wantDestructive, destructive)
+			}
+
+			// Applying the up diff to the from schema must reach the to schema
+			applied := NewSchemaSnapshot("public", "applied", tt.fromCatalog)
+			if err := applied.ApplySQL(tt.from + "\n" + diff.SQL()); err != nil {
+				t.Fatalf("ApplySQL(diff) failed: %v\n%s", err, diff.SQL())
+			}
+			if remaining := DiffSchemas(applied, to).Changes; len(remaining) > 0 {
+				t.Errorf("Expected no changes after applying diff, got %+v", remaining)
+			}
+		})
+	}
+}
+
+func TestLoadSchemaDirectory_RepositoryMigrations(t *testing.T) {
+	dir := "../../platform/storage/postgres/migrations"
+	snapshot, err := LoadSchemaDirectory(dir, "public")
+	if err != nil {
+		t.Fatalf("LoadSchemaDirectory failed: %v", err)
+	}
+	if len(snapshot.Tables) == 0 || len(snapshot.Functions) == 0 {
+		t.Fatalf("Expected tables and functions, got %d tables and %d functions", len(snapshot.Tables), len(snapshot.Functions))
+	}
+
+	// Rolling every migration back leaves nothing to diff against an empty schema
+	empty := NewSchemaSnapshot("public", "empty", false)
+	down := DiffSchemas(snapshot, empty)
+	if len(down.Destructive()) == 0 {
+		t.Error("Expected dropping the schema to be destructive")
+	}
+	if changes := DiffSchemas(snapshot, snapshot).Changes; len(changes) != 0 {
+		t.Errorf("Expected no changes between identical schemas, got %d", len(changes))
+	}
+}
+
+func TestLoadSchemaDump(t *testing.T) {
+	dump := `\restrict abc
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+CREATE TYPE public.role AS ENUM (
+    'admin',
+    'member'
+);
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    role public.role DEFAULT 'member'::public.role NOT NULL
+);
+CREATE SEQUENCE public.users_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+\unrestrict abc
+`
+	path := t.TempDir() + "/schema.sql"
+	if err := os.WriteFile(path, []byte(dump), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fromDump, err := LoadSchemaDump(path, "public")
+	if err != nil {
+		t.Fatalf("LoadSchemaDump failed: %v", err)
+	}
+	fromSQL := NewSchemaSnapshot("public", "sql", true)
+	if err := fromSQL.ApplySQL(`CREATE TYPE role AS ENUM ('admin', 'member');
+		CREATE TABLE users (id serial PRIMARY KEY, role role NOT NULL DEFAULT 'member');`); err != nil {
+		t.Fatal(err)
+	}
+	if changes := DiffSchemas(fromDump, fromSQL).Changes; len(changes) != 0 {
+		t.Errorf("Expected dump to match DDL, got %+v", changes)
+	}
+}
+
+func TestPostgresTool_DiffSchema(t *testing.T) {
+	pgTool := NewPostgresTool(config.Postgres{}, nil, slog.Default())
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_init.up.sql":    "CREATE TABLE users (id serial PRIMARY KEY, legacy text);",
+		"001_init.down.sql":  "DROP TABLE users;",
+		"002_email.up.sql":   "ALTER TABLE users ADD COLUMN email text;",
+		"002_email.down.sql": "ALTER TABLE users DROP COLUMN email;",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := pgTool.Execute(context.Background(), &tool.ToolInput{
+		Parameters: map[string]interface{}{
+			"action": "diff_schema",
+			"from":   "directory:" + dir,
+			"to":     "sql:CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL);",
+			"name":   "Tighten users",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Expected success but got error: %s", result.Error)
+	}
+
+	migration, ok := result.Data.Result.(*MigrationResult)
+	if !ok {
+		t.Fatalf("Expected *MigrationResult, got %T", result.Data.Result)
+	}
+	if migration.Filename != "003_tighten_users.up.sql" || migration.DownFilename != "003_tighten_users.down.sql" {
+		t.Errorf("Unexpected filenames %s and %s", migration.Filename, migration.DownFilename)
+	}
+	if len(migration.Destructive) != 1 {
+		t.Errorf("Expected the dropped column to be destructive, got %v", migration.Destructive)
+	}
+	for _, want := range []string{"WARNING", "ALTER TABLE users ALTER COLUMN email SET NOT NULL;", "ALTER TABLE users DROP COLUMN legacy;"} {
+		if !strings.Contains(migration.UpSQL, want) {
+			t.Errorf("Expected up migration to contain %q:\n%s", want, migration.UpSQL)
+		}
+	}
+	if !strings.Contains(migration.DownSQL, "ALTER TABLE users ADD COLUMN legacy text;") {
+		t.Errorf("Expected down migration to restore the column:\n%s", migration.DownSQL)
+	}
+
+	result, err = pgTool.Execute(context.Background(), &tool.ToolInput{
+		Parameters: map[string]interface{}{"action": "diff_schema", "from": "sql:", "to": "nowhere"},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.Success {
+		t.Error("Expected failure for an unknown schema source")
+	}
+}
+
+func TestLockAnalyzer_Analyze(t *testing.T) {
+	tests := []struct {
+		name      string
+		migration string
+		version   int
+		want      []StatementLock // Operation, Lock, Rewrite, Scan and Risk are compared, and Table with Index
+		wantSafe  []string        // the safe rewrite of the first statement
+	}{
+		{
+			name:      "blocking index build",
+			migration: "CREATE INDEX idx_users_email ON users (email);",
+			want:      []StatementLock{{Operation: "CREATE INDEX", Lock: LockShare, Scan: true, Risk: "medium"}},
+			wantSafe:  []string{"CREATE INDEX CONCURRENTLY idx_users_email ON users (email)"},
+		},
+		{
+			name:      "concurrent index build",
+			migration: "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);",
+			want:      []StatementLock{{Operation: "CREATE INDEX CONCURRENTLY", Lock: LockShareUpdateExclusive, Scan: true, Risk: "low"}},
+		},
+		{
+			name:      "constant default",
+			migration: "ALTER TABLE users ADD COLUMN active boolean NOT NULL DEFAULT true;",
+			want:      []StatementLock{{Operation: "ADD COLUMN", Lock: LockAccessExclusive, Risk: "low"}},
+		},
+		{
+			name:      "constant default before 11",
+			migration: "ALTER TABLE users ADD COLUMN active boolean NOT NULL DEFAULT true;",
+			version:   10,
+			want:      []StatementLock{{Operation: "ADD COLUMN", Lock: LockAccessExclusive, Rewrite: true, Risk: "high"}},
+		},
+		{
+			name:      "volatile default",
+			migration: "ALTER TABLE users ADD COLUMN token uuid DEFAULT gen_random_uuid();",
+			want:      []StatementLock{{Operation: "ADD COLUMN", Lock: LockAccessExclusive, Rewrite: true, Risk: "high"}},
+			wantSafe: []string{
+				"ALTER TABLE users ADD COLUMN token uuid",
+				"ALTER TABLE users ALTER COLUMN token SET DEFAULT gen_random_uuid()",
+				"UPDATE users SET token = gen_random_uuid() WHERE token IS NULL AND id BETWEEN $1 AND $2",
+				"Repeat the backfill for each id range, then add NOT NULL and other constraints in a later migration",
+			},
+		},
+		{
+			name:      "serial column",
+			migration: "ALTER TABLE app.users ADD COLUMN seq bigserial NOT NULL;",
+			want:      []StatementLock{{Operation: "ADD COLUMN", Lock: LockAccessExclusive, Rewrite: true, Risk: "high"}},
+			wantSafe: []string{
+				"ALTER TABLE app.users ADD COLUMN seq bigint",
+				"CREATE SEQUENCE app.users_seq_seq OWNED BY app.users.seq",
+				"ALTER TABLE app.users ALTER COLUMN seq SET DEFAULT nextval('app.users_seq_seq')",
+				"UPDATE app.users SET seq = nextval('app.users_seq_seq') WHERE seq IS NULL AND id BETWEEN $1 AND $2",
+				"Repeat the backfill for each id range, then add NOT NULL and other constraints in a later migration",
+			},
+		},
+		{
+			name:      "stored generated column",
+			migration: "ALTER TABLE orders ADD COLUMN total numeric GENERATED ALWAYS AS (price * qty) STORED;",
+			want:      []StatementLock{{Operation: "ADD COLUMN", Lock: LockAccessExclusive, Rewrite: true, Risk: "high"}},
+			wantSafe: []string{
+				"ALTER TABLE orders ADD COLUMN total numeric",
+				"UPDATE orders SET total = (price * qty) WHERE total IS NULL AND id BETWEEN $1 AND $2",
+				"Repeat the backfill for each id range and keep total filled from the application or a trigger; turning it into a generated column rewrites the table",
+			},
+		},
+		{
+			name:      "type change",
+			migration: "ALTER TABLE users ALTER COLUMN id TYPE bigint;",
+			want:      []StatementLock{{Operation: "ALTER COLUMN TYPE", Lock: LockAccessExclusive, Rewrite: true, Risk: "high"}},
+			wantSafe: []string{
+				"ALTER TABLE users ADD COLUMN id_new bigint",
+				"Write to both columns from the application (or a trigger) and backfill in batches",
+				"Swap the columns in a later migration: DROP COLUMN id, then RENAME COLUMN id_new TO id",
+			},
+		},
+		{
+			name:      "foreign key",
+			migration: "ALTER TABLE posts ADD CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id);",
+			want:      []StatementLock{{Operation: "ADD FOREIGN KEY", Lock: LockShareRowExclusive, Scan: true, Risk: "medium"}},
+			wantSafe: []string{
+				"ALTER TABLE posts ADD CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID",
+				"ALTER TABLE posts VALIDATE CONSTRAINT posts_user_fk",
+			},
+		},
+		{
+			name:      "unnamed foreign key",
+			migration: "ALTER TABLE posts ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;",
+			want:      []StatementLock{{Operation: "ADD FOREIGN KEY", Lock: LockShareRowExclusive, Scan: true, Risk: "medium"}},
+			wantSafe: []string{
+				"ALTER TABLE posts ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE NOT VALID",
+				"ALTER TABLE posts VALIDATE CONSTRAINT posts_user_id_fkey",
+			},
+		},
+		{
+			name:      "unnamed check",
+			migration: "ALTER TABLE users ADD CHECK (age > 0);",
+			want:      []StatementLock{{Operation: "ADD CHECK", Lock: LockAccessExclusive, Scan: true, Risk: "high"}},
+			wantSafe: []string{
+				"ALTER TABLE users ADD CONSTRAINT users_age_check CHECK (age > 0) NOT VALID",
+				"ALTER TABLE users VALIDATE CONSTRAINT users_age_check",
+			},
+		},
+		{
+			name: "foreign key not valid then validate",
+			migration: `ALTER TABLE posts ADD CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID;
+				ALTER TABLE posts VALIDATE CONSTRAINT posts_user_fk;`,
+			want: []StatementLock{
+				{Operation: "ADD FOREIGN KEY", Lock: LockShareRowExclusive, Risk: "low"},
+				{Operation: "VALIDATE CONSTRAINT", Lock: LockShareUpdateExclusive, Scan: true, Risk: "low"},
+			},
+		},
+		{
+			name:      "set not null",
+			migration: "ALTER TABLE users ALTER COLUMN email SET NOT NULL;",
+			want:      []StatementLock{{Operation: "SET NOT NULL", Lock: LockAccessExclusive, Scan: true, Risk: "high"}},
+			wantSafe: []string{
+				"ALTER TABLE users ADD CONSTRAINT users_email_not_null CHECK (email IS NOT NULL) NOT VALID",
+				"ALTER TABLE users VALIDATE CONSTRAINT users_email_not_null",
+				"ALTER TABLE users ALTER COLUMN email SET NOT NULL",
+				"ALTER TABLE users DROP CONSTRAINT users_email_not_null",
+			},
+		},
+		{
+			name: "set not null after validated check",
+			migration: `ALTER TABLE users ADD CONSTRAINT users_email_not_null CHECK (email IS NOT NULL) NOT VALID;
+				ALTER TABLE users VALIDATE CONSTRAINT users_email_not_null;
+				ALTER TABLE users ALTER COLUMN email SET NOT NULL;`,
+			want: []StatementLock{
+				{Operation: "ADD CHECK", Lock: LockAccessExclusive, Risk: "low"},
+				{Operation: "VALIDATE CONSTRAINT", Lock: LockShareUpdateExclusive, Scan: true, Risk: "low"},
+				{Operation: "SET NOT NULL", Lock: LockAccessExclusive, Risk: "low"},
+			},
+		},
+		{
+			name:      "new table",
+			migration: "CREATE TABLE tags (id bigint PRIMARY KEY, name text);\nCREATE INDEX idx_tags_name ON tags (name);",
+			want:      []StatementLock{{Operation: "CREATE INDEX", Lock: LockShare, Scan: true, Risk: "low"}},
+		},
+		{
+			name:      "drop index",
+			migration: "DROP INDEX idx_users_email;",
+			want:      []StatementLock{{Operation: "DROP INDEX", Index: "idx_users_email", Lock: LockAccessExclusive, Risk: "low"}},
+			wantSafe:  []string{"DROP INDEX CONCURRENTLY IF EXISTS idx_users_email"},
+		},
+		{
+			name:      "drop index created by the migration",
+			migration: "CREATE INDEX CONCURRENTLY idx_users_email ON app.users (email);\nDROP INDEX CONCURRENTLY app.idx_users_email;",
+			want: []StatementLock{
+				{Operation: "CREATE INDEX CONCURRENTLY", Lock: LockShareUpdateExclusive, Scan: true, Risk: "low"},
+				{Operation: "DROP INDEX CONCURRENTLY", Table: "app.users", Index: "app.idx_users_email", Lock: LockShareUpdateExclusive, Risk: "low"},
+			},
+		},
+		{
+			name:      "blocking reindex",
+			migration: "REINDEX (VERBOSE) INDEX app.idx_users_email;",
+			want:      []StatementLock{{Operation: "REINDEX", Lock: LockAccessExclusive, Scan: true, Risk: "high"}},
+			wantSafe:  []string{"REINDEX INDEX CONCURRENTLY app.idx_users_email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, errs := parseSQL(tt.migration)
+			if len(errs) > 0 {
+				t.Fatalf("parse failed: %v", errs)
+			}
+			analysis := NewLockAnalyzer(slog.Default(), nil, tt.version).Analyze(context.Background(), tt.migration, script)
+			if len(analysis.Statements) != len(tt.want) {
+				t.Fatalf("Expected %d statements, got %+v", len(tt.want), analysis.Statements)
+			}
+			for i, want := range tt.want {
+				got := analysis.Statements[i]
+				if got.Operation != want.Operation || got.Lock != want.Lock || got.Rewrite != want.Rewrite ||
+					got.Scan != want.Scan || got.Risk != want.Risk {
+					t.Errorf("Statement %d: expected %s %s rewrite=%v scan=%v risk=%s, got %s %s rewrite=%v scan=%v risk=%s",
+						i, want.Operation, want.Lock, want.Rewrite, want.Scan, want.Risk,
+						got.Operation, got.Lock, got.Rewrite, got.Scan, got.Risk)
+				}
+				if want.Index != "" && (got.Index != want.Index || got.Table != want.Table) {
+					t.Errorf("Statement %d: expected index %q of table %q, got index %q of table %q",
+						i, want.Index, want.Table, got.Index, got.Table)
+				}
+			}
+			if tt.wantSafe != nil && !reflect.DeepEqual(analysis.Statements[0].SafeRewrite, tt.wantSafe) {
+				t.Errorf("Expected safe rewrite %q, got %q", tt.wantSafe, analysis.Statements[0].SafeRewrite)
+			}
+		})
+	}
+}
+
+func TestIsBinaryCoercible(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"character varying(50)", "character varying(100)", true},
+		{"character varying(100)", "character varying(50)", false},
+		{"character varying(50)", "text", true},
+		{"numeric(10,2)", "numeric(12,2)", true},
+		{"numeric(10,2)", "numeric(12,3)", false},
+		{"integer", "bigint", false},
+	}
+	for _, tt := range tests {
+		if got := isBinaryCoercible(tt.from, tt.to); got != tt.want {
+			t.Errorf("isBinaryCoercible(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -9,7 +9,10 @@ type PostgresInput struct {
 	Schema           string `json:"schema,omitempty"`
 	Table            string `json:"table,omitempty"`
 	MigrationType    string `json:"migration_type,omitempty"`
+	Connection       string `json:"connection,omitempty"`
 	ConnectionString string `json:"connection_string,omitempty"`
+	Analyze          bool   `json:"analyze,omitempty"`
+	MaxRows          int    `json:"max_rows,omitempty"`
 }
 
 // Validate checks if the input parameters are valid
@@ -20,7 +23,7 @@ func (p *PostgresInput) Validate() error {
 
 	// Validate action-specific requirements
 	switch p.Action {
-	case "analyze_query", "optimize_query", "explain_query":
+	case "analyze_query", "optimize_query", "explain_query", "execute_query":
 		if p.Query == "" {
 			return fmt.Errorf("query is required for %s action", p.Action)
 		}
@@ -59,9 +62,18 @@ func (p *PostgresInput) ToMap() map[string]interface{} {
 	if p.MigrationType != "" {
 		m["migration_type"] = p.MigrationType
 	}
+	if p.Connection != "" {
+		m["connection"] = p.Connection
+	}
 	if p.ConnectionString != "" {
 		m["connection_string"] = p.ConnectionString
 	}
+	if p.Analyze {
+		m["analyze"] = p.Analyze
+	}
+	if p.MaxRows > 0 {
+		m["max_rows"] = p.MaxRows
+	}
 
 	return m
 }
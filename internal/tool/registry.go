@@ -62,6 +62,19 @@ type Registry struct {
 	info      map[string]ToolInfo
 	mutex     sync.RWMutex
 	logger    *slog.Logger
+
+	// Execution counts and health, maintained by Execute and the Supervisor
+	usage       map[string]*usage
+	health      map[string]bool   // result of the last health check
+	quarantined map[string]string // reason by quarantined tool
+	lastCheck   time.Time
+}
+
+// usage counts the executions of a tool
+type usage struct {
+	total, failed int64
+	totalTime     time.Duration
+	lastExecution time.Time
 }
 
 // NewRegistry creates a new tool registry
@@ -71,6 +84,10 @@ func NewRegistry(logger *slog.Logger) *Registry {
 		factories: make(map[string]ToolFactory),
 		info:      make(map[string]ToolInfo),
 		logger:    logger,
+
+		usage:       make(map[string]*usage),
+		health:      make(map[string]bool),
+		quarantined: make(map[string]string),
 	}
 }
 
@@ -111,9 +128,12 @@ func (r *Registry) Unregister(name string) error {
 		delete(r.tools, name)
 	}
 
-	// Remove factory and info
+	// Remove factory, info and health
 	delete(r.factories, name)
 	delete(r.info, name)
+	delete(r.usage, name)
+	delete(r.health, name)
+	delete(r.quarantined, name)
 
 	r.logger.Debug("Tool unregistered", slog.String("tool", name))
 	return nil
@@ -174,11 +194,23 @@ func (r *Registry) GetTool(name string, config *ToolConfig) (Tool, error) {
 	return tool, nil
 }
 
-// Execute executes a tool with the given input
+// Execute executes a tool with the given input. Quarantined tools are
+// refused until the Supervisor re-admits them.
 func (r *Registry) Execute(ctx context.Context, name string, input *ToolInput, config *ToolConfig) (*ToolResult, error) {
 	startTime := time.Now()
 
-	tool, err := r.GetTool(name, config)
+	if reason, quarantined := r.Quarantined(name); quarantined {
+		err := NewToolQuarantinedError(name, reason)
+		return &ToolResult{
+			Success:       false,
+			Error:         fmt.Sprintf("tool %s is quarantined: %s", name, reason),
+			ExecutionTime: time.Since(startTime),
+		}, err
+	}
+
+	// Instances are shared by every caller, so per-call config is applied
+	// here rather than passed to the factory
+	tool, err := r.GetTool(name, nil)
 	if err != nil {
 		return &ToolResult{
 			Success:       false,
@@ -193,13 +225,17 @@ func (r *Registry) Execute(ctx context.Context, name string, input *ToolInput, c
 			Parameters: make(map[string]interface{}),
 		}
 	}
+	if config != nil {
+		input = applyConfig(input, config)
+	}
 
 	r.logger.Debug("Executing tool",
 		slog.String("tool", name),
 		slog.Any("parameters", input.Parameters),
 		slog.Any("context", input.Context))
 
-	result, err := tool.Execute(ctx, input)
+	result, err := r.executeWithConfig(ctx, tool, input, config)
+	r.recordExecution(name, err == nil && (result == nil || result.Success), time.Since(startTime))
 	if err != nil {
 		r.logger.Error("Tool execution failed",
 			slog.String("tool", name),
@@ -239,19 +275,25 @@ func (r *Registry) IsRegistered(name string) bool {
 	return exists
 }
 
-// ListTools returns a list of all registered tools
+// ListTools returns a list of all registered tools that are not
+// quarantined
 func (r *Registry) ListTools() []ToolInfo {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
 	tools := make([]ToolInfo, 0, len(r.info))
-	for _, info := range r.info {
-		tools = append(tools, info)
+	for name, info := range r.info {
+		if _, quarantined := r.quarantined[name]; !quarantined {
+			tools = append(tools, info)
+		}
 	}
 
 	// If no instances exist, create info from factories
-	if len(tools) == 0 {
+	if len(r.info) == 0 {
 		for name := range r.factories {
+			if _, quarantined := r.quarantined[name]; quarantined {
+				continue
+			}
 			tools = append(tools, ToolInfo{
 				Name:        name,
 				Description: "Tool description not available",
@@ -277,6 +319,9 @@ func (r *Registry) GetToolInfo(name string) (*ToolInfo, error) {
 	defer r.mutex.RUnlock()
 
 	if info, exists := r.info[name]; exists {
+		if _, quarantined := r.quarantined[name]; quarantined {
+			info.IsEnabled = false
+		}
 		return &info, nil
 	}
 
@@ -326,6 +371,7 @@ func (r *Registry) Stats(ctx context.Context) (*RegistryStats, error) {
 
 	toolStats := make(map[string]*ToolStats)
 	activeCount := 0
+	unhealthyCount := 0
 
 	for name := range r.factories {
 		isActive := r.tools[name] != nil
@@ -333,31 +379,136 @@ func (r *Registry) Stats(ctx context.Context) (*RegistryStats, error) {
 			activeCount++
 		}
 
-		toolStats[name] = &ToolStats{
-			TotalExecutions:   0,           // TODO: Implement execution tracking
-			SuccessfulRuns:    0,           // TODO: Implement success tracking
-			FailedRuns:        0,           // TODO: Implement error tracking
-			AverageRunTime:    0,           // TODO: Implement timing tracking
-			LastExecutionTime: time.Time{}, // TODO: Implement execution tracking
-			ErrorRate:         0,           // 0% error rate until tracking is implemented
-			IsHealthy:         true,        // Default to healthy
+		// Tools are healthy until a health check says otherwise
+		healthy, checked := r.health[name]
+		isHealthy := !checked || healthy
+		if isActive && !isHealthy {
+			unhealthyCount++
+		}
+
+		stat := &ToolStats{IsHealthy: isHealthy}
+		if u := r.usage[name]; u != nil {
+			stat.TotalExecutions = u.total
+			stat.SuccessfulRuns = u.total - u.failed
+			stat.FailedRuns = u.failed
+			stat.AverageRunTime = u.totalTime / time.Duration(u.total)
+			stat.LastExecutionTime = u.lastExecution
+			stat.ErrorRate = float64(u.failed) / float64(u.total)
 		}
+		toolStats[name] = stat
 	}
 
+	lastCheck := r.lastCheck
+	if lastCheck.IsZero() {
+		lastCheck = time.Now()
+	}
 	stats := &RegistryStats{
 		TotalTools:      len(r.factories),
 		EnabledTools:    activeCount,
 		DisabledTools:   len(r.factories) - activeCount,
 		ToolsByCategory: r.countByCategory(),
 		ExecutionStats:  toolStats,
-		LastHealthCheck: time.Now(),
-		HealthyTools:    activeCount, // TODO: Implement actual health checking
-		UnhealthyTools:  0,           // TODO: Implement actual health checking
+		LastHealthCheck: lastCheck,
+		HealthyTools:    activeCount - unhealthyCount,
+		UnhealthyTools:  unhealthyCount,
 	}
 
 	return stats, nil
 }
 
+// recordExecution counts an execution of a tool
+func (r *Registry) recordExecution(name string, success bool, duration time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.factories[name]; !exists {
+		return
+	}
+	u := r.usage[name]
+	if u == nil {
+		u = &usage{}
+		r.usage[name] = u
+	}
+	u.total++
+	if !success {
+		u.failed++
+	}
+	u.totalTime += duration
+	u.lastExecution = time.Now()
+}
+
+// usageCounts returns the executions and failures of a tool so far
+func (r *Registry) usageCounts(name string) (total, failed int64) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if u := r.usage[name]; u != nil {
+		return u.total, u.failed
+	}
+	return 0, 0
+}
+
+// instances returns the instantiated tools by name
+func (r *Registry) instances() map[string]Tool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tools := make(map[string]Tool, len(r.tools))
+	for name, tool := range r.tools {
+		tools[name] = tool
+	}
+	return tools
+}
+
+// setHealth records the result of a health check
+func (r *Registry) setHealth(name string, healthy bool, checkedAt time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.factories[name]; !exists {
+		return
+	}
+	r.health[name] = healthy
+	if checkedAt.After(r.lastCheck) {
+		r.lastCheck = checkedAt
+	}
+}
+
+// Quarantine hides a tool from ListTools and refuses to execute it
+func (r *Registry) Quarantine(name, reason string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.factories[name]; !exists {
+		return fmt.Errorf("tool %s is not registered", name)
+	}
+	r.quarantined[name] = reason
+	r.logger.Warn("Tool quarantined",
+		slog.String("tool", name),
+		slog.String("reason", reason))
+	return nil
+}
+
+// Readmit lifts the quarantine of a tool
+func (r *Registry) Readmit(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, quarantined := r.quarantined[name]; quarantined {
+		delete(r.quarantined, name)
+		r.logger.Info("Tool re-admitted", slog.String("tool", name))
+	}
+}
+
+// Quarantined reports whether a tool is quarantined and why
+func (r *Registry) Quarantined(name string) (string, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	reason, quarantined := r.quarantined[name]
+	return reason, quarantined
+}
+
 // countByCategory counts tools by category
 func (r *Registry) countByCategory() map[string]int {
 	counts := make(map[string]int)
@@ -387,6 +538,9 @@ func (r *Registry) Close(ctx context.Context) error {
 	r.tools = make(map[string]Tool)
 	r.factories = make(map[string]ToolFactory)
 	r.info = make(map[string]ToolInfo)
+	r.usage = make(map[string]*usage)
+	r.health = make(map[string]bool)
+	r.quarantined = make(map[string]string)
 
 	r.logger.Info("Tool registry closed")
 	return lastErr
@@ -417,6 +571,58 @@ func (r *Registry) getToolCategory(name string) string {
 // LEGACY COMPATIBILITY METHODS
 // These methods provide backward compatibility with existing code that uses map[string]interface{}
 
+// applyConfig returns a copy of input carrying config, with the pinned
+// parameters in config.Custom replacing those given by the caller
+func applyConfig(input *ToolInput, config *ToolConfig) *ToolInput {
+	configured := *input
+	configured.Config = config
+	if len(config.Custom) > 0 {
+		params := make(map[string]interface{}, len(input.Parameters)+len(config.Custom))
+		for k, v := range input.Parameters {
+			params[k] = v
+		}
+		for k, v := range config.Custom {
+			params[k] = v
+		}
+		configured.Parameters = params
+	}
+	return &configured
+}
+
+// executeWithConfig runs a tool within config's timeout, retrying failed
+// executions up to config.MaxRetries times. Unsuccessful results are not
+// retried; they are the tool's answer.
+func (r *Registry) executeWithConfig(ctx context.Context, tool Tool, input *ToolInput, config *ToolConfig) (*ToolResult, error) {
+	if config == nil {
+		return tool.Execute(ctx, input)
+	}
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	result, err := tool.Execute(ctx, input)
+	for attempt := 1; err != nil && attempt <= config.MaxRetries; attempt++ {
+		if config.RetryDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return result, err
+			case <-time.After(config.RetryDelay):
+			}
+		}
+		if ctx.Err() != nil {
+			return result, err
+		}
+		r.logger.Debug("Retrying tool execution",
+			slog.String("tool", tool.Name()),
+			slog.Int("attempt", attempt),
+			slog.Any("error", err))
+		result, err = tool.Execute(ctx, input)
+	}
+	return result, err
+}
+
 // ExecuteLegacy executes a tool with legacy map[string]interface{} input for backward compatibility
 func (r *Registry) ExecuteLegacy(ctx context.Context, name string, input map[string]interface{}, config map[string]interface{}) (*ToolResult, error) {
 	// Convert legacy input and config to new types
@@ -3,8 +3,10 @@ package tool
 import (
 	"context"
 	"errors"
+	"io"
 	"log/slog"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -828,3 +830,96 @@ func (s *slowTool) Execute(ctx context.Context, input *ToolInput) (*ToolResult,
 }
 func (s *slowTool) Health(ctx context.Context) error { return nil }
 func (s *slowTool) Close(ctx context.Context) error  { return nil }
+
+// flakyTool fails a number of times before succeeding and records the
+// input of its last execution
+type flakyTool struct {
+	testTool
+	failures  int
+	attempts  int
+	lastInput *ToolInput
+	deadline  bool
+}
+
+func (f *flakyTool) Execute(ctx context.Context, input *ToolInput) (*ToolResult, error) {
+	f.attempts++
+	f.lastInput = input
+	_, f.deadline = ctx.Deadline()
+	if f.attempts <= f.failures {
+		return nil, errors.New("temporary failure")
+	}
+	return &ToolResult{Success: true}, nil
+}
+
+// TestRegistryExecuteConfig tests that per-call config is applied to the
+// execution rather than the shared instance
+func TestRegistryExecuteConfig(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name         string
+		failures     int
+		config       *ToolConfig
+		wantErr      bool
+		wantAttempts int
+		wantParams   map[string]interface{}
+		wantDeadline bool
+	}{
+		{
+			name:         "no_config",
+			wantAttempts: 1,
+			wantParams:   map[string]interface{}{"connection": "main", "query": "SELECT 1"},
+		},
+		{
+			name:         "pinned_parameters",
+			config:       &ToolConfig{Custom: map[string]interface{}{"connection": "analytics"}},
+			wantAttempts: 1,
+			wantParams:   map[string]interface{}{"connection": "analytics", "query": "SELECT 1"},
+		},
+		{
+			name:         "retries_until_success",
+			failures:     2,
+			config:       &ToolConfig{MaxRetries: 2, RetryDelay: time.Millisecond, Timeout: time.Second},
+			wantAttempts: 3,
+			wantParams:   map[string]interface{}{"connection": "main", "query": "SELECT 1"},
+			wantDeadline: true,
+		},
+		{
+			name:         "retries_exhausted",
+			failures:     5,
+			config:       &ToolConfig{MaxRetries: 1},
+			wantErr:      true,
+			wantAttempts: 2,
+			wantParams:   map[string]interface{}{"connection": "main", "query": "SELECT 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyTool{failures: tt.failures}
+			registry := NewRegistry(logger)
+			_ = registry.Register("flaky", func(*ToolConfig, *slog.Logger) (Tool, error) { return flaky, nil })
+
+			input := &ToolInput{Parameters: map[string]interface{}{"connection": "main", "query": "SELECT 1"}}
+			_, err := registry.Execute(context.Background(), "flaky", input, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if flaky.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", flaky.attempts, tt.wantAttempts)
+			}
+			if !reflect.DeepEqual(flaky.lastInput.Parameters, tt.wantParams) {
+				t.Errorf("parameters = %v, want %v", flaky.lastInput.Parameters, tt.wantParams)
+			}
+			if flaky.lastInput.Config != tt.config {
+				t.Errorf("input config = %+v, want %+v", flaky.lastInput.Config, tt.config)
+			}
+			if flaky.deadline != tt.wantDeadline {
+				t.Errorf("deadline set = %v, want %v", flaky.deadline, tt.wantDeadline)
+			}
+			if input.Parameters["connection"] != "main" {
+				t.Error("Execute() modified the caller's parameters")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// cacheSource is recorded in search_cache.source
+const cacheSource = "searxng"
+
+// Cache stores search responses by query key
+type Cache interface {
+	// Get returns the cached response, or nil when there is none or it expired
+	Get(ctx context.Context, key string) (*Response, error)
+	Set(ctx context.Context, key, query string, resp *Response, ttl time.Duration) error
+}
+
+// QueriesCache implements Cache on top of the search_cache table
+type QueriesCache struct {
+	queries *sqlc.Queries
+}
+
+// NewQueriesCache creates a search cache backed by sqlc queries
+func NewQueriesCache(queries *sqlc.Queries) *QueriesCache {
+	return &QueriesCache{
+		queries: queries,
+	}
+}
+
+// Get returns an unexpired cached response
+func (c *QueriesCache) Get(ctx context.Context, key string) (*Response, error) {
+	row, err := c.queries.GetSearchCache(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search cache: %w", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(row.Results, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode cached results: %w", err)
+	}
+	return &resp, nil
+}
+
+// Set stores a response until ttl passes and drops expired entries
+func (c *QueriesCache) Set(ctx context.Context, key, query string, resp *Response, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+
+	err = c.queries.UpsertSearchCache(ctx, sqlc.UpsertSearchCacheParams{
+		QueryHash: key,
+		QueryText: query,
+		Results:   data,
+		Source:    cacheSource,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write search cache: %w", err)
+	}
+
+	if _, err := c.queries.DeleteExpiredSearchCache(ctx); err != nil {
+		return fmt.Errorf("failed to expire search cache: %w", err)
+	}
+	return nil
+}
+
+// cacheKey hashes everything that changes a response, so the same words
+// searched in another language or with page text are cached apart
+func cacheKey(q Query, limit, fetch int) string {
+	parts := []string{
+		strings.ToLower(collapseSpace(q.Text)),
+		strings.Join(q.Categories, ","),
+		q.Language,
+		q.TimeRange,
+		strconv.Itoa(q.Page),
+		strconv.Itoa(limit),
+		strconv.Itoa(fetch),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// maxPageBytes caps how much of a page is downloaded
+	maxPageBytes = 2 << 20
+	// maxContentChars caps the extracted text per page
+	maxContentChars = 4000
+)
+
+// skippedElements never contain readable text
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Form: true, atom.Button: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+}
+
+// blockElements end a paragraph of text
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Ul: true, atom.Ol: true, atom.Pre: true, atom.Blockquote: true,
+	atom.Table: true, atom.Tr: true, atom.Br: true, atom.Dd: true, atom.Dt: true,
+}
+
+// Fetcher downloads pages and extracts their readable text
+type Fetcher struct {
+	http *http.Client
+}
+
+// NewFetcher creates a fetcher that uses client for requests
+func NewFetcher(client *http.Client) *Fetcher {
+	return &Fetcher{http: client}
+}
+
+// Fetch downloads an HTML or plain text page and returns its readable text
+func (f *Fetcher) Fetch(ctx context.Context, pageURL string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("unsupported URL %q", pageURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html,text/plain;q=0.9")
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s returned %d", pageURL, resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	body := io.LimitReader(resp.Body, maxPageBytes)
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "":
+		doc, err := html.Parse(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", pageURL, err)
+		}
+		return truncate(extractText(doc), maxContentChars), nil
+	case "text/plain", "text/markdown":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", pageURL, err)
+		}
+		return truncate(strings.TrimSpace(string(data)), maxContentChars), nil
+	default:
+		return "", fmt.Errorf("%s is %s, not a text page", pageURL, mediaType)
+	}
+}
+
+// extractText returns the text of the main content of a document: the
+// first article or main element when there is one, otherwise the body,
+// without navigation, scripts and other chrome
+func extractText(doc *html.Node) string {
+	root := findElement(doc, atom.Article)
+	if root == nil {
+		root = findElement(doc, atom.Main)
+	}
+	if root == nil {
+		root = findElement(doc, atom.Body)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	var paragraphs []string
+	var current strings.Builder
+	flush := func() {
+		if text := collapseSpace(current.String()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		current.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			current.WriteString(n.Data)
+			current.WriteByte(' ')
+			return
+		case html.ElementNode:
+			if skippedElements[n.DataAtom] {
+				return
+			}
+		}
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			flush()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			flush()
+		}
+	}
+	walk(root)
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// findElement returns the first element of the given type in document order
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes on a rune boundary, preferring to
+// cut at the end of a paragraph or sentence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	s = s[:cut]
+	if i := strings.LastIndex(s, "\n\n"); i > n/2 {
+		return s[:i]
+	}
+	if i := strings.LastIndex(s, ". "); i > n/2 {
+		return s[:i+1]
+	}
+	return s + "…"
+}
@@ -0,0 +1,276 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client queries the JSON API of a SearXNG instance
+type Client struct {
+	http    *http.Client
+	baseURL string
+}
+
+// NewClient creates a SearXNG client for baseURL. The instance must have
+// the json format enabled in its settings.yml.
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Client{
+		http:    &http.Client{Timeout: timeout},
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Query is a search request
+type Query struct {
+	Text       string
+	Categories []string // e.g. general, it, news
+	Language   string   // e.g. en, zh-TW
+	TimeRange  string   // day, month or year
+	Page       int
+}
+
+// Response is a normalised search response
+type Response struct {
+	Query       string   `json:"query"`
+	Results     []Result `json:"results"`
+	Answers     []string `json:"answers,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Engines that failed to answer, with the reason SearXNG gave
+	Unresponsive map[string]string `json:"unresponsive_engines,omitempty"`
+}
+
+// Result is a single search hit
+type Result struct {
+	Title         string   `json:"title"`
+	URL           string   `json:"url"`
+	Snippet       string   `json:"snippet,omitempty"`
+	Engines       []string `json:"engines,omitempty"`
+	Score         float64  `json:"score,omitempty"`
+	Category      string   `json:"category,omitempty"`
+	PublishedDate string   `json:"published_date,omitempty"`
+	// Content is the readable text of the page, when it was fetched
+	Content string `json:"content,omitempty"`
+}
+
+// searxngResponse is the JSON returned by /search?format=json
+type searxngResponse struct {
+	Query   string `json:"query"`
+	Results []struct {
+		Title         string   `json:"title"`
+		URL           string   `json:"url"`
+		Content       string   `json:"content"`
+		Engine        string   `json:"engine"`
+		Engines       []string `json:"engines"`
+		Score         float64  `json:"score"`
+		Category      string   `json:"category"`
+		PublishedDate *string  `json:"publishedDate"`
+	} `json:"results"`
+	Answers             []json.RawMessage `json:"answers"`
+	Suggestions         []string          `json:"suggestions"`
+	UnresponsiveEngines [][]string        `json:"unresponsive_engines"`
+}
+
+// Search runs a query and returns its normalised results
+func (c *Client) Search(ctx context.Context, q Query) (*Response, error) {
+	params := url.Values{}
+	params.Set("q", q.Text)
+	params.Set("format", "json")
+	if len(q.Categories) > 0 {
+		params.Set("categories", strings.Join(q.Categories, ","))
+	}
+	if q.Language != "" {
+		params.Set("language", q.Language)
+	}
+	if q.TimeRange != "" {
+		params.Set("time_range", q.TimeRange)
+	}
+	if q.Page > 1 {
+		params.Set("pageno", strconv.Itoa(q.Page))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SearXNG: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("SearXNG refused the json format (403); enable it under search.formats in settings.yml")
+		}
+		return nil, fmt.Errorf("SearXNG returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var raw searxngResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode SearXNG response: %w", err)
+	}
+
+	return normalize(q.Text, &raw), nil
+}
+
+// Health checks that the instance answers its health endpoint
+func (c *Client) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/healthz", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("SearXNG is unreachable: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SearXNG health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// normalize cleans up titles and snippets, drops results without a URL,
+// merges duplicates of the same page and orders the rest by score
+func normalize(query string, raw *searxngResponse) *Response {
+	out := &Response{Query: query, Suggestions: raw.Suggestions}
+
+	index := make(map[string]int)
+	for _, r := range raw.Results {
+		u, ok := canonicalURL(r.URL)
+		if !ok {
+			continue
+		}
+		engines := r.Engines
+		if len(engines) == 0 && r.Engine != "" {
+			engines = []string{r.Engine}
+		}
+
+		if i, seen := index[u]; seen {
+			// The same page found through another engine
+			prev := &out.Results[i]
+			prev.Engines = mergeEngines(prev.Engines, engines)
+			prev.Score = max(prev.Score, r.Score)
+			if prev.Snippet == "" {
+				prev.Snippet = collapseSpace(r.Content)
+			}
+			continue
+		}
+
+		result := Result{
+			Title:    collapseSpace(r.Title),
+			URL:      r.URL,
+			Snippet:  collapseSpace(r.Content),
+			Engines:  mergeEngines(nil, engines),
+			Score:    r.Score,
+			Category: r.Category,
+		}
+		if result.Title == "" {
+			result.Title = u
+		}
+		if r.PublishedDate != nil {
+			result.PublishedDate = *r.PublishedDate
+		}
+		index[u] = len(out.Results)
+		out.Results = append(out.Results, result)
+	}
+
+	// SearXNG already orders by score; keep its order for ties
+	sort.SliceStable(out.Results, func(i, j int) bool {
+		return out.Results[i].Score > out.Results[j].Score
+	})
+
+	// Answers are strings in older releases and objects in newer ones
+	for _, a := range raw.Answers {
+		var text string
+		if json.Unmarshal(a, &text) != nil {
+			var obj struct {
+				Answer string `json:"answer"`
+			}
+			if json.Unmarshal(a, &obj) != nil {
+				continue
+			}
+			text = obj.Answer
+		}
+		if text = collapseSpace(text); text != "" {
+			out.Answers = append(out.Answers, text)
+		}
+	}
+
+	for _, e := range raw.UnresponsiveEngines {
+		if len(e) == 0 {
+			continue
+		}
+		if out.Unresponsive == nil {
+			out.Unresponsive = make(map[string]string)
+		}
+		reason := ""
+		if len(e) > 1 {
+			reason = e[1]
+		}
+		out.Unresponsive[e[0]] = reason
+	}
+
+	return out
+}
+
+// trackingParams are query parameters that do not change the page
+var trackingParams = []string{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "gclid", "fbclid"}
+
+// canonicalURL returns the key under which duplicate results are merged:
+// no fragment, tracking parameters or trailing slash, and a lower case
+// host. Only http and https results are kept.
+func canonicalURL(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	u.Scheme = "https"
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	if u.RawQuery != "" {
+		q := u.Query()
+		for _, p := range trackingParams {
+			q.Del(p)
+		}
+		u.RawQuery = q.Encode()
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	return u.String(), true
+}
+
+// mergeEngines appends the engines not yet in list
+func mergeEngines(list, engines []string) []string {
+	for _, e := range engines {
+		found := false
+		for _, have := range list {
+			if have == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// collapseSpace trims s and replaces runs of whitespace with one space
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,302 @@
+// Package search provides the web search tool for the Assistant.
+// It queries a SearXNG instance, caches responses in the search_cache
+// table and can fetch the readable text of top results for grounding.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+const (
+	// maxFetch caps how many result pages are downloaded per search
+	maxFetch = 5
+	// fetchTimeout bounds each page download
+	fetchTimeout = 10 * time.Second
+)
+
+// WebSearchTool implements the Tool interface for web search
+type WebSearchTool struct {
+	config  config.Search
+	client  *Client
+	fetcher *Fetcher
+	cache   Cache
+	logger  *slog.Logger
+}
+
+// NewWebSearchTool creates a new web search tool instance. cache may be
+// nil, in which case every search goes to SearXNG.
+func NewWebSearchTool(cfg config.Search, cache Cache, logger *slog.Logger) *WebSearchTool {
+	if !cfg.EnableCache {
+		cache = nil
+	}
+	return &WebSearchTool{
+		config:  cfg,
+		client:  NewClient(cfg.SearXNGURL, cfg.Timeout),
+		fetcher: NewFetcher(&http.Client{Timeout: fetchTimeout}),
+		cache:   cache,
+		logger:  logger,
+	}
+}
+
+// Name returns the tool name
+func (t *WebSearchTool) Name() string {
+	return "web_search"
+}
+
+// Description returns the tool description
+func (t *WebSearchTool) Description() string {
+	return "Search the web through SearXNG and return ranked results with citations, optionally with the text of the top pages"
+}
+
+// Parameters returns the tool parameter schema
+func (t *WebSearchTool) Parameters() *tool.ToolParametersSchema {
+	return &tool.ToolParametersSchema{
+		Type: "object",
+		Properties: map[string]tool.ParameterProperty{
+			"query": {
+				Type:        tool.ParameterTypeString,
+				Description: "The search query",
+			},
+			"max_results": {
+				Type:        tool.ParameterTypeInteger,
+				Description: "Maximum number of results; defaults to the configured limit",
+			},
+			"categories": {
+				Type:        tool.ParameterTypeString,
+				Description: "Comma-separated SearXNG categories such as general, it or news",
+			},
+			"language": {
+				Type:        tool.ParameterTypeString,
+				Description: "Result language such as en or zh-TW",
+			},
+			"time_range": {
+				Type:        tool.ParameterTypeString,
+				Description: "Only return results from the last day, month or year",
+				Enum:        []string{"day", "month", "year"},
+			},
+			"page": {
+				Type:        tool.ParameterTypeInteger,
+				Description: "Result page, starting at 1",
+			},
+			"fetch_content": {
+				Type:        tool.ParameterTypeInteger,
+				Description: "Fetch and extract the readable text of this many top results (at most 5)",
+			},
+			"use_cache": {
+				Type:        tool.ParameterTypeBoolean,
+				Description: "Serve repeated searches from the cache; defaults to true",
+			},
+		},
+		Required: []string{"query"},
+	}
+}
+
+// Execute runs a search with the given parameters
+func (t *WebSearchTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.ToolResult, error) {
+	startTime := time.Now()
+
+	params := input.Parameters
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+
+	text, _ := params["query"].(string)
+	if strings.TrimSpace(text) == "" {
+		return &tool.ToolResult{
+			Success: false,
+			Error:   "query parameter is required",
+		}, nil
+	}
+
+	q := Query{
+		Text:       strings.TrimSpace(text),
+		Categories: splitList(params["categories"]),
+		Language:   stringParam(params, "language"),
+		TimeRange:  stringParam(params, "time_range"),
+		Page:       intParam(params, "page", 1),
+	}
+	switch q.TimeRange {
+	case "", "day", "month", "year":
+	default:
+		return &tool.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("invalid time_range: %s", q.TimeRange),
+		}, nil
+	}
+
+	limit := intParam(params, "max_results", t.config.MaxResults)
+	if limit <= 0 {
+		limit = 10
+	}
+	fetch := min(max(intParam(params, "fetch_content", 0), 0), maxFetch, limit)
+	useCache := t.cache != nil
+	if v, ok := params["use_cache"].(bool); ok && !v {
+		useCache = false
+	}
+
+	t.logger.Info("Executing web search",
+		slog.String("query", q.Text),
+		slog.Int("max_results", limit),
+		slog.Int("fetch_content", fetch))
+
+	resp, cached, err := t.search(ctx, q, limit, fetch, useCache)
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         err.Error(),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+
+	// Convert result to map[string]interface{} for output
+	var outputMap map[string]interface{}
+	resultJSON, err := json.Marshal(resp)
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         fmt.Sprintf("failed to marshal result: %v", err),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+	if err := json.Unmarshal(resultJSON, &outputMap); err != nil {
+		outputMap = map[string]interface{}{
+			"result": resp,
+		}
+	}
+	outputMap["cached"] = cached
+
+	return &tool.ToolResult{
+		Success: true,
+		Data: &tool.ToolResultData{
+			Output:    outputMap,
+			Citations: citations(resp),
+		},
+		ExecutionTime: time.Since(startTime),
+	}, nil
+}
+
+// search serves a query from the cache or SearXNG, reporting whether the
+// response was cached
+func (t *WebSearchTool) search(ctx context.Context, q Query, limit, fetch int, useCache bool) (*Response, bool, error) {
+	key := cacheKey(q, limit, fetch)
+	if useCache {
+		resp, err := t.cache.Get(ctx, key)
+		if err != nil {
+			// A broken cache should not break search
+			t.logger.Warn("Search cache read failed", slog.Any("error", err))
+		} else if resp != nil {
+			return resp, true, nil
+		}
+	}
+
+	resp, err := t.client.Search(ctx, q)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(resp.Results) > limit {
+		resp.Results = resp.Results[:limit]
+	}
+	if fetch > 0 {
+		t.fetchContent(ctx, resp.Results[:min(fetch, len(resp.Results))])
+	}
+
+	if useCache {
+		if err := t.cache.Set(ctx, key, q.Text, resp, t.config.CacheTTL); err != nil {
+			t.logger.Warn("Search cache write failed", slog.Any("error", err))
+		}
+	}
+	return resp, false, nil
+}
+
+// fetchContent fills in the page text of results concurrently. Pages that
+// cannot be fetched keep their snippet only.
+func (t *WebSearchTool) fetchContent(ctx context.Context, results []Result) {
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(r *Result) {
+			defer wg.Done()
+			content, err := t.fetcher.Fetch(ctx, r.URL)
+			if err != nil {
+				t.logger.Debug("Failed to fetch result content",
+					slog.String("url", r.URL),
+					slog.Any("error", err))
+				return
+			}
+			r.Content = content
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+// citations lists the results as sources in rank order
+func citations(resp *Response) []tool.Citation {
+	list := make([]tool.Citation, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		list = append(list, tool.Citation{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: r.Snippet,
+		})
+	}
+	return list
+}
+
+// stringParam reads a trimmed string parameter
+func stringParam(params map[string]interface{}, key string) string {
+	s, _ := params[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// intParam reads an integer parameter, which arrives as a float64 from
+// JSON
+func intParam(params map[string]interface{}, key string, def int) int {
+	switch v := params[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return def
+}
+
+// splitList reads a comma-separated string or a list of strings
+func splitList(v interface{}) []string {
+	var list []string
+	switch v := v.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	case []interface{}:
+		for _, s := range v {
+			if s, ok := s.(string); ok && strings.TrimSpace(s) != "" {
+				list = append(list, strings.TrimSpace(s))
+			}
+		}
+	}
+	return list
+}
+
+// Health checks that SearXNG is reachable
+func (t *WebSearchTool) Health(ctx context.Context) error {
+	return t.client.Health(ctx)
+}
+
+// Close closes the web search tool
+func (t *WebSearchTool) Close(ctx context.Context) error {
+	t.logger.Debug("Web search tool closed")
+	return nil
+}
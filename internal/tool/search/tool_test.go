@@ -0,0 +1,269 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// memoryCache is an in-process Cache for tests
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*Response
+	ttls    map[string]time.Duration
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: map[string]*Response{}, ttls: map[string]time.Duration{}}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) (*Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key], nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key, query string, resp *Response, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = resp
+	c.ttls[key] = ttl
+	return nil
+}
+
+const article = `<!DOCTYPE html>
+<html><head><title>Go 1.24</title><script>var tracking = 1;</script></head>
+<body>
+<nav><a href="/">Home</a> | <a href="/blog">Blog</a></nav>
+<article>
+  <h1>Go 1.24 is released</h1>
+  <p>Generic type   aliases are now
+  fully supported.</p>
+  <aside>Subscribe to our newsletter</aside>
+  <ul><li>Swiss table maps</li><li>Weak pointers</li></ul>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+
+// searxng starts a SearXNG stand-in and counts the searches it serves
+func searxng(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var searches atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "json" {
+			http.Error(w, "format", http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("q") == "forbidden" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		searches.Add(1)
+		base := "http://" + r.Host
+		fmt.Fprintf(w, `{
+  "query": %q,
+  "results": [
+    {"title": "Plain text notes", "url": "%[2]s/notes.txt", "content": "notes", "engines": ["bing"], "score": 0.5},
+    {"title": "  Go 1.24\n Release Notes ", "url": "%[2]s/article", "content": "What is new", "engine": "google", "score": 3.0, "category": "it", "publishedDate": "2025-02-11T00:00:00"},
+    {"title": "Go 1.24 Release Notes", "url": "%[2]s/article/?utm_source=feed#top", "content": "", "engines": ["duckduckgo", "google"], "score": 2.0},
+    {"title": "Broken", "url": "%[2]s/missing", "content": "gone", "engines": ["bing"], "score": 1.0},
+    {"title": "Script", "url": "javascript:alert(1)", "content": "x", "score": 9.0}
+  ],
+  "answers": ["Go 1.24 was released in February 2025", {"answer": "  Next: Go 1.25 "}],
+  "suggestions": ["go 1.24 generics"],
+  "unresponsive_engines": [["brave", "timeout"]]
+}`, r.URL.Query().Get("q"), base)
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(article))
+	})
+	mux.HandleFunc("/notes.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("  plain notes\n"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &searches
+}
+
+func TestClient_Search(t *testing.T) {
+	server, _ := searxng(t)
+	resp, err := NewClient(server.URL+"/", time.Second).Search(context.Background(), Query{Text: "go 1.24"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	var got []string
+	for _, r := range resp.Results {
+		got = append(got, fmt.Sprintf("%s %v %.1f", r.Title, r.Engines, r.Score))
+	}
+	want := []string{
+		"Go 1.24 Release Notes [google duckduckgo] 3.0",
+		"Broken [bing] 1.0",
+		"Plain text notes [bing] 0.5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %q\nwant %q", got, want)
+	}
+	if first := resp.Results[0]; first.URL != server.URL+"/article" || first.Snippet != "What is new" || first.PublishedDate == "" {
+		t.Errorf("first result = %+v", first)
+	}
+	if !reflect.DeepEqual(resp.Answers, []string{"Go 1.24 was released in February 2025", "Next: Go 1.25"}) {
+		t.Errorf("answers = %q", resp.Answers)
+	}
+	if resp.Unresponsive["brave"] != "timeout" {
+		t.Errorf("unresponsive = %v", resp.Unresponsive)
+	}
+
+	_, err = NewClient(server.URL, time.Second).Search(context.Background(), Query{Text: "forbidden"})
+	if err == nil || !strings.Contains(err.Error(), "settings.yml") {
+		t.Errorf("Search() error = %v, want a hint to enable the json format", err)
+	}
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	server, _ := searxng(t)
+	fetcher := NewFetcher(server.Client())
+
+	text, err := fetcher.Fetch(context.Background(), server.URL+"/article")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	want := "Go 1.24 is released\n\nGeneric type aliases are now fully supported.\n\nSwiss table maps\n\nWeak pointers"
+	if text != want {
+		t.Errorf("text = %q\nwant %q", text, want)
+	}
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/missing"); err == nil {
+		t.Error("Fetch() succeeded for a 404")
+	}
+	if _, err := fetcher.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("Fetch() accepted a file URL")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	s := strings.Repeat("a", 60) + ". " + strings.Repeat("b", 60)
+	if got := truncate(s, 100); got != strings.Repeat("a", 60)+"." {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := truncate("世界世界", 7); got != "世界…" {
+		t.Errorf("truncate() = %q, want a rune boundary", got)
+	}
+}
+
+func TestWebSearchTool_Execute(t *testing.T) {
+	server, searches := searxng(t)
+	cache := newMemoryCache()
+	searchTool := NewWebSearchTool(config.Search{
+		SearXNGURL:  server.URL,
+		Timeout:     time.Second,
+		MaxResults:  2,
+		EnableCache: true,
+		CacheTTL:    time.Hour,
+	}, cache, discardLogger())
+
+	if err := searchTool.Health(context.Background()); err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+
+	execute := func(params map[string]interface{}) *tool.ToolResult {
+		t.Helper()
+		result, err := searchTool.Execute(context.Background(), &tool.ToolInput{Parameters: params})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return result
+	}
+
+	params := map[string]interface{}{"query": "go 1.24", "fetch_content": float64(2)}
+	result := execute(params)
+	if !result.Success {
+		t.Fatalf("Execute() = %+v", result)
+	}
+	if result.Data.Output["cached"] != false {
+		t.Errorf("first search was served from the cache")
+	}
+	results, _ := result.Data.Output["results"].([]interface{})
+	if len(results) != 2 {
+		t.Fatalf("results = %v, want max_results from config", results)
+	}
+	if content, _ := results[0].(map[string]interface{})["content"].(string); !strings.HasPrefix(content, "Go 1.24 is released") {
+		t.Errorf("content = %q", content)
+	}
+	if _, ok := results[1].(map[string]interface{})["content"]; ok {
+		t.Error("a page that failed to fetch has content")
+	}
+	wantCitations := []tool.Citation{
+		{Title: "Go 1.24 Release Notes", URL: server.URL + "/article", Snippet: "What is new"},
+		{Title: "Broken", URL: server.URL + "/missing", Snippet: "gone"},
+	}
+	if !reflect.DeepEqual(result.Data.Citations, wantCitations) {
+		t.Errorf("citations = %+v", result.Data.Citations)
+	}
+
+	// The same search is served from the cache, stored with the configured TTL
+	result = execute(params)
+	if result.Data.Output["cached"] != true || searches.Load() != 1 {
+		t.Errorf("cached = %v after %d searches", result.Data.Output["cached"], searches.Load())
+	}
+	if len(result.Data.Citations) != 2 {
+		t.Errorf("cached citations = %+v", result.Data.Citations)
+	}
+	for _, ttl := range cache.ttls {
+		if ttl != time.Hour {
+			t.Errorf("ttl = %v", ttl)
+		}
+	}
+
+	// Other options are cached apart, and the cache can be bypassed
+	execute(map[string]interface{}{"query": "go 1.24", "fetch_content": float64(2), "language": "en"})
+	execute(map[string]interface{}{"query": "go 1.24", "fetch_content": float64(2), "use_cache": false})
+	if searches.Load() != 3 {
+		t.Errorf("searches = %d, want 3", searches.Load())
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{},
+		{"query": "go", "time_range": "week"},
+		{"query": "forbidden"},
+	} {
+		if result := execute(bad); result.Success {
+			t.Errorf("Execute(%v) succeeded", bad)
+		}
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	a := cacheKey(Query{Text: "Go  Generics"}, 10, 0)
+	if a != cacheKey(Query{Text: "go generics "}, 10, 0) {
+		t.Error("cacheKey() differs for the same words")
+	}
+	if a == cacheKey(Query{Text: "go generics", Categories: []string{"it"}}, 10, 0) || a == cacheKey(Query{Text: "go generics"}, 10, 1) {
+		t.Error("cacheKey() ignores options")
+	}
+	if len(a) != 64 {
+		t.Errorf("cacheKey() length = %d, want 64 for query_hash", len(a))
+	}
+}
@@ -0,0 +1,476 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// AllTools is the settings key that applies to every tool. A tool's own
+// key takes precedence over it within the same scope.
+const AllTools = "*"
+
+// user_preferences categories tool settings are kept under
+const (
+	settingsCategory             = "tools"
+	conversationSettingsCategory = "tools:conversation:"
+)
+
+// ToolSetting enables or disables a tool and overrides its configuration.
+// Zero fields leave the value of lower-precedence scopes in place.
+type ToolSetting struct {
+	Enabled    *bool
+	Timeout    time.Duration
+	MaxRetries int
+	RetryDelay time.Duration
+	// Custom pins tool parameters; they replace those given in the input,
+	// e.g. {"connection": "analytics"} keeps a conversation on one database
+	Custom map[string]interface{}
+}
+
+// toolSettingJSON is the stored and API form of ToolSetting, with
+// durations written as strings such as "30s"
+type toolSettingJSON struct {
+	Enabled    *bool                  `json:"enabled,omitempty"`
+	Timeout    string                 `json:"timeout,omitempty"`
+	MaxRetries int                    `json:"max_retries,omitempty"`
+	RetryDelay string                 `json:"retry_delay,omitempty"`
+	Custom     map[string]interface{} `json:"custom,omitempty"`
+}
+
+// MarshalJSON writes durations as strings
+func (s ToolSetting) MarshalJSON() ([]byte, error) {
+	v := toolSettingJSON{
+		Enabled:    s.Enabled,
+		MaxRetries: s.MaxRetries,
+		Custom:     s.Custom,
+	}
+	if s.Timeout > 0 {
+		v.Timeout = s.Timeout.String()
+	}
+	if s.RetryDelay > 0 {
+		v.RetryDelay = s.RetryDelay.String()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads durations written as strings
+func (s *ToolSetting) UnmarshalJSON(data []byte) error {
+	var v toolSettingJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	setting := ToolSetting{
+		Enabled:    v.Enabled,
+		MaxRetries: v.MaxRetries,
+		Custom:     v.Custom,
+	}
+	var err error
+	if v.Timeout != "" {
+		if setting.Timeout, err = time.ParseDuration(v.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if v.RetryDelay != "" {
+		if setting.RetryDelay, err = time.ParseDuration(v.RetryDelay); err != nil {
+			return fmt.Errorf("invalid retry_delay: %w", err)
+		}
+	}
+	if setting.Timeout < 0 || setting.RetryDelay < 0 || setting.MaxRetries < 0 {
+		return errors.New("timeout, retry_delay and max_retries cannot be negative")
+	}
+	*s = setting
+	return nil
+}
+
+// ToolSettings are the tool settings of one scope keyed by tool name or
+// AllTools
+type ToolSettings map[string]ToolSetting
+
+// Subject is who tool settings are resolved for
+type Subject struct {
+	UserID         string
+	Roles          []string
+	ConversationID string
+}
+
+// Policy is the tool availability and configuration resolved for a
+// subject. A nil Policy enables every tool without overrides.
+type Policy struct {
+	ceiling   []ToolSettings // role settings, in increasing precedence
+	layers    []ToolSettings // in increasing precedence
+	favorites []string
+}
+
+// NewPolicy creates a policy from settings layers in increasing
+// precedence. Favorite tools are listed first by Filter.
+func NewPolicy(favorites []string, layers ...ToolSettings) *Policy {
+	return &Policy{
+		layers:    layers,
+		favorites: favorites,
+	}
+}
+
+// NewRolePolicy creates a policy whose role settings are a ceiling for
+// the layers: the layers cannot enable a tool the roles disable, raise
+// the timeout or retries beyond theirs, shorten their retry delay or
+// replace the parameters they pin.
+func NewRolePolicy(favorites []string, roles []ToolSettings, layers ...ToolSettings) *Policy {
+	return &Policy{
+		ceiling:   roles,
+		layers:    layers,
+		favorites: favorites,
+	}
+}
+
+// Setting returns the combined setting of a tool across all layers,
+// capped by the role settings
+func (p *Policy) Setting(name string) ToolSetting {
+	if p == nil {
+		return ToolSetting{}
+	}
+	return capSetting(mergeLayers(p.ceiling, name), mergeLayers(p.layers, name))
+}
+
+// mergeLayers combines the settings of a tool across layers
+func mergeLayers(layers []ToolSettings, name string) ToolSetting {
+	var merged ToolSetting
+	for _, layer := range layers {
+		for _, key := range []string{AllTools, name} {
+			if s, ok := layer[key]; ok {
+				merged = mergeSetting(merged, s)
+			}
+		}
+	}
+	return merged
+}
+
+// capSetting applies s within the limits of ceiling
+func capSetting(ceiling, s ToolSetting) ToolSetting {
+	capped := mergeSetting(ceiling, s)
+	if ceiling.Enabled != nil && !*ceiling.Enabled {
+		capped.Enabled = ceiling.Enabled
+	}
+	if ceiling.Timeout > 0 {
+		capped.Timeout = min(capped.Timeout, ceiling.Timeout)
+	}
+	if ceiling.MaxRetries > 0 {
+		capped.MaxRetries = min(capped.MaxRetries, ceiling.MaxRetries)
+	}
+	capped.RetryDelay = max(capped.RetryDelay, ceiling.RetryDelay)
+	if len(ceiling.Custom) > 0 {
+		capped.Custom = mergeSetting(capped, ToolSetting{Custom: ceiling.Custom}).Custom
+	}
+	return capped
+}
+
+// Enabled reports whether a tool is available; tools are enabled unless a
+// setting disables them
+func (p *Policy) Enabled(name string) bool {
+	enabled := p.Setting(name).Enabled
+	return enabled == nil || *enabled
+}
+
+// Config returns the configuration overrides for a tool, or nil when
+// there are none
+func (p *Policy) Config(name string) *ToolConfig {
+	s := p.Setting(name)
+	if s.Timeout == 0 && s.MaxRetries == 0 && s.RetryDelay == 0 && len(s.Custom) == 0 {
+		return nil
+	}
+	return &ToolConfig{
+		Timeout:    s.Timeout,
+		MaxRetries: s.MaxRetries,
+		RetryDelay: s.RetryDelay,
+		Custom:     s.Custom,
+	}
+}
+
+// Filter drops disabled tools and lists favorites first, in the order
+// they were favorited
+func (p *Policy) Filter(tools []ToolInfo) []ToolInfo {
+	filtered := make([]ToolInfo, 0, len(tools))
+	for _, t := range tools {
+		if p.Enabled(t.Name) {
+			filtered = append(filtered, t)
+		}
+	}
+	if p == nil || len(p.favorites) == 0 {
+		return filtered
+	}
+
+	rank := func(name string) int {
+		if i := slices.Index(p.favorites, name); i >= 0 {
+			return i
+		}
+		return len(p.favorites)
+	}
+	slices.SortStableFunc(filtered, func(a, b ToolInfo) int {
+		return rank(a.Name) - rank(b.Name)
+	})
+	return filtered
+}
+
+// mergeSetting overrides base with the non-zero fields of s. Pinned
+// parameters are merged key by key.
+func mergeSetting(base, s ToolSetting) ToolSetting {
+	if s.Enabled != nil {
+		base.Enabled = s.Enabled
+	}
+	if s.Timeout > 0 {
+		base.Timeout = s.Timeout
+	}
+	if s.MaxRetries > 0 {
+		base.MaxRetries = s.MaxRetries
+	}
+	if s.RetryDelay > 0 {
+		base.RetryDelay = s.RetryDelay
+	}
+	if len(s.Custom) > 0 {
+		custom := make(map[string]interface{}, len(base.Custom)+len(s.Custom))
+		for k, v := range base.Custom {
+			custom[k] = v
+		}
+		for k, v := range s.Custom {
+			custom[k] = v
+		}
+		base.Custom = custom
+	}
+	return base
+}
+
+// MergeConfig returns base with the non-zero fields of override applied;
+// either may be nil
+func MergeConfig(base, override *ToolConfig) *ToolConfig {
+	if override == nil {
+		return base
+	}
+	if base == nil {
+		return override
+	}
+	merged := mergeSetting(
+		ToolSetting{Timeout: base.Timeout, MaxRetries: base.MaxRetries, RetryDelay: base.RetryDelay, Custom: base.Custom},
+		ToolSetting{Timeout: override.Timeout, MaxRetries: override.MaxRetries, RetryDelay: override.RetryDelay, Custom: override.Custom},
+	)
+	return &ToolConfig{
+		Timeout:    merged.Timeout,
+		MaxRetries: merged.MaxRetries,
+		RetryDelay: merged.RetryDelay,
+		Custom:     merged.Custom,
+	}
+}
+
+// SettingsStore persists the tool settings of users and conversations.
+// An empty conversationID addresses the user's own settings.
+type SettingsStore interface {
+	Settings(ctx context.Context, userID, conversationID string) (ToolSettings, error)
+	SetSetting(ctx context.Context, userID, conversationID, name string, setting ToolSetting) error
+	DeleteSetting(ctx context.Context, userID, conversationID, name string) error
+	// Favorites returns the user's favorite tools
+	Favorites(ctx context.Context, userID string) ([]string, error)
+}
+
+// QueriesSettingsStore implements SettingsStore on top of the
+// user_preferences table, one row per tool and scope
+type QueriesSettingsStore struct {
+	queries *sqlc.Queries
+}
+
+// NewQueriesSettingsStore creates a settings store backed by sqlc queries
+func NewQueriesSettingsStore(queries *sqlc.Queries) *QueriesSettingsStore {
+	return &QueriesSettingsStore{
+		queries: queries,
+	}
+}
+
+// settingsScope returns the user_preferences category of a scope
+func settingsScope(userID, conversationID string) (pgtype.UUID, string, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return pgtype.UUID{}, "", fmt.Errorf("invalid user ID: %w", err)
+	}
+	category := settingsCategory
+	if conversationID != "" {
+		if _, err := uuid.Parse(conversationID); err != nil {
+			return pgtype.UUID{}, "", fmt.Errorf("invalid conversation ID: %w", err)
+		}
+		category = conversationSettingsCategory + conversationID
+	}
+	return pgtype.UUID{Bytes: id, Valid: true}, category, nil
+}
+
+// Settings returns the stored settings of a scope
+func (s *QueriesSettingsStore) Settings(ctx context.Context, userID, conversationID string) (ToolSettings, error) {
+	id, category, err := settingsScope(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.GetUserPreferencesByCategory(ctx, sqlc.GetUserPreferencesByCategoryParams{
+		Column1:  id,
+		Category: category,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool settings: %w", err)
+	}
+
+	settings := make(ToolSettings, len(rows))
+	for _, row := range rows {
+		var setting ToolSetting
+		if err := json.Unmarshal(row.PreferenceValue, &setting); err != nil {
+			return nil, fmt.Errorf("invalid setting for tool %s: %w", row.PreferenceKey, err)
+		}
+		settings[row.PreferenceKey] = setting
+	}
+	return settings, nil
+}
+
+// SetSetting stores the setting of a tool, replacing any previous one
+func (s *QueriesSettingsStore) SetSetting(ctx context.Context, userID, conversationID, name string, setting ToolSetting) error {
+	id, category, err := settingsScope(userID, conversationID)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(setting)
+	if err != nil {
+		return fmt.Errorf("failed to encode tool setting: %w", err)
+	}
+
+	_, err = s.queries.CreateUserPreference(ctx, sqlc.CreateUserPreferenceParams{
+		Column1:         id,
+		Category:        category,
+		PreferenceKey:   name,
+		PreferenceValue: value,
+		ValueType:       "object",
+		Description:     pgtype.Text{String: "Tool enablement and configuration", Valid: true},
+		Metadata:        json.RawMessage(`{}`),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store tool setting: %w", err)
+	}
+	return nil
+}
+
+// DeleteSetting removes the setting of a tool
+func (s *QueriesSettingsStore) DeleteSetting(ctx context.Context, userID, conversationID, name string) error {
+	id, category, err := settingsScope(userID, conversationID)
+	if err != nil {
+		return err
+	}
+	err = s.queries.DeleteUserPreference(ctx, sqlc.DeleteUserPreferenceParams{
+		Column1:       id,
+		Category:      category,
+		PreferenceKey: name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tool setting: %w", err)
+	}
+	return nil
+}
+
+// Favorites returns the favoriteTools of the user's preferences
+func (s *QueriesSettingsStore) Favorites(ctx context.Context, userID string) ([]string, error) {
+	id, _, err := settingsScope(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.queries.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var preferences struct {
+		FavoriteTools []string `json:"favoriteTools"`
+	}
+	if len(user.Preferences) > 0 {
+		if err := json.Unmarshal(user.Preferences, &preferences); err != nil {
+			return nil, fmt.Errorf("failed to parse user preferences: %w", err)
+		}
+	}
+	return preferences.FavoriteTools, nil
+}
+
+// SettingsResolver resolves the tool policy of a subject from role
+// settings in the configuration and stored user and conversation settings
+type SettingsResolver struct {
+	roles  map[string]ToolSettings
+	store  SettingsStore
+	logger *slog.Logger
+}
+
+// NewSettingsResolver creates a resolver. store may be nil, leaving only
+// the role settings.
+func NewSettingsResolver(roles map[string]map[string]config.ToolOverride, store SettingsStore, logger *slog.Logger) *SettingsResolver {
+	converted := make(map[string]ToolSettings, len(roles))
+	for role, overrides := range roles {
+		settings := make(ToolSettings, len(overrides))
+		for name, o := range overrides {
+			settings[name] = ToolSetting{
+				Enabled:    o.Enabled,
+				Timeout:    o.Timeout,
+				MaxRetries: o.MaxRetries,
+				RetryDelay: o.RetryDelay,
+				Custom:     o.Custom,
+			}
+		}
+		converted[role] = settings
+	}
+
+	return &SettingsResolver{
+		roles:  converted,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Store returns the settings store, or nil without one
+func (r *SettingsResolver) Store() SettingsStore {
+	return r.store
+}
+
+// Resolve returns the policy of a subject: user settings, then
+// conversation settings, within the ceiling of the role settings in the
+// order of the subject's roles. Favorites that cannot be read are logged
+// and ignored.
+func (r *SettingsResolver) Resolve(ctx context.Context, subject Subject) (*Policy, error) {
+	var roles []ToolSettings
+	for _, role := range subject.Roles {
+		if settings, ok := r.roles[role]; ok {
+			roles = append(roles, settings)
+		}
+	}
+	if r.store == nil || subject.UserID == "" {
+		return NewRolePolicy(nil, roles), nil
+	}
+
+	user, err := r.store.Settings(ctx, subject.UserID, "")
+	if err != nil {
+		return nil, err
+	}
+	layers := []ToolSettings{user}
+
+	if subject.ConversationID != "" {
+		conversation, err := r.store.Settings(ctx, subject.UserID, subject.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, conversation)
+	}
+
+	favorites, err := r.store.Favorites(ctx, subject.UserID)
+	if err != nil {
+		r.logger.Warn("Failed to read favorite tools",
+			slog.String("user_id", subject.UserID),
+			slog.Any("error", err))
+	}
+	return NewRolePolicy(favorites, roles, layers...), nil
+}
@@ -0,0 +1,280 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+)
+
+// memorySettingsStore keeps tool settings by user and conversation
+type memorySettingsStore struct {
+	settings  map[[2]string]ToolSettings
+	favorites map[string][]string
+}
+
+func newMemorySettingsStore() *memorySettingsStore {
+	return &memorySettingsStore{
+		settings:  make(map[[2]string]ToolSettings),
+		favorites: make(map[string][]string),
+	}
+}
+
+func (s *memorySettingsStore) Settings(ctx context.Context, userID, conversationID string) (ToolSettings, error) {
+	return s.settings[[2]string{userID, conversationID}], nil
+}
+
+func (s *memorySettingsStore) SetSetting(ctx context.Context, userID, conversationID, name string, setting ToolSetting) error {
+	key := [2]string{userID, conversationID}
+	if s.settings[key] == nil {
+		s.settings[key] = make(ToolSettings)
+	}
+	s.settings[key][name] = setting
+	return nil
+}
+
+func (s *memorySettingsStore) DeleteSetting(ctx context.Context, userID, conversationID, name string) error {
+	delete(s.settings[[2]string{userID, conversationID}], name)
+	return nil
+}
+
+func (s *memorySettingsStore) Favorites(ctx context.Context, userID string) ([]string, error) {
+	return s.favorites[userID], nil
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestPolicy_Precedence(t *testing.T) {
+	policy := NewPolicy(nil,
+		ToolSettings{
+			AllTools: {Enabled: boolPtr(false), Timeout: time.Minute},
+			"search": {Enabled: boolPtr(true), MaxRetries: 1},
+		},
+		ToolSettings{
+			"search":   {Timeout: 10 * time.Second, Custom: map[string]interface{}{"limit": 5}},
+			"postgres": {Enabled: boolPtr(true), Custom: map[string]interface{}{"connection": "main"}},
+		},
+		ToolSettings{
+			AllTools:   {Enabled: boolPtr(false)},
+			"postgres": {Custom: map[string]interface{}{"connection": "analytics"}},
+		},
+	)
+
+	tests := []struct {
+		name        string
+		tool        string
+		wantEnabled bool
+		wantConfig  *ToolConfig
+	}{
+		{
+			name:        "wildcard_disables",
+			tool:        "docker",
+			wantEnabled: false,
+			wantConfig:  &ToolConfig{Timeout: time.Minute},
+		},
+		{
+			// the tool's own key wins over the wildcard of the same layer,
+			// but not over the wildcard of a later layer
+			name:        "later_wildcard_wins",
+			tool:        "search",
+			wantEnabled: false,
+			wantConfig:  &ToolConfig{Timeout: 10 * time.Second, MaxRetries: 1, Custom: map[string]interface{}{"limit": 5}},
+		},
+		{
+			name:        "pinned_parameter_override",
+			tool:        "postgres",
+			wantEnabled: false,
+			wantConfig:  &ToolConfig{Timeout: time.Minute, Custom: map[string]interface{}{"connection": "analytics"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Enabled(tt.tool); got != tt.wantEnabled {
+				t.Errorf("Enabled(%q) = %v, want %v", tt.tool, got, tt.wantEnabled)
+			}
+			if got := policy.Config(tt.tool); !reflect.DeepEqual(got, tt.wantConfig) {
+				t.Errorf("Config(%q) = %+v, want %+v", tt.tool, got, tt.wantConfig)
+			}
+		})
+	}
+}
+
+func TestPolicy_Nil(t *testing.T) {
+	var policy *Policy
+	if !policy.Enabled("anything") {
+		t.Error("nil policy should enable every tool")
+	}
+	if cfg := policy.Config("anything"); cfg != nil {
+		t.Errorf("nil policy Config() = %+v, want nil", cfg)
+	}
+	tools := []ToolInfo{{Name: "a"}, {Name: "b"}}
+	if got := policy.Filter(tools); len(got) != 2 {
+		t.Errorf("nil policy Filter() kept %d tools, want 2", len(got))
+	}
+}
+
+func TestPolicy_Filter(t *testing.T) {
+	policy := NewPolicy([]string{"search", "godev"},
+		ToolSettings{"docker": {Enabled: boolPtr(false)}},
+	)
+	tools := []ToolInfo{{Name: "docker"}, {Name: "godev"}, {Name: "postgres"}, {Name: "search"}}
+
+	var got []string
+	for _, info := range policy.Filter(tools) {
+		got = append(got, info.Name)
+	}
+	want := []string{"search", "godev", "postgres"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeConfig(t *testing.T) {
+	base := &ToolConfig{Timeout: time.Second, MaxRetries: 3, Custom: map[string]interface{}{"connection": "main", "limit": 1}}
+	override := &ToolConfig{Timeout: time.Minute, Custom: map[string]interface{}{"connection": "analytics"}}
+
+	got := MergeConfig(base, override)
+	want := &ToolConfig{Timeout: time.Minute, MaxRetries: 3, Custom: map[string]interface{}{"connection": "analytics", "limit": 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeConfig() = %+v, want %+v", got, want)
+	}
+	if base.Custom["connection"] != "main" {
+		t.Error("MergeConfig() modified its base")
+	}
+	if MergeConfig(nil, override) != override || MergeConfig(base, nil) != base {
+		t.Error("MergeConfig() with nil should return the other config")
+	}
+}
+
+func TestToolSetting_JSON(t *testing.T) {
+	setting := ToolSetting{
+		Enabled:    boolPtr(true),
+		Timeout:    30 * time.Second,
+		MaxRetries: 2,
+		RetryDelay: 500 * time.Millisecond,
+		Custom:     map[string]interface{}{"connection": "analytics"},
+	}
+
+	data, err := json.Marshal(setting)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"enabled":true,"timeout":"30s","max_retries":2,"retry_delay":"500ms","custom":{"connection":"analytics"}}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded ToolSetting
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, setting) {
+		t.Errorf("Unmarshal() = %+v, want %+v", decoded, setting)
+	}
+
+	for _, invalid := range []string{`{"timeout":"soon"}`, `{"retry_delay":"-1s"}`, `{"max_retries":-1}`} {
+		if err := json.Unmarshal([]byte(invalid), &decoded); err == nil {
+			t.Errorf("Unmarshal(%s) error = nil, want error", invalid)
+		}
+	}
+}
+
+func TestSettingsResolver_Resolve(t *testing.T) {
+	store := newMemorySettingsStore()
+	ctx := context.Background()
+	_ = store.SetSetting(ctx, "u1", "", "postgres", ToolSetting{Enabled: boolPtr(true)})
+	_ = store.SetSetting(ctx, "u1", "c1", "postgres", ToolSetting{Custom: map[string]interface{}{"connection": "analytics"}})
+	store.favorites["u1"] = []string{"postgres"}
+
+	_ = store.SetSetting(ctx, "u3", "", "postgres", ToolSetting{
+		Timeout:    30 * time.Second,
+		MaxRetries: 5,
+		Custom:     map[string]interface{}{"schema": "private"},
+	})
+
+	roles := map[string]map[string]config.ToolOverride{
+		"viewer": {
+			AllTools:   {Enabled: boolPtr(false)},
+			"postgres": {Timeout: time.Minute},
+		},
+		"analyst": {
+			"postgres": {Timeout: time.Minute, MaxRetries: 1, RetryDelay: time.Second, Custom: map[string]interface{}{"schema": "public"}},
+		},
+	}
+	resolver := NewSettingsResolver(roles, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name         string
+		subject      Subject
+		wantPostgres bool
+		wantDocker   bool
+		wantConfig   *ToolConfig
+	}{
+		{
+			name:         "role_only",
+			subject:      Subject{Roles: []string{"viewer"}},
+			wantPostgres: false,
+			wantDocker:   false,
+			wantConfig:   &ToolConfig{Timeout: time.Minute},
+		},
+		{
+			// a user cannot enable what their role disables
+			name:         "user_cannot_enable_over_role",
+			subject:      Subject{UserID: "u1", Roles: []string{"viewer"}},
+			wantPostgres: false,
+			wantDocker:   false,
+			wantConfig:   &ToolConfig{Timeout: time.Minute},
+		},
+		{
+			name:         "conversation_pins_connection",
+			subject:      Subject{UserID: "u1", Roles: []string{"analyst"}, ConversationID: "c1"},
+			wantPostgres: true,
+			wantDocker:   true,
+			wantConfig:   &ToolConfig{Timeout: time.Minute, MaxRetries: 1, RetryDelay: time.Second, Custom: map[string]interface{}{"connection": "analytics", "schema": "public"}},
+		},
+		{
+			// limits can only be tightened and pinned parameters kept
+			name:         "role_limits_are_a_ceiling",
+			subject:      Subject{UserID: "u3", Roles: []string{"analyst"}},
+			wantPostgres: true,
+			wantDocker:   true,
+			wantConfig:   &ToolConfig{Timeout: 30 * time.Second, MaxRetries: 1, RetryDelay: time.Second, Custom: map[string]interface{}{"schema": "public"}},
+		},
+		{
+			name:         "unknown_role",
+			subject:      Subject{UserID: "u2", Roles: []string{"admin"}},
+			wantPostgres: true,
+			wantDocker:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := resolver.Resolve(ctx, tt.subject)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got := policy.Enabled("postgres"); got != tt.wantPostgres {
+				t.Errorf("Enabled(postgres) = %v, want %v", got, tt.wantPostgres)
+			}
+			if got := policy.Enabled("docker"); got != tt.wantDocker {
+				t.Errorf("Enabled(docker) = %v, want %v", got, tt.wantDocker)
+			}
+			if got := policy.Config("postgres"); !reflect.DeepEqual(got, tt.wantConfig) {
+				t.Errorf("Config(postgres) = %+v, want %+v", got, tt.wantConfig)
+			}
+		})
+	}
+
+	policy, _ := resolver.Resolve(ctx, Subject{UserID: "u1"})
+	filtered := policy.Filter([]ToolInfo{{Name: "docker"}, {Name: "postgres"}})
+	if filtered[0].Name != "postgres" {
+		t.Errorf("Filter() listed %s first, want the favorite postgres", filtered[0].Name)
+	}
+}
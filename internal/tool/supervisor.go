@@ -0,0 +1,388 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/platform/event"
+)
+
+// HealthState is the supervised state of a tool
+type HealthState string
+
+const (
+	HealthHealthy     HealthState = "healthy"
+	HealthUnhealthy   HealthState = "unhealthy"
+	HealthQuarantined HealthState = "quarantined"
+)
+
+// healthStateValues are the values of the health state gauge
+var healthStateValues = map[HealthState]int64{
+	HealthHealthy:     0,
+	HealthUnhealthy:   1,
+	HealthQuarantined: 2,
+}
+
+// ToolHealth is the supervised health of an instantiated tool
+type ToolHealth struct {
+	Name                string      `json:"name"`
+	State               HealthState `json:"state"`
+	Reason              string      `json:"reason,omitempty"` // why the tool is quarantined
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+	LastError           string      `json:"last_error,omitempty"`
+	LastCheck           time.Time   `json:"last_check"`
+	NextCheck           time.Time   `json:"next_check"`
+}
+
+// HealthChange is the data of event.EventToolHealthChange events
+type HealthChange struct {
+	Tool   string      `json:"tool"`
+	From   HealthState `json:"from"`
+	To     HealthState `json:"to"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// supervised is the health record the Supervisor keeps for a tool
+type supervised struct {
+	ToolHealth
+	passes            int   // consecutive passing checks while quarantined
+	executions, fails int64 // registry counts at the previous check
+}
+
+// supervisorMetrics are the instruments the Supervisor reports to
+type supervisorMetrics struct {
+	checks      metric.Int64Counter
+	state       metric.Int64Gauge
+	quarantines metric.Int64Counter
+}
+
+// Supervisor health-checks instantiated tools in the background. Tools
+// whose checks keep failing, or whose executions mostly fail, are
+// quarantined in the registry and re-admitted after passing checks.
+type Supervisor struct {
+	registry *Registry
+	cfg      config.ToolHealth
+	events   event.EventPublisher
+	metrics  *supervisorMetrics
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	tools map[string]*supervised
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSupervisor creates a supervisor for the tools of registry. events may
+// be nil.
+func NewSupervisor(registry *Registry, cfg config.ToolHealth, events event.EventPublisher, logger *slog.Logger) (*Supervisor, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxBackoff < cfg.Interval {
+		cfg.MaxBackoff = cfg.Interval
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.RecoveryProbes <= 0 {
+		cfg.RecoveryProbes = 1
+	}
+
+	metrics, err := newSupervisorMetrics(otel.Meter("assistant.tool"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool health metrics: %w", err)
+	}
+
+	return &Supervisor{
+		registry: registry,
+		cfg:      cfg,
+		events:   events,
+		metrics:  metrics,
+		logger:   logger,
+		tools:    make(map[string]*supervised),
+	}, nil
+}
+
+// newSupervisorMetrics creates the tool health instruments
+func newSupervisorMetrics(meter metric.Meter) (*supervisorMetrics, error) {
+	checks, err := meter.Int64Counter(
+		"assistant.tool.health_checks",
+		metric.WithDescription("Tool health checks by result"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := meter.Int64Gauge(
+		"assistant.tool.health_state",
+		metric.WithDescription("Tool health state: 0 healthy, 1 unhealthy, 2 quarantined"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantines, err := meter.Int64Counter(
+		"assistant.tool.quarantines",
+		metric.WithDescription("Times a tool was quarantined"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &supervisorMetrics{
+		checks:      checks,
+		state:       state,
+		quarantines: quarantines,
+	}, nil
+}
+
+// Start checks the tools every interval until Close is called
+func (s *Supervisor) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.Check(context.Background())
+			}
+		}
+	}()
+}
+
+// Close stops the background checks
+func (s *Supervisor) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// Check health-checks the tools that are due. Failing tools are checked
+// with exponential backoff up to the configured maximum.
+func (s *Supervisor) Check(ctx context.Context) {
+	now := time.Now()
+	instances := s.registry.instances()
+
+	s.mu.Lock()
+	for name := range s.tools {
+		if _, ok := instances[name]; !ok {
+			delete(s.tools, name)
+		}
+	}
+	due := make(map[string]Tool)
+	for name, t := range instances {
+		st := s.tools[name]
+		if st == nil {
+			st = &supervised{ToolHealth: ToolHealth{Name: name, State: HealthHealthy}}
+			s.tools[name] = st
+		}
+		if !now.Before(st.NextCheck) {
+			due[name] = t
+		}
+	}
+	s.mu.Unlock()
+
+	// Probe concurrently so one slow tool does not delay the others
+	var wg sync.WaitGroup
+	var resultsMu sync.Mutex
+	results := make(map[string]error, len(due))
+	for name, t := range due {
+		wg.Add(1)
+		go func(name string, t Tool) {
+			defer wg.Done()
+			err := s.probe(ctx, name, t)
+			resultsMu.Lock()
+			results[name] = err
+			resultsMu.Unlock()
+		}(name, t)
+	}
+	wg.Wait()
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.update(ctx, name, results[name], now)
+	}
+}
+
+// probe runs a tool's health check with the configured timeout
+func (s *Supervisor) probe(ctx context.Context, name string, t Tool) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("health check panic: %v", r)
+		}
+	}()
+
+	if err := t.Health(ctx); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// update applies a check result to a tool's health and reports changes
+func (s *Supervisor) update(ctx context.Context, name string, probeErr error, now time.Time) {
+	total, failed := s.registry.usageCounts(name)
+
+	s.mu.Lock()
+	st := s.tools[name]
+	if st == nil {
+		s.mu.Unlock()
+		return
+	}
+	from := st.State
+	st.LastCheck = now
+	executions, fails := total-st.executions, failed-st.fails
+	st.executions, st.fails = total, failed
+
+	if probeErr != nil {
+		st.ConsecutiveFailures++
+		st.LastError = probeErr.Error()
+		st.passes = 0
+	} else {
+		st.ConsecutiveFailures = 0
+		st.LastError = ""
+	}
+
+	switch {
+	case st.State == HealthQuarantined:
+		if probeErr == nil {
+			st.passes++
+			if st.passes >= s.cfg.RecoveryProbes {
+				st.State = HealthHealthy
+				st.Reason = ""
+				st.passes = 0
+			}
+		}
+	case probeErr != nil && st.ConsecutiveFailures >= s.cfg.FailureThreshold:
+		st.State = HealthQuarantined
+		st.Reason = fmt.Sprintf("%d consecutive health checks failed: %v", st.ConsecutiveFailures, probeErr)
+	case probeErr != nil:
+		st.State = HealthUnhealthy
+	case s.errorRateExceeded(executions, fails):
+		st.State = HealthQuarantined
+		st.Reason = fmt.Sprintf("%d of %d executions failed", fails, executions)
+	default:
+		st.State = HealthHealthy
+	}
+
+	// Failing tools are checked less often; a quarantined tool that passes
+	// is checked again soon so it can be re-admitted
+	delay := s.cfg.Interval
+	if probeErr != nil {
+		for i := 1; i < st.ConsecutiveFailures && delay < s.cfg.MaxBackoff; i++ {
+			delay *= 2
+		}
+		delay = min(delay, s.cfg.MaxBackoff)
+	}
+	st.NextCheck = now.Add(delay)
+
+	to, reason := st.State, st.Reason
+	s.mu.Unlock()
+
+	s.registry.setHealth(name, probeErr == nil, now)
+
+	result := "pass"
+	if probeErr != nil {
+		result = "fail"
+	}
+	toolAttr := attribute.String("tool", name)
+	s.metrics.checks.Add(ctx, 1, metric.WithAttributes(toolAttr, attribute.String("result", result)))
+	s.metrics.state.Record(ctx, healthStateValues[to], metric.WithAttributes(toolAttr))
+
+	if from == to {
+		return
+	}
+	switch {
+	case to == HealthQuarantined:
+		if err := s.registry.Quarantine(name, reason); err != nil {
+			s.logger.Warn("Failed to quarantine tool", slog.String("tool", name), slog.Any("error", err))
+		}
+		s.metrics.quarantines.Add(ctx, 1, metric.WithAttributes(toolAttr))
+	case from == HealthQuarantined:
+		s.registry.Readmit(name)
+	}
+	s.publish(ctx, HealthChange{Tool: name, From: from, To: to, Reason: reason})
+}
+
+// errorRateExceeded reports whether enough executions failed since the
+// previous check to quarantine a tool
+func (s *Supervisor) errorRateExceeded(executions, fails int64) bool {
+	if s.cfg.ErrorRateThreshold <= 0 || s.cfg.MinExecutions <= 0 {
+		return false
+	}
+	return executions >= int64(s.cfg.MinExecutions) &&
+		float64(fails)/float64(executions) >= s.cfg.ErrorRateThreshold
+}
+
+// publish logs a health change and publishes it as an event
+func (s *Supervisor) publish(ctx context.Context, change HealthChange) {
+	s.logger.Info("Tool health changed",
+		slog.String("tool", change.Tool),
+		slog.String("from", string(change.From)),
+		slog.String("to", string(change.To)),
+		slog.String("reason", change.Reason))
+
+	if s.events == nil {
+		return
+	}
+	priority := event.PriorityNormal
+	if change.To == HealthQuarantined {
+		priority = event.PriorityHigh
+	}
+	err := s.events.Publish(ctx, event.Event{
+		Type:     event.EventToolHealthChange,
+		Source:   "tool.supervisor",
+		Target:   change.Tool,
+		Data:     change,
+		Priority: priority,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to publish tool health change",
+			slog.String("tool", change.Tool),
+			slog.Any("error", err))
+	}
+}
+
+// Status returns the health of the supervised tools by name
+func (s *Supervisor) Status() []ToolHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]ToolHealth, 0, len(s.tools))
+	for _, st := range s.tools {
+		status = append(status, st.ToolHealth)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
+}
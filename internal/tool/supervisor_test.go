@@ -0,0 +1,242 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/platform/event"
+)
+
+// recordingPublisher collects published events
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []event.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e event.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, e)
+	return nil
+}
+
+func (p *recordingPublisher) changes() []HealthChange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changes := make([]HealthChange, len(p.events))
+	for i, e := range p.events {
+		changes[i] = e.Data.(HealthChange)
+	}
+	return changes
+}
+
+// newSupervisedRegistry registers and instantiates a test tool
+func newSupervisedRegistry(t *testing.T, tool *testTool, cfg config.ToolHealth) (*Registry, *Supervisor, *recordingPublisher) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := NewRegistry(logger)
+	if err := registry.Register("flaky", func(*ToolConfig, *slog.Logger) (Tool, error) { return tool, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.GetTool("flaky", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	events := &recordingPublisher{}
+	supervisor, err := NewSupervisor(registry, cfg, events, logger)
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+	return registry, supervisor, events
+}
+
+// checkDue waits out the check interval and checks again
+func checkDue(s *Supervisor) {
+	time.Sleep(2 * time.Millisecond)
+	s.Check(context.Background())
+}
+
+func TestSupervisor_QuarantineAndRecovery(t *testing.T) {
+	tool := &testTool{name: "flaky"}
+	registry, supervisor, events := newSupervisedRegistry(t, tool, config.ToolHealth{
+		Interval:         time.Millisecond,
+		FailureThreshold: 2,
+		RecoveryProbes:   2,
+	})
+
+	checkDue(supervisor)
+	if got := supervisor.Status()[0].State; got != HealthHealthy {
+		t.Fatalf("State = %s, want healthy", got)
+	}
+
+	tool.healthErr = errors.New("connection refused")
+	checkDue(supervisor)
+	if got := supervisor.Status()[0]; got.State != HealthUnhealthy || got.LastError != "connection refused" {
+		t.Fatalf("Status() = %+v, want unhealthy", got)
+	}
+	stats, _ := registry.Stats(context.Background())
+	if stats.UnhealthyTools != 1 || stats.ExecutionStats["flaky"].IsHealthy {
+		t.Errorf("Stats() = %+v, want one unhealthy tool", stats)
+	}
+
+	checkDue(supervisor)
+	if got := supervisor.Status()[0]; got.State != HealthQuarantined || !strings.Contains(got.Reason, "2 consecutive health checks failed") {
+		t.Fatalf("Status() = %+v, want quarantined", got)
+	}
+	if len(registry.ListTools()) != 0 {
+		t.Error("ListTools() includes a quarantined tool")
+	}
+	if info, _ := registry.GetToolInfo("flaky"); info.IsEnabled {
+		t.Error("GetToolInfo() IsEnabled = true for a quarantined tool")
+	}
+	result, err := registry.Execute(context.Background(), "flaky", nil, nil)
+	if err == nil || result.Success || !strings.Contains(result.Error, "quarantined") {
+		t.Errorf("Execute() = %+v, %v, want a quarantine error", result, err)
+	}
+	if !IsToolExecutionError(err) || !IsRetryableToolError(err) {
+		t.Errorf("Execute() error = %v, want a retryable execution error", err)
+	}
+
+	// One passing probe is not enough to re-admit
+	tool.healthErr = nil
+	checkDue(supervisor)
+	if _, quarantined := registry.Quarantined("flaky"); !quarantined {
+		t.Fatal("tool re-admitted after one passing check")
+	}
+	checkDue(supervisor)
+	if _, quarantined := registry.Quarantined("flaky"); quarantined {
+		t.Fatal("tool still quarantined after two passing checks")
+	}
+	if len(registry.ListTools()) != 1 {
+		t.Error("ListTools() does not include the re-admitted tool")
+	}
+
+	want := []HealthChange{
+		{Tool: "flaky", From: HealthHealthy, To: HealthUnhealthy},
+		{Tool: "flaky", From: HealthUnhealthy, To: HealthQuarantined, Reason: "2 consecutive health checks failed: connection refused"},
+		{Tool: "flaky", From: HealthQuarantined, To: HealthHealthy},
+	}
+	got := events.changes()
+	if len(got) != len(want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if events.events[1].Type != event.EventToolHealthChange || events.events[1].Priority != event.PriorityHigh {
+		t.Errorf("quarantine event = %+v", events.events[1])
+	}
+}
+
+func TestSupervisor_ErrorRate(t *testing.T) {
+	tool := &testTool{name: "flaky", executeResult: &ToolResult{Success: false, Error: "upstream 503"}}
+	registry, supervisor, _ := newSupervisedRegistry(t, tool, config.ToolHealth{
+		Interval:           time.Millisecond,
+		ErrorRateThreshold: 0.5,
+		MinExecutions:      4,
+	})
+
+	// Too few executions to judge
+	for range 3 {
+		registry.Execute(context.Background(), "flaky", nil, nil)
+	}
+	checkDue(supervisor)
+	if got := supervisor.Status()[0].State; got != HealthHealthy {
+		t.Fatalf("State = %s after 3 failures, want healthy", got)
+	}
+
+	// Counts start over after each check
+	for range 4 {
+		registry.Execute(context.Background(), "flaky", nil, nil)
+	}
+	checkDue(supervisor)
+	got := supervisor.Status()[0]
+	if got.State != HealthQuarantined || got.Reason != "4 of 4 executions failed" {
+		t.Fatalf("Status() = %+v, want quarantined for its error rate", got)
+	}
+
+	stats, _ := registry.Stats(context.Background())
+	if s := stats.ExecutionStats["flaky"]; s.TotalExecutions != 7 || s.FailedRuns != 7 || s.ErrorRate != 1 {
+		t.Errorf("ExecutionStats = %+v, want 7 failed executions", s)
+	}
+
+	// Refused executions do not count against the tool
+	registry.Execute(context.Background(), "flaky", nil, nil)
+	if total, _ := registry.usageCounts("flaky"); total != 7 {
+		t.Errorf("usage = %d executions, want 7", total)
+	}
+}
+
+func TestSupervisor_Backoff(t *testing.T) {
+	tool := &testTool{name: "flaky", healthErr: errors.New("down")}
+	_, supervisor, _ := newSupervisedRegistry(t, tool, config.ToolHealth{
+		Interval:         time.Minute,
+		MaxBackoff:       3 * time.Minute,
+		FailureThreshold: 10,
+	})
+
+	var delays []time.Duration
+	for i := range 4 {
+		if i > 0 {
+			supervisor.mu.Lock()
+			supervisor.tools["flaky"].NextCheck = time.Time{}
+			supervisor.mu.Unlock()
+		}
+		supervisor.Check(context.Background())
+		st := supervisor.Status()[0]
+		delays = append(delays, st.NextCheck.Sub(st.LastCheck))
+	}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("delays = %v, want %v", delays, want)
+			break
+		}
+	}
+
+	// Not due yet: no new check
+	before := supervisor.Status()[0].LastCheck
+	supervisor.Check(context.Background())
+	if supervisor.Status()[0].LastCheck != before {
+		t.Error("Check() probed a tool before it was due")
+	}
+}
+
+func TestSupervisor_Start(t *testing.T) {
+	tool := &testTool{name: "flaky", healthErr: errors.New("down")}
+	registry, supervisor, _ := newSupervisedRegistry(t, tool, config.ToolHealth{
+		Interval:         5 * time.Millisecond,
+		FailureThreshold: 1,
+	})
+
+	supervisor.Start()
+	defer supervisor.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, quarantined := registry.Quarantined("flaky"); quarantined {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tool was not quarantined by the background checks")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Unregistered tools are no longer supervised
+	registry.Unregister("flaky")
+	supervisor.Close()
+	supervisor.Check(context.Background())
+	if status := supervisor.Status(); len(status) != 0 {
+		t.Errorf("Status() = %v, want no tools", status)
+	}
+}
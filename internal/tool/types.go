@@ -110,10 +110,21 @@ type ToolResultData struct {
 
 	// Any files or artifacts produced
 	Artifacts []ToolArtifact `json:"artifacts,omitempty"`
+
+	// Sources the result was drawn from, for attaching to answers
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation identifies a source behind a tool result
+type Citation struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
 }
 
 // ToolArtifact represents a file or artifact produced by a tool
 type ToolArtifact struct {
+	ID          string `json:"id,omitempty"` // set once the artifact store holds it; Content and Path are then empty
 	Name        string `json:"name"`
 	Type        string `json:"type"` // "file", "image", "code", etc.
 	Content     []byte `json:"content,omitempty"`
@@ -0,0 +1,386 @@
+package workflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// validID matches workflow names and step ids
+var validID = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,47}$`)
+
+// Definition is a workflow read from YAML
+type Definition struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Description string                 `yaml:"description" json:"description"`
+	Inputs      map[string]Input       `yaml:"inputs" json:"inputs,omitempty"`
+	Steps       []*Step                `yaml:"steps" json:"steps"`
+	Outputs     map[string]interface{} `yaml:"outputs" json:"outputs,omitempty"` // values may hold ${...} references
+
+	Source string `yaml:"-" json:"source,omitempty"` // file the definition was loaded from
+}
+
+// Input declares a workflow input
+type Input struct {
+	Type        string      `yaml:"type" json:"type"` // string, number, integer, boolean, array or object
+	Description string      `yaml:"description" json:"description,omitempty"`
+	Required    bool        `yaml:"required" json:"required,omitempty"`
+	Default     interface{} `yaml:"default" json:"default,omitempty"`
+	Enum        []string    `yaml:"enum" json:"enum,omitempty"`
+}
+
+// Step is a tool call or an LLM prompt. Steps run as soon as the steps
+// they need, explicitly or through references, have finished.
+type Step struct {
+	ID    string                 `yaml:"id" json:"id"`
+	Tool  string                 `yaml:"tool" json:"tool,omitempty"`
+	LLM   *LLMStep               `yaml:"llm" json:"llm,omitempty"`
+	Input map[string]interface{} `yaml:"input" json:"input,omitempty"`
+	Needs []string               `yaml:"needs" json:"needs,omitempty"`
+
+	// If skips the step unless the condition holds
+	If string `yaml:"if" json:"if,omitempty"`
+	// ForEach runs the step once per element of a list, exposed as item
+	// and index
+	ForEach string `yaml:"for_each" json:"for_each,omitempty"`
+
+	Retry           Retry         `yaml:"retry" json:"retry,omitempty"`
+	Timeout         time.Duration `yaml:"timeout" json:"timeout,omitempty"` // per attempt
+	ContinueOnError bool          `yaml:"continue_on_error" json:"continue_on_error,omitempty"`
+
+	deps      []string
+	condition *condition
+	forEach   *template
+}
+
+// LLMStep sends a prompt to the AI provider
+type LLMStep struct {
+	Prompt    string `yaml:"prompt" json:"prompt"`
+	System    string `yaml:"system" json:"system,omitempty"`
+	Provider  string `yaml:"provider" json:"provider,omitempty"`
+	Model     string `yaml:"model" json:"model,omitempty"`
+	MaxTokens int    `yaml:"max_tokens" json:"max_tokens,omitempty"`
+}
+
+// Retry controls how often a failed step is attempted
+type Retry struct {
+	Attempts int           `yaml:"attempts" json:"attempts,omitempty"` // total attempts, default 1
+	Delay    time.Duration `yaml:"delay" json:"delay,omitempty"`
+	Backoff  float64       `yaml:"backoff" json:"backoff,omitempty"` // delay multiplier, default 2
+}
+
+// Parse reads and validates a workflow definition
+func Parse(data []byte) (*Definition, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var def Definition
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
+	}
+	if err := def.validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// validate checks a definition and resolves step dependencies
+func (d *Definition) validate() error {
+	if !validID.MatchString(d.Name) {
+		return fmt.Errorf("invalid workflow name %q: use letters, digits and underscores", d.Name)
+	}
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("workflow %s has no steps", d.Name)
+	}
+	for name, in := range d.Inputs {
+		switch in.Type {
+		case "", tool.ParameterTypeString, tool.ParameterTypeNumber, tool.ParameterTypeInteger,
+			tool.ParameterTypeBoolean, tool.ParameterTypeArray, tool.ParameterTypeObject:
+		default:
+			return fmt.Errorf("input %s: unknown type %q", name, in.Type)
+		}
+	}
+
+	steps := make(map[string]*Step, len(d.Steps))
+	for _, s := range d.Steps {
+		if !validID.MatchString(s.ID) {
+			return fmt.Errorf("invalid step id %q: use letters, digits and underscores", s.ID)
+		}
+		if steps[s.ID] != nil {
+			return fmt.Errorf("duplicate step id %s", s.ID)
+		}
+		steps[s.ID] = s
+	}
+
+	var errs []error
+	for _, s := range d.Steps {
+		if err := d.validateStep(s, steps); err != nil {
+			errs = append(errs, fmt.Errorf("step %s: %w", s.ID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	outputRefs, err := collectRefs(map[string]interface{}(d.Outputs))
+	if err != nil {
+		return fmt.Errorf("outputs: %w", err)
+	}
+	for _, ref := range outputRefs {
+		if err := d.checkRef(ref, steps, false); err != nil {
+			return fmt.Errorf("outputs: %w", err)
+		}
+	}
+
+	if cycle := d.cycle(steps); cycle != nil {
+		return fmt.Errorf("steps form a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// validateStep checks a step and records what it depends on
+func (d *Definition) validateStep(s *Step, steps map[string]*Step) error {
+	switch {
+	case s.Tool == "" && s.LLM == nil:
+		return fmt.Errorf("needs a tool or an llm prompt")
+	case s.Tool != "" && s.LLM != nil:
+		return fmt.Errorf("cannot have both a tool and an llm prompt")
+	case s.LLM != nil && strings.TrimSpace(s.LLM.Prompt) == "":
+		return fmt.Errorf("llm prompt is empty")
+	case s.LLM != nil && len(s.Input) > 0:
+		return fmt.Errorf("input is only used by tool steps; reference values in the prompt")
+	case s.Retry.Attempts < 0 || s.Retry.Delay < 0 || s.Retry.Backoff < 0 || s.Timeout < 0:
+		return fmt.Errorf("retry and timeout values must not be negative")
+	}
+
+	var refs []*path
+	r, err := collectRefs(map[string]interface{}(s.Input))
+	if err != nil {
+		return err
+	}
+	refs = append(refs, r...)
+	if s.LLM != nil {
+		r, err := collectRefs([]interface{}{s.LLM.Prompt, s.LLM.System})
+		if err != nil {
+			return err
+		}
+		refs = append(refs, r...)
+	}
+	if s.If != "" {
+		if s.condition, err = parseCondition(s.If); err != nil {
+			return err
+		}
+		refs = append(refs, s.condition.refs()...)
+	}
+	if s.ForEach != "" {
+		if s.forEach, err = parseTemplate(s.ForEach); err != nil {
+			return err
+		}
+		if len(s.forEach.parts) != 1 || s.forEach.parts[0].ref == nil {
+			return fmt.Errorf("for_each must be a single reference such as ${steps.list.output.items}")
+		}
+		// item and index are not yet bound in the for_each expression
+		if root := s.forEach.parts[0].ref.root; root == rootItem || root == rootIndex {
+			return fmt.Errorf("for_each cannot reference %s", root)
+		}
+		refs = append(refs, s.forEach.refs()...)
+	}
+
+	deps := make(map[string]bool)
+	for _, id := range s.Needs {
+		if steps[id] == nil {
+			return fmt.Errorf("needs unknown step %s", id)
+		}
+		deps[id] = true
+	}
+	for _, ref := range refs {
+		if err := d.checkRef(ref, steps, s.ForEach != ""); err != nil {
+			return err
+		}
+		if id := ref.step(); id != "" {
+			deps[id] = true
+		}
+	}
+	if deps[s.ID] {
+		return fmt.Errorf("references itself")
+	}
+	s.deps = make([]string, 0, len(deps))
+	for id := range deps {
+		s.deps = append(s.deps, id)
+	}
+	sort.Strings(s.deps)
+	return nil
+}
+
+// checkRef rejects references to unknown inputs and steps
+func (d *Definition) checkRef(ref *path, steps map[string]*Step, inLoop bool) error {
+	switch ref.root {
+	case rootInputs:
+		if len(ref.segments) == 0 || ref.segments[0].isIndex || ref.segments[0].wildcard {
+			return fmt.Errorf("%s: reference an input by name", ref.raw)
+		}
+		if _, ok := d.Inputs[ref.segments[0].key]; !ok {
+			return fmt.Errorf("%s: unknown input %s", ref.raw, ref.segments[0].key)
+		}
+	case rootSteps:
+		if steps[ref.step()] == nil {
+			return fmt.Errorf("%s: unknown step %s", ref.raw, ref.step())
+		}
+	case rootItem, rootIndex:
+		if !inLoop {
+			return fmt.Errorf("%s: %s is only defined in for_each steps", ref.raw, ref.root)
+		}
+	}
+	return nil
+}
+
+// cycle returns the steps of a dependency cycle, or nil
+func (d *Definition) cycle(steps map[string]*Step) []string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		switch state[id] {
+		case visiting:
+			for i, s := range stack {
+				if s == id {
+					return append(append([]string(nil), stack[i:]...), id)
+				}
+			}
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range steps[id].deps {
+			if c := visit(dep); c != nil {
+				return c
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		return nil
+	}
+	for _, s := range d.Steps {
+		if c := visit(s.ID); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// Parameters describes the workflow inputs as a tool parameter schema
+func (d *Definition) Parameters() *tool.ToolParametersSchema {
+	schema := &tool.ToolParametersSchema{
+		Type:       "object",
+		Properties: make(map[string]tool.ParameterProperty, len(d.Inputs)),
+	}
+	for _, name := range sortedKeys(d.Inputs) {
+		in := d.Inputs[name]
+		typ := in.Type
+		if typ == "" {
+			typ = tool.ParameterTypeString
+		}
+		schema.Properties[name] = tool.ParameterProperty{
+			Type:        typ,
+			Description: in.Description,
+			Default:     in.Default,
+			Enum:        in.Enum,
+		}
+		if in.Required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// bindInputs applies defaults and checks the given inputs against the
+// declared ones
+func (d *Definition) bindInputs(given map[string]interface{}) (map[string]interface{}, error) {
+	inputs := make(map[string]interface{}, len(d.Inputs))
+	for name := range given {
+		if _, ok := d.Inputs[name]; !ok {
+			return nil, fmt.Errorf("unknown input %s", name)
+		}
+	}
+	for _, name := range sortedKeys(d.Inputs) {
+		in := d.Inputs[name]
+		v, ok := given[name]
+		if !ok || v == nil {
+			if in.Required {
+				return nil, fmt.Errorf("input %s is required", name)
+			}
+			v = in.Default
+		}
+		if v != nil {
+			normalized, err := normalize(v)
+			if err != nil {
+				return nil, fmt.Errorf("input %s: %w", name, err)
+			}
+			v = normalized
+			if err := checkType(v, in); err != nil {
+				return nil, fmt.Errorf("input %s: %w", name, err)
+			}
+		}
+		inputs[name] = v
+	}
+	return inputs, nil
+}
+
+// checkType checks a normalized input value
+func checkType(v interface{}, in Input) error {
+	ok := true
+	switch in.Type {
+	case "", tool.ParameterTypeString:
+		_, ok = v.(string)
+	case tool.ParameterTypeNumber:
+		_, ok = v.(float64)
+	case tool.ParameterTypeInteger:
+		n, isNum := v.(float64)
+		ok = isNum && n == float64(int64(n))
+	case tool.ParameterTypeBoolean:
+		_, ok = v.(bool)
+	case tool.ParameterTypeArray:
+		_, ok = v.([]interface{})
+	case tool.ParameterTypeObject:
+		_, ok = v.(map[string]interface{})
+	}
+	if !ok {
+		typ := in.Type
+		if typ == "" {
+			typ = tool.ParameterTypeString
+		}
+		return fmt.Errorf("want %s, got %T", typ, v)
+	}
+	if len(in.Enum) > 0 {
+		s, _ := v.(string)
+		for _, e := range in.Enum {
+			if e == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(in.Enum, ", "))
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
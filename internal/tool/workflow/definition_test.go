@@ -0,0 +1,266 @@
+package workflow
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	scope := map[string]interface{}{
+		"inputs": map[string]interface{}{"name": "web", "count": float64(3)},
+		"steps": map[string]interface{}{
+			"scan": map[string]interface{}{
+				"output": map[string]interface{}{
+					"issues": []interface{}{
+						map[string]interface{}{"line": float64(1), "rule": "DL3006"},
+						map[string]interface{}{"line": float64(4), "rule": "DL3008"},
+					},
+					"a.b": "dotted",
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		template string
+		want     interface{}
+	}{
+		{"${inputs.name}", "web"},
+		{"${inputs.count}", float64(3)},
+		{"${$.inputs.count}", float64(3)},
+		{"${steps.scan.output.issues[1].rule}", "DL3008"},
+		{"${steps.scan.output.issues[*].line}", []interface{}{float64(1), float64(4)}},
+		{`${steps.scan.output["a.b"]}`, "dotted"},
+		{"${steps.scan.output.missing.deeper}", nil},
+		{"${steps.scan.output.issues[9]}", nil},
+		{"image ${inputs.name}:${inputs.count}", "image web:3"},
+		{"lines ${steps.scan.output.issues[*].line}", "lines [1,4]"},
+		{"[${inputs.missing}]", "[]"},
+		{"$${inputs.name}", "${inputs.name}"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseTemplate() error = %v", err)
+			}
+			if got := tmpl.eval(scope); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("eval() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"${inputs.name", "${}", "${outputs.x}", "${inputs.[0]}", "${inputs.list[x]}"} {
+		if _, err := parseTemplate(bad); err == nil {
+			t.Errorf("parseTemplate(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCondition(t *testing.T) {
+	scope := map[string]interface{}{
+		"inputs": map[string]interface{}{"env": "prod", "replicas": float64(3), "dry_run": false},
+		"steps": map[string]interface{}{
+			"scan": map[string]interface{}{
+				"success": true,
+				"output":  map[string]interface{}{"issues": []interface{}{"a", "b"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"steps.scan.success", true},
+		{"!inputs.dry_run", true},
+		{`inputs.env == "prod"`, true},
+		{"inputs.env != 'prod'", false},
+		{"inputs.replicas >= 3 && inputs.replicas < 10", true},
+		{"len(steps.scan.output.issues) > 1", true},
+		{"len(steps.scan.output.missing) == 0", true},
+		{"inputs.dry_run || (inputs.replicas > 5)", false},
+		{"${inputs.env} == 'dev' || true", true},
+		{"steps.missing.success", false},
+		{"inputs.env", true},
+		{"inputs.replicas == 3.0", true},
+		{"null == inputs.nothing", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cond, err := parseCondition(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCondition() error = %v", err)
+			}
+			if got := cond.eval(scope); got != tt.want {
+				t.Errorf("eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "inputs.a ==", "(inputs.a", "len(inputs.a", "inputs.a = 1", "'open", "outputs.x"} {
+		if _, err := parseCondition(bad); err == nil {
+			t.Errorf("parseCondition(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	def, err := Parse([]byte(`
+name: review
+inputs:
+  path: {type: string, required: true}
+steps:
+  - id: summary
+    llm:
+      prompt: "Summarise ${steps.scan.output}"
+  - id: scan
+    tool: docker
+    input: {action: analyze_dockerfile, dockerfile_path: "${inputs.path}"}
+  - id: notify
+    tool: slack
+    needs: [scan]
+    if: steps.scan.success
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := map[string][]string{"summary": {"scan"}, "scan": nil, "notify": {"scan"}}
+	for _, s := range def.Steps {
+		if fmt.Sprint(s.deps) != fmt.Sprint(want[s.ID]) {
+			t.Errorf("step %s deps = %v, want %v", s.ID, s.deps, want[s.ID])
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{
+			name: "invalid name",
+			yaml: "name: my-flow\nsteps: [{id: a, tool: x}]",
+			want: "invalid workflow name",
+		},
+		{
+			name: "no steps",
+			yaml: "name: flow",
+			want: "has no steps",
+		},
+		{
+			name: "unknown field",
+			yaml: "name: flow\nsteps: [{id: a, tool: x, retries: 3}]",
+			want: "field retries not found",
+		},
+		{
+			name: "duplicate step",
+			yaml: "name: flow\nsteps: [{id: a, tool: x}, {id: a, tool: y}]",
+			want: "duplicate step id a",
+		},
+		{
+			name: "tool and llm",
+			yaml: "name: flow\nsteps: [{id: a, tool: x, llm: {prompt: hi}}]",
+			want: "step a:",
+		},
+		{
+			name: "unknown step",
+			yaml: "name: flow\nsteps: [{id: a, tool: x, input: {v: '${steps.b.result}'}}]",
+			want: "unknown step b",
+		},
+		{
+			name: "unknown input",
+			yaml: "name: flow\nsteps: [{id: a, tool: x, if: inputs.flag}]",
+			want: "unknown input flag",
+		},
+		{
+			name: "item outside for_each",
+			yaml: "name: flow\nsteps: [{id: a, tool: x, input: {v: '${item}'}}]",
+			want: "item",
+		},
+		{
+			name: "self reference",
+			yaml: "name: flow\nsteps: [{id: a, tool: x, input: {v: '${steps.a.result}'}}]",
+			want: "step a:",
+		},
+		{
+			name: "cycle",
+			yaml: "name: flow\nsteps: [{id: a, tool: x, needs: [c]}, {id: b, tool: x, needs: [a]}, {id: c, tool: x, needs: [b]}]",
+			want: "steps form a cycle",
+		},
+		{
+			name: "bad output",
+			yaml: "name: flow\nsteps: [{id: a, tool: x}]\noutputs: {r: '${steps.z.result}'}",
+			want: "outputs:",
+		},
+		{
+			name: "bad input type",
+			yaml: "name: flow\ninputs: {n: {type: float}}\nsteps: [{id: a, tool: x}]",
+			want: `unknown type "float"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if err == nil {
+				t.Fatal("Parse() succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindInputs(t *testing.T) {
+	def, err := Parse([]byte(`
+name: flow
+inputs:
+  path: {type: string, required: true}
+  level: {type: string, default: warn, enum: [info, warn, error]}
+  limit: {type: integer, default: 10}
+  tags: {type: array}
+steps: [{id: a, tool: x}]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	got, err := def.bindInputs(map[string]interface{}{"path": "Dockerfile", "tags": []string{"a"}})
+	if err != nil {
+		t.Fatalf("bindInputs() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"path":  "Dockerfile",
+		"level": "warn",
+		"limit": float64(10),
+		"tags":  []interface{}{"a"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bindInputs() = %#v, want %#v", got, want)
+	}
+
+	for _, given := range []map[string]interface{}{
+		{},
+		{"path": 1},
+		{"path": "x", "level": "debug"},
+		{"path": "x", "limit": 1.5},
+		{"path": "x", "other": true},
+	} {
+		if _, err := def.bindInputs(given); err == nil {
+			t.Errorf("bindInputs(%v) succeeded, want error", given)
+		}
+	}
+
+	schema := def.Parameters()
+	if !reflect.DeepEqual(schema.Required, []string{"path"}) {
+		t.Errorf("Parameters().Required = %v, want [path]", schema.Required)
+	}
+	if p := schema.Properties["level"]; p.Default != "warn" || len(p.Enum) != 3 {
+		t.Errorf("Parameters().Properties[level] = %+v", p)
+	}
+}
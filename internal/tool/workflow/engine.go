@@ -0,0 +1,538 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// Limits that keep a workflow from running away
+const (
+	maxForEachItems = 100
+	maxNestingDepth = 5
+)
+
+// ErrInvalidInput is returned when a run's inputs do not match the
+// workflow's declared inputs
+var ErrInvalidInput = errors.New("invalid workflow input")
+
+// ToolExecutor runs tool steps; *tool.Registry satisfies it
+type ToolExecutor interface {
+	Execute(ctx context.Context, name string, input *tool.ToolInput, config *tool.ToolConfig) (*tool.ToolResult, error)
+}
+
+// LLM runs llm steps; *ai.Service satisfies it
+type LLM interface {
+	GenerateResponse(ctx context.Context, request *ai.GenerateRequest, providerName string) (*ai.GenerateResponse, error)
+}
+
+// RunStore records finished runs
+type RunStore interface {
+	SaveRun(ctx context.Context, run *Run) error
+}
+
+// StepStatus is the outcome of a step
+type StepStatus string
+
+const (
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	StepSkipped   StepStatus = "skipped"
+)
+
+// Run is the record of one workflow execution
+type Run struct {
+	ID         string                 `json:"id"`
+	Workflow   string                 `json:"workflow"`
+	UserID     string                 `json:"user_id,omitempty"`
+	Inputs     map[string]interface{} `json:"inputs"`
+	Outputs    map[string]interface{} `json:"outputs,omitempty"`
+	Steps      []*StepRun             `json:"steps"`
+	Success    bool                   `json:"success"`
+	Error      string                 `json:"error,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	Duration   time.Duration          `json:"duration"`
+	TokensUsed int                    `json:"tokens_used,omitempty"`
+}
+
+// StepRun is the record of one step. A for_each step records each item
+// in Items and collects their results and outputs into lists.
+type StepRun struct {
+	ID         string        `json:"id"`
+	Status     StepStatus    `json:"status"`
+	Tool       string        `json:"tool,omitempty"`
+	Input      interface{}   `json:"input,omitempty"` // rendered tool input or prompt
+	Result     interface{}   `json:"result,omitempty"`
+	Output     interface{}   `json:"output,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Attempts   int           `json:"attempts,omitempty"`
+	Items      []*StepRun    `json:"items,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+	TokensUsed int           `json:"tokens_used,omitempty"`
+}
+
+// value is how later steps see a step: steps.<id>.result and so on
+func (s *StepRun) value() map[string]interface{} {
+	return map[string]interface{}{
+		"status":  string(s.Status),
+		"success": s.Status == StepSucceeded,
+		"result":  s.Result,
+		"output":  s.Output,
+		"error":   s.Error,
+	}
+}
+
+// Engine runs workflow definitions
+type Engine struct {
+	tools       ToolExecutor
+	llm         LLM
+	store       RunStore
+	maxParallel int
+	stepTimeout time.Duration
+	logger      *slog.Logger
+}
+
+// NewEngine creates a workflow engine. llm and store may be nil: llm
+// steps then fail and runs are not recorded.
+func NewEngine(tools ToolExecutor, llm LLM, store RunStore, cfg config.Workflows, logger *slog.Logger) *Engine {
+	if cfg.MaxParallel <= 0 {
+		cfg.MaxParallel = 4
+	}
+	if cfg.StepTimeout <= 0 {
+		cfg.StepTimeout = 5 * time.Minute
+	}
+	return &Engine{
+		tools:       tools,
+		llm:         llm,
+		store:       store,
+		maxParallel: cfg.MaxParallel,
+		stepTimeout: cfg.StepTimeout,
+		logger:      logger,
+	}
+}
+
+type depthKey struct{}
+
+// Run executes a workflow. Invalid inputs return ErrInvalidInput; step
+// failures are reported in the returned run.
+func (e *Engine) Run(ctx context.Context, def *Definition, inputs map[string]interface{}, tctx *tool.ToolContext) (*Run, error) {
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxNestingDepth {
+		return nil, fmt.Errorf("workflow %s: workflows are nested more than %d deep", def.Name, maxNestingDepth)
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	bound, err := def.bindInputs(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if tctx == nil {
+		tctx = &tool.ToolContext{}
+	}
+
+	run := &Run{
+		ID:        uuid.NewString(),
+		Workflow:  def.Name,
+		UserID:    tctx.UserID,
+		Inputs:    bound,
+		StartedAt: time.Now(),
+	}
+	e.logger.Info("Workflow started",
+		slog.String("workflow", def.Name),
+		slog.String("run_id", run.ID))
+
+	e.schedule(ctx, def, run, tctx)
+
+	run.Success = run.Error == ""
+	if run.Success {
+		if outputs, err := e.outputs(def, run); err != nil {
+			run.Success = false
+			run.Error = err.Error()
+		} else {
+			run.Outputs = outputs
+		}
+	}
+	run.Duration = time.Since(run.StartedAt)
+
+	e.logger.Info("Workflow finished",
+		slog.String("workflow", def.Name),
+		slog.String("run_id", run.ID),
+		slog.Bool("success", run.Success),
+		slog.Duration("duration", run.Duration))
+
+	if e.store != nil {
+		// The record outlives a cancelled request
+		if err := e.store.SaveRun(context.WithoutCancel(ctx), run); err != nil {
+			e.logger.Warn("Failed to record workflow run",
+				slog.String("run_id", run.ID),
+				slog.Any("error", err))
+		}
+	}
+	return run, nil
+}
+
+// schedule runs steps in dependency order, up to maxParallel at a time.
+// After a failure no further steps start; steps that never ran are
+// recorded as skipped.
+func (e *Engine) schedule(ctx context.Context, def *Definition, run *Run, tctx *tool.ToolContext) {
+	records := make(map[string]*StepRun, len(def.Steps))
+	finished := make(chan *StepRun)
+	running := 0
+	started := make(map[string]bool)
+	scope := map[string]interface{}{
+		rootInputs: run.Inputs,
+		rootSteps:  map[string]interface{}{},
+	}
+	stepValues := scope[rootSteps].(map[string]interface{})
+
+	for {
+		// Skipping a step can make others ready, so start steps until a
+		// pass changes nothing
+		for progress := true; progress && run.Error == "" && ctx.Err() == nil; {
+			progress = false
+			for _, s := range def.Steps {
+				if started[s.ID] || running >= e.maxParallel || !e.ready(s, records) {
+					continue
+				}
+				started[s.ID] = true
+				progress = true
+
+				// The scope is read-only while steps run; each step gets
+				// the values of its finished dependencies
+				stepScope := copyScope(scope)
+				if reason := e.skip(s, stepScope); reason != "" {
+					record := &StepRun{ID: s.ID, Status: StepSkipped, Error: reason, StartedAt: time.Now()}
+					records[s.ID] = record
+					stepValues[s.ID] = record.value()
+					continue
+				}
+				running++
+				go func(s *Step) {
+					finished <- e.runStep(ctx, s, stepScope, tctx)
+				}(s)
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		record := <-finished
+		running--
+		records[record.ID] = record
+		stepValues[record.ID] = record.value()
+		run.TokensUsed += record.TokensUsed
+		if record.Status == StepFailed && !stepByID(def, record.ID).ContinueOnError && run.Error == "" {
+			run.Error = fmt.Sprintf("step %s failed: %s", record.ID, record.Error)
+		}
+	}
+
+	if run.Error == "" && ctx.Err() != nil {
+		run.Error = fmt.Sprintf("workflow cancelled: %v", ctx.Err())
+	}
+	for _, s := range def.Steps {
+		record := records[s.ID]
+		if record == nil {
+			record = &StepRun{ID: s.ID, Status: StepSkipped, Error: "not run"}
+		}
+		run.Steps = append(run.Steps, record)
+	}
+}
+
+// ready reports whether every dependency of a step has finished
+func (e *Engine) ready(s *Step, records map[string]*StepRun) bool {
+	for _, dep := range s.deps {
+		if records[dep] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// skip returns why a ready step should not run, or "". Steps after a
+// failed continue_on_error step still run and can check its success.
+func (e *Engine) skip(s *Step, scope map[string]interface{}) string {
+	if s.condition != nil && !s.condition.eval(scope) {
+		return fmt.Sprintf("condition is false: %s", s.If)
+	}
+	return ""
+}
+
+// runStep runs a step, once per item for for_each steps
+func (e *Engine) runStep(ctx context.Context, s *Step, scope map[string]interface{}, tctx *tool.ToolContext) *StepRun {
+	record := &StepRun{ID: s.ID, Tool: s.Tool, StartedAt: time.Now()}
+	defer func() { record.Duration = time.Since(record.StartedAt) }()
+
+	if s.forEach == nil {
+		e.attempt(ctx, s, scope, tctx, record)
+		return record
+	}
+
+	value := s.forEach.eval(scope)
+	list, ok := value.([]interface{})
+	switch {
+	case !ok && value != nil:
+		record.Status = StepFailed
+		record.Error = fmt.Sprintf("for_each %s is not a list", s.ForEach)
+		return record
+	case len(list) > maxForEachItems:
+		record.Status = StepFailed
+		record.Error = fmt.Sprintf("for_each %s has %d items, more than %d", s.ForEach, len(list), maxForEachItems)
+		return record
+	}
+
+	// Items run in parallel, bounded like steps
+	record.Items = make([]*StepRun, len(list))
+	sem := make(chan struct{}, e.maxParallel)
+	done := make(chan struct{})
+	for i, item := range list {
+		go func(i int, item interface{}) {
+			sem <- struct{}{}
+			defer func() { <-sem; done <- struct{}{} }()
+			itemScope := copyScope(scope)
+			itemScope[rootItem] = item
+			itemScope[rootIndex] = float64(i)
+			itemRecord := &StepRun{ID: fmt.Sprintf("%s[%d]", s.ID, i), StartedAt: time.Now()}
+			e.attempt(ctx, s, itemScope, tctx, itemRecord)
+			itemRecord.Duration = time.Since(itemRecord.StartedAt)
+			record.Items[i] = itemRecord
+		}(i, item)
+	}
+	for range list {
+		<-done
+	}
+
+	results := make([]interface{}, len(list))
+	outputs := make([]interface{}, len(list))
+	record.Status = StepSucceeded
+	for i, item := range record.Items {
+		results[i] = item.Result
+		outputs[i] = item.Output
+		record.TokensUsed += item.TokensUsed
+		if item.Status == StepFailed && record.Status != StepFailed {
+			record.Status = StepFailed
+			record.Error = fmt.Sprintf("item %d: %s", i, item.Error)
+		}
+	}
+	record.Result = results
+	record.Output = outputs
+	return record
+}
+
+// attempt runs a step with its retry policy and fills in the record
+func (e *Engine) attempt(ctx context.Context, s *Step, scope map[string]interface{}, tctx *tool.ToolContext, record *StepRun) {
+	attempts := s.Retry.Attempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	delay := s.Retry.Delay
+	backoff := s.Retry.Backoff
+	if backoff == 0 {
+		backoff = 2
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = e.stepTimeout
+	}
+
+	for record.Attempts = 1; ; record.Attempts++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		var err error
+		if s.LLM != nil {
+			err = e.runLLM(attemptCtx, s, scope, tctx, record)
+		} else {
+			err = e.runTool(attemptCtx, s, scope, tctx, record)
+		}
+		cancel()
+		if err == nil {
+			record.Status = StepSucceeded
+			record.Error = ""
+			return
+		}
+
+		record.Status = StepFailed
+		record.Error = err.Error()
+		if record.Attempts >= attempts || ctx.Err() != nil {
+			return
+		}
+		e.logger.Warn("Workflow step failed, retrying",
+			slog.String("step", record.ID),
+			slog.Int("attempt", record.Attempts),
+			slog.Any("error", err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = time.Duration(float64(delay) * backoff)
+	}
+}
+
+// runTool runs a tool step attempt
+func (e *Engine) runTool(ctx context.Context, s *Step, scope map[string]interface{}, tctx *tool.ToolContext, record *StepRun) error {
+	rendered, err := render(map[string]interface{}(s.Input), scope)
+	if err != nil {
+		return err
+	}
+	params, err := normalize(rendered)
+	if err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+	record.Input = params
+	input, _ := params.(map[string]interface{})
+	if input == nil {
+		input = make(map[string]interface{})
+	}
+
+	result, execErr := e.tools.Execute(ctx, s.Tool, &tool.ToolInput{Parameters: input, Context: tctx}, nil)
+	if result != nil && result.Data != nil {
+		if record.Result, err = normalize(result.Data.Result); err != nil {
+			return fmt.Errorf("unreadable result: %w", err)
+		}
+		var output interface{}
+		if len(result.Data.Output) > 0 {
+			if output, err = normalize(result.Data.Output); err != nil {
+				return fmt.Errorf("unreadable output: %w", err)
+			}
+		}
+		record.Output = output
+	}
+	switch {
+	case result != nil && !result.Success:
+		return errors.New(result.Error)
+	case execErr != nil:
+		return execErr
+	}
+	return nil
+}
+
+// runLLM runs an llm step attempt
+func (e *Engine) runLLM(ctx context.Context, s *Step, scope map[string]interface{}, tctx *tool.ToolContext, record *StepRun) error {
+	if e.llm == nil {
+		return fmt.Errorf("no AI provider is available for llm steps")
+	}
+	prompt, err := parseTemplate(s.LLM.Prompt)
+	if err != nil {
+		return err
+	}
+	text := format(prompt.eval(scope))
+	record.Input = text
+
+	req := &ai.GenerateRequest{
+		Messages:  []ai.Message{{Role: "user", Content: text}},
+		MaxTokens: s.LLM.MaxTokens,
+		Model:     s.LLM.Model,
+		Metadata:  &ai.RequestMetadata{UserID: tctx.UserID, ConversationID: tctx.ConversationID},
+	}
+	if s.LLM.System != "" {
+		system, err := parseTemplate(s.LLM.System)
+		if err != nil {
+			return err
+		}
+		sys := format(system.eval(scope))
+		req.SystemPrompt = &sys
+	}
+
+	resp, err := e.llm.GenerateResponse(ctx, req, s.LLM.Provider)
+	if err != nil {
+		return err
+	}
+	record.Result = resp.Content
+	record.Output = map[string]interface{}{
+		"text":     resp.Content,
+		"model":    resp.Model,
+		"provider": resp.Provider,
+	}
+	record.TokensUsed += resp.TokensUsed.TotalTokens
+	return nil
+}
+
+// outputs renders the workflow outputs. Without declared outputs, the
+// results of the steps nothing depends on are returned.
+func (e *Engine) outputs(def *Definition, run *Run) (map[string]interface{}, error) {
+	steps := make(map[string]interface{}, len(run.Steps))
+	for _, s := range run.Steps {
+		steps[s.ID] = s.value()
+	}
+	scope := map[string]interface{}{rootInputs: run.Inputs, rootSteps: steps}
+
+	if len(def.Outputs) > 0 {
+		rendered, err := render(def.Outputs, scope)
+		if err != nil {
+			return nil, fmt.Errorf("outputs: %w", err)
+		}
+		out, err := normalize(rendered)
+		if err != nil {
+			return nil, fmt.Errorf("outputs: %w", err)
+		}
+		return out.(map[string]interface{}), nil
+	}
+
+	needed := make(map[string]bool)
+	for _, s := range def.Steps {
+		for _, dep := range s.deps {
+			needed[dep] = true
+		}
+	}
+	outputs := make(map[string]interface{})
+	for _, s := range run.Steps {
+		if !needed[s.ID] && s.Status == StepSucceeded {
+			if s.Result != nil {
+				outputs[s.ID] = s.Result
+			} else {
+				outputs[s.ID] = s.Output
+			}
+		}
+	}
+	return outputs, nil
+}
+
+// normalize converts a value to its JSON form (maps, lists, float64,
+// string, bool and nil) so expressions see tool results uniformly
+func normalize(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// copyScope copies the top level of a scope and its step values
+func copyScope(scope map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(scope)+2)
+	for k, v := range scope {
+		out[k] = v
+	}
+	steps := scope[rootSteps].(map[string]interface{})
+	copied := make(map[string]interface{}, len(steps))
+	for k, v := range steps {
+		copied[k] = v
+	}
+	out[rootSteps] = copied
+	return out
+}
+
+func stepByID(def *Definition, id string) *Step {
+	for _, s := range def.Steps {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
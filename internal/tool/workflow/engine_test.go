@@ -0,0 +1,501 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/ai"
+	"github.com/koopa0/assistant-go/internal/config"
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// toolFunc answers a fake tool call
+type toolFunc func(params map[string]interface{}) (*tool.ToolResult, error)
+
+// fakeTools is a ToolExecutor that records calls
+type fakeTools struct {
+	mu      sync.Mutex
+	tools   map[string]toolFunc
+	calls   []string
+	running int32
+	peak    int32
+}
+
+func (f *fakeTools) Execute(ctx context.Context, name string, input *tool.ToolInput, _ *tool.ToolConfig) (*tool.ToolResult, error) {
+	n := atomic.AddInt32(&f.running, 1)
+	defer atomic.AddInt32(&f.running, -1)
+	f.mu.Lock()
+	f.calls = append(f.calls, name)
+	if n > f.peak {
+		f.peak = n
+	}
+	fn := f.tools[name]
+	f.mu.Unlock()
+	if fn == nil {
+		return nil, errors.New("tool " + name + " not found")
+	}
+	return fn(input.Parameters)
+}
+
+// echo returns its parameters as the result
+func echo(params map[string]interface{}) (*tool.ToolResult, error) {
+	return &tool.ToolResult{Success: true, Data: &tool.ToolResultData{Result: params}}, nil
+}
+
+// fakeLLM answers prompts by echoing them
+type fakeLLM struct {
+	prompts []string
+}
+
+func (f *fakeLLM) GenerateResponse(ctx context.Context, req *ai.GenerateRequest, provider string) (*ai.GenerateResponse, error) {
+	f.prompts = append(f.prompts, req.Messages[0].Content)
+	return &ai.GenerateResponse{
+		Content:    "summary of: " + req.Messages[0].Content,
+		Model:      "fake",
+		Provider:   "fake",
+		TokensUsed: ai.TokenUsage{TotalTokens: 42},
+	}, nil
+}
+
+// memoryStore records saved runs
+type memoryStore struct {
+	runs []*Run
+}
+
+func (s *memoryStore) SaveRun(ctx context.Context, run *Run) error {
+	s.runs = append(s.runs, run)
+	return nil
+}
+
+func mustParse(t *testing.T, yaml string) *Definition {
+	t.Helper()
+	def, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return def
+}
+
+func newTestEngine(tools *fakeTools, llm LLM, store RunStore) *Engine {
+	return NewEngine(tools, llm, store, config.Workflows{MaxParallel: 4, StepTimeout: time.Second}, discardLogger())
+}
+
+func stepStatuses(run *Run) map[string]StepStatus {
+	statuses := make(map[string]StepStatus, len(run.Steps))
+	for _, s := range run.Steps {
+		statuses[s.ID] = s.Status
+	}
+	return statuses
+}
+
+func TestEngine_DataFlow(t *testing.T) {
+	tools := &fakeTools{tools: map[string]toolFunc{
+		"analyze": func(params map[string]interface{}) (*tool.ToolResult, error) {
+			return &tool.ToolResult{Success: true, Data: &tool.ToolResultData{
+				Output: map[string]interface{}{"issues": []string{"latest tag", "no user"}},
+			}}, nil
+		},
+		"optimize": echo,
+	}}
+	llm := &fakeLLM{}
+	store := &memoryStore{}
+	def := mustParse(t, `
+name: review
+inputs:
+  path: {type: string, required: true}
+steps:
+  - id: analyze
+    tool: analyze
+    input: {path: "${inputs.path}"}
+  - id: optimize
+    tool: optimize
+    if: len(steps.analyze.output.issues) > 0
+    input:
+      issues: ${steps.analyze.output.issues}
+      first: ${steps.analyze.output.issues[0]}
+  - id: summarise
+    llm:
+      prompt: "Fixed ${steps.optimize.result.first} in ${inputs.path}"
+outputs:
+  summary: ${steps.summarise.result}
+  count: ${steps.optimize.result.issues}
+`)
+
+	run, err := newTestEngine(tools, llm, store).Run(context.Background(), def,
+		map[string]interface{}{"path": "Dockerfile"}, &tool.ToolContext{UserID: "u1"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !run.Success {
+		t.Fatalf("Run() failed: %s", run.Error)
+	}
+	if want := []string{"analyze", "optimize"}; !reflect.DeepEqual(tools.calls, want) {
+		t.Errorf("calls = %v, want %v", tools.calls, want)
+	}
+	if want := []string{"Fixed latest tag in Dockerfile"}; !reflect.DeepEqual(llm.prompts, want) {
+		t.Errorf("prompts = %v, want %v", llm.prompts, want)
+	}
+	want := map[string]interface{}{
+		"summary": "summary of: Fixed latest tag in Dockerfile",
+		"count":   []interface{}{"latest tag", "no user"},
+	}
+	if !reflect.DeepEqual(run.Outputs, want) {
+		t.Errorf("Outputs = %#v, want %#v", run.Outputs, want)
+	}
+	if run.TokensUsed != 42 || run.UserID != "u1" {
+		t.Errorf("TokensUsed = %d, UserID = %q", run.TokensUsed, run.UserID)
+	}
+	if len(store.runs) != 1 || store.runs[0] != run {
+		t.Errorf("store has %d runs, want the run", len(store.runs))
+	}
+}
+
+func TestEngine_Parallel(t *testing.T) {
+	slow := func(params map[string]interface{}) (*tool.ToolResult, error) {
+		time.Sleep(50 * time.Millisecond)
+		return echo(params)
+	}
+	tools := &fakeTools{tools: map[string]toolFunc{"slow": slow}}
+	def := mustParse(t, `
+name: fan
+steps:
+  - {id: a, tool: slow}
+  - {id: b, tool: slow}
+  - {id: c, tool: slow}
+  - {id: join, tool: slow, needs: [a, b, c]}
+`)
+
+	run, err := newTestEngine(tools, nil, nil).Run(context.Background(), def, nil, nil)
+	if err != nil || !run.Success {
+		t.Fatalf("Run() = %+v, %v", run, err)
+	}
+	if tools.peak != 3 {
+		t.Errorf("peak concurrency = %d, want 3", tools.peak)
+	}
+	if run.Steps[3].ID != "join" {
+		t.Errorf("join did not run last: %v", run.Steps)
+	}
+	// Without declared outputs the sink step's result is returned
+	if _, ok := run.Outputs["join"]; !ok || len(run.Outputs) != 1 {
+		t.Errorf("Outputs = %v, want the join result", run.Outputs)
+	}
+}
+
+func TestEngine_Conditions(t *testing.T) {
+	tools := &fakeTools{tools: map[string]toolFunc{"t": echo}}
+	def := mustParse(t, `
+name: cond
+inputs:
+  env: {type: string, default: dev}
+steps:
+  - {id: deploy, tool: t, if: "inputs.env == 'prod'"}
+  - {id: notify, tool: t, if: steps.deploy.success}
+  - {id: report, tool: t, needs: [deploy], input: {deployed: "${steps.deploy.status}"}}
+`)
+
+	run, err := newTestEngine(tools, nil, nil).Run(context.Background(), def, nil, nil)
+	if err != nil || !run.Success {
+		t.Fatalf("Run() = %+v, %v", run, err)
+	}
+	want := map[string]StepStatus{"deploy": StepSkipped, "notify": StepSkipped, "report": StepSucceeded}
+	if got := stepStatuses(run); !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if got := run.Outputs["report"]; !reflect.DeepEqual(got, map[string]interface{}{"deployed": "skipped"}) {
+		t.Errorf("report result = %v", got)
+	}
+}
+
+func TestEngine_ForEach(t *testing.T) {
+	tools := &fakeTools{tools: map[string]toolFunc{
+		"scan": func(params map[string]interface{}) (*tool.ToolResult, error) {
+			image, ok := params["image"].(string)
+			if !ok {
+				return &tool.ToolResult{Success: false, Error: "image must be a string"}, nil
+			}
+			return &tool.ToolResult{Success: true, Data: &tool.ToolResultData{
+				Result: "scanned " + image,
+			}}, nil
+		},
+	}}
+	def := mustParse(t, `
+name: loop
+inputs:
+  images: {type: array, required: true}
+steps:
+  - id: scan
+    tool: scan
+    for_each: ${inputs.images}
+    input: {image: "${item}", position: "${index}"}
+outputs:
+  results: ${steps.scan.result}
+`)
+
+	run, err := newTestEngine(tools, nil, nil).Run(context.Background(), def,
+		map[string]interface{}{"images": []string{"nginx", "redis", "postgres"}}, nil)
+	if err != nil || !run.Success {
+		t.Fatalf("Run() = %+v, %v", run, err)
+	}
+	want := []interface{}{"scanned nginx", "scanned redis", "scanned postgres"}
+	if got := run.Outputs["results"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	if items := run.Steps[0].Items; len(items) != 3 || items[2].ID != "scan[2]" {
+		t.Errorf("items = %v", items)
+	}
+	if got := run.Steps[0].Items[1].Input; !reflect.DeepEqual(got, map[string]interface{}{"image": "redis", "position": float64(1)}) {
+		t.Errorf("item input = %v", got)
+	}
+
+	run, err = newTestEngine(tools, nil, nil).Run(context.Background(), def,
+		map[string]interface{}{"images": []interface{}{"nginx", 7}}, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if run.Success || !strings.Contains(run.Error, "item 1") {
+		t.Errorf("Run() error = %q, want the failing item", run.Error)
+	}
+}
+
+func TestEngine_Retry(t *testing.T) {
+	var calls int32
+	tools := &fakeTools{tools: map[string]toolFunc{
+		"flaky": func(params map[string]interface{}) (*tool.ToolResult, error) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				return &tool.ToolResult{Success: false, Error: "temporarily unavailable"}, nil
+			}
+			return echo(params)
+		},
+	}}
+	def := mustParse(t, `
+name: retry
+steps:
+  - {id: call, tool: flaky, retry: {attempts: 3, delay: 1ms}}
+`)
+
+	run, err := newTestEngine(tools, nil, nil).Run(context.Background(), def, nil, nil)
+	if err != nil || !run.Success {
+		t.Fatalf("Run() = %+v, %v", run, err)
+	}
+	if run.Steps[0].Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", run.Steps[0].Attempts)
+	}
+
+	calls = -10
+	run, _ = newTestEngine(tools, nil, nil).Run(context.Background(), def, nil, nil)
+	if run.Success || run.Error != "step call failed: temporarily unavailable" {
+		t.Errorf("Run() error = %q", run.Error)
+	}
+}
+
+func TestEngine_Timeout(t *testing.T) {
+	engine := NewEngine(ctxTools{}, nil, nil, config.Workflows{}, discardLogger())
+	def := mustParse(t, `
+name: slow
+steps:
+  - {id: wait, tool: hang, timeout: 20ms}
+`)
+
+	run, err := engine.Run(context.Background(), def, nil, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if run.Success || !strings.Contains(run.Error, context.DeadlineExceeded.Error()) {
+		t.Errorf("Run() error = %q, want a deadline error", run.Error)
+	}
+}
+
+// ctxTools blocks until the call is cancelled
+type ctxTools struct{}
+
+func (ctxTools) Execute(ctx context.Context, name string, input *tool.ToolInput, _ *tool.ToolConfig) (*tool.ToolResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestEngine_Failure(t *testing.T) {
+	tools := &fakeTools{tools: map[string]toolFunc{
+		"ok": echo,
+		"broken": func(map[string]interface{}) (*tool.ToolResult, error) {
+			return &tool.ToolResult{Success: false, Error: "boom"}, nil
+		},
+	}}
+	def := mustParse(t, `
+name: fail
+steps:
+  - {id: lint, tool: broken, continue_on_error: true}
+  - {id: report, tool: ok, input: {lint_ok: "${steps.lint.success}", why: "${steps.lint.error}"}}
+  - {id: build, tool: broken, needs: [report]}
+  - {id: push, tool: ok, needs: [build]}
+`)
+
+	run, err := newTestEngine(tools, nil, nil).Run(context.Background(), def, nil, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if run.Success || run.Error != "step build failed: boom" {
+		t.Errorf("Run() error = %q", run.Error)
+	}
+	want := map[string]StepStatus{"lint": StepFailed, "report": StepSucceeded, "build": StepFailed, "push": StepSkipped}
+	if got := stepStatuses(run); !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if got := run.Steps[1].Result; !reflect.DeepEqual(got, map[string]interface{}{"lint_ok": false, "why": "boom"}) {
+		t.Errorf("report result = %v", got)
+	}
+	if run.Outputs != nil {
+		t.Errorf("Outputs = %v, want none for a failed run", run.Outputs)
+	}
+}
+
+func TestEngine_InvalidInput(t *testing.T) {
+	def := mustParse(t, `
+name: strict
+inputs:
+  path: {type: string, required: true}
+steps: [{id: a, tool: t}]
+`)
+	_, err := newTestEngine(&fakeTools{}, nil, nil).Run(context.Background(), def, nil, nil)
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Run() error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestWorkflowTool(t *testing.T) {
+	tools := &fakeTools{tools: map[string]toolFunc{"t": echo}}
+	def := mustParse(t, `
+name: greet
+description: Greets someone.
+inputs:
+  who: {type: string, required: true}
+steps:
+  - {id: hello, tool: t, input: {msg: "hello ${inputs.who}"}}
+outputs:
+  message: ${steps.hello.result.msg}
+`)
+	wt := NewWorkflowTool(def, newTestEngine(tools, nil, nil))
+
+	if wt.Name() != "workflow_greet" {
+		t.Errorf("Name() = %q", wt.Name())
+	}
+	if !strings.Contains(wt.Description(), "Greets someone.") || !strings.Contains(wt.Description(), "hello") {
+		t.Errorf("Description() = %q", wt.Description())
+	}
+	if p := wt.Parameters(); len(p.Required) != 1 || p.Required[0] != "who" {
+		t.Errorf("Parameters() = %+v", p)
+	}
+
+	result, err := wt.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{"who": "gopher"}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.Success || !reflect.DeepEqual(result.Data.Result, map[string]interface{}{"message": "hello gopher"}) {
+		t.Errorf("Execute() = %+v", result)
+	}
+	if result.Data.Output["run_id"] == "" || len(result.Data.Output["steps"].([]interface{})) != 1 {
+		t.Errorf("Execute() output = %v", result.Data.Output)
+	}
+
+	result, err = wt.Execute(context.Background(), &tool.ToolInput{Parameters: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Success || !strings.Contains(result.Error, "input who is required") {
+		t.Errorf("Execute() = %+v, want an input error", result)
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("one.yaml", "name: one\nsteps: [{id: a, tool: echo}]\n")
+	write("two.yml", "name: two\nsteps: [{id: a, tool: workflow_one}]\n")
+	write("broken.yaml", "name: broken\nsteps: []\n")
+	write("copy.yaml", "name: one\nsteps: [{id: b, tool: echo}]\n")
+	write("notes.txt", "not a workflow")
+
+	registry := tool.NewRegistry(discardLogger())
+	echoFactory := func(*tool.ToolConfig, *slog.Logger) (tool.Tool, error) { return echoTool{}, nil }
+	if err := registry.Register("echo", echoFactory); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(dir, NewEngine(registry, nil, nil, config.Workflows{}, discardLogger()), registry, discardLogger())
+
+	err := m.Reload()
+	if err == nil || !strings.Contains(err.Error(), "broken.yaml") || !strings.Contains(err.Error(), "already defined in") {
+		t.Errorf("Reload() error = %v, want the broken and duplicate files", err)
+	}
+	var names []string
+	for _, def := range m.List() {
+		names = append(names, def.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"one", "two"}) {
+		t.Fatalf("List() = %v, want [one two]", names)
+	}
+
+	// Workflows run as tools, including from other workflows
+	result, err := registry.Execute(context.Background(), "workflow_two", &tool.ToolInput{Parameters: map[string]interface{}{}}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("Execute(workflow_two) = %+v, %v", result, err)
+	}
+
+	os.Remove(filepath.Join(dir, "two.yml"))
+	os.Remove(filepath.Join(dir, "broken.yaml"))
+	os.Remove(filepath.Join(dir, "copy.yaml"))
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if registry.IsRegistered("workflow_two") || !registry.IsRegistered("workflow_one") {
+		t.Error("Reload() did not replace the registered workflows")
+	}
+	if _, err := m.Get("two"); !errors.Is(err, ErrUnknownWorkflow) {
+		t.Errorf("Get(two) error = %v, want ErrUnknownWorkflow", err)
+	}
+	if def, err := m.Get("workflow_one"); err != nil || def.Source != filepath.Join(dir, "one.yaml") {
+		t.Errorf("Get(workflow_one) = %v, %v", def, err)
+	}
+}
+
+// echoTool returns its parameters
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echoes its parameters" }
+func (echoTool) Parameters() *tool.ToolParametersSchema {
+	return &tool.ToolParametersSchema{Type: "object"}
+}
+func (echoTool) Health(context.Context) error { return nil }
+func (echoTool) Close(context.Context) error  { return nil }
+func (echoTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.ToolResult, error) {
+	return echo(input.Parameters)
+}
+
+func TestQueriesStore_SaveRunWithoutUser(t *testing.T) {
+	// Both cases fail before the store touches the database
+	store := NewQueriesStore(nil)
+	for _, userID := range []string{"", "cli"} {
+		err := store.SaveRun(context.Background(), &Run{ID: "run-1", UserID: userID})
+		if err == nil || !strings.Contains(err.Error(), "user id") {
+			t.Errorf("SaveRun(user %q) error = %v, want a user id error", userID, err)
+		}
+	}
+}
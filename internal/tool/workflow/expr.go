@@ -0,0 +1,667 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Expressions reference values in the run scope with a JSONPath-like
+// syntax: inputs.name, steps.<id>.output.items[0].name, steps.<id>.result,
+// item and index inside for_each steps. [*] maps the rest of a path over
+// a list and ["key"] reads keys that are not identifiers. A leading $. is
+// accepted. Missing keys and out of range indexes evaluate to null.
+
+// Roots of the run scope
+const (
+	rootInputs = "inputs"
+	rootSteps  = "steps"
+	rootItem   = "item"
+	rootIndex  = "index"
+)
+
+// path is a parsed reference
+type path struct {
+	raw      string
+	root     string
+	segments []segment
+}
+
+// segment is one accessor of a path
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// step returns the step id a path references, if any
+func (p *path) step() string {
+	if p.root == rootSteps && len(p.segments) > 0 && !p.segments[0].isIndex && !p.segments[0].wildcard {
+		return p.segments[0].key
+	}
+	return ""
+}
+
+// parsePath parses a reference such as steps.analyze.output.issues[0]
+func parsePath(s string) (*path, error) {
+	raw := strings.TrimSpace(s)
+	src := strings.TrimPrefix(raw, "$.")
+	p := &path{raw: raw}
+
+	i := 0
+	ident := func() string {
+		start := i
+		for i < len(src) && isIdentByte(src[i]) {
+			i++
+		}
+		return src[start:i]
+	}
+
+	p.root = ident()
+	switch p.root {
+	case rootInputs, rootSteps, rootItem, rootIndex:
+	case "":
+		return nil, fmt.Errorf("invalid reference %q", raw)
+	default:
+		return nil, fmt.Errorf("invalid reference %q: must start with inputs, steps, item or index", raw)
+	}
+
+	for i < len(src) {
+		switch src[i] {
+		case '.':
+			i++
+			key := ident()
+			if key == "" {
+				return nil, fmt.Errorf("invalid reference %q: empty key at offset %d", raw, i)
+			}
+			p.segments = append(p.segments, segment{key: key})
+		case '[':
+			end := strings.IndexByte(src[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid reference %q: unclosed [", raw)
+			}
+			inner := strings.TrimSpace(src[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "*":
+				p.segments = append(p.segments, segment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				p.segments = append(p.segments, segment{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid reference %q: bad index [%s]", raw, inner)
+				}
+				p.segments = append(p.segments, segment{index: n, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("invalid reference %q: unexpected %q", raw, src[i])
+		}
+	}
+	if p.root == rootSteps && p.step() == "" {
+		return nil, fmt.Errorf("invalid reference %q: missing step id", raw)
+	}
+	return p, nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// eval resolves a path in the scope
+func (p *path) eval(scope map[string]interface{}) interface{} {
+	return walk(scope[p.root], p.segments)
+}
+
+func walk(v interface{}, segments []segment) interface{} {
+	for n, s := range segments {
+		switch {
+		case s.wildcard:
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil
+			}
+			out := make([]interface{}, len(list))
+			for j, elem := range list {
+				out[j] = walk(elem, segments[n+1:])
+			}
+			return out
+		case s.isIndex:
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil
+			}
+			idx := s.index
+			if idx < 0 {
+				idx += len(list)
+			}
+			if idx < 0 || idx >= len(list) {
+				return nil
+			}
+			v = list[idx]
+		default:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = m[s.key]
+		}
+	}
+	return v
+}
+
+// template is a string with ${...} references. $${ escapes a literal ${.
+type template struct {
+	parts []templatePart
+}
+
+type templatePart struct {
+	literal string
+	ref     *path
+}
+
+// parseTemplate parses a string with embedded references
+func parseTemplate(s string) (*template, error) {
+	t := &template{}
+	var lit strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			lit.WriteString("${")
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed ${ in %q", s)
+			}
+			ref, err := parsePath(s[i+2 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			if lit.Len() > 0 {
+				t.parts = append(t.parts, templatePart{literal: lit.String()})
+				lit.Reset()
+			}
+			t.parts = append(t.parts, templatePart{ref: ref})
+			i += end + 1
+		default:
+			lit.WriteByte(s[i])
+			i++
+		}
+	}
+	if lit.Len() > 0 {
+		t.parts = append(t.parts, templatePart{literal: lit.String()})
+	}
+	return t, nil
+}
+
+// refs returns the references of a template
+func (t *template) refs() []*path {
+	var refs []*path
+	for _, p := range t.parts {
+		if p.ref != nil {
+			refs = append(refs, p.ref)
+		}
+	}
+	return refs
+}
+
+// eval renders the template. A template that is a single reference keeps
+// the referenced value's type; otherwise values are formatted into text.
+func (t *template) eval(scope map[string]interface{}) interface{} {
+	if len(t.parts) == 1 && t.parts[0].ref != nil {
+		return t.parts[0].ref.eval(scope)
+	}
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.ref == nil {
+			b.WriteString(p.literal)
+			continue
+		}
+		b.WriteString(format(p.ref.eval(scope)))
+	}
+	return b.String()
+}
+
+// format renders a value into template text: strings as is, null as
+// nothing and everything else as JSON
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// collectRefs parses every template in a YAML value (strings, maps and
+// lists) and returns the references
+func collectRefs(v interface{}) ([]*path, error) {
+	var refs []*path
+	switch v := v.(type) {
+	case string:
+		t, err := parseTemplate(v)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, t.refs()...)
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			r, err := collectRefs(v[k])
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, r...)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			r, err := collectRefs(elem)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, r...)
+		}
+	}
+	return refs, nil
+}
+
+// render evaluates every template in a YAML value
+func render(v interface{}, scope map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		t, err := parseTemplate(v)
+		if err != nil {
+			return nil, err
+		}
+		return t.eval(scope), nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			r, err := render(elem, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			r, err := render(elem, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// condition is a parsed if expression:
+//
+//	expr    = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ ("==" | "!=" | "<" | "<=" | ">" | ">=") operand ]
+//	operand = "(" expr ")" | "len(" expr ")" | literal | reference
+//
+// References may be written bare or as ${...}.
+type condition struct {
+	raw  string
+	root node
+}
+
+type node interface {
+	eval(scope map[string]interface{}) interface{}
+}
+
+type (
+	literalNode struct{ value interface{} }
+	refNode     struct{ ref *path }
+	notNode     struct{ x node }
+	lenNode     struct{ x node }
+	binaryNode  struct {
+		op   string
+		x, y node
+	}
+)
+
+func (n literalNode) eval(map[string]interface{}) interface{}   { return n.value }
+func (n refNode) eval(scope map[string]interface{}) interface{} { return n.ref.eval(scope) }
+func (n notNode) eval(scope map[string]interface{}) interface{} { return !truthy(n.x.eval(scope)) }
+
+func (n lenNode) eval(scope map[string]interface{}) interface{} {
+	switch v := n.x.eval(scope).(type) {
+	case string:
+		return float64(len(v))
+	case []interface{}:
+		return float64(len(v))
+	case map[string]interface{}:
+		return float64(len(v))
+	}
+	return float64(0)
+}
+
+func (n binaryNode) eval(scope map[string]interface{}) interface{} {
+	switch n.op {
+	case "&&":
+		return truthy(n.x.eval(scope)) && truthy(n.y.eval(scope))
+	case "||":
+		return truthy(n.x.eval(scope)) || truthy(n.y.eval(scope))
+	}
+	x, y := n.x.eval(scope), n.y.eval(scope)
+	switch n.op {
+	case "==":
+		return equal(x, y)
+	case "!=":
+		return !equal(x, y)
+	}
+	c, ok := compare(x, y)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// parseCondition parses an if expression
+func parseCondition(s string) (*condition, error) {
+	p := &condParser{src: s}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", s, err)
+	}
+	root, err := p.expr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", s, err)
+	}
+	return &condition{raw: s, root: root}, nil
+}
+
+// eval reports whether the condition holds
+func (c *condition) eval(scope map[string]interface{}) bool {
+	return truthy(c.root.eval(scope))
+}
+
+// refs returns the references of a condition
+func (c *condition) refs() []*path {
+	var refs []*path
+	var visit func(n node)
+	visit = func(n node) {
+		switch n := n.(type) {
+		case refNode:
+			refs = append(refs, n.ref)
+		case notNode:
+			visit(n.x)
+		case lenNode:
+			visit(n.x)
+		case binaryNode:
+			visit(n.x)
+			visit(n.y)
+		}
+	}
+	visit(c.root)
+	return refs
+}
+
+type tokenKind int
+
+const (
+	tokOp tokenKind = iota
+	tokLiteral
+	tokRef
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	ref   *path
+}
+
+type condParser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func (p *condParser) tokenize() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return fmt.Errorf("unclosed ${")
+			}
+			ref, err := parsePath(s[i+2 : i+end])
+			if err != nil {
+				return err
+			}
+			p.tokens = append(p.tokens, token{kind: tokRef, text: s[i : i+end+1], ref: ref})
+			i += end + 1
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return fmt.Errorf("unterminated string")
+			}
+			text := s[i : i+end+2]
+			p.tokens = append(p.tokens, token{kind: tokLiteral, text: text, value: s[i+1 : i+end+1]})
+			i += end + 2
+		case strings.ContainsRune("=!<>&|", rune(c)):
+			op := string(c)
+			if i+1 < len(s) {
+				switch two := s[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "=" || op == "&" || op == "|" {
+				return fmt.Errorf("unknown operator %q", op)
+			}
+			p.tokens = append(p.tokens, token{kind: tokOp, text: op})
+			i += len(op)
+		case c == '(' || c == ')':
+			p.tokens = append(p.tokens, token{kind: tokOp, text: string(c)})
+			i++
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || s[j] >= '0' && s[j] <= '9') {
+				j++
+			}
+			n, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return fmt.Errorf("bad number %q", s[i:j])
+			}
+			p.tokens = append(p.tokens, token{kind: tokLiteral, text: s[i:j], value: n})
+			i = j
+		default:
+			j := i
+			for j < len(s) && (isIdentByte(s[j]) || s[j] == '.' || s[j] == '$' || s[j] == '[') {
+				if s[j] == '[' {
+					end := strings.IndexByte(s[j:], ']')
+					if end < 0 {
+						return fmt.Errorf("unclosed [")
+					}
+					j += end
+				}
+				j++
+			}
+			if j == i {
+				return fmt.Errorf("unexpected %q", c)
+			}
+			text := s[i:j]
+			switch text {
+			case "true", "false":
+				p.tokens = append(p.tokens, token{kind: tokLiteral, text: text, value: text == "true"})
+			case "null":
+				p.tokens = append(p.tokens, token{kind: tokLiteral, text: text})
+			case "len":
+				p.tokens = append(p.tokens, token{kind: tokOp, text: text})
+			default:
+				ref, err := parsePath(text)
+				if err != nil {
+					return err
+				}
+				p.tokens = append(p.tokens, token{kind: tokRef, text: text, ref: ref})
+			}
+			i = j
+		}
+	}
+	return nil
+}
+
+func (p *condParser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokOp && p.tokens[p.pos].text == text
+}
+
+func (p *condParser) expr() (node, error) {
+	x, err := p.and()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var y node
+		if y, err = p.and(); err == nil {
+			x = binaryNode{op: "||", x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *condParser) and() (node, error) {
+	x, err := p.unary()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var y node
+		if y, err = p.unary(); err == nil {
+			x = binaryNode{op: "&&", x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *condParser) unary() (node, error) {
+	if p.peek("!") {
+		p.pos++
+		x, err := p.unary()
+		return notNode{x: x}, err
+	}
+	x, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.peek(op) {
+			p.pos++
+			y, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op: op, x: x, y: y}, nil
+		}
+	}
+	return x, nil
+}
+
+func (p *condParser) operand() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case t.kind == tokLiteral:
+		return literalNode{value: t.value}, nil
+	case t.kind == tokRef:
+		return refNode{ref: t.ref}, nil
+	case t.text == "(" || t.text == "len":
+		if t.text == "len" {
+			if !p.peek("(") {
+				return nil, fmt.Errorf("len needs parentheses")
+			}
+			p.pos++
+		}
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		if t.text == "len" {
+			return lenNode{x: x}, nil
+		}
+		return x, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// truthy reports whether a value counts as true: null, false, 0, "" and
+// empty lists and objects are false
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+func equal(x, y interface{}) bool {
+	if c, ok := compare(x, y); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// compare orders two numbers or two strings
+func compare(x, y interface{}) (int, bool) {
+	switch x := x.(type) {
+	case float64:
+		if y, ok := y.(float64); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	}
+	return 0, false
+}
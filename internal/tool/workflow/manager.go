@@ -0,0 +1,142 @@
+// Package workflow runs declarative multi-tool workflows. A workflow is a
+// YAML file describing a DAG of tool and LLM steps whose inputs reference
+// earlier outputs; steps can be conditional, fan out over lists and retry.
+// Each workflow is also registered as a tool named workflow_<name>.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// ErrUnknownWorkflow is returned for a workflow that is not defined
+var ErrUnknownWorkflow = errors.New("workflow is not defined")
+
+// toolPrefix prefixes the tool name of each workflow
+const toolPrefix = "workflow_"
+
+// Manager loads workflow definitions from a directory and keeps them
+// registered as tools
+type Manager struct {
+	dir      string
+	engine   *Engine
+	registry *tool.Registry
+	logger   *slog.Logger
+
+	mu   sync.RWMutex
+	defs map[string]*Definition
+}
+
+// NewManager creates a manager for the workflows in dir
+func NewManager(dir string, engine *Engine, registry *tool.Registry, logger *slog.Logger) *Manager {
+	return &Manager{
+		dir:      dir,
+		engine:   engine,
+		registry: registry,
+		logger:   logger,
+		defs:     make(map[string]*Definition),
+	}
+}
+
+// Reload reads every .yaml and .yml file in the directory and replaces the
+// registered workflows. Invalid files are skipped; their errors are joined
+// into the returned error.
+func (m *Manager) Reload() error {
+	files, err := filepath.Glob(filepath.Join(m.dir, "*.y*ml"))
+	if err != nil {
+		return fmt.Errorf("failed to list workflows: %w", err)
+	}
+	if _, err := os.Stat(m.dir); err != nil {
+		return fmt.Errorf("failed to read workflows directory: %w", err)
+	}
+	sort.Strings(files)
+
+	var errs []error
+	defs := make(map[string]*Definition)
+	for _, file := range files {
+		if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read workflow: %w", err))
+			continue
+		}
+		def, err := Parse(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(file), err))
+			continue
+		}
+		if prev, ok := defs[def.Name]; ok {
+			errs = append(errs, fmt.Errorf("%s: workflow %s is already defined in %s", filepath.Base(file), def.Name, filepath.Base(prev.Source)))
+			continue
+		}
+		def.Source = file
+		defs[def.Name] = def
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name := range m.defs {
+		if err := m.registry.Unregister(toolPrefix + name); err != nil {
+			m.logger.Warn("Failed to unregister workflow tool",
+				slog.String("workflow", name),
+				slog.Any("error", err))
+		}
+	}
+	m.defs = make(map[string]*Definition, len(defs))
+	for _, name := range sortedKeys(defs) {
+		def := defs[name]
+		factory := func(cfg *tool.ToolConfig, logger *slog.Logger) (tool.Tool, error) {
+			return NewWorkflowTool(def, m.engine), nil
+		}
+		if err := m.registry.Register(toolPrefix+name, factory); err != nil {
+			errs = append(errs, fmt.Errorf("workflow %s: %w", name, err))
+			continue
+		}
+		m.defs[name] = def
+	}
+
+	m.logger.Info("Workflows loaded",
+		slog.String("dir", m.dir),
+		slog.Int("count", len(m.defs)))
+	return errors.Join(errs...)
+}
+
+// List returns the loaded workflows by name
+func (m *Manager) List() []*Definition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	defs := make([]*Definition, 0, len(m.defs))
+	for _, name := range sortedKeys(m.defs) {
+		defs = append(defs, m.defs[name])
+	}
+	return defs
+}
+
+// Get returns a loaded workflow
+func (m *Manager) Get(name string) (*Definition, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	def, ok := m.defs[strings.TrimPrefix(name, toolPrefix)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownWorkflow, name)
+	}
+	return def, nil
+}
+
+// Engine returns the engine workflows run on
+func (m *Manager) Engine() *Engine {
+	return m.engine
+}
@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+)
+
+// chainType is recorded in chain_executions.chain_type for workflow runs
+const chainType = "workflow"
+
+// QueriesStore implements RunStore on top of the chain_executions table
+type QueriesStore struct {
+	queries *sqlc.Queries
+}
+
+// NewQueriesStore creates a run store backed by sqlc queries
+func NewQueriesStore(queries *sqlc.Queries) *QueriesStore {
+	return &QueriesStore{
+		queries: queries,
+	}
+}
+
+// SaveRun records a run with its workflow name and run id in the
+// metadata. chain_executions rows belong to a user, so a run without a
+// valid user id is an error.
+func (s *QueriesStore) SaveRun(ctx context.Context, run *Run) error {
+	if run.UserID == "" {
+		return fmt.Errorf("workflow run %s has no user id", run.ID)
+	}
+	userID, err := uuid.Parse(run.UserID)
+	if err != nil {
+		return fmt.Errorf("workflow run %s has an invalid user id %q: %w", run.ID, run.UserID, err)
+	}
+
+	inputs, err := json.Marshal(run.Inputs)
+	if err != nil {
+		return fmt.Errorf("failed to encode inputs: %w", err)
+	}
+	outputs, err := json.Marshal(run.Outputs)
+	if err != nil {
+		return fmt.Errorf("failed to encode outputs: %w", err)
+	}
+	steps, err := json.Marshal(run.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to encode steps: %w", err)
+	}
+	metadata, err := json.Marshal(map[string]interface{}{
+		"workflow": run.Workflow,
+		"run_id":   run.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	_, err = s.queries.CreateChainExecution(ctx, sqlc.CreateChainExecutionParams{
+		ChainType:       chainType,
+		Column2:         pgtype.UUID{Bytes: userID, Valid: true},
+		Input:           string(inputs),
+		Output:          pgtype.Text{String: string(outputs), Valid: run.Outputs != nil},
+		Steps:           steps,
+		ExecutionTimeMs: pgtype.Int4{Int32: int32(run.Duration.Milliseconds()), Valid: true},
+		TokensUsed:      pgtype.Int4{Int32: int32(run.TokensUsed), Valid: true},
+		Success:         pgtype.Bool{Bool: run.Success, Valid: true},
+		ErrorMessage:    pgtype.Text{String: run.Error, Valid: run.Error != ""},
+		Metadata:        metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record workflow run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/koopa0/assistant-go/internal/tool"
+)
+
+// WorkflowTool exposes a workflow as a tool whose parameters are the
+// workflow inputs
+type WorkflowTool struct {
+	def    *Definition
+	engine *Engine
+}
+
+// NewWorkflowTool creates a tool that runs def
+func NewWorkflowTool(def *Definition, engine *Engine) *WorkflowTool {
+	return &WorkflowTool{
+		def:    def,
+		engine: engine,
+	}
+}
+
+// Name returns the tool name
+func (t *WorkflowTool) Name() string {
+	return toolPrefix + t.def.Name
+}
+
+// Description returns the workflow description and its steps
+func (t *WorkflowTool) Description() string {
+	ids := make([]string, len(t.def.Steps))
+	for i, s := range t.def.Steps {
+		ids[i] = s.ID
+	}
+	desc := strings.TrimSpace(t.def.Description)
+	if desc == "" {
+		desc = "Runs the " + t.def.Name + " workflow."
+	}
+	return fmt.Sprintf("%s (workflow steps: %s)", desc, strings.Join(ids, ", "))
+}
+
+// Parameters returns the workflow inputs
+func (t *WorkflowTool) Parameters() *tool.ToolParametersSchema {
+	return t.def.Parameters()
+}
+
+// Execute runs the workflow
+func (t *WorkflowTool) Execute(ctx context.Context, input *tool.ToolInput) (*tool.ToolResult, error) {
+	startTime := time.Now()
+
+	run, err := t.engine.Run(ctx, t.def, input.Parameters, input.Context)
+	if err != nil {
+		return &tool.ToolResult{
+			Success:       false,
+			Error:         err.Error(),
+			ExecutionTime: time.Since(startTime),
+		}, nil
+	}
+
+	steps := make([]interface{}, len(run.Steps))
+	for i, s := range run.Steps {
+		step := map[string]interface{}{"id": s.ID, "status": string(s.Status)}
+		if s.Error != "" {
+			step["error"] = s.Error
+		}
+		steps[i] = step
+	}
+	return &tool.ToolResult{
+		Success: run.Success,
+		Data: &tool.ToolResultData{
+			Result: run.Outputs,
+			Output: map[string]interface{}{
+				"run_id":      run.ID,
+				"outputs":     run.Outputs,
+				"steps":       steps,
+				"tokens_used": run.TokensUsed,
+			},
+		},
+		Error:         run.Error,
+		ExecutionTime: time.Since(startTime),
+	}, nil
+}
+
+// Health reports the tool as healthy; the tools it calls are checked by
+// the registry
+func (t *WorkflowTool) Health(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the workflow tool
+func (t *WorkflowTool) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,247 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koopa0/assistant-go/internal/langchain/collection"
+	"github.com/koopa0/assistant-go/internal/langchain/indexer"
+	"github.com/koopa0/assistant-go/internal/platform/storage/postgres/sqlc"
+	"github.com/koopa0/assistant-go/test/testutil"
+)
+
+const migrationsPath = "../../internal/platform/storage/postgres/migrations"
+
+// TestCodeIndexStore_Schema stores chunks through the index store against
+// the migrated schema, whose upserts need the indexes the migrations create
+func TestCodeIndexStore_Schema(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dbContainer, cleanup := testutil.SetupTestDatabase(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	require.NoError(t, dbContainer.WaitForHealthy(ctx, 10*time.Second))
+	require.NoError(t, dbContainer.RunMigrations(ctx, migrationsPath))
+
+	pool, err := dbContainer.GetConnectionPool(ctx)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	store := indexer.NewQueriesStore(sqlc.New(pool), uuid.MustParse(collection.DefaultID), "test-embedder")
+	id := uuid.New()
+	vector := make([]float32, 1536)
+	vector[0] = 1
+
+	// Storing a chunk again replaces it
+	require.NoError(t, store.PutChunk(ctx, "code", id, "func a() {}", vector, map[string]any{"path": "a.go"}))
+	require.NoError(t, store.PutChunk(ctx, "code", id, "func b() {}", vector, map[string]any{"path": "a.go"}))
+
+	var count int
+	var content string
+	err = pool.QueryRow(ctx, `SELECT COUNT(*), MAX(content_text) FROM embeddings WHERE content_type = 'code' AND content_id = $1`, id).Scan(&count, &content)
+	require.NoError(t, err)
+	require.Equal(t, 1, count, "chunk should be stored once")
+	require.Equal(t, "func b() {}", content)
+
+	require.NoError(t, store.DeleteChunk(ctx, "code", id))
+	err = pool.QueryRow(ctx, `SELECT COUNT(*) FROM embeddings WHERE content_id = $1`, id).Scan(&count)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+// TestCodeIndexStore_CollectionSearch stores chunks through the index store
+// and finds them by searching their collection, which only compares the
+// vectors of its active model
+func TestCodeIndexStore_CollectionSearch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dbContainer, cleanup := testutil.SetupTestDatabase(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	require.NoError(t, dbContainer.WaitForHealthy(ctx, 10*time.Second))
+	require.NoError(t, dbContainer.RunMigrations(ctx, migrationsPath))
+
+	pool, err := dbContainer.GetConnectionPool(ctx)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	queries := sqlc.New(pool)
+	adopted, err := collection.NewQueriesStore(queries).AdoptModel(ctx, collection.DefaultID, "test-embedder")
+	require.NoError(t, err)
+	require.True(t, adopted, "the default collection has no model before")
+
+	defaultID := uuid.MustParse(collection.DefaultID)
+	current := indexer.NewQueriesStore(queries, defaultID, "test-embedder")
+	other := indexer.NewQueriesStore(queries, defaultID, "other-embedder")
+	vector := make([]float32, 8)
+	vector[0] = 1
+
+	id, stale := uuid.New(), uuid.New()
+	require.NoError(t, current.PutChunk(ctx, "code", id, "func a() {}", vector, map[string]any{"path": "a.go"}))
+	require.NoError(t, other.PutChunk(ctx, "code", stale, "func b() {}", vector, map[string]any{"path": "b.go"}))
+
+	rows, err := queries.SearchEmbeddingsByVector(ctx, sqlc.SearchEmbeddingsByVectorParams{
+		QueryEmbedding: pgvector.NewVector(vector),
+		ContentTypes:   []string{"code"},
+		Tags:           []string{},
+		CollectionIds:  []pgtype.UUID{{Bytes: defaultID, Valid: true}},
+		Threshold:      0.5,
+		ResultLimit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1, "only the vectors of the active model are searched")
+	require.Equal(t, id, uuid.UUID(rows[0].ContentID.Bytes))
+}
+
+// TestMemorySearch_ModelFilter searches memories next to placeholder
+// vectors of another model and dimension, which must neither fail the
+// search nor be ranked
+func TestMemorySearch_ModelFilter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dbContainer, cleanup := testutil.SetupTestDatabase(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	require.NoError(t, dbContainer.WaitForHealthy(ctx, 10*time.Second))
+	require.NoError(t, dbContainer.RunMigrations(ctx, migrationsPath))
+
+	pool, err := dbContainer.GetConnectionPool(ctx)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	queries := sqlc.New(pool)
+	defaultID := pgtype.UUID{Bytes: uuid.MustParse(collection.DefaultID), Valid: true}
+	vector := make([]float32, 8)
+	vector[0] = 1
+	placeholder := make([]float32, 1536)
+	for i := range placeholder {
+		placeholder[i] = 0.1
+	}
+
+	id := uuid.New()
+	for _, row := range []struct {
+		id     uuid.UUID
+		vector []float32
+		model  string
+	}{
+		{id, vector, "test-embedder"},
+		{uuid.New(), placeholder, "mock"},
+		{uuid.New(), vector, "other-embedder"},
+	} {
+		_, err := queries.CreateEmbedding(ctx, sqlc.CreateEmbeddingParams{
+			CollectionID: defaultID,
+			ContentType:  "memory",
+			ContentID:    pgtype.UUID{Bytes: row.id, Valid: true},
+			ContentText:  "memory",
+			Embedding:    pgvector.NewVector(row.vector),
+			Metadata:     []byte("{}"),
+			Model:        row.model,
+		})
+		require.NoError(t, err)
+	}
+
+	rows, err := queries.SearchSimilarEmbeddings(ctx, sqlc.SearchSimilarEmbeddingsParams{
+		QueryEmbedding: pgvector.NewVector(vector),
+		ContentType:    "memory",
+		Model:          "test-embedder",
+		Threshold:      0.5,
+		ResultLimit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1, "only the vectors of the searched model are compared")
+	require.Equal(t, id, uuid.UUID(rows[0].ContentID.Bytes))
+}
+
+// TestSearchEmbeddingsByVector_DimensionIndexes searches vectors of the
+// indexed dimensions next to each other and checks that the planner can
+// answer a search from the partial index of the query's dimensions
+func TestSearchEmbeddingsByVector_DimensionIndexes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dbContainer, cleanup := testutil.SetupTestDatabase(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	require.NoError(t, dbContainer.WaitForHealthy(ctx, 10*time.Second))
+	require.NoError(t, dbContainer.RunMigrations(ctx, migrationsPath))
+
+	pool, err := dbContainer.GetConnectionPool(ctx)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	queries := sqlc.New(pool)
+	_, err = collection.NewQueriesStore(queries).AdoptModel(ctx, collection.DefaultID, "test-embedder")
+	require.NoError(t, err)
+	store := indexer.NewQueriesStore(queries, uuid.MustParse(collection.DefaultID), "test-embedder")
+
+	ids := map[int]uuid.UUID{}
+	for _, dims := range []int{768, 1536} {
+		vector := make([]float32, dims)
+		vector[0] = 1
+		ids[dims] = uuid.New()
+		require.NoError(t, store.PutChunk(ctx, "code", ids[dims], "func a() {}", vector, map[string]any{"path": "a.go"}))
+	}
+
+	conn, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+	_, err = conn.Exec(ctx, "SET enable_seqscan = off")
+	require.NoError(t, err)
+
+	for dims, id := range ids {
+		query := make([]float32, dims)
+		query[0] = 1
+		params := sqlc.SearchEmbeddingsByVectorParams{
+			QueryEmbedding: pgvector.NewVector(query),
+			ContentTypes:   []string{},
+			Tags:           []string{},
+			CollectionIds:  []pgtype.UUID{},
+			Threshold:      0.5,
+			ResultLimit:    10,
+		}
+		rows, err := sqlc.New(conn).SearchEmbeddingsByVector(ctx, params)
+		require.NoError(t, err)
+		require.Len(t, rows, 1, "only vectors of the query's dimensions are searched")
+		require.Equal(t, id, uuid.UUID(rows[0].ContentID.Bytes))
+
+		var plan strings.Builder
+		explain, err := conn.Query(ctx, "EXPLAIN "+sqlc.SearchEmbeddingsByVector,
+			params.QueryEmbedding, params.ContentTypes, params.PathPrefix, params.Tags,
+			params.CollectionIds, params.Threshold, params.ResultLimit)
+		require.NoError(t, err)
+		for explain.Next() {
+			var line string
+			require.NoError(t, explain.Scan(&line))
+			plan.WriteString(line + "\n")
+		}
+		require.NoError(t, explain.Err())
+		require.Contains(t, plan.String(), fmt.Sprintf("idx_embeddings_vector_%d", dims))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/koopa0/assistant-go/internal/ai"
+	"github.com/koopa0/assistant-go/internal/ai/local"
 )
 
 // MockAIManager provides a mock implementation of AI manager for testing
@@ -275,22 +276,10 @@ func (m *MockAIManager) generateMockContent(prompt string) string {
 	return fmt.Sprintf("Mock response for: %s", prompt)
 }
 
-// generateMockEmbedding generates a mock embedding vector
+// generateMockEmbedding generates a deterministic embedding with the local
+// embedder, so similar texts get similar vectors
 func (m *MockAIManager) generateMockEmbedding(text string) []float64 {
-	// Generate deterministic embedding based on text hash
-	hash := 0
-	for _, char := range text {
-		hash = hash*31 + int(char)
-	}
-
-	rand.Seed(int64(hash))
-	embedding := make([]float64, 1536) // Standard embedding dimension
-
-	for i := range embedding {
-		embedding[i] = rand.Float64()*2 - 1 // Values between -1 and 1
-	}
-
-	return embedding
+	return local.NewEmbedder(1536).Embed(text) // Standard embedding dimension
 }
 
 // updateUsageStats updates usage statistics
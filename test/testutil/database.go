@@ -12,6 +12,9 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/koopa0/assistant-go/internal/config"
+	storage "github.com/koopa0/assistant-go/internal/platform/storage/postgres"
 )
 
 // DatabaseContainer wraps a PostgreSQL test container
@@ -28,6 +31,7 @@ type DatabaseContainer struct {
 // NewPostgreSQLContainer creates a new PostgreSQL test container with pgvector extension
 func NewPostgreSQLContainer(ctx context.Context, t *testing.T) (*DatabaseContainer, error) {
 	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
 
 	// Create PostgreSQL container with pgvector
 	container, err := postgres.RunContainer(ctx,
@@ -147,10 +151,21 @@ func SetupTestDatabase(t *testing.T) (*DatabaseContainer, func()) {
 	return container, cleanup
 }
 
-// RunMigrations runs database migrations for testing
+// RunMigrations applies the migrations in migrationsPath to the database
 func (dc *DatabaseContainer) RunMigrations(ctx context.Context, migrationsPath string) error {
-	// This would integrate with your migration system
-	// For now, we'll assume migrations are handled by init scripts
+	client, err := storage.NewClient(ctx, config.DatabaseConfig{
+		URL:            dc.URL,
+		MaxConnections: 2,
+		MigrationsPath: migrationsPath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect for migrations: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
 	return nil
 }
 